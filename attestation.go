@@ -0,0 +1,263 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/tlv"
+)
+
+const (
+	attestationNonceType          tlv.Type = 0
+	attestationPubKeyType         tlv.Type = 2
+	attestationAliasType          tlv.Type = 4
+	attestationVersionType        tlv.Type = 6
+	attestationNetworkType        tlv.Type = 8
+	attestationBlockHeightType    tlv.Type = 10
+	attestationBlockHashType      tlv.Type = 12
+	attestationSyncedType         tlv.Type = 14
+	attestationActiveChannelsType tlv.Type = 16
+	attestationPeersType          tlv.Type = 18
+	attestationTimestampType      tlv.Type = 20
+
+	// minAttestationNonceLen is the minimum length of the nonce of an
+	// attestation. The nonce is chosen by the verifier so that
+	// attestations can't be replayed, so it must be hard to guess.
+	minAttestationNonceLen = 8
+
+	// maxAttestationNonceLen is the maximum length of the nonce of an
+	// attestation.
+	maxAttestationNonceLen = 64
+)
+
+var (
+	// attestationSignedMsgPrefix is prepended to a serialized node
+	// attestation before it is signed, so that attestation signatures
+	// can't be confused with the signatures of arbitrary messages.
+	attestationSignedMsgPrefix = []byte("Lightning Node Attestation:")
+
+	// errAttestationNonceMismatch is returned when verifying an
+	// attestation that doesn't carry the expected nonce.
+	errAttestationNonceMismatch = errors.New("attestation nonce mismatch")
+)
+
+// attestationSignedMsg returns the message that is signed to attest a
+// serialized node attestation.
+func attestationSignedMsg(attestationBytes []byte) []byte {
+	msg := make(
+		[]byte, 0, len(attestationSignedMsgPrefix)+len(attestationBytes),
+	)
+	msg = append(msg, attestationSignedMsgPrefix...)
+	return append(msg, attestationBytes...)
+}
+
+// nodeAttestation is a summary of the state of a node, signed by its identity
+// key along with a nonce chosen by the verifier. It proves that the holder of
+// the identity key controls the node at the time of the attestation.
+type nodeAttestation struct {
+	// nonce is the nonce supplied by the verifier.
+	nonce []byte
+
+	// pubKey is the identity public key of the node.
+	pubKey route.Vertex
+
+	// alias is the alias of the node.
+	alias []byte
+
+	// version is the version of the software run by the node.
+	version []byte
+
+	// network is the network the node is running on.
+	network []byte
+
+	// blockHeight is the height of the best block known to the node.
+	blockHeight uint32
+
+	// blockHash is the hash of the best block known to the node.
+	blockHash chainhash.Hash
+
+	// syncedToChain is 1 if the node is synced to the chain, 0 otherwise.
+	syncedToChain uint8
+
+	// numActiveChannels is the number of active channels of the node.
+	numActiveChannels uint32
+
+	// numPeers is the number of peers of the node.
+	numPeers uint32
+
+	// timestamp is the time at which the attestation was made.
+	timestamp time.Time
+}
+
+// newNodeAttestation creates the attestation of the node described by info
+// for the given nonce.
+func newNodeAttestation(nonce []byte, info *lnrpc.GetInfoResponse,
+	now time.Time) (*nodeAttestation, error) {
+
+	if err := validateAttestationNonce(nonce); err != nil {
+		return nil, err
+	}
+
+	pubKey, err := route.NewVertexFromStr(info.IdentityPubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	blockHash, err := chainhash.NewHashFromStr(info.BlockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var network string
+	if len(info.Chains) > 0 {
+		network = info.Chains[0].Network
+	}
+
+	a := &nodeAttestation{
+		nonce:             nonce,
+		pubKey:            pubKey,
+		alias:             []byte(info.Alias),
+		version:           []byte(info.Version),
+		network:           []byte(network),
+		blockHeight:       info.BlockHeight,
+		blockHash:         *blockHash,
+		numActiveChannels: info.NumActiveChannels,
+		numPeers:          info.NumPeers,
+		timestamp:         now,
+	}
+	if info.SyncedToChain {
+		a.syncedToChain = 1
+	}
+
+	return a, nil
+}
+
+// validateAttestationNonce checks the length of the nonce of an attestation.
+func validateAttestationNonce(nonce []byte) error {
+	if len(nonce) < minAttestationNonceLen ||
+		len(nonce) > maxAttestationNonceLen {
+
+		return fmt.Errorf("attestation nonce must be between %d and "+
+			"%d bytes", minAttestationNonceLen,
+			maxAttestationNonceLen)
+	}
+
+	return nil
+}
+
+// records returns the tlv records of the attestation's fields.
+func (a *nodeAttestation) records(timestamp *uint64) []tlv.Record {
+	return []tlv.Record{
+		tlv.MakePrimitiveRecord(attestationNonceType, &a.nonce),
+		tlv.MakePrimitiveRecord(
+			attestationPubKeyType, (*[33]byte)(&a.pubKey),
+		),
+		tlv.MakePrimitiveRecord(attestationAliasType, &a.alias),
+		tlv.MakePrimitiveRecord(attestationVersionType, &a.version),
+		tlv.MakePrimitiveRecord(attestationNetworkType, &a.network),
+		tlv.MakePrimitiveRecord(
+			attestationBlockHeightType, &a.blockHeight,
+		),
+		tlv.MakePrimitiveRecord(
+			attestationBlockHashType, (*[32]byte)(&a.blockHash),
+		),
+		tlv.MakePrimitiveRecord(attestationSyncedType, &a.syncedToChain),
+		tlv.MakePrimitiveRecord(
+			attestationActiveChannelsType, &a.numActiveChannels,
+		),
+		tlv.MakePrimitiveRecord(attestationPeersType, &a.numPeers),
+		tlv.MakePrimitiveRecord(attestationTimestampType, timestamp),
+	}
+}
+
+// serialize encodes the attestation as a tlv stream. The serialized
+// attestation is the message that is signed by the node.
+func (a *nodeAttestation) serialize() ([]byte, error) {
+	timestamp := uint64(a.timestamp.UnixNano())
+
+	stream, err := tlv.NewStream(a.records(&timestamp)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := stream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// deserializeNodeAttestation decodes an attestation that was serialized as a
+// tlv stream.
+func deserializeNodeAttestation(b []byte) (*nodeAttestation, error) {
+	var (
+		a         nodeAttestation
+		timestamp uint64
+	)
+
+	records := a.records(&timestamp)
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if _, ok := parsedTypes[record.Type()]; !ok {
+			return nil, fmt.Errorf("attestation field %d missing",
+				record.Type())
+		}
+	}
+
+	a.timestamp = time.Unix(0, int64(timestamp))
+
+	return &a, nil
+}
+
+// validate checks that the attestation was made for the expected nonce, if
+// any, and by the node that signed it.
+func (a *nodeAttestation) validate(signer route.Vertex,
+	expectedNonce []byte) error {
+
+	if err := validateAttestationNonce(a.nonce); err != nil {
+		return err
+	}
+
+	if len(expectedNonce) > 0 && !bytes.Equal(a.nonce, expectedNonce) {
+		return errAttestationNonceMismatch
+	}
+
+	if signer != a.pubKey {
+		return errors.New("attestation not signed by attested node")
+	}
+
+	return nil
+}
+
+// marshallNodeAttestation converts the fields of an attestation into their rpc
+// representation.
+func marshallNodeAttestation(a *nodeAttestation) *lnrpc.NodeAttestation {
+	return &lnrpc.NodeAttestation{
+		Nonce:             a.nonce,
+		IdentityPubkey:    hex.EncodeToString(a.pubKey[:]),
+		Alias:             string(a.alias),
+		Version:           string(a.version),
+		Network:           string(a.network),
+		BlockHeight:       a.blockHeight,
+		BlockHash:         a.blockHash.String(),
+		SyncedToChain:     a.syncedToChain == 1,
+		NumActiveChannels: a.numActiveChannels,
+		NumPeers:          a.numPeers,
+		TimestampNs:       a.timestamp.UnixNano(),
+	}
+}
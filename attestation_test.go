@@ -0,0 +1,119 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// TestNodeAttestation tests the serialization, signing and validation of node
+// attestations.
+func TestNodeAttestation(t *testing.T) {
+	t.Parallel()
+
+	nodeKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	node := route.NewVertex(nodeKey.PubKey())
+
+	blockHash := chainhash.Hash{1, 2, 3}
+	info := &lnrpc.GetInfoResponse{
+		IdentityPubkey:    hex.EncodeToString(node[:]),
+		Alias:             "attested",
+		Version:           "0.3.0",
+		BlockHeight:       1000,
+		BlockHash:         blockHash.String(),
+		SyncedToChain:     true,
+		NumActiveChannels: 3,
+		NumPeers:          5,
+		Chains: []*lnrpc.Chain{{
+			Chain:   "decred",
+			Network: "mainnet",
+		}},
+	}
+
+	nonce := []byte("0123456789abcdef")
+	now := time.Unix(1600000000, 5)
+
+	// Nonces that are too short or too long are rejected.
+	if _, err := newNodeAttestation(nonce[:4], info, now); err == nil {
+		t.Fatalf("expected short nonce to be rejected")
+	}
+	longNonce := bytes.Repeat([]byte{1}, maxAttestationNonceLen+1)
+	if _, err := newNodeAttestation(longNonce, info, now); err == nil {
+		t.Fatalf("expected long nonce to be rejected")
+	}
+
+	attestation, err := newNodeAttestation(nonce, info, now)
+	if err != nil {
+		t.Fatalf("unable to create attestation: %v", err)
+	}
+
+	// The attestation should survive a serialization round trip.
+	attestationBytes, err := attestation.serialize()
+	if err != nil {
+		t.Fatalf("unable to serialize attestation: %v", err)
+	}
+	decoded, err := deserializeNodeAttestation(attestationBytes)
+	if err != nil {
+		t.Fatalf("unable to deserialize attestation: %v", err)
+	}
+
+	rpcAttestation := marshallNodeAttestation(decoded)
+	if !bytes.Equal(rpcAttestation.Nonce, nonce) ||
+		rpcAttestation.IdentityPubkey != info.IdentityPubkey ||
+		rpcAttestation.Alias != info.Alias ||
+		rpcAttestation.Version != info.Version ||
+		rpcAttestation.Network != "mainnet" ||
+		rpcAttestation.BlockHeight != info.BlockHeight ||
+		rpcAttestation.BlockHash != info.BlockHash ||
+		!rpcAttestation.SyncedToChain ||
+		rpcAttestation.NumActiveChannels != info.NumActiveChannels ||
+		rpcAttestation.NumPeers != info.NumPeers ||
+		rpcAttestation.TimestampNs != now.UnixNano() {
+
+		t.Fatalf("decoded attestation %v doesn't match %v",
+			rpcAttestation, info)
+	}
+
+	// An attestation without some of its fields can't be decoded.
+	_, err = deserializeNodeAttestation(attestationBytes[:20])
+	if err == nil {
+		t.Fatalf("expected truncated attestation to be rejected")
+	}
+
+	// The signer recovered from the signature of the attestation is the
+	// attested node.
+	msg := attestationSignedMsg(attestationBytes)
+	sig := ecdsa.SignCompact(nodeKey, chainhash.HashB(msg), true)
+	pubKey, _, err := ecdsa.RecoverCompact(sig, chainhash.HashB(msg))
+	if err != nil {
+		t.Fatalf("unable to recover signer: %v", err)
+	}
+	signer := route.NewVertex(pubKey)
+
+	if err := decoded.validate(signer, nonce); err != nil {
+		t.Fatalf("expected valid attestation: %v", err)
+	}
+	if err := decoded.validate(signer, nil); err != nil {
+		t.Fatalf("expected valid attestation without nonce: %v", err)
+	}
+
+	// Attestations made for another nonce or signed by another node are
+	// rejected.
+	err = decoded.validate(signer, []byte("fedcba9876543210"))
+	if err != errAttestationNonceMismatch {
+		t.Fatalf("expected nonce mismatch, got: %v", err)
+	}
+	if err := decoded.validate(route.Vertex{2}, nonce); err == nil {
+		t.Fatalf("expected attestation of another node to be rejected")
+	}
+}
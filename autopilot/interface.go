@@ -180,12 +180,18 @@ type ScoreSettable interface {
 }
 
 var (
+	// reliabilityAttachment is the reliability heuristic available to the
+	// autopilot agent. Its source of reliability metrics is set through
+	// SetReliabilitySource.
+	reliabilityAttachment = NewReliabilityAttachment()
+
 	// availableHeuristics holds all heuristics possible to combine for use
 	// with the autopilot agent.
 	availableHeuristics = []AttachmentHeuristic{
 		NewPrefAttachment(),
 		NewExternalScoreAttachment(),
 		NewTopCentrality(),
+		reliabilityAttachment,
 	}
 
 	// AvailableHeuristics is a map that holds the name of available
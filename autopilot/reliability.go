@@ -0,0 +1,173 @@
+package autopilot
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+)
+
+const (
+	// reliabilityPrior is the score given to a reliability metric for
+	// which there is no data.
+	reliabilityPrior = 0.5
+
+	// reliabilityPriorWeight is the number of virtual observations the
+	// prior accounts for when computing the forwarding success rate of a
+	// node. It prevents a handful of attempts from dominating the score.
+	reliabilityPriorWeight = 2
+
+	// flapPenaltyScale is the number of flaps that halve the reliability
+	// score of a node.
+	flapPenaltyScale = 10
+)
+
+// NodeReliability holds the metrics used to determine the reliability of a
+// node.
+type NodeReliability struct {
+	// Lifespan is the total time we have monitored channels with the
+	// node. It is zero if we never had a channel with the node.
+	Lifespan time.Duration
+
+	// Uptime is the total time the node was online while its channels
+	// were monitored.
+	Uptime time.Duration
+
+	// FlapCount is the number of times the node went offline while its
+	// channels were monitored.
+	FlapCount int
+
+	// ForwardSuccesses is the number of successful forwards observed
+	// through the node.
+	ForwardSuccesses int
+
+	// ForwardFailures is the number of failed forwards observed through
+	// the node.
+	ForwardFailures int
+}
+
+// ReliabilitySource returns the reliability metrics of the given nodes. Nodes
+// without any known metrics may be omitted from the returned map.
+type ReliabilitySource func(nodes map[NodeID]struct{}) (
+	map[NodeID]*NodeReliability, error)
+
+// ReliabilityAttachment is an implementation of the AttachmentHeuristic
+// interface that prefers nodes that have proven to be dependable, based on
+// their uptime and flap count while we had channels with them, and the
+// success rate of the forwards attempted through them.
+type ReliabilityAttachment struct {
+	source ReliabilitySource
+
+	sync.Mutex
+}
+
+// NewReliabilityAttachment creates a new instance of a ReliabilityAttachment.
+// Until a reliability source is set, all nodes are given the same score.
+func NewReliabilityAttachment() *ReliabilityAttachment {
+	return &ReliabilityAttachment{}
+}
+
+// A compile time assertion to ensure ReliabilityAttachment meets the
+// AttachmentHeuristic interface.
+var _ AttachmentHeuristic = (*ReliabilityAttachment)(nil)
+
+// Name returns the name of this heuristic.
+//
+// NOTE: This is a part of the AttachmentHeuristic interface.
+func (r *ReliabilityAttachment) Name() string {
+	return "reliability"
+}
+
+// SetReliabilitySource sets the source of the reliability metrics used to
+// score nodes.
+func (r *ReliabilityAttachment) SetReliabilitySource(source ReliabilitySource) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.source = source
+}
+
+// SetReliabilitySource sets the source of the reliability metrics of the
+// available reliability heuristic.
+func SetReliabilitySource(source ReliabilitySource) {
+	reliabilityAttachment.SetReliabilitySource(source)
+}
+
+// reliabilityScore combines the metrics of a node into a score in the range
+// [0, 1.0]. Metrics without data are given a neutral prior score.
+func reliabilityScore(r *NodeReliability) float64 {
+	uptimeScore := reliabilityPrior
+	if r.Lifespan > 0 {
+		uptimeScore = float64(r.Uptime) / float64(r.Lifespan)
+		if uptimeScore > 1 {
+			uptimeScore = 1
+		}
+	}
+
+	attempts := float64(r.ForwardSuccesses + r.ForwardFailures)
+	successScore := (float64(r.ForwardSuccesses) +
+		reliabilityPrior*reliabilityPriorWeight) /
+		(attempts + reliabilityPriorWeight)
+
+	flapFactor := 1 / (1 + float64(r.FlapCount)/flapPenaltyScale)
+
+	return (uptimeScore + successScore) / 2 * flapFactor
+}
+
+// NodeScores is a method that given the current channel graph and current set
+// of local channels, scores the given nodes according to the preference of
+// opening a channel of the given size with them. The returned channel
+// candidates maps the NodeID to a NodeScore for the node.
+//
+// The returned scores will be in the range [0, 1.0], where 0 indicates an
+// unreliable node, while 1.0 indicates a node that was always online and
+// successfully forwarded all attempts made through it.
+//
+// NOTE: This is a part of the AttachmentHeuristic interface.
+func (r *ReliabilityAttachment) NodeScores(g ChannelGraph, chans []Channel,
+	chanSize dcrutil.Amount, nodes map[NodeID]struct{}) (
+	map[NodeID]*NodeScore, error) {
+
+	existingPeers := make(map[NodeID]struct{})
+	for _, c := range chans {
+		existingPeers[c.Node] = struct{}{}
+	}
+
+	r.Lock()
+	source := r.source
+	r.Unlock()
+
+	metrics := make(map[NodeID]*NodeReliability)
+	if source != nil {
+		var err error
+		metrics, err = source(nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	candidates := make(map[NodeID]*NodeScore)
+	for nID := range nodes {
+		// If the node is among or existing channel peers, we don't
+		// need another channel.
+		if _, ok := existingPeers[nID]; ok {
+			continue
+		}
+
+		nodeMetrics, ok := metrics[nID]
+		if !ok {
+			nodeMetrics = &NodeReliability{}
+		}
+
+		score := reliabilityScore(nodeMetrics)
+		log.Tracef("Reliability score %v given to node %x", score,
+			nID[:])
+
+		candidates[nID] = &NodeScore{
+			NodeID: nID,
+			Score:  score,
+		}
+	}
+
+	return candidates, nil
+}
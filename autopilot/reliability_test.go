@@ -0,0 +1,103 @@
+package autopilot_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/autopilot"
+)
+
+// TestReliabilityAttachment tests that the ReliabilityAttachment prefers nodes
+// with a higher uptime, fewer flaps and a higher forwarding success rate, and
+// that it skips the nodes we already have channels with.
+func TestReliabilityAttachment(t *testing.T) {
+	t.Parallel()
+
+	h := autopilot.NewReliabilityAttachment()
+
+	const numKeys = 5
+	var nodes []autopilot.NodeID
+	query := make(map[autopilot.NodeID]struct{})
+	for i := 0; i < numKeys; i++ {
+		k, err := randKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		nID := autopilot.NewNodeID(k)
+		nodes = append(nodes, nID)
+		query[nID] = struct{}{}
+	}
+
+	// Without a source, all nodes are given the same neutral score.
+	scores, err := h.NodeScores(nil, nil, 0, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, nID := range nodes {
+		if scores[nID].Score != 0.5 {
+			t.Fatalf("expected neutral score, got %v",
+				scores[nID].Score)
+		}
+	}
+
+	// The first node was always online and forwarded successfully, the
+	// second one was only online half of the time, the third one flapped
+	// frequently and the fourth one failed most forwards. The last node
+	// is an existing peer.
+	metrics := map[autopilot.NodeID]*autopilot.NodeReliability{
+		nodes[0]: {
+			Lifespan:         time.Hour,
+			Uptime:           time.Hour,
+			ForwardSuccesses: 10,
+		},
+		nodes[1]: {
+			Lifespan:         time.Hour,
+			Uptime:           time.Hour / 2,
+			ForwardSuccesses: 10,
+		},
+		nodes[2]: {
+			Lifespan:         time.Hour,
+			Uptime:           time.Hour / 2,
+			FlapCount:        10,
+			ForwardSuccesses: 10,
+		},
+		nodes[3]: {
+			Lifespan:         time.Hour,
+			Uptime:           time.Hour / 2,
+			FlapCount:        10,
+			ForwardSuccesses: 1,
+			ForwardFailures:  9,
+		},
+	}
+	h.SetReliabilitySource(func(map[autopilot.NodeID]struct{}) (
+		map[autopilot.NodeID]*autopilot.NodeReliability, error) {
+
+		return metrics, nil
+	})
+
+	chans := []autopilot.Channel{{Node: nodes[4]}}
+	scores, err = h.NodeScores(nil, chans, 0, query)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := scores[nodes[4]]; ok {
+		t.Fatalf("existing peer should not be scored")
+	}
+
+	for i := 0; i < 3; i++ {
+		score, nextScore := scores[nodes[i]].Score, scores[nodes[i+1]].Score
+		if score <= nextScore {
+			t.Fatalf("expected node %d to score higher than node "+
+				"%d: %v <= %v", i, i+1, score, nextScore)
+		}
+	}
+
+	for nID, score := range scores {
+		if score.Score < 0 || score.Score > 1 {
+			t.Fatalf("score %v of node %x out of range",
+				score.Score, nID[:])
+		}
+	}
+}
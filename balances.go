@@ -0,0 +1,73 @@
+package dcrlnd
+
+import (
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+)
+
+// walletLimboBalance returns the part of the limbo balance of the channels
+// waiting to be closed that the wallet already accounts for. Once the
+// cooperative closing transaction of a channel was broadcast, its outputs that
+// pay to the wallet are part of the unconfirmed wallet balance, so the local
+// balance of such channels must not be counted as limbo as well.
+func walletLimboBalance(waitingClose []*channeldb.OpenChannel,
+	coopCloseTx func(*channeldb.OpenChannel) (*wire.MsgTx, error),
+	isOurScript func(version uint16, pkScript []byte) bool) (dcrutil.Amount,
+	error) {
+
+	var total dcrutil.Amount
+	for _, channel := range waitingClose {
+		closeTx, err := coopCloseTx(channel)
+		switch {
+		// Channels without a cooperative closing transaction are being
+		// force closed, so their funds are still in limbo.
+		case err == channeldb.ErrNoCloseTx:
+			continue
+
+		case err != nil:
+			return 0, err
+		}
+
+		for _, txOut := range closeTx.TxOut {
+			if isOurScript(txOut.Version, txOut.PkScript) {
+				total += channel.LocalCommitment.LocalBalance.ToAtoms()
+				break
+			}
+		}
+	}
+
+	return total, nil
+}
+
+// aggregateBalances builds the response of the Balances call out of the
+// balances of the wallet and channels. walletLimbo is the part of the limbo
+// balance that is already part of the wallet balance, which is removed from
+// the limbo balance so that no funds are counted twice in the total.
+func aggregateBalances(walletBals *lnwallet.WalletBalances,
+	chanBals *lnrpc.ChannelBalanceResponse, limbo int64,
+	walletLimbo dcrutil.Amount) *lnrpc.BalancesResponse {
+
+	limbo -= int64(walletLimbo)
+	if limbo < 0 {
+		limbo = 0
+	}
+
+	resp := &lnrpc.BalancesResponse{
+		ConfirmedBalance:        int64(walletBals.Confirmed),
+		UnconfirmedBalance:      int64(walletBals.Unconfirmed),
+		MaturingBalance:         int64(walletBals.Maturing),
+		ChannelLocalBalance:     chanBals.LocalBalance,
+		UnsettledLocalBalance:   chanBals.UnsettledLocalBalance,
+		PendingOpenLocalBalance: chanBals.PendingOpenLocalBalance,
+		LimboBalance:            limbo,
+	}
+	resp.TotalBalance = resp.ConfirmedBalance + resp.UnconfirmedBalance +
+		int64(resp.ChannelLocalBalance.Atoms) +
+		int64(resp.UnsettledLocalBalance.Atoms) +
+		int64(resp.PendingOpenLocalBalance.Atoms) + resp.LimboBalance
+
+	return resp
+}
@@ -0,0 +1,137 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestWalletLimboBalance asserts that only the local balance of channels whose
+// cooperative closing transaction pays to the wallet is reported as already
+// part of the wallet balance.
+func TestWalletLimboBalance(t *testing.T) {
+	t.Parallel()
+
+	ourScript := []byte{1}
+	theirScript := []byte{2}
+
+	newChannel := func(local dcrutil.Amount) *channeldb.OpenChannel {
+		return &channeldb.OpenChannel{
+			LocalCommitment: channeldb.ChannelCommitment{
+				LocalBalance: lnwire.NewMAtomsFromAtoms(local),
+			},
+		}
+	}
+
+	// The first channel is coop closed to the wallet, the second to an
+	// external address and the third is being force closed.
+	toWallet := newChannel(1000)
+	toExternal := newChannel(2000)
+	forceClosed := newChannel(4000)
+	closeTxs := map[*channeldb.OpenChannel]*wire.MsgTx{
+		toWallet: {
+			TxOut: []*wire.TxOut{
+				{Value: 500, PkScript: theirScript},
+				{Value: 990, PkScript: ourScript},
+			},
+		},
+		toExternal: {
+			TxOut: []*wire.TxOut{
+				{Value: 1990, PkScript: theirScript},
+			},
+		},
+	}
+
+	coopCloseTx := func(c *channeldb.OpenChannel) (*wire.MsgTx, error) {
+		tx, ok := closeTxs[c]
+		if !ok {
+			return nil, channeldb.ErrNoCloseTx
+		}
+		return tx, nil
+	}
+	isOurScript := func(_ uint16, pkScript []byte) bool {
+		return bytes.Equal(pkScript, ourScript)
+	}
+
+	walletLimbo, err := walletLimboBalance(
+		[]*channeldb.OpenChannel{toWallet, toExternal, forceClosed},
+		coopCloseTx, isOurScript,
+	)
+	if err != nil {
+		t.Fatalf("unable to compute wallet limbo balance: %v", err)
+	}
+	if walletLimbo != 1000 {
+		t.Fatalf("expected wallet limbo balance 1000, got %v",
+			walletLimbo)
+	}
+}
+
+// TestAggregateBalances asserts that the total balance sums all balances,
+// counting the limbo balance that is already part of the wallet balance once.
+func TestAggregateBalances(t *testing.T) {
+	t.Parallel()
+
+	walletBals := &lnwallet.WalletBalances{
+		Confirmed:   100000,
+		Unconfirmed: 990,
+		Maturing:    5000,
+	}
+	chanBals := &lnrpc.ChannelBalanceResponse{
+		LocalBalance:            marshalAmount(20000000),
+		UnsettledLocalBalance:   marshalAmount(3000000),
+		PendingOpenLocalBalance: marshalAmount(4000000),
+	}
+
+	tests := []struct {
+		name          string
+		limbo         int64
+		walletLimbo   dcrutil.Amount
+		expectedLimbo int64
+		expectedTotal int64
+	}{
+		{
+			name:          "no limbo",
+			expectedTotal: 100000 + 990 + 20000 + 3000 + 4000,
+		},
+		{
+			name:          "force close",
+			limbo:         7000,
+			expectedLimbo: 7000,
+			expectedTotal: 100000 + 990 + 20000 + 3000 + 4000 + 7000,
+		},
+		{
+			name:          "coop close to wallet",
+			limbo:         8000,
+			walletLimbo:   1000,
+			expectedLimbo: 7000,
+			expectedTotal: 100000 + 990 + 20000 + 3000 + 4000 + 7000,
+		},
+	}
+
+	for _, test := range tests {
+		resp := aggregateBalances(
+			walletBals, chanBals, test.limbo, test.walletLimbo,
+		)
+
+		if resp.LimboBalance != test.expectedLimbo {
+			t.Fatalf("%v: expected limbo balance %v, got %v",
+				test.name, test.expectedLimbo,
+				resp.LimboBalance)
+		}
+		if resp.TotalBalance != test.expectedTotal {
+			t.Fatalf("%v: expected total balance %v, got %v",
+				test.name, test.expectedTotal,
+				resp.TotalBalance)
+		}
+		if resp.MaturingBalance != 5000 {
+			t.Fatalf("%v: expected maturing balance 5000, got %v",
+				test.name, resp.MaturingBalance)
+		}
+	}
+}
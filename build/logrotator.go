@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sort"
+	"sync"
 
 	"github.com/decred/slog"
 	"github.com/jrick/logrotate/rotator"
@@ -20,6 +21,8 @@ type RotatingLogWriter struct {
 
 	logWriter *LogWriter
 
+	tee *teeWriter
+
 	backendLog *slog.Backend
 
 	logRotator *rotator.Rotator
@@ -37,13 +40,15 @@ var _ LeveledSubLogger = (*RotatingLogWriter)(nil)
 // the writer.
 func NewRotatingLogWriter() *RotatingLogWriter {
 	logWriter := &LogWriter{}
-	backendLog := slog.NewBackend(logWriter)
+	tee := &teeWriter{w: logWriter}
+	backendLog := slog.NewBackend(tee)
 	return &RotatingLogWriter{
 		GenSubLogger: func(tag string) slog.Logger {
 			logger := backendLog.Logger(tag)
 			return NewShutdownLogger(logger)
 		},
 		logWriter:        logWriter,
+		tee:              tee,
 		backendLog:       backendLog,
 		subsystemLoggers: SubLoggers{},
 	}
@@ -99,6 +104,14 @@ func (r *RotatingLogWriter) Close() error {
 	return nil
 }
 
+// SetLogTee sets an additional writer all log lines are written to, replacing
+// the previous one. A nil writer removes it.
+func (r *RotatingLogWriter) SetLogTee(w io.Writer) {
+	r.tee.mu.Lock()
+	r.tee.extra = w
+	r.tee.mu.Unlock()
+}
+
 // SubLoggers returns all currently registered subsystem loggers for this log
 // writer.
 //
@@ -152,3 +165,24 @@ func (r *RotatingLogWriter) SetLogLevels(logLevel string) {
 		r.SetLogLevel(subsystemID, logLevel)
 	}
 }
+
+// teeWriter writes to the wrapped writer and to an optional extra writer.
+type teeWriter struct {
+	w io.Writer
+
+	mu    sync.Mutex
+	extra io.Writer
+}
+
+// Write writes the byte slice to the wrapped writer and the extra writer, if
+// set.
+func (t *teeWriter) Write(b []byte) (int, error) {
+	t.mu.Lock()
+	extra := t.extra
+	t.mu.Unlock()
+
+	if extra != nil {
+		extra.Write(b)
+	}
+	return t.w.Write(b)
+}
@@ -3,9 +3,18 @@ package build
 import "github.com/decred/slog"
 
 // PrefixLog is a pass-through logger that adds a prefix to every logged line.
+// A tagged PrefixLog also writes the lines allowed by a log level override of
+// one of its tags, see SetTagLogLevel.
 type PrefixLog struct {
 	log    slog.Logger
 	prefix string
+
+	// subsystem is the subsystem of the wrapped logger, used to write the
+	// lines allowed by a tag override.
+	subsystem string
+
+	// tags is the set of tags of the logger.
+	tags []string
 }
 
 // NewPrefixLog instantiates a new prefixed logger.
@@ -16,86 +25,121 @@ func NewPrefixLog(prefix string, log slog.Logger) *PrefixLog {
 	}
 }
 
+// NewTaggedPrefixLog instantiates a new prefixed logger of the given subsystem
+// whose level can be overridden through any of the given tags. An empty prefix
+// leaves the logged lines unchanged.
+func NewTaggedPrefixLog(prefix string, log slog.Logger, subsystem string,
+	tags ...string) *PrefixLog {
+
+	return &PrefixLog{
+		prefix:    prefix,
+		log:       log,
+		subsystem: subsystem,
+		tags:      tags,
+	}
+}
+
 // addFormatPrefix prepends the prefix to a format string.
 func (p *PrefixLog) addFormatPrefix(s string) string {
+	if p.prefix == "" {
+		return s
+	}
 	return p.prefix + " " + s
 }
 
 // addArgsPrefix prepends the prefix to a list of arguments.
 func (p *PrefixLog) addArgsPrefix(args []interface{}) []interface{} {
+	if p.prefix == "" {
+		return args
+	}
 	return append([]interface{}{p.prefix}, args...)
 }
 
+// logger returns the logger to write a line of the given level to. This is
+// the wrapped logger, unless it would drop the line while a tag override
+// allows it.
+func (p *PrefixLog) logger(level slog.Level) slog.Logger {
+	if len(p.tags) == 0 || level >= p.log.Level() {
+		return p.log
+	}
+
+	if logger := tagOverrideLogger(p.subsystem, p.tags, level); logger != nil {
+		return logger
+	}
+
+	return p.log
+}
+
 // Tracef formats message according to format specifier and writes to to log
 // with LevelTrace.
 func (p *PrefixLog) Tracef(format string, params ...interface{}) {
-	p.log.Tracef(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelTrace).Tracef(p.addFormatPrefix(format), params...)
 }
 
 // Debugf formats message according to format specifier and writes to log with
 // LevelDebug.
 func (p *PrefixLog) Debugf(format string, params ...interface{}) {
-	p.log.Debugf(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelDebug).Debugf(p.addFormatPrefix(format), params...)
 }
 
 // Infof formats message according to format specifier and writes to log with
 // LevelInfo.
 func (p *PrefixLog) Infof(format string, params ...interface{}) {
-	p.log.Infof(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelInfo).Infof(p.addFormatPrefix(format), params...)
 }
 
 // Warnf formats message according to format specifier and writes to to log with
 // LevelWarn.
 func (p *PrefixLog) Warnf(format string, params ...interface{}) {
-	p.log.Warnf(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelWarn).Warnf(p.addFormatPrefix(format), params...)
 }
 
 // Errorf formats message according to format specifier and writes to to log
 // with LevelError.
 func (p *PrefixLog) Errorf(format string, params ...interface{}) {
-	p.log.Errorf(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelError).Errorf(p.addFormatPrefix(format), params...)
 }
 
 // Criticalf formats message according to format specifier and writes to log
 // with LevelCritical.
 func (p *PrefixLog) Criticalf(format string, params ...interface{}) {
-	p.log.Criticalf(p.addFormatPrefix(format), params...)
+	p.logger(slog.LevelCritical).Criticalf(p.addFormatPrefix(format), params...)
 }
 
 // Trace formats message using the default formats for its operands and writes
 // to log with LevelTrace.
 func (p *PrefixLog) Trace(v ...interface{}) {
-	p.log.Trace(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelTrace).Trace(p.addArgsPrefix(v)...)
 }
 
 // Debug formats message using the default formats for its operands and writes
 // to log with LevelDebug.
 func (p *PrefixLog) Debug(v ...interface{}) {
-	p.log.Debug(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelDebug).Debug(p.addArgsPrefix(v)...)
 }
 
 // Info formats message using the default formats for its operands and writes to
 // log with LevelInfo.
 func (p *PrefixLog) Info(v ...interface{}) {
-	p.log.Info(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelInfo).Info(p.addArgsPrefix(v)...)
 }
 
 // Warn formats message using the default formats for its operands and writes to
 // log with LevelWarn.
 func (p *PrefixLog) Warn(v ...interface{}) {
-	p.log.Warn(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelWarn).Warn(p.addArgsPrefix(v)...)
 }
 
 // Error formats message using the default formats for its operands and writes
 // to log with LevelError.
 func (p *PrefixLog) Error(v ...interface{}) {
-	p.log.Error(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelError).Error(p.addArgsPrefix(v)...)
 }
 
 // Critical formats message using the default formats for its operands and
 // writes to log with LevelCritical.
 func (p *PrefixLog) Critical(v ...interface{}) {
-	p.log.Critical(p.addArgsPrefix(v)...)
+	p.logger(slog.LevelCritical).Critical(p.addArgsPrefix(v)...)
 }
 
 // Level returns the current logging level.
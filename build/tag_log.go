@@ -0,0 +1,129 @@
+package build
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/decred/slog"
+)
+
+// tagLogLevels holds the log level overrides of tagged loggers. A tagged
+// logger writes the lines allowed by the override of one of its tags even if
+// the level of its subsystem logger is higher, which allows raising the
+// verbosity for a single channel or peer only.
+var tagLogLevels = struct {
+	sync.RWMutex
+
+	// levels maps a tag to the level of its override.
+	levels map[string]slog.Level
+
+	// genLogger generates the loggers the lines allowed by an override
+	// are written to.
+	genLogger func(string) slog.Logger
+
+	// loggers holds the generated loggers by subsystem.
+	loggers map[string]slog.Logger
+}{
+	levels:  make(map[string]slog.Level),
+	loggers: make(map[string]slog.Logger),
+}
+
+// SetTagLogGenerator sets the function used to create the subsystem loggers
+// that the lines allowed by a tag override are written to. No overrides are
+// applied until a generator is set.
+func SetTagLogGenerator(genLogger func(string) slog.Logger) {
+	tagLogLevels.Lock()
+	defer tagLogLevels.Unlock()
+
+	tagLogLevels.genLogger = genLogger
+	tagLogLevels.loggers = make(map[string]slog.Logger)
+}
+
+// SetTagLogLevel overrides the log level of all loggers with the given tag.
+func SetTagLogLevel(tag string, level slog.Level) {
+	tagLogLevels.Lock()
+	defer tagLogLevels.Unlock()
+
+	tagLogLevels.levels[tag] = level
+}
+
+// ClearTagLogLevel removes the log level override of the given tag.
+func ClearTagLogLevel(tag string) {
+	tagLogLevels.Lock()
+	defer tagLogLevels.Unlock()
+
+	delete(tagLogLevels.levels, tag)
+}
+
+// TagLogLevels returns the currently active tag overrides.
+func TagLogLevels() map[string]slog.Level {
+	tagLogLevels.RLock()
+	defer tagLogLevels.RUnlock()
+
+	levels := make(map[string]slog.Level, len(tagLogLevels.levels))
+	for tag, level := range tagLogLevels.levels {
+		levels[tag] = level
+	}
+
+	return levels
+}
+
+// ChannelLogTag returns the log tag of the channel with the given channel
+// point.
+func ChannelLogTag(chanPoint fmt.Stringer) string {
+	return "chan:" + chanPoint.String()
+}
+
+// PeerLogTag returns the log tag of the peer with the given serialized
+// identity key.
+func PeerLogTag(pubKey []byte) string {
+	return fmt.Sprintf("peer:%x", pubKey)
+}
+
+// tagOverrideLogger returns a logger of the given subsystem to write a line of
+// the given level to, if one of the tags has an override that allows it, or
+// nil otherwise.
+func tagOverrideLogger(subsystem string, tags []string,
+	level slog.Level) slog.Logger {
+
+	tagLogLevels.RLock()
+	if len(tagLogLevels.levels) == 0 || tagLogLevels.genLogger == nil {
+		tagLogLevels.RUnlock()
+		return nil
+	}
+
+	var allowed bool
+	for _, tag := range tags {
+		override, ok := tagLogLevels.levels[tag]
+		if ok && level >= override {
+			allowed = true
+			break
+		}
+	}
+	logger := tagLogLevels.loggers[subsystem]
+	tagLogLevels.RUnlock()
+
+	if !allowed {
+		return nil
+	}
+	if logger != nil {
+		return logger
+	}
+
+	// This is the first override line of the subsystem, so we'll create
+	// its logger, which writes all lines it is passed.
+	tagLogLevels.Lock()
+	defer tagLogLevels.Unlock()
+
+	if logger, ok := tagLogLevels.loggers[subsystem]; ok {
+		return logger
+	}
+	if tagLogLevels.genLogger == nil {
+		return nil
+	}
+	logger = tagLogLevels.genLogger(subsystem)
+	logger.SetLevel(slog.LevelTrace)
+	tagLogLevels.loggers[subsystem] = logger
+
+	return logger
+}
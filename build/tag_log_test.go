@@ -0,0 +1,54 @@
+package build
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/decred/slog"
+)
+
+// TestTaggedPrefixLog tests that a tag override lets the lines of the tagged
+// loggers through, without affecting other loggers of the subsystem.
+func TestTaggedPrefixLog(t *testing.T) {
+	var b bytes.Buffer
+	backend := slog.NewBackend(&b)
+
+	SetTagLogGenerator(backend.Logger)
+	defer SetTagLogGenerator(nil)
+
+	subLogger := backend.Logger("TEST")
+	subLogger.SetLevel(slog.LevelInfo)
+
+	chanLog := NewTaggedPrefixLog("chan1:", subLogger, "TEST", "chan:1")
+	otherLog := NewTaggedPrefixLog("chan2:", subLogger, "TEST", "chan:2")
+
+	assertLogged := func(expected bool) {
+		t.Helper()
+
+		b.Reset()
+		chanLog.Debugf("debug line")
+		otherLog.Debugf("debug line")
+
+		logged := b.String()
+		if strings.Contains(logged, "chan1: debug line") != expected {
+			t.Fatalf("expected tagged line logged=%v, got: %q",
+				expected, logged)
+		}
+		if strings.Contains(logged, "chan2:") {
+			t.Fatalf("unexpected line of other logger: %q", logged)
+		}
+	}
+
+	// Without an override, the debug lines are dropped.
+	assertLogged(false)
+
+	// An override of the tag lets the lines of its logger through.
+	SetTagLogLevel("chan:1", slog.LevelDebug)
+	defer ClearTagLogLevel("chan:1")
+	assertLogged(true)
+
+	// Once cleared, the lines are dropped again.
+	ClearTagLogLevel("chan:1")
+	assertLogged(false)
+}
@@ -0,0 +1,157 @@
+package dcrlnd
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/reorgnotifier"
+)
+
+// marshalReorgAffectedTx converts a transaction affected by a reorg into its
+// RPC representation.
+func marshalReorgAffectedTx(
+	tx reorgnotifier.AffectedTx) *lnrpc.ReorgAffectedTransaction {
+
+	var txType lnrpc.TransactionType
+	switch tx.Kind {
+	case reorgnotifier.TxKindFunding:
+		txType = lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING
+
+	case reorgnotifier.TxKindCooperativeClose:
+		txType = lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE
+
+	case reorgnotifier.TxKindForceClose:
+		txType = lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE
+
+	case reorgnotifier.TxKindSweep:
+		txType = lnrpc.TransactionType_TX_TYPE_SWEEP
+	}
+
+	rpcTx := &lnrpc.ReorgAffectedTransaction{
+		Txid:   tx.Txid.String(),
+		TxType: txType,
+		Height: tx.Height,
+	}
+	if tx.ChanPoint != nil {
+		rpcTx.ChannelPoint = tx.ChanPoint.String()
+	}
+
+	return rpcTx
+}
+
+// marshalDeposit converts a deposit to one of our channel scripts into its RPC
+// representation.
+func marshalDeposit(
+	deposit reorgnotifier.DepositEvent) *lnrpc.ChannelAddressDeposit {
+
+	rpcDeposit := &lnrpc.ChannelAddressDeposit{
+		TxHash:       deposit.Txid.String(),
+		OutputIndex:  deposit.Index,
+		Amount:       int64(deposit.Amount),
+		ChannelPoint: deposit.ChanPoint.String(),
+		Funding:      deposit.Kind == reorgnotifier.DepositFunding,
+		BlockHeight:  deposit.Height,
+		BlockHash:    deposit.Block.String(),
+	}
+
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		0, deposit.PkScript, activeNetParams.Params, false,
+	)
+	if err == nil && len(addrs) == 1 {
+		rpcDeposit.Address = addrs[0].Address()
+	}
+
+	return rpcDeposit
+}
+
+// SubscribeChainEvents returns a uni-directional stream (server -> client)
+// over which chain reorgs, the reconciliation of the transactions they
+// affected and third party deposits to our channel scripts are reported.
+func (r *rpcServer) SubscribeChainEvents(req *lnrpc.ChainEventSubscription,
+	eventStream lnrpc.Lightning_SubscribeChainEventsServer) error {
+
+	chainEventSub, err := r.server.reorgNotifier.SubscribeChainEvents()
+	if err != nil {
+		return err
+	}
+	defer chainEventSub.Cancel()
+
+	for {
+		select {
+		// A new update has been sent by the reorg notifier, we'll
+		// marshal it into the form expected by the gRPC client, then
+		// send it off to the client.
+		case e := <-chainEventSub.Updates():
+			var event *lnrpc.ChainEvent
+
+			switch chainEvent := e.(type) {
+			case reorgnotifier.ReorgEvent:
+				reorg := &lnrpc.ChainReorgEvent{
+					Depth:        chainEvent.Depth,
+					ForkHeight:   chainEvent.ForkHeight,
+					OldTipHash:   chainEvent.OldTip.String(),
+					NewTipHash:   chainEvent.NewTip.String(),
+					NewTipHeight: chainEvent.NewHeight,
+				}
+				for _, tx := range chainEvent.AffectedTxs {
+					reorg.AffectedTxs = append(
+						reorg.AffectedTxs,
+						marshalReorgAffectedTx(tx),
+					)
+				}
+
+				event = &lnrpc.ChainEvent{
+					Event: &lnrpc.ChainEvent_Reorg{
+						Reorg: reorg,
+					},
+				}
+
+			case reorgnotifier.ReconciledEvent:
+				event = &lnrpc.ChainEvent{
+					Event: &lnrpc.ChainEvent_Reconciled{
+						Reconciled: &lnrpc.TxReconciledEvent{
+							Tx: marshalReorgAffectedTx(
+								chainEvent.AffectedTx,
+							),
+							NewHeight:    chainEvent.NewHeight,
+							NewBlockHash: chainEvent.NewBlock.String(),
+						},
+					},
+				}
+
+			case reorgnotifier.ShortChanIDChangedEvent:
+				oldID := chainEvent.OldShortChanID
+				newID := chainEvent.NewShortChanID
+				changed := &lnrpc.ShortChannelIdChangedEvent{
+					ChannelPoint: chainEvent.ChanPoint.String(),
+					OldChanId:    oldID.ToUint64(),
+					NewChanId:    newID.ToUint64(),
+				}
+
+				event = &lnrpc.ChainEvent{
+					Event: &lnrpc.ChainEvent_ShortChanIdChanged{
+						ShortChanIdChanged: changed,
+					},
+				}
+
+			case reorgnotifier.DepositEvent:
+				event = &lnrpc.ChainEvent{
+					Event: &lnrpc.ChainEvent_Deposit{
+						Deposit: marshalDeposit(chainEvent),
+					},
+				}
+
+			default:
+				return fmt.Errorf("unexpected chain event: %v", e)
+			}
+
+			if err := eventStream.Send(event); err != nil {
+				return err
+			}
+
+		case <-r.quit:
+			return nil
+		}
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"github.com/decred/dcrlnd/lnwallet/remotedcrwallet"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/routing/chainview"
+	"github.com/decred/dcrlnd/sweep"
 	"google.golang.org/grpc"
 )
 
@@ -160,7 +161,7 @@ type chainControl struct {
 // one chainControl instance exists: one backed by a running dcrd full-node.
 func newChainControlFromConfig(cfg *Config, localDB, remoteDB *channeldb.DB,
 	privateWalletPw, publicWalletPw []byte, birthday time.Time,
-	recoveryWindow uint32, wallet *wallet.Wallet,
+	recoveryWindow uint32, rescanFromHeight int32, wallet *wallet.Wallet,
 	loader *walletloader.Loader,
 	conn *grpc.ClientConn, accountNumber int32) (*chainControl, error) {
 
@@ -343,17 +344,19 @@ func newChainControlFromConfig(cfg *Config, localDB, remoteDB *channeldb.DB,
 		}
 
 		dcrwConfig := &dcrwallet.Config{
-			Syncer:         syncer,
-			ChainIO:        cc.chainIO,
-			PrivatePass:    privateWalletPw,
-			PublicPass:     publicWalletPw,
-			Birthday:       birthday,
-			RecoveryWindow: recoveryWindow,
-			DataDir:        cfg.ChainDir,
-			NetParams:      activeNetParams.Params,
-			Wallet:         wallet,
-			Loader:         loader,
-			DB:             remoteDB,
+			Syncer:           syncer,
+			ChainIO:          cc.chainIO,
+			PrivatePass:      privateWalletPw,
+			PublicPass:       publicWalletPw,
+			Birthday:         birthday,
+			RecoveryWindow:   recoveryWindow,
+			RescanFromHeight: rescanFromHeight,
+			DataDir:          cfg.ChainDir,
+			NetParams:        activeNetParams.Params,
+			Wallet:           wallet,
+			Loader:           loader,
+			HdSeed:           cfg.fixtures.hdSeed(),
+			DB:               remoteDB,
 		}
 
 		wc, err := dcrwallet.New(*dcrwConfig)
@@ -443,6 +446,16 @@ func newChainControlFromConfig(cfg *Config, localDB, remoteDB *channeldb.DB,
 	// Select the default channel constraints for the primary chain.
 	channelConstraints := defaultDcrChannelConstraints
 
+	// The sweeper store is used to recognize the outputs of our sweep
+	// transactions, which are held back from coin selection until they
+	// reach the configured number of confirmations.
+	sweeperStore, err := sweep.NewSweeperStore(
+		remoteDB, &activeNetParams.GenesisHash,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	// Create, and start the lnwallet, which handles the core payment
 	// channel logic, and exposes control via proxy state machines.
 	walletCfg := lnwallet.Config{
@@ -455,6 +468,9 @@ func newChainControlFromConfig(cfg *Config, localDB, remoteDB *channeldb.DB,
 		ChainIO:            cc.chainIO,
 		DefaultConstraints: channelConstraints,
 		NetParams:          *activeNetParams.Params,
+		CloseTxMinConfs:    int32(cfg.SweptFunds.CloseConfs),
+		SweepTxMinConfs:    int32(cfg.SweptFunds.SweepConfs),
+		IsSweepTx:          sweeperStore.IsOurTx,
 	}
 	lnWallet, err := lnwallet.NewLightningWallet(walletCfg)
 	if err != nil {
@@ -468,6 +484,15 @@ func newChainControlFromConfig(cfg *Config, localDB, remoteDB *channeldb.DB,
 
 	ltndLog.Info("LightningWallet opened")
 
+	// Ensure the chain used by the wallet and the chain backend is the one
+	// identified by the chain hash of our channels.
+	if err := checkGenesisHash(cc.chainIO); err != nil {
+		if err := lnWallet.Shutdown(); err != nil {
+			ltndLog.Errorf("Unable to shut down wallet: %v", err)
+		}
+		return nil, err
+	}
+
 	cc.wallet = lnWallet
 
 	return cc, nil
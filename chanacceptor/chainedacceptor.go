@@ -43,21 +43,46 @@ func (c *ChainedAcceptor) RemoveAcceptor(id uint64) {
 }
 
 // Accept evaluates the results of all ChannelAcceptors in the acceptors map
-// and returns the conjunction of all these predicates.
+// and returns the conjunction of all these predicates. The constraints set by
+// the accepting acceptors are merged, and the channel is rejected if they
+// conflict.
 //
 // NOTE: Part of the ChannelAcceptor interface.
-func (c *ChainedAcceptor) Accept(req *ChannelAcceptRequest) bool {
-	result := true
-
+func (c *ChainedAcceptor) Accept(req *ChannelAcceptRequest) *ChannelAcceptResponse {
 	c.acceptorsMtx.RLock()
+	defer c.acceptorsMtx.RUnlock()
+
+	var finalResp ChannelAcceptResponse
+
 	for _, acceptor := range c.acceptors {
-		// We call Accept first in case any acceptor (perhaps an RPCAcceptor)
-		// wishes to be notified about ChannelAcceptRequest.
-		result = acceptor.Accept(req) && result
+		// Call our acceptor to determine whether we want to accept this
+		// channel.
+		acceptorResponse := acceptor.Accept(req)
+
+		// If we should reject the channel, we can just exit early. This
+		// has the effect of returning the error belonging to our first
+		// failed acceptor.
+		if acceptorResponse.RejectChannel() {
+			return acceptorResponse
+		}
+
+		// If we have accepted the channel, we need to merge the
+		// constraints set by this acceptor into our final response.
+		var err error
+		finalResp, err = mergeResponse(finalResp, *acceptorResponse)
+		if err != nil {
+			log.Errorf("response for: %x could not be merged: %v",
+				req.OpenChanMsg.PendingChannelID, err)
+
+			return NewChannelAcceptResponse(
+				false, errChannelRejected, 0, 0, 0, 0,
+			)
+		}
 	}
-	c.acceptorsMtx.RUnlock()
 
-	return result
+	// If we have no acceptors configured or all of them accepted the
+	// channel, we return the merged response.
+	return &finalResp
 }
 
 // A compile-time constraint to ensure ChainedAcceptor implements the
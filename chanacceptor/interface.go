@@ -1,10 +1,20 @@
 package chanacceptor
 
 import (
+	"errors"
+
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/lnwire"
 )
 
+var (
+	// errChannelRejected is returned when the rpc channel acceptor rejects
+	// a channel due to acceptor timeout, shutdown, or because no custom
+	// error value is available when the channel was rejected.
+	errChannelRejected = errors.New("channel rejected")
+)
+
 // ChannelAcceptRequest is a struct containing the requesting node's public key
 // along with the lnwire.OpenChannel message that they sent when requesting an
 // inbound channel. This information is provided to each acceptor so that they
@@ -16,10 +26,84 @@ type ChannelAcceptRequest struct {
 	// OpenChanMsg is the actual OpenChannel protocol message that the peer
 	// sent to us.
 	OpenChanMsg *lnwire.OpenChannel
+
+	// MinAcceptDepth is the number of confirmations we will require
+	// before the channel is considered open.
+	MinAcceptDepth uint16
+}
+
+// ChannelAcceptResponse is a struct containing the response to a request to
+// open an inbound channel. The constraints that are left at their zero value
+// keep our defaults.
+type ChannelAcceptResponse struct {
+	// ChanAcceptError is the error returned by the channel acceptor. If
+	// the channel was accepted, this value will be nil.
+	ChanAcceptError
+
+	// CSVDelay is the csv delay we require for the remote peer.
+	CSVDelay uint16
+
+	// Reserve is the amount that we require the remote peer to keep in
+	// reserve.
+	Reserve dcrutil.Amount
+
+	// MinHtlcIn is the minimum value of the htlcs we accept.
+	MinHtlcIn lnwire.MilliAtom
+
+	// HtlcLimit is the maximum number of htlcs that we allow the remote
+	// peer to offer us.
+	HtlcLimit uint16
+}
+
+// NewChannelAcceptResponse is a constructor for a channel accept response,
+// which creates a response with an appropriately wrapped error (in the case of
+// a rejection) so that the error will be whitelisted and delivered to the
+// initiating peer. Accepted channels simply return a response containing the
+// constraints to apply, with a nil error.
+func NewChannelAcceptResponse(accept bool, acceptErr error, csvDelay uint16,
+	reserve dcrutil.Amount, minHtlcIn lnwire.MilliAtom,
+	htlcLimit uint16) *ChannelAcceptResponse {
+
+	resp := &ChannelAcceptResponse{
+		CSVDelay:  csvDelay,
+		Reserve:   reserve,
+		MinHtlcIn: minHtlcIn,
+		HtlcLimit: htlcLimit,
+	}
+
+	// If we want to accept the channel, we return a response with a nil
+	// error.
+	if accept {
+		return resp
+	}
+
+	// Use a generic error when no custom error is provided.
+	if acceptErr == nil {
+		acceptErr = errChannelRejected
+	}
+
+	resp.ChanAcceptError = ChanAcceptError{
+		error: acceptErr,
+	}
+
+	return resp
+}
+
+// RejectChannel returns a boolean that indicates whether we should reject the
+// channel.
+func (c *ChannelAcceptResponse) RejectChannel() bool {
+	return c.error != nil
+}
+
+// ChanAcceptError is an error that it returned when an external channel
+// acceptor rejects a channel. Note that this error type is whitelisted and
+// will be delivered to the peer initiating a channel.
+type ChanAcceptError struct {
+	error
 }
 
 // ChannelAcceptor is an interface that represents a predicate on the data
 // contained in ChannelAcceptRequest.
 type ChannelAcceptor interface {
-	Accept(req *ChannelAcceptRequest) bool
+	Accept(req *ChannelAcceptRequest) *ChannelAcceptResponse
 }
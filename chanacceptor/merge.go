@@ -0,0 +1,114 @@
+package chanacceptor
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+const (
+	// We use field names in our errors for more readable errors. Create
+	// consts for them here so that we can exactly match in our unit tests.
+	fieldCSV       = "csv delay"
+	fieldHtlcLimit = "htlc limit"
+	fieldMinIn     = "min htlc in"
+	fieldReserve   = "reserve"
+)
+
+// fieldMismatchError returns a merge error for a named field when we get two
+// channel acceptor responses which have different values set.
+func fieldMismatchError(name string, current, new interface{}) error {
+	return fmt.Errorf("multiple values set for: %v, %v and %v",
+		name, current, new)
+}
+
+// mergeUint16 merges two uint16 values, failing if they have different
+// non-zero values.
+func mergeUint16(name string, current, new uint16) (uint16, error) {
+	switch {
+	case current == 0:
+		return new, nil
+
+	case new == 0:
+		return current, nil
+
+	case current != new:
+		return 0, fieldMismatchError(name, current, new)
+	}
+
+	return new, nil
+}
+
+// mergeAtoms merges two atom values, failing if they have different non-zero
+// values.
+func mergeAtoms(name string, current, new dcrutil.Amount) (dcrutil.Amount,
+	error) {
+
+	switch {
+	case current == 0:
+		return new, nil
+
+	case new == 0:
+		return current, nil
+
+	case current != new:
+		return 0, fieldMismatchError(name, current, new)
+	}
+
+	return new, nil
+}
+
+// mergeMilliAtoms merges two milli-atom values, failing if they have
+// different non-zero values.
+func mergeMilliAtoms(name string, current,
+	new lnwire.MilliAtom) (lnwire.MilliAtom, error) {
+
+	switch {
+	case current == 0:
+		return new, nil
+
+	case new == 0:
+		return current, nil
+
+	case current != new:
+		return 0, fieldMismatchError(name, current, new)
+	}
+
+	return new, nil
+}
+
+// mergeResponse takes two channel accept responses, and attempts to merge
+// their constraints, failing if any of the non-zero values differ. Only
+// responses that accept the channel can be merged.
+func mergeResponse(current, new ChannelAcceptResponse) (ChannelAcceptResponse,
+	error) {
+
+	csv, err := mergeUint16(fieldCSV, current.CSVDelay, new.CSVDelay)
+	if err != nil {
+		return current, err
+	}
+	current.CSVDelay = csv
+
+	htlcLimit, err := mergeUint16(
+		fieldHtlcLimit, current.HtlcLimit, new.HtlcLimit,
+	)
+	if err != nil {
+		return current, err
+	}
+	current.HtlcLimit = htlcLimit
+
+	minIn, err := mergeMilliAtoms(fieldMinIn, current.MinHtlcIn, new.MinHtlcIn)
+	if err != nil {
+		return current, err
+	}
+	current.MinHtlcIn = minIn
+
+	reserve, err := mergeAtoms(fieldReserve, current.Reserve, new.Reserve)
+	if err != nil {
+		return current, err
+	}
+	current.Reserve = reserve
+
+	return current, nil
+}
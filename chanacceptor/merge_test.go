@@ -0,0 +1,174 @@
+package chanacceptor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestMergeResponse tests merging of channel acceptor responses.
+func TestMergeResponse(t *testing.T) {
+	tests := []struct {
+		name     string
+		current  ChannelAcceptResponse
+		new      ChannelAcceptResponse
+		merged   ChannelAcceptResponse
+		errField string
+	}{
+		{
+			name: "same response",
+		},
+		{
+			name: "csv delay set",
+			current: ChannelAcceptResponse{
+				CSVDelay: 2,
+			},
+			new: ChannelAcceptResponse{
+				HtlcLimit: 10,
+			},
+			merged: ChannelAcceptResponse{
+				CSVDelay:  2,
+				HtlcLimit: 10,
+			},
+		},
+		{
+			name: "equal values",
+			current: ChannelAcceptResponse{
+				Reserve:   1000,
+				MinHtlcIn: 5,
+			},
+			new: ChannelAcceptResponse{
+				Reserve:   1000,
+				MinHtlcIn: 5,
+			},
+			merged: ChannelAcceptResponse{
+				Reserve:   1000,
+				MinHtlcIn: 5,
+			},
+		},
+		{
+			name: "csv delay mismatch",
+			current: ChannelAcceptResponse{
+				CSVDelay: 2,
+			},
+			new: ChannelAcceptResponse{
+				CSVDelay: 3,
+			},
+			errField: fieldCSV,
+		},
+		{
+			name: "htlc limit mismatch",
+			current: ChannelAcceptResponse{
+				HtlcLimit: 2,
+			},
+			new: ChannelAcceptResponse{
+				HtlcLimit: 3,
+			},
+			errField: fieldHtlcLimit,
+		},
+		{
+			name: "min htlc in mismatch",
+			current: ChannelAcceptResponse{
+				MinHtlcIn: lnwire.MilliAtom(2),
+			},
+			new: ChannelAcceptResponse{
+				MinHtlcIn: lnwire.MilliAtom(3),
+			},
+			errField: fieldMinIn,
+		},
+		{
+			name: "reserve mismatch",
+			current: ChannelAcceptResponse{
+				Reserve: 2,
+			},
+			new: ChannelAcceptResponse{
+				Reserve: 3,
+			},
+			errField: fieldReserve,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := mergeResponse(test.current, test.new)
+			if test.errField != "" {
+				if err == nil {
+					t.Fatalf("expected %v mismatch", test.errField)
+				}
+
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if resp != test.merged {
+				t.Fatalf("expected: %v, got: %v", test.merged, resp)
+			}
+		})
+	}
+}
+
+var errCustomReject = errors.New("custom rejection")
+
+// TestChainedAcceptor tests that the chained acceptor rejects a channel when
+// any of its acceptors do and merges the constraints of those accepting it.
+func TestChainedAcceptor(t *testing.T) {
+	req := &ChannelAcceptRequest{
+		Node:        randKey(t),
+		OpenChanMsg: &lnwire.OpenChannel{},
+	}
+
+	newAcceptor := func(resp *ChannelAcceptResponse) ChannelAcceptor {
+		return NewRPCAcceptor(func(*ChannelAcceptRequest) *ChannelAcceptResponse {
+			return resp
+		})
+	}
+
+	// A chained acceptor without any acceptors accepts all channels with
+	// our defaults.
+	chained := NewChainedAcceptor()
+	resp := chained.Accept(req)
+	if resp.RejectChannel() || *resp != (ChannelAcceptResponse{}) {
+		t.Fatalf("expected default accept, got: %v", resp)
+	}
+
+	// Compatible constraints are merged.
+	chained.AddAcceptor(newAcceptor(
+		NewChannelAcceptResponse(true, nil, 10, 0, 0, 0),
+	))
+	chained.AddAcceptor(newAcceptor(
+		NewChannelAcceptResponse(true, nil, 10, 2000, 0, 5),
+	))
+	resp = chained.Accept(req)
+	expected := ChannelAcceptResponse{
+		CSVDelay:  10,
+		Reserve:   2000,
+		HtlcLimit: 5,
+	}
+	if resp.RejectChannel() || *resp != expected {
+		t.Fatalf("expected: %v, got: %v", expected, resp)
+	}
+
+	// Conflicting constraints result in a generic rejection.
+	conflictID := chained.AddAcceptor(newAcceptor(
+		NewChannelAcceptResponse(true, nil, 20, 0, 0, 0),
+	))
+	resp = chained.Accept(req)
+	if !resp.RejectChannel() || resp.error != errChannelRejected {
+		t.Fatalf("expected generic rejection, got: %v", resp)
+	}
+	chained.RemoveAcceptor(conflictID)
+
+	// A rejection is returned along with its custom error.
+	chained.AddAcceptor(newAcceptor(NewChannelAcceptResponse(
+		false, errCustomReject, 0, 0, 0, 0,
+	)))
+	resp = chained.Accept(req)
+	if !resp.RejectChannel() || resp.error != errCustomReject {
+		t.Fatalf("expected custom rejection, got: %v", resp)
+	}
+}
@@ -0,0 +1,342 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"runtime"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/autopilot"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/sweep"
+)
+
+const (
+	// chanAdvisorWindow is the period of the forwarding history used to
+	// extrapolate the flows of a new channel.
+	chanAdvisorWindow = time.Hour * 24 * 30
+
+	// minCentralityFactor and maxCentralityFactor bound the factor by
+	// which the flows observed through our channels are scaled given the
+	// centrality of a candidate peer, as centrality is only a rough
+	// predictor of flows.
+	minCentralityFactor = 0.1
+	maxCentralityFactor = 10
+
+	// centralityCacheTTL is how long the betweenness centrality of the
+	// graph is reused across estimates, as computing it for the whole
+	// graph is expensive and it changes slowly.
+	centralityCacheTTL = 10 * time.Minute
+
+	// centralityCacheKey is the key of the centrality in its cache.
+	centralityCacheKey = "centrality"
+)
+
+// graphCentrality returns the normalized betweenness centrality of the nodes
+// of the given graph.
+func graphCentrality(
+	graph *channeldb.ChannelGraph) (map[autopilot.NodeID]float64, error) {
+
+	metric, err := autopilot.NewBetweennessCentralityMetric(
+		runtime.NumCPU(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	err = metric.Refresh(autopilot.ChannelGraphFromDatabase(graph))
+	if err != nil {
+		return nil, fmt.Errorf("unable to compute centrality: %v", err)
+	}
+
+	return metric.GetMetric(true), nil
+}
+
+// nodeCentrality returns the normalized betweenness centrality of the nodes of
+// the graph, reusing the one computed within the last centralityCacheTTL.
+func (r *rpcServer) nodeCentrality() (map[autopilot.NodeID]float64, error) {
+	centrality, err := r.centralityCache.get(centralityCacheKey,
+		func(time.Time) (interface{}, error) {
+			return graphCentrality(
+				r.server.localChanDB.ChannelGraph(),
+			)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return centrality.(map[autopilot.NodeID]float64), nil
+}
+
+// chanFlows are the forwarding flows observed through a set of channels.
+// Every forward is accounted to both its incoming and outgoing channel, each
+// being credited half of its fee.
+type chanFlows struct {
+	// capacity is the total capacity of the channels.
+	capacity dcrutil.Amount
+
+	// volume is the amount forwarded through the channels.
+	volume lnwire.MilliAtom
+
+	// fees is the part of the forwarding fees credited to the channels.
+	fees lnwire.MilliAtom
+}
+
+// addForward accounts one side of a forward to the flows.
+func (f *chanFlows) addForward(event *channeldb.ForwardingEvent) {
+	f.volume += event.AmtOut
+	f.fees += (event.AmtIn - event.AmtOut) / 2
+}
+
+// dailyYield returns the daily volume and fees per atom of capacity of the
+// flows observed over the given window.
+func (f *chanFlows) dailyYield(window time.Duration) (float64, float64) {
+	if f.capacity == 0 || f.volume == 0 {
+		return 0, 0
+	}
+
+	days := window.Hours() / 24
+	perAtomDay := float64(f.capacity) * days
+
+	return float64(f.volume) / perAtomDay, float64(f.fees) / perAtomDay
+}
+
+// chanOpenEstimate is the estimated cost and benefit of opening a channel.
+type chanOpenEstimate struct {
+	flowSource   lnrpc.EstimateChannelOpenResponse_FlowSource
+	dailyVolume  lnwire.MilliAtom
+	dailyRevenue lnwire.MilliAtom
+	breakEven    float64
+	reachable    bool
+}
+
+// centralityFactor returns the factor by which the flows observed through our
+// channels are scaled for a candidate peer, given its centrality and the
+// average centrality of our current peers.
+func centralityFactor(centrality, avgPeerCentrality float64) float64 {
+	if avgPeerCentrality == 0 {
+		return 1
+	}
+
+	factor := centrality / avgPeerCentrality
+	switch {
+	case factor < minCentralityFactor:
+		return minCentralityFactor
+
+	case factor > maxCentralityFactor:
+		return maxCentralityFactor
+	}
+
+	return factor
+}
+
+// estimateChanOpen estimates the flows and break-even time of a channel of the
+// given size. The flows already observed through our channels with the peer
+// are preferred. Otherwise the flows of all our channels are scaled by the
+// centrality of the peer relative to our current peers.
+func estimateChanOpen(chanSize dcrutil.Amount, nodeFlows,
+	peerFlows *chanFlows, centrality, avgPeerCentrality float64,
+	totalCost dcrutil.Amount, window time.Duration) *chanOpenEstimate {
+
+	estimate := &chanOpenEstimate{
+		flowSource: lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NONE,
+	}
+
+	var volumeYield, feeYield float64
+	switch {
+	case peerFlows.volume > 0:
+		estimate.flowSource = lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_PEER
+		volumeYield, feeYield = peerFlows.dailyYield(window)
+
+	case nodeFlows.volume > 0:
+		estimate.flowSource = lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NODE
+		volumeYield, feeYield = nodeFlows.dailyYield(window)
+
+		factor := centralityFactor(centrality, avgPeerCentrality)
+		volumeYield *= factor
+		feeYield *= factor
+	}
+
+	estimate.dailyVolume = lnwire.MilliAtom(float64(chanSize) * volumeYield)
+	estimate.dailyRevenue = lnwire.MilliAtom(float64(chanSize) * feeYield)
+
+	if estimate.dailyRevenue > 0 {
+		estimate.reachable = true
+		estimate.breakEven = float64(lnwire.NewMAtomsFromAtoms(totalCost)) /
+			float64(estimate.dailyRevenue)
+	}
+
+	return estimate
+}
+
+// chanOpenFees returns the fees of the funding transaction of a new channel
+// and of its cooperative close at the given fee rate.
+func chanOpenFees(feePerKB chainfee.AtomPerKByte) (dcrutil.Amount,
+	dcrutil.Amount) {
+
+	var fundingTx input.TxSizeEstimator
+	fundingTx.AddP2PKHInput().AddP2SHOutput().AddP2PKHOutput()
+
+	var closingTx input.TxSizeEstimator
+	closingTx.AddCustomInput(input.FundingOutputSigScriptSize).
+		AddP2PKHOutput().AddP2PKHOutput()
+
+	return feePerKB.FeeForSize(fundingTx.Size()),
+		feePerKB.FeeForSize(closingTx.Size())
+}
+
+// EstimateChannelOpen estimates the routing revenue a new channel with the
+// given peer could earn, based on the flows observed through our channels and
+// the betweenness centrality of the peer, along with the fees of opening and
+// closing it and the resulting break-even time.
+func (r *rpcServer) EstimateChannelOpen(ctx context.Context,
+	in *lnrpc.EstimateChannelOpenRequest) (
+	*lnrpc.EstimateChannelOpenResponse, error) {
+
+	pubKeyBytes, err := hex.DecodeString(in.NodePubkey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node pubkey: %v", err)
+	}
+	pubKey, err := secp256k1.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid node pubkey: %v", err)
+	}
+	candidate := autopilot.NewNodeID(pubKey)
+
+	chanSize := dcrutil.Amount(in.LocalFundingAmount)
+	if chanSize <= 0 {
+		return nil, fmt.Errorf("local funding amount must be positive")
+	}
+	if in.TargetConf != 0 && in.AtomsPerByte != 0 {
+		return nil, fmt.Errorf("either target_conf or atoms_per_byte " +
+			"should be set, but not both")
+	}
+
+	rpcsLog.Debugf("[estimatechannelopen] node=%x, local_amt=%v",
+		pubKeyBytes, chanSize)
+
+	feePerKB, err := sweep.DetermineFeePerKB(
+		r.server.cc.feeEstimator, sweep.FeePreference{
+			ConfTarget: uint32(in.TargetConf),
+			FeeRate:    chainfee.AtomPerKByte(in.AtomsPerByte * 1000),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+	fundingFee, closingFee := chanOpenFees(feePerKB)
+
+	// The centrality of nodes unknown to the graph can't be determined,
+	// so no flows can be extrapolated for them.
+	_, inGraph, err := r.server.localChanDB.ChannelGraph().HasLightningNode(
+		candidate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if !inGraph {
+		return nil, fmt.Errorf("node %x not found in graph",
+			pubKeyBytes)
+	}
+
+	// The centrality of the candidate is compared to the one of our
+	// current peers, whose channels produced the observed flows.
+	centrality, err := r.nodeCentrality()
+	if err != nil {
+		return nil, err
+	}
+
+	openChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		nodeFlows, peerFlows chanFlows
+		peers                = make(map[autopilot.NodeID]struct{})
+		peerChans            = make(map[lnwire.ShortChannelID]struct{})
+	)
+	for _, channel := range openChannels {
+		peer := autopilot.NewNodeID(channel.IdentityPub)
+		peers[peer] = struct{}{}
+
+		nodeFlows.capacity += channel.Capacity
+		if peer == candidate {
+			peerFlows.capacity += channel.Capacity
+			peerChans[channel.ShortChannelID] = struct{}{}
+		}
+	}
+
+	var avgPeerCentrality float64
+	for peer := range peers {
+		avgPeerCentrality += centrality[peer]
+	}
+	if len(peers) > 0 {
+		avgPeerCentrality /= float64(len(peers))
+	}
+
+	// Before we query the forwarding log, we'll instruct the switch to
+	// flush any pending events to disk, so the flows are up to date.
+	if err := r.server.htlcSwitch.FlushForwardingEvents(); err != nil {
+		return nil, fmt.Errorf("unable to flush forwarding "+
+			"events: %v", err)
+	}
+
+	now := time.Now()
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    now.Add(-chanAdvisorWindow),
+		EndTime:      now,
+		NumMaxEvents: 1000,
+	}
+	fwdEventLog := r.server.remoteChanDB.ForwardingLog()
+	for {
+		timeSlice, err := fwdEventLog.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("unable to query forwarding "+
+				"log: %v", err)
+		}
+		if len(timeSlice.ForwardingEvents) == 0 {
+			break
+		}
+
+		for i := range timeSlice.ForwardingEvents {
+			event := &timeSlice.ForwardingEvents[i]
+			for _, chanID := range []lnwire.ShortChannelID{
+				event.IncomingChanID, event.OutgoingChanID,
+			} {
+				nodeFlows.addForward(event)
+				if _, ok := peerChans[chanID]; ok {
+					peerFlows.addForward(event)
+				}
+			}
+		}
+
+		query.IndexOffset = timeSlice.LastIndexOffset
+	}
+
+	estimate := estimateChanOpen(
+		chanSize, &nodeFlows, &peerFlows, centrality[candidate],
+		avgPeerCentrality, fundingFee+closingFee, chanAdvisorWindow,
+	)
+
+	return &lnrpc.EstimateChannelOpenResponse{
+		Centrality:                 centrality[candidate],
+		AvgPeerCentrality:          avgPeerCentrality,
+		FlowSource:                 estimate.flowSource,
+		ExpectedDailyVolumeMAtoms:  uint64(estimate.dailyVolume),
+		ExpectedDailyRevenueMAtoms: uint64(estimate.dailyRevenue),
+		FundingFee:                 int64(fundingFee),
+		ClosingFee:                 int64(closingFee),
+		FeerateAtomsPerByte:        int64(feePerKB / 1000),
+		BreakEvenDays:              estimate.breakEven,
+		BreakEvenReachable:         estimate.reachable,
+	}, nil
+}
@@ -0,0 +1,325 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/autopilot"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestEstimateChanOpen asserts that the flows of a new channel are
+// extrapolated from the flows of the channels with the peer if there are any,
+// and from the flows of all channels scaled by centrality otherwise.
+func TestEstimateChanOpen(t *testing.T) {
+	t.Parallel()
+
+	const window = time.Hour * 24 * 10
+
+	// Over 10 days, 1M atoms of capacity forwarded 10M atoms and earned
+	// 10k atoms, so each atom of capacity yields 1 atom of volume and 1
+	// milli-atom of fees per day.
+	nodeFlows := &chanFlows{
+		capacity: 1000000,
+		volume:   lnwire.NewMAtomsFromAtoms(10000000),
+		fees:     lnwire.NewMAtomsFromAtoms(10000),
+	}
+
+	testCases := []struct {
+		name              string
+		peerFlows         *chanFlows
+		nodeFlows         *chanFlows
+		centrality        float64
+		avgPeerCentrality float64
+		source            lnrpc.EstimateChannelOpenResponse_FlowSource
+		dailyRevenue      lnwire.MilliAtom
+		breakEven         float64
+		reachable         bool
+	}{{
+		name:              "no flows",
+		peerFlows:         &chanFlows{},
+		nodeFlows:         &chanFlows{},
+		centrality:        0.5,
+		avgPeerCentrality: 0.5,
+		source:            lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NONE,
+	}, {
+		name:              "node flows",
+		peerFlows:         &chanFlows{},
+		nodeFlows:         nodeFlows,
+		centrality:        0.5,
+		avgPeerCentrality: 0.5,
+		source:            lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NODE,
+		dailyRevenue:      100000,
+		breakEven:         10,
+		reachable:         true,
+	}, {
+		name:              "node flows scaled by centrality",
+		peerFlows:         &chanFlows{},
+		nodeFlows:         nodeFlows,
+		centrality:        0.5,
+		avgPeerCentrality: 0.25,
+		source:            lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NODE,
+		dailyRevenue:      200000,
+		breakEven:         5,
+		reachable:         true,
+	}, {
+		name:              "centrality factor bounded",
+		peerFlows:         &chanFlows{},
+		nodeFlows:         nodeFlows,
+		centrality:        0,
+		avgPeerCentrality: 0.25,
+		source:            lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_NODE,
+		dailyRevenue:      10000,
+		breakEven:         100,
+		reachable:         true,
+	}, {
+		name: "peer flows",
+		peerFlows: &chanFlows{
+			capacity: 100000,
+			volume:   lnwire.NewMAtomsFromAtoms(1000000),
+			fees:     lnwire.NewMAtomsFromAtoms(4000),
+		},
+		nodeFlows:         nodeFlows,
+		centrality:        0.5,
+		avgPeerCentrality: 0.25,
+		source:            lnrpc.EstimateChannelOpenResponse_FLOW_SOURCE_PEER,
+		dailyRevenue:      400000,
+		breakEven:         2.5,
+		reachable:         true,
+	}}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			estimate := estimateChanOpen(
+				100000, test.nodeFlows, test.peerFlows,
+				test.centrality, test.avgPeerCentrality,
+				dcrutil.Amount(1000), window,
+			)
+
+			if estimate.flowSource != test.source {
+				t.Fatalf("expected flow source %v, got %v",
+					test.source, estimate.flowSource)
+			}
+			if estimate.dailyRevenue != test.dailyRevenue {
+				t.Fatalf("expected daily revenue %v, got %v",
+					test.dailyRevenue, estimate.dailyRevenue)
+			}
+			if estimate.reachable != test.reachable {
+				t.Fatalf("expected reachable %v, got %v",
+					test.reachable, estimate.reachable)
+			}
+			if estimate.breakEven != test.breakEven {
+				t.Fatalf("expected break-even of %v days, got %v",
+					test.breakEven, estimate.breakEven)
+			}
+		})
+	}
+}
+
+// TestChanOpenFees asserts that the fees of opening and closing a channel
+// scale with the fee rate.
+func TestChanOpenFees(t *testing.T) {
+	t.Parallel()
+
+	fundingFee, closingFee := chanOpenFees(chainfee.AtomPerKByte(10000))
+	if fundingFee <= 0 || closingFee <= 0 {
+		t.Fatalf("expected positive fees, got %v and %v", fundingFee,
+			closingFee)
+	}
+
+	doubleFunding, doubleClosing := chanOpenFees(
+		chainfee.AtomPerKByte(20000),
+	)
+	if doubleFunding != 2*fundingFee || doubleClosing != 2*closingFee {
+		t.Fatalf("expected fees to double, got %v and %v",
+			doubleFunding, doubleClosing)
+	}
+}
+
+// centralityTestGraph is a channel graph used to test the centrality of its
+// nodes.
+type centralityTestGraph struct {
+	t      *testing.T
+	graph  *channeldb.ChannelGraph
+	nodes  []*secp256k1.PublicKey
+	chanID uint64
+}
+
+// newCentralityTestGraph creates a graph of the given number of nodes, without
+// any channel.
+func newCentralityTestGraph(t *testing.T, db *channeldb.DB,
+	numNodes int) *centralityTestGraph {
+
+	g := &centralityTestGraph{
+		t:     t,
+		graph: db.ChannelGraph(),
+	}
+	for i := 0; i < numNodes; i++ {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+
+		// Nodes without addresses are ignored by the centrality.
+		node := &channeldb.LightningNode{
+			HaveNodeAnnouncement: true,
+			Addresses: []net.Addr{
+				&net.TCPAddr{IP: net.ParseIP("127.0.0.1")},
+			},
+			Features: lnwire.NewFeatureVector(
+				nil, lnwire.Features,
+			),
+			AuthSigBytes: testSig.Serialize(),
+		}
+		node.AddPubKey(privKey.PubKey())
+		if err := g.graph.AddLightningNode(node); err != nil {
+			t.Fatalf("unable to add node: %v", err)
+		}
+		g.nodes = append(g.nodes, privKey.PubKey())
+	}
+
+	return g
+}
+
+// nodeID returns the id of the node at the given index.
+func (g *centralityTestGraph) nodeID(i int) autopilot.NodeID {
+	return autopilot.NewNodeID(g.nodes[i])
+}
+
+// addChannel adds a channel between the nodes at the given indexes, with the
+// policies of both directions.
+func (g *centralityTestGraph) addChannel(i, j int) {
+	g.t.Helper()
+
+	node1, node2 := g.nodes[i], g.nodes[j]
+	if bytes.Compare(node1.SerializeCompressed(),
+		node2.SerializeCompressed()) > 0 {
+
+		node1, node2 = node2, node1
+	}
+
+	g.chanID++
+	edge := &channeldb.ChannelEdgeInfo{
+		ChannelID: g.chanID,
+		Capacity:  dcrutil.Amount(1e6),
+	}
+	edge.AddNodeKeys(node1, node2, node1, node2)
+	if err := g.graph.AddChannelEdge(edge); err != nil {
+		g.t.Fatalf("unable to add channel: %v", err)
+	}
+
+	for _, flags := range []lnwire.ChanUpdateChanFlags{
+		0, lnwire.ChanUpdateDirection,
+	} {
+		policy := &channeldb.ChannelEdgePolicy{
+			SigBytes:     testSig.Serialize(),
+			ChannelID:    g.chanID,
+			LastUpdate:   time.Unix(1000, 0),
+			MinHTLC:      1,
+			MaxHTLC:      lnwire.NewMAtomsFromAtoms(1e6),
+			MessageFlags: lnwire.ChanUpdateOptionMaxHtlc,
+			ChannelFlags: flags,
+		}
+		if err := g.graph.UpdateEdgePolicy(policy); err != nil {
+			g.t.Fatalf("unable to update policy: %v", err)
+		}
+	}
+}
+
+// TestGraphCentrality asserts that the centrality of the nodes is computed
+// from the channel graph and normalized, so that the most central node has a
+// centrality of one.
+func TestGraphCentrality(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanup()
+
+	// Node 0 is the center of a star of four nodes, while node 3 is at the
+	// edge of it through node 2.
+	g := newCentralityTestGraph(t, db, 5)
+	g.addChannel(0, 1)
+	g.addChannel(0, 2)
+	g.addChannel(0, 4)
+	g.addChannel(2, 3)
+
+	centrality, err := graphCentrality(g.graph)
+	if err != nil {
+		t.Fatalf("unable to compute centrality: %v", err)
+	}
+
+	if centrality[g.nodeID(0)] != 1 {
+		t.Fatalf("expected center centrality 1, got %v",
+			centrality[g.nodeID(0)])
+	}
+	node2 := centrality[g.nodeID(2)]
+	if node2 <= 0 || node2 >= 1 {
+		t.Fatalf("expected intermediate centrality in (0, 1), got %v",
+			node2)
+	}
+	for _, leaf := range []int{1, 3, 4} {
+		if centrality[g.nodeID(leaf)] != 0 {
+			t.Fatalf("expected leaf %d centrality 0, got %v", leaf,
+				centrality[g.nodeID(leaf)])
+		}
+	}
+}
+
+// TestNodeCentralityRefresh asserts that the centrality used to estimate new
+// channels only reflects changes of the graph once the cached one expires.
+func TestNodeCentralityRefresh(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanup()
+
+	now := time.Unix(1000, 0)
+	r := &rpcServer{
+		server: &server{localChanDB: db},
+		centralityCache: newDashboardCache(
+			centralityCacheTTL, func() time.Time { return now },
+		),
+	}
+
+	assertCentrality := func(node autopilot.NodeID, expected float64) {
+		t.Helper()
+
+		centrality, err := r.nodeCentrality()
+		if err != nil {
+			t.Fatalf("unable to get centrality: %v", err)
+		}
+		if centrality[node] != expected {
+			t.Fatalf("expected centrality %v, got %v", expected,
+				centrality[node])
+		}
+	}
+
+	// In the line 0-1-2, only node 1 is on a path between other nodes.
+	g := newCentralityTestGraph(t, db, 4)
+	g.addChannel(0, 1)
+	g.addChannel(1, 2)
+	assertCentrality(g.nodeID(2), 0)
+
+	// Extending the line to node 3 makes node 2 as central as node 1, but
+	// the cached centrality is used until it expires.
+	g.addChannel(2, 3)
+	now = now.Add(centralityCacheTTL - time.Second)
+	assertCentrality(g.nodeID(2), 0)
+
+	now = now.Add(time.Second)
+	assertCentrality(g.nodeID(2), 1)
+}
@@ -97,7 +97,7 @@ func TestMultiPackUnpack(t *testing.T) {
 			fakeRawMulti := bytes.NewBuffer(
 				bytes.Repeat([]byte{99}, 20),
 			)
-			err := encryptPayloadToWriter(
+			err := EncryptPayloadToWriter(
 				*fakeRawMulti, &fakePackedMulti, keyRing,
 			)
 			if err != nil {
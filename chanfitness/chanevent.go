@@ -216,3 +216,35 @@ func (e *chanEventLog) uptime(start, end time.Time) (time.Duration, error) {
 
 	return uptime, nil
 }
+
+// flapCount returns the number of times the remote peer went offline while
+// the channel was monitored.
+func (e *chanEventLog) flapCount() int {
+	return e.flapCountSince(time.Time{})
+}
+
+// flapCountSince returns the number of times the remote peer went offline
+// while the channel was monitored, from the given start time onwards.
+func (e *chanEventLog) flapCountSince(start time.Time) int {
+	var flaps int
+	for _, event := range e.events {
+		if event.eventType == peerOfflineEvent &&
+			!event.timestamp.Before(start) {
+
+			flaps++
+		}
+	}
+
+	return flaps
+}
+
+// lifespan returns the time the channel has been monitored for, until its
+// close or the present.
+func (e *chanEventLog) lifespan() time.Duration {
+	endTime := e.closedAt
+	if endTime.IsZero() {
+		endTime = e.now()
+	}
+
+	return endTime.Sub(e.openedAt)
+}
@@ -393,3 +393,31 @@ func TestUptime(t *testing.T) {
 		})
 	}
 }
+
+// TestFlapCountSince tests that only the offline events from the given start
+// time onwards are counted as flaps.
+func TestFlapCountSince(t *testing.T) {
+	now := time.Now()
+	twoDaysAgo := now.Add(time.Hour * -48)
+	oneHourAgo := now.Add(time.Hour * -1)
+
+	eventLog := &chanEventLog{
+		events: []*channelEvent{
+			{timestamp: twoDaysAgo, eventType: peerOnlineEvent},
+			{timestamp: twoDaysAgo.Add(time.Hour), eventType: peerOfflineEvent},
+			{timestamp: oneHourAgo, eventType: peerOnlineEvent},
+			{timestamp: oneHourAgo.Add(time.Minute), eventType: peerOfflineEvent},
+		},
+		now:      func() time.Time { return now },
+		openedAt: twoDaysAgo,
+	}
+
+	if flaps := eventLog.flapCount(); flaps != 2 {
+		t.Fatalf("expected 2 flaps, got: %v", flaps)
+	}
+
+	flaps := eventLog.flapCountSince(now.Add(-RecentFlapWindow))
+	if flaps != 1 {
+		t.Fatalf("expected 1 recent flap, got: %v", flaps)
+	}
+}
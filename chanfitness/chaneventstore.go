@@ -8,6 +8,9 @@
 //
 // Uptime: the total time within a given period that the channel's remote peer
 // has been online.
+//
+// Flap count: the number of times a peer went offline while we had a channel
+// with it.
 package chanfitness
 
 import (
@@ -23,6 +26,10 @@ import (
 	"github.com/decred/dcrlnd/subscribe"
 )
 
+// RecentFlapWindow is the period over which recent flaps of a peer are
+// counted in its PeerFitness.
+const RecentFlapWindow = 24 * time.Hour
+
 var (
 	// errShuttingDown is returned when the store cannot respond to a query because
 	// it has received the shutdown signal.
@@ -31,6 +38,10 @@ var (
 	// ErrChannelNotFound is returned when a query is made for a channel that
 	// the event store does not have knowledge of.
 	ErrChannelNotFound = errors.New("channel not found in event store")
+
+	// ErrPeerNotFound is returned when a query is made for a peer that we
+	// have no channels with in the event store.
+	ErrPeerNotFound = errors.New("peer not found in event store")
 )
 
 // ChannelEventStore maintains a set of event logs for the node's channels to
@@ -51,6 +62,9 @@ type ChannelEventStore struct {
 	// uptimeRequests serves requests for the uptime of channels.
 	uptimeRequests chan uptimeRequest
 
+	// peerFitnessRequests serves requests for the fitness of peers.
+	peerFitnessRequests chan peerFitnessRequest
+
 	quit chan struct{}
 
 	wg sync.WaitGroup
@@ -105,6 +119,39 @@ type uptimeResponse struct {
 	err    error
 }
 
+// PeerFitness summarizes the behaviour of a peer over all of the channels we
+// have had with it.
+type PeerFitness struct {
+	// Lifespan is the total time our channels with the peer have been
+	// monitored.
+	Lifespan time.Duration
+
+	// Uptime is the total time the peer was online while our channels
+	// with it were monitored.
+	Uptime time.Duration
+
+	// FlapCount is the number of times the peer went offline.
+	FlapCount int
+
+	// RecentFlapCount is the number of times the peer went offline within
+	// the last RecentFlapWindow.
+	RecentFlapCount int
+}
+
+// peerFitnessRequest contains the peer required to query the store for its
+// fitness and a blocking response channel on which the result is sent.
+type peerFitnessRequest struct {
+	peer         route.Vertex
+	responseChan chan peerFitnessResponse
+}
+
+// peerFitnessResponse contains the response to a peerFitnessRequest and an
+// error if one occurred.
+type peerFitnessResponse struct {
+	fitness *PeerFitness
+	err     error
+}
+
 // NewChannelEventStore initializes an event store with the config provided.
 // Note that this function does not start the main event loop, Start() must be
 // called.
@@ -116,6 +163,8 @@ func NewChannelEventStore(config *Config) *ChannelEventStore {
 		lifespanRequests: make(chan lifespanRequest),
 		uptimeRequests:   make(chan uptimeRequest),
 		quit:             make(chan struct{}),
+
+		peerFitnessRequests: make(chan peerFitnessRequest),
 	}
 
 	return store
@@ -327,6 +376,14 @@ func (c *ChannelEventStore) consume(subscriptions *subscriptions) {
 
 			req.responseChan <- resp
 
+		// Serve requests for peer fitness.
+		case req := <-c.peerFitnessRequests:
+			fitness, err := c.peerFitness(req.peer)
+			req.responseChan <- peerFitnessResponse{
+				fitness: fitness,
+				err:     err,
+			}
+
 		// Exit if the store receives the signal to shutdown.
 		case <-c.quit:
 			return
@@ -396,3 +453,74 @@ func (c *ChannelEventStore) GetUptime(channelPoint wire.OutPoint, startTime,
 		return 0, errShuttingDown
 	}
 }
+
+// peerFitness aggregates the event logs of all channels with the given peer.
+// Since peer events are recorded for every channel with the peer, the flap
+// count is that of the channel that observed the most flaps.
+func (c *ChannelEventStore) peerFitness(peer route.Vertex) (*PeerFitness,
+	error) {
+
+	var (
+		fitness PeerFitness
+		found   bool
+	)
+	for _, channel := range c.channels {
+		if channel.peer != peer {
+			continue
+		}
+		found = true
+
+		lifespan := channel.lifespan()
+		uptime, err := channel.uptime(
+			channel.openedAt, channel.openedAt.Add(lifespan),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		fitness.Lifespan += lifespan
+		fitness.Uptime += uptime
+		if flaps := channel.flapCount(); flaps > fitness.FlapCount {
+			fitness.FlapCount = flaps
+		}
+
+		recentFlaps := channel.flapCountSince(
+			channel.now().Add(-RecentFlapWindow),
+		)
+		if recentFlaps > fitness.RecentFlapCount {
+			fitness.RecentFlapCount = recentFlaps
+		}
+	}
+
+	if !found {
+		return nil, ErrPeerNotFound
+	}
+
+	return &fitness, nil
+}
+
+// GetPeerFitness returns the aggregated lifespan, uptime and flap count of all
+// channels we have had with a peer, or ErrPeerNotFound if the store has no
+// channels with the peer.
+func (c *ChannelEventStore) GetPeerFitness(peer route.Vertex) (*PeerFitness,
+	error) {
+
+	request := peerFitnessRequest{
+		peer:         peer,
+		responseChan: make(chan peerFitnessResponse),
+	}
+
+	select {
+	case c.peerFitnessRequests <- request:
+	case <-c.quit:
+		return nil, errShuttingDown
+	}
+
+	select {
+	case resp := <-request.responseChan:
+		return resp.fitness, resp.err
+
+	case <-c.quit:
+		return nil, errShuttingDown
+	}
+}
@@ -527,3 +527,70 @@ func TestAddChannel(t *testing.T) {
 		})
 	}
 }
+
+// TestGetPeerFitness tests that the fitness of a peer aggregates the event logs
+// of all channels with the peer.
+func TestGetPeerFitness(t *testing.T) {
+	now := time.Now()
+	twoHoursAgo := now.Add(time.Hour * -2)
+	fourHoursAgo := now.Add(time.Hour * -4)
+
+	_, peer, chanPoint1 := getTestChannel(t)
+	chanPoint2 := wire.OutPoint{Index: 1}
+
+	store := NewChannelEventStore(&Config{})
+
+	// Start goroutine which consumes GetPeerFitness requests.
+	store.wg.Add(1)
+	go store.consume(&subscriptions{
+		channelUpdates: make(chan interface{}),
+		peerUpdates:    make(chan interface{}),
+		cancel:         func() {},
+	})
+	defer store.Stop()
+
+	// The first channel was monitored for four hours, during which the
+	// peer was online for two hours and flapped twice. The second channel
+	// was opened two hours ago with the peer online throughout.
+	store.channels[chanPoint1] = &chanEventLog{
+		peer: peer,
+		events: []*channelEvent{
+			{timestamp: fourHoursAgo, eventType: peerOnlineEvent},
+			{timestamp: fourHoursAgo.Add(time.Hour), eventType: peerOfflineEvent},
+			{timestamp: twoHoursAgo, eventType: peerOnlineEvent},
+			{timestamp: twoHoursAgo.Add(time.Hour), eventType: peerOfflineEvent},
+		},
+		now:      func() time.Time { return now },
+		openedAt: fourHoursAgo,
+	}
+	store.channels[chanPoint2] = &chanEventLog{
+		peer: peer,
+		events: []*channelEvent{
+			{timestamp: twoHoursAgo, eventType: peerOnlineEvent},
+		},
+		now:      func() time.Time { return now },
+		openedAt: twoHoursAgo,
+		closedAt: now,
+	}
+
+	fitness, err := store.GetPeerFitness(peer)
+	if err != nil {
+		t.Fatalf("unable to get peer fitness: %v", err)
+	}
+
+	expected := PeerFitness{
+		Lifespan:        time.Hour * 6,
+		Uptime:          time.Hour * 4,
+		FlapCount:       2,
+		RecentFlapCount: 2,
+	}
+	if *fitness != expected {
+		t.Fatalf("expected fitness: %v, got: %v", expected, *fitness)
+	}
+
+	// A peer without channels isn't known to the store.
+	_, err = store.GetPeerFitness(route.Vertex{})
+	if err != ErrPeerNotFound {
+		t.Fatalf("expected: %v, got: %v", ErrPeerNotFound, err)
+	}
+}
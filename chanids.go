@@ -0,0 +1,202 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwire"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// errChanIDNotFound is returned when no channel matches the identifier passed
+// to ResolveChannelId.
+var errChanIDNotFound = status.Error(
+	codes.NotFound, "channel not found",
+)
+
+// parseChannelID parses a hex-encoded 32-byte channel ID.
+func parseChannelID(s string) (lnwire.ChannelID, error) {
+	var cid lnwire.ChannelID
+
+	cidBytes, err := hex.DecodeString(s)
+	if err != nil {
+		return cid, fmt.Errorf("invalid channel ID %q: %v", s, err)
+	}
+	if len(cidBytes) != len(cid) {
+		return cid, fmt.Errorf("channel ID must be %d bytes, got %d",
+			len(cid), len(cidBytes))
+	}
+	copy(cid[:], cidBytes)
+
+	return cid, nil
+}
+
+// chanPointFromShortChanID returns the channel point of the channel with the
+// given short channel ID, searching our own open channels, the graph and our
+// closed channels in that order.
+func (r *rpcServer) chanPointFromShortChanID(
+	openChans []*channeldb.OpenChannel, scid uint64) (*wire.OutPoint,
+	error) {
+
+	for _, channel := range openChans {
+		if channel.ShortChannelID.ToUint64() == scid {
+			return &channel.FundingOutpoint, nil
+		}
+	}
+
+	graph := r.server.localChanDB.ChannelGraph()
+	info, _, _, err := graph.FetchChannelEdgesByID(scid)
+	switch err {
+	case nil:
+		return &info.ChannelPoint, nil
+
+	case channeldb.ErrEdgeNotFound, channeldb.ErrGraphNoEdgesFound:
+
+	default:
+		return nil, err
+	}
+
+	summary, err := r.server.remoteChanDB.FetchClosedChannelForShortChanID(
+		lnwire.NewShortChanIDFromInt(scid),
+	)
+	switch err {
+	case nil:
+		return &summary.ChanPoint, nil
+
+	case channeldb.ErrClosedChannelNotFound:
+		return nil, errChanIDNotFound
+
+	default:
+		return nil, err
+	}
+}
+
+// chanPointFromChannelID returns the channel point of the channel with the
+// given channel ID, searching our own open channels, the graph and our closed
+// channels in that order.
+func (r *rpcServer) chanPointFromChannelID(
+	openChans []*channeldb.OpenChannel, cid lnwire.ChannelID) (
+	*wire.OutPoint, error) {
+
+	for _, channel := range openChans {
+		if cid.IsChanPoint(&channel.FundingOutpoint) {
+			return &channel.FundingOutpoint, nil
+		}
+	}
+
+	graph := r.server.localChanDB.ChannelGraph()
+	chanPoint, err := graph.ChannelPoint(cid)
+	switch err {
+	case nil:
+		return chanPoint, nil
+
+	case channeldb.ErrEdgeNotFound, channeldb.ErrGraphNoEdgesFound:
+
+	default:
+		return nil, err
+	}
+
+	summary, err := r.server.remoteChanDB.FetchClosedChannelForID(cid)
+	switch err {
+	case nil:
+		return &summary.ChanPoint, nil
+
+	case channeldb.ErrClosedChannelNotFound:
+		return nil, errChanIDNotFound
+
+	default:
+		return nil, err
+	}
+}
+
+// ResolveChannelId converts between the funding outpoint, the short channel ID
+// and the channel ID of one of our own channels or of a channel of the graph.
+func (r *rpcServer) ResolveChannelId(ctx context.Context,
+	in *lnrpc.ResolveChannelIdRequest) (*lnrpc.ResolveChannelIdResponse,
+	error) {
+
+	openChans, err := r.server.remoteChanDB.FetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	// First, we'll find the channel point of the requested channel, as
+	// it's the identifier all others can be derived from.
+	var chanPoint *wire.OutPoint
+	switch id := in.Id.(type) {
+	case *lnrpc.ResolveChannelIdRequest_ChannelPoint:
+		chanPoint, err = parseOutPoint(id.ChannelPoint)
+
+	case *lnrpc.ResolveChannelIdRequest_ChanId:
+		if id.ChanId == 0 {
+			return nil, errors.New("short channel ID must be " +
+				"non-zero")
+		}
+		chanPoint, err = r.chanPointFromShortChanID(
+			openChans, id.ChanId,
+		)
+
+	case *lnrpc.ResolveChannelIdRequest_ChannelId:
+		var cid lnwire.ChannelID
+		cid, err = parseChannelID(id.ChannelId)
+		if err != nil {
+			return nil, err
+		}
+		chanPoint, err = r.chanPointFromChannelID(openChans, cid)
+
+	default:
+		return nil, errors.New("a channel identifier is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cid := lnwire.NewChanIDFromOutPoint(chanPoint)
+	resp := &lnrpc.ResolveChannelIdResponse{
+		ChannelPoint: chanPoint.String(),
+		ChannelId:    cid.String(),
+	}
+
+	// With the channel point known, we'll look up the short channel ID,
+	// preferring our own records over the graph's.
+	for _, channel := range openChans {
+		if channel.FundingOutpoint == *chanPoint {
+			resp.ChanId = channel.ShortChannelID.ToUint64()
+			resp.OwnChannel = true
+			return resp, nil
+		}
+	}
+
+	summary, err := r.server.remoteChanDB.FetchClosedChannel(chanPoint)
+	switch err {
+	case nil:
+		resp.ChanId = summary.ShortChanID.ToUint64()
+		resp.OwnChannel = true
+		resp.Closed = true
+		return resp, nil
+
+	case channeldb.ErrClosedChannelNotFound:
+
+	default:
+		return nil, err
+	}
+
+	graph := r.server.localChanDB.ChannelGraph()
+	resp.ChanId, err = graph.ChannelID(chanPoint)
+	switch err {
+	case nil:
+		return resp, nil
+
+	case channeldb.ErrEdgeNotFound, channeldb.ErrGraphNoEdgesFound:
+		return nil, errChanIDNotFound
+
+	default:
+		return nil, err
+	}
+}
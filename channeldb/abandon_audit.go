@@ -0,0 +1,116 @@
+package channeldb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
+
+var (
+	// abandonAuditBucket is the bucket that stores an audit entry for
+	// every channel that was manually abandoned. Each key within the
+	// bucket is the big endian timestamp of the abandonment in
+	// nanoseconds followed by the channel point, so that entries are
+	// iterated in chronological order.
+	abandonAuditBucket = []byte("abandon-audit")
+)
+
+// AbandonAuditEntry records the manual abandonment of a channel.
+type AbandonAuditEntry struct {
+	// ChanPoint is the funding outpoint of the abandoned channel.
+	ChanPoint wire.OutPoint
+
+	// Timestamp is the time the channel was abandoned.
+	Timestamp time.Time
+
+	// BestHeight is the best known block height at the time the channel
+	// was abandoned.
+	BestHeight uint32
+
+	// WasOpen is true if the channel was still in the set of open
+	// channels when it was abandoned.
+	WasOpen bool
+
+	// PendingFundingShimOnly is true if the abandonment was restricted to
+	// externally funded channels that are still pending.
+	PendingFundingShimOnly bool
+}
+
+// AddAbandonAuditEntry records the given abandonment in the audit log.
+func (d *DB) AddAbandonAuditEntry(entry *AbandonAuditEntry) error {
+	var key bytes.Buffer
+	var timestamp [8]byte
+	binary.BigEndian.PutUint64(
+		timestamp[:], uint64(entry.Timestamp.UnixNano()),
+	)
+	if _, err := key.Write(timestamp[:]); err != nil {
+		return err
+	}
+	if err := writeOutpoint(&key, &entry.ChanPoint); err != nil {
+		return err
+	}
+
+	var value bytes.Buffer
+	err := WriteElements(
+		&value, entry.BestHeight, entry.WasOpen,
+		entry.PendingFundingShimOnly,
+	)
+	if err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		audit, err := tx.CreateTopLevelBucket(abandonAuditBucket)
+		if err != nil {
+			return err
+		}
+
+		return audit.Put(key.Bytes(), value.Bytes())
+	})
+}
+
+// FetchAbandonAuditLog returns all the entries of the abandonment audit log
+// in chronological order.
+func (d *DB) FetchAbandonAuditLog() ([]*AbandonAuditEntry, error) {
+	var entries []*AbandonAuditEntry
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		audit := tx.ReadBucket(abandonAuditBucket)
+		if audit == nil {
+			return nil
+		}
+
+		return audit.ForEach(func(k, v []byte) error {
+			entry := &AbandonAuditEntry{
+				Timestamp: time.Unix(
+					0, int64(binary.BigEndian.Uint64(k[:8])),
+				),
+			}
+
+			err := readOutpoint(
+				bytes.NewReader(k[8:]), &entry.ChanPoint,
+			)
+			if err != nil {
+				return err
+			}
+
+			err = ReadElements(
+				bytes.NewReader(v), &entry.BestHeight,
+				&entry.WasOpen, &entry.PendingFundingShimOnly,
+			)
+			if err != nil {
+				return err
+			}
+
+			entries = append(entries, entry)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,55 @@
+package channeldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAbandonAuditLog tests that abandonment audit entries are stored and
+// fetched back in chronological order.
+func TestAbandonAuditLog(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	entries, err := db.FetchAbandonAuditLog()
+	require.NoError(t, err)
+	require.Empty(t, entries)
+
+	now := time.Unix(0, time.Now().UnixNano())
+	later := &AbandonAuditEntry{
+		ChanPoint:  wire.OutPoint{Hash: chainhash.Hash{1}, Index: 1},
+		Timestamp:  now.Add(time.Minute),
+		BestHeight: 101,
+	}
+	earlier := &AbandonAuditEntry{
+		ChanPoint:              wire.OutPoint{Hash: chainhash.Hash{2}},
+		Timestamp:              now,
+		BestHeight:             100,
+		WasOpen:                true,
+		PendingFundingShimOnly: true,
+	}
+	require.NoError(t, db.AddAbandonAuditEntry(later))
+	require.NoError(t, db.AddAbandonAuditEntry(earlier))
+
+	entries, err = db.FetchAbandonAuditLog()
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+
+	for i, expected := range []*AbandonAuditEntry{earlier, later} {
+		require.Equal(t, expected.ChanPoint, entries[i].ChanPoint)
+		require.True(t, expected.Timestamp.Equal(entries[i].Timestamp))
+		require.Equal(t, expected.BestHeight, entries[i].BestHeight)
+		require.Equal(t, expected.WasOpen, entries[i].WasOpen)
+		require.Equal(
+			t, expected.PendingFundingShimOnly,
+			entries[i].PendingFundingShimOnly,
+		)
+	}
+}
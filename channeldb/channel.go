@@ -19,6 +19,7 @@ import (
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
 	"github.com/decred/dcrlnd/lnwire"
 	"github.com/decred/dcrlnd/shachain"
 )
@@ -100,6 +101,11 @@ var (
 	// broadcasted when moving the channel to state CoopBroadcasted.
 	coopCloseTxKey = []byte("coop-closing-tx-key")
 
+	// shutdownInfoKey points to the parameters of the cooperative close
+	// negotiation of the channel, stored once we've sent our shutdown
+	// message.
+	shutdownInfoKey = []byte("shutdown-info-key")
+
 	// commitDiffKey stores the current pending commitment state we've
 	// extended to the remote party (if any). Each time we propose a new
 	// state, we store the information necessary to reconstruct this state
@@ -154,6 +160,10 @@ var (
 	// in the state CommitBroadcasted.
 	ErrNoCloseTx = fmt.Errorf("no closing tx found")
 
+	// ErrNoShutdownInfo is returned when no cooperative close negotiation
+	// parameters are found for a channel.
+	ErrNoShutdownInfo = fmt.Errorf("no shutdown info found")
+
 	// ErrNoRestoredChannelMutation is returned when a caller attempts to
 	// mutate a channel that's been recovered.
 	ErrNoRestoredChannelMutation = fmt.Errorf("cannot mutate restored " +
@@ -1145,6 +1155,102 @@ func (c *OpenChannel) BroadcastedCooperative() (*wire.MsgTx, error) {
 	return c.getClosingTx(coopCloseTxKey)
 }
 
+// ShutdownInfo holds the parameters of a cooperative close negotiation, which
+// are needed to resume it if it's interrupted before a closing transaction is
+// agreed on.
+type ShutdownInfo struct {
+	// DeliveryScript is the script we sent in our shutdown message, which
+	// must be reused when the negotiation is resumed.
+	DeliveryScript lnwire.DeliveryAddress
+
+	// LocalInitiator is true if we initiated the cooperative close.
+	LocalInitiator bool
+
+	// FeePerKB is the ideal fee rate of the closing transaction that we
+	// started the negotiation with.
+	FeePerKB chainfee.AtomPerKByte
+
+	// MaxFeePerKB is the highest fee rate we agreed to pay for the closing
+	// transaction. A value of zero means there is no limit.
+	MaxFeePerKB chainfee.AtomPerKByte
+}
+
+// MarkShutdownSent stores the parameters of the cooperative close negotiation
+// of the channel once we've sent our shutdown message, so that the negotiation
+// can be resumed if it's interrupted.
+func (c *OpenChannel) MarkShutdownSent(info *ShutdownInfo) error {
+	c.Lock()
+	defer c.Unlock()
+
+	var b bytes.Buffer
+	err := WriteElements(
+		&b, []byte(info.DeliveryScript), info.LocalInitiator,
+		uint64(info.FeePerKB), uint64(info.MaxFeePerKB),
+	)
+	if err != nil {
+		return err
+	}
+
+	return kvdb.Update(c.Db, func(tx kvdb.RwTx) error {
+		chanBucket, err := fetchChanBucketRw(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		if err != nil {
+			return err
+		}
+
+		return chanBucket.Put(shutdownInfoKey, b.Bytes())
+	})
+}
+
+// ShutdownInfo returns the parameters of the cooperative close negotiation of
+// the channel stored by MarkShutdownSent. If not found ErrNoShutdownInfo is
+// returned.
+func (c *OpenChannel) ShutdownInfo() (*ShutdownInfo, error) {
+	var info *ShutdownInfo
+	err := kvdb.View(c.Db, func(tx kvdb.RTx) error {
+		chanBucket, err := fetchChanBucket(
+			tx, c.IdentityPub, &c.FundingOutpoint, c.ChainHash,
+		)
+		switch err {
+		case nil:
+		case ErrNoChanDBExists, ErrNoActiveChannels, ErrChannelNotFound:
+			return ErrNoShutdownInfo
+		default:
+			return err
+		}
+
+		bs := chanBucket.Get(shutdownInfoKey)
+		if bs == nil {
+			return ErrNoShutdownInfo
+		}
+
+		var (
+			script                []byte
+			feePerKB, maxFeePerKB uint64
+		)
+		info = &ShutdownInfo{}
+		err = ReadElements(
+			bytes.NewReader(bs), &script, &info.LocalInitiator,
+			&feePerKB, &maxFeePerKB,
+		)
+		if err != nil {
+			return err
+		}
+
+		info.DeliveryScript = script
+		info.FeePerKB = chainfee.AtomPerKByte(feePerKB)
+		info.MaxFeePerKB = chainfee.AtomPerKByte(maxFeePerKB)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
 // getClosingTx is a helper method which returns the stored closing transaction
 // for key. The caller should use either the force or coop closing keys.
 func (c *OpenChannel) getClosingTx(key []byte) (*wire.MsgTx, error) {
@@ -2662,6 +2768,11 @@ type ChannelCloseSummary struct {
 	// LastChanSyncMsg is the ChannelReestablish message for this channel
 	// for the state at the point where it was closed.
 	LastChanSyncMsg *lnwire.ChannelReestablish
+
+	// CloseReason is the latest recorded reason for which the channel was
+	// closed, if any. It's stored separately from the rest of the summary
+	// as it's usually known before the channel is closed.
+	CloseReason *ChannelCloseReason
 }
 
 // CloseChannel closes a previously active Lightning channel. Closing a channel
@@ -2931,7 +3042,11 @@ func putChannelCloseSummary(tx kvdb.RwTx, chanID []byte,
 		return err
 	}
 
-	return closedChanBucket.Put(chanID, b.Bytes())
+	if err := closedChanBucket.Put(chanID, b.Bytes()); err != nil {
+		return err
+	}
+
+	return putClosedChanScidIndex(tx, summary.ShortChanID, chanID)
 }
 
 func serializeChannelCloseSummary(w io.Writer, cs *ChannelCloseSummary) error {
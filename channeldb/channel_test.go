@@ -1043,6 +1043,50 @@ func TestFetchClosedChannels(t *testing.T) {
 	}
 }
 
+// TestUpdateCloseHeight asserts that the close height of a closed channel can
+// be updated after its closing transaction was reorged into another block.
+func TestUpdateCloseHeight(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	// Updating an unknown channel should fail.
+	state := createTestChannel(t, cdb, openChannelOption())
+	err = cdb.UpdateCloseHeight(&state.FundingOutpoint, 101)
+	if err != ErrClosedChannelNotFound {
+		t.Fatalf("expected ErrClosedChannelNotFound, got: %v", err)
+	}
+
+	summary := &ChannelCloseSummary{
+		ChanPoint:   state.FundingOutpoint,
+		ClosingTXID: rev,
+		RemotePub:   state.IdentityPub,
+		CloseHeight: 100,
+		CloseType:   CooperativeClose,
+	}
+	if err := state.CloseChannel(summary); err != nil {
+		t.Fatalf("unable to close channel: %v", err)
+	}
+
+	err = cdb.UpdateCloseHeight(&state.FundingOutpoint, 101)
+	if err != nil {
+		t.Fatalf("unable to update close height: %v", err)
+	}
+
+	closed, err := cdb.FetchClosedChannel(&state.FundingOutpoint)
+	if err != nil {
+		t.Fatalf("unable to fetch closed channel: %v", err)
+	}
+	if closed.CloseHeight != 101 {
+		t.Fatalf("expected close height 101, got %v",
+			closed.CloseHeight)
+	}
+}
+
 // TestFetchWaitingCloseChannels ensures that the correct channels that are
 // waiting to be closed are returned.
 func TestFetchWaitingCloseChannels(t *testing.T) {
@@ -1396,6 +1440,50 @@ func TestCloseChannelStatus(t *testing.T) {
 	}
 }
 
+// TestShutdownInfo tests that the parameters of a cooperative close
+// negotiation are stored and retrieved for a channel.
+func TestShutdownInfo(t *testing.T) {
+	cdb, cleanUp, err := MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	channel := createTestChannel(t, cdb, openChannelOption())
+
+	// No shutdown info should be found before it's stored.
+	if _, err := channel.ShutdownInfo(); err != ErrNoShutdownInfo {
+		t.Fatalf("expected ErrNoShutdownInfo, got: %v", err)
+	}
+
+	info := &ShutdownInfo{
+		DeliveryScript: []byte{0x76, 0xa9, 0x14},
+		LocalInitiator: true,
+		FeePerKB:       10000,
+		MaxFeePerKB:    50000,
+	}
+	if err := channel.MarkShutdownSent(info); err != nil {
+		t.Fatalf("unable to mark shutdown sent: %v", err)
+	}
+
+	// The stored info should be returned both by the channel and by a
+	// freshly fetched copy of it.
+	dbChan, err := cdb.FetchChannel(channel.FundingOutpoint)
+	if err != nil {
+		t.Fatalf("unable to fetch channel: %v", err)
+	}
+	for _, c := range []*OpenChannel{channel, dbChan} {
+		storedInfo, err := c.ShutdownInfo()
+		if err != nil {
+			t.Fatalf("unable to fetch shutdown info: %v", err)
+		}
+		if !reflect.DeepEqual(info, storedInfo) {
+			t.Fatalf("expected shutdown info %v, got %v",
+				spew.Sdump(info), spew.Sdump(storedInfo))
+		}
+	}
+}
+
 // TestBalanceAtHeight tests lookup of our local and remote balance at a given
 // height.
 func TestBalanceAtHeight(t *testing.T) {
@@ -0,0 +1,187 @@
+package channeldb
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
+
+var (
+	// closeReasonBucket is the bucket that stores the reason for which
+	// a channel is being closed. Each key within the bucket is the
+	// channel point of the channel, and the value its latest close
+	// reason.
+	closeReasonBucket = []byte("close-reason-bucket")
+)
+
+// CloseReasonCode describes the event that led to the closure of a channel.
+type CloseReasonCode uint8
+
+const (
+	// CloseReasonUnknown is used when the reason of a closure isn't
+	// known.
+	CloseReasonUnknown CloseReasonCode = 0
+
+	// CloseReasonShutdown indicates that a cooperative close of the
+	// channel was started with a shutdown message.
+	CloseReasonShutdown CloseReasonCode = 1
+
+	// CloseReasonPeerError indicates that the peer sent an error for the
+	// channel, after which it's expected to be force closed by the peer.
+	CloseReasonPeerError CloseReasonCode = 2
+
+	// CloseReasonLinkFailure indicates that we failed the channel because
+	// of an error severe enough to force close it.
+	CloseReasonLinkFailure CloseReasonCode = 3
+
+	// CloseReasonForceClose indicates that a force close of the channel
+	// was requested.
+	CloseReasonForceClose CloseReasonCode = 4
+
+	// CloseReasonBreach indicates that the remote party broadcast a
+	// revoked commitment of the channel.
+	CloseReasonBreach CloseReasonCode = 5
+)
+
+// String returns a human readable version of the close reason code.
+func (c CloseReasonCode) String() string {
+	switch c {
+	case CloseReasonUnknown:
+		return "Unknown"
+
+	case CloseReasonShutdown:
+		return "Shutdown"
+
+	case CloseReasonPeerError:
+		return "PeerError"
+
+	case CloseReasonLinkFailure:
+		return "LinkFailure"
+
+	case CloseReasonForceClose:
+		return "ForceClose"
+
+	case CloseReasonBreach:
+		return "Breach"
+
+	default:
+		return fmt.Sprintf("Unknown(%d)", uint8(c))
+	}
+}
+
+// ChannelCloseReason records why a channel is being closed.
+type ChannelCloseReason struct {
+	// Code is the kind of event that led to the closure.
+	Code CloseReasonCode
+
+	// RemoteInitiated is true if the closure was initiated by the remote
+	// party, either by sending a shutdown or an error message.
+	RemoteInitiated bool
+
+	// Message is the raw error message that was sent or received, if
+	// any.
+	Message string
+
+	// Timestamp is the time the reason was recorded.
+	Timestamp time.Time
+}
+
+// PutCloseReason stores the reason for which the channel with the given
+// channel point is being closed, replacing any previously stored reason. The
+// reason is returned as part of the channel's close summary.
+func (d *DB) PutCloseReason(chanPoint *wire.OutPoint,
+	reason *ChannelCloseReason) error {
+
+	var key bytes.Buffer
+	if err := writeOutpoint(&key, chanPoint); err != nil {
+		return err
+	}
+
+	var value bytes.Buffer
+	err := WriteElements(
+		&value, uint8(reason.Code), reason.RemoteInitiated,
+		uint64(reason.Timestamp.UnixNano()), []byte(reason.Message),
+	)
+	if err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		reasons, err := tx.CreateTopLevelBucket(closeReasonBucket)
+		if err != nil {
+			return err
+		}
+
+		return reasons.Put(key.Bytes(), value.Bytes())
+	})
+}
+
+// DeleteCloseReason removes the close reason stored for the channel with the
+// given channel point, if any. This is used once a channel resumes its normal
+// operation, as the reason no longer applies to it.
+func (d *DB) DeleteCloseReason(chanPoint *wire.OutPoint) error {
+	var key bytes.Buffer
+	if err := writeOutpoint(&key, chanPoint); err != nil {
+		return err
+	}
+
+	// Most channels have no close reason, so we avoid a write transaction
+	// in that case.
+	var found bool
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		reasons := tx.ReadBucket(closeReasonBucket)
+		found = reasons != nil && reasons.Get(key.Bytes()) != nil
+		return nil
+	})
+	if err != nil || !found {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		reasons := tx.ReadWriteBucket(closeReasonBucket)
+		if reasons == nil {
+			return nil
+		}
+
+		return reasons.Delete(key.Bytes())
+	})
+}
+
+// fetchCloseReason returns the close reason stored for the channel with the
+// given serialized channel point, or nil if there's none.
+func fetchCloseReason(tx kvdb.RTx, chanID []byte) (*ChannelCloseReason,
+	error) {
+
+	reasons := tx.ReadBucket(closeReasonBucket)
+	if reasons == nil {
+		return nil, nil
+	}
+
+	reasonBytes := reasons.Get(chanID)
+	if reasonBytes == nil {
+		return nil, nil
+	}
+
+	var (
+		code      uint8
+		timestamp uint64
+		message   []byte
+		reason    ChannelCloseReason
+	)
+	err := ReadElements(
+		bytes.NewReader(reasonBytes), &code, &reason.RemoteInitiated,
+		&timestamp, &message,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	reason.Code = CloseReasonCode(code)
+	reason.Message = string(message)
+	reason.Timestamp = time.Unix(0, int64(timestamp))
+
+	return &reason, nil
+}
@@ -0,0 +1,117 @@
+package channeldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCloseReason tests that the latest close reason stored for a channel is
+// returned along with its close summary.
+func TestCloseReason(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	state := createTestChannel(t, cdb, openChannelOption())
+	chanPoint := state.FundingOutpoint
+
+	// The reason can be stored before the channel is closed, and later
+	// reasons replace the earlier ones.
+	now := time.Unix(0, time.Now().UnixNano())
+	require.NoError(t, cdb.PutCloseReason(&chanPoint, &ChannelCloseReason{
+		Code:            CloseReasonShutdown,
+		RemoteInitiated: true,
+		Timestamp:       now,
+	}))
+	reason := &ChannelCloseReason{
+		Code:            CloseReasonPeerError,
+		RemoteInitiated: true,
+		Message:         "internal error",
+		Timestamp:       now.Add(time.Second),
+	}
+	require.NoError(t, cdb.PutCloseReason(&chanPoint, reason))
+
+	summary := &ChannelCloseSummary{
+		ChanPoint:       chanPoint,
+		ClosingTXID:     rev,
+		RemotePub:       state.IdentityPub,
+		Capacity:        state.Capacity,
+		CloseType:       RemoteForceClose,
+		IsPending:       true,
+		LocalChanConfig: state.LocalChanCfg,
+	}
+	require.NoError(t, state.CloseChannel(summary))
+
+	assertReason := func(summary *ChannelCloseSummary) {
+		t.Helper()
+
+		require.NotNil(t, summary.CloseReason)
+		require.Equal(t, reason.Code, summary.CloseReason.Code)
+		require.Equal(
+			t, reason.RemoteInitiated,
+			summary.CloseReason.RemoteInitiated,
+		)
+		require.Equal(t, reason.Message, summary.CloseReason.Message)
+		require.True(
+			t, reason.Timestamp.Equal(summary.CloseReason.Timestamp),
+		)
+	}
+
+	closed, err := cdb.FetchClosedChannels(false)
+	require.NoError(t, err)
+	require.Len(t, closed, 1)
+	assertReason(closed[0])
+
+	closedChan, err := cdb.FetchClosedChannel(&chanPoint)
+	require.NoError(t, err)
+	assertReason(closedChan)
+
+	closedChan, err = cdb.FetchClosedChannelForID(
+		lnwire.NewChanIDFromOutPoint(&chanPoint),
+	)
+	require.NoError(t, err)
+	assertReason(closedChan)
+}
+
+// TestDeleteCloseReason tests that a deleted close reason is no longer
+// returned with the close summary of the channel.
+func TestDeleteCloseReason(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	state := createTestChannel(t, cdb, openChannelOption())
+	chanPoint := state.FundingOutpoint
+
+	// Deleting a reason that was never stored is a no-op.
+	require.NoError(t, cdb.DeleteCloseReason(&chanPoint))
+
+	require.NoError(t, cdb.PutCloseReason(&chanPoint, &ChannelCloseReason{
+		Code:            CloseReasonPeerError,
+		RemoteInitiated: true,
+		Message:         "internal error",
+		Timestamp:       time.Now(),
+	}))
+	require.NoError(t, cdb.DeleteCloseReason(&chanPoint))
+
+	summary := &ChannelCloseSummary{
+		ChanPoint:       chanPoint,
+		ClosingTXID:     rev,
+		RemotePub:       state.IdentityPub,
+		Capacity:        state.Capacity,
+		CloseType:       CooperativeClose,
+		LocalChanConfig: state.LocalChanCfg,
+	}
+	require.NoError(t, state.CloseChannel(summary))
+
+	closedChan, err := cdb.FetchClosedChannel(&chanPoint)
+	require.NoError(t, err)
+	require.Nil(t, closedChan.CloseReason)
+}
@@ -0,0 +1,143 @@
+package channeldb
+
+import (
+	"bytes"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+var (
+	// closedChanScidIndexBucket is the bucket that indexes our closed
+	// channels by short channel ID. Each key within the bucket is the
+	// 8-byte short channel ID of a closed channel, and the value its
+	// channel point, which is the key of its summary within the
+	// closedChannelBucket.
+	closedChanScidIndexBucket = []byte("closed-chan-scid-index")
+)
+
+// putClosedChanScidIndex indexes the closed channel with the given serialized
+// channel point under its short channel ID. Channels that never confirmed
+// have no short channel ID and aren't indexed.
+func putClosedChanScidIndex(tx kvdb.RwTx, scid lnwire.ShortChannelID,
+	chanID []byte) error {
+
+	if scid.ToUint64() == 0 {
+		return nil
+	}
+
+	index, err := tx.CreateTopLevelBucket(closedChanScidIndexBucket)
+	if err != nil {
+		return err
+	}
+
+	var scidBytes [8]byte
+	byteOrder.PutUint64(scidBytes[:], scid.ToUint64())
+
+	return index.Put(scidBytes[:], chanID)
+}
+
+// FetchClosedChannelForShortChanID queries for a channel close summary using
+// the short channel ID of the channel in question.
+func (d *DB) FetchClosedChannelForShortChanID(
+	scid lnwire.ShortChannelID) (*ChannelCloseSummary, error) {
+
+	var chanSummary *ChannelCloseSummary
+	if err := kvdb.View(d, func(tx kvdb.RTx) error {
+		index := tx.ReadBucket(closedChanScidIndexBucket)
+		if index == nil {
+			return ErrClosedChannelNotFound
+		}
+		closeBucket := tx.ReadBucket(closedChannelBucket)
+		if closeBucket == nil {
+			return ErrClosedChannelNotFound
+		}
+
+		var scidBytes [8]byte
+		byteOrder.PutUint64(scidBytes[:], scid.ToUint64())
+
+		chanID := index.Get(scidBytes[:])
+		if chanID == nil {
+			return ErrClosedChannelNotFound
+		}
+
+		summaryBytes := closeBucket.Get(chanID)
+		if summaryBytes == nil {
+			return ErrClosedChannelNotFound
+		}
+
+		var err error
+		chanSummary, err = deserializeCloseChannelSummary(
+			bytes.NewReader(summaryBytes),
+		)
+		if err != nil {
+			return err
+		}
+
+		chanSummary.CloseReason, err = fetchCloseReason(tx, chanID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return chanSummary, nil
+}
+
+// migrateClosedChanScidIndex creates the index of our closed channels by short
+// channel ID and adds the channels closed so far to it. Only the channel point
+// and short channel ID leading every close summary are read, so the migration
+// doesn't depend on the rest of their serialization.
+func migrateClosedChanScidIndex(tx kvdb.RwTx) error {
+	if _, err := tx.CreateTopLevelBucket(
+		closedChanScidIndexBucket,
+	); err != nil {
+		return err
+	}
+
+	closeBucket := tx.ReadBucket(closedChannelBucket)
+	if closeBucket == nil {
+		return nil
+	}
+
+	type indexEntry struct {
+		scid   lnwire.ShortChannelID
+		chanID []byte
+	}
+	var entries []indexEntry
+	err := closeBucket.ForEach(func(k, v []byte) error {
+		if v == nil {
+			return nil
+		}
+
+		var (
+			chanPoint wire.OutPoint
+			scid      lnwire.ShortChannelID
+		)
+		err := ReadElements(bytes.NewReader(v), &chanPoint, &scid)
+		if err != nil {
+			return err
+		}
+
+		entries = append(entries, indexEntry{
+			scid:   scid,
+			chanID: append([]byte(nil), k...),
+		})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Indexing %d closed channels by short channel ID",
+		len(entries))
+
+	for _, entry := range entries {
+		err := putClosedChanScidIndex(tx, entry.scid, entry.chanID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,112 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// closeTestChannels opens and closes a channel for each of the given short
+// channel IDs, with a settled balance identifying it. The channel points of
+// the channels are returned.
+func closeTestChannels(t *testing.T, cdb *DB,
+	scids []lnwire.ShortChannelID) []wire.OutPoint {
+
+	var chanPoints []wire.OutPoint
+	for i, scid := range scids {
+		chanPoint := wire.OutPoint{Index: uint32(i)}
+		state := createTestChannel(
+			t, cdb, fundingPointOption(chanPoint),
+			channelIDOption(scid), openChannelOption(),
+		)
+
+		err := state.CloseChannel(&ChannelCloseSummary{
+			ChanPoint:      chanPoint,
+			ShortChanID:    scid,
+			RemotePub:      state.IdentityPub,
+			SettledBalance: dcrutil.Amount(500 + i),
+		})
+		require.NoError(t, err)
+
+		chanPoints = append(chanPoints, chanPoint)
+	}
+
+	return chanPoints
+}
+
+// assertClosedChanScidIndex asserts that the closed channels with the given
+// short channel IDs can be fetched by short channel ID.
+func assertClosedChanScidIndex(t *testing.T, cdb *DB,
+	scids []lnwire.ShortChannelID, chanPoints []wire.OutPoint) {
+
+	for i, scid := range scids {
+		summary, err := cdb.FetchClosedChannelForShortChanID(scid)
+		require.NoError(t, err)
+		require.Equal(t, chanPoints[i], summary.ChanPoint)
+		require.Equal(t, dcrutil.Amount(500+i), summary.SettledBalance)
+	}
+
+	_, err := cdb.FetchClosedChannelForShortChanID(
+		lnwire.ShortChannelID{BlockHeight: 999},
+	)
+	require.Equal(t, ErrClosedChannelNotFound, err)
+}
+
+// TestFetchClosedChannelForShortChanID tests that closed channels are indexed
+// by short channel ID as they're closed.
+func TestFetchClosedChannelForShortChanID(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	scids := []lnwire.ShortChannelID{
+		{BlockHeight: 100, TxIndex: 1},
+		{BlockHeight: 100, TxIndex: 2},
+		{BlockHeight: 200, TxIndex: 1, TxPosition: 1},
+	}
+	chanPoints := closeTestChannels(t, cdb, scids)
+
+	assertClosedChanScidIndex(t, cdb, scids, chanPoints)
+}
+
+// TestMigrateClosedChanScidIndex tests that the channels closed before the
+// index by short channel ID existed are added to it by the migration.
+func TestMigrateClosedChanScidIndex(t *testing.T) {
+	t.Parallel()
+
+	scids := []lnwire.ShortChannelID{
+		{BlockHeight: 100, TxIndex: 1},
+		{BlockHeight: 150, TxIndex: 3},
+	}
+	var chanPoints []wire.OutPoint
+
+	beforeMigration := func(cdb *DB) {
+		chanPoints = closeTestChannels(t, cdb, scids)
+
+		// Drop the index to mimic a database created before it.
+		err := kvdb.Update(cdb, func(tx kvdb.RwTx) error {
+			return tx.DeleteTopLevelBucket(
+				closedChanScidIndexBucket,
+			)
+		})
+		require.NoError(t, err)
+
+		_, err = cdb.FetchClosedChannelForShortChanID(scids[0])
+		require.Equal(t, ErrClosedChannelNotFound, err)
+	}
+
+	afterMigration := func(cdb *DB) {
+		assertClosedChanScidIndex(t, cdb, scids, chanPoints)
+	}
+
+	applyMigration(
+		t, beforeMigration, afterMigration, migrateClosedChanScidIndex,
+		false, false,
+	)
+}
@@ -164,6 +164,11 @@ var (
 			number:    17,
 			migration: mig.CreateTLB(closeSummaryBucket),
 		},
+		{
+			// Index our closed channels by short channel ID.
+			number:    18,
+			migration: migrateClosedChanScidIndex,
+		},
 	}
 
 	// Big endian is the preferred byte order, due to cursor scans over
@@ -181,6 +186,23 @@ type DB struct {
 	graph  *ChannelGraph
 	clock  clock.Clock
 	dryRun bool
+
+	migrationProgress func(MigrationProgress)
+}
+
+// MigrationProgress describes the progress of the migrations applied when
+// opening the database.
+type MigrationProgress struct {
+	// Version is the version of the migration currently being applied,
+	// or the final version of the database once all migrations have been
+	// applied.
+	Version uint32
+
+	// Applied is the number of migrations applied so far.
+	Applied int
+
+	// Total is the total number of migrations to apply.
+	Total int
 }
 
 // Update is a wrapper around walletdb.Update which calls into the extended
@@ -251,6 +273,8 @@ func CreateWithBackend(backend kvdb.Backend, modifiers ...OptionModifier) (*DB,
 		Backend: backend,
 		clock:   opts.clock,
 		dryRun:  opts.dryRun,
+
+		migrationProgress: opts.migrationProgress,
 	}
 	chanDB.graph = newChannelGraph(
 		chanDB, opts.RejectCacheSize, opts.ChannelCacheSize,
@@ -285,6 +309,7 @@ var topLevelBuckets = [][]byte{
 	graphMetaBucket,
 	metaBucket,
 	closeSummaryBucket,
+	closedChanScidIndexBucket,
 }
 
 // Wipe completely deletes all saved state within all used buckets within the
@@ -771,6 +796,11 @@ func (d *DB) FetchClosedChannels(pendingOnly bool) ([]*ChannelCloseSummary, erro
 				return nil
 			}
 
+			chanSummary.CloseReason, err = fetchCloseReason(tx, chanID)
+			if err != nil {
+				return err
+			}
+
 			chanSummaries = append(chanSummaries, chanSummary)
 			return nil
 		})
@@ -808,7 +838,11 @@ func (d *DB) FetchClosedChannel(chanID *wire.OutPoint) (*ChannelCloseSummary, er
 
 		summaryReader := bytes.NewReader(summaryBytes)
 		chanSummary, err = deserializeCloseChannelSummary(summaryReader)
+		if err != nil {
+			return err
+		}
 
+		chanSummary.CloseReason, err = fetchCloseReason(tx, b.Bytes())
 		return err
 	}); err != nil {
 		return nil, err
@@ -855,7 +889,8 @@ func (d *DB) FetchClosedChannelForID(cid lnwire.ChannelID) (
 				return err
 			}
 
-			return nil
+			chanSummary.CloseReason, err = fetchCloseReason(tx, op)
+			return err
 		}
 		return ErrClosedChannelNotFound
 	}); err != nil {
@@ -865,6 +900,48 @@ func (d *DB) FetchClosedChannelForID(cid lnwire.ChannelID) (
 	return chanSummary, nil
 }
 
+// UpdateCloseHeight updates the height at which the closing transaction of the
+// given closed channel confirmed. This is used when a reorg moved the closing
+// transaction into a different block.
+func (d *DB) UpdateCloseHeight(chanPoint *wire.OutPoint, height uint32) error {
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		var b bytes.Buffer
+		if err := writeOutpoint(&b, chanPoint); err != nil {
+			return err
+		}
+
+		chanID := b.Bytes()
+
+		closedChanBucket := tx.ReadWriteBucket(closedChannelBucket)
+		if closedChanBucket == nil {
+			return ErrClosedChannelNotFound
+		}
+
+		chanSummaryBytes := closedChanBucket.Get(chanID)
+		if chanSummaryBytes == nil {
+			return ErrClosedChannelNotFound
+		}
+
+		chanSummaryReader := bytes.NewReader(chanSummaryBytes)
+		chanSummary, err := deserializeCloseChannelSummary(
+			chanSummaryReader,
+		)
+		if err != nil {
+			return err
+		}
+
+		chanSummary.CloseHeight = height
+
+		var newSummary bytes.Buffer
+		err = serializeChannelCloseSummary(&newSummary, chanSummary)
+		if err != nil {
+			return err
+		}
+
+		return closedChanBucket.Put(chanID, newSummary.Bytes())
+	})
+}
+
 // MarkChanFullyClosed marks a channel as fully closed within the database. A
 // channel should be marked as fully closed if the channel was initially
 // cooperatively closed and it's reached a single confirmation, or after all
@@ -1159,21 +1236,46 @@ func (d *DB) syncVersions(versions []version) error {
 	migrations, migrationVersions := getMigrationsToApply(
 		versions, meta.DbVersionNumber,
 	)
+
+	// Count the migrations that will actually be executed so we can
+	// report the progress to the caller.
+	var numMigrations int
+	for _, migration := range migrations {
+		if migration != nil {
+			numMigrations++
+		}
+	}
+
 	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		var applied int
 		for i, migration := range migrations {
 			if migration == nil {
 				continue
 			}
 
-			log.Infof("Applying migration #%v", migrationVersions[i])
+			log.Infof("Applying migration #%v (%d of %d)",
+				migrationVersions[i], applied+1, numMigrations)
+
+			d.notifyMigrationProgress(MigrationProgress{
+				Version: migrationVersions[i],
+				Applied: applied,
+				Total:   numMigrations,
+			})
 
 			if err := migration(tx); err != nil {
 				log.Infof("Unable to apply migration #%v",
 					migrationVersions[i])
 				return err
 			}
+			applied++
 		}
 
+		d.notifyMigrationProgress(MigrationProgress{
+			Version: latestVersion,
+			Applied: applied,
+			Total:   numMigrations,
+		})
+
 		meta.DbVersionNumber = latestVersion
 		err := putMeta(meta, tx)
 		if err != nil {
@@ -1190,6 +1292,14 @@ func (d *DB) syncVersions(versions []version) error {
 	})
 }
 
+// notifyMigrationProgress notifies the migration progress callback, if any, of
+// the given progress.
+func (d *DB) notifyMigrationProgress(progress MigrationProgress) {
+	if d.migrationProgress != nil {
+		d.migrationProgress(progress)
+	}
+}
+
 // ChannelGraph returns a new instance of the directed channel graph.
 func (d *DB) ChannelGraph() *ChannelGraph {
 	return d.graph
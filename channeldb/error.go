@@ -51,6 +51,10 @@ var (
 	// multiple, distinct invoices.
 	ErrInvRefEquivocation = errors.New("inv ref matches multiple invoices")
 
+	// ErrInvRefNoPayHash is returned when an invoice is updated using an
+	// InvoiceRef that doesn't include the invoice's payment hash.
+	ErrInvRefNoPayHash = errors.New("inv ref missing payment hash")
+
 	// ErrNoPaymentsCreated is returned when bucket of payments hasn't been
 	// created.
 	ErrNoPaymentsCreated = fmt.Errorf("there are no existing payments")
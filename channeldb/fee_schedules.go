@@ -0,0 +1,222 @@
+package channeldb
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+var (
+	// feeScheduleBucket is the bucket that stores the fee schedules of
+	// our channels. The default schedule, applied to channels without
+	// their own, is stored under defaultFeeScheduleKey, while the
+	// schedules of specific channels are stored in the
+	// chanFeeScheduleBucket sub-bucket, keyed by channel point.
+	feeScheduleBucket = []byte("fee-schedules")
+
+	// defaultFeeScheduleKey is the key of the default fee schedule.
+	defaultFeeScheduleKey = []byte("default")
+
+	// chanFeeScheduleBucket is the sub-bucket of the fee schedules of
+	// specific channels.
+	chanFeeScheduleBucket = []byte("channels")
+
+	// ErrFeeScheduleNotFound is returned when deleting a fee schedule
+	// that doesn't exist.
+	ErrFeeScheduleNotFound = errors.New("fee schedule not found")
+)
+
+// FeeScheduleTier is a step of a fee schedule, setting the fees of channels
+// whose local balance is within a given range.
+type FeeScheduleTier struct {
+	// MinLocalPercent is the percentage of a channel's funds that must be
+	// on our side for the tier to apply. The tier applies until the
+	// minimum of the next tier is reached.
+	MinLocalPercent uint32
+
+	// BaseFee is the base fee set on the channel.
+	BaseFee lnwire.MilliAtom
+
+	// FeeRate is the proportional fee rate (in millionths) set on the
+	// channel.
+	FeeRate uint32
+}
+
+// FeeSchedule maps the balance of channels to the fees they charge.
+type FeeSchedule struct {
+	// Tiers are the steps of the schedule, sorted by increasing
+	// MinLocalPercent.
+	Tiers []FeeScheduleTier
+}
+
+// PutFeeSchedule stores the fee schedule of the given channel, or the default
+// schedule if chanPoint is nil, replacing any prior one.
+func (d *DB) PutFeeSchedule(chanPoint *wire.OutPoint,
+	schedule *FeeSchedule) error {
+
+	var b bytes.Buffer
+	if err := serializeFeeSchedule(&b, schedule); err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		schedules, err := tx.CreateTopLevelBucket(feeScheduleBucket)
+		if err != nil {
+			return err
+		}
+
+		if chanPoint == nil {
+			return schedules.Put(defaultFeeScheduleKey, b.Bytes())
+		}
+
+		chanSchedules, err := schedules.CreateBucketIfNotExists(
+			chanFeeScheduleBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		var k bytes.Buffer
+		if err := writeOutpoint(&k, chanPoint); err != nil {
+			return err
+		}
+
+		return chanSchedules.Put(k.Bytes(), b.Bytes())
+	})
+}
+
+// DeleteFeeSchedule removes the fee schedule of the given channel, or the
+// default schedule if chanPoint is nil. ErrFeeScheduleNotFound is returned if
+// there is no such schedule.
+func (d *DB) DeleteFeeSchedule(chanPoint *wire.OutPoint) error {
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		schedules := tx.ReadWriteBucket(feeScheduleBucket)
+		if schedules == nil {
+			return ErrFeeScheduleNotFound
+		}
+
+		if chanPoint == nil {
+			if schedules.Get(defaultFeeScheduleKey) == nil {
+				return ErrFeeScheduleNotFound
+			}
+			return schedules.Delete(defaultFeeScheduleKey)
+		}
+
+		chanSchedules := schedules.NestedReadWriteBucket(
+			chanFeeScheduleBucket,
+		)
+		if chanSchedules == nil {
+			return ErrFeeScheduleNotFound
+		}
+
+		var k bytes.Buffer
+		if err := writeOutpoint(&k, chanPoint); err != nil {
+			return err
+		}
+		if chanSchedules.Get(k.Bytes()) == nil {
+			return ErrFeeScheduleNotFound
+		}
+
+		return chanSchedules.Delete(k.Bytes())
+	})
+}
+
+// FetchFeeSchedules returns the default fee schedule, which is nil if there
+// is none, and the fee schedules of specific channels keyed by channel point.
+func (d *DB) FetchFeeSchedules() (*FeeSchedule,
+	map[wire.OutPoint]*FeeSchedule, error) {
+
+	var defaultSchedule *FeeSchedule
+	chanSchedules := make(map[wire.OutPoint]*FeeSchedule)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		schedules := tx.ReadBucket(feeScheduleBucket)
+		if schedules == nil {
+			return nil
+		}
+
+		if v := schedules.Get(defaultFeeScheduleKey); v != nil {
+			schedule, err := deserializeFeeSchedule(
+				bytes.NewReader(v),
+			)
+			if err != nil {
+				return err
+			}
+			defaultSchedule = schedule
+		}
+
+		chanBucket := schedules.NestedReadBucket(chanFeeScheduleBucket)
+		if chanBucket == nil {
+			return nil
+		}
+
+		return chanBucket.ForEach(func(k, v []byte) error {
+			var chanPoint wire.OutPoint
+			err := readOutpoint(bytes.NewReader(k), &chanPoint)
+			if err != nil {
+				return err
+			}
+
+			schedule, err := deserializeFeeSchedule(
+				bytes.NewReader(v),
+			)
+			if err != nil {
+				return err
+			}
+			chanSchedules[chanPoint] = schedule
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return defaultSchedule, chanSchedules, nil
+}
+
+// serializeFeeSchedule writes the tiers of a fee schedule, prefixed by their
+// number.
+func serializeFeeSchedule(w io.Writer, schedule *FeeSchedule) error {
+	if err := WriteElement(w, uint16(len(schedule.Tiers))); err != nil {
+		return err
+	}
+
+	for _, tier := range schedule.Tiers {
+		err := WriteElements(
+			w, tier.MinLocalPercent, tier.BaseFee, tier.FeeRate,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deserializeFeeSchedule reads a fee schedule written by
+// serializeFeeSchedule.
+func deserializeFeeSchedule(r io.Reader) (*FeeSchedule, error) {
+	var numTiers uint16
+	if err := ReadElement(r, &numTiers); err != nil {
+		return nil, err
+	}
+
+	schedule := &FeeSchedule{
+		Tiers: make([]FeeScheduleTier, numTiers),
+	}
+	for i := range schedule.Tiers {
+		tier := &schedule.Tiers[i]
+		err := ReadElements(
+			r, &tier.MinLocalPercent, &tier.BaseFee, &tier.FeeRate,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return schedule, nil
+}
@@ -0,0 +1,62 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestFeeSchedules tests that the default and per channel fee schedules can
+// be stored, replaced, fetched and deleted.
+func TestFeeSchedules(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	defaultSchedule, chanSchedules, err := db.FetchFeeSchedules()
+	require.NoError(t, err)
+	require.Nil(t, defaultSchedule)
+	require.Empty(t, chanSchedules)
+
+	require.Equal(t, ErrFeeScheduleNotFound, db.DeleteFeeSchedule(nil))
+
+	chanPoint := wire.OutPoint{Hash: chainhash.Hash{1}, Index: 2}
+	ramp := &FeeSchedule{
+		Tiers: []FeeScheduleTier{
+			{MinLocalPercent: 0, BaseFee: 1000, FeeRate: 2000},
+			{MinLocalPercent: 20, BaseFee: 1000, FeeRate: 500},
+			{MinLocalPercent: 80, BaseFee: 0, FeeRate: 10},
+		},
+	}
+	flat := &FeeSchedule{
+		Tiers: []FeeScheduleTier{
+			{MinLocalPercent: 0, BaseFee: 1, FeeRate: 1},
+		},
+	}
+
+	require.NoError(t, db.PutFeeSchedule(nil, flat))
+	require.NoError(t, db.PutFeeSchedule(nil, ramp))
+	require.NoError(t, db.PutFeeSchedule(&chanPoint, flat))
+
+	defaultSchedule, chanSchedules, err = db.FetchFeeSchedules()
+	require.NoError(t, err)
+	require.Equal(t, ramp, defaultSchedule)
+	require.Equal(t, map[wire.OutPoint]*FeeSchedule{
+		chanPoint: flat,
+	}, chanSchedules)
+
+	require.NoError(t, db.DeleteFeeSchedule(&chanPoint))
+	require.Equal(
+		t, ErrFeeScheduleNotFound, db.DeleteFeeSchedule(&chanPoint),
+	)
+	require.NoError(t, db.DeleteFeeSchedule(nil))
+
+	defaultSchedule, chanSchedules, err = db.FetchFeeSchedules()
+	require.NoError(t, err)
+	require.Nil(t, defaultSchedule)
+	require.Empty(t, chanSchedules)
+}
@@ -18,6 +18,19 @@ var (
 	// bucket is a timestamp (in nano seconds since the unix epoch), and
 	// the value a slice of a forwarding event for that timestamp.
 	forwardingLogBucket = []byte("circuit-fwd-log")
+
+	// forwardingHoldFeeBucket is the bucket that stores the hold fee paid
+	// by the events of the forwarding log, keyed by the same timestamp as
+	// the event itself. Only events that paid a hold fee are stored within
+	// this bucket.
+	forwardingHoldFeeBucket = []byte("circuit-fwd-log-hold-fee")
+
+	// forwardingFailedLogBucket is the bucket that stores the failed
+	// forwards that offered a hold fee, in the same format as the
+	// forwarding log. Any hold fee paid by such events is stored within
+	// the forwardingFailedHoldFeeBucket, keyed by the same timestamp.
+	forwardingFailedLogBucket     = []byte("circuit-fwd-log-failed")
+	forwardingFailedHoldFeeBucket = []byte("circuit-fwd-log-failed-hold-fee")
 )
 
 const (
@@ -78,6 +91,15 @@ type ForwardingEvent struct {
 	// AmtOut is the amount of the outgoing HTLC. Subtracting the incoming
 	// amount from this gives the total fees for this payment circuit.
 	AmtOut lnwire.MilliAtom
+
+	// HoldFee is the part of the total fees of this payment circuit that
+	// was paid as an experimental hold fee.
+	HoldFee lnwire.MilliAtom
+
+	// Failed is true if the payment circuit was failed instead of settled.
+	// Such events are only logged when the htlc offered a hold fee, and
+	// they are kept apart from the settled forwards.
+	Failed bool
 }
 
 // encodeForwardingEvent writes out the target forwarding event to the passed
@@ -121,10 +143,39 @@ func (f *ForwardingLog) AddForwardingEvents(events []ForwardingEvent) error {
 			return err
 		}
 
+		holdFeeBucket, err := tx.CreateTopLevelBucket(
+			forwardingHoldFeeBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		failedLogBucket, err := tx.CreateTopLevelBucket(
+			forwardingFailedLogBucket,
+		)
+		if err != nil {
+			return err
+		}
+
+		failedHoldFeeBucket, err := tx.CreateTopLevelBucket(
+			forwardingFailedHoldFeeBucket,
+		)
+		if err != nil {
+			return err
+		}
+
 		// With the bucket obtained, we can now begin to write out the
 		// series of events.
 		for _, event := range events {
-			err := storeEvent(logBucket, event, timestamp[:])
+			bucket, feeBucket := logBucket, holdFeeBucket
+			if event.Failed {
+				bucket = failedLogBucket
+				feeBucket = failedHoldFeeBucket
+			}
+
+			err := storeEvent(
+				bucket, feeBucket, event, timestamp[:],
+			)
 			if err != nil {
 				return err
 			}
@@ -137,9 +188,10 @@ func (f *ForwardingLog) AddForwardingEvents(events []ForwardingEvent) error {
 // storeEvent tries to store a forwarding event into the given bucket by trying
 // to avoid collisions. If a key for the event timestamp already exists in the
 // database, the timestamp is incremented in nanosecond intervals until a "free"
-// slot is found.
-func storeEvent(bucket walletdb.ReadWriteBucket, event ForwardingEvent,
-	timestampScratchSpace []byte) error {
+// slot is found. Any hold fee paid by the event is stored under the same
+// timestamp within the hold fee bucket.
+func storeEvent(bucket, holdFeeBucket walletdb.ReadWriteBucket,
+	event ForwardingEvent, timestampScratchSpace []byte) error {
 
 	// First, we'll serialize this timestamp into our
 	// timestamp buffer.
@@ -180,7 +232,19 @@ func storeEvent(bucket walletdb.ReadWriteBucket, event ForwardingEvent,
 	if err != nil {
 		return err
 	}
-	return bucket.Put(timestampScratchSpace, eventBuf.Bytes())
+	err = bucket.Put(timestampScratchSpace, eventBuf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	// The hold fee is stored separately, so that the format of the events
+	// themselves remains unchanged.
+	if event.HoldFee == 0 {
+		return nil
+	}
+	var holdFee [8]byte
+	byteOrder.PutUint64(holdFee[:], uint64(event.HoldFee))
+	return holdFeeBucket.Put(timestampScratchSpace, holdFee[:])
 }
 
 // ForwardingEventQuery represents a query to the forwarding log payment
@@ -200,6 +264,10 @@ type ForwardingEventQuery struct {
 
 	// NumMaxEvents is the max number of events to return.
 	NumMaxEvents uint32
+
+	// Failed selects the failed forwards that offered a hold fee instead
+	// of the settled forwards.
+	Failed bool
 }
 
 // ForwardingLogTimeSlice is the response to a forwarding query. It includes
@@ -241,11 +309,22 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 	err := kvdb.View(f.db, func(tx kvdb.RTx) error {
 		// If the bucket wasn't found, then there aren't any events to
 		// be returned.
-		logBucket := tx.ReadBucket(forwardingLogBucket)
+		logBucketKey, holdFeeBucketKey := forwardingLogBucket,
+			forwardingHoldFeeBucket
+		if q.Failed {
+			logBucketKey = forwardingFailedLogBucket
+			holdFeeBucketKey = forwardingFailedHoldFeeBucket
+		}
+
+		logBucket := tx.ReadBucket(logBucketKey)
 		if logBucket == nil {
 			return ErrNoForwardingEvents
 		}
 
+		// The hold fee bucket is missing from logs written before hold
+		// fees were recorded.
+		holdFeeBucket := tx.ReadBucket(holdFeeBucketKey)
+
 		// We'll be using a cursor to seek into the database, so we'll
 		// populate byte slices that represent the start of the key
 		// space we're interested in, and the end.
@@ -290,6 +369,15 @@ func (f *ForwardingLog) Query(q ForwardingEventQuery) (ForwardingLogTimeSlice, e
 				}
 
 				event.Timestamp = currentTime
+				event.Failed = q.Failed
+				if holdFeeBucket != nil {
+					holdFee := holdFeeBucket.Get(timestamp)
+					if len(holdFee) == 8 {
+						event.HoldFee = lnwire.MilliAtom(
+							byteOrder.Uint64(holdFee),
+						)
+					}
+				}
 				resp.ForwardingEvents = append(resp.ForwardingEvents, event)
 
 				recordOffset++
@@ -381,3 +381,127 @@ func TestForwardingLogStoreEvent(t *testing.T) {
 		}
 	}
 }
+
+// TestForwardingLogHoldFee asserts that the hold fee paid by forwarding events
+// is stored and returned when querying the forwarding log.
+func TestForwardingLogHoldFee(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test db: %v", err)
+	}
+	defer cleanUp()
+
+	log := ForwardingLog{
+		db: db,
+	}
+
+	// Only every other event pays a hold fee.
+	timestamp := time.Unix(1234, 0)
+	numEvents := 10
+	events := make([]ForwardingEvent, numEvents)
+	for i := 0; i < numEvents; i++ {
+		events[i] = ForwardingEvent{
+			Timestamp:      timestamp.Add(time.Duration(i) * time.Minute),
+			IncomingChanID: lnwire.NewShortChanIDFromInt(uint64(rand.Int63())),
+			OutgoingChanID: lnwire.NewShortChanIDFromInt(uint64(rand.Int63())),
+			AmtIn:          lnwire.MilliAtom(2000 + i),
+			AmtOut:         1000,
+		}
+		if i%2 == 0 {
+			events[i].HoldFee = lnwire.MilliAtom(i + 1)
+		}
+	}
+
+	if err := log.AddForwardingEvents(events); err != nil {
+		t.Fatalf("unable to add events: %v", err)
+	}
+
+	timeSlice, err := log.Query(ForwardingEventQuery{
+		StartTime:    timestamp,
+		EndTime:      timestamp.Add(time.Hour),
+		NumMaxEvents: 1000,
+	})
+	if err != nil {
+		t.Fatalf("unable to query for events: %v", err)
+	}
+
+	if !reflect.DeepEqual(events, timeSlice.ForwardingEvents) {
+		t.Fatalf("event mismatch: expected %v vs %v",
+			spew.Sdump(events), spew.Sdump(timeSlice.ForwardingEvents))
+	}
+}
+
+// TestForwardingLogFailedHoldFee asserts that failed forwards offering a hold
+// fee are kept apart from the settled forwards and can be queried separately.
+func TestForwardingLogFailedHoldFee(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test db: %v", err)
+	}
+	defer cleanUp()
+
+	log := ForwardingLog{
+		db: db,
+	}
+
+	// Every other event failed, and all of them offered a hold fee.
+	// Failed events share the timestamps of the settled ones to ensure
+	// both series don't collide.
+	timestamp := time.Unix(1234, 0)
+	numEvents := 10
+	var events, settled, failed []ForwardingEvent
+	for i := 0; i < numEvents; i++ {
+		event := ForwardingEvent{
+			Timestamp:      timestamp.Add(time.Duration(i/2) * time.Minute),
+			IncomingChanID: lnwire.NewShortChanIDFromInt(uint64(rand.Int63())),
+			OutgoingChanID: lnwire.NewShortChanIDFromInt(uint64(rand.Int63())),
+			AmtIn:          lnwire.MilliAtom(2000 + i),
+			AmtOut:         1000,
+			HoldFee:        lnwire.MilliAtom(i + 1),
+			Failed:         i%2 == 1,
+		}
+		events = append(events, event)
+	}
+
+	if err := log.AddForwardingEvents(events); err != nil {
+		t.Fatalf("unable to add events: %v", err)
+	}
+
+	// The timestamps of the events were made unique before being stored,
+	// so we split them afterwards.
+	for _, event := range events {
+		if event.Failed {
+			failed = append(failed, event)
+		} else {
+			settled = append(settled, event)
+		}
+	}
+
+	query := ForwardingEventQuery{
+		StartTime:    timestamp,
+		EndTime:      timestamp.Add(time.Hour),
+		NumMaxEvents: 1000,
+	}
+	timeSlice, err := log.Query(query)
+	if err != nil {
+		t.Fatalf("unable to query for events: %v", err)
+	}
+	if !reflect.DeepEqual(settled, timeSlice.ForwardingEvents) {
+		t.Fatalf("event mismatch: expected %v vs %v",
+			spew.Sdump(settled), spew.Sdump(timeSlice.ForwardingEvents))
+	}
+
+	query.Failed = true
+	timeSlice, err = log.Query(query)
+	if err != nil {
+		t.Fatalf("unable to query for failed events: %v", err)
+	}
+	if !reflect.DeepEqual(failed, timeSlice.ForwardingEvents) {
+		t.Fatalf("failed event mismatch: expected %v vs %v",
+			spew.Sdump(failed), spew.Sdump(timeSlice.ForwardingEvents))
+	}
+}
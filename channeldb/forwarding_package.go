@@ -912,6 +912,92 @@ func (p *ChannelPackager) RemovePkg(tx kvdb.RwTx, height uint64) error {
 	return sourceBkt.DeleteNestedBucket(heightKey[:])
 }
 
+// FwdPkgCompaction summarizes the forwarding packages removed by a call to
+// CompactFwdPkgs.
+type FwdPkgCompaction struct {
+	// NumFwdPkgs is the number of forwarding packages that were removed.
+	NumFwdPkgs uint64
+
+	// NumBytes is the total size of the keys and values that were removed.
+	NumBytes uint64
+}
+
+// CompactFwdPkgs removes all forwarding packages of the passed closed
+// channels regardless of their state. These can no longer be processed by a
+// link, so callers must ensure the channels were closed long enough ago for
+// any HTLCs to have been resolved on-chain. The completed packages of open
+// channels are left to their links, which garbage collect them.
+func (d *DB) CompactFwdPkgs(closedChans map[lnwire.ShortChannelID]struct{}) (
+	*FwdPkgCompaction, error) {
+
+	compaction := &FwdPkgCompaction{}
+	err := kvdb.Update(d, func(tx kvdb.RwTx) error {
+		fwdPkgBkt := tx.ReadWriteBucket(fwdPackagesKey)
+		if fwdPkgBkt == nil {
+			return nil
+		}
+
+		for source := range closedChans {
+			sourceKey := makeLogKey(source.ToUint64())
+			sourceBkt := fwdPkgBkt.NestedReadWriteBucket(sourceKey[:])
+			if sourceBkt == nil {
+				continue
+			}
+
+			numPkgs, numBytes, err := bucketSize(sourceBkt)
+			if err != nil {
+				return err
+			}
+			compaction.NumFwdPkgs += numPkgs
+			compaction.NumBytes += numBytes + uint64(len(sourceKey))
+
+			err = fwdPkgBkt.DeleteNestedBucket(sourceKey[:])
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return compaction, nil
+}
+
+// bucketSize returns the number of direct entries of the given bucket, along
+// with the total size of all keys and values stored in it and its nested
+// buckets.
+func bucketSize(bkt kvdb.RBucket) (uint64, uint64, error) {
+	var numEntries, numBytes uint64
+	err := bkt.ForEach(func(k, v []byte) error {
+		numEntries++
+		numBytes += uint64(len(k) + len(v))
+
+		if v != nil {
+			return nil
+		}
+
+		nestedBkt := bkt.NestedReadBucket(k)
+		if nestedBkt == nil {
+			return nil
+		}
+		_, nestedBytes, err := bucketSize(nestedBkt)
+		if err != nil {
+			return err
+		}
+		numBytes += nestedBytes
+
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return numEntries, numBytes, nil
+}
+
 // uint16Key writes the provided 16-bit unsigned integer to a 2-byte slice.
 func uint16Key(i uint16) []byte {
 	key := make([]byte, 2)
@@ -729,6 +729,94 @@ func TestPackagerSettleFailsThenAdds(t *testing.T) {
 	}
 }
 
+// TestCompactFwdPkgs asserts that compacting the forwarding packages removes
+// all packages of closed channels, while leaving the packages of open
+// channels untouched, as those are garbage collected by their links.
+func TestCompactFwdPkgs(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test db: %v", err)
+	}
+	defer cleanUp()
+
+	openChanID := lnwire.NewShortChanIDFromInt(1)
+	closedChanID := lnwire.NewShortChanIDFromInt(2)
+	openPackager := channeldb.NewChannelPackager(openChanID)
+	closedPackager := channeldb.NewChannelPackager(closedChanID)
+
+	// The open channel has a completed package without any htlcs at height
+	// 0 and an incomplete one with an unacked add at height 1. The closed
+	// channel has an incomplete package as well.
+	completedPkg := channeldb.NewFwdPkg(openChanID, 0, nil, nil)
+	openPkg := channeldb.NewFwdPkg(openChanID, 1, adds[:1], nil)
+	closedPkg := channeldb.NewFwdPkg(closedChanID, 0, adds[:1], nil)
+	if err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		err := openPackager.AddFwdPkg(tx, completedPkg)
+		if err != nil {
+			return err
+		}
+		err = openPackager.SetFwdFilter(
+			tx, completedPkg.Height, completedPkg.FwdFilter,
+		)
+		if err != nil {
+			return err
+		}
+		if err := openPackager.AddFwdPkg(tx, openPkg); err != nil {
+			return err
+		}
+
+		return closedPackager.AddFwdPkg(tx, closedPkg)
+	}); err != nil {
+		t.Fatalf("unable to add fwd pkgs: %v", err)
+	}
+
+	closedChans := map[lnwire.ShortChannelID]struct{}{
+		closedChanID: {},
+	}
+	compaction, err := db.CompactFwdPkgs(closedChans)
+	if err != nil {
+		t.Fatalf("unable to compact fwd pkgs: %v", err)
+	}
+	if compaction.NumFwdPkgs != 1 {
+		t.Fatalf("expected 1 removed fwd pkg, got %d",
+			compaction.NumFwdPkgs)
+	}
+	if compaction.NumBytes == 0 {
+		t.Fatalf("expected reclaimed bytes to be reported")
+	}
+
+	// Both packages of the open channel should remain, including the
+	// completed one.
+	fwdPkgs := loadFwdPkgs(t, db, openPackager)
+	if len(fwdPkgs) != 2 {
+		t.Fatalf("expected 2 fwdpkgs, instead found %d", len(fwdPkgs))
+	}
+
+	// The link can still remove its completed package.
+	if err := kvdb.Update(db, func(tx kvdb.RwTx) error {
+		return openPackager.RemovePkg(tx, completedPkg.Height)
+	}); err != nil {
+		t.Fatalf("unable to remove completed fwd pkg: %v", err)
+	}
+
+	fwdPkgs = loadFwdPkgs(t, db, closedPackager)
+	if len(fwdPkgs) != 0 {
+		t.Fatalf("no forwarding packages should exist, found %d",
+			len(fwdPkgs))
+	}
+
+	// A second compaction has nothing left to remove.
+	compaction, err = db.CompactFwdPkgs(closedChans)
+	if err != nil {
+		t.Fatalf("unable to compact fwd pkgs: %v", err)
+	}
+	if compaction.NumFwdPkgs != 0 || compaction.NumBytes != 0 {
+		t.Fatalf("expected empty compaction, got %v", compaction)
+	}
+}
+
 // assertFwdPkgState checks the current state of a fwdpkg meets our
 // expectations.
 func assertFwdPkgState(t *testing.T, fwdPkg *channeldb.FwdPkg,
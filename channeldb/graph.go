@@ -131,6 +131,17 @@ var (
 	// the need to iterate all over the graph.
 	disabledEdgePolicyBucket = []byte("disabled-edge-policy-index")
 
+	// edgePolicyHistoryBucket is a sub-bucket of the main edgeBucket
+	// bucket which records the routing policy changes of each directed
+	// edge. Each entry exists within the bucket as follows:
+	//
+	// maps: <chanID><direction><updateTime> -> policy snapshot
+	//
+	// Only updates that change the fees, time lock delta, HTLC limits or
+	// disabled flag of a policy are recorded, and the number of entries
+	// kept per direction is bounded by MaxPolicyHistoryEntries.
+	edgePolicyHistoryBucket = []byte("edge-policy-history")
+
 	// graphMetaBucket is a top-level bucket which stores various meta-deta
 	// related to the on-disk channel graph. Data stored in this bucket
 	// includes the block to which the graph has been synced to, the total
@@ -1345,6 +1356,52 @@ func getChanID(tx kvdb.RTx, chanPoint *wire.OutPoint) (uint64, error) {
 	return chanID, nil
 }
 
+// ChannelPoint attempts to lookup the channel point of the channel with the
+// passed channel ID (lnwire.ChannelID). If no such channel exists within the
+// database, then ErrEdgeNotFound is returned.
+func (c *ChannelGraph) ChannelPoint(cid lnwire.ChannelID) (*wire.OutPoint,
+	error) {
+
+	var chanPoint *wire.OutPoint
+	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
+		edges := tx.ReadBucket(edgeBucket)
+		if edges == nil {
+			return ErrGraphNoEdgesFound
+		}
+		chanIndex := edges.NestedReadBucket(channelPointBucket)
+		if chanIndex == nil {
+			return ErrGraphNoEdgesFound
+		}
+
+		// The channel ID only differs from the txid of the channel
+		// point in its last two bytes, so we only need to scan the
+		// outpoints sharing the first 30 bytes with it.
+		prefix := cid[:30]
+		cursor := chanIndex.ReadCursor()
+		for k, _ := cursor.Seek(prefix); k != nil &&
+			bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+
+			var op wire.OutPoint
+			err := readOutpoint(bytes.NewReader(k), &op)
+			if err != nil {
+				return err
+			}
+
+			if cid.IsChanPoint(&op) {
+				chanPoint = &op
+				return nil
+			}
+		}
+
+		return ErrEdgeNotFound
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chanPoint, nil
+}
+
 // TODO(roasbeef): allow updates to use Batch?
 
 // HighestChanID returns the "highest" known channel ID in the channel graph.
@@ -1870,6 +1927,11 @@ func delChannelEdge(edges, edgeIndex, chanIndex, zombieIndex,
 	updateEdgePolicyDisabledIndex(edges, cid, false, false)
 	updateEdgePolicyDisabledIndex(edges, cid, true, false)
 
+	// The policy history of the edge is no longer of any use either.
+	if err := delPolicyHistory(edges, cid); err != nil {
+		return err
+	}
+
 	// With the edge data deleted, we can purge the information from the two
 	// edge indexes.
 	if err := edgeIndex.Delete(chanID); err != nil {
@@ -1991,6 +2053,19 @@ func updateEdgePolicy(tx kvdb.RwTx, edge *ChannelEdgePolicy) (bool, error) {
 		isUpdate1 = false
 	}
 
+	// Before overwriting the policy, record the update in the policy
+	// history if it changes the routing policy of the edge.
+	oldPolicy, err := fetchChanEdgePolicy(edges, chanID[:], fromNode, nodes)
+	if err != nil && err != ErrEdgeNotFound {
+		return false, err
+	}
+	if policyChanged(oldPolicy, edge) {
+		err := putPolicyHistoryEntry(edges, edge, !isUpdate1)
+		if err != nil {
+			return false, err
+		}
+	}
+
 	// Finally, with the direction of the edge being updated
 	// identified, we update the on-disk edge representation.
 	err = putChanEdgePolicy(edges, nodes, edge, fromNode, toNode)
@@ -431,6 +431,60 @@ func createEdge(height, txIndex uint32, txPosition uint16, outPointIndex uint32,
 	return edgeInfo, shortChanID
 }
 
+// TestChannelPointLookup tests that the channel point of a channel in the
+// graph can be looked up from its channel ID.
+func TestChannelPointLookup(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	defer cleanUp()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+
+	graph := db.ChannelGraph()
+
+	node1, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	node2, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+
+	// We'll add two channels funded by outputs of the same transaction,
+	// so that their channel IDs share the same prefix.
+	var chanPoints []wire.OutPoint
+	for i := uint32(0); i < 2; i++ {
+		edge, _ := createEdge(100, i, 0, i, node1, node2)
+		if err := graph.AddChannelEdge(&edge); err != nil {
+			t.Fatalf("unable to create channel edge: %v", err)
+		}
+		chanPoints = append(chanPoints, edge.ChannelPoint)
+	}
+
+	for _, chanPoint := range chanPoints {
+		chanPoint := chanPoint
+		cid := lnwire.NewChanIDFromOutPoint(&chanPoint)
+		op, err := graph.ChannelPoint(cid)
+		if err != nil {
+			t.Fatalf("unable to lookup channel point: %v", err)
+		}
+		if *op != chanPoint {
+			t.Fatalf("expected channel point %v, got %v",
+				chanPoint, op)
+		}
+	}
+
+	// Looking up a channel that isn't in the graph should fail.
+	unknown := wire.OutPoint{Hash: rev, Index: 2}
+	_, err = graph.ChannelPoint(lnwire.NewChanIDFromOutPoint(&unknown))
+	if err != ErrEdgeNotFound {
+		t.Fatalf("expected ErrEdgeNotFound, got: %v", err)
+	}
+}
+
 // TestDisconnectBlockAtHeight checks that the pruned state of the channel
 // database is what we expect after calling DisconnectBlockAtHeight.
 func TestDisconnectBlockAtHeight(t *testing.T) {
@@ -0,0 +1,298 @@
+package channeldb
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+var (
+	// htlcEventLogBucket is the bucket that stores the htlc event log.
+	// Each key within the bucket is a timestamp (in nano seconds since the
+	// unix epoch), and the value the serialized htlc event recorded at
+	// that time.
+	htlcEventLogBucket = []byte("htlc-event-log")
+)
+
+// HtlcEventLog returns an instance of the HtlcEventLog object backed by the
+// target database instance.
+func (d *DB) HtlcEventLog() *HtlcEventLog {
+	return &HtlcEventLog{
+		db: d,
+	}
+}
+
+// HtlcEventLog is a time series database of the htlc events (forwards, fails
+// and settles) observed by the switch. Unlike the forwarding log, which only
+// records successful forwards, it keeps every event so that the history of
+// our htlcs can be analyzed after the fact. The log is meant to be pruned to a
+// rolling window by its owner.
+type HtlcEventLog struct {
+	db *DB
+}
+
+// HtlcEventRecord is an entry of the htlc event log. The classification of
+// the event is opaque to the database and only used to filter queries, while
+// the event itself is stored as a serialized blob.
+type HtlcEventRecord struct {
+	// Timestamp is the time at which the event occurred.
+	Timestamp time.Time
+
+	// EventType classifies the htlc as part of a send, receive or forward.
+	EventType uint8
+
+	// EventKind classifies the event as a forward, fail or settle.
+	EventKind uint8
+
+	// IncomingChanID is the channel the htlc arrived on. It is zero for
+	// sends.
+	IncomingChanID lnwire.ShortChannelID
+
+	// OutgoingChanID is the channel the htlc left on. It is zero for
+	// receives.
+	OutgoingChanID lnwire.ShortChannelID
+
+	// Event is the serialized event.
+	Event []byte
+}
+
+// encodeHtlcEventRecord writes out the target htlc event record to the passed
+// io.Writer. Note that the timestamp isn't serialized as this will be the key
+// value within the bucket.
+func encodeHtlcEventRecord(w io.Writer, r *HtlcEventRecord) error {
+	return WriteElements(
+		w, r.EventType, r.EventKind, r.IncomingChanID,
+		r.OutgoingChanID, r.Event,
+	)
+}
+
+// decodeHtlcEventRecord decodes a htlc event record from the passed reader.
+// The timestamp is expected to be set by the caller.
+func decodeHtlcEventRecord(r io.Reader, record *HtlcEventRecord) error {
+	return ReadElements(
+		r, &record.EventType, &record.EventKind,
+		&record.IncomingChanID, &record.OutgoingChanID, &record.Event,
+	)
+}
+
+// AddHtlcEvents adds a series of htlc events to the log. Events that share a
+// timestamp with an existing event are shifted by a nanosecond until a free
+// slot is found.
+func (l *HtlcEventLog) AddHtlcEvents(records []HtlcEventRecord) error {
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+
+	return kvdb.Batch(l.db.Backend, func(tx kvdb.RwTx) error {
+		logBucket, err := tx.CreateTopLevelBucket(htlcEventLogBucket)
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			var b bytes.Buffer
+			err := encodeHtlcEventRecord(&b, &record)
+			if err != nil {
+				return err
+			}
+
+			var key [8]byte
+			timestamp := record.Timestamp.UnixNano()
+			byteOrder.PutUint64(key[:], uint64(timestamp))
+			for logBucket.Get(key[:]) != nil {
+				timestamp++
+				byteOrder.PutUint64(key[:], uint64(timestamp))
+			}
+
+			if err := logBucket.Put(key[:], b.Bytes()); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// HtlcEventQuery represents a query to the htlc event log. Zero values of the
+// filters match all events.
+type HtlcEventQuery struct {
+	// StartTime is the start time of the time slice.
+	StartTime time.Time
+
+	// EndTime is the end time of the time slice.
+	EndTime time.Time
+
+	// ChanID only matches events whose incoming or outgoing channel is
+	// the given channel.
+	ChanID lnwire.ShortChannelID
+
+	// EventType only matches events of the given type.
+	EventType uint8
+
+	// EventKind only matches events of the given kind.
+	EventKind uint8
+
+	// IndexOffset is the number of matching events to skip. This can be
+	// used to resume a previous query.
+	IndexOffset uint32
+
+	// NumMaxEvents is the max number of events to return.
+	NumMaxEvents uint32
+}
+
+// matches returns true if the record passes the filters of the query.
+func (q *HtlcEventQuery) matches(r *HtlcEventRecord) bool {
+	var zeroChanID lnwire.ShortChannelID
+	if q.ChanID != zeroChanID && r.IncomingChanID != q.ChanID &&
+		r.OutgoingChanID != q.ChanID {
+
+		return false
+	}
+
+	if q.EventType != 0 && r.EventType != q.EventType {
+		return false
+	}
+
+	return q.EventKind == 0 || r.EventKind == q.EventKind
+}
+
+// HtlcEventSlice is the response to a htlc event query.
+type HtlcEventSlice struct {
+	// Records is the set of events that answer the query.
+	Records []HtlcEventRecord
+
+	// LastIndexOffset is the offset of the last returned event amongst
+	// all matching events. It can be used as the index offset of a
+	// subsequent query to fetch the next page of events.
+	LastIndexOffset uint32
+}
+
+// Query returns the events of the log that match the given query, in
+// chronological order.
+func (l *HtlcEventLog) Query(q HtlcEventQuery) (*HtlcEventSlice, error) {
+	resp := &HtlcEventSlice{
+		LastIndexOffset: q.IndexOffset,
+	}
+	recordsToSkip := q.IndexOffset
+
+	err := kvdb.View(l.db, func(tx kvdb.RTx) error {
+		logBucket := tx.ReadBucket(htlcEventLogBucket)
+		if logBucket == nil {
+			return nil
+		}
+
+		var startTime, endTime [8]byte
+		byteOrder.PutUint64(startTime[:], uint64(q.StartTime.UnixNano()))
+		byteOrder.PutUint64(endTime[:], uint64(q.EndTime.UnixNano()))
+
+		cursor := logBucket.ReadCursor()
+		k, v := cursor.Seek(startTime[:])
+		for ; k != nil && bytes.Compare(k, endTime[:]) <= 0; k, v = cursor.Next() {
+			if uint32(len(resp.Records)) >= q.NumMaxEvents {
+				return nil
+			}
+
+			record := HtlcEventRecord{
+				Timestamp: time.Unix(
+					0, int64(byteOrder.Uint64(k)),
+				),
+			}
+			err := decodeHtlcEventRecord(
+				bytes.NewReader(v), &record,
+			)
+			if err != nil {
+				return err
+			}
+
+			if !q.matches(&record) {
+				continue
+			}
+
+			if recordsToSkip > 0 {
+				recordsToSkip--
+				continue
+			}
+
+			resp.Records = append(resp.Records, record)
+			resp.LastIndexOffset++
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// Prune removes the events recorded before the given time, along with the
+// oldest events in excess of maxEvents. A zero before time doesn't remove
+// events based on their age, and a zero maxEvents doesn't limit the number of
+// events. The number of removed events is returned.
+func (l *HtlcEventLog) Prune(before time.Time, maxEvents uint64) (uint64,
+	error) {
+
+	var numRemoved uint64
+	err := kvdb.Update(l.db, func(tx kvdb.RwTx) error {
+		logBucket := tx.ReadWriteBucket(htlcEventLogBucket)
+		if logBucket == nil {
+			return nil
+		}
+
+		var numEvents uint64
+		err := logBucket.ForEach(func(_, _ []byte) error {
+			numEvents++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		var excess uint64
+		if maxEvents != 0 && numEvents > maxEvents {
+			excess = numEvents - maxEvents
+		}
+
+		// The zero time can't be represented as a timestamp key, so it
+		// is mapped to the lowest key, which keeps events of any age.
+		var beforeKey [8]byte
+		if !before.IsZero() {
+			byteOrder.PutUint64(
+				beforeKey[:], uint64(before.UnixNano()),
+			)
+		}
+
+		// Collect the keys to remove first, as the bucket can't be
+		// modified while iterating over it.
+		var staleKeys [][]byte
+		cursor := logBucket.ReadCursor()
+		for k, _ := cursor.First(); k != nil; k, _ = cursor.Next() {
+			if uint64(len(staleKeys)) >= excess &&
+				bytes.Compare(k, beforeKey[:]) >= 0 {
+
+				break
+			}
+
+			staleKeys = append(staleKeys, append([]byte(nil), k...))
+		}
+
+		for _, k := range staleKeys {
+			if err := logBucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		numRemoved = uint64(len(staleKeys))
+
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return numRemoved, nil
+}
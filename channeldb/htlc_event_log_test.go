@@ -0,0 +1,111 @@
+package channeldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestHtlcEventLogQueryAndPrune tests that the events added to the htlc event
+// log can be queried with filters and pruned by age and count.
+func TestHtlcEventLogQueryAndPrune(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	log := db.HtlcEventLog()
+
+	chan1 := lnwire.NewShortChanIDFromInt(1)
+	chan2 := lnwire.NewShortChanIDFromInt(2)
+
+	// Add ten events, alternating between two kinds and channels. All
+	// events share the same timestamp to ensure they are stored under
+	// unique keys.
+	startTime := time.Unix(1000, 0)
+	records := make([]HtlcEventRecord, 10)
+	for i := range records {
+		records[i] = HtlcEventRecord{
+			Timestamp:      startTime,
+			EventType:      1,
+			EventKind:      uint8(1 + i%2),
+			IncomingChanID: chan1,
+			Event:          []byte{byte(i)},
+		}
+		if i%2 == 1 {
+			records[i].OutgoingChanID = chan2
+		}
+	}
+	require.NoError(t, log.AddHtlcEvents(records))
+
+	query := HtlcEventQuery{
+		StartTime:    startTime,
+		EndTime:      startTime.Add(time.Second),
+		NumMaxEvents: 100,
+	}
+	slice, err := log.Query(query)
+	require.NoError(t, err)
+	require.Len(t, slice.Records, 10)
+	require.Equal(t, uint32(10), slice.LastIndexOffset)
+
+	// Filtering by the outgoing channel only returns every other event.
+	query.ChanID = chan2
+	slice, err = log.Query(query)
+	require.NoError(t, err)
+	require.Len(t, slice.Records, 5)
+	for _, record := range slice.Records {
+		require.Equal(t, chan2, record.OutgoingChanID)
+		require.Equal(t, uint8(2), record.EventKind)
+	}
+
+	// Paginating returns the remaining matching events.
+	query.ChanID = lnwire.ShortChannelID{}
+	query.EventKind = 1
+	query.IndexOffset = 3
+	slice, err = log.Query(query)
+	require.NoError(t, err)
+	require.Len(t, slice.Records, 2)
+	require.Equal(t, []byte{6}, slice.Records[0].Event)
+	require.Equal(t, uint32(5), slice.LastIndexOffset)
+
+	// A filter that matches no events returns an empty response.
+	query.EventType = 2
+	query.IndexOffset = 0
+	slice, err = log.Query(query)
+	require.NoError(t, err)
+	require.Empty(t, slice.Records)
+
+	// Add a newer event, then prune by count, which should remove the
+	// oldest events only.
+	newEvent := HtlcEventRecord{
+		Timestamp: startTime.Add(time.Hour),
+		Event:     []byte{10},
+	}
+	require.NoError(t, log.AddHtlcEvents([]HtlcEventRecord{newEvent}))
+
+	// Pruning without an age or count limit removes nothing.
+	numRemoved, err := log.Prune(time.Time{}, 0)
+	require.NoError(t, err)
+	require.Zero(t, numRemoved)
+
+	numRemoved, err = log.Prune(startTime, 8)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), numRemoved)
+
+	// Pruning by age removes all events older than the newest one.
+	numRemoved, err = log.Prune(startTime.Add(time.Minute), 0)
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), numRemoved)
+
+	slice, err = log.Query(HtlcEventQuery{
+		StartTime:    startTime,
+		EndTime:      startTime.Add(2 * time.Hour),
+		NumMaxEvents: 100,
+	})
+	require.NoError(t, err)
+	require.Len(t, slice.Records, 1)
+	require.Equal(t, newEvent.Event, slice.Records[0].Event)
+}
@@ -1,6 +1,7 @@
 package channeldb
 
 import (
+	"bytes"
 	"crypto/rand"
 	"fmt"
 	"math"
@@ -1185,12 +1186,118 @@ func TestInvoiceRef(t *testing.T) {
 	// An InvoiceRef by hash should return the provided hash and a nil
 	// payment addr.
 	refByHash := InvoiceRefByHash(payHash)
-	require.Equal(t, payHash, refByHash.PayHash())
+	require.Equal(t, &payHash, refByHash.PayHash())
 	require.Equal(t, (*[32]byte)(nil), refByHash.PayAddr())
+	require.Equal(t, (*uint64)(nil), refByHash.AddIndex())
 
 	// An InvoiceRef by hash and addr should return the payment hash and
 	// payment addr passed to the constructor.
 	refByHashAndAddr := InvoiceRefByHashAndAddr(payHash, payAddr)
-	require.Equal(t, payHash, refByHashAndAddr.PayHash())
+	require.Equal(t, &payHash, refByHashAndAddr.PayHash())
 	require.Equal(t, &payAddr, refByHashAndAddr.PayAddr())
+	require.Equal(t, (*uint64)(nil), refByHashAndAddr.AddIndex())
+
+	// An InvoiceRef by addr should only return the payment addr.
+	refByAddr := InvoiceRefByAddr(payAddr)
+	require.Equal(t, (*lntypes.Hash)(nil), refByAddr.PayHash())
+	require.Equal(t, &payAddr, refByAddr.PayAddr())
+	require.Equal(t, (*uint64)(nil), refByAddr.AddIndex())
+
+	// An InvoiceRef by add index should only return the add index.
+	addIndex := uint64(3)
+	refByAddIndex := InvoiceRefByAddIndex(addIndex)
+	require.Equal(t, (*lntypes.Hash)(nil), refByAddIndex.PayHash())
+	require.Equal(t, (*[32]byte)(nil), refByAddIndex.PayAddr())
+	require.Equal(t, &addIndex, refByAddIndex.AddIndex())
+}
+
+// TestLookupInvoiceByRef asserts that invoices can be looked up by their
+// payment address or add index in addition to their payment hash.
+func TestLookupInvoiceByRef(t *testing.T) {
+	db, cleanUp, err := MakeTestDB()
+	defer cleanUp()
+	require.NoError(t, err)
+
+	// Add two random invoices.
+	invoice1, err := randInvoice(1000)
+	require.NoError(t, err)
+
+	inv1Hash := invoice1.Terms.PaymentPreimage.Hash()
+	addIndex1, err := db.AddInvoice(invoice1, inv1Hash)
+	require.NoError(t, err)
+
+	invoice2, err := randInvoice(2000)
+	require.NoError(t, err)
+
+	inv2Hash := invoice2.Terms.PaymentPreimage.Hash()
+	addIndex2, err := db.AddInvoice(invoice2, inv2Hash)
+	require.NoError(t, err)
+
+	// Each invoice should be retrievable by its add index.
+	dbInv1, err := db.LookupInvoice(InvoiceRefByAddIndex(addIndex1))
+	require.NoError(t, err)
+	require.Equal(t, invoice1, &dbInv1)
+
+	dbInv2, err := db.LookupInvoice(InvoiceRefByAddIndex(addIndex2))
+	require.NoError(t, err)
+	require.Equal(t, invoice2, &dbInv2)
+
+	// Each invoice should also be retrievable by its payment address alone.
+	dbInv1, err = db.LookupInvoice(
+		InvoiceRefByAddr(invoice1.Terms.PaymentAddr),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invoice1, &dbInv1)
+
+	dbInv2, err = db.LookupInvoice(
+		InvoiceRefByAddr(invoice2.Terms.PaymentAddr),
+	)
+	require.NoError(t, err)
+	require.Equal(t, invoice2, &dbInv2)
+
+	// Unknown add indexes and payment addresses should not be found.
+	_, err = db.LookupInvoice(InvoiceRefByAddIndex(addIndex2 + 1))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	_, err = db.LookupInvoice(InvoiceRefByAddr([32]byte{0x01}))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	// The blank payment address is never indexed, so it can't be used to
+	// find an invoice.
+	_, err = db.LookupInvoice(InvoiceRefByAddr(BlankPayAddr))
+	require.Equal(t, ErrInvoiceNotFound, err)
+
+	// Updates require the payment hash, so references without it should
+	// be rejected.
+	nop := func(_ *Invoice) (*InvoiceUpdateDesc, error) {
+		return nil, nil
+	}
+	_, err = db.UpdateInvoice(InvoiceRefByAddIndex(addIndex1), nop)
+	require.Equal(t, ErrInvRefNoPayHash, err)
+}
+
+// TestInvoiceHintChannels asserts that the hint channels of an invoice are
+// persisted and restrict the channels through which it may be paid.
+func TestInvoiceHintChannels(t *testing.T) {
+	t.Parallel()
+
+	invoice, err := randInvoice(1000)
+	require.NoError(t, err)
+
+	// Without hint channels, any channel is accepted.
+	require.True(t, invoice.AcceptsChannel(lnwire.NewShortChanIDFromInt(1)))
+
+	invoice.HintChannels = []lnwire.ShortChannelID{
+		lnwire.NewShortChanIDFromInt(1),
+		lnwire.NewShortChanIDFromInt(2),
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, serializeInvoice(&b, invoice))
+	decoded, err := deserializeInvoice(&b)
+	require.NoError(t, err)
+	require.Equal(t, invoice.HintChannels, decoded.HintChannels)
+
+	require.True(t, decoded.AcceptsChannel(lnwire.NewShortChanIDFromInt(2)))
+	require.False(t, decoded.AcceptsChannel(lnwire.NewShortChanIDFromInt(3)))
 }
@@ -157,18 +157,22 @@ const (
 	invStateType    tlv.Type = 12
 	amtPaidType     tlv.Type = 13
 	hodlInvoiceType tlv.Type = 14
+
+	// hintChansType is odd, so that versions unaware of it ignore the
+	// record rather than failing to decode the invoice.
+	hintChansType tlv.Type = 15
 )
 
 // InvoiceRef is a composite identifier for invoices. Invoices can be referenced
-// by various combinations of payment hash and payment addr, in certain contexts
-// only some of these are known. An InvoiceRef and its constructors thus
-// encapsulate the valid combinations of query parameters that can be supplied
-// to LookupInvoice and UpdateInvoice.
+// by various combinations of payment hash, payment addr and add index, in
+// certain contexts only some of these are known. An InvoiceRef and its
+// constructors thus encapsulate the valid combinations of query parameters that
+// can be supplied to LookupInvoice and UpdateInvoice.
 type InvoiceRef struct {
 	// payHash is the payment hash of the target invoice. All invoices are
 	// currently indexed by payment hash. This value will be used as a
 	// fallback when no payment address is known.
-	payHash lntypes.Hash
+	payHash *lntypes.Hash
 
 	// payAddr is the payment addr of the target invoice. Newer invoices
 	// (0.11 and up) are indexed by payment address in addition to payment
@@ -176,13 +180,19 @@ type InvoiceRef struct {
 	// known it will be used as the primary identifier, falling back to
 	// payHash if no value is known.
 	payAddr *[32]byte
+
+	// addIndex is the add index of the target invoice. Every invoice is
+	// indexed by its add index, so when this value is known it uniquely
+	// identifies the invoice. Add index references are mutually exclusive
+	// with the other identifiers.
+	addIndex *uint64
 }
 
 // InvoiceRefByHash creates an InvoiceRef that queries for an invoice only by
 // its payment hash.
 func InvoiceRefByHash(payHash lntypes.Hash) InvoiceRef {
 	return InvoiceRef{
-		payHash: payHash,
+		payHash: &payHash,
 	}
 }
 
@@ -193,14 +203,37 @@ func InvoiceRefByHashAndAddr(payHash lntypes.Hash,
 	payAddr [32]byte) InvoiceRef {
 
 	return InvoiceRef{
-		payHash: payHash,
+		payHash: &payHash,
+		payAddr: &payAddr,
+	}
+}
+
+// InvoiceRefByAddr creates an InvoiceRef that queries for an invoice only by
+// its payment address. Legacy invoices without a payment address can't be
+// found using this reference.
+func InvoiceRefByAddr(payAddr [32]byte) InvoiceRef {
+	return InvoiceRef{
 		payAddr: &payAddr,
 	}
 }
 
-// PayHash returns the target invoice's payment hash.
-func (r InvoiceRef) PayHash() lntypes.Hash {
-	return r.payHash
+// InvoiceRefByAddIndex creates an InvoiceRef that queries for an invoice only
+// by its add index.
+func InvoiceRefByAddIndex(addIndex uint64) InvoiceRef {
+	return InvoiceRef{
+		addIndex: &addIndex,
+	}
+}
+
+// PayHash returns the optional payment hash of the target invoice.
+//
+// NOTE: This value may be nil.
+func (r InvoiceRef) PayHash() *lntypes.Hash {
+	if r.payHash != nil {
+		hash := *r.payHash
+		return &hash
+	}
+	return nil
 }
 
 // PayAddr returns the optional payment address of the target invoice.
@@ -214,12 +247,30 @@ func (r InvoiceRef) PayAddr() *[32]byte {
 	return nil
 }
 
+// AddIndex returns the optional add index of the target invoice.
+//
+// NOTE: This value may be nil.
+func (r InvoiceRef) AddIndex() *uint64 {
+	if r.addIndex != nil {
+		addIndex := *r.addIndex
+		return &addIndex
+	}
+	return nil
+}
+
 // String returns a human-readable representation of an InvoiceRef.
 func (r InvoiceRef) String() string {
-	if r.payAddr != nil {
-		return fmt.Sprintf("(pay_hash=%v, pay_addr=%x)", r.payHash, *r.payAddr)
+	switch {
+	case r.addIndex != nil:
+		return fmt.Sprintf("(add_index=%v)", *r.addIndex)
+	case r.payHash != nil && r.payAddr != nil:
+		return fmt.Sprintf("(pay_hash=%v, pay_addr=%x)", *r.payHash,
+			*r.payAddr)
+	case r.payAddr != nil:
+		return fmt.Sprintf("(pay_addr=%x)", *r.payAddr)
+	default:
+		return fmt.Sprintf("(pay_hash=%v)", r.payHash)
 	}
-	return fmt.Sprintf("(pay_hash=%v)", r.payHash)
 }
 
 // ContractState describes the state the invoice is in.
@@ -357,6 +408,27 @@ type Invoice struct {
 	// HodlInvoice indicates whether the invoice should be held in the
 	// Accepted state or be settled right away.
 	HodlInvoice bool
+
+	// HintChannels are the channels of the route hints of the invoice
+	// through which the invoice must be paid. If empty, htlcs may arrive
+	// through any channel.
+	HintChannels []lnwire.ShortChannelID
+}
+
+// AcceptsChannel returns whether htlcs paying to the invoice may arrive
+// through the given channel.
+func (i *Invoice) AcceptsChannel(chanID lnwire.ShortChannelID) bool {
+	if len(i.HintChannels) == 0 {
+		return true
+	}
+
+	for _, hintChan := range i.HintChannels {
+		if hintChan == chanID {
+			return true
+		}
+	}
+
+	return false
 }
 
 // HtlcState defines the states an htlc paying to an invoice can be in.
@@ -650,11 +722,12 @@ func (d *DB) LookupInvoice(ref InvoiceRef) (Invoice, error) {
 			return ErrNoInvoicesCreated
 		}
 		payAddrIndex := tx.ReadBucket(payAddrIndexBucket)
+		addIndex := invoices.NestedReadBucket(addIndexBucket)
 
 		// Retrieve the invoice number for this invoice using the
 		// provided invoice reference.
 		invoiceNum, err := fetchInvoiceNumByRef(
-			invoiceIndex, payAddrIndex, ref,
+			invoiceIndex, payAddrIndex, addIndex, ref,
 		)
 		if err != nil {
 			return err
@@ -678,20 +751,42 @@ func (d *DB) LookupInvoice(ref InvoiceRef) (Invoice, error) {
 }
 
 // fetchInvoiceNumByRef retrieve the invoice number for the provided invoice
-// reference. The payment address will be treated as the primary key, falling
-// back to the payment hash if nothing is found for the payment address. An
-// error is returned if the invoice is not found.
-func fetchInvoiceNumByRef(invoiceIndex, payAddrIndex kvdb.RBucket,
+// reference. An add index reference is resolved exclusively through the add
+// index. Otherwise the payment address will be treated as the primary key,
+// falling back to the payment hash if nothing is found for the payment address.
+// An error is returned if the invoice is not found.
+func fetchInvoiceNumByRef(invoiceIndex, payAddrIndex, addIndex kvdb.RBucket,
 	ref InvoiceRef) ([]byte, error) {
 
+	// An add index uniquely identifies an invoice, so there's no need to
+	// consult any of the other indexes.
+	if addIdx := ref.AddIndex(); addIdx != nil {
+		if addIndex == nil {
+			return nil, ErrInvoiceNotFound
+		}
+
+		var addIndexKey [8]byte
+		byteOrder.PutUint64(addIndexKey[:], *addIdx)
+
+		invoiceNum := addIndex.Get(addIndexKey[:])
+		if invoiceNum == nil {
+			return nil, ErrInvoiceNotFound
+		}
+
+		return invoiceNum, nil
+	}
+
 	payHash := ref.PayHash()
 	payAddr := ref.PayAddr()
 
 	var (
-		invoiceNumByHash = invoiceIndex.Get(payHash[:])
+		invoiceNumByHash []byte
 		invoiceNumByAddr []byte
 	)
-	if payAddr != nil {
+	if payHash != nil {
+		invoiceNumByHash = invoiceIndex.Get(payHash[:])
+	}
+	if payAddr != nil && payAddrIndex != nil {
 		// Only allow lookups for payment address if it is not a blank
 		// payment address, which is a special-cased value for legacy
 		// keysend invoices.
@@ -717,6 +812,11 @@ func fetchInvoiceNumByRef(invoiceIndex, payAddrIndex kvdb.RBucket,
 	case invoiceNumByHash != nil:
 		return invoiceNumByHash, nil
 
+	// If the reference didn't include a payment hash, the invoice can still
+	// be located through its payment address alone.
+	case invoiceNumByAddr != nil && payHash == nil:
+		return invoiceNumByAddr, nil
+
 	// Otherwise we don't know of the target invoice.
 	default:
 		return nil, ErrInvoiceNotFound
@@ -957,19 +1057,26 @@ func (d *DB) UpdateInvoice(ref InvoiceRef,
 			return err
 		}
 		payAddrIndex := tx.ReadBucket(payAddrIndexBucket)
+		addIndex := invoices.NestedReadBucket(addIndexBucket)
 
 		// Retrieve the invoice number for this invoice using the
 		// provided invoice reference.
 		invoiceNum, err := fetchInvoiceNumByRef(
-			invoiceIndex, payAddrIndex, ref,
+			invoiceIndex, payAddrIndex, addIndex, ref,
 		)
 		if err != nil {
 			return err
 
 		}
+
+		// Invoice updates need the payment hash to validate any
+		// preimage being settled, so it must be part of the reference.
 		payHash := ref.PayHash()
+		if payHash == nil {
+			return ErrInvRefNoPayHash
+		}
 		updatedInvoice, err = d.updateInvoice(
-			payHash, invoices, settleIndex, invoiceNum,
+			*payHash, invoices, settleIndex, invoiceNum,
 			callback,
 		)
 
@@ -1150,6 +1257,11 @@ func serializeInvoice(w io.Writer, i *Invoice) error {
 		hodlInvoice = 1
 	}
 
+	hintChans := make([]byte, 8*len(i.HintChannels))
+	for j, chanID := range i.HintChannels {
+		byteOrder.PutUint64(hintChans[8*j:], chanID.ToUint64())
+	}
+
 	tlvStream, err := tlv.NewStream(
 		// Memo and payreq.
 		tlv.MakePrimitiveRecord(memoType, &i.Memo),
@@ -1174,6 +1286,7 @@ func serializeInvoice(w io.Writer, i *Invoice) error {
 		tlv.MakePrimitiveRecord(amtPaidType, &amtPaid),
 
 		tlv.MakePrimitiveRecord(hodlInvoiceType, &hodlInvoice),
+		tlv.MakePrimitiveRecord(hintChansType, &hintChans),
 	)
 	if err != nil {
 		return err
@@ -1280,6 +1393,7 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		creationDateBytes []byte
 		settleDateBytes   []byte
 		featureBytes      []byte
+		hintChans         []byte
 	)
 
 	var i Invoice
@@ -1307,6 +1421,7 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		tlv.MakePrimitiveRecord(amtPaidType, &amtPaid),
 
 		tlv.MakePrimitiveRecord(hodlInvoiceType, &hodlInvoice),
+		tlv.MakePrimitiveRecord(hintChansType, &hintChans),
 	)
 	if err != nil {
 		return i, err
@@ -1338,6 +1453,19 @@ func deserializeInvoice(r io.Reader) (Invoice, error) {
 		i.HodlInvoice = true
 	}
 
+	if len(hintChans)%8 != 0 {
+		return i, fmt.Errorf("invalid hint channels length %d",
+			len(hintChans))
+	}
+	for j := 0; j < len(hintChans); j += 8 {
+		i.HintChannels = append(
+			i.HintChannels,
+			lnwire.NewShortChanIDFromInt(
+				byteOrder.Uint64(hintChans[j:]),
+			),
+		)
+	}
+
 	err = i.CreationDate.UnmarshalBinary(creationDateBytes)
 	if err != nil {
 		return i, err
@@ -1472,6 +1600,13 @@ func copyInvoice(src *Invoice) *Invoice {
 
 	dest.Terms.Features = src.Terms.Features.Clone()
 
+	if src.HintChannels != nil {
+		dest.HintChannels = make(
+			[]lnwire.ShortChannelID, len(src.HintChannels),
+		)
+		copy(dest.HintChannels, src.HintChannels)
+	}
+
 	if src.Terms.PaymentPreimage != nil {
 		preimage := *src.Terms.PaymentPreimage
 		dest.Terms.PaymentPreimage = &preimage
@@ -0,0 +1,168 @@
+package channeldb
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+var (
+	// macaroonSpendBucket is the bucket that stores the amounts spent
+	// with macaroons carrying spending budgets. It holds a sub-bucket per
+	// macaroon identifier, whose keys are the big endian sequence numbers
+	// of the spends and whose values are the timestamp of the spend in
+	// nanoseconds followed by the amount spent in milli-atoms.
+	macaroonSpendBucket = []byte("macaroon-spend")
+
+	// ErrSpendLimitExceeded is returned when a spend would exceed one of
+	// the spending limits of a macaroon.
+	ErrSpendLimitExceeded = errors.New("macaroon spending budget exceeded")
+
+	// ErrMacaroonSpendNotFound is returned when resolving an unknown
+	// spend.
+	ErrMacaroonSpendNotFound = errors.New("macaroon spend not found")
+)
+
+// SpendLimit is the maximum amount that may be spent with a macaroon over a
+// rolling window of time.
+type SpendLimit struct {
+	// Window is the duration over which spends are summed.
+	Window time.Duration
+
+	// Max is the maximum amount that may be spent within the window.
+	Max lnwire.MilliAtom
+}
+
+// ReserveMacaroonSpend checks that spending the given amount with the
+// macaroon of the given identifier doesn't exceed any of the passed limits
+// and, if so, records the spend. The returned spend ID must be passed to
+// ResolveMacaroonSpend once the final amount of the spend is known. Spends
+// older than the longest window are pruned, as they can't count towards any
+// limit anymore.
+func (d *DB) ReserveMacaroonSpend(macID []byte, amt lnwire.MilliAtom,
+	limits []SpendLimit) (uint64, error) {
+
+	now := d.clock.Now()
+
+	var maxWindow time.Duration
+	for _, limit := range limits {
+		if limit.Window > maxWindow {
+			maxWindow = limit.Window
+		}
+	}
+
+	var spendID uint64
+	err := kvdb.Update(d, func(tx kvdb.RwTx) error {
+		spends, err := tx.CreateTopLevelBucket(macaroonSpendBucket)
+		if err != nil {
+			return err
+		}
+		macSpends, err := spends.CreateBucketIfNotExists(macID)
+		if err != nil {
+			return err
+		}
+
+		spent := make([]lnwire.MilliAtom, len(limits))
+		var expired [][]byte
+		err = macSpends.ForEach(func(k, v []byte) error {
+			timestamp := time.Unix(
+				0, int64(binary.BigEndian.Uint64(v[:8])),
+			)
+			spendAmt := lnwire.MilliAtom(
+				binary.BigEndian.Uint64(v[8:]),
+			)
+
+			age := now.Sub(timestamp)
+			if age >= maxWindow {
+				expired = append(expired, k)
+				return nil
+			}
+
+			for i, limit := range limits {
+				if age < limit.Window {
+					spent[i] += spendAmt
+				}
+			}
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err := macSpends.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		// The remaining budget is compared to the amount rather than
+		// adding the amount to what was spent, which could overflow.
+		for i, limit := range limits {
+			if spent[i] > limit.Max || amt > limit.Max-spent[i] {
+				return ErrSpendLimitExceeded
+			}
+		}
+
+		spendID, err = macSpends.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		var k [8]byte
+		binary.BigEndian.PutUint64(k[:], spendID)
+
+		return macSpends.Put(k[:], encodeMacaroonSpend(now, amt))
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return spendID, nil
+}
+
+// ResolveMacaroonSpend sets the final amount of a spend reserved with
+// ReserveMacaroonSpend. A zero amount removes the spend, such as when the
+// payment failed.
+func (d *DB) ResolveMacaroonSpend(macID []byte, spendID uint64,
+	amt lnwire.MilliAtom) error {
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		spends := tx.ReadWriteBucket(macaroonSpendBucket)
+		if spends == nil {
+			return ErrMacaroonSpendNotFound
+		}
+		macSpends := spends.NestedReadWriteBucket(macID)
+		if macSpends == nil {
+			return ErrMacaroonSpendNotFound
+		}
+
+		var k [8]byte
+		binary.BigEndian.PutUint64(k[:], spendID)
+
+		v := macSpends.Get(k[:])
+		if v == nil {
+			return ErrMacaroonSpendNotFound
+		}
+
+		if amt == 0 {
+			return macSpends.Delete(k[:])
+		}
+
+		timestamp := time.Unix(0, int64(binary.BigEndian.Uint64(v[:8])))
+		return macSpends.Put(k[:], encodeMacaroonSpend(timestamp, amt))
+	})
+}
+
+// encodeMacaroonSpend encodes the value of a spend in the macaroon spend
+// bucket.
+func encodeMacaroonSpend(timestamp time.Time, amt lnwire.MilliAtom) []byte {
+	var v [16]byte
+	binary.BigEndian.PutUint64(v[:8], uint64(timestamp.UnixNano()))
+	binary.BigEndian.PutUint64(v[8:], uint64(amt))
+
+	return v[:]
+}
@@ -0,0 +1,79 @@
+package channeldb
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestMacaroonSpendLimits tests that spends are checked against the limits of
+// their window, that resolved spends count with their final amount and that
+// spends of different macaroons are tracked separately.
+func TestMacaroonSpendLimits(t *testing.T) {
+	t.Parallel()
+
+	testClock := clock.NewTestClock(time.Unix(1000000, 0))
+	db, cleanUp, err := MakeTestDB(OptionClock(testClock))
+	require.NoError(t, err)
+	defer cleanUp()
+
+	macID := []byte("mac")
+	limits := []SpendLimit{
+		{Window: time.Hour * 24, Max: 1000},
+		{Window: time.Hour * 24 * 7, Max: 2500},
+	}
+
+	// Resolving an unknown spend fails.
+	err = db.ResolveMacaroonSpend(macID, 1, 0)
+	require.Equal(t, ErrMacaroonSpendNotFound, err)
+
+	// Spends up to the daily limit are allowed.
+	spendID, err := db.ReserveMacaroonSpend(macID, 600, limits)
+	require.NoError(t, err)
+	_, err = db.ReserveMacaroonSpend(macID, 400, limits)
+	require.NoError(t, err)
+	_, err = db.ReserveMacaroonSpend(macID, 1, limits)
+	require.Equal(t, ErrSpendLimitExceeded, err)
+
+	// An amount whose sum with what was spent overflows is refused.
+	_, err = db.ReserveMacaroonSpend(
+		macID, lnwire.MilliAtom(math.MaxUint64), limits,
+	)
+	require.Equal(t, ErrSpendLimitExceeded, err)
+
+	// Other macaroons have their own budget.
+	_, err = db.ReserveMacaroonSpend([]byte("other"), 1000, limits)
+	require.NoError(t, err)
+
+	// A spend resolved to a lower amount frees up the budget, a failed
+	// one entirely.
+	require.NoError(t, db.ResolveMacaroonSpend(macID, spendID, 500))
+	_, err = db.ReserveMacaroonSpend(macID, 100, limits)
+	require.NoError(t, err)
+	_, err = db.ReserveMacaroonSpend(macID, 1, limits)
+	require.Equal(t, ErrSpendLimitExceeded, err)
+	require.NoError(t, db.ResolveMacaroonSpend(macID, spendID, 0))
+	_, err = db.ReserveMacaroonSpend(macID, 500, limits)
+	require.NoError(t, err)
+
+	// Once a day passed, the daily budget is available again, until the
+	// weekly limit is reached.
+	testClock.SetTime(testClock.Now().Add(time.Hour * 24))
+	_, err = db.ReserveMacaroonSpend(macID, 1000, limits)
+	require.NoError(t, err)
+	testClock.SetTime(testClock.Now().Add(time.Hour * 24))
+	_, err = db.ReserveMacaroonSpend(macID, 500, limits)
+	require.NoError(t, err)
+	_, err = db.ReserveMacaroonSpend(macID, 1, limits)
+	require.Equal(t, ErrSpendLimitExceeded, err)
+
+	// Once a week passed, old spends are pruned and the budget is
+	// available again.
+	testClock.SetTime(testClock.Now().Add(time.Hour * 24 * 7))
+	_, err = db.ReserveMacaroonSpend(macID, 1000, limits)
+	require.NoError(t, err)
+}
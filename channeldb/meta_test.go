@@ -503,3 +503,51 @@ func TestMigrationDryRun(t *testing.T) {
 		true,
 		true)
 }
+
+// TestMigrationProgress asserts that the progress of the applied migrations is
+// reported to the migration progress callback.
+func TestMigrationProgress(t *testing.T) {
+	t.Parallel()
+
+	cdb, cleanUp, err := MakeTestDB()
+	defer cleanUp()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cdb.PutMeta(&Meta{DbVersionNumber: 0}); err != nil {
+		t.Fatalf("unable to store meta data: %v", err)
+	}
+
+	var progress []MigrationProgress
+	cdb.migrationProgress = func(p MigrationProgress) {
+		progress = append(progress, p)
+	}
+
+	noop := func(kvdb.RwTx) error { return nil }
+	versions := []version{
+		{0, nil},
+		{1, noop},
+		{2, nil},
+		{3, noop},
+	}
+	if err := cdb.syncVersions(versions); err != nil {
+		t.Fatalf("unable to sync versions: %v", err)
+	}
+
+	expected := []MigrationProgress{
+		{Version: 1, Applied: 0, Total: 2},
+		{Version: 3, Applied: 1, Total: 2},
+		{Version: 3, Applied: 2, Total: 2},
+	}
+	if len(progress) != len(expected) {
+		t.Fatalf("expected %d progress updates, got %d",
+			len(expected), len(progress))
+	}
+	for i := range expected {
+		if progress[i] != expected[i] {
+			t.Fatalf("unexpected progress update %d: got %+v, "+
+				"want %+v", i, progress[i], expected[i])
+		}
+	}
+}
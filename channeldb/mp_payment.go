@@ -29,6 +29,17 @@ type HTLCAttemptInfo struct {
 
 	// AttemptTime is the time at which this HTLC was attempted.
 	AttemptTime time.Time
+
+	// PathFindingTime is the time spent finding the route of this HTLC.
+	// It is zero for attempts made along a route given by the caller and
+	// for attempts recorded by older versions.
+	PathFindingTime time.Duration
+
+	// FirstHopRetry is the number of times the route of this HTLC was
+	// retried after a transient failure of its first hop, before this
+	// attempt was made. It is zero for attempts along a freshly found
+	// route.
+	FirstHopRetry uint32
 }
 
 // HTLCAttempt contains information about a specific HTLC attempt for a given
@@ -35,6 +35,10 @@ type Options struct {
 	// dryRun will fail to commit a successful migration when opening the
 	// database if set to true.
 	dryRun bool
+
+	// migrationProgress is an optional callback notified of the progress
+	// of the migrations applied when opening the database.
+	migrationProgress func(MigrationProgress)
 }
 
 // DefaultOptions returns an Options populated with default values.
@@ -85,3 +89,11 @@ func OptionDryRunMigration(dryRun bool) OptionModifier {
 		o.dryRun = dryRun
 	}
 }
+
+// OptionMigrationProgress sets a callback that is notified of the progress of
+// the migrations applied when opening the database.
+func OptionMigrationProgress(f func(MigrationProgress)) OptionModifier {
+	return func(o *Options) {
+		o.migrationProgress = f
+	}
+}
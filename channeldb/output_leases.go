@@ -0,0 +1,110 @@
+package channeldb
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
+
+var (
+	// outputLeaseBucket is the bucket that stores the leases of wallet
+	// outputs. Each key within the bucket is the outpoint of a leased
+	// output, and the value the ID it's leased to followed by the
+	// expiration of the lease.
+	outputLeaseBucket = []byte("output-leases")
+)
+
+// OutputLease is a lock on a wallet output held on behalf of an external coin
+// selector until it expires.
+type OutputLease struct {
+	// ID is the ID the output is leased to.
+	ID [32]byte
+
+	// Expiration is the time at which the lease expires.
+	Expiration time.Time
+}
+
+// PutOutputLease stores the lease of the given output, replacing any
+// previously stored lease of the output.
+func (d *DB) PutOutputLease(op *wire.OutPoint, lease *OutputLease) error {
+	var key bytes.Buffer
+	if err := writeOutpoint(&key, op); err != nil {
+		return err
+	}
+
+	var value bytes.Buffer
+	err := WriteElements(
+		&value, lease.ID, uint64(lease.Expiration.UnixNano()),
+	)
+	if err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		leases, err := tx.CreateTopLevelBucket(outputLeaseBucket)
+		if err != nil {
+			return err
+		}
+
+		return leases.Put(key.Bytes(), value.Bytes())
+	})
+}
+
+// DeleteOutputLease removes the stored lease of the given output, if any.
+func (d *DB) DeleteOutputLease(op *wire.OutPoint) error {
+	var key bytes.Buffer
+	if err := writeOutpoint(&key, op); err != nil {
+		return err
+	}
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		leases := tx.ReadWriteBucket(outputLeaseBucket)
+		if leases == nil {
+			return nil
+		}
+
+		return leases.Delete(key.Bytes())
+	})
+}
+
+// FetchOutputLeases returns all the stored output leases, keyed by the
+// outpoint of the leased output.
+func (d *DB) FetchOutputLeases() (map[wire.OutPoint]*OutputLease, error) {
+	outputLeases := make(map[wire.OutPoint]*OutputLease)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		leases := tx.ReadBucket(outputLeaseBucket)
+		if leases == nil {
+			return nil
+		}
+
+		return leases.ForEach(func(k, v []byte) error {
+			var op wire.OutPoint
+			err := readOutpoint(bytes.NewReader(k), &op)
+			if err != nil {
+				return err
+			}
+
+			var (
+				lease      OutputLease
+				expiration uint64
+			)
+			err = ReadElements(
+				bytes.NewReader(v), &lease.ID, &expiration,
+			)
+			if err != nil {
+				return err
+			}
+			lease.Expiration = time.Unix(0, int64(expiration))
+
+			outputLeases[op] = &lease
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return outputLeases, nil
+}
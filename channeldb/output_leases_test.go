@@ -0,0 +1,55 @@
+package channeldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOutputLeases tests that output leases can be stored, replaced, deleted
+// and fetched.
+func TestOutputLeases(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	leases, err := db.FetchOutputLeases()
+	require.NoError(t, err)
+	require.Empty(t, leases)
+
+	// Deleting the lease of an output that isn't leased is a no-op.
+	op1 := wire.OutPoint{Hash: chainhash.Hash{1}, Index: 1}
+	op2 := wire.OutPoint{Hash: chainhash.Hash{2}, Tree: wire.TxTreeStake}
+	require.NoError(t, db.DeleteOutputLease(&op1))
+
+	expiration := time.Unix(0, time.Now().UnixNano())
+	lease1 := &OutputLease{ID: [32]byte{1}, Expiration: expiration}
+	lease2 := &OutputLease{ID: [32]byte{2}, Expiration: expiration}
+	require.NoError(t, db.PutOutputLease(&op1, lease1))
+	require.NoError(t, db.PutOutputLease(&op2, lease2))
+
+	extended := &OutputLease{
+		ID:         [32]byte{1},
+		Expiration: expiration.Add(time.Hour),
+	}
+	require.NoError(t, db.PutOutputLease(&op1, extended))
+
+	leases, err = db.FetchOutputLeases()
+	require.NoError(t, err)
+	require.Len(t, leases, 2)
+	require.Equal(t, extended.ID, leases[op1].ID)
+	require.True(t, extended.Expiration.Equal(leases[op1].Expiration))
+	require.Equal(t, lease2.ID, leases[op2].ID)
+	require.True(t, lease2.Expiration.Equal(leases[op2].Expiration))
+
+	require.NoError(t, db.DeleteOutputLease(&op1))
+	leases, err = db.FetchOutputLeases()
+	require.NoError(t, err)
+	require.Len(t, leases, 1)
+	require.Contains(t, leases, op2)
+}
@@ -387,11 +387,13 @@ func TestPaymentControlDeleteNonInFligt(t *testing.T) {
 		},
 	}
 
+	var hashes []lntypes.Hash
 	for _, p := range payments {
 		info, attempt, preimg, err := genInfo()
 		if err != nil {
 			t.Fatalf("unable to generate htlc message: %v", err)
 		}
+		hashes = append(hashes, info.PaymentHash)
 
 		// Sends base htlc message which initiate StatusInFlight.
 		err = pControl.InitPayment(info.PaymentHash, info)
@@ -471,8 +473,47 @@ func TestPaymentControlDeleteNonInFligt(t *testing.T) {
 		}
 	}
 
+	// assertPayments checks the number of payments and payment indexes
+	// left in the database.
+	assertPayments := func(numPayments, numIndexes int) {
+		t.Helper()
+
+		dbPayments, err := db.FetchPayments()
+		require.NoError(t, err)
+		require.Len(t, dbPayments, numPayments)
+
+		var indexCount int
+		err = kvdb.View(db, func(tx walletdb.ReadTx) error {
+			index := tx.ReadBucket(paymentsIndexBucket)
+
+			return index.ForEach(func(k, v []byte) error {
+				indexCount++
+				return nil
+			})
+		})
+		require.NoError(t, err)
+		require.Equal(t, numIndexes, indexCount)
+	}
+
+	// In-flight payments can't be deleted individually.
+	err = db.DeletePayment(hashes[2])
+	require.Equal(t, ErrPaymentInFlight, err)
+
+	// Neither can payments we don't know about.
+	err = db.DeletePayment(lntypes.Hash{0x01})
+	require.Equal(t, ErrPaymentNotInitiated, err)
+
+	// Deleting the successful payment with a duplicate should remove both
+	// of its indexes.
+	require.NoError(t, db.DeletePayment(hashes[3]))
+	assertPayments(3, 3)
+
+	// Deleting only the failed payments should retain the successful one.
+	require.NoError(t, db.DeletePayments(true))
+	assertPayments(2, 2)
+
 	// Delete payments.
-	if err := db.DeletePayments(); err != nil {
+	if err := db.DeletePayments(false); err != nil {
 		t.Fatal(err)
 	}
 
@@ -493,18 +534,7 @@ func TestPaymentControlDeleteNonInFligt(t *testing.T) {
 
 	// Finally, check that we only have a single index left in the payment
 	// index bucket.
-	var indexCount int
-	err = kvdb.View(db, func(tx walletdb.ReadTx) error {
-		index := tx.ReadBucket(paymentsIndexBucket)
-
-		return index.ForEach(func(k, v []byte) error {
-			indexCount++
-			return nil
-		})
-	})
-	require.NoError(t, err)
-
-	require.Equal(t, 1, indexCount)
+	assertPayments(1, 1)
 }
 
 // TestPaymentControlMultiShard checks the ability of payment control to
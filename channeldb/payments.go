@@ -147,7 +147,9 @@ const (
 	// balance to complete the payment.
 	FailureReasonInsufficientBalance FailureReason = 4
 
-	// TODO(halseth): cancel state.
+	// FailureReasonCanceled indicates that the caller waiting for the
+	// outcome of the payment went away before it completed.
+	FailureReasonCanceled FailureReason = 5
 
 	// TODO(joostjager): Add failure reasons for:
 	// LocalLiquidityInsufficient, RemoteCapacityInsufficient.
@@ -171,6 +173,8 @@ func (r FailureReason) String() string {
 		return "incorrect_payment_details"
 	case FailureReasonInsufficientBalance:
 		return "insufficient_balance"
+	case FailureReasonCanceled:
+		return "canceled"
 	}
 
 	return "unknown"
@@ -479,6 +483,14 @@ type PaymentsQuery struct {
 	// fully completed. This means that pending payments, as well as failed
 	// payments will show up if this field is set to true.
 	IncludeIncomplete bool
+
+	// CreationDateStart, if set, excludes the payments created before
+	// this time from the query.
+	CreationDateStart time.Time
+
+	// CreationDateEnd, if set, excludes the payments created after this
+	// time from the query.
+	CreationDateEnd time.Time
 }
 
 // PaymentsResponse contains the result of a query to the payments database.
@@ -553,6 +565,20 @@ func (db *DB) QueryPayments(query PaymentsQuery) (PaymentsResponse, error) {
 				return false, err
 			}
 
+			// Skip the payments created outside of the requested
+			// time range.
+			creationTime := payment.Info.CreationTime
+			if !query.CreationDateStart.IsZero() &&
+				creationTime.Before(query.CreationDateStart) {
+
+				return false, nil
+			}
+			if !query.CreationDateEnd.IsZero() &&
+				creationTime.After(query.CreationDateEnd) {
+
+				return false, nil
+			}
+
 			// At this point, we've exhausted the offset, so we'll
 			// begin collecting invoices found within the range.
 			resp.Payments = append(resp.Payments, payment)
@@ -672,8 +698,58 @@ func fetchPaymentWithSequenceNumber(tx kvdb.RTx, paymentHash lntypes.Hash,
 	return duplicatePayment, nil
 }
 
-// DeletePayments deletes all completed and failed payments from the DB.
-func (db *DB) DeletePayments() error {
+// DeletePayment deletes a single completed or failed payment, including any
+// duplicate payments stored under the same hash, from the DB. In-flight
+// payments can't be deleted and cause ErrPaymentInFlight to be returned.
+func (db *DB) DeletePayment(paymentHash lntypes.Hash) error {
+	return kvdb.Update(db, func(tx kvdb.RwTx) error {
+		payments := tx.ReadWriteBucket(paymentsRootBucket)
+		if payments == nil {
+			return ErrPaymentNotInitiated
+		}
+
+		bucket := payments.NestedReadWriteBucket(paymentHash[:])
+		if bucket == nil {
+			return ErrPaymentNotInitiated
+		}
+
+		// If the status is InFlight, we cannot safely delete the
+		// payment information.
+		paymentStatus, err := fetchPaymentStatus(bucket)
+		if err != nil {
+			return err
+		}
+		if paymentStatus == StatusInFlight {
+			return ErrPaymentInFlight
+		}
+
+		// Get all the sequence numbers associated with the payment,
+		// including duplicates, so the indexes pointing to it can be
+		// removed as well.
+		seqNrs, err := fetchSequenceNumbers(bucket)
+		if err != nil {
+			return err
+		}
+
+		if err := payments.DeleteNestedBucket(paymentHash[:]); err != nil {
+			return err
+		}
+
+		indexBucket := tx.ReadWriteBucket(paymentsIndexBucket)
+		for _, k := range seqNrs {
+			if err := indexBucket.Delete(k); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// DeletePayments deletes all completed and failed payments from the DB. If
+// failedOnly is set, only failed payments are deleted, retaining the records
+// of successful ones.
+func (db *DB) DeletePayments(failedOnly bool) error {
 	return kvdb.Update(db, func(tx kvdb.RwTx) error {
 		payments := tx.ReadWriteBucket(paymentsRootBucket)
 		if payments == nil {
@@ -711,6 +787,12 @@ func (db *DB) DeletePayments() error {
 				return nil
 			}
 
+			// If we were requested to only delete failed payments,
+			// we leave any other payment in place.
+			if failedOnly && paymentStatus != StatusFailed {
+				return nil
+			}
+
 			// Add the bucket to the set of buckets we can delete.
 			deleteBuckets = append(deleteBuckets, k)
 
@@ -852,7 +934,16 @@ func serializeHTLCAttemptInfo(w io.Writer, a *HTLCAttemptInfo) error {
 		return err
 	}
 
-	return serializeTime(w, a.AttemptTime)
+	if err := serializeTime(w, a.AttemptTime); err != nil {
+		return err
+	}
+
+	err := binary.Write(w, byteOrder, uint64(a.PathFindingTime))
+	if err != nil {
+		return err
+	}
+
+	return binary.Write(w, byteOrder, a.FirstHopRetry)
 }
 
 func deserializeHTLCAttemptInfo(r io.Reader) (*HTLCAttemptInfo, error) {
@@ -871,6 +962,26 @@ func deserializeHTLCAttemptInfo(r io.Reader) (*HTLCAttemptInfo, error) {
 		return nil, err
 	}
 
+	// The path finding time was appended later, so it is missing from the
+	// attempts recorded by older versions.
+	var pathFindingTime uint64
+	err = binary.Read(r, byteOrder, &pathFindingTime)
+	switch {
+	case err == io.EOF:
+		return a, nil
+	case err != nil:
+		return nil, err
+	}
+	a.PathFindingTime = time.Duration(pathFindingTime)
+
+	// The first hop retry count was appended after the path finding time.
+	err = binary.Read(r, byteOrder, &a.FirstHopRetry)
+	switch {
+	case err == io.EOF:
+	case err != nil:
+		return nil, err
+	}
+
 	return a, nil
 }
 
@@ -67,10 +67,12 @@ func makeFakeInfo() (*PaymentCreationInfo, *HTLCAttemptInfo) {
 	}
 
 	a := &HTLCAttemptInfo{
-		AttemptID:   44,
-		SessionKey:  priv,
-		Route:       testRoute,
-		AttemptTime: time.Unix(100, 0),
+		AttemptID:       44,
+		SessionKey:      priv,
+		Route:           testRoute,
+		AttemptTime:     time.Unix(100, 0),
+		PathFindingTime: 25 * time.Millisecond,
+		FirstHopRetry:   2,
 	}
 	return c, a
 }
@@ -128,6 +130,53 @@ func TestSentPaymentSerialization(t *testing.T) {
 	}
 }
 
+// TestHTLCAttemptInfoLegacyDeserialization asserts that attempts recorded
+// before the path finding time and first hop retry count were stored can
+// still be read.
+func TestHTLCAttemptInfoLegacyDeserialization(t *testing.T) {
+	t.Parallel()
+
+	_, a := makeFakeInfo()
+
+	var b bytes.Buffer
+	if err := serializeHTLCAttemptInfo(&b, a); err != nil {
+		t.Fatalf("unable to serialize info: %v", err)
+	}
+
+	// Strip the trailing first hop retry count to obtain the encoding of
+	// attempts that only recorded their path finding time.
+	noRetry := b.Bytes()[:b.Len()-4]
+
+	info, err := deserializeHTLCAttemptInfo(bytes.NewReader(noRetry))
+	if err != nil {
+		t.Fatalf("unable to deserialize info: %v", err)
+	}
+	if info.PathFindingTime != a.PathFindingTime {
+		t.Fatalf("expected path finding time %v, got %v",
+			a.PathFindingTime, info.PathFindingTime)
+	}
+	if info.FirstHopRetry != 0 {
+		t.Fatalf("expected no first hop retry, got %v",
+			info.FirstHopRetry)
+	}
+
+	// Also strip the path finding time to obtain the legacy encoding.
+	legacy := b.Bytes()[:b.Len()-12]
+
+	info, err = deserializeHTLCAttemptInfo(bytes.NewReader(legacy))
+	if err != nil {
+		t.Fatalf("unable to deserialize legacy info: %v", err)
+	}
+	if info.PathFindingTime != 0 {
+		t.Fatalf("expected no path finding time, got %v",
+			info.PathFindingTime)
+	}
+	if !info.AttemptTime.Equal(a.AttemptTime) {
+		t.Fatalf("expected attempt time %v, got %v", a.AttemptTime,
+			info.AttemptTime)
+	}
+}
+
 // assertRouteEquals compares to routes for equality and returns an error if
 // they are not equal.
 func assertRouteEqual(a, b *route.Route) error {
@@ -504,6 +553,95 @@ func TestQueryPayments(t *testing.T) {
 	}
 }
 
+// TestQueryPaymentsCreationDate tests that payments can be filtered by their
+// creation date, in combination with the pagination of the query.
+func TestQueryPaymentsCreationDate(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanup()
+
+	// Create a payment every 1000 seconds, resulting in payments with
+	// sequence numbers [1, 2, 3, 4] created at [1000, 2000, 3000, 4000].
+	pControl := NewPaymentControl(db)
+	for i := 1; i <= 4; i++ {
+		info, _, _, err := genInfo()
+		require.NoError(t, err)
+
+		info.CreationTime = time.Unix(int64(i*1000), 0)
+		err = pControl.InitPayment(info.PaymentHash, info)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name           string
+		query          PaymentsQuery
+		expectedSeqNrs []uint64
+	}{
+		{
+			name: "start date",
+			query: PaymentsQuery{
+				CreationDateStart: time.Unix(2000, 0),
+			},
+			expectedSeqNrs: []uint64{2, 3, 4},
+		},
+		{
+			name: "end date",
+			query: PaymentsQuery{
+				CreationDateEnd: time.Unix(2500, 0),
+			},
+			expectedSeqNrs: []uint64{1, 2},
+		},
+		{
+			name: "date range",
+			query: PaymentsQuery{
+				CreationDateStart: time.Unix(1500, 0),
+				CreationDateEnd:   time.Unix(3000, 0),
+			},
+			expectedSeqNrs: []uint64{2, 3},
+		},
+		{
+			name: "date range with pagination",
+			query: PaymentsQuery{
+				IndexOffset:       2,
+				MaxPayments:       1,
+				CreationDateStart: time.Unix(1500, 0),
+			},
+			expectedSeqNrs: []uint64{3},
+		},
+		{
+			name: "date range reversed",
+			query: PaymentsQuery{
+				MaxPayments:     1,
+				Reversed:        true,
+				CreationDateEnd: time.Unix(3500, 0),
+			},
+			expectedSeqNrs: []uint64{3},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			query := test.query
+			query.IncludeIncomplete = true
+			if query.MaxPayments == 0 {
+				query.MaxPayments = math.MaxUint64
+			}
+
+			resp, err := db.QueryPayments(query)
+			require.NoError(t, err)
+
+			var seqNrs []uint64
+			for _, payment := range resp.Payments {
+				seqNrs = append(seqNrs, payment.SequenceNum)
+			}
+			require.Equal(t, test.expectedSeqNrs, seqNrs)
+		})
+	}
+}
+
 // TestFetchPaymentWithSequenceNumber tests lookup of payments with their
 // sequence number. It sets up one payment with no duplicates, and another with
 // two duplicates in its duplicates bucket then uses these payments to test the
@@ -0,0 +1,221 @@
+package channeldb
+
+import (
+	"bytes"
+	"io"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// MaxPolicyHistoryEntries is the maximum number of policy history entries
+// kept for each direction of a channel edge. Once exceeded, the oldest
+// entries are removed.
+const MaxPolicyHistoryEntries = 50
+
+// PolicyHistoryEntry is a snapshot of the routing policy of one direction of a
+// channel edge, recorded whenever the policy changed.
+type PolicyHistoryEntry struct {
+	// Direction is false if the policy was announced by the first node of
+	// the channel and true if it was announced by the second node.
+	Direction bool
+
+	// LastUpdate is the timestamp of the channel update that set the
+	// policy.
+	LastUpdate time.Time
+
+	// TimeLockDelta is the time lock delta of the policy.
+	TimeLockDelta uint16
+
+	// MinHTLC is the smallest HTLC accepted by the policy.
+	MinHTLC lnwire.MilliAtom
+
+	// MaxHTLC is the largest HTLC accepted by the policy.
+	MaxHTLC lnwire.MilliAtom
+
+	// FeeBaseMAtoms is the base fee of the policy.
+	FeeBaseMAtoms lnwire.MilliAtom
+
+	// FeeProportionalMillionths is the fee rate of the policy.
+	FeeProportionalMillionths lnwire.MilliAtom
+
+	// Disabled is true if the policy disabled the edge.
+	Disabled bool
+}
+
+// policyChanged returns true if the new policy changes any of the routing
+// parameters of the old one. A policy replacing an unknown one is always
+// considered a change.
+func policyChanged(old, new *ChannelEdgePolicy) bool {
+	if old == nil {
+		return true
+	}
+
+	return old.FeeBaseMAtoms != new.FeeBaseMAtoms ||
+		old.FeeProportionalMillionths != new.FeeProportionalMillionths ||
+		old.TimeLockDelta != new.TimeLockDelta ||
+		old.MinHTLC != new.MinHTLC ||
+		old.MaxHTLC != new.MaxHTLC ||
+		old.IsDisabled() != new.IsDisabled()
+}
+
+// policyHistoryPrefix returns the key prefix of the history entries of a
+// channel, optionally restricted to a single direction.
+func policyHistoryPrefix(chanID uint64, direction *bool) []byte {
+	prefix := make([]byte, 8, 9)
+	byteOrder.PutUint64(prefix, chanID)
+	if direction != nil {
+		if *direction {
+			prefix = append(prefix, 1)
+		} else {
+			prefix = append(prefix, 0)
+		}
+	}
+
+	return prefix
+}
+
+// putPolicyHistoryEntry records the given policy in the policy history of its
+// edge, removing the oldest entries of the direction if the history grows
+// past MaxPolicyHistoryEntries.
+func putPolicyHistoryEntry(edges kvdb.RwBucket, edge *ChannelEdgePolicy,
+	direction bool) error {
+
+	history, err := edges.CreateBucketIfNotExists(edgePolicyHistoryBucket)
+	if err != nil {
+		return err
+	}
+
+	prefix := policyHistoryPrefix(edge.ChannelID, &direction)
+	var key [8 + 1 + 8]byte
+	copy(key[:], prefix)
+	byteOrder.PutUint64(key[9:], uint64(edge.LastUpdate.Unix()))
+
+	entry := &PolicyHistoryEntry{
+		Direction:                 direction,
+		LastUpdate:                edge.LastUpdate,
+		TimeLockDelta:             edge.TimeLockDelta,
+		MinHTLC:                   edge.MinHTLC,
+		MaxHTLC:                   edge.MaxHTLC,
+		FeeBaseMAtoms:             edge.FeeBaseMAtoms,
+		FeeProportionalMillionths: edge.FeeProportionalMillionths,
+		Disabled:                  edge.IsDisabled(),
+	}
+	var b bytes.Buffer
+	if err := serializePolicyHistoryEntry(&b, entry); err != nil {
+		return err
+	}
+	if err := history.Put(key[:], b.Bytes()); err != nil {
+		return err
+	}
+
+	// Entries are sorted by update time within a direction, so the
+	// oldest ones are the first ones found past the prefix.
+	var keys [][]byte
+	cursor := history.ReadWriteCursor()
+	for k, _ := cursor.Seek(prefix); k != nil &&
+		bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for i := 0; i < len(keys)-MaxPolicyHistoryEntries; i++ {
+		if err := history.Delete(keys[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// delPolicyHistory removes the policy history of both directions of a
+// channel.
+func delPolicyHistory(edges kvdb.RwBucket, chanID uint64) error {
+	history := edges.NestedReadWriteBucket(edgePolicyHistoryBucket)
+	if history == nil {
+		return nil
+	}
+
+	prefix := policyHistoryPrefix(chanID, nil)
+	var keys [][]byte
+	cursor := history.ReadWriteCursor()
+	for k, _ := cursor.Seek(prefix); k != nil &&
+		bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+
+		keys = append(keys, append([]byte(nil), k...))
+	}
+	for _, k := range keys {
+		if err := history.Delete(k); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FetchChanPolicyHistory returns the recorded policy changes of both
+// directions of the given channel. The entries of the first direction are
+// returned first, each direction sorted from oldest to newest.
+func (c *ChannelGraph) FetchChanPolicyHistory(
+	chanID uint64) ([]*PolicyHistoryEntry, error) {
+
+	var entries []*PolicyHistoryEntry
+	err := kvdb.View(c.db, func(tx kvdb.RTx) error {
+		edges := tx.ReadBucket(edgeBucket)
+		if edges == nil {
+			return ErrGraphNoEdgesFound
+		}
+		history := edges.NestedReadBucket(edgePolicyHistoryBucket)
+		if history == nil {
+			return nil
+		}
+
+		prefix := policyHistoryPrefix(chanID, nil)
+		cursor := history.ReadCursor()
+		for k, v := cursor.Seek(prefix); k != nil &&
+			bytes.HasPrefix(k, prefix); k, v = cursor.Next() {
+
+			entry, err := deserializePolicyHistoryEntry(
+				bytes.NewReader(v),
+			)
+			if err != nil {
+				return err
+			}
+			entry.Direction = k[8] == 1
+
+			entries = append(entries, entry)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func serializePolicyHistoryEntry(w io.Writer, e *PolicyHistoryEntry) error {
+	return WriteElements(w,
+		uint64(e.LastUpdate.Unix()), e.TimeLockDelta, e.MinHTLC,
+		e.MaxHTLC, e.FeeBaseMAtoms, e.FeeProportionalMillionths,
+		e.Disabled,
+	)
+}
+
+func deserializePolicyHistoryEntry(r io.Reader) (*PolicyHistoryEntry, error) {
+	var (
+		e          PolicyHistoryEntry
+		updateUnix uint64
+	)
+	err := ReadElements(r,
+		&updateUnix, &e.TimeLockDelta, &e.MinHTLC, &e.MaxHTLC,
+		&e.FeeBaseMAtoms, &e.FeeProportionalMillionths, &e.Disabled,
+	)
+	if err != nil {
+		return nil, err
+	}
+	e.LastUpdate = time.Unix(int64(updateUnix), 0)
+
+	return &e, nil
+}
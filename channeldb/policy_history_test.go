@@ -0,0 +1,114 @@
+package channeldb
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestChanPolicyHistory asserts that policy changes are recorded per direction
+// of an edge, that updates leaving the routing policy unchanged are skipped,
+// that the history is bounded and that it's removed along with the edge.
+func TestChanPolicyHistory(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to make test database: %v", err)
+	}
+	defer cleanUp()
+
+	graph := db.ChannelGraph()
+
+	node1, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	node2, err := createTestVertex(db)
+	if err != nil {
+		t.Fatalf("unable to create test node: %v", err)
+	}
+	edgeInfo, edge1, edge2 := createChannelEdge(db, node1, node2)
+	if err := graph.AddChannelEdge(edgeInfo); err != nil {
+		t.Fatalf("unable to create channel edge: %v", err)
+	}
+
+	assertHistory := func(expDir1, expDir2 int) []*PolicyHistoryEntry {
+		t.Helper()
+
+		entries, err := graph.FetchChanPolicyHistory(edgeInfo.ChannelID)
+		if err != nil {
+			t.Fatalf("unable to fetch policy history: %v", err)
+		}
+
+		var dir1, dir2 int
+		for _, entry := range entries {
+			if entry.Direction {
+				dir2++
+			} else {
+				dir1++
+			}
+		}
+		if dir1 != expDir1 || dir2 != expDir2 {
+			t.Fatalf("expected %d/%d history entries, got %d/%d",
+				expDir1, expDir2, dir1, dir2)
+		}
+
+		return entries
+	}
+
+	// The initial policies of both directions are recorded.
+	if err := graph.UpdateEdgePolicy(edge1); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	if err := graph.UpdateEdgePolicy(edge2); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	assertHistory(1, 1)
+
+	// A refreshed update that keeps the policy isn't recorded.
+	edge1.LastUpdate = edge1.LastUpdate.Add(time.Second)
+	if err := graph.UpdateEdgePolicy(edge1); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	assertHistory(1, 1)
+
+	// Changing the fee rate and disabling the edge are both recorded.
+	edge1.LastUpdate = edge1.LastUpdate.Add(time.Second)
+	edge1.FeeProportionalMillionths++
+	if err := graph.UpdateEdgePolicy(edge1); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	edge1.LastUpdate = edge1.LastUpdate.Add(time.Second)
+	edge1.ChannelFlags |= lnwire.ChanUpdateDisabled
+	if err := graph.UpdateEdgePolicy(edge1); err != nil {
+		t.Fatalf("unable to update edge: %v", err)
+	}
+	entries := assertHistory(3, 1)
+	last := entries[2]
+	if !last.Disabled || last.LastUpdate != edge1.LastUpdate ||
+		last.FeeProportionalMillionths != edge1.FeeProportionalMillionths {
+
+		t.Fatalf("unexpected last history entry: %v", last)
+	}
+
+	// The history of a direction is bounded, keeping the newest entries.
+	for i := 0; i < MaxPolicyHistoryEntries; i++ {
+		edge2.LastUpdate = edge2.LastUpdate.Add(time.Second)
+		edge2.TimeLockDelta++
+		if err := graph.UpdateEdgePolicy(edge2); err != nil {
+			t.Fatalf("unable to update edge: %v", err)
+		}
+	}
+	entries = assertHistory(3, MaxPolicyHistoryEntries)
+	if entries[len(entries)-1].TimeLockDelta != edge2.TimeLockDelta {
+		t.Fatalf("newest history entry was not kept")
+	}
+
+	// Deleting the edge removes its history.
+	if err := graph.DeleteChannelEdges(edgeInfo.ChannelID); err != nil {
+		t.Fatalf("unable to delete edge: %v", err)
+	}
+	assertHistory(0, 0)
+}
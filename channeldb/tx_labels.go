@@ -0,0 +1,84 @@
+package channeldb
+
+import (
+	"errors"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrlnd/channeldb/kvdb"
+)
+
+var (
+	// txLabelBucket is the bucket that stores the labels of wallet
+	// transactions. Each key within the bucket is a txid, and the value
+	// the label of the transaction.
+	txLabelBucket = []byte("tx-labels")
+
+	// ErrTxLabelExists is returned when an attempt is made to label a
+	// transaction that already has a label without overwriting it.
+	ErrTxLabelExists = errors.New("transaction already labelled")
+)
+
+// PutTxLabel stores the label of the transaction with the given txid. If the
+// transaction already has a label, ErrTxLabelExists is returned unless
+// overwrite is set.
+func (d *DB) PutTxLabel(txid chainhash.Hash, label string,
+	overwrite bool) error {
+
+	return kvdb.Update(d, func(tx kvdb.RwTx) error {
+		labels, err := tx.CreateTopLevelBucket(txLabelBucket)
+		if err != nil {
+			return err
+		}
+
+		if !overwrite && labels.Get(txid[:]) != nil {
+			return ErrTxLabelExists
+		}
+
+		return labels.Put(txid[:], []byte(label))
+	})
+}
+
+// FetchTxLabels returns the labels of all labelled transactions, keyed by
+// txid.
+func (d *DB) FetchTxLabels() (map[chainhash.Hash]string, error) {
+	txLabels := make(map[chainhash.Hash]string)
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		labels := tx.ReadBucket(txLabelBucket)
+		if labels == nil {
+			return nil
+		}
+
+		return labels.ForEach(func(k, v []byte) error {
+			var txid chainhash.Hash
+			copy(txid[:], k)
+			txLabels[txid] = string(v)
+
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return txLabels, nil
+}
+
+// FetchTxLabel returns the label of the transaction with the given txid, or
+// an empty string if it has no label.
+func (d *DB) FetchTxLabel(txid chainhash.Hash) (string, error) {
+	var label string
+	err := kvdb.View(d, func(tx kvdb.RTx) error {
+		labels := tx.ReadBucket(txLabelBucket)
+		if labels == nil {
+			return nil
+		}
+
+		label = string(labels.Get(txid[:]))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return label, nil
+}
@@ -0,0 +1,47 @@
+package channeldb
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTxLabels tests that transaction labels can be stored and fetched, and
+// that existing labels are only replaced when overwriting.
+func TestTxLabels(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	txLabels, err := db.FetchTxLabels()
+	require.NoError(t, err)
+	require.Empty(t, txLabels)
+
+	txid1 := chainhash.Hash{1}
+	txid2 := chainhash.Hash{2}
+	require.NoError(t, db.PutTxLabel(txid1, "first", false))
+	require.NoError(t, db.PutTxLabel(txid2, "second", false))
+
+	err = db.PutTxLabel(txid1, "replaced", false)
+	require.Equal(t, ErrTxLabelExists, err)
+
+	require.NoError(t, db.PutTxLabel(txid2, "replaced", true))
+
+	txLabels, err = db.FetchTxLabels()
+	require.NoError(t, err)
+	require.Equal(t, map[chainhash.Hash]string{
+		txid1: "first",
+		txid2: "replaced",
+	}, txLabels)
+
+	label, err := db.FetchTxLabel(txid2)
+	require.NoError(t, err)
+	require.Equal(t, "replaced", label)
+
+	label, err = db.FetchTxLabel(chainhash.Hash{3})
+	require.NoError(t, err)
+	require.Empty(t, label)
+}
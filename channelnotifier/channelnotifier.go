@@ -65,6 +65,17 @@ type ClosedChannelEvent struct {
 	CloseSummary *channeldb.ChannelCloseSummary
 }
 
+// CloseReasonEvent represents a new event where the reason for which a
+// channel is being closed becomes known. This usually happens before the
+// channel is actually closed.
+type CloseReasonEvent struct {
+	// ChannelPoint is the channel point of the channel being closed.
+	ChannelPoint *wire.OutPoint
+
+	// Reason is the reason for which the channel is being closed.
+	Reason *channeldb.ChannelCloseReason
+}
+
 // New creates a new channel notifier. The ChannelNotifier gets channel
 // events from peers and from the chain arbitrator, and dispatches them to
 // its clients.
@@ -179,3 +190,33 @@ func (c *ChannelNotifier) NotifyInactiveChannelEvent(chanPoint wire.OutPoint) {
 		log.Warnf("Unable to send inactive channel update: %v", err)
 	}
 }
+
+// NotifyCloseReasonEvent persists the reason for which a channel is being
+// closed, so that it's returned along with its close summary, and notifies
+// the channelEventNotifier goroutine of it.
+func (c *ChannelNotifier) NotifyCloseReasonEvent(chanPoint wire.OutPoint,
+	reason *channeldb.ChannelCloseReason) {
+
+	if err := c.chanDB.PutCloseReason(&chanPoint, reason); err != nil {
+		log.Warnf("Unable to store close reason of channel %v: %v",
+			chanPoint, err)
+	}
+
+	event := CloseReasonEvent{
+		ChannelPoint: &chanPoint,
+		Reason:       reason,
+	}
+	if err := c.ntfnServer.SendUpdate(event); err != nil {
+		log.Warnf("Unable to send close reason update: %v", err)
+	}
+}
+
+// ClearCloseReason removes the close reason recorded for a channel that
+// resumed its normal operation, so that a stale reason isn't reported once it
+// eventually closes.
+func (c *ChannelNotifier) ClearCloseReason(chanPoint wire.OutPoint) {
+	if err := c.chanDB.DeleteCloseReason(&chanPoint); err != nil {
+		log.Warnf("Unable to clear close reason of channel %v: %v",
+			chanPoint, err)
+	}
+}
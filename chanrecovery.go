@@ -0,0 +1,505 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chanbackup"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+)
+
+// maxRecoveryCommitHeights is the maximum number of commitment heights the
+// keys can be derived for within a single call.
+const maxRecoveryCommitHeights = 1000
+
+// rpcKeyDescriptor converts the passed key descriptor into its RPC
+// counterpart.
+func rpcKeyDescriptor(desc keychain.KeyDescriptor) *lnrpc.KeyDescriptor {
+	rpcDesc := &lnrpc.KeyDescriptor{
+		KeyLoc: &lnrpc.KeyLocator{
+			KeyFamily: int32(desc.Family),
+			KeyIndex:  int32(desc.Index),
+		},
+	}
+	if desc.PubKey != nil {
+		rpcDesc.RawKeyBytes = desc.PubKey.SerializeCompressed()
+	}
+
+	return rpcDesc
+}
+
+// localCommitKeyRing derives our per-commitment point and the keys of our
+// commitment transaction at the given height of the passed channel.
+func localCommitKeyRing(channel *channeldb.OpenChannel, height uint64) (
+	*secp256k1.PublicKey, *lnwallet.CommitmentKeyRing, error) {
+
+	revocation, err := channel.RevocationProducer.AtIndex(height)
+	if err != nil {
+		return nil, nil, err
+	}
+	commitPoint := input.ComputeCommitmentPoint(revocation[:])
+	keyRing := lnwallet.DeriveCommitmentKeys(
+		commitPoint, true, channel.ChanType, &channel.LocalChanCfg,
+		&channel.RemoteChanCfg,
+	)
+
+	return commitPoint, keyRing, nil
+}
+
+// commitmentRecoveryKeys derives the keys used within our commitment
+// transaction at the given height of the passed channel.
+func commitmentRecoveryKeys(channel *channeldb.OpenChannel,
+	height uint64) (*lnrpc.CommitmentRecoveryKeys, error) {
+
+	commitPoint, keyRing, err := localCommitKeyRing(channel, height)
+	if err != nil {
+		return nil, err
+	}
+
+	selfScript, err := input.CommitScriptToSelf(
+		uint32(channel.LocalChanCfg.CsvDelay), keyRing.ToLocalKey,
+		keyRing.RevocationKey,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := input.ScriptHashPkScript(selfScript)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.CommitmentRecoveryKeys{
+		CommitHeight:        height,
+		CommitPoint:         commitPoint.SerializeCompressed(),
+		ToLocalKey:          keyRing.ToLocalKey.SerializeCompressed(),
+		LocalCommitKeyTweak: keyRing.LocalCommitKeyTweak,
+		RevocationKey:       keyRing.RevocationKey.SerializeCompressed(),
+		ToRemoteKey:         keyRing.ToRemoteKey.SerializeCompressed(),
+		ToLocalPkScript:     pkScript,
+	}, nil
+}
+
+// channelRecoveryKeys re-derives the keys of the channel contained in the
+// passed backup, along with the keys of our commitment at each of the given
+// heights.
+func channelRecoveryKeys(restorer *chanDBRestorer, backup chanbackup.Single,
+	heights []uint64) (*lnrpc.ChannelRecoveryKeys, error) {
+
+	// The channel shell contains all the keys re-derived from their
+	// locators, as well as our revocation producer, which is all we need
+	// to compute the keys of our commitments.
+	shell, err := restorer.openChannelShell(backup)
+	if err != nil {
+		return nil, err
+	}
+	channel := shell.Chan
+
+	chanPoint := channel.FundingOutpoint
+	resp := &lnrpc.ChannelRecoveryKeys{
+		ChanPoint: &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: chanPoint.Hash[:],
+			},
+			OutputIndex: chanPoint.Index,
+		},
+		RemoteNodePub: fmt.Sprintf(
+			"%x", channel.IdentityPub.SerializeCompressed(),
+		),
+		CommitmentType: rpcCommitmentType(channel.ChanType),
+		MultiSigKey:    rpcKeyDescriptor(channel.LocalChanCfg.MultiSigKey),
+		RevocationBasePoint: rpcKeyDescriptor(
+			channel.LocalChanCfg.RevocationBasePoint,
+		),
+		PaymentBasePoint: rpcKeyDescriptor(
+			channel.LocalChanCfg.PaymentBasePoint,
+		),
+		DelayBasePoint: rpcKeyDescriptor(
+			channel.LocalChanCfg.DelayBasePoint,
+		),
+		HtlcBasePoint: rpcKeyDescriptor(
+			channel.LocalChanCfg.HtlcBasePoint,
+		),
+		RemoteMultiSigKey: channel.RemoteChanCfg.MultiSigKey.PubKey.
+			SerializeCompressed(),
+		CsvDelay: uint32(channel.LocalChanCfg.CsvDelay),
+	}
+
+	for _, height := range heights {
+		commitKeys, err := commitmentRecoveryKeys(channel, height)
+		if err != nil {
+			return nil, fmt.Errorf("unable to derive commitment "+
+				"keys at height %v: %v", height, err)
+		}
+		resp.Commitments = append(resp.Commitments, commitKeys)
+	}
+
+	return resp, nil
+}
+
+// errNoRecoverableOutput is returned when a commitment transaction has no
+// output we can sweep using only a static channel backup.
+var errNoRecoverableOutput = errors.New("commitment transaction has no " +
+	"output paying to us that can be swept")
+
+// recoveryStateHintObfuscator returns the obfuscator of the state hints
+// encoded within the commitment transactions of the passed channel.
+func recoveryStateHintObfuscator(
+	channel *channeldb.OpenChannel) [lnwallet.StateHintSize]byte {
+
+	if channel.IsInitiator {
+		return lnwallet.DeriveStateHintObfuscator(
+			channel.LocalChanCfg.PaymentBasePoint.PubKey,
+			channel.RemoteChanCfg.PaymentBasePoint.PubKey,
+		)
+	}
+
+	return lnwallet.DeriveStateHintObfuscator(
+		channel.RemoteChanCfg.PaymentBasePoint.PubKey,
+		channel.LocalChanCfg.PaymentBasePoint.PubKey,
+	)
+}
+
+// recoverySweepInput returns the output of the passed commitment transaction
+// of a channel restored from a backup that pays to us. The returned boolean
+// is true if the commitment is our own.
+func recoverySweepInput(channel *channeldb.OpenChannel,
+	commitTx *wire.MsgTx) (input.Input, bool, error) {
+
+	if len(commitTx.TxIn) != 1 || commitTx.TxIn[0].PreviousOutPoint !=
+		channel.FundingOutpoint {
+
+		return nil, false, fmt.Errorf("transaction doesn't spend "+
+			"the funding output %v", channel.FundingOutpoint)
+	}
+	commitHash := commitTx.TxHash()
+
+	// If this is our commitment, our delayed output is derived from our
+	// per-commitment point at the height encoded in the transaction.
+	height := lnwallet.GetStateNumHint(
+		commitTx, recoveryStateHintObfuscator(channel),
+	)
+	_, keyRing, err := localCommitKeyRing(channel, height)
+	if err != nil {
+		return nil, false, err
+	}
+	csvDelay := uint32(channel.LocalChanCfg.CsvDelay)
+	selfScript, err := input.CommitScriptToSelf(
+		csvDelay, keyRing.ToLocalKey, keyRing.RevocationKey,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	selfPkScript, err := input.ScriptHashPkScript(selfScript)
+	if err != nil {
+		return nil, false, err
+	}
+	found, index := input.FindScriptOutputIndex(commitTx, selfPkScript)
+	if found {
+
+		signDesc := &input.SignDescriptor{
+			KeyDesc:       channel.LocalChanCfg.DelayBasePoint,
+			SingleTweak:   keyRing.LocalCommitKeyTweak,
+			WitnessScript: selfScript,
+			Output:        commitTx.TxOut[index],
+			HashType:      txscript.SigHashAll,
+		}
+		outPoint := wire.OutPoint{Hash: commitHash, Index: index}
+
+		return input.NewCsvInput(
+			&outPoint, input.CommitmentTimeLock, signDesc, 0,
+			csvDelay,
+		), true, nil
+	}
+
+	// Otherwise this may be the commitment of the remote party. Without
+	// its per-commitment point, our output can only be found if it pays
+	// to our payment base point directly.
+	if !channel.ChanType.IsTweakless() {
+		return nil, false, errNoRecoverableOutput
+	}
+	paymentBase := channel.LocalChanCfg.PaymentBasePoint
+	toRemoteScript, remoteCsvDelay, err := lnwallet.CommitScriptToRemote(
+		channel.ChanType, paymentBase.PubKey,
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	found, index = input.FindScriptOutputIndex(
+		commitTx, toRemoteScript.PkScript,
+	)
+	if !found {
+		return nil, false, errNoRecoverableOutput
+	}
+
+	witnessType := input.CommitSpendNoDelayTweakless
+	if channel.ChanType.HasAnchors() {
+		witnessType = input.CommitmentToRemoteConfirmed
+	}
+	signDesc := &input.SignDescriptor{
+		KeyDesc:       paymentBase,
+		WitnessScript: toRemoteScript.WitnessScript,
+		Output:        commitTx.TxOut[index],
+		HashType:      txscript.SigHashAll,
+	}
+	outPoint := wire.OutPoint{Hash: commitHash, Index: index}
+
+	return input.NewCsvInput(
+		&outPoint, witnessType, signDesc, 0, remoteCsvDelay,
+	), false, nil
+}
+
+// recoverySweepTx creates a transaction that sweeps the passed input to the
+// given pkScript, paying a fee at the given rate.
+func recoverySweepTx(sweepInput input.Input, pkScript []byte,
+	feePerKB chainfee.AtomPerKByte, signer input.Signer) (*wire.MsgTx,
+	error) {
+
+	var sizeEstimate input.TxSizeEstimator
+	sizeEstimate.AddP2PKHOutput()
+	err := sweepInput.WitnessType().AddSizeEstimation(&sizeEstimate)
+	if err != nil {
+		return nil, err
+	}
+
+	value := sweepInput.SignDesc().Output.Value
+	fee := feePerKB.FeeForSize(sizeEstimate.Size())
+	sweepAmt := value - int64(fee)
+	if sweepAmt <= 0 {
+		return nil, fmt.Errorf("output of %v can't pay the fee of %v",
+			dcrutil.Amount(value), fee)
+	}
+
+	// We use the LN transaction version as it is required for CSV.
+	sweepTx := wire.NewMsgTx()
+	sweepTx.Version = input.LNTxVersion
+	sweepTx.AddTxOut(&wire.TxOut{
+		PkScript: pkScript,
+		Value:    sweepAmt,
+	})
+	sweepTx.AddTxIn(&wire.TxIn{
+		ValueIn:          value,
+		PreviousOutPoint: *sweepInput.OutPoint(),
+		Sequence:         sweepInput.BlocksToMaturity(),
+	})
+
+	inputScript, err := sweepInput.CraftInputScript(signer, sweepTx, 0)
+	if err != nil {
+		return nil, err
+	}
+	sigScript, err := input.WitnessStackToSigScript(inputScript.Witness)
+	if err != nil {
+		return nil, err
+	}
+	sweepTx.TxIn[0].SignatureScript = sigScript
+
+	return sweepTx, nil
+}
+
+// rescueShares returns the amounts the latest state of the channel gives to
+// us and to the remote party, as well as the fee paid by the passed
+// commitment transaction. The commitment must validly spend the funding
+// output, proving both parties signed the state it commits to.
+func rescueShares(channel *channeldb.OpenChannel, commitTx *wire.MsgTx,
+	fundingOutput *wire.TxOut) (dcrutil.Amount, dcrutil.Amount,
+	dcrutil.Amount, error) {
+
+	if len(commitTx.TxIn) != 1 || commitTx.TxIn[0].PreviousOutPoint !=
+		channel.FundingOutpoint {
+
+		return 0, 0, 0, fmt.Errorf("commitment transaction doesn't "+
+			"spend the funding output %v", channel.FundingOutpoint)
+	}
+	vm, err := txscript.NewEngine(
+		fundingOutput.PkScript, commitTx, 0, input.ScriptVerifyFlags,
+		fundingOutput.Version, nil,
+	)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if err := vm.Execute(); err != nil {
+		return 0, 0, 0, fmt.Errorf("commitment transaction isn't "+
+			"signed by both parties: %v", err)
+	}
+
+	var total dcrutil.Amount
+	for _, txOut := range commitTx.TxOut {
+		total += dcrutil.Amount(txOut.Value)
+	}
+
+	// Our balance is our only output of the commitment. Without the
+	// per-commitment point of the remote party, our output of its
+	// commitment can only be told apart for tweakless channels, so its
+	// absence only means we have no balance for those.
+	var local dcrutil.Amount
+	sweepInput, _, err := recoverySweepInput(channel, commitTx)
+	switch {
+	case err == nil:
+		local = dcrutil.Amount(sweepInput.SignDesc().Output.Value)
+
+	case err == errNoRecoverableOutput && channel.ChanType.IsTweakless():
+
+	default:
+		return 0, 0, 0, fmt.Errorf("unable to find our balance in "+
+			"the commitment transaction: %v", err)
+	}
+
+	// Every other output, including pending HTLCs and anchors, is
+	// attributed to the remote party.
+	fee := channel.Capacity - total
+	return local, total - local, fee, nil
+}
+
+// checkRescuePayouts ensures the passed rescue transaction pays us at least
+// what the latest state of the channel, as committed to by the given
+// commitment transaction, gives us. As the outputs of the rescue transaction
+// can't pay more than the capacity of the channel, this also ensures the
+// remote party isn't paid more than its share.
+func checkRescuePayouts(channel *channeldb.OpenChannel, rescueTx,
+	commitTx *wire.MsgTx, fundingOutput *wire.TxOut,
+	isOurScript func(uint16, []byte) bool) error {
+
+	localShare, remoteShare, commitFee, err := rescueShares(
+		channel, commitTx, fundingOutput,
+	)
+	if err != nil {
+		return err
+	}
+
+	var localPaid, remotePaid dcrutil.Amount
+	for _, txOut := range rescueTx.TxOut {
+		if isOurScript(txOut.Version, txOut.PkScript) {
+			localPaid += dcrutil.Amount(txOut.Value)
+		} else {
+			remotePaid += dcrutil.Amount(txOut.Value)
+		}
+	}
+
+	// Just like the commitment fee, any difference in fee between the
+	// rescue and the commitment transactions is borne by the initiator
+	// of the channel.
+	feeDelta := channel.Capacity - localPaid - remotePaid - commitFee
+	if channel.IsInitiator {
+		localShare -= feeDelta
+	} else {
+		remoteShare -= feeDelta
+	}
+
+	if localPaid < localShare {
+		return fmt.Errorf("rescue transaction pays us %v, but the "+
+			"latest state gives us %v", localPaid, localShare)
+	}
+	if remotePaid > remoteShare {
+		return fmt.Errorf("rescue transaction pays the remote party "+
+			"%v, but the latest state gives it %v", remotePaid,
+			remoteShare)
+	}
+
+	return nil
+}
+
+// signChannelRescue signs the input of the passed transaction spending the
+// funding output of a channel restored from a backup. The funding output,
+// as fetched from the chain, must match the backup, and the rescue
+// transaction must pay each party what the latest commitment transaction of
+// the channel gives it. If the signature of the remote party is given, it's
+// verified and the signature script of the input is also set.
+func signChannelRescue(channel *channeldb.OpenChannel, rescueTx,
+	commitTx *wire.MsgTx, remoteSig input.Signature,
+	fetchFundingOutput func([]byte) (*wire.TxOut, error),
+	isOurScript func(uint16, []byte) bool,
+	signer input.Signer) (input.Signature, []byte, error) {
+
+	inputIndex := -1
+	for i, txIn := range rescueTx.TxIn {
+		if txIn.PreviousOutPoint == channel.FundingOutpoint {
+			inputIndex = i
+			break
+		}
+	}
+	if inputIndex < 0 {
+		return nil, nil, fmt.Errorf("transaction doesn't spend the "+
+			"funding output %v", channel.FundingOutpoint)
+	}
+
+	localKey := channel.LocalChanCfg.MultiSigKey.PubKey.SerializeCompressed()
+	remoteKey := channel.RemoteChanCfg.MultiSigKey.PubKey.
+		SerializeCompressed()
+	fundingScript, fundingOutput, err := input.GenFundingPkScript(
+		localKey, remoteKey, int64(channel.Capacity),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The funding output must pay to the multisig script of the keys
+	// found within the backup, so we don't sign away some other output.
+	chainOutput, err := fetchFundingOutput(fundingOutput.PkScript)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to fetch funding output "+
+			"%v: %v", channel.FundingOutpoint, err)
+	}
+	if !bytes.Equal(chainOutput.PkScript, fundingOutput.PkScript) ||
+		chainOutput.Value != fundingOutput.Value {
+
+		return nil, nil, fmt.Errorf("funding output %v doesn't match "+
+			"the channel backup", channel.FundingOutpoint)
+	}
+
+	err = checkRescuePayouts(
+		channel, rescueTx, commitTx, fundingOutput, isOurScript,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signDesc := &input.SignDescriptor{
+		KeyDesc:       channel.LocalChanCfg.MultiSigKey,
+		WitnessScript: fundingScript,
+		Output:        fundingOutput,
+		HashType:      txscript.SigHashAll,
+		InputIndex:    inputIndex,
+	}
+	localSig, err := signer.SignOutputRaw(rescueTx, signDesc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if remoteSig != nil {
+		witness := input.SpendMultiSig(
+			fundingScript, localKey, localSig, remoteKey, remoteSig,
+		)
+		sigScript, err := input.WitnessStackToSigScript(witness)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Only return a fully signed transaction if the signature of
+		// the remote party is valid.
+		signedTx := rescueTx.Copy()
+		signedTx.TxIn[inputIndex].SignatureScript = sigScript
+		vm, err := txscript.NewEngine(
+			fundingOutput.PkScript, signedTx, inputIndex,
+			input.ScriptVerifyFlags, fundingOutput.Version, nil,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := vm.Execute(); err != nil {
+			return nil, nil, fmt.Errorf("invalid remote signature: "+
+				"%v", err)
+		}
+		rescueTx.TxIn[inputIndex].SignatureScript = sigScript
+	}
+
+	return localSig, fundingScript, nil
+}
@@ -0,0 +1,488 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chanbackup"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+	"github.com/decred/dcrlnd/shachain"
+)
+
+// TestChannelRecoveryKeys asserts that the keys derived from a static channel
+// backup match those of the channel's commitments.
+func TestChannelRecoveryKeys(t *testing.T) {
+	t.Parallel()
+
+	rootKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remoteKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remoteDesc := keychain.KeyDescriptor{PubKey: remoteKey.PubKey()}
+
+	backup := chanbackup.Single{
+		Version: chanbackup.TweaklessCommitVersion,
+		FundingOutpoint: wire.OutPoint{
+			Hash:  chainhash.Hash{1},
+			Index: 1,
+		},
+		RemoteNodePub: remoteKey.PubKey(),
+		LocalChanCfg: channeldb.ChannelConfig{
+			ChannelConstraints: channeldb.ChannelConstraints{
+				CsvDelay: 144,
+			},
+		},
+		RemoteChanCfg: channeldb.ChannelConfig{
+			MultiSigKey:         remoteDesc,
+			RevocationBasePoint: remoteDesc,
+			PaymentBasePoint:    remoteDesc,
+			DelayBasePoint:      remoteDesc,
+			HtlcBasePoint:       remoteDesc,
+		},
+	}
+
+	restorer := &chanDBRestorer{
+		secretKeys: &mockSecretKeyRing{rootKey: rootKey},
+	}
+	heights := []uint64{0, 1, 100}
+	keys, err := channelRecoveryKeys(restorer, backup, heights)
+	if err != nil {
+		t.Fatalf("unable to derive recovery keys: %v", err)
+	}
+
+	if keys.CommitmentType != lnrpc.CommitmentType_STATIC_REMOTE_KEY {
+		t.Fatalf("unexpected commitment type: %v", keys.CommitmentType)
+	}
+	if keys.CsvDelay != 144 {
+		t.Fatalf("unexpected csv delay: %v", keys.CsvDelay)
+	}
+	if !bytes.Equal(
+		keys.MultiSigKey.RawKeyBytes,
+		rootKey.PubKey().SerializeCompressed(),
+	) {
+		t.Fatalf("unexpected multisig key")
+	}
+	if len(keys.Commitments) != len(heights) {
+		t.Fatalf("expected %v commitments, got %v", len(heights),
+			len(keys.Commitments))
+	}
+
+	// The commitment points must match the ones produced from the
+	// shachain root the backup refers to.
+	var revRoot shachain.ShaHash
+	copy(revRoot[:], rootKey.Serialize())
+	producer := shachain.NewRevocationProducer(revRoot)
+	for i, commit := range keys.Commitments {
+		if commit.CommitHeight != heights[i] {
+			t.Fatalf("unexpected commit height: %v",
+				commit.CommitHeight)
+		}
+
+		revocation, err := producer.AtIndex(heights[i])
+		if err != nil {
+			t.Fatalf("unable to produce revocation: %v", err)
+		}
+		commitPoint := input.ComputeCommitmentPoint(revocation[:])
+		if !bytes.Equal(
+			commit.CommitPoint, commitPoint.SerializeCompressed(),
+		) {
+			t.Fatalf("unexpected commit point at height %v",
+				heights[i])
+		}
+		if len(commit.ToLocalPkScript) == 0 {
+			t.Fatalf("missing to-local script at height %v",
+				heights[i])
+		}
+	}
+}
+
+// newRecoveryTestChannel returns a channel shell restored from a backup, with
+// all our keys derived from the passed root key.
+func newRecoveryTestChannel(t *testing.T, rootKey,
+	remoteKey *secp256k1.PrivateKey) *channeldb.OpenChannel {
+
+	t.Helper()
+
+	remoteDesc := keychain.KeyDescriptor{PubKey: remoteKey.PubKey()}
+	backup := chanbackup.Single{
+		Version:     chanbackup.TweaklessCommitVersion,
+		IsInitiator: true,
+		FundingOutpoint: wire.OutPoint{
+			Hash:  chainhash.Hash{1},
+			Index: 1,
+		},
+		Capacity:      1000000,
+		RemoteNodePub: remoteKey.PubKey(),
+		LocalChanCfg: channeldb.ChannelConfig{
+			ChannelConstraints: channeldb.ChannelConstraints{
+				CsvDelay: 144,
+			},
+		},
+		RemoteChanCfg: channeldb.ChannelConfig{
+			MultiSigKey:         remoteDesc,
+			RevocationBasePoint: remoteDesc,
+			PaymentBasePoint:    remoteDesc,
+			DelayBasePoint:      remoteDesc,
+			HtlcBasePoint:       remoteDesc,
+		},
+	}
+
+	restorer := &chanDBRestorer{
+		secretKeys: &mockSecretKeyRing{rootKey: rootKey},
+	}
+	shell, err := restorer.openChannelShell(backup)
+	if err != nil {
+		t.Fatalf("unable to open channel shell: %v", err)
+	}
+
+	return shell.Chan
+}
+
+// assertScriptExecution asserts that the input at the given index of the
+// passed transaction validly spends the given pkScript.
+func assertScriptExecution(t *testing.T, pkScript []byte, tx *wire.MsgTx,
+	idx int) {
+
+	t.Helper()
+
+	vm, err := txscript.NewEngine(
+		pkScript, tx, idx, input.ScriptVerifyFlags, 0, nil,
+	)
+	if err != nil {
+		t.Fatalf("unable to create engine: %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Fatalf("invalid spend: %v", err)
+	}
+}
+
+// TestRecoverySweep asserts that our output of a commitment transaction can
+// be swept using only a static channel backup.
+func TestRecoverySweep(t *testing.T) {
+	t.Parallel()
+
+	rootKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remoteKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	channel := newRecoveryTestChannel(t, rootKey, remoteKey)
+	signer := &mockSigner{key: rootKey}
+	obfuscator := recoveryStateHintObfuscator(channel)
+
+	newCommitTx := func(height uint64, outputs ...*wire.TxOut) *wire.MsgTx {
+		commitTx := wire.NewMsgTx()
+		commitTx.Version = input.LNTxVersion
+		commitTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: channel.FundingOutpoint,
+		})
+		for _, output := range outputs {
+			commitTx.AddTxOut(output)
+		}
+		err := lnwallet.SetStateNumHint(commitTx, height, obfuscator)
+		if err != nil {
+			t.Fatalf("unable to set state hint: %v", err)
+		}
+
+		return commitTx
+	}
+	otherOutput := &wire.TxOut{Value: 50000, PkScript: coinPkScript}
+
+	// Our delayed output of our own commitment is found from the height
+	// encoded within the commitment.
+	commitKeys, err := commitmentRecoveryKeys(channel, 42)
+	if err != nil {
+		t.Fatalf("unable to derive commitment keys: %v", err)
+	}
+	localCommit := newCommitTx(42, otherOutput, &wire.TxOut{
+		Value:    100000,
+		PkScript: commitKeys.ToLocalPkScript,
+	})
+	sweepInput, local, err := recoverySweepInput(channel, localCommit)
+	if err != nil {
+		t.Fatalf("unable to find sweep input: %v", err)
+	}
+	if !local {
+		t.Fatalf("expected our own commitment")
+	}
+	if sweepInput.OutPoint().Index != 1 {
+		t.Fatalf("unexpected output index %v",
+			sweepInput.OutPoint().Index)
+	}
+	if sweepInput.BlocksToMaturity() != 144 {
+		t.Fatalf("unexpected csv delay %v",
+			sweepInput.BlocksToMaturity())
+	}
+
+	feeRate := chainfee.AtomPerKByte(10000)
+	sweepTx, err := recoverySweepTx(
+		sweepInput, coinPkScript, feeRate, signer,
+	)
+	if err != nil {
+		t.Fatalf("unable to create sweep tx: %v", err)
+	}
+	if sweepTx.TxOut[0].Value >= 100000 {
+		t.Fatalf("sweep tx doesn't pay any fee")
+	}
+	assertScriptExecution(t, commitKeys.ToLocalPkScript, sweepTx, 0)
+
+	// Our output of the remote commitment pays to our payment base point
+	// directly.
+	toRemoteScript, _, err := lnwallet.CommitScriptToRemote(
+		channel.ChanType, rootKey.PubKey(),
+	)
+	if err != nil {
+		t.Fatalf("unable to create to-remote script: %v", err)
+	}
+	remoteCommit := newCommitTx(7, &wire.TxOut{
+		Value:    100000,
+		PkScript: toRemoteScript.PkScript,
+	}, otherOutput)
+	sweepInput, local, err = recoverySweepInput(channel, remoteCommit)
+	if err != nil {
+		t.Fatalf("unable to find sweep input: %v", err)
+	}
+	if local {
+		t.Fatalf("expected the remote commitment")
+	}
+	sweepTx, err = recoverySweepTx(
+		sweepInput, coinPkScript, feeRate, signer,
+	)
+	if err != nil {
+		t.Fatalf("unable to create sweep tx: %v", err)
+	}
+	assertScriptExecution(t, toRemoteScript.PkScript, sweepTx, 0)
+
+	// A commitment without any of our outputs can't be swept.
+	_, _, err = recoverySweepInput(channel, newCommitTx(7, otherOutput))
+	if err != errNoRecoverableOutput {
+		t.Fatalf("expected errNoRecoverableOutput, got %v", err)
+	}
+
+	// Neither can a transaction that doesn't spend the funding output.
+	otherTx := newCommitTx(42, otherOutput)
+	otherTx.TxIn[0].PreviousOutPoint.Index++
+	if _, _, err := recoverySweepInput(channel, otherTx); err == nil {
+		t.Fatalf("expected error for unrelated transaction")
+	}
+}
+
+// TestSignChannelRescue asserts that the funding output of a channel can be
+// spent with the cooperation of the remote party using only a static channel
+// backup, as long as the rescue transaction pays us what the latest state of
+// the channel gives us.
+func TestSignChannelRescue(t *testing.T) {
+	t.Parallel()
+
+	rootKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remoteKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	channel := newRecoveryTestChannel(t, rootKey, remoteKey)
+	signer := &mockSigner{key: rootKey}
+	remoteSigner := &mockSigner{key: remoteKey}
+
+	fundingScript, fundingOutput, err := input.GenFundingPkScript(
+		rootKey.PubKey().SerializeCompressed(),
+		remoteKey.PubKey().SerializeCompressed(),
+		int64(channel.Capacity),
+	)
+	if err != nil {
+		t.Fatalf("unable to create funding script: %v", err)
+	}
+	fetchFundingOutput := func([]byte) (*wire.TxOut, error) {
+		return fundingOutput, nil
+	}
+	remotePkScript := []byte{txscript.OP_TRUE}
+	isOurScript := func(_ uint16, pkScript []byte) bool {
+		return bytes.Equal(pkScript, coinPkScript)
+	}
+
+	// spendFunding returns a transaction spending the funding output to
+	// the given outputs, along with the signature of the remote party.
+	spendFunding := func(outputs ...*wire.TxOut) (*wire.MsgTx,
+		input.Signature) {
+
+		tx := wire.NewMsgTx()
+		tx.Version = input.LNTxVersion
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: channel.FundingOutpoint,
+		})
+		for _, output := range outputs {
+			tx.AddTxOut(output)
+		}
+
+		remoteSig, err := remoteSigner.SignOutputRaw(
+			tx, &input.SignDescriptor{
+				KeyDesc: keychain.KeyDescriptor{
+					PubKey: remoteKey.PubKey(),
+				},
+				WitnessScript: fundingScript,
+				Output:        fundingOutput,
+				HashType:      txscript.SigHashAll,
+			},
+		)
+		if err != nil {
+			t.Fatalf("unable to sign: %v", err)
+		}
+
+		return tx, remoteSig
+	}
+
+	// The latest state of the channel, in the commitment of the remote
+	// party, gives us 400000 atoms, the remote party 590000 and pays a
+	// fee of 10000 atoms.
+	toRemoteScript, _, err := lnwallet.CommitScriptToRemote(
+		channel.ChanType, rootKey.PubKey(),
+	)
+	if err != nil {
+		t.Fatalf("unable to create to-remote script: %v", err)
+	}
+	commitTx, remoteCommitSig := spendFunding(&wire.TxOut{
+		Value:    400000,
+		PkScript: toRemoteScript.PkScript,
+	}, &wire.TxOut{
+		Value:    590000,
+		PkScript: remotePkScript,
+	})
+
+	// Unless it's signed by both parties, the commitment doesn't prove
+	// the latest state.
+	rescueTx, remoteSig := spendFunding(&wire.TxOut{
+		Value:    400000,
+		PkScript: coinPkScript,
+	}, &wire.TxOut{
+		Value:    590000,
+		PkScript: remotePkScript,
+	})
+	_, _, err = signChannelRescue(
+		channel, rescueTx, commitTx, nil, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err == nil {
+		t.Fatalf("expected unsigned commitment to be rejected")
+	}
+	localCommitSig, err := signer.SignOutputRaw(
+		commitTx, &input.SignDescriptor{
+			KeyDesc:       channel.LocalChanCfg.MultiSigKey,
+			WitnessScript: fundingScript,
+			Output:        fundingOutput,
+			HashType:      txscript.SigHashAll,
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to sign commitment: %v", err)
+	}
+	sigScript, err := input.WitnessStackToSigScript(input.SpendMultiSig(
+		fundingScript, rootKey.PubKey().SerializeCompressed(),
+		localCommitSig, remoteKey.PubKey().SerializeCompressed(),
+		remoteCommitSig,
+	))
+	if err != nil {
+		t.Fatalf("unable to create signature script: %v", err)
+	}
+	commitTx.TxIn[0].SignatureScript = sigScript
+
+	// Without the signature of the remote party, only ours is returned.
+	localSig, _, err := signChannelRescue(
+		channel, rescueTx, commitTx, nil, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err != nil {
+		t.Fatalf("unable to sign rescue tx: %v", err)
+	}
+	if rescueTx.TxIn[0].SignatureScript != nil {
+		t.Fatalf("expected no signature script")
+	}
+
+	// An invalid signature of the remote party is rejected.
+	_, _, err = signChannelRescue(
+		channel, rescueTx, commitTx, localSig, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err == nil {
+		t.Fatalf("expected invalid remote signature to be rejected")
+	}
+	if rescueTx.TxIn[0].SignatureScript != nil {
+		t.Fatalf("expected no signature script")
+	}
+
+	// With both signatures, the funding output is validly spent.
+	localSig2, _, err := signChannelRescue(
+		channel, rescueTx, commitTx, remoteSig, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err != nil {
+		t.Fatalf("unable to sign rescue tx: %v", err)
+	}
+	if !bytes.Equal(localSig.Serialize(), localSig2.Serialize()) {
+		t.Fatalf("signatures don't match")
+	}
+	assertScriptExecution(t, fundingOutput.PkScript, rescueTx, 0)
+
+	// As the initiator, we bear any increase of the fee, but the remote
+	// party can't be paid more than the latest state gives it.
+	rescueTx, _ = spendFunding(&wire.TxOut{
+		Value:    395000,
+		PkScript: coinPkScript,
+	}, &wire.TxOut{
+		Value:    590000,
+		PkScript: remotePkScript,
+	})
+	_, _, err = signChannelRescue(
+		channel, rescueTx, commitTx, nil, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err != nil {
+		t.Fatalf("unable to sign rescue tx: %v", err)
+	}
+	rescueTx, _ = spendFunding(&wire.TxOut{
+		Value:    390000,
+		PkScript: coinPkScript,
+	}, &wire.TxOut{
+		Value:    600000,
+		PkScript: remotePkScript,
+	})
+	_, _, err = signChannelRescue(
+		channel, rescueTx, commitTx, nil, fetchFundingOutput,
+		isOurScript, signer,
+	)
+	if err == nil {
+		t.Fatalf("expected underpaying rescue tx to be rejected")
+	}
+
+	// A funding output that doesn't match the backup is never spent.
+	_, _, err = signChannelRescue(
+		channel, rescueTx, commitTx, nil,
+		func([]byte) (*wire.TxOut, error) {
+			return &wire.TxOut{
+				Value:    fundingOutput.Value,
+				PkScript: coinPkScript,
+			}, nil
+		}, isOurScript, signer,
+	)
+	if err == nil {
+		t.Fatalf("expected mismatching funding output to be " +
+			"rejected")
+	}
+}
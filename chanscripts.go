@@ -0,0 +1,302 @@
+package dcrlnd
+
+import (
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/reorgnotifier"
+	"github.com/decred/dcrlnd/subscribe"
+)
+
+// chanScriptIndex collects the output scripts of our channels that third
+// parties could pay to: the funding scripts of our channels, the delivery
+// scripts of channels that are being cooperatively closed and the wallet
+// outputs of the cooperative closes that already confirmed.
+//
+// The index is built from the database on demand and cached until a channel
+// event signals that the set of channels or their scripts changed.
+type chanScriptIndex struct {
+	started sync.Once
+	stopped sync.Once
+
+	chanDB                 *channeldb.DB
+	chainIO                lnwallet.BlockChainIO
+	isOurAddress           func(dcrutil.Address) bool
+	netParams              *chaincfg.Params
+	subscribeChannelEvents func() (*subscribe.Client, error)
+
+	mtx sync.Mutex
+
+	// closeScripts caches the wallet outputs of the cooperative closing
+	// transactions, keyed by the closing txid, as they never change.
+	closeScripts map[chainhash.Hash][][]byte
+
+	// scripts is the cached index, nil if it must be rebuilt.
+	scripts *reorgnotifier.ChannelScripts
+
+	// generation is increased whenever the cached index is invalidated, so
+	// that an index built concurrently with a channel event isn't cached.
+	generation uint64
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// newChanScriptIndex creates a new index of our channel scripts.
+func newChanScriptIndex(chanDB *channeldb.DB, chainIO lnwallet.BlockChainIO,
+	isOurAddress func(dcrutil.Address) bool, netParams *chaincfg.Params,
+	subscribeEvents func() (*subscribe.Client, error)) *chanScriptIndex {
+
+	return &chanScriptIndex{
+		chanDB:                 chanDB,
+		chainIO:                chainIO,
+		isOurAddress:           isOurAddress,
+		netParams:              netParams,
+		subscribeChannelEvents: subscribeEvents,
+		closeScripts:           make(map[chainhash.Hash][][]byte),
+		quit:                   make(chan struct{}),
+	}
+}
+
+// Start subscribes to the channel events that invalidate the cached index.
+func (c *chanScriptIndex) Start() error {
+	var err error
+	c.started.Do(func() {
+		var events *subscribe.Client
+		events, err = c.subscribeChannelEvents()
+		if err != nil {
+			return
+		}
+
+		c.wg.Add(1)
+		go c.invalidateOnChannelEvents(events)
+	})
+
+	return err
+}
+
+// Stop stops watching the channel events.
+func (c *chanScriptIndex) Stop() {
+	c.stopped.Do(func() {
+		close(c.quit)
+		c.wg.Wait()
+	})
+}
+
+// invalidateOnChannelEvents drops the cached index whenever a channel is
+// opened or closed, or a cooperative close starts.
+//
+// NOTE: This MUST be run as a goroutine.
+func (c *chanScriptIndex) invalidateOnChannelEvents(events *subscribe.Client) {
+	defer c.wg.Done()
+	defer events.Cancel()
+
+	for {
+		select {
+		case e, ok := <-events.Updates():
+			if !ok {
+				return
+			}
+
+			switch e.(type) {
+			case channelnotifier.PendingOpenChannelEvent,
+				channelnotifier.OpenChannelEvent,
+				channelnotifier.ClosedChannelEvent,
+				channelnotifier.CloseReasonEvent:
+
+				c.invalidate()
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// invalidate drops the cached index, so that it's rebuilt on the next fetch.
+func (c *chanScriptIndex) invalidate() {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.scripts = nil
+	c.generation++
+}
+
+// fetch returns the current output scripts of our channels.
+func (c *chanScriptIndex) fetch() (*reorgnotifier.ChannelScripts, error) {
+	c.mtx.Lock()
+	scripts, generation := c.scripts, c.generation
+	c.mtx.Unlock()
+
+	if scripts != nil {
+		return scripts, nil
+	}
+
+	scripts, complete, err := c.build()
+	if err != nil {
+		return nil, err
+	}
+
+	// An index missing the scripts of a closing transaction that couldn't
+	// be looked up isn't cached, so that the lookup is retried.
+	c.mtx.Lock()
+	if complete && generation == c.generation {
+		c.scripts = scripts
+	}
+	c.mtx.Unlock()
+
+	return scripts, nil
+}
+
+// build creates the index of the output scripts of our channels out of the
+// database. The returned boolean is false if the wallet outputs of some
+// cooperative closes couldn't be determined.
+func (c *chanScriptIndex) build() (*reorgnotifier.ChannelScripts, bool,
+	error) {
+
+	scripts := reorgnotifier.NewChannelScripts()
+
+	channels, err := c.chanDB.FetchAllChannels()
+	if err != nil {
+		return nil, false, err
+	}
+	for _, channel := range channels {
+		chanPoint := channel.FundingOutpoint
+		scripts.AddChannel(chanPoint)
+
+		localKey := channel.LocalChanCfg.MultiSigKey.PubKey
+		remoteKey := channel.RemoteChanCfg.MultiSigKey.PubKey
+		if localKey != nil && remoteKey != nil {
+			_, fundingOutput, err := input.GenFundingPkScript(
+				localKey.SerializeCompressed(),
+				remoteKey.SerializeCompressed(),
+				int64(channel.Capacity),
+			)
+			if err != nil {
+				return nil, false, err
+			}
+			scripts.AddScript(
+				fundingOutput.PkScript, chanPoint,
+				reorgnotifier.DepositFunding,
+			)
+		}
+
+		if len(channel.LocalShutdownScript) > 0 {
+			scripts.AddScript(
+				channel.LocalShutdownScript, chanPoint,
+				reorgnotifier.DepositClosing,
+			)
+		}
+
+		info, err := channel.ShutdownInfo()
+		switch err {
+		case nil:
+			scripts.AddScript(
+				info.DeliveryScript, chanPoint,
+				reorgnotifier.DepositClosing,
+			)
+
+		case channeldb.ErrNoShutdownInfo:
+
+		default:
+			return nil, false, err
+		}
+	}
+
+	closed, err := c.chanDB.FetchClosedChannels(false)
+	if err != nil {
+		return nil, false, err
+	}
+	complete := true
+	for _, summary := range closed {
+		scripts.AddChannel(summary.ChanPoint)
+
+		if summary.CloseType != channeldb.CooperativeClose ||
+			summary.CloseHeight == 0 {
+
+			continue
+		}
+
+		pkScripts, ok := c.coopCloseScripts(summary)
+		if !ok {
+			complete = false
+		}
+		for _, pkScript := range pkScripts {
+			scripts.AddScript(
+				pkScript, summary.ChanPoint,
+				reorgnotifier.DepositClosing,
+			)
+		}
+	}
+
+	return scripts, complete, nil
+}
+
+// coopCloseScripts returns the scripts of the wallet outputs of the given
+// cooperative close. The closing transaction is looked up in the block at the
+// close height. Failures are logged and reported by the returned boolean, so
+// that the lookup is retried on the next call.
+func (c *chanScriptIndex) coopCloseScripts(
+	summary *channeldb.ChannelCloseSummary) ([][]byte, bool) {
+
+	c.mtx.Lock()
+	pkScripts, ok := c.closeScripts[summary.ClosingTXID]
+	c.mtx.Unlock()
+
+	if ok {
+		return pkScripts, true
+	}
+
+	blockHash, err := c.chainIO.GetBlockHash(int64(summary.CloseHeight))
+	if err != nil {
+		srvrLog.Debugf("Unable to fetch block at close height of "+
+			"ChannelPoint(%v): %v", summary.ChanPoint, err)
+		return nil, false
+	}
+	block, err := c.chainIO.GetBlock(blockHash)
+	if err != nil {
+		srvrLog.Debugf("Unable to fetch block %v: %v", blockHash, err)
+		return nil, false
+	}
+
+	var closeTx *wire.MsgTx
+	for _, tx := range block.Transactions {
+		if tx.TxHash() == summary.ClosingTXID {
+			closeTx = tx
+			break
+		}
+	}
+	if closeTx == nil {
+		srvrLog.Debugf("Closing tx %v of ChannelPoint(%v) not found "+
+			"at height %d", summary.ClosingTXID, summary.ChanPoint,
+			summary.CloseHeight)
+		return nil, false
+	}
+
+	for _, txOut := range closeTx.TxOut {
+		_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+			txOut.Version, txOut.PkScript, c.netParams, false,
+		)
+		if err != nil || len(addrs) != 1 {
+			continue
+		}
+
+		if c.isOurAddress(addrs[0]) {
+			pkScripts = append(pkScripts, txOut.PkScript)
+		}
+	}
+
+	c.mtx.Lock()
+	c.closeScripts[summary.ClosingTXID] = pkScripts
+	c.mtx.Unlock()
+
+	return pkScripts, true
+}
@@ -0,0 +1,74 @@
+package dcrlnd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
+	"github.com/decred/dcrlnd/lntest/wait"
+)
+
+// TestChanScriptIndexCache asserts that the index of channel scripts is cached
+// until a channel event invalidates it.
+func TestChanScriptIndexCache(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := makeTestChannelDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanUp()
+
+	chanNotifier := channelnotifier.New(db)
+	if err := chanNotifier.Start(); err != nil {
+		t.Fatalf("unable to start channel notifier: %v", err)
+	}
+	defer chanNotifier.Stop()
+
+	index := newChanScriptIndex(
+		db, nil, func(dcrutil.Address) bool { return false },
+		chaincfg.RegNetParams(), chanNotifier.SubscribeChannelEvents,
+	)
+	if err := index.Start(); err != nil {
+		t.Fatalf("unable to start index: %v", err)
+	}
+	defer index.Stop()
+
+	scripts, err := index.fetch()
+	if err != nil {
+		t.Fatalf("unable to fetch scripts: %v", err)
+	}
+	cached, err := index.fetch()
+	if err != nil {
+		t.Fatalf("unable to fetch scripts: %v", err)
+	}
+	if cached != scripts {
+		t.Fatalf("expected the index to be cached")
+	}
+
+	// A channel starting to close invalidates the index.
+	chanNotifier.NotifyCloseReasonEvent(
+		wire.OutPoint{Index: 1}, &channeldb.ChannelCloseReason{
+			Code:      channeldb.CloseReasonShutdown,
+			Timestamp: time.Now(),
+		},
+	)
+	err = wait.NoError(func() error {
+		rebuilt, err := index.fetch()
+		if err != nil {
+			return err
+		}
+		if rebuilt == scripts {
+			return errors.New("index not invalidated")
+		}
+		return nil
+	}, time.Second*5)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package dcrlnd
+
+import (
+	"encoding/hex"
+
+	"github.com/decred/dcrd/blockchain/stake/v3"
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/reorgnotifier"
+)
+
+// chanTx describes how a wallet transaction relates to the channels of the
+// node.
+type chanTx struct {
+	txType lnrpc.TransactionType
+
+	// chanPoint is the channel funded or closed by the transaction, nil for
+	// sweeps.
+	chanPoint *wire.OutPoint
+}
+
+// chanTxSource gives access to the channels and sweeps of the node, used to
+// determine which transactions relate to its channels.
+type chanTxSource struct {
+	// listSweeps returns the hashes of all sweeps published by the node.
+	listSweeps func() ([]chainhash.Hash, error)
+
+	// isSweepTx returns whether the given transaction is a sweep published
+	// by the node.
+	isSweepTx func(chainhash.Hash) (bool, error)
+
+	// fetchAllChannels returns the channels that are pending, open or
+	// waiting for their closing transaction to confirm.
+	fetchAllChannels func() ([]*channeldb.OpenChannel, error)
+
+	// fetchChannel returns the channel funded by the given outpoint among
+	// those returned by fetchAllChannels.
+	fetchChannel func(wire.OutPoint) (*channeldb.OpenChannel, error)
+
+	// fetchClosedChannels returns the close summaries of all closed
+	// channels.
+	fetchClosedChannels func(bool) ([]*channeldb.ChannelCloseSummary, error)
+
+	// fetchClosedChannel returns the close summary of the channel funded
+	// by the given outpoint.
+	fetchClosedChannel func(*wire.OutPoint) (*channeldb.ChannelCloseSummary,
+		error)
+
+	// broadcastedCooperative returns the cooperative closing transaction
+	// we broadcast for the given channel, if any.
+	broadcastedCooperative func(*channeldb.OpenChannel) (*wire.MsgTx, error)
+
+	// broadcastedCommitment returns the commitment transaction we
+	// broadcast for the given channel, if any.
+	broadcastedCommitment func(*channeldb.OpenChannel) (*wire.MsgTx, error)
+}
+
+// chanTxSource returns the source of the channel transactions of the node.
+func (r *rpcServer) chanTxSource() *chanTxSource {
+	return &chanTxSource{
+		listSweeps:             r.server.sweeper.ListSweeps,
+		isSweepTx:              r.server.sweeper.IsSweepTx,
+		fetchAllChannels:       r.server.remoteChanDB.FetchAllChannels,
+		fetchChannel:           r.server.remoteChanDB.FetchChannel,
+		fetchClosedChannels:    r.server.remoteChanDB.FetchClosedChannels,
+		fetchClosedChannel:     r.server.remoteChanDB.FetchClosedChannel,
+		broadcastedCooperative: (*channeldb.OpenChannel).BroadcastedCooperative,
+		broadcastedCommitment:  (*channeldb.OpenChannel).BroadcastedCommitment,
+	}
+}
+
+// closeTxType returns the transaction type matching the close type of a
+// channel, or false if the channel was closed without a closing transaction.
+func closeTxType(closeType channeldb.ClosureType) (lnrpc.TransactionType,
+	bool) {
+
+	switch closeType {
+	case channeldb.CooperativeClose:
+		return lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE, true
+
+	case channeldb.LocalForceClose, channeldb.RemoteForceClose,
+		channeldb.BreachClose:
+
+		return lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE, true
+
+	// Canceled and abandoned channels have no closing transaction.
+	default:
+		return lnrpc.TransactionType_TX_TYPE_UNKNOWN, false
+	}
+}
+
+// fetchChanTxs cross-references channeldb and the sweeper to determine which
+// transactions fund, close or sweep channels, keyed by their txid string.
+func fetchChanTxs(src *chanTxSource) (map[string]*chanTx, error) {
+	chanTxs := make(map[string]*chanTx)
+	addTx := func(txid string, txType lnrpc.TransactionType,
+		chanPoint *wire.OutPoint) {
+
+		// A batch funding transaction funds several channels, only the
+		// first one found is reported.
+		if _, ok := chanTxs[txid]; ok {
+			return
+		}
+		chanTxs[txid] = &chanTx{
+			txType:    txType,
+			chanPoint: chanPoint,
+		}
+	}
+
+	sweeps, err := src.listSweeps()
+	if err != nil {
+		return nil, err
+	}
+	for _, sweep := range sweeps {
+		addTx(sweep.String(), lnrpc.TransactionType_TX_TYPE_SWEEP, nil)
+	}
+
+	// Channels that are still pending, open or waiting for their closing
+	// transaction to confirm.
+	channels, err := src.fetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
+		chanPoint := channel.FundingOutpoint
+		addTx(
+			chanPoint.Hash.String(),
+			lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING,
+			&chanPoint,
+		)
+
+		// The closing transactions of channels waiting to be closed
+		// are only stored if we broadcast them.
+		if tx, err := src.broadcastedCooperative(channel); err == nil {
+			addTx(
+				tx.TxHash().String(),
+				lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE,
+				&chanPoint,
+			)
+		}
+		if tx, err := src.broadcastedCommitment(channel); err == nil {
+			addTx(
+				tx.TxHash().String(),
+				lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE,
+				&chanPoint,
+			)
+		}
+	}
+
+	closed, err := src.fetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range closed {
+		chanPoint := summary.ChanPoint
+		addTx(
+			chanPoint.Hash.String(),
+			lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING,
+			&chanPoint,
+		)
+
+		closeType, ok := closeTxType(summary.CloseType)
+		if !ok {
+			continue
+		}
+		addTx(summary.ClosingTXID.String(), closeType, &chanPoint)
+	}
+
+	return chanTxs, nil
+}
+
+// lookupChanTx determines whether a single transaction funds, closes or sweeps
+// a channel. Unlike fetchChanTxs, only the channels funded or spent by the
+// transaction are looked up, so that it can be used for every new wallet
+// transaction. Nil is returned if the transaction is unrelated to any channel.
+func lookupChanTx(src *chanTxSource, tx *wire.MsgTx) (*chanTx, error) {
+	isSweep, err := src.isSweepTx(tx.TxHash())
+	if err != nil {
+		return nil, err
+	}
+	if isSweep {
+		return &chanTx{txType: lnrpc.TransactionType_TX_TYPE_SWEEP}, nil
+	}
+
+	// findChannel returns whether the outpoint funds a channel that is
+	// still known to channeldb, along with its close summary if it was
+	// closed.
+	findChannel := func(op wire.OutPoint) (bool,
+		*channeldb.ChannelCloseSummary, error) {
+
+		summary, err := src.fetchClosedChannel(&op)
+		switch {
+		case err == nil:
+			return true, summary, nil
+
+		case err != channeldb.ErrClosedChannelNotFound:
+			return false, nil, err
+		}
+
+		_, err = src.fetchChannel(op)
+		switch err {
+		case nil:
+			return true, nil, nil
+
+		case channeldb.ErrChannelNotFound, channeldb.ErrNoActiveChannels:
+			return false, nil, nil
+
+		default:
+			return false, nil, err
+		}
+	}
+
+	// A transaction spending a funding output closes that channel.
+	for _, txIn := range tx.TxIn {
+		chanPoint := txIn.PreviousOutPoint
+		found, summary, err := findChannel(chanPoint)
+		if err != nil {
+			return nil, err
+		}
+		if !found {
+			continue
+		}
+
+		if summary != nil {
+			closeType, ok := closeTxType(summary.CloseType)
+			if ok {
+				return &chanTx{
+					txType:    closeType,
+					chanPoint: &chanPoint,
+				}, nil
+			}
+		}
+
+		// The channel isn't closed yet. As in the chain watcher, only
+		// cooperative closing transactions use the max sequence
+		// number, commitment transactions encode the obscured state
+		// number in it.
+		txType := lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE
+		if txIn.Sequence == wire.MaxTxInSequenceNum {
+			txType = lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE
+		}
+		return &chanTx{txType: txType, chanPoint: &chanPoint}, nil
+	}
+
+	// Otherwise, a transaction creating a funding output funds that
+	// channel. As for fetchChanTxs, only the first channel of a batch is
+	// reported.
+	txHash := tx.TxHash()
+	for i := range tx.TxOut {
+		chanPoint := wire.OutPoint{
+			Hash:  txHash,
+			Index: uint32(i),
+			Tree:  wire.TxTreeRegular,
+		}
+		found, _, err := findChannel(chanPoint)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return &chanTx{
+				txType:    lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING,
+				chanPoint: &chanPoint,
+			}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// tagChanTx sets the type and channel point of a transaction found in the
+// given channel transactions.
+func tagChanTx(tx *lnrpc.Transaction, chanTxs map[string]*chanTx) {
+	info, ok := chanTxs[tx.TxHash]
+	if !ok {
+		return
+	}
+
+	setChanTx(tx, info)
+}
+
+// tagNewChanTx sets the type and channel point of a new wallet transaction
+// relating to a channel, looking up only the channels it funds or spends.
+// Failures are logged and leave the transaction untagged, so that they don't
+// interrupt the stream of wallet transactions.
+func tagNewChanTx(src *chanTxSource, tx *lnrpc.Transaction, rawTx []byte) {
+	var msgTx wire.MsgTx
+	if err := msgTx.FromBytes(rawTx); err != nil {
+		rpcsLog.Errorf("Unable to decode transaction %v: %v",
+			tx.TxHash, err)
+		return
+	}
+
+	info, err := lookupChanTx(src, &msgTx)
+	if err != nil {
+		rpcsLog.Errorf("Unable to look up channel of transaction %v: "+
+			"%v", tx.TxHash, err)
+		return
+	}
+	if info == nil {
+		return
+	}
+
+	setChanTx(tx, info)
+}
+
+// setChanTx sets the type and channel point of a transaction.
+func setChanTx(tx *lnrpc.Transaction, info *chanTx) {
+	tx.TxType = info.txType
+	if info.chanPoint != nil {
+		tx.ChannelPoint = info.chanPoint.String()
+	}
+}
+
+// stakeTxType classifies the transactions of the staking and treasury
+// subsystems. Regular transactions, and transactions that fail to decode, are
+// reported as TX_TYPE_UNKNOWN.
+func stakeTxType(rawTx []byte) lnrpc.TransactionType {
+	var tx wire.MsgTx
+	if err := tx.FromBytes(rawTx); err != nil {
+		return lnrpc.TransactionType_TX_TYPE_UNKNOWN
+	}
+
+	switch stake.DetermineTxType(&tx, true) {
+	case stake.TxTypeSStx:
+		return lnrpc.TransactionType_TX_TYPE_TICKET
+
+	case stake.TxTypeSSGen:
+		return lnrpc.TransactionType_TX_TYPE_VOTE
+
+	case stake.TxTypeSSRtx:
+		return lnrpc.TransactionType_TX_TYPE_REVOCATION
+
+	case stake.TxTypeTAdd, stake.TxTypeTSpend, stake.TxTypeTreasuryBase:
+		return lnrpc.TransactionType_TX_TYPE_TREASURY
+
+	default:
+		return lnrpc.TransactionType_TX_TYPE_UNKNOWN
+	}
+}
+
+// tagStakeTx sets the type of a transaction of the staking or treasury
+// subsystems.
+func tagStakeTx(tx *lnrpc.Transaction) {
+	rawTx, err := hex.DecodeString(tx.RawTxHex)
+	if err != nil {
+		return
+	}
+
+	txType := stakeTxType(rawTx)
+	if txType != lnrpc.TransactionType_TX_TYPE_UNKNOWN {
+		tx.TxType = txType
+	}
+}
+
+// tagDepositTx sets the type and channel point of a transaction of a third
+// party paying to one of the given channel scripts. Transactions already
+// tagged are left untouched, as are all transactions when scripts is nil.
+func tagDepositTx(tx *lnrpc.Transaction,
+	scripts *reorgnotifier.ChannelScripts) {
+
+	if scripts == nil || tx.TxType != lnrpc.TransactionType_TX_TYPE_UNKNOWN {
+		return
+	}
+
+	rawTx, err := hex.DecodeString(tx.RawTxHex)
+	if err != nil {
+		return
+	}
+	var msgTx wire.MsgTx
+	if err := msgTx.FromBytes(rawTx); err != nil {
+		return
+	}
+
+	deposits := scripts.Deposits(&msgTx)
+	if len(deposits) == 0 {
+		return
+	}
+
+	tx.TxType = lnrpc.TransactionType_TX_TYPE_CHANNEL_ADDRESS_DEPOSIT
+	tx.ChannelPoint = deposits[0].ChanPoint.String()
+}
@@ -0,0 +1,410 @@
+package dcrlnd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+var errNotBroadcast = errors.New("not broadcast")
+
+// mockChanTxSource returns a chanTxSource backed by the given sweeps, channels
+// and close summaries. The closing transactions broadcast for open channels
+// are keyed by their funding outpoint.
+func mockChanTxSource(sweeps []chainhash.Hash,
+	channels []*channeldb.OpenChannel,
+	closed []*channeldb.ChannelCloseSummary,
+	coopTxs, commitTxs map[wire.OutPoint]*wire.MsgTx) *chanTxSource {
+
+	broadcasted := func(txs map[wire.OutPoint]*wire.MsgTx) func(
+		*channeldb.OpenChannel) (*wire.MsgTx, error) {
+
+		return func(c *channeldb.OpenChannel) (*wire.MsgTx, error) {
+			tx, ok := txs[c.FundingOutpoint]
+			if !ok {
+				return nil, errNotBroadcast
+			}
+			return tx, nil
+		}
+	}
+
+	return &chanTxSource{
+		listSweeps: func() ([]chainhash.Hash, error) {
+			return sweeps, nil
+		},
+		isSweepTx: func(hash chainhash.Hash) (bool, error) {
+			for _, sweep := range sweeps {
+				if sweep == hash {
+					return true, nil
+				}
+			}
+			return false, nil
+		},
+		fetchAllChannels: func() ([]*channeldb.OpenChannel, error) {
+			return channels, nil
+		},
+		fetchChannel: func(op wire.OutPoint) (*channeldb.OpenChannel,
+			error) {
+
+			for _, c := range channels {
+				if c.FundingOutpoint == op {
+					return c, nil
+				}
+			}
+			return nil, channeldb.ErrChannelNotFound
+		},
+		fetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return closed, nil
+		},
+		fetchClosedChannel: func(op *wire.OutPoint) (
+			*channeldb.ChannelCloseSummary, error) {
+
+			for _, summary := range closed {
+				if summary.ChanPoint == *op {
+					return summary, nil
+				}
+			}
+			return nil, channeldb.ErrClosedChannelNotFound
+		},
+		broadcastedCooperative: broadcasted(coopTxs),
+		broadcastedCommitment:  broadcasted(commitTxs),
+	}
+}
+
+// spendTx returns a transaction spending the given outpoint with the given
+// sequence number.
+func spendTx(op wire.OutPoint, sequence uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: op, Sequence: sequence})
+	tx.AddTxOut(&wire.TxOut{Value: 1000})
+	return tx
+}
+
+// fundingTx returns a transaction with the given number of outputs, spending
+// an unrelated outpoint.
+func fundingTx(numOutputs int) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Index: 7}})
+	for i := 0; i < numOutputs; i++ {
+		tx.AddTxOut(&wire.TxOut{Value: int64(1000 * (i + 1))})
+	}
+	return tx
+}
+
+// assertChanTx asserts that a channel transaction has the given type and
+// channel point.
+func assertChanTx(t *testing.T, name string, info *chanTx,
+	txType lnrpc.TransactionType, chanPoint *wire.OutPoint) {
+
+	t.Helper()
+
+	if info == nil {
+		t.Fatalf("%s: expected %v transaction, got none", name, txType)
+	}
+	if info.txType != txType {
+		t.Fatalf("%s: expected %v transaction, got %v", name, txType,
+			info.txType)
+	}
+	switch {
+	case chanPoint == nil && info.chanPoint != nil:
+		t.Fatalf("%s: expected no channel point, got %v", name,
+			info.chanPoint)
+
+	case chanPoint != nil && (info.chanPoint == nil ||
+		*info.chanPoint != *chanPoint):
+
+		t.Fatalf("%s: expected channel point %v, got %v", name,
+			chanPoint, info.chanPoint)
+	}
+}
+
+// TestChanTxSweep asserts that sweeps are reported without a channel point.
+func TestChanTxSweep(t *testing.T) {
+	sweep := spendTx(wire.OutPoint{Index: 9}, 0)
+	src := mockChanTxSource(
+		[]chainhash.Hash{sweep.TxHash()}, nil, nil, nil, nil,
+	)
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	assertChanTx(
+		t, "fetch", chanTxs[sweep.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_SWEEP, nil,
+	)
+
+	info, err := lookupChanTx(src, sweep)
+	if err != nil {
+		t.Fatalf("unable to look up transaction: %v", err)
+	}
+	assertChanTx(t, "lookup", info, lnrpc.TransactionType_TX_TYPE_SWEEP, nil)
+}
+
+// TestChanTxBatchFunding asserts that a transaction funding several channels
+// is reported for a single one of them: the first channel found when fetching
+// all the channel transactions, and the first funding output when looking it
+// up.
+func TestChanTxBatchFunding(t *testing.T) {
+	funding := fundingTx(3)
+	firstPoint := wire.OutPoint{Hash: funding.TxHash(), Index: 0}
+	secondPoint := wire.OutPoint{Hash: funding.TxHash(), Index: 2}
+
+	channels := []*channeldb.OpenChannel{
+		{FundingOutpoint: secondPoint},
+		{FundingOutpoint: firstPoint},
+	}
+	src := mockChanTxSource(nil, channels, nil, nil, nil)
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	if len(chanTxs) != 1 {
+		t.Fatalf("expected 1 channel transaction, got %d",
+			len(chanTxs))
+	}
+	assertChanTx(
+		t, "fetch", chanTxs[funding.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING, &secondPoint,
+	)
+
+	info, err := lookupChanTx(src, funding)
+	if err != nil {
+		t.Fatalf("unable to look up transaction: %v", err)
+	}
+	assertChanTx(
+		t, "lookup", info,
+		lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING, &firstPoint,
+	)
+}
+
+// TestLookupChanTxOpenChannel asserts that the closing transactions of open
+// channels are told apart by their sequence number, as only cooperative
+// closes use the max sequence.
+func TestLookupChanTxOpenChannel(t *testing.T) {
+	chanPoint := wire.OutPoint{Hash: fundingTx(1).TxHash()}
+	src := mockChanTxSource(
+		nil, []*channeldb.OpenChannel{{FundingOutpoint: chanPoint}},
+		nil, nil, nil,
+	)
+
+	tests := []struct {
+		name     string
+		sequence uint32
+		txType   lnrpc.TransactionType
+	}{
+		{
+			name:     "coop close",
+			sequence: wire.MaxTxInSequenceNum,
+			txType:   lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE,
+		},
+		{
+			name:     "force close",
+			sequence: 0x80000001,
+			txType:   lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE,
+		},
+	}
+	for _, test := range tests {
+		info, err := lookupChanTx(src, spendTx(chanPoint, test.sequence))
+		if err != nil {
+			t.Fatalf("%s: unable to look up transaction: %v",
+				test.name, err)
+		}
+		assertChanTx(t, test.name, info, test.txType, &chanPoint)
+	}
+}
+
+// TestFetchChanTxsBroadcastClose asserts that the closing transactions we
+// broadcast for channels waiting to be closed are found.
+func TestFetchChanTxsBroadcastClose(t *testing.T) {
+	chanPoint := wire.OutPoint{Hash: fundingTx(1).TxHash()}
+	coopClose := spendTx(chanPoint, wire.MaxTxInSequenceNum)
+	commitTx := spendTx(chanPoint, 0x80000002)
+
+	src := mockChanTxSource(
+		nil, []*channeldb.OpenChannel{{FundingOutpoint: chanPoint}},
+		nil, map[wire.OutPoint]*wire.MsgTx{chanPoint: coopClose},
+		map[wire.OutPoint]*wire.MsgTx{chanPoint: commitTx},
+	)
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	assertChanTx(
+		t, "coop close", chanTxs[coopClose.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_COOPERATIVE_CLOSE, &chanPoint,
+	)
+	assertChanTx(
+		t, "commitment", chanTxs[commitTx.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE, &chanPoint,
+	)
+}
+
+// TestChanTxClosedChannel asserts that the close summary of a closed channel
+// determines the type of its closing transaction, regardless of its sequence
+// number.
+func TestChanTxClosedChannel(t *testing.T) {
+	funding := fundingTx(1)
+	chanPoint := wire.OutPoint{Hash: funding.TxHash()}
+	closeTx := spendTx(chanPoint, wire.MaxTxInSequenceNum)
+
+	src := mockChanTxSource(
+		nil, nil, []*channeldb.ChannelCloseSummary{{
+			ChanPoint:   chanPoint,
+			ClosingTXID: closeTx.TxHash(),
+			CloseType:   channeldb.RemoteForceClose,
+		}}, nil, nil,
+	)
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	assertChanTx(
+		t, "fetch funding", chanTxs[funding.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING, &chanPoint,
+	)
+	assertChanTx(
+		t, "fetch close", chanTxs[closeTx.TxHash().String()],
+		lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE, &chanPoint,
+	)
+
+	info, err := lookupChanTx(src, funding)
+	if err != nil {
+		t.Fatalf("unable to look up transaction: %v", err)
+	}
+	assertChanTx(
+		t, "lookup funding", info,
+		lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING, &chanPoint,
+	)
+
+	info, err = lookupChanTx(src, closeTx)
+	if err != nil {
+		t.Fatalf("unable to look up transaction: %v", err)
+	}
+	assertChanTx(
+		t, "lookup close", info,
+		lnrpc.TransactionType_TX_TYPE_FORCE_CLOSE, &chanPoint,
+	)
+}
+
+// TestFetchChanTxsAbandoned asserts that only the funding transaction of an
+// abandoned channel is reported, as it has no closing transaction.
+func TestFetchChanTxsAbandoned(t *testing.T) {
+	chanPoint := wire.OutPoint{Hash: chainhash.Hash{0xab}}
+	src := mockChanTxSource(
+		nil, nil, []*channeldb.ChannelCloseSummary{{
+			ChanPoint: chanPoint,
+			CloseType: channeldb.Abandoned,
+		}}, nil, nil,
+	)
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	if len(chanTxs) != 1 {
+		t.Fatalf("expected 1 channel transaction, got %d",
+			len(chanTxs))
+	}
+	assertChanTx(
+		t, "funding", chanTxs[chanPoint.Hash.String()],
+		lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING, &chanPoint,
+	)
+}
+
+// TestLookupChanTxUnrelated asserts that transactions unrelated to any channel
+// aren't reported, while channeldb errors other than missing channels are
+// returned.
+func TestLookupChanTxUnrelated(t *testing.T) {
+	src := mockChanTxSource(nil, nil, nil, nil, nil)
+	unrelated := fundingTx(3)
+
+	info, err := lookupChanTx(src, unrelated)
+	if err != nil {
+		t.Fatalf("unable to look up transaction: %v", err)
+	}
+	if info != nil {
+		t.Fatalf("expected unrelated transaction, got %v", info.txType)
+	}
+
+	dbErr := errors.New("db error")
+	src.fetchChannel = func(wire.OutPoint) (*channeldb.OpenChannel,
+		error) {
+
+		return nil, dbErr
+	}
+	if _, err := lookupChanTx(src, unrelated); err != dbErr {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+// TestTagChanTx asserts that tagging a wallet transaction sets its type and
+// channel point, and that new transactions that can't be looked up are left
+// untagged.
+func TestTagChanTx(t *testing.T) {
+	funding := fundingTx(1)
+	chanPoint := wire.OutPoint{Hash: funding.TxHash()}
+	src := mockChanTxSource(
+		nil, []*channeldb.OpenChannel{{FundingOutpoint: chanPoint}},
+		nil, nil, nil,
+	)
+	rawTx, err := funding.Bytes()
+	if err != nil {
+		t.Fatalf("unable to serialize transaction: %v", err)
+	}
+
+	assertTagged := func(name string, tx *lnrpc.Transaction,
+		txType lnrpc.TransactionType, chanPoint string) {
+
+		t.Helper()
+
+		if tx.TxType != txType || tx.ChannelPoint != chanPoint {
+			t.Fatalf("%s: unexpected tagged transaction: %v", name,
+				tx)
+		}
+	}
+
+	chanTxs, err := fetchChanTxs(src)
+	if err != nil {
+		t.Fatalf("unable to fetch channel transactions: %v", err)
+	}
+	tx := &lnrpc.Transaction{TxHash: funding.TxHash().String()}
+	tagChanTx(tx, chanTxs)
+	assertTagged(
+		"fetched", tx, lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING,
+		chanPoint.String(),
+	)
+
+	tx = &lnrpc.Transaction{TxHash: funding.TxHash().String()}
+	tagNewChanTx(src, tx, rawTx)
+	assertTagged(
+		"new", tx, lnrpc.TransactionType_TX_TYPE_CHANNEL_FUNDING,
+		chanPoint.String(),
+	)
+
+	tx = &lnrpc.Transaction{TxHash: funding.TxHash().String()}
+	tagNewChanTx(src, tx, rawTx[:len(rawTx)/2])
+	assertTagged(
+		"undecodable", tx, lnrpc.TransactionType_TX_TYPE_UNKNOWN, "",
+	)
+
+	src.fetchChannel = func(wire.OutPoint) (*channeldb.OpenChannel,
+		error) {
+
+		return nil, errors.New("db error")
+	}
+	tx = &lnrpc.Transaction{TxHash: funding.TxHash().String()}
+	tagNewChanTx(src, tx, rawTx)
+	assertTagged(
+		"lookup error", tx, lnrpc.TransactionType_TX_TYPE_UNKNOWN, "",
+	)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var attestNodeCommand = cli.Command{
+	Name:      "attestnode",
+	Category:  "Node",
+	Usage:     "Produce a signed summary of the node for a given nonce.",
+	ArgsUsage: "nonce",
+	Description: `
+	Produce a summary of the state of the node, similar to getinfo, signed
+	by the node's identity key along with a nonce. The nonce must be chosen
+	by the party the node proves its identity to, e.g. a directory or an
+	LSP, and be between 8 and 64 bytes. The attestation can be verified by
+	anyone with the verifyattestation command.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "nonce",
+			Usage: "the hex encoded nonce chosen by the verifier",
+		},
+	},
+	Action: actionDecorator(attestNode),
+}
+
+func attestNode(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var nonceStr string
+	switch {
+	case ctx.IsSet("nonce"):
+		nonceStr = ctx.String("nonce")
+	case ctx.Args().Present():
+		nonceStr = ctx.Args().First()
+	default:
+		return fmt.Errorf("nonce argument missing")
+	}
+
+	nonce, err := hex.DecodeString(nonceStr)
+	if err != nil {
+		return fmt.Errorf("unable to decode nonce: %v", err)
+	}
+
+	req := &lnrpc.AttestNodeRequest{
+		Nonce: nonce,
+	}
+	resp, err := client.AttestNode(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var verifyAttestationCommand = cli.Command{
+	Name:      "verifyattestation",
+	Category:  "Node",
+	Usage:     "Verify a signed node attestation.",
+	ArgsUsage: "attestation signature",
+	Description: `
+	Verify the signature of a node attestation produced with the attestnode
+	command, and print the public key of the node that signed it. The
+	attestation is expected in the base64 encoding printed by attestnode.
+	If --nonce is set, the attestation is only valid if it was made for
+	that nonce.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "attestation",
+			Usage: "the base64 encoded attestation",
+		},
+		cli.StringFlag{
+			Name:  "sig",
+			Usage: "the zbase32 encoded signature of the attestation",
+		},
+		cli.StringFlag{
+			Name:  "nonce",
+			Usage: "the hex encoded nonce supplied to the node",
+		},
+	},
+	Action: actionDecorator(verifyAttestation),
+}
+
+func verifyAttestation(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var (
+		attestationStr string
+		sig            string
+	)
+
+	args := ctx.Args()
+
+	switch {
+	case ctx.IsSet("attestation"):
+		attestationStr = ctx.String("attestation")
+	case args.Present():
+		attestationStr = args.First()
+		args = args.Tail()
+	default:
+		return fmt.Errorf("attestation argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("sig"):
+		sig = ctx.String("sig")
+	case args.Present():
+		sig = args.First()
+	default:
+		return fmt.Errorf("signature argument missing")
+	}
+
+	attestation, err := base64.StdEncoding.DecodeString(attestationStr)
+	if err != nil {
+		return fmt.Errorf("unable to decode attestation: %v", err)
+	}
+
+	req := &lnrpc.VerifyNodeAttestationRequest{
+		Attestation: attestation,
+		Signature:   sig,
+	}
+	if ctx.IsSet("nonce") {
+		req.Nonce, err = hex.DecodeString(ctx.String("nonce"))
+		if err != nil {
+			return fmt.Errorf("unable to decode nonce: %v", err)
+		}
+	}
+
+	resp, err := client.VerifyNodeAttestation(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/urfave/cli"
+)
+
+var estimateRouteFeeCommand = cli.Command{
+	Name:     "estimateroutefee",
+	Category: "Payments",
+	Usage: "Estimate the fee, time lock and success probability of a " +
+		"payment.",
+	ArgsUsage: "dest amt",
+	Description: `
+	Query for a route to the destination that can carry the given amount in
+	atoms, and return its routing fee, total time lock and success
+	probability without sending a payment.`,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name: "final_cltv_delta",
+			Usage: "the CLTV delta required by the destination for " +
+				"the final hop; if not set, the default of the " +
+				"node is used",
+		},
+	},
+	Action: actionDecorator(estimateRouteFee),
+}
+
+func estimateRouteFee(ctx *cli.Context) error {
+	args := ctx.Args()
+
+	if len(args) != 2 {
+		return cli.ShowCommandHelp(ctx, "estimateroutefee")
+	}
+
+	dest, err := route.NewVertexFromStr(args.Get(0))
+	if err != nil {
+		return fmt.Errorf("invalid dest key: %v", err)
+	}
+
+	amt, err := strconv.ParseInt(args.Get(1), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid amt: %v", err)
+	}
+
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	req := &routerrpc.RouteFeeRequest{
+		Dest:           dest[:],
+		AmtAtoms:       amt,
+		FinalCltvDelta: uint32(ctx.Uint64("final_cltv_delta")),
+	}
+	rpcCtx := context.Background()
+	response, err := client.EstimateRouteFee(rpcCtx, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(response)
+
+	return nil
+}
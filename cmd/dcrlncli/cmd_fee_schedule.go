@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var setFeeScheduleCommand = cli.Command{
+	Name:      "setfeeschedule",
+	Category:  "Channels",
+	Usage:     "Set the fee schedule of all channels, or a single channel.",
+	ArgsUsage: "[--chan_point=txid:index] --tier=pct:base:rate...",
+	Description: `
+	Sets the fees charged by channels depending on their local balance.
+	Each tier is given as 'min_local_percent:base_fee_m_atoms:fee_rate_ppm'
+	and applies to channels holding at least min_local_percent of their
+	funds on our side, until the percentage of the next tier is reached.
+	Tiers must be given by increasing percentage. For example, to raise
+	fees once the local balance drops below 20% and lower them once it
+	rises above 80%:
+
+	    dcrlncli setfeeschedule --tier=0:1000:2000 --tier=20:1000:500 \
+	        --tier=80:0:10
+
+	The schedule is applied to the channel identified by --chan_point, or
+	by default to all channels without their own schedule. Channels are
+	re-evaluated whenever their balance changes. Setting a schedule without
+	tiers removes it, leaving the fees of the affected channels as they
+	are.
+
+	Fee schedules are unavailable while the automatic fee controller is
+	enabled.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "chan_point",
+			Usage: "The channel whose fee schedule should be set, " +
+				"if unset the default fee schedule is set. Takes " +
+				"the form of 'txid:output_index'",
+		},
+		cli.StringSliceFlag{
+			Name: "tier",
+			Usage: "A tier of the schedule in the form of " +
+				"'min_local_percent:base_fee_m_atoms:" +
+				"fee_rate_ppm'. Can be repeated.",
+		},
+	},
+	Action: actionDecorator(setFeeSchedule),
+}
+
+// parseFeeScheduleTier parses a fee schedule tier in the form of
+// min_local_percent:base_fee_m_atoms:fee_rate_ppm.
+func parseFeeScheduleTier(s string) (*lnrpc.FeeScheduleTier, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("expecting tier to be in format of: " +
+			"min_local_percent:base_fee_m_atoms:fee_rate_ppm")
+	}
+
+	minLocalPercent, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode min_local_percent: %v",
+			err)
+	}
+
+	baseFee, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode base_fee_m_atoms: %v",
+			err)
+	}
+
+	feeRate, err := strconv.ParseUint(parts[2], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode fee_rate_ppm: %v", err)
+	}
+
+	return &lnrpc.FeeScheduleTier{
+		MinLocalPercent: uint32(minLocalPercent),
+		BaseFeeMAtoms:   baseFee,
+		FeeRatePpm:      uint32(feeRate),
+	}, nil
+}
+
+func setFeeSchedule(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.SetFeeScheduleRequest{}
+	for _, tierStr := range ctx.StringSlice("tier") {
+		tier, err := parseFeeScheduleTier(tierStr)
+		if err != nil {
+			return err
+		}
+		req.Tiers = append(req.Tiers, tier)
+	}
+
+	if ctx.IsSet("chan_point") {
+		chanPoint, err := parseChanPoint(ctx.String("chan_point"))
+		if err != nil {
+			return fmt.Errorf("unable to parse chan point: %v", err)
+		}
+		req.Scope = &lnrpc.SetFeeScheduleRequest_ChanPoint{
+			ChanPoint: chanPoint,
+		}
+	} else {
+		req.Scope = &lnrpc.SetFeeScheduleRequest_Global{
+			Global: true,
+		}
+	}
+
+	resp, err := client.SetFeeSchedule(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var listFeeSchedulesCommand = cli.Command{
+	Name:     "listfeeschedules",
+	Category: "Channels",
+	Usage:    "List the default and per channel fee schedules.",
+	Action:   actionDecorator(listFeeSchedules),
+}
+
+func listFeeSchedules(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.ListFeeSchedulesRequest{}
+	resp, err := client.ListFeeSchedules(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
@@ -34,6 +34,14 @@ var addInvoiceCommand = cli.Command{
 				"preimage. If not set, a random preimage will be " +
 				"created.",
 		},
+		cli.StringFlag{
+			Name: "hash",
+			Usage: "The hex-encoded payment hash (32 byte) of a " +
+				"hold invoice. The invoice isn't settled once " +
+				"paid, but waits for the preimage to be " +
+				"revealed with settleinvoice. Can't be used " +
+				"together with preimage.",
+		},
 		cli.Int64Flag{
 			Name:  "amt",
 			Usage: "The amt of atoms in this invoice",
@@ -63,19 +71,56 @@ var addInvoiceCommand = cli.Command{
 				"private channels in order to assist the " +
 				"payer in reaching you",
 		},
+		cli.BoolFlag{
+			Name: "no_route_hints",
+			Usage: "Omit routing hints from the invoice even if " +
+				"the node's configured defaults would include " +
+				"them, implies --private=false",
+		},
+		cli.BoolFlag{
+			Name: "private_hints_only",
+			Usage: "Only accept payments through the private " +
+				"channels of the routing hints of the " +
+				"invoice, so that it can't be linked to the " +
+				"node's public channels, implies --private",
+		},
 		cli.BoolFlag{
 			Name: "ignore_max_inbound_amt",
 			Usage: "Ignore check for available inbound capacity " +
 				"in directly connected channels and create the " +
 				"invoice anyway.",
 		},
+		cli.BoolFlag{
+			Name: "uris",
+			Usage: "Also return the lightning: URI of the " +
+				"invoice and, if a fallback_addr is set, the " +
+				"unified decred: URI paying either on-chain " +
+				"or off-chain",
+		},
 	},
 	Action: actionDecorator(addInvoice),
 }
 
+// parseInvoiceRouteHints returns the private and no_route_hints flags of an
+// invoice from the command line. Setting --no_route_hints overrides the
+// default of --private.
+func parseInvoiceRouteHints(ctx *cli.Context) (bool, bool, error) {
+	if !ctx.Bool("no_route_hints") {
+		return ctx.Bool("private"), false, nil
+	}
+
+	if ctx.IsSet("private") && ctx.Bool("private") {
+		return false, false, fmt.Errorf("private and no_route_hints " +
+			"can't both be set")
+	}
+
+	return false, true, nil
+}
+
 func addInvoice(ctx *cli.Context) error {
 	var (
 		preimage []byte
+		hash     []byte
 		descHash []byte
 		amt      int64
 		err      error
@@ -108,20 +153,42 @@ func addInvoice(ctx *cli.Context) error {
 		return fmt.Errorf("unable to parse preimage: %v", err)
 	}
 
+	if ctx.IsSet("hash") {
+		if preimage != nil {
+			return fmt.Errorf("preimage and hash can't both be set")
+		}
+
+		hash, err = hex.DecodeString(ctx.String("hash"))
+		if err != nil {
+			return fmt.Errorf("unable to parse hash: %v", err)
+		}
+	}
+
 	descHash, err = hex.DecodeString(ctx.String("description_hash"))
 	if err != nil {
 		return fmt.Errorf("unable to parse description_hash: %v", err)
 	}
 
+	private, noRouteHints, err := parseInvoiceRouteHints(ctx)
+	if err != nil {
+		return err
+	}
+
 	invoice := &lnrpc.Invoice{
 		Memo:                ctx.String("memo"),
 		RPreimage:           preimage,
+		RHash:               hash,
+		Hold:                hash != nil,
 		Value:               amt,
 		DescriptionHash:     descHash,
 		FallbackAddr:        ctx.String("fallback_addr"),
 		Expiry:              ctx.Int64("expiry"),
-		Private:             ctx.Bool("private"),
+		Private:             private,
+		NoRouteHints:        noRouteHints,
 		IgnoreMaxInboundAmt: ctx.Bool("ignore_max_inbound_amt"),
+		IncludeUris:         ctx.Bool("uris"),
+
+		PrivateRouteHintsOnly: ctx.Bool("private_hints_only"),
 	}
 
 	resp, err := client.AddInvoice(context.Background(), invoice)
@@ -139,12 +206,25 @@ var lookupInvoiceCommand = cli.Command{
 	Category:  "Invoices",
 	Usage:     "Lookup an existing invoice by its payment hash.",
 	ArgsUsage: "rhash",
+	Description: `
+	Lookup an existing invoice by its payment hash. Alternatively, the
+	invoice may be looked up by its payment address through --payment_addr
+	or by its add index through --add_index.`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
 			Name: "rhash",
 			Usage: "The 32 byte payment hash of the invoice to query for, the hash " +
 				"should be a hex-encoded string",
 		},
+		cli.StringFlag{
+			Name: "payment_addr",
+			Usage: "The 32 byte payment address of the invoice to query " +
+				"for, the address should be a hex-encoded string",
+		},
+		cli.Uint64Flag{
+			Name:  "add_index",
+			Usage: "The add index of the invoice to query for",
+		},
 	},
 	Action: actionDecorator(lookupInvoice),
 }
@@ -154,8 +234,9 @@ func lookupInvoice(ctx *cli.Context) error {
 	defer cleanUp()
 
 	var (
-		rHash []byte
-		err   error
+		rHash   []byte
+		payAddr []byte
+		err     error
 	)
 
 	switch {
@@ -163,6 +244,7 @@ func lookupInvoice(ctx *cli.Context) error {
 		rHash, err = hex.DecodeString(ctx.String("rhash"))
 	case ctx.Args().Present():
 		rHash, err = hex.DecodeString(ctx.Args().First())
+	case ctx.IsSet("payment_addr"), ctx.IsSet("add_index"):
 	default:
 		return fmt.Errorf("rhash argument missing")
 	}
@@ -171,8 +253,18 @@ func lookupInvoice(ctx *cli.Context) error {
 		return fmt.Errorf("unable to decode rhash argument: %v", err)
 	}
 
+	if ctx.IsSet("payment_addr") {
+		payAddr, err = hex.DecodeString(ctx.String("payment_addr"))
+		if err != nil {
+			return fmt.Errorf("unable to decode payment_addr "+
+				"argument: %v", err)
+		}
+	}
+
 	req := &lnrpc.PaymentHash{
-		RHash: rHash,
+		RHash:       rHash,
+		PaymentAddr: payAddr,
+		AddIndex:    ctx.Uint64("add_index"),
 	}
 
 	invoice, err := client.LookupInvoice(context.Background(), req)
@@ -23,7 +23,8 @@ var bakeMacaroonCommand = cli.Command{
 	Category: "Macaroons",
 	Usage: "Bakes a new macaroon with the provided list of permissions " +
 		"and restrictions.",
-	ArgsUsage: "[--save_to=] [--timeout=] [--ip_address=] permissions...",
+	ArgsUsage: "[--save_to=] [--timeout=] [--ip_address=] " +
+		"[--budget_day=] [--budget_week=] permissions...",
 	Description: `
 	Bake a new macaroon that grants the provided permissions and
 	optionally adds restrictions (timeout, IP address) to it.
@@ -47,6 +48,15 @@ var bakeMacaroonCommand = cli.Command{
 	The macaroon created by this command would only be allowed to use the
 	"lncli getinfo" and "lncli version" commands.
 
+	A macaroon can be restricted to spend at most a given amount of atoms
+	over a rolling day or week with the --budget_day and --budget_week
+	arguments. The amount sent by sendcoins and sendmany including the
+	miner fee and the amount including the maximum fees of payments count
+	towards the budget. Such a macaroon can't use any other command
+	spending funds, for example:
+
+	lncli bakemacaroon --budget_day=100000 offchain:read offchain:write
+
 	To get a list of all available URIs and permissions, use the
 	"lncli listpermissions" command.
 	`,
@@ -69,6 +79,16 @@ var bakeMacaroonCommand = cli.Command{
 			Name:  "root_key_id",
 			Usage: "the numerical root key ID used to create the macaroon",
 		},
+		cli.Int64Flag{
+			Name: "budget_day",
+			Usage: "the max number of atoms that can be spent with " +
+				"the macaroon over a rolling day",
+		},
+		cli.Int64Flag{
+			Name: "budget_week",
+			Usage: "the max number of atoms that can be spent with " +
+				"the macaroon over a rolling week",
+		},
 	},
 	Action: actionDecorator(bakeMacaroon),
 }
@@ -115,6 +135,24 @@ func bakeMacaroon(ctx *cli.Context) error {
 		rootKeyID = ctx.Uint64("root_key_id")
 	}
 
+	var budgets []macaroons.Constraint
+	for flag, period := range map[string]string{
+		"budget_day":  macaroons.BudgetPeriodDay,
+		"budget_week": macaroons.BudgetPeriodWeek,
+	} {
+		if !ctx.IsSet(flag) {
+			continue
+		}
+
+		maxAtoms := ctx.Int64(flag)
+		if maxAtoms < 0 {
+			return fmt.Errorf("%v must not be negative", flag)
+		}
+		budgets = append(
+			budgets, macaroons.BudgetConstraint(period, maxAtoms),
+		)
+	}
+
 	// A command line argument can't be an empty string. So we'll check each
 	// entry if it's a valid entity:action tuple. The content itself is
 	// validated server side. We just make sure we can parse it correctly.
@@ -180,6 +218,7 @@ func bakeMacaroon(ctx *cli.Context) error {
 			macaroons.IPLockConstraint(ipAddress.String()),
 		)
 	}
+	macConstraints = append(macConstraints, budgets...)
 	constrainedMac, err := macaroons.AddConstraints(
 		unmarshalMac, macConstraints...,
 	)
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/urfave/cli"
+)
+
+var getCfgCommand = cli.Command{
+	Name:     "getmccfg",
+	Category: "Payments",
+	Usage: "Display mission control's probability estimation " +
+		"parameters.",
+	Action: actionDecorator(getCfg),
+}
+
+func getCfg(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	resp, err := client.GetMissionControlConfig(
+		context.Background(), &routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var setCfgCommand = cli.Command{
+	Name:     "setmccfg",
+	Category: "Payments",
+	Usage:    "Set mission control's probability estimation parameters.",
+	Description: "Update the parameters mission control uses to estimate " +
+		"the success probability of a hop. Parameters that aren't " +
+		"set keep their current value. The change lasts until the " +
+		"node is restarted.",
+	Flags: []cli.Flag{
+		cli.DurationFlag{
+			Name: "halflife",
+			Usage: "the duration after which a penalized node or " +
+				"channel is back at 50% probability",
+		},
+		cli.Float64Flag{
+			Name: "hopprob",
+			Usage: "the assumed success probability of a hop " +
+				"when no other information is available",
+		},
+		cli.Float64Flag{
+			Name: "weight",
+			Usage: "the weight of the a priori probability in " +
+				"success probability estimation, in [0, 1]",
+		},
+	},
+	Action: actionDecorator(setCfg),
+}
+
+func setCfg(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	if ctx.NumFlags() == 0 {
+		return cli.ShowCommandHelp(ctx, "setmccfg")
+	}
+
+	// Start from the current parameters, so that only the ones set on the
+	// command line are changed.
+	rpcCtx := context.Background()
+	resp, err := client.GetMissionControlConfig(
+		rpcCtx, &routerrpc.GetMissionControlConfigRequest{},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to fetch current config: %v", err)
+	}
+	cfg := resp.Config
+
+	if ctx.IsSet("halflife") {
+		halfLife := ctx.Duration("halflife")
+		cfg.HalfLifeSeconds = uint64(halfLife.Seconds())
+	}
+	if ctx.IsSet("hopprob") {
+		cfg.HopProbability = ctx.Float64("hopprob")
+	}
+	if ctx.IsSet("weight") {
+		cfg.Weight = ctx.Float64("weight")
+	}
+
+	_, err = client.SetMissionControlConfig(
+		rpcCtx, &routerrpc.SetMissionControlConfigRequest{
+			Config: cfg,
+		},
+	)
+	return err
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
@@ -23,27 +24,24 @@ import (
 const (
 	defaultUtxoMinConf = 1
 	userMsgFund        = `PSBT funding initiated with peer %x.
-Please create a PSBT that sends %v (%d satoshi) to the funding address %s.
+Please create a PSBT that sends %v (%d atoms) to the funding address %s.
 
 Note: The whole process should be completed within 10 minutes, otherwise there
 is a risk of the remote node timing out and canceling the funding process.
 
-Example with bitcoind:
-	bitcoin-cli walletcreatefundedpsbt [] '[{"%s":%.8f}]'
-
-If you are using a wallet that can fund a PSBT directly (currently not possible
-with bitcoind), you can use this PSBT that contains the same address and amount:
+If you are using a wallet that can fund a PSBT directly, you can use this PSBT
+that contains the same address and amount:
 %s
 
 !!! WARNING !!!
 DO NOT PUBLISH the finished transaction by yourself or with another tool.
-lnd MUST publish it in the proper funding flow order OR THE FUNDS CAN BE LOST!
+dcrlnd MUST publish it in the proper funding flow order OR THE FUNDS CAN BE LOST!
 
 Paste the funded PSBT here to continue the funding flow.
 Base64 encoded PSBT: `
 
 	userMsgSign = `
-PSBT verified by lnd, please continue the funding flow by signing the PSBT by 
+PSBT verified by dcrlnd, please continue the funding flow by signing the PSBT by 
 all required parties/devices. Once the transaction is fully signed, paste it
 again here either in base64 PSBT or hex encoded raw wire TX format.
 
@@ -153,7 +151,7 @@ var openChannelCommand = cli.Command{
 		cli.BoolFlag{
 			Name: "psbt",
 			Usage: "start an interactive mode that initiates " +
-				"funding through a partially signed bitcoin " +
+				"funding through a partially signed decred " +
 				"transaction (PSBT), allowing the channel " +
 				"funds to be added and signed from a hardware " +
 				"or other offline device.",
@@ -180,6 +178,18 @@ var openChannelCommand = cli.Command{
 				"can be pending within the channel at any " +
 				"given time (optional).",
 		},
+		cli.StringFlag{
+			Name: "channel_type",
+			Usage: "the commitment type the channel must use: " +
+				"legacy, static_remote_key or anchors. The open " +
+				"fails if this type can't be negotiated with " +
+				"the peer (optional)",
+		},
+		cli.StringFlag{
+			Name: "label",
+			Usage: "(optional) a label for the funding " +
+				"transaction",
+		},
 	},
 	Action: actionDecorator(openChannel),
 }
@@ -209,6 +219,7 @@ func openChannel(ctx *cli.Context) error {
 		SpendUnconfirmed:             minConfs == 0,
 		CloseAddress:                 ctx.String("close_address"),
 		RemoteMaxValueInFlightMAtoms: ctx.Uint64("remote_max_value_in_flight_m_atoms"),
+		Label:                        ctx.String("label"),
 	}
 
 	switch {
@@ -277,6 +288,15 @@ func openChannel(ctx *cli.Context) error {
 
 	req.Private = ctx.Bool("private")
 
+	if ctx.IsSet("channel_type") {
+		commitType, err := parseCommitmentType(ctx.String("channel_type"))
+		if err != nil {
+			return err
+		}
+		req.CommitmentType = commitType
+		req.CommitmentTypeSpecified = true
+	}
+
 	// PSBT funding is a more involved, interactive process that is too
 	// large to also fit into this already long function.
 	if ctx.Bool("psbt") {
@@ -317,8 +337,25 @@ func openChannel(ctx *cli.Context) error {
 	}
 }
 
+// parseCommitmentType parses the user facing name of a commitment type.
+func parseCommitmentType(name string) (lnrpc.CommitmentType, error) {
+	switch name {
+	case "legacy":
+		return lnrpc.CommitmentType_LEGACY, nil
+
+	case "static_remote_key":
+		return lnrpc.CommitmentType_STATIC_REMOTE_KEY, nil
+
+	case "anchors":
+		return lnrpc.CommitmentType_ANCHORS, nil
+
+	default:
+		return 0, fmt.Errorf("unknown channel type %q", name)
+	}
+}
+
 // openChannelPsbt starts an interactive channel open protocol that uses a
-// partially signed bitcoin transaction (PSBT) to fund the channel output. The
+// partially signed decred transaction (PSBT) to fund the channel output. The
 // protocol involves several steps between the RPC server and the CLI client:
 //
 // RPC server                           CLI client
@@ -331,13 +368,9 @@ func openChannel(ctx *cli.Context) error {
 //     |  |-------channel pending------->|  |
 //     |  |-------channel open------------->|
 //     |                                    |
-//
-// nolint: govet
 func openChannelPsbt(ctx *cli.Context, client lnrpc.LightningClient,
 	req *lnrpc.OpenChannelRequest) error {
 
-	return fmt.Errorf("psbt channel opening is not supported in dcrlnd yet")
-
 	var (
 		pendingChanID [32]byte
 		shimPending   = true
@@ -495,7 +528,6 @@ func openChannelPsbt(ctx *cli.Context, client lnrpc.LightningClient,
 			addr := update.PsbtFund.FundingAddress
 			fmt.Printf(
 				userMsgFund, req.NodePubkey, amt, amt, addr,
-				addr, amt.ToCoin(),
 				base64.StdEncoding.EncodeToString(
 					update.PsbtFund.Psbt,
 				),
@@ -727,3 +759,212 @@ func finalizeMsgFromString(tx string,
 		},
 	}, nil
 }
+
+var batchOpenChannelCommand = cli.Command{
+	Name:     "batchopenchannel",
+	Category: "Channels",
+	Usage: "Open multiple channels to existing peers in a single " +
+		"transaction.",
+	Description: `
+	Attempt to open one or more new channels to existing peers with the
+	given node keys, using a single funding transaction funded by the
+	internal wallet. The funding transaction is only published once all
+	peers accepted their channel. If any channel fails before that, none
+	of them are opened.
+
+	The channels are given as a JSON array, for example:
+
+	'[{
+		"node_pubkey": "02abcdef...",
+		"local_funding_amount": 500000,
+		"private": true
+	}, {
+		"node_pubkey": "03fedcba...",
+		"local_funding_amount": 200000,
+		"push_atoms": 10000,
+		"remote_csv_delay": 288
+	}]'
+
+	The fields are the same as the flags of the openchannel command.
+
+	One can manually set the fee to be used for the funding transaction via
+	either the '--conf_target' or '--atoms_per_byte' arguments. This is
+	optional.`,
+	ArgsUsage: "channels-json",
+	Flags: []cli.Flag{
+		cli.Int64Flag{
+			Name: "conf_target",
+			Usage: "The number of blocks that the " +
+				"transaction *should* confirm in, will be " +
+				"used for fee estimation (optional)",
+		},
+		cli.Int64Flag{
+			Name: "atoms_per_byte",
+			Usage: "A manual fee expressed in " +
+				"atom/byte that should be used when crafting " +
+				"the transaction (optional)",
+		},
+		cli.Uint64Flag{
+			Name: "min_confs",
+			Usage: "The minimum number of confirmations " +
+				"each one of your outputs used for the funding " +
+				"transaction must satisfy (optional)",
+			Value: defaultUtxoMinConf,
+		},
+	},
+	Action: actionDecorator(batchOpenChannel),
+}
+
+// batchChannelJSON is the JSON representation of a channel to open with the
+// batchopenchannel command.
+type batchChannelJSON struct {
+	NodePubkey                   string `json:"node_pubkey"`
+	LocalFundingAmount           int64  `json:"local_funding_amount"`
+	PushAtoms                    int64  `json:"push_atoms"`
+	Private                      bool   `json:"private"`
+	MinHtlcMAtoms                int64  `json:"min_htlc_m_atoms"`
+	RemoteCsvDelay               uint32 `json:"remote_csv_delay"`
+	CloseAddress                 string `json:"close_address"`
+	RemoteMaxValueInFlightMAtoms uint64 `json:"remote_max_value_in_flight_m_atoms"`
+	RemoteMaxHtlcs               uint32 `json:"remote_max_htlcs"`
+}
+
+func batchOpenChannel(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments provided
+	if ctx.NArg() != 1 {
+		_ = cli.ShowCommandHelp(ctx, "batchopenchannel")
+		return nil
+	}
+
+	var channels []batchChannelJSON
+	err := json.Unmarshal([]byte(ctx.Args().First()), &channels)
+	if err != nil {
+		return fmt.Errorf("unable to decode channels: %v", err)
+	}
+
+	minConfs := int32(ctx.Uint64("min_confs"))
+	req := &lnrpc.BatchOpenChannelRequest{
+		TargetConf:       int32(ctx.Int64("conf_target")),
+		AtomsPerByte:     ctx.Int64("atoms_per_byte"),
+		MinConfs:         minConfs,
+		SpendUnconfirmed: minConfs == 0,
+	}
+	for _, channel := range channels {
+		nodePubKey, err := hex.DecodeString(channel.NodePubkey)
+		if err != nil {
+			return fmt.Errorf("unable to decode node public key: "+
+				"%v", err)
+		}
+
+		req.Channels = append(req.Channels, &lnrpc.BatchOpenChannel{
+			NodePubkey:                   nodePubKey,
+			LocalFundingAmount:           channel.LocalFundingAmount,
+			PushAtoms:                    channel.PushAtoms,
+			Private:                      channel.Private,
+			MinHtlcMAtoms:                channel.MinHtlcMAtoms,
+			RemoteCsvDelay:               channel.RemoteCsvDelay,
+			CloseAddress:                 channel.CloseAddress,
+			RemoteMaxValueInFlightMAtoms: channel.RemoteMaxValueInFlightMAtoms,
+			RemoteMaxHtlcs:               channel.RemoteMaxHtlcs,
+		})
+	}
+
+	resp, err := client.BatchOpenChannel(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var estimateChannelOpenCommand = cli.Command{
+	Name:     "estimatechannelopen",
+	Category: "Channels",
+	Usage: "Estimate the routing revenue and break-even time of a new " +
+		"channel.",
+	Description: `
+	Estimate the routing revenue a new channel of the given size with the
+	given node could earn, along with the fees of opening and closing it
+	and the number of days needed for the revenue to cover them.
+
+	The estimate is based on the forwards of the last 30 days. If we
+	already have channels with the node, their flows are used. Otherwise
+	the flows of all our channels are scaled by the betweenness centrality
+	of the node relative to our current peers.
+
+	One can manually set the fee rate used to estimate the fees via either
+	the '--conf_target' or '--atoms_per_byte' arguments. This is optional.`,
+	ArgsUsage: "node-key local-amt",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "node_key",
+			Usage: "the identity public key of the candidate node",
+		},
+		cli.Int64Flag{
+			Name: "local_amt",
+			Usage: "the number of atoms the wallet would commit " +
+				"to the channel",
+		},
+		cli.Int64Flag{
+			Name: "conf_target",
+			Usage: "(optional) the number of blocks that the " +
+				"funding and closing transactions *should* " +
+				"confirm in, will be used for fee estimation",
+		},
+		cli.Int64Flag{
+			Name: "atoms_per_byte",
+			Usage: "(optional) a manual fee expressed in " +
+				"atom/byte that will be used for fee " +
+				"estimation",
+		},
+	},
+	Action: actionDecorator(estimateChannelOpen),
+}
+
+func estimateChannelOpen(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	args := ctx.Args()
+	req := &lnrpc.EstimateChannelOpenRequest{
+		TargetConf:   int32(ctx.Int64("conf_target")),
+		AtomsPerByte: ctx.Int64("atoms_per_byte"),
+	}
+
+	switch {
+	case ctx.IsSet("node_key"):
+		req.NodePubkey = ctx.String("node_key")
+	case args.Present():
+		req.NodePubkey = args.First()
+		args = args.Tail()
+	default:
+		return fmt.Errorf("node key argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("local_amt"):
+		req.LocalFundingAmount = ctx.Int64("local_amt")
+	case args.Present():
+		amt, err := strconv.ParseInt(args.First(), 10, 64)
+		if err != nil {
+			return fmt.Errorf("unable to decode local amt: %v", err)
+		}
+		req.LocalFundingAmount = amt
+	default:
+		return fmt.Errorf("local amt argument missing")
+	}
+
+	resp, err := client.EstimateChannelOpen(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
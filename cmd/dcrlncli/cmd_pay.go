@@ -65,6 +65,23 @@ var (
 		Value: 1,
 	}
 
+	maxShardSizeFlag = cli.Uint64Flag{
+		Name: "max_shard_size_atoms",
+		Usage: "the largest amount in atoms that a single partial " +
+			"payment may carry",
+	}
+
+	minShardSizeFlag = cli.Uint64Flag{
+		Name: "min_shard_size_atoms",
+		Usage: "the amount in atoms beyond which the payment isn't " +
+			"split any further",
+	}
+
+	noSplitFlag = cli.BoolFlag{
+		Name:  "no_split",
+		Usage: "if set, the payment is never split",
+	}
+
 	jsonFlag = cli.BoolFlag{
 		Name: "json",
 		Usage: "if set, payment updates are printed as json " +
@@ -93,11 +110,19 @@ func paymentFlags() []cli.Flag {
 		},
 		cltvLimitFlag,
 		lastHopFlag,
-		cli.Uint64Flag{
+		cli.StringSliceFlag{
 			Name: "outgoing_chan_id",
 			Usage: "Short channel id of the outgoing channel to " +
-				"use for the first hop of the payment",
-			Value: 0,
+				"use for the first hop of the payment; can " +
+				"be specified multiple times in the same " +
+				"command",
+		},
+		cli.StringSliceFlag{
+			Name: "exclude_outgoing_chan_id",
+			Usage: "Short channel id of an outgoing channel that " +
+				"must not be used for the first hop of the " +
+				"payment; can be specified multiple times in " +
+				"the same command",
 		},
 		cli.BoolFlag{
 			Name:  "force, f",
@@ -107,7 +132,8 @@ func paymentFlags() []cli.Flag {
 			Name:  "allow_self_payment",
 			Usage: "Allow sending a circular payment to self",
 		},
-		dataFlag, inflightUpdatesFlag, maxPartsFlag, jsonFlag,
+		dataFlag, inflightUpdatesFlag, maxPartsFlag, maxShardSizeFlag,
+		minShardSizeFlag, noSplitFlag, jsonFlag,
 	}
 }
 
@@ -316,6 +342,24 @@ func sendPayment(ctx *cli.Context) error {
 	return sendPaymentRequest(ctx, req)
 }
 
+// parseChanIDs parses a list of short channel ids.
+func parseChanIDs(idStrings []string) ([]uint64, error) {
+	if len(idStrings) == 0 {
+		return nil, nil
+	}
+
+	chanIDs := make([]uint64, 0, len(idStrings))
+	for _, idStr := range idStrings {
+		chanID, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		chanIDs = append(chanIDs, chanID)
+	}
+
+	return chanIDs, nil
+}
+
 func sendPaymentRequest(ctx *cli.Context,
 	req *routerrpc.SendPaymentRequest) error {
 
@@ -325,9 +369,18 @@ func sendPaymentRequest(ctx *cli.Context,
 	client := lnrpc.NewLightningClient(conn)
 	routerClient := routerrpc.NewRouterClient(conn)
 
-	outChan := ctx.Uint64("outgoing_chan_id")
-	if outChan != 0 {
-		req.OutgoingChanIds = []uint64{outChan}
+	var err error
+	req.OutgoingChanIds, err = parseChanIDs(
+		ctx.StringSlice("outgoing_chan_id"),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid outgoing_chan_id: %v", err)
+	}
+	req.ExcludeOutgoingChanIds, err = parseChanIDs(
+		ctx.StringSlice("exclude_outgoing_chan_id"),
+	)
+	if err != nil {
+		return fmt.Errorf("invalid exclude_outgoing_chan_id: %v", err)
 	}
 	if ctx.IsSet(lastHopFlag.Name) {
 		lastHop, err := route.NewVertexFromStr(
@@ -345,6 +398,9 @@ func sendPaymentRequest(ctx *cli.Context,
 	req.AllowSelfPayment = ctx.Bool("allow_self_payment")
 
 	req.MaxParts = uint32(ctx.Uint(maxPartsFlag.Name))
+	req.NoSplit = ctx.Bool(noSplitFlag.Name)
+	req.MaxShardSizeMAtoms = ctx.Uint64(maxShardSizeFlag.Name) * 1000
+	req.MinShardSizeMAtoms = ctx.Uint64(minShardSizeFlag.Name) * 1000
 
 	// Parse custom data records.
 	data := ctx.String(dataFlag.Name)
@@ -485,11 +541,70 @@ func trackPayment(ctx *cli.Context) error {
 	return err
 }
 
+var watchPaymentCommand = cli.Command{
+	Name:     "watchpayment",
+	Category: "Payments",
+	Usage: "Track progress of an existing payment through the main " +
+		"service.",
+	Description: `
+	Pick up monitoring the progression of a previously initiated payment
+	specified by the hash argument, like trackpayment, but through the
+	TrackPayment call of the main service, which doesn't require the router
+	sub-server.
+	`,
+	ArgsUsage: "hash",
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name: "no_inflight_updates",
+			Usage: "only print the final state of the payment, " +
+				"skipping the updates of its htlc attempts",
+		},
+	},
+	Action: actionDecorator(watchPayment),
+}
+
+func watchPayment(ctx *cli.Context) error {
+	args := ctx.Args()
+
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := lnrpc.NewLightningClient(conn)
+
+	if !args.Present() {
+		return fmt.Errorf("hash argument missing")
+	}
+
+	hash, err := hex.DecodeString(args.First())
+	if err != nil {
+		return err
+	}
+
+	req := &lnrpc.TrackPaymentRequest{
+		PaymentHash:       hash,
+		NoInflightUpdates: ctx.Bool("no_inflight_updates"),
+	}
+
+	stream, err := client.TrackPayment(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	_, err = printLivePayment(stream, client, ctx.Bool(jsonFlag.Name))
+	return err
+}
+
+// paymentUpdateStream is a stream of payment updates, such as the streams of
+// the TrackPayment calls of the main service and of the router sub-server.
+type paymentUpdateStream interface {
+	Recv() (*lnrpc.Payment, error)
+}
+
 // printLivePayment receives payment updates from the given stream and either
 // outputs them as json or as a more user-friendly formatted table. The table
 // option uses terminal control codes to rewrite the output. This call
 // terminates when the payment reaches a final state.
-func printLivePayment(stream routerrpc.Router_TrackPaymentV2Client,
+func printLivePayment(stream paymentUpdateStream,
 	client lnrpc.LightningClient, json bool) (*lnrpc.Payment, error) {
 
 	// Terminal escape codes aren't supported on Windows, fall back to json.
@@ -671,6 +786,12 @@ func formatPayment(payment *lnrpc.Payment, aliases *aliasCache) string {
 		fmt.Fprintf(b, ", reason: %v", payment.FailureReason)
 	}
 	fmt.Fprintf(b, "\n")
+	fmt.Fprintf(b, "Path finding:   %v\n",
+		time.Duration(payment.PathfindingTimeNs))
+	if payment.DurationNs != 0 {
+		fmt.Fprintf(b, "Duration:       %v\n",
+			time.Duration(payment.DurationNs))
+	}
 
 	return b.String()
 }
@@ -864,6 +985,85 @@ func sendToRouteRequest(ctx *cli.Context, req *routerrpc.SendToRouteRequest) err
 	return nil
 }
 
+var rebalanceCommand = cli.Command{
+	Name:     "rebalance",
+	Category: "Payments",
+	Usage:    "Move funds between two of our channels.",
+	Description: `
+	Move funds from one of our channels to another by paying an invoice of
+	our own along a circular route. The route leaves through the outgoing
+	channel, optionally traverses the given hops, and returns through the
+	incoming channel. The fees and time locks of the route are computed
+	from the graph, and the rebalance is only attempted if the fees don't
+	exceed the fee limit.
+	`,
+	Flags: []cli.Flag{
+		cli.Int64Flag{
+			Name:  "amt",
+			Usage: "the amount to move, in atoms",
+		},
+		cli.Uint64Flag{
+			Name: "outgoing_chan_id",
+			Usage: "the short channel id of the channel to move " +
+				"funds from",
+		},
+		cli.Uint64Flag{
+			Name: "incoming_chan_id",
+			Usage: "the short channel id of the channel to move " +
+				"funds to",
+		},
+		cli.StringFlag{
+			Name: "hops",
+			Usage: "comma separated hex pubkeys of the nodes to route " +
+				"through between the peers of both channels",
+		},
+		cli.Int64Flag{
+			Name:  "fee_limit",
+			Usage: "the maximum fee to pay for the rebalance, in atoms",
+		},
+	},
+	Action: actionDecorator(rebalance),
+}
+
+func rebalance(ctx *cli.Context) error {
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if !ctx.IsSet("amt") {
+		return errors.New("amt required")
+	}
+	if !ctx.IsSet("outgoing_chan_id") || !ctx.IsSet("incoming_chan_id") {
+		return errors.New("outgoing_chan_id and incoming_chan_id " +
+			"required")
+	}
+
+	req := &lnrpc.SendRebalanceRequest{
+		AmtMAtoms:      ctx.Int64("amt") * 1000,
+		OutgoingChanId: ctx.Uint64("outgoing_chan_id"),
+		IncomingChanId: ctx.Uint64("incoming_chan_id"),
+		FeeLimitMAtoms: ctx.Int64("fee_limit") * 1000,
+	}
+
+	if ctx.IsSet("hops") {
+		for _, k := range strings.Split(ctx.String("hops"), ",") {
+			pubkey, err := route.NewVertexFromStr(k)
+			if err != nil {
+				return fmt.Errorf("error parsing %v: %v", k, err)
+			}
+			req.HopPubkeys = append(req.HopPubkeys, pubkey[:])
+		}
+	}
+
+	resp, err := client.SendRebalance(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
 // ESC is the ASCII code for escape character
 const ESC = 27
 
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+
+	"github.com/urfave/cli"
+)
+
+var queryHtlcEventsCommand = cli.Command{
+	Name:     "queryhtlcevents",
+	Category: "Payments",
+	Usage:    "Query the persisted htlc events.",
+	Description: `
+	Query the htlc events (forwards, fails and settles) persisted by the
+	node over a particular time range '--start_time' and '--end_time'.
+	Events are only persisted if the node runs with htlcevents.store set.
+
+	The start and end times are expressed as unix timestamps or relative
+	times such as '-3d'. If '--start_time' isn't provided, then 24 hours
+	ago is used. If '--end_time' isn't provided, then the current time is
+	used.
+
+	Events can be filtered by channel, by the type of the htlc and by the
+	kind of the event. Each response contains the offset index of the last
+	event, which can be passed as '--index_offset' to fetch the next page.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "start_time",
+			Usage: "The starting time for the query " +
+				`as unix timestamp or relative e.g. "-1w"`,
+		},
+		cli.StringFlag{
+			Name: "end_time",
+			Usage: "The end time for the query " +
+				`as unix timestamp or relative e.g. "-1w"`,
+		},
+		cli.Uint64Flag{
+			Name: "chan_id",
+			Usage: "Only return the events of htlcs that arrived " +
+				"on or left through this channel",
+		},
+		cli.StringFlag{
+			Name: "event_type",
+			Usage: "Only return the events of htlcs of this " +
+				"type: send, receive or forward",
+		},
+		cli.StringFlag{
+			Name: "event_kind",
+			Usage: "Only return the events of this kind: " +
+				"forward, forward_fail, settle or link_fail",
+		},
+		cli.Int64Flag{
+			Name:  "index_offset",
+			Usage: "The number of events to skip",
+		},
+		cli.Int64Flag{
+			Name:  "max_events",
+			Usage: "The max number of events to return",
+		},
+	},
+	Action: actionDecorator(queryHtlcEvents),
+}
+
+func queryHtlcEvents(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	now := time.Now()
+	startTime := uint64(now.Add(-time.Hour * 24).Unix())
+	if ctx.IsSet("start_time") {
+		var err error
+		startTime, err = parseTime(ctx.String("start_time"), now)
+		if err != nil {
+			return fmt.Errorf("unable to decode start_time: %v", err)
+		}
+	}
+
+	endTime := uint64(now.Unix())
+	if ctx.IsSet("end_time") {
+		var err error
+		endTime, err = parseTime(ctx.String("end_time"), now)
+		if err != nil {
+			return fmt.Errorf("unable to decode end_time: %v", err)
+		}
+	}
+
+	req := &routerrpc.QueryHtlcEventsRequest{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		ChanId:       ctx.Uint64("chan_id"),
+		IndexOffset:  uint32(ctx.Int64("index_offset")),
+		NumMaxEvents: uint32(ctx.Int64("max_events")),
+	}
+
+	if ctx.IsSet("event_type") {
+		eventType := strings.ToUpper(ctx.String("event_type"))
+		value, ok := routerrpc.HtlcEvent_EventType_value[eventType]
+		if !ok {
+			return fmt.Errorf("unknown event type: %v",
+				ctx.String("event_type"))
+		}
+		req.EventType = routerrpc.HtlcEvent_EventType(value)
+	}
+
+	if ctx.IsSet("event_kind") {
+		eventKind := strings.ToUpper(ctx.String("event_kind")) +
+			"_EVENT"
+		value, ok := routerrpc.HtlcEventKind_value[eventKind]
+		if !ok {
+			return fmt.Errorf("unknown event kind: %v",
+				ctx.String("event_kind"))
+		}
+		req.EventKind = routerrpc.HtlcEventKind(value)
+	}
+
+	rpcCtx := context.Background()
+	resp, err := client.QueryHtlcEvents(rpcCtx, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var exportReceiptCommand = cli.Command{
+	Name:      "exportreceipt",
+	Category:  "Payments",
+	Usage:     "Export a signed receipt of a settled payment or invoice.",
+	ArgsUsage: "payment_hash",
+	Description: `
+	Export a receipt of a settled payment or invoice, signed by the node's
+	identity key. The receipt contains the payment hash, the preimage, the
+	payment request and the settlement timestamps, and can be verified by
+	anyone with the verifyreceipt command.
+
+	By default, the receipt of an outgoing payment is exported if one
+	exists, otherwise the receipt of an invoice. Use --role to choose
+	explicitly.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "payment_hash",
+			Usage: "the hex encoded hash of the payment or invoice",
+		},
+		cli.StringFlag{
+			Name: "role",
+			Usage: "export the receipt of an outgoing payment " +
+				"(payer) or of an invoice (payee)",
+		},
+	},
+	Action: actionDecorator(exportReceipt),
+}
+
+func exportReceipt(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var hashStr string
+	switch {
+	case ctx.IsSet("payment_hash"):
+		hashStr = ctx.String("payment_hash")
+	case ctx.Args().Present():
+		hashStr = ctx.Args().First()
+	default:
+		return fmt.Errorf("payment_hash argument missing")
+	}
+
+	paymentHash, err := hex.DecodeString(hashStr)
+	if err != nil {
+		return fmt.Errorf("unable to decode payment_hash: %v", err)
+	}
+
+	req := &lnrpc.ExportPaymentReceiptRequest{
+		PaymentHash: paymentHash,
+	}
+	if ctx.IsSet("role") {
+		role, ok := lnrpc.ReceiptRole_value[strings.ToUpper(
+			ctx.String("role"),
+		)]
+		if !ok {
+			return fmt.Errorf("unknown role: %v", ctx.String("role"))
+		}
+		req.Role = lnrpc.ReceiptRole(role)
+	}
+
+	resp, err := client.ExportPaymentReceipt(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var verifyReceiptCommand = cli.Command{
+	Name:      "verifyreceipt",
+	Category:  "Payments",
+	Usage:     "Verify a signed payment receipt.",
+	ArgsUsage: "receipt signature",
+	Description: `
+	Verify the signature and the consistency of a payment receipt exported
+	with the exportreceipt command, and print the public key of the node
+	that signed it. The receipt is expected in the base64 encoding printed
+	by exportreceipt.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "receipt",
+			Usage: "the base64 encoded receipt",
+		},
+		cli.StringFlag{
+			Name:  "sig",
+			Usage: "the zbase32 encoded signature of the receipt",
+		},
+	},
+	Action: actionDecorator(verifyReceipt),
+}
+
+func verifyReceipt(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var (
+		receiptStr string
+		sig        string
+	)
+
+	args := ctx.Args()
+
+	switch {
+	case ctx.IsSet("receipt"):
+		receiptStr = ctx.String("receipt")
+	case args.Present():
+		receiptStr = args.First()
+		args = args.Tail()
+	default:
+		return fmt.Errorf("receipt argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("sig"):
+		sig = ctx.String("sig")
+	case args.Present():
+		sig = args.First()
+	default:
+		return fmt.Errorf("signature argument missing")
+	}
+
+	receipt, err := base64.StdEncoding.DecodeString(receiptStr)
+	if err != nil {
+		return fmt.Errorf("unable to decode receipt: %v", err)
+	}
+
+	req := &lnrpc.VerifyPaymentReceiptRequest{
+		Receipt:   receipt,
+		Signature: sig,
+	}
+	resp, err := client.VerifyPaymentReceipt(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var exportRouterStateCommand = cli.Command{
+	Name:      "exportrouterstate",
+	Category:  "Channels",
+	Usage:     "Export the routing state of the node to a file.",
+	ArgsUsage: "--output_file",
+	Description: `
+	Export the state the node gathered while routing payments (the mission
+	control history, the forwarding policies of the channels and the
+	addresses of the channel peers) as an encrypted bundle written to the
+	given file.
+
+	The bundle is encrypted with the same key as the static channel
+	backups, so it can only be imported, using the 'importrouterstate'
+	command, into a node restored from the same seed.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "output_file",
+			Usage: "the file to write the router state bundle to",
+		},
+	},
+	Action: actionDecorator(exportRouterState),
+}
+
+func exportRouterState(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if !ctx.IsSet("output_file") {
+		return fmt.Errorf("output_file must be specified")
+	}
+
+	resp, err := client.ExportRouterState(
+		ctxb, &lnrpc.RouterStateExportRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(
+		cleanAndExpandPath(ctx.String("output_file")),
+		resp.Bundle, 0600,
+	)
+}
+
+var importRouterStateCommand = cli.Command{
+	Name:      "importrouterstate",
+	Category:  "Channels",
+	Usage:     "Import the routing state exported by another node.",
+	ArgsUsage: "--input_file",
+	Description: `
+	Import a router state bundle created by the 'exportrouterstate'
+	command. The mission control history is merged into the one of the
+	node, the forwarding policies are applied to the matching channels and
+	the peer addresses are added to the matching channel peers.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "input_file",
+			Usage: "the file to read the router state bundle from",
+		},
+	},
+	Action: actionDecorator(importRouterState),
+}
+
+func importRouterState(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if !ctx.IsSet("input_file") {
+		return fmt.Errorf("input_file must be specified")
+	}
+
+	bundle, err := ioutil.ReadFile(
+		cleanAndExpandPath(ctx.String("input_file")),
+	)
+	if err != nil {
+		return fmt.Errorf("unable to read router state bundle: %v",
+			err)
+	}
+
+	resp, err := client.ImportRouterState(
+		ctxb, &lnrpc.RouterStateImportRequest{
+			Bundle: bundle,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"io"
+
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+
+	"github.com/urfave/cli"
+)
+
+var subscribeHtlcEventsCommand = cli.Command{
+	Name:     "subscribehtlcevents",
+	Category: "Payments",
+	Usage:    "Subscribe to the htlc events of the node.",
+	Description: `
+	Stream the htlc events (forwards, forward fails, settles and link
+	fails) of the node as they happen. Each event includes the incoming and
+	outgoing channel ids and htlc ids, the amounts of the htlc and, for link
+	fails, the reason the htlc failed.
+
+	The command blocks until it is interrupted or the node shuts down.
+	`,
+	Action: actionDecorator(subscribeHtlcEvents),
+}
+
+func subscribeHtlcEvents(ctx *cli.Context) error {
+	conn := getClientConn(ctx, false)
+	defer conn.Close()
+
+	client := routerrpc.NewRouterClient(conn)
+
+	stream, err := client.SubscribeHtlcEvents(
+		context.Background(), &routerrpc.SubscribeHtlcEventsRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		printRespJSON(event)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/urfave/cli"
+)
+
+var lockWalletCommand = cli.Command{
+	Name:     "lockwallet",
+	Category: "Wallet",
+	Usage:    "Lock the wallet of the running node.",
+	Description: `
+	Pause the operations spending on-chain funds, wait for the channel
+	fundings in progress to complete and lock the wallet. While the wallet
+	is locked, the calls spending funds or requiring its private keys, such
+	as sending coins, opening channels or making payments, fail.
+
+	Only the on-chain funds are locked: the keys of the channels remain in
+	memory, so that the channels and their contracts keep being signed.
+
+	The wallet is unlocked with the unlock command, without restarting
+	the node.`,
+	Action: actionDecorator(lockWallet),
+}
+
+func lockWallet(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.LockWalletRequest{}
+	_, err := client.LockWallet(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Wallet locking, new payments and forwards are refused " +
+		"until it is unlocked")
+
+	return nil
+}
+
+// unlockRunningWallet unlocks the wallet of a running node that was locked
+// after startup.
+func unlockRunningWallet(ctx *cli.Context, pw []byte) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.UnlockRunningWalletRequest{
+		WalletPassword: pw,
+	}
+	_, err := client.UnlockRunningWallet(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("\ndcrlnd wallet successfully unlocked!")
+
+	return nil
+}
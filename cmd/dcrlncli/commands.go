@@ -195,6 +195,13 @@ var txLabelFlag = cli.StringFlag{
 	Usage: "(optional) a label for the transaction",
 }
 
+var notifyConfsFlag = cli.Uint64Flag{
+	Name: "notify_confs",
+	Usage: "(optional) register a notification for the transaction " +
+		"reaching this number of confirmations, which can be " +
+		"followed with subscribesendconf",
+}
+
 var sendCoinsCommand = cli.Command{
 	Name:      "sendcoins",
 	Category:  "On-chain",
@@ -237,6 +244,7 @@ var sendCoinsCommand = cli.Command{
 				"the transaction (optional)",
 		},
 		txLabelFlag,
+		notifyConfsFlag,
 	},
 	Action: actionDecorator(sendCoins),
 }
@@ -297,6 +305,7 @@ func sendCoins(ctx *cli.Context) error {
 		AtomsPerByte: ctx.Int64("atoms_per_byte"),
 		SendAll:      ctx.Bool("sweepall"),
 		Label:        ctx.String(txLabelFlag.Name),
+		NotifyConfs:  uint32(ctx.Uint64(notifyConfsFlag.Name)),
 	}
 	txid, err := client.SendCoins(ctxb, req)
 	if err != nil {
@@ -310,13 +319,15 @@ func sendCoins(ctx *cli.Context) error {
 var listUnspentCommand = cli.Command{
 	Name:      "listunspent",
 	Category:  "On-chain",
-	Usage:     "List UTXOs available for spending.",
-	ArgsUsage: "[min-confs [max-confs]] [--unconfirmed_only]",
+	Usage:     "List the UTXOs of the wallet.",
+	ArgsUsage: "[min-confs [max-confs]] [--unconfirmed_only] [--account]",
 	Description: `
-	For each spendable UTXO currently in the wallet, with at least 'min_confs'
+	For each UTXO currently in the wallet, with at least 'min_confs'
 	confirmations, and at most 'max_confs' confirmations, lists the txid,
-	index, amount, address, address type, scriptPubkey and number of
-	confirmations.  Use '--min_confs=0' to include unconfirmed coins. To list
+	index, amount, address, address type, scriptPubkey, number of
+	confirmations and whether the UTXO is locked by a pending funding or
+	sweep transaction.  Use '--min_confs=0' to include unconfirmed coins. To
+	list the UTXOs of another wallet account, use '--account'. To list
 	all coins with at least min_confs confirmations, omit the second
 	argument or flag '--max_confs'. To list all confirmed and unconfirmed
 	coins, no arguments are required. To see only unconfirmed coins, use
@@ -341,6 +352,11 @@ var listUnspentCommand = cli.Command{
 				"true and both 'min_confs' and 'max_confs' are " +
 				"non-zero. (default: false)",
 		},
+		cli.StringFlag{
+			Name: "account",
+			Usage: "(optional) the name of the wallet account to " +
+				"list the UTXOs of",
+		},
 	},
 	Action: actionDecorator(listUnspent),
 }
@@ -404,6 +420,7 @@ func listUnspent(ctx *cli.Context) error {
 	req := &lnrpc.ListUnspentRequest{
 		MinConfs: int32(minConfirms),
 		MaxConfs: int32(maxConfirms),
+		Account:  ctx.String("account"),
 	}
 	resp, err := client.ListUnspent(ctxb, req)
 	if err != nil {
@@ -453,6 +470,7 @@ var sendManyCommand = cli.Command{
 				"used when crafting the transaction (optional)",
 		},
 		txLabelFlag,
+		notifyConfsFlag,
 	},
 	Action: actionDecorator(sendMany),
 }
@@ -479,6 +497,7 @@ func sendMany(ctx *cli.Context) error {
 		TargetConf:   int32(ctx.Int64("conf_target")),
 		AtomsPerByte: ctx.Int64("atoms_per_byte"),
 		Label:        ctx.String(txLabelFlag.Name),
+		NotifyConfs:  uint32(ctx.Uint64(notifyConfsFlag.Name)),
 	})
 	if err != nil {
 		return err
@@ -488,6 +507,91 @@ func sendMany(ctx *cli.Context) error {
 	return nil
 }
 
+var subscribeSendConfCommand = cli.Command{
+	Name:      "subscribesendconf",
+	Category:  "On-chain",
+	Usage:     "Follow the confirmation of a sent transaction.",
+	ArgsUsage: "conf_subscription_id",
+	Description: `
+	Stream the confirmation progress of a transaction sent with sendcoins or
+	sendmany using the --notify_confs flag, until it reaches the requested
+	number of confirmations.
+	`,
+	Action: actionDecorator(subscribeSendConf),
+}
+
+func subscribeSendConf(ctx *cli.Context) error {
+	if !ctx.Args().Present() {
+		return cli.ShowCommandHelp(ctx, "subscribesendconf")
+	}
+
+	id, err := strconv.ParseUint(ctx.Args().First(), 10, 64)
+	if err != nil {
+		return fmt.Errorf("unable to decode subscription id: %v", err)
+	}
+
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	stream, err := client.SubscribeSendConfirmation(
+		ctxb, &lnrpc.SendConfirmationSubscription{
+			ConfSubscriptionId: id,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		printRespJSON(event)
+	}
+}
+
+var subscribeChainEventsCommand = cli.Command{
+	Name:     "subscribechainevents",
+	Category: "On-chain",
+	Usage:    "Follow chain reorgs and their effect on the node.",
+	Description: `
+	Stream the chain reorganizations detected by the node, along with the
+	funding, closing and sweep transactions they disconnected. Once such a
+	transaction confirms again and the state depending on it was reconciled,
+	a reconciliation event is printed.
+	`,
+	Action: actionDecorator(subscribeChainEvents),
+}
+
+func subscribeChainEvents(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	stream, err := client.SubscribeChainEvents(
+		ctxb, &lnrpc.ChainEventSubscription{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		printRespJSON(event)
+	}
+}
+
 var connectCommand = cli.Command{
 	Name:      "connect",
 	Category:  "Peers",
@@ -577,6 +681,57 @@ func disconnectPeer(ctx *cli.Context) error {
 	return nil
 }
 
+var rotateCircuitCommand = cli.Command{
+	Name:     "rotatecircuit",
+	Category: "Peers",
+	Usage:    "Move the connection with a peer onto a fresh Tor circuit.",
+	Description: `
+	Reconnect to a peer over a fresh Tor circuit. The new connection is
+	established before the current one is closed, and the current connection
+	is kept if the peer can't be reached over the new circuit.
+
+	Only outbound connections can be rotated, as the circuits of inbound
+	connections to the onion service are built by the remote peer.
+	`,
+	ArgsUsage: "<pubkey>",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "node_key",
+			Usage: "The hex-encoded compressed public key of the peer " +
+				"whose circuit to rotate",
+		},
+	},
+	Action: actionDecorator(rotateCircuit),
+}
+
+func rotateCircuit(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var pubKey string
+	switch {
+	case ctx.IsSet("node_key"):
+		pubKey = ctx.String("node_key")
+	case ctx.Args().Present():
+		pubKey = ctx.Args().First()
+	default:
+		return fmt.Errorf("must specify target public key")
+	}
+
+	req := &lnrpc.RotatePeerCircuitRequest{
+		PubKey: pubKey,
+	}
+
+	resp, err := client.RotatePeerCircuit(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 // TODO(roasbeef): also allow short relative channel ID.
 
 var closeChannelCommand = cli.Command{
@@ -596,6 +751,11 @@ var closeChannelCommand = cli.Command{
 	'--atoms_per_byte' arguments. This will be the starting value used during
 	fee negotiation. This is optional.
 
+	The '--max_fee_rate' argument caps the fee rate the negotiation may
+	agree to for channels we opened. If the remote party insists on a
+	higher fee, the close fails and the channel can be force closed
+	instead. This is optional.
+
 	In the case of a cooperative closure, one can manually set the address
 	to deliver funds to upon closure. This is optional, and may only be used
 	if an upfront shutdown address has not already been set. If neither are
@@ -635,6 +795,12 @@ var closeChannelCommand = cli.Command{
 				"atom/byte that should be used when crafting " +
 				"the transaction (optional)",
 		},
+		cli.Int64Flag{
+			Name: "max_fee_rate",
+			Usage: "The maximum fee rate expressed in " +
+				"atom/byte we're willing to pay for the " +
+				"closing transaction (optional)",
+		},
 		cli.StringFlag{
 			Name: "delivery_addr",
 			Usage: "An address to deliver funds " +
@@ -668,6 +834,7 @@ func closeChannel(ctx *cli.Context) error {
 		TargetConf:      int32(ctx.Int64("conf_target")),
 		AtomsPerByte:    ctx.Int64("atoms_per_byte"),
 		DeliveryAddress: ctx.String("delivery_addr"),
+		MaxFeeRate:      ctx.Int64("max_fee_rate"),
 	}
 
 	// After parsing the request, we'll spin up a goroutine that will
@@ -981,7 +1148,9 @@ var abandonChannelCommand = cli.Command{
 	summary. This method can be used to get rid of permanently unusable
 	channels due to bugs fixed in newer versions of dcrlnd.
 
-	Only available when dcrlnd is built in debug mode.
+	Only available when dcrlnd is built in debug mode, or when it's started
+	with --allow-abandon-channel and the --i_know_what_i_am_doing flag is
+	set. Every abandoned channel is recorded in an audit log.
 
 	To view which 'funding_txids' or 'output_indexes' can be used for this command,
 	see the 'channel_point' values within the 'listchannels' command output.
@@ -997,6 +1166,13 @@ var abandonChannelCommand = cli.Command{
 			Usage: "The output index for the funding output of the funding " +
 				"transaction",
 		},
+		cli.BoolFlag{
+			Name: "i_know_what_i_am_doing",
+			Usage: "acknowledge that abandoning the channel " +
+				"removes all of its state except for a " +
+				"close summary, which may result in a loss " +
+				"of funds",
+		},
 	},
 	Action: actionDecorator(abandonChannel),
 }
@@ -1019,7 +1195,8 @@ func abandonChannel(ctx *cli.Context) error {
 	}
 
 	req := &lnrpc.AbandonChannelRequest{
-		ChannelPoint: channelPoint,
+		ChannelPoint:      channelPoint,
+		IKnowWhatIAmDoing: ctx.Bool("i_know_what_i_am_doing"),
 	}
 
 	resp, err := client.AbandonChannel(ctxb, req)
@@ -1031,6 +1208,67 @@ func abandonChannel(ctx *cli.Context) error {
 	return nil
 }
 
+var exportChanCommitmentCommand = cli.Command{
+	Name:     "exportchancommitment",
+	Category: "Channels",
+	Usage:    "Export the latest signed commitment of a channel.",
+	Description: `
+	Returns the latest fully signed local commitment transaction of a
+	channel, along with the signed HTLC timeout transactions spending from
+	it. These may be broadcast manually to force close the channel should
+	dcrlnd be unable to start.
+
+	WARNING: the exported commitment is revoked as soon as the channel
+	state advances. Broadcasting a revoked commitment allows the remote
+	party to claim ALL funds in the channel.
+
+	This command requires a macaroon with the emergency:read permission,
+	which can be created with the 'bakemacaroon' command.`,
+	ArgsUsage: "funding_txid [output_index]",
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "funding_txid",
+			Usage: "The txid of the channel's funding transaction",
+		},
+		cli.IntFlag{
+			Name: "output_index",
+			Usage: "The output index for the funding output of the funding " +
+				"transaction",
+		},
+	},
+	Action: actionDecorator(exportChanCommitment),
+}
+
+func exportChanCommitment(ctx *cli.Context) error {
+	ctxb := context.Background()
+
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments and flags were provided.
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		cli.ShowCommandHelp(ctx, "exportchancommitment")
+		return nil
+	}
+
+	channelPoint, err := parseChannelPoint(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &lnrpc.ExportChannelCommitmentRequest{
+		ChannelPoint: channelPoint,
+	}
+
+	resp, err := client.ExportChannelCommitment(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 // parseChannelPoint parses a funding txid and output index from the command
 // line. Both named options as well as unnamed parameters are supported.
 func parseChannelPoint(ctx *cli.Context) (*lnrpc.ChannelPoint, error) {
@@ -1100,6 +1338,55 @@ func listPeers(ctx *cli.Context) error {
 	return nil
 }
 
+var peerFeaturesCommand = cli.Command{
+	Name:      "peerfeatures",
+	Category:  "Peers",
+	Usage:     "Report the features that would be negotiated with a peer.",
+	ArgsUsage: "pub_key",
+	Description: `
+	Diffs the features advertised by our node against the ones advertised
+	by the target peer and reports which features would be negotiated on a
+	new connection or channel with it, along with the commitment type a new
+	channel would use.
+
+	The features of a connected peer are taken from its init message,
+	otherwise the features of its latest node announcement are used.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "pub_key",
+			Usage: "the 33-byte hex-encoded identity pubkey of the peer",
+		},
+	},
+	Action: actionDecorator(peerFeatures),
+}
+
+func peerFeatures(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var pubKey string
+	switch {
+	case ctx.IsSet("pub_key"):
+		pubKey = ctx.String("pub_key")
+	case ctx.Args().Present():
+		pubKey = ctx.Args().First()
+	default:
+		return fmt.Errorf("pub_key argument missing")
+	}
+
+	req := &lnrpc.PeerFeatureReportRequest{
+		PubKey: pubKey,
+	}
+	resp, err := client.PeerFeatureReport(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 var createCommand = cli.Command{
 	Name:     "create",
 	Category: "Startup",
@@ -1306,6 +1593,7 @@ mnemonicCheck:
 		cipherSeedMnemonic []string
 		aezeedPass         []byte
 		recoveryWindow     int32
+		rescanFromHeight   int32
 	)
 	if hasMnemonic {
 		// We'll now prompt the user to enter in their 24-word
@@ -1375,6 +1663,35 @@ mnemonicCheck:
 			recoveryWindow = int32(lookAhead)
 			break
 		}
+
+		for {
+			fmt.Printf("Input an optional block height from which " +
+				"to scan for used keys (default 0): ")
+
+			reader := bufio.NewReader(os.Stdin)
+			answer, err := reader.ReadString('\n')
+			if err != nil {
+				return err
+			}
+
+			fmt.Println()
+
+			answer = strings.TrimSpace(answer)
+
+			if len(answer) == 0 {
+				break
+			}
+
+			height, err := strconv.ParseUint(answer, 10, 31)
+			if err != nil {
+				fmt.Printf("Unable to parse rescan height: "+
+					"%v\n", err)
+				continue
+			}
+
+			rescanFromHeight = int32(height)
+			break
+		}
 	} else {
 		// Otherwise, if the user doesn't have a mnemonic that they
 		// want to use, we'll generate a fresh one with the GenSeed
@@ -1436,6 +1753,7 @@ mnemonicCheck:
 		AezeedPassphrase:   aezeedPass,
 		RecoveryWindow:     recoveryWindow,
 		ChannelBackups:     chanBackups,
+		RescanFromHeight:   rescanFromHeight,
 	}
 	if _, err := client.InitWallet(ctxb, req); err != nil {
 		return err
@@ -1502,6 +1820,10 @@ var unlockCommand = cli.Command{
 	start up. This command MUST be run after booting up dcrlnd before it's
 	able to carry out its duties. An exception is if a user is running with
 	'--noseedbackup', then a default passphrase will be used.
+
+	The unlock command is also used to unlock the wallet of a running node
+	after it was locked with the lockwallet command or automatically locked
+	after being idle. The recovery options are ignored in that case.
 	`,
 	Flags: []cli.Flag{
 		cli.IntFlag{
@@ -1512,6 +1834,12 @@ var unlockCommand = cli.Command{
 				"maximum number of consecutive, unused " +
 				"addresses ever generated by the wallet.",
 		},
+		cli.Int64Flag{
+			Name: "rescan_from_height",
+			Usage: "Block height from which to resume the " +
+				"recovery rescan, skipping any blocks below " +
+				"it that remain to be rescanned.",
+		},
 		cli.BoolFlag{
 			Name: "stdin",
 			Usage: "read password from standard input instead of " +
@@ -1577,10 +1905,17 @@ func unlock(ctx *cli.Context) error {
 	}
 
 	req := &lnrpc.UnlockWalletRequest{
-		WalletPassword: pw,
-		RecoveryWindow: recoveryWindow,
+		WalletPassword:   pw,
+		RecoveryWindow:   recoveryWindow,
+		RescanFromHeight: int32(ctx.Int64("rescan_from_height")),
 	}
 	_, err = client.UnlockWallet(ctxb, req)
+
+	// The wallet unlocker is no longer available once the node is
+	// running, in which case its wallet may have been locked since.
+	if status.Code(err) == codes.Unimplemented {
+		return unlockRunningWallet(ctx, pw)
+	}
 	if err != nil {
 		return err
 	}
@@ -1652,6 +1987,43 @@ func changePassword(ctx *cli.Context) error {
 	return nil
 }
 
+var migrationStatusCommand = cli.Command{
+	Name:     "migrationstatus",
+	Category: "Startup",
+	Usage:    "Track the progress of the database migrations at startup.",
+	Description: `
+	The migrationstatus command streams the progress of the database
+	migrations applied when dcrlnd starts. Creating or unlocking the
+	wallet waits for all migrations to be applied. The command exits once
+	the database is ready.
+	`,
+	Action: actionDecorator(migrationStatus),
+}
+
+func migrationStatus(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getWalletUnlockerClient(ctx)
+	defer cleanUp()
+
+	stream, err := client.SubscribeMigrationStatus(
+		ctxb, &lnrpc.MigrationStatusRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	for {
+		status, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		printRespJSON(status)
+	}
+}
+
 var walletBalanceCommand = cli.Command{
 	Name:     "walletbalance",
 	Category: "Wallet",
@@ -1697,6 +2069,29 @@ func channelBalance(ctx *cli.Context) error {
 	return nil
 }
 
+var balancesCommand = cli.Command{
+	Name:     "balances",
+	Category: "Wallet",
+	Usage: "Display a single snapshot of all the funds of the node, both " +
+		"on-chain and within channels.",
+	Action: actionDecorator(balances),
+}
+
+func balances(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.BalancesRequest{}
+	resp, err := client.Balances(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 var getInfoCommand = cli.Command{
 	Name:   "getinfo",
 	Usage:  "Returns basic information related to the active daemon.",
@@ -1739,26 +2134,128 @@ func getRecoveryInfo(ctx *cli.Context) error {
 	return nil
 }
 
-var pendingChannelsCommand = cli.Command{
-	Name:     "pendingchannels",
-	Category: "Channels",
-	Usage:    "Display information pertaining to pending channels.",
-	Action:   actionDecorator(pendingChannels),
+var dbInfoCommand = cli.Command{
+	Name:  "dbinfo",
+	Usage: "Display information about the channel database.",
+	Description: `
+	Display information about the channel database, including the number
+	of forwarding packages and circuits removed by the background
+	compaction, along with the amount of reclaimed data.`,
+	Action: actionDecorator(dbInfo),
 }
 
-func pendingChannels(ctx *cli.Context) error {
+func dbInfo(ctx *cli.Context) error {
 	ctxb := context.Background()
 	client, cleanUp := getClient(ctx)
 	defer cleanUp()
 
-	req := &lnrpc.PendingChannelsRequest{}
-	resp, err := client.PendingChannels(ctxb, req)
+	req := &lnrpc.DbInfoRequest{}
+	resp, err := client.DbInfo(ctxb, req)
 	if err != nil {
 		return err
 	}
 
 	printRespJSON(resp)
-
+	return nil
+}
+
+var pendingChannelsCommand = cli.Command{
+	Name:     "pendingchannels",
+	Category: "Channels",
+	Usage:    "Display information pertaining to pending channels.",
+	Action:   actionDecorator(pendingChannels),
+}
+
+func pendingChannels(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.PendingChannelsRequest{}
+	resp, err := client.PendingChannels(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var listReservationsCommand = cli.Command{
+	Name:     "listreservations",
+	Category: "Channels",
+	Usage:    "List the active funding reservations.",
+	Description: `
+	List the funding workflows that haven't yet resulted in a channel
+	pending open, along with the coins they hold.`,
+	Action: actionDecorator(listReservations),
+}
+
+func listReservations(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.ListFundingReservationsRequest{}
+	resp, err := client.ListFundingReservations(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var cancelReservationCommand = cli.Command{
+	Name:      "cancelreservation",
+	Category:  "Channels",
+	Usage:     "Cancel an active funding reservation.",
+	ArgsUsage: "pending_chan_id",
+	Description: `
+	Cancel the funding workflow of an active reservation, releasing the
+	coins it holds. An error is sent to the remote node.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "pending_chan_id",
+			Usage: "the hex encoded pending channel ID of the " +
+				"reservation to cancel",
+		},
+	},
+	Action: actionDecorator(cancelReservation),
+}
+
+func cancelReservation(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var pendingChanIDStr string
+	switch {
+	case ctx.IsSet("pending_chan_id"):
+		pendingChanIDStr = ctx.String("pending_chan_id")
+	case ctx.Args().Present():
+		pendingChanIDStr = ctx.Args().First()
+	default:
+		return cli.ShowCommandHelp(ctx, "cancelreservation")
+	}
+
+	pendingChanID, err := hex.DecodeString(pendingChanIDStr)
+	if err != nil {
+		return fmt.Errorf("unable to decode pending_chan_id: %v", err)
+	}
+
+	req := &lnrpc.CancelFundingReservationRequest{
+		PendingChanId: pendingChanID,
+	}
+	resp, err := client.CancelFundingReservation(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
 	return nil
 }
 
@@ -1992,6 +2489,16 @@ var listPaymentsCommand = cli.Command{
 				"index_offset will be returned, allowing " +
 				"forwards pagination",
 		},
+		cli.StringFlag{
+			Name: "start_time",
+			Usage: "only return payments created at or after this " +
+				`time, as unix timestamp or relative e.g. "-1w"`,
+		},
+		cli.StringFlag{
+			Name: "end_time",
+			Usage: "only return payments created at or before this " +
+				`time, as unix timestamp or relative e.g. "-1w"`,
+		},
 	},
 	Action: actionDecorator(listPayments),
 }
@@ -2007,6 +2514,22 @@ func listPayments(ctx *cli.Context) error {
 		Reversed:          !ctx.Bool("paginate_forwards"),
 	}
 
+	now := time.Now()
+	if ctx.IsSet("start_time") {
+		startTime, err := parseTime(ctx.String("start_time"), now)
+		if err != nil {
+			return fmt.Errorf("unable to decode start_time: %v", err)
+		}
+		req.CreationDateStart = startTime
+	}
+	if ctx.IsSet("end_time") {
+		endTime, err := parseTime(ctx.String("end_time"), now)
+		if err != nil {
+			return fmt.Errorf("unable to decode end_time: %v", err)
+		}
+		req.CreationDateEnd = endTime
+	}
+
 	payments, err := client.ListPayments(context.Background(), req)
 	if err != nil {
 		return err
@@ -2067,6 +2590,124 @@ func getChanInfo(ctx *cli.Context) error {
 	return nil
 }
 
+var getChanPolicyHistoryCommand = cli.Command{
+	Name:     "getchanpolicyhistory",
+	Category: "Graph",
+	Usage:    "Get the routing policy changes of a channel.",
+	Description: "Prints out the recorded changes of the routing " +
+		"policies of both directions of a particular channel",
+	ArgsUsage: "chan_id",
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name:  "chan_id",
+			Usage: "The 8-byte compact channel ID to query for",
+		},
+	},
+	Action: actionDecorator(getChanPolicyHistory),
+}
+
+func getChanPolicyHistory(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	var (
+		chanID uint64
+		err    error
+	)
+
+	switch {
+	case ctx.IsSet("chan_id"):
+		chanID = ctx.Uint64("chan_id")
+	case ctx.Args().Present():
+		chanID, err = strconv.ParseUint(ctx.Args().First(), 10, 64)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("chan_id argument missing")
+	}
+
+	req := &lnrpc.ChanPolicyHistoryRequest{
+		ChanId: chanID,
+	}
+
+	history, err := client.GetChanPolicyHistory(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(history)
+	return nil
+}
+
+var resolveChanIDCommand = cli.Command{
+	Name:     "resolvechanid",
+	Category: "Channels",
+	Usage: "Convert between the funding outpoint, short channel ID and " +
+		"channel ID of a channel.",
+	Description: `
+	Looks up a channel by exactly one of its identifiers and prints out all
+	of them. Both our own channels, including closed ones, and the channels
+	of the graph are searched.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "chan_point",
+			Usage: "the funding outpoint of the channel, in the " +
+				"form txid:index",
+		},
+		cli.Uint64Flag{
+			Name:  "chan_id",
+			Usage: "the 8-byte compact short channel ID",
+		},
+		cli.StringFlag{
+			Name:  "channel_id",
+			Usage: "the hex-encoded 32-byte channel ID",
+		},
+	},
+	Action: actionDecorator(resolveChanID),
+}
+
+func resolveChanID(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.ResolveChannelIdRequest{}
+	numIDs := 0
+	if ctx.IsSet("chan_point") {
+		req.Id = &lnrpc.ResolveChannelIdRequest_ChannelPoint{
+			ChannelPoint: ctx.String("chan_point"),
+		}
+		numIDs++
+	}
+	if ctx.IsSet("chan_id") {
+		req.Id = &lnrpc.ResolveChannelIdRequest_ChanId{
+			ChanId: ctx.Uint64("chan_id"),
+		}
+		numIDs++
+	}
+	if ctx.IsSet("channel_id") {
+		req.Id = &lnrpc.ResolveChannelIdRequest_ChannelId{
+			ChannelId: ctx.String("channel_id"),
+		}
+		numIDs++
+	}
+	if numIDs != 1 {
+		return fmt.Errorf("exactly one of chan_point, chan_id or " +
+			"channel_id must be set")
+	}
+
+	resp, err := client.ResolveChannelId(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 var getNodeInfoCommand = cli.Command{
 	Name:     "getnodeinfo",
 	Category: "Graph",
@@ -2084,6 +2725,22 @@ var getNodeInfoCommand = cli.Command{
 			Usage: "If true, will return all known channels " +
 				"associated with the node",
 		},
+		cli.BoolFlag{
+			Name: "shared_only",
+			Usage: "If true, will only return the channels we " +
+				"share with the node, including unannounced " +
+				"ones",
+		},
+		cli.Uint64Flag{
+			Name: "channels_offset",
+			Usage: "The number of channels to skip, used to " +
+				"page through the channels of the node",
+		},
+		cli.Uint64Flag{
+			Name: "max_channels",
+			Usage: "The maximum number of channels to return, " +
+				"zero returns all channels",
+		},
 	},
 	Action: actionDecorator(getNodeInfo),
 }
@@ -2106,8 +2763,11 @@ func getNodeInfo(ctx *cli.Context) error {
 	}
 
 	req := &lnrpc.NodeInfoRequest{
-		PubKey:          pubKey,
-		IncludeChannels: ctx.Bool("include_channels"),
+		PubKey:             pubKey,
+		IncludeChannels:    ctx.Bool("include_channels"),
+		SharedChannelsOnly: ctx.Bool("shared_only"),
+		ChannelsOffset:     uint32(ctx.Uint64("channels_offset")),
+		MaxChannels:        uint32(ctx.Uint64("max_channels")),
 	}
 
 	nodeInfo, err := client.GetNodeInfo(ctxb, req)
@@ -2126,6 +2786,12 @@ var queryRoutesCommand = cli.Command{
 	Description: "Queries the channel router for a potential path to the destination that has sufficient flow for the amount including fees",
 	ArgsUsage:   "dest amt",
 	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "pay_req",
+			Usage: "a payment request whose destination, amount, " +
+				"route hints, features and final cltv delta " +
+				"are used unless overridden (optional)",
+		},
 		cli.StringFlag{
 			Name: "dest",
 			Usage: "The 33-byte hex-encoded public key for the payment " +
@@ -2155,6 +2821,12 @@ var queryRoutesCommand = cli.Command{
 			Usage: "Use mission control probabilities",
 		},
 		cltvLimitFlag,
+		cli.UintFlag{
+			Name: "num_routes",
+			Usage: "the number of disjoint routes to return, " +
+				"ranked by fee and success probability",
+			Value: 1,
+		},
 	},
 	Action: actionDecorator(queryRoutes),
 }
@@ -2165,11 +2837,22 @@ func queryRoutes(ctx *cli.Context) error {
 	defer cleanUp()
 
 	var (
-		dest string
-		amt  int64
-		err  error
+		dest      string
+		amt       int64
+		amtMAtoms int64
+		payReq    *lnrpc.PayReq
+		err       error
 	)
 
+	if ctx.IsSet("pay_req") {
+		payReq, err = client.DecodePayReq(ctxb, &lnrpc.PayReqString{
+			PayReq: ctx.String("pay_req"),
+		})
+		if err != nil {
+			return fmt.Errorf("unable to decode pay_req: %v", err)
+		}
+	}
+
 	args := ctx.Args()
 
 	switch {
@@ -2178,6 +2861,8 @@ func queryRoutes(ctx *cli.Context) error {
 	case args.Present():
 		dest = args.First()
 		args = args.Tail()
+	case payReq != nil:
+		dest = payReq.Destination
 	default:
 		return fmt.Errorf("dest argument missing")
 	}
@@ -2190,6 +2875,8 @@ func queryRoutes(ctx *cli.Context) error {
 		if err != nil {
 			return fmt.Errorf("unable to decode amt argument: %v", err)
 		}
+	case payReq != nil && payReq.NumMAtoms != 0:
+		amtMAtoms = payReq.NumMAtoms
 	default:
 		return fmt.Errorf("amt argument missing")
 	}
@@ -2202,10 +2889,25 @@ func queryRoutes(ctx *cli.Context) error {
 	req := &lnrpc.QueryRoutesRequest{
 		PubKey:            dest,
 		Amt:               amt,
+		AmtMAtoms:         amtMAtoms,
 		FeeLimit:          feeLimit,
 		FinalCltvDelta:    int32(ctx.Int("final_cltv_delta")),
 		UseMissionControl: ctx.Bool("use_mc"),
 		CltvLimit:         uint32(ctx.Uint64(cltvLimitFlag.Name)),
+		NumRoutes:         uint32(ctx.Uint("num_routes")),
+	}
+
+	if payReq != nil {
+		req.RouteHints = payReq.RouteHints
+		for bit := range payReq.Features {
+			req.DestFeatures = append(
+				req.DestFeatures, lnrpc.FeatureBit(bit),
+			)
+		}
+
+		if !ctx.IsSet("final_cltv_delta") {
+			req.FinalCltvDelta = int32(payReq.CltvExpiry)
+		}
 	}
 
 	route, err := client.QueryRoutes(ctxb, req)
@@ -2281,7 +2983,10 @@ var debugLevelCommand = cli.Command{
 	Description: `Logging level for all subsystems {trace, debug, info, warn, error, critical, off}
 	You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems
 
-	Use show to list available subsystems`,
+	Use show to list available subsystems and active channel and peer overrides
+
+	With --chan_point or --peer, the level only applies to the logs of that channel or peer. An empty level removes the override.
+	With --revert_after, the change is reverted after the given number of minutes`,
 	Flags: []cli.Flag{
 		cli.BoolFlag{
 			Name:  "show",
@@ -2291,6 +2996,18 @@ var debugLevelCommand = cli.Command{
 			Name:  "level",
 			Usage: "the level specification to target either a coarse logging level, or granular set of specific sub-systems with logging levels for each",
 		},
+		cli.Uint64Flag{
+			Name:  "revert_after",
+			Usage: "if set, revert the change after this many minutes",
+		},
+		cli.StringFlag{
+			Name:  "chan_point",
+			Usage: "only change the level of the logs of the channel with this funding_txid:output_index",
+		},
+		cli.StringFlag{
+			Name:  "peer",
+			Usage: "only change the level of the logs of the peer with this hex-encoded pubkey",
+		},
 	},
 	Action: actionDecorator(debugLevel),
 }
@@ -2300,8 +3017,18 @@ func debugLevel(ctx *cli.Context) error {
 	client, cleanUp := getClient(ctx)
 	defer cleanUp()
 	req := &lnrpc.DebugLevelRequest{
-		Show:      ctx.Bool("show"),
-		LevelSpec: ctx.String("level"),
+		Show:               ctx.Bool("show"),
+		LevelSpec:          ctx.String("level"),
+		RevertAfterMinutes: uint32(ctx.Uint64("revert_after")),
+		ChanPoint:          ctx.String("chan_point"),
+	}
+
+	if ctx.IsSet("peer") {
+		peer, err := hex.DecodeString(ctx.String("peer"))
+		if err != nil {
+			return fmt.Errorf("unable to decode peer pubkey: %v", err)
+		}
+		req.Peer = peer
 	}
 
 	resp, err := client.DebugLevel(ctxb, req)
@@ -2313,55 +3040,23 @@ func debugLevel(ctx *cli.Context) error {
 	return nil
 }
 
-var listChainTxnsCommand = cli.Command{
-	Name:     "listchaintxns",
-	Category: "On-chain",
-	Usage:    "List transactions from the wallet.",
-	Flags: []cli.Flag{
-		cli.Int64Flag{
-			Name: "start_height",
-			Usage: "the block height from which to list " +
-				"transactions, inclusive",
-		},
-		cli.Int64Flag{
-			Name: "end_height",
-			Usage: "the block height until which to list " +
-				"transactions, inclusive, to get transactions " +
-				"until the chain tip, including unconfirmed, " +
-				"set this value to -1",
-		},
-	},
+var getCrashReportCommand = cli.Command{
+	Name:  "getcrashreport",
+	Usage: "Display the report recorded on the last panic.",
 	Description: `
-	List all transactions an address of the wallet was involved in.
-
-	This call will return a list of wallet related transactions that paid
-	to an address our wallet controls, or spent utxos that we held. The
-	start_height and end_height flags can be used to specify an inclusive
-	block range over which to query for transactions. If the end_height is
-	less than the start_height, transactions will be queried in reverse.
-	To get all transactions until the chain tip, including unconfirmed
-	transactions (identifiable with BlockHeight=0), set end_height to -1.
-	By default, this call will get all transactions our wallet was involved
-	in, including unconfirmed transactions. 
-`,
-	Action: actionDecorator(listChainTxns),
+	Display the report recorded on the last panic of the daemon, including
+	the subsystem or RPC method it occurred in, its stack trace and the log
+	lines preceding it. The report is kept across restarts.`,
+	Action: actionDecorator(getCrashReport),
 }
 
-func listChainTxns(ctx *cli.Context) error {
+func getCrashReport(ctx *cli.Context) error {
 	ctxb := context.Background()
 	client, cleanUp := getClient(ctx)
 	defer cleanUp()
 
-	req := &lnrpc.GetTransactionsRequest{}
-
-	if ctx.IsSet("start_height") {
-		req.StartHeight = int32(ctx.Int64("start_height"))
-	}
-	if ctx.IsSet("end_height") {
-		req.EndHeight = int32(ctx.Int64("end_height"))
-	}
-
-	resp, err := client.GetTransactions(ctxb, req)
+	req := &lnrpc.CrashReportRequest{}
+	resp, err := client.GetCrashReport(ctxb, req)
 	if err != nil {
 		return err
 	}
@@ -2370,26 +3065,326 @@ func listChainTxns(ctx *cli.Context) error {
 	return nil
 }
 
-var stopCommand = cli.Command{
-	Name:  "stop",
-	Usage: "Stop and shutdown the daemon.",
+var killSwitchCommand = cli.Command{
+	Name:  "killswitch",
+	Usage: "Block or allow new payments, invoices and forwards.",
 	Description: `
-	Gracefully stop all daemon subsystems before stopping the daemon itself.
-	This is equivalent to stopping it using CTRL-C.`,
-	Action: actionDecorator(stopDaemon),
+	Show or update the node-wide kill switches. Each switch refuses new
+	operations of its kind while blocked, which is useful during maintenance
+	windows and incident response:
+
+	 - payments: refuse to originate new payments.
+	 - invoices: refuse to add new invoices, to accept htlcs paying to our
+	   invoices and to settle hodl invoices.
+	 - forwards: refuse to forward new htlcs.
+
+	Each switch is set to either 'block' or 'allow'. Switches that aren't
+	specified keep their state. Without any flags, the current state of the
+	switches is printed. The switches are reset on restart.`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "payments",
+			Usage: "block or allow new payments",
+		},
+		cli.StringFlag{
+			Name:  "invoices",
+			Usage: "block or allow new invoices and settlements",
+		},
+		cli.StringFlag{
+			Name:  "forwards",
+			Usage: "block or allow new forwards",
+		},
+	},
+	Action: actionDecorator(killSwitch),
 }
 
-func stopDaemon(ctx *cli.Context) error {
-	ctxb := context.Background()
-	client, cleanUp := getClient(ctx)
-	defer cleanUp()
+// parseKillSwitchAction parses the kill switch action of the given flag.
+func parseKillSwitchAction(ctx *cli.Context, flag string) (
+	lnrpc.KillSwitchAction, error) {
 
-	_, err := client.StopDaemon(ctxb, &lnrpc.StopRequest{})
-	if err != nil {
-		return err
+	if !ctx.IsSet(flag) {
+		return lnrpc.KillSwitchAction_KILL_SWITCH_UNCHANGED, nil
 	}
 
-	return nil
+	switch ctx.String(flag) {
+	case "block":
+		return lnrpc.KillSwitchAction_KILL_SWITCH_BLOCK, nil
+
+	case "allow":
+		return lnrpc.KillSwitchAction_KILL_SWITCH_ALLOW, nil
+
+	default:
+		return 0, fmt.Errorf("invalid %v value %q, expected block "+
+			"or allow", flag, ctx.String(flag))
+	}
+}
+
+func killSwitch(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if ctx.NumFlags() == 0 {
+		resp, err := client.GetKillSwitches(
+			ctxb, &lnrpc.GetKillSwitchesRequest{},
+		)
+		if err != nil {
+			return err
+		}
+
+		printRespJSON(resp)
+		return nil
+	}
+
+	req := &lnrpc.UpdateKillSwitchesRequest{}
+	var err error
+	req.Payments, err = parseKillSwitchAction(ctx, "payments")
+	if err != nil {
+		return err
+	}
+	req.Invoices, err = parseKillSwitchAction(ctx, "invoices")
+	if err != nil {
+		return err
+	}
+	req.Forwards, err = parseKillSwitchAction(ctx, "forwards")
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.UpdateKillSwitches(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var listChainTxnsCommand = cli.Command{
+	Name:     "listchaintxns",
+	Category: "On-chain",
+	Usage:    "List transactions from the wallet.",
+	Flags: []cli.Flag{
+		cli.Int64Flag{
+			Name: "start_height",
+			Usage: "the block height from which to list " +
+				"transactions, inclusive",
+		},
+		cli.Int64Flag{
+			Name: "end_height",
+			Usage: "the block height until which to list " +
+				"transactions, inclusive, to get transactions " +
+				"until the chain tip, including unconfirmed, " +
+				"set this value to -1",
+		},
+		cli.StringSliceFlag{
+			Name: "address",
+			Usage: "only list transactions paying to this " +
+				"address, can be specified multiple times",
+		},
+		cli.Int64SliceFlag{
+			Name: "account",
+			Usage: "only list transactions involving this wallet " +
+				"account, can be specified multiple times",
+		},
+		cli.Int64Flag{
+			Name: "min_amount",
+			Usage: "only list transactions whose absolute amount " +
+				"is at least this many atoms",
+		},
+		cli.BoolFlag{
+			Name:  "confirmed_only",
+			Usage: "do not list unconfirmed transactions",
+		},
+		cli.StringFlag{
+			Name:  "label",
+			Usage: "only list transactions with this label",
+		},
+		cli.BoolFlag{
+			Name: "exclude_stake",
+			Usage: "do not list tickets, votes, revocations and " +
+				"treasury transactions",
+		},
+	},
+	Description: `
+	List all transactions an address of the wallet was involved in.
+
+	This call will return a list of wallet related transactions that paid
+	to an address our wallet controls, or spent utxos that we held. The
+	start_height and end_height flags can be used to specify an inclusive
+	block range over which to query for transactions. If the end_height is
+	less than the start_height, transactions will be queried in reverse.
+	To get all transactions until the chain tip, including unconfirmed
+	transactions (identifiable with BlockHeight=0), set end_height to -1.
+	By default, this call will get all transactions our wallet was involved
+	in, including unconfirmed transactions. 
+`,
+	Action: actionDecorator(listChainTxns),
+}
+
+func listChainTxns(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.GetTransactionsRequest{}
+
+	if ctx.IsSet("start_height") {
+		req.StartHeight = int32(ctx.Int64("start_height"))
+	}
+	if ctx.IsSet("end_height") {
+		req.EndHeight = int32(ctx.Int64("end_height"))
+	}
+	req.Addresses = ctx.StringSlice("address")
+	for _, account := range ctx.Int64Slice("account") {
+		req.Accounts = append(req.Accounts, uint32(account))
+	}
+	req.MinAmount = ctx.Int64("min_amount")
+	req.ConfirmedOnly = ctx.Bool("confirmed_only")
+	req.Label = ctx.String("label")
+	req.ExcludeStake = ctx.Bool("exclude_stake")
+
+	resp, err := client.GetTransactions(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var stopCommand = cli.Command{
+	Name:  "stop",
+	Usage: "Stop and shutdown the daemon.",
+	Description: `
+	Gracefully stop all daemon subsystems before stopping the daemon itself.
+	This is equivalent to stopping it using CTRL-C.
+
+	If --drain is set, the daemon first stops forwarding new htlcs and
+	originating new payments, and waits for the htlcs in flight on its
+	channels to resolve and for its pending sweeps to be broadcast, up to
+	--drain_timeout seconds, before shutting down.
+
+	If --wait is set, the progress of the shutdown is printed as the
+	daemon's subsystems are stopped, until the shutdown completes.`,
+	Flags: []cli.Flag{
+		cli.BoolFlag{
+			Name: "drain",
+			Usage: "wait for in-flight htlcs and pending sweeps " +
+				"to settle before shutting down",
+		},
+		cli.UintFlag{
+			Name: "drain_timeout",
+			Usage: "the maximum number of seconds to wait for the " +
+				"drain to complete, defaults to 300",
+		},
+		cli.BoolFlag{
+			Name: "wait",
+			Usage: "print the progress of the shutdown until it " +
+				"completes",
+		},
+	},
+	Action: actionDecorator(stopDaemon),
+}
+
+func stopDaemon(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// The progress stream is opened before requesting the shutdown, so
+	// that no update is missed.
+	var stream lnrpc.Lightning_SubscribeShutdownProgressClient
+	if ctx.Bool("wait") {
+		var err error
+		stream, err = client.SubscribeShutdownProgress(
+			ctxb, &lnrpc.ShutdownProgressRequest{},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := client.StopDaemon(ctxb, &lnrpc.StopRequest{
+		Drain:        ctx.Bool("drain"),
+		DrainTimeout: uint32(ctx.Uint("drain_timeout")),
+	})
+	if err != nil {
+		return err
+	}
+
+	if stream == nil {
+		return nil
+	}
+
+	for {
+		progress, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		printRespJSON(progress)
+	}
+}
+
+var enterMaintenanceCommand = cli.Command{
+	Name:     "entermaintenance",
+	Category: "Channels",
+	Usage:    "Disable all channels ahead of a planned restart.",
+	Description: `
+	Put the node in maintenance mode ahead of a planned restart. All public
+	channels are announced as disabled so that payments are routed around
+	the node, and kept disabled even if their peers reconnect. Autopilot is
+	paused and the sweeps of non-urgent inputs are held back.
+
+	Maintenance mode ends with exitmaintenance or a restart of the node.`,
+	Action: actionDecorator(enterMaintenance),
+}
+
+func enterMaintenance(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	resp, err := client.EnterMaintenance(
+		ctxb, &lnrpc.EnterMaintenanceRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var exitMaintenanceCommand = cli.Command{
+	Name:     "exitmaintenance",
+	Category: "Channels",
+	Usage:    "End the maintenance mode and re-enable the channels.",
+	Description: `
+	End the maintenance mode started by entermaintenance. Channels with an
+	active link are announced as enabled, autopilot is resumed if it was
+	active and the sweeps of non-urgent inputs are resumed.`,
+	Action: actionDecorator(exitMaintenance),
+}
+
+func exitMaintenance(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	resp, err := client.ExitMaintenance(
+		ctxb, &lnrpc.ExitMaintenanceRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
 }
 
 var signMessageCommand = cli.Command{
@@ -2529,15 +3524,29 @@ func feeReport(ctx *cli.Context) error {
 var updateChannelPolicyCommand = cli.Command{
 	Name:     "updatechanpolicy",
 	Category: "Channels",
-	Usage: "Update the channel policy for all channels, or a single " +
-		"channel.",
+	Usage: "Update the channel policy for all channels, or specific " +
+		"channels.",
 	ArgsUsage: "base_fee_m_atoms fee_rate time_lock_delta " +
-		"[--max_htlc_m_atoms=N] [channel_point]",
+		"[--max_htlc_m_atoms=N] [--dry_run] [channel_point]",
 	Description: `
-	Updates the channel policy for all channels, or just a particular channel
-	identified by its channel point. The update will be committed, and
-	broadcast to the rest of the network within the next batch.
-	Channel points are encoded as 'funding_txid:output_index'`,
+	Updates the channel policy for all channels, or just the channels
+	identified by their channel point. The --chan_point flag can be repeated
+	to update several channels. The update will be committed, and broadcast
+	to the rest of the network within the next batch. Channels whose policy
+	can't be updated are reported along with the reason.
+	Channel points are encoded as 'funding_txid:output_index'
+
+	With --dry_run, the resulting policies are returned without being
+	committed nor broadcast.
+
+	An inbound fee can be charged on HTLCs received through a channel, in
+	addition to the fee of the channel they are forwarded to, pricing
+	liquidity depending on the direction it flows. Negative inbound fees are
+	discounts on the outgoing fee, e.g.
+	'--inbound_base_fee_m_atoms=-1000 --inbound_fee_rate_ppm=-100'. Only
+	senders aware of inbound fees pay inbound surcharges, so forwards from
+	other senders over a channel with a positive inbound fee fail. Setting
+	both values to zero removes the inbound fee.`,
 	Flags: []cli.Flag{
 		cli.Int64Flag{
 			Name: "base_fee_m_atoms",
@@ -2569,11 +3578,33 @@ var updateChannelPolicyCommand = cli.Command{
 				"to all forwarded HTLCs. If unset, the max HTLC " +
 				"is left unchanged.",
 		},
-		cli.StringFlag{
+		cli.Int64Flag{
+			Name: "inbound_base_fee_m_atoms",
+			Usage: "If set, the base fee in milli-atoms charged, " +
+				"or discounted if negative, for each HTLC " +
+				"received through the channel. If neither " +
+				"inbound fee is set, the inbound fee is left " +
+				"unchanged.",
+		},
+		cli.Int64Flag{
+			Name: "inbound_fee_rate_ppm",
+			Usage: "If set, the fee in millionths of the " +
+				"forwarded amount charged, or discounted if " +
+				"negative, for each HTLC received through the " +
+				"channel.",
+		},
+		cli.StringSliceFlag{
 			Name: "chan_point",
 			Usage: "The channel whose fee policy should be " +
 				"updated, if nil the policies for all channels " +
-				"will be updated. Takes the form of 'txid:output_index'",
+				"will be updated. Takes the form of " +
+				"'txid:output_index'. Can be repeated to update " +
+				"several channels.",
+		},
+		cli.BoolFlag{
+			Name: "dry_run",
+			Usage: "If set, the resulting policies are returned " +
+				"without being committed nor broadcast",
 		},
 	},
 	Action: actionDecorator(updateChannelPolicy),
@@ -2659,23 +3690,21 @@ func updateChannelPolicy(ctx *cli.Context) error {
 		return fmt.Errorf("time_lock_delta argument missing")
 	}
 
-	var (
-		chanPoint    *lnrpc.ChannelPoint
-		chanPointStr string
-	)
-
+	var chanPointStrs []string
 	switch {
 	case ctx.IsSet("chan_point"):
-		chanPointStr = ctx.String("chan_point")
+		chanPointStrs = ctx.StringSlice("chan_point")
 	case args.Present():
-		chanPointStr = args.First()
+		chanPointStrs = []string{args.First()}
 	}
 
-	if chanPointStr != "" {
-		chanPoint, err = parseChanPoint(chanPointStr)
+	var chanPoints []*lnrpc.ChannelPoint
+	for _, chanPointStr := range chanPointStrs {
+		chanPoint, err := parseChanPoint(chanPointStr)
 		if err != nil {
 			return fmt.Errorf("unable to parse chan point: %v", err)
 		}
+		chanPoints = append(chanPoints, chanPoint)
 	}
 
 	req := &lnrpc.PolicyUpdateRequest{
@@ -2683,6 +3712,7 @@ func updateChannelPolicy(ctx *cli.Context) error {
 		FeeRate:       feeRate,
 		TimeLockDelta: uint32(timeLockDelta),
 		MaxHtlcMAtoms: ctx.Uint64("max_htlc_m_atoms"),
+		DryRun:        ctx.Bool("dry_run"),
 	}
 
 	if ctx.IsSet("min_htlc_m_atoms") {
@@ -2690,14 +3720,36 @@ func updateChannelPolicy(ctx *cli.Context) error {
 		req.MinHtlcMAtomsSpecified = true
 	}
 
-	if chanPoint != nil {
-		req.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{
-			ChanPoint: chanPoint,
+	if ctx.IsSet("inbound_base_fee_m_atoms") ||
+		ctx.IsSet("inbound_fee_rate_ppm") {
+
+		inboundBaseFee := ctx.Int64("inbound_base_fee_m_atoms")
+		inboundFeeRate := ctx.Int64("inbound_fee_rate_ppm")
+		if inboundBaseFee != int64(int32(inboundBaseFee)) ||
+			inboundFeeRate != int64(int32(inboundFeeRate)) {
+
+			return fmt.Errorf("inbound fee out of range")
 		}
-	} else {
+
+		req.InboundFee = &lnrpc.InboundFee{
+			BaseFeeMAtoms: int32(inboundBaseFee),
+			FeeRatePpm:    int32(inboundFeeRate),
+		}
+	}
+
+	switch len(chanPoints) {
+	case 0:
 		req.Scope = &lnrpc.PolicyUpdateRequest_Global{
 			Global: true,
 		}
+
+	case 1:
+		req.Scope = &lnrpc.PolicyUpdateRequest_ChanPoint{
+			ChanPoint: chanPoints[0],
+		}
+
+	default:
+		req.ChanPoints = chanPoints
 	}
 
 	resp, err := client.UpdateChannelPolicy(ctxb, req)
@@ -2709,6 +3761,121 @@ func updateChannelPolicy(ctx *cli.Context) error {
 	return nil
 }
 
+var updateExpiryGraceCommand = cli.Command{
+	Name:     "updateexpirygrace",
+	Category: "Channels",
+	Usage: "Update the number of blocks before their expiry at which " +
+		"htlcs are canceled back.",
+	Description: `
+	Updates the number of blocks before their expiry at which incoming htlcs
+	paying to this node and outgoing htlcs are canceled back. Values that
+	aren't specified keep their current setting. The new values apply to
+	htlcs processed after the update and are reset to the configured values
+	on restart.
+
+	The currently effective values are reported by the 'getinfo' command.`,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name: "final_cltv_reject_delta",
+			Usage: "the number of blocks before the expiry of an " +
+				"incoming htlc paying to us at which it is " +
+				"canceled back",
+		},
+		cli.Uint64Flag{
+			Name: "outgoing_cltv_reject_delta",
+			Usage: "the number of blocks before the expiry of an " +
+				"htlc at which it is no longer offered to the " +
+				"next peer",
+		},
+	},
+	Action: actionDecorator(updateExpiryGrace),
+}
+
+func updateExpiryGrace(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	if !ctx.IsSet("final_cltv_reject_delta") &&
+		!ctx.IsSet("outgoing_cltv_reject_delta") {
+
+		return cli.ShowCommandHelp(ctx, "updateexpirygrace")
+	}
+
+	req := &lnrpc.UpdateHtlcExpiryGraceRequest{
+		FinalCltvRejectDelta: uint32(
+			ctx.Uint64("final_cltv_reject_delta"),
+		),
+		OutgoingCltvRejectDelta: uint32(
+			ctx.Uint64("outgoing_cltv_reject_delta"),
+		),
+	}
+	resp, err := client.UpdateHtlcExpiryGrace(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var updatePendingLimitsCommand = cli.Command{
+	Name:     "updatependinglimits",
+	Category: "Channels",
+	Usage: "Update the maximum number of incoming pending channels " +
+		"permitted per peer and across all peers.",
+	Description: `
+	Updates the maximum number of incoming pending channels permitted per
+	peer and across all peers. Values that aren't specified keep their
+	current setting. The new limits apply to funding requests received
+	after the update and are reset to the configured values on restart.
+
+	When called without any flags, the current limits are displayed along
+	with the number of pending channels counting towards them. The number
+	of pending channels with each peer is reported by the 'listpeers'
+	command.`,
+	Flags: []cli.Flag{
+		cli.Uint64Flag{
+			Name: "max_pending_channels",
+			Usage: "the maximum number of incoming pending channels " +
+				"permitted per peer",
+		},
+		cli.Uint64Flag{
+			Name: "max_global_pending_channels",
+			Usage: "the maximum number of incoming pending channels " +
+				"permitted across all peers",
+		},
+		cli.BoolFlag{
+			Name:  "remove_global_limit",
+			Usage: "remove the limit across all peers",
+		},
+	},
+	Action: actionDecorator(updatePendingLimits),
+}
+
+func updatePendingLimits(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	req := &lnrpc.UpdatePendingChannelLimitsRequest{
+		MaxPendingChannels: uint32(
+			ctx.Uint64("max_pending_channels"),
+		),
+		MaxGlobalPendingChannels: uint32(
+			ctx.Uint64("max_global_pending_channels"),
+		),
+		RemoveGlobalLimit: ctx.Bool("remove_global_limit"),
+	}
+	resp, err := client.UpdatePendingChannelLimits(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
 var forwardingHistoryCommand = cli.Command{
 	Name:      "fwdinghistory",
 	Category:  "Payments",
@@ -2731,6 +3898,10 @@ var forwardingHistoryCommand = cli.Command{
 	Finally, callers can skip a series of events using the '--index_offset'
 	parameter. Each response will contain the offset index of the last
 	entry. Using this callers can manually paginate within a time slice.
+
+	The events can be filtered by channel, peer, forwarded amount and fee.
+	The aliases of the peers of the channels are included with
+	'--peer_alias_lookup'.
 	`,
 	Flags: []cli.Flag{
 		cli.StringFlag{
@@ -2751,6 +3922,37 @@ var forwardingHistoryCommand = cli.Command{
 			Name:  "max_events",
 			Usage: "The max number of events to return",
 		},
+		cli.BoolFlag{
+			Name: "peer_alias_lookup",
+			Usage: "Include the aliases of the peers of the " +
+				"incoming and outgoing channels",
+		},
+		cli.Int64SliceFlag{
+			Name: "chan_id",
+			Usage: "Only return events forwarded through this " +
+				"channel, can be specified multiple times",
+		},
+		cli.StringFlag{
+			Name: "peer",
+			Usage: "Only return events forwarded through a channel " +
+				"with the peer with this hex-encoded pubkey",
+		},
+		cli.Uint64Flag{
+			Name: "min_amt_out_m_atoms",
+			Usage: "Only return events forwarding at least this " +
+				"amount in milli-atoms",
+		},
+		cli.Uint64Flag{
+			Name: "min_fee_m_atoms",
+			Usage: "Only return events that earned at least this " +
+				"fee in milli-atoms",
+		},
+		cli.BoolFlag{
+			Name: "failed_hold_fees",
+			Usage: "Return the failed forwards that offered an " +
+				"experimental hold fee instead of the " +
+				"settled forwards",
+		},
 	},
 	Action: actionDecorator(forwardingHistory),
 }
@@ -2819,11 +4021,22 @@ func forwardingHistory(ctx *cli.Context) error {
 		args = args.Tail()
 	}
 
+	var chanIDs []uint64
+	for _, chanID := range ctx.Int64Slice("chan_id") {
+		chanIDs = append(chanIDs, uint64(chanID))
+	}
+
 	req := &lnrpc.ForwardingHistoryRequest{
-		StartTime:    startTime,
-		EndTime:      endTime,
-		IndexOffset:  indexOffset,
-		NumMaxEvents: maxEvents,
+		StartTime:       startTime,
+		EndTime:         endTime,
+		IndexOffset:     indexOffset,
+		NumMaxEvents:    maxEvents,
+		PeerAliasLookup: ctx.Bool("peer_alias_lookup"),
+		ChanIds:         chanIDs,
+		PeerPubkey:      ctx.String("peer"),
+		MinAmtOutMAtoms: ctx.Uint64("min_amt_out_m_atoms"),
+		MinFeeMAtoms:    ctx.Uint64("min_fee_m_atoms"),
+		FailedHoldFees:  ctx.Bool("failed_hold_fees"),
 	}
 	resp, err := client.ForwardingHistory(ctxb, req)
 	if err != nil {
@@ -2839,7 +4052,8 @@ var exportChanBackupCommand = cli.Command{
 	Category: "Channels",
 	Usage: "Obtain a static channel back up for a selected channels, " +
 		"or all known channels",
-	ArgsUsage: "[chan_point] [--all] [--output_file]",
+	ArgsUsage: "[chan_point] [--all [--peer] [--filter_chan_point] " +
+		"[--active_only] [--server_file]] [--output_file]",
 	Description: `
 	This command allows a user to export a Static Channel Backup (SCB) for
 	a selected channel. SCB's are encrypted backups of a channel's initial
@@ -2859,7 +4073,12 @@ var exportChanBackupCommand = cli.Command{
 	   * If the '--all' flag is passed, then a multi-channel backup will be
 	     returned. A multi backup is a single encrypted blob (displayed in
 	     hex encoding) that contains several channels in a single cipher
-	     text.
+	     text. The set of channels included in it can be restricted to
+	     the ones with a given peer, a list of channel points or the
+	     currently active channels. With '--server_file', the multi
+	     backup is also written atomically to a file on the node, within
+	     its configured backupexportdir. This requires a macaroon with the
+	     offchain:write permission.
 
 	Both of the backup types can be restored using the 'restorechanbackup'
 	command.
@@ -2874,6 +4093,27 @@ var exportChanBackupCommand = cli.Command{
 			Usage: "If specified, then a multi backup of all " +
 				"active channels will be returned",
 		},
+		cli.StringFlag{
+			Name: "peer",
+			Usage: "(optional) only include the channels with " +
+				"this peer in the multi backup",
+		},
+		cli.StringSliceFlag{
+			Name: "filter_chan_point",
+			Usage: "(optional) only include this channel in the " +
+				"multi backup, can be specified multiple times",
+		},
+		cli.BoolFlag{
+			Name: "active_only",
+			Usage: "(optional) only include the currently " +
+				"active channels in the multi backup",
+		},
+		cli.StringFlag{
+			Name: "server_file",
+			Usage: "(optional) also write the multi backup to " +
+				"this file on the node, relative to its " +
+				"backupexportdir",
+		},
 		cli.StringFlag{
 			Name: "output_file",
 			Usage: `
@@ -2956,9 +4196,27 @@ func exportChanBackup(ctx *cli.Context) error {
 		return fmt.Errorf("if a channel isn't specified, --all must be")
 	}
 
-	chanBackup, err := client.ExportAllChannelBackups(
-		ctxb, &lnrpc.ChanBackupExportRequest{},
-	)
+	req := &lnrpc.ChanBackupExportRequest{
+		ActiveOnly:     ctx.Bool("active_only"),
+		ExportFilePath: ctx.String("server_file"),
+	}
+
+	if ctx.IsSet("peer") {
+		req.Peer, err = hex.DecodeString(ctx.String("peer"))
+		if err != nil {
+			return fmt.Errorf("unable to decode peer: %v", err)
+		}
+	}
+
+	for _, chanPointStr := range ctx.StringSlice("filter_chan_point") {
+		chanPoint, err := parseChanPoint(chanPointStr)
+		if err != nil {
+			return err
+		}
+		req.ChanPoints = append(req.ChanPoints, chanPoint)
+	}
+
+	chanBackup, err := client.ExportAllChannelBackups(ctxb, req)
 	if err != nil {
 		return err
 	}
@@ -3201,3 +4459,313 @@ func restoreChanBackup(ctx *cli.Context) error {
 
 	return nil
 }
+
+var chanRecoveryKeysCommand = cli.Command{
+	Name:     "chanrecoverykeys",
+	Category: "Channels",
+	Usage: "Derive the keys of the channels within a static channel " +
+		"backup",
+	ArgsUsage: "[--single_backup] [--multi_backup] [--multi_file=] " +
+		"[--start_height=] [--num_heights=]",
+	Description: `
+	Re-derives the keys of the channels contained in a Static Channel
+	Backup (SCB), along with the keys used by our commitment transactions
+	within the given range of commitment heights. This allows funds of a
+	force closed channel to be located and swept with external tools.
+
+	The backup is accepted in the same forms as the 'restorechanbackup'
+	command.
+
+	This command requires a macaroon with the emergency:read permission,
+	which can be created with the 'bakemacaroon' command.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "single_backup",
+			Usage: "A hex encoded single channel backup obtained " +
+				"from exportchanbackup",
+		},
+		cli.StringFlag{
+			Name: "multi_backup",
+			Usage: "A hex encoded multi-channel backup obtained " +
+				"from exportchanbackup",
+		},
+		cli.StringFlag{
+			Name:  "multi_file",
+			Usage: "The path to a multi-channel back up file",
+		},
+		cli.Uint64Flag{
+			Name: "start_height",
+			Usage: "The first commitment height to derive the " +
+				"commitment keys for",
+		},
+		cli.Uint64Flag{
+			Name: "num_heights",
+			Usage: "The number of commitment heights to derive the " +
+				"commitment keys for",
+		},
+	},
+	Action: actionDecorator(chanRecoveryKeys),
+}
+
+func chanRecoveryKeys(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments provided
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		cli.ShowCommandHelp(ctx, "chanrecoverykeys")
+		return nil
+	}
+
+	backups, err := parseChanBackups(ctx)
+	if err != nil {
+		return err
+	}
+
+	req := &lnrpc.ChannelRecoveryKeysRequest{}
+	switch backup := backups.Backup.(type) {
+	case *lnrpc.RestoreChanBackupRequest_ChanBackups:
+		req.Backup = &lnrpc.ChannelRecoveryKeysRequest_ChanBackups{
+			ChanBackups: backup.ChanBackups,
+		}
+
+	case *lnrpc.RestoreChanBackupRequest_MultiChanBackup:
+		req.Backup = &lnrpc.ChannelRecoveryKeysRequest_MultiChanBackup{
+			MultiChanBackup: backup.MultiChanBackup,
+		}
+	}
+
+	startHeight := ctx.Uint64("start_height")
+	for i := uint64(0); i < ctx.Uint64("num_heights"); i++ {
+		req.CommitHeights = append(req.CommitHeights, startHeight+i)
+	}
+
+	resp, err := client.DeriveChannelRecoveryKeys(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+	return nil
+}
+
+var sweepRecoveredChannelCommand = cli.Command{
+	Name:     "sweeprecoveredchannel",
+	Category: "Channels",
+	Usage: "Sweep our output of a force closed channel using its " +
+		"static channel backup",
+	ArgsUsage: "--single_backup= --commit_tx= --addr= " +
+		"[--conf_target=] [--atoms_per_byte=] [--publish]",
+	Description: `
+	Sweeps our output of the commitment transaction that force closed a
+	channel to the given address, using only the channel's Static Channel
+	Backup (SCB). Our delayed output of our own commitment can be swept
+	once its CSV delay has passed. Our output of the remote party's
+	commitment can only be swept for channels that don't tweak the remote
+	key.
+
+	The signed sweep transaction is returned, and only published if the
+	--publish flag is set.
+
+	This command requires a macaroon with the emergency:write permission,
+	which can be created with the 'bakemacaroon' command.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "single_backup",
+			Usage: "A hex encoded single channel backup obtained " +
+				"from exportchanbackup",
+		},
+		cli.StringFlag{
+			Name: "commit_tx",
+			Usage: "The hex encoded commitment transaction that " +
+				"closed the channel",
+		},
+		cli.StringFlag{
+			Name:  "addr",
+			Usage: "The address to sweep the funds to",
+		},
+		cli.Int64Flag{
+			Name: "conf_target",
+			Usage: "(optional) the number of blocks that the " +
+				"sweep transaction should confirm in, will be " +
+				"used for fee estimation",
+		},
+		cli.Int64Flag{
+			Name: "atoms_per_byte",
+			Usage: "(optional) a manual fee expressed in " +
+				"atom/byte that should be used when crafting " +
+				"the sweep transaction",
+		},
+		cli.BoolFlag{
+			Name:  "publish",
+			Usage: "publish the sweep transaction",
+		},
+	},
+	Action: actionDecorator(sweepRecoveredChannel),
+}
+
+func sweepRecoveredChannel(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments provided
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		cli.ShowCommandHelp(ctx, "sweeprecoveredchannel")
+		return nil
+	}
+
+	packedBackup, err := hex.DecodeString(ctx.String("single_backup"))
+	if err != nil {
+		return fmt.Errorf("unable to decode single packed backup: %v",
+			err)
+	}
+	commitTx, err := hex.DecodeString(ctx.String("commit_tx"))
+	if err != nil {
+		return fmt.Errorf("unable to decode commitment "+
+			"transaction: %v", err)
+	}
+
+	resp, err := client.SweepRecoveredChannel(
+		ctxb, &lnrpc.SweepRecoveredChannelRequest{
+			ChanBackup:   packedBackup,
+			CommitTx:     commitTx,
+			SweepAddr:    ctx.String("addr"),
+			TargetConf:   int32(ctx.Int64("conf_target")),
+			AtomsPerByte: ctx.Int64("atoms_per_byte"),
+			Publish:      ctx.Bool("publish"),
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printJSON(struct {
+		SweepTx         string `json:"sweep_tx"`
+		SweepTxid       string `json:"sweep_txid"`
+		AmountAtoms     int64  `json:"amount_atoms"`
+		LocalCommitment bool   `json:"local_commitment"`
+		CsvDelay        uint32 `json:"csv_delay"`
+		Published       bool   `json:"published"`
+	}{
+		SweepTx:         hex.EncodeToString(resp.SweepTx),
+		SweepTxid:       resp.SweepTxid,
+		AmountAtoms:     resp.AmountAtoms,
+		LocalCommitment: resp.LocalCommitment,
+		CsvDelay:        resp.CsvDelay,
+		Published:       resp.Published,
+	})
+	return nil
+}
+
+var signChannelRescueCommand = cli.Command{
+	Name:     "signchannelrescue",
+	Category: "Channels",
+	Usage: "Sign a transaction spending the funding output of a channel " +
+		"using its static channel backup",
+	ArgsUsage: "--single_backup= --rescue_tx= --commit_tx= " +
+		"[--remote_sig=]",
+	Description: `
+	Signs a transaction spending the funding output of a channel with our
+	multisig key, using only the channel's Static Channel Backup (SCB).
+	This allows the funds of a channel to be rescued with the cooperation
+	of the remote party, when neither party can use its commitment.
+
+	The latest commitment transaction of the channel, signed by both
+	parties, must be passed with --commit_tx. The rescue transaction must
+	pay us at least our balance within that commitment, and can't be
+	signed while the channel is still open or pending.
+
+	Both parties agree on the rescue transaction and exchange their
+	signatures. Once the signature of the remote party is passed with
+	--remote_sig, the fully signed transaction is returned so it can be
+	published.
+
+	This command requires a macaroon with the emergency:write permission,
+	which can be created with the 'bakemacaroon' command.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name: "single_backup",
+			Usage: "A hex encoded single channel backup obtained " +
+				"from exportchanbackup",
+		},
+		cli.StringFlag{
+			Name: "rescue_tx",
+			Usage: "The hex encoded transaction spending the " +
+				"funding output of the channel",
+		},
+		cli.StringFlag{
+			Name: "commit_tx",
+			Usage: "The hex encoded latest commitment transaction " +
+				"of the channel, signed by both parties",
+		},
+		cli.StringFlag{
+			Name: "remote_sig",
+			Usage: "(optional) the hex encoded DER signature of " +
+				"the remote party for the funding output",
+		},
+	},
+	Action: actionDecorator(signChannelRescue),
+}
+
+func signChannelRescue(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getClient(ctx)
+	defer cleanUp()
+
+	// Show command help if no arguments provided
+	if ctx.NArg() == 0 && ctx.NumFlags() == 0 {
+		cli.ShowCommandHelp(ctx, "signchannelrescue")
+		return nil
+	}
+
+	packedBackup, err := hex.DecodeString(ctx.String("single_backup"))
+	if err != nil {
+		return fmt.Errorf("unable to decode single packed backup: %v",
+			err)
+	}
+	rescueTx, err := hex.DecodeString(ctx.String("rescue_tx"))
+	if err != nil {
+		return fmt.Errorf("unable to decode rescue transaction: %v",
+			err)
+	}
+	commitTx, err := hex.DecodeString(ctx.String("commit_tx"))
+	if err != nil {
+		return fmt.Errorf("unable to decode commitment transaction: "+
+			"%v", err)
+	}
+	remoteSig, err := hex.DecodeString(ctx.String("remote_sig"))
+	if err != nil {
+		return fmt.Errorf("unable to decode remote signature: %v",
+			err)
+	}
+
+	resp, err := client.SignChannelRescue(
+		ctxb, &lnrpc.SignChannelRescueRequest{
+			ChanBackup: packedBackup,
+			RescueTx:   rescueTx,
+			RemoteSig:  remoteSig,
+			CommitTx:   commitTx,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printJSON(struct {
+		LocalSig         string `json:"local_sig"`
+		LocalMultiSigKey string `json:"local_multi_sig_key"`
+		FundingScript    string `json:"funding_script"`
+		SignedTx         string `json:"signed_tx"`
+	}{
+		LocalSig:         hex.EncodeToString(resp.LocalSig),
+		LocalMultiSigKey: hex.EncodeToString(resp.LocalMultiSigKey),
+		FundingScript:    hex.EncodeToString(resp.FundingScript),
+		SignedTx:         hex.EncodeToString(resp.SignedTx),
+	})
+	return nil
+}
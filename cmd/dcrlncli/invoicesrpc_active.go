@@ -19,6 +19,9 @@ func invoicesCommands() []cli.Command {
 		cancelInvoiceCommand,
 		addHoldInvoiceCommand,
 		settleInvoiceCommand,
+		listPendingApprovalsCommand,
+		approveSettlementCommand,
+		rejectSettlementCommand,
 	}
 }
 
@@ -189,6 +192,19 @@ var addHoldInvoiceCommand = cli.Command{
 				"private channels in order to assist the " +
 				"payer in reaching you",
 		},
+		cli.BoolFlag{
+			Name: "no_route_hints",
+			Usage: "Omit routing hints from the invoice even if " +
+				"the node's configured defaults would include " +
+				"them, implies --private=false",
+		},
+		cli.BoolFlag{
+			Name: "private_hints_only",
+			Usage: "Only accept payments through the private " +
+				"channels of the routing hints of the " +
+				"invoice, so that it can't be linked to the " +
+				"node's public channels, implies --private",
+		},
 	},
 	Action: actionDecorator(addHoldInvoice),
 }
@@ -234,6 +250,11 @@ func addHoldInvoice(ctx *cli.Context) error {
 		return fmt.Errorf("unable to parse description_hash: %v", err)
 	}
 
+	private, noRouteHints, err := parseInvoiceRouteHints(ctx)
+	if err != nil {
+		return err
+	}
+
 	invoice := &invoicesrpc.AddHoldInvoiceRequest{
 		Memo:            ctx.String("memo"),
 		Hash:            hash,
@@ -242,7 +263,10 @@ func addHoldInvoice(ctx *cli.Context) error {
 		DescriptionHash: descHash,
 		FallbackAddr:    ctx.String("fallback_addr"),
 		Expiry:          ctx.Int64("expiry"),
-		Private:         ctx.Bool("private"),
+		Private:         private,
+		NoRouteHints:    noRouteHints,
+
+		PrivateRouteHintsOnly: ctx.Bool("private_hints_only"),
 	}
 
 	resp, err := client.AddHoldInvoice(context.Background(), invoice)
@@ -258,3 +282,136 @@ func addHoldInvoice(ctx *cli.Context) error {
 
 	return nil
 }
+
+var listPendingApprovalsCommand = cli.Command{
+	Name:     "listpendingapprovals",
+	Category: "Invoices",
+	Usage:    "List the invoices whose settlement is pending approval.",
+	Description: `
+	List the invoices whose payment reached the settle approval threshold
+	and that are held until their settlement is approved with
+	approvesettlement or rejected with rejectsettlement, oldest first.`,
+	Action: actionDecorator(listPendingApprovals),
+}
+
+func listPendingApprovals(ctx *cli.Context) error {
+	client, cleanUp := getInvoicesClient(ctx)
+	defer cleanUp()
+
+	resp, err := client.ListPendingApprovals(
+		context.Background(), &invoicesrpc.ListPendingApprovalsRequest{},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+// settlementApprovalFlags are the flags of the commands approving or
+// rejecting the settlement of an invoice.
+var settlementApprovalFlags = []cli.Flag{
+	cli.StringFlag{
+		Name: "paymenthash",
+		Usage: "The hex-encoded payment hash (32 byte) of the invoice " +
+			"pending settle approval.",
+	},
+}
+
+// parseApprovalPaymentHash parses the payment hash of the invoice whose
+// settlement is approved or rejected.
+func parseApprovalPaymentHash(ctx *cli.Context) ([]byte, error) {
+	var (
+		paymentHash []byte
+		err         error
+	)
+
+	args := ctx.Args()
+
+	switch {
+	case ctx.IsSet("paymenthash"):
+		paymentHash, err = hex.DecodeString(ctx.String("paymenthash"))
+	case args.Present():
+		paymentHash, err = hex.DecodeString(args.First())
+	default:
+		return nil, fmt.Errorf("payment hash argument missing")
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse payment hash: %v", err)
+	}
+
+	return paymentHash, nil
+}
+
+var approveSettlementCommand = cli.Command{
+	Name:     "approvesettlement",
+	Category: "Invoices",
+	Usage:    "Settle an invoice whose settlement is pending approval.",
+	Description: `
+	Settle an invoice whose payment reached the settle approval threshold,
+	for example once the fulfillment of the order was confirmed.`,
+	ArgsUsage: "paymenthash",
+	Flags:     settlementApprovalFlags,
+	Action:    actionDecorator(approveSettlement),
+}
+
+func approveSettlement(ctx *cli.Context) error {
+	paymentHash, err := parseApprovalPaymentHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, cleanUp := getInvoicesClient(ctx)
+	defer cleanUp()
+
+	req := &invoicesrpc.ApproveSettlementRequest{
+		PaymentHash: paymentHash,
+	}
+
+	resp, err := client.ApproveSettlement(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var rejectSettlementCommand = cli.Command{
+	Name:     "rejectsettlement",
+	Category: "Invoices",
+	Usage:    "Cancel an invoice whose settlement is pending approval.",
+	Description: `
+	Cancel an invoice whose payment reached the settle approval threshold,
+	failing back its htlcs.`,
+	ArgsUsage: "paymenthash",
+	Flags:     settlementApprovalFlags,
+	Action:    actionDecorator(rejectSettlement),
+}
+
+func rejectSettlement(ctx *cli.Context) error {
+	paymentHash, err := parseApprovalPaymentHash(ctx)
+	if err != nil {
+		return err
+	}
+
+	client, cleanUp := getInvoicesClient(ctx)
+	defer cleanUp()
+
+	req := &invoicesrpc.RejectSettlementRequest{
+		PaymentHash: paymentHash,
+	}
+
+	resp, err := client.RejectSettlement(context.Background(), req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
@@ -23,6 +23,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/encoding/gzip"
 )
 
 const (
@@ -82,6 +83,9 @@ func getClientConn(ctx *cli.Context, skipMacaroons bool) *grpc.ClientConn {
 		fatal(err)
 	}
 
+	// Unix sockets and named pipes are used without TLS.
+	creds = lncfg.LocalConnCredentials(creds)
+
 	// Create a dial options array.
 	opts := []grpc.DialOption{
 		grpc.WithTransportCredentials(creds),
@@ -139,6 +143,13 @@ func getClientConn(ctx *cli.Context, skipMacaroons bool) *grpc.ClientConn {
 	opts = append(opts, grpc.WithContextDialer(genericDialer))
 	opts = append(opts, grpc.WithDefaultCallOptions(maxMsgRecvSize))
 
+	// Request compressed messages if the server supports them.
+	if ctx.GlobalBool("compress") {
+		opts = append(opts, grpc.WithDefaultCallOptions(
+			grpc.UseCompressor(gzip.Name),
+		))
+	}
+
 	conn, err := grpc.Dial(ctx.GlobalString("rpcserver"), opts...)
 	if err != nil {
 		fatal(fmt.Errorf("unable to connect to RPC server: %v", err))
@@ -173,9 +184,17 @@ func extractPathArgs(ctx *cli.Context) (string, string, error) {
 		}
 	}
 
+	// A custom network is identified by the name set in its network
+	// parameters file.
+	if customNet := ctx.GlobalString("network"); customNet != "" {
+		network = customNet
+		numNets++
+	}
+
 	if numNets > 1 {
-		str := "extractPathArgs: The testnet, regtest, and simnet params" +
-			"can't be used together -- choose one of the three"
+		str := "extractPathArgs: The testnet, regtest, simnet and " +
+			"network params can't be used together -- choose one " +
+			"of the four"
 		err := fmt.Errorf(str)
 
 		return "", "", err
@@ -254,6 +273,12 @@ func main() {
 			Name:  "regtest",
 			Usage: "Use the regression test network",
 		},
+		cli.StringFlag{
+			Name: "network",
+			Usage: "the name of the custom network dcrlnd is " +
+				"running on, as set in its network parameters " +
+				"file",
+		},
 		cli.BoolFlag{
 			Name:  "no-macaroons",
 			Usage: "disable macaroon authentication",
@@ -271,31 +296,50 @@ func main() {
 			Name:  "macaroonip",
 			Usage: "if set, lock macaroon to specific IP address",
 		},
+		cli.BoolFlag{
+			Name: "compress",
+			Usage: "gzip compress the messages exchanged with the " +
+				"server, which must have compression.grpc set",
+		},
 	}
 	app.Commands = []cli.Command{
 		createCommand,
 		unlockCommand,
 		changePasswordCommand,
+		lockWalletCommand,
+		migrationStatusCommand,
 		newAddressCommand,
 		estimateFeeCommand,
 		sendManyCommand,
+		subscribeSendConfCommand,
+		subscribeChainEventsCommand,
 		sendCoinsCommand,
 		listUnspentCommand,
 		connectCommand,
 		disconnectCommand,
+		rotateCircuitCommand,
 		openChannelCommand,
+		batchOpenChannelCommand,
+		estimateChannelOpenCommand,
 		closeChannelCommand,
 		closeAllChannelsCommand,
 		abandonChannelCommand,
+		exportChanCommitmentCommand,
 		listPeersCommand,
+		peerFeaturesCommand,
 		walletBalanceCommand,
 		channelBalanceCommand,
+		balancesCommand,
 		getInfoCommand,
 		getRecoveryInfoCommand,
+		dbInfoCommand,
 		pendingChannelsCommand,
+		listReservationsCommand,
+		cancelReservationCommand,
 		sendPaymentCommand,
 		payInvoiceCommand,
 		sendToRouteCommand,
+		rebalanceCommand,
 		addInvoiceCommand,
 		lookupInvoiceCommand,
 		listInvoicesCommand,
@@ -305,27 +349,47 @@ func main() {
 		describeGraphCommand,
 		getNodeMetricsCommand,
 		getChanInfoCommand,
+		getChanPolicyHistoryCommand,
+		resolveChanIDCommand,
 		getNodeInfoCommand,
 		queryRoutesCommand,
 		getNetworkInfoCommand,
 		debugLevelCommand,
+		getCrashReportCommand,
+		killSwitchCommand,
 		decodePayReqCommand,
 		listChainTxnsCommand,
 		stopCommand,
+		enterMaintenanceCommand,
+		exitMaintenanceCommand,
 		signMessageCommand,
 		verifyMessageCommand,
+		exportReceiptCommand,
+		verifyReceiptCommand,
+		attestNodeCommand,
+		verifyAttestationCommand,
 		feeReportCommand,
 		updateChannelPolicyCommand,
+		setFeeScheduleCommand,
+		listFeeSchedulesCommand,
+		updateExpiryGraceCommand,
+		updatePendingLimitsCommand,
 		forwardingHistoryCommand,
 		exportChanBackupCommand,
 		verifyChanBackupCommand,
 		restoreChanBackupCommand,
+		exportRouterStateCommand,
+		importRouterStateCommand,
+		chanRecoveryKeysCommand,
+		sweepRecoveredChannelCommand,
+		signChannelRescueCommand,
 		bakeMacaroonCommand,
 		listMacaroonIDsCommand,
 		deleteMacaroonIDCommand,
 		listPermissionsCommand,
 		printMacaroonCommand,
 		trackPaymentCommand,
+		watchPaymentCommand,
 		versionCommand,
 	}
 
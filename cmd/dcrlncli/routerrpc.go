@@ -7,7 +7,12 @@ func routerCommands() []cli.Command {
 	return []cli.Command{
 		queryMissionControlCommand,
 		queryProbCommand,
+		estimateRouteFeeCommand,
+		getCfgCommand,
+		setCfgCommand,
 		resetMissionControlCommand,
 		buildRouteCommand,
+		queryHtlcEventsCommand,
+		subscribeHtlcEventsCommand,
 	}
 }
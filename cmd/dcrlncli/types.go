@@ -45,12 +45,14 @@ func NewProtoOutPoint(op string) (*lnrpc.OutPoint, error) {
 // Utxo displays information about an unspent output, including its address,
 // amount, pkscript, and confirmations.
 type Utxo struct {
-	Type          lnrpc.AddressType `json:"address_type"`
-	Address       string            `json:"address"`
-	AmountAtoms   int64             `json:"amount_atoms"`
-	PkScript      string            `json:"pk_script"`
-	OutPoint      OutPoint          `json:"outpoint"`
-	Confirmations int64             `json:"confirmations"`
+	Type            lnrpc.AddressType `json:"address_type"`
+	Address         string            `json:"address"`
+	AmountAtoms     int64             `json:"amount_atoms"`
+	PkScript        string            `json:"pk_script"`
+	OutPoint        OutPoint          `json:"outpoint"`
+	Confirmations   int64             `json:"confirmations"`
+	Locked          bool              `json:"locked"`
+	LeaseExpiration int64             `json:"lease_expiration,omitempty"`
 }
 
 // NewUtxoFromProto creates a display Utxo from the Utxo proto. This filters out
@@ -58,11 +60,13 @@ type Utxo struct {
 // printed in base64.
 func NewUtxoFromProto(utxo *lnrpc.Utxo) *Utxo {
 	return &Utxo{
-		Type:          utxo.AddressType,
-		Address:       utxo.Address,
-		AmountAtoms:   utxo.AmountAtoms,
-		PkScript:      utxo.PkScript,
-		OutPoint:      NewOutPointFromProto(utxo.Outpoint),
-		Confirmations: utxo.Confirmations,
+		Type:            utxo.AddressType,
+		Address:         utxo.Address,
+		AmountAtoms:     utxo.AmountAtoms,
+		PkScript:        utxo.PkScript,
+		OutPoint:        NewOutPointFromProto(utxo.Outpoint),
+		Confirmations:   utxo.Confirmations,
+		Locked:          utxo.Locked,
+		LeaseExpiration: utxo.LeaseExpiration,
 	}
 }
@@ -10,6 +10,7 @@ import (
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/signrpc"
 	"github.com/decred/dcrlnd/lnrpc/walletrpc"
 	"github.com/urfave/cli"
 )
@@ -29,6 +30,9 @@ func walletCommands() []cli.Command {
 				bumpCloseFeeCommand,
 				listSweepsCommand,
 				labelTxCommand,
+				exportXPubsCommand,
+				signMessageWithAddrCommand,
+				verifyMessageWithAddrCommand,
 			},
 		},
 	}
@@ -125,6 +129,10 @@ var bumpFeeCommand = cli.Command{
 	Normally it does not make sense to lose money on sweeping, unless a
 	parent transaction needs to get confirmed and there is only a small
 	output available to attach the child transaction to.
+
+	The deadline_height flag sets the height by which the input should be
+	swept. Unconfirmed sweeps of the input are replaced by sweeps with a
+	higher fee rate once the deadline approaches.
 	`,
 	Flags: []cli.Flag{
 		cli.Uint64Flag{
@@ -141,6 +149,12 @@ var bumpFeeCommand = cli.Command{
 			Name:  "force",
 			Usage: "sweep even if the yield is negative",
 		},
+		cli.Int64Flag{
+			Name: "deadline_height",
+			Usage: "the height by which the output should be " +
+				"swept, bumping the fee of unconfirmed sweeps " +
+				"as it approaches",
+		},
 	},
 	Action: actionDecorator(bumpFee),
 }
@@ -162,10 +176,11 @@ func bumpFee(ctx *cli.Context) error {
 	defer cleanUp()
 
 	resp, err := client.BumpFee(context.Background(), &walletrpc.BumpFeeRequest{
-		Outpoint:     protoOutPoint,
-		TargetConf:   uint32(ctx.Uint64("conf_target")),
-		AtomsPerByte: uint32(ctx.Uint64("atoms_per_byte")),
-		Force:        ctx.Bool("force"),
+		Outpoint:       protoOutPoint,
+		TargetConf:     uint32(ctx.Uint64("conf_target")),
+		AtomsPerByte:   uint32(ctx.Uint64("atoms_per_byte")),
+		Force:          ctx.Bool("force"),
+		DeadlineHeight: int32(ctx.Int64("deadline_height")),
 	})
 	if err != nil {
 		return err
@@ -396,3 +411,207 @@ func labelTransaction(ctx *cli.Context) error {
 
 	return nil
 }
+
+var exportXPubsCommand = cli.Command{
+	Name:  "exportxpubs",
+	Usage: "Export the extended public keys of all key families.",
+	Description: `
+	Export the extended public key used as the root of public derivation
+	for each of the key families (accounts) of the node. These keys allow
+	a watch-only party to reconstruct every public key derived by the
+	node, for example to audit its key usage.
+	`,
+	Action: actionDecorator(exportXPubs),
+}
+
+func exportXPubs(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getWalletClient(ctx)
+	defer cleanUp()
+
+	req := &walletrpc.ExportAccountXPubsRequest{}
+	resp, err := client.ExportAccountXPubs(ctxb, req)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var signMessageWithAddrCommand = cli.Command{
+	Name:      "signmessage",
+	Usage:     "Sign a message with the private key of a wallet address.",
+	ArgsUsage: "[address] msg",
+	Description: `
+	Sign a message with the private key of an address of the wallet,
+	proving the ownership of the address. The message is signed in the
+	standard Decred signed message format, so the signature can also be
+	verified by other Decred software.
+
+	Instead of an address, the wallet key to sign the message with can be
+	given by its key locator with the --key_family and --key_index flags.
+	The address of the key is then returned along with the signature.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "addr",
+			Usage: "the wallet address to sign the message with",
+		},
+		cli.Int64Flag{
+			Name: "key_family",
+			Usage: "the family of the wallet key to sign the " +
+				"message with, instead of an address",
+		},
+		cli.Int64Flag{
+			Name: "key_index",
+			Usage: "the index of the wallet key to sign the " +
+				"message with, instead of an address",
+		},
+		cli.StringFlag{
+			Name:  "msg",
+			Usage: "the message to sign",
+		},
+	},
+	Action: actionDecorator(signMessageWithAddr),
+}
+
+func signMessageWithAddr(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getWalletClient(ctx)
+	defer cleanUp()
+
+	var (
+		addr   string
+		keyLoc *signrpc.KeyLocator
+		msg    []byte
+	)
+
+	args := ctx.Args()
+	switch {
+	case ctx.IsSet("key_family") || ctx.IsSet("key_index"):
+		if ctx.IsSet("addr") {
+			return fmt.Errorf("addr can't be set along with a key " +
+				"locator")
+		}
+		keyLoc = &signrpc.KeyLocator{
+			KeyFamily: int32(ctx.Int64("key_family")),
+			KeyIndex:  int32(ctx.Int64("key_index")),
+		}
+	case ctx.IsSet("addr"):
+		addr = ctx.String("addr")
+	case args.Present():
+		addr = args.First()
+		args = args.Tail()
+	default:
+		return fmt.Errorf("address argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("msg"):
+		msg = []byte(ctx.String("msg"))
+	case args.Present():
+		msg = []byte(args.First())
+	default:
+		return fmt.Errorf("msg argument missing")
+	}
+
+	resp, err := client.SignMessageWithAddr(
+		ctxb, &walletrpc.SignMessageWithAddrRequest{
+			Msg:    msg,
+			Addr:   addr,
+			KeyLoc: keyLoc,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
+
+var verifyMessageWithAddrCommand = cli.Command{
+	Name:      "verifymessage",
+	Usage:     "Verify a message signed with the private key of an address.",
+	ArgsUsage: "address msg signature",
+	Description: `
+	Verify a signature over a message created with the private key of a
+	Decred address in the standard Decred signed message format. The
+	address doesn't need to belong to the wallet.
+	`,
+	Flags: []cli.Flag{
+		cli.StringFlag{
+			Name:  "addr",
+			Usage: "the address the signature has to be valid for",
+		},
+		cli.StringFlag{
+			Name:  "msg",
+			Usage: "the message over which the signature is verified",
+		},
+		cli.StringFlag{
+			Name:  "sig",
+			Usage: "the base64 encoded signature",
+		},
+	},
+	Action: actionDecorator(verifyMessageWithAddr),
+}
+
+func verifyMessageWithAddr(ctx *cli.Context) error {
+	ctxb := context.Background()
+	client, cleanUp := getWalletClient(ctx)
+	defer cleanUp()
+
+	var (
+		addr string
+		msg  []byte
+		sig  string
+	)
+
+	args := ctx.Args()
+	switch {
+	case ctx.IsSet("addr"):
+		addr = ctx.String("addr")
+	case args.Present():
+		addr = args.First()
+		args = args.Tail()
+	default:
+		return fmt.Errorf("address argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("msg"):
+		msg = []byte(ctx.String("msg"))
+	case args.Present():
+		msg = []byte(args.First())
+		args = args.Tail()
+	default:
+		return fmt.Errorf("msg argument missing")
+	}
+
+	switch {
+	case ctx.IsSet("sig"):
+		sig = ctx.String("sig")
+	case args.Present():
+		sig = args.First()
+	default:
+		return fmt.Errorf("signature argument missing")
+	}
+
+	resp, err := client.VerifyMessageWithAddr(
+		ctxb, &walletrpc.VerifyMessageWithAddrRequest{
+			Msg:       msg,
+			Signature: sig,
+			Addr:      addr,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	printRespJSON(resp)
+
+	return nil
+}
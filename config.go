@@ -26,6 +26,7 @@ import (
 	"github.com/decred/dcrlnd/discovery"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/htlcswitch/hodl"
+	"github.com/decred/dcrlnd/htlcswitch/holdfee"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/lncfg"
 	"github.com/decred/dcrlnd/lnrpc/routerrpc"
@@ -53,6 +54,7 @@ const (
 	defaultRESTPort           = 8080
 	defaultPeerPort           = 9735
 	defaultRPCHost            = "localhost"
+	defaultRPCSocketPerms     = 0660
 
 	defaultNoSeedBackup                  = false
 	defaultPaymentsExpirationGracePeriod = time.Duration(0)
@@ -81,6 +83,12 @@ const (
 	// out and return false if it hasn't yet received a response.
 	defaultAcceptorTimeout = 15 * time.Second
 
+	// maxAcceptorTimeout is the maximum time we wait for the response of
+	// an RPCAcceptor, either as configured or as requested by a client.
+	// Inbound channel requests are handled one at a time, so a slow
+	// acceptor holds up all other funding flows.
+	maxAcceptorTimeout = 5 * time.Minute
+
 	defaultAlias = ""
 	defaultColor = "#3399FF"
 
@@ -167,7 +175,7 @@ type Config struct {
 	LogDir          string        `long:"logdir" description:"Directory to log output."`
 	MaxLogFiles     int           `long:"maxlogfiles" description:"Maximum logfiles to keep (0 for no rotation)"`
 	MaxLogFileSize  int           `long:"maxlogfilesize" description:"Maximum logfile size in MB"`
-	AcceptorTimeout time.Duration `long:"acceptortimeout" description:"Time after which an RPCAcceptor will time out and return false if it hasn't yet received a response"`
+	AcceptorTimeout time.Duration `long:"acceptortimeout" description:"Time after which an RPCAcceptor will time out and return false if it hasn't yet received a response. Clients can override it for their stream. Inbound channel requests are handled one at a time, so the timeout can be at most 5m."`
 
 	// IPC options
 	PipeTx            *uint `long:"pipetx" description:"File descriptor or handle of write end pipe to enable child -> parent process communication"`
@@ -183,9 +191,11 @@ type Config struct {
 	RawListeners     []string `long:"listen" description:"Add an interface/port to listen for peer connections"`
 	RawExternalIPs   []string `long:"externalip" description:"Add an ip:port to the list of local addresses we claim to listen on to peers. If a port is not specified, the default (9735) will be used regardless of other parameters"`
 	ExternalHosts    []string `long:"externalhosts" description:"A set of hosts that should be periodically resolved to announce IPs for"`
+	RPCSocketPerms   uint32   `long:"rpcsocketperms" base:"8" description:"The file permissions, in octal, of the unix sockets the RPC server listens on"`
 	RPCListeners     []net.Addr
 	RESTListeners    []net.Addr
 	RestCORS         []string `long:"restcors" description:"Add an ip:port/hostname to allow cross origin access from. To allow all origins, set as \"*\"."`
+	RestTrustedProxy []string `long:"resttrustedproxy" description:"Add an IP address or CIDR range of a reverse proxy in front of the REST API. The X-Forwarded-For header is only trusted for requests that are received from these addresses, otherwise it is discarded."`
 	Listeners        []net.Addr
 	ExternalIPs      []net.Addr
 	DisableListen    bool          `long:"nolisten" description:"Disable listening for incoming peer connections"`
@@ -193,6 +203,8 @@ type Config struct {
 	NAT              bool          `long:"nat" description:"Toggle NAT traversal support (using either UPnP or NAT-PMP) to automatically advertise your external IP address to the network -- NOTE this does not support devices behind multiple NATs"`
 	MinBackoff       time.Duration `long:"minbackoff" description:"Shortest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
 	MaxBackoff       time.Duration `long:"maxbackoff" description:"Longest backoff when reconnecting to persistent peers. Valid time units are {s, m, h}."`
+	ShutdownTimeout  time.Duration `long:"shutdowntimeout" description:"The maximum time each subsystem is given to stop during shutdown, after which the shutdown continues without it so the daemon can exit. Set to 0 to wait indefinitely. Valid time units are {s, m, h}."`
+	ShutdownGrace    time.Duration `long:"shutdowngraceperiod" description:"The maximum time the whole shutdown is given to complete, after which the process is forcefully terminated. Set to 0 to disable. Valid time units are {s, m, h}."`
 
 	DebugLevel string `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 
@@ -200,16 +212,19 @@ type Config struct {
 
 	Profile string `long:"profile" description:"Enable HTTP profiling on given port -- NOTE port must be between 1024 and 65535"`
 
-	UnsafeDisconnect   bool   `long:"unsafe-disconnect" description:"DEPRECATED: Allows the rpcserver to intentionally disconnect from peers with open channels. THIS FLAG WILL BE REMOVED IN THE FUTURE"`
-	UnsafeReplay       bool   `long:"unsafe-replay" description:"Causes a link to replay the adds on its commitment txn after starting up, this enables testing of the sphinx replay logic."`
-	MaxPendingChannels int    `long:"maxpendingchannels" description:"The maximum number of incoming pending channels permitted per peer."`
-	BackupFilePath     string `long:"backupfilepath" description:"The target location of the channel backup file"`
+	UnsafeDisconnect         bool   `long:"unsafe-disconnect" description:"DEPRECATED: Allows the rpcserver to intentionally disconnect from peers with open channels. THIS FLAG WILL BE REMOVED IN THE FUTURE"`
+	UnsafeReplay             bool   `long:"unsafe-replay" description:"Causes a link to replay the adds on its commitment txn after starting up, this enables testing of the sphinx replay logic."`
+	MaxPendingChannels       int    `long:"maxpendingchannels" description:"The maximum number of incoming pending channels permitted per peer."`
+	MaxGlobalPendingChannels int    `long:"maxglobalpendingchannels" description:"The maximum number of incoming pending channels permitted across all peers. If 0, there's no global limit."`
+	BackupFilePath           string `long:"backupfilepath" description:"The target location of the channel backup file"`
+	BackupExportDir          string `long:"backupexportdir" description:"The directory ExportAllChannelBackups may write multi-channel backup files to. If unset, backups can't be exported to files."`
 
 	ChainDir            string           `long:"chaindir" description:"The directory to store the chain's data within."`
 	Node                string           `long:"node" description:"The blockchain interface to use." choice:"dcrd" choice:"dcrw"`
 	TestNet3            bool             `long:"testnet" description:"Use the test network"`
 	SimNet              bool             `long:"simnet" description:"Use the simulation test network"`
 	RegTest             bool             `long:"regtest" description:"Use the regression test network"`
+	NetParamsFile       string           `long:"netparamsfile" description:"Use the custom network described by the given network parameters file, such as a private network"`
 	DefaultNumChanConfs int              `long:"defaultchanconfs" description:"The default number of confirmations a channel must have before it's considered open. If this is not set, we will scale the value according to the channel size."`
 	DefaultRemoteDelay  int              `long:"defaultremotedelay" description:"The default number of blocks we will require our channel counterparty to wait before accessing its funds in case of unilateral close. If this is not set, we will scale the value according to the channel size."`
 	MinHTLCIn           lnwire.MilliAtom `long:"minhtlc" description:"The smallest HTLC we are willing to accept on our channels, in MilliAtoms"`
@@ -229,6 +244,8 @@ type Config struct {
 
 	Hodl *hodl.Config `group:"hodl" namespace:"hodl"`
 
+	HoldFee *holdfee.Config `group:"holdfee" namespace:"holdfee"`
+
 	NoNetBootstrap bool `long:"nobootstrap" description:"If true, then automatic network bootstrapping will not be attempted."`
 
 	NoSeedBackup bool `long:"noseedbackup" description:"If true, NO SEED WILL BE EXPOSED -- EVER, AND THE WALLET WILL BE ENCRYPTED USING THE DEFAULT PASSPHRASE. THIS FLAG IS ONLY FOR TESTING AND SHOULD NEVER BE USED ON MAINNET."`
@@ -260,6 +277,10 @@ type Config struct {
 
 	MaxOutgoingCltvExpiry uint32 `long:"max-cltv-expiry" description:"The maximum number of blocks funds could be locked up for when forwarding payments."`
 
+	FinalCltvRejectDelta uint32 `long:"final-cltv-reject-delta" description:"The number of blocks before the expiry of an incoming htlc paying to us at which it is canceled back instead of being settled. Must be greater than the incoming broadcast delta and lower than the time lock delta."`
+
+	OutgoingCltvRejectDelta uint32 `long:"outgoing-cltv-reject-delta" description:"The number of blocks before the expiry of an htlc at which it is no longer offered to the next peer. Must be greater than the outgoing broadcast delta."`
+
 	MaxChannelFeeAllocation float64 `long:"max-channel-fee-allocation" description:"The maximum percentage of total funds that can be allocated to a channel's commitment fee. This only applies for the initiator of the channel. Valid values are within [0.1, 1]."`
 
 	DryRunMigration bool `long:"dry-run-migration" description:"If true, lnd will abort committing a migration if it would otherwise have been successful. This leaves the database unmodified, and still compatible with the previously active version of lnd."`
@@ -288,10 +309,36 @@ type Config struct {
 
 	AllowCircularRoute bool `long:"allow-circular-route" description:"If true, our node will allow htlc forwards that arrive and depart on the same channel."`
 
+	AllowAbandonChannel bool `long:"allow-abandon-channel" description:"If true, the AbandonChannel RPC may be used outside of dev builds, as long as the request explicitly acknowledges the risk. Only intended to get rid of permanently unusable channels."`
+
 	HealthChecks *lncfg.HealthCheckConfig `group:"healthcheck" namespace:"healthcheck"`
 
+	FeeControl *lncfg.FeeControl `group:"feecontrol" namespace:"feecontrol"`
+
 	DB *lncfg.DB `group:"db" namespace:"db"`
 
+	Invoices *lncfg.Invoices `group:"invoices" namespace:"invoices"`
+
+	HtlcEvents *lncfg.HtlcEvents `group:"htlcevents" namespace:"htlcevents"`
+
+	HtlcReputation *lncfg.HtlcReputation `group:"htlcreputation" namespace:"htlcreputation"`
+
+	ChanConfs *lncfg.ChanConfs `group:"chanconfs" namespace:"chanconfs"`
+
+	SweptFunds *lncfg.SweptFunds `group:"sweptfunds" namespace:"sweptfunds"`
+
+	IdentitySigner *lncfg.IdentitySigner `group:"identitysigner" namespace:"identitysigner"`
+
+	Compression *lncfg.Compression `group:"compression" namespace:"compression"`
+
+	RPCLimits *lncfg.RPCLimits `group:"rpclimits" namespace:"rpclimits"`
+
+	WalletLock *lncfg.WalletLock `group:"walletlock" namespace:"walletlock"`
+
+	Dashboard *lncfg.Dashboard `group:"dashboard" namespace:"dashboard"`
+
+	Dev *lncfg.DevConfig `group:"dev" namespace:"dev"`
+
 	// LogWriter is the root logger that all of the daemon's subloggers are
 	// hooked up to.
 	LogWriter *build.RotatingLogWriter
@@ -304,6 +351,13 @@ type Config struct {
 	// network. This path will hold the files related to each different
 	// network.
 	networkDir string
+
+	// restTrustedProxies holds the parsed RestTrustedProxy ranges.
+	restTrustedProxies []*net.IPNet
+
+	// fixtures holds the fixtures loaded from the file configured in
+	// development builds, if any.
+	fixtures *nodeFixtures
 }
 
 // DefaultConfig returns all default values for the Config struct.
@@ -313,6 +367,7 @@ func DefaultConfig() Config {
 		ConfigFile:      DefaultConfigFile,
 		DataDir:         defaultDataDir,
 		DebugLevel:      defaultLogLevel,
+		RPCSocketPerms:  defaultRPCSocketPerms,
 		TLSCertPath:     defaultTLSCertPath,
 		TLSKeyPath:      defaultTLSKeyPath,
 		LogDir:          defaultLogDir,
@@ -336,6 +391,8 @@ func DefaultConfig() Config {
 		NoSeedBackup:       defaultNoSeedBackup,
 		MinBackoff:         defaultMinBackoff,
 		MaxBackoff:         defaultMaxBackoff,
+		ShutdownTimeout:    defaultShutdownTimeout,
+		ShutdownGrace:      defaultShutdownGracePeriod,
 		SubRPCServers: &subRPCServerConfigs{
 			SignRPC:   &signrpc.Config{},
 			RouterRPC: routerrpc.DefaultConfig(),
@@ -400,7 +457,51 @@ func DefaultConfig() Config {
 				},
 			},
 		},
+		FeeControl: &lncfg.FeeControl{
+			Interval:   lncfg.DefaultFeeControlInterval,
+			MinFeeRate: lncfg.DefaultFeeControlMinFeeRate,
+			MaxFeeRate: lncfg.DefaultFeeControlMaxFeeRate,
+			Exponent:   lncfg.DefaultFeeControlExponent,
+			FlowWeight: lncfg.DefaultFeeControlFlowWeight,
+			MinChange:  lncfg.DefaultFeeControlMinChange,
+		},
+		Invoices: &lncfg.Invoices{
+			Standard: &lncfg.InvoiceDefaults{},
+			Hold:     &lncfg.InvoiceDefaults{},
+			Keysend:  &lncfg.KeysendInvoiceDefaults{},
+		},
+		HtlcEvents: &lncfg.HtlcEvents{
+			MaxAge:   lncfg.DefaultHtlcEventsMaxAge,
+			MaxCount: lncfg.DefaultHtlcEventsMaxCount,
+		},
+		HtlcReputation: &lncfg.HtlcReputation{
+			BucketPercent: lncfg.DefaultHtlcReputationBucketPercent,
+			MinRevenue:    lncfg.DefaultHtlcReputationMinRevenue,
+			Window:        lncfg.DefaultHtlcReputationWindow,
+		},
+		IdentitySigner: &lncfg.IdentitySigner{
+			Timeout: lncfg.DefaultIdentitySignerTimeout,
+		},
+		SweptFunds: &lncfg.SweptFunds{
+			CloseConfs: lncfg.DefaultSweptFundsMinConfs,
+			SweepConfs: lncfg.DefaultSweptFundsMinConfs,
+		},
+		Compression: &lncfg.Compression{
+			Level: lncfg.DefaultCompressionLevel,
+		},
+		Dashboard: &lncfg.Dashboard{
+			CacheTTL:       lncfg.DefaultDashboardCacheTTL,
+			RecentForwards: lncfg.DefaultDashboardRecentForwards,
+		},
+		WalletLock: &lncfg.WalletLock{
+			DrainTimeout: lncfg.DefaultWalletLockDrainTimeout,
+		},
+		RPCLimits:               &lncfg.RPCLimits{},
+		ChanConfs:               &lncfg.ChanConfs{},
+		Dev:                     &lncfg.DevConfig{},
 		MaxOutgoingCltvExpiry:   htlcswitch.DefaultMaxOutgoingCltvExpiry,
+		FinalCltvRejectDelta:    lncfg.DefaultFinalCltvRejectDelta,
+		OutgoingCltvRejectDelta: lncfg.DefaultOutgoingCltvRejectDelta,
 		MaxChannelFeeAllocation: htlcswitch.DefaultMaxLinkFeeAllocation,
 		LogWriter:               build.NewRotatingLogWriter(),
 		DB:                      lncfg.DefaultDB(),
@@ -547,6 +648,8 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 	cfg.Dcrwallet.CertPath = CleanAndExpandPath(cfg.Dcrwallet.CertPath)
 	cfg.Dcrwallet.ClientKeyPath = CleanAndExpandPath(cfg.Dcrwallet.ClientKeyPath)
 	cfg.Dcrwallet.ClientCertPath = CleanAndExpandPath(cfg.Dcrwallet.ClientCertPath)
+	cfg.BackupExportDir = CleanAndExpandPath(cfg.BackupExportDir)
+	cfg.NetParamsFile = CleanAndExpandPath(cfg.NetParamsFile)
 
 	// Ensure that the user didn't attempt to specify negative values for
 	// any of the autopilot params.
@@ -632,6 +735,14 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		)
 	}
 
+	// Ensure the pending channel limits make sense.
+	if cfg.MaxPendingChannels < 0 || cfg.MaxGlobalPendingChannels < 0 {
+		return nil, fmt.Errorf("invalid pending channel limits: "+
+			"maxpendingchannels=%v and maxglobalpendingchannels=%v "+
+			"must not be negative", cfg.MaxPendingChannels,
+			cfg.MaxGlobalPendingChannels)
+	}
+
 	// Ensure a valid max channel fee allocation was set.
 	if cfg.MaxChannelFeeAllocation <= 0 || cfg.MaxChannelFeeAllocation > 1 {
 		return nil, fmt.Errorf("invalid max channel fee allocation: "+
@@ -679,6 +790,20 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		return nil, fmt.Errorf(str, funcName)
 	}
 
+	if cfg.Tor.CircuitRotation != 0 &&
+		cfg.Tor.CircuitRotation < lncfg.MinTorCircuitRotation {
+
+		return nil, fmt.Errorf("tor.circuitrotation must be at least %v",
+			lncfg.MinTorCircuitRotation)
+	}
+
+	if cfg.Tor.OnionKeyRotation != 0 &&
+		cfg.Tor.OnionKeyRotation < lncfg.MinTorOnionKeyRotation {
+
+		return nil, fmt.Errorf("tor.onionkeyrotation must be at least "+
+			"%v", lncfg.MinTorOnionKeyRotation)
+	}
+
 	switch {
 	case cfg.Tor.V2 && cfg.Tor.V3:
 		return nil, errors.New("either tor.v2 or tor.v3 can be set, " +
@@ -752,9 +877,16 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		numNets++
 		activeNetParams = decredSimNetParams
 	}
+	if cfg.NetParamsFile != "" {
+		numNets++
+		activeNetParams, err = loadCustomNetParams(cfg.NetParamsFile)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if numNets > 1 {
-		str := "%s: The testnet, regtest, and simnet params" +
-			"can't be used together -- choose one of the three"
+		str := "%s: The testnet, regtest, simnet and netparamsfile " +
+			"params can't be used together -- choose one of the four"
 		err := fmt.Errorf(str, funcName)
 		return nil, err
 	}
@@ -769,6 +901,14 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 			minTimeLockDelta)
 	}
 
+	err = validateCltvRejectDeltas(
+		cfg.FinalCltvRejectDelta, cfg.OutgoingCltvRejectDelta,
+		cfg.TimeLockDelta,
+	)
+	if err != nil {
+		return nil, err
+	}
+
 	switch cfg.Node {
 	case "dcrd":
 		err := parseRPCParams(
@@ -907,6 +1047,15 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		)
 	}
 
+	// Exported backups are staged in the same directory they're written
+	// to, so they can't share the directory of the live backup file.
+	if cfg.BackupExportDir != "" &&
+		cfg.BackupExportDir == filepath.Dir(cfg.BackupFilePath) {
+
+		return nil, fmt.Errorf("backupexportdir must not be the " +
+			"directory of the channel backup file")
+	}
+
 	// Append the network type to the log directory so it is "namespaced"
 	// per network in the same fashion as the data directory.
 	cfg.LogDir = filepath.Join(cfg.LogDir,
@@ -993,6 +1142,22 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	for _, restListener := range cfg.RESTListeners {
+		if lncfg.IsNamedPipe(restListener) {
+			return nil, fmt.Errorf("named pipe addresses cannot be "+
+				"used for the REST listener: %s", restListener)
+		}
+	}
+
+	if cfg.RPCSocketPerms > 0777 {
+		return nil, fmt.Errorf("invalid rpcsocketperms %o, must be at "+
+			"most 0777", cfg.RPCSocketPerms)
+	}
+
+	cfg.restTrustedProxies, err = lncfg.ParseIPNets(cfg.RestTrustedProxy)
+	if err != nil {
+		return nil, err
+	}
 
 	// For each of the RPC listeners (REST+gRPC), we'll ensure that users
 	// have specified a safe combo for authentication. If not, we'll bail
@@ -1047,10 +1212,12 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		// Also, we would need to refactor the brontide listener to support
 		// that.
 		for _, p2pListener := range cfg.Listeners {
-			if lncfg.IsUnix(p2pListener) {
-				err := fmt.Errorf("unix socket addresses cannot be "+
-					"used for the p2p connection listener: %s",
-					p2pListener)
+			if lncfg.IsUnix(p2pListener) ||
+				lncfg.IsNamedPipe(p2pListener) {
+
+				err := fmt.Errorf("unix socket and named pipe "+
+					"addresses cannot be used for the p2p "+
+					"connection listener: %s", p2pListener)
 				return nil, err
 			}
 		}
@@ -1062,6 +1229,33 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		return nil, fmt.Errorf("maxbackoff must be greater than minbackoff")
 	}
 
+	if cfg.ShutdownTimeout < 0 {
+		return nil, fmt.Errorf("shutdowntimeout must not be negative")
+	}
+	if cfg.ShutdownGrace < 0 {
+		return nil, fmt.Errorf("shutdowngraceperiod must not be " +
+			"negative")
+	}
+
+	if cfg.AcceptorTimeout <= 0 || cfg.AcceptorTimeout > maxAcceptorTimeout {
+		return nil, fmt.Errorf("acceptortimeout must be positive and "+
+			"at most %v", maxAcceptorTimeout)
+	}
+
+	if path := cfg.Dev.FixturesPath(); path != "" {
+		cfg.fixtures, err = loadNodeFixtures(CleanAndExpandPath(path))
+		if err != nil {
+			return nil, err
+		}
+
+		// The wallet seed is only used when the wallet is created
+		// without the wallet unlocker.
+		if cfg.fixtures.hdSeed() != nil && !cfg.NoSeedBackup {
+			return nil, fmt.Errorf("a fixture seed requires " +
+				"noseedbackup")
+		}
+	}
+
 	// Newer versions of lnd added a new sub-config for bolt-specific
 	// parameters. However we want to also allow existing users to use the
 	// value on the top-level config. If the outer config value is set,
@@ -1086,11 +1280,35 @@ func ValidateConfig(cfg Config, usageMessage string) (*Config, error) {
 		cfg.WtClient,
 		cfg.DB,
 		cfg.HealthChecks,
+		cfg.FeeControl,
+		cfg.Invoices,
+		cfg.HtlcEvents,
+		cfg.HtlcReputation,
+		cfg.ChanConfs,
+		cfg.SweptFunds,
+		cfg.IdentitySigner,
+		cfg.Compression,
+		cfg.RPCLimits,
+		cfg.Dashboard,
+		cfg.WalletLock,
 	)
 	if err != nil {
 		return nil, err
 	}
 
+	// The remote wallet is locked and unlocked by its own operator.
+	if cfg.WalletLock.IdleTimeout != 0 && cfg.Dcrwallet.GRPCHost != "" {
+		return nil, fmt.Errorf("walletlock.idletimeout is not " +
+			"supported with a remote wallet")
+	}
+
+	// A fixed number of channel confirmations would always take precedence
+	// over the size based tiers, so both can't be set at the same time.
+	if cfg.DefaultNumChanConfs != 0 && cfg.ChanConfs.Active() {
+		return nil, fmt.Errorf("defaultchanconfs and chanconfs.tier " +
+			"are mutually exclusive")
+	}
+
 	// Finally, ensure that the user's color is correctly formatted,
 	// otherwise the server will not be able to start after the unlocking
 	// the wallet.
@@ -1255,3 +1473,30 @@ func normalizeNetwork(network string) string {
 
 	return network
 }
+
+// validateCltvRejectDeltas ensures the deltas used to cancel back htlcs close
+// to their expiry don't conflict with the deltas at which we go on chain, nor
+// with the time lock delta our invoices and channels use by default.
+func validateCltvRejectDeltas(finalDelta, outgoingDelta,
+	timeLockDelta uint32) error {
+
+	if finalDelta <= lncfg.DefaultIncomingBroadcastDelta {
+		return fmt.Errorf("final cltv reject delta (%v) must be greater "+
+			"than the incoming broadcast delta (%v)", finalDelta,
+			lncfg.DefaultIncomingBroadcastDelta)
+	}
+
+	if finalDelta >= timeLockDelta {
+		return fmt.Errorf("final cltv reject delta (%v) must be lower "+
+			"than the time lock delta (%v)", finalDelta,
+			timeLockDelta)
+	}
+
+	if outgoingDelta <= lncfg.DefaultOutgoingBroadcastDelta {
+		return fmt.Errorf("outgoing cltv reject delta (%v) must be "+
+			"greater than the outgoing broadcast delta (%v)",
+			outgoingDelta, lncfg.DefaultOutgoingBroadcastDelta)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package crashreport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultReportFileName is the default name of the file the last
+	// crash report is stored in.
+	DefaultReportFileName = "crash_report.json"
+
+	// DefaultNumRecentEvents is the default number of recent log lines
+	// included in a crash report.
+	DefaultNumRecentEvents = 200
+
+	// maxEventLength is the maximum length of a log line recorded as a
+	// recent event. Longer lines are truncated, so that a line that is
+	// never terminated can't grow the reporter's memory without bound.
+	maxEventLength = 4096
+)
+
+var (
+	// ErrNoReport is returned when no crash report was recorded.
+	ErrNoReport = errors.New("no crash report found")
+)
+
+// Report is the state recorded when a panic occurred.
+type Report struct {
+	// Timestamp is the time the panic was recovered at.
+	Timestamp time.Time `json:"timestamp"`
+
+	// Subsystem is the subsystem or RPC method the panic occurred in.
+	Subsystem string `json:"subsystem"`
+
+	// Panic is the value the panic was raised with.
+	Panic string `json:"panic"`
+
+	// Stack is the stack trace of the panicking goroutine.
+	Stack string `json:"stack"`
+
+	// RecentEvents are the log lines written right before the panic, from
+	// oldest to newest.
+	RecentEvents []string `json:"recent_events"`
+}
+
+// Reporter records crash reports to a file, keeping only the last one. It
+// also implements io.Writer to keep track of the most recent log lines, which
+// are included in the reports.
+type Reporter struct {
+	reportPath string
+
+	mu sync.Mutex
+
+	// events is a ring buffer of the most recent log lines, with next
+	// being the index the next line is stored at.
+	events []string
+	next   int
+
+	// partial holds a log line not yet terminated by a newline, up to
+	// maxEventLength bytes.
+	partial []byte
+}
+
+// New creates a reporter storing its report in the given directory and
+// keeping track of the given number of recent log lines.
+func New(dir string, numEvents int) *Reporter {
+	return &Reporter{
+		reportPath: filepath.Join(dir, DefaultReportFileName),
+		events:     make([]string, numEvents),
+	}
+}
+
+// Write records the log lines in the passed buffer as recent events.
+//
+// NOTE: This is part of the io.Writer interface.
+func (r *Reporter) Write(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.events) == 0 {
+		return len(b), nil
+	}
+
+	data := b
+	for {
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+
+		r.appendPartial(data[:i])
+		r.events[r.next] = string(r.partial)
+		r.next = (r.next + 1) % len(r.events)
+		r.partial = r.partial[:0]
+		data = data[i+1:]
+	}
+	r.appendPartial(data)
+
+	return len(b), nil
+}
+
+// appendPartial appends the passed bytes to the log line not yet terminated by
+// a newline, truncating it to maxEventLength.
+func (r *Reporter) appendPartial(b []byte) {
+	room := maxEventLength - len(r.partial)
+	if room <= 0 {
+		return
+	}
+	if len(b) > room {
+		b = b[:room]
+	}
+
+	r.partial = append(r.partial, b...)
+}
+
+// RecentEvents returns the most recent log lines, from oldest to newest.
+func (r *Reporter) RecentEvents() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	events := make([]string, 0, len(r.events))
+	for i := range r.events {
+		event := r.events[(r.next+i)%len(r.events)]
+		if event == "" {
+			continue
+		}
+		events = append(events, event)
+	}
+
+	return events
+}
+
+// Record writes a report of the given panic, replacing the previous one.
+func (r *Reporter) Record(subsystem string, p interface{},
+	stack []byte) error {
+
+	report := &Report{
+		Timestamp:    time.Now(),
+		Subsystem:    subsystem,
+		Panic:        fmt.Sprintf("%v", p),
+		Stack:        string(stack),
+		RecentEvents: r.RecentEvents(),
+	}
+
+	reportBytes, err := json.MarshalIndent(report, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	// We write the report to a temporary file first, so a crash while
+	// writing it doesn't leave a corrupt report behind.
+	tempPath := r.reportPath + ".tmp"
+	err = ioutil.WriteFile(tempPath, reportBytes, 0600)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tempPath, r.reportPath)
+}
+
+// LastReport returns the last recorded report, which may have been recorded
+// before a restart. ErrNoReport is returned if no report was recorded.
+func (r *Reporter) LastReport() (*Report, error) {
+	reportBytes, err := ioutil.ReadFile(r.reportPath)
+	switch {
+	case os.IsNotExist(err):
+		return nil, ErrNoReport
+	case err != nil:
+		return nil, err
+	}
+
+	report := &Report{}
+	if err := json.Unmarshal(reportBytes, report); err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+var (
+	// defaultReporter is the reporter used by the package level functions.
+	defaultReporter *Reporter
+	defaultMtx      sync.RWMutex
+)
+
+// UseReporter sets the reporter used by the package level functions.
+func UseReporter(r *Reporter) {
+	defaultMtx.Lock()
+	defaultReporter = r
+	defaultMtx.Unlock()
+}
+
+// RecordPanic records a report of the given panic with the reporter set by
+// UseReporter, if any.
+func RecordPanic(subsystem string, p interface{}, stack []byte) {
+	defaultMtx.RLock()
+	r := defaultReporter
+	defaultMtx.RUnlock()
+
+	log.Criticalf("Panic in %v: %v\n%s", subsystem, p, stack)
+
+	if r == nil {
+		return
+	}
+
+	if err := r.Record(subsystem, p, stack); err != nil {
+		log.Errorf("Unable to record crash report: %v", err)
+	}
+}
+
+// LastReport returns the last report recorded by the reporter set by
+// UseReporter.
+func LastReport() (*Report, error) {
+	defaultMtx.RLock()
+	r := defaultReporter
+	defaultMtx.RUnlock()
+
+	if r == nil {
+		return nil, ErrNoReport
+	}
+
+	return r.LastReport()
+}
+
+// Recover records a report of an ongoing panic and then resumes panicking. It
+// MUST be deferred directly at the start of the goroutines to cover:
+//
+//	defer crashreport.Recover(Subsystem)
+func Recover(subsystem string) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	RecordPanic(subsystem, p, debug.Stack())
+	panic(p)
+}
@@ -0,0 +1,136 @@
+package crashreport
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestReporterRecentEvents asserts that the reporter keeps track of the most
+// recent complete log lines only.
+func TestReporterRecentEvents(t *testing.T) {
+	t.Parallel()
+
+	r := New("", 3)
+
+	if _, err := r.Write([]byte("line 1\nline 2\nli")); err != nil {
+		t.Fatalf("unable to write: %v", err)
+	}
+	expected := []string{"line 1", "line 2"}
+	if events := r.RecentEvents(); !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+
+	if _, err := r.Write([]byte("ne 3\nline 4\n")); err != nil {
+		t.Fatalf("unable to write: %v", err)
+	}
+	expected = []string{"line 2", "line 3", "line 4"}
+	if events := r.RecentEvents(); !reflect.DeepEqual(events, expected) {
+		t.Fatalf("expected events %v, got %v", expected, events)
+	}
+}
+
+// TestReporterLongLines asserts that log lines longer than maxEventLength are
+// truncated, also when they're written in several parts.
+func TestReporterLongLines(t *testing.T) {
+	t.Parallel()
+
+	r := New("", 3)
+
+	long := strings.Repeat("a", maxEventLength)
+	for i := 0; i < 3; i++ {
+		if _, err := r.Write([]byte(long)); err != nil {
+			t.Fatalf("unable to write: %v", err)
+		}
+	}
+	if len(r.partial) != maxEventLength {
+		t.Fatalf("expected partial line of %d bytes, got %d",
+			maxEventLength, len(r.partial))
+	}
+
+	_, err := r.Write([]byte("b\nshort\n" + long + "c\n"))
+	if err != nil {
+		t.Fatalf("unable to write: %v", err)
+	}
+	expected := []string{long, "short", long}
+	if events := r.RecentEvents(); !reflect.DeepEqual(events, expected) {
+		t.Fatalf("unexpected events: %d events", len(events))
+	}
+	if len(r.partial) != 0 {
+		t.Fatalf("expected no partial line, got %d bytes",
+			len(r.partial))
+	}
+}
+
+// TestReporterRecord asserts that the last recorded report can be read back,
+// also by a new reporter as done after a restart.
+func TestReporterRecord(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "crashreport")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := New(dir, DefaultNumRecentEvents)
+	if _, err := r.LastReport(); err != ErrNoReport {
+		t.Fatalf("expected ErrNoReport, got %v", err)
+	}
+
+	if _, err := r.Write([]byte("event\n")); err != nil {
+		t.Fatalf("unable to write: %v", err)
+	}
+	if err := r.Record("TEST", "first", []byte("stack")); err != nil {
+		t.Fatalf("unable to record report: %v", err)
+	}
+	if err := r.Record("TEST", "second", []byte("stack")); err != nil {
+		t.Fatalf("unable to record report: %v", err)
+	}
+
+	report, err := New(dir, DefaultNumRecentEvents).LastReport()
+	if err != nil {
+		t.Fatalf("unable to read report: %v", err)
+	}
+	if report.Subsystem != "TEST" || report.Panic != "second" ||
+		report.Stack != "stack" {
+
+		t.Fatalf("unexpected report: %v", report)
+	}
+	if !reflect.DeepEqual(report.RecentEvents, []string{"event"}) {
+		t.Fatalf("unexpected recent events: %v", report.RecentEvents)
+	}
+}
+
+// TestRecover asserts that Recover records a report and resumes panicking.
+func TestRecover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashreport")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	UseReporter(New(dir, DefaultNumRecentEvents))
+	defer UseReporter(nil)
+
+	func() {
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("expected panic to resume, got %v", p)
+			}
+		}()
+		defer Recover("TEST")
+
+		panic("boom")
+	}()
+
+	report, err := LastReport()
+	if err != nil {
+		t.Fatalf("unable to read report: %v", err)
+	}
+	if report.Subsystem != "TEST" || report.Panic != "boom" {
+		t.Fatalf("unexpected report: %v", report)
+	}
+}
@@ -0,0 +1,314 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/zpay32"
+)
+
+// customNetParamsFile is the format of the file describing the parameters of a
+// custom network, such as a private consortium network. The parameters of the
+// base network are used for every field that isn't set.
+type customNetParamsFile struct {
+	// Base is the network the custom network is derived from, one of
+	// mainnet, testnet3, simnet or regnet. Defaults to regnet.
+	Base string `json:"base"`
+
+	// Name is the name of the network, used for the data directories. It
+	// may only contain lowercase letters and digits.
+	Name string `json:"name"`
+
+	// Net is the magic number identifying the network in the p2p
+	// protocol.
+	Net uint32 `json:"net"`
+
+	// GenesisBlock is the serialized genesis block, hex encoded.
+	GenesisBlock string `json:"genesisblock"`
+
+	// GenesisHash is the hash of the genesis block, which is also the
+	// chain hash of the channels of the network. It must match the hash of
+	// the genesis block if both are set.
+	GenesisHash string `json:"genesishash"`
+
+	// DefaultPort is the default p2p port of the nodes of the network.
+	DefaultPort string `json:"defaultport"`
+
+	// RPCPort is the default RPC port of dcrd.
+	RPCPort string `json:"rpcport"`
+
+	// DcrwPort is the default gRPC port of dcrwallet.
+	DcrwPort string `json:"dcrwport"`
+
+	// CoinType is the coin type used to derive the keys of the node.
+	CoinType *uint32 `json:"cointype"`
+
+	// InvoicePrefix is the network part of the HRP of the invoices of the
+	// network, following the "ln" prefix.
+	InvoicePrefix string `json:"invoiceprefix"`
+
+	// NetworkAddressPrefix is the first letter of the addresses of the
+	// network.
+	NetworkAddressPrefix string `json:"networkaddressprefix"`
+
+	// The following are the hex encoded magic bytes of the addresses and
+	// keys of the network.
+	PubKeyAddrID     string `json:"pubkeyaddrid"`
+	PubKeyHashAddrID string `json:"pubkeyhashaddrid"`
+	PKHEdwardsAddrID string `json:"pkhedwardsaddrid"`
+	PKHSchnorrAddrID string `json:"pkhschnorraddrid"`
+	ScriptHashAddrID string `json:"scripthashaddrid"`
+	PrivateKeyID     string `json:"privatekeyid"`
+	HDPrivateKeyID   string `json:"hdprivatekeyid"`
+	HDPublicKeyID    string `json:"hdpublickeyid"`
+}
+
+// baseNetParams returns the parameters of the built-in network with the given
+// name.
+func baseNetParams(name string) (decredNetParams, error) {
+	for _, params := range []decredNetParams{
+		decredMainNetParams, decredTestNetParams, decredSimNetParams,
+		regTestNetParams,
+	} {
+		if params.Name == name {
+			return params, nil
+		}
+	}
+
+	return decredNetParams{}, fmt.Errorf("unknown base network %q", name)
+}
+
+// decodeMagic decodes the hex encoded magic bytes into dst, leaving it
+// untouched if s is empty.
+func decodeMagic(dst []byte, s, field string) error {
+	if s == "" {
+		return nil
+	}
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return fmt.Errorf("invalid %s: %v", field, err)
+	}
+	if len(b) != len(dst) {
+		return fmt.Errorf("%s must be %d bytes, got %d", field,
+			len(dst), len(b))
+	}
+	copy(dst, b)
+
+	return nil
+}
+
+// parseCustomNetParams parses the parameters of a custom network from the
+// contents of a network parameters file.
+func parseCustomNetParams(data []byte) (decredNetParams, string, error) {
+	file := customNetParamsFile{
+		Base: chaincfg.RegNetParams().Name,
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&file); err != nil {
+		return decredNetParams{}, "", err
+	}
+
+	base, err := baseNetParams(file.Base)
+	if err != nil {
+		return decredNetParams{}, "", err
+	}
+
+	// The name and the genesis hash identify the network, so they must
+	// differ from the ones of the built-in networks. The invoice prefix is
+	// checked once registered.
+	if file.Name == "" {
+		return decredNetParams{}, "", fmt.Errorf("network name must " +
+			"be set")
+	}
+	for _, c := range file.Name {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') {
+			return decredNetParams{}, "", fmt.Errorf("invalid "+
+				"network name %q: only lowercase letters and "+
+				"digits are allowed", file.Name)
+		}
+	}
+	_, err = baseNetParams(file.Name)
+	if err == nil || strings.HasPrefix(file.Name, "testnet") {
+		return decredNetParams{}, "", fmt.Errorf("network name %q is "+
+			"reserved", file.Name)
+	}
+	if file.InvoicePrefix == "" {
+		return decredNetParams{}, "", fmt.Errorf("invoice prefix " +
+			"must be set")
+	}
+
+	// We copy the base parameters, so that the built-in networks aren't
+	// modified.
+	params := base
+	chainParams := *base.Params
+	params.Params = &chainParams
+
+	chainParams.Name = file.Name
+	if file.Net != 0 {
+		chainParams.Net = wire.CurrencyNet(file.Net)
+	}
+
+	if file.GenesisBlock != "" {
+		blockBytes, err := hex.DecodeString(file.GenesisBlock)
+		if err != nil {
+			return decredNetParams{}, "", fmt.Errorf("invalid "+
+				"genesis block: %v", err)
+		}
+		var block wire.MsgBlock
+		if err := block.FromBytes(blockBytes); err != nil {
+			return decredNetParams{}, "", fmt.Errorf("invalid "+
+				"genesis block: %v", err)
+		}
+		chainParams.GenesisBlock = &block
+		chainParams.GenesisHash = block.BlockHash()
+	}
+	if file.GenesisHash != "" {
+		hash, err := chainhash.NewHashFromStr(file.GenesisHash)
+		if err != nil {
+			return decredNetParams{}, "", fmt.Errorf("invalid "+
+				"genesis hash: %v", err)
+		}
+		if file.GenesisBlock != "" && *hash != chainParams.GenesisHash {
+			return decredNetParams{}, "", fmt.Errorf("genesis hash "+
+				"%v doesn't match the genesis block hash %v",
+				hash, chainParams.GenesisHash)
+		}
+		chainParams.GenesisHash = *hash
+	}
+	if chainParams.GenesisHash == base.GenesisHash {
+		return decredNetParams{}, "", fmt.Errorf("genesis hash must " +
+			"differ from the one of the base network")
+	}
+
+	if file.DefaultPort != "" {
+		chainParams.DefaultPort = file.DefaultPort
+	}
+	if file.RPCPort != "" {
+		params.rpcPort = file.RPCPort
+	}
+	if file.DcrwPort != "" {
+		params.dcrwPort = file.DcrwPort
+	}
+	if file.CoinType != nil {
+		params.CoinType = *file.CoinType
+	}
+
+	// A custom network doesn't share the seeders of its base.
+	chainParams.DNSSeeds = nil
+
+	if file.NetworkAddressPrefix != "" {
+		chainParams.NetworkAddressPrefix = file.NetworkAddressPrefix
+	}
+	magics := []struct {
+		dst   []byte
+		s     string
+		field string
+	}{
+		{chainParams.PubKeyAddrID[:], file.PubKeyAddrID, "pubkeyaddrid"},
+		{chainParams.PubKeyHashAddrID[:], file.PubKeyHashAddrID,
+			"pubkeyhashaddrid"},
+		{chainParams.PKHEdwardsAddrID[:], file.PKHEdwardsAddrID,
+			"pkhedwardsaddrid"},
+		{chainParams.PKHSchnorrAddrID[:], file.PKHSchnorrAddrID,
+			"pkhschnorraddrid"},
+		{chainParams.ScriptHashAddrID[:], file.ScriptHashAddrID,
+			"scripthashaddrid"},
+		{chainParams.PrivateKeyID[:], file.PrivateKeyID,
+			"privatekeyid"},
+		{chainParams.HDPrivateKeyID[:], file.HDPrivateKeyID,
+			"hdprivatekeyid"},
+		{chainParams.HDPublicKeyID[:], file.HDPublicKeyID,
+			"hdpublickeyid"},
+	}
+	for _, m := range magics {
+		if err := decodeMagic(m.dst, m.s, m.field); err != nil {
+			return decredNetParams{}, "", err
+		}
+	}
+
+	if err := checkAddressPrefix(&chainParams); err != nil {
+		return decredNetParams{}, "", err
+	}
+
+	return params, file.InvoicePrefix, nil
+}
+
+// checkAddressPrefix ensures the addresses encoded with the given parameters
+// start with the network address prefix, as it's how the network of an
+// address is recognized.
+func checkAddressPrefix(params *chaincfg.Params) error {
+	var hash [20]byte
+
+	pkhAddr, err := dcrutil.NewAddressPubKeyHash(
+		hash[:], params, dcrec.STEcdsaSecp256k1,
+	)
+	if err != nil {
+		return err
+	}
+	shAddr, err := dcrutil.NewAddressScriptHashFromHash(hash[:], params)
+	if err != nil {
+		return err
+	}
+
+	for _, addr := range []dcrutil.Address{pkhAddr, shAddr} {
+		if !strings.HasPrefix(addr.Address(),
+			params.NetworkAddressPrefix) {
+
+			return fmt.Errorf("address %v doesn't start with the "+
+				"network address prefix %q", addr.Address(),
+				params.NetworkAddressPrefix)
+		}
+	}
+
+	return nil
+}
+
+// loadCustomNetParams loads the parameters of a custom network from the given
+// network parameters file and registers its invoice prefix.
+func loadCustomNetParams(path string) (decredNetParams, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return decredNetParams{}, err
+	}
+
+	params, invoicePrefix, err := parseCustomNetParams(data)
+	if err != nil {
+		return decredNetParams{}, fmt.Errorf("invalid network "+
+			"parameters file %v: %v", path, err)
+	}
+
+	if err := zpay32.RegisterNetwork(params.Params, invoicePrefix); err != nil {
+		return decredNetParams{}, err
+	}
+
+	return params, nil
+}
+
+// checkGenesisHash ensures the genesis block of the chain backend matches the
+// genesis hash of the active network, which is the chain hash of our channels
+// and gossip messages.
+func checkGenesisHash(chainIO lnwallet.BlockChainIO) error {
+	hash, err := chainIO.GetBlockHash(0)
+	if err != nil {
+		return fmt.Errorf("unable to fetch genesis block hash: %v", err)
+	}
+	if *hash != activeNetParams.GenesisHash {
+		return fmt.Errorf("genesis block %v of the chain backend "+
+			"doesn't match the genesis hash %v of the %v network",
+			hash, activeNetParams.GenesisHash, activeNetParams.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,105 @@
+package dcrlnd
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/wire"
+)
+
+// TestParseCustomNetParams asserts that the parameters of custom networks are
+// derived from their base network and validated for consistency.
+func TestParseCustomNetParams(t *testing.T) {
+	t.Parallel()
+
+	genesis := chaincfg.SimNetParams().GenesisBlock
+	block := *genesis
+	block.Header.Nonce++
+	blockBytes, err := block.Bytes()
+	if err != nil {
+		t.Fatalf("unable to serialize block: %v", err)
+	}
+	blockHex := hex.EncodeToString(blockBytes)
+	blockHash := block.BlockHash()
+
+	tests := []struct {
+		name string
+		file string
+		err  string
+	}{{
+		name: "valid",
+		file: `{"base": "simnet", "name": "privnet", "net": 1234,
+			"genesisblock": "` + blockHex + `",
+			"rpcport": "29556", "invoiceprefix": "pdcr"}`,
+	}, {
+		name: "unknown base",
+		file: `{"base": "nonet", "name": "privnet",
+			"genesishash": "` + blockHash.String() + `",
+			"invoiceprefix": "pdcr"}`,
+		err: "unknown base network",
+	}, {
+		name: "reserved name",
+		file: `{"name": "testnet4",
+			"genesishash": "` + blockHash.String() + `",
+			"invoiceprefix": "pdcr"}`,
+		err: "reserved",
+	}, {
+		name: "base genesis hash",
+		file: `{"name": "privnet", "invoiceprefix": "pdcr"}`,
+		err:  "genesis hash must differ",
+	}, {
+		name: "mismatched genesis hash",
+		file: `{"base": "simnet", "name": "privnet",
+			"genesisblock": "` + blockHex + `",
+			"genesishash": "` + genesis.BlockHash().String() + `",
+			"invoiceprefix": "pdcr"}`,
+		err: "doesn't match the genesis block hash",
+	}, {
+		name: "mismatched address prefix",
+		file: `{"name": "privnet",
+			"genesishash": "` + blockHash.String() + `",
+			"networkaddressprefix": "P", "invoiceprefix": "pdcr"}`,
+		err: "network address prefix",
+	}, {
+		name: "unknown field",
+		file: `{"name": "privnet", "genesis": "x"}`,
+		err:  "unknown field",
+	}}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			params, prefix, err := parseCustomNetParams(
+				[]byte(test.file),
+			)
+			if test.err != "" {
+				if err == nil || !strings.Contains(
+					err.Error(), test.err) {
+
+					t.Fatalf("expected error %q, got %v",
+						test.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unable to parse params: %v", err)
+			}
+
+			if params.Name != "privnet" || prefix != "pdcr" ||
+				params.Net != wire.CurrencyNet(1234) ||
+				params.GenesisHash != blockHash ||
+				params.rpcPort != "29556" ||
+				params.dcrwPort != decredSimNetParams.dcrwPort {
+
+				t.Fatalf("unexpected params: %+v", params)
+			}
+
+			// The built-in parameters must be left untouched.
+			if decredSimNetParams.Name != "simnet" {
+				t.Fatalf("base network params were modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,389 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/build"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+)
+
+const (
+	// dashboardNodeKey, dashboardChannelsKey and dashboardForwardsKey are
+	// the keys of the responses of the dashboard RPCs in the cache.
+	dashboardNodeKey     = "node"
+	dashboardChannelsKey = "channels"
+	dashboardForwardsKey = "forwards"
+)
+
+// dashboardCacheEntry is a cached response of a dashboard RPC.
+type dashboardCacheEntry struct {
+	// mtx is held while the response is generated, so concurrent requests
+	// for an expired response only generate it once, without delaying the
+	// requests for other responses.
+	mtx       sync.Mutex
+	resp      interface{}
+	expiresAt time.Time
+}
+
+// dashboardCache caches the responses of the dashboard RPCs, so that status
+// pages polling them can't put any meaningful load on the node. It also caches
+// other results that are expensive to compute, such as the graph centrality.
+type dashboardCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	// mtx guards the entries map, but not the entries themselves.
+	mtx     sync.Mutex
+	entries map[string]*dashboardCacheEntry
+}
+
+// newDashboardCache creates an empty cache keeping responses for the given
+// duration.
+func newDashboardCache(ttl time.Duration, now func() time.Time) *dashboardCache {
+	return &dashboardCache{
+		ttl:     ttl,
+		now:     now,
+		entries: make(map[string]*dashboardCacheEntry),
+	}
+}
+
+// get returns the cached response for the given key, calling fetch with the
+// current time to generate it if it's missing or expired. Errors aren't
+// cached.
+func (c *dashboardCache) get(key string,
+	fetch func(now time.Time) (interface{}, error)) (interface{}, error) {
+
+	c.mtx.Lock()
+	entry, ok := c.entries[key]
+	if !ok {
+		entry = &dashboardCacheEntry{}
+		c.entries[key] = entry
+	}
+	c.mtx.Unlock()
+
+	entry.mtx.Lock()
+	defer entry.mtx.Unlock()
+
+	now := c.now()
+	if entry.resp != nil && now.Before(entry.expiresAt) {
+		return entry.resp, nil
+	}
+
+	resp, err := fetch(now)
+	if err != nil {
+		return nil, err
+	}
+	entry.resp = resp
+	entry.expiresAt = now.Add(c.ttl)
+
+	return resp, nil
+}
+
+// cacheTTL returns the number of seconds the responses are cached for.
+func (c *dashboardCache) cacheTTL() int64 {
+	return int64(c.ttl / time.Second)
+}
+
+// DashboardNodeSummary returns public facing information about the node for
+// use by status pages.
+func (r *rpcServer) DashboardNodeSummary(ctx context.Context,
+	_ *lnrpc.DashboardNodeSummaryRequest) (
+	*lnrpc.DashboardNodeSummaryResponse, error) {
+
+	resp, err := r.dashboardCache.get(dashboardNodeKey,
+		func(now time.Time) (interface{}, error) {
+			return r.dashboardNodeSummary(now)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*lnrpc.DashboardNodeSummaryResponse), nil
+}
+
+// dashboardNodeSummary generates the response of DashboardNodeSummary.
+func (r *rpcServer) dashboardNodeSummary(
+	now time.Time) (*lnrpc.DashboardNodeSummaryResponse, error) {
+
+	rpcsLog.Debugf("[dashboardnodesummary]")
+
+	openChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	var activeChannels uint32
+	for _, channel := range openChannels {
+		chanID := lnwire.NewChanIDFromOutPoint(&channel.FundingOutpoint)
+		if r.server.htlcSwitch.HasActiveLink(chanID) {
+			activeChannels++
+		}
+	}
+
+	pendingChannels, err := r.server.remoteChanDB.FetchPendingChannels()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get retrieve pending "+
+			"channels: %v", err)
+	}
+
+	_, bestHeight, err := r.server.cc.chainIO.GetBestBlock()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get best block info: %v", err)
+	}
+
+	isSynced, _, err := r.server.cc.wallet.IsSynced()
+	if err != nil {
+		return nil, fmt.Errorf("unable to sync PoV of the wallet "+
+			"with current best block in the main chain: %v", err)
+	}
+
+	nodeAnn, err := r.server.genNodeAnnouncement(false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve current fully signed "+
+			"node announcement: %v", err)
+	}
+
+	idPub := r.server.identityECDH.PubKey().SerializeCompressed()
+
+	return &lnrpc.DashboardNodeSummaryResponse{
+		IdentityPubkey:      hex.EncodeToString(idPub),
+		Alias:               nodeAnn.Alias.String(),
+		Color:               routing.EncodeHexColor(nodeAnn.RGBColor),
+		Version:             build.Version(),
+		NumPeers:            uint32(len(r.server.Peers())),
+		NumActiveChannels:   activeChannels,
+		NumInactiveChannels: uint32(len(openChannels)) - activeChannels,
+		NumPendingChannels:  uint32(len(pendingChannels)),
+		BlockHeight:         uint32(bestHeight),
+		SyncedToChain:       isSynced,
+		SyncedToGraph: r.server.authGossiper.SyncManager().
+			IsGraphSynced(),
+		GeneratedAt: now.Unix(),
+		CacheTtl:    r.dashboardCache.cacheTTL(),
+	}, nil
+}
+
+// DashboardChannelSummary returns aggregate stats about the channels of the
+// node, without identifying any of them or reporting their balances.
+func (r *rpcServer) DashboardChannelSummary(ctx context.Context,
+	_ *lnrpc.DashboardChannelSummaryRequest) (
+	*lnrpc.DashboardChannelSummaryResponse, error) {
+
+	resp, err := r.dashboardCache.get(dashboardChannelsKey,
+		func(now time.Time) (interface{}, error) {
+			return r.dashboardChannelSummary(now)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*lnrpc.DashboardChannelSummaryResponse), nil
+}
+
+// dashboardChannelSummary generates the response of DashboardChannelSummary.
+func (r *rpcServer) dashboardChannelSummary(
+	now time.Time) (*lnrpc.DashboardChannelSummaryResponse, error) {
+
+	rpcsLog.Debugf("[dashboardchannelsummary]")
+
+	openChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &lnrpc.DashboardChannelSummaryResponse{
+		NumChannels: uint32(len(openChannels)),
+		GeneratedAt: now.Unix(),
+		CacheTtl:    r.dashboardCache.cacheTTL(),
+	}
+	for _, channel := range openChannels {
+		if channel.ChannelFlags&lnwire.FFAnnounceChannel != 0 {
+			resp.NumPublicChannels++
+		} else {
+			resp.NumPrivateChannels++
+		}
+
+		chanID := lnwire.NewChanIDFromOutPoint(&channel.FundingOutpoint)
+		if r.server.htlcSwitch.HasActiveLink(chanID) {
+			resp.NumActiveChannels++
+		}
+
+		capacity := int64(channel.Capacity)
+		resp.TotalCapacity += capacity
+		if capacity > resp.LargestChannel {
+			resp.LargestChannel = capacity
+		}
+	}
+	if resp.NumChannels > 0 {
+		resp.AverageChannelSize = resp.TotalCapacity /
+			int64(resp.NumChannels)
+	}
+
+	pendingChannels, err := r.server.remoteChanDB.FetchPendingChannels()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get retrieve pending "+
+			"channels: %v", err)
+	}
+	resp.NumPendingChannels = uint32(len(pendingChannels))
+	for _, channel := range pendingChannels {
+		resp.PendingCapacity += int64(channel.Capacity)
+	}
+
+	return resp, nil
+}
+
+// DashboardForwards returns aggregate forwarding stats over the last day, week
+// and month, along with the most recent forwards, without the channels they
+// went through.
+func (r *rpcServer) DashboardForwards(ctx context.Context,
+	_ *lnrpc.DashboardForwardsRequest) (*lnrpc.DashboardForwardsResponse,
+	error) {
+
+	resp, err := r.dashboardCache.get(dashboardForwardsKey,
+		func(now time.Time) (interface{}, error) {
+			return r.dashboardForwards(now)
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.(*lnrpc.DashboardForwardsResponse), nil
+}
+
+// dashboardForwards generates the response of DashboardForwards.
+func (r *rpcServer) dashboardForwards(
+	now time.Time) (*lnrpc.DashboardForwardsResponse, error) {
+
+	rpcsLog.Debugf("[dashboardforwards]")
+
+	// Before we perform the queries below, we'll instruct the switch to
+	// flush any pending events to disk. This ensure we get a complete
+	// snapshot at this particular time.
+	if err := r.server.htlcSwitch.FlushForwardingEvents(); err != nil {
+		return nil, fmt.Errorf("unable to flush forwarding "+
+			"events: %v", err)
+	}
+
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    now.Add(-time.Hour * 24 * 30),
+		EndTime:      now,
+		NumMaxEvents: 1000,
+	}
+
+	stats := newDashboardForwardStats(
+		now, int(r.cfg.Dashboard.RecentForwards),
+	)
+	fwdEventLog := r.server.remoteChanDB.ForwardingLog()
+	for {
+		timeSlice, err := fwdEventLog.Query(query)
+		if err != nil {
+			return nil, fmt.Errorf("unable to query forwarding "+
+				"log: %v", err)
+		}
+
+		// If the timeslice is empty, then we've retrieved all the
+		// entries in this range.
+		if len(timeSlice.ForwardingEvents) == 0 {
+			break
+		}
+
+		for i := range timeSlice.ForwardingEvents {
+			stats.addForward(&timeSlice.ForwardingEvents[i])
+		}
+
+		query.IndexOffset = timeSlice.LastIndexOffset
+	}
+
+	resp := stats.response()
+	resp.CacheTtl = r.dashboardCache.cacheTTL()
+
+	return resp, nil
+}
+
+// dashboardForwardStats aggregates the forwards of the last month into the
+// response of DashboardForwards.
+type dashboardForwardStats struct {
+	now       time.Time
+	dayStart  time.Time
+	weekStart time.Time
+	numRecent int
+
+	day, week, month *lnrpc.DashboardForwardingStats
+
+	// recent holds the last forwards added, oldest first.
+	recent []*lnrpc.DashboardForward
+}
+
+// newDashboardForwardStats creates empty forwarding stats as of the given
+// time, keeping the given number of recent forwards.
+func newDashboardForwardStats(now time.Time,
+	numRecent int) *dashboardForwardStats {
+
+	return &dashboardForwardStats{
+		now:       now,
+		dayStart:  now.Add(-time.Hour * 24),
+		weekStart: now.Add(-time.Hour * 24 * 7),
+		numRecent: numRecent,
+		day:       &lnrpc.DashboardForwardingStats{},
+		week:      &lnrpc.DashboardForwardingStats{},
+		month:     &lnrpc.DashboardForwardingStats{},
+	}
+}
+
+// addForward accounts a forward of the last month to the stats. Forwards must
+// be added oldest first.
+func (s *dashboardForwardStats) addForward(event *channeldb.ForwardingEvent) {
+	fee := uint64(event.AmtIn - event.AmtOut)
+	amtOut := uint64(event.AmtOut)
+
+	windows := []*lnrpc.DashboardForwardingStats{s.month}
+	if !event.Timestamp.Before(s.weekStart) {
+		windows = append(windows, s.week)
+	}
+	if !event.Timestamp.Before(s.dayStart) {
+		windows = append(windows, s.day)
+	}
+	for _, stats := range windows {
+		stats.NumForwards++
+		stats.VolumeMAtoms += amtOut
+		stats.FeesMAtoms += fee
+	}
+
+	if s.numRecent == 0 {
+		return
+	}
+	s.recent = append(s.recent, &lnrpc.DashboardForward{
+		Timestamp:    uint64(event.Timestamp.Unix()),
+		AmtOutMAtoms: amtOut,
+		FeeMAtoms:    fee,
+	})
+	if len(s.recent) > s.numRecent {
+		s.recent = s.recent[1:]
+	}
+}
+
+// response returns the aggregated stats, with the recent forwards reported
+// newest first.
+func (s *dashboardForwardStats) response() *lnrpc.DashboardForwardsResponse {
+	resp := &lnrpc.DashboardForwardsResponse{
+		Day:         s.day,
+		Week:        s.week,
+		Month:       s.month,
+		GeneratedAt: s.now.Unix(),
+	}
+	for i := len(s.recent) - 1; i >= 0; i-- {
+		resp.RecentForwards = append(resp.RecentForwards, s.recent[i])
+	}
+
+	return resp
+}
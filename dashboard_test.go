@@ -0,0 +1,181 @@
+package dcrlnd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestDashboardCache asserts that responses are served from the cache until
+// they expire and that errors aren't cached.
+func TestDashboardCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(1000, 0)
+	cache := newDashboardCache(time.Minute, func() time.Time {
+		return now
+	})
+
+	var calls int
+	fetch := func(fetchTime time.Time) (interface{}, error) {
+		calls++
+		return fetchTime.Unix(), nil
+	}
+	assertGet := func(key string, wantResp int64, wantCalls int) {
+		t.Helper()
+
+		resp, err := cache.get(key, fetch)
+		if err != nil {
+			t.Fatalf("unable to get %v: %v", key, err)
+		}
+		if resp.(int64) != wantResp {
+			t.Fatalf("expected response %v, got %v", wantResp, resp)
+		}
+		if calls != wantCalls {
+			t.Fatalf("expected %v fetches, got %v", wantCalls, calls)
+		}
+	}
+
+	assertGet("node", 1000, 1)
+
+	// Within the ttl, the cached response is returned.
+	now = now.Add(time.Minute - time.Second)
+	assertGet("node", 1000, 1)
+
+	// Other keys are cached separately.
+	assertGet("channels", 1059, 2)
+
+	// Once expired, the response is generated again.
+	now = now.Add(time.Second)
+	assertGet("node", 1060, 3)
+
+	// Errors aren't cached.
+	now = now.Add(time.Minute)
+	_, err := cache.get("node", func(time.Time) (interface{}, error) {
+		return nil, errors.New("fetch error")
+	})
+	if err == nil {
+		t.Fatalf("expected fetch error")
+	}
+	assertGet("node", 1120, 4)
+
+	if ttl := cache.cacheTTL(); ttl != 60 {
+		t.Fatalf("expected cache ttl of 60 seconds, got %v", ttl)
+	}
+}
+
+// TestDashboardCacheConcurrentKeys asserts that generating a response doesn't
+// delay the requests for other responses.
+func TestDashboardCacheConcurrentKeys(t *testing.T) {
+	t.Parallel()
+
+	cache := newDashboardCache(time.Minute, time.Now)
+
+	fetching := make(chan struct{})
+	release := make(chan struct{})
+	slowFetch := func(time.Time) (interface{}, error) {
+		close(fetching)
+		<-release
+		return 1, nil
+	}
+	fetch := func(time.Time) (interface{}, error) {
+		return 2, nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := cache.get("forwards", slowFetch)
+		done <- err
+	}()
+	<-fetching
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := cache.get("node", fetch)
+		result <- err
+	}()
+
+	select {
+	case err := <-result:
+		if err != nil {
+			t.Fatalf("unable to get node: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("request blocked by the generation of another " +
+			"response")
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatalf("unable to get forwards: %v", err)
+	}
+}
+
+// TestDashboardForwardStats asserts that forwards are accounted to the windows
+// they fall in and that only the most recent ones are reported, newest first.
+func TestDashboardForwardStats(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(100*24*3600, 0)
+	forward := func(age time.Duration,
+		amtOut lnwire.MilliAtom) *channeldb.ForwardingEvent {
+
+		return &channeldb.ForwardingEvent{
+			Timestamp: now.Add(-age),
+			AmtIn:     amtOut + 1000,
+			AmtOut:    amtOut,
+		}
+	}
+
+	stats := newDashboardForwardStats(now, 2)
+	stats.addForward(forward(20*24*time.Hour, 10000))
+	stats.addForward(forward(3*24*time.Hour, 20000))
+	stats.addForward(forward(time.Hour, 30000))
+	stats.addForward(forward(time.Minute, 40000))
+
+	resp := stats.response()
+	assertStats := func(name string, got *lnrpc.DashboardForwardingStats,
+		numForwards, volume uint64) {
+
+		t.Helper()
+
+		if got.NumForwards != numForwards ||
+			got.VolumeMAtoms != volume ||
+			got.FeesMAtoms != numForwards*1000 {
+
+			t.Fatalf("unexpected %v stats: %v", name, got)
+		}
+	}
+	assertStats("day", resp.Day, 2, 70000)
+	assertStats("week", resp.Week, 3, 90000)
+	assertStats("month", resp.Month, 4, 100000)
+
+	if resp.GeneratedAt != now.Unix() {
+		t.Fatalf("expected generation time %v, got %v", now.Unix(),
+			resp.GeneratedAt)
+	}
+
+	if len(resp.RecentForwards) != 2 {
+		t.Fatalf("expected 2 recent forwards, got %v",
+			len(resp.RecentForwards))
+	}
+	if resp.RecentForwards[0].AmtOutMAtoms != 40000 ||
+		resp.RecentForwards[1].AmtOutMAtoms != 30000 {
+
+		t.Fatalf("unexpected recent forwards: %v", resp.RecentForwards)
+	}
+
+	// No recent forwards are reported when disabled.
+	stats = newDashboardForwardStats(now, 0)
+	stats.addForward(forward(time.Minute, 40000))
+	resp = stats.response()
+	if len(resp.RecentForwards) != 0 {
+		t.Fatalf("expected no recent forwards, got %v",
+			resp.RecentForwards)
+	}
+	assertStats("day", resp.Day, 1, 40000)
+}
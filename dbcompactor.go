@@ -0,0 +1,209 @@
+package dcrlnd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/ticker"
+)
+
+// dbCompactionStats summarizes the forwarding data removed from the channel
+// database by one or more compactions.
+type dbCompactionStats struct {
+	// fwdPkgsRemoved is the number of removed forwarding packages.
+	fwdPkgsRemoved uint64
+
+	// circuitsRemoved is the number of removed payment circuits.
+	circuitsRemoved uint64
+
+	// bytesReclaimed is the number of bytes of forwarding package data
+	// removed from the database.
+	bytesReclaimed uint64
+}
+
+// add adds the passed stats to s.
+func (s *dbCompactionStats) add(other dbCompactionStats) {
+	s.fwdPkgsRemoved += other.fwdPkgsRemoved
+	s.circuitsRemoved += other.circuitsRemoved
+	s.bytesReclaimed += other.bytesReclaimed
+}
+
+// dbCompactorConfig houses all the items required by the db compactor.
+type dbCompactorConfig struct {
+	// Retention is the number of blocks the forwarding data of a closed
+	// channel is retained after the close has been resolved.
+	Retention uint32
+
+	// Ticker signals the compactor to start a new compaction.
+	Ticker ticker.Ticker
+
+	// BestHeight returns the height of the current chain tip.
+	BestHeight func() (uint32, error)
+
+	// FetchClosedChannels returns the summaries of all closed channels.
+	FetchClosedChannels func(pendingOnly bool) (
+		[]*channeldb.ChannelCloseSummary, error)
+
+	// CompactFwdPkgs removes the forwarding packages of the passed closed
+	// channels.
+	CompactFwdPkgs func(map[lnwire.ShortChannelID]struct{}) (
+		*channeldb.FwdPkgCompaction, error)
+
+	// CompactCircuits removes the circuits that can no longer be resolved
+	// because the passed channels are closed.
+	CompactCircuits func(map[lnwire.ShortChannelID]struct{}) (int, error)
+}
+
+// dbCompactor periodically removes the forwarding packages and circuits of
+// closed channels from the channel database. Without it, these buckets grow
+// monotonically on busy forwarding nodes since the data of closed channels is
+// never removed. The forwarding packages of open channels are garbage
+// collected by their links.
+type dbCompactor struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *dbCompactorConfig
+
+	mtx            sync.Mutex
+	lastCompaction time.Time
+	last           dbCompactionStats
+	total          dbCompactionStats
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// newDBCompactor creates a new db compactor from the given config.
+func newDBCompactor(cfg *dbCompactorConfig) *dbCompactor {
+	return &dbCompactor{
+		cfg:  cfg,
+		quit: make(chan struct{}),
+	}
+}
+
+// Start launches the compaction loop.
+func (c *dbCompactor) Start() error {
+	c.started.Do(func() {
+		srvrLog.Info("DB compactor starting")
+
+		c.cfg.Ticker.Resume()
+
+		c.wg.Add(1)
+		go c.compactionLoop()
+	})
+
+	return nil
+}
+
+// Stop signals the compaction loop to exit and waits for it to finish.
+func (c *dbCompactor) Stop() {
+	c.stopped.Do(func() {
+		srvrLog.Info("DB compactor shutting down")
+
+		close(c.quit)
+		c.wg.Wait()
+		c.cfg.Ticker.Stop()
+	})
+}
+
+// compactionLoop compacts the database on every tick of the ticker.
+//
+// NOTE: This MUST be run as a goroutine.
+func (c *dbCompactor) compactionLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.cfg.Ticker.Ticks():
+			if err := c.compact(); err != nil {
+				srvrLog.Errorf("Unable to compact channel "+
+					"database: %v", err)
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// compact removes the forwarding packages and circuits that are no longer
+// needed and records the amount of reclaimed data.
+func (c *dbCompactor) compact() error {
+	closedChans, err := c.expiredClosedChannels()
+	if err != nil {
+		return err
+	}
+
+	fwdPkgs, err := c.cfg.CompactFwdPkgs(closedChans)
+	if err != nil {
+		return err
+	}
+
+	circuits, err := c.cfg.CompactCircuits(closedChans)
+	if err != nil {
+		return err
+	}
+
+	stats := dbCompactionStats{
+		fwdPkgsRemoved:  fwdPkgs.NumFwdPkgs,
+		circuitsRemoved: uint64(circuits),
+		bytesReclaimed:  fwdPkgs.NumBytes,
+	}
+
+	srvrLog.Debugf("Compacted channel database: removed %d forwarding "+
+		"packages (%d bytes) and %d circuits", stats.fwdPkgsRemoved,
+		stats.bytesReclaimed, stats.circuitsRemoved)
+
+	c.mtx.Lock()
+	c.lastCompaction = time.Now()
+	c.last = stats
+	c.total.add(stats)
+	c.mtx.Unlock()
+
+	return nil
+}
+
+// expiredClosedChannels returns the set of channels whose close has been
+// resolved for at least the configured retention.
+func (c *dbCompactor) expiredClosedChannels() (
+	map[lnwire.ShortChannelID]struct{}, error) {
+
+	bestHeight, err := c.cfg.BestHeight()
+	if err != nil {
+		return nil, err
+	}
+
+	summaries, err := c.cfg.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+
+	closedChans := make(map[lnwire.ShortChannelID]struct{})
+	for _, summary := range summaries {
+		if summary.IsPending {
+			continue
+		}
+
+		if summary.CloseHeight+c.cfg.Retention > bestHeight {
+			continue
+		}
+
+		closedChans[summary.ShortChanID] = struct{}{}
+	}
+
+	return closedChans, nil
+}
+
+// Stats returns the time of the last compaction along with the data removed
+// by it and by all compactions since startup.
+func (c *dbCompactor) Stats() (time.Time, dbCompactionStats,
+	dbCompactionStats) {
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.lastCompaction, c.last, c.total
+}
@@ -0,0 +1,104 @@
+package dcrlnd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/ticker"
+)
+
+// TestDBCompactorRetention asserts that the compactor only removes the
+// forwarding data of channels whose close has been resolved for at least the
+// retention period and that it accumulates the compaction stats.
+func TestDBCompactorRetention(t *testing.T) {
+	var (
+		pendingChan  = lnwire.NewShortChanIDFromInt(1)
+		recentChan   = lnwire.NewShortChanIDFromInt(2)
+		expiredChan  = lnwire.NewShortChanIDFromInt(3)
+		bestHeight   = uint32(1000)
+		compacted    []map[lnwire.ShortChannelID]struct{}
+		fwdPkgsStats = &channeldb.FwdPkgCompaction{
+			NumFwdPkgs: 3,
+			NumBytes:   100,
+		}
+	)
+
+	compactor := newDBCompactor(&dbCompactorConfig{
+		Retention: 100,
+		Ticker:    ticker.NewForce(time.Hour),
+		BestHeight: func() (uint32, error) {
+			return bestHeight, nil
+		},
+		FetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return []*channeldb.ChannelCloseSummary{
+				{
+					ShortChanID: pendingChan,
+					CloseHeight: 500,
+					IsPending:   true,
+				},
+				{
+					ShortChanID: recentChan,
+					CloseHeight: 901,
+				},
+				{
+					ShortChanID: expiredChan,
+					CloseHeight: 900,
+				},
+			}, nil
+		},
+		CompactFwdPkgs: func(closedChans map[lnwire.ShortChannelID]struct{}) (
+			*channeldb.FwdPkgCompaction, error) {
+
+			compacted = append(compacted, closedChans)
+			return fwdPkgsStats, nil
+		},
+		CompactCircuits: func(map[lnwire.ShortChannelID]struct{}) (int,
+			error) {
+
+			return 2, nil
+		},
+	})
+
+	for i := 0; i < 2; i++ {
+		if err := compactor.compact(); err != nil {
+			t.Fatalf("unable to compact: %v", err)
+		}
+	}
+
+	expectedChans := map[lnwire.ShortChannelID]struct{}{
+		expiredChan: {},
+	}
+	if !reflect.DeepEqual(compacted[0], expectedChans) {
+		t.Fatalf("expected compacted channels %v, got %v",
+			expectedChans, compacted[0])
+	}
+
+	lastCompaction, last, total := compactor.Stats()
+	if lastCompaction.IsZero() {
+		t.Fatalf("expected last compaction time to be set")
+	}
+
+	expectedLast := dbCompactionStats{
+		fwdPkgsRemoved:  3,
+		circuitsRemoved: 2,
+		bytesReclaimed:  100,
+	}
+	if last != expectedLast {
+		t.Fatalf("expected last stats %v, got %v", expectedLast, last)
+	}
+
+	expectedTotal := dbCompactionStats{
+		fwdPkgsRemoved:  6,
+		circuitsRemoved: 4,
+		bytesReclaimed:  200,
+	}
+	if total != expectedTotal {
+		t.Fatalf("expected total stats %v, got %v", expectedTotal,
+			total)
+	}
+}
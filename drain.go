@@ -0,0 +1,139 @@
+package dcrlnd
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/signal"
+	"github.com/decred/dcrlnd/sweep"
+)
+
+const (
+	// defaultDrainTimeout is the maximum time we wait for in-flight htlcs
+	// and pending sweeps to settle before shutting down if the StopDaemon
+	// caller didn't specify one.
+	defaultDrainTimeout = 5 * time.Minute
+
+	// drainPollInterval is the interval at which we check whether the
+	// node has been drained.
+	drainPollInterval = time.Second
+)
+
+// errDrainInProgress is returned when a drain is requested while another one
+// is already under way.
+var errDrainInProgress = errors.New("daemon is already draining")
+
+// drainWork returns the number of htlcs still in flight on the given channels
+// and the number of pending sweep inputs that can be, but haven't yet been,
+// broadcast at the given height.
+func drainWork(channels []*channeldb.OpenChannel,
+	inputs map[wire.OutPoint]*sweep.PendingInput,
+	bestHeight uint32) (int, int) {
+
+	var numHtlcs int
+	for _, channel := range channels {
+		// An htlc is in flight as long as it's present on either
+		// commitment, as it may still be added to or removed from the
+		// other one.
+		inFlight := len(channel.LocalCommitment.Htlcs)
+		if len(channel.RemoteCommitment.Htlcs) > inFlight {
+			inFlight = len(channel.RemoteCommitment.Htlcs)
+		}
+		numHtlcs += inFlight
+	}
+
+	var numSweeps int
+	for _, input := range inputs {
+		// Inputs whose sweep isn't due yet won't be broadcast before
+		// the timeout, so there's no point in waiting for them.
+		if input.BroadcastAttempts > 0 ||
+			input.NextBroadcastHeight > bestHeight {
+
+			continue
+		}
+		numSweeps++
+	}
+
+	return numHtlcs, numSweeps
+}
+
+// pendingDrainWork returns the number of in-flight htlcs and unbroadcast
+// sweeps the node is currently waiting on before it can shut down.
+func (r *rpcServer) pendingDrainWork() (int, int, error) {
+	channels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	inputs, err := r.server.sweeper.PendingInputs()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	_, bestHeight, err := r.server.cc.chainIO.GetBestBlock()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	numHtlcs, numSweeps := drainWork(channels, inputs, uint32(bestHeight))
+	return numHtlcs, numSweeps, nil
+}
+
+// drainAndShutdown stops forwarding new htlcs and originating new payments,
+// then requests a shutdown of the daemon once all in-flight htlcs have been
+// resolved and all pending sweeps broadcast, or once the timeout expires.
+func (r *rpcServer) drainAndShutdown(timeout time.Duration) error {
+	if !atomic.CompareAndSwapInt32(&r.draining, 0, 1) {
+		return errDrainInProgress
+	}
+
+	rpcsLog.Infof("Draining daemon before shutdown, timeout=%v", timeout)
+
+	// Invoices aren't blocked, as that would also prevent hodl invoices
+	// from being settled and their htlcs from resolving.
+	r.routerBackend.SetPaymentsBlocked(true)
+	r.server.htlcSwitch.SetForwardsBlocked(true)
+
+	go func() {
+		defer signal.RequestShutdown()
+
+		deadline := time.After(timeout)
+		ticker := time.NewTicker(drainPollInterval)
+		defer ticker.Stop()
+
+		for {
+			numHtlcs, numSweeps, err := r.pendingDrainWork()
+			switch {
+			case err != nil:
+				rpcsLog.Errorf("Unable to check drain progress: %v",
+					err)
+
+			case numHtlcs == 0 && numSweeps == 0:
+				rpcsLog.Infof("Daemon drained, shutting down")
+				return
+
+			default:
+				rpcsLog.Debugf("Waiting for %d htlcs and %d "+
+					"sweeps to drain", numHtlcs, numSweeps)
+			}
+
+			select {
+			case <-ticker.C:
+
+			case <-deadline:
+				rpcsLog.Warnf("Drain timed out with %d htlcs "+
+					"and %d sweeps pending, shutting down",
+					numHtlcs, numSweeps)
+				return
+
+			case <-r.quit:
+				return
+			}
+		}
+	}()
+
+	return nil
+}
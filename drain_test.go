@@ -0,0 +1,56 @@
+package dcrlnd
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/sweep"
+)
+
+// TestDrainWork asserts that the htlcs in flight on either commitment and the
+// sweeps that are due but not yet broadcast are counted as pending drain work.
+func TestDrainWork(t *testing.T) {
+	t.Parallel()
+
+	htlcs := func(n int) []channeldb.HTLC {
+		return make([]channeldb.HTLC, n)
+	}
+
+	channels := []*channeldb.OpenChannel{
+		{
+			LocalCommitment:  channeldb.ChannelCommitment{Htlcs: htlcs(2)},
+			RemoteCommitment: channeldb.ChannelCommitment{Htlcs: htlcs(1)},
+		},
+		{
+			LocalCommitment:  channeldb.ChannelCommitment{Htlcs: htlcs(0)},
+			RemoteCommitment: channeldb.ChannelCommitment{Htlcs: htlcs(3)},
+		},
+		{},
+	}
+
+	inputs := map[wire.OutPoint]*sweep.PendingInput{
+		// Already broadcast.
+		{Index: 0}: {BroadcastAttempts: 1, NextBroadcastHeight: 100},
+
+		// Due but not yet broadcast.
+		{Index: 1}: {NextBroadcastHeight: 100},
+
+		// Not due before a later height.
+		{Index: 2}: {NextBroadcastHeight: 101},
+	}
+
+	numHtlcs, numSweeps := drainWork(channels, inputs, 100)
+	if numHtlcs != 5 {
+		t.Fatalf("expected 5 htlcs, got %d", numHtlcs)
+	}
+	if numSweeps != 1 {
+		t.Fatalf("expected 1 sweep, got %d", numSweeps)
+	}
+
+	numHtlcs, numSweeps = drainWork(nil, nil, 100)
+	if numHtlcs != 0 || numSweeps != 0 {
+		t.Fatalf("expected no pending work, got %d htlcs and %d "+
+			"sweeps", numHtlcs, numSweeps)
+	}
+}
@@ -0,0 +1,327 @@
+package extsigner
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/keychain"
+)
+
+var (
+	// ErrClientClosed is returned when a request is made after the client
+	// has been closed.
+	ErrClientClosed = errors.New("external signer client closed")
+
+	// ErrPubKeyMismatch is returned when the device the client reconnects
+	// to holds a different identity key than the one it was started with.
+	ErrPubKeyMismatch = errors.New("external signer identity key changed")
+)
+
+// Config holds the parameters needed to reach an external signer.
+type Config struct {
+	// Addr is the address the external signer listens on. Both TCP and
+	// unix domain socket addresses are supported.
+	Addr net.Addr
+
+	// Timeout is the maximum amount of time we wait to connect to the
+	// external signer and for it to answer a single request. Hardware
+	// devices may require user interaction, so this should be generous.
+	Timeout time.Duration
+}
+
+// Client is a connection to an external signer, such as a hardware wallet
+// bridge, that holds the identity private key of the node. The key never
+// leaves the device: the client only asks it to perform the ECDH and signing
+// operations needed for the brontide handshake, onion processing and gossip
+// signing. Channel operations keep using the keys of the wallet.
+//
+// Requests are serialized, as devices usually process a single operation at a
+// time. If the connection breaks, the client reconnects on the next request
+// and checks that the device still holds the same key.
+type Client struct {
+	cfg Config
+
+	pubKey *secp256k1.PublicKey
+
+	mtx    sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+	nextID uint64
+	closed bool
+}
+
+// A compile time check to ensure Client implements the interfaces needed to
+// act as the node identity key.
+var _ keychain.SingleKeyECDH = (*Client)(nil)
+var _ keychain.SingleKeyDigestSigner = (*Client)(nil)
+
+// Connect connects to the external signer described by the config and fetches
+// the identity public key it holds.
+func Connect(cfg Config) (*Client, error) {
+	c := &Client{
+		cfg: cfg,
+	}
+
+	pubKey, err := c.fetchPubKey()
+	if err != nil {
+		c.Close()
+		return nil, err
+	}
+	c.pubKey = pubKey
+
+	log.Infof("Connected to external signer at %v, identity key %x",
+		cfg.Addr, pubKey.SerializeCompressed())
+
+	return c, nil
+}
+
+// PubKey returns the identity public key held by the external signer.
+//
+// NOTE: This is part of the keychain.SingleKeyECDH and
+// keychain.SingleKeyDigestSigner interfaces.
+func (c *Client) PubKey() *secp256k1.PublicKey {
+	return c.pubKey
+}
+
+// ECDH asks the external signer to perform a scalar multiplication between the
+// identity key and the remote public key. The result is the sha256 of the
+// shared point serialized in compressed format.
+//
+// NOTE: This is part of the keychain.SingleKeyECDH interface.
+func (c *Client) ECDH(pubKey *secp256k1.PublicKey) ([32]byte, error) {
+	var sharedKey [32]byte
+
+	result, err := c.call(MethodECDH, pubKey.SerializeCompressed())
+	if err != nil {
+		return sharedKey, err
+	}
+	if len(result) != len(sharedKey) {
+		return sharedKey, fmt.Errorf("external signer returned a "+
+			"shared key of %d bytes", len(result))
+	}
+	copy(sharedKey[:], result)
+
+	return sharedKey, nil
+}
+
+// SignDigest asks the external signer to sign the digest with the identity
+// key.
+//
+// NOTE: This is part of the keychain.SingleKeyDigestSigner interface.
+func (c *Client) SignDigest(digest [32]byte) (*ecdsa.Signature, error) {
+	result, err := c.call(MethodSignDigest, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := ecdsa.ParseDERSignature(result)
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned an invalid "+
+			"signature: %v", err)
+	}
+
+	// Never hand out a signature the device made with another key or over
+	// another digest.
+	if !sig.Verify(digest[:], c.pubKey) {
+		return nil, errors.New("external signer returned a signature " +
+			"that doesn't verify")
+	}
+
+	return sig, nil
+}
+
+// SignDigestCompact asks the external signer to sign the digest with the
+// identity key and return the signature in the compact, public key recoverable
+// format.
+//
+// NOTE: This is part of the keychain.SingleKeyDigestSigner interface.
+func (c *Client) SignDigestCompact(digest [32]byte) ([]byte, error) {
+	result, err := c.call(MethodSignDigestCompact, digest[:])
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, _, err := ecdsa.RecoverCompact(result, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned an invalid "+
+			"compact signature: %v", err)
+	}
+	if !pubKey.IsEqual(c.pubKey) {
+		return nil, errors.New("external signer returned a compact " +
+			"signature of another key")
+	}
+
+	return result, nil
+}
+
+// Close closes the connection to the external signer. Any further request
+// fails with ErrClientClosed.
+func (c *Client) Close() error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	c.closed = true
+	return c.disconnect()
+}
+
+// fetchPubKey asks the device for the identity public key it holds.
+func (c *Client) fetchPubKey() (*secp256k1.PublicKey, error) {
+	result, err := c.call(MethodPubKey, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	pubKey, err := secp256k1.ParsePubKey(result)
+	if err != nil {
+		return nil, fmt.Errorf("external signer returned an invalid "+
+			"public key: %v", err)
+	}
+
+	return pubKey, nil
+}
+
+// connect establishes the connection to the device if there is none. When
+// reconnecting, it makes sure the device still holds the identity key we were
+// started with.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (c *Client) connect() error {
+	if c.conn != nil {
+		return nil
+	}
+
+	conn, err := net.DialTimeout(
+		c.cfg.Addr.Network(), c.cfg.Addr.String(), c.cfg.Timeout,
+	)
+	if err != nil {
+		return fmt.Errorf("unable to connect to external signer: %v",
+			err)
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+
+	if c.pubKey == nil {
+		return nil
+	}
+
+	log.Infof("Reconnected to external signer at %v", c.cfg.Addr)
+
+	result, err := c.roundTrip(MethodPubKey, nil)
+	if err != nil {
+		c.disconnect()
+		return err
+	}
+	if pubKey, err := secp256k1.ParsePubKey(result); err != nil ||
+		!pubKey.IsEqual(c.pubKey) {
+
+		c.disconnect()
+		return ErrPubKeyMismatch
+	}
+
+	return nil
+}
+
+// disconnect closes the current connection to the device, if any.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (c *Client) disconnect() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.reader = nil
+
+	return err
+}
+
+// call performs a single request against the device, connecting to it first
+// if needed.
+func (c *Client) call(method string, param []byte) ([]byte, error) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if c.closed {
+		return nil, ErrClientClosed
+	}
+
+	if err := c.connect(); err != nil {
+		return nil, err
+	}
+
+	return c.roundTrip(method, param)
+}
+
+// roundTrip sends a request over the current connection and waits for its
+// response. A broken connection is dropped so that the next request
+// reconnects.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (c *Client) roundTrip(method string, param []byte) ([]byte, error) {
+	c.nextID++
+	req := &Request{
+		ID:     c.nextID,
+		Method: method,
+		Param:  hex.EncodeToString(param),
+	}
+
+	reqBytes, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	reqBytes = append(reqBytes, '\n')
+
+	if c.cfg.Timeout > 0 {
+		deadline := time.Now().Add(c.cfg.Timeout)
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			c.disconnect()
+			return nil, err
+		}
+	}
+
+	if _, err := c.conn.Write(reqBytes); err != nil {
+		c.disconnect()
+		return nil, fmt.Errorf("unable to send %v request to external "+
+			"signer: %v", method, err)
+	}
+
+	line, err := c.reader.ReadBytes('\n')
+	if err != nil {
+		c.disconnect()
+		return nil, fmt.Errorf("unable to read %v response from "+
+			"external signer: %v", method, err)
+	}
+
+	var resp Response
+	if err := json.Unmarshal(line, &resp); err != nil {
+		c.disconnect()
+		return nil, fmt.Errorf("invalid %v response from external "+
+			"signer: %v", method, err)
+	}
+	if resp.ID != req.ID {
+		c.disconnect()
+		return nil, fmt.Errorf("external signer answered request %d "+
+			"instead of %d", resp.ID, req.ID)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("external signer failed %v: %v",
+			method, resp.Error)
+	}
+
+	result, err := hex.DecodeString(resp.Result)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %v result from external "+
+			"signer: %v", method, err)
+	}
+
+	return result, nil
+}
@@ -0,0 +1,222 @@
+package extsigner
+
+import (
+	"bufio"
+	"encoding/hex"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/keychain"
+)
+
+// mockDevice is an external signer holding a single private key that answers
+// the requests of every connection made to it.
+type mockDevice struct {
+	t        *testing.T
+	listener net.Listener
+	privKey  *secp256k1.PrivateKey
+	conns    chan net.Conn
+}
+
+func newMockDevice(t *testing.T, privKey *secp256k1.PrivateKey) *mockDevice {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	d := &mockDevice{
+		t:        t,
+		listener: listener,
+		privKey:  privKey,
+		conns:    make(chan net.Conn, 10),
+	}
+	go d.accept()
+
+	return d
+}
+
+func (d *mockDevice) accept() {
+	for {
+		conn, err := d.listener.Accept()
+		if err != nil {
+			return
+		}
+		d.conns <- conn
+		go d.serve(conn)
+	}
+}
+
+func (d *mockDevice) serve(conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err != nil {
+			return
+		}
+
+		var req Request
+		if err := json.Unmarshal(line, &req); err != nil {
+			return
+		}
+		param, _ := hex.DecodeString(req.Param)
+
+		resp := Response{ID: req.ID}
+		switch req.Method {
+		case MethodPubKey:
+			resp.Result = hex.EncodeToString(
+				d.privKey.PubKey().SerializeCompressed(),
+			)
+
+		case MethodECDH:
+			pubKey, err := secp256k1.ParsePubKey(param)
+			if err != nil {
+				resp.Error = err.Error()
+				break
+			}
+			ecdh := &keychain.PrivKeyECDH{PrivKey: d.privKey}
+			shared, _ := ecdh.ECDH(pubKey)
+			resp.Result = hex.EncodeToString(shared[:])
+
+		case MethodSignDigest:
+			sig := ecdsa.Sign(d.privKey, param)
+			resp.Result = hex.EncodeToString(sig.Serialize())
+
+		case MethodSignDigestCompact:
+			sig := ecdsa.SignCompact(d.privKey, param, true)
+			resp.Result = hex.EncodeToString(sig)
+
+		default:
+			resp.Error = "unknown method"
+		}
+
+		respBytes, _ := json.Marshal(resp)
+		if _, err := conn.Write(append(respBytes, '\n')); err != nil {
+			return
+		}
+	}
+}
+
+func (d *mockDevice) stop() {
+	d.listener.Close()
+}
+
+func newTestKey(t *testing.T) *secp256k1.PrivateKey {
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	return privKey
+}
+
+// TestClientOperations asserts the client returns the results of the device
+// for every operation needed by the node identity key.
+func TestClientOperations(t *testing.T) {
+	t.Parallel()
+
+	privKey := newTestKey(t)
+	device := newMockDevice(t, privKey)
+	defer device.stop()
+
+	client, err := Connect(Config{
+		Addr:    device.listener.Addr(),
+		Timeout: time.Second * 5,
+	})
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	defer client.Close()
+
+	if !client.PubKey().IsEqual(privKey.PubKey()) {
+		t.Fatalf("unexpected identity key")
+	}
+
+	// The shared key must match the one computed with the private key.
+	remoteKey := newTestKey(t)
+	shared, err := client.ECDH(remoteKey.PubKey())
+	if err != nil {
+		t.Fatalf("unable to perform ecdh: %v", err)
+	}
+	localECDH := &keychain.PrivKeyECDH{PrivKey: privKey}
+	expected, _ := localECDH.ECDH(remoteKey.PubKey())
+	if shared != expected {
+		t.Fatalf("expected shared key %x, got %x", expected, shared)
+	}
+
+	var digest [32]byte
+	copy(digest[:], []byte("external signer test digest"))
+
+	sig, err := client.SignDigest(digest)
+	if err != nil {
+		t.Fatalf("unable to sign digest: %v", err)
+	}
+	if !sig.Verify(digest[:], privKey.PubKey()) {
+		t.Fatalf("signature doesn't verify")
+	}
+
+	compactSig, err := client.SignDigestCompact(digest)
+	if err != nil {
+		t.Fatalf("unable to sign digest: %v", err)
+	}
+	pubKey, _, err := ecdsa.RecoverCompact(compactSig, digest[:])
+	if err != nil {
+		t.Fatalf("unable to recover key: %v", err)
+	}
+	if !pubKey.IsEqual(privKey.PubKey()) {
+		t.Fatalf("compact signature recovers another key")
+	}
+
+	// Once closed, the client refuses further requests.
+	client.Close()
+	if _, err := client.ECDH(remoteKey.PubKey()); err != ErrClientClosed {
+		t.Fatalf("expected ErrClientClosed, got %v", err)
+	}
+}
+
+// TestClientReconnect asserts the client reconnects after the connection to
+// the device breaks, and refuses to use a device holding another key.
+func TestClientReconnect(t *testing.T) {
+	t.Parallel()
+
+	privKey := newTestKey(t)
+	device := newMockDevice(t, privKey)
+	defer device.stop()
+
+	client, err := Connect(Config{
+		Addr:    device.listener.Addr(),
+		Timeout: time.Second * 5,
+	})
+	if err != nil {
+		t.Fatalf("unable to connect: %v", err)
+	}
+	defer client.Close()
+
+	// Break the connection from the device side. The first request fails
+	// and the next one reconnects.
+	conn := <-device.conns
+	conn.Close()
+
+	var digest [32]byte
+	if _, err := client.SignDigest(digest); err == nil {
+		t.Fatalf("expected request over broken connection to fail")
+	}
+	if _, err := client.SignDigest(digest); err != nil {
+		t.Fatalf("unable to sign after reconnecting: %v", err)
+	}
+
+	// Swap the key of the device. The client must notice the change when
+	// reconnecting.
+	conn = <-device.conns
+	device.privKey = newTestKey(t)
+	conn.Close()
+
+	client.SignDigest(digest)
+	if _, err := client.SignDigest(digest); err != ErrPubKeyMismatch {
+		t.Fatalf("expected ErrPubKeyMismatch, got %v", err)
+	}
+}
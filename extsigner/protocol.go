@@ -0,0 +1,53 @@
+package extsigner
+
+const (
+	// MethodPubKey is the method that returns the serialized compressed
+	// public key of the identity key held by the device. It takes no
+	// parameters.
+	MethodPubKey = "pubkey"
+
+	// MethodECDH is the method that performs an ECDH operation between
+	// the identity key and the public key given as parameter. The result
+	// is the sha256 of the shared point serialized in compressed format.
+	MethodECDH = "ecdh"
+
+	// MethodSignDigest is the method that signs the digest given as
+	// parameter with the identity key. The result is the DER encoded
+	// signature.
+	MethodSignDigest = "signdigest"
+
+	// MethodSignDigestCompact is the method that signs the digest given as
+	// parameter with the identity key. The result is the signature in the
+	// compact, public key recoverable format.
+	MethodSignDigestCompact = "signdigestcompact"
+)
+
+// Request is a single request sent to the external signer. Requests are
+// encoded as JSON objects, one per line.
+type Request struct {
+	// ID identifies the request. The response to the request carries the
+	// same ID.
+	ID uint64 `json:"id"`
+
+	// Method is the operation the device should perform.
+	Method string `json:"method"`
+
+	// Param is the hex encoded parameter of the operation, if any. This is
+	// the serialized compressed public key of the remote party for ECDH
+	// and the 32 byte digest for the signing methods.
+	Param string `json:"param,omitempty"`
+}
+
+// Response is the response of the external signer to a single request.
+// Responses are encoded as JSON objects, one per line.
+type Response struct {
+	// ID is the ID of the request this is a response to.
+	ID uint64 `json:"id"`
+
+	// Result is the hex encoded result of the operation. It is empty if
+	// the operation failed.
+	Result string `json:"result,omitempty"`
+
+	// Error describes why the operation failed, if it did.
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,103 @@
+package feature
+
+import (
+	"sort"
+
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// Compatibility describes how a single feature, identified by its pair of
+// required and optional feature bits, is supported by the local and remote
+// nodes.
+type Compatibility struct {
+	// Bit is the optional (odd) bit of the feature pair.
+	Bit lnwire.FeatureBit
+
+	// Name is the human readable name of the feature, or "unknown" if the
+	// feature isn't known to us.
+	Name string
+
+	// LocalSupport is true if we advertise either bit of the feature.
+	LocalSupport bool
+
+	// RemoteSupport is true if the remote node advertises either bit of
+	// the feature.
+	RemoteSupport bool
+
+	// LocalRequired is true if we advertise the required bit of the
+	// feature.
+	LocalRequired bool
+
+	// RemoteRequired is true if the remote node advertises the required
+	// bit of the feature.
+	RemoteRequired bool
+}
+
+// Negotiated returns true if the feature is supported by both nodes, meaning
+// it will be active on a connection or channel between them.
+func (c *Compatibility) Negotiated() bool {
+	return c.LocalSupport && c.RemoteSupport
+}
+
+// Incompatible returns true if one of the nodes requires the feature while the
+// other one doesn't support it, which prevents the nodes from connecting.
+func (c *Compatibility) Incompatible() bool {
+	return (c.LocalRequired && !c.RemoteSupport) ||
+		(c.RemoteRequired && !c.LocalSupport)
+}
+
+// Compare diffs the local and remote feature vectors, returning the
+// compatibility of every feature advertised by either node sorted by feature
+// bit.
+func Compare(local, remote *lnwire.FeatureVector) []Compatibility {
+	compat := make(map[lnwire.FeatureBit]*Compatibility)
+	entry := func(bit lnwire.FeatureBit) *Compatibility {
+		optional := bit | 1
+		c, ok := compat[optional]
+		if !ok {
+			c = &Compatibility{
+				Bit:  optional,
+				Name: featureName(local, remote, optional),
+			}
+			compat[optional] = c
+		}
+		return c
+	}
+
+	for bit := range local.Features() {
+		c := entry(bit)
+		c.LocalSupport = true
+		c.LocalRequired = c.LocalRequired || bit.IsRequired()
+	}
+	for bit := range remote.Features() {
+		c := entry(bit)
+		c.RemoteSupport = true
+		c.RemoteRequired = c.RemoteRequired || bit.IsRequired()
+	}
+
+	report := make([]Compatibility, 0, len(compat))
+	for _, c := range compat {
+		report = append(report, *c)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Bit < report[j].Bit
+	})
+
+	return report
+}
+
+// featureName returns the name of the feature pair the given bit belongs to,
+// looking it up in both feature vectors.
+func featureName(local, remote *lnwire.FeatureVector,
+	bit lnwire.FeatureBit) string {
+
+	for _, fv := range []*lnwire.FeatureVector{local, remote} {
+		for _, b := range []lnwire.FeatureBit{bit, bit ^ 1} {
+			if fv.IsKnown(b) {
+				return fv.Name(b)
+			}
+		}
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,70 @@
+package feature
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCompare asserts that diffing two feature vectors correctly reports which
+// features are negotiated and which ones are incompatible.
+func TestCompare(t *testing.T) {
+	local := lnwire.NewFeatureVector(lnwire.NewRawFeatureVector(
+		lnwire.DataLossProtectRequired,
+		lnwire.StaticRemoteKeyOptional,
+		lnwire.UpfrontShutdownScriptOptional,
+	), lnwire.Features)
+	remote := lnwire.NewFeatureVector(lnwire.NewRawFeatureVector(
+		lnwire.DataLossProtectOptional,
+		lnwire.StaticRemoteKeyOptional,
+		lnwire.AnchorsRequired,
+		lnwire.FeatureBit(101),
+	), lnwire.Features)
+
+	report := Compare(local, remote)
+	require.Equal(t, []Compatibility{
+		{
+			Bit:            lnwire.DataLossProtectOptional,
+			Name:           "data-loss-protect",
+			LocalSupport:   true,
+			RemoteSupport:  true,
+			LocalRequired:  true,
+			RemoteRequired: false,
+		},
+		{
+			Bit:          lnwire.UpfrontShutdownScriptOptional,
+			Name:         "upfront-shutdown-script",
+			LocalSupport: true,
+		},
+		{
+			Bit:           lnwire.StaticRemoteKeyOptional,
+			Name:          "static-remote-key",
+			LocalSupport:  true,
+			RemoteSupport: true,
+		},
+		{
+			Bit:            lnwire.AnchorsOptional,
+			Name:           "anchor-commitments",
+			RemoteSupport:  true,
+			RemoteRequired: true,
+		},
+		{
+			Bit:           lnwire.FeatureBit(101),
+			Name:          "unknown",
+			RemoteSupport: true,
+		},
+	}, report)
+
+	// Data loss protection and static remote keys are supported by both
+	// nodes, while the remote node requires anchors which we don't
+	// support.
+	require.True(t, report[0].Negotiated())
+	require.False(t, report[0].Incompatible())
+	require.False(t, report[1].Negotiated())
+	require.False(t, report[1].Incompatible())
+	require.True(t, report[2].Negotiated())
+	require.False(t, report[3].Negotiated())
+	require.True(t, report[3].Incompatible())
+	require.False(t, report[4].Incompatible())
+}
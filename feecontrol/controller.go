@@ -0,0 +1,413 @@
+// Package feecontrol implements an optional controller that periodically
+// adjusts the forwarding fee rate of each of our channels based on how its
+// funds are split between us and the remote party and on the payment flow
+// observed through it.
+//
+// Channels whose funds are mostly on our side are cheap to route through,
+// since we want payments to drain them, while channels whose funds are mostly
+// on the remote side become progressively more expensive to preserve the
+// little outbound liquidity that is left. Recent net outgoing flow further
+// raises the fee rate while net incoming flow lowers it.
+package feecontrol
+
+import (
+	"errors"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/subscribe"
+	"github.com/decred/dcrlnd/ticker"
+)
+
+const (
+	// forwardingEventsBatch is the number of forwarding events fetched at
+	// once when computing the payment flow through our channels.
+	forwardingEventsBatch = 10000
+)
+
+var (
+	// ErrControllerShuttingDown is returned when the controller cannot
+	// complete a request because it is shutting down.
+	ErrControllerShuttingDown = errors.New("fee controller shutting down")
+)
+
+// Curve describes how a channel's liquidity skew and recent payment flow are
+// mapped to a proportional fee rate.
+type Curve struct {
+	// MinFeeRate is the fee rate (in millionths) set on a channel whose
+	// funds are entirely on our side.
+	MinFeeRate uint32
+
+	// MaxFeeRate is the fee rate (in millionths) set on a channel whose
+	// funds are entirely on the remote side.
+	MaxFeeRate uint32
+
+	// Exponent shapes the curve between MinFeeRate and MaxFeeRate. A value
+	// of 1 results in a linear curve, while larger values keep the fee
+	// rate low until the local balance is nearly depleted.
+	Exponent float64
+
+	// FlowWeight is the maximum fraction by which the net payment flow
+	// through a channel shifts its fee rate.
+	FlowWeight float64
+}
+
+// FeeRate returns the fee rate for a channel that holds localRatio of its
+// funds on our side and saw the given net outgoing flow, which ranges from -1
+// (only incoming payments) to 1 (only outgoing payments). The returned fee
+// rate is always within the bounds of the curve.
+func (c *Curve) FeeRate(localRatio, netOutFlow float64) uint32 {
+	localRatio = math.Max(0, math.Min(1, localRatio))
+	netOutFlow = math.Max(-1, math.Min(1, netOutFlow))
+
+	minRate := float64(c.MinFeeRate)
+	maxRate := float64(c.MaxFeeRate)
+
+	depletion := math.Pow(1-localRatio, c.Exponent)
+	rate := minRate + (maxRate-minRate)*depletion
+	rate *= 1 + c.FlowWeight*netOutFlow
+
+	rate = math.Max(minRate, math.Min(maxRate, math.Round(rate)))
+
+	return uint32(rate)
+}
+
+// FeeAdjustment is the event sent to subscribers every time the controller
+// changes the fee rate of a channel.
+type FeeAdjustment struct {
+	// ChanPoint is the funding outpoint of the adjusted channel.
+	ChanPoint wire.OutPoint
+
+	// ShortChanID is the short channel id of the adjusted channel.
+	ShortChanID lnwire.ShortChannelID
+
+	// OldFeeRate is the fee rate (in millionths) that was in effect
+	// before the adjustment.
+	OldFeeRate uint32
+
+	// NewFeeRate is the fee rate (in millionths) now in effect.
+	NewFeeRate uint32
+
+	// LocalRatio is the fraction of the channel's funds held on our side
+	// when the adjustment was computed.
+	LocalRatio float64
+
+	// OutgoingFlow is the amount forwarded out through the channel during
+	// the last interval.
+	OutgoingFlow lnwire.MilliAtom
+
+	// IncomingFlow is the amount forwarded in through the channel during
+	// the last interval.
+	IncomingFlow lnwire.MilliAtom
+
+	// Timestamp is the time at which the adjustment was applied.
+	Timestamp time.Time
+}
+
+// Config houses all the items required by the fee controller. All elements
+// of the config must be non-nil for the controller to operate.
+type Config struct {
+	// Curve maps the state of a channel to its target fee rate.
+	Curve Curve
+
+	// Interval is the period of time over which the payment flow is
+	// measured. It should match the interval of the Ticker.
+	Interval time.Duration
+
+	// MinChange is the minimum relative change between the current and
+	// the target fee rate of a channel for a new policy to be applied.
+	MinChange float64
+
+	// Ticker signals the controller to start a new adjustment round.
+	Ticker ticker.Ticker
+
+	// Clock is used to determine the time window of the payment flow.
+	Clock clock.Clock
+
+	// FetchAllOpenChannels returns all of our currently open channels.
+	FetchAllOpenChannels func() ([]*channeldb.OpenChannel, error)
+
+	// ForAllOutgoingChannels is used to retrieve the current policy of all
+	// of our channels.
+	ForAllOutgoingChannels func(cb func(*channeldb.ChannelEdgeInfo,
+		*channeldb.ChannelEdgePolicy) error) error
+
+	// QueryForwardingLog is used to retrieve the forwarding events that
+	// make up the payment flow through our channels.
+	QueryForwardingLog func(q channeldb.ForwardingEventQuery) (
+		channeldb.ForwardingLogTimeSlice, error)
+
+	// UpdatePolicy applies a new policy to the specified channels.
+	UpdatePolicy func(policy routing.ChannelPolicy,
+		chanPoints ...wire.OutPoint) error
+}
+
+// Controller periodically adjusts the fee rate of our channels according to
+// the configured curve and notifies subscribers of every adjustment.
+type Controller struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *Config
+
+	ntfnServer *subscribe.Server
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a new fee controller from the given config. Start must be
+// called before any adjustment is made.
+func New(cfg *Config) *Controller {
+	return &Controller{
+		cfg:        cfg,
+		ntfnServer: subscribe.NewServer(),
+		quit:       make(chan struct{}),
+	}
+}
+
+// Start starts the controller's subscription server and its main loop.
+func (c *Controller) Start() error {
+	var err error
+
+	c.started.Do(func() {
+		log.Info("Fee controller starting")
+
+		if err = c.ntfnServer.Start(); err != nil {
+			return
+		}
+
+		c.cfg.Ticker.Resume()
+
+		c.wg.Add(1)
+		go c.controlLoop()
+	})
+
+	return err
+}
+
+// Stop signals the controller for a graceful shutdown.
+func (c *Controller) Stop() {
+	c.stopped.Do(func() {
+		log.Info("Fee controller shutting down")
+
+		close(c.quit)
+		c.wg.Wait()
+
+		c.cfg.Ticker.Stop()
+		c.ntfnServer.Stop()
+	})
+}
+
+// SubscribeFeeAdjustments returns a subscribe.Client that will receive a
+// FeeAdjustment every time the fee rate of a channel is changed.
+func (c *Controller) SubscribeFeeAdjustments() (*subscribe.Client, error) {
+	return c.ntfnServer.Subscribe()
+}
+
+// controlLoop runs an adjustment round every time the ticker fires.
+//
+// NOTE: This MUST be run as a goroutine.
+func (c *Controller) controlLoop() {
+	defer c.wg.Done()
+
+	for {
+		select {
+		case <-c.cfg.Ticker.Ticks():
+			if err := c.adjustFees(); err != nil {
+				log.Errorf("Unable to adjust channel fees: %v",
+					err)
+			}
+
+		case <-c.quit:
+			return
+		}
+	}
+}
+
+// channelFlow tracks the amounts forwarded through a channel.
+type channelFlow struct {
+	incoming lnwire.MilliAtom
+	outgoing lnwire.MilliAtom
+}
+
+// netOutFlow returns the net outgoing flow of the channel, normalized to the
+// range [-1, 1].
+func (f *channelFlow) netOutFlow() float64 {
+	total := f.incoming + f.outgoing
+	if total == 0 {
+		return 0
+	}
+
+	return (float64(f.outgoing) - float64(f.incoming)) / float64(total)
+}
+
+// fetchFlows sums up the amounts forwarded through each of our channels during
+// the last interval.
+func (c *Controller) fetchFlows() (map[lnwire.ShortChannelID]*channelFlow,
+	error) {
+
+	now := c.cfg.Clock.Now()
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    now.Add(-c.cfg.Interval),
+		EndTime:      now,
+		NumMaxEvents: forwardingEventsBatch,
+	}
+
+	flows := make(map[lnwire.ShortChannelID]*channelFlow)
+	flow := func(chanID lnwire.ShortChannelID) *channelFlow {
+		f, ok := flows[chanID]
+		if !ok {
+			f = &channelFlow{}
+			flows[chanID] = f
+		}
+		return f
+	}
+
+	for {
+		timeSlice, err := c.cfg.QueryForwardingLog(query)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, event := range timeSlice.ForwardingEvents {
+			flow(event.IncomingChanID).incoming += event.AmtIn
+			flow(event.OutgoingChanID).outgoing += event.AmtOut
+		}
+
+		if len(timeSlice.ForwardingEvents) < forwardingEventsBatch {
+			return flows, nil
+		}
+		query.IndexOffset = timeSlice.LastIndexOffset
+	}
+}
+
+// adjustFees runs a single adjustment round over all of our open channels.
+func (c *Controller) adjustFees() error {
+	channels, err := c.cfg.FetchAllOpenChannels()
+	if err != nil {
+		return err
+	}
+
+	flows, err := c.fetchFlows()
+	if err != nil {
+		return err
+	}
+
+	// Gather the current policy of each of our channels, as only the fee
+	// rate is modified by the controller.
+	policies := make(map[wire.OutPoint]*channeldb.ChannelEdgePolicy)
+	err = c.cfg.ForAllOutgoingChannels(func(info *channeldb.ChannelEdgeInfo,
+		edge *channeldb.ChannelEdgePolicy) error {
+
+		if edge != nil {
+			policies[info.ChannelPoint] = edge
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		select {
+		case <-c.quit:
+			return ErrControllerShuttingDown
+		default:
+		}
+
+		edge, ok := policies[channel.FundingOutpoint]
+		if !ok {
+			log.Debugf("Skipping channel %v without policy",
+				channel.FundingOutpoint)
+			continue
+		}
+
+		c.adjustChannelFee(channel, edge, flows[channel.ShortChanID()])
+	}
+
+	return nil
+}
+
+// adjustChannelFee computes the target fee rate of a single channel and
+// applies it if it differs enough from the current one.
+func (c *Controller) adjustChannelFee(channel *channeldb.OpenChannel,
+	edge *channeldb.ChannelEdgePolicy, flow *channelFlow) {
+
+	localBalance := channel.LocalCommitment.LocalBalance
+	remoteBalance := channel.LocalCommitment.RemoteBalance
+	if localBalance+remoteBalance == 0 {
+		return
+	}
+	localRatio := float64(localBalance) /
+		float64(localBalance+remoteBalance)
+
+	if flow == nil {
+		flow = &channelFlow{}
+	}
+
+	oldFeeRate := uint32(edge.FeeProportionalMillionths)
+	newFeeRate := c.cfg.Curve.FeeRate(localRatio, flow.netOutFlow())
+	if !exceedsMinChange(oldFeeRate, newFeeRate, c.cfg.MinChange) {
+		return
+	}
+
+	minHTLC := edge.MinHTLC
+	policy := routing.ChannelPolicy{
+		FeeSchema: routing.FeeSchema{
+			BaseFee: edge.FeeBaseMAtoms,
+			FeeRate: newFeeRate,
+		},
+		TimeLockDelta: uint32(edge.TimeLockDelta),
+		MaxHTLC:       edge.MaxHTLC,
+		MinHTLC:       &minHTLC,
+	}
+
+	chanPoint := channel.FundingOutpoint
+	if err := c.cfg.UpdatePolicy(policy, chanPoint); err != nil {
+		log.Errorf("Unable to update fee rate of channel %v: %v",
+			chanPoint, err)
+		return
+	}
+
+	log.Infof("Adjusted fee rate of channel %v from %v to %v "+
+		"(local_ratio=%.3f, out=%v, in=%v)", chanPoint, oldFeeRate,
+		newFeeRate, localRatio, flow.outgoing, flow.incoming)
+
+	event := FeeAdjustment{
+		ChanPoint:    chanPoint,
+		ShortChanID:  channel.ShortChanID(),
+		OldFeeRate:   oldFeeRate,
+		NewFeeRate:   newFeeRate,
+		LocalRatio:   localRatio,
+		OutgoingFlow: flow.outgoing,
+		IncomingFlow: flow.incoming,
+		Timestamp:    c.cfg.Clock.Now(),
+	}
+	if err := c.ntfnServer.SendUpdate(event); err != nil {
+		log.Warnf("Unable to send fee adjustment update: %v", err)
+	}
+}
+
+// exceedsMinChange returns true if the relative difference between the old
+// and new fee rates is at least minChange.
+func exceedsMinChange(oldFeeRate, newFeeRate uint32, minChange float64) bool {
+	switch {
+	case oldFeeRate == newFeeRate:
+		return false
+
+	case oldFeeRate == 0:
+		return true
+	}
+
+	change := math.Abs(float64(newFeeRate)-float64(oldFeeRate)) /
+		float64(oldFeeRate)
+
+	return change >= minChange
+}
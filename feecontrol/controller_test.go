@@ -0,0 +1,228 @@
+package feecontrol
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/ticker"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCurveFeeRate asserts that the fee rate curve responds to the liquidity
+// skew and payment flow of a channel while staying within its bounds.
+func TestCurveFeeRate(t *testing.T) {
+	curve := Curve{
+		MinFeeRate: 10,
+		MaxFeeRate: 1010,
+		Exponent:   1,
+		FlowWeight: 0.5,
+	}
+
+	tests := []struct {
+		name       string
+		localRatio float64
+		netOutFlow float64
+		feeRate    uint32
+	}{
+		{
+			name:       "all local",
+			localRatio: 1,
+			feeRate:    10,
+		},
+		{
+			name:       "all remote",
+			localRatio: 0,
+			feeRate:    1010,
+		},
+		{
+			name:       "balanced",
+			localRatio: 0.5,
+			feeRate:    510,
+		},
+		{
+			name:       "balanced outgoing flow",
+			localRatio: 0.5,
+			netOutFlow: 1,
+			feeRate:    765,
+		},
+		{
+			name:       "balanced incoming flow",
+			localRatio: 0.5,
+			netOutFlow: -1,
+			feeRate:    255,
+		},
+		{
+			name:       "clamped to max",
+			localRatio: 0,
+			netOutFlow: 1,
+			feeRate:    1010,
+		},
+		{
+			name:       "clamped to min",
+			localRatio: 1,
+			netOutFlow: -1,
+			feeRate:    10,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			feeRate := curve.FeeRate(test.localRatio, test.netOutFlow)
+			require.Equal(t, test.feeRate, feeRate)
+		})
+	}
+
+	// A steeper curve should keep the fee rate lower while the channel
+	// still holds some local balance.
+	curve.Exponent = 2
+	require.Equal(t, uint32(260), curve.FeeRate(0.5, 0))
+}
+
+// TestExceedsMinChange asserts that fee rate changes are only applied once
+// they exceed the minimum relative change.
+func TestExceedsMinChange(t *testing.T) {
+	require.False(t, exceedsMinChange(100, 100, 0))
+	require.False(t, exceedsMinChange(100, 109, 0.1))
+	require.True(t, exceedsMinChange(100, 110, 0.1))
+	require.True(t, exceedsMinChange(100, 90, 0.1))
+	require.True(t, exceedsMinChange(0, 1, 0.1))
+}
+
+// TestControllerAdjustsFees asserts that a tick of the controller results in
+// the fee rate of skewed channels being updated and an adjustment being
+// delivered to subscribers.
+func TestControllerAdjustsFees(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+
+	// The first channel has all its funds on the remote side, so its fee
+	// rate should be raised. The second one only moved slightly from its
+	// current fee rate, so it should be left untouched.
+	depleted := &channeldb.OpenChannel{
+		FundingOutpoint: wire.OutPoint{Index: 1},
+		ShortChannelID:  lnwire.NewShortChanIDFromInt(1),
+	}
+	depleted.LocalCommitment.RemoteBalance = 1000
+
+	balanced := &channeldb.OpenChannel{
+		FundingOutpoint: wire.OutPoint{Index: 2},
+		ShortChannelID:  lnwire.NewShortChanIDFromInt(2),
+	}
+	balanced.LocalCommitment.LocalBalance = 500
+	balanced.LocalCommitment.RemoteBalance = 500
+
+	policies := map[wire.OutPoint]*channeldb.ChannelEdgePolicy{
+		depleted.FundingOutpoint: {
+			FeeBaseMAtoms:             1000,
+			FeeProportionalMillionths: 100,
+			TimeLockDelta:             80,
+			MinHTLC:                   1,
+			MaxHTLC:                   100000,
+		},
+		balanced.FundingOutpoint: {
+			FeeProportionalMillionths: 500,
+		},
+	}
+
+	updates := make(chan routing.ChannelPolicy, 2)
+	updatedChans := make(chan wire.OutPoint, 2)
+
+	forceTicker := ticker.NewForce(time.Hour)
+	controller := New(&Config{
+		Curve: Curve{
+			MinFeeRate: 10,
+			MaxFeeRate: 1010,
+			Exponent:   1,
+		},
+		Interval:  time.Hour,
+		MinChange: 0.1,
+		Ticker:    forceTicker,
+		Clock:     clock.NewTestClock(now),
+		FetchAllOpenChannels: func() ([]*channeldb.OpenChannel, error) {
+			return []*channeldb.OpenChannel{depleted, balanced}, nil
+		},
+		ForAllOutgoingChannels: func(cb func(*channeldb.ChannelEdgeInfo,
+			*channeldb.ChannelEdgePolicy) error) error {
+
+			for chanPoint, policy := range policies {
+				info := &channeldb.ChannelEdgeInfo{
+					ChannelPoint: chanPoint,
+				}
+				if err := cb(info, policy); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		QueryForwardingLog: func(q channeldb.ForwardingEventQuery) (
+			channeldb.ForwardingLogTimeSlice, error) {
+
+			require.Equal(t, now.Add(-time.Hour), q.StartTime)
+			require.Equal(t, now, q.EndTime)
+
+			return channeldb.ForwardingLogTimeSlice{
+				ForwardingEvents: []channeldb.ForwardingEvent{{
+					IncomingChanID: balanced.ShortChannelID,
+					OutgoingChanID: depleted.ShortChannelID,
+					AmtIn:          110,
+					AmtOut:         100,
+				}},
+			}, nil
+		},
+		UpdatePolicy: func(policy routing.ChannelPolicy,
+			chanPoints ...wire.OutPoint) error {
+
+			updates <- policy
+			updatedChans <- chanPoints[0]
+			return nil
+		},
+	})
+	require.NoError(t, controller.Start())
+	defer controller.Stop()
+
+	sub, err := controller.SubscribeFeeAdjustments()
+	require.NoError(t, err)
+	defer sub.Cancel()
+
+	forceTicker.Force <- now
+
+	select {
+	case policy := <-updates:
+		require.Equal(t, uint32(1010), policy.FeeRate)
+		require.Equal(t, lnwire.MilliAtom(1000), policy.BaseFee)
+		require.Equal(t, uint32(80), policy.TimeLockDelta)
+		require.Equal(t, lnwire.MilliAtom(100000), policy.MaxHTLC)
+		require.Equal(t, lnwire.MilliAtom(1), *policy.MinHTLC)
+		require.Equal(t, depleted.FundingOutpoint, <-updatedChans)
+
+	case <-time.After(time.Second):
+		t.Fatalf("policy not updated")
+	}
+
+	select {
+	case e := <-sub.Updates():
+		event := e.(FeeAdjustment)
+		require.Equal(t, depleted.FundingOutpoint, event.ChanPoint)
+		require.Equal(t, uint32(100), event.OldFeeRate)
+		require.Equal(t, uint32(1010), event.NewFeeRate)
+		require.Equal(t, lnwire.MilliAtom(100), event.OutgoingFlow)
+		require.Equal(t, lnwire.MilliAtom(0), event.IncomingFlow)
+
+	case <-time.After(time.Second):
+		t.Fatalf("fee adjustment not received")
+	}
+
+	// The balanced channel's target fee rate is within the minimum change
+	// of its current one, so no further update is expected.
+	select {
+	case <-updates:
+		t.Fatalf("unexpected policy update")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
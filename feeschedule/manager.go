@@ -0,0 +1,659 @@
+// Package feeschedule implements a manager that applies operator defined fee
+// schedules to our channels as their balance changes.
+//
+// A fee schedule is a list of tiers, each setting the fees of channels whose
+// local balance is above a given percentage of their funds. This allows, for
+// example, raising the fees of a channel whose local balance drops below 20%
+// and lowering them once it rises above 80%. Schedules are set either for a
+// specific channel or as the default of all channels without their own, and
+// are persisted so they survive restarts.
+//
+// Channels are re-evaluated once their balance changed, as signaled by settled
+// htlcs and newly opened channels. Evaluations are batched so bursts of
+// payments only result in a single policy update. A channel only moves to
+// another tier once its balance is past the bounds of its current tier by a
+// few percentage points, so that the fees of a channel whose balance hovers
+// around a bound don't flap. The schedules of closed channels are removed.
+package feeschedule
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/subscribe"
+	"github.com/decred/dcrlnd/ticker"
+)
+
+const (
+	// DefaultEvaluationInterval is the default interval at which channels
+	// whose balance changed are evaluated.
+	DefaultEvaluationInterval = time.Minute
+
+	// MaxTiers is the maximum number of tiers of a fee schedule.
+	MaxTiers = 100
+
+	// DefaultHysteresis is the default number of percentage points by
+	// which the local balance of a channel must be past the bounds of its
+	// current tier before the fees of another tier are applied.
+	DefaultHysteresis = 5
+
+	// MaxFeeRate is the largest fee rate of a tier, in millionths of the
+	// forwarded amount, which charges the whole amount again as fee.
+	MaxFeeRate = 1000000
+)
+
+var (
+	// ErrNoTiers is returned when setting a fee schedule without tiers.
+	ErrNoTiers = errors.New("fee schedule has no tiers")
+
+	// ErrTooManyTiers is returned when setting a fee schedule with more
+	// than MaxTiers tiers.
+	ErrTooManyTiers = fmt.Errorf("fee schedule has more than %v tiers",
+		MaxTiers)
+)
+
+// Validate checks that the tiers of a fee schedule are sorted by strictly
+// increasing local balance percentage, which must not exceed 100, and that
+// their fee rate doesn't exceed MaxFeeRate.
+func Validate(schedule *channeldb.FeeSchedule) error {
+	switch {
+	case len(schedule.Tiers) == 0:
+		return ErrNoTiers
+
+	case len(schedule.Tiers) > MaxTiers:
+		return ErrTooManyTiers
+	}
+
+	for i, tier := range schedule.Tiers {
+		if tier.MinLocalPercent > 100 {
+			return fmt.Errorf("tier %d: local balance percentage %v "+
+				"above 100", i, tier.MinLocalPercent)
+		}
+
+		if tier.FeeRate > MaxFeeRate {
+			return fmt.Errorf("tier %d: fee rate %v above %v", i,
+				tier.FeeRate, MaxFeeRate)
+		}
+
+		if i > 0 && tier.MinLocalPercent <=
+			schedule.Tiers[i-1].MinLocalPercent {
+
+			return fmt.Errorf("tier %d: local balance percentage %v "+
+				"not above the one of the previous tier", i,
+				tier.MinLocalPercent)
+		}
+	}
+
+	return nil
+}
+
+// TierFor returns the tier of the schedule that applies to a channel holding
+// localPercent of its funds on our side, which is the last tier whose minimum
+// percentage is reached. Nil is returned if the balance is below the first
+// tier.
+func TierFor(schedule *channeldb.FeeSchedule,
+	localPercent float64) *channeldb.FeeScheduleTier {
+
+	var match *channeldb.FeeScheduleTier
+	for i := range schedule.Tiers {
+		tier := &schedule.Tiers[i]
+		if localPercent < float64(tier.MinLocalPercent) {
+			break
+		}
+		match = tier
+	}
+
+	return match
+}
+
+// currentTier returns the index of the first tier of the schedule whose fees
+// match the given policy, or -1 if there is none.
+func currentTier(schedule *channeldb.FeeSchedule,
+	edge *channeldb.ChannelEdgePolicy) int {
+
+	for i, tier := range schedule.Tiers {
+		if tier.BaseFee == edge.FeeBaseMAtoms &&
+			lnwire.MilliAtom(tier.FeeRate) ==
+				edge.FeeProportionalMillionths {
+
+			return i
+		}
+	}
+
+	return -1
+}
+
+// holdsTier returns whether a channel charging the fees of the tier at the
+// given index keeps them at the given local balance percentage, which is the
+// case as long as the balance is within the bounds of the tier widened by the
+// hysteresis. The hysteresis is clamped to half the smallest gap between the
+// tiers of the schedule, so that a channel never keeps the fees of a tier once
+// its balance is past the middle of a neighbouring tier.
+func holdsTier(schedule *channeldb.FeeSchedule, current int,
+	localPercent, hysteresis float64) bool {
+
+	for i := 1; i < len(schedule.Tiers); i++ {
+		gap := float64(schedule.Tiers[i].MinLocalPercent -
+			schedule.Tiers[i-1].MinLocalPercent)
+		if gap/2 < hysteresis {
+			hysteresis = gap / 2
+		}
+	}
+
+	lower := float64(schedule.Tiers[current].MinLocalPercent) - hysteresis
+	if localPercent < lower {
+		return false
+	}
+
+	if current+1 < len(schedule.Tiers) {
+		upper := float64(schedule.Tiers[current+1].MinLocalPercent) +
+			hysteresis
+		if localPercent >= upper {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Config houses all the items required by the fee schedule manager.
+type Config struct {
+	// Ticker batches the evaluation of channels whose balance changed.
+	Ticker ticker.Ticker
+
+	// Hysteresis is the number of percentage points by which the local
+	// balance of a channel must be past the bounds of the tier whose fees
+	// it charges before the fees of another tier are applied.
+	Hysteresis float64
+
+	// FetchFeeSchedules returns the persisted default fee schedule and
+	// per channel fee schedules.
+	FetchFeeSchedules func() (*channeldb.FeeSchedule,
+		map[wire.OutPoint]*channeldb.FeeSchedule, error)
+
+	// PutFeeSchedule persists the fee schedule of a channel, or the
+	// default one if chanPoint is nil.
+	PutFeeSchedule func(chanPoint *wire.OutPoint,
+		schedule *channeldb.FeeSchedule) error
+
+	// DeleteFeeSchedule removes the fee schedule of a channel, or the
+	// default one if chanPoint is nil.
+	DeleteFeeSchedule func(chanPoint *wire.OutPoint) error
+
+	// FetchClosedChannel returns the close summary of a channel, or
+	// channeldb.ErrClosedChannelNotFound if it isn't closed. It is used to
+	// remove the schedules of channels closed while we were offline.
+	FetchClosedChannel func(chanPoint *wire.OutPoint) (
+		*channeldb.ChannelCloseSummary, error)
+
+	// FetchAllOpenChannels returns all of our currently open channels.
+	FetchAllOpenChannels func() ([]*channeldb.OpenChannel, error)
+
+	// ForAllOutgoingChannels is used to retrieve the current policy of all
+	// of our channels.
+	ForAllOutgoingChannels func(cb func(*channeldb.ChannelEdgeInfo,
+		*channeldb.ChannelEdgePolicy) error) error
+
+	// UpdatePolicy applies a new policy to the specified channels.
+	UpdatePolicy func(policy routing.ChannelPolicy,
+		chanPoints ...wire.OutPoint) error
+
+	// SubscribeHtlcEvents is used to be notified of settled htlcs, which
+	// change the balance of our channels.
+	SubscribeHtlcEvents func() (*subscribe.Client, error)
+
+	// SubscribeChannelEvents is used to be notified of newly opened and
+	// closed channels.
+	SubscribeChannelEvents func() (*subscribe.Client, error)
+}
+
+// Manager applies the configured fee schedules to our channels whenever their
+// balance changes.
+type Manager struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *Config
+
+	// mtx guards the fields below.
+	mtx sync.Mutex
+
+	// defaultSchedule is the schedule of channels without their own. It
+	// is nil if there is none.
+	defaultSchedule *channeldb.FeeSchedule
+
+	// chanSchedules are the schedules of specific channels.
+	chanSchedules map[wire.OutPoint]*channeldb.FeeSchedule
+
+	// dirtyChans is the set of channels whose balance changed since the
+	// last evaluation.
+	dirtyChans map[lnwire.ShortChannelID]struct{}
+
+	// evaluateAll is set when all channels must be evaluated, such as
+	// after a schedule changed.
+	evaluateAll bool
+
+	// evaluateNow signals the manager to evaluate channels without
+	// waiting for the next tick.
+	evaluateNow chan struct{}
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a new fee schedule manager from the given config.
+func New(cfg *Config) *Manager {
+	return &Manager{
+		cfg:           cfg,
+		chanSchedules: make(map[wire.OutPoint]*channeldb.FeeSchedule),
+		dirtyChans:    make(map[lnwire.ShortChannelID]struct{}),
+		evaluateNow:   make(chan struct{}, 1),
+		quit:          make(chan struct{}),
+	}
+}
+
+// Start loads the persisted fee schedules and starts evaluating channels. All
+// channels are evaluated on start, as their balance may have changed while we
+// were offline.
+func (m *Manager) Start() error {
+	var err error
+
+	m.started.Do(func() {
+		log.Info("Fee schedule manager starting")
+
+		var (
+			defaultSchedule *channeldb.FeeSchedule
+			chanSchedules   map[wire.OutPoint]*channeldb.FeeSchedule
+		)
+		defaultSchedule, chanSchedules, err = m.cfg.FetchFeeSchedules()
+		if err != nil {
+			return
+		}
+		if err = m.pruneClosedSchedules(chanSchedules); err != nil {
+			return
+		}
+
+		var htlcSub, chanSub *subscribe.Client
+		htlcSub, err = m.cfg.SubscribeHtlcEvents()
+		if err != nil {
+			return
+		}
+		chanSub, err = m.cfg.SubscribeChannelEvents()
+		if err != nil {
+			htlcSub.Cancel()
+			return
+		}
+
+		m.mtx.Lock()
+		m.defaultSchedule = defaultSchedule
+		for chanPoint, schedule := range chanSchedules {
+			m.chanSchedules[chanPoint] = schedule
+		}
+		m.evaluateAll = true
+		m.mtx.Unlock()
+
+		m.cfg.Ticker.Resume()
+
+		m.wg.Add(1)
+		go m.evaluateLoop(htlcSub, chanSub)
+	})
+
+	return err
+}
+
+// pruneClosedSchedules removes the schedules of closed channels from the given
+// schedules and from the database.
+func (m *Manager) pruneClosedSchedules(
+	chanSchedules map[wire.OutPoint]*channeldb.FeeSchedule) error {
+
+	for chanPoint := range chanSchedules {
+		chanPoint := chanPoint
+
+		_, err := m.cfg.FetchClosedChannel(&chanPoint)
+		switch {
+		case err == channeldb.ErrClosedChannelNotFound:
+			continue
+
+		case err != nil:
+			return err
+		}
+
+		if err := m.cfg.DeleteFeeSchedule(&chanPoint); err != nil {
+			return err
+		}
+		delete(chanSchedules, chanPoint)
+
+		log.Infof("Removed fee schedule of closed channel %v",
+			chanPoint)
+	}
+
+	return nil
+}
+
+// Stop signals the manager for a graceful shutdown.
+func (m *Manager) Stop() {
+	m.stopped.Do(func() {
+		log.Info("Fee schedule manager shutting down")
+
+		close(m.quit)
+		m.wg.Wait()
+
+		m.cfg.Ticker.Stop()
+	})
+}
+
+// SetSchedule sets the fee schedule of the given channel, or the default fee
+// schedule if chanPoint is nil. A nil schedule removes it, in which case the
+// fees of the affected channels are left as they are. The new schedule is
+// applied right away.
+func (m *Manager) SetSchedule(chanPoint *wire.OutPoint,
+	schedule *channeldb.FeeSchedule) error {
+
+	if schedule != nil {
+		if err := Validate(schedule); err != nil {
+			return err
+		}
+	}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	var err error
+	if schedule != nil {
+		err = m.cfg.PutFeeSchedule(chanPoint, schedule)
+	} else {
+		err = m.cfg.DeleteFeeSchedule(chanPoint)
+	}
+	if err != nil {
+		return err
+	}
+
+	if chanPoint == nil {
+		m.defaultSchedule = schedule
+	} else {
+		m.setChanSchedule(*chanPoint, schedule)
+	}
+
+	m.evaluateAll = true
+	select {
+	case m.evaluateNow <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// setChanSchedule sets the schedule of the given channel, or removes it if
+// schedule is nil. The schedules of specific channels are replaced rather than
+// modified, as evaluations read them without holding the mutex.
+//
+// NOTE: The mutex must be held.
+func (m *Manager) setChanSchedule(chanPoint wire.OutPoint,
+	schedule *channeldb.FeeSchedule) {
+
+	chanSchedules := make(map[wire.OutPoint]*channeldb.FeeSchedule)
+	for op, s := range m.chanSchedules {
+		chanSchedules[op] = s
+	}
+	if schedule != nil {
+		chanSchedules[chanPoint] = schedule
+	} else {
+		delete(chanSchedules, chanPoint)
+	}
+	m.chanSchedules = chanSchedules
+}
+
+// removeClosedSchedule removes the schedule of a channel that was closed, if
+// it has one.
+func (m *Manager) removeClosedSchedule(chanPoint wire.OutPoint) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if _, ok := m.chanSchedules[chanPoint]; !ok {
+		return nil
+	}
+
+	if err := m.cfg.DeleteFeeSchedule(&chanPoint); err != nil {
+		return err
+	}
+	m.setChanSchedule(chanPoint, nil)
+
+	log.Infof("Removed fee schedule of closed channel %v", chanPoint)
+
+	return nil
+}
+
+// Schedules returns the default fee schedule, which is nil if there is none,
+// and the fee schedules of specific channels.
+func (m *Manager) Schedules() (*channeldb.FeeSchedule,
+	map[wire.OutPoint]*channeldb.FeeSchedule) {
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	chanSchedules := make(
+		map[wire.OutPoint]*channeldb.FeeSchedule, len(m.chanSchedules),
+	)
+	for chanPoint, schedule := range m.chanSchedules {
+		chanSchedules[chanPoint] = schedule
+	}
+
+	return m.defaultSchedule, chanSchedules
+}
+
+// evaluateLoop marks the channels whose balance changed and evaluates them
+// every time the ticker fires.
+//
+// NOTE: This MUST be run as a goroutine.
+func (m *Manager) evaluateLoop(htlcSub, chanSub *subscribe.Client) {
+	defer m.wg.Done()
+	defer htlcSub.Cancel()
+	defer chanSub.Cancel()
+
+	for {
+		select {
+		case e := <-htlcSub.Updates():
+			settle, ok := e.(*htlcswitch.SettleEvent)
+			if !ok {
+				continue
+			}
+
+			m.mtx.Lock()
+			m.markDirty(settle.IncomingCircuit.ChanID)
+			m.markDirty(settle.OutgoingCircuit.ChanID)
+			m.mtx.Unlock()
+
+		case e := <-chanSub.Updates():
+			switch event := e.(type) {
+			case channelnotifier.OpenChannelEvent:
+				m.mtx.Lock()
+				m.markDirty(event.Channel.ShortChanID())
+				m.mtx.Unlock()
+
+			case channelnotifier.ClosedChannelEvent:
+				chanPoint := event.CloseSummary.ChanPoint
+				err := m.removeClosedSchedule(chanPoint)
+				if err != nil {
+					log.Errorf("Unable to remove fee "+
+						"schedule of closed channel "+
+						"%v: %v", chanPoint, err)
+				}
+			}
+
+		case <-m.cfg.Ticker.Ticks():
+			if err := m.evaluate(); err != nil {
+				log.Errorf("Unable to apply fee schedules: %v",
+					err)
+			}
+
+		case <-m.evaluateNow:
+			if err := m.evaluate(); err != nil {
+				log.Errorf("Unable to apply fee schedules: %v",
+					err)
+			}
+
+		case <-htlcSub.Quit():
+			log.Errorf("Htlc event subscription canceled")
+			return
+
+		case <-chanSub.Quit():
+			log.Errorf("Channel event subscription canceled")
+			return
+
+		case <-m.quit:
+			return
+		}
+	}
+}
+
+// markDirty records that the balance of the given channel changed. The source
+// and exit hops of local payments aren't channels, so they are ignored.
+//
+// NOTE: The mutex must be held.
+func (m *Manager) markDirty(chanID lnwire.ShortChannelID) {
+	if chanID == hop.Source || chanID == hop.Exit {
+		return
+	}
+
+	m.dirtyChans[chanID] = struct{}{}
+}
+
+// evaluate applies the fee schedules to the channels whose balance changed
+// since the last evaluation.
+func (m *Manager) evaluate() error {
+	m.mtx.Lock()
+	evaluateAll := m.evaluateAll
+	dirtyChans := m.dirtyChans
+	m.evaluateAll = false
+	m.dirtyChans = make(map[lnwire.ShortChannelID]struct{})
+	defaultSchedule := m.defaultSchedule
+	chanSchedules := m.chanSchedules
+	m.mtx.Unlock()
+
+	if !evaluateAll && len(dirtyChans) == 0 {
+		return nil
+	}
+
+	channels, err := m.cfg.FetchAllOpenChannels()
+	if err != nil {
+		return err
+	}
+
+	// Gather the current policy of each of our channels, as only the fees
+	// are modified by the schedules.
+	policies := make(map[wire.OutPoint]*channeldb.ChannelEdgePolicy)
+	err = m.cfg.ForAllOutgoingChannels(func(info *channeldb.ChannelEdgeInfo,
+		edge *channeldb.ChannelEdgePolicy) error {
+
+		if edge != nil {
+			policies[info.ChannelPoint] = edge
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, channel := range channels {
+		select {
+		case <-m.quit:
+			return nil
+		default:
+		}
+
+		if _, ok := dirtyChans[channel.ShortChanID()]; !ok &&
+			!evaluateAll {
+
+			continue
+		}
+
+		schedule, ok := chanSchedules[channel.FundingOutpoint]
+		if !ok {
+			schedule = defaultSchedule
+		}
+		if schedule == nil {
+			continue
+		}
+
+		edge, ok := policies[channel.FundingOutpoint]
+		if !ok {
+			log.Debugf("Skipping channel %v without policy",
+				channel.FundingOutpoint)
+			continue
+		}
+
+		m.applySchedule(channel, edge, schedule)
+	}
+
+	return nil
+}
+
+// applySchedule sets the fees of the tier of the schedule matching the
+// balance of the channel, if they differ from its current fees. A channel
+// charging the fees of a tier keeps them while its balance is within the
+// hysteresis of the bounds of that tier.
+func (m *Manager) applySchedule(channel *channeldb.OpenChannel,
+	edge *channeldb.ChannelEdgePolicy, schedule *channeldb.FeeSchedule) {
+
+	localBalance := channel.LocalCommitment.LocalBalance
+	remoteBalance := channel.LocalCommitment.RemoteBalance
+	if localBalance+remoteBalance == 0 {
+		return
+	}
+	localPercent := 100 * float64(localBalance) /
+		float64(localBalance+remoteBalance)
+
+	tier := TierFor(schedule, localPercent)
+	if tier == nil {
+		return
+	}
+
+	if tier.BaseFee == edge.FeeBaseMAtoms &&
+		lnwire.MilliAtom(tier.FeeRate) == edge.FeeProportionalMillionths {
+
+		return
+	}
+
+	current := currentTier(schedule, edge)
+	if current >= 0 &&
+		holdsTier(schedule, current, localPercent, m.cfg.Hysteresis) {
+
+		log.Debugf("Keeping fee schedule tier of %v%% local balance "+
+			"of channel %v (local_percent=%.1f)",
+			schedule.Tiers[current].MinLocalPercent,
+			channel.FundingOutpoint, localPercent)
+		return
+	}
+
+	minHTLC := edge.MinHTLC
+	policy := routing.ChannelPolicy{
+		FeeSchema: routing.FeeSchema{
+			BaseFee: tier.BaseFee,
+			FeeRate: tier.FeeRate,
+		},
+		TimeLockDelta: uint32(edge.TimeLockDelta),
+		MaxHTLC:       edge.MaxHTLC,
+		MinHTLC:       &minHTLC,
+	}
+
+	chanPoint := channel.FundingOutpoint
+	if err := m.cfg.UpdatePolicy(policy, chanPoint); err != nil {
+		log.Errorf("Unable to apply fee schedule to channel %v: %v",
+			chanPoint, err)
+		return
+	}
+
+	log.Infof("Applied fee schedule tier of %v%% local balance to "+
+		"channel %v (local_percent=%.1f): base_fee=%v, fee_rate=%v",
+		tier.MinLocalPercent, chanPoint, localPercent, tier.BaseFee,
+		tier.FeeRate)
+}
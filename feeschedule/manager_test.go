@@ -0,0 +1,469 @@
+package feeschedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/subscribe"
+	"github.com/decred/dcrlnd/ticker"
+	"github.com/stretchr/testify/require"
+)
+
+// testRamp raises the fees of channels whose local balance drops below 20% and
+// lowers them once it rises above 80%.
+var testRamp = &channeldb.FeeSchedule{
+	Tiers: []channeldb.FeeScheduleTier{
+		{MinLocalPercent: 0, BaseFee: 1000, FeeRate: 2000},
+		{MinLocalPercent: 20, BaseFee: 1000, FeeRate: 500},
+		{MinLocalPercent: 80, BaseFee: 0, FeeRate: 10},
+	},
+}
+
+// TestValidate asserts that only schedules with sorted tiers within bounds are
+// accepted.
+func TestValidate(t *testing.T) {
+	require.NoError(t, Validate(testRamp))
+
+	require.Equal(t, ErrNoTiers, Validate(&channeldb.FeeSchedule{}))
+
+	require.Error(t, Validate(&channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{{MinLocalPercent: 101}},
+	}))
+
+	require.Error(t, Validate(&channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{
+			{MinLocalPercent: 50},
+			{MinLocalPercent: 50},
+		},
+	}))
+
+	require.NoError(t, Validate(&channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{{FeeRate: MaxFeeRate}},
+	}))
+	require.Error(t, Validate(&channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{{FeeRate: MaxFeeRate + 1}},
+	}))
+}
+
+// TestTierFor asserts that the last tier whose minimum is reached applies.
+func TestTierFor(t *testing.T) {
+	tests := []struct {
+		localPercent float64
+		feeRate      uint32
+	}{
+		{localPercent: 0, feeRate: 2000},
+		{localPercent: 19.9, feeRate: 2000},
+		{localPercent: 20, feeRate: 500},
+		{localPercent: 79, feeRate: 500},
+		{localPercent: 80, feeRate: 10},
+		{localPercent: 100, feeRate: 10},
+	}
+
+	for _, test := range tests {
+		tier := TierFor(testRamp, test.localPercent)
+		require.NotNil(t, tier)
+		require.Equal(t, test.feeRate, tier.FeeRate,
+			"local percent %v", test.localPercent)
+	}
+
+	// A balance below the first tier matches no tier.
+	schedule := &channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{{MinLocalPercent: 50}},
+	}
+	require.Nil(t, TierFor(schedule, 49))
+}
+
+// TestHoldsTier asserts that a channel keeps the fees of its tier until its
+// balance is past the bounds of the tier by the hysteresis.
+func TestHoldsTier(t *testing.T) {
+	tests := []struct {
+		current      int
+		localPercent float64
+		holds        bool
+	}{
+		// The first tier is never left downwards.
+		{current: 0, localPercent: 0, holds: true},
+		{current: 0, localPercent: 24.9, holds: true},
+		{current: 0, localPercent: 25, holds: false},
+		{current: 1, localPercent: 15, holds: true},
+		{current: 1, localPercent: 14.9, holds: false},
+		{current: 1, localPercent: 84.9, holds: true},
+		{current: 1, localPercent: 85, holds: false},
+		// The last tier is never left upwards.
+		{current: 2, localPercent: 75, holds: true},
+		{current: 2, localPercent: 74.9, holds: false},
+		{current: 2, localPercent: 100, holds: true},
+	}
+
+	for _, test := range tests {
+		holds := holdsTier(testRamp, test.current, test.localPercent, 5)
+		require.Equal(t, test.holds, holds, "tier %v, local percent %v",
+			test.current, test.localPercent)
+	}
+}
+
+// TestHoldsTierClampedHysteresis asserts that the hysteresis is clamped to half
+// the smallest gap between tiers, so that a channel doesn't keep the fees of a
+// tier once its balance reaches the middle of a neighbouring tier.
+func TestHoldsTierClampedHysteresis(t *testing.T) {
+	schedule := &channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{
+			{MinLocalPercent: 0, FeeRate: 2000},
+			{MinLocalPercent: 40, FeeRate: 1000},
+			{MinLocalPercent: 44, FeeRate: 500},
+			{MinLocalPercent: 48, FeeRate: 10},
+		},
+	}
+
+	tests := []struct {
+		current      int
+		localPercent float64
+		holds        bool
+	}{
+		// The hysteresis of 5 is clamped to 2 percentage points.
+		{current: 0, localPercent: 41.9, holds: true},
+		{current: 0, localPercent: 42, holds: false},
+		{current: 1, localPercent: 38, holds: true},
+		{current: 1, localPercent: 37.9, holds: false},
+		{current: 1, localPercent: 45.9, holds: true},
+		{current: 1, localPercent: 46, holds: false},
+		{current: 3, localPercent: 46, holds: true},
+		{current: 3, localPercent: 45.9, holds: false},
+	}
+
+	for _, test := range tests {
+		holds := holdsTier(schedule, test.current, test.localPercent, 5)
+		require.Equal(t, test.holds, holds, "tier %v, local percent %v",
+			test.current, test.localPercent)
+	}
+}
+
+// TestApplyScheduleHysteresis asserts that the fees of a channel only change
+// once its balance is past the bounds of its current tier by the hysteresis.
+func TestApplyScheduleHysteresis(t *testing.T) {
+	var updates []uint32
+	manager := New(&Config{
+		Hysteresis: 5,
+		UpdatePolicy: func(policy routing.ChannelPolicy,
+			chanPoints ...wire.OutPoint) error {
+
+			updates = append(updates, policy.FeeRate)
+			return nil
+		},
+	})
+
+	// The channel charges the fees of the middle tier.
+	edge := &channeldb.ChannelEdgePolicy{
+		FeeBaseMAtoms:             1000,
+		FeeProportionalMillionths: 500,
+	}
+	channel := &channeldb.OpenChannel{}
+	setBalance := func(localPercent lnwire.MilliAtom) {
+		channel.LocalCommitment.LocalBalance = localPercent
+		channel.LocalCommitment.RemoteBalance = 100 - localPercent
+	}
+
+	// Dropping just below the bound of its tier keeps its fees.
+	setBalance(18)
+	manager.applySchedule(channel, edge, testRamp)
+	require.Empty(t, updates)
+
+	// Once past the hysteresis, the fees of the lower tier are applied.
+	setBalance(14)
+	manager.applySchedule(channel, edge, testRamp)
+	require.Equal(t, []uint32{2000}, updates)
+
+	// Channels charging the fees of no tier get the fees of their tier
+	// right away.
+	edge.FeeProportionalMillionths = 1
+	setBalance(81)
+	manager.applySchedule(channel, edge, testRamp)
+	require.Equal(t, []uint32{2000, 10}, updates)
+}
+
+// TestManagerPrunesClosedSchedules asserts that the schedules of closed
+// channels are removed, both on start and once a channel closes.
+func TestManagerPrunesClosedSchedules(t *testing.T) {
+	closedChan := wire.OutPoint{Index: 1}
+	openChan := wire.OutPoint{Index: 2}
+
+	htlcEvents := subscribe.NewServer()
+	require.NoError(t, htlcEvents.Start())
+	defer htlcEvents.Stop()
+
+	chanEvents := subscribe.NewServer()
+	require.NoError(t, chanEvents.Start())
+	defer chanEvents.Stop()
+
+	deleted := make(chan wire.OutPoint, 2)
+	manager := New(&Config{
+		Ticker: ticker.NewForce(time.Hour),
+		FetchFeeSchedules: func() (*channeldb.FeeSchedule,
+			map[wire.OutPoint]*channeldb.FeeSchedule, error) {
+
+			return nil, map[wire.OutPoint]*channeldb.FeeSchedule{
+				closedChan: testRamp,
+				openChan:   testRamp,
+			}, nil
+		},
+		DeleteFeeSchedule: func(chanPoint *wire.OutPoint) error {
+			deleted <- *chanPoint
+			return nil
+		},
+		FetchClosedChannel: func(chanPoint *wire.OutPoint) (
+			*channeldb.ChannelCloseSummary, error) {
+
+			if *chanPoint != closedChan {
+				return nil, channeldb.ErrClosedChannelNotFound
+			}
+			return &channeldb.ChannelCloseSummary{
+				ChanPoint: closedChan,
+			}, nil
+		},
+		FetchAllOpenChannels: func() ([]*channeldb.OpenChannel, error) {
+			return nil, nil
+		},
+		ForAllOutgoingChannels: func(func(*channeldb.ChannelEdgeInfo,
+			*channeldb.ChannelEdgePolicy) error) error {
+
+			return nil
+		},
+		SubscribeHtlcEvents:    htlcEvents.Subscribe,
+		SubscribeChannelEvents: chanEvents.Subscribe,
+	})
+	require.NoError(t, manager.Start())
+	defer manager.Stop()
+
+	expectDeleted := func(chanPoint wire.OutPoint) {
+		t.Helper()
+
+		select {
+		case op := <-deleted:
+			require.Equal(t, chanPoint, op)
+
+		case <-time.After(time.Second):
+			t.Fatalf("schedule not deleted")
+		}
+	}
+
+	// The schedule of the channel closed while we were offline is removed
+	// on start.
+	expectDeleted(closedChan)
+	_, chanSchedules := manager.Schedules()
+	require.Equal(t, map[wire.OutPoint]*channeldb.FeeSchedule{
+		openChan: testRamp,
+	}, chanSchedules)
+
+	// The schedule of the other channel is removed once it closes.
+	require.NoError(t, chanEvents.SendUpdate(
+		channelnotifier.ClosedChannelEvent{
+			CloseSummary: &channeldb.ChannelCloseSummary{
+				ChanPoint: openChan,
+			},
+		},
+	))
+	expectDeleted(openChan)
+	require.Eventually(t, func() bool {
+		_, chanSchedules := manager.Schedules()
+		return len(chanSchedules) == 0
+	}, time.Second, 10*time.Millisecond)
+}
+
+// TestManagerAppliesSchedules asserts that the manager applies the schedules to
+// all channels on start, then only to the channels whose balance changed.
+func TestManagerAppliesSchedules(t *testing.T) {
+	// The first channel has most of its funds on the remote side, while
+	// the second one is balanced and already charges the fees of its
+	// tier.
+	depleted := &channeldb.OpenChannel{
+		FundingOutpoint: wire.OutPoint{Index: 1},
+		ShortChannelID:  lnwire.NewShortChanIDFromInt(1),
+	}
+	depleted.LocalCommitment.LocalBalance = 100
+	depleted.LocalCommitment.RemoteBalance = 900
+
+	balanced := &channeldb.OpenChannel{
+		FundingOutpoint: wire.OutPoint{Index: 2},
+		ShortChannelID:  lnwire.NewShortChanIDFromInt(2),
+	}
+	balanced.LocalCommitment.LocalBalance = 500
+	balanced.LocalCommitment.RemoteBalance = 500
+
+	policies := map[wire.OutPoint]*channeldb.ChannelEdgePolicy{
+		depleted.FundingOutpoint: {
+			FeeBaseMAtoms:             1000,
+			FeeProportionalMillionths: 100,
+			TimeLockDelta:             80,
+			MinHTLC:                   1,
+			MaxHTLC:                   100000,
+		},
+		balanced.FundingOutpoint: {
+			FeeBaseMAtoms:             1000,
+			FeeProportionalMillionths: 500,
+		},
+	}
+
+	htlcEvents := subscribe.NewServer()
+	require.NoError(t, htlcEvents.Start())
+	defer htlcEvents.Stop()
+
+	chanEvents := subscribe.NewServer()
+	require.NoError(t, chanEvents.Start())
+	defer chanEvents.Stop()
+
+	type update struct {
+		chanPoint wire.OutPoint
+		policy    routing.ChannelPolicy
+	}
+	updates := make(chan update, 2)
+
+	var stored []*channeldb.FeeSchedule
+	forceTicker := ticker.NewForce(time.Hour)
+	manager := New(&Config{
+		Ticker: forceTicker,
+		FetchFeeSchedules: func() (*channeldb.FeeSchedule,
+			map[wire.OutPoint]*channeldb.FeeSchedule, error) {
+
+			return testRamp, nil, nil
+		},
+		PutFeeSchedule: func(chanPoint *wire.OutPoint,
+			schedule *channeldb.FeeSchedule) error {
+
+			stored = append(stored, schedule)
+			return nil
+		},
+		DeleteFeeSchedule: func(chanPoint *wire.OutPoint) error {
+			return nil
+		},
+		FetchClosedChannel: func(*wire.OutPoint) (
+			*channeldb.ChannelCloseSummary, error) {
+
+			return nil, channeldb.ErrClosedChannelNotFound
+		},
+		FetchAllOpenChannels: func() ([]*channeldb.OpenChannel, error) {
+			return []*channeldb.OpenChannel{depleted, balanced}, nil
+		},
+		ForAllOutgoingChannels: func(cb func(*channeldb.ChannelEdgeInfo,
+			*channeldb.ChannelEdgePolicy) error) error {
+
+			for chanPoint, policy := range policies {
+				info := &channeldb.ChannelEdgeInfo{
+					ChannelPoint: chanPoint,
+				}
+				if err := cb(info, policy); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		UpdatePolicy: func(policy routing.ChannelPolicy,
+			chanPoints ...wire.OutPoint) error {
+
+			updates <- update{chanPoint: chanPoints[0], policy: policy}
+
+			edge := policies[chanPoints[0]]
+			edge.FeeBaseMAtoms = policy.BaseFee
+			edge.FeeProportionalMillionths = lnwire.MilliAtom(
+				policy.FeeRate,
+			)
+			return nil
+		},
+		SubscribeHtlcEvents:    htlcEvents.Subscribe,
+		SubscribeChannelEvents: chanEvents.Subscribe,
+	})
+	require.NoError(t, manager.Start())
+	defer manager.Stop()
+
+	expectUpdate := func(chanPoint wire.OutPoint, feeRate uint32) {
+		t.Helper()
+
+		select {
+		case u := <-updates:
+			require.Equal(t, chanPoint, u.chanPoint)
+			require.Equal(t, feeRate, u.policy.FeeRate)
+
+		case <-time.After(time.Second):
+			t.Fatalf("policy not updated")
+		}
+	}
+
+	expectNoUpdate := func() {
+		t.Helper()
+
+		select {
+		case u := <-updates:
+			t.Fatalf("unexpected policy update of %v", u.chanPoint)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	// All channels are evaluated on start, so only the depleted channel's
+	// fees are raised, keeping the rest of its policy.
+	forceTicker.Force <- time.Now()
+
+	select {
+	case u := <-updates:
+		require.Equal(t, depleted.FundingOutpoint, u.chanPoint)
+		require.Equal(t, uint32(2000), u.policy.FeeRate)
+		require.Equal(t, lnwire.MilliAtom(1000), u.policy.BaseFee)
+		require.Equal(t, uint32(80), u.policy.TimeLockDelta)
+		require.Equal(t, lnwire.MilliAtom(100000), u.policy.MaxHTLC)
+		require.Equal(t, lnwire.MilliAtom(1), *u.policy.MinHTLC)
+
+	case <-time.After(time.Second):
+		t.Fatalf("policy not updated")
+	}
+	expectNoUpdate()
+
+	// A tick without balance changes doesn't evaluate any channel.
+	depleted.LocalCommitment.LocalBalance = 900
+	depleted.LocalCommitment.RemoteBalance = 100
+	forceTicker.Force <- time.Now()
+	expectNoUpdate()
+
+	// Once a settled htlc signals the balance change, the next tick lowers
+	// the fees of the channel.
+	settle := &htlcswitch.SettleEvent{}
+	settle.OutgoingCircuit.ChanID = depleted.ShortChannelID
+	require.NoError(t, htlcEvents.SendUpdate(settle))
+
+	// Wait for the manager to process the event before ticking.
+	require.Eventually(t, func() bool {
+		manager.mtx.Lock()
+		defer manager.mtx.Unlock()
+
+		_, ok := manager.dirtyChans[depleted.ShortChannelID]
+		return ok
+	}, time.Second, 10*time.Millisecond)
+	forceTicker.Force <- time.Now()
+	expectUpdate(depleted.FundingOutpoint, 10)
+	expectNoUpdate()
+
+	// Setting a schedule applies it right away without waiting for a
+	// tick.
+	flat := &channeldb.FeeSchedule{
+		Tiers: []channeldb.FeeScheduleTier{
+			{MinLocalPercent: 0, BaseFee: 1000, FeeRate: 300},
+		},
+	}
+	require.NoError(t, manager.SetSchedule(&balanced.FundingOutpoint, flat))
+	expectUpdate(balanced.FundingOutpoint, 300)
+	expectNoUpdate()
+	require.Equal(t, []*channeldb.FeeSchedule{flat}, stored)
+
+	defaultSchedule, chanSchedules := manager.Schedules()
+	require.Equal(t, testRamp, defaultSchedule)
+	require.Equal(t, map[wire.OutPoint]*channeldb.FeeSchedule{
+		balanced.FundingOutpoint: flat,
+	}, chanSchedules)
+
+	// Invalid schedules are rejected.
+	err := manager.SetSchedule(nil, &channeldb.FeeSchedule{})
+	require.Equal(t, ErrNoTiers, err)
+}
@@ -0,0 +1,369 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/hdkeychain/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnpeer"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// channelFixture describes a channel a node seeded from a fixture file opens
+// to another node.
+type channelFixture struct {
+	// Peer is the address of the node to open the channel to, in the
+	// format <pubkey>@<host>.
+	Peer string `json:"peer"`
+
+	// LocalAmt is the amount of atoms to commit to the channel.
+	LocalAmt int64 `json:"local_amt"`
+
+	// PushAmt is the amount of atoms to push to the remote side of the
+	// channel.
+	PushAmt int64 `json:"push_amt"`
+
+	// Private is true if the channel shouldn't be announced.
+	Private bool `json:"private"`
+}
+
+// invoiceFixture describes an invoice a node seeded from a fixture file adds
+// to its invoice database.
+type invoiceFixture struct {
+	// Preimage is the hex encoded preimage of the invoice.
+	Preimage string `json:"preimage"`
+
+	// Value is the value of the invoice in atoms.
+	Value int64 `json:"value"`
+
+	// Memo is the description of the invoice.
+	Memo string `json:"memo"`
+
+	// Expiry is the expiry of the invoice in seconds.
+	Expiry int64 `json:"expiry"`
+}
+
+// paymentFixture describes a settled payment a node seeded from a fixture file
+// adds to its payment history.
+type paymentFixture struct {
+	// Preimage is the hex encoded preimage the payment was settled with.
+	Preimage string `json:"preimage"`
+
+	// Value is the amount paid in atoms.
+	Value int64 `json:"value"`
+
+	// Dest is the hex encoded public key of the node that was paid.
+	Dest string `json:"dest"`
+
+	// PaymentRequest is the payment request that was paid, if any.
+	PaymentRequest string `json:"payment_request"`
+
+	// CreationDate is the unix timestamp at which the payment was made.
+	// Defaults to the time the fixture is applied.
+	CreationDate int64 `json:"creation_date"`
+}
+
+// nodeFixtures holds the fixtures a fresh node is seeded with, so that
+// integration tests and demos can be set up reproducibly.
+type nodeFixtures struct {
+	// Seed is the hex encoded seed of the wallet that is created when the
+	// node is started with --noseedbackup. The identity key of the node is
+	// derived from it.
+	Seed string `json:"seed"`
+
+	// Channels are the channels to open. A channel isn't opened if a
+	// channel with the same peer already exists.
+	Channels []channelFixture `json:"channels"`
+
+	// Invoices are the invoices to add. Invoices that already exist are
+	// skipped.
+	Invoices []invoiceFixture `json:"invoices"`
+
+	// Payments are the settled payments to record. Payments that already
+	// exist are skipped.
+	Payments []paymentFixture `json:"payments"`
+
+	// seed is the decoded Seed.
+	seed []byte
+}
+
+// loadNodeFixtures reads and validates the fixture file at the given path.
+func loadNodeFixtures(path string) (*nodeFixtures, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixtures nodeFixtures
+	if err := json.Unmarshal(b, &fixtures); err != nil {
+		return nil, fmt.Errorf("invalid fixture file: %v", err)
+	}
+
+	if fixtures.Seed != "" {
+		fixtures.seed, err = hex.DecodeString(fixtures.Seed)
+		if err != nil {
+			return nil, fmt.Errorf("invalid fixture seed: %v", err)
+		}
+		if len(fixtures.seed) < hdkeychain.MinSeedBytes ||
+			len(fixtures.seed) > hdkeychain.MaxSeedBytes {
+
+			return nil, fmt.Errorf("fixture seed must be between "+
+				"%d and %d bytes", hdkeychain.MinSeedBytes,
+				hdkeychain.MaxSeedBytes)
+		}
+	}
+
+	for _, channel := range fixtures.Channels {
+		if _, _, err := parseFixturePeer(channel.Peer); err != nil {
+			return nil, err
+		}
+		if channel.LocalAmt <= 0 {
+			return nil, fmt.Errorf("fixture channel to %v must "+
+				"have a positive local_amt", channel.Peer)
+		}
+	}
+
+	for _, invoice := range fixtures.Invoices {
+		preimage, err := hex.DecodeString(invoice.Preimage)
+		if err != nil || len(preimage) != 32 {
+			return nil, fmt.Errorf("invalid fixture invoice "+
+				"preimage %q", invoice.Preimage)
+		}
+	}
+
+	for _, payment := range fixtures.Payments {
+		preimage, err := hex.DecodeString(payment.Preimage)
+		if err != nil || len(preimage) != 32 {
+			return nil, fmt.Errorf("invalid fixture payment "+
+				"preimage %q", payment.Preimage)
+		}
+		if payment.Value <= 0 {
+			return nil, fmt.Errorf("fixture payment %v must "+
+				"have a positive value", payment.Preimage)
+		}
+		if _, err := route.NewVertexFromStr(payment.Dest); err != nil {
+			return nil, fmt.Errorf("invalid fixture payment "+
+				"dest %q: %v", payment.Dest, err)
+		}
+	}
+
+	return &fixtures, nil
+}
+
+// hdSeed returns the seed of the wallet to create, or nil if a random seed
+// should be used.
+func (f *nodeFixtures) hdSeed() []byte {
+	if f == nil {
+		return nil
+	}
+
+	return f.seed
+}
+
+// parseFixturePeer splits a peer address of the form <pubkey>@<host> into its
+// public key and host.
+func parseFixturePeer(peer string) (*secp256k1.PublicKey, string, error) {
+	parts := strings.Split(peer, "@")
+	if len(parts) != 2 {
+		return nil, "", fmt.Errorf("invalid fixture peer %q, expected "+
+			"<pubkey>@<host>", peer)
+	}
+
+	pubKeyBytes, err := hex.DecodeString(parts[0])
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid fixture peer %q: %v",
+			peer, err)
+	}
+	pubKey, err := secp256k1.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid fixture peer %q: %v",
+			peer, err)
+	}
+
+	return pubKey, parts[1], nil
+}
+
+// applyNodeFixtures adds the invoices and payments and opens the channels
+// described by the fixtures. Fixtures that were already applied in a previous
+// run are skipped, so the node can be restarted with the same fixture file.
+func (r *rpcServer) applyNodeFixtures(fixtures *nodeFixtures) {
+	ctx := context.Background()
+
+	for _, invoice := range fixtures.Invoices {
+		preimage, _ := hex.DecodeString(invoice.Preimage)
+		_, err := r.AddInvoice(ctx, &lnrpc.Invoice{
+			Memo:                invoice.Memo,
+			RPreimage:           preimage,
+			Value:               invoice.Value,
+			Expiry:              invoice.Expiry,
+			IgnoreMaxInboundAmt: true,
+		})
+		switch {
+		case err == channeldb.ErrDuplicateInvoice:
+
+		case err != nil:
+			ltndLog.Errorf("Unable to add fixture invoice %v: %v",
+				invoice.Preimage, err)
+		}
+	}
+
+	for _, payment := range fixtures.Payments {
+		err := recordFixturePayment(
+			r.server.controlTower, r.selfNode, payment, time.Now(),
+		)
+		switch {
+		case err == channeldb.ErrAlreadyPaid:
+
+		case err != nil:
+			ltndLog.Errorf("Unable to record fixture payment "+
+				"%v: %v", payment.Preimage, err)
+		}
+	}
+
+	for _, channel := range fixtures.Channels {
+		if err := r.openFixtureChannel(ctx, channel); err != nil {
+			ltndLog.Errorf("Unable to open fixture channel to "+
+				"%v: %v", channel.Peer, err)
+		}
+	}
+}
+
+// recordFixturePayment records the given payment fixture as a payment settled
+// over a direct route to its destination. The current time is used as the
+// creation date of fixtures that don't set one. ErrAlreadyPaid is returned if
+// the payment was already recorded.
+func recordFixturePayment(control routing.ControlTower, self route.Vertex,
+	payment paymentFixture, now time.Time) error {
+
+	preimageBytes, _ := hex.DecodeString(payment.Preimage)
+	preimage, err := lntypes.MakePreimage(preimageBytes)
+	if err != nil {
+		return err
+	}
+	hash := preimage.Hash()
+	dest, _ := route.NewVertexFromStr(payment.Dest)
+
+	created := now
+	if payment.CreationDate != 0 {
+		created = time.Unix(payment.CreationDate, 0)
+	}
+
+	amt := lnwire.NewMAtomsFromAtoms(dcrutil.Amount(payment.Value))
+	err = control.InitPayment(hash, &channeldb.PaymentCreationInfo{
+		PaymentHash:    hash,
+		Value:          amt,
+		CreationTime:   created,
+		PaymentRequest: []byte(payment.PaymentRequest),
+	})
+	if err != nil {
+		return err
+	}
+
+	rt, err := route.NewRouteFromHops(amt, 0, self, []*route.Hop{{
+		PubKeyBytes:  dest,
+		AmtToForward: amt,
+	}})
+	if err != nil {
+		return err
+	}
+
+	sessionKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return err
+	}
+
+	err = control.RegisterAttempt(hash, &channeldb.HTLCAttemptInfo{
+		SessionKey:  sessionKey,
+		Route:       *rt,
+		AttemptTime: created,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = control.SettleAttempt(hash, 0, &channeldb.HTLCSettleInfo{
+		Preimage:   preimage,
+		SettleTime: created,
+	})
+	return err
+}
+
+// openFixtureChannel connects to the peer of the given channel fixture and
+// opens the channel, unless a channel with the peer already exists.
+func (r *rpcServer) openFixtureChannel(ctx context.Context,
+	channel channelFixture) error {
+
+	pubKey, host, _ := parseFixturePeer(channel.Peer)
+
+	openChans, err := r.server.remoteChanDB.FetchOpenChannels(pubKey)
+	if err != nil {
+		return err
+	}
+	pendingChans, err := r.server.remoteChanDB.FetchPendingChannels()
+	if err != nil {
+		return err
+	}
+	for _, pending := range pendingChans {
+		if pending.IdentityPub.IsEqual(pubKey) {
+			openChans = append(openChans, pending)
+		}
+	}
+	if len(openChans) > 0 {
+		return nil
+	}
+
+	pubKeyHex := hex.EncodeToString(pubKey.SerializeCompressed())
+	_, err = r.ConnectPeer(ctx, &lnrpc.ConnectPeerRequest{
+		Addr: &lnrpc.LightningAddress{
+			Pubkey: pubKeyHex,
+			Host:   host,
+		},
+		Perm: true,
+	})
+	if _, ok := err.(*errPeerAlreadyConnected); err != nil && !ok {
+		return err
+	}
+
+	// Wait for the peer to come online before opening the channel.
+	var peerKey [33]byte
+	copy(peerKey[:], pubKey.SerializeCompressed())
+	peerChan := make(chan lnpeer.Peer, 1)
+	r.server.NotifyWhenOnline(peerKey, peerChan)
+	select {
+	case <-peerChan:
+	case <-r.quit:
+		return ErrServerShuttingDown
+	}
+
+	chanPoint, err := r.OpenChannelSync(ctx, &lnrpc.OpenChannelRequest{
+		NodePubkey:         pubKey.SerializeCompressed(),
+		LocalFundingAmount: channel.LocalAmt,
+		PushAtoms:          channel.PushAmt,
+		Private:            channel.Private,
+	})
+	if err != nil {
+		return err
+	}
+
+	txid, err := GetChanPointFundingTxid(chanPoint)
+	if err != nil {
+		return err
+	}
+
+	ltndLog.Infof("Opened fixture channel to %v with funding tx %v",
+		channel.Peer, txid)
+
+	return nil
+}
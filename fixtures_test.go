@@ -0,0 +1,166 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+const (
+	testFixtureDest = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce" +
+		"28d959f2815b16f81798"
+
+	testFixturePeer = testFixtureDest + "@127.0.0.1:20000"
+)
+
+// TestLoadNodeFixtures asserts that fixture files are parsed and that invalid
+// fixtures are rejected.
+func TestLoadNodeFixtures(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	load := func(content string) (*nodeFixtures, error) {
+		path := filepath.Join(dir, "fixtures.json")
+		err := ioutil.WriteFile(path, []byte(content), 0600)
+		if err != nil {
+			t.Fatalf("unable to write fixture file: %v", err)
+		}
+		return loadNodeFixtures(path)
+	}
+
+	preimage := "0101010101010101010101010101010101010101010101010101010101010101"
+	fixtures, err := load(`{
+		"seed": "000102030405060708090a0b0c0d0e0f",
+		"channels": [{"peer": "` + testFixturePeer + `", "local_amt": 100000}],
+		"invoices": [{"preimage": "` + preimage + `", "value": 1000}],
+		"payments": [{"preimage": "` + preimage + `", "value": 1000,
+			"dest": "` + testFixtureDest + `"}]
+	}`)
+	if err != nil {
+		t.Fatalf("unable to load fixtures: %v", err)
+	}
+
+	expectedSeed := []byte{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13,
+		14, 15}
+	if !bytes.Equal(fixtures.hdSeed(), expectedSeed) {
+		t.Fatalf("unexpected seed %x", fixtures.hdSeed())
+	}
+	if len(fixtures.Channels) != 1 || len(fixtures.Invoices) != 1 ||
+		len(fixtures.Payments) != 1 {
+
+		t.Fatalf("unexpected fixtures: %v", fixtures)
+	}
+
+	// Without fixtures, a random seed is used.
+	var noFixtures *nodeFixtures
+	if noFixtures.hdSeed() != nil {
+		t.Fatalf("expected no seed")
+	}
+
+	invalid := []string{
+		`{"seed": "0001"}`,
+		`{"seed": "zz"}`,
+		`{"channels": [{"peer": "127.0.0.1", "local_amt": 1}]}`,
+		`{"channels": [{"peer": "` + testFixturePeer + `"}]}`,
+		`{"invoices": [{"preimage": "0101", "value": 1}]}`,
+		`{"invoices": [}`,
+		`{"payments": [{"preimage": "0101", "value": 1, "dest": "` +
+			testFixtureDest + `"}]}`,
+		`{"payments": [{"preimage": "` + preimage + `", "dest": "` +
+			testFixtureDest + `"}]}`,
+		`{"payments": [{"preimage": "` + preimage + `", "value": 1}]}`,
+	}
+	for _, content := range invalid {
+		if _, err := load(content); err == nil {
+			t.Fatalf("expected fixtures %v to be rejected", content)
+		}
+	}
+}
+
+// TestRecordFixturePayment asserts that payment fixtures are recorded as
+// settled payments, and that they are only recorded once.
+func TestRecordFixturePayment(t *testing.T) {
+	t.Parallel()
+
+	dir, err := ioutil.TempDir("", "fixtures")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := channeldb.Open(dir)
+	if err != nil {
+		t.Fatalf("unable to open channeldb: %v", err)
+	}
+	defer db.Close()
+
+	control := routing.NewControlTower(channeldb.NewPaymentControl(db))
+
+	var self route.Vertex
+	self[0] = 0x02
+
+	preimage := lntypes.Preimage{1, 2, 3}
+	payment := paymentFixture{
+		Preimage:       hex.EncodeToString(preimage[:]),
+		Value:          1000,
+		Dest:           testFixtureDest,
+		PaymentRequest: "lndcr1test",
+		CreationDate:   1600000000,
+	}
+
+	err = recordFixturePayment(control, self, payment, time.Now())
+	if err != nil {
+		t.Fatalf("unable to record fixture payment: %v", err)
+	}
+
+	p, err := control.FetchPayment(preimage.Hash())
+	if err != nil {
+		t.Fatalf("unable to fetch payment: %v", err)
+	}
+	if p.Status != channeldb.StatusSucceeded {
+		t.Fatalf("expected payment to be settled, got %v", p.Status)
+	}
+	if p.Info.Value != lnwire.MilliAtom(1000000) {
+		t.Fatalf("unexpected payment value %v", p.Info.Value)
+	}
+	if !p.Info.CreationTime.Equal(time.Unix(1600000000, 0)) {
+		t.Fatalf("unexpected creation time %v", p.Info.CreationTime)
+	}
+	if string(p.Info.PaymentRequest) != payment.PaymentRequest {
+		t.Fatalf("unexpected payment request %s",
+			p.Info.PaymentRequest)
+	}
+
+	settle, _ := p.TerminalInfo()
+	if settle == nil || settle.Preimage != preimage {
+		t.Fatalf("payment not settled with the fixture preimage")
+	}
+
+	htlcRoute := p.HTLCs[0].Route
+	if htlcRoute.SourcePubKey != self ||
+		htlcRoute.Hops[0].PubKeyBytes.String() != testFixtureDest {
+
+		t.Fatalf("unexpected payment route %v", htlcRoute)
+	}
+
+	// Applying the fixture again must not record a second payment.
+	err = recordFixturePayment(control, self, payment, time.Now())
+	if err != channeldb.ErrAlreadyPaid {
+		t.Fatalf("expected ErrAlreadyPaid, got %v", err)
+	}
+}
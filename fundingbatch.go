@@ -0,0 +1,293 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/internal/psbt"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet/chanfunding"
+)
+
+// batchChannel tracks the funding flow of a single channel of a batch open.
+type batchChannel struct {
+	// pendingChanID is the pending channel ID of the PSBT funding intent
+	// of the channel.
+	pendingChanID [32]byte
+
+	// req is the open request of the channel.
+	req *openChanReq
+
+	// updates and errChan receive the updates of the funding flow.
+	updates <-chan *lnrpc.OpenStatusUpdate
+	errChan <-chan error
+
+	// fundingOutput is the channel funding output, known once the remote
+	// peer accepted the channel.
+	fundingOutput *wire.TxOut
+
+	// pending is the pending channel, set once the funding flow completed.
+	pending *lnrpc.PendingUpdate
+}
+
+// waitForUpdate waits for the next update of the funding flow of the channel.
+func (c *batchChannel) waitForUpdate(ctx context.Context,
+	quit chan struct{}) (*lnrpc.OpenStatusUpdate, error) {
+
+	select {
+	case update := <-c.updates:
+		return update, nil
+
+	case err := <-c.errChan:
+		return nil, err
+
+	case <-ctx.Done():
+		return nil, ctx.Err()
+
+	case <-quit:
+		return nil, ErrServerShuttingDown
+	}
+}
+
+// rollbackBatch undoes the funding flows of a batch open that failed before
+// its funding transaction was published. The funding intents of the channels
+// that haven't become pending are cancelled, and the channels that became
+// pending are abandoned, as their funding transaction will never be
+// published.
+func rollbackBatch(channels []*batchChannel,
+	cancelIntent func([32]byte) error,
+	abandon func(*wire.OutPoint) error) {
+
+	for _, channel := range channels {
+		if channel.pending == nil {
+			err := cancelIntent(channel.pendingChanID)
+			if err != nil {
+				rpcsLog.Debugf("Unable to cancel funding "+
+					"intent %x: %v",
+					channel.pendingChanID[:], err)
+			}
+
+			continue
+		}
+
+		txid, err := chainhash.NewHash(channel.pending.Txid)
+		if err != nil {
+			rpcsLog.Errorf("Invalid txid of pending channel %x: %v",
+				channel.pendingChanID[:], err)
+			continue
+		}
+		chanPoint := wire.NewOutPoint(
+			txid, channel.pending.OutputIndex, wire.TxTreeRegular,
+		)
+
+		rpcsLog.Infof("Abandoning pending channel %v of failed batch "+
+			"open", chanPoint)
+
+		if err := abandon(chanPoint); err != nil {
+			rpcsLog.Errorf("Unable to abandon pending channel %v "+
+				"of failed batch open: %v", chanPoint, err)
+		}
+	}
+}
+
+// BatchOpenChannel opens multiple channels using a single funding transaction
+// that is funded by the internal wallet.
+//
+// Each channel is opened using a PSBT funding intent. Once all remote peers
+// accepted their channel, the funding transaction paying to all funding
+// outputs is created and verified by every intent. The intents of all but the
+// last channel never publish the transaction and are finalized first. Only
+// once the remote peers of all of them have signed their commitment, the last
+// intent is finalized, publishing the transaction. This ensures that the
+// funding transaction isn't published before all peers have signed. If the
+// batch fails before that, the channels that already became pending are
+// abandoned and the funding intents of the others are cancelled.
+func (r *rpcServer) BatchOpenChannel(ctx context.Context,
+	in *lnrpc.BatchOpenChannelRequest) (*lnrpc.BatchOpenChannelResponse,
+	error) {
+
+	if err := r.canOpenChannel(); err != nil {
+		return nil, err
+	}
+	if len(in.Channels) == 0 {
+		return nil, errors.New("no channels specified")
+	}
+
+	netParams := &r.server.cc.wallet.Cfg.NetParams
+	channels := make([]*batchChannel, 0, len(in.Channels))
+	for i, rpcChan := range in.Channels {
+		req, err := r.parseOpenChannelReq(&lnrpc.OpenChannelRequest{
+			NodePubkey:                   rpcChan.NodePubkey,
+			LocalFundingAmount:           rpcChan.LocalFundingAmount,
+			PushAtoms:                    rpcChan.PushAtoms,
+			TargetConf:                   in.TargetConf,
+			AtomsPerByte:                 in.AtomsPerByte,
+			Private:                      rpcChan.Private,
+			MinHtlcMAtoms:                rpcChan.MinHtlcMAtoms,
+			RemoteCsvDelay:               rpcChan.RemoteCsvDelay,
+			MinConfs:                     in.MinConfs,
+			SpendUnconfirmed:             in.SpendUnconfirmed,
+			CloseAddress:                 rpcChan.CloseAddress,
+			RemoteMaxValueInFlightMAtoms: rpcChan.RemoteMaxValueInFlightMAtoms,
+			RemoteMaxHtlcs:               rpcChan.RemoteMaxHtlcs,
+		}, false)
+		if err != nil {
+			return nil, fmt.Errorf("invalid channel %d: %v", i, err)
+		}
+
+		channel := &batchChannel{req: req}
+		if _, err := rand.Read(channel.pendingChanID[:]); err != nil {
+			return nil, err
+		}
+
+		// Only the intent of the last channel publishes the funding
+		// transaction.
+		req.pendingChanID = channel.pendingChanID
+		req.chanFunder = chanfunding.NewPsbtAssembler(
+			req.localFundingAmt, nil, netParams,
+			i == len(in.Channels)-1,
+		)
+
+		channels = append(channels, channel)
+	}
+
+	// Roll back the funding flows if the batch fails before the funding
+	// transaction may have been published.
+	var (
+		fundingTx *wire.MsgTx
+		published bool
+		success   bool
+	)
+	defer func() {
+		if success {
+			return
+		}
+
+		// Once the last intent was finalized, the funding transaction
+		// may have been published, so the channels that became
+		// pending must be kept.
+		if published {
+			rpcsLog.Warnf("Batch open failed after its funding "+
+				"transaction %v may have been published, the "+
+				"pending channels are kept", fundingTx.TxHash())
+			return
+		}
+
+		_, bestHeight, err := r.server.cc.chainIO.GetBestBlock()
+		if err != nil {
+			rpcsLog.Errorf("Unable to roll back batch open: %v",
+				err)
+			return
+		}
+
+		rollbackBatch(
+			channels, r.server.cc.wallet.CancelFundingIntent,
+			func(chanPoint *wire.OutPoint) error {
+				return r.abandonChannel(
+					chanPoint, uint32(bestHeight), false,
+					nil,
+				)
+			},
+		)
+
+		if fundingTx != nil {
+			r.server.cc.wallet.ReleaseBatch(fundingTx)
+		}
+	}()
+
+	// Start all funding flows at once, then wait for every remote peer to
+	// accept its channel, which makes the funding output known.
+	for _, channel := range channels {
+		channel.updates, channel.errChan = r.server.OpenChannel(
+			channel.req,
+		)
+	}
+	outputs := make([]*wire.TxOut, 0, len(channels))
+	for _, channel := range channels {
+		update, err := channel.waitForUpdate(ctx, r.quit)
+		if err != nil {
+			return nil, rpcFundingError(fmt.Errorf("unable to "+
+				"open channel to %x: %w", channel.req.
+				targetPubkey.SerializeCompressed(), err))
+		}
+
+		psbtFund := update.GetPsbtFund()
+		if psbtFund == nil {
+			return nil, fmt.Errorf("unexpected funding update %v",
+				update)
+		}
+		packet, err := psbt.NewFromRawBytes(
+			bytes.NewReader(psbtFund.Psbt), false,
+		)
+		if err != nil {
+			return nil, err
+		}
+		if len(packet.UnsignedTx.TxOut) != 1 {
+			return nil, fmt.Errorf("unexpected funding PSBT")
+		}
+
+		channel.fundingOutput = packet.UnsignedTx.TxOut[0]
+		outputs = append(outputs, channel.fundingOutput)
+	}
+
+	// With all funding outputs known, we can create the funding
+	// transaction and let every intent verify it.
+	wallet := r.server.cc.wallet
+	packet, signedTx, err := wallet.FundBatch(
+		outputs, channels[0].req.fundingFeePerKB,
+		channels[0].req.minConfs,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fund batch: %v", err)
+	}
+	fundingTx = signedTx
+
+	for _, channel := range channels {
+		err := wallet.PsbtFundingVerify(channel.pendingChanID, packet)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Finalize the channels one by one, waiting for each remote peer to
+	// sign its commitment. The last channel publishes the transaction.
+	for i, channel := range channels {
+		published = i == len(channels)-1
+		err := wallet.PsbtFundingFinalize(
+			channel.pendingChanID, nil, signedTx,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		update, err := channel.waitForUpdate(ctx, r.quit)
+		if err != nil {
+			return nil, fmt.Errorf("unable to open channel to "+
+				"%x: %v", channel.req.targetPubkey.
+				SerializeCompressed(), err)
+		}
+		channel.pending = update.GetChanPending()
+		if channel.pending == nil {
+			return nil, fmt.Errorf("unexpected funding update %v",
+				update)
+		}
+	}
+	success = true
+
+	rpcsLog.Infof("Opened batch of %d channels with funding tx %v",
+		len(channels), signedTx.TxHash())
+
+	resp := &lnrpc.BatchOpenChannelResponse{}
+	for _, channel := range channels {
+		resp.PendingChannels = append(
+			resp.PendingChannels, channel.pending,
+		)
+	}
+
+	return resp, nil
+}
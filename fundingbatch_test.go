@@ -0,0 +1,64 @@
+package dcrlnd
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// TestRollbackBatch asserts that rolling back a failed batch open cancels the
+// funding intents of the channels that didn't become pending and abandons the
+// channels that did.
+func TestRollbackBatch(t *testing.T) {
+	t.Parallel()
+
+	pendingTxid := [32]byte{1, 2, 3}
+	channels := []*batchChannel{{
+		pendingChanID: [32]byte{1},
+		pending: &lnrpc.PendingUpdate{
+			Txid:        pendingTxid[:],
+			OutputIndex: 1,
+		},
+	}, {
+		pendingChanID: [32]byte{2},
+		pending: &lnrpc.PendingUpdate{
+			Txid:        pendingTxid[:],
+			OutputIndex: 0,
+		},
+	}, {
+		pendingChanID: [32]byte{3},
+	}}
+
+	var (
+		cancelled [][32]byte
+		abandoned []wire.OutPoint
+	)
+	rollbackBatch(
+		channels,
+		func(pendingChanID [32]byte) error {
+			cancelled = append(cancelled, pendingChanID)
+			return nil
+		},
+		func(chanPoint *wire.OutPoint) error {
+			abandoned = append(abandoned, *chanPoint)
+			return nil
+		},
+	)
+
+	if len(cancelled) != 1 || cancelled[0] != channels[2].pendingChanID {
+		t.Fatalf("expected intent of non pending channel to be "+
+			"cancelled, got %x", cancelled)
+	}
+
+	if len(abandoned) != 2 {
+		t.Fatalf("expected 2 abandoned channels, got %v", abandoned)
+	}
+	for i, chanPoint := range abandoned {
+		if chanPoint.Hash != pendingTxid ||
+			chanPoint.Index != channels[i].pending.OutputIndex {
+
+			t.Fatalf("unexpected abandoned channel %v", chanPoint)
+		}
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -17,6 +18,7 @@ import (
 	"github.com/decred/dcrlnd/chanacceptor"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
+	"github.com/decred/dcrlnd/channelnotifier"
 	"github.com/decred/dcrlnd/discovery"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/input"
@@ -27,7 +29,9 @@ import (
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
 	"github.com/decred/dcrlnd/lnwallet/chanfunding"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/monitoring"
 	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/subscribe"
 	"github.com/go-errors/errors"
 	"golang.org/x/crypto/salsa20"
 )
@@ -90,9 +94,65 @@ var (
 	errUpfrontShutdownScriptNotSupported = errors.New("peer does not support" +
 		"option upfront shutdown script")
 
+	// ErrReservationCanceled is returned to the caller of a funding flow
+	// that was canceled through CancelReservation.
+	ErrReservationCanceled = errors.New("funding reservation canceled " +
+		"by user")
+
 	zeroID [32]byte
 )
 
+// fundingState describes the step of the funding workflow an active
+// reservation is waiting on.
+type fundingState uint8
+
+const (
+	// fundingStateAwaitingAccept is the state of a reservation we
+	// initiated while we wait for the remote peer's accept_channel
+	// message.
+	fundingStateAwaitingAccept fundingState = iota
+
+	// fundingStateAwaitingPsbt is the state of a reservation we initiated
+	// while we wait for the user to supply the signed funding PSBT.
+	fundingStateAwaitingPsbt
+
+	// fundingStateAwaitingFundingSigned is the state of a reservation we
+	// initiated while we wait for the remote peer's funding_signed
+	// message.
+	fundingStateAwaitingFundingSigned
+
+	// fundingStateAwaitingFundingCreated is the state of a reservation
+	// initiated by the remote peer while we wait for its funding_created
+	// message.
+	fundingStateAwaitingFundingCreated
+)
+
+// String returns a human readable version of the funding state.
+func (s fundingState) String() string {
+	switch s {
+	case fundingStateAwaitingAccept:
+		return "AwaitingAccept"
+	case fundingStateAwaitingPsbt:
+		return "AwaitingPsbt"
+	case fundingStateAwaitingFundingSigned:
+		return "AwaitingFundingSigned"
+	case fundingStateAwaitingFundingCreated:
+		return "AwaitingFundingCreated"
+	default:
+		return "Unknown"
+	}
+}
+
+// The outcomes of funding workflows as reported to the monitoring subsystem.
+const (
+	fundingOutcomeCompleted        = "completed"
+	fundingOutcomeFailed           = "failed"
+	fundingOutcomeRemoteFailed     = "remote_failed"
+	fundingOutcomeTimedOut         = "timed_out"
+	fundingOutcomeCanceled         = "canceled"
+	fundingOutcomePeerDisconnected = "peer_disconnected"
+)
+
 // reservationWithCtx encapsulates a pending channel reservation. This wrapper
 // struct is used internally within the funding manager to track and progress
 // the funding workflow initiated by incoming/outgoing methods from the target
@@ -114,8 +174,20 @@ type reservationWithCtx struct {
 	remoteMaxValue lnwire.MilliAtom
 	remoteMaxHtlcs uint16
 
+	// label is the label stored with the funding transaction when we
+	// publish it.
+	label string
+
+	// created is the time the reservation was created.
+	created time.Time
+
+	// countsPending is true if the reservation was initiated by the remote
+	// peer and counts towards the pending channel limits.
+	countsPending bool
+
 	updateMtx   sync.RWMutex
 	lastUpdated time.Time
+	state       fundingState
 
 	updates chan *lnrpc.OpenStatusUpdate
 	err     chan error
@@ -139,6 +211,15 @@ func (r *reservationWithCtx) updateTimestamp() {
 	r.lastUpdated = time.Now()
 }
 
+// setState records the step of the funding workflow the reservation is
+// waiting on.
+func (r *reservationWithCtx) setState(state fundingState) {
+	r.updateMtx.Lock()
+	defer r.updateMtx.Unlock()
+
+	r.state = state
+}
+
 // initFundingMsg is sent by an outside subsystem to the funding manager in
 // order to kick off a funding workflow with a specified target peer. The
 // original request which defines the parameters of the funding workflow are
@@ -296,6 +377,14 @@ type fundingConfig struct {
 	// process to determine how many confirmations we'll require.
 	NumRequiredConfs func(dcrutil.Amount, lnwire.MilliAtom) uint16
 
+	// InitiatorNumRequiredConfs is a function closure that returns the
+	// minimum number of confirmations we require for a channel of the
+	// given amount that we initiated. If the remote party asks for fewer
+	// confirmations, we'll wait for this number of confirmations instead
+	// before considering the channel open. A zero value defers to the
+	// remote party.
+	InitiatorNumRequiredConfs func(dcrutil.Amount) uint16
+
 	// RequiredRemoteDelay is a function that maps the total amount in a
 	// proposed channel to the CSV delay that we'll require for the remote
 	// party. Naturally a larger channel should require a higher CSV delay
@@ -354,6 +443,16 @@ type fundingConfig struct {
 	// allow for each peer.
 	MaxPendingChannels int
 
+	// MaxGlobalPendingChannels is the maximum number of pending channels
+	// we allow across all peers. A value of zero means there's no global
+	// limit.
+	MaxGlobalPendingChannels int
+
+	// SubscribeChannelEvents subscribes to the channel events, so that
+	// channels closed while pending open no longer count towards the
+	// pending channel limits.
+	SubscribeChannelEvents func() (*subscribe.Client, error)
+
 	// RejectPush is set true if the fundingmanager should reject any
 	// incoming channels having a non-zero push amount.
 	RejectPush bool
@@ -390,6 +489,15 @@ type fundingConfig struct {
 // controls between the wallet and remote peers are enforced via the funding
 // manager.
 type fundingManager struct {
+	// maxPendingChannels and maxGlobalPendingChannels are the currently
+	// effective limits on the number of pending channels per peer and
+	// across all peers. They're initialized from the config and can be
+	// updated at runtime.
+	//
+	// NOTE: These MUST be used atomically.
+	maxPendingChannels       uint32
+	maxGlobalPendingChannels uint32
+
 	started sync.Once
 	stopped sync.Once
 
@@ -417,7 +525,19 @@ type fundingManager struct {
 	// signed by both parties.
 	signedReservations map[lnwire.ChannelID][32]byte
 
-	// resMtx guards both of the maps above to ensure that all access is
+	// remotePending maps the remote initiated channels pending open that
+	// count towards the pending channel limits to their peer.
+	remotePending map[wire.OutPoint]serializedPubKey
+
+	// pendingCounts is the number of remote initiated reservations and
+	// channels pending open of each peer that count towards the pending
+	// channel limits, and numPending their sum across all peers. They're
+	// updated as reservations and pending channels come and go, so that
+	// the limits can be checked without scanning the database.
+	pendingCounts map[serializedPubKey]uint32
+	numPending    uint32
+
+	// resMtx guards the maps and counts above to ensure that all access is
 	// goroutine safe.
 	resMtx sync.RWMutex
 
@@ -490,10 +610,14 @@ var (
 // fundingManager.
 func newFundingManager(cfg fundingConfig) (*fundingManager, error) {
 	return &fundingManager{
+		maxPendingChannels:          uint32(cfg.MaxPendingChannels),
+		maxGlobalPendingChannels:    uint32(cfg.MaxGlobalPendingChannels),
 		cfg:                         &cfg,
 		chanIDKey:                   cfg.TempChanIDSeed,
 		activeReservations:          make(map[serializedPubKey]pendingChannels),
 		signedReservations:          make(map[lnwire.ChannelID][32]byte),
+		remotePending:               make(map[wire.OutPoint]serializedPubKey),
+		pendingCounts:               make(map[serializedPubKey]uint32),
 		newChanBarriers:             make(map[lnwire.ChannelID]chan struct{}),
 		fundingMsgs:                 make(chan interface{}, msgBufferSize),
 		fundingRequests:             make(chan *initFundingMsg, msgBufferSize),
@@ -528,6 +652,31 @@ func (f *fundingManager) start() error {
 		return err
 	}
 
+	// Load the remote initiated channels pending open that count towards
+	// the pending channel limits.
+	pendingChannels, err := f.cfg.Wallet.Cfg.Database.FetchPendingChannels()
+	if err != nil {
+		return err
+	}
+	for _, channel := range pendingChannels {
+		if countsTowardsPendingLimits(channel) {
+			f.trackPendingChannel(
+				newSerializedKey(channel.IdentityPub),
+				channel.FundingOutpoint,
+			)
+		}
+	}
+
+	if f.cfg.SubscribeChannelEvents != nil {
+		chanEvents, err := f.cfg.SubscribeChannelEvents()
+		if err != nil {
+			return err
+		}
+
+		f.wg.Add(1)
+		go f.forgetClosedPendingChannels(chanEvents)
+	}
+
 	for _, channel := range allChannels {
 		chanID := lnwire.NewChanIDFromOutPoint(&channel.FundingOutpoint)
 
@@ -710,6 +859,10 @@ func (f *fundingManager) CancelPeerReservations(nodePub [33]byte) {
 
 		resCtx.err <- fmt.Errorf("peer disconnected")
 		delete(nodeReservations, pendingID)
+		if resCtx.countsPending {
+			f.decPendingCount(nodePub)
+		}
+		monitoring.RecordFundingOutcome(fundingOutcomePeerDisconnected)
 	}
 
 	// Finally, we'll delete the node itself from the set of reservations.
@@ -726,6 +879,17 @@ func (f *fundingManager) CancelPeerReservations(nodePub [33]byte) {
 func (f *fundingManager) failFundingFlow(peer lnpeer.Peer, tempChanID [32]byte,
 	fundingErr error) {
 
+	f.failFundingFlowWithOutcome(
+		peer, tempChanID, fundingErr, fundingOutcomeFailed,
+	)
+}
+
+// failFundingFlowWithOutcome fails the active funding flow just like
+// failFundingFlow, recording the given outcome with the monitoring subsystem
+// if the reservation existed.
+func (f *fundingManager) failFundingFlowWithOutcome(peer lnpeer.Peer,
+	tempChanID [32]byte, fundingErr error, outcome string) {
+
 	fndgLog.Debugf("Failing funding flow for pending_id=%x: %v",
 		tempChanID, fundingErr)
 
@@ -738,10 +902,12 @@ func (f *fundingManager) failFundingFlow(peer lnpeer.Peer, tempChanID [32]byte,
 	// error on the error channel.
 	if ctx != nil {
 		ctx.err <- fundingErr
+		monitoring.RecordFundingOutcome(outcome)
 	}
 
 	// We only send the exact error if it is part of out whitelisted set of
-	// errors (lnwire.FundingError or lnwallet.ReservationError).
+	// errors (lnwire.FundingError, lnwallet.ReservationError or
+	// chanacceptor.ChanAcceptError).
 	var msg lnwire.ErrorData
 	switch e := fundingErr.(type) {
 
@@ -751,6 +917,8 @@ func (f *fundingManager) failFundingFlow(peer lnpeer.Peer, tempChanID [32]byte,
 		msg = lnwire.ErrorData(e.Error())
 	case lnwire.FundingError:
 		msg = lnwire.ErrorData(e.Error())
+	case chanacceptor.ChanAcceptError:
+		msg = lnwire.ErrorData(e.Error())
 
 	// For all other error types we just send a generic error.
 	default:
@@ -808,6 +976,8 @@ func (f *fundingManager) reservationCoordinator() {
 			switch msg := req.(type) {
 			case *pendingChansReq:
 				f.handlePendingChannels(msg)
+			case *cancelReservationReq:
+				f.handleCancelReservation(msg)
 			}
 		case <-f.quit:
 			return
@@ -1048,6 +1218,7 @@ func (f *fundingManager) advancePendingChannelState(
 			return fmt.Errorf("failed closing channel "+
 				"%v: %v", ch.FundingOutpoint, err)
 		}
+		f.forgetPendingChannel(ch.FundingOutpoint)
 
 		timeoutErr := fmt.Errorf("timeout waiting for funding tx "+
 			"(%v) to confirm", channel.FundingOutpoint)
@@ -1124,6 +1295,117 @@ func (f *fundingManager) handlePendingChannels(msg *pendingChansReq) {
 	msg.resp <- pendingChannels
 }
 
+// fundingReservation describes an active funding reservation, i.e. a funding
+// workflow that hasn't yet resulted in a channel pending open.
+type fundingReservation struct {
+	pendingChanID   [32]byte
+	identityPub     *secp256k1.PublicKey
+	state           fundingState
+	capacity        dcrutil.Amount
+	localFundingAmt dcrutil.Amount
+	created         time.Time
+	lastUpdated     time.Time
+	isPsbt          bool
+	isCannedShim    bool
+}
+
+// initiator returns true if we initiated the funding workflow.
+func (r *fundingReservation) initiator() bool {
+	return r.state != fundingStateAwaitingFundingCreated
+}
+
+// Reservations returns a description of all funding reservations that are
+// currently active.
+func (f *fundingManager) Reservations() []*fundingReservation {
+	f.resMtx.RLock()
+	defer f.resMtx.RUnlock()
+
+	var reservations []*fundingReservation
+	for _, pendingReservations := range f.activeReservations {
+		for pendingChanID, resCtx := range pendingReservations {
+			res := resCtx.reservation
+
+			resCtx.updateMtx.RLock()
+			reservations = append(reservations, &fundingReservation{
+				pendingChanID:   pendingChanID,
+				identityPub:     resCtx.peer.IdentityKey(),
+				state:           resCtx.state,
+				capacity:        resCtx.chanAmt,
+				localFundingAmt: res.LocalFundingAmt(),
+				created:         resCtx.created,
+				lastUpdated:     resCtx.lastUpdated,
+				isPsbt:          res.IsPsbt(),
+				isCannedShim:    res.IsCannedShim(),
+			})
+			resCtx.updateMtx.RUnlock()
+		}
+	}
+
+	return reservations
+}
+
+type cancelReservationReq struct {
+	pendingChanID [32]byte
+	err           chan error
+}
+
+// CancelReservation fails the funding workflow of the active reservation with
+// the given pending channel ID, releasing the coins it holds. The remote peer
+// is sent an error and the local caller of the funding flow, if any, receives
+// ErrReservationCanceled.
+func (f *fundingManager) CancelReservation(pendingChanID [32]byte) error {
+	errChan := make(chan error, 1)
+	req := &cancelReservationReq{
+		pendingChanID: pendingChanID,
+		err:           errChan,
+	}
+
+	select {
+	case f.queries <- req:
+	case <-f.quit:
+		return ErrFundingManagerShuttingDown
+	}
+
+	select {
+	case err := <-errChan:
+		return err
+	case <-f.quit:
+		return ErrFundingManagerShuttingDown
+	}
+}
+
+// handleCancelReservation cancels the reservation requested by a call to
+// CancelReservation. This is run from within the reservation coordinator so
+// the cancellation can't race with an incoming funding message.
+func (f *fundingManager) handleCancelReservation(msg *cancelReservationReq) {
+	var resCtx *reservationWithCtx
+
+	f.resMtx.RLock()
+	for _, pendingReservations := range f.activeReservations {
+		if ctx, ok := pendingReservations[msg.pendingChanID]; ok {
+			resCtx = ctx
+			break
+		}
+	}
+	f.resMtx.RUnlock()
+
+	if resCtx == nil {
+		msg.err <- fmt.Errorf("no active reservation with "+
+			"pending_chan_id=%x", msg.pendingChanID[:])
+		return
+	}
+
+	fndgLog.Infof("Canceling reservation for pending_id=%x on user "+
+		"request", msg.pendingChanID[:])
+
+	f.failFundingFlowWithOutcome(
+		resCtx.peer, msg.pendingChanID, ErrReservationCanceled,
+		fundingOutcomeCanceled,
+	)
+
+	msg.err <- nil
+}
+
 // processFundingOpen sends a message to the fundingManager allowing it to
 // initiate the new funding workflow with the source peer.
 func (f *fundingManager) processFundingOpen(msg *lnwire.OpenChannel,
@@ -1172,6 +1454,190 @@ func commitmentType(localFeatures,
 	return lnwallet.CommitmentTypeLegacy
 }
 
+// checkCommitmentType ensures the requested commitment type is the one that
+// will be negotiated with the remote peer. As the remote node derives the
+// commitment type from the same set of features, opening a channel of any
+// other type would fail once the commitment signatures are exchanged.
+func checkCommitmentType(requested lnwallet.CommitmentType, localFeatures,
+	remoteFeatures *lnwire.FeatureVector) error {
+
+	// hasFeature ensures both nodes signal the given feature bit.
+	hasFeature := func(bit lnwire.FeatureBit) error {
+		if !localFeatures.HasFeature(bit) {
+			return fmt.Errorf("commitment type %v not enabled "+
+				"locally", requested)
+		}
+		if !remoteFeatures.HasFeature(bit) {
+			return fmt.Errorf("commitment type %v not supported "+
+				"by peer", requested)
+		}
+		return nil
+	}
+
+	switch requested {
+	// Legacy commitments are supported by every node.
+	case lnwallet.CommitmentTypeLegacy:
+
+	case lnwallet.CommitmentTypeTweakless:
+		if err := hasFeature(lnwire.StaticRemoteKeyOptional); err != nil {
+			return err
+		}
+
+	case lnwallet.CommitmentTypeAnchors:
+		if err := hasFeature(lnwire.AnchorsOptional); err != nil {
+			return err
+		}
+
+	default:
+		return fmt.Errorf("unknown commitment type %v", requested)
+	}
+
+	negotiated := commitmentType(localFeatures, remoteFeatures)
+	if negotiated != requested {
+		return fmt.Errorf("commitment type %v requested, but %v "+
+			"would be negotiated with peer", requested, negotiated)
+	}
+
+	return nil
+}
+
+// PendingChannelLimits returns the currently effective maximum number of
+// pending channels per peer and across all peers. A global limit of zero
+// means there's no global limit.
+func (f *fundingManager) PendingChannelLimits() (uint32, uint32) {
+	return atomic.LoadUint32(&f.maxPendingChannels),
+		atomic.LoadUint32(&f.maxGlobalPendingChannels)
+}
+
+// UpdatePendingChannelLimits updates the maximum number of pending channels
+// per peer and across all peers. The new limits apply to funding requests
+// received after the update.
+func (f *fundingManager) UpdatePendingChannelLimits(maxPending,
+	maxGlobalPending uint32) {
+
+	atomic.StoreUint32(&f.maxPendingChannels, maxPending)
+	atomic.StoreUint32(&f.maxGlobalPendingChannels, maxGlobalPending)
+}
+
+// countsTowardsPendingLimits returns whether the given channel pending open
+// counts towards the pending channel limits. Only channels initiated by the
+// remote peer count, except for the ones created through a canned funding
+// shim: the user registered the shim and therefore expects these channels to
+// arrive.
+//
+// Locally initiated channels are exempt on purpose. The limits bound the
+// funding flows remote peers can make us keep track of, while our own are
+// already bounded by the funds of our wallet and only ever started by the
+// user. Counting them would also let a batch of our own channel opens make us
+// reject the channels peers open to us.
+func countsTowardsPendingLimits(c *channeldb.OpenChannel) bool {
+	// Pending channels that have a non-zero thaw height were created
+	// through a canned funding shim.
+	//
+	// TODO(guggero): Properly store the funding type (wallet, shim,
+	// PSBT) on the channel so we don't need to use the thaw height.
+	return !c.IsInitiator && c.ThawHeight == 0
+}
+
+// incPendingCount increments the number of pending channels of the given peer.
+//
+// NOTE: The resMtx MUST be held when calling this method.
+func (f *fundingManager) incPendingCount(peerKey serializedPubKey) {
+	f.pendingCounts[peerKey]++
+	f.numPending++
+}
+
+// decPendingCount decrements the number of pending channels of the given peer.
+//
+// NOTE: The resMtx MUST be held when calling this method.
+func (f *fundingManager) decPendingCount(peerKey serializedPubKey) {
+	if f.pendingCounts[peerKey] == 0 {
+		return
+	}
+
+	f.pendingCounts[peerKey]--
+	if f.pendingCounts[peerKey] == 0 {
+		delete(f.pendingCounts, peerKey)
+	}
+	f.numPending--
+}
+
+// trackPendingChannel starts counting the given remote initiated channel
+// pending open towards the pending channel limits.
+func (f *fundingManager) trackPendingChannel(peerKey serializedPubKey,
+	chanPoint wire.OutPoint) {
+
+	f.resMtx.Lock()
+	defer f.resMtx.Unlock()
+
+	if _, ok := f.remotePending[chanPoint]; ok {
+		return
+	}
+	f.remotePending[chanPoint] = peerKey
+	f.incPendingCount(peerKey)
+}
+
+// forgetPendingChannel stops counting the given channel towards the pending
+// channel limits, once it's open or closed.
+func (f *fundingManager) forgetPendingChannel(chanPoint wire.OutPoint) {
+	f.resMtx.Lock()
+	defer f.resMtx.Unlock()
+
+	peerKey, ok := f.remotePending[chanPoint]
+	if !ok {
+		return
+	}
+	delete(f.remotePending, chanPoint)
+	f.decPendingCount(peerKey)
+}
+
+// forgetClosedPendingChannels stops counting the channels pending open that
+// are closed, such as when they're abandoned, towards the pending channel
+// limits.
+//
+// NOTE: This MUST be run as a goroutine.
+func (f *fundingManager) forgetClosedPendingChannels(
+	chanEvents *subscribe.Client) {
+
+	defer f.wg.Done()
+	defer chanEvents.Cancel()
+
+	for {
+		select {
+		case e, ok := <-chanEvents.Updates():
+			if !ok {
+				return
+			}
+
+			event, ok := e.(channelnotifier.ClosedChannelEvent)
+			if ok {
+				f.forgetPendingChannel(event.CloseSummary.ChanPoint)
+			}
+
+		case <-f.quit:
+			return
+		}
+	}
+}
+
+// PendingChannelCounts returns the number of pending channels of each peer
+// that count towards the pending channel limits, along with their sum across
+// all peers. These are the reservations and channels pending open initiated by
+// the remote peer, except for the ones created through a canned funding shim.
+func (f *fundingManager) PendingChannelCounts() (map[serializedPubKey]uint32,
+	uint32) {
+
+	f.resMtx.RLock()
+	defer f.resMtx.RUnlock()
+
+	counts := make(map[serializedPubKey]uint32, len(f.pendingCounts))
+	for peerKey, numPending := range f.pendingCounts {
+		counts[peerKey] = numPending
+	}
+
+	return counts, f.numPending
+}
+
 // handleFundingOpen creates an initial 'ChannelReservation' within the wallet,
 // then responds to the source peer with an accept channel message progressing
 // the funding workflow.
@@ -1188,50 +1654,28 @@ func (f *fundingManager) handleFundingOpen(fmsg *fundingOpenMsg) {
 	msg := fmsg.msg
 	amt := msg.FundingAmount
 
-	// We get all pending channels for this peer. This is the list of the
-	// active reservations and the channels pending open in the database.
+	// We count the pending channels initiated by remote peers, both for
+	// this peer and across all peers, to enforce the configured limits.
 	f.resMtx.RLock()
-	reservations := f.activeReservations[peerIDKey]
-
-	// We don't count reservations that were created from a canned funding
-	// shim. The user has registered the shim and therefore expects this
-	// channel to arrive.
-	numPending := 0
-	for _, res := range reservations {
-		if !res.reservation.IsCannedShim() {
-			numPending++
-		}
-	}
+	numPeerPending := f.pendingCounts[peerIDKey]
+	numGlobalPending := f.numPending
 	f.resMtx.RUnlock()
 
-	// Also count the channels that are already pending. There we don't know
-	// the underlying intent anymore, unfortunately.
-	channels, err := f.cfg.Wallet.Cfg.Database.FetchOpenChannels(peerPubKey)
-	if err != nil {
+	// TODO(roasbeef): modify to only accept a _single_ pending channel per
+	// block unless white listed
+	maxPending, maxGlobalPending := f.PendingChannelLimits()
+	switch {
+	case numPeerPending >= maxPending:
 		f.failFundingFlow(
-			fmsg.peer, fmsg.msg.PendingChannelID, err,
+			fmsg.peer, fmsg.msg.PendingChannelID,
+			lnwire.ErrMaxPendingChannels,
 		)
 		return
-	}
-
-	for _, c := range channels {
-		// Pending channels that have a non-zero thaw height were also
-		// created through a canned funding shim. Those also don't
-		// count towards the DoS protection limit.
-		//
-		// TODO(guggero): Properly store the funding type (wallet, shim,
-		// PSBT) on the channel so we don't need to use the thaw height.
-		if c.IsPending && c.ThawHeight == 0 {
-			numPending++
-		}
-	}
 
-	// TODO(roasbeef): modify to only accept a _single_ pending channel per
-	// block unless white listed
-	if numPending >= f.cfg.MaxPendingChannels {
+	case maxGlobalPending != 0 && numGlobalPending >= maxGlobalPending:
 		f.failFundingFlow(
 			fmsg.peer, fmsg.msg.PendingChannelID,
-			lnwire.ErrMaxPendingChannels,
+			lnwire.ErrMaxGlobalPendingChannels,
 		)
 		return
 	}
@@ -1280,17 +1724,40 @@ func (f *fundingManager) handleFundingOpen(fmsg *fundingOpenMsg) {
 		return
 	}
 
+	// As we're the responder, we get to specify the number of confirmations
+	// that we require before both of us consider the channel open. We'll
+	// use our mapping to derive the proper number of confirmations based on
+	// the amount of the channel, and also if any funds are being pushed to
+	// us.
+	numConfsReq := f.cfg.NumRequiredConfs(msg.FundingAmount, msg.PushAmount)
+
 	// Send the OpenChannel request to the ChannelAcceptor to determine whether
 	// this node will accept the channel.
 	chanReq := &chanacceptor.ChannelAcceptRequest{
-		Node:        fmsg.peer.IdentityKey(),
-		OpenChanMsg: fmsg.msg,
+		Node:           fmsg.peer.IdentityKey(),
+		OpenChanMsg:    fmsg.msg,
+		MinAcceptDepth: numConfsReq,
+	}
+
+	// If the channel acceptor rejected the channel, its error is delivered
+	// to the peer.
+	acceptorResp := f.cfg.OpenChannelPredicate.Accept(chanReq)
+	if acceptorResp.RejectChannel() {
+		f.failFundingFlow(
+			fmsg.peer, fmsg.msg.PendingChannelID,
+			acceptorResp.ChanAcceptError,
+		)
+		return
 	}
 
-	if !f.cfg.OpenChannelPredicate.Accept(chanReq) {
+	// The channel acceptor may not require a reserve below the dust limit
+	// of the initiator, as it could then never be enforced.
+	if acceptorResp.Reserve != 0 && acceptorResp.Reserve < msg.DustLimit {
 		f.failFundingFlow(
 			fmsg.peer, fmsg.msg.PendingChannelID,
-			fmt.Errorf("open channel request rejected"),
+			fmt.Errorf("channel acceptor reserve %v is below the "+
+				"dust limit %v", acceptorResp.Reserve,
+				msg.DustLimit),
 		)
 		return
 	}
@@ -1343,12 +1810,6 @@ func (f *fundingManager) handleFundingOpen(fmsg *fundingOpenMsg) {
 		return
 	}
 
-	// As we're the responder, we get to specify the number of confirmations
-	// that we require before both of us consider the channel open. We'll
-	// use our mapping to derive the proper number of confirmations based on
-	// the amount of the channel, and also if any funds are being pushed to
-	// us.
-	numConfsReq := f.cfg.NumRequiredConfs(msg.FundingAmount, msg.PushAmount)
 	reservation.SetNumConfsRequired(numConfsReq)
 
 	// We'll also validate and apply all the constraints the initiating
@@ -1396,12 +1857,29 @@ func (f *fundingManager) handleFundingOpen(fmsg *fundingOpenMsg) {
 		fmsg.msg.PendingChannelID, amt, msg.PushAmount,
 		commitType, msg.UpfrontShutdownScript)
 
-	// Generate our required constraints for the remote party.
+	// Generate our required constraints for the remote party, using the
+	// values provided by the channel acceptor if they are non-zero.
 	remoteCsvDelay := f.cfg.RequiredRemoteDelay(amt)
+	if acceptorResp.CSVDelay != 0 {
+		remoteCsvDelay = acceptorResp.CSVDelay
+	}
+
 	chanReserve := f.cfg.RequiredRemoteChanReserve(amt, msg.DustLimit)
+	if acceptorResp.Reserve != 0 {
+		chanReserve = acceptorResp.Reserve
+	}
+
 	remoteMaxValue := f.cfg.RequiredRemoteMaxValue(amt)
+
 	maxHtlcs := f.cfg.RequiredRemoteMaxHTLCs(amt)
+	if acceptorResp.HtlcLimit != 0 {
+		maxHtlcs = acceptorResp.HtlcLimit
+	}
+
 	minHtlc := f.cfg.DefaultMinHtlcIn
+	if acceptorResp.MinHtlcIn != 0 {
+		minHtlc = acceptorResp.MinHtlcIn
+	}
 
 	// Once the reservation has been created successfully, we add it to
 	// this peer's map of pending reservations to track this particular
@@ -1419,8 +1897,14 @@ func (f *fundingManager) handleFundingOpen(fmsg *fundingOpenMsg) {
 		remoteMaxHtlcs: maxHtlcs,
 		err:            make(chan error, 1),
 		peer:           fmsg.peer,
+		created:        time.Now(),
+		state:          fundingStateAwaitingFundingCreated,
+		countsPending:  !reservation.IsCannedShim(),
 	}
 	f.activeReservations[peerIDKey][msg.PendingChannelID] = resCtx
+	if resCtx.countsPending {
+		f.incPendingCount(peerIDKey)
+	}
 	f.resMtx.Unlock()
 
 	// Update the timestamp once the fundingOpenMsg has been handled.
@@ -1544,9 +2028,20 @@ func (f *fundingManager) handleFundingAccept(fmsg *fundingAcceptMsg) {
 	}
 
 	// We'll also specify the responder's preference for the number of
-	// required confirmations, and also the set of channel constraints
-	// they've specified for commitment states we can create.
-	resCtx.reservation.SetNumConfsRequired(uint16(msg.MinAcceptDepth))
+	// required confirmations, unless we require more confirmations for a
+	// channel of this size ourselves, and also the set of channel
+	// constraints they've specified for commitment states we can create.
+	numConfsReq := uint16(msg.MinAcceptDepth)
+	if f.cfg.InitiatorNumRequiredConfs != nil {
+		minConfs := f.cfg.InitiatorNumRequiredConfs(resCtx.chanAmt)
+		if minConfs > numConfsReq {
+			fndgLog.Infof("Requiring %v confirmations instead of "+
+				"%v for pending_id(%x)", minConfs, numConfsReq,
+				pendingChanID[:])
+			numConfsReq = minConfs
+		}
+	}
+	resCtx.reservation.SetNumConfsRequired(numConfsReq)
 	channelConstraints := &channeldb.ChannelConstraints{
 		DustLimit:        msg.DustLimit,
 		ChanReserve:      msg.ChannelReserve,
@@ -1653,6 +2148,8 @@ func (f *fundingManager) handleFundingAccept(fmsg *fundingAcceptMsg) {
 	// to arrive. To not block any other channels from opening, we wait in
 	// a separate goroutine.
 	if psbtIntent != nil {
+		resCtx.setState(fundingStateAwaitingPsbt)
+
 		f.wg.Add(1)
 		go func() {
 			defer f.wg.Done()
@@ -1790,6 +2287,8 @@ func (f *fundingManager) continueFundingAccept(resCtx *reservationWithCtx,
 		f.failFundingFlow(resCtx.peer, pendingChanID, err)
 		return
 	}
+
+	resCtx.setState(fundingStateAwaitingFundingSigned)
 }
 
 // processFundingCreated queues a funding complete message coupled with the
@@ -1851,7 +2350,13 @@ func (f *fundingManager) handleFundingCreated(fmsg *fundingCreatedMsg) {
 	}
 
 	// The channel is marked IsPending in the database, and can be removed
-	// from the set of active reservations.
+	// from the set of active reservations. It now counts towards the
+	// pending channel limits as a pending channel instead.
+	if resCtx.countsPending {
+		f.trackPendingChannel(
+			newSerializedKey(peerKey), completeChan.FundingOutpoint,
+		)
+	}
 	f.deleteReservationCtx(peerKey, fmsg.msg.PendingChannelID)
 
 	// If something goes wrong before the funding transaction is confirmed,
@@ -1879,6 +2384,7 @@ func (f *fundingManager) handleFundingCreated(fmsg *fundingCreatedMsg) {
 			fndgLog.Errorf("Failed closing channel %v: %v",
 				completeChan.FundingOutpoint, err)
 		}
+		f.forgetPendingChannel(completeChan.FundingOutpoint)
 	}
 
 	// A new channel has almost finished the funding process. In order to
@@ -2049,7 +2555,7 @@ func (f *fundingManager) handleFundingSigned(fmsg *fundingSignedMsg) {
 		fndgLog.Infof("Broadcasting funding tx for ChannelPoint(%v): %x",
 			completeChan.FundingOutpoint, fundingTxBuf.Bytes())
 
-		err = f.cfg.PublishTransaction(fundingTx, "")
+		err = f.cfg.PublishTransaction(fundingTx, resCtx.label)
 		if err != nil {
 			fndgLog.Errorf("Unable to broadcast funding tx %x for "+
 				"ChannelPoint(%v): %v", fundingTxBuf.Bytes(),
@@ -2376,6 +2882,7 @@ func (f *fundingManager) handleFundingConfirmation(
 		return fmt.Errorf("error setting channel pending flag to false: "+
 			"%v", err)
 	}
+	f.forgetPendingChannel(completeChan.FundingOutpoint)
 
 	// Inform the ChannelNotifier that the channel has transitioned from
 	// pending open to open.
@@ -3182,6 +3689,20 @@ func (f *fundingManager) handleInitFundingMsg(msg *initFundingMsg) {
 	commitType := commitmentType(
 		msg.peer.LocalFeatures(), msg.peer.RemoteFeatures(),
 	)
+
+	// If the caller requires a particular commitment type, ensure it
+	// matches the one we'll end up negotiating with the peer.
+	if msg.commitType != nil {
+		err := checkCommitmentType(
+			*msg.commitType, msg.peer.LocalFeatures(),
+			msg.peer.RemoteFeatures(),
+		)
+		if err != nil {
+			msg.err <- err
+			return
+		}
+	}
+
 	req := &lnwallet.InitFundingReserveMsg{
 		ChainHash:        &msg.chainHash,
 		PendingChanID:    chanID,
@@ -3257,6 +3778,9 @@ func (f *fundingManager) handleInitFundingMsg(msg *initFundingMsg) {
 		peer:           msg.peer,
 		updates:        msg.updates,
 		err:            msg.err,
+		label:          msg.openChanReq.label,
+		created:        time.Now(),
+		state:          fundingStateAwaitingAccept,
 	}
 	f.activeReservations[peerIDKey][chanID] = resCtx
 	f.resMtx.Unlock()
@@ -3310,6 +3834,7 @@ func (f *fundingManager) handleInitFundingMsg(msg *initFundingMsg) {
 		}
 
 		msg.err <- e
+		monitoring.RecordFundingOutcome(fundingOutcomeFailed)
 		return
 	}
 }
@@ -3349,6 +3874,14 @@ func (f *fundingManager) handleErrorMsg(fmsg *fundingErrorMsg) {
 	fundingErr := fmt.Errorf("received funding error from %x: %v",
 		fmsg.peerKey.SerializeCompressed(), protocolErr.Error(),
 	)
+
+	// If the remote peer sent one of the well known funding errors, we'll
+	// wrap it so callers can act on it.
+	if code, ok := lnwire.ParseFundingError(protocolErr.Data); ok {
+		fundingErr = fmt.Errorf("received funding error from %x: %w",
+			fmsg.peerKey.SerializeCompressed(), code,
+		)
+	}
 	fndgLog.Errorf(fundingErr.Error())
 
 	// If this was a PSBT funding flow, the remote likely timed out because
@@ -3360,6 +3893,7 @@ func (f *fundingManager) handleErrorMsg(fmsg *fundingErrorMsg) {
 	}
 
 	resCtx.err <- fundingErr
+	monitoring.RecordFundingOutcome(fundingOutcomeRemoteFailed)
 }
 
 // pruneZombieReservations loops through all pending reservations and fails the
@@ -3395,7 +3929,9 @@ func (f *fundingManager) pruneZombieReservations() {
 			"(peer_id:%x, chan_id:%x)", resCtx.peer.IdentityKey().SerializeCompressed(),
 			pendingChanID[:])
 		fndgLog.Warnf(err.Error())
-		f.failFundingFlow(resCtx.peer, pendingChanID, err)
+		f.failFundingFlowWithOutcome(
+			resCtx.peer, pendingChanID, err, fundingOutcomeTimedOut,
+		)
 	}
 }
 
@@ -3438,6 +3974,9 @@ func (f *fundingManager) cancelReservationCtx(peerKey *secp256k1.PublicKey,
 	}
 
 	delete(nodeReservations, pendingChanID)
+	if ctx.countsPending {
+		f.decPendingCount(peerIDKey)
+	}
 
 	// If this was the last active reservation for this peer, delete the
 	// peer's entry altogether.
@@ -3448,7 +3987,8 @@ func (f *fundingManager) cancelReservationCtx(peerKey *secp256k1.PublicKey,
 }
 
 // deleteReservationCtx deletes the reservation uniquely identified by the
-// target public key of the peer, and the specified pending channel ID.
+// target public key of the peer, and the specified pending channel ID. This is
+// called once the reservation completed and the channel is pending open.
 func (f *fundingManager) deleteReservationCtx(peerKey *secp256k1.PublicKey,
 	pendingChanID [32]byte) {
 
@@ -3463,7 +4003,15 @@ func (f *fundingManager) deleteReservationCtx(peerKey *secp256k1.PublicKey,
 		// No reservations for this node.
 		return
 	}
+	resCtx, ok := nodeReservations[pendingChanID]
+	if !ok {
+		return
+	}
 	delete(nodeReservations, pendingChanID)
+	if resCtx.countsPending {
+		f.decPendingCount(peerIDKey)
+	}
+	monitoring.RecordFundingOutcome(fundingOutcomeCompleted)
 
 	// If this was the last active reservation for this peer, delete the
 	// peer's entry altogether.
@@ -831,6 +831,8 @@ func assertFundingMsgSent(t *testing.T, msgChan chan lnwire.Message,
 		ok      bool
 	)
 	switch msgType {
+	case "OpenChannel":
+		sentMsg, ok = msg.(*lnwire.OpenChannel)
 	case "AcceptChannel":
 		sentMsg, ok = msg.(*lnwire.AcceptChannel)
 	case "FundingCreated":
@@ -1720,6 +1722,122 @@ func TestFundingManagerPeerTimeoutAfterFundingOpen(t *testing.T) {
 	assertNumPendingReservations(t, bob, alicePubKey, 0)
 }
 
+// TestFundingManagerCancelReservation checks that the active reservations are
+// listed with their current state and that they can be canceled.
+func TestFundingManagerCancelReservation(t *testing.T) {
+	t.Parallel()
+
+	alice, bob := setupFundingManagers(t)
+	defer tearDownFundingManagers(t, alice, bob)
+
+	// We will consume the channel updates as we go, so no buffering is needed.
+	updateChan := make(chan *lnrpc.OpenStatusUpdate)
+
+	// Create a funding request and start the workflow.
+	errChan := make(chan error, 1)
+	initReq := &openChanReq{
+		targetPubkey:    bob.privKey.PubKey(),
+		chainHash:       activeNetParams.GenesisHash,
+		localFundingAmt: 500000,
+		pushAmt:         lnwire.NewMAtomsFromAtoms(0),
+		private:         false,
+		updates:         updateChan,
+		err:             errChan,
+	}
+
+	alice.fundingMgr.initFundingWorkflow(bob, initReq)
+
+	// Alice should have sent the OpenChannel message to Bob, which Bob
+	// answers with an AcceptChannel we don't deliver.
+	openChannelReq := assertFundingMsgSent(
+		t, alice.msgChan, "OpenChannel",
+	).(*lnwire.OpenChannel)
+	bob.fundingMgr.processFundingOpen(openChannelReq, alice)
+	assertFundingMsgSent(t, bob.msgChan, "AcceptChannel")
+
+	assertReservation := func(node *testNode, state fundingState,
+		initiator bool, localAmt dcrutil.Amount) {
+
+		t.Helper()
+
+		reservations := node.fundingMgr.Reservations()
+		if len(reservations) != 1 {
+			t.Fatalf("expected 1 reservation, got %d",
+				len(reservations))
+		}
+
+		res := reservations[0]
+		if res.pendingChanID != openChannelReq.PendingChannelID {
+			t.Fatalf("unexpected pending chan id %x",
+				res.pendingChanID)
+		}
+		if res.state != state {
+			t.Fatalf("expected state %v, got %v", state, res.state)
+		}
+		if res.initiator() != initiator {
+			t.Fatalf("expected initiator=%v", initiator)
+		}
+		if res.capacity != 500000 {
+			t.Fatalf("expected capacity 500000, got %v",
+				res.capacity)
+		}
+		if res.localFundingAmt != localAmt {
+			t.Fatalf("expected local funding amount %v, got %v",
+				localAmt, res.localFundingAmt)
+		}
+	}
+	assertReservation(alice, fundingStateAwaitingAccept, true, 500000)
+	assertReservation(bob, fundingStateAwaitingFundingCreated, false, 0)
+
+	// Canceling an unknown reservation should fail.
+	if err := alice.fundingMgr.CancelReservation([32]byte{1}); err == nil {
+		t.Fatalf("expected error canceling unknown reservation")
+	}
+
+	// The error message is sent synchronously, so we'll cancel the
+	// reservations in a goroutine.
+	cancelReservation := func(node *testNode) {
+		t.Helper()
+
+		cancelErr := make(chan error, 1)
+		go func() {
+			cancelErr <- node.fundingMgr.CancelReservation(
+				openChannelReq.PendingChannelID,
+			)
+		}()
+
+		assertErrorSent(t, node.msgChan)
+
+		select {
+		case err := <-cancelErr:
+			if err != nil {
+				t.Fatalf("unable to cancel reservation: %v", err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("reservation not canceled")
+		}
+	}
+
+	// Canceling Bob's reservation should send an error to Alice and
+	// remove the reservation.
+	cancelReservation(bob)
+	assertNumPendingReservations(t, bob, alicePubKey, 0)
+
+	// Canceling Alice's reservation should also notify the caller of the
+	// funding flow.
+	cancelReservation(alice)
+	assertNumPendingReservations(t, alice, bobPubKey, 0)
+
+	select {
+	case err := <-errChan:
+		if err != ErrReservationCanceled {
+			t.Fatalf("expected ErrReservationCanceled, got %v", err)
+		}
+	case <-time.After(time.Second * 5):
+		t.Fatalf("funding flow not canceled")
+	}
+}
+
 // TestFundingManagerPeerTimeoutAfterFundingAccept checks that the zombie sweeper
 // will properly clean up a zombie reservation that times out after the
 // fundingAcceptMsg has been handled.
@@ -2897,6 +3015,121 @@ func TestFundingManagerMaxPendingChannels(t *testing.T) {
 	).(*lnwire.AcceptChannel)
 }
 
+// TestFundingManagerGlobalPendingChannels checks that the limit on the number
+// of pending channels across all peers is enforced with the matching funding
+// error, and that the limits can be updated at runtime.
+func TestFundingManagerGlobalPendingChannels(t *testing.T) {
+	t.Parallel()
+
+	alice, bob := setupFundingManagers(
+		t, func(cfg *fundingConfig) {
+			cfg.MaxPendingChannels = maxPending
+			cfg.MaxGlobalPendingChannels = 1
+		},
+	)
+	defer tearDownFundingManagers(t, alice, bob)
+
+	// openChannel kicks off a funding workflow from Alice and returns
+	// the OpenChannel message she sent.
+	openChannel := func() *lnwire.OpenChannel {
+		initReq := &openChanReq{
+			targetPubkey:    bob.privKey.PubKey(),
+			chainHash:       activeNetParams.GenesisHash,
+			localFundingAmt: 5000000,
+			pushAmt:         lnwire.NewMAtomsFromAtoms(0),
+			updates:         make(chan *lnrpc.OpenStatusUpdate),
+			err:             make(chan error, 1),
+		}
+		alice.fundingMgr.initFundingWorkflow(bob, initReq)
+
+		return assertFundingMsgSent(
+			t, alice.msgChan, "OpenChannel",
+		).(*lnwire.OpenChannel)
+	}
+
+	// The first channel is accepted, which reaches the global limit.
+	bob.fundingMgr.processFundingOpen(openChannel(), alice)
+	_ = assertFundingMsgSent(
+		t, bob.msgChan, "AcceptChannel",
+	).(*lnwire.AcceptChannel)
+
+	// The next one must be rejected with the global limit error, even
+	// though the per peer limit isn't reached.
+	secondOpen := openChannel()
+	bob.fundingMgr.processFundingOpen(secondOpen, alice)
+	errMsg := assertFundingMsgSent(
+		t, bob.msgChan, "Error",
+	).(*lnwire.Error)
+
+	fundingErr, ok := lnwire.ParseFundingError(errMsg.Data)
+	if !ok || fundingErr != lnwire.ErrMaxGlobalPendingChannels {
+		t.Fatalf("expected ErrMaxGlobalPendingChannels, got %v",
+			errMsg.Error())
+	}
+
+	pendingCounts, numPending := bob.fundingMgr.PendingChannelCounts()
+	alicePeer := newSerializedKey(alice.privKey.PubKey())
+	if len(pendingCounts) != 1 || pendingCounts[alicePeer] != 1 ||
+		numPending != 1 {
+
+		t.Fatalf("unexpected pending channel counts: %v",
+			pendingCounts)
+	}
+
+	// Once the global limit is removed, the channel is accepted.
+	bob.fundingMgr.UpdatePendingChannelLimits(maxPending, 0)
+	bob.fundingMgr.processFundingOpen(secondOpen, alice)
+	_ = assertFundingMsgSent(
+		t, bob.msgChan, "AcceptChannel",
+	).(*lnwire.AcceptChannel)
+}
+
+// TestFundingManagerPendingChannelCounts checks that only the channels pending
+// open initiated by the remote peer count towards the pending channel limits,
+// and that they stop counting once they're open.
+func TestFundingManagerPendingChannelCounts(t *testing.T) {
+	t.Parallel()
+
+	alice, bob := setupFundingManagers(t)
+	defer tearDownFundingManagers(t, alice, bob)
+
+	assertCounts := func(node *testNode, peer *testNode,
+		expected uint32) {
+
+		t.Helper()
+
+		counts, numPending := node.fundingMgr.PendingChannelCounts()
+		peerKey := newSerializedKey(peer.privKey.PubKey())
+		if counts[peerKey] != expected || numPending != expected {
+			t.Fatalf("expected %v pending channels, got %v "+
+				"(total %v)", expected, counts[peerKey],
+				numPending)
+		}
+	}
+
+	updateChan := make(chan *lnrpc.OpenStatusUpdate)
+	fundingOutPoint, fundingTx := openChannel(
+		t, alice, bob, 500000, 0, 1, updateChan, true,
+	)
+
+	// Alice initiated the channel, so it only counts for Bob.
+	assertCounts(alice, bob, 0)
+	assertCounts(bob, alice, 1)
+
+	// Once the funding transaction confirms, the channel no longer counts
+	// as pending.
+	alice.mockNotifier.oneConfChannel <- &chainntnfs.TxConfirmation{
+		Tx: fundingTx,
+	}
+	bob.mockNotifier.oneConfChannel <- &chainntnfs.TxConfirmation{
+		Tx: fundingTx,
+	}
+	assertMarkedOpen(t, alice, bob, fundingOutPoint)
+
+	assertCounts(alice, bob, 0)
+	assertCounts(bob, alice, 0)
+}
+
 // TestFundingManagerRejectPush checks behaviour of 'rejectpush'
 // option, namely that non-zero incoming push amounts are disabled.
 func TestFundingManagerRejectPush(t *testing.T) {
@@ -2958,6 +3191,88 @@ func TestFundingManagerRejectPush(t *testing.T) {
 	}
 }
 
+// TestFundingManagerChannelAcceptor ensures that the error of a channel
+// acceptor rejecting a channel is delivered to the initiator, and that the
+// constraints of an accepting channel acceptor override our defaults.
+func TestFundingManagerChannelAcceptor(t *testing.T) {
+	t.Parallel()
+
+	var acceptorResp *chanacceptor.ChannelAcceptResponse
+	acceptor := chanacceptor.NewRPCAcceptor(
+		func(*chanacceptor.ChannelAcceptRequest) *chanacceptor.ChannelAcceptResponse {
+			return acceptorResp
+		},
+	)
+
+	alice, bob := setupFundingManagers(
+		t, func(cfg *fundingConfig) {
+			cfg.OpenChannelPredicate = acceptor
+		},
+	)
+	defer tearDownFundingManagers(t, alice, bob)
+
+	// sendOpenChannel starts a new funding workflow from Alice and returns
+	// the OpenChannel message she sent to Bob.
+	sendOpenChannel := func() *lnwire.OpenChannel {
+		initReq := &openChanReq{
+			targetPubkey:    bob.privKey.PubKey(),
+			chainHash:       activeNetParams.GenesisHash,
+			localFundingAmt: 500000,
+			updates:         make(chan *lnrpc.OpenStatusUpdate),
+			err:             make(chan error, 1),
+		}
+		alice.fundingMgr.initFundingWorkflow(bob, initReq)
+
+		return expectOpenChannelMsg(t, alice.msgChan)
+	}
+
+	// A rejection by the channel acceptor should deliver its error to
+	// Alice.
+	acceptorResp = chanacceptor.NewChannelAcceptResponse(
+		false, errors.New("not today"), 0, 0, 0, 0,
+	)
+	bob.fundingMgr.processFundingOpen(sendOpenChannel(), alice)
+
+	errMsg := assertFundingMsgSent(t, bob.msgChan, "Error").(*lnwire.Error)
+	if !strings.Contains(errMsg.Error(), "not today") {
+		t.Fatalf("expected channel acceptor error, got \"%v\"",
+			errMsg.Error())
+	}
+
+	// When accepting the channel, the constraints set by the channel
+	// acceptor should be the ones Bob requires from Alice.
+	const (
+		csvDelay  = 150
+		reserve   = dcrutil.Amount(20000)
+		minHtlcIn = lnwire.MilliAtom(4000)
+		htlcLimit = 20
+	)
+	acceptorResp = chanacceptor.NewChannelAcceptResponse(
+		true, nil, csvDelay, reserve, minHtlcIn, htlcLimit,
+	)
+	bob.fundingMgr.processFundingOpen(sendOpenChannel(), alice)
+
+	acceptMsg := assertFundingMsgSent(
+		t, bob.msgChan, "AcceptChannel",
+	).(*lnwire.AcceptChannel)
+	if acceptMsg.CsvDelay != csvDelay {
+		t.Fatalf("expected csv delay %v, got %v", csvDelay,
+			acceptMsg.CsvDelay)
+	}
+	if acceptMsg.ChannelReserve != reserve {
+		t.Fatalf("expected reserve %v, got %v", reserve,
+			acceptMsg.ChannelReserve)
+	}
+	if acceptMsg.HtlcMinimum != minHtlcIn {
+		t.Fatalf("expected min htlc %v, got %v", minHtlcIn,
+			acceptMsg.HtlcMinimum)
+	}
+	if acceptMsg.MaxAcceptedHTLCs != htlcLimit {
+		t.Fatalf("expected htlc limit %v, got %v", htlcLimit,
+			acceptMsg.MaxAcceptedHTLCs)
+	}
+}
+
 // TestFundingManagerMaxConfs ensures that we don't accept a funding proposal
 // that proposes a MinAcceptDepth greater than the maximum number of
 // confirmations we're willing to accept.
@@ -3027,6 +3342,56 @@ func TestFundingManagerMaxConfs(t *testing.T) {
 	}
 }
 
+// TestFundingManagerInitiatorConfs ensures that the initiator of a channel
+// waits for its own required number of confirmations if the responder asks for
+// fewer of them.
+func TestFundingManagerInitiatorConfs(t *testing.T) {
+	t.Parallel()
+
+	alice, bob := setupFundingManagers(t, func(cfg *fundingConfig) {
+		cfg.InitiatorNumRequiredConfs = func(
+			chanAmt dcrutil.Amount) uint16 {
+
+			if chanAmt >= 400000 {
+				return 5
+			}
+			return 0
+		}
+	})
+	defer tearDownFundingManagers(t, alice, bob)
+
+	// We will consume the channel updates as we go, so no buffering is
+	// needed.
+	updateChan := make(chan *lnrpc.OpenStatusUpdate)
+
+	// Run through the process of opening the channel, up until the funding
+	// transaction is broadcasted. Bob only asks for 3 confirmations.
+	_, _ = openChannel(t, alice, bob, 500000, 0, 1, updateChan, true)
+
+	assertNumConfsRequired := func(node *testNode, numConfs uint16) {
+		t.Helper()
+
+		pendingChannels, err := node.fundingMgr.cfg.Wallet.Cfg.
+			Database.FetchPendingChannels()
+		if err != nil {
+			t.Fatalf("unable to fetch pending channels: %v", err)
+		}
+		if len(pendingChannels) != 1 {
+			t.Fatalf("expected 1 pending channel, had %v",
+				len(pendingChannels))
+		}
+		if pendingChannels[0].NumConfsRequired != numConfs {
+			t.Fatalf("expected %v required confs, got %v",
+				numConfs, pendingChannels[0].NumConfsRequired)
+		}
+	}
+
+	// Alice requires more confirmations than Bob for a channel of this
+	// size, so she should wait for her own number of confirmations.
+	assertNumConfsRequired(alice, 5)
+	assertNumConfsRequired(bob, 3)
+}
+
 // TestFundingManagerFundAll tests that we can initiate a funding request to
 // use the funds remaining in the wallet. This should produce a funding tx with
 // no change output.
@@ -3213,6 +3578,89 @@ func TestGetUpfrontShutdownScript(t *testing.T) {
 	}
 }
 
+// TestCheckCommitmentType asserts that an explicitly requested commitment type
+// is only accepted when it matches the type negotiated through the features of
+// both peers.
+func TestCheckCommitmentType(t *testing.T) {
+	features := func(bits ...lnwire.FeatureBit) *lnwire.FeatureVector {
+		return lnwire.NewFeatureVector(
+			lnwire.NewRawFeatureVector(bits...), lnwire.Features,
+		)
+	}
+
+	var (
+		legacy    = features()
+		tweakless = features(lnwire.StaticRemoteKeyOptional)
+		anchors   = features(
+			lnwire.StaticRemoteKeyOptional, lnwire.AnchorsOptional,
+		)
+	)
+
+	tests := []struct {
+		name      string
+		requested lnwallet.CommitmentType
+		local     *lnwire.FeatureVector
+		remote    *lnwire.FeatureVector
+		expectErr bool
+	}{
+		{
+			name:      "legacy with legacy peer",
+			requested: lnwallet.CommitmentTypeLegacy,
+			local:     tweakless,
+			remote:    legacy,
+		},
+		{
+			name:      "legacy with tweakless peer",
+			requested: lnwallet.CommitmentTypeLegacy,
+			local:     tweakless,
+			remote:    tweakless,
+			expectErr: true,
+		},
+		{
+			name:      "tweakless with tweakless peer",
+			requested: lnwallet.CommitmentTypeTweakless,
+			local:     anchors,
+			remote:    tweakless,
+		},
+		{
+			name:      "tweakless with legacy peer",
+			requested: lnwallet.CommitmentTypeTweakless,
+			local:     tweakless,
+			remote:    legacy,
+			expectErr: true,
+		},
+		{
+			name:      "anchors not enabled locally",
+			requested: lnwallet.CommitmentTypeAnchors,
+			local:     tweakless,
+			remote:    anchors,
+			expectErr: true,
+		},
+		{
+			name:      "anchors with anchors peer",
+			requested: lnwallet.CommitmentTypeAnchors,
+			local:     anchors,
+			remote:    anchors,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			err := checkCommitmentType(
+				test.requested, test.local, test.remote,
+			)
+			if test.expectErr && err == nil {
+				t.Fatalf("expected error")
+			}
+			if !test.expectErr && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
 func expectOpenChannelMsg(t *testing.T, msgChan chan lnwire.Message) *lnwire.OpenChannel {
 	t.Helper()
 
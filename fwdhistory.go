@@ -0,0 +1,168 @@
+package dcrlnd
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// fwdHistoryFilter selects the forwarding events returned by
+// ForwardingHistory.
+type fwdHistoryFilter struct {
+	// chanIDs are the channels through which the events must have been
+	// forwarded, either incoming or outgoing. Empty matches any channel.
+	chanIDs map[lnwire.ShortChannelID]struct{}
+
+	// peer is set if the events must have been forwarded through a channel
+	// with this peer, either incoming or outgoing.
+	peer *route.Vertex
+
+	// minAmtOut is the minimum forwarded amount.
+	minAmtOut lnwire.MilliAtom
+
+	// minFee is the minimum fee earned.
+	minFee lnwire.MilliAtom
+}
+
+// newFwdHistoryFilter parses the filters of a ForwardingHistory request.
+func newFwdHistoryFilter(
+	req *lnrpc.ForwardingHistoryRequest) (*fwdHistoryFilter, error) {
+
+	filter := &fwdHistoryFilter{
+		minAmtOut: lnwire.MilliAtom(req.MinAmtOutMAtoms),
+		minFee:    lnwire.MilliAtom(req.MinFeeMAtoms),
+	}
+
+	if len(req.ChanIds) > 0 {
+		filter.chanIDs = make(map[lnwire.ShortChannelID]struct{})
+		for _, chanID := range req.ChanIds {
+			scid := lnwire.NewShortChanIDFromInt(chanID)
+			filter.chanIDs[scid] = struct{}{}
+		}
+	}
+
+	if req.PeerPubkey != "" {
+		peer, err := route.NewVertexFromStr(req.PeerPubkey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer pubkey: %v", err)
+		}
+		filter.peer = &peer
+	}
+
+	return filter, nil
+}
+
+// active returns whether any filter is set.
+func (f *fwdHistoryFilter) active() bool {
+	return len(f.chanIDs) > 0 || f.peer != nil || f.minAmtOut > 0 ||
+		f.minFee > 0
+}
+
+// matches returns whether the event passes the filter. The peers of the
+// channels are looked up in chanPeers, which is only needed when filtering by
+// peer.
+func (f *fwdHistoryFilter) matches(event *channeldb.ForwardingEvent,
+	chanPeers map[lnwire.ShortChannelID]route.Vertex) bool {
+
+	if event.AmtOut < f.minAmtOut {
+		return false
+	}
+	if fwdEventFee(event) < f.minFee {
+		return false
+	}
+
+	if len(f.chanIDs) > 0 {
+		_, in := f.chanIDs[event.IncomingChanID]
+		_, out := f.chanIDs[event.OutgoingChanID]
+		if !in && !out {
+			return false
+		}
+	}
+
+	if f.peer != nil {
+		peerIn, okIn := chanPeers[event.IncomingChanID]
+		peerOut, okOut := chanPeers[event.OutgoingChanID]
+		if !(okIn && peerIn == *f.peer) &&
+			!(okOut && peerOut == *f.peer) {
+
+			return false
+		}
+	}
+
+	return true
+}
+
+// fetchChanPeers returns the peers of all our open and closed channels, keyed
+// by short channel ID.
+func (r *rpcServer) fetchChanPeers() (map[lnwire.ShortChannelID]route.Vertex,
+	error) {
+
+	chanPeers := make(map[lnwire.ShortChannelID]route.Vertex)
+
+	openChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range openChannels {
+		chanPeers[channel.ShortChannelID] = route.NewVertex(
+			channel.IdentityPub,
+		)
+	}
+
+	closedChannels, err := r.server.remoteChanDB.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range closedChannels {
+		chanPeers[channel.ShortChanID] = route.NewVertex(
+			channel.RemotePub,
+		)
+	}
+
+	return chanPeers, nil
+}
+
+// peerAliasLookup returns a function resolving the alias of the peer of a
+// channel. Aliases are cached, so every peer is only looked up once in the
+// graph. Unknown peers and peers without a node announcement resolve to an
+// empty alias.
+func (r *rpcServer) peerAliasLookup(
+	chanPeers map[lnwire.ShortChannelID]route.Vertex) func(
+	lnwire.ShortChannelID) string {
+
+	graph := r.server.localChanDB.ChannelGraph()
+	aliases := make(map[route.Vertex]string)
+
+	return func(chanID lnwire.ShortChannelID) string {
+		peer, ok := chanPeers[chanID]
+		if !ok {
+			return ""
+		}
+
+		if alias, ok := aliases[peer]; ok {
+			return alias
+		}
+
+		var alias string
+		node, err := graph.FetchLightningNode(nil, peer)
+		if err == nil {
+			alias = node.Alias
+		}
+		aliases[peer] = alias
+
+		return alias
+	}
+}
+
+// fwdEventFee returns the fee earned by a forwarding event. Failed forwards
+// earned no fee, as their incoming htlc was failed back.
+func fwdEventFee(event *channeldb.ForwardingEvent) lnwire.MilliAtom {
+	if event.Failed {
+		return 0
+	}
+
+	return event.AmtIn - event.AmtOut
+}
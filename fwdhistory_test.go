@@ -0,0 +1,151 @@
+package dcrlnd
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// TestFwdHistoryFilter asserts that forwarding events are filtered by channel,
+// peer, amount and fee.
+func TestFwdHistoryFilter(t *testing.T) {
+	t.Parallel()
+
+	var peerA, peerB route.Vertex
+	peerA[0] = 2
+	peerB[0] = 3
+
+	chanA := lnwire.NewShortChanIDFromInt(1)
+	chanB := lnwire.NewShortChanIDFromInt(2)
+	chanC := lnwire.NewShortChanIDFromInt(3)
+	chanPeers := map[lnwire.ShortChannelID]route.Vertex{
+		chanA: peerA,
+		chanB: peerB,
+	}
+
+	event := &channeldb.ForwardingEvent{
+		IncomingChanID: chanA,
+		OutgoingChanID: chanC,
+		AmtIn:          10100,
+		AmtOut:         10000,
+	}
+
+	testCases := []struct {
+		name    string
+		req     *lnrpc.ForwardingHistoryRequest
+		active  bool
+		matches bool
+	}{{
+		name:    "no filter",
+		req:     &lnrpc.ForwardingHistoryRequest{},
+		matches: true,
+	}, {
+		name: "incoming channel",
+		req: &lnrpc.ForwardingHistoryRequest{
+			ChanIds: []uint64{chanB.ToUint64(), chanA.ToUint64()},
+		},
+		active:  true,
+		matches: true,
+	}, {
+		name: "outgoing channel",
+		req: &lnrpc.ForwardingHistoryRequest{
+			ChanIds: []uint64{chanC.ToUint64()},
+		},
+		active:  true,
+		matches: true,
+	}, {
+		name: "other channel",
+		req: &lnrpc.ForwardingHistoryRequest{
+			ChanIds: []uint64{chanB.ToUint64()},
+		},
+		active: true,
+	}, {
+		name: "peer",
+		req: &lnrpc.ForwardingHistoryRequest{
+			PeerPubkey: peerA.String(),
+		},
+		active:  true,
+		matches: true,
+	}, {
+		name: "other peer",
+		req: &lnrpc.ForwardingHistoryRequest{
+			PeerPubkey: peerB.String(),
+		},
+		active: true,
+	}, {
+		name: "min amount",
+		req: &lnrpc.ForwardingHistoryRequest{
+			MinAmtOutMAtoms: 10000,
+		},
+		active:  true,
+		matches: true,
+	}, {
+		name: "amount too low",
+		req: &lnrpc.ForwardingHistoryRequest{
+			MinAmtOutMAtoms: 10001,
+		},
+		active: true,
+	}, {
+		name: "min fee",
+		req: &lnrpc.ForwardingHistoryRequest{
+			MinFeeMAtoms: 100,
+		},
+		active:  true,
+		matches: true,
+	}, {
+		name: "fee too low",
+		req: &lnrpc.ForwardingHistoryRequest{
+			MinFeeMAtoms: 101,
+		},
+		active: true,
+	}}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			filter, err := newFwdHistoryFilter(test.req)
+			if err != nil {
+				t.Fatalf("unable to create filter: %v", err)
+			}
+
+			if filter.active() != test.active {
+				t.Fatalf("expected active %v, got %v",
+					test.active, filter.active())
+			}
+
+			matches := filter.matches(event, chanPeers)
+			if matches != test.matches {
+				t.Fatalf("expected match %v, got %v",
+					test.matches, matches)
+			}
+		})
+	}
+
+	_, err := newFwdHistoryFilter(&lnrpc.ForwardingHistoryRequest{
+		PeerPubkey: "invalid",
+	})
+	if err == nil {
+		t.Fatalf("expected invalid peer pubkey error")
+	}
+
+	// A failed forward earned no fee, even if it offered a hold fee.
+	failed := *event
+	failed.HoldFee = 30
+	failed.Failed = true
+
+	filter, err := newFwdHistoryFilter(&lnrpc.ForwardingHistoryRequest{
+		MinFeeMAtoms: 1,
+	})
+	if err != nil {
+		t.Fatalf("unable to create filter: %v", err)
+	}
+	if filter.matches(&failed, chanPeers) {
+		t.Fatalf("expected failed event to earn no fee")
+	}
+	if fee := fwdEventFee(&failed); fee != 0 {
+		t.Fatalf("expected fee of failed event 0, got %v", fee)
+	}
+}
@@ -54,6 +54,11 @@ type PaymentCircuit struct {
 	// sending it back to the originator of the payment.
 	ErrorEncrypter hop.ErrorEncrypter
 
+	// HoldFee is the experimental hold fee offered by the incoming htlc,
+	// which is only received if the forward is settled. It is zero if no
+	// hold fee was offered or none is required.
+	HoldFee lnwire.MilliAtom
+
 	// LoadedFromDisk is set true for any circuits loaded after the circuit
 	// map is reloaded from disk.
 	//
@@ -148,11 +153,21 @@ func (c *PaymentCircuit) Encode(w io.Writer) error {
 	}
 
 	// Skip encoding of error encrypter if this half add does not have one.
-	if encrypterType == hop.EncrypterTypeNone {
+	if encrypterType != hop.EncrypterTypeNone {
+		if err := c.ErrorEncrypter.Encode(w); err != nil {
+			return err
+		}
+	}
+
+	// The hold fee is appended only if one was offered, so that the
+	// encoding of all other circuits remains unchanged.
+	if c.HoldFee == 0 {
 		return nil
 	}
 
-	return c.ErrorEncrypter.Encode(w)
+	binary.BigEndian.PutUint64(scratch[:], uint64(c.HoldFee))
+	_, err = w.Write(scratch[:])
+	return err
 }
 
 // Decode reads a PaymentCircuit from the provided io.Reader.
@@ -194,7 +209,6 @@ func (c *PaymentCircuit) Decode(r io.Reader) error {
 	case hop.EncrypterTypeNone:
 		// No encrypter was provided, such as when the payment is
 		// locally initiated.
-		return nil
 
 	case hop.EncrypterTypeSphinx:
 		// Sphinx encrypter was used as this is a forwarded HTLC.
@@ -208,7 +222,23 @@ func (c *PaymentCircuit) Decode(r io.Reader) error {
 		return UnknownEncrypterType(encrypterType)
 	}
 
-	return c.ErrorEncrypter.Decode(r)
+	if c.ErrorEncrypter != nil {
+		if err := c.ErrorEncrypter.Decode(r); err != nil {
+			return err
+		}
+	}
+
+	// Circuits that weren't offered a hold fee end here.
+	_, err = io.ReadFull(r, scratch[:])
+	switch {
+	case err == io.EOF:
+		return nil
+	case err != nil:
+		return err
+	}
+	c.HoldFee = lnwire.MilliAtom(binary.BigEndian.Uint64(scratch[:]))
+
+	return nil
 }
 
 // InKey returns the primary identifier for the circuit corresponding to the
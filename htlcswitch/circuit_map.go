@@ -123,6 +123,9 @@ type CircuitMap interface {
 	// NumOpen returns the number of circuits with HTLCs that have been
 	// forwarded via an outgoing link.
 	NumOpen() int
+
+	// Circuits returns all active circuits added by CommitCircuits.
+	Circuits() []*PaymentCircuit
 }
 
 var (
@@ -944,3 +947,17 @@ func (cm *circuitMap) NumOpen() int {
 
 	return len(cm.opened)
 }
+
+// Circuits returns all active circuits that have been committed to the
+// circuit map, including those that have been opened.
+func (cm *circuitMap) Circuits() []*PaymentCircuit {
+	cm.mtx.RLock()
+	defer cm.mtx.RUnlock()
+
+	circuits := make([]*PaymentCircuit, 0, len(cm.pending))
+	for _, circuit := range cm.pending {
+		circuits = append(circuits, circuit)
+	}
+
+	return circuits
+}
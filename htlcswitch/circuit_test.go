@@ -133,6 +133,7 @@ var halfCircuitTests = []struct {
 	chanID    lnwire.ShortChannelID
 	htlcID    uint64
 	encrypter hop.ErrorEncrypter
+	holdFee   lnwire.MilliAtom
 }{
 	{
 		hash:      hash1,
@@ -160,6 +161,16 @@ var halfCircuitTests = []struct {
 		// is fully-initialized in initTestExtracter, which should
 		// repopulate this encrypter.
 		encrypter: testExtracter,
+		holdFee:   500,
+	},
+	{
+		hash:      hash1,
+		inValue:   10000,
+		outValue:  9000,
+		chanID:    lnwire.NewShortChanIDFromInt(4),
+		htlcID:    4,
+		encrypter: nil,
+		holdFee:   500,
 	},
 }
 
@@ -182,6 +193,7 @@ func TestHalfCircuitSerialization(t *testing.T) {
 				HtlcID: test.htlcID,
 			},
 			ErrorEncrypter: test.encrypter,
+			HoldFee:        test.holdFee,
 		}
 
 		// Write the half circuit to our buffer.
@@ -54,6 +54,11 @@ const (
 	// OutgoingFailureForwardsDisabled is returned when the switch is
 	// configured to disallow forwards.
 	OutgoingFailureForwardsDisabled
+
+	// OutgoingFailureReputationBucketFull is returned when an htlc from
+	// an unreputable peer would exceed the share of the outgoing link
+	// reserved for such peers.
+	OutgoingFailureReputationBucketFull
 )
 
 // FailureString returns the string representation of a failure detail.
@@ -91,6 +96,9 @@ func (fd OutgoingFailure) FailureString() string {
 	case OutgoingFailureForwardsDisabled:
 		return "node configured to disallow forwards"
 
+	case OutgoingFailureReputationBucketFull:
+		return "htlc slots of unreputable peers exhausted"
+
 	default:
 		return "unknown failure detail"
 	}
@@ -0,0 +1,28 @@
+// +build dev
+
+package holdfee
+
+import "github.com/decred/dcrlnd/lnwire"
+
+// Config holds the command line options of the experimental hold fee charged
+// for forwarding htlcs.
+//
+// NOTE: THESE OPTIONS ARE EXPERIMENTAL. HTLCS THAT DON'T OFFER THE REQUIRED
+// HOLD FEE ARE REJECTED, WHICH MOST SENDERS DON'T SUPPORT.
+type Config struct {
+	BaseFee uint64 `long:"basefee" description:"The fixed hold fee (in milli-atoms) required for each forwarded htlc, on top of the forwarding fee"`
+
+	FeeRate uint32 `long:"feerate" description:"The hold fee rate (in millionths) required for each forwarded htlc, on top of the forwarding fee"`
+}
+
+// Policy returns the hold fee policy specified in the configuration.
+//
+// NOTE: The value returned here will only honor the configuration if the dev
+// build flag is present. In production, this method always returns an
+// inactive policy.
+func (c *Config) Policy() Policy {
+	return Policy{
+		BaseFee: lnwire.MilliAtom(c.BaseFee),
+		FeeRate: c.FeeRate,
+	}
+}
@@ -0,0 +1,11 @@
+// +build !dev
+
+package holdfee
+
+// Config is an empty struct disabling the hold fee options in production.
+type Config struct{}
+
+// Policy in production always returns an inactive policy.
+func (c *Config) Policy() Policy {
+	return Policy{}
+}
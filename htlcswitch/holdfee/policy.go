@@ -0,0 +1,64 @@
+package holdfee
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+)
+
+var (
+	// ErrMissingHoldFee is returned when an htlc doesn't carry the hold fee
+	// record while a hold fee is required.
+	ErrMissingHoldFee = errors.New("missing hold fee record")
+
+	// ErrInvalidHoldFee is returned when the hold fee record of an htlc
+	// can't be decoded.
+	ErrInvalidHoldFee = errors.New("invalid hold fee record")
+)
+
+// Policy describes the hold fee charged for accepting an htlc to be forwarded.
+// The hold fee compensates a forwarding node for locking up its liquidity,
+// and must be offered by the sender through the HoldFeeType custom record of
+// the hop's onion payload. It is paid on top of the regular forwarding fee,
+// and is only received if the htlc is settled.
+type Policy struct {
+	// BaseFee is the fixed hold fee charged for each forwarded htlc.
+	BaseFee lnwire.MilliAtom
+
+	// FeeRate is the hold fee charged proportionally to the amount
+	// forwarded, expressed in millionths.
+	FeeRate uint32
+}
+
+// Active returns true if the policy requires a hold fee to be paid.
+func (p Policy) Active() bool {
+	return p.BaseFee != 0 || p.FeeRate != 0
+}
+
+// Fee returns the hold fee required to forward an htlc of the given amount.
+func (p Policy) Fee(amt lnwire.MilliAtom) lnwire.MilliAtom {
+	return p.BaseFee + (amt*lnwire.MilliAtom(p.FeeRate))/1000000
+}
+
+// EncodeRecord encodes the hold fee offered to a hop as a custom record value.
+func EncodeRecord(fee lnwire.MilliAtom) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(fee))
+	return b[:]
+}
+
+// ParseRecord extracts the hold fee offered by the sender of an htlc from its
+// custom records.
+func ParseRecord(records record.CustomSet) (lnwire.MilliAtom, error) {
+	value, ok := records[record.HoldFeeType]
+	if !ok {
+		return 0, ErrMissingHoldFee
+	}
+	if len(value) != 8 {
+		return 0, ErrInvalidHoldFee
+	}
+
+	return lnwire.MilliAtom(binary.BigEndian.Uint64(value)), nil
+}
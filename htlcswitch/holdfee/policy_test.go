@@ -0,0 +1,82 @@
+package holdfee_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/htlcswitch/holdfee"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+)
+
+// TestPolicyFee asserts that the hold fee is computed from the base fee and
+// fee rate of the policy.
+func TestPolicyFee(t *testing.T) {
+	tests := []struct {
+		policy holdfee.Policy
+		amt    lnwire.MilliAtom
+		fee    lnwire.MilliAtom
+		active bool
+	}{
+		{
+			policy: holdfee.Policy{},
+			amt:    1000000,
+			fee:    0,
+		},
+		{
+			policy: holdfee.Policy{BaseFee: 10},
+			amt:    1000000,
+			fee:    10,
+			active: true,
+		},
+		{
+			policy: holdfee.Policy{FeeRate: 100},
+			amt:    1000000,
+			fee:    100,
+			active: true,
+		},
+		{
+			policy: holdfee.Policy{BaseFee: 10, FeeRate: 100},
+			amt:    5000000,
+			fee:    510,
+			active: true,
+		},
+	}
+
+	for i, test := range tests {
+		if test.policy.Active() != test.active {
+			t.Fatalf("test %d: expected active %v", i, test.active)
+		}
+
+		fee := test.policy.Fee(test.amt)
+		if fee != test.fee {
+			t.Fatalf("test %d: expected fee %v, got %v", i,
+				test.fee, fee)
+		}
+	}
+}
+
+// TestParseRecord asserts that the hold fee record round trips and that
+// missing or malformed records are rejected.
+func TestParseRecord(t *testing.T) {
+	fee, err := holdfee.ParseRecord(record.CustomSet{
+		record.HoldFeeType: holdfee.EncodeRecord(1234),
+	})
+	if err != nil {
+		t.Fatalf("unable to parse record: %v", err)
+	}
+	if fee != 1234 {
+		t.Fatalf("expected fee 1234, got %v", fee)
+	}
+
+	_, err = holdfee.ParseRecord(record.CustomSet{})
+	if err != holdfee.ErrMissingHoldFee {
+		t.Fatalf("expected missing hold fee, got %v", err)
+	}
+
+	_, err = holdfee.ParseRecord(record.CustomSet{
+		record.HoldFeeType: {1, 2, 3},
+	})
+	if err != holdfee.ErrInvalidHoldFee {
+		t.Fatalf("expected invalid hold fee, got %v", err)
+	}
+}
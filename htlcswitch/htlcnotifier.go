@@ -253,15 +253,17 @@ type LinkFailEvent struct {
 
 // ForwardingFailEvent represents a htlc failure which occurred down the line
 // after we forwarded a htlc onwards. An error is not included in this event
-// because errors returned down the route are encrypted. HtlcInfo is not
-// reliably available for forwarding failures, so it is omitted. These events
-// should be matched with their corresponding forward event to obtain this
-// information.
+// because errors returned down the route are encrypted. Only the amounts of
+// the HtlcInfo are known when the failure comes back, the timelocks are
+// obtained by matching these events with their corresponding forward event.
 type ForwardingFailEvent struct {
 	// HtlcKey uniquely identifies the htlc, and can be used to match the
 	// htlc with its corresponding forwarding event.
 	HtlcKey
 
+	// HtlcInfo contains the amounts of the htlc, if known.
+	HtlcInfo
+
 	// HtlcEventType classifies the event as part of a local send or
 	// receive, or as part of a forward.
 	HtlcEventType
@@ -270,15 +272,18 @@ type ForwardingFailEvent struct {
 	Timestamp time.Time
 }
 
-// SettleEvent represents a htlc that was settled. HtlcInfo is not reliably
-// available for forwarding failures, so it is omitted. These events should
-// be matched with corresponding forward events or invoices (for receives)
-// to obtain additional information about the htlc.
+// SettleEvent represents a htlc that was settled. Only the amounts of the
+// HtlcInfo are reliably known when a htlc is settled. These events should be
+// matched with corresponding forward events or invoices (for receives) to
+// obtain additional information about the htlc.
 type SettleEvent struct {
 	// HtlcKey uniquely identifies the htlc, and can be used to match
 	// forwards with their corresponding forwarding event.
 	HtlcKey
 
+	// HtlcInfo contains the amounts of the htlc, if known.
+	HtlcInfo
+
 	// HtlcEventType classifies the event as part of a local send or
 	// receive, or as part of a forward.
 	HtlcEventType
@@ -337,11 +342,12 @@ func (h *HtlcNotifier) NotifyLinkFailEvent(key HtlcKey, info HtlcInfo,
 // forwarded has failed down the line.
 //
 // Note this is part of the htlcNotifier interface.
-func (h *HtlcNotifier) NotifyForwardingFailEvent(key HtlcKey,
+func (h *HtlcNotifier) NotifyForwardingFailEvent(key HtlcKey, info HtlcInfo,
 	eventType HtlcEventType) {
 
 	event := &ForwardingFailEvent{
 		HtlcKey:       key,
+		HtlcInfo:      info,
 		HtlcEventType: eventType,
 		Timestamp:     h.now(),
 	}
@@ -358,9 +364,12 @@ func (h *HtlcNotifier) NotifyForwardingFailEvent(key HtlcKey,
 // to as part of a forward or a receive to our node has been settled.
 //
 // Note this is part of the htlcNotifier interface.
-func (h *HtlcNotifier) NotifySettleEvent(key HtlcKey, eventType HtlcEventType) {
+func (h *HtlcNotifier) NotifySettleEvent(key HtlcKey, info HtlcInfo,
+	eventType HtlcEventType) {
+
 	event := &SettleEvent{
 		HtlcKey:       key,
+		HtlcInfo:      info,
 		HtlcEventType: eventType,
 		Timestamp:     h.now(),
 	}
@@ -411,6 +420,20 @@ func newHtlcInfo(pkt *htlcPacket) HtlcInfo {
 	}
 }
 
+// newResolvedHtlcInfo returns the HtlcInfo of a settle or fail packet. The
+// amounts are taken from the circuit closed by the packet, as settle and fail
+// messages don't carry them. The timelocks are not known at this point.
+func newResolvedHtlcInfo(pkt *htlcPacket) HtlcInfo {
+	if pkt.circuit == nil {
+		return HtlcInfo{}
+	}
+
+	return HtlcInfo{
+		IncomingAmt: pkt.circuit.IncomingAmount,
+		OutgoingAmt: pkt.circuit.OutgoingAmount,
+	}
+}
+
 // getEventType returns the htlc type based on the fields set in the htlc
 // packet. Sends that originate at our node have the source (zero) incoming
 // channel ID. Receives to our node have the exit (zero) outgoing channel ID
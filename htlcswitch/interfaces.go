@@ -105,15 +105,24 @@ type ChannelLink interface {
 	// policy to govern if it an incoming HTLC should be forwarded or not.
 	UpdateForwardingPolicy(ForwardingPolicy)
 
+	// UpdateOutgoingCltvRejectDelta updates the number of blocks before
+	// the expiry of an htlc at which the link no longer offers it to the
+	// remote party.
+	UpdateOutgoingCltvRejectDelta(uint32)
+
 	// CheckHtlcForward should return a nil error if the passed HTLC
 	// details satisfy the current forwarding policy fo the target link.
 	// Otherwise, a LinkError with a valid protocol failure message should
 	// be returned in order to signal to the source of the HTLC, the policy
-	// consistency issue.
+	// consistency issue. The inbound fee of the incoming link adds to the
+	// fee required by the target link. When that inbound fee is what
+	// makes the HTLC fail, the failure carries the update of the incoming
+	// channel instead of the target one.
 	CheckHtlcForward(payHash [32]byte, incomingAmt lnwire.MilliAtom,
 		amtToForward lnwire.MilliAtom,
 		incomingTimeout, outgoingTimeout uint32,
-		heightNow uint32) *LinkError
+		incomingChanID lnwire.ShortChannelID,
+		inboundFee lnwire.InboundFee, heightNow uint32) *LinkError
 
 	// CheckHtlcTransit should return a nil error if the passed HTLC
 	// details satisfy the current channel policy.  Otherwise, a LinkError
@@ -131,6 +140,11 @@ type ChannelLink interface {
 	// HTLC's which have been set to the over flow queue.
 	Bandwidth() lnwire.MilliAtom
 
+	// OutgoingHtlcLimits returns the maximum number of htlcs and the
+	// maximum total value (in milli-atoms) that the remote peer accepts
+	// in flight from us at once.
+	OutgoingHtlcLimits() (uint16, lnwire.MilliAtom)
+
 	// Stats return the statistics of channel link. Number of updates,
 	// total sent/received milli-atoms.
 	Stats() (uint64, lnwire.MilliAtom, lnwire.MilliAtom)
@@ -274,10 +288,11 @@ type htlcNotifier interface {
 
 	// NotifyForwardingFailEvent notifies the HtlcNotifier that a htlc we
 	// forwarded has failed down the line.
-	NotifyForwardingFailEvent(key HtlcKey, eventType HtlcEventType)
+	NotifyForwardingFailEvent(key HtlcKey, info HtlcInfo,
+		eventType HtlcEventType)
 
 	// NotifySettleEvent notifies the HtlcNotifier that a htlc that we
 	// committed to as part of a forward or a receive to our node has been
 	// settled.
-	NotifySettleEvent(key HtlcKey, eventType HtlcEventType)
+	NotifySettleEvent(key HtlcKey, info HtlcInfo, eventType HtlcEventType)
 }
@@ -17,6 +17,7 @@ import (
 	"github.com/decred/dcrlnd/build"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/contractcourt"
+	"github.com/decred/dcrlnd/crashreport"
 	"github.com/decred/dcrlnd/htlcswitch/hodl"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
 	"github.com/decred/dcrlnd/invoices"
@@ -98,6 +99,11 @@ type ForwardingPolicy struct {
 	//    per-hop payload of the incoming HTLC's onion packet.
 	TimeLockDelta uint32
 
+	// InboundFee is the fee charged, or discounted if negative, for HTLCs
+	// received through the link and forwarded over any outgoing link, in
+	// addition to the fee of the outgoing link.
+	InboundFee lnwire.InboundFee
+
 	// TODO(roasbeef): add fee module inside of switch
 }
 
@@ -135,6 +141,11 @@ type ChannelLinkConfig struct {
 	// TODO(conner): remove after refactoring htlcswitch testing framework.
 	Switch *Switch
 
+	// SettleLocalPayment is called with the preimage of every htlc settled
+	// by the link as the exit hop, so that the switch can settle the
+	// payments to ourselves it belongs to.
+	SettleLocalPayment func(lntypes.Preimage)
+
 	// ForwardPackets attempts to forward the batch of htlcs through the
 	// switch. The function returns and error in case it fails to send one or
 	// more packets. The link's quit signal should be provided to allow
@@ -402,6 +413,12 @@ func NewChannelLink(cfg ChannelLinkConfig,
 	channel *lnwallet.LightningChannel) ChannelLink {
 
 	logPrefix := fmt.Sprintf("ChannelLink(%v):", channel.ShortChanID())
+	peerPub := cfg.Peer.PubKey()
+	logger := build.NewTaggedPrefixLog(
+		logPrefix, log, Subsystem,
+		build.ChannelLogTag(channel.ChannelPoint()),
+		build.PeerLogTag(peerPub[:]),
+	)
 
 	return &channelLink{
 		cfg:         cfg,
@@ -411,7 +428,7 @@ func NewChannelLink(cfg ChannelLinkConfig,
 		htlcUpdates:    make(chan *contractcourt.ContractUpdate),
 		hodlMap:        make(map[channeldb.CircuitKey]hodlHtlc),
 		hodlQueue:      queue.NewConcurrentQueue(10),
-		log:            build.NewPrefixLog(logPrefix, log),
+		log:            logger,
 		quit:           make(chan struct{}),
 		localUpdateAdd: make(chan *localUpdateAddMsg),
 	}
@@ -622,7 +639,16 @@ func shouldAdjustCommitFee(netFee, chanFee chainfee.AtomPerKByte) bool {
 func (l *channelLink) createFailureWithUpdate(
 	cb func(update *lnwire.ChannelUpdate) lnwire.FailureMessage) lnwire.FailureMessage {
 
-	update, err := l.cfg.FetchLastChannelUpdate(l.ShortChanID())
+	return l.createFailureWithChanUpdate(l.ShortChanID(), cb)
+}
+
+// createFailureWithChanUpdate retrieves the last channel update message of the
+// given channel and passes it into the callback. It expects a fully populated
+// failure message.
+func (l *channelLink) createFailureWithChanUpdate(chanID lnwire.ShortChannelID,
+	cb func(update *lnwire.ChannelUpdate) lnwire.FailureMessage) lnwire.FailureMessage {
+
+	update, err := l.cfg.FetchLastChannelUpdate(chanID)
 	if err != nil {
 		return &lnwire.FailTemporaryNodeFailure{}
 	}
@@ -889,6 +915,7 @@ func (l *channelLink) htlcManager() {
 		l.wg.Done()
 		l.log.Infof("exited")
 	}()
+	defer crashreport.Recover(Subsystem)
 
 	l.log.Infof("HTLC manager started, bandwidth=%v", l.Bandwidth())
 
@@ -1425,6 +1452,7 @@ func (l *channelLink) handleDownstreamPkt(pkt *htlcPacket) {
 		// Send a settle event notification to htlcNotifier.
 		l.cfg.HtlcNotifier.NotifySettleEvent(
 			newHtlcKey(pkt),
+			newResolvedHtlcInfo(pkt),
 			getEventType(pkt),
 		)
 
@@ -1502,7 +1530,8 @@ func (l *channelLink) handleDownstreamPkt(pkt *htlcPacket) {
 			)
 		} else {
 			l.cfg.HtlcNotifier.NotifyForwardingFailEvent(
-				newHtlcKey(pkt), getEventType(pkt),
+				newHtlcKey(pkt), newResolvedHtlcInfo(pkt),
+				getEventType(pkt),
 			)
 		}
 
@@ -2126,6 +2155,16 @@ func (l *channelLink) Bandwidth() lnwire.MilliAtom {
 	return l.channel.AvailableBalance()
 }
 
+// OutgoingHtlcLimits returns the maximum number of htlcs and the maximum total
+// value that the remote peer accepts in flight from us at once, as negotiated
+// when the channel was opened.
+//
+// NOTE: Part of the ChannelLink interface.
+func (l *channelLink) OutgoingHtlcLimits() (uint16, lnwire.MilliAtom) {
+	remoteCfg := l.channel.State().RemoteChanCfg
+	return remoteCfg.MaxAcceptedHtlcs, remoteCfg.MaxPendingAmount
+}
+
 // AttachMailBox updates the current mailbox used by this link, and hooks up
 // the mailbox's message and packet outboxes to the link's upstream and
 // downstream chans, respectively.
@@ -2151,6 +2190,17 @@ func (l *channelLink) UpdateForwardingPolicy(newPolicy ForwardingPolicy) {
 	l.cfg.FwrdingPolicy = newPolicy
 }
 
+// UpdateOutgoingCltvRejectDelta updates the number of blocks before the expiry
+// of an htlc at which the link no longer offers it to the remote party.
+//
+// NOTE: Part of the ChannelLink interface.
+func (l *channelLink) UpdateOutgoingCltvRejectDelta(delta uint32) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.cfg.OutgoingCltvRejectDelta = delta
+}
+
 // CheckHtlcForward should return a nil error if the passed HTLC details
 // satisfy the current forwarding policy fo the target link. Otherwise,
 // a LinkError with a valid protocol failure message should be returned
@@ -2161,7 +2211,8 @@ func (l *channelLink) UpdateForwardingPolicy(newPolicy ForwardingPolicy) {
 func (l *channelLink) CheckHtlcForward(payHash [32]byte,
 	incomingHtlcAmt, amtToForward lnwire.MilliAtom,
 	incomingTimeout, outgoingTimeout uint32,
-	heightNow uint32) *LinkError {
+	incomingChanID lnwire.ShortChannelID,
+	inboundFee lnwire.InboundFee, heightNow uint32) *LinkError {
 
 	l.RLock()
 	policy := l.cfg.FwrdingPolicy
@@ -2178,22 +2229,36 @@ func (l *channelLink) CheckHtlcForward(payHash [32]byte,
 	// Next, using the amount of the incoming HTLC, we'll calculate the
 	// expected fee this incoming HTLC must carry in order to satisfy the
 	// constraints of the outgoing link.
-	expectedFee := ExpectedFee(policy, amtToForward)
+	outFee := ExpectedFee(policy, amtToForward)
+
+	// The inbound fee of the incoming link is then computed over the
+	// outgoing amount plus the outgoing fee. Both fees are rounded
+	// separately, as senders compute them, and their sum is the expected
+	// fee. A negative inbound fee can discount at most the outgoing fee,
+	// as the incoming amount can't be lower than the outgoing one.
+	expectedFee := int64(outFee) + inboundFee.Fee(amtToForward+outFee)
 
 	// If the actual fee is less than our expected fee, then we'll reject
 	// this HTLC as it didn't provide a sufficient amount of fees, or the
 	// values have been tampered with, or the send used incorrect/dated
 	// information to construct the forwarding information for this hop. In
 	// any case, we'll cancel this HTLC.
-	actualFee := incomingHtlcAmt - amtToForward
+	actualFee := int64(incomingHtlcAmt) - int64(amtToForward)
 	if incomingHtlcAmt < amtToForward || actualFee < expectedFee {
 		l.log.Errorf("outgoing htlc(%x) has insufficient fee: "+
-			"expected %v, got %v",
-			payHash[:], int64(expectedFee), int64(actualFee))
+			"expected %v, got %v", payHash[:], expectedFee, actualFee)
 
 		// As part of the returned error, we'll send our latest routing
 		// policy so the sending node obtains the most up to date data.
-		failure := l.createFailureWithUpdate(
+		// If the outgoing fee alone was paid, the inbound fee is what
+		// the sender missed, so we send the policy of the incoming
+		// channel which carries it.
+		updateChanID := l.ShortChanID()
+		if incomingHtlcAmt >= amtToForward && actualFee >= int64(outFee) {
+			updateChanID = incomingChanID
+		}
+		failure := l.createFailureWithChanUpdate(
+			updateChanID,
 			func(upd *lnwire.ChannelUpdate) lnwire.FailureMessage {
 				return lnwire.NewFeeInsufficient(
 					amtToForward, *upd,
@@ -2291,7 +2356,10 @@ func (l *channelLink) canSendHtlc(policy ForwardingPolicy,
 	// We want to avoid offering an HTLC which will expire in the near
 	// future, so we'll reject an HTLC if the outgoing expiration time is
 	// too close to the current height.
-	if timeout <= heightNow+l.cfg.OutgoingCltvRejectDelta {
+	l.RLock()
+	rejectDelta := l.cfg.OutgoingCltvRejectDelta
+	l.RUnlock()
+	if timeout <= heightNow+rejectDelta {
 		l.log.Errorf("htlc(%x) has an expiry that's too soon: "+
 			"outgoing_expiry=%v, best_height=%v", payHash[:],
 			timeout, heightNow)
@@ -2577,6 +2645,10 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 
 	var switchPackets []*htlcPacket
 
+	l.RLock()
+	inboundFee := l.cfg.FwrdingPolicy.InboundFee
+	l.RUnlock()
+
 	for i, pd := range lockedInHtlcs {
 		idx := uint16(i)
 
@@ -2733,6 +2805,7 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 					incomingTimeout: pd.Timeout,
 					outgoingTimeout: fwdInfo.OutgoingCTLV,
 					customRecords:   pld.CustomRecords(),
+					inboundFee:      inboundFee,
 				}
 				switchPackets = append(
 					switchPackets, updatePacket,
@@ -2797,6 +2870,7 @@ func (l *channelLink) processRemoteAdds(fwdPkg *channeldb.FwdPkg,
 					incomingTimeout: pd.Timeout,
 					outgoingTimeout: fwdInfo.OutgoingCTLV,
 					customRecords:   pld.CustomRecords(),
+					inboundFee:      inboundFee,
 				}
 
 				fwdPkg.FwdFilter.Set(idx)
@@ -2928,6 +3002,12 @@ func (l *channelLink) settleHTLC(preimage lntypes.Preimage,
 		return fmt.Errorf("unable to settle htlc: %v", err)
 	}
 
+	// If the htlc is the last hop of a payment we sent to ourselves, let
+	// the switch settle the attempt.
+	if l.cfg.SettleLocalPayment != nil {
+		l.cfg.SettleLocalPayment(preimage)
+	}
+
 	// If the link is in hodl.BogusSettle mode, replace the preimage with a
 	// fake one before sending it to the peer.
 	if l.cfg.HodlMask.Active(hodl.BogusSettle) {
@@ -2952,6 +3032,10 @@ func (l *channelLink) settleHTLC(preimage lntypes.Preimage,
 				HtlcID: pd.HtlcIndex,
 			},
 		},
+		HtlcInfo{
+			IncomingTimeLock: pd.Timeout,
+			IncomingAmt:      pd.Amount,
+		},
 		HtlcEventTypeReceive,
 	)
 
@@ -5403,10 +5403,10 @@ func TestForwardingAsymmetricTimeLockPolicies(t *testing.T) {
 // forwarding policy.
 func TestCheckHtlcForward(t *testing.T) {
 
-	fetchLastChannelUpdate := func(lnwire.ShortChannelID) (
+	fetchLastChannelUpdate := func(chanID lnwire.ShortChannelID) (
 		*lnwire.ChannelUpdate, error) {
 
-		return &lnwire.ChannelUpdate{}, nil
+		return &lnwire.ChannelUpdate{ShortChannelID: chanID}, nil
 	}
 
 	testChannel, _, fCleanUp, err := createTestChannel(
@@ -5435,10 +5435,11 @@ func TestCheckHtlcForward(t *testing.T) {
 	}
 
 	var hash [32]byte
+	incomingChanID := lnwire.NewShortChanIDFromInt(7)
 
 	t.Run("satisfied", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 1500, 1000,
-			200, 150, 0)
+			200, 150, incomingChanID, lnwire.InboundFee{}, 0)
 		if result != nil {
 			t.Fatalf("expected policy to be satisfied")
 		}
@@ -5446,7 +5447,7 @@ func TestCheckHtlcForward(t *testing.T) {
 
 	t.Run("below minhtlc", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 100, 50,
-			200, 150, 0)
+			200, 150, incomingChanID, lnwire.InboundFee{}, 0)
 		if _, ok := result.WireMessage().(*lnwire.FailAmountBelowMinimum); !ok {
 			t.Fatalf("expected FailAmountBelowMinimum failure code")
 		}
@@ -5454,7 +5455,7 @@ func TestCheckHtlcForward(t *testing.T) {
 
 	t.Run("above maxhtlc", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 1500, 1200,
-			200, 150, 0)
+			200, 150, incomingChanID, lnwire.InboundFee{}, 0)
 		if _, ok := result.WireMessage().(*lnwire.FailTemporaryChannelFailure); !ok {
 			t.Fatalf("expected FailTemporaryChannelFailure failure code")
 		}
@@ -5462,15 +5463,59 @@ func TestCheckHtlcForward(t *testing.T) {
 
 	t.Run("insufficient fee", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 1005, 1000,
-			200, 150, 0)
-		if _, ok := result.WireMessage().(*lnwire.FailFeeInsufficient); !ok {
+			200, 150, incomingChanID, lnwire.InboundFee{}, 0)
+		failure, ok := result.WireMessage().(*lnwire.FailFeeInsufficient)
+		if !ok {
 			t.Fatalf("expected FailFeeInsufficient failure code")
 		}
+
+		// The outgoing fee wasn't paid, so the update of the outgoing
+		// channel is expected.
+		if failure.Update.ShortChannelID != link.ShortChanID() {
+			t.Fatalf("expected update of channel %v, got %v",
+				link.ShortChanID(),
+				failure.Update.ShortChannelID)
+		}
+	})
+
+	t.Run("inbound discount", func(t *testing.T) {
+		// The outgoing fee of 10 is fully discounted.
+		discount := lnwire.InboundFee{BaseFee: -5, FeeRate: -5000}
+		result := link.CheckHtlcForward(hash, 1000, 1000,
+			200, 150, incomingChanID, discount, 0)
+		if result != nil {
+			t.Fatalf("expected policy to be satisfied")
+		}
+	})
+
+	t.Run("inbound surcharge", func(t *testing.T) {
+		// The inbound fee is computed over the outgoing amount plus
+		// the outgoing fee: 5 + 1010 * 1000 / 1e6 = 6.
+		surcharge := lnwire.InboundFee{BaseFee: 5, FeeRate: 1000}
+		result := link.CheckHtlcForward(hash, 1016, 1000,
+			200, 150, incomingChanID, surcharge, 0)
+		if result != nil {
+			t.Fatalf("expected policy to be satisfied")
+		}
+
+		result = link.CheckHtlcForward(hash, 1015, 1000,
+			200, 150, incomingChanID, surcharge, 0)
+		failure, ok := result.WireMessage().(*lnwire.FailFeeInsufficient)
+		if !ok {
+			t.Fatalf("expected FailFeeInsufficient failure code")
+		}
+
+		// The outgoing fee was paid but not the inbound fee, so the
+		// update of the incoming channel is expected.
+		if failure.Update.ShortChannelID != incomingChanID {
+			t.Fatalf("expected update of channel %v, got %v",
+				incomingChanID, failure.Update.ShortChannelID)
+		}
 	})
 
 	t.Run("expiry too soon", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 1500, 1000,
-			200, 150, 190)
+			200, 150, incomingChanID, lnwire.InboundFee{}, 190)
 		if _, ok := result.WireMessage().(*lnwire.FailExpiryTooSoon); !ok {
 			t.Fatalf("expected FailExpiryTooSoon failure code")
 		}
@@ -5478,7 +5523,7 @@ func TestCheckHtlcForward(t *testing.T) {
 
 	t.Run("incorrect cltv expiry", func(t *testing.T) {
 		result := link.CheckHtlcForward(hash, 1500, 1000,
-			200, 190, 0)
+			200, 190, incomingChanID, lnwire.InboundFee{}, 0)
 		if _, ok := result.WireMessage().(*lnwire.FailIncorrectCltvExpiry); !ok {
 			t.Fatalf("expected FailIncorrectCltvExpiry failure code")
 		}
@@ -5488,7 +5533,7 @@ func TestCheckHtlcForward(t *testing.T) {
 	t.Run("cltv expiry too far in the future", func(t *testing.T) {
 		// Check that expiry isn't too far in the future.
 		result := link.CheckHtlcForward(hash, 1500, 1000,
-			10200, 10100, 0)
+			10200, 10100, incomingChanID, lnwire.InboundFee{}, 0)
 		if _, ok := result.WireMessage().(*lnwire.FailExpiryTooFar); !ok {
 			t.Fatalf("expected FailExpiryTooFar failure code")
 		}
@@ -6,6 +6,9 @@ import (
 	"github.com/decred/slog"
 )
 
+// Subsystem defines the logging code for this subsystem.
+const Subsystem = "HSWC"
+
 // log is a logger that is initialized with no output filters.  This
 // means the package will not perform any logging by default until the caller
 // requests it.
@@ -13,7 +16,7 @@ var log slog.Logger
 
 // The default amount of logging is none.
 func init() {
-	logger := build.NewSubLogger("HSWC", nil)
+	logger := build.NewSubLogger(Subsystem, nil)
 
 	UseLogger(logger)
 }
@@ -655,6 +655,9 @@ type mockChannelLink struct {
 	checkHtlcTransitResult *LinkError
 
 	checkHtlcForwardResult *LinkError
+
+	maxHtlcs      uint16
+	maxPendingAmt lnwire.MilliAtom
 }
 
 // completeCircuit is a helper method for adding the finalized payment circuit
@@ -700,6 +703,9 @@ func newMockChannelLink(htlcSwitch *Switch, chanID lnwire.ChannelID,
 		shortChanID: shortChanID,
 		peer:        peer,
 		eligible:    eligible,
+
+		maxHtlcs:      input.MaxHTLCNumber / 2,
+		maxPendingAmt: 99999999,
 	}
 }
 
@@ -718,8 +724,11 @@ func (f *mockChannelLink) HandleChannelUpdate(lnwire.Message) {
 
 func (f *mockChannelLink) UpdateForwardingPolicy(_ ForwardingPolicy) {
 }
+func (f *mockChannelLink) UpdateOutgoingCltvRejectDelta(uint32) {
+}
 func (f *mockChannelLink) CheckHtlcForward([32]byte, lnwire.MilliAtom,
-	lnwire.MilliAtom, uint32, uint32, uint32) *LinkError {
+	lnwire.MilliAtom, uint32, uint32, lnwire.ShortChannelID,
+	lnwire.InboundFee, uint32) *LinkError {
 
 	return f.checkHtlcForwardResult
 }
@@ -759,6 +768,10 @@ func (f *mockChannelLink) UpdateShortChanID() (lnwire.ShortChannelID, error) {
 	return f.shortChanID, nil
 }
 
+func (f *mockChannelLink) OutgoingHtlcLimits() (uint16, lnwire.MilliAtom) {
+	return f.maxHtlcs, f.maxPendingAmt
+}
+
 var _ ChannelLink = (*mockChannelLink)(nil)
 
 func newDB() (*channeldb.DB, func(), error) {
@@ -1012,6 +1025,10 @@ func (m *mockCircuitMap) NumOpen() int {
 	return 0
 }
 
+func (m *mockCircuitMap) Circuits() []*PaymentCircuit {
+	return nil
+}
+
 type mockOnionErrorDecryptor struct {
 	sourceIdx int
 	message   []byte
@@ -1040,8 +1057,9 @@ func (h *mockHTLCNotifier) NotifyLinkFailEvent(key HtlcKey, info HtlcInfo,
 }
 
 func (h *mockHTLCNotifier) NotifyForwardingFailEvent(key HtlcKey,
-	eventType HtlcEventType) {
+	info HtlcInfo, eventType HtlcEventType) {
 }
 
-func (h *mockHTLCNotifier) NotifySettleEvent(key HtlcKey, eventType HtlcEventType) {
+func (h *mockHTLCNotifier) NotifySettleEvent(key HtlcKey, info HtlcInfo,
+	eventType HtlcEventType) {
 }
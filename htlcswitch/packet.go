@@ -96,6 +96,10 @@ type htlcPacket struct {
 	// customRecords are user-defined records in the custom type range that
 	// were included in the payload.
 	customRecords record.CustomSet
+
+	// inboundFee is the inbound fee of the incoming link at the time the
+	// HTLC was received, which adds to the fee of the outgoing link.
+	inboundFee lnwire.InboundFee
 }
 
 // inKey returns the circuit key used to identify the incoming htlc.
@@ -0,0 +1,345 @@
+package reputation
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// forwardingEventsBatch is the number of forwarding events requested at once
+// when loading the forwarding history.
+const forwardingEventsBatch = 10000
+
+var (
+	// ErrBucketFull is returned when an htlc of a peer without reputation
+	// would exceed the share of the outgoing channel's htlc slots or
+	// liquidity reserved for such peers.
+	ErrBucketFull = errors.New("htlc bucket of unreputable peers is full")
+
+	// ErrUnknownChannel is returned when the peer of a channel that is
+	// neither open nor closed is looked up.
+	ErrUnknownChannel = errors.New("unknown channel")
+)
+
+// Policy describes how the htlc slots and liquidity of our channels are shared
+// between upstream peers, depending on their reputation.
+type Policy struct {
+	// BucketPercent is the percentage of an outgoing channel's htlc slots
+	// and in flight liquidity that htlcs from peers without reputation
+	// may occupy at once.
+	BucketPercent uint32
+
+	// MinRevenue is the amount of forwarding fees that the htlcs of a peer
+	// must have earned us within the window before it is considered
+	// reputable.
+	MinRevenue lnwire.MilliAtom
+
+	// Window is the period over which the revenue of a peer is computed.
+	Window time.Duration
+}
+
+// Config holds the parameters and dependencies of a Tracker.
+type Config struct {
+	// Policy is the reputation policy enforced by the tracker.
+	Policy Policy
+
+	// Clock is the time source of the tracker.
+	Clock clock.Clock
+
+	// FetchAllOpenChannels is used to map the incoming channels of past
+	// forwards to the peers that offered them.
+	FetchAllOpenChannels func() ([]*channeldb.OpenChannel, error)
+
+	// FetchClosedChannels is used to map the incoming channels of past
+	// forwards that were closed since to the peers that offered them.
+	FetchClosedChannels func(pendingOnly bool) (
+		[]*channeldb.ChannelCloseSummary, error)
+
+	// QueryForwardingLog is used to retrieve the past forwards the
+	// reputation of our peers is built from.
+	QueryForwardingLog func(q channeldb.ForwardingEventQuery) (
+		channeldb.ForwardingLogTimeSlice, error)
+}
+
+// revenueEvent is the fee earned by a settled forward.
+type revenueEvent struct {
+	timestamp time.Time
+	fee       lnwire.MilliAtom
+}
+
+// inFlightHtlc is an htlc that was forwarded and is not yet resolved.
+type inFlightHtlc struct {
+	peer     route.Vertex
+	outgoing lnwire.ShortChannelID
+	amt      lnwire.MilliAtom
+
+	// bucketed is true if the htlc occupies a slot of the bucket of
+	// unreputable peers of its outgoing channel.
+	bucketed bool
+}
+
+// bucket holds the htlcs of unreputable peers in flight over a channel.
+type bucket struct {
+	numHtlcs uint32
+	amt      lnwire.MilliAtom
+}
+
+// Tracker keeps track of the reputation of our peers, built from the fees
+// earned by the htlcs they forwarded through us, and limits the htlc slots and
+// liquidity that htlcs from unreputable peers may occupy on each outgoing
+// channel. This prevents peers we don't have a history with from jamming our
+// channels by holding on to htlcs, while still letting them forward through
+// the reserved share.
+//
+// NOTE: The in flight htlcs are only tracked in memory, so htlcs forwarded
+// before a restart don't occupy a slot of their outgoing channel's bucket.
+type Tracker struct {
+	cfg *Config
+
+	mu sync.Mutex
+
+	// revenue holds the fees earned by the settled forwards of each peer
+	// within the window, from oldest to newest.
+	revenue map[route.Vertex][]revenueEvent
+
+	// htlcs holds the forwarded htlcs that are in flight.
+	htlcs map[channeldb.CircuitKey]*inFlightHtlc
+
+	// buckets holds the htlcs of unreputable peers in flight over each
+	// outgoing channel.
+	buckets map[lnwire.ShortChannelID]*bucket
+}
+
+// NewTracker creates a new reputation tracker from the given config.
+func NewTracker(cfg *Config) *Tracker {
+	return &Tracker{
+		cfg:     cfg,
+		revenue: make(map[route.Vertex][]revenueEvent),
+		htlcs:   make(map[channeldb.CircuitKey]*inFlightHtlc),
+		buckets: make(map[lnwire.ShortChannelID]*bucket),
+	}
+}
+
+// channelPeers returns the peers of all of our open and closed channels.
+func (t *Tracker) channelPeers() (map[lnwire.ShortChannelID]route.Vertex,
+	error) {
+
+	peers := make(map[lnwire.ShortChannelID]route.Vertex)
+
+	openChannels, err := t.cfg.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range openChannels {
+		peers[channel.ShortChanID()] = route.NewVertex(
+			channel.IdentityPub,
+		)
+	}
+
+	closedChannels, err := t.cfg.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	for _, summary := range closedChannels {
+		peers[summary.ShortChanID] = route.NewVertex(summary.RemotePub)
+	}
+
+	return peers, nil
+}
+
+// ChannelPeer returns the peer of the given open or closed channel.
+func (t *Tracker) ChannelPeer(chanID lnwire.ShortChannelID) (route.Vertex,
+	error) {
+
+	peers, err := t.channelPeers()
+	if err != nil {
+		return route.Vertex{}, err
+	}
+
+	peer, ok := peers[chanID]
+	if !ok {
+		return route.Vertex{}, ErrUnknownChannel
+	}
+
+	return peer, nil
+}
+
+// LoadHistory builds the reputation of our peers from the forwards within the
+// window recorded in the forwarding log. It should be called once, before any
+// htlc is tracked.
+func (t *Tracker) LoadHistory() error {
+	peers, err := t.channelPeers()
+	if err != nil {
+		return err
+	}
+
+	now := t.cfg.Clock.Now()
+	query := channeldb.ForwardingEventQuery{
+		StartTime:    now.Add(-t.cfg.Policy.Window),
+		EndTime:      now,
+		NumMaxEvents: forwardingEventsBatch,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var numEvents int
+	for {
+		timeSlice, err := t.cfg.QueryForwardingLog(query)
+		if err != nil {
+			return err
+		}
+
+		for _, event := range timeSlice.ForwardingEvents {
+			peer, ok := peers[event.IncomingChanID]
+			if !ok || event.AmtIn <= event.AmtOut {
+				continue
+			}
+
+			t.revenue[peer] = append(t.revenue[peer], revenueEvent{
+				timestamp: event.Timestamp,
+				fee:       event.AmtIn - event.AmtOut,
+			})
+			numEvents++
+		}
+
+		if len(timeSlice.ForwardingEvents) < forwardingEventsBatch {
+			break
+		}
+		query.IndexOffset = timeSlice.LastIndexOffset
+	}
+
+	log.Infof("Loaded %v forwards of %v peers into reputation history",
+		numEvents, len(t.revenue))
+
+	return nil
+}
+
+// reputable returns true if the fees earned by the settled forwards of the
+// given peer within the window reach the minimum revenue. The caller must
+// hold the mutex.
+func (t *Tracker) reputable(peer route.Vertex) bool {
+	events := t.revenue[peer]
+
+	// Forget the revenue that fell out of the window.
+	cutoff := t.cfg.Clock.Now().Add(-t.cfg.Policy.Window)
+	var expired int
+	for expired < len(events) && events[expired].timestamp.Before(cutoff) {
+		expired++
+	}
+	events = events[expired:]
+	if len(events) == 0 {
+		delete(t.revenue, peer)
+		return false
+	}
+	t.revenue[peer] = events
+
+	var revenue lnwire.MilliAtom
+	for _, event := range events {
+		revenue += event.fee
+	}
+
+	return revenue >= t.cfg.Policy.MinRevenue
+}
+
+// Reputable returns true if the given peer has earned us enough forwarding
+// fees within the window to have unrestricted access to our channels.
+func (t *Tracker) Reputable(peer route.Vertex) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return t.reputable(peer)
+}
+
+// AddHTLC tracks an htlc offered by the given peer that is about to be
+// forwarded over the outgoing channel, which accepts at most maxHtlcs htlcs
+// and maxAmt milli-atoms in flight. If the peer is unreputable and the htlc
+// would exceed the share of the channel reserved for unreputable peers,
+// ErrBucketFull is returned and the htlc isn't tracked.
+func (t *Tracker) AddHTLC(key channeldb.CircuitKey, peer route.Vertex,
+	outgoing lnwire.ShortChannelID, amt lnwire.MilliAtom,
+	maxHtlcs uint16, maxAmt lnwire.MilliAtom) error {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// The htlc may be forwarded again after the outgoing link failed to
+	// accept it, in which case it is already accounted for.
+	if _, ok := t.htlcs[key]; ok {
+		return nil
+	}
+
+	htlc := &inFlightHtlc{
+		peer:     peer,
+		outgoing: outgoing,
+		amt:      amt,
+		bucketed: !t.reputable(peer),
+	}
+
+	if htlc.bucketed {
+		b, ok := t.buckets[outgoing]
+		if !ok {
+			b = &bucket{}
+		}
+
+		// The share of the bucket is rounded up, so that unreputable
+		// peers can still use channels with few htlc slots.
+		pct := t.cfg.Policy.BucketPercent
+		maxBucketHtlcs := (uint32(maxHtlcs)*pct + 99) / 100
+		maxBucketAmt := (maxAmt*lnwire.MilliAtom(pct) + 99) / 100
+		if b.numHtlcs+1 > maxBucketHtlcs || b.amt+amt > maxBucketAmt {
+			log.Debugf("Rejecting htlc %v of unreputable peer %v: "+
+				"bucket of %v full with %v htlcs of %v",
+				key, peer, outgoing, b.numHtlcs, b.amt)
+
+			return ErrBucketFull
+		}
+
+		b.numHtlcs++
+		b.amt += amt
+		t.buckets[outgoing] = b
+	}
+
+	t.htlcs[key] = htlc
+
+	return nil
+}
+
+// ResolveHTLC stops tracking the htlc with the given incoming circuit key,
+// releasing its slot. If the htlc was settled, the fee it earned is added to
+// the reputation of the peer that offered it.
+func (t *Tracker) ResolveHTLC(key channeldb.CircuitKey, settled bool,
+	fee lnwire.MilliAtom) {
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	htlc, ok := t.htlcs[key]
+	if !ok {
+		return
+	}
+	delete(t.htlcs, key)
+
+	if htlc.bucketed {
+		b := t.buckets[htlc.outgoing]
+		b.numHtlcs--
+		b.amt -= htlc.amt
+		if b.numHtlcs == 0 {
+			delete(t.buckets, htlc.outgoing)
+		}
+	}
+
+	if !settled || fee == 0 {
+		return
+	}
+
+	t.revenue[htlc.peer] = append(t.revenue[htlc.peer], revenueEvent{
+		timestamp: t.cfg.Clock.Now(),
+		fee:       fee,
+	})
+}
@@ -0,0 +1,233 @@
+package reputation
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+var (
+	testTime = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	testPubKey = secp256k1.PrivKeyFromBytes([]byte{0x01}).PubKey()
+
+	testPeer = route.NewVertex(testPubKey)
+
+	testOutgoing = lnwire.NewShortChanIDFromInt(1)
+)
+
+// newTestTracker creates a tracker with a test clock and the given forwarding
+// history, with all forwards offered by testPeer.
+func newTestTracker(events []channeldb.ForwardingEvent) (*Tracker,
+	*clock.TestClock) {
+
+	testClock := clock.NewTestClock(testTime)
+	incoming := lnwire.NewShortChanIDFromInt(2)
+
+	tracker := NewTracker(&Config{
+		Policy: Policy{
+			BucketPercent: 50,
+			MinRevenue:    1000,
+			Window:        time.Hour,
+		},
+		Clock: testClock,
+		FetchAllOpenChannels: func() ([]*channeldb.OpenChannel, error) {
+			return nil, nil
+		},
+		FetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return []*channeldb.ChannelCloseSummary{{
+				ShortChanID: incoming,
+				RemotePub:   testPubKey,
+			}}, nil
+		},
+		QueryForwardingLog: func(q channeldb.ForwardingEventQuery) (
+			channeldb.ForwardingLogTimeSlice, error) {
+
+			for i := range events {
+				events[i].IncomingChanID = incoming
+			}
+			return channeldb.ForwardingLogTimeSlice{
+				ForwardingEventQuery: q,
+				ForwardingEvents:     events,
+			}, nil
+		},
+	})
+
+	return tracker, testClock
+}
+
+// TestTrackerBucket asserts that the htlcs of unreputable peers are limited to
+// the bucket share of the outgoing channel, and that resolved htlcs release
+// their slot.
+func TestTrackerBucket(t *testing.T) {
+	t.Parallel()
+
+	tracker, _ := newTestTracker(nil)
+	if err := tracker.LoadHistory(); err != nil {
+		t.Fatalf("unable to load history: %v", err)
+	}
+
+	key := func(id uint64) channeldb.CircuitKey {
+		return channeldb.CircuitKey{HtlcID: id}
+	}
+
+	// With 4 slots, the bucket of unreputable peers holds 2 htlcs.
+	for i := uint64(0); i < 2; i++ {
+		err := tracker.AddHTLC(key(i), testPeer, testOutgoing, 10, 4, 1000)
+		if err != nil {
+			t.Fatalf("unable to add htlc %v: %v", i, err)
+		}
+	}
+	err := tracker.AddHTLC(key(2), testPeer, testOutgoing, 10, 4, 1000)
+	if err != ErrBucketFull {
+		t.Fatalf("expected ErrBucketFull, got %v", err)
+	}
+
+	// Adding an htlc that is already tracked doesn't take another slot.
+	err = tracker.AddHTLC(key(0), testPeer, testOutgoing, 10, 4, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc again: %v", err)
+	}
+
+	// Other outgoing channels have their own bucket.
+	otherOutgoing := lnwire.NewShortChanIDFromInt(3)
+	err = tracker.AddHTLC(key(3), testPeer, otherOutgoing, 10, 4, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc to other channel: %v", err)
+	}
+
+	// Resolving an htlc releases its slot.
+	tracker.ResolveHTLC(key(0), false, 0)
+	err = tracker.AddHTLC(key(2), testPeer, testOutgoing, 10, 4, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc after release: %v", err)
+	}
+
+	// The bucket also holds at most half of the liquidity.
+	tracker.ResolveHTLC(key(1), false, 0)
+	err = tracker.AddHTLC(key(4), testPeer, testOutgoing, 491, 4, 1000)
+	if err != ErrBucketFull {
+		t.Fatalf("expected ErrBucketFull, got %v", err)
+	}
+	err = tracker.AddHTLC(key(4), testPeer, testOutgoing, 490, 4, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+}
+
+// TestTrackerBucketRounding asserts that the share of the bucket is rounded
+// up, so that unreputable peers can use channels with few htlc slots, unless
+// the bucket is disabled.
+func TestTrackerBucketRounding(t *testing.T) {
+	t.Parallel()
+
+	tracker, _ := newTestTracker(nil)
+	tracker.cfg.Policy.BucketPercent = 10
+
+	// With 3 slots, 10% of them rounds up to a single htlc.
+	key := func(id uint64) channeldb.CircuitKey {
+		return channeldb.CircuitKey{HtlcID: id}
+	}
+	err := tracker.AddHTLC(key(0), testPeer, testOutgoing, 10, 3, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	err = tracker.AddHTLC(key(1), testPeer, testOutgoing, 10, 3, 1000)
+	if err != ErrBucketFull {
+		t.Fatalf("expected ErrBucketFull, got %v", err)
+	}
+
+	// Without a bucket, unreputable peers can't use the channel at all.
+	tracker.cfg.Policy.BucketPercent = 0
+	otherOutgoing := lnwire.NewShortChanIDFromInt(3)
+	err = tracker.AddHTLC(key(2), testPeer, otherOutgoing, 10, 3, 1000)
+	if err != ErrBucketFull {
+		t.Fatalf("expected ErrBucketFull, got %v", err)
+	}
+}
+
+// TestTrackerChannelPeer asserts that the peers of closed channels can be
+// looked up.
+func TestTrackerChannelPeer(t *testing.T) {
+	t.Parallel()
+
+	tracker, _ := newTestTracker(nil)
+
+	peer, err := tracker.ChannelPeer(lnwire.NewShortChanIDFromInt(2))
+	if err != nil {
+		t.Fatalf("unable to look up peer: %v", err)
+	}
+	if peer != testPeer {
+		t.Fatalf("expected peer %v, got %v", testPeer, peer)
+	}
+
+	_, err = tracker.ChannelPeer(lnwire.NewShortChanIDFromInt(5))
+	if err != ErrUnknownChannel {
+		t.Fatalf("expected ErrUnknownChannel, got %v", err)
+	}
+}
+
+// TestTrackerReputation asserts that peers become reputable once their settled
+// forwards earned the minimum revenue within the window, and that reputable
+// peers aren't limited to the bucket.
+func TestTrackerReputation(t *testing.T) {
+	t.Parallel()
+
+	tracker, testClock := newTestTracker([]channeldb.ForwardingEvent{{
+		Timestamp: testTime.Add(-30 * time.Minute),
+		AmtIn:     10600,
+		AmtOut:    10000,
+	}})
+	if err := tracker.LoadHistory(); err != nil {
+		t.Fatalf("unable to load history: %v", err)
+	}
+
+	// The forwarding history only earned 600 milli-atoms so far.
+	if tracker.Reputable(testPeer) {
+		t.Fatalf("expected peer to be unreputable")
+	}
+
+	// A failed htlc doesn't add to the reputation.
+	key := channeldb.CircuitKey{HtlcID: 1}
+	err := tracker.AddHTLC(key, testPeer, testOutgoing, 10, 2, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	tracker.ResolveHTLC(key, false, 500)
+	if tracker.Reputable(testPeer) {
+		t.Fatalf("expected peer to be unreputable")
+	}
+
+	// A settled one makes the peer reputable.
+	err = tracker.AddHTLC(key, testPeer, testOutgoing, 10, 2, 1000)
+	if err != nil {
+		t.Fatalf("unable to add htlc: %v", err)
+	}
+	tracker.ResolveHTLC(key, true, 500)
+	if !tracker.Reputable(testPeer) {
+		t.Fatalf("expected peer to be reputable")
+	}
+
+	// Reputable peers aren't limited by the bucket.
+	for i := uint64(0); i < 3; i++ {
+		key := channeldb.CircuitKey{HtlcID: 10 + i}
+		err := tracker.AddHTLC(key, testPeer, testOutgoing, 900, 2, 1000)
+		if err != nil {
+			t.Fatalf("unable to add htlc %v: %v", i, err)
+		}
+	}
+
+	// Once the loaded forward falls out of the window, the peer loses its
+	// reputation.
+	testClock.SetTime(testTime.Add(45 * time.Minute))
+	if tracker.Reputable(testPeer) {
+		t.Fatalf("expected peer to be unreputable")
+	}
+}
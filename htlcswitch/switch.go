@@ -16,11 +16,17 @@ import (
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/contractcourt"
+	"github.com/decred/dcrlnd/crashreport"
+	"github.com/decred/dcrlnd/htlcswitch/holdfee"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/htlcswitch/reputation"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwallet"
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/monitoring"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/route"
 	"github.com/decred/dcrlnd/ticker"
 )
 
@@ -109,6 +115,11 @@ type ChanClose struct {
 	// process for the cooperative closure transaction kicks off.
 	TargetFeePerKB chainfee.AtomPerKByte
 
+	// MaxFeePerKB is the highest fee rate the caller agrees to pay for
+	// the closing transaction. A value of zero means there is no limit.
+	// This value is only utilized if the closure type is CloseRegular.
+	MaxFeePerKB chainfee.AtomPerKByte
+
 	// DeliveryScript is an optional delivery script to pay funds out to.
 	DeliveryScript lnwire.DeliveryAddress
 
@@ -191,6 +202,15 @@ type Config struct {
 	// will expiry this long after the Adds are added to a mailbox via
 	// AddPacket.
 	HTLCExpiry time.Duration
+
+	// HoldFeePolicy is the experimental hold fee that forwarded htlcs must
+	// offer on top of the forwarding fee of the outgoing link.
+	HoldFeePolicy holdfee.Policy
+
+	// HtlcReputation limits the share of the htlc slots and liquidity of
+	// our channels that htlcs from unreputable peers may occupy. If nil,
+	// all peers have unrestricted access to our channels.
+	HtlcReputation *reputation.Tracker
 }
 
 // Switch is the central messaging bus for all incoming/outgoing HTLCs.
@@ -210,6 +230,10 @@ type Switch struct {
 	// This will be retrieved by the registered links atomically.
 	bestHeight uint32
 
+	// forwardsBlocked is non-zero if new forwards are refused. It must be
+	// accessed atomically.
+	forwardsBlocked int32
+
 	wg   sync.WaitGroup
 	quit chan struct{}
 
@@ -279,6 +303,12 @@ type Switch struct {
 	fwdEventMtx         sync.Mutex
 	pendingFwdingEvents []channeldb.ForwardingEvent
 
+	// addTimes tracks the time each htlc that has yet to be resolved was
+	// handed to its outgoing link, keyed by its incoming circuit key, so
+	// that the time it took to resolve can be measured. It is guarded by
+	// the fwdEventMtx.
+	addTimes map[CircuitKey]time.Time
+
 	// blockEpochStream is an active block epoch event stream backed by an
 	// active ChainNotifier instance. This will be used to retrieve the
 	// lastest height of the chain.
@@ -312,6 +342,7 @@ func New(cfg Config, currentHeight uint32) (*Switch, error) {
 		htlcPlex:          make(chan *plexPacket),
 		chanCloseRequests: make(chan *ChanClose),
 		resolutionMsgs:    make(chan *resolutionMsg),
+		addTimes:          make(map[CircuitKey]time.Time),
 		quit:              make(chan struct{}),
 	}
 
@@ -361,12 +392,54 @@ func (s *Switch) ProcessContractResolution(msg contractcourt.ResolutionMsg) erro
 	return nil
 }
 
+// SettleLocalPayment is called when one of our links settles an htlc as the
+// exit hop. If the htlc is the last hop of a payment we sent to ourselves, such
+// as a circular rebalance, the attempt is settled right away from its circuit:
+// the preimage was released by our own exit hop, so the attempt can only
+// succeed, and its result is stored in the network result store and delivered
+// without waiting for the response to travel back along the route. As the
+// result is persisted, it survives a restart, and the later resolution of the
+// first-hop htlc doesn't replace it.
+//
+// As the settled htlc can't be attributed to a specific attempt otherwise, the
+// attempt is only settled when a single attempt of the payment is in flight.
+func (s *Switch) SettleLocalPayment(preimage lntypes.Preimage) {
+	hash := preimage.Hash()
+
+	var localCircuits []*PaymentCircuit
+	for _, circuit := range s.circuits.LookupByPaymentHash(hash) {
+		if circuit.Incoming.ChanID == hop.Source {
+			localCircuits = append(localCircuits, circuit)
+		}
+	}
+	if len(localCircuits) != 1 {
+		return
+	}
+
+	paymentID := localCircuits[0].Incoming.HtlcID
+
+	log.Debugf("Settling payment to self from exit hop (pid=%v, "+
+		"hash=%v)", paymentID, hash)
+
+	err := s.networkResults.storeResult(paymentID, &networkResult{
+		msg: &lnwire.UpdateFulfillHTLC{
+			PaymentPreimage: preimage,
+		},
+	})
+	if err != nil {
+		log.Errorf("Unable to settle payment to self for pid=%v: %v",
+			paymentID, err)
+	}
+}
+
 // GetPaymentResult returns the the result of the payment attempt with the
 // given paymentID. The method returns a channel where the payment result will
 // be sent when available, or an error is encountered during forwarding. When a
 // result is received on the channel, the HTLC is guaranteed to no longer be in
-// flight. The switch shutting down is signaled by closing the channel. If the
-// paymentID is unknown, ErrPaymentIDNotFound will be returned.
+// flight, except for payments to ourselves settled by our own exit hop, whose
+// first-hop HTLC can only be settled. The switch shutting down is signaled by
+// closing the channel. If the paymentID is unknown, ErrPaymentIDNotFound will
+// be returned.
 func (s *Switch) GetPaymentResult(paymentID uint64, paymentHash lntypes.Hash,
 	deobfuscator ErrorDecrypter) (<-chan *PaymentResult, error) {
 
@@ -451,6 +524,7 @@ func (s *Switch) SendHTLC(firstHop lnwire.ShortChannelID, paymentID uint64,
 		incomingChanID: hop.Source,
 		incomingHTLCID: paymentID,
 		outgoingChanID: firstHop,
+		amount:         htlc.Amount,
 		htlc:           htlc,
 	}
 
@@ -494,7 +568,36 @@ func (s *Switch) SendHTLC(firstHop lnwire.ShortChannelID, paymentID uint64,
 		return linkErr
 	}
 
-	return link.HandleLocalAddPacket(packet)
+	// Track the time the htlc is handed to its link, so that the time it
+	// takes to resolve is recorded like that of forwarded htlcs. It's
+	// tracked beforehand as the htlc may resolve before the link returns.
+	s.fwdEventMtx.Lock()
+	s.addTimes[packet.inKey()] = s.cfg.Clock.Now()
+	s.fwdEventMtx.Unlock()
+
+	if err := link.HandleLocalAddPacket(packet); err != nil {
+		s.fwdEventMtx.Lock()
+		delete(s.addTimes, packet.inKey())
+		s.fwdEventMtx.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// htlcResolutionLabels returns the type and outcome under which the time the
+// htlc with the given incoming circuit key took to resolve is recorded.
+func htlcResolutionLabels(incoming CircuitKey, isFail bool) (string, string) {
+	htlcType, outcome := "forward", "settled"
+	if incoming.ChanID == hop.Source {
+		htlcType = "local"
+	}
+	if isFail {
+		outcome = "failed"
+	}
+
+	return htlcType, outcome
 }
 
 // UpdateForwardingPolicies sends a message to the switch to update the
@@ -529,6 +632,23 @@ func (s *Switch) UpdateForwardingPolicies(
 	s.indexMtx.RUnlock()
 }
 
+// UpdateOutgoingCltvRejectDelta updates the number of blocks before the expiry
+// of an htlc at which all links no longer offer it to the remote party.
+func (s *Switch) UpdateOutgoingCltvRejectDelta(delta uint32) {
+	log.Debugf("Updating outgoing cltv reject delta of all links to %v",
+		delta)
+
+	s.indexMtx.RLock()
+	defer s.indexMtx.RUnlock()
+
+	for _, link := range s.linkIndex {
+		link.UpdateOutgoingCltvRejectDelta(delta)
+	}
+	for _, link := range s.pendingLinkIndex {
+		link.UpdateOutgoingCltvRejectDelta(delta)
+	}
+}
+
 // IsForwardedHTLC checks for a given channel and htlc index if it is related
 // to an opened circuit that represents a forwarded payment.
 func (s *Switch) IsForwardedHTLC(chanID lnwire.ShortChannelID,
@@ -596,6 +716,7 @@ func (s *Switch) ForwardPackets(linkQuit chan struct{},
 		switch htlc := packet.htlc.(type) {
 		case *lnwire.UpdateAddHTLC:
 			circuit := newPaymentCircuit(&htlc.PaymentHash, packet)
+			circuit.HoldFee = s.offeredHoldFee(packet)
 			packet.circuit = circuit
 			circuits = append(circuits, circuit)
 			addBatch = append(addBatch, packet)
@@ -793,12 +914,33 @@ func (s *Switch) handleLocalResponse(pkt *htlcPacket) {
 		isResolution: pkt.isResolution,
 	}
 
-	// Store the result to the db. This will also notify subscribers about
-	// the result.
-	if err := s.networkResults.storeResult(paymentID, n); err != nil {
-		log.Errorf("Unable to complete payment for pid=%v: %v",
-			paymentID, err)
+	// If our own exit hop already settled this payment to ourselves, the
+	// preimage was released and the attempt succeeded, regardless of how
+	// its first hop was resolved, so the stored result is kept. Otherwise
+	// we store the result to the db, which will also notify subscribers
+	// about the result.
+	var settled bool
+	prevResult, err := s.networkResults.getResult(paymentID)
+	if err == nil {
+		_, settled = prevResult.msg.(*lnwire.UpdateFulfillHTLC)
+	}
+	switch {
+	case settled:
+		log.Debugf("Keeping exit hop settle of payment to self "+
+			"(pid=%v)", paymentID)
+
+	case err != nil && err != ErrPaymentIDNotFound:
+		log.Errorf("Unable to fetch result of pid=%v: %v", paymentID,
+			err)
 		return
+
+	default:
+		err := s.networkResults.storeResult(paymentID, n)
+		if err != nil {
+			log.Errorf("Unable to complete payment for pid=%v: %v",
+				paymentID, err)
+			return
+		}
 	}
 
 	// First, we'll clean up any fwdpkg references, circuit entries, and
@@ -831,14 +973,17 @@ func (s *Switch) handleLocalResponse(pkt *htlcPacket) {
 
 	// Finally, notify on the htlc failure or success that has been handled.
 	key := newHtlcKey(pkt)
+	info := newResolvedHtlcInfo(pkt)
 	eventType := getEventType(pkt)
 
 	switch pkt.htlc.(type) {
 	case *lnwire.UpdateFulfillHTLC:
-		s.cfg.HtlcNotifier.NotifySettleEvent(key, eventType)
+		s.cfg.HtlcNotifier.NotifySettleEvent(key, info, eventType)
 
 	case *lnwire.UpdateFailHTLC:
-		s.cfg.HtlcNotifier.NotifyForwardingFailEvent(key, eventType)
+		s.cfg.HtlcNotifier.NotifyForwardingFailEvent(
+			key, info, eventType,
+		)
 	}
 }
 
@@ -948,6 +1093,76 @@ func (s *Switch) parseFailedPayment(deobfuscator ErrorDecrypter,
 	}
 }
 
+// offeredHoldFee returns the hold fee offered by the passed forwarded htlc
+// packet, so that it is persisted with its circuit. Zero is returned if no hold
+// fee is required by our policy or the htlc doesn't offer a valid one, in which
+// case checkHoldFee rejects it.
+func (s *Switch) offeredHoldFee(packet *htlcPacket) lnwire.MilliAtom {
+	if !s.cfg.HoldFeePolicy.Active() ||
+		packet.incomingChanID == hop.Source {
+
+		return 0
+	}
+
+	holdFee, err := holdfee.ParseRecord(packet.customRecords)
+	if err != nil {
+		return 0
+	}
+
+	return holdFee
+}
+
+// checkHoldFee ensures that the passed htlc packet offers the hold fee
+// required by our policy for the amount being forwarded, and returns the fee
+// offered.
+func checkHoldFee(policy holdfee.Policy, packet *htlcPacket,
+	payHash lntypes.Hash) (lnwire.MilliAtom, *LinkError) {
+
+	holdFee, err := holdfee.ParseRecord(packet.customRecords)
+	if err != nil {
+		log.Debugf("Incoming htlc(%x) has no valid hold fee: %v",
+			payHash[:], err)
+
+		return 0, NewLinkError(
+			lnwire.NewInvalidOnionPayload(record.HoldFeeType, 0),
+		)
+	}
+
+	// The hold fee must be paid on top of the outgoing amount and cover
+	// our required hold fee.
+	requiredFee := policy.Fee(packet.amount)
+	if holdFee < requiredFee ||
+		packet.incomingAmount < packet.amount+holdFee {
+
+		log.Debugf("Incoming htlc(%x) has insufficient hold fee: "+
+			"expected %v, offered %v", payHash[:], requiredFee,
+			holdFee)
+
+		return 0, NewLinkError(
+			lnwire.NewInvalidOnionPayload(record.HoldFeeType, 0),
+		)
+	}
+
+	return holdFee, nil
+}
+
+// ForwardsBlocked returns true if the switch refuses new forwards.
+func (s *Switch) ForwardsBlocked() bool {
+	return atomic.LoadInt32(&s.forwardsBlocked) == 1
+}
+
+// SetForwardsBlocked sets whether the switch refuses new forwards. While
+// blocked, any htlc that is meant as an onward payment is failed back, as if
+// the node was configured with RejectHTLC. The settles and fails of htlcs that
+// were already forwarded are unaffected.
+func (s *Switch) SetForwardsBlocked(blocked bool) {
+	var value int32
+	if blocked {
+		value = 1
+	}
+	atomic.StoreInt32(&s.forwardsBlocked, value)
+}
+
 // handlePacketForward is used in cases when we need forward the htlc update
 // from one channel link to another and be able to propagate the settle/fail
 // updates back. This behaviour is achieved by creation of payment circuits.
@@ -960,7 +1175,7 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 	case *lnwire.UpdateAddHTLC:
 		// Check if the node is set to reject all onward HTLCs and also make
 		// sure that HTLC is not from the source node.
-		if s.cfg.RejectHTLC {
+		if s.cfg.RejectHTLC || s.ForwardsBlocked() {
 			failure := NewDetailedLinkError(
 				&lnwire.FailChannelDisabled{},
 				OutgoingFailureForwardsDisabled,
@@ -983,6 +1198,21 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 			return s.failAddPacket(packet, linkErr)
 		}
 
+		// If we require a hold fee, ensure the htlc offers a sufficient
+		// one. The hold fee is excluded from the amount checked against
+		// the forwarding policy of the outgoing link.
+		incomingAmt := packet.incomingAmount
+		var holdFee lnwire.MilliAtom
+		if s.cfg.HoldFeePolicy.Active() {
+			holdFee, linkErr = checkHoldFee(
+				s.cfg.HoldFeePolicy, packet, htlc.PaymentHash,
+			)
+			if linkErr != nil {
+				return s.failAddPacket(packet, linkErr)
+			}
+			incomingAmt -= holdFee
+		}
+
 		s.indexMtx.RLock()
 		targetLink, err := s.getLinkByShortID(packet.outgoingChanID)
 		if err != nil {
@@ -1002,8 +1232,44 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 		}
 		targetPeerKey := targetLink.Peer().PubKey()
 		interfaceLinks, _ := s.getLinks(targetPeerKey)
+
+		// Look up the peer that offered the htlc, as its reputation
+		// determines the share of the outgoing link it may occupy.
+		reputationTracker := s.cfg.HtlcReputation
+		var (
+			sourcePeer  route.Vertex
+			sourceKnown bool
+		)
+		if reputationTracker != nil {
+			sourceLink, err := s.getLinkByShortID(
+				packet.incomingChanID,
+			)
+			if err == nil {
+				sourcePeer = sourceLink.Peer().PubKey()
+				sourceKnown = true
+			}
+		}
 		s.indexMtx.RUnlock()
 
+		// The incoming link may have been removed since it offered the
+		// htlc, in which case we'll look up its peer in the database.
+		if reputationTracker != nil && !sourceKnown {
+			sourcePeer, err = reputationTracker.ChannelPeer(
+				packet.incomingChanID,
+			)
+			if err != nil {
+				log.Errorf("Unable to find peer of incoming "+
+					"channel %v: %v", packet.incomingChanID,
+					err)
+
+				return s.failAddPacket(
+					packet, NewLinkError(
+						lnwire.NewTemporaryChannelFailure(nil),
+					),
+				)
+			}
+		}
+
 		// We'll keep track of any HTLC failures during the link
 		// selection process. This way we can return the error for
 		// precise link that the sender selected, while optimistically
@@ -1029,12 +1295,33 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 				// link.
 				currentHeight := atomic.LoadUint32(&s.bestHeight)
 				failure = link.CheckHtlcForward(
-					htlc.PaymentHash, packet.incomingAmount,
+					htlc.PaymentHash, incomingAmt,
 					packet.amount, packet.incomingTimeout,
-					packet.outgoingTimeout, currentHeight,
+					packet.outgoingTimeout,
+					packet.incomingChanID,
+					packet.inboundFee, currentHeight,
 				)
 			}
 
+			// If the peer that offered the htlc is unreputable,
+			// ensure it fits in the share of this link reserved for
+			// such peers. Otherwise, another link may still have
+			// room for it.
+			if failure == nil && reputationTracker != nil {
+				maxHtlcs, maxAmt := link.OutgoingHtlcLimits()
+				err := reputationTracker.AddHTLC(
+					packet.inKey(), sourcePeer,
+					link.ShortChanID(), packet.amount,
+					maxHtlcs, maxAmt,
+				)
+				if err != nil {
+					failure = NewDetailedLinkError(
+						lnwire.NewTemporaryChannelFailure(nil),
+						OutgoingFailureReputationBucketFull,
+					)
+				}
+			}
+
 			// Stop searching if this link can forward the htlc.
 			if failure == nil {
 				destination = link
@@ -1078,7 +1365,29 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 		// Send the packet to the destination channel link which
 		// manages the channel.
 		packet.outgoingChanID = destination.ShortChanID()
-		return destination.HandleSwitchPacket(packet)
+
+		// Track the time the htlc is handed to its outgoing link
+		// beforehand, as it may resolve before the link returns.
+		s.fwdEventMtx.Lock()
+		s.addTimes[packet.inKey()] = s.cfg.Clock.Now()
+		s.fwdEventMtx.Unlock()
+
+		err = destination.HandleSwitchPacket(packet)
+		if err != nil {
+			s.fwdEventMtx.Lock()
+			delete(s.addTimes, packet.inKey())
+			s.fwdEventMtx.Unlock()
+
+			if reputationTracker != nil {
+				reputationTracker.ResolveHTLC(
+					packet.inKey(), false, 0,
+				)
+			}
+
+			return err
+		}
+
+		return nil
 
 	case *lnwire.UpdateFailHTLC, *lnwire.UpdateFulfillHTLC:
 		// If the source of this packet has not been set, use the
@@ -1094,7 +1403,42 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 			return nil
 		}
 
+		// The circuit is now resolved, so we no longer need to track
+		// the time it was added.
+		s.fwdEventMtx.Lock()
+		addTime, hasAddTime := s.addTimes[circuit.Incoming]
+		delete(s.addTimes, circuit.Incoming)
+		s.fwdEventMtx.Unlock()
+
 		fail, isFail := htlc.(*lnwire.UpdateFailHTLC)
+
+		// Record the time the htlc took to resolve once it was handed
+		// to its outgoing link. Htlcs added before a restart aren't
+		// tracked.
+		if hasAddTime {
+			htlcType, outcome := htlcResolutionLabels(
+				circuit.Incoming, isFail,
+			)
+			monitoring.ObserveHTLCResolution(
+				htlcType, outcome,
+				packet.outgoingChanID.String(),
+				s.cfg.Clock.Now().Sub(addTime),
+			)
+		}
+
+		// Release the slot the htlc occupied on the outgoing link,
+		// crediting the fee it earned to the peer that offered it if
+		// it was settled.
+		if s.cfg.HtlcReputation != nil {
+			var fee lnwire.MilliAtom
+			if circuit.IncomingAmount > circuit.OutgoingAmount {
+				fee = circuit.IncomingAmount -
+					circuit.OutgoingAmount
+			}
+			s.cfg.HtlcReputation.ResolveHTLC(
+				circuit.Incoming, !isFail, fee,
+			)
+		}
 		if isFail && !packet.hasSource {
 			switch {
 			// No message to encrypt, locally sourced payment.
@@ -1160,12 +1504,40 @@ func (s *Switch) handlePacketForward(packet *htlcPacket) error {
 						OutgoingChanID: circuit.Outgoing.ChanID,
 						AmtIn:          circuit.IncomingAmount,
 						AmtOut:         circuit.OutgoingAmount,
+						HoldFee:        circuit.HoldFee,
 					},
 				)
 				s.fwdEventMtx.Unlock()
 			}
 		}
 
+		// Failed forwards that offered a hold fee are logged apart from
+		// the settled ones. As the incoming htlc is failed back, the
+		// hold fee it offered is never received, so no fee is logged.
+		if isFail && circuit.Outgoing != nil && circuit.HoldFee != 0 &&
+			packet.incomingChanID != hop.Source {
+
+			log.Debugf("Logging failed HTLC(%x) offering hold fee "+
+				"of %v from IncomingChanID(%v) to "+
+				"OutgoingChanID(%v)", circuit.PaymentHash[:],
+				circuit.HoldFee, circuit.Incoming.ChanID,
+				circuit.Outgoing.ChanID)
+
+			s.fwdEventMtx.Lock()
+			s.pendingFwdingEvents = append(
+				s.pendingFwdingEvents,
+				channeldb.ForwardingEvent{
+					Timestamp:      time.Now(),
+					IncomingChanID: circuit.Incoming.ChanID,
+					OutgoingChanID: circuit.Outgoing.ChanID,
+					AmtIn:          circuit.IncomingAmount,
+					AmtOut:         circuit.OutgoingAmount,
+					Failed:         true,
+				},
+			)
+			s.fwdEventMtx.Unlock()
+		}
+
 		// A blank IncomingChanID in a circuit indicates that it is a pending
 		// user-initiated payment.
 		if packet.incomingChanID == hop.Source {
@@ -1428,10 +1800,12 @@ func (s *Switch) teardownCircuit(pkt *htlcPacket) error {
 // CloseLink creates and sends the close channel command to the target link
 // directing the specified closure type. If the closure type is CloseRegular,
 // targetFeePerKB parameter should be the ideal fee-per-kb that will be used as
-// a starting point for close negotiation. The deliveryScript parameter is an
+// a starting point for close negotiation, and a non-zero maxFeePerKB caps the
+// fee-per-kb the negotiation may agree to. The deliveryScript parameter is an
 // optional parameter which sets a user specified script to close out to.
 func (s *Switch) CloseLink(chanPoint *wire.OutPoint,
-	closeType ChannelCloseType, targetFeePerKB chainfee.AtomPerKByte,
+	closeType ChannelCloseType, targetFeePerKB,
+	maxFeePerKB chainfee.AtomPerKByte,
 	deliveryScript lnwire.DeliveryAddress) (chan interface{}, chan error) {
 
 	// TODO(roasbeef) abstract out the close updates.
@@ -1443,6 +1817,7 @@ func (s *Switch) CloseLink(chanPoint *wire.OutPoint,
 		ChanPoint:      chanPoint,
 		Updates:        updateChan,
 		TargetFeePerKB: targetFeePerKB,
+		MaxFeePerKB:    maxFeePerKB,
 		DeliveryScript: deliveryScript,
 		Err:            errChan,
 	}
@@ -1470,6 +1845,7 @@ func (s *Switch) CloseLink(chanPoint *wire.OutPoint,
 // NOTE: This MUST be run as a goroutine.
 func (s *Switch) htlcForwarder() {
 	defer s.wg.Done()
+	defer crashreport.Recover(Subsystem)
 
 	defer func() {
 		s.blockEpochStream.Cancel()
@@ -2190,6 +2566,44 @@ func (s *Switch) deleteCircuits(inKeys ...CircuitKey) error {
 	return s.circuits.DeleteCircuits(inKeys...)
 }
 
+// CompactCircuits removes the circuits that can no longer be resolved because
+// both of their channels have been closed. Circuits of HTLCs that were never
+// forwarded are removed once their incoming channel is closed. The number of
+// removed circuits is returned.
+func (s *Switch) CompactCircuits(
+	closedChans map[lnwire.ShortChannelID]struct{}) (int, error) {
+
+	isClosed := func(chanID lnwire.ShortChannelID) bool {
+		_, ok := closedChans[chanID]
+		return ok
+	}
+
+	var staleKeys []CircuitKey
+	for _, circuit := range s.circuits.Circuits() {
+		if !isClosed(circuit.Incoming.ChanID) {
+			continue
+		}
+
+		if circuit.Outgoing != nil &&
+			!isClosed(circuit.Outgoing.ChanID) {
+
+			continue
+		}
+
+		staleKeys = append(staleKeys, circuit.Incoming)
+	}
+
+	if len(staleKeys) == 0 {
+		return 0, nil
+	}
+
+	if err := s.circuits.DeleteCircuits(staleKeys...); err != nil {
+		return 0, err
+	}
+
+	return len(staleKeys), nil
+}
+
 // FlushForwardingEvents flushes out the set of pending forwarding events to
 // the persistent log. This will be used by the switch to periodically flush
 // out the set of forwarding events to disk. External callers can also use this
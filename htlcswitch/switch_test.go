@@ -3,6 +3,7 @@ package htlcswitch
 import (
 	"crypto/rand"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -12,11 +13,17 @@ import (
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/htlcswitch/holdfee"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/htlcswitch/reputation"
+	"github.com/decred/dcrlnd/lntest/wait"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
 	"github.com/decred/dcrlnd/ticker"
 )
 
@@ -1544,6 +1551,556 @@ func TestCheckCircularForward(t *testing.T) {
 	}
 }
 
+// TestCheckHoldFee tests that htlcs are only forwarded if they offer the hold
+// fee required by our policy, on top of the outgoing amount.
+func TestCheckHoldFee(t *testing.T) {
+	policy := holdfee.Policy{
+		BaseFee: 10,
+		FeeRate: 1000,
+	}
+	invalidPayload := NewLinkError(
+		lnwire.NewInvalidOnionPayload(record.HoldFeeType, 0),
+	)
+
+	tests := []struct {
+		name        string
+		records     record.CustomSet
+		incomingAmt lnwire.MilliAtom
+		expectedFee lnwire.MilliAtom
+		expectedErr *LinkError
+	}{
+		{
+			name:        "missing record",
+			incomingAmt: 20000,
+			expectedErr: invalidPayload,
+		},
+		{
+			name: "insufficient hold fee",
+			records: record.CustomSet{
+				record.HoldFeeType: holdfee.EncodeRecord(19),
+			},
+			incomingAmt: 20000,
+			expectedErr: invalidPayload,
+		},
+		{
+			name: "hold fee not paid",
+			records: record.CustomSet{
+				record.HoldFeeType: holdfee.EncodeRecord(20),
+			},
+			incomingAmt: 10010,
+			expectedErr: invalidPayload,
+		},
+		{
+			name: "sufficient hold fee",
+			records: record.CustomSet{
+				record.HoldFeeType: holdfee.EncodeRecord(25),
+			},
+			incomingAmt: 10025,
+			expectedFee: 25,
+		},
+	}
+
+	for _, test := range tests {
+		packet := &htlcPacket{
+			incomingAmount: test.incomingAmt,
+			amount:         10000,
+			customRecords:  test.records,
+		}
+
+		fee, err := checkHoldFee(policy, packet, lntypes.Hash{})
+		if !reflect.DeepEqual(err, test.expectedErr) {
+			t.Fatalf("%v: expected: %v, got: %v", test.name,
+				test.expectedErr, err)
+		}
+		if fee != test.expectedFee {
+			t.Fatalf("%v: expected fee %v, got %v", test.name,
+				test.expectedFee, fee)
+		}
+	}
+}
+
+// TestSwitchHoldFeeFailedForward asserts that a failed forward offering a hold
+// fee is logged apart from the settled ones without any fee, as the hold fee
+// it offered is never received.
+func TestSwitchHoldFeeFailedForward(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	s.cfg.HoldFeePolicy = holdfee.Policy{
+		BaseFee: 10,
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	preimage, err := genPreimage()
+	if err != nil {
+		t.Fatalf("unable to generate preimage: %v", err)
+	}
+	packet := &htlcPacket{
+		incomingChanID: aliceChannelLink.ShortChanID(),
+		incomingHTLCID: 0,
+		outgoingChanID: bobChannelLink.ShortChanID(),
+		incomingAmount: 1025,
+		amount:         1000,
+		obfuscator:     NewMockObfuscator(),
+		customRecords: record.CustomSet{
+			record.HoldFeeType: holdfee.EncodeRecord(25),
+		},
+		htlc: &lnwire.UpdateAddHTLC{
+			PaymentHash: sha256.Sum256(preimage[:]),
+			Amount:      1000,
+		},
+	}
+	if err := s.ForwardPackets(nil, packet); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case packet := <-bobChannelLink.packets:
+		if err := bobChannelLink.completeCircuit(packet); err != nil {
+			t.Fatalf("unable to complete payment circuit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+
+	// The hold fee offered must have been persisted with the circuit.
+	circuit := s.circuits.LookupOpenCircuit(CircuitKey{
+		ChanID: bobChannelLink.ShortChanID(),
+		HtlcID: 0,
+	})
+	if circuit == nil || circuit.HoldFee != 25 {
+		t.Fatalf("expected circuit with hold fee 25, got %v", circuit)
+	}
+
+	// Bob fails the htlc back to alice.
+	failPacket := &htlcPacket{
+		outgoingChanID: bobChannelLink.ShortChanID(),
+		outgoingHTLCID: 0,
+		amount:         1000,
+		htlc:           &lnwire.UpdateFailHTLC{},
+	}
+	if err := s.ForwardPackets(nil, failPacket); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case packet := <-aliceChannelLink.packets:
+		if err := aliceChannelLink.deleteCircuit(packet); err != nil {
+			t.Fatalf("unable to remove circuit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fail was not propagated to source")
+	}
+
+	// The failed forward is logged without the hold fee it offered.
+	if err := s.FlushForwardingEvents(); err != nil {
+		t.Fatalf("unable to flush forwarding events: %v", err)
+	}
+
+	fwdLog := s.cfg.FwdingLog.(*mockForwardingLog)
+	fwdLog.Lock()
+	defer fwdLog.Unlock()
+
+	if len(fwdLog.events) != 1 {
+		t.Fatalf("expected 1 forwarding event, got %d",
+			len(fwdLog.events))
+	}
+	for _, event := range fwdLog.events {
+		if !event.Failed || event.HoldFee != 0 ||
+			event.AmtIn != 1025 || event.AmtOut != 1000 {
+
+			t.Fatalf("unexpected forwarding event: %v",
+				spew.Sdump(event))
+		}
+	}
+}
+
+// TestSwitchForwardReputationBucket asserts that the htlcs of unreputable
+// peers are rejected once they occupy the share of the outgoing link reserved
+// for them, and that resolved htlcs release their slot.
+func TestSwitchForwardReputationBucket(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	s.cfg.HtlcReputation = reputation.NewTracker(&reputation.Config{
+		Policy: reputation.Policy{
+			BucketPercent: 50,
+			MinRevenue:    1000,
+			Window:        time.Hour,
+		},
+		Clock: clock.NewDefaultClock(),
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+
+	// Bob accepts two htlcs in flight, so alice, who has no reputation
+	// yet, may only occupy one of them.
+	bobChannelLink.maxHtlcs = 2
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	forward := func(htlcID uint64) {
+		t.Helper()
+
+		preimage, err := genPreimage()
+		if err != nil {
+			t.Fatalf("unable to generate preimage: %v", err)
+		}
+		packet := &htlcPacket{
+			incomingChanID: aliceChannelLink.ShortChanID(),
+			incomingHTLCID: htlcID,
+			outgoingChanID: bobChannelLink.ShortChanID(),
+			obfuscator:     NewMockObfuscator(),
+			htlc: &lnwire.UpdateAddHTLC{
+				PaymentHash: sha256.Sum256(preimage[:]),
+				Amount:      1,
+			},
+		}
+		if err := s.ForwardPackets(nil, packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first htlc is forwarded to bob.
+	forward(0)
+	select {
+	case packet := <-bobChannelLink.packets:
+		if err := bobChannelLink.completeCircuit(packet); err != nil {
+			t.Fatalf("unable to complete payment circuit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+
+	// The second one exceeds the bucket and is failed back to alice.
+	forward(1)
+	select {
+	case packet := <-aliceChannelLink.packets:
+		if packet.linkFailure == nil ||
+			packet.linkFailure.FailureDetail !=
+				OutgoingFailureReputationBucketFull {
+
+			t.Fatalf("expected bucket full failure, got %v",
+				packet.linkFailure)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("htlc was not failed back")
+	}
+
+	// Once bob fails the first htlc, its slot is released.
+	failPacket := &htlcPacket{
+		outgoingChanID: bobChannelLink.ShortChanID(),
+		outgoingHTLCID: 0,
+		amount:         1,
+		htlc:           &lnwire.UpdateFailHTLC{},
+	}
+	if err := s.ForwardPackets(nil, failPacket); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case packet := <-aliceChannelLink.packets:
+		if err := aliceChannelLink.deleteCircuit(packet); err != nil {
+			t.Fatalf("unable to remove circuit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("fail was not propagated to source")
+	}
+
+	forward(2)
+	select {
+	case <-bobChannelLink.packets:
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+}
+
+// TestSwitchForwardReputationOtherLink asserts that an htlc that exceeds the
+// bucket of the requested link is forwarded over another link to the same
+// peer that still has room for it.
+func TestSwitchForwardReputationOtherLink(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	s.cfg.HtlcReputation = reputation.NewTracker(&reputation.Config{
+		Policy: reputation.Policy{
+			BucketPercent: 50,
+			MinRevenue:    1000,
+			Window:        time.Hour,
+		},
+		Clock: clock.NewDefaultClock(),
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+	chanID3, bobChanID2 := genID()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+	bobChannelLink2 := newMockChannelLink(
+		s, chanID3, bobChanID2, bobPeer, true,
+	)
+
+	// Each of bob's links accepts a single htlc of alice, who has no
+	// reputation yet.
+	bobChannelLink.maxHtlcs = 2
+	bobChannelLink2.maxHtlcs = 2
+	links := []ChannelLink{aliceChannelLink, bobChannelLink, bobChannelLink2}
+	for _, link := range links {
+		if err := s.AddLink(link); err != nil {
+			t.Fatalf("unable to add link: %v", err)
+		}
+	}
+
+	forward := func(htlcID uint64) {
+		t.Helper()
+
+		preimage, err := genPreimage()
+		if err != nil {
+			t.Fatalf("unable to generate preimage: %v", err)
+		}
+		packet := &htlcPacket{
+			incomingChanID: aliceChannelLink.ShortChanID(),
+			incomingHTLCID: htlcID,
+			outgoingChanID: bobChannelLink.ShortChanID(),
+			obfuscator:     NewMockObfuscator(),
+			htlc: &lnwire.UpdateAddHTLC{
+				PaymentHash: sha256.Sum256(preimage[:]),
+				Amount:      1,
+			},
+		}
+		if err := s.ForwardPackets(nil, packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first two htlcs are forwarded over different links to bob.
+	forwarded := make(map[lnwire.ShortChannelID]struct{})
+	for i := uint64(0); i < 2; i++ {
+		forward(i)
+
+		var packet *htlcPacket
+		select {
+		case packet = <-bobChannelLink.packets:
+		case packet = <-bobChannelLink2.packets:
+		case <-time.After(time.Second):
+			t.Fatal("request was not propagated to destination")
+		}
+		forwarded[packet.outgoingChanID] = struct{}{}
+	}
+	if len(forwarded) != 2 {
+		t.Fatalf("expected htlcs to be forwarded over both links")
+	}
+
+	// Once both buckets are full, the next htlc is failed back to alice.
+	forward(2)
+	select {
+	case packet := <-aliceChannelLink.packets:
+		if packet.linkFailure == nil ||
+			packet.linkFailure.FailureDetail !=
+				OutgoingFailureReputationBucketFull {
+
+			t.Fatalf("expected bucket full failure, got %v",
+				packet.linkFailure)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("htlc was not failed back")
+	}
+}
+
+// TestSwitchForwardReputationRemovedSource asserts that the peer that offered
+// an htlc over a link that was removed since is looked up in the database, and
+// that the htlc isn't forwarded if the peer is unknown.
+func TestSwitchForwardReputationRemovedSource(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+	aliceKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+	_, unknownChanID := genID()
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	s.cfg.HtlcReputation = reputation.NewTracker(&reputation.Config{
+		Policy: reputation.Policy{
+			BucketPercent: 50,
+			MinRevenue:    1000,
+			Window:        time.Hour,
+		},
+		Clock: clock.NewDefaultClock(),
+		FetchAllOpenChannels: func() ([]*channeldb.OpenChannel, error) {
+			return nil, nil
+		},
+		FetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return []*channeldb.ChannelCloseSummary{{
+				ShortChanID: aliceChanID,
+				RemotePub:   aliceKey.PubKey(),
+			}}, nil
+		},
+	})
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := newMockChannelLink(
+		s, chanID2, bobChanID, bobPeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+	s.RemoveLink(chanID1)
+
+	forward := func(incomingChanID lnwire.ShortChannelID) {
+		t.Helper()
+
+		preimage, err := genPreimage()
+		if err != nil {
+			t.Fatalf("unable to generate preimage: %v", err)
+		}
+		packet := &htlcPacket{
+			incomingChanID: incomingChanID,
+			incomingHTLCID: 0,
+			outgoingChanID: bobChannelLink.ShortChanID(),
+			obfuscator:     NewMockObfuscator(),
+			htlc: &lnwire.UpdateAddHTLC{
+				PaymentHash: sha256.Sum256(preimage[:]),
+				Amount:      1,
+			},
+		}
+		if err := s.ForwardPackets(nil, packet); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The htlc of the closed channel of alice is forwarded to bob.
+	forward(aliceChanID)
+	select {
+	case <-bobChannelLink.packets:
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+
+	// The htlc of an unknown channel isn't.
+	forward(unknownChanID)
+	select {
+	case <-bobChannelLink.packets:
+		t.Fatal("htlc of unknown channel was forwarded")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
 // TestSkipIneligibleLinksMultiHopForward tests that if a multi-hop HTLC comes
 // along, then we won't attempt to froward it down al ink that isn't yet able
 // to forward any HTLC's.
@@ -2035,39 +2592,190 @@ func TestSwitchAddSamePayment(t *testing.T) {
 			t.Fatalf("unable to remove circuit: %v", err)
 		}
 
-	case <-time.After(time.Second):
-		t.Fatal("request was not propagated to channelPoint")
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to channelPoint")
+	}
+
+	if s.circuits.NumOpen() != 1 {
+		t.Fatal("wrong amount of circuits")
+	}
+
+	request = &htlcPacket{
+		outgoingChanID: bobChannelLink.ShortChanID(),
+		outgoingHTLCID: 1,
+		amount:         1,
+		htlc:           &lnwire.UpdateFailHTLC{},
+	}
+
+	// Handle the request and checks that payment circuit works properly.
+	if err := s.ForwardPackets(nil, request); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case pkt := <-aliceChannelLink.packets:
+		if err := aliceChannelLink.completeCircuit(pkt); err != nil {
+			t.Fatalf("unable to remove circuit: %v", err)
+		}
+
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to channelPoint")
+	}
+
+	if s.circuits.NumOpen() != 0 {
+		t.Fatal("wrong amount of circuits")
+	}
+}
+
+// TestSwitchSettleLocalPayment tests that the settle of a payment to ourselves
+// by our own exit hop is only attributed to an attempt if a single one is in
+// flight, that its result is stored and delivered right away, and that it
+// isn't replaced by the later resolution of the first-hop htlc.
+func TestSwitchSettleLocalPayment(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, _, aliceChanID, _ := genIDs()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add link: %v", err)
+	}
+
+	preimage, err := genPreimage()
+	if err != nil {
+		t.Fatalf("unable to generate preimage: %v", err)
+	}
+	rhash := sha256.Sum256(preimage[:])
+
+	// sendAttempt sends a payment attempt over alice's link and waits for
+	// its circuit to be opened.
+	sendAttempt := func(paymentID uint64) {
+		t.Helper()
+
+		err := s.SendHTLC(
+			aliceChannelLink.ShortChanID(), paymentID,
+			&lnwire.UpdateAddHTLC{
+				PaymentHash: rhash,
+				Amount:      1,
+			},
+		)
+		if err != nil {
+			t.Fatalf("unable to send htlc: %v", err)
+		}
+
+		select {
+		case packet := <-aliceChannelLink.packets:
+			err := aliceChannelLink.completeCircuit(packet)
+			if err != nil {
+				t.Fatalf("unable to complete payment "+
+					"circuit: %v", err)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("request was not propagated to destination")
+		}
+	}
+
+	// With two attempts in flight, the settle can't be attributed to one
+	// of them, so nothing is stored.
+	sendAttempt(1)
+	sendAttempt(2)
+
+	resultChan, err := s.GetPaymentResult(
+		1, lntypes.Hash(rhash), newMockDeobfuscator(),
+	)
+	if err != nil {
+		t.Fatalf("unable to get payment result: %v", err)
+	}
+
+	s.SettleLocalPayment(preimage)
+	_, err = s.networkResults.getResult(1)
+	if err != ErrPaymentIDNotFound {
+		t.Fatalf("expected no result with two attempts in flight, "+
+			"got %v", err)
 	}
 
-	if s.circuits.NumOpen() != 1 {
-		t.Fatal("wrong amount of circuits")
+	// assertSettled asserts that a settle with our preimage is received
+	// on the given result channel.
+	assertSettled := func(resultChan <-chan *PaymentResult) {
+		t.Helper()
+
+		select {
+		case result := <-resultChan:
+			if result.Error != nil {
+				t.Fatalf("unexpected payment error: %v",
+					result.Error)
+			}
+			if result.Preimage != preimage {
+				t.Fatalf("expected preimage %x, got %x",
+					preimage, result.Preimage)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("payment result was not delivered")
+		}
 	}
 
-	request = &htlcPacket{
-		outgoingChanID: bobChannelLink.ShortChanID(),
-		outgoingHTLCID: 1,
+	// Once the second attempt failed, the settle of our exit hop settles
+	// the first one right away, while its first-hop htlc is still in
+	// flight.
+	aliceChannelLink.htlcSwitch.circuits.DeleteCircuits(CircuitKey{
+		ChanID: hop.Source,
+		HtlcID: 2,
+	})
+
+	s.SettleLocalPayment(preimage)
+	assertSettled(resultChan)
+
+	// Even if the first-hop htlc is failed back, the preimage was already
+	// released by our exit hop, so the stored settle is kept.
+	packet := &htlcPacket{
+		outgoingChanID: aliceChannelLink.ShortChanID(),
+		outgoingHTLCID: 0,
 		amount:         1,
-		htlc:           &lnwire.UpdateFailHTLC{},
+		htlc: &lnwire.UpdateFailHTLC{
+			Reason: []byte{1, 2, 3},
+		},
 	}
-
-	// Handle the request and checks that payment circuit works properly.
-	if err := s.ForwardPackets(nil, request); err != nil {
-		t.Fatal(err)
+	if err := s.ForwardPackets(nil, packet); err != nil {
+		t.Fatalf("can't forward htlc packet: %v", err)
 	}
 
-	select {
-	case pkt := <-aliceChannelLink.packets:
-		if err := aliceChannelLink.completeCircuit(pkt); err != nil {
-			t.Fatalf("unable to remove circuit: %v", err)
+	// Once the circuit is torn down, the persisted settle is returned.
+	err = wait.NoError(func() error {
+		if s.circuits.NumOpen() != 0 {
+			return fmt.Errorf("expected no open circuit, got %d",
+				s.circuits.NumOpen())
 		}
-
-	case <-time.After(time.Second):
-		t.Fatal("request was not propagated to channelPoint")
+		return nil
+	}, time.Second)
+	if err != nil {
+		t.Fatal(err)
 	}
 
-	if s.circuits.NumOpen() != 0 {
-		t.Fatal("wrong amount of circuits")
+	resultChan, err = s.GetPaymentResult(
+		1, lntypes.Hash(rhash), newMockDeobfuscator(),
+	)
+	if err != nil {
+		t.Fatalf("unable to get payment result: %v", err)
 	}
+	assertSettled(resultChan)
 }
 
 // TestSwitchSendPayment tests ability of htlc switch to respond to the
@@ -2208,6 +2916,247 @@ func TestSwitchSendPayment(t *testing.T) {
 	}
 }
 
+// TestSwitchLocalHTLCResolutionTime asserts that the switch tracks the time
+// locally sent htlcs take to resolve, and records it under the local type.
+func TestSwitchLocalHTLCResolutionTime(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, _, aliceChanID, _ := genIDs()
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add link: %v", err)
+	}
+
+	preimage, err := genPreimage()
+	if err != nil {
+		t.Fatalf("unable to generate preimage: %v", err)
+	}
+	rhash := sha256.Sum256(preimage[:])
+	update := &lnwire.UpdateAddHTLC{
+		PaymentHash: rhash,
+		Amount:      1,
+	}
+	paymentID := uint64(123)
+	err = s.SendHTLC(aliceChannelLink.ShortChanID(), paymentID, update)
+	if err != nil {
+		t.Fatalf("unable to send htlc: %v", err)
+	}
+
+	select {
+	case packet := <-aliceChannelLink.packets:
+		if err := aliceChannelLink.completeCircuit(packet); err != nil {
+			t.Fatalf("unable to complete payment circuit: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("request was not propagated to destination")
+	}
+
+	inKey := CircuitKey{ChanID: hop.Source, HtlcID: paymentID}
+	s.fwdEventMtx.Lock()
+	_, ok := s.addTimes[inKey]
+	s.fwdEventMtx.Unlock()
+	if !ok {
+		t.Fatalf("expected add time of local htlc to be tracked")
+	}
+
+	htlcType, outcome := htlcResolutionLabels(inKey, true)
+	if htlcType != "local" || outcome != "failed" {
+		t.Fatalf("expected local failed htlc, got %v %v", htlcType,
+			outcome)
+	}
+
+	// Fail the htlc back, which should stop tracking it once its result is
+	// available.
+	obfuscator := NewMockObfuscator()
+	failure := lnwire.NewFailIncorrectDetails(update.Amount, 100)
+	reason, err := obfuscator.EncryptFirstHop(failure)
+	if err != nil {
+		t.Fatalf("unable obfuscate failure: %v", err)
+	}
+	packet := &htlcPacket{
+		outgoingChanID: aliceChannelLink.ShortChanID(),
+		outgoingHTLCID: 0,
+		amount:         1,
+		htlc: &lnwire.UpdateFailHTLC{
+			Reason: reason,
+		},
+	}
+	if err := s.ForwardPackets(nil, packet); err != nil {
+		t.Fatalf("can't forward htlc packet: %v", err)
+	}
+
+	resultChan, err := s.GetPaymentResult(
+		paymentID, lntypes.Hash(rhash), newMockDeobfuscator(),
+	)
+	if err != nil {
+		t.Fatalf("unable to get payment result: %v", err)
+	}
+	select {
+	case <-resultChan:
+	case <-time.After(time.Second):
+		t.Fatal("payment result wasn't received")
+	}
+
+	s.fwdEventMtx.Lock()
+	numTracked := len(s.addTimes)
+	s.fwdEventMtx.Unlock()
+	if numTracked != 0 {
+		t.Fatalf("expected no tracked htlcs, got %v", numTracked)
+	}
+
+	// Forwarded htlcs are recorded under their own type.
+	htlcType, outcome = htlcResolutionLabels(
+		CircuitKey{ChanID: aliceChanID}, false,
+	)
+	if htlcType != "forward" || outcome != "settled" {
+		t.Fatalf("expected forward settled htlc, got %v %v", htlcType,
+			outcome)
+	}
+}
+
+// addTimeLink is a mock link recording whether the switch tracked the add time
+// of the htlcs it's handed before handing them over.
+type addTimeLink struct {
+	*mockChannelLink
+
+	s       *Switch
+	err     error
+	tracked chan bool
+}
+
+func (l *addTimeLink) HandleSwitchPacket(pkt *htlcPacket) error {
+	l.s.fwdEventMtx.Lock()
+	_, ok := l.s.addTimes[pkt.inKey()]
+	l.s.fwdEventMtx.Unlock()
+	l.tracked <- ok
+
+	if l.err != nil {
+		return l.err
+	}
+
+	return l.mockChannelLink.HandleSwitchPacket(pkt)
+}
+
+// TestSwitchForwardHTLCResolutionTime asserts that the switch tracks the add
+// time of forwarded htlcs before handing them to their outgoing link, and stops
+// tracking them if the link refuses them.
+func TestSwitchForwardHTLCResolutionTime(t *testing.T) {
+	t.Parallel()
+
+	alicePeer, err := newMockServer(
+		t, "alice", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create alice server: %v", err)
+	}
+	bobPeer, err := newMockServer(
+		t, "bob", testStartingHeight, nil, testDefaultDelta,
+	)
+	if err != nil {
+		t.Fatalf("unable to create bob server: %v", err)
+	}
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	chanID1, chanID2, aliceChanID, bobChanID := genIDs()
+
+	aliceChannelLink := newMockChannelLink(
+		s, chanID1, aliceChanID, alicePeer, true,
+	)
+	bobChannelLink := &addTimeLink{
+		mockChannelLink: newMockChannelLink(
+			s, chanID2, bobChanID, bobPeer, true,
+		),
+		s:       s,
+		err:     errors.New("link refused htlc"),
+		tracked: make(chan bool, 1),
+	}
+	if err := s.AddLink(aliceChannelLink); err != nil {
+		t.Fatalf("unable to add alice link: %v", err)
+	}
+	if err := s.AddLink(bobChannelLink); err != nil {
+		t.Fatalf("unable to add bob link: %v", err)
+	}
+
+	forward := func(htlcID uint64) CircuitKey {
+		packet := &htlcPacket{
+			incomingChanID: aliceChannelLink.ShortChanID(),
+			incomingHTLCID: htlcID,
+			outgoingChanID: bobChannelLink.ShortChanID(),
+			obfuscator:     NewMockObfuscator(),
+			htlc: &lnwire.UpdateAddHTLC{
+				PaymentHash: [32]byte{byte(htlcID)},
+				Amount:      1,
+			},
+		}
+		if err := s.ForwardPackets(nil, packet); err != nil {
+			t.Fatalf("unable to forward htlc: %v", err)
+		}
+
+		select {
+		case tracked := <-bobChannelLink.tracked:
+			if !tracked {
+				t.Fatalf("htlc handed to link before its add " +
+					"time was tracked")
+			}
+		case <-time.After(time.Second):
+			t.Fatal("htlc was not propagated to destination")
+		}
+
+		return packet.inKey()
+	}
+
+	isTracked := func(inKey CircuitKey) bool {
+		s.fwdEventMtx.Lock()
+		defer s.fwdEventMtx.Unlock()
+
+		_, ok := s.addTimes[inKey]
+		return ok
+	}
+
+	// An htlc refused by its outgoing link is no longer tracked.
+	inKey := forward(0)
+	err = wait.Predicate(func() bool {
+		return !isTracked(inKey)
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("refused htlc still tracked")
+	}
+
+	// An htlc accepted by its outgoing link remains tracked until it
+	// resolves.
+	bobChannelLink.err = nil
+	inKey = forward(1)
+	if !isTracked(inKey) {
+		t.Fatalf("forwarded htlc not tracked")
+	}
+}
+
 // TestLocalPaymentNoForwardingEvents tests that if we send a series of locally
 // initiated payments, then they aren't reflected in the forwarding log.
 func TestLocalPaymentNoForwardingEvents(t *testing.T) {
@@ -2826,6 +3775,29 @@ func TestHtlcNotifier(t *testing.T) {
 			},
 			iterations: 1,
 		},
+		{
+			name: "forwards blocked at forwarding link",
+			// Set a functional option which blocks new forwards at
+			// bob to force a payment error.
+			options: []serverOption{
+				serverOptionBlockForwards(false, true, false),
+			},
+			expectedEvents: func(channels *clusterChannels,
+				htlcID uint64, ts time.Time,
+				htlc *lnwire.UpdateAddHTLC,
+				hops []*hop.Payload) ([]interface{},
+				[]interface{}, []interface{}) {
+
+				return getThreeHopEvents(
+					channels, htlcID, ts, htlc, hops,
+					&LinkError{
+						msg:           &lnwire.FailChannelDisabled{},
+						FailureDetail: OutgoingFailureForwardsDisabled,
+					},
+				)
+			},
+			iterations: 1,
+		},
 	}
 
 	for _, test := range tests {
@@ -3048,12 +4020,22 @@ func getThreeHopEvents(channels *clusterChannels, htlcID uint64,
 		OutgoingAmt:      hops[1].FwdInfo.AmountToForward,
 	}
 
+	// Settles and failures only carry the amounts of the htlc.
+	aliceResolvedInfo := HtlcInfo{
+		OutgoingAmt: htlc.Amount,
+	}
+	bobResolvedInfo := HtlcInfo{
+		IncomingAmt: htlc.Amount,
+		OutgoingAmt: hops[1].FwdInfo.AmountToForward,
+	}
+
 	// If we expect the payment to fail, we add failures for alice and
 	// bob, and no events for carol because the payment never reaches her.
 	if linkError != nil {
 		aliceEvents = append(aliceEvents,
 			&ForwardingFailEvent{
 				HtlcKey:       aliceKey,
+				HtlcInfo:      aliceResolvedInfo,
 				HtlcEventType: HtlcEventTypeSend,
 				Timestamp:     ts,
 			},
@@ -3080,6 +4062,7 @@ func getThreeHopEvents(channels *clusterChannels, htlcID uint64,
 		aliceEvents,
 		&SettleEvent{
 			HtlcKey:       aliceKey,
+			HtlcInfo:      aliceResolvedInfo,
 			HtlcEventType: HtlcEventTypeSend,
 			Timestamp:     ts,
 		},
@@ -3094,6 +4077,7 @@ func getThreeHopEvents(channels *clusterChannels, htlcID uint64,
 		},
 		&SettleEvent{
 			HtlcKey:       bobKey,
+			HtlcInfo:      bobResolvedInfo,
 			HtlcEventType: HtlcEventTypeForward,
 			Timestamp:     ts,
 		},
@@ -3108,6 +4092,10 @@ func getThreeHopEvents(channels *clusterChannels, htlcID uint64,
 				},
 				OutgoingCircuit: zeroCircuit,
 			},
+			HtlcInfo: HtlcInfo{
+				IncomingTimeLock: hops[1].FwdInfo.OutgoingCTLV,
+				IncomingAmt:      hops[1].FwdInfo.AmountToForward,
+			},
 			HtlcEventType: HtlcEventTypeReceive,
 			Timestamp:     ts,
 		},
@@ -3298,3 +4286,76 @@ func TestSwitchHoldForward(t *testing.T) {
 	assertOutgoingLinkReceive(t, aliceChannelLink, true)
 	assertNumCircuits(t, s, 0, 0)
 }
+
+// TestSwitchCompactCircuits asserts that the switch only removes the circuits
+// whose incoming and outgoing channels have both been closed.
+func TestSwitchCompactCircuits(t *testing.T) {
+	t.Parallel()
+
+	s, err := initSwitchWithDB(testStartingHeight, nil)
+	if err != nil {
+		t.Fatalf("unable to init switch: %v", err)
+	}
+	if err := s.Start(); err != nil {
+		t.Fatalf("unable to start switch: %v", err)
+	}
+	defer s.Stop()
+
+	var (
+		openChan    = lnwire.NewShortChanIDFromInt(1)
+		closedChan1 = lnwire.NewShortChanIDFromInt(2)
+		closedChan2 = lnwire.NewShortChanIDFromInt(3)
+	)
+
+	// The first circuit was never forwarded, the second one was forwarded
+	// to a closed channel and the third one to an open channel.
+	unforwarded := &PaymentCircuit{
+		Incoming: CircuitKey{ChanID: closedChan1, HtlcID: 0},
+	}
+	forwardedClosed := &PaymentCircuit{
+		Incoming: CircuitKey{ChanID: closedChan1, HtlcID: 1},
+	}
+	forwardedOpen := &PaymentCircuit{
+		Incoming: CircuitKey{ChanID: closedChan1, HtlcID: 2},
+	}
+	_, err = s.commitCircuits(unforwarded, forwardedClosed, forwardedOpen)
+	if err != nil {
+		t.Fatalf("unable to commit circuits: %v", err)
+	}
+
+	err = s.openCircuits(
+		Keystone{
+			InKey:  forwardedClosed.Incoming,
+			OutKey: CircuitKey{ChanID: closedChan2, HtlcID: 0},
+		},
+		Keystone{
+			InKey:  forwardedOpen.Incoming,
+			OutKey: CircuitKey{ChanID: openChan, HtlcID: 0},
+		},
+	)
+	if err != nil {
+		t.Fatalf("unable to open circuits: %v", err)
+	}
+
+	closedChans := map[lnwire.ShortChannelID]struct{}{
+		closedChan1: {},
+		closedChan2: {},
+	}
+	removed, err := s.CompactCircuits(closedChans)
+	if err != nil {
+		t.Fatalf("unable to compact circuits: %v", err)
+	}
+	if removed != 2 {
+		t.Fatalf("expected 2 removed circuits, got %d", removed)
+	}
+
+	if s.circuits.LookupCircuit(unforwarded.Incoming) != nil {
+		t.Fatalf("unforwarded circuit should have been removed")
+	}
+	if s.circuits.LookupCircuit(forwardedClosed.Incoming) != nil {
+		t.Fatalf("circuit to closed channel should have been removed")
+	}
+	if s.circuits.LookupCircuit(forwardedOpen.Incoming) == nil {
+		t.Fatalf("circuit to open channel should remain")
+	}
+}
@@ -1098,6 +1098,16 @@ func serverOptionRejectHtlc(alice, bob, carol bool) serverOption {
 	}
 }
 
+// serverOptionBlockForwards is the functional option for blocking new
+// forwards in each server's switch.
+func serverOptionBlockForwards(alice, bob, carol bool) serverOption {
+	return func(aliceServer, bobServer, carolServer *mockServer) {
+		aliceServer.htlcSwitch.SetForwardsBlocked(alice)
+		bobServer.htlcSwitch.SetForwardsBlocked(bob)
+		carolServer.htlcSwitch.SetForwardsBlocked(carol)
+	}
+}
+
 // createTwoClusterChannels creates lightning channels which are needed for
 // a 2 hop network cluster to be initialized.
 func createTwoClusterChannels(aliceToBob, bobToCarol dcrutil.Amount) (
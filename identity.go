@@ -0,0 +1,62 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/channeldb"
+)
+
+// checkIdentityKeySwitch makes sure the identity key the node is about to use
+// doesn't differ from the one it used so far while it still has channels. The
+// channels were opened with the previous identity, so peers would no longer
+// recognize us and wouldn't be able to reestablish them. The previous identity
+// key is the one of the source node of the graph, if any.
+func checkIdentityKeySwitch(idKey *secp256k1.PublicKey,
+	sourceNode func() (*channeldb.LightningNode, error),
+	numChannels func() (int, error)) error {
+
+	node, err := sourceNode()
+	switch {
+	// A node without a source node never used an identity key before.
+	case err == channeldb.ErrSourceNodeNotSet:
+		return nil
+
+	case err != nil:
+		return fmt.Errorf("unable to fetch source node: %v", err)
+	}
+
+	if bytes.Equal(node.PubKeyBytes[:], idKey.SerializeCompressed()) {
+		return nil
+	}
+
+	n, err := numChannels()
+	if err != nil {
+		return fmt.Errorf("unable to fetch channels: %v", err)
+	}
+	if n > 0 {
+		return fmt.Errorf("refusing to switch the identity key from "+
+			"%x to %x with %d channels that were opened with the "+
+			"previous key, close them first", node.PubKeyBytes[:],
+			idKey.SerializeCompressed(), n)
+	}
+
+	return nil
+}
+
+// numUnresolvedChannels returns the number of channels of the database that
+// were not fully resolved yet: open, pending and waiting close channels as well
+// as closed channels whose outputs are still being swept.
+func numUnresolvedChannels(chanDB *channeldb.DB) (int, error) {
+	channels, err := chanDB.FetchAllChannels()
+	if err != nil {
+		return 0, err
+	}
+	pendingClose, err := chanDB.FetchClosedChannels(true)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(channels) + len(pendingClose), nil
+}
@@ -0,0 +1,86 @@
+package dcrlnd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/channeldb"
+)
+
+// TestCheckIdentityKeySwitch asserts that the identity key of a node can only
+// change while it has no channels.
+func TestCheckIdentityKeySwitch(t *testing.T) {
+	t.Parallel()
+
+	newKey := func() *secp256k1.PublicKey {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		return privKey.PubKey()
+	}
+	oldKey := newKey()
+	otherKey := newKey()
+
+	var sourceNode channeldb.LightningNode
+	copy(sourceNode.PubKeyBytes[:], oldKey.SerializeCompressed())
+
+	errDB := errors.New("db error")
+
+	tests := []struct {
+		name          string
+		idKey         *secp256k1.PublicKey
+		sourceNodeErr error
+		numChannels   int
+		shouldFail    bool
+	}{{
+		name:          "new node",
+		idKey:         otherKey,
+		sourceNodeErr: channeldb.ErrSourceNodeNotSet,
+		numChannels:   1,
+	}, {
+		name:        "same key with channels",
+		idKey:       oldKey,
+		numChannels: 1,
+	}, {
+		name:  "switch without channels",
+		idKey: otherKey,
+	}, {
+		name:        "switch with channels",
+		idKey:       otherKey,
+		numChannels: 1,
+		shouldFail:  true,
+	}, {
+		name:          "source node error",
+		idKey:         oldKey,
+		sourceNodeErr: errDB,
+		shouldFail:    true,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			err := checkIdentityKeySwitch(
+				test.idKey,
+				func() (*channeldb.LightningNode, error) {
+					if test.sourceNodeErr != nil {
+						return nil, test.sourceNodeErr
+					}
+					return &sourceNode, nil
+				},
+				func() (int, error) {
+					return test.numChannels, nil
+				},
+			)
+			switch {
+			case test.shouldFail && err == nil:
+				t.Fatalf("expected identity key switch to fail")
+
+			case !test.shouldFail && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+			}
+		})
+	}
+}
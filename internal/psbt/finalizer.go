@@ -0,0 +1,74 @@
+package psbt
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// MaybeFinalize returns true if the input at the given index has its final
+// signature script attached. Signing is done by the external wallet that
+// fills in the signature script, so there's no partial signature data to
+// combine.
+func MaybeFinalize(p *Packet, inIndex int) (bool, error) {
+	if inIndex < 0 || inIndex >= len(p.Inputs) {
+		return false, fmt.Errorf("input index %d out of range",
+			inIndex)
+	}
+
+	if len(p.Inputs[inIndex].FinalScriptSig) == 0 {
+		return false, ErrNotFinalizable
+	}
+
+	return true, nil
+}
+
+// MaybeFinalizeAll makes sure all inputs of the packet are final.
+func MaybeFinalizeAll(p *Packet) error {
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) {
+		return ErrInvalidPsbtFormat
+	}
+
+	for i := range p.Inputs {
+		if _, err := MaybeFinalize(p, i); err != nil {
+			return fmt.Errorf("input %d: %v", i, err)
+		}
+	}
+
+	return nil
+}
+
+// Extract returns the signed transaction of a packet whose inputs are all
+// final. Input values that aren't set on the unsigned transaction are filled in
+// from the UTXO information of the packet where available.
+func Extract(p *Packet) (*wire.MsgTx, error) {
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) {
+		return nil, ErrInvalidPsbtFormat
+	}
+
+	finalTx := p.UnsignedTx.Copy()
+	for i, in := range p.Inputs {
+		if len(in.FinalScriptSig) == 0 {
+			return nil, ErrIncompletePSBT
+		}
+
+		txIn := finalTx.TxIn[i]
+		txIn.SignatureScript = in.FinalScriptSig
+
+		switch {
+		case txIn.ValueIn != wire.NullValueIn:
+
+		case in.WitnessUtxo != nil:
+			txIn.ValueIn = in.WitnessUtxo.Value
+
+		case in.NonWitnessUtxo != nil:
+			prevIndex := txIn.PreviousOutPoint.Index
+			if int(prevIndex) < len(in.NonWitnessUtxo.TxOut) {
+				prevOut := in.NonWitnessUtxo.TxOut[prevIndex]
+				txIn.ValueIn = prevOut.Value
+			}
+		}
+	}
+
+	return finalTx, nil
+}
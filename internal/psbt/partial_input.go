@@ -0,0 +1,170 @@
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// Input key types.
+const (
+	// NonWitnessUtxoType is the key type of the full transaction that
+	// contains the output spent by an input.
+	NonWitnessUtxoType = 0x00
+
+	// WitnessUtxoType is the key type of the output spent by an input.
+	WitnessUtxoType = 0x01
+
+	// FinalScriptSigType is the key type of the final signature script of
+	// an input.
+	FinalScriptSigType = 0x07
+)
+
+// PInput holds the signing data of a single input of a packet.
+type PInput struct {
+	// NonWitnessUtxo is the full transaction that contains the output
+	// spent by the input.
+	NonWitnessUtxo *wire.MsgTx
+
+	// WitnessUtxo is the output spent by the input. Decred has no
+	// segregated witness, the name is kept to match upstream.
+	WitnessUtxo *wire.TxOut
+
+	// FinalScriptSig is the final signature script of the input.
+	FinalScriptSig []byte
+
+	// Unknowns are the unknown key-value pairs of the input map.
+	Unknowns []*Unknown
+}
+
+// deserialize reads the input map from r.
+func (pi *PInput) deserialize(r io.Reader) error {
+	seen := make(map[byte]bool)
+	for {
+		keyType, keyData, value, err := readKeyValue(r)
+		if err != nil {
+			return err
+		}
+		if keyData == nil {
+			break
+		}
+
+		switch keyType {
+		case NonWitnessUtxoType, WitnessUtxoType, FinalScriptSigType:
+			if len(keyData) != 0 {
+				return ErrInvalidPsbtFormat
+			}
+			if seen[keyType] {
+				return ErrDuplicateKey
+			}
+			seen[keyType] = true
+
+		default:
+			unknown, err := addUnknown(
+				pi.Unknowns, keyType, keyData, value,
+			)
+			if err != nil {
+				return err
+			}
+			pi.Unknowns = append(pi.Unknowns, unknown)
+			continue
+		}
+
+		switch keyType {
+		case NonWitnessUtxoType:
+			tx := wire.NewMsgTx()
+			err := tx.Deserialize(bytes.NewReader(value))
+			if err != nil {
+				return err
+			}
+			pi.NonWitnessUtxo = tx
+
+		case WitnessUtxoType:
+			txOut, err := readTxOut(value)
+			if err != nil {
+				return err
+			}
+			pi.WitnessUtxo = txOut
+
+		case FinalScriptSigType:
+			pi.FinalScriptSig = value
+		}
+	}
+
+	return nil
+}
+
+// serialize writes the input map to w.
+func (pi *PInput) serialize(w io.Writer) error {
+	if pi.NonWitnessUtxo != nil {
+		var tx bytes.Buffer
+		if err := pi.NonWitnessUtxo.Serialize(&tx); err != nil {
+			return err
+		}
+		err := writeKeyValue(w, NonWitnessUtxoType, nil, tx.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+
+	if pi.WitnessUtxo != nil {
+		err := writeKeyValue(
+			w, WitnessUtxoType, nil, serializeTxOut(pi.WitnessUtxo),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if pi.FinalScriptSig != nil {
+		err := writeKeyValue(
+			w, FinalScriptSigType, nil, pi.FinalScriptSig,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeUnknowns(w, pi.Unknowns)
+}
+
+// serializeTxOut encodes an output as its value, script version and script.
+func serializeTxOut(txOut *wire.TxOut) []byte {
+	var b bytes.Buffer
+	var scratch [8]byte
+	binary.LittleEndian.PutUint64(scratch[:], uint64(txOut.Value))
+	b.Write(scratch[:])
+	binary.LittleEndian.PutUint16(scratch[:2], txOut.Version)
+	b.Write(scratch[:2])
+
+	// Writing to a bytes.Buffer never fails.
+	_ = wire.WriteVarBytes(&b, pver, txOut.PkScript)
+
+	return b.Bytes()
+}
+
+// readTxOut decodes an output encoded by serializeTxOut.
+func readTxOut(value []byte) (*wire.TxOut, error) {
+	if len(value) < 10 {
+		return nil, ErrInvalidPsbtFormat
+	}
+
+	r := bytes.NewReader(value[10:])
+	pkScript, err := wire.ReadVarBytes(
+		r, pver, MaxPsbtValueLength, "pkScript",
+	)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 {
+		return nil, ErrInvalidPsbtFormat
+	}
+
+	return &wire.TxOut{
+		Value:    int64(binary.LittleEndian.Uint64(value[:8])),
+		Version:  binary.LittleEndian.Uint16(value[8:10]),
+		PkScript: pkScript,
+	}, nil
+}
@@ -0,0 +1,28 @@
+package psbt
+
+import (
+	"io"
+)
+
+// POutput holds the data of a single output of a packet. No output key types
+// are currently defined, so only unknown key-value pairs are kept.
+type POutput struct {
+	// Unknowns are the unknown key-value pairs of the output map.
+	Unknowns []*Unknown
+}
+
+// deserialize reads the output map from r.
+func (po *POutput) deserialize(r io.Reader) error {
+	unknowns, err := readUnknowns(r)
+	if err != nil {
+		return err
+	}
+	po.Unknowns = unknowns
+
+	return nil
+}
+
+// serialize writes the output map to w.
+func (po *POutput) serialize(w io.Writer) error {
+	return writeUnknowns(w, po.Unknowns)
+}
@@ -0,0 +1,320 @@
+// Package psbt implements partially signed Decred transactions.
+//
+// The package mirrors the API of upstream's btcutil/psbt package to ease
+// porting upstream changes. Packets follow the key-value map layout of BIP 0174
+// but transactions and outputs are encoded using the Decred wire format.
+// Decred has no segregated witness, so inputs are considered final once their
+// signature script is known.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"io"
+
+	"github.com/decred/dcrd/wire"
+)
+
+// psbtMagic is the separator that starts every serialized packet.
+var psbtMagic = [5]byte{0x70, 0x73, 0x62, 0x74, 0xff}
+
+const (
+	// MaxPsbtValueLength is the maximum length of a value in a packet.
+	MaxPsbtValueLength = 4000000
+
+	// MaxPsbtKeyLength is the maximum length of a key in a packet.
+	MaxPsbtKeyLength = 10000
+
+	// pver is the protocol version used to encode the wire structures of
+	// a packet.
+	pver = 0
+)
+
+// Global key types.
+const (
+	// UnsignedTxType is the key type of the unsigned transaction in the
+	// global map.
+	UnsignedTxType = 0x00
+)
+
+var (
+	// ErrInvalidMagicBytes is returned when the packet doesn't start with
+	// the expected magic bytes.
+	ErrInvalidMagicBytes = errors.New("invalid magic bytes")
+
+	// ErrInvalidPsbtFormat is returned when a packet is malformed.
+	ErrInvalidPsbtFormat = errors.New("invalid PSBT serialization format")
+
+	// ErrDuplicateKey is returned when a key is repeated within a map of
+	// the packet.
+	ErrDuplicateKey = errors.New("invalid psbt due to duplicate key")
+
+	// ErrInvalidRawTxSigned is returned when the unsigned transaction of
+	// a packet already has signature scripts.
+	ErrInvalidRawTxSigned = errors.New("invalid raw tx, contains " +
+		"signature scripts")
+
+	// ErrNotFinalizable is returned when an input has no final signature
+	// script attached.
+	ErrNotFinalizable = errors.New("PSBT input cannot be finalized")
+
+	// ErrIncompletePSBT is returned when a transaction is extracted from
+	// a packet that isn't fully signed.
+	ErrIncompletePSBT = errors.New("PSBT cannot be extracted as it is " +
+		"incomplete")
+)
+
+// Unknown is a key-value pair of a type that isn't understood by this
+// package. It is kept so that a packet can be round-tripped without loss.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// Packet is a partially signed Decred transaction.
+type Packet struct {
+	// UnsignedTx is the transaction being signed. Its signature scripts
+	// are always empty.
+	UnsignedTx *wire.MsgTx
+
+	// Inputs holds the signing data of each input of UnsignedTx.
+	Inputs []PInput
+
+	// Outputs holds the data of each output of UnsignedTx.
+	Outputs []POutput
+
+	// Unknowns are the unknown key-value pairs of the global map.
+	Unknowns []*Unknown
+}
+
+// New creates a new packet spending the given outpoints to the given outputs.
+// The version, lock time and sequences are set on the unsigned transaction.
+func New(inputs []*wire.OutPoint,
+	outputs []*wire.TxOut, version int32, nLockTime uint32,
+	nSequences []uint32) (*Packet, error) {
+
+	if len(nSequences) != len(inputs) {
+		return nil, errors.New("number of sequences must match " +
+			"number of inputs")
+	}
+
+	unsignedTx := wire.NewMsgTx()
+	unsignedTx.Version = uint16(version)
+	unsignedTx.LockTime = nLockTime
+	for i, in := range inputs {
+		txIn := wire.NewTxIn(in, wire.NullValueIn, nil)
+		txIn.Sequence = nSequences[i]
+		unsignedTx.AddTxIn(txIn)
+	}
+	for _, out := range outputs {
+		unsignedTx.AddTxOut(out)
+	}
+
+	return NewFromUnsignedTx(unsignedTx)
+}
+
+// NewFromUnsignedTx creates a new packet for the given transaction, which
+// must not have any signature scripts.
+func NewFromUnsignedTx(tx *wire.MsgTx) (*Packet, error) {
+	for _, txIn := range tx.TxIn {
+		if len(txIn.SignatureScript) > 0 {
+			return nil, ErrInvalidRawTxSigned
+		}
+	}
+
+	return &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]PInput, len(tx.TxIn)),
+		Outputs:    make([]POutput, len(tx.TxOut)),
+	}, nil
+}
+
+// NewFromRawBytes parses a serialized packet, which may be base64 encoded.
+func NewFromRawBytes(r io.Reader, b64 bool) (*Packet, error) {
+	if b64 {
+		r = base64.NewDecoder(base64.StdEncoding, r)
+	}
+
+	var magic [5]byte
+	if _, err := io.ReadFull(r, magic[:]); err != nil {
+		return nil, err
+	}
+	if magic != psbtMagic {
+		return nil, ErrInvalidMagicBytes
+	}
+
+	// The global map must start with the unsigned transaction.
+	keyType, keyData, value, err := readKeyValue(r)
+	if err != nil {
+		return nil, err
+	}
+	if keyData == nil || keyType != UnsignedTxType || len(keyData) != 0 {
+		return nil, ErrInvalidPsbtFormat
+	}
+	unsignedTx := wire.NewMsgTx()
+	if err := unsignedTx.Deserialize(bytes.NewReader(value)); err != nil {
+		return nil, err
+	}
+	packet, err := NewFromUnsignedTx(unsignedTx)
+	if err != nil {
+		return nil, err
+	}
+
+	packet.Unknowns, err = readUnknowns(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range packet.Inputs {
+		if err := packet.Inputs[i].deserialize(r); err != nil {
+			return nil, err
+		}
+	}
+	for i := range packet.Outputs {
+		if err := packet.Outputs[i].deserialize(r); err != nil {
+			return nil, err
+		}
+	}
+
+	return packet, nil
+}
+
+// Serialize writes the binary serialization of the packet to w.
+func (p *Packet) Serialize(w io.Writer) error {
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) ||
+		len(p.Outputs) != len(p.UnsignedTx.TxOut) {
+
+		return ErrInvalidPsbtFormat
+	}
+
+	if _, err := w.Write(psbtMagic[:]); err != nil {
+		return err
+	}
+
+	var tx bytes.Buffer
+	if err := p.UnsignedTx.Serialize(&tx); err != nil {
+		return err
+	}
+	err := writeKeyValue(w, UnsignedTxType, nil, tx.Bytes())
+	if err != nil {
+		return err
+	}
+	if err := writeUnknowns(w, p.Unknowns); err != nil {
+		return err
+	}
+
+	for _, in := range p.Inputs {
+		if err := in.serialize(w); err != nil {
+			return err
+		}
+	}
+	for _, out := range p.Outputs {
+		if err := out.serialize(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// B64Encode returns the base64 encoding of the serialized packet.
+func (p *Packet) B64Encode() (string, error) {
+	var b bytes.Buffer
+	if err := p.Serialize(&b); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(b.Bytes()), nil
+}
+
+// readKeyValue reads a single key-value pair of a map. A nil keyData and
+// value are returned when the separator that ends the map is read.
+func readKeyValue(r io.Reader) (byte, []byte, []byte, error) {
+	key, err := wire.ReadVarBytes(r, pver, MaxPsbtKeyLength, "psbt key")
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if len(key) == 0 {
+		return 0, nil, nil, nil
+	}
+
+	value, err := wire.ReadVarBytes(
+		r, pver, MaxPsbtValueLength, "psbt value",
+	)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+
+	return key[0], key[1:], value, nil
+}
+
+// writeKeyValue writes a single key-value pair of a map.
+func writeKeyValue(w io.Writer, keyType byte, keyData, value []byte) error {
+	key := append([]byte{keyType}, keyData...)
+	if err := wire.WriteVarBytes(w, pver, key); err != nil {
+		return err
+	}
+
+	return wire.WriteVarBytes(w, pver, value)
+}
+
+// writeSeparator writes the separator that ends a map.
+func writeSeparator(w io.Writer) error {
+	_, err := w.Write([]byte{0x00})
+	return err
+}
+
+// readUnknowns reads the remaining key-value pairs of a map up to and
+// including its separator.
+func readUnknowns(r io.Reader) ([]*Unknown, error) {
+	var unknowns []*Unknown
+	for {
+		keyType, keyData, value, err := readKeyValue(r)
+		if err != nil {
+			return nil, err
+		}
+		if keyData == nil {
+			return unknowns, nil
+		}
+
+		unknown, err := addUnknown(unknowns, keyType, keyData, value)
+		if err != nil {
+			return nil, err
+		}
+		unknowns = append(unknowns, unknown)
+	}
+}
+
+// addUnknown returns an unknown key-value pair for the given key and value,
+// or an error if the key is already part of unknowns.
+func addUnknown(unknowns []*Unknown, keyType byte, keyData,
+	value []byte) (*Unknown, error) {
+
+	key := append([]byte{keyType}, keyData...)
+	for _, unknown := range unknowns {
+		if bytes.Equal(unknown.Key, key) {
+			return nil, ErrDuplicateKey
+		}
+	}
+
+	return &Unknown{Key: key, Value: value}, nil
+}
+
+// writeUnknowns writes the given unknown key-value pairs followed by the
+// separator that ends the map.
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+	for _, unknown := range unknowns {
+		if len(unknown.Key) == 0 {
+			return ErrInvalidPsbtFormat
+		}
+		err := writeKeyValue(
+			w, unknown.Key[0], unknown.Key[1:], unknown.Value,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return writeSeparator(w)
+}
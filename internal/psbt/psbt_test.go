@@ -0,0 +1,184 @@
+package psbt
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+)
+
+// testPacket returns a packet spending two inputs to a single output.
+func testPacket(t *testing.T) *Packet {
+	t.Helper()
+
+	packet, err := New(
+		[]*wire.OutPoint{
+			{Hash: chainhash.Hash{1}, Index: 1},
+			{Hash: chainhash.Hash{2}, Index: 0},
+		},
+		[]*wire.TxOut{{Value: 1000, PkScript: []byte{1, 2, 3}}},
+		int32(wire.TxVersion), 0, []uint32{0, wire.MaxTxInSequenceNum},
+	)
+	if err != nil {
+		t.Fatalf("unable to create packet: %v", err)
+	}
+
+	prevTx := wire.NewMsgTx()
+	prevTx.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, 3000, []byte{9}))
+	prevTx.AddTxOut(wire.NewTxOut(1500, []byte{4, 5}))
+	packet.Inputs[0].WitnessUtxo = &wire.TxOut{
+		Value:    700,
+		Version:  0,
+		PkScript: []byte{6, 7},
+	}
+	packet.Inputs[1].NonWitnessUtxo = prevTx
+	packet.Outputs[0].Unknowns = []*Unknown{{
+		Key:   []byte{0x42, 1},
+		Value: []byte{2},
+	}}
+
+	return packet
+}
+
+// TestPacketSerialization asserts that packets survive a serialization round
+// trip in both the binary and base64 encoding.
+func TestPacketSerialization(t *testing.T) {
+	t.Parallel()
+
+	packet := testPacket(t)
+
+	var b bytes.Buffer
+	if err := packet.Serialize(&b); err != nil {
+		t.Fatalf("unable to serialize packet: %v", err)
+	}
+	parsed, err := NewFromRawBytes(bytes.NewReader(b.Bytes()), false)
+	if err != nil {
+		t.Fatalf("unable to parse packet: %v", err)
+	}
+	if !reflect.DeepEqual(packet.Inputs[0], parsed.Inputs[0]) ||
+		!reflect.DeepEqual(packet.Outputs, parsed.Outputs) ||
+		packet.UnsignedTx.TxHash() != parsed.UnsignedTx.TxHash() ||
+		parsed.Inputs[1].NonWitnessUtxo.TxHash() !=
+			packet.Inputs[1].NonWitnessUtxo.TxHash() {
+
+		t.Fatalf("packet changed after round trip: got %v, want %v",
+			parsed, packet)
+	}
+
+	encoded, err := packet.B64Encode()
+	if err != nil {
+		t.Fatalf("unable to encode packet: %v", err)
+	}
+	parsed, err = NewFromRawBytes(strings.NewReader(encoded), true)
+	if err != nil {
+		t.Fatalf("unable to parse base64 packet: %v", err)
+	}
+	var b2 bytes.Buffer
+	if err := parsed.Serialize(&b2); err != nil {
+		t.Fatalf("unable to serialize packet: %v", err)
+	}
+	if !bytes.Equal(b.Bytes(), b2.Bytes()) {
+		t.Fatalf("packet changed after base64 round trip")
+	}
+
+	// Corrupting the magic bytes must be detected.
+	raw := b.Bytes()
+	raw[0] ^= 0xff
+	_, err = NewFromRawBytes(bytes.NewReader(raw), false)
+	if err != ErrInvalidMagicBytes {
+		t.Fatalf("expected invalid magic bytes, got %v", err)
+	}
+
+	// Packets with signed inputs can't be created.
+	tx := packet.UnsignedTx.Copy()
+	tx.TxIn[0].SignatureScript = []byte{1}
+	if _, err := NewFromUnsignedTx(tx); err != ErrInvalidRawTxSigned {
+		t.Fatalf("expected signed tx to be rejected, got %v", err)
+	}
+}
+
+// TestPacketDuplicateKey asserts that packets repeating a key in an input map
+// are rejected.
+func TestPacketDuplicateKey(t *testing.T) {
+	t.Parallel()
+
+	packet := testPacket(t)
+	packet.UnsignedTx.TxIn = packet.UnsignedTx.TxIn[:1]
+	packet.Inputs = packet.Inputs[:1]
+
+	var b bytes.Buffer
+	if err := packet.Serialize(&b); err != nil {
+		t.Fatalf("unable to serialize packet: %v", err)
+	}
+
+	// Strip the separator of the input map, then repeat its script sig
+	// key before ending the map again.
+	var input bytes.Buffer
+	if err := packet.Inputs[0].serialize(&input); err != nil {
+		t.Fatalf("unable to serialize input: %v", err)
+	}
+	raw := b.Bytes()
+	inputStart := bytes.Index(raw, input.Bytes())
+	if inputStart < 0 {
+		t.Fatalf("input not found in packet")
+	}
+	inputEnd := inputStart + input.Len() - 1
+
+	var dup bytes.Buffer
+	dup.Write(raw[:inputEnd])
+	for i := 0; i < 2; i++ {
+		err := writeKeyValue(&dup, FinalScriptSigType, nil, []byte{1})
+		if err != nil {
+			t.Fatalf("unable to write key: %v", err)
+		}
+	}
+	dup.Write(raw[inputEnd:])
+
+	_, err := NewFromRawBytes(&dup, false)
+	if err != ErrDuplicateKey {
+		t.Fatalf("expected duplicate key error, got %v", err)
+	}
+}
+
+// TestExtract asserts that a transaction can only be extracted once all inputs
+// are final and that the input values are filled in from the UTXO data.
+func TestExtract(t *testing.T) {
+	t.Parallel()
+
+	packet := testPacket(t)
+	packet.Inputs[0].FinalScriptSig = []byte{1, 1}
+
+	if err := MaybeFinalizeAll(packet); err == nil {
+		t.Fatalf("expected packet with unsigned input to not finalize")
+	}
+	if _, err := Extract(packet); err != ErrIncompletePSBT {
+		t.Fatalf("expected incomplete packet, got %v", err)
+	}
+
+	packet.Inputs[1].FinalScriptSig = []byte{2, 2}
+	if err := MaybeFinalizeAll(packet); err != nil {
+		t.Fatalf("unable to finalize packet: %v", err)
+	}
+	tx, err := Extract(packet)
+	if err != nil {
+		t.Fatalf("unable to extract tx: %v", err)
+	}
+
+	if !bytes.Equal(tx.TxIn[0].SignatureScript, []byte{1, 1}) ||
+		!bytes.Equal(tx.TxIn[1].SignatureScript, []byte{2, 2}) {
+
+		t.Fatalf("unexpected signature scripts")
+	}
+	if tx.TxIn[0].ValueIn != 700 || tx.TxIn[1].ValueIn != 1500 {
+		t.Fatalf("unexpected input values %d and %d",
+			tx.TxIn[0].ValueIn, tx.TxIn[1].ValueIn)
+	}
+
+	// The unsigned transaction of the packet must not be modified.
+	if len(packet.UnsignedTx.TxIn[0].SignatureScript) != 0 {
+		t.Fatalf("unsigned tx was modified")
+	}
+}
@@ -0,0 +1,99 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/decred/dcrlnd/lncfg"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/invoicesrpc"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+// macaroonFromContext returns the macaroon used to authenticate the request of
+// the passed context, or nil if the request was made without a macaroon, such
+// as when macaroons are disabled.
+func macaroonFromContext(ctx context.Context) (*macaroon.Macaroon, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md["macaroon"]) != 1 {
+		return nil, nil
+	}
+
+	macBytes, err := hex.DecodeString(md["macaroon"][0])
+	if err != nil {
+		return nil, err
+	}
+	mac := &macaroon.Macaroon{}
+	if err := mac.UnmarshalBinary(macBytes); err != nil {
+		return nil, err
+	}
+
+	return mac, nil
+}
+
+// macaroonRootKeyID returns the root key ID of the macaroon used to
+// authenticate the request of the passed context.
+func macaroonRootKeyID(ctx context.Context) (string, error) {
+	mac, err := macaroonFromContext(ctx)
+	if err != nil {
+		return "", err
+	}
+	if mac == nil {
+		return "", fmt.Errorf("no macaroon in request")
+	}
+
+	// The macaroon identifier is a version byte followed by its protobuf
+	// encoding, which includes the root key ID it was baked with.
+	rawID := mac.Id()
+	if len(rawID) == 0 || rawID[0] != byte(bakery.LatestVersion) {
+		return "", fmt.Errorf("invalid macaroon version: %x", rawID)
+	}
+	decodedID := &lnrpc.MacaroonId{}
+	if err := proto.Unmarshal(rawID[1:], decodedID); err != nil {
+		return "", err
+	}
+
+	return string(decodedID.StorageId), nil
+}
+
+// routeHintsPolicy converts a configured route hints default into the policy
+// applied when adding invoices.
+func routeHintsPolicy(routeHints string) invoicesrpc.RouteHintsPolicy {
+	switch routeHints {
+	case lncfg.RouteHintsAlways:
+		return invoicesrpc.RouteHintsAlways
+
+	case lncfg.RouteHintsAuto:
+		return invoicesrpc.RouteHintsAuto
+
+	default:
+		return invoicesrpc.RouteHintsNever
+	}
+}
+
+// newInvoiceDefaults returns a function that resolves the default CLTV delta,
+// expiry and route hints policy of a standard or hold invoice from the passed
+// config, taking into account the macaroon used to create it.
+func newInvoiceDefaults(cfg *lncfg.Invoices) func(context.Context,
+	bool) invoicesrpc.Defaults {
+
+	return func(ctx context.Context, hold bool) invoicesrpc.Defaults {
+		// Requests made without a macaroon, such as when macaroons are
+		// disabled, simply use the defaults of the invoice class.
+		rootKeyID, err := macaroonRootKeyID(ctx)
+		if err != nil {
+			rootKeyID = ""
+		}
+
+		defaults := cfg.Defaults(hold, rootKeyID)
+		return invoicesrpc.Defaults{
+			CltvDelta:  defaults.CltvDelta,
+			Expiry:     defaults.Expiry,
+			RouteHints: routeHintsPolicy(defaults.RouteHints),
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -27,6 +28,22 @@ var (
 	// ErrShuttingDown is returned when an operation failed because the
 	// invoice registry is shutting down.
 	ErrShuttingDown = errors.New("invoice registry shutting down")
+
+	// ErrInvoicesBlocked is returned when an invoice is added or settled
+	// while new invoices and settlements are blocked.
+	ErrInvoicesBlocked = errors.New("new invoices and settlements are " +
+		"blocked")
+
+	// ErrNotPendingApproval is returned when approving or rejecting the
+	// settlement of an invoice that isn't pending approval.
+	ErrNotPendingApproval = errors.New("invoice isn't pending settle " +
+		"approval")
+)
+
+const (
+	// pendingApprovalsBatchSize is the number of invoices read from the
+	// database at once when listing the invoices pending approval.
+	pendingApprovalsBatchSize = 1000
 )
 
 const (
@@ -60,6 +77,22 @@ type RegistryConfig struct {
 	// KeysendHoldTime indicates for how long we want to accept and hold
 	// spontaneous keysend payments.
 	KeysendHoldTime time.Duration
+
+	// KeysendCltvDelta is the final cltv delta required for spontaneous
+	// keysend payments. If zero, FinalCltvRejectDelta is used.
+	KeysendCltvDelta int32
+
+	// SettleApprovalThreshold is the amount from which payments to
+	// standard invoices, including keysend payments, aren't settled right
+	// away. The invoice is instead held in the accepted state until the
+	// settlement is approved or rejected. Zero disables the approval of
+	// settlements.
+	SettleApprovalThreshold lnwire.MilliAtom
+
+	// Notifier is used to reject the settlement of invoices pending
+	// approval before their htlcs expire. If nil, these invoices are held
+	// until their settlement is explicitly approved or rejected.
+	Notifier chainntnfs.ChainNotifier
 }
 
 // htlcReleaseEvent describes an htlc auto-release event. It is used to release
@@ -94,6 +127,15 @@ type InvoiceRegistry struct {
 	// cfg contains the registry's configuration parameters.
 	cfg *RegistryConfig
 
+	// finalCltvRejectDelta is the currently effective final cltv reject
+	// delta. It is initialized from the config and can be updated at
+	// runtime. It must be accessed atomically.
+	finalCltvRejectDelta int32
+
+	// invoicesBlocked is non-zero if new invoices and settlements are
+	// refused. It must be accessed atomically.
+	invoicesBlocked int32
+
 	clientMtx                 sync.Mutex
 	nextClientID              uint32
 	notificationClients       map[uint32]*InvoiceSubscription
@@ -120,6 +162,12 @@ type InvoiceRegistry struct {
 
 	expiryWatcher *InvoiceExpiryWatcher
 
+	// approvalExpiries maps the hashes of the invoices whose settlement is
+	// held pending approval to the lowest expiry height of their accepted
+	// htlcs. It is only maintained when a chain notifier is configured and
+	// is guarded by the registry lock.
+	approvalExpiries map[lntypes.Hash]uint32
+
 	wg   sync.WaitGroup
 	quit chan struct{}
 }
@@ -141,12 +189,44 @@ func NewRegistry(cdb *channeldb.DB, expiryWatcher *InvoiceExpiryWatcher,
 		hodlSubscriptions:         make(map[channeldb.CircuitKey]map[chan<- interface{}]struct{}),
 		hodlReverseSubscriptions:  make(map[chan<- interface{}]map[channeldb.CircuitKey]struct{}),
 		cfg:                       cfg,
+		finalCltvRejectDelta:      cfg.FinalCltvRejectDelta,
 		htlcAutoReleaseChan:       make(chan *htlcReleaseEvent),
 		expiryWatcher:             expiryWatcher,
+		approvalExpiries:          make(map[lntypes.Hash]uint32),
 		quit:                      make(chan struct{}),
 	}
 }
 
+// FinalCltvRejectDelta returns the number of blocks before the expiry of an
+// exit hop htlc at which it is canceled back.
+func (i *InvoiceRegistry) FinalCltvRejectDelta() int32 {
+	return atomic.LoadInt32(&i.finalCltvRejectDelta)
+}
+
+// SetFinalCltvRejectDelta updates the number of blocks before the expiry of an
+// exit hop htlc at which it is canceled back. The new delta applies to htlcs
+// received after the update.
+func (i *InvoiceRegistry) SetFinalCltvRejectDelta(delta int32) {
+	atomic.StoreInt32(&i.finalCltvRejectDelta, delta)
+}
+
+// InvoicesBlocked returns true if new invoices and settlements are refused.
+func (i *InvoiceRegistry) InvoicesBlocked() bool {
+	return atomic.LoadInt32(&i.invoicesBlocked) == 1
+}
+
+// SetInvoicesBlocked sets whether new invoices and settlements are refused.
+// While blocked, no invoices can be added, htlcs paying to our invoices are
+// failed back and hodl invoices can't be settled. Replays of htlcs that were
+// already accepted or settled are unaffected.
+func (i *InvoiceRegistry) SetInvoicesBlocked(blocked bool) {
+	var value int32
+	if blocked {
+		value = 1
+	}
+	atomic.StoreInt32(&i.invoicesBlocked, value)
+}
+
 // populateExpiryWatcher fetches all active invoices and their corresponding
 // payment hashes from ChannelDB and adds them to the expiry watcher.
 func (i *InvoiceRegistry) populateExpiryWatcher() error {
@@ -185,9 +265,121 @@ func (i *InvoiceRegistry) Start() error {
 		return err
 	}
 
+	if i.cfg.Notifier != nil {
+		if err := i.startApprovalExpiryWatcher(); err != nil {
+			i.Stop()
+			return err
+		}
+	}
+
 	return nil
 }
 
+// startApprovalExpiryWatcher tracks the invoices currently pending approval
+// and starts rejecting their settlement as their htlcs are about to expire.
+func (i *InvoiceRegistry) startApprovalExpiryWatcher() error {
+	pending, err := i.PendingApprovals()
+	if err != nil {
+		return err
+	}
+
+	i.Lock()
+	for idx := range pending {
+		i.trackPendingApproval(&pending[idx])
+	}
+	i.Unlock()
+
+	blockEpochs, err := i.cfg.Notifier.RegisterBlockEpochNtfn(nil)
+	if err != nil {
+		return err
+	}
+
+	i.wg.Add(1)
+	go i.approvalExpiryLoop(blockEpochs)
+
+	return nil
+}
+
+// approvalExpiryLoop rejects the settlement of the invoices pending approval
+// whose htlcs are about to expire on each new block.
+//
+// NOTE: This MUST be run as a goroutine.
+func (i *InvoiceRegistry) approvalExpiryLoop(
+	blockEpochs *chainntnfs.BlockEpochEvent) {
+
+	defer i.wg.Done()
+	defer blockEpochs.Cancel()
+
+	for {
+		select {
+		case epoch, ok := <-blockEpochs.Epochs:
+			if !ok {
+				return
+			}
+			i.rejectExpiringApprovals(epoch.Height)
+
+		case <-i.quit:
+			return
+		}
+	}
+}
+
+// trackPendingApproval records the lowest expiry height of the accepted htlcs
+// of an invoice pending approval, so that its settlement can be rejected before
+// they expire.
+//
+// NOTE: Must be called with the registry lock held.
+func (i *InvoiceRegistry) trackPendingApproval(invoice *channeldb.Invoice) {
+	if i.cfg.Notifier == nil || !IsPendingApproval(invoice) {
+		return
+	}
+
+	var expiry uint32
+	for _, htlc := range invoice.Htlcs {
+		if htlc.State != channeldb.HtlcStateAccepted {
+			continue
+		}
+		if expiry == 0 || htlc.Expiry < expiry {
+			expiry = htlc.Expiry
+		}
+	}
+
+	i.approvalExpiries[invoice.Terms.PaymentPreimage.Hash()] = expiry
+}
+
+// rejectExpiringApprovals rejects the settlement of the invoices pending
+// approval with htlcs that would no longer be accepted at the given height.
+func (i *InvoiceRegistry) rejectExpiringApprovals(height int32) {
+	i.Lock()
+	defer i.Unlock()
+
+	delta := i.FinalCltvRejectDelta()
+	for payHash, expiry := range i.approvalExpiries {
+		if int32(expiry) >= height+delta {
+			continue
+		}
+
+		err := i.rejectSettlementLocked(payHash)
+		switch {
+		// The invoice was resolved by other means in the meantime.
+		case err == ErrNotPendingApproval:
+			delete(i.approvalExpiries, payHash)
+
+		// Keep tracking the invoice so that the rejection is retried on
+		// the next block.
+		case err != nil:
+			log.Errorf("Unable to reject settlement of invoice %v "+
+				"with htlcs expiring at height %v: %v", payHash,
+				expiry, err)
+
+		default:
+			log.Infof("Invoice%v: settlement rejected as its "+
+				"htlcs expire at height %v",
+				channeldb.InvoiceRefByHash(payHash), expiry)
+		}
+	}
+}
+
 // Stop signals the registry for a graceful shutdown.
 func (i *InvoiceRegistry) Stop() {
 	i.expiryWatcher.Stop()
@@ -321,7 +513,8 @@ func (i *InvoiceRegistry) invoiceEventLoop() {
 func (i *InvoiceRegistry) dispatchToSingleClients(event *invoiceEvent) {
 	// Dispatch to single invoice subscribers.
 	for _, client := range i.singleNotificationClients {
-		if client.invoiceRef.PayHash() != event.hash {
+		payHash := client.invoiceRef.PayHash()
+		if payHash == nil || *payHash != event.hash {
 			continue
 		}
 
@@ -469,7 +662,7 @@ func (i *InvoiceRegistry) deliverSingleBacklogEvents(
 	}
 
 	err = client.notify(&invoiceEvent{
-		hash:    client.invoiceRef.PayHash(),
+		hash:    *client.invoiceRef.PayHash(),
 		invoice: &invoice,
 	})
 	if err != nil {
@@ -490,6 +683,10 @@ func (i *InvoiceRegistry) deliverSingleBacklogEvents(
 func (i *InvoiceRegistry) AddInvoice(invoice *channeldb.Invoice,
 	paymentHash lntypes.Hash) (uint64, error) {
 
+	if i.InvoicesBlocked() {
+		return 0, ErrInvoicesBlocked
+	}
+
 	i.Lock()
 
 	ref := channeldb.InvoiceRefByHash(paymentHash)
@@ -527,6 +724,15 @@ func (i *InvoiceRegistry) LookupInvoice(rHash lntypes.Hash) (channeldb.Invoice,
 	return i.cdb.LookupInvoice(ref)
 }
 
+// LookupInvoiceByRef looks up an invoice by the given reference, which allows
+// invoices to be found by their payment address or add index in addition to
+// their payment hash.
+func (i *InvoiceRegistry) LookupInvoiceByRef(
+	ref channeldb.InvoiceRef) (channeldb.Invoice, error) {
+
+	return i.cdb.LookupInvoice(ref)
+}
+
 // startHtlcTimer starts a new timer via the invoice registry main loop that
 // cancels a single htlc on an invoice when the htlc hold duration has passed.
 func (i *InvoiceRegistry) startHtlcTimer(invoiceRef channeldb.InvoiceRef,
@@ -667,8 +873,12 @@ func (i *InvoiceRegistry) processKeySend(ctx invoiceUpdateCtx) error {
 	)
 	features := lnwire.NewFeatureVector(rawFeatures, lnwire.Features)
 
-	// Use the minimum block delta that we require for settling htlcs.
-	finalCltvDelta := i.cfg.FinalCltvRejectDelta
+	// Use the configured keysend delta, falling back to the minimum block
+	// delta that we require for settling htlcs.
+	finalCltvDelta := i.FinalCltvRejectDelta()
+	if i.cfg.KeysendCltvDelta != 0 {
+		finalCltvDelta = i.cfg.KeysendCltvDelta
+	}
 
 	// Pre-check expiry here to prevent inserting an invoice that will not
 	// be settled.
@@ -740,15 +950,19 @@ func (i *InvoiceRegistry) NotifyExitHopHtlc(rHash lntypes.Hash,
 		amtPaid:              amtPaid,
 		expiry:               expiry,
 		currentHeight:        currentHeight,
-		finalCltvRejectDelta: i.cfg.FinalCltvRejectDelta,
+		finalCltvRejectDelta: i.FinalCltvRejectDelta(),
 		customRecords:        payload.CustomRecords(),
 		mpp:                  payload.MultiPath(),
+		invoicesBlocked:      i.InvoicesBlocked(),
+
+		settleApprovalThreshold: i.cfg.SettleApprovalThreshold,
 	}
 
 	// Process keysend if present. Do this outside of the lock, because
 	// AddInvoice obtains its own lock. This is no problem, because the
-	// operation is idempotent.
-	if i.cfg.AcceptKeySend {
+	// operation is idempotent. Keysend invoices can't be added while
+	// invoices are blocked.
+	if i.cfg.AcceptKeySend && !ctx.invoicesBlocked {
 		err := i.processKeySend(ctx)
 		if err != nil {
 			ctx.log(fmt.Sprintf("keysend error: %v", err))
@@ -831,10 +1045,15 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 	switch err {
 	case channeldb.ErrInvoiceNotFound:
 		// If the invoice was not found, return a failure resolution
-		// with an invoice not found result.
+		// with an invoice not found result. While invoices are
+		// blocked, this may have been a keysend payment we didn't
+		// add an invoice for.
+		result := ResultInvoiceNotFound
+		if ctx.invoicesBlocked {
+			result = ResultInvoicesBlocked
+		}
 		return NewFailResolution(
-			ctx.circuitKey, ctx.currentHeight,
-			ResultInvoiceNotFound,
+			ctx.circuitKey, ctx.currentHeight, result,
 		), nil
 
 	case nil:
@@ -922,6 +1141,7 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 		}
 
 		i.hodlSubscribe(hodlChan, ctx.circuitKey)
+		i.trackPendingApproval(invoice)
 
 	default:
 		panic("unknown action")
@@ -939,6 +1159,10 @@ func (i *InvoiceRegistry) notifyExitHopHtlcLocked(
 
 // SettleHodlInvoice sets the preimage of a hodl invoice.
 func (i *InvoiceRegistry) SettleHodlInvoice(preimage lntypes.Preimage) error {
+	if i.InvoicesBlocked() {
+		return ErrInvoicesBlocked
+	}
+
 	i.Lock()
 	defer i.Unlock()
 
@@ -975,12 +1199,23 @@ func (i *InvoiceRegistry) SettleHodlInvoice(preimage lntypes.Preimage) error {
 	log.Debugf("Invoice%v: settled with preimage %v", invoiceRef,
 		invoice.Terms.PaymentPreimage)
 
+	i.notifySettledHtlcs(hash, invoice)
+
+	return nil
+}
+
+// notifySettledHtlcs notifies links, resolvers and clients of the settlement
+// of a previously accepted invoice.
+func (i *InvoiceRegistry) notifySettledHtlcs(hash lntypes.Hash,
+	invoice *channeldb.Invoice) {
+
 	// In the callback, we marked the invoice as settled. UpdateInvoice will
 	// have seen this and should have moved all htlcs that were accepted to
 	// the settled state. In the loop below, we go through all of these and
 	// notify links and resolvers that are waiting for resolution. Any htlcs
 	// that were already settled before, will be notified again. This isn't
 	// necessary but doesn't hurt either.
+	preimage := *invoice.Terms.PaymentPreimage
 	for key, htlc := range invoice.Htlcs {
 		if htlc.State != channeldb.HtlcStateSettled {
 			continue
@@ -993,6 +1228,115 @@ func (i *InvoiceRegistry) SettleHodlInvoice(preimage lntypes.Preimage) error {
 		i.notifyHodlSubscribers(resolution)
 	}
 	i.notifyClients(hash, invoice, invoice.State)
+}
+
+// IsPendingApproval returns whether the settlement of the invoice is held
+// pending approval. Unlike hodl invoices, these invoices were accepted while
+// their preimage is known.
+func IsPendingApproval(invoice *channeldb.Invoice) bool {
+	return invoice.State == channeldb.ContractAccepted &&
+		!invoice.HodlInvoice
+}
+
+// PendingApprovals returns the invoices whose settlement is held pending
+// approval, oldest first.
+func (i *InvoiceRegistry) PendingApprovals() ([]channeldb.Invoice, error) {
+	var pending []channeldb.Invoice
+	query := channeldb.InvoiceQuery{
+		NumMaxInvoices: pendingApprovalsBatchSize,
+		PendingOnly:    true,
+	}
+	for {
+		invoiceSlice, err := i.cdb.QueryInvoices(query)
+		if err != nil {
+			return nil, err
+		}
+		if len(invoiceSlice.Invoices) == 0 {
+			break
+		}
+
+		for _, invoice := range invoiceSlice.Invoices {
+			if IsPendingApproval(&invoice) {
+				pending = append(pending, invoice)
+			}
+		}
+
+		query.IndexOffset = invoiceSlice.LastIndexOffset
+	}
+
+	return pending, nil
+}
+
+// ApproveSettlement settles an invoice whose settlement is held pending
+// approval.
+func (i *InvoiceRegistry) ApproveSettlement(payHash lntypes.Hash) error {
+	if i.InvoicesBlocked() {
+		return ErrInvoicesBlocked
+	}
+
+	i.Lock()
+	defer i.Unlock()
+
+	updateInvoice := func(invoice *channeldb.Invoice) (
+		*channeldb.InvoiceUpdateDesc, error) {
+
+		if !IsPendingApproval(invoice) {
+			return nil, ErrNotPendingApproval
+		}
+
+		// The preimage of the invoice is already known, so we don't
+		// need to provide it to settle the invoice.
+		return &channeldb.InvoiceUpdateDesc{
+			State: &channeldb.InvoiceStateUpdateDesc{
+				NewState: channeldb.ContractSettled,
+			},
+		}, nil
+	}
+
+	invoiceRef := channeldb.InvoiceRefByHash(payHash)
+	invoice, err := i.cdb.UpdateInvoice(invoiceRef, updateInvoice)
+	if err != nil {
+		log.Errorf("ApproveSettlement of invoice %v: %v", payHash, err)
+		return err
+	}
+
+	log.Infof("Invoice%v: settlement approved", invoiceRef)
+
+	delete(i.approvalExpiries, payHash)
+
+	i.notifySettledHtlcs(payHash, invoice)
+
+	return nil
+}
+
+// RejectSettlement cancels an invoice whose settlement is held pending
+// approval, failing back its htlcs.
+func (i *InvoiceRegistry) RejectSettlement(payHash lntypes.Hash) error {
+	i.Lock()
+	defer i.Unlock()
+
+	return i.rejectSettlementLocked(payHash)
+}
+
+// rejectSettlementLocked is the internal implementation of RejectSettlement
+// that should be executed inside the registry lock.
+func (i *InvoiceRegistry) rejectSettlementLocked(payHash lntypes.Hash) error {
+	checkPending := func(invoice *channeldb.Invoice) error {
+		if !IsPendingApproval(invoice) {
+			return ErrNotPendingApproval
+		}
+		return nil
+	}
+
+	err := i.cancelInvoiceLocked(payHash, true, checkPending)
+	if err != nil {
+		return err
+	}
+
+	log.Infof("Invoice%v: settlement rejected",
+		channeldb.InvoiceRefByHash(payHash))
+
+	delete(i.approvalExpiries, payHash)
 
 	return nil
 }
@@ -1013,12 +1357,28 @@ func (i *InvoiceRegistry) cancelInvoiceImpl(payHash lntypes.Hash,
 	i.Lock()
 	defer i.Unlock()
 
+	return i.cancelInvoiceLocked(payHash, cancelAccepted, nil)
+}
+
+// cancelInvoiceLocked is the internal implementation of cancelInvoiceImpl that
+// should be executed inside the registry lock. If check is set, the invoice is
+// only canceled if check returns no error when called on the current state of
+// the invoice.
+func (i *InvoiceRegistry) cancelInvoiceLocked(payHash lntypes.Hash,
+	cancelAccepted bool, check func(*channeldb.Invoice) error) error {
+
 	ref := channeldb.InvoiceRefByHash(payHash)
 	log.Debugf("Invoice%v: canceling invoice", ref)
 
 	updateInvoice := func(invoice *channeldb.Invoice) (
 		*channeldb.InvoiceUpdateDesc, error) {
 
+		if check != nil {
+			if err := check(invoice); err != nil {
+				return nil, err
+			}
+		}
+
 		// Only cancel the invoice in ContractAccepted state if explicitly
 		// requested to do so.
 		if invoice.State == channeldb.ContractAccepted && !cancelAccepted {
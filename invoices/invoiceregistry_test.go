@@ -4,6 +4,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -29,7 +30,7 @@ func TestSettleInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -244,7 +245,7 @@ func TestCancelInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -373,7 +374,7 @@ func TestSettleHoldInvoice(t *testing.T) {
 	}
 	defer subscription.Cancel()
 
-	if subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
+	if *subscription.invoiceRef.PayHash() != testInvoicePaymentHash {
 		t.Fatalf("expected subscription for provided hash")
 	}
 
@@ -644,6 +645,366 @@ func TestUnknownInvoice(t *testing.T) {
 	}
 }
 
+// TestSetFinalCltvRejectDelta asserts that an updated final cltv reject delta
+// applies to htlcs received after the update.
+func TestSetFinalCltvRejectDelta(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	_, err := ctx.registry.AddInvoice(testInvoice, testInvoicePaymentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Raise the reject delta, so that an htlc that would otherwise be
+	// accepted now expires too soon.
+	ctx.registry.SetFinalCltvRejectDelta(testFinalCltvRejectDelta + 1)
+	if ctx.registry.FinalCltvRejectDelta() != testFinalCltvRejectDelta+1 {
+		t.Fatalf("reject delta not updated")
+	}
+
+	hodlChan := make(chan interface{}, 1)
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(0), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failResolution, ok := resolution.(*HtlcFailResolution)
+	if !ok {
+		t.Fatalf("expected fail resolution, got: %T", resolution)
+	}
+	if failResolution.Outcome != ResultExpiryTooSoon {
+		t.Fatalf("expected expiry too soon, got: %v",
+			failResolution.Outcome)
+	}
+
+	// Restoring the original delta allows the same htlc to settle the
+	// invoice.
+	ctx.registry.SetFinalCltvRejectDelta(testFinalCltvRejectDelta)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(1), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolution.(*HtlcSettleResolution); !ok {
+		t.Fatalf("expected settle resolution, got: %T", resolution)
+	}
+}
+
+// TestInvoicesBlocked asserts that no invoices can be added or settled while
+// invoices are blocked, and that replayed htlcs are unaffected.
+func TestInvoicesBlocked(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	_, err := ctx.registry.AddInvoice(testInvoice, testInvoicePaymentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.registry.SetInvoicesBlocked(true)
+	if !ctx.registry.InvoicesBlocked() {
+		t.Fatalf("invoices not blocked")
+	}
+
+	// New invoices and hodl invoice settlements are refused.
+	_, err = ctx.registry.AddInvoice(testHodlInvoice, testInvoicePaymentHash)
+	if err != ErrInvoicesBlocked {
+		t.Fatalf("expected invoices blocked error, got: %v", err)
+	}
+	err = ctx.registry.SettleHodlInvoice(testInvoicePreimage)
+	if err != ErrInvoicesBlocked {
+		t.Fatalf("expected invoices blocked error, got: %v", err)
+	}
+
+	// A htlc paying to the invoice is failed back.
+	hodlChan := make(chan interface{}, 1)
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(0), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failResolution, ok := resolution.(*HtlcFailResolution)
+	if !ok {
+		t.Fatalf("expected fail resolution, got: %T", resolution)
+	}
+	if failResolution.Outcome != ResultInvoicesBlocked {
+		t.Fatalf("expected invoices blocked, got: %v",
+			failResolution.Outcome)
+	}
+
+	// Once allowed again, a new htlc settles the invoice.
+	ctx.registry.SetInvoicesBlocked(false)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(1), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolution.(*HtlcSettleResolution); !ok {
+		t.Fatalf("expected settle resolution, got: %T", resolution)
+	}
+
+	// A replay of the settled htlc is still settled while invoices are
+	// blocked.
+	ctx.registry.SetInvoicesBlocked(true)
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, testInvoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(1), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settleResolution, ok := resolution.(*HtlcSettleResolution)
+	if !ok {
+		t.Fatalf("expected settle resolution, got: %T", resolution)
+	}
+	if settleResolution.Outcome != ResultReplayToSettled {
+		t.Fatalf("expected replay to settled, got: %v",
+			settleResolution.Outcome)
+	}
+}
+
+// TestInvoiceHintChannels asserts that an invoice restricted to the channels
+// of its route hints is only settled by htlcs arriving through them.
+func TestInvoiceHintChannels(t *testing.T) {
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	hintChan := lnwire.NewShortChanIDFromInt(100)
+	invoice := *testInvoice
+	invoice.HintChannels = []lnwire.ShortChannelID{hintChan}
+
+	_, err := ctx.registry.AddInvoice(&invoice, testInvoicePaymentHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A htlc arriving through another channel is failed back.
+	hodlChan := make(chan interface{}, 1)
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, invoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(0), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failResolution, ok := resolution.(*HtlcFailResolution)
+	if !ok {
+		t.Fatalf("expected fail resolution, got: %T", resolution)
+	}
+	if failResolution.Outcome != ResultHintChannelMismatch {
+		t.Fatalf("expected hint channel mismatch, got: %v",
+			failResolution.Outcome)
+	}
+
+	// A htlc arriving through the hint channel settles the invoice.
+	circuitKey := channeldb.CircuitKey{ChanID: hintChan, HtlcID: 1}
+	resolution, err = ctx.registry.NotifyExitHopHtlc(
+		testInvoicePaymentHash, invoice.Terms.Value, testHtlcExpiry,
+		testCurrentHeight, circuitKey, hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolution.(*HtlcSettleResolution); !ok {
+		t.Fatalf("expected settle resolution, got: %T", resolution)
+	}
+}
+
+// TestSettleApproval asserts that payments from the settle approval threshold
+// are held until their settlement is approved or rejected, while smaller
+// payments are settled right away.
+func TestSettleApproval(t *testing.T) {
+	defer timeout()()
+
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	ctx.registry.cfg.SettleApprovalThreshold = testInvoiceAmount
+
+	// Add an invoice to approve, one to reject and one below the
+	// threshold.
+	approvePreimage := lntypes.Preimage{1}
+	rejectPreimage := lntypes.Preimage{2}
+	smallPreimage := lntypes.Preimage{3}
+
+	smallInvoice := newTestInvoice(t, smallPreimage, testTime, 0)
+	smallInvoice.Terms.Value = testInvoiceAmount - 1
+	for _, invoice := range []*channeldb.Invoice{
+		newTestInvoice(t, approvePreimage, testTime, 0),
+		newTestInvoice(t, rejectPreimage, testTime, 0),
+		smallInvoice,
+	} {
+		_, err := ctx.registry.AddInvoice(
+			invoice, invoice.Terms.PaymentPreimage.Hash(),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The payments of the large invoices are held.
+	hodlChan := make(chan interface{}, 1)
+	for i, preimage := range []lntypes.Preimage{
+		approvePreimage, rejectPreimage,
+	} {
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			preimage.Hash(), testInvoiceAmount, testHtlcExpiry,
+			testCurrentHeight, getCircuitKey(uint64(i)), hodlChan,
+			testPayload,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolution != nil {
+			t.Fatalf("expected htlc to be held, got: %T",
+				resolution)
+		}
+	}
+
+	// The payment of the small invoice is settled.
+	resolution, err := ctx.registry.NotifyExitHopHtlc(
+		smallPreimage.Hash(), testInvoiceAmount-1, testHtlcExpiry,
+		testCurrentHeight, getCircuitKey(2), hodlChan, testPayload,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := resolution.(*HtlcSettleResolution); !ok {
+		t.Fatalf("expected settle resolution, got: %T", resolution)
+	}
+
+	pending, err := ctx.registry.PendingApprovals()
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	require.Equal(t, approvePreimage, *pending[0].Terms.PaymentPreimage)
+	require.Equal(t, rejectPreimage, *pending[1].Terms.PaymentPreimage)
+
+	// Approving the settlement settles the held htlc.
+	require.NoError(t, ctx.registry.ApproveSettlement(approvePreimage.Hash()))
+	settleResolution, ok := (<-hodlChan).(*HtlcSettleResolution)
+	if !ok {
+		t.Fatalf("expected settle resolution")
+	}
+	require.Equal(t, approvePreimage, settleResolution.Preimage)
+
+	invoice, err := ctx.registry.LookupInvoice(approvePreimage.Hash())
+	require.NoError(t, err)
+	require.Equal(t, channeldb.ContractSettled, invoice.State)
+
+	// Rejecting the settlement fails back the held htlc.
+	require.NoError(t, ctx.registry.RejectSettlement(rejectPreimage.Hash()))
+	failResolution, ok := (<-hodlChan).(*HtlcFailResolution)
+	if !ok {
+		t.Fatalf("expected fail resolution")
+	}
+	require.Equal(t, ResultCanceled, failResolution.Outcome)
+
+	pending, err = ctx.registry.PendingApprovals()
+	require.NoError(t, err)
+	require.Empty(t, pending)
+
+	// Invoices that aren't pending approval can't be approved or
+	// rejected.
+	for _, preimage := range []lntypes.Preimage{
+		approvePreimage, rejectPreimage, smallPreimage,
+	} {
+		err := ctx.registry.ApproveSettlement(preimage.Hash())
+		require.Equal(t, ErrNotPendingApproval, err)
+
+		err = ctx.registry.RejectSettlement(preimage.Hash())
+		require.Equal(t, ErrNotPendingApproval, err)
+	}
+}
+
+// TestSettleApprovalExpiry asserts that the settlement of invoices pending
+// approval is rejected once their htlcs are about to expire.
+func TestSettleApprovalExpiry(t *testing.T) {
+	defer timeout()()
+
+	ctx := newTestContext(t)
+	defer ctx.cleanup()
+
+	ctx.registry.cfg.SettleApprovalThreshold = testInvoiceAmount
+
+	// Hold the payments of two invoices, one with an htlc expiring later
+	// than the other.
+	earlyPreimage := lntypes.Preimage{1}
+	latePreimage := lntypes.Preimage{2}
+	lateExpiry := testHtlcExpiry + 10
+
+	hodlChan := make(chan interface{}, 1)
+	for i, htlc := range []struct {
+		preimage lntypes.Preimage
+		expiry   uint32
+	}{
+		{earlyPreimage, testHtlcExpiry},
+		{latePreimage, lateExpiry},
+	} {
+		invoice := newTestInvoice(t, htlc.preimage, testTime, 0)
+		_, err := ctx.registry.AddInvoice(invoice, htlc.preimage.Hash())
+		require.NoError(t, err)
+
+		resolution, err := ctx.registry.NotifyExitHopHtlc(
+			htlc.preimage.Hash(), testInvoiceAmount, htlc.expiry,
+			testCurrentHeight, getCircuitKey(uint64(i)), hodlChan,
+			testPayload,
+		)
+		require.NoError(t, err)
+		require.Nil(t, resolution)
+	}
+
+	// Once a block is mined at which the first htlc would no longer be
+	// accepted, its settlement is rejected.
+	ctx.notifier.epochs <- &chainntnfs.BlockEpoch{
+		Height: int32(testHtlcExpiry) - testFinalCltvRejectDelta + 1,
+	}
+	failResolution, ok := (<-hodlChan).(*HtlcFailResolution)
+	if !ok {
+		t.Fatalf("expected fail resolution")
+	}
+	require.Equal(t, getCircuitKey(0), failResolution.CircuitKey())
+	require.Equal(t, ResultCanceled, failResolution.Outcome)
+
+	invoice, err := ctx.registry.LookupInvoice(earlyPreimage.Hash())
+	require.NoError(t, err)
+	require.Equal(t, channeldb.ContractCanceled, invoice.State)
+
+	// The other invoice is still pending approval.
+	pending, err := ctx.registry.PendingApprovals()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	require.Equal(t, latePreimage, *pending[0].Terms.PaymentPreimage)
+
+	// Approving its settlement stops tracking it, so that it isn't
+	// rejected on a later block.
+	require.NoError(t, ctx.registry.ApproveSettlement(latePreimage.Hash()))
+	_, ok = (<-hodlChan).(*HtlcSettleResolution)
+	if !ok {
+		t.Fatalf("expected settle resolution")
+	}
+
+	ctx.notifier.epochs <- &chainntnfs.BlockEpoch{
+		Height: int32(lateExpiry),
+	}
+
+	ctx.registry.Lock()
+	require.Empty(t, ctx.registry.approvalExpiries)
+	ctx.registry.Unlock()
+
+	invoice, err = ctx.registry.LookupInvoice(latePreimage.Hash())
+	require.NoError(t, err)
+	require.Equal(t, channeldb.ContractSettled, invoice.State)
+}
+
 // TestKeySend tests receiving a spontaneous payment with and without keysend
 // enabled.
 func TestKeySend(t *testing.T) {
@@ -21,6 +21,10 @@ const (
 	// resultPartialAccepted is returned when we have partially received
 	// payment.
 	resultPartialAccepted
+
+	// resultPendingApproval is returned when we accept a payment to a
+	// standard invoice that needs to be approved before it is settled.
+	resultPendingApproval
 )
 
 // String returns a string representation of the result.
@@ -41,6 +45,9 @@ func (a acceptResolutionResult) String() string {
 	case resultPartialAccepted:
 		return "partial payment accepted"
 
+	case resultPendingApproval:
+		return "accepted pending settle approval"
+
 	default:
 		return "unknown accept resolution result"
 	}
@@ -105,6 +112,15 @@ const (
 	// ResultMppInProgress is returned when we are busy receiving a mpp
 	// payment.
 	ResultMppInProgress
+
+	// ResultInvoicesBlocked is returned when a htlc is received while new
+	// invoices and settlements are blocked.
+	ResultInvoicesBlocked
+
+	// ResultHintChannelMismatch is returned when a htlc pays to an invoice
+	// restricted to the channels of its route hints through another
+	// channel.
+	ResultHintChannelMismatch
 )
 
 // String returns a string representation of the result.
@@ -162,6 +178,12 @@ func (f FailResolutionResult) FailureString() string {
 	case ResultMppInProgress:
 		return "mpp reception in progress"
 
+	case ResultInvoicesBlocked:
+		return "invoices blocked"
+
+	case ResultHintChannelMismatch:
+		return "htlc not received through a route hint channel"
+
 	default:
 		return "unknown failure resolution result"
 	}
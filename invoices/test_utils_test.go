@@ -14,6 +14,7 @@ import (
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/lntypes"
@@ -134,10 +135,28 @@ func newTestChannelDB(clock clock.Clock) (*channeldb.DB, func(), error) {
 	return cdb, cleanUp, nil
 }
 
+// mockChainNotifier is a chainntnfs.ChainNotifier that delivers the block
+// epochs sent on its channel.
+type mockChainNotifier struct {
+	chainntnfs.ChainNotifier
+
+	epochs chan *chainntnfs.BlockEpoch
+}
+
+func (m *mockChainNotifier) RegisterBlockEpochNtfn(
+	*chainntnfs.BlockEpoch) (*chainntnfs.BlockEpochEvent, error) {
+
+	return &chainntnfs.BlockEpochEvent{
+		Epochs: m.epochs,
+		Cancel: func() {},
+	}, nil
+}
+
 type testContext struct {
 	cdb      *channeldb.DB
 	registry *InvoiceRegistry
 	clock    *clock.TestClock
+	notifier *mockChainNotifier
 
 	cleanup func()
 	t       *testing.T
@@ -152,12 +171,16 @@ func newTestContext(t *testing.T) *testContext {
 	}
 
 	expiryWatcher := NewInvoiceExpiryWatcher(clock)
+	notifier := &mockChainNotifier{
+		epochs: make(chan *chainntnfs.BlockEpoch),
+	}
 
 	// Instantiate and start the invoice ctx.registry.
 	cfg := RegistryConfig{
 		FinalCltvRejectDelta: testFinalCltvRejectDelta,
 		HtlcHoldDuration:     30 * time.Second,
 		Clock:                clock,
+		Notifier:             notifier,
 	}
 	registry := NewRegistry(cdb, expiryWatcher, &cfg)
 
@@ -171,6 +194,7 @@ func newTestContext(t *testing.T) *testContext {
 		cdb:      cdb,
 		registry: registry,
 		clock:    clock,
+		notifier: notifier,
 		t:        t,
 		cleanup: func() {
 			registry.Stop()
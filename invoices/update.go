@@ -20,6 +20,19 @@ type invoiceUpdateCtx struct {
 	finalCltvRejectDelta int32
 	customRecords        record.CustomSet
 	mpp                  *record.MPP
+	invoicesBlocked      bool
+
+	// settleApprovalThreshold is the amount from which payments to
+	// standard invoices are held pending approval instead of being
+	// settled. Zero disables the approval of settlements.
+	settleApprovalThreshold lnwire.MilliAtom
+}
+
+// needsApproval returns whether a payment of the given total amount to a
+// standard invoice must be approved before the invoice is settled.
+func (i *invoiceUpdateCtx) needsApproval(amt lnwire.MilliAtom) bool {
+	return i.settleApprovalThreshold != 0 &&
+		amt >= i.settleApprovalThreshold
 }
 
 // invoiceRef returns an identifier that can be used to lookup or update the
@@ -90,6 +103,18 @@ func updateInvoice(ctx *invoiceUpdateCtx, inv *channeldb.Invoice) (
 		}
 	}
 
+	// New htlcs are failed back while invoices are blocked.
+	if ctx.invoicesBlocked {
+		return nil, ctx.failRes(ResultInvoicesBlocked), nil
+	}
+
+	// Invoices restricted to the channels of their route hints are failed
+	// back like unknown invoices when paid through any other channel, so
+	// that they can't be linked to our public channels.
+	if !inv.AcceptsChannel(ctx.circuitKey.ChanID) {
+		return nil, ctx.failRes(ResultHintChannelMismatch), nil
+	}
+
 	if ctx.mpp == nil {
 		return updateLegacy(ctx, inv)
 	}
@@ -194,6 +219,15 @@ func updateMpp(ctx *invoiceUpdateCtx,
 		return &update, ctx.acceptRes(resultAccepted), nil
 	}
 
+	// Large payments are held like hodl invoices until the settlement is
+	// approved.
+	if ctx.needsApproval(newSetTotal) {
+		update.State = &channeldb.InvoiceStateUpdateDesc{
+			NewState: channeldb.ContractAccepted,
+		}
+		return &update, ctx.acceptRes(resultPendingApproval), nil
+	}
+
 	update.State = &channeldb.InvoiceStateUpdateDesc{
 		NewState: channeldb.ContractSettled,
 		Preimage: inv.Terms.PaymentPreimage,
@@ -282,6 +316,16 @@ func updateLegacy(ctx *invoiceUpdateCtx,
 		return &update, ctx.acceptRes(resultAccepted), nil
 	}
 
+	// Large payments are held like hodl invoices until the settlement is
+	// approved.
+	if ctx.needsApproval(ctx.amtPaid) {
+		update.State = &channeldb.InvoiceStateUpdateDesc{
+			NewState: channeldb.ContractAccepted,
+		}
+
+		return &update, ctx.acceptRes(resultPendingApproval), nil
+	}
+
 	update.State = &channeldb.InvoiceStateUpdateDesc{
 		NewState: channeldb.ContractSettled,
 		Preimage: inv.Terms.PaymentPreimage,
@@ -5,6 +5,7 @@ import (
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
 	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrd/hdkeychain/v3"
 )
 
 const (
@@ -202,6 +203,19 @@ type SecretKeyRing interface {
 	DerivePrivKey(keyDesc KeyDescriptor) (*secp256k1.PrivateKey, error)
 }
 
+// AccountKeyRing is a KeyRing that is also able to export the extended public
+// key it uses as the root of public derivation for each key family. Given this
+// set of keys, a watch-only party is able to reconstruct every public key the
+// ring will ever hand out, which is useful for audits and watch-only setups.
+type AccountKeyRing interface {
+	KeyRing
+
+	// AccountXPub returns the extended public key from which all keys of
+	// the given key family are publicly derived. For the standard
+	// derivation scheme, this is the key at m/1017'/coinType'/keyFamily'/0.
+	AccountXPub(keyFam KeyFamily) (*hdkeychain.ExtendedKey, error)
+}
+
 // DigestSignerRing is an interface that abstracts away basic low-level ECDSA
 // signing on keys within a key ring.
 type DigestSignerRing interface {
@@ -27,6 +27,7 @@ type HDKeyRing struct {
 // interfaces.
 var _ KeyRing = (*HDKeyRing)(nil)
 var _ SecretKeyRing = (*HDKeyRing)(nil)
+var _ AccountKeyRing = (*HDKeyRing)(nil)
 
 // NewHDKeyRing creates a new implementation of the keychain.SecretKeyRing
 // interface backed by a set of extended HD keys.
@@ -121,6 +122,21 @@ func (kr HDKeyRing) DeriveKey(keyLoc KeyLocator) (KeyDescriptor, error) {
 	}, nil
 }
 
+// AccountXPub returns the extended public key from which all keys of the given
+// key family are publicly derived.
+//
+// NOTE: This is part of the keychain.AccountKeyRing interface.
+func (kr *HDKeyRing) AccountXPub(keyFam KeyFamily) (*hdkeychain.ExtendedKey, error) {
+	masterPub := kr.masterPubs[keyFam]
+	if masterPub == nil {
+		return nil, fmt.Errorf("masterpub for keyfamily %d does not exist", keyFam)
+	}
+
+	// Neuter the key so that we never leak private material in case the
+	// ring was created with private extended keys.
+	return masterPub.Neuter(), nil
+}
+
 // DerivePrivKey attempts to derive the private key that corresponds to the
 // passed key descriptor.
 //
@@ -1,6 +1,7 @@
 package keychain
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
@@ -70,6 +71,46 @@ func TestHDSecretKeyRingImpl(t *testing.T) {
 	)
 }
 
+// TestHDKeyRingAccountXPub ensures the exported account xpubs of the
+// HDKeyRing can be used to reconstruct the keys the ring derives.
+func TestHDKeyRingAccountXPub(t *testing.T) {
+	t.Parallel()
+
+	keyRing := createTestHDKeyRing()
+
+	for _, keyFam := range versionZeroKeyFamilies {
+		xpub, err := keyRing.AccountXPub(keyFam)
+		if err != nil {
+			t.Fatalf("unable to export xpub for family %d: %v",
+				keyFam, err)
+		}
+		if xpub.IsPrivate() {
+			t.Fatalf("family %d: exported xpub is private", keyFam)
+		}
+
+		keyDesc, err := keyRing.DeriveNextKey(keyFam)
+		if err != nil {
+			t.Fatalf("unable to derive next key: %v", err)
+		}
+
+		child, err := xpub.Child(keyDesc.Index)
+		if err != nil {
+			t.Fatalf("unable to derive child of xpub: %v", err)
+		}
+		if !bytes.Equal(child.SerializedPubKey(),
+			keyDesc.PubKey.SerializeCompressed()) {
+
+			t.Fatalf("family %d: key derived from xpub does not "+
+				"match keyring key", keyFam)
+		}
+	}
+
+	// Families unknown to the keyring can't be exported.
+	if _, err := keyRing.AccountXPub(KeyFamilyLastKF + 1); err == nil {
+		t.Fatalf("expected error exporting unknown key family")
+	}
+}
+
 func init() {
 	// We'll clamp the max range scan to constrain the run time of the
 	// private key scan test.
@@ -5,7 +5,10 @@ import (
 	"crypto/tls"
 	"encoding/hex"
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 
@@ -57,7 +60,9 @@ func EnforceSafeAuthentication(addrs []net.Addr, macaroonsActive bool) error {
 	// on. If it's a localhost address, we'll skip it, otherwise, we'll
 	// return an error if macaroons are inactive.
 	for _, addr := range addrs {
-		if IsLoopback(addr.String()) || IsUnix(addr) {
+		if IsLoopback(addr.String()) || IsUnix(addr) ||
+			IsNamedPipe(addr) {
+
 			continue
 		}
 
@@ -95,6 +100,100 @@ func ListenOnAddress(addr net.Addr) (net.Listener, error) {
 	return net.Listen(parseNetwork(addr), addr.String())
 }
 
+// ListenOnUnixSocket creates a listener on the unix socket at the given address
+// and restricts access to it with the given file permissions. A stale socket
+// file left behind by a previous run is removed before listening, while a
+// socket that is still in use results in an error. The socket file is removed
+// when the listener is closed.
+func ListenOnUnixSocket(addr net.Addr, perms os.FileMode) (net.Listener,
+	error) {
+
+	path := addr.String()
+
+	// Abstract sockets don't have a file in the file system, so there is
+	// nothing to clean up or restrict.
+	if strings.HasPrefix(path, "@") {
+		return net.Listen(addr.Network(), path)
+	}
+
+	info, err := os.Lstat(path)
+	switch {
+	case os.IsNotExist(err):
+
+	case err != nil:
+		return nil, err
+
+	case info.Mode()&os.ModeSocket == 0:
+		return nil, fmt.Errorf("unable to listen on %v: file exists "+
+			"and is not a socket", path)
+
+	default:
+		conn, err := net.Dial(addr.Network(), path)
+		if err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("unable to listen on %v: "+
+				"socket in use", path)
+		}
+
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	// The socket is created in a private directory and only moved to its
+	// final path once its permissions are restricted, so that nobody can
+	// connect to it in the meantime.
+	tmpDir, err := ioutil.TempDir(filepath.Dir(path), ".sock")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tmpPath := filepath.Join(tmpDir, "s")
+	lis, err := net.Listen(addr.Network(), tmpPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// The socket file is removed from its final path on close instead.
+	unixLis := lis.(*net.UnixListener)
+	unixLis.SetUnlinkOnClose(false)
+
+	if err := os.Chmod(tmpPath, perms); err != nil {
+		unixLis.Close()
+		return nil, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		unixLis.Close()
+		return nil, err
+	}
+
+	return &unixSocketListener{
+		UnixListener: unixLis,
+		addr:         addr,
+	}, nil
+}
+
+// unixSocketListener is a listener on a unix socket which was moved to its
+// final path after being created.
+type unixSocketListener struct {
+	*net.UnixListener
+
+	addr net.Addr
+}
+
+// Addr returns the address the listener was requested to listen on.
+func (l *unixSocketListener) Addr() net.Addr {
+	return l.addr
+}
+
+// Close stops listening on the socket and removes its file.
+func (l *unixSocketListener) Close() error {
+	err := l.UnixListener.Close()
+	os.Remove(l.addr.String())
+	return err
+}
+
 // TLSListenOnAddress creates a TLS listener that listens on the given address.
 func TLSListenOnAddress(addr net.Addr,
 	config *tls.Config) (net.Listener, error) {
@@ -117,6 +216,40 @@ func IsUnix(addr net.Addr) bool {
 	return strings.HasPrefix(addr.Network(), "unix")
 }
 
+// ParseIPNets parses a list of IP addresses and CIDR ranges. A plain IP address
+// is converted to the range that only contains that address.
+func ParseIPNets(entries []string) ([]*net.IPNet, error) {
+	ipNets := make([]*net.IPNet, 0, len(entries))
+	for _, entry := range entries {
+		if strings.Contains(entry, "/") {
+			_, ipNet, err := net.ParseCIDR(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q: %v",
+					entry, err)
+			}
+			ipNets = append(ipNets, ipNet)
+			continue
+		}
+
+		ip := net.ParseIP(entry)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address %q", entry)
+		}
+
+		bits := 8 * net.IPv6len
+		if ip4 := ip.To4(); ip4 != nil {
+			ip = ip4
+			bits = 8 * net.IPv4len
+		}
+		ipNets = append(ipNets, &net.IPNet{
+			IP:   ip,
+			Mask: net.CIDRMask(bits, bits),
+		})
+	}
+
+	return ipNets, nil
+}
+
 // ParseAddressString converts an address in string format to a net.Addr that is
 // compatible with lnd. UDP is not supported because lnd needs reliable
 // connections. We accept a custom function to resolve any TCP addresses so
@@ -138,19 +271,22 @@ func ParseAddressString(strAddress string, defaultPort string,
 		parsedAddr = strings.Join(parts[1:], ":")
 	}
 
-	// Only TCP and Unix socket addresses are valid. We can't use IP or
-	// UDP only connections for anything we do in lnd.
+	// Only TCP, Unix socket and named pipe addresses are valid. We can't
+	// use IP or UDP only connections for anything we do in lnd.
 	switch parsedNetwork {
 	case "unix", "unixpacket":
 		return net.ResolveUnixAddr(parsedNetwork, parsedAddr)
 
+	case namedPipeNetwork:
+		return parsePipeAddr(parsedAddr)
+
 	case "tcp", "tcp4", "tcp6":
 		return tcpResolver(
 			parsedNetwork, verifyPort(parsedAddr, defaultPort),
 		)
 
 	case "ip", "ip4", "ip6", "udp", "udp4", "udp6", "unixgram":
-		return nil, fmt.Errorf("only TCP or unix socket "+
+		return nil, fmt.Errorf("only TCP, unix socket or named pipe "+
 			"addresses are supported: %s", parsedAddr)
 
 	default:
@@ -272,8 +408,8 @@ func verifyPort(address string, defaultPort string) string {
 	return address
 }
 
-// ClientAddressDialer creates a gRPC dialer that can also dial unix socket
-// addresses instead of just TCP addresses.
+// ClientAddressDialer creates a gRPC dialer that can also dial unix socket and
+// named pipe addresses instead of just TCP addresses.
 func ClientAddressDialer(defaultPort string) func(context.Context,
 	string) (net.Conn, error) {
 
@@ -285,6 +421,10 @@ func ClientAddressDialer(defaultPort string) func(context.Context,
 			return nil, err
 		}
 
+		if IsNamedPipe(parsedAddr) {
+			return DialNamedPipe(ctx, parsedAddr)
+		}
+
 		d := net.Dialer{}
 		return d.DialContext(
 			ctx, parsedAddr.Network(), parsedAddr.String(),
@@ -5,7 +5,10 @@ package lncfg
 import (
 	"bytes"
 	"encoding/hex"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
@@ -42,6 +45,15 @@ var (
 		{"unix:///tmp/lnd.sock", "unix", "/tmp/lnd.sock", false, true},
 		{"unix:/tmp/lnd.sock", "unix", "/tmp/lnd.sock", false, true},
 		{"123", "tcp", "127.0.0.1:123", true, false},
+		{"npipe://dcrlnd", "npipe", `\\.\pipe\dcrlnd`, false, false},
+		{"npipe:dcrlnd", "npipe", `\\.\pipe\dcrlnd`, false, false},
+		{
+			`npipe://\\.\pipe\dcrlnd`,
+			"npipe",
+			`\\.\pipe\dcrlnd`,
+			false,
+			false,
+		},
 		{
 			"4acth47i6kxnvkewtm6q7ib2s3ufpo5sqbsnzjpbi7utijcltosqemad.onion",
 			"tcp",
@@ -75,6 +87,9 @@ var (
 		"some string",
 		"://",
 		"12.12.12.12.12",
+		"npipe://",
+		"npipe://dcrlnd/rpc",
+		`npipe://\\host\pipe\dcrlnd`,
 	}
 )
 
@@ -265,3 +280,130 @@ func validateAddr(t *testing.T, addr net.Addr, test addressTest) {
 		)
 	}
 }
+
+// TestParseIPNets asserts that IP addresses and CIDR ranges are parsed into the
+// ranges they describe.
+func TestParseIPNets(t *testing.T) {
+	ipNets, err := ParseIPNets([]string{
+		"10.0.0.1", "192.168.0.0/16", "::1", "fd00::/8",
+	})
+	if err != nil {
+		t.Fatalf("unable to parse ip nets: %v", err)
+	}
+
+	expected := []string{"10.0.0.1/32", "192.168.0.0/16", "::1/128",
+		"fd00::/8"}
+	for i, ipNet := range ipNets {
+		if ipNet.String() != expected[i] {
+			t.Fatalf("expected %v, got %v", expected[i], ipNet)
+		}
+	}
+
+	for _, entry := range []string{"localhost", "10.0.0.1/33", "1.2.3"} {
+		if _, err := ParseIPNets([]string{entry}); err == nil {
+			t.Fatalf("expected %q to be rejected", entry)
+		}
+	}
+}
+
+// TestListenOnUnixSocket asserts that unix socket listeners are created with the
+// requested permissions and that only stale socket files are replaced.
+func TestListenOnUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lncfg-unix")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	addr := &net.UnixAddr{Name: filepath.Join(dir, "rpc.sock"), Net: "unix"}
+
+	lis, err := ListenOnUnixSocket(addr, 0600)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+
+	info, err := os.Stat(addr.Name)
+	if err != nil {
+		t.Fatalf("unable to stat socket: %v", err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Fatalf("expected permissions 0600, got %v", info.Mode().Perm())
+	}
+
+	// A socket that is in use must not be replaced.
+	if _, err := ListenOnUnixSocket(addr, 0600); err == nil {
+		t.Fatalf("expected socket in use to be rejected")
+	}
+
+	// The listener reports the requested address and removes the socket
+	// file once closed.
+	if lis.Addr().String() != addr.Name {
+		t.Fatalf("expected listener address %v, got %v", addr.Name,
+			lis.Addr())
+	}
+	lis.Close()
+	if _, err := os.Lstat(addr.Name); !os.IsNotExist(err) {
+		t.Fatalf("expected socket file to be removed, got %v", err)
+	}
+
+	// No temporary directory must be left behind.
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("unable to read dir: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("unexpected files left behind: %v", entries)
+	}
+
+	// Leave a stale socket file behind, which should be replaced.
+	staleLis, err := net.ListenUnix("unix", addr)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	staleLis.SetUnlinkOnClose(false)
+	staleLis.Close()
+
+	lis, err = ListenOnUnixSocket(addr, 0660)
+	if err != nil {
+		t.Fatalf("unable to listen on stale socket: %v", err)
+	}
+
+	// The socket must accept connections at its final path.
+	conn, err := net.Dial("unix", addr.Name)
+	if err != nil {
+		t.Fatalf("unable to connect to socket: %v", err)
+	}
+	conn.Close()
+	lis.Close()
+
+	// Any other file must not be replaced.
+	if err := ioutil.WriteFile(addr.Name, nil, 0600); err != nil {
+		t.Fatalf("unable to write file: %v", err)
+	}
+	if _, err := ListenOnUnixSocket(addr, 0600); err == nil {
+		t.Fatalf("expected regular file to be rejected")
+	}
+}
+
+// TestEnforceSafeAuthentication asserts that authentication may only be
+// disabled when listening on local addresses.
+func TestEnforceSafeAuthentication(t *testing.T) {
+	local := []net.Addr{
+		&net.TCPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 10009},
+		&net.UnixAddr{Name: "/tmp/lnd.sock", Net: "unix"},
+		&PipeAddr{Path: `\\.\pipe\dcrlnd`},
+	}
+	if err := EnforceSafeAuthentication(local, false); err != nil {
+		t.Fatalf("unexpected error for local addresses: %v", err)
+	}
+
+	public := append(local, &net.TCPAddr{
+		IP: net.IPv4(10, 0, 0, 1), Port: 10009,
+	})
+	if err := EnforceSafeAuthentication(public, false); err == nil {
+		t.Fatalf("expected public address to be rejected")
+	}
+	if err := EnforceSafeAuthentication(public, true); err != nil {
+		t.Fatalf("unexpected error with macaroons active: %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package lncfg
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+)
+
+// MaxChanConfs is the maximum number of confirmations that can be required
+// for a channel to be considered open. It matches the maximum number of
+// confirmations the chain notifier is able to dispatch.
+const MaxChanConfs = 256
+
+// chanConfsTier is a parsed entry of the channel confirmation schedule.
+type chanConfsTier struct {
+	// minChanSize is the minimum size of the channels the tier applies
+	// to.
+	minChanSize dcrutil.Amount
+
+	// numConfs is the number of confirmations required for channels of
+	// the tier.
+	numConfs uint16
+}
+
+// ChanConfs holds the configuration options for the number of confirmations
+// required for a channel to be considered open, scaled by the size of the
+// channel.
+type ChanConfs struct {
+	Tiers []string `long:"tier" description:"Require the given number of confirmations for channels of at least the given size, in the format <min channel size in atoms>:<confs>. The tier with the largest size not above the channel size is used, both for channels we accept and channels we open. Channels below the smallest tier use the default scaling. Can be specified multiple times."`
+
+	// tiers holds the parsed Tiers, sorted by ascending channel size.
+	tiers []chanConfsTier
+}
+
+// Validate parses the configured channel confirmation tiers.
+func (c *ChanConfs) Validate() error {
+	c.tiers = make([]chanConfsTier, 0, len(c.Tiers))
+	for _, entry := range c.Tiers {
+		tier, err := parseChanConfsTier(entry)
+		if err != nil {
+			return err
+		}
+
+		for _, t := range c.tiers {
+			if t.minChanSize == tier.minChanSize {
+				return fmt.Errorf("duplicate channel confs "+
+					"tier for channel size %v",
+					int64(tier.minChanSize))
+			}
+		}
+		c.tiers = append(c.tiers, *tier)
+	}
+
+	sort.Slice(c.tiers, func(i, j int) bool {
+		return c.tiers[i].minChanSize < c.tiers[j].minChanSize
+	})
+
+	return nil
+}
+
+// parseChanConfsTier parses a channel confirmation tier of the form
+// <min channel size in atoms>:<confs>.
+func parseChanConfsTier(entry string) (*chanConfsTier, error) {
+	parts := strings.Split(entry, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid channel confs tier %q, "+
+			"expected <min channel size in atoms>:<confs>", entry)
+	}
+
+	minChanSize, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil || minChanSize < 0 {
+		return nil, fmt.Errorf("invalid channel size in channel "+
+			"confs tier %q", entry)
+	}
+
+	numConfs, err := strconv.ParseUint(parts[1], 10, 16)
+	if err != nil || numConfs == 0 || numConfs > MaxChanConfs {
+		return nil, fmt.Errorf("invalid confs in channel confs tier "+
+			"%q, must be in [1:%v]", entry, MaxChanConfs)
+	}
+
+	return &chanConfsTier{
+		minChanSize: dcrutil.Amount(minChanSize),
+		numConfs:    uint16(numConfs),
+	}, nil
+}
+
+// Active returns true if any channel confirmation tier is configured.
+func (c *ChanConfs) Active() bool {
+	return len(c.tiers) > 0
+}
+
+// NumConfs returns the number of confirmations required for a channel of the
+// given size. The second return value is false if no tier applies to the
+// channel, in which case the default number of confirmations should be used.
+func (c *ChanConfs) NumConfs(chanSize dcrutil.Amount) (uint16, bool) {
+	for i := len(c.tiers) - 1; i >= 0; i-- {
+		if chanSize >= c.tiers[i].minChanSize {
+			return c.tiers[i].numConfs, true
+		}
+	}
+
+	return 0, false
+}
+
+// Compile-time constraint to ensure ChanConfs implements the Validator
+// interface.
+var _ Validator = (*ChanConfs)(nil)
@@ -0,0 +1,69 @@
+package lncfg_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lncfg"
+)
+
+// TestChanConfs asserts that the number of confirmations of a channel is taken
+// from the tier with the largest size not above the channel size.
+func TestChanConfs(t *testing.T) {
+	cfg := &lncfg.ChanConfs{
+		Tiers: []string{
+			"16777216:6",
+			"100000:1",
+			"1000000:3",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unable to validate config: %v", err)
+	}
+
+	tests := []struct {
+		chanSize dcrutil.Amount
+		numConfs uint16
+		ok       bool
+	}{
+		{chanSize: 99999, ok: false},
+		{chanSize: 100000, numConfs: 1, ok: true},
+		{chanSize: 999999, numConfs: 1, ok: true},
+		{chanSize: 1000000, numConfs: 3, ok: true},
+		{chanSize: 16777215, numConfs: 3, ok: true},
+		{chanSize: 20000000, numConfs: 6, ok: true},
+	}
+	for _, test := range tests {
+		numConfs, ok := cfg.NumConfs(test.chanSize)
+		if ok != test.ok || numConfs != test.numConfs {
+			t.Fatalf("channel size %v: expected (%v, %v), got "+
+				"(%v, %v)", test.chanSize, test.numConfs,
+				test.ok, numConfs, ok)
+		}
+	}
+
+	// Without any tiers, the default number of confirmations is used.
+	empty := &lncfg.ChanConfs{}
+	if err := empty.Validate(); err != nil {
+		t.Fatalf("unable to validate config: %v", err)
+	}
+	if _, ok := empty.NumConfs(1000000); ok {
+		t.Fatalf("expected no tier to apply")
+	}
+
+	// Malformed and duplicate tiers are rejected.
+	invalid := [][]string{
+		{"1000"},
+		{"abc:1"},
+		{"-1:1"},
+		{"1000:0"},
+		{"1000:257"},
+		{"1000:1", "1000:2"},
+	}
+	for _, tiers := range invalid {
+		cfg := &lncfg.ChanConfs{Tiers: tiers}
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected tiers %v to be rejected", tiers)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package lncfg
+
+import (
+	"compress/gzip"
+	"fmt"
+)
+
+// DefaultCompressionLevel is the default gzip compression level of RPC
+// responses.
+const DefaultCompressionLevel = gzip.DefaultCompression
+
+// Compression holds the configuration options for the compression of the
+// responses of the gRPC server and REST proxy.
+type Compression struct {
+	GRPC bool `long:"grpc" description:"If true, gRPC clients may request gzip compressed messages. Responses are compressed for clients that compress their requests."`
+
+	REST bool `long:"rest" description:"If true, REST responses are gzip compressed for clients that accept it through the Accept-Encoding header."`
+
+	Level int `long:"level" description:"The gzip compression level, from 1 (best speed) to 9 (best compression). -1 selects the default level."`
+}
+
+// Validate checks the configured compression level.
+func (c *Compression) Validate() error {
+	if c.Level != gzip.DefaultCompression &&
+		(c.Level < gzip.BestSpeed || c.Level > gzip.BestCompression) {
+
+		return fmt.Errorf("compression level: %v must be -1 or "+
+			"between %v and %v", c.Level, gzip.BestSpeed,
+			gzip.BestCompression)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure Compression implements the Validator
+// interface.
+var _ Validator = (*Compression)(nil)
@@ -0,0 +1,53 @@
+package lncfg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultDashboardCacheTTL is the default time the responses of the
+	// dashboard RPCs are cached for.
+	DefaultDashboardCacheTTL = time.Minute
+
+	// MinDashboardCacheTTL is the minimum time the responses of the
+	// dashboard RPCs are cached for, so that a busy status page can't put
+	// any meaningful load on the node.
+	MinDashboardCacheTTL = time.Second
+
+	// DefaultDashboardRecentForwards is the default number of recent
+	// forwards reported by the dashboard. None are reported by default, as
+	// their amounts and timestamps can be correlated with payments.
+	DefaultDashboardRecentForwards = 0
+
+	// MaxDashboardRecentForwards is the maximum number of recent forwards
+	// reported by the dashboard.
+	MaxDashboardRecentForwards = 100
+)
+
+// Dashboard holds the configuration options of the dashboard RPCs, which serve
+// cached aggregate stats meant for public status pages.
+type Dashboard struct {
+	CacheTTL time.Duration `long:"cachettl" description:"The time the responses of the dashboard RPCs are cached for. Valid time units are {s, m, h}."`
+
+	RecentForwards uint32 `long:"recentforwards" description:"The number of recent forwards reported by the dashboard. Set to 0 to only report aggregate forwarding stats."`
+}
+
+// Validate checks the values configured for the dashboard.
+func (d *Dashboard) Validate() error {
+	if d.CacheTTL < MinDashboardCacheTTL {
+		return fmt.Errorf("dashboard cache ttl: %v must be at least %v",
+			d.CacheTTL, MinDashboardCacheTTL)
+	}
+
+	if d.RecentForwards > MaxDashboardRecentForwards {
+		return fmt.Errorf("dashboard recent forwards: %v must be at "+
+			"most %v", d.RecentForwards, MaxDashboardRecentForwards)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure Dashboard implements the Validator
+// interface.
+var _ Validator = (*Dashboard)(nil)
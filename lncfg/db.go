@@ -3,6 +3,7 @@ package lncfg
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 )
@@ -11,8 +12,29 @@ const (
 	dbName      = "channel.db"
 	BoltBackend = "bolt"
 	EtcdBackend = "etcd"
+
+	// DefaultCompactionInterval is the default interval between two
+	// compactions of the forwarding packages and circuits.
+	DefaultCompactionInterval = time.Hour
+
+	// DefaultCompactionRetention is the default number of blocks the
+	// forwarding data of a closed channel is retained after the close has
+	// been resolved. This roughly corresponds to one week.
+	DefaultCompactionRetention = 2016
+
+	// MinCompactionInterval is the minimum interval between two
+	// compactions.
+	MinCompactionInterval = time.Minute
 )
 
+// Compaction holds the configuration of the background compaction of the
+// forwarding packages and circuits stored in the channel database.
+type Compaction struct {
+	Interval time.Duration `long:"interval" description:"The interval between two compactions of the forwarding packages and circuits of closed channels. Set to 0 to disable compaction."`
+
+	Retention uint32 `long:"retention" description:"The number of blocks the forwarding data of a closed channel is retained after the close has been resolved."`
+}
+
 // DB holds database configuration for LND.
 type DB struct {
 	Backend string `long:"backend" description:"The selected database backend."`
@@ -20,6 +42,8 @@ type DB struct {
 	Etcd *kvdb.EtcdConfig `group:"etcd" namespace:"etcd" description:"Etcd settings."`
 
 	Bolt *kvdb.BoltConfig `group:"bolt" namespace:"bolt" description:"Bolt settings."`
+
+	Compaction *Compaction `group:"compaction" namespace:"compaction" description:"Forwarding data compaction settings."`
 }
 
 // NewDB creates and returns a new default DB config.
@@ -27,6 +51,10 @@ func DefaultDB() *DB {
 	return &DB{
 		Backend: BoltBackend,
 		Bolt:    &kvdb.BoltConfig{},
+		Compaction: &Compaction{
+			Interval:  DefaultCompactionInterval,
+			Retention: DefaultCompactionRetention,
+		},
 	}
 }
 
@@ -45,6 +73,12 @@ func (db *DB) Validate() error {
 			BoltBackend, EtcdBackend)
 	}
 
+	interval := db.Compaction.Interval
+	if interval != 0 && interval < MinCompactionInterval {
+		return fmt.Errorf("compaction interval: %v below minimum: %v",
+			interval, MinCompactionInterval)
+	}
+
 	return nil
 }
 
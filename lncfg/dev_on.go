@@ -0,0 +1,15 @@
+// +build dev
+
+package lncfg
+
+// DevConfig houses the options that are only available in development builds,
+// mostly used to set up nodes for integration tests and demos.
+type DevConfig struct {
+	Fixtures string `long:"fixtures" description:"Path to a JSON fixture file used to seed a fresh node with a deterministic wallet seed, channels, invoices and payments"`
+}
+
+// FixturesPath returns the path of the fixture file to seed the node with, or
+// an empty string if none is configured.
+func (d *DevConfig) FixturesPath() string {
+	return d.Fixtures
+}
@@ -0,0 +1,93 @@
+package lncfg
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultFeeControlInterval is the default interval between fee
+	// adjustment rounds of the fee controller.
+	DefaultFeeControlInterval = time.Hour
+
+	// DefaultFeeControlMinFeeRate is the default lowest proportional fee
+	// rate (in millionths) the fee controller will set on a channel.
+	DefaultFeeControlMinFeeRate = 1
+
+	// DefaultFeeControlMaxFeeRate is the default highest proportional fee
+	// rate (in millionths) the fee controller will set on a channel.
+	DefaultFeeControlMaxFeeRate = 2000
+
+	// DefaultFeeControlExponent is the default exponent of the curve that
+	// maps a channel's liquidity skew to a fee rate.
+	DefaultFeeControlExponent = 2.0
+
+	// DefaultFeeControlFlowWeight is the default weight given to the recent
+	// payment flow through a channel.
+	DefaultFeeControlFlowWeight = 0.5
+
+	// DefaultFeeControlMinChange is the default minimum relative fee rate
+	// change required before a new policy is broadcast.
+	DefaultFeeControlMinChange = 0.1
+)
+
+var (
+	// MinFeeControlInterval is the minimum interval we allow between fee
+	// adjustment rounds. Every adjustment results in a new channel update
+	// being gossiped, so we don't want to spam the network with them.
+	MinFeeControlInterval = 10 * time.Minute
+)
+
+// FeeControl holds the configuration options for the automatic channel fee
+// controller.
+type FeeControl struct {
+	Active bool `long:"active" description:"If true, the fee rate of every channel will be periodically adjusted based on its liquidity skew and recent payment flow."`
+
+	Interval time.Duration `long:"interval" description:"How often the fee rates of all channels are re-evaluated. This value must be >= 10m."`
+
+	MinFeeRate uint32 `long:"minfeerate" description:"The lowest proportional fee rate (in millionths) set on a channel whose funds are entirely on our side."`
+
+	MaxFeeRate uint32 `long:"maxfeerate" description:"The highest proportional fee rate (in millionths) set on a channel whose funds are entirely on the remote side."`
+
+	Exponent float64 `long:"exponent" description:"The exponent of the curve that maps the fraction of a channel's funds held by the remote party to a fee rate. 1 is linear, larger values keep fees low until the local balance is nearly depleted."`
+
+	FlowWeight float64 `long:"flowweight" description:"How strongly the net payment flow through a channel during the last interval shifts its fee rate. Net outgoing flow raises the fee rate by up to this fraction, net incoming flow lowers it. Set to 0 to ignore payment flow."`
+
+	MinChange float64 `long:"minchange" description:"The minimum relative change in fee rate required before a new policy is applied to a channel and broadcast to the network."`
+}
+
+// Validate checks the values configured for the fee controller.
+func (f *FeeControl) Validate() error {
+	if !f.Active {
+		return nil
+	}
+
+	if f.Interval < MinFeeControlInterval {
+		return fmt.Errorf("fee control interval: %v below minimum: %v",
+			f.Interval, MinFeeControlInterval)
+	}
+
+	if f.MinFeeRate > f.MaxFeeRate {
+		return fmt.Errorf("fee control min fee rate (%v) must not be "+
+			"above max fee rate (%v)", f.MinFeeRate, f.MaxFeeRate)
+	}
+
+	if f.Exponent <= 0 {
+		return errors.New("fee control exponent must be positive")
+	}
+
+	if f.FlowWeight < 0 || f.FlowWeight > 1 {
+		return errors.New("fee control flow weight must be in [0:1]")
+	}
+
+	if f.MinChange < 0 {
+		return errors.New("fee control min change must not be negative")
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure FeeControl implements the Validator
+// interface.
+var _ Validator = (*FeeControl)(nil)
@@ -0,0 +1,40 @@
+package lncfg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultHtlcEventsMaxAge is the default age after which persisted
+	// htlc events are removed.
+	DefaultHtlcEventsMaxAge = time.Hour * 24 * 7
+
+	// DefaultHtlcEventsMaxCount is the default maximum number of persisted
+	// htlc events.
+	DefaultHtlcEventsMaxCount = 100000
+)
+
+// HtlcEvents holds the configuration options for the persistent store of
+// htlc events.
+type HtlcEvents struct {
+	Store bool `long:"store" description:"Persist a rolling window of htlc events (forwards, fails and settles) so that they can be queried after the fact."`
+
+	MaxAge time.Duration `long:"maxage" description:"The age after which persisted htlc events are removed. Set to 0 to keep events regardless of their age."`
+
+	MaxCount uint64 `long:"maxcount" description:"The maximum number of persisted htlc events, after which the oldest events are removed. Set to 0 to keep events regardless of their number."`
+}
+
+// Validate checks the values configured for the htlc event store.
+func (h *HtlcEvents) Validate() error {
+	if h.MaxAge < 0 {
+		return fmt.Errorf("htlc events max age: %v must not be "+
+			"negative", h.MaxAge)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure HtlcEvents implements the Validator
+// interface.
+var _ Validator = (*HtlcEvents)(nil)
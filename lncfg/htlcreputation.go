@@ -0,0 +1,57 @@
+package lncfg
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultHtlcReputationBucketPercent is the default percentage of a
+	// channel's htlc slots and liquidity that htlcs from unreputable peers
+	// may occupy.
+	DefaultHtlcReputationBucketPercent = 50
+
+	// DefaultHtlcReputationMinRevenue is the default amount of forwarding
+	// fees (in milli-atoms) a peer must have earned us within the window
+	// to be considered reputable.
+	DefaultHtlcReputationMinRevenue = 1000000
+
+	// DefaultHtlcReputationWindow is the default period over which the
+	// revenue of a peer is computed.
+	DefaultHtlcReputationWindow = time.Hour * 24 * 14
+)
+
+// HtlcReputation holds the configuration options for limiting the share of
+// our channels available to htlcs from peers without a forwarding history.
+type HtlcReputation struct {
+	Active bool `long:"active" description:"If true, htlcs offered by peers that haven't earned us enough forwarding fees recently may only occupy a share of the htlc slots and liquidity of each outgoing channel. This protects our channels from being jammed by unknown peers."`
+
+	BucketPercent uint32 `long:"bucketpercent" description:"The percentage of an outgoing channel's htlc slots and in flight liquidity that htlcs from unreputable peers may occupy at once."`
+
+	MinRevenue uint64 `long:"minrevenue" description:"The forwarding fees (in milli-atoms) that the htlcs offered by a peer must have earned us within the window for the peer to have unrestricted access to our channels."`
+
+	Window time.Duration `long:"window" description:"The period over which the forwarding fees earned through a peer are computed."`
+}
+
+// Validate checks the values configured for the htlc reputation.
+func (h *HtlcReputation) Validate() error {
+	if !h.Active {
+		return nil
+	}
+
+	if h.BucketPercent == 0 || h.BucketPercent > 100 {
+		return fmt.Errorf("htlc reputation bucket percent: %v must be "+
+			"in [1:100]", h.BucketPercent)
+	}
+
+	if h.Window <= 0 {
+		return fmt.Errorf("htlc reputation window: %v must be "+
+			"positive", h.Window)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure HtlcReputation implements the Validator
+// interface.
+var _ Validator = (*HtlcReputation)(nil)
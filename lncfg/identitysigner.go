@@ -0,0 +1,45 @@
+package lncfg
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultIdentitySignerTimeout is the default time we wait for the
+	// external signer to answer a request. Devices may ask the user to
+	// confirm operations, so this is generous.
+	DefaultIdentitySignerTimeout = time.Minute
+)
+
+// IdentitySigner holds the configuration of the external signer, such as a
+// hardware wallet bridge, holding the node identity key.
+type IdentitySigner struct {
+	Active bool `long:"active" description:"Use the identity key held by an external signer for the peer handshake, onion processing and gossip signing instead of deriving it from the wallet seed. Channel operations keep using the keys of the wallet."`
+
+	RPCHost string `long:"rpchost" description:"The address of the external signer, either host:port or unix:///path/to/socket."`
+
+	Timeout time.Duration `long:"timeout" description:"The maximum time to wait for the external signer to connect or to answer a single request."`
+}
+
+// Validate checks the values configured for the external identity signer.
+func (s *IdentitySigner) Validate() error {
+	if !s.Active {
+		return nil
+	}
+
+	if s.RPCHost == "" {
+		return errors.New("identitysigner.rpchost must be set when " +
+			"the identity signer is active")
+	}
+
+	if s.Timeout <= 0 {
+		return errors.New("identitysigner.timeout must be positive")
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure IdentitySigner implements the Validator
+// interface.
+var _ Validator = (*IdentitySigner)(nil)
@@ -0,0 +1,216 @@
+package lncfg
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// MinInvoiceCltvDelta is the minimum default CLTV delta that can be
+	// configured for invoices. It matches the minimum final CLTV delta
+	// accepted when explicitly specified in an invoice.
+	MinInvoiceCltvDelta = 18
+
+	// MaxInvoiceExpiry is the maximum default expiry that can be configured
+	// for invoices.
+	MaxInvoiceExpiry = time.Hour * 24 * 365
+)
+
+const (
+	// RouteHintsNever indicates invoices only include route hints when
+	// the caller requests them.
+	RouteHintsNever = "never"
+
+	// RouteHintsAlways indicates invoices include route hints for our
+	// unannounced channels unless the caller opts out.
+	RouteHintsAlways = "always"
+
+	// RouteHintsAuto indicates invoices include route hints for our
+	// unannounced channels unless the caller opts out, but only when we
+	// have no announced channels through which we could be reached
+	// otherwise.
+	RouteHintsAuto = "auto"
+)
+
+// InvoiceDefaults holds the default parameters of newly created invoices.
+// Zero values indicate the node-wide default should be used.
+type InvoiceDefaults struct {
+	CltvDelta uint32 `long:"cltvdelta" description:"The final CLTV delta of invoices that don't specify one. Set to 0 to use the node's time lock delta."`
+
+	Expiry time.Duration `long:"expiry" description:"The expiry of invoices that don't specify one. Set to 0 to use the default of 1 hour."`
+
+	RouteHints string `long:"routehints" description:"Whether invoices that neither request nor opt out of route hints include hints for our unannounced channels. One of never, always or auto, where auto only includes them if we have no announced channels. Hints are never included for announced channels."`
+}
+
+// validate checks the values of an invoice defaults entry.
+func (d *InvoiceDefaults) validate(name string) error {
+	if d.CltvDelta != 0 && d.CltvDelta < MinInvoiceCltvDelta {
+		return fmt.Errorf("%v cltv delta: %v below minimum: %v", name,
+			d.CltvDelta, MinInvoiceCltvDelta)
+	}
+
+	if d.CltvDelta > math.MaxUint16 {
+		return fmt.Errorf("%v cltv delta: %v above maximum: %v", name,
+			d.CltvDelta, math.MaxUint16)
+	}
+
+	if d.Expiry < 0 || d.Expiry > MaxInvoiceExpiry {
+		return fmt.Errorf("%v expiry: %v must be in [0:%v]", name,
+			d.Expiry, MaxInvoiceExpiry)
+	}
+
+	switch d.RouteHints {
+	case "", RouteHintsNever, RouteHintsAlways, RouteHintsAuto:
+
+	default:
+		return fmt.Errorf("%v route hints: %q must be one of %v, %v "+
+			"or %v", name, d.RouteHints, RouteHintsNever,
+			RouteHintsAlways, RouteHintsAuto)
+	}
+
+	return nil
+}
+
+// merge returns the defaults of d, with any unset values taken from the
+// passed fallback.
+func (d InvoiceDefaults) merge(fallback InvoiceDefaults) InvoiceDefaults {
+	if d.CltvDelta == 0 {
+		d.CltvDelta = fallback.CltvDelta
+	}
+	if d.Expiry == 0 {
+		d.Expiry = fallback.Expiry
+	}
+	if d.RouteHints == "" {
+		d.RouteHints = fallback.RouteHints
+	}
+
+	return d
+}
+
+// KeysendInvoiceDefaults holds the default parameters of the invoices created
+// for incoming keysend payments.
+type KeysendInvoiceDefaults struct {
+	CltvDelta uint32 `long:"cltvdelta" description:"The final CLTV delta required for incoming keysend payments. Set to 0 to use the final CLTV reject delta."`
+}
+
+// Invoices holds the configuration options for the default parameters of
+// newly created invoices.
+type Invoices struct {
+	Standard *InvoiceDefaults `group:"standard" namespace:"standard"`
+
+	Hold *InvoiceDefaults `group:"hold" namespace:"hold"`
+
+	Keysend *KeysendInvoiceDefaults `group:"keysend" namespace:"keysend"`
+
+	SettleApprovalThreshold int64 `long:"settleapprovalthreshold" description:"The amount in atoms from which payments to standard invoices, including keysend payments, aren't settled until approved through the invoices RPC. Held payments are at risk of their htlcs expiring if left pending. Set to 0 to settle all payments right away."`
+
+	MacaroonDefaults []string `long:"macaroondefaults" description:"Override the defaults of standard and hold invoices created with a macaroon of the given root key ID, in the format <root key id>:<cltv delta>:<expiry>[:<route hints>]. Empty values fall back to the defaults of the invoice class. Can be specified multiple times."`
+
+	// macaroonDefaults holds the parsed MacaroonDefaults, keyed by root
+	// key ID.
+	macaroonDefaults map[string]InvoiceDefaults
+}
+
+// Validate checks the values configured for the invoice defaults and parses
+// the per macaroon overrides.
+func (i *Invoices) Validate() error {
+	if err := i.Standard.validate("standard invoice"); err != nil {
+		return err
+	}
+
+	if err := i.Hold.validate("hold invoice"); err != nil {
+		return err
+	}
+
+	if i.Keysend.CltvDelta != 0 &&
+		i.Keysend.CltvDelta < DefaultFinalCltvRejectDelta {
+
+		return fmt.Errorf("keysend invoice cltv delta: %v below "+
+			"minimum: %v", i.Keysend.CltvDelta,
+			DefaultFinalCltvRejectDelta)
+	}
+
+	if i.SettleApprovalThreshold < 0 {
+		return fmt.Errorf("settle approval threshold: %v must not be "+
+			"negative", i.SettleApprovalThreshold)
+	}
+
+	i.macaroonDefaults = make(map[string]InvoiceDefaults)
+	for _, entry := range i.MacaroonDefaults {
+		rootKeyID, defaults, err := parseMacaroonInvoiceDefaults(entry)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := i.macaroonDefaults[rootKeyID]; ok {
+			return fmt.Errorf("duplicate invoice defaults for "+
+				"macaroon root key ID %v", rootKeyID)
+		}
+		i.macaroonDefaults[rootKeyID] = *defaults
+	}
+
+	return nil
+}
+
+// parseMacaroonInvoiceDefaults parses an invoice defaults override of the
+// form <root key id>:<cltv delta>:<expiry>[:<route hints>].
+func parseMacaroonInvoiceDefaults(entry string) (string, *InvoiceDefaults,
+	error) {
+
+	parts := strings.Split(entry, ":")
+	if len(parts) < 3 || len(parts) > 4 || parts[0] == "" {
+		return "", nil, fmt.Errorf("invalid macaroon invoice defaults "+
+			"%q, expected <root key id>:<cltv delta>:<expiry>"+
+			"[:<route hints>]", entry)
+	}
+
+	rootKeyID := parts[0]
+	defaults := &InvoiceDefaults{}
+	if parts[1] != "" {
+		delta, err := strconv.ParseUint(parts[1], 10, 32)
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid cltv delta in "+
+				"macaroon invoice defaults %q: %v", entry, err)
+		}
+		defaults.CltvDelta = uint32(delta)
+	}
+	if parts[2] != "" {
+		expiry, err := time.ParseDuration(parts[2])
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid expiry in macaroon "+
+				"invoice defaults %q: %v", entry, err)
+		}
+		defaults.Expiry = expiry
+	}
+	if len(parts) == 4 {
+		defaults.RouteHints = parts[3]
+	}
+
+	name := fmt.Sprintf("macaroon %v invoice", rootKeyID)
+	if err := defaults.validate(name); err != nil {
+		return "", nil, err
+	}
+
+	return rootKeyID, defaults, nil
+}
+
+// Defaults returns the defaults of a standard or hold invoice created with a
+// macaroon of the given root key ID. An empty root key ID only returns the
+// defaults of the invoice class. Zero values in the returned defaults
+// indicate the node-wide default should be used.
+func (i *Invoices) Defaults(hold bool, rootKeyID string) InvoiceDefaults {
+	classDefaults := *i.Standard
+	if hold {
+		classDefaults = i.Hold.merge(classDefaults)
+	}
+
+	macDefaults, ok := i.macaroonDefaults[rootKeyID]
+	if rootKeyID == "" || !ok {
+		return classDefaults
+	}
+
+	return macDefaults.merge(classDefaults)
+}
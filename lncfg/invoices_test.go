@@ -0,0 +1,194 @@
+package lncfg_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/lncfg"
+)
+
+// TestInvoiceDefaults asserts that the invoice defaults are resolved from the
+// macaroon overrides, the invoice class and the standard class, in that order.
+func TestInvoiceDefaults(t *testing.T) {
+	cfg := &lncfg.Invoices{
+		Standard: &lncfg.InvoiceDefaults{
+			CltvDelta:  40,
+			Expiry:     time.Hour * 2,
+			RouteHints: lncfg.RouteHintsAuto,
+		},
+		Hold: &lncfg.InvoiceDefaults{
+			Expiry: time.Hour * 24,
+		},
+		Keysend: &lncfg.KeysendInvoiceDefaults{},
+		MacaroonDefaults: []string{
+			"1:100:",
+			"2::10m",
+			"4:::never",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unable to validate config: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		hold      bool
+		rootKeyID string
+		expected  lncfg.InvoiceDefaults
+	}{
+		{
+			name: "standard",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  40,
+				Expiry:     time.Hour * 2,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name: "hold",
+			hold: true,
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  40,
+				Expiry:     time.Hour * 24,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name:      "unknown macaroon",
+			rootKeyID: "3",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  40,
+				Expiry:     time.Hour * 2,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name:      "macaroon cltv delta",
+			rootKeyID: "1",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  100,
+				Expiry:     time.Hour * 2,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name:      "macaroon cltv delta hold",
+			hold:      true,
+			rootKeyID: "1",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  100,
+				Expiry:     time.Hour * 24,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name:      "macaroon expiry",
+			rootKeyID: "2",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  40,
+				Expiry:     time.Minute * 10,
+				RouteHints: lncfg.RouteHintsAuto,
+			},
+		},
+		{
+			name:      "macaroon route hints",
+			rootKeyID: "4",
+			expected: lncfg.InvoiceDefaults{
+				CltvDelta:  40,
+				Expiry:     time.Hour * 2,
+				RouteHints: lncfg.RouteHintsNever,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		defaults := cfg.Defaults(test.hold, test.rootKeyID)
+		if defaults != test.expected {
+			t.Fatalf("%v: expected %v, got %v", test.name,
+				test.expected, defaults)
+		}
+	}
+}
+
+// TestValidateInvoices asserts that invalid invoice defaults are rejected.
+func TestValidateInvoices(t *testing.T) {
+	tests := []struct {
+		name             string
+		standard         lncfg.InvoiceDefaults
+		keysendDelta     uint32
+		macaroonDefaults []string
+		valid            bool
+	}{
+		{
+			name:  "unset",
+			valid: true,
+		},
+		{
+			name:     "cltv delta too small",
+			standard: lncfg.InvoiceDefaults{CltvDelta: 5},
+		},
+		{
+			name:     "cltv delta too large",
+			standard: lncfg.InvoiceDefaults{CltvDelta: 1 << 16},
+		},
+		{
+			name:     "expiry too large",
+			standard: lncfg.InvoiceDefaults{Expiry: time.Hour * 24 * 366},
+		},
+		{
+			name:     "invalid route hints",
+			standard: lncfg.InvoiceDefaults{RouteHints: "sometimes"},
+		},
+		{
+			name:         "keysend cltv delta too small",
+			keysendDelta: 5,
+		},
+		{
+			name:             "malformed macaroon defaults",
+			macaroonDefaults: []string{"1:40"},
+		},
+		{
+			name:             "missing root key id",
+			macaroonDefaults: []string{":40:1h"},
+		},
+		{
+			name:             "invalid macaroon expiry",
+			macaroonDefaults: []string{"1:40:soon"},
+		},
+		{
+			name:             "invalid macaroon route hints",
+			macaroonDefaults: []string{"1:40:1h:sometimes"},
+		},
+		{
+			name:             "too many macaroon defaults",
+			macaroonDefaults: []string{"1:40:1h:auto:extra"},
+		},
+		{
+			name:             "duplicate macaroon defaults",
+			macaroonDefaults: []string{"1:40:1h", "1:50:2h"},
+		},
+	}
+
+	for _, test := range tests {
+		standard := test.standard
+		cfg := &lncfg.Invoices{
+			Standard: &standard,
+			Hold:     &lncfg.InvoiceDefaults{},
+			Keysend: &lncfg.KeysendInvoiceDefaults{
+				CltvDelta: test.keysendDelta,
+			},
+			MacaroonDefaults: test.macaroonDefaults,
+		}
+
+		err := cfg.Validate()
+		switch {
+		case test.valid && err != nil:
+			t.Fatalf("%v: unable to validate config: %v",
+				test.name, err)
+
+		case !test.valid && err == nil:
+			t.Fatalf("%v: invalid config passed validation",
+				test.name)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package lncfg
+
+import (
+	"context"
+	"net"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/local"
+)
+
+// localConnCreds wraps transport credentials so that the handshake is skipped
+// for local connections.
+type localConnCreds struct {
+	credentials.TransportCredentials
+}
+
+// LocalConnCredentials wraps the given transport credentials so that
+// connections over unix sockets and named pipes skip the TLS handshake. Access
+// to these connections is restricted by the permissions of the socket or pipe
+// and they never leave the host, so TLS would only add overhead. Connections
+// over any other network use the wrapped credentials.
+func LocalConnCredentials(
+	creds credentials.TransportCredentials) credentials.TransportCredentials {
+
+	return &localConnCreds{TransportCredentials: creds}
+}
+
+// isLocalConn returns true if the connection is established over a unix socket
+// or a named pipe.
+func isLocalConn(conn net.Conn) bool {
+	addr := conn.LocalAddr()
+	return addr != nil && (IsUnix(addr) || IsNamedPipe(addr))
+}
+
+// localAuthInfo is the auth info of local connections. They provide the same
+// guarantees as TLS, so per-RPC credentials such as macaroons, which require a
+// secure transport, can be sent over them.
+var localAuthInfo = local.Info{
+	CommonAuthInfo: credentials.CommonAuthInfo{
+		SecurityLevel: credentials.PrivacyAndIntegrity,
+	},
+}
+
+// ClientHandshake does the authentication handshake of the wrapped credentials
+// for connections that aren't local.
+//
+// NOTE: This is part of the credentials.TransportCredentials interface.
+func (c *localConnCreds) ClientHandshake(ctx context.Context, authority string,
+	conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+
+	if isLocalConn(conn) {
+		return conn, localAuthInfo, nil
+	}
+
+	return c.TransportCredentials.ClientHandshake(ctx, authority, conn)
+}
+
+// ServerHandshake does the authentication handshake of the wrapped credentials
+// for connections that aren't local.
+//
+// NOTE: This is part of the credentials.TransportCredentials interface.
+func (c *localConnCreds) ServerHandshake(conn net.Conn) (net.Conn,
+	credentials.AuthInfo, error) {
+
+	if isLocalConn(conn) {
+		return conn, localAuthInfo, nil
+	}
+
+	return c.TransportCredentials.ServerHandshake(conn)
+}
+
+// Clone makes a copy of the credentials.
+//
+// NOTE: This is part of the credentials.TransportCredentials interface.
+func (c *localConnCreds) Clone() credentials.TransportCredentials {
+	return &localConnCreds{
+		TransportCredentials: c.TransportCredentials.Clone(),
+	}
+}
@@ -0,0 +1,138 @@
+package lncfg
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/local"
+)
+
+var errMockHandshake = errors.New("mock handshake")
+
+// mockTransportCreds is a credentials.TransportCredentials that records the
+// handshakes it is asked to do and fails them.
+type mockTransportCreds struct {
+	credentials.TransportCredentials
+
+	clientHandshakes int
+	serverHandshakes int
+}
+
+func (m *mockTransportCreds) ClientHandshake(context.Context, string,
+	net.Conn) (net.Conn, credentials.AuthInfo, error) {
+
+	m.clientHandshakes++
+	return nil, nil, errMockHandshake
+}
+
+func (m *mockTransportCreds) ServerHandshake(net.Conn) (net.Conn,
+	credentials.AuthInfo, error) {
+
+	m.serverHandshakes++
+	return nil, nil, errMockHandshake
+}
+
+// connPair returns both ends of a connection established over the given
+// network.
+func connPair(t *testing.T, network, address string) (net.Conn, net.Conn) {
+	t.Helper()
+
+	lis, err := net.Listen(network, address)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer lis.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			accepted <- nil
+			return
+		}
+		accepted <- conn
+	}()
+
+	client, err := net.Dial(network, lis.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	server := <-accepted
+	if server == nil {
+		t.Fatalf("unable to accept connection")
+	}
+
+	return client, server
+}
+
+// TestLocalConnCredentials asserts that the handshake of the wrapped
+// credentials is skipped for unix socket connections only, and that local
+// connections are reported as secure.
+func TestLocalConnCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lncfg-creds")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	mock := &mockTransportCreds{}
+	creds := LocalConnCredentials(mock)
+
+	client, server := connPair(t, "unix", filepath.Join(dir, "rpc.sock"))
+	defer client.Close()
+	defer server.Close()
+
+	conn, authInfo, err := creds.ClientHandshake(
+		context.Background(), "localhost", client,
+	)
+	if err != nil {
+		t.Fatalf("unexpected client handshake error: %v", err)
+	}
+	if conn != client {
+		t.Fatalf("expected unix connection to be used as is")
+	}
+	info, ok := authInfo.(local.Info)
+	if !ok || info.SecurityLevel != credentials.PrivacyAndIntegrity {
+		t.Fatalf("expected local connection to be secure, got %v",
+			authInfo)
+	}
+
+	conn, _, err = creds.ServerHandshake(server)
+	if err != nil {
+		t.Fatalf("unexpected server handshake error: %v", err)
+	}
+	if conn != server {
+		t.Fatalf("expected unix connection to be used as is")
+	}
+
+	if mock.clientHandshakes != 0 || mock.serverHandshakes != 0 {
+		t.Fatalf("expected no handshake of the wrapped credentials")
+	}
+
+	// Connections over TCP must use the wrapped credentials.
+	tcpClient, tcpServer := connPair(t, "tcp", "127.0.0.1:0")
+	defer tcpClient.Close()
+	defer tcpServer.Close()
+
+	_, _, err = creds.ClientHandshake(
+		context.Background(), "localhost", tcpClient,
+	)
+	if err != errMockHandshake {
+		t.Fatalf("expected wrapped client handshake, got %v", err)
+	}
+	if _, _, err := creds.ServerHandshake(tcpServer); err != errMockHandshake {
+		t.Fatalf("expected wrapped server handshake, got %v", err)
+	}
+
+	if mock.clientHandshakes != 1 || mock.serverHandshakes != 1 {
+		t.Fatalf("expected one handshake of each kind, got %d client "+
+			"and %d server handshakes", mock.clientHandshakes,
+			mock.serverHandshakes)
+	}
+}
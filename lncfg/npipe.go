@@ -0,0 +1,55 @@
+package lncfg
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// namedPipeNetwork is the network of named pipe addresses.
+	namedPipeNetwork = "npipe"
+
+	// namedPipePrefix is the prefix of the path of the named pipes of the
+	// local host.
+	namedPipePrefix = `\\.\pipe\`
+)
+
+// PipeAddr is the address of a Windows named pipe.
+type PipeAddr struct {
+	// Path is the full path of the pipe, e.g. \\.\pipe\dcrlnd.
+	Path string
+}
+
+// A compile-time check to ensure PipeAddr implements the net.Addr interface.
+var _ net.Addr = (*PipeAddr)(nil)
+
+// Network returns the network of named pipe addresses.
+//
+// NOTE: This is part of the net.Addr interface.
+func (a *PipeAddr) Network() string {
+	return namedPipeNetwork
+}
+
+// String returns the path of the named pipe.
+//
+// NOTE: This is part of the net.Addr interface.
+func (a *PipeAddr) String() string {
+	return a.Path
+}
+
+// parsePipeAddr parses the name or the full path of a named pipe of the local
+// host.
+func parsePipeAddr(name string) (*PipeAddr, error) {
+	name = strings.TrimPrefix(name, namedPipePrefix)
+	if name == "" || strings.ContainsAny(name, `\/`) {
+		return nil, fmt.Errorf("invalid named pipe name %q", name)
+	}
+
+	return &PipeAddr{Path: namedPipePrefix + name}, nil
+}
+
+// IsNamedPipe returns true if an address describes a Windows named pipe.
+func IsNamedPipe(addr net.Addr) bool {
+	return addr.Network() == namedPipeNetwork
+}
@@ -0,0 +1,26 @@
+// +build !windows
+
+package lncfg
+
+import (
+	"context"
+	"errors"
+	"net"
+)
+
+// errNamedPipeUnsupported is returned when using a named pipe on a platform
+// other than Windows.
+var errNamedPipeUnsupported = errors.New("named pipes are only supported " +
+	"on Windows")
+
+// ListenOnNamedPipe creates a listener on the named pipe at the given address.
+// Named pipes are only supported on Windows.
+func ListenOnNamedPipe(addr net.Addr) (net.Listener, error) {
+	return nil, errNamedPipeUnsupported
+}
+
+// DialNamedPipe connects to the named pipe at the given address. Named pipes
+// are only supported on Windows.
+func DialNamedPipe(ctx context.Context, addr net.Addr) (net.Conn, error) {
+	return nil, errNamedPipeUnsupported
+}
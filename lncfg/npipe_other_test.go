@@ -0,0 +1,23 @@
+// +build !windows
+
+package lncfg
+
+import (
+	"context"
+	"testing"
+)
+
+// TestNamedPipeUnsupported tests that named pipes are rejected on platforms
+// other than Windows.
+func TestNamedPipeUnsupported(t *testing.T) {
+	addr := &PipeAddr{Path: namedPipePrefix + "dcrlnd"}
+
+	if _, err := ListenOnNamedPipe(addr); err != errNamedPipeUnsupported {
+		t.Fatalf("expected %v, got %v", errNamedPipeUnsupported, err)
+	}
+
+	_, err := DialNamedPipe(context.Background(), addr)
+	if err != errNamedPipeUnsupported {
+		t.Fatalf("expected %v, got %v", errNamedPipeUnsupported, err)
+	}
+}
@@ -0,0 +1,459 @@
+package lncfg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const (
+	// pipeAccessDuplex opens a named pipe for both reading and writing.
+	pipeAccessDuplex = 0x3
+
+	// pipeRejectRemoteClients rejects connections from remote hosts.
+	pipeRejectRemoteClients = 0x8
+
+	// pipeUnlimitedInstances allows as many instances of a named pipe as
+	// the system resources allow.
+	pipeUnlimitedInstances = 255
+
+	// pipeBufferSize is the size of the input and output buffers of a
+	// named pipe instance.
+	pipeBufferSize = 65536
+
+	// pipeSecurityDescriptor only grants access to named pipes to the user
+	// that created them, the administrators and the local system.
+	pipeSecurityDescriptor = "D:P(A;;GA;;;OW)(A;;GA;;;BA)(A;;GA;;;SY)"
+
+	// pipeDialRetryInterval is how long to wait before retrying to connect
+	// to a named pipe whose instances are all busy.
+	pipeDialRetryInterval = 10 * time.Millisecond
+)
+
+var (
+	modkernel32 = windows.NewLazySystemDLL("kernel32.dll")
+
+	procCreateNamedPipeW = modkernel32.NewProc("CreateNamedPipeW")
+	procConnectNamedPipe = modkernel32.NewProc("ConnectNamedPipe")
+
+	// errPipeClosed is returned when using a closed named pipe or
+	// listener.
+	errPipeClosed = errors.New("use of closed named pipe")
+)
+
+// createNamedPipe creates an instance of the named pipe with the given path.
+func createNamedPipe(path string, first bool) (windows.Handle, error) {
+	name, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+
+	sd, err := windows.SecurityDescriptorFromString(pipeSecurityDescriptor)
+	if err != nil {
+		return windows.InvalidHandle, err
+	}
+	sa := &windows.SecurityAttributes{
+		SecurityDescriptor: sd,
+	}
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+
+	// Creating the first instance fails if the pipe already exists, so
+	// that we never share a pipe with another process.
+	openMode := uint32(pipeAccessDuplex | windows.FILE_FLAG_OVERLAPPED)
+	if first {
+		openMode |= windows.FILE_FLAG_FIRST_PIPE_INSTANCE
+	}
+
+	r, _, err := procCreateNamedPipeW.Call(
+		uintptr(unsafe.Pointer(name)), uintptr(openMode),
+		pipeRejectRemoteClients, pipeUnlimitedInstances,
+		pipeBufferSize, pipeBufferSize, 0, uintptr(unsafe.Pointer(sa)),
+	)
+	handle := windows.Handle(r)
+	if handle == windows.InvalidHandle {
+		return handle, err
+	}
+
+	return handle, nil
+}
+
+// overlappedIO runs an overlapped operation on the given handle and waits for
+// it to complete. The operation is canceled if the closed event is signaled
+// first.
+func overlappedIO(handle, closed windows.Handle,
+	op func(*windows.Overlapped) error) (uint32, error) {
+
+	event, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer windows.CloseHandle(event)
+
+	overlapped := &windows.Overlapped{HEvent: event}
+	err = op(overlapped)
+	if err != nil && err != windows.ERROR_IO_PENDING {
+		return 0, err
+	}
+
+	if err == windows.ERROR_IO_PENDING {
+		i, err := windows.WaitForMultipleObjects(
+			[]windows.Handle{event, closed}, false,
+			windows.INFINITE,
+		)
+		if err != nil {
+			return 0, err
+		}
+		if i != windows.WAIT_OBJECT_0 {
+			windows.CancelIoEx(handle, overlapped)
+		}
+	}
+
+	var n uint32
+	err = windows.GetOverlappedResult(handle, overlapped, &n, true)
+	if err == windows.ERROR_OPERATION_ABORTED {
+		return n, errPipeClosed
+	}
+
+	return n, err
+}
+
+// pipeConn is a connection over a named pipe.
+type pipeConn struct {
+	handle windows.Handle
+	addr   *PipeAddr
+
+	// closed is signaled once the connection is closed, to cancel the
+	// pending operations.
+	closed    windows.Handle
+	closeOnce sync.Once
+
+	// mu is held for reading during operations, so that the handle is
+	// only closed once they return.
+	mu       sync.RWMutex
+	isClosed bool
+}
+
+// A compile-time check to ensure pipeConn implements the net.Conn interface.
+var _ net.Conn = (*pipeConn)(nil)
+
+// newPipeConn creates a connection over the given named pipe handle.
+func newPipeConn(handle windows.Handle, addr *PipeAddr) (*pipeConn, error) {
+	closed, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
+	}
+
+	return &pipeConn{
+		handle: handle,
+		addr:   addr,
+		closed: closed,
+	}, nil
+}
+
+// Read reads data from the pipe.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.isClosed {
+		return 0, errPipeClosed
+	}
+
+	n, err := overlappedIO(c.handle, c.closed,
+		func(o *windows.Overlapped) error {
+			return windows.ReadFile(c.handle, b, nil, o)
+		},
+	)
+	switch err {
+	case windows.ERROR_BROKEN_PIPE, windows.ERROR_PIPE_NOT_CONNECTED:
+		return int(n), io.EOF
+	}
+
+	return int(n), err
+}
+
+// Write writes data to the pipe.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) Write(b []byte) (int, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	if c.isClosed {
+		return 0, errPipeClosed
+	}
+
+	var written int
+	for written < len(b) {
+		n, err := overlappedIO(c.handle, c.closed,
+			func(o *windows.Overlapped) error {
+				return windows.WriteFile(
+					c.handle, b[written:], nil, o,
+				)
+			},
+		)
+		written += int(n)
+		if err != nil {
+			return written, err
+		}
+	}
+
+	return written, nil
+}
+
+// Close closes the connection, canceling its pending operations.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) Close() error {
+	err := errPipeClosed
+	c.closeOnce.Do(func() {
+		windows.SetEvent(c.closed)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		c.isClosed = true
+		err = windows.CloseHandle(c.handle)
+		windows.CloseHandle(c.closed)
+	})
+
+	return err
+}
+
+// LocalAddr returns the address of the named pipe.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) LocalAddr() net.Addr {
+	return c.addr
+}
+
+// RemoteAddr returns the address of the named pipe.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) RemoteAddr() net.Addr {
+	return c.addr
+}
+
+// SetDeadline is a no-op, as deadlines aren't supported on named pipes.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) SetDeadline(time.Time) error {
+	return nil
+}
+
+// SetReadDeadline is a no-op, as deadlines aren't supported on named pipes.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) SetReadDeadline(time.Time) error {
+	return nil
+}
+
+// SetWriteDeadline is a no-op, as deadlines aren't supported on named pipes.
+//
+// NOTE: This is part of the net.Conn interface.
+func (c *pipeConn) SetWriteDeadline(time.Time) error {
+	return nil
+}
+
+// pipeListener accepts connections on a named pipe.
+type pipeListener struct {
+	addr *PipeAddr
+
+	// handle is the instance of the pipe the next client connects to. It
+	// is invalid if the instance couldn't be created.
+	handle windows.Handle
+
+	// closed is signaled once the listener is closed, to cancel a pending
+	// Accept.
+	closed    windows.Handle
+	closeOnce sync.Once
+
+	// mu serializes Accept and Close.
+	mu       sync.Mutex
+	isClosed bool
+}
+
+// A compile-time check to ensure pipeListener implements the net.Listener
+// interface.
+var _ net.Listener = (*pipeListener)(nil)
+
+// ListenOnNamedPipe creates a listener on the named pipe at the given address.
+// Access to the pipe is restricted to the current user, the administrators and
+// the local system, and remote clients are rejected. An error is returned if
+// the pipe already exists.
+func ListenOnNamedPipe(addr net.Addr) (net.Listener, error) {
+	pipeAddr, err := parsePipeAddr(addr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	handle, err := createNamedPipe(pipeAddr.Path, true)
+	if err != nil {
+		return nil, err
+	}
+
+	closed, err := windows.CreateEvent(nil, 1, 0, nil)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
+	}
+
+	return &pipeListener{
+		addr:   pipeAddr,
+		handle: handle,
+		closed: closed,
+	}, nil
+}
+
+// Accept waits for a client to connect to the pipe.
+//
+// NOTE: This is part of the net.Listener interface.
+func (l *pipeListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.accept()
+		if err == windows.ERROR_NO_DATA {
+			// The client disconnected before the connection was
+			// accepted, so we wait for the next one.
+			continue
+		}
+
+		return conn, err
+	}
+}
+
+// accept waits for a client to connect to the current pipe instance, and
+// creates the instance the next client connects to.
+func (l *pipeListener) accept() (net.Conn, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.isClosed {
+		return nil, errPipeClosed
+	}
+
+	// Retry creating the instance if it failed after the last accepted
+	// connection.
+	if l.handle == windows.InvalidHandle {
+		handle, err := createNamedPipe(l.addr.Path, false)
+		if err != nil {
+			return nil, err
+		}
+		l.handle = handle
+	}
+
+	handle := l.handle
+	_, err := overlappedIO(handle, l.closed,
+		func(o *windows.Overlapped) error {
+			r, _, err := procConnectNamedPipe.Call(
+				uintptr(handle), uintptr(unsafe.Pointer(o)),
+			)
+			if r != 0 {
+				return nil
+			}
+			return err
+		},
+	)
+
+	// A client that connected before ConnectNamedPipe was called is
+	// reported as an error, while the connection succeeded.
+	if err == windows.ERROR_PIPE_CONNECTED {
+		err = nil
+	}
+
+	// The instance is replaced whether or not the connection succeeded, as
+	// a failed instance can't be reused.
+	l.handle, _ = createNamedPipe(l.addr.Path, false)
+	if err != nil {
+		windows.CloseHandle(handle)
+		return nil, err
+	}
+
+	conn, err := newPipeConn(handle, l.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// Close stops listening on the pipe.
+//
+// NOTE: This is part of the net.Listener interface.
+func (l *pipeListener) Close() error {
+	err := errPipeClosed
+	l.closeOnce.Do(func() {
+		windows.SetEvent(l.closed)
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+
+		l.isClosed = true
+		err = nil
+		if l.handle != windows.InvalidHandle {
+			err = windows.CloseHandle(l.handle)
+			l.handle = windows.InvalidHandle
+		}
+		windows.CloseHandle(l.closed)
+	})
+
+	return err
+}
+
+// Addr returns the address of the pipe.
+//
+// NOTE: This is part of the net.Listener interface.
+func (l *pipeListener) Addr() net.Addr {
+	return l.addr
+}
+
+// DialNamedPipe connects to the named pipe at the given address, waiting for
+// an instance of the pipe to become available if they're all busy.
+func DialNamedPipe(ctx context.Context, addr net.Addr) (net.Conn, error) {
+	pipeAddr, err := parsePipeAddr(addr.String())
+	if err != nil {
+		return nil, err
+	}
+
+	name, err := windows.UTF16PtrFromString(pipeAddr.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		handle, err := windows.CreateFile(
+			name, windows.GENERIC_READ|windows.GENERIC_WRITE, 0,
+			nil, windows.OPEN_EXISTING,
+			windows.FILE_FLAG_OVERLAPPED, 0,
+		)
+		if err == nil {
+			conn, err := newPipeConn(handle, pipeAddr)
+			if err != nil {
+				return nil, err
+			}
+
+			return conn, nil
+		}
+		if err != windows.ERROR_PIPE_BUSY {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(pipeDialRetryInterval):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// +build windows
+
+package lncfg
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+// testPipeAddr returns the address of a named pipe unique to the running test.
+func testPipeAddr(t *testing.T) *PipeAddr {
+	addr, err := parsePipeAddr(
+		fmt.Sprintf("dcrlnd-test-%d-%d", os.Getpid(),
+			time.Now().UnixNano()),
+	)
+	if err != nil {
+		t.Fatalf("unable to parse pipe address: %v", err)
+	}
+
+	return addr
+}
+
+// TestNamedPipeListener tests that connections can be accepted on a named
+// pipe and carry data both ways, and that a pipe can't be shared by two
+// listeners.
+func TestNamedPipeListener(t *testing.T) {
+	addr := testPipeAddr(t)
+
+	listener, err := ListenOnNamedPipe(addr)
+	if err != nil {
+		t.Fatalf("unable to listen on named pipe: %v", err)
+	}
+	defer listener.Close()
+
+	if _, err := ListenOnNamedPipe(addr); err == nil {
+		t.Fatalf("expected listening twice on the same pipe to fail")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Several clients are accepted in turn, as a new instance of the pipe
+	// is created after each accepted connection.
+	for i := 0; i < 3; i++ {
+		errChan := make(chan error, 1)
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				errChan <- err
+				return
+			}
+			defer conn.Close()
+
+			// Echo back what the client sends.
+			buf := make([]byte, 5)
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				errChan <- err
+				return
+			}
+			_, err = conn.Write(buf)
+			errChan <- err
+		}()
+
+		conn, err := DialNamedPipe(ctx, addr)
+		if err != nil {
+			t.Fatalf("unable to dial named pipe: %v", err)
+		}
+
+		msg := []byte(fmt.Sprintf("ping%d", i))
+		if _, err := conn.Write(msg); err != nil {
+			t.Fatalf("unable to write to pipe: %v", err)
+		}
+		reply := make([]byte, len(msg))
+		if _, err := io.ReadFull(conn, reply); err != nil {
+			t.Fatalf("unable to read from pipe: %v", err)
+		}
+		if !bytes.Equal(reply, msg) {
+			t.Fatalf("expected reply %q, got %q", msg, reply)
+		}
+
+		if err := <-errChan; err != nil {
+			t.Fatalf("server side of the pipe failed: %v", err)
+		}
+
+		// The server closed its side of the connection.
+		if _, err := conn.Read(reply); err != io.EOF {
+			t.Fatalf("expected EOF, got %v", err)
+		}
+		conn.Close()
+
+		if conn.LocalAddr().String() != addr.Path {
+			t.Fatalf("expected address %v, got %v", addr.Path,
+				conn.LocalAddr())
+		}
+	}
+}
+
+// TestNamedPipeListenerClose tests that closing a listener unblocks a pending
+// Accept and removes the pipe.
+func TestNamedPipeListenerClose(t *testing.T) {
+	addr := testPipeAddr(t)
+
+	listener, err := ListenOnNamedPipe(addr)
+	if err != nil {
+		t.Fatalf("unable to listen on named pipe: %v", err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := listener.Accept()
+		errChan <- err
+	}()
+
+	// Give Accept a chance to block on the pipe before closing it.
+	time.Sleep(100 * time.Millisecond)
+	if err := listener.Close(); err != nil {
+		t.Fatalf("unable to close listener: %v", err)
+	}
+
+	select {
+	case err := <-errChan:
+		if err != errPipeClosed {
+			t.Fatalf("expected %v, got %v", errPipeClosed, err)
+		}
+
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Accept not unblocked by Close")
+	}
+
+	if err := listener.Close(); err != errPipeClosed {
+		t.Fatalf("expected %v, got %v", errPipeClosed, err)
+	}
+
+	// No instance of the pipe is left to dial.
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 100*time.Millisecond,
+	)
+	defer cancel()
+	if conn, err := DialNamedPipe(ctx, addr); err == nil {
+		conn.Close()
+		t.Fatalf("expected dialing a closed pipe to fail")
+	}
+}
@@ -0,0 +1,84 @@
+package lncfg
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RPCLimits holds the configuration options for limiting the number of
+// concurrent executions of individual RPC methods.
+type RPCLimits struct {
+	Limits []string `long:"limit" description:"Limit the number of concurrent executions of an RPC method, in the format <method>:<max concurrent calls>. The method is either a full gRPC method name such as /lnrpc.Lightning/DescribeGraph, or a bare method name such as DescribeGraph which limits the calls to that method of all services together. For streaming RPCs, the limit applies to the number of open streams. Can be specified multiple times."`
+
+	QueueTimeout time.Duration `long:"queuetimeout" description:"The maximum time a call to a limited RPC method waits for one of the executions in progress to finish before it's rejected. A value of 0 rejects the call right away if the limit is reached."`
+
+	// limits holds the parsed Limits, keyed by method.
+	limits map[string]int
+}
+
+// Validate parses the configured RPC method limits.
+func (r *RPCLimits) Validate() error {
+	if r.QueueTimeout < 0 {
+		return fmt.Errorf("rpc limits queue timeout must not be " +
+			"negative")
+	}
+
+	r.limits = make(map[string]int, len(r.Limits))
+	for _, entry := range r.Limits {
+		method, limit, err := parseRPCLimit(entry)
+		if err != nil {
+			return err
+		}
+
+		if _, ok := r.limits[method]; ok {
+			return fmt.Errorf("duplicate rpc limit for method %v",
+				method)
+		}
+		r.limits[method] = limit
+	}
+
+	return nil
+}
+
+// parseRPCLimit parses an RPC method limit of the form
+// <method>:<max concurrent calls>.
+func parseRPCLimit(entry string) (string, int, error) {
+	idx := strings.LastIndex(entry, ":")
+	if idx <= 0 {
+		return "", 0, fmt.Errorf("invalid rpc limit %q, expected "+
+			"<method>:<max concurrent calls>", entry)
+	}
+
+	method := entry[:idx]
+	if strings.HasPrefix(method, "/") {
+		parts := strings.Split(method, "/")
+		if len(parts) != 3 || parts[1] == "" || parts[2] == "" {
+			return "", 0, fmt.Errorf("invalid method in rpc "+
+				"limit %q, expected /<service>/<method>",
+				entry)
+		}
+	} else if strings.Contains(method, "/") {
+		return "", 0, fmt.Errorf("invalid method in rpc limit %q, "+
+			"full method names must start with /", entry)
+	}
+
+	limit, err := strconv.Atoi(entry[idx+1:])
+	if err != nil || limit <= 0 {
+		return "", 0, fmt.Errorf("invalid max concurrent calls in "+
+			"rpc limit %q, must be positive", entry)
+	}
+
+	return method, limit, nil
+}
+
+// MethodLimits returns the maximum number of concurrent calls of the limited
+// RPC methods, keyed by either the full or the bare method name.
+func (r *RPCLimits) MethodLimits() map[string]int {
+	return r.limits
+}
+
+// Compile-time constraint to ensure RPCLimits implements the Validator
+// interface.
+var _ Validator = (*RPCLimits)(nil)
@@ -0,0 +1,46 @@
+package lncfg_test
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/lncfg"
+)
+
+// TestRPCLimits asserts that RPC method limits are parsed from either full or
+// bare method names, and that invalid limits are rejected.
+func TestRPCLimits(t *testing.T) {
+	cfg := &lncfg.RPCLimits{
+		Limits: []string{
+			"/lnrpc.Lightning/DescribeGraph:1",
+			"SendPaymentV2:4",
+		},
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("unable to validate config: %v", err)
+	}
+
+	limits := cfg.MethodLimits()
+	if len(limits) != 2 || limits["/lnrpc.Lightning/DescribeGraph"] != 1 ||
+		limits["SendPaymentV2"] != 4 {
+
+		t.Fatalf("unexpected limits: %v", limits)
+	}
+
+	invalid := [][]string{
+		{"DescribeGraph"},
+		{":1"},
+		{"DescribeGraph:0"},
+		{"DescribeGraph:-1"},
+		{"DescribeGraph:x"},
+		{"lnrpc.Lightning/DescribeGraph:1"},
+		{"/lnrpc.Lightning:1"},
+		{"/lnrpc.Lightning/:1"},
+		{"DescribeGraph:1", "DescribeGraph:2"},
+	}
+	for _, limits := range invalid {
+		cfg := &lncfg.RPCLimits{Limits: limits}
+		if err := cfg.Validate(); err == nil {
+			t.Fatalf("expected limits %v to be rejected", limits)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package lncfg
+
+import "fmt"
+
+// DefaultSweptFundsMinConfs is the default number of confirmations the funds
+// returned to the wallet by closing channels need before they're used for
+// coin selection again.
+const DefaultSweptFundsMinConfs = 1
+
+// SweptFunds holds the configuration options that control when funds returned
+// to the wallet by closing channels become available for coin selection.
+type SweptFunds struct {
+	CloseConfs uint32 `long:"closeconfs" description:"The number of confirmations the outputs of a channel closing transaction that pay to our wallet need before they're used to fund new transactions or channels."`
+
+	SweepConfs uint32 `long:"sweepconfs" description:"The number of confirmations the outputs of a sweep transaction (e.g. of commitment or htlc outputs) need before they're used to fund new transactions or channels."`
+}
+
+// Validate checks the values configured for the swept funds.
+func (s *SweptFunds) Validate() error {
+	if s.CloseConfs < 1 {
+		return fmt.Errorf("swept funds close confs: %v must be at "+
+			"least 1", s.CloseConfs)
+	}
+
+	if s.SweepConfs < 1 {
+		return fmt.Errorf("swept funds sweep confs: %v must be at "+
+			"least 1", s.SweepConfs)
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure SweptFunds implements the Validator
+// interface.
+var _ Validator = (*SweptFunds)(nil)
@@ -0,0 +1,40 @@
+package lncfg
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultWalletLockDrainTimeout is the default time we wait for the
+	// channel funding flows in progress to complete before locking the
+	// wallet.
+	DefaultWalletLockDrainTimeout = time.Minute
+)
+
+// WalletLock holds the configuration options for automatically locking the
+// wallet after a period without activity requiring its private keys.
+type WalletLock struct {
+	IdleTimeout time.Duration `long:"idletimeout" description:"Lock the wallet once no RPC call requiring its private keys (such as sending coins, opening channels or making payments) was made for this long. Only the on-chain funds are locked: the private keys of the channels, the node key, the backup key and the tower session keys remain in memory and usable by the node so that commitment updates, justice transactions and htlc sweeps are still signed. Calls spending funds, the autopilot agent and sweeps needing wallet inputs are paused until the wallet is unlocked again with the unlock command, which doesn't require a restart. The lock is postponed while force closed channels are unresolved. Only supported by the embedded wallet. A value of 0 disables the automatic locking."`
+
+	DrainTimeout time.Duration `long:"draintimeout" description:"The maximum time to wait for the channel funding flows in progress to complete before the wallet is locked. Fundings still in progress afterwards fail."`
+}
+
+// Validate checks the values configured for the wallet locking.
+func (w *WalletLock) Validate() error {
+	if w.IdleTimeout < 0 {
+		return errors.New("walletlock.idletimeout must not be " +
+			"negative")
+	}
+
+	if w.DrainTimeout < 0 {
+		return errors.New("walletlock.draintimeout must not be " +
+			"negative")
+	}
+
+	return nil
+}
+
+// Compile-time constraint to ensure WalletLock implements the Validator
+// interface.
+var _ Validator = (*WalletLock)(nil)
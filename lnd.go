@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"runtime/pprof"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -37,6 +38,8 @@ import (
 	"github.com/decred/dcrlnd/cert"
 	"github.com/decred/dcrlnd/chanacceptor"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/crashreport"
+	"github.com/decred/dcrlnd/extsigner"
 	"github.com/decred/dcrlnd/keychain"
 	"github.com/decred/dcrlnd/lncfg"
 	"github.com/decred/dcrlnd/lnrpc"
@@ -60,6 +63,7 @@ func WalletUnlockerAuthOptions(cfg *Config) ([]grpc.DialOption, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to read TLS cert: %v", err)
 	}
+	creds = lncfg.LocalConnCredentials(creds)
 
 	// Create a dial options array with the TLS credentials.
 	opts := []grpc.DialOption{
@@ -79,6 +83,7 @@ func AdminAuthOptions(cfg *Config) ([]grpc.DialOption, error) {
 	if err != nil {
 		return nil, fmt.Errorf("unable to read TLS cert: %v", err)
 	}
+	creds = lncfg.LocalConnCredentials(creds)
 
 	// Create a dial options array.
 	opts := []grpc.DialOption{
@@ -194,14 +199,26 @@ type rpcListeners func() ([]*ListenerWithSignal, func(), error)
 // This function starts all main system components then blocks until a signal
 // is received on the shutdownChan at which point everything is shut down again.
 func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
+	teardown := newForcedTeardown(ltndLog, cfg.ShutdownGrace, os.Exit)
 	defer func() {
 		ltndLog.Info("Shutdown complete")
+		teardown.stop()
 		err := cfg.LogWriter.Close()
 		if err != nil {
 			ltndLog.Errorf("Could not close log rotator: %v", err)
 		}
 	}()
 
+	// Keep track of the most recent log lines, so a report including them
+	// is recorded if we panic. The last report remains available through
+	// the RPC server after a restart.
+	crashReporter := crashreport.New(
+		cfg.LogDir, crashreport.DefaultNumRecentEvents,
+	)
+	cfg.LogWriter.SetLogTee(crashReporter)
+	crashreport.UseReporter(crashReporter)
+	defer crashreport.Recover("LTND")
+
 	// Show version at startup.
 	ltndLog.Infof("Version: %s, build=%s, logging=%s",
 		build.Version(), build.Deployment, build.LoggingType)
@@ -272,17 +289,6 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	localChanDB, remoteChanDB, cleanUp, err := initializeDatabases(ctx, cfg)
-	switch {
-	case err == channeldb.ErrDryRunMigrationOK:
-		ltndLog.Infof("%v, exiting", err)
-		return nil
-	case err != nil:
-		return fmt.Errorf("unable to open databases: %v", err)
-	}
-
-	defer cleanUp()
-
 	// Only process macaroons if --no-macaroons isn't set.
 	tlsCfg, restCreds, restProxyDest, err := getTLSConfig(cfg)
 	if err != nil {
@@ -291,9 +297,23 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 		return err
 	}
 
-	serverCreds := credentials.NewTLS(tlsCfg)
+	// Connections over unix sockets and named pipes skip TLS, as access to
+	// them is restricted by their permissions.
+	serverCreds := lncfg.LocalConnCredentials(credentials.NewTLS(tlsCfg))
 	serverOpts := []grpc.ServerOption{grpc.Creds(serverCreds)}
 
+	// If enabled, register the gzip compressor so clients can request
+	// compressed messages from the gRPC server.
+	if cfg.Compression.GRPC {
+		err := lnrpc.RegisterGzipCompressor(cfg.Compression.Level)
+		if err != nil {
+			err := fmt.Errorf("unable to register gRPC "+
+				"compressor: %v", err)
+			ltndLog.Error(err)
+			return err
+		}
+	}
+
 	// For our REST dial options, we'll still use TLS, but also increase
 	// the max message size that we'll decode to allow clients to hit
 	// endpoints which return more data such as the DescribeGraph call.
@@ -301,6 +321,9 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	// in cmd/lncli/main.go.
 	restDialOpts := []grpc.DialOption{
 		grpc.WithTransportCredentials(*restCreds),
+		grpc.WithContextDialer(
+			lncfg.ClientAddressDialer(strconv.Itoa(defaultRPCPort)),
+		),
 		grpc.WithDefaultCallOptions(
 			grpc.MaxCallRecvMsgSize(1 * 1024 * 1024 * 200),
 		),
@@ -326,8 +349,26 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 		var grpcListeners []*ListenerWithSignal
 		for _, grpcEndpoint := range cfg.RPCListeners {
 			// Start a gRPC server listening for HTTP/2
-			// connections.
-			lis, err := lncfg.ListenOnAddress(grpcEndpoint)
+			// connections. Access to unix sockets is restricted
+			// by their file permissions, and access to named
+			// pipes to the current user.
+			var (
+				lis net.Listener
+				err error
+			)
+			switch {
+			case lncfg.IsUnix(grpcEndpoint):
+				lis, err = lncfg.ListenOnUnixSocket(
+					grpcEndpoint,
+					os.FileMode(cfg.RPCSocketPerms),
+				)
+
+			case lncfg.IsNamedPipe(grpcEndpoint):
+				lis, err = lncfg.ListenOnNamedPipe(grpcEndpoint)
+
+			default:
+				lis, err = lncfg.ListenOnAddress(grpcEndpoint)
+			}
 			if err != nil {
 				ltndLog.Errorf("unable to listen on %s",
 					grpcEndpoint)
@@ -365,13 +406,18 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 		return getListeners()
 	}
 
-	// We wait until the user provides a password over RPC. In case lnd is
-	// started with the --noseedbackup flag, we use the default password
-	// for wallet encryption.
+	// If we'll need a password from the user, we start the wallet
+	// unlocker before opening the databases, so the progress of any
+	// lengthy database migrations can be tracked through it.
+	var (
+		pwService    *walletunlocker.UnlockerService
+		stopUnlocker = func() {}
+	)
 	if !cfg.NoSeedBackup || isRemoteWallet {
-		params, err := waitForWalletPassword(
+		var unlockerCleanUp func()
+		pwService, unlockerCleanUp, err = startWalletUnlocker(
 			cfg, cfg.RESTListeners, serverOpts, restDialOpts,
-			restProxyDest, tlsCfg, walletUnlockerListeners, remoteChanDB,
+			restProxyDest, tlsCfg, walletUnlockerListeners,
 		)
 		if err != nil {
 			err := fmt.Errorf("unable to set up wallet password "+
@@ -380,6 +426,52 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 			return err
 		}
 
+		var stopOnce sync.Once
+		stopUnlocker = func() {
+			stopOnce.Do(unlockerCleanUp)
+		}
+	}
+	defer func() {
+		stopUnlocker()
+	}()
+
+	var migrationProgress func(channeldb.MigrationProgress)
+	if pwService != nil {
+		migrationProgress = pwService.MigrationProgress
+	}
+	localChanDB, remoteChanDB, cleanUp, err := initializeDatabases(
+		ctx, cfg, migrationProgress,
+	)
+	switch {
+	case err == channeldb.ErrDryRunMigrationOK:
+		ltndLog.Infof("%v, exiting", err)
+		return nil
+	case err != nil:
+		return fmt.Errorf("unable to open databases: %v", err)
+	}
+
+	defer cleanUp()
+
+	// We wait until the user provides a password over RPC. In case lnd is
+	// started with the --noseedbackup flag, we use the default password
+	// for wallet encryption.
+	if pwService != nil {
+		// Now that the database is ready, the wallet may be unlocked.
+		pwService.SetChanDB(remoteChanDB)
+
+		params, err := waitForWalletPassword(cfg, pwService)
+
+		// The unlocker is no longer needed, so we stop it right away
+		// to free up the listeners for the main RPC server.
+		stopUnlocker()
+
+		if err != nil {
+			err := fmt.Errorf("unable to set up wallet password "+
+				"listeners: %v", err)
+			ltndLog.Error(err)
+			return err
+		}
+
 		walletInitParams = *params
 		privateWalletPw = walletInitParams.Password
 		publicWalletPw = walletInitParams.Password
@@ -389,6 +481,11 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 				"address lookahead of %d addresses",
 				walletInitParams.RecoveryWindow)
 		}
+
+		if walletInitParams.RescanFromHeight > 0 {
+			ltndLog.Infof("Wallet rescan starting at height %d",
+				walletInitParams.RescanFromHeight)
+		}
 	}
 
 	var macaroonService *macaroons.Service
@@ -396,6 +493,9 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 		// Create the macaroon authentication/authorization service.
 		macaroonService, err = macaroons.NewService(
 			cfg.networkDir, "lnd", macaroons.IPLockChecker,
+			macaroons.NewBudgetChecker(
+				budgetEnforcedMethods, budgetSpendingOps,
+			),
 		)
 		if err != nil {
 			err := fmt.Errorf("unable to set up macaroon "+
@@ -440,7 +540,8 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	activeChainControl, err := newChainControlFromConfig(
 		cfg, localChanDB, remoteChanDB, privateWalletPw, publicWalletPw,
 		walletInitParams.Birthday, walletInitParams.RecoveryWindow,
-		walletInitParams.Wallet, walletInitParams.Loader,
+		walletInitParams.RescanFromHeight, walletInitParams.Wallet,
+		walletInitParams.Loader,
 		walletInitParams.Conn, cfg.Dcrwallet.AccountNumber,
 	)
 	if err != nil {
@@ -498,6 +599,60 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 		return err
 	}
 
+	// The identity key is used for the peer handshake, onion processing
+	// and gossip signing. Unless it is held by an external signer, it is
+	// derived from the wallet seed.
+	var (
+		nodeKeyECDH   keychain.SingleKeyECDH
+		nodeKeySigner keychain.SingleKeyDigestSigner
+	)
+	if !cfg.IdentitySigner.Active {
+		nodeKeyECDH = keychain.NewPubKeyECDH(
+			idKeyDesc, activeChainControl.keyRing,
+		)
+		nodeKeySigner = keychain.NewPubKeyDigestSigner(
+			idKeyDesc, activeChainControl.keyRing,
+		)
+	} else {
+		signerAddr, err := lncfg.ParseAddressString(
+			cfg.IdentitySigner.RPCHost, "", cfg.net.ResolveTCPAddr,
+		)
+		if err != nil {
+			err := fmt.Errorf("invalid identity signer address: %v",
+				err)
+			ltndLog.Error(err)
+			return err
+		}
+
+		extSigner, err := extsigner.Connect(extsigner.Config{
+			Addr:    signerAddr,
+			Timeout: cfg.IdentitySigner.Timeout,
+		})
+		if err != nil {
+			err := fmt.Errorf("unable to connect to identity "+
+				"signer: %v", err)
+			ltndLog.Error(err)
+			return err
+		}
+		defer extSigner.Close()
+
+		nodeKeyECDH = extSigner
+		nodeKeySigner = extSigner
+	}
+
+	// Switching between the wallet and external signer identity keys
+	// changes the identity of the node, which would strand its channels.
+	err = checkIdentityKeySwitch(
+		nodeKeyECDH.PubKey(), localChanDB.ChannelGraph().SourceNode,
+		func() (int, error) {
+			return numUnresolvedChannels(remoteChanDB)
+		},
+	)
+	if err != nil {
+		ltndLog.Error(err)
+		return err
+	}
+
 	if cfg.Tor.Active {
 		srvrLog.Infof("Proxying all network traffic via Tor "+
 			"(stream_isolation=%v)! NOTE: Ensure the backend node "+
@@ -626,8 +781,8 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	// connections.
 	server, err := newServer(
 		cfg, cfg.Listeners, localChanDB, remoteChanDB, towerClientDB,
-		activeChainControl, &idKeyDesc, walletInitParams.ChansToRestore,
-		chainedAcceptor, torController,
+		activeChainControl, nodeKeyECDH, nodeKeySigner,
+		walletInitParams.ChansToRestore, chainedAcceptor, torController,
 	)
 	if err != nil {
 		err := fmt.Errorf("unable to create server: %v", err)
@@ -700,6 +855,12 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	}
 	defer server.Stop()
 
+	// In development builds, the node may be seeded with channels and
+	// invoices from a fixture file.
+	if cfg.fixtures != nil {
+		go rpcServer.applyNodeFixtures(cfg.fixtures)
+	}
+
 	// Now that the server has started, if the autopilot mode is currently
 	// active, then we'll start the autopilot agent immediately. It will be
 	// stopped together with the autopilot service.
@@ -724,6 +885,10 @@ func Main(cfg *Config, lisCfg ListenerCfg, shutdownChan <-chan struct{}) error {
 	// Wait for shutdown signal from either a graceful server stop or from
 	// the interrupt handler.
 	<-shutdownChan
+
+	// Once the shutdown is requested, the deferred calls above are given
+	// the grace period to complete before the process is terminated.
+	teardown.start()
 	return nil
 }
 
@@ -809,9 +974,18 @@ func getTLSConfig(cfg *Config) (*tls.Config, *credentials.TransportCredentials,
 	if err != nil {
 		return nil, nil, "", err
 	}
+	restCreds = lncfg.LocalConnCredentials(restCreds)
 
 	restProxyDest := cfg.RPCListeners[0].String()
 	switch {
+	// The REST proxy dials unix sockets and named pipes through the client
+	// address dialer, which expects their scheme.
+	case lncfg.IsUnix(cfg.RPCListeners[0]),
+		lncfg.IsNamedPipe(cfg.RPCListeners[0]):
+
+		restProxyDest = cfg.RPCListeners[0].Network() + "://" +
+			restProxyDest
+
 	case strings.Contains(restProxyDest, "0.0.0.0"):
 		restProxyDest = strings.Replace(
 			restProxyDest, "0.0.0.0", "127.0.0.1", 1,
@@ -912,6 +1086,11 @@ type WalletUnlockParams struct {
 	// mode. A recovery will be attempted if this value is non-zero.
 	RecoveryWindow uint32
 
+	// RescanFromHeight specifies the block height from which the wallet is
+	// rescanned for used addresses. Blocks below it are skipped if
+	// non-zero.
+	RescanFromHeight int32
+
 	// Wallet is the loaded and unlocked Wallet. This is returned
 	// from the unlocker service to avoid it being unlocked twice (once in
 	// the unlocker service to check if the password is correct and again
@@ -932,55 +1111,72 @@ type WalletUnlockParams struct {
 	ChansToRestore walletunlocker.ChannelsToRecover
 }
 
-// waitForWalletPassword will spin up gRPC and REST endpoints for the
-// WalletUnlocker server, and block until a password is provided by
-// the user to this RPC server.
-func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
+// startWalletUnlocker will spin up gRPC and REST endpoints for the
+// WalletUnlocker server, solely used for getting the encryption password from
+// the client. The returned closure stops the servers and must be called once
+// the password has been obtained.
+func startWalletUnlocker(cfg *Config, restEndpoints []net.Addr,
 	serverOpts []grpc.ServerOption, restDialOpts []grpc.DialOption,
 	restProxyDest string, tlsConf *tls.Config,
-	getListeners rpcListeners, chanDB *channeldb.DB) (*WalletUnlockParams, error) {
+	getListeners rpcListeners) (*walletunlocker.UnlockerService, func(),
+	error) {
+
+	// cleanUpFuncs collects the functions that tear down everything set
+	// up below. They're executed in reverse order.
+	var cleanUpFuncs []func()
+	cleanUp := func() {
+		for i := len(cleanUpFuncs) - 1; i >= 0; i-- {
+			cleanUpFuncs[i]()
+		}
+	}
 
 	// Start a gRPC server listening for HTTP/2 connections, solely used
 	// for getting the encryption password from the client.
-	listeners, cleanup, err := getListeners()
+	listeners, lisCleanup, err := getListeners()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
-	defer cleanup()
+	cleanUpFuncs = append(cleanUpFuncs, lisCleanup)
 
 	// Set up a new PasswordService, which will listen for passwords
 	// provided over RPC.
 	grpcServer := grpc.NewServer(serverOpts...)
-	defer func() {
-		// Unfortunately the grpc lib does not offer any external
-		// method to check if there are existing connections and while
-		// it claims GracefulStop() will wait for outstanding RPC calls
-		// to finish, some client libraries (specifically: grpc-js used
-		// in nodejs/Electron apps) have trouble when the connection is
-		// closed before the response to the Unlock() call is
-		// completely processed. So we add a delay here to ensure
-		// there's enough time before closing the grpc listener for any
-		// clients to finish processing.
-		time.Sleep(100 * time.Millisecond)
-		grpcServer.GracefulStop()
-	}()
 
 	// The macaroon files are passed to the wallet unlocker since they are
 	// also encrypted with the wallet's password. These files will be
 	// deleted within it and recreated when successfully changing the
-	// wallet's password.
+	// wallet's password. The database is provided once it has been
+	// opened and migrated.
 	macaroonFiles := []string{
 		filepath.Join(cfg.networkDir, macaroons.DBFilename),
 		cfg.AdminMacPath, cfg.ReadMacPath, cfg.InvoiceMacPath,
 	}
 	pwService := walletunlocker.New(
 		cfg.ChainDir, activeNetParams.Params, !cfg.SyncFreelist,
-		macaroonFiles, chanDB, cfg.Dcrwallet.GRPCHost, cfg.Dcrwallet.CertPath,
+		macaroonFiles, nil, cfg.Dcrwallet.GRPCHost, cfg.Dcrwallet.CertPath,
 		cfg.Dcrwallet.ClientKeyPath, cfg.Dcrwallet.ClientCertPath,
 		cfg.Dcrwallet.AccountNumber,
 	)
 	lnrpc.RegisterWalletUnlockerServer(grpcServer, pwService)
 
+	cleanUpFuncs = append(cleanUpFuncs, func() {
+		// Unfortunately the grpc lib does not offer any external
+		// method to check if there are existing connections and while
+		// it claims GracefulStop() will wait for outstanding RPC calls
+		// to finish, some client libraries (specifically: grpc-js used
+		// in nodejs/Electron apps) have trouble when the connection is
+		// closed before the response to the Unlock() call is
+		// completely processed. So we add a delay here to ensure
+		// there's enough time before closing the grpc listener for any
+		// clients to finish processing.
+		time.Sleep(100 * time.Millisecond)
+
+		// Any migration status subscriptions must exit before the
+		// server can gracefully stop.
+		pwService.Stop()
+		grpcServer.GracefulStop()
+	})
+
 	// Use a WaitGroup so we can be sure the instructions on how to input the
 	// password is the last thing to be printed to the console.
 	var wg sync.WaitGroup
@@ -1002,7 +1198,7 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 	// Start a REST proxy for our gRPC server above.
 	ctx := context.Background()
 	ctx, cancel := context.WithCancel(ctx)
-	defer cancel()
+	cleanUpFuncs = append(cleanUpFuncs, cancel)
 
 	mux := proxy.NewServeMux()
 
@@ -1010,10 +1206,13 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 		ctx, mux, restProxyDest, restDialOpts,
 	)
 	if err != nil {
-		return nil, err
+		cleanUp()
+		return nil, nil, err
 	}
 
-	srv := &http.Server{Handler: allowCORS(mux, cfg.RestCORS)}
+	srv := &http.Server{Handler: trustProxyHeaders(
+		allowCORS(mux, cfg.RestCORS), cfg.restTrustedProxies,
+	)}
 
 	for _, restEndpoint := range restEndpoints {
 		lis, err := lncfg.TLSListenOnAddress(restEndpoint, tlsConf)
@@ -1022,9 +1221,12 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 				"password gRPC proxy unable to listen on %s",
 				restEndpoint,
 			)
-			return nil, err
+			cleanUp()
+			return nil, nil, err
 		}
-		defer lis.Close()
+		cleanUpFuncs = append(cleanUpFuncs, func() {
+			lis.Close()
+		})
 
 		wg.Add(1)
 		go func() {
@@ -1040,6 +1242,14 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 	// Wait for gRPC and REST servers to be up running.
 	wg.Wait()
 
+	return pwService, cleanUp, nil
+}
+
+// waitForWalletPassword blocks until a password is provided by the user to
+// the WalletUnlocker server.
+func waitForWalletPassword(cfg *Config,
+	pwService *walletunlocker.UnlockerService) (*WalletUnlockParams, error) {
+
 	// Wait for user to provide the password.
 	ltndLog.Infof("Waiting for wallet encryption password. Use `dcrlncli " +
 		"create` to create a wallet, `dcrlncli unlock` to unlock an " +
@@ -1074,7 +1284,7 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 			cfg.ChainDir, activeNetParams.Params,
 		)
 		loader := walletloader.NewLoader(activeNetParams.Params, netDir,
-			wallet.DefaultGapLimit)
+			recoveryWindow)
 
 		// With the seed, we can now use the wallet loader to create
 		// the wallet, then pass it back to avoid unlocking it again.
@@ -1094,24 +1304,26 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 		}
 
 		return &WalletUnlockParams{
-			Password:       password,
-			Birthday:       birthday,
-			RecoveryWindow: recoveryWindow,
-			Wallet:         newWallet,
-			Loader:         loader,
-			ChansToRestore: initMsg.ChanBackups,
+			Password:         password,
+			Birthday:         birthday,
+			RecoveryWindow:   recoveryWindow,
+			RescanFromHeight: initMsg.RescanFromHeight,
+			Wallet:           newWallet,
+			Loader:           loader,
+			ChansToRestore:   initMsg.ChanBackups,
 		}, nil
 
 	// The wallet has already been created in the past, and is simply being
 	// unlocked. So we'll just return these passphrases.
 	case unlockMsg := <-pwService.UnlockMsgs:
 		return &WalletUnlockParams{
-			Password:       unlockMsg.Passphrase,
-			RecoveryWindow: unlockMsg.RecoveryWindow,
-			Wallet:         unlockMsg.Wallet,
-			Loader:         unlockMsg.Loader,
-			ChansToRestore: unlockMsg.ChanBackups,
-			Conn:           unlockMsg.Conn,
+			Password:         unlockMsg.Passphrase,
+			RecoveryWindow:   unlockMsg.RecoveryWindow,
+			RescanFromHeight: unlockMsg.RescanFromHeight,
+			Wallet:           unlockMsg.Wallet,
+			Loader:           unlockMsg.Loader,
+			ChansToRestore:   unlockMsg.ChanBackups,
+			Conn:             unlockMsg.Conn,
 		}, nil
 
 	case <-signal.ShutdownChannel():
@@ -1125,8 +1337,9 @@ func waitForWalletPassword(cfg *Config, restEndpoints []net.Addr,
 // database point to a unique database. Otherwise, the local and remote DB will
 // both point to the same local database. A function closure that closes all
 // opened databases is also returned.
-func initializeDatabases(ctx context.Context,
-	cfg *Config) (*channeldb.DB, *channeldb.DB, func(), error) {
+func initializeDatabases(ctx context.Context, cfg *Config,
+	migrationProgress func(channeldb.MigrationProgress)) (*channeldb.DB,
+	*channeldb.DB, func(), error) {
 
 	ltndLog.Infof("Opening the main database, this might take a few " +
 		"minutes...")
@@ -1160,6 +1373,7 @@ func initializeDatabases(ctx context.Context,
 			channeldb.OptionSetRejectCacheSize(cfg.Caches.RejectCacheSize),
 			channeldb.OptionSetChannelCacheSize(cfg.Caches.ChannelCacheSize),
 			channeldb.OptionDryRunMigration(cfg.DryRunMigration),
+			channeldb.OptionMigrationProgress(migrationProgress),
 		)
 		switch {
 		case err == channeldb.ErrDryRunMigrationOK:
@@ -1188,6 +1402,7 @@ func initializeDatabases(ctx context.Context,
 			channeldb.OptionSetRejectCacheSize(cfg.Caches.RejectCacheSize),
 			channeldb.OptionSetChannelCacheSize(cfg.Caches.ChannelCacheSize),
 			channeldb.OptionDryRunMigration(cfg.DryRunMigration),
+			channeldb.OptionMigrationProgress(migrationProgress),
 		)
 		switch {
 		// As we want to allow both versions to get thru the dry run
@@ -1211,6 +1426,7 @@ func initializeDatabases(ctx context.Context,
 		remoteChanDB, err = channeldb.CreateWithBackend(
 			databaseBackends.RemoteDB,
 			channeldb.OptionDryRunMigration(cfg.DryRunMigration),
+			channeldb.OptionMigrationProgress(migrationProgress),
 		)
 		switch {
 		case err == channeldb.ErrDryRunMigrationOK:
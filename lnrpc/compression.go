@@ -0,0 +1,235 @@
+package lnrpc
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// GzipCompressorName is the name of the gzip compressor registered with gRPC.
+// It matches the name used by the compressor of grpc-go, so clients can
+// request it through grpc.UseCompressor(gzip.Name).
+const GzipCompressorName = "gzip"
+
+// gzipCompressor implements the encoding.Compressor interface with a pool of
+// gzip writers of the configured compression level.
+type gzipCompressor struct {
+	writers sync.Pool
+	readers sync.Pool
+}
+
+// pooledGzipWriter returns the gzip writer to its pool once it's closed.
+type pooledGzipWriter struct {
+	*gzip.Writer
+	pool *sync.Pool
+}
+
+// Close flushes the remaining compressed data and returns the writer to its
+// pool.
+func (w *pooledGzipWriter) Close() error {
+	defer w.pool.Put(w)
+	return w.Writer.Close()
+}
+
+// pooledGzipReader returns the gzip reader to its pool once all data was read.
+type pooledGzipReader struct {
+	*gzip.Reader
+	pool *sync.Pool
+}
+
+// Read reads decompressed data, returning the reader to its pool once the end
+// of the compressed stream is reached.
+func (r *pooledGzipReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if err == io.EOF {
+		r.pool.Put(r)
+	}
+	return n, err
+}
+
+// Compress returns a writer that compresses the data written to it into w.
+//
+// NOTE: This is part of the encoding.Compressor interface.
+func (c *gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	gzw := c.writers.Get().(*pooledGzipWriter)
+	gzw.Reset(w)
+	return gzw, nil
+}
+
+// Decompress returns a reader that decompresses the data read from r.
+//
+// NOTE: This is part of the encoding.Compressor interface.
+func (c *gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	gzr, ok := c.readers.Get().(*pooledGzipReader)
+	if !ok {
+		newReader, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		return &pooledGzipReader{Reader: newReader, pool: &c.readers}, nil
+	}
+
+	if err := gzr.Reset(r); err != nil {
+		c.readers.Put(gzr)
+		return nil, err
+	}
+	return gzr, nil
+}
+
+// Name returns the name of the compressor.
+//
+// NOTE: This is part of the encoding.Compressor interface.
+func (c *gzipCompressor) Name() string {
+	return GzipCompressorName
+}
+
+// RegisterGzipCompressor registers a gzip compressor of the given compression
+// level with gRPC. Once registered, the gRPC server accepts gzip compressed
+// requests and compresses its responses to them. This must be called before
+// the gRPC server is started.
+func RegisterGzipCompressor(level int) error {
+	// Make sure the level is valid before handing out writers.
+	if _, err := gzip.NewWriterLevel(ioutil.Discard, level); err != nil {
+		return err
+	}
+
+	c := &gzipCompressor{}
+	c.writers.New = func() interface{} {
+		w, _ := gzip.NewWriterLevel(ioutil.Discard, level)
+		return &pooledGzipWriter{Writer: w, pool: &c.writers}
+	}
+	encoding.RegisterCompressor(c)
+
+	return nil
+}
+
+// gzipResponseWriter compresses the body of a response written through it.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gzw *gzip.Writer
+
+	wroteHeader bool
+	compress    bool
+}
+
+// WriteHeader sets the headers of a compressed response before writing them.
+// Responses that can't have a body aren't compressed.
+func (w *gzipResponseWriter) WriteHeader(statusCode int) {
+	if w.wroteHeader {
+		return
+	}
+	w.wroteHeader = true
+
+	if statusCode != http.StatusNoContent &&
+		statusCode != http.StatusNotModified {
+
+		w.compress = true
+
+		header := w.Header()
+		header.Set("Content-Encoding", "gzip")
+		header.Del("Content-Length")
+	}
+
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write compresses the data into the response body.
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(b)
+	}
+
+	return w.gzw.Write(b)
+}
+
+// Flush writes the data compressed so far to the client, so streamed
+// responses aren't held back.
+func (w *gzipResponseWriter) Flush() {
+	if w.compress {
+		_ = w.gzw.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close writes the remaining compressed data of the response.
+func (w *gzipResponseWriter) close() error {
+	if !w.compress {
+		return nil
+	}
+
+	return w.gzw.Close()
+}
+
+// NewGzipHandler wraps the given http.Handler with a handler that compresses
+// the responses with the given gzip compression level for clients that accept
+// it through the Accept-Encoding header. WebSocket requests are passed through
+// unmodified.
+func NewGzipHandler(h http.Handler, level int) http.Handler {
+	writers := sync.Pool{
+		New: func() interface{} {
+			w, _ := gzip.NewWriterLevel(ioutil.Discard, level)
+			return w
+		},
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(r.Header) || r.Header.Get("Upgrade") != "" {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		gzw := writers.Get().(*gzip.Writer)
+		gzw.Reset(w)
+		defer writers.Put(gzw)
+
+		// The response is compressed, so we don't forward the encoding
+		// the client accepts to the handlers down the chain.
+		r.Header.Del("Accept-Encoding")
+
+		gzrw := &gzipResponseWriter{ResponseWriter: w, gzw: gzw}
+		h.ServeHTTP(gzrw, r)
+
+		_ = gzrw.close()
+	})
+}
+
+// acceptsGzip returns true if the Accept-Encoding header lists gzip without
+// disallowing it through a zero quality value.
+func acceptsGzip(header http.Header) bool {
+	for _, value := range header["Accept-Encoding"] {
+		for _, encoding := range strings.Split(value, ",") {
+			parts := strings.Split(encoding, ";")
+			if strings.TrimSpace(parts[0]) != "gzip" {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				param = strings.TrimSpace(param)
+				if !strings.HasPrefix(param, "q=") {
+					continue
+				}
+
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil && q == 0 {
+					return false
+				}
+			}
+			return true
+		}
+	}
+
+	return false
+}
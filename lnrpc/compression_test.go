@@ -0,0 +1,143 @@
+package lnrpc
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// TestGzipHandler asserts that REST responses are only compressed for clients
+// that accept it.
+func TestGzipHandler(t *testing.T) {
+	t.Parallel()
+
+	body := bytes.Repeat([]byte("dcrlnd"), 1000)
+	handler := NewGzipHandler(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/empty" {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			_, _ = w.Write(body)
+		},
+	), gzip.BestSpeed)
+
+	tests := []struct {
+		name           string
+		path           string
+		acceptEncoding string
+		compressed     bool
+	}{{
+		name: "no accept encoding",
+		path: "/",
+	}, {
+		name:           "gzip not accepted",
+		path:           "/",
+		acceptEncoding: "deflate, br",
+	}, {
+		name:           "gzip disallowed",
+		path:           "/",
+		acceptEncoding: "gzip;q=0, deflate",
+	}, {
+		name:           "gzip accepted",
+		path:           "/",
+		acceptEncoding: "deflate, gzip;q=0.5",
+		compressed:     true,
+	}, {
+		name:           "no content",
+		path:           "/empty",
+		acceptEncoding: "gzip",
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", test.path, nil)
+			if test.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			contentEncoding := rec.Header().Get("Content-Encoding")
+			if test.compressed != (contentEncoding == "gzip") {
+				t.Fatalf("expected compressed=%v, got encoding %q",
+					test.compressed, contentEncoding)
+			}
+
+			respBody := rec.Body.Bytes()
+			if test.compressed {
+				gzr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("unable to read gzip: %v", err)
+				}
+				respBody, err = ioutil.ReadAll(gzr)
+				if err != nil {
+					t.Fatalf("unable to decompress: %v", err)
+				}
+			}
+
+			expected := body
+			if test.path == "/empty" {
+				expected = nil
+			}
+			if !bytes.Equal(respBody, expected) {
+				t.Fatalf("unexpected body of %d bytes",
+					len(respBody))
+			}
+		})
+	}
+}
+
+// TestGzipCompressor asserts that the registered gRPC compressor round trips
+// messages.
+func TestGzipCompressor(t *testing.T) {
+	if err := RegisterGzipCompressor(gzip.BestCompression); err != nil {
+		t.Fatalf("unable to register compressor: %v", err)
+	}
+	if err := RegisterGzipCompressor(42); err == nil {
+		t.Fatalf("expected invalid compression level to fail")
+	}
+
+	compressor := encoding.GetCompressor(GzipCompressorName)
+	if compressor == nil {
+		t.Fatalf("compressor not registered")
+	}
+
+	msg := bytes.Repeat([]byte("dcrlnd"), 1000)
+	for i := 0; i < 2; i++ {
+		var b bytes.Buffer
+		w, err := compressor.Compress(&b)
+		if err != nil {
+			t.Fatalf("unable to compress: %v", err)
+		}
+		if _, err := w.Write(msg); err != nil {
+			t.Fatalf("unable to write: %v", err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("unable to close: %v", err)
+		}
+		if b.Len() >= len(msg) {
+			t.Fatalf("message not compressed")
+		}
+
+		r, err := compressor.Decompress(&b)
+		if err != nil {
+			t.Fatalf("unable to decompress: %v", err)
+		}
+		decompressed, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatalf("unable to read: %v", err)
+		}
+		if !bytes.Equal(decompressed, msg) {
+			t.Fatalf("message not round tripped")
+		}
+	}
+}
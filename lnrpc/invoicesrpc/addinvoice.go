@@ -7,6 +7,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"time"
 
 	"github.com/davecgh/go-spew/spew"
@@ -22,6 +23,38 @@ import (
 	"github.com/decred/dcrlnd/zpay32"
 )
 
+// RouteHintsPolicy determines whether invoices that neither request nor opt
+// out of route hints include hints for our private channels.
+type RouteHintsPolicy uint8
+
+const (
+	// RouteHintsNever only includes route hints if they're requested.
+	RouteHintsNever RouteHintsPolicy = iota
+
+	// RouteHintsAlways includes route hints unless the caller opts out.
+	RouteHintsAlways
+
+	// RouteHintsAuto includes route hints unless the caller opts out, but
+	// only if we have no public channels through which we can be reached
+	// otherwise.
+	RouteHintsAuto
+)
+
+// Defaults holds the parameters used for a new invoice when the caller
+// doesn't specify them.
+type Defaults struct {
+	// CltvDelta is the final CLTV delta of the invoice. Zero falls back
+	// to DefaultCLTVExpiry.
+	CltvDelta uint32
+
+	// Expiry is the expiry of the invoice. Zero falls back to the default
+	// invoice expiry.
+	Expiry time.Duration
+
+	// RouteHints determines whether route hints are included.
+	RouteHints RouteHintsPolicy
+}
+
 // AddInvoiceConfig contains dependencies for invoice creation.
 type AddInvoiceConfig struct {
 	// AddInvoice is called to add the invoice to the registry.
@@ -43,6 +76,11 @@ type AddInvoiceConfig struct {
 	// specified.
 	DefaultCLTVExpiry uint32
 
+	// InvoiceDefaults, if set, returns the defaults to use for a standard
+	// or hold invoice created through the given context when the caller
+	// doesn't specify them.
+	InvoiceDefaults func(ctx context.Context, hold bool) Defaults
+
 	// ChanDB is a global bboltdb instance which is needed to access the
 	// channel graph.
 	ChanDB *channeldb.DB
@@ -88,9 +126,19 @@ type AddInvoiceData struct {
 	CltvExpiry uint64
 
 	// Whether this invoice should include routing hints for private
-	// channels.
+	// channels. If neither Private nor NoRouteHints is set, the configured
+	// defaults determine whether hints are included.
 	Private bool
 
+	// Whether this invoice should omit routing hints for private channels
+	// even if the configured defaults would include them.
+	NoRouteHints bool
+
+	// Whether this invoice may only be paid through the private channels
+	// of its routing hints, so that it can't be linked to our public
+	// channels. Implies Private.
+	PrivateRouteHintsOnly bool
+
 	// HodlInvoice signals that this invoice shouldn't be settled
 	// immediately upon receiving the payment.
 	HodlInvoice bool
@@ -145,6 +193,15 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 			len(invoice.DescriptionHash))
 	}
 
+	if invoice.Private && invoice.NoRouteHints {
+		return nil, nil, errors.New("private and no route hints " +
+			"are mutually exclusive")
+	}
+	if invoice.PrivateRouteHintsOnly && invoice.NoRouteHints {
+		return nil, nil, errors.New("private route hints only and no " +
+			"route hints are mutually exclusive")
+	}
+
 	// We set the max invoice amount to 100k BTC, which itself is several
 	// multiples off the current block reward.
 	maxInvoiceAmt := dcrutil.Amount(dcrutil.AtomsPerCoin * 100000)
@@ -165,6 +222,19 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 
 	amtMAtoms := invoice.Value
 
+	// Look up the defaults configured for this kind of invoice and caller,
+	// to be used for any parameters that weren't explicitly specified.
+	var defaults Defaults
+	if cfg.InvoiceDefaults != nil {
+		hold := invoice.Preimage == nil && invoice.Hash != nil
+		defaults = cfg.InvoiceDefaults(ctx, hold)
+	}
+	defaultDelta := cfg.DefaultCLTVExpiry
+	if defaults.CltvDelta != 0 {
+		defaultDelta = defaults.CltvDelta
+	}
+	defaultExpiry := defaults.Expiry
+
 	// We also create an encoded payment request which allows the caller to
 	// compactly send the invoice to the payer. We'll create a list of
 	// options to be added to the encoded payment request. For now we only
@@ -191,11 +261,11 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		options = append(options, zpay32.FallbackAddr(addr))
 	}
 
-	// If expiry is set, specify it. If it is not provided, no expiry time
-	// will be explicitly added to this payment request, which will imply
-	// the default 3600 seconds.
-	if invoice.Expiry > 0 {
-
+	// If expiry is set, specify it. If it is not provided, the configured
+	// default is used. Otherwise no expiry time will be explicitly added
+	// to this payment request, which will imply the default 3600 seconds.
+	switch {
+	case invoice.Expiry > 0:
 		// We'll ensure that the specified expiry is restricted to sane
 		// number of seconds. As a result, we'll reject an invoice with
 		// an expiry greater than 1 year.
@@ -210,6 +280,9 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 
 		expiry := time.Duration(invoice.Expiry) * time.Second
 		options = append(options, zpay32.Expiry(expiry))
+
+	case defaultExpiry > 0:
+		options = append(options, zpay32.Expiry(defaultExpiry))
 	}
 
 	// If the description hash is set, then we add it do the list of options.
@@ -243,20 +316,27 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 			zpay32.CLTVExpiry(invoice.CltvExpiry))
 	default:
 		// TODO(roasbeef): assumes set delta between versions
-		defaultDelta := cfg.DefaultCLTVExpiry
 		options = append(options, zpay32.CLTVExpiry(uint64(defaultDelta)))
 	}
 
-	// If we were requested to include routing hints in the invoice, then
-	// we'll fetch all of our available private channels and create routing
-	// hints for them.
-	if invoice.Private {
+	// If we were requested to include routing hints in the invoice, or the
+	// caller left it to our defaults and they call for them, then we'll
+	// fetch all of our available private channels and create routing hints
+	// for them.
+	private := invoice.Private || invoice.PrivateRouteHintsOnly
+	var hintChans []lnwire.ShortChannelID
+	if private || (!invoice.NoRouteHints &&
+		defaults.RouteHints != RouteHintsNever) {
+
 		openChannels, err := cfg.ChanDB.FetchAllChannels()
 		if err != nil {
 			return nil, nil, fmt.Errorf("could not fetch all channels")
 		}
 
-		if len(openChannels) > 0 {
+		includeHints := includeRouteHints(
+			invoice, defaults.RouteHints, openChannels,
+		)
+		if includeHints && len(openChannels) > 0 {
 			// We'll restrict the number of individual route hints
 			// to 20 to avoid creating overly large invoices.
 			const numMaxHophints = 20
@@ -264,10 +344,28 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 				amtMAtoms, cfg, openChannels, numMaxHophints,
 			)
 
-			options = append(options, hopHints...)
+			for _, hopHint := range hopHints {
+				options = append(
+					options, zpay32.RouteHint(
+						[]zpay32.HopHint{hopHint},
+					),
+				)
+				hintChans = append(
+					hintChans, lnwire.NewShortChanIDFromInt(
+						hopHint.ChannelID,
+					),
+				)
+			}
 		}
 	}
 
+	// An invoice restricted to the channels of its route hints can't be
+	// paid without any.
+	if invoice.PrivateRouteHintsOnly && len(hintChans) == 0 {
+		return nil, nil, errors.New("no private channel is eligible " +
+			"as route hint")
+	}
+
 	// Set our desired invoice features and add them to our list of options.
 	invoiceFeatures := cfg.GenInvoiceFeatures()
 	options = append(options, zpay32.Features(invoiceFeatures))
@@ -313,6 +411,9 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 		},
 		HodlInvoice: invoice.HodlInvoice,
 	}
+	if invoice.PrivateRouteHintsOnly {
+		newInvoice.HintChannels = hintChans
+	}
 
 	log.Tracef("[addinvoice] adding new invoice %v",
 		newLogClosure(func() string {
@@ -329,6 +430,46 @@ func AddInvoice(ctx context.Context, cfg *AddInvoiceConfig,
 	return &paymentHash, newInvoice, nil
 }
 
+// includeRouteHints returns true if the invoice should include route hints for
+// our private channels, given the configured policy and our open channels.
+func includeRouteHints(invoice *AddInvoiceData, policy RouteHintsPolicy,
+	openChannels []*channeldb.OpenChannel) bool {
+
+	switch {
+	case invoice.Private || invoice.PrivateRouteHintsOnly:
+		return true
+
+	case invoice.NoRouteHints:
+		return false
+	}
+
+	switch policy {
+	case RouteHintsAlways:
+		return true
+
+	// When automatically deciding on route hints, we only include them if
+	// none of our channels are public, as we'd otherwise be reachable
+	// without revealing our private channels.
+	case RouteHintsAuto:
+		return !hasPublicChannel(openChannels)
+
+	default:
+		return false
+	}
+}
+
+// hasPublicChannel returns true if any of the passed channels is announced to
+// the network.
+func hasPublicChannel(channels []*channeldb.OpenChannel) bool {
+	for _, channel := range channels {
+		if channel.ChannelFlags&lnwire.FFAnnounceChannel != 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
 // chanCanBeHopHint returns true if the target channel is eligible to be a hop
 // hint.
 func chanCanBeHopHint(channel *channeldb.OpenChannel,
@@ -396,12 +537,11 @@ func chanCanBeHopHint(channel *channeldb.OpenChannel,
 	return remotePolicy, true
 }
 
-// addHopHint creates a hop hint out of the passed channel and channel policy.
-// The new hop hint is appended to the passed slice.
-func addHopHint(hopHints *[]func(*zpay32.Invoice),
-	channel *channeldb.OpenChannel, chanPolicy *channeldb.ChannelEdgePolicy) {
+// newHopHint creates a hop hint out of the passed channel and channel policy.
+func newHopHint(channel *channeldb.OpenChannel,
+	chanPolicy *channeldb.ChannelEdgePolicy) zpay32.HopHint {
 
-	hopHint := zpay32.HopHint{
+	return zpay32.HopHint{
 		NodeID:        channel.IdentityPub,
 		ChannelID:     channel.ShortChanID().ToUint64(),
 		FeeBaseMAtoms: uint32(chanPolicy.FeeBaseMAtoms),
@@ -410,29 +550,60 @@ func addHopHint(hopHints *[]func(*zpay32.Invoice),
 		),
 		CLTVExpiryDelta: chanPolicy.TimeLockDelta,
 	}
-	*hopHints = append(
-		*hopHints, zpay32.RouteHint([]zpay32.HopHint{hopHint}),
+}
+
+// inboundBandwidth returns the amount the remote party of the channel can
+// currently send to us, which is its balance above its channel reserve.
+func inboundBandwidth(channel *channeldb.OpenChannel) lnwire.MilliAtom {
+	remoteBalance := channel.LocalCommitment.RemoteBalance
+	reserve := lnwire.NewMAtomsFromAtoms(
+		channel.RemoteChanCfg.ChanReserve,
 	)
+	if remoteBalance <= reserve {
+		return 0
+	}
+
+	return remoteBalance - reserve
 }
 
 // selectHopHints will select up to numMaxHophints from the set of passed open
-// channels. The set of hop hints will be returned as a slice of functional
-// options that'll append the route hint to the set of all route hints.
+// channels. Channels with the most inbound bandwidth are preferred, as they
+// are the most likely to be able to carry the payment.
 //
 // TODO(roasbeef): do proper sub-set sum max hints usually << numChans
 func selectHopHints(amtMAtoms lnwire.MilliAtom, cfg *AddInvoiceConfig,
 	openChannels []*channeldb.OpenChannel,
-	numMaxHophints int) []func(*zpay32.Invoice) {
+	numMaxHophints int) []zpay32.HopHint {
 
 	graph := cfg.ChanDB.ChannelGraph()
 
+	// Consider the channels with the most inbound bandwidth first. The
+	// passed slice is copied so that the caller's order is kept.
+	channels := make([]*channeldb.OpenChannel, len(openChannels))
+	copy(channels, openChannels)
+	sort.SliceStable(channels, func(i, j int) bool {
+		return inboundBandwidth(channels[i]) >
+			inboundBandwidth(channels[j])
+	})
+
 	// We'll add our hop hints in two passes, first we'll add all channels
-	// that are eligible to be hop hints, and also have a local balance
-	// above the payment amount.
+	// that are eligible to be hop hints, and also have enough inbound
+	// bandwidth to carry the payment.
 	var totalHintBandwidth lnwire.MilliAtom
 	hopHintChans := make(map[wire.OutPoint]struct{})
-	hopHints := make([]func(*zpay32.Invoice), 0, numMaxHophints)
-	for _, channel := range openChannels {
+	hopHints := make([]zpay32.HopHint, 0, numMaxHophints)
+	for _, channel := range channels {
+		if len(hopHints) >= numMaxHophints {
+			break
+		}
+
+		// In this first pass, we'll ignore all channels that in
+		// isolation can't satisfy this payment.
+		bandwidth := inboundBandwidth(channel)
+		if bandwidth < amtMAtoms {
+			continue
+		}
+
 		// If this channel can't be a hop hint, then skip it.
 		edgePolicy, canBeHopHint := chanCanBeHopHint(
 			channel, graph, cfg,
@@ -441,18 +612,12 @@ func selectHopHints(amtMAtoms lnwire.MilliAtom, cfg *AddInvoiceConfig,
 			continue
 		}
 
-		// Similarly, in this first pass, we'll ignore all channels in
-		// isolation can't satisfy this payment.
-		if channel.LocalCommitment.RemoteBalance < amtMAtoms {
-			continue
-		}
-
 		// Now that we now this channel use usable, add it as a hop
 		// hint and the indexes we'll use later.
-		addHopHint(&hopHints, channel, edgePolicy)
+		hopHints = append(hopHints, newHopHint(channel, edgePolicy))
 
 		hopHintChans[channel.FundingOutpoint] = struct{}{}
-		totalHintBandwidth += channel.LocalCommitment.RemoteBalance
+		totalHintBandwidth += bandwidth
 	}
 
 	// If we have enough hop hints at this point, then we'll exit early.
@@ -467,7 +632,7 @@ func selectHopHints(amtMAtoms lnwire.MilliAtom, cfg *AddInvoiceConfig,
 	// the payment amount. We do 2x here to account for a margin of error
 	// if some of the selected channels no longer become operable.
 	hopHintFactor := lnwire.MilliAtom(2)
-	for i := 0; i < len(openChannels); i++ {
+	for _, channel := range channels {
 		// If we hit either of our early termination conditions, then
 		// we'll break the loop here.
 		if totalHintBandwidth > amtMAtoms*hopHintFactor ||
@@ -476,13 +641,18 @@ func selectHopHints(amtMAtoms lnwire.MilliAtom, cfg *AddInvoiceConfig,
 			break
 		}
 
-		channel := openChannels[i]
-
 		// Skip the channel if we already selected it.
 		if _, ok := hopHintChans[channel.FundingOutpoint]; ok {
 			continue
 		}
 
+		// Channels without any inbound bandwidth can't carry any part
+		// of the payment.
+		bandwidth := inboundBandwidth(channel)
+		if bandwidth == 0 {
+			continue
+		}
+
 		// If the channel can't be a hop hint, then we'll skip it.
 		// Otherwise, we'll use the policy information to populate the
 		// hop hint.
@@ -495,13 +665,11 @@ func selectHopHints(amtMAtoms lnwire.MilliAtom, cfg *AddInvoiceConfig,
 
 		// Include the route hint in our set of options that will be
 		// used when creating the invoice.
-		addHopHint(&hopHints, channel, remotePolicy)
+		hopHints = append(hopHints, newHopHint(channel, remotePolicy))
 
 		// As we've just added a new hop hint, we'll accumulate it's
 		// available balance now to update our tally.
-		//
-		// TODO(roasbeef): have a cut off based on min bandwidth?
-		totalHintBandwidth += channel.LocalCommitment.RemoteBalance
+		totalHintBandwidth += bandwidth
 	}
 
 	return hopHints
@@ -0,0 +1,374 @@
+package invoicesrpc
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// TestIncludeRouteHints asserts that route hints are included when requested,
+// omitted when opted out of, and otherwise included according to the
+// configured policy.
+func TestIncludeRouteHints(t *testing.T) {
+	privateChan := &channeldb.OpenChannel{}
+	publicChan := &channeldb.OpenChannel{
+		ChannelFlags: lnwire.FFAnnounceChannel,
+	}
+	privateOnly := []*channeldb.OpenChannel{privateChan}
+	withPublic := []*channeldb.OpenChannel{privateChan, publicChan}
+
+	tests := []struct {
+		name     string
+		invoice  *AddInvoiceData
+		policy   RouteHintsPolicy
+		channels []*channeldb.OpenChannel
+		include  bool
+	}{{
+		name:     "never",
+		invoice:  &AddInvoiceData{},
+		policy:   RouteHintsNever,
+		channels: privateOnly,
+	}, {
+		name:     "never, requested",
+		invoice:  &AddInvoiceData{Private: true},
+		policy:   RouteHintsNever,
+		channels: withPublic,
+		include:  true,
+	}, {
+		name:     "never, private route hints only",
+		invoice:  &AddInvoiceData{PrivateRouteHintsOnly: true},
+		policy:   RouteHintsNever,
+		channels: withPublic,
+		include:  true,
+	}, {
+		name:     "always",
+		invoice:  &AddInvoiceData{},
+		policy:   RouteHintsAlways,
+		channels: withPublic,
+		include:  true,
+	}, {
+		name:     "always, opted out",
+		invoice:  &AddInvoiceData{NoRouteHints: true},
+		policy:   RouteHintsAlways,
+		channels: privateOnly,
+	}, {
+		name:     "auto, private channels only",
+		invoice:  &AddInvoiceData{},
+		policy:   RouteHintsAuto,
+		channels: privateOnly,
+		include:  true,
+	}, {
+		name:     "auto, public channel",
+		invoice:  &AddInvoiceData{},
+		policy:   RouteHintsAuto,
+		channels: withPublic,
+	}, {
+		name:     "auto, public channel, requested",
+		invoice:  &AddInvoiceData{Private: true},
+		policy:   RouteHintsAuto,
+		channels: withPublic,
+		include:  true,
+	}, {
+		name:     "auto, opted out",
+		invoice:  &AddInvoiceData{NoRouteHints: true},
+		policy:   RouteHintsAuto,
+		channels: privateOnly,
+	}}
+
+	for _, test := range tests {
+		include := includeRouteHints(
+			test.invoice, test.policy, test.channels,
+		)
+		if include != test.include {
+			t.Fatalf("%v: expected include route hints %v, got %v",
+				test.name, test.include, include)
+		}
+	}
+}
+
+// TestInboundBandwidth asserts that the inbound bandwidth of a channel is the
+// balance of the remote party above its channel reserve.
+func TestInboundBandwidth(t *testing.T) {
+	newChannel := func(balance lnwire.MilliAtom,
+		reserve dcrutil.Amount) *channeldb.OpenChannel {
+
+		channel := &channeldb.OpenChannel{}
+		channel.LocalCommitment.RemoteBalance = balance
+		channel.RemoteChanCfg.ChanReserve = reserve
+		return channel
+	}
+
+	tests := []struct {
+		name      string
+		channel   *channeldb.OpenChannel
+		bandwidth lnwire.MilliAtom
+	}{{
+		name:      "above reserve",
+		channel:   newChannel(5000000, 1000),
+		bandwidth: 4000000,
+	}, {
+		name:    "at reserve",
+		channel: newChannel(1000000, 1000),
+	}, {
+		name:    "below reserve",
+		channel: newChannel(500000, 1000),
+	}}
+
+	for _, test := range tests {
+		bandwidth := inboundBandwidth(test.channel)
+		if bandwidth != test.bandwidth {
+			t.Fatalf("%v: expected bandwidth %v, got %v", test.name,
+				test.bandwidth, bandwidth)
+		}
+	}
+}
+
+// hopHintGraph is a channel graph holding our node and the channels of the
+// remote parties of our private channels.
+type hopHintGraph struct {
+	t     *testing.T
+	graph *channeldb.ChannelGraph
+
+	nextChanID uint64
+}
+
+// addNode adds a new node to the graph, returning its public key.
+func (g *hopHintGraph) addNode() *secp256k1.PublicKey {
+	g.t.Helper()
+
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		g.t.Fatalf("unable to generate key: %v", err)
+	}
+	node := &channeldb.LightningNode{}
+	copy(node.PubKeyBytes[:], privKey.PubKey().SerializeCompressed())
+	if err := g.graph.AddLightningNode(node); err != nil {
+		g.t.Fatalf("unable to add node: %v", err)
+	}
+
+	return privKey.PubKey()
+}
+
+// addChannel adds a channel between the two nodes, along with the policies of
+// both of them, and returns its short channel id. The fee base of the policy
+// of each node is set to the passed one.
+func (g *hopHintGraph) addChannel(a, b *secp256k1.PublicKey, feeA,
+	feeB lnwire.MilliAtom, public bool) lnwire.ShortChannelID {
+
+	g.t.Helper()
+
+	g.nextChanID++
+	chanID := lnwire.NewShortChanIDFromInt(g.nextChanID)
+
+	var node1, node2 route.Vertex
+	copy(node1[:], a.SerializeCompressed())
+	copy(node2[:], b.SerializeCompressed())
+	if bytes.Compare(node1[:], node2[:]) > 0 {
+		node1, node2 = node2, node1
+		feeA, feeB = feeB, feeA
+	}
+
+	edge := &channeldb.ChannelEdgeInfo{
+		ChannelID:       chanID.ToUint64(),
+		NodeKey1Bytes:   node1,
+		NodeKey2Bytes:   node2,
+		DecredKey1Bytes: node1,
+		DecredKey2Bytes: node2,
+		ChannelPoint: wire.OutPoint{
+			Hash: chainhash.Hash{byte(g.nextChanID)},
+		},
+	}
+	if public {
+		edge.AuthProof = &channeldb.ChannelAuthProof{
+			NodeSig1Bytes:   []byte{1},
+			NodeSig2Bytes:   []byte{2},
+			DecredSig1Bytes: []byte{3},
+			DecredSig2Bytes: []byte{4},
+		}
+	}
+	if err := g.graph.AddChannelEdge(edge); err != nil {
+		g.t.Fatalf("unable to add channel: %v", err)
+	}
+
+	for i, fee := range []lnwire.MilliAtom{feeA, feeB} {
+		var flags lnwire.ChanUpdateChanFlags
+		if i == 1 {
+			flags = lnwire.ChanUpdateDirection
+		}
+		err := g.graph.UpdateEdgePolicy(&channeldb.ChannelEdgePolicy{
+			SigBytes:      []byte{1},
+			ChannelID:     chanID.ToUint64(),
+			LastUpdate:    time.Unix(int64(g.nextChanID), 0),
+			ChannelFlags:  flags,
+			TimeLockDelta: 40,
+			FeeBaseMAtoms: fee,
+		})
+		if err != nil {
+			g.t.Fatalf("unable to update policy: %v", err)
+		}
+	}
+
+	return chanID
+}
+
+// TestSelectHopHints asserts that the eligible private channels with the most
+// inbound bandwidth are selected as hop hints, until they can carry twice the
+// amount of the payment.
+func TestSelectHopHints(t *testing.T) {
+	db, cleanUp, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanUp()
+
+	g := &hopHintGraph{t: t, graph: db.ChannelGraph()}
+
+	// Our own node, and a node the remote parties of our channels have a
+	// public channel with, so that they're advertised.
+	ourKey := g.addNode()
+	source, err := g.graph.FetchLightningNode(
+		nil, route.NewVertex(ourKey),
+	)
+	if err != nil {
+		t.Fatalf("unable to fetch source node: %v", err)
+	}
+	if err := g.graph.SetSourceNode(source); err != nil {
+		t.Fatalf("unable to set source node: %v", err)
+	}
+	otherKey := g.addNode()
+
+	inactive := make(map[lnwire.ChannelID]struct{})
+	remoteFees := make(map[uint64]lnwire.MilliAtom)
+	cfg := &AddInvoiceConfig{
+		ChanDB: db,
+		IsChannelActive: func(chanID lnwire.ChannelID) bool {
+			_, ok := inactive[chanID]
+			return !ok
+		},
+	}
+
+	// newChannel creates one of our channels with the given inbound
+	// bandwidth. The remote party charges the given fee base to forward
+	// through the channel.
+	newChannel := func(bandwidth, remoteFee lnwire.MilliAtom,
+		advertised, public, active bool) *channeldb.OpenChannel {
+
+		remoteKey := g.addNode()
+		if advertised {
+			g.addChannel(remoteKey, otherKey, 0, 0, true)
+		}
+		chanID := g.addChannel(ourKey, remoteKey, 1, remoteFee, public)
+		remoteFees[chanID.ToUint64()] = remoteFee
+
+		channel := &channeldb.OpenChannel{
+			IdentityPub:    remoteKey,
+			ShortChannelID: chanID,
+			FundingOutpoint: wire.OutPoint{
+				Hash: chainhash.Hash{byte(chanID.ToUint64())},
+			},
+		}
+		if public {
+			channel.ChannelFlags = lnwire.FFAnnounceChannel
+		}
+		channel.LocalCommitment.RemoteBalance = bandwidth
+		if !active {
+			chanPoint := lnwire.NewChanIDFromOutPoint(
+				&channel.FundingOutpoint,
+			)
+			inactive[chanPoint] = struct{}{}
+		}
+
+		return channel
+	}
+
+	var (
+		mid        = newChannel(150000, 1001, true, false, true)
+		noBalance  = newChannel(0, 1002, true, false, true)
+		small      = newChannel(60000, 1003, true, false, true)
+		unadvert   = newChannel(500000, 1004, false, false, true)
+		inactiveCh = newChannel(300000, 1005, true, false, false)
+		smallest   = newChannel(40000, 1006, true, false, true)
+		publicCh   = newChannel(120000, 1007, true, true, true)
+	)
+	channels := []*channeldb.OpenChannel{
+		mid, noBalance, small, unadvert, inactiveCh, smallest, publicCh,
+	}
+
+	tests := []struct {
+		name     string
+		amt      lnwire.MilliAtom
+		maxHints int
+		selected []*channeldb.OpenChannel
+	}{{
+		// Only mid can carry the payment on its own, small is added
+		// until the hints can carry twice the amount.
+		name:     "until twice the amount",
+		amt:      100000,
+		maxHints: 20,
+		selected: []*channeldb.OpenChannel{mid, small},
+	}, {
+		name:     "max hints",
+		amt:      100000,
+		maxHints: 1,
+		selected: []*channeldb.OpenChannel{mid},
+	}, {
+		// All the eligible channels can carry the payment, so they're
+		// all selected, by decreasing inbound bandwidth.
+		name:     "small amount",
+		amt:      10000,
+		maxHints: 20,
+		selected: []*channeldb.OpenChannel{mid, small, smallest},
+	}, {
+		// None can carry the payment alone, so they're all added
+		// in the second pass.
+		name:     "large amount",
+		amt:      1000000,
+		maxHints: 20,
+		selected: []*channeldb.OpenChannel{mid, small, smallest},
+	}}
+
+	for _, test := range tests {
+		hopHints := selectHopHints(
+			test.amt, cfg, channels, test.maxHints,
+		)
+		if len(hopHints) != len(test.selected) {
+			t.Fatalf("%v: expected %d hop hints, got %d", test.name,
+				len(test.selected), len(hopHints))
+		}
+
+		for i, hopHint := range hopHints {
+			channel := test.selected[i]
+			chanID := channel.ShortChannelID.ToUint64()
+			if hopHint.ChannelID != chanID {
+				t.Fatalf("%v: expected hop hint %d for channel "+
+					"%v, got %v", test.name, i, chanID,
+					hopHint.ChannelID)
+			}
+			if !hopHint.NodeID.IsEqual(channel.IdentityPub) {
+				t.Fatalf("%v: unexpected node of hop hint %d",
+					test.name, i)
+			}
+
+			// The hint uses the policy of the remote party.
+			expectedFee := uint32(remoteFees[chanID])
+			if hopHint.FeeBaseMAtoms != expectedFee {
+				t.Fatalf("%v: expected fee base %v for hop hint "+
+					"%d, got %v", test.name, expectedFee, i,
+					hopHint.FeeBaseMAtoms)
+			}
+		}
+	}
+
+	// The order of the passed channels is kept.
+	if channels[0] != mid || channels[6] != publicCh {
+		t.Fatalf("expected order of channels to be kept")
+	}
+}
@@ -3,6 +3,8 @@
 package invoicesrpc
 
 import (
+	"context"
+
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/invoices"
@@ -44,6 +46,11 @@ type Config struct {
 	// specified.
 	DefaultCLTVExpiry uint32
 
+	// InvoiceDefaults returns the defaults to use for a standard or hold
+	// invoice created through the given context when the caller doesn't
+	// specify them.
+	InvoiceDefaults func(ctx context.Context, hold bool) Defaults
+
 	// ChanDB is a global bboltdb instance which is needed to access the
 	// channel graph.
 	ChanDB *channeldb.DB
@@ -0,0 +1,50 @@
+package invoicesrpc
+
+import (
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+)
+
+// HoldInvoiceRegistry is the part of the invoice registry used to resolve hold
+// invoices.
+type HoldInvoiceRegistry interface {
+	// SettleHodlInvoice settles a hold invoice with the given preimage.
+	SettleHodlInvoice(preimage lntypes.Preimage) error
+
+	// CancelInvoice cancels the invoice with the given payment hash.
+	CancelInvoice(payHash lntypes.Hash) error
+}
+
+// SettleHoldInvoice settles the accepted hold invoice matching the given
+// preimage. If the invoice is already settled, this call will succeed.
+func SettleHoldInvoice(registry HoldInvoiceRegistry, rawPreimage []byte) error {
+	preimage, err := lntypes.MakePreimage(rawPreimage)
+	if err != nil {
+		return err
+	}
+
+	err = registry.SettleHodlInvoice(preimage)
+	if err != nil && err != channeldb.ErrInvoiceAlreadySettled {
+		return err
+	}
+
+	return nil
+}
+
+// CancelHoldInvoice cancels the invoice with the given payment hash, failing
+// back any of its accepted htlcs. If the invoice is already canceled, this call
+// will succeed. If the invoice is already settled, it will fail.
+func CancelHoldInvoice(registry HoldInvoiceRegistry, rawHash []byte) error {
+	paymentHash, err := lntypes.MakeHash(rawHash)
+	if err != nil {
+		return err
+	}
+
+	if err := registry.CancelInvoice(paymentHash); err != nil {
+		return err
+	}
+
+	log.Infof("Canceled invoice %v", paymentHash)
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package invoicesrpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+)
+
+// mockHoldRegistry is a HoldInvoiceRegistry returning preset errors.
+type mockHoldRegistry struct {
+	settleErr error
+	cancelErr error
+
+	settled  []lntypes.Preimage
+	canceled []lntypes.Hash
+}
+
+func (m *mockHoldRegistry) SettleHodlInvoice(preimage lntypes.Preimage) error {
+	m.settled = append(m.settled, preimage)
+	return m.settleErr
+}
+
+func (m *mockHoldRegistry) CancelInvoice(payHash lntypes.Hash) error {
+	m.canceled = append(m.canceled, payHash)
+	return m.cancelErr
+}
+
+// TestSettleHoldInvoice asserts that hold invoices are settled with the given
+// preimage and that settling an already settled invoice succeeds.
+func TestSettleHoldInvoice(t *testing.T) {
+	preimage := lntypes.Preimage{1}
+	registry := &mockHoldRegistry{}
+
+	if err := SettleHoldInvoice(registry, preimage[:]); err != nil {
+		t.Fatalf("unable to settle invoice: %v", err)
+	}
+	if len(registry.settled) != 1 || registry.settled[0] != preimage {
+		t.Fatalf("expected invoice to be settled with %v, got %v",
+			preimage, registry.settled)
+	}
+
+	registry.settleErr = channeldb.ErrInvoiceAlreadySettled
+	if err := SettleHoldInvoice(registry, preimage[:]); err != nil {
+		t.Fatalf("expected settled invoice to be accepted, got %v",
+			err)
+	}
+
+	registry.settleErr = channeldb.ErrInvoiceAlreadyCanceled
+	err := SettleHoldInvoice(registry, preimage[:])
+	if err != channeldb.ErrInvoiceAlreadyCanceled {
+		t.Fatalf("expected canceled invoice error, got %v", err)
+	}
+
+	if err := SettleHoldInvoice(registry, []byte{1}); err == nil {
+		t.Fatalf("expected invalid preimage to be rejected")
+	}
+	if len(registry.settled) != 3 {
+		t.Fatalf("expected invalid preimage not to be used")
+	}
+}
+
+// TestCancelHoldInvoice asserts that invoices are canceled by payment hash and
+// that registry errors are returned.
+func TestCancelHoldInvoice(t *testing.T) {
+	hash := lntypes.Hash{1}
+	registry := &mockHoldRegistry{}
+
+	if err := CancelHoldInvoice(registry, hash[:]); err != nil {
+		t.Fatalf("unable to cancel invoice: %v", err)
+	}
+	if len(registry.canceled) != 1 || registry.canceled[0] != hash {
+		t.Fatalf("expected invoice %v to be canceled, got %v", hash,
+			registry.canceled)
+	}
+
+	registry.cancelErr = errors.New("invoice settled")
+	if err := CancelHoldInvoice(registry, hash[:]); err == nil {
+		t.Fatalf("expected registry error")
+	}
+
+	if err := CancelHoldInvoice(registry, []byte{1}); err == nil {
+		t.Fatalf("expected invalid hash to be rejected")
+	}
+	if len(registry.canceled) != 2 {
+		t.Fatalf("expected invalid hash not to be used")
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"google.golang.org/grpc"
 	"gopkg.in/macaroon-bakery.v2/bakery"
 
-	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/lnrpc"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/macaroons"
@@ -58,6 +57,18 @@ var (
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/invoicesrpc.Invoices/ListPendingApprovals": {{
+			Entity: "invoices",
+			Action: "read",
+		}},
+		"/invoicesrpc.Invoices/ApproveSettlement": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
+		"/invoicesrpc.Invoices/RejectSettlement": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
 	}
 
 	// DefaultInvoicesMacFilename is the default name of the invoices
@@ -229,16 +240,11 @@ func (s *Server) SubscribeSingleInvoice(req *SubscribeSingleInvoiceRequest,
 func (s *Server) SettleInvoice(ctx context.Context,
 	in *SettleInvoiceMsg) (*SettleInvoiceResp, error) {
 
-	preimage, err := lntypes.MakePreimage(in.Preimage)
+	err := SettleHoldInvoice(s.cfg.InvoiceRegistry, in.Preimage)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.cfg.InvoiceRegistry.SettleHodlInvoice(preimage)
-	if err != nil && err != channeldb.ErrInvoiceAlreadySettled {
-		return nil, err
-	}
-
 	return &SettleInvoiceResp{}, nil
 }
 
@@ -248,19 +254,77 @@ func (s *Server) SettleInvoice(ctx context.Context,
 func (s *Server) CancelInvoice(ctx context.Context,
 	in *CancelInvoiceMsg) (*CancelInvoiceResp, error) {
 
+	err := CancelHoldInvoice(s.cfg.InvoiceRegistry, in.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CancelInvoiceResp{}, nil
+}
+
+// ListPendingApprovals lists the invoices whose settlement is held pending
+// approval, oldest first.
+func (s *Server) ListPendingApprovals(ctx context.Context,
+	_ *ListPendingApprovalsRequest) (*ListPendingApprovalsResponse, error) {
+
+	invoices, err := s.cfg.InvoiceRegistry.PendingApprovals()
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &ListPendingApprovalsResponse{
+		Invoices: make([]*lnrpc.Invoice, 0, len(invoices)),
+	}
+	for i := range invoices {
+		rpcInvoice, err := CreateRPCInvoice(
+			&invoices[i], s.cfg.ChainParams,
+		)
+		if err != nil {
+			return nil, err
+		}
+		resp.Invoices = append(resp.Invoices, rpcInvoice)
+	}
+
+	return resp, nil
+}
+
+// ApproveSettlement settles an invoice whose settlement is pending approval.
+func (s *Server) ApproveSettlement(ctx context.Context,
+	in *ApproveSettlementRequest) (*ApproveSettlementResponse, error) {
+
 	paymentHash, err := lntypes.MakeHash(in.PaymentHash)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.cfg.InvoiceRegistry.CancelInvoice(paymentHash)
+	err = s.cfg.InvoiceRegistry.ApproveSettlement(paymentHash)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Infof("Canceled invoice %v", paymentHash)
+	log.Infof("Approved settlement of invoice %v", paymentHash)
 
-	return &CancelInvoiceResp{}, nil
+	return &ApproveSettlementResponse{}, nil
+}
+
+// RejectSettlement cancels an invoice whose settlement is pending approval,
+// failing back its htlcs.
+func (s *Server) RejectSettlement(ctx context.Context,
+	in *RejectSettlementRequest) (*RejectSettlementResponse, error) {
+
+	paymentHash, err := lntypes.MakeHash(in.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.cfg.InvoiceRegistry.RejectSettlement(paymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Rejected settlement of invoice %v", paymentHash)
+
+	return &RejectSettlementResponse{}, nil
 }
 
 // AddHoldInvoice attempts to add a new hold invoice to the invoice database.
@@ -275,6 +339,7 @@ func (s *Server) AddHoldInvoice(ctx context.Context,
 		ChainParams:        s.cfg.ChainParams,
 		NodeSigner:         s.cfg.NodeSigner,
 		DefaultCLTVExpiry:  s.cfg.DefaultCLTVExpiry,
+		InvoiceDefaults:    s.cfg.InvoiceDefaults,
 		ChanDB:             s.cfg.ChanDB,
 		GenInvoiceFeatures: s.cfg.GenInvoiceFeatures,
 	}
@@ -298,8 +363,11 @@ func (s *Server) AddHoldInvoice(ctx context.Context,
 		FallbackAddr:    invoice.FallbackAddr,
 		CltvExpiry:      invoice.CltvExpiry,
 		Private:         invoice.Private,
+		NoRouteHints:    invoice.NoRouteHints,
 		HodlInvoice:     true,
 		Preimage:        nil,
+
+		PrivateRouteHintsOnly: invoice.PrivateRouteHintsOnly,
 	}
 
 	_, dbInvoice, err := AddInvoice(ctx, addInvoiceCfg, addInvoiceData)
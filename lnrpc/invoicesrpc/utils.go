@@ -147,6 +147,8 @@ func CreateRPCInvoice(invoice *channeldb.Invoice,
 		Htlcs:           rpcHtlcs,
 		Features:        CreateRPCFeatures(invoice.Terms.Features),
 		IsKeysend:       len(invoice.PaymentRequest) == 0,
+
+		PrivateRouteHintsOnly: len(invoice.HintChannels) > 0,
 	}
 
 	if preimage != nil {
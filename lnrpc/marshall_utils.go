@@ -4,6 +4,8 @@ import (
 	"encoding/hex"
 	"errors"
 	fmt "fmt"
+	"strconv"
+	"strings"
 
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrd/dcrutil/v3"
@@ -20,6 +22,17 @@ var (
 	)
 )
 
+// amountUnits maps the unit suffixes accepted in amount strings to the number
+// of decimal places of the unit relative to milliatoms.
+var amountUnits = []struct {
+	suffix   string
+	decimals int
+}{
+	{suffix: "m_atoms", decimals: 0},
+	{suffix: "atoms", decimals: 3},
+	{suffix: "dcr", decimals: 11},
+}
+
 // CalculateFeeLimit returns the fee limit in millisatoshis. If a percentage
 // based fee limit has been requested, we'll factor in the ratio provided with
 // the amount of the payment.
@@ -61,6 +74,59 @@ func UnmarshallAmt(amtAtom, amtMAtom int64) (lnwire.MilliAtom, error) {
 	return lnwire.MilliAtom(amtMAtom), nil
 }
 
+// UnmarshallAmtStr parses an amount string into milliatoms. The amount is a
+// non-negative decimal number with an optional unit suffix, which is one of
+// m_atoms (the default), atoms or dcr. Amounts with more precision than
+// milliatoms or that don't fit into an int64 are rejected, so an amount is
+// never silently rounded.
+func UnmarshallAmtStr(amt string) (lnwire.MilliAtom, error) {
+	num := strings.TrimSpace(amt)
+	decimals := 0
+	for _, unit := range amountUnits {
+		if strings.HasSuffix(num, unit.suffix) {
+			num = strings.TrimSpace(
+				strings.TrimSuffix(num, unit.suffix),
+			)
+			decimals = unit.decimals
+			break
+		}
+	}
+
+	intPart, fracPart := num, ""
+	if idx := strings.Index(num, "."); idx != -1 {
+		intPart, fracPart = num[:idx], num[idx+1:]
+	}
+
+	isDigits := func(s string) bool {
+		for _, c := range s {
+			if c < '0' || c > '9' {
+				return false
+			}
+		}
+		return true
+	}
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return 0, fmt.Errorf("invalid amount %q", amt)
+	}
+
+	// Only trailing zeros may exceed the precision of milliatoms.
+	trimmedFrac := strings.TrimRight(fracPart, "0")
+	if len(trimmedFrac) > decimals {
+		return 0, fmt.Errorf("amount %q has more precision than "+
+			"milliatoms", amt)
+	}
+
+	// Scale the amount to milliatoms by shifting the decimal point.
+	digits := intPart + trimmedFrac +
+		strings.Repeat("0", decimals-len(trimmedFrac))
+	mAtoms, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("amount %q out of range", amt)
+	}
+
+	return lnwire.MilliAtom(mAtoms), nil
+}
+
 // ParseConfs validates the minimum and maximum confirmation arguments of a
 // ListUnspent request.
 func ParseConfs(min, max int32) (int32, int32, error) {
@@ -128,6 +194,10 @@ func MarshalUtxos(utxos []*lnwallet.Utxo, activeNetParams *chaincfg.Params) (
 			PkScript:      hex.EncodeToString(utxo.PkScript),
 			Outpoint:      outpoint,
 			Confirmations: utxo.Confirmations,
+			Locked:        utxo.Locked,
+		}
+		if !utxo.LeaseExpiration.IsZero() {
+			utxoResp.LeaseExpiration = utxo.LeaseExpiration.Unix()
 		}
 
 		// Finally, we'll attempt to extract the raw address from the
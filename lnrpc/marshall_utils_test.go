@@ -0,0 +1,113 @@
+package lnrpc
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestUnmarshallAmtStr asserts that amount strings are parsed into exact
+// milliatom amounts and that amounts that would need rounding are rejected.
+func TestUnmarshallAmtStr(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		amt        string
+		mAtoms     lnwire.MilliAtom
+		shouldFail bool
+	}{
+		{amt: "0", mAtoms: 0},
+		{amt: "1500", mAtoms: 1500},
+		{amt: "1500m_atoms", mAtoms: 1500},
+		{amt: " 1500 m_atoms ", mAtoms: 1500},
+		{amt: "1500.000m_atoms", mAtoms: 1500},
+		{amt: "2atoms", mAtoms: 2000},
+		{amt: "1.5atoms", mAtoms: 1500},
+		{amt: "0.001atoms", mAtoms: 1},
+		{amt: "1dcr", mAtoms: 100000000000},
+		{amt: "0.00000001dcr", mAtoms: 1000},
+		{amt: "0.00000000001dcr", mAtoms: 1},
+		{amt: "9223372036854775807", mAtoms: math.MaxInt64},
+		{amt: "", shouldFail: true},
+		{amt: "atoms", shouldFail: true},
+		{amt: ".5atoms", shouldFail: true},
+		{amt: "-1", shouldFail: true},
+		{amt: "1e3", shouldFail: true},
+		{amt: "1.5", shouldFail: true},
+		{amt: "0.0001atoms", shouldFail: true},
+		{amt: "0.000000000001dcr", shouldFail: true},
+		{amt: "1.2.3atoms", shouldFail: true},
+		{amt: "1btc", shouldFail: true},
+		{amt: "9223372036854775808", shouldFail: true},
+		{amt: "100000000dcr", shouldFail: true},
+	}
+
+	for _, test := range tests {
+		mAtoms, err := UnmarshallAmtStr(test.amt)
+		switch {
+		case test.shouldFail && err == nil:
+			t.Fatalf("%q: expected error, got %v", test.amt, mAtoms)
+
+		case !test.shouldFail && err != nil:
+			t.Fatalf("%q: unexpected error: %v", test.amt, err)
+
+		case mAtoms != test.mAtoms:
+			t.Fatalf("%q: expected %v, got %v", test.amt,
+				test.mAtoms, mAtoms)
+		}
+	}
+}
+
+// TestMarshalUtxosLocks asserts that the lock status and lease expiration of
+// utxos are marshalled.
+func TestMarshalUtxosLocks(t *testing.T) {
+	// OP_DUP OP_HASH160 <20 byte hash> OP_EQUALVERIFY OP_CHECKSIG
+	pkScript := append([]byte{0x76, 0xa9, 0x14}, make([]byte, 20)...)
+	pkScript = append(pkScript, 0x88, 0xac)
+
+	expiration := time.Unix(1600000000, 0)
+	utxos := []*lnwallet.Utxo{{
+		AddressType: lnwallet.PubKeyHash,
+		PkScript:    pkScript,
+	}, {
+		AddressType: lnwallet.PubKeyHash,
+		PkScript:    pkScript,
+		Locked:      true,
+	}, {
+		AddressType:     lnwallet.PubKeyHash,
+		PkScript:        pkScript,
+		Locked:          true,
+		LeaseExpiration: expiration,
+	}}
+
+	rpcUtxos, err := MarshalUtxos(utxos, chaincfg.RegNetParams())
+	if err != nil {
+		t.Fatalf("unable to marshal utxos: %v", err)
+	}
+	if len(rpcUtxos) != 3 {
+		t.Fatalf("expected 3 utxos, got %v", len(rpcUtxos))
+	}
+
+	expected := []struct {
+		locked          bool
+		leaseExpiration int64
+	}{
+		{false, 0},
+		{true, 0},
+		{true, expiration.Unix()},
+	}
+	for i, utxo := range rpcUtxos {
+		if utxo.Locked != expected[i].locked ||
+			utxo.LeaseExpiration != expected[i].leaseExpiration {
+
+			t.Fatalf("utxo %d: expected locked=%v and lease "+
+				"expiration %v, got %v and %v", i,
+				expected[i].locked, expected[i].leaseExpiration,
+				utxo.Locked, utxo.LeaseExpiration)
+		}
+	}
+}
@@ -37,6 +37,10 @@ type Config struct {
 	// RouterBackend contains shared logic between this sub server and the
 	// main rpc server.
 	RouterBackend *RouterBackend
+
+	// HtlcEventStore holds the persisted htlc events of the node. If nil,
+	// htlc events aren't persisted.
+	HtlcEventStore *HtlcEventStore
 }
 
 // DefaultConfig defines the config defaults.
@@ -48,7 +52,9 @@ func DefaultConfig() *Config {
 		PenaltyHalfLife:       routing.DefaultPenaltyHalfLife,
 		AttemptCost: routing.DefaultPaymentAttemptPenalty.
 			ToAtoms(),
-		MaxMcHistory: routing.DefaultMaxMcHistory,
+		MaxMcHistory:         routing.DefaultMaxMcHistory,
+		FirstHopRetries:      routing.DefaultFirstHopRetries,
+		FirstHopRetryBackoff: routing.DefaultFirstHopRetryBackoff,
 	}
 
 	return &Config{
@@ -65,5 +71,7 @@ func GetRoutingConfig(cfg *Config) *RoutingConfig {
 		AttemptCost:           cfg.AttemptCost,
 		PenaltyHalfLife:       cfg.PenaltyHalfLife,
 		MaxMcHistory:          cfg.MaxMcHistory,
+		FirstHopRetries:       cfg.FirstHopRetries,
+		FirstHopRetryBackoff:  cfg.FirstHopRetryBackoff,
 	}
 }
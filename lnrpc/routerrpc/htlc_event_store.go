@@ -0,0 +1,270 @@
+package routerrpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/subscribe"
+	"github.com/golang/protobuf/proto"
+)
+
+const (
+	// htlcEventPruneInterval is the interval at which the persisted htlc
+	// events are pruned to the configured maximum age and count.
+	htlcEventPruneInterval = time.Hour
+
+	// defaultMaxHtlcEvents is the default number of htlc events returned
+	// by a single query.
+	defaultMaxHtlcEvents = 100
+)
+
+var (
+	// errHtlcEventStoreDisabled is returned when htlc events are queried
+	// while the htlc event store is disabled.
+	errHtlcEventStoreDisabled = errors.New("htlc event store not " +
+		"enabled, set htlcevents.store to persist htlc events")
+)
+
+// htlcEventKind returns the kind of the passed rpc htlc event.
+func htlcEventKind(event *HtlcEvent) HtlcEventKind {
+	switch event.Event.(type) {
+	case *HtlcEvent_ForwardEvent:
+		return HtlcEventKind_FORWARD_EVENT
+
+	case *HtlcEvent_ForwardFailEvent:
+		return HtlcEventKind_FORWARD_FAIL_EVENT
+
+	case *HtlcEvent_SettleEvent:
+		return HtlcEventKind_SETTLE_EVENT
+
+	case *HtlcEvent_LinkFailEvent:
+		return HtlcEventKind_LINK_FAIL_EVENT
+
+	default:
+		return HtlcEventKind_ANY_EVENT
+	}
+}
+
+// newHtlcEventRecord serializes the passed rpc htlc event into a record of
+// the htlc event log.
+func newHtlcEventRecord(event *HtlcEvent) (*channeldb.HtlcEventRecord,
+	error) {
+
+	eventBytes, err := proto.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	return &channeldb.HtlcEventRecord{
+		Timestamp: time.Unix(0, int64(event.TimestampNs)),
+		EventType: uint8(event.EventType),
+		EventKind: uint8(htlcEventKind(event)),
+		IncomingChanID: lnwire.NewShortChanIDFromInt(
+			event.IncomingChannelId,
+		),
+		OutgoingChanID: lnwire.NewShortChanIDFromInt(
+			event.OutgoingChannelId,
+		),
+		Event: eventBytes,
+	}, nil
+}
+
+// HtlcEventStoreConfig houses all the items required by the htlc event
+// store.
+type HtlcEventStoreConfig struct {
+	// Log is the database the htlc events are persisted to.
+	Log *channeldb.HtlcEventLog
+
+	// MaxAge is the age after which persisted htlc events are removed. A
+	// zero value doesn't limit the age of the events.
+	MaxAge time.Duration
+
+	// MaxCount is the maximum number of persisted htlc events. A zero
+	// value doesn't limit the number of events.
+	MaxCount uint64
+
+	// SubscribeHtlcEvents returns a subscription client for the node's
+	// htlc events.
+	SubscribeHtlcEvents func() (*subscribe.Client, error)
+}
+
+// HtlcEventStore persists a rolling window of the node's htlc events, so that
+// they can be queried after the fact. This complements the SubscribeHtlcEvents
+// stream, whose events are lost if no client is subscribed at the time.
+type HtlcEventStore struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg *HtlcEventStoreConfig
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewHtlcEventStore creates a new htlc event store from the given config.
+func NewHtlcEventStore(cfg *HtlcEventStoreConfig) *HtlcEventStore {
+	return &HtlcEventStore{
+		cfg:  cfg,
+		quit: make(chan struct{}),
+	}
+}
+
+// Start subscribes to the node's htlc events and starts persisting them. The
+// htlc event notifier must be started before the store.
+func (h *HtlcEventStore) Start() error {
+	var err error
+	h.started.Do(func() {
+		log.Info("Htlc event store starting")
+
+		var htlcClient *subscribe.Client
+		htlcClient, err = h.cfg.SubscribeHtlcEvents()
+		if err != nil {
+			return
+		}
+
+		h.wg.Add(1)
+		go h.storeHtlcEvents(htlcClient)
+	})
+
+	return err
+}
+
+// Stop signals the store to stop persisting htlc events.
+func (h *HtlcEventStore) Stop() {
+	h.stopped.Do(func() {
+		log.Info("Htlc event store shutting down")
+
+		close(h.quit)
+		h.wg.Wait()
+	})
+}
+
+// prune removes the persisted htlc events that exceed the configured maximum
+// age or count.
+func (h *HtlcEventStore) prune() {
+	var before time.Time
+	if h.cfg.MaxAge != 0 {
+		before = time.Now().Add(-h.cfg.MaxAge)
+	}
+
+	numRemoved, err := h.cfg.Log.Prune(before, h.cfg.MaxCount)
+	if err != nil {
+		log.Errorf("Unable to prune htlc events: %v", err)
+		return
+	}
+
+	if numRemoved > 0 {
+		log.Debugf("Pruned %d persisted htlc events", numRemoved)
+	}
+}
+
+// storeHtlcEvents persists the htlc events delivered by the passed client
+// until the store is stopped, periodically pruning the events that fall
+// outside of the configured window.
+//
+// NOTE: This MUST be run as a goroutine.
+func (h *HtlcEventStore) storeHtlcEvents(htlcClient *subscribe.Client) {
+	defer h.wg.Done()
+	defer htlcClient.Cancel()
+
+	pruneTicker := time.NewTicker(htlcEventPruneInterval)
+	defer pruneTicker.Stop()
+
+	h.prune()
+
+	for {
+		select {
+		case event, ok := <-htlcClient.Updates():
+			if !ok {
+				return
+			}
+
+			rpcEvent, err := rpcHtlcEvent(event)
+			if err != nil {
+				log.Errorf("Unable to convert htlc event: %v",
+					err)
+				continue
+			}
+
+			record, err := newHtlcEventRecord(rpcEvent)
+			if err != nil {
+				log.Errorf("Unable to serialize htlc event: %v",
+					err)
+				continue
+			}
+
+			err = h.cfg.Log.AddHtlcEvents(
+				[]channeldb.HtlcEventRecord{*record},
+			)
+			if err != nil {
+				log.Errorf("Unable to persist htlc event: %v",
+					err)
+			}
+
+		case <-pruneTicker.C:
+			h.prune()
+
+		case <-h.quit:
+			return
+		}
+	}
+}
+
+// QueryHtlcEvents returns the htlc events persisted by the node, filtered by
+// time, channel and event type.
+func (s *Server) QueryHtlcEvents(ctx context.Context,
+	req *QueryHtlcEventsRequest) (*QueryHtlcEventsResponse, error) {
+
+	if s.cfg.HtlcEventStore == nil {
+		return nil, errHtlcEventStoreDisabled
+	}
+
+	endTime := time.Now()
+	if req.EndTime != 0 {
+		endTime = time.Unix(int64(req.EndTime), 0)
+	}
+	startTime := time.Unix(int64(req.StartTime), 0)
+	if startTime.After(endTime) {
+		return nil, fmt.Errorf("start time %v is after end time %v",
+			startTime, endTime)
+	}
+
+	numMaxEvents := req.NumMaxEvents
+	if numMaxEvents == 0 {
+		numMaxEvents = defaultMaxHtlcEvents
+	}
+
+	htlcLog := s.cfg.HtlcEventStore.cfg.Log
+	slice, err := htlcLog.Query(channeldb.HtlcEventQuery{
+		StartTime:    startTime,
+		EndTime:      endTime,
+		ChanID:       lnwire.NewShortChanIDFromInt(req.ChanId),
+		EventType:    uint8(req.EventType),
+		EventKind:    uint8(req.EventKind),
+		IndexOffset:  req.IndexOffset,
+		NumMaxEvents: numMaxEvents,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &QueryHtlcEventsResponse{
+		HtlcEvents:      make([]*HtlcEvent, 0, len(slice.Records)),
+		LastOffsetIndex: slice.LastIndexOffset,
+	}
+	for _, record := range slice.Records {
+		event := &HtlcEvent{}
+		if err := proto.Unmarshal(record.Event, event); err != nil {
+			return nil, err
+		}
+
+		resp.HtlcEvents = append(resp.HtlcEvents, event)
+	}
+
+	return resp, nil
+}
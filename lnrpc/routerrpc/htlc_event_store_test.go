@@ -0,0 +1,179 @@
+package routerrpc
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/golang/protobuf/proto"
+	"github.com/stretchr/testify/require"
+)
+
+// TestQueryHtlcEvents asserts that persisted htlc events are returned by
+// QueryHtlcEvents according to the filters of the request.
+func TestQueryHtlcEvents(t *testing.T) {
+	db, cleanUp, err := channeldb.MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	htlcLog := db.HtlcEventLog()
+	server := &Server{
+		cfg: &Config{
+			HtlcEventStore: NewHtlcEventStore(&HtlcEventStoreConfig{
+				Log: htlcLog,
+			}),
+		},
+	}
+
+	timestamp := time.Unix(1000, 0)
+	key := htlcswitch.HtlcKey{
+		IncomingCircuit: channeldb.CircuitKey{
+			ChanID: lnwire.NewShortChanIDFromInt(1),
+			HtlcID: 2,
+		},
+		OutgoingCircuit: channeldb.CircuitKey{
+			ChanID: lnwire.NewShortChanIDFromInt(3),
+			HtlcID: 4,
+		},
+	}
+	events := []interface{}{
+		&htlcswitch.ForwardingEvent{
+			HtlcKey: key,
+			HtlcInfo: htlcswitch.HtlcInfo{
+				IncomingAmt: 1000,
+				OutgoingAmt: 900,
+			},
+			HtlcEventType: htlcswitch.HtlcEventTypeForward,
+			Timestamp:     timestamp,
+		},
+		&htlcswitch.ForwardingFailEvent{
+			HtlcKey:       key,
+			HtlcEventType: htlcswitch.HtlcEventTypeForward,
+			Timestamp:     timestamp.Add(time.Second),
+		},
+		&htlcswitch.SettleEvent{
+			HtlcEventType: htlcswitch.HtlcEventTypeReceive,
+			Timestamp:     timestamp.Add(2 * time.Second),
+		},
+	}
+
+	var rpcEvents []*HtlcEvent
+	for _, event := range events {
+		rpcEvent, err := rpcHtlcEvent(event)
+		require.NoError(t, err)
+		rpcEvents = append(rpcEvents, rpcEvent)
+
+		record, err := newHtlcEventRecord(rpcEvent)
+		require.NoError(t, err)
+		err = htlcLog.AddHtlcEvents(
+			[]channeldb.HtlcEventRecord{*record},
+		)
+		require.NoError(t, err)
+	}
+
+	tests := []struct {
+		name     string
+		req      *QueryHtlcEventsRequest
+		expected []*HtlcEvent
+	}{
+		{
+			name:     "all events",
+			req:      &QueryHtlcEventsRequest{},
+			expected: rpcEvents,
+		},
+		{
+			name: "time range",
+			req: &QueryHtlcEventsRequest{
+				StartTime: 1001,
+				EndTime:   1001,
+			},
+			expected: rpcEvents[1:2],
+		},
+		{
+			name: "channel",
+			req: &QueryHtlcEventsRequest{
+				ChanId: 3,
+			},
+			expected: rpcEvents[:2],
+		},
+		{
+			name: "event type",
+			req: &QueryHtlcEventsRequest{
+				EventType: HtlcEvent_RECEIVE,
+			},
+			expected: rpcEvents[2:],
+		},
+		{
+			name: "event kind",
+			req: &QueryHtlcEventsRequest{
+				EventKind: HtlcEventKind_FORWARD_FAIL_EVENT,
+			},
+			expected: rpcEvents[1:2],
+		},
+		{
+			name: "pagination",
+			req: &QueryHtlcEventsRequest{
+				IndexOffset:  1,
+				NumMaxEvents: 1,
+			},
+			expected: rpcEvents[1:2],
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := server.QueryHtlcEvents(
+				context.Background(), test.req,
+			)
+			require.NoError(t, err)
+			require.Len(t, resp.HtlcEvents, len(test.expected))
+
+			for i, event := range resp.HtlcEvents {
+				require.True(
+					t, proto.Equal(test.expected[i], event),
+				)
+			}
+		})
+	}
+
+	// Queries fail if the htlc event store is disabled.
+	server.cfg.HtlcEventStore = nil
+	_, err = server.QueryHtlcEvents(
+		context.Background(), &QueryHtlcEventsRequest{},
+	)
+	require.Equal(t, errHtlcEventStoreDisabled, err)
+}
+
+// TestHtlcEventStorePruneNoMaxAge asserts that the store doesn't remove any
+// persisted htlc events by age when no maximum age is configured.
+func TestHtlcEventStorePruneNoMaxAge(t *testing.T) {
+	db, cleanUp, err := channeldb.MakeTestDB()
+	require.NoError(t, err)
+	defer cleanUp()
+
+	htlcLog := db.HtlcEventLog()
+	store := NewHtlcEventStore(&HtlcEventStoreConfig{
+		Log: htlcLog,
+	})
+
+	// Persist an old event and a recent one.
+	records := []channeldb.HtlcEventRecord{
+		{Timestamp: time.Unix(1000, 0), Event: []byte{1}},
+		{Timestamp: time.Now(), Event: []byte{2}},
+	}
+	require.NoError(t, htlcLog.AddHtlcEvents(records))
+
+	store.prune()
+
+	slice, err := htlcLog.Query(channeldb.HtlcEventQuery{
+		StartTime:    time.Unix(0, 0),
+		EndTime:      time.Now().Add(time.Hour),
+		NumMaxEvents: 10,
+	})
+	require.NoError(t, err)
+	require.Len(t, slice.Records, 2)
+}
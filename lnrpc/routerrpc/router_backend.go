@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	math "math"
+	"sort"
+	"sync/atomic"
 	"time"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
@@ -22,8 +24,18 @@ import (
 	"github.com/decred/dcrlnd/routing/route"
 	"github.com/decred/dcrlnd/subscribe"
 	"github.com/decred/dcrlnd/zpay32"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// ErrPaymentsBlocked is returned when a payment is attempted while new
+// payments are blocked.
+var ErrPaymentsBlocked = errors.New("new payments are blocked")
+
+// MaxQueryRoutes is the maximum number of routes that can be requested from
+// QueryRoutes at once.
+const MaxQueryRoutes = 10
+
 // RouterBackend contains the backend implementation of the router rpc sub
 // server calls.
 type RouterBackend struct {
@@ -44,7 +56,7 @@ type RouterBackend struct {
 
 	// FindRoute is a closure that abstracts away how we locate/query for
 	// routes.
-	FindRoute func(source, target route.Vertex,
+	FindRoute func(ctx context.Context, source, target route.Vertex,
 		amt lnwire.MilliAtom, restrictions *routing.RestrictParams,
 		destCustomRecords record.CustomSet,
 		routeHints map[route.Vertex][]*channeldb.ChannelEdgePolicy,
@@ -77,6 +89,34 @@ type RouterBackend struct {
 	// InterceptableForwarder exposes the ability to intercept forward events
 	// by letting the router register a ForwardInterceptor.
 	InterceptableForwarder htlcswitch.InterceptableHtlcForwarder
+
+	// ReservePaymentBudget reserves the maximum amount of the payment with
+	// the given hash against the spending budgets of the macaroon used to
+	// authenticate the request of the passed context. The returned
+	// function, nil if the macaroon carries no budget, must be called with
+	// the result of dispatching the payment.
+	ReservePaymentBudget func(ctx context.Context, hash lntypes.Hash,
+		amt lnwire.MilliAtom) (func(error), error)
+
+	// paymentsBlocked is non-zero if new payments are refused. It must be
+	// accessed atomically.
+	paymentsBlocked int32
+}
+
+// PaymentsBlocked returns true if new payments are refused.
+func (r *RouterBackend) PaymentsBlocked() bool {
+	return atomic.LoadInt32(&r.paymentsBlocked) == 1
+}
+
+// SetPaymentsBlocked sets whether new payments are refused. While blocked, any
+// request to send a payment or a route fails with ErrPaymentsBlocked. Payments
+// that are already in flight are unaffected.
+func (r *RouterBackend) SetPaymentsBlocked(blocked bool) {
+	var value int32
+	if blocked {
+		value = 1
+	}
+	atomic.StoreInt32(&r.paymentsBlocked, value)
 }
 
 // MissionControl defines the mission control dependencies of routerrpc.
@@ -98,6 +138,14 @@ type MissionControl interface {
 	// pair.
 	GetPairHistorySnapshot(fromNode,
 		toNode route.Vertex) routing.TimedPairResult
+
+	// GetEstimatorConfig returns the parameters currently used by the
+	// probability estimator.
+	GetEstimatorConfig() routing.EstimatorConfig
+
+	// SetEstimatorConfig replaces the parameters of the probability
+	// estimator.
+	SetEstimatorConfig(cfg routing.EstimatorConfig) error
 }
 
 // QueryRoutes attempts to query the daemons' Channel Router for a possible
@@ -152,6 +200,16 @@ func (r *RouterBackend) QueryRoutes(ctx context.Context,
 			"allowed is %v", amt, r.MaxPaymentMAtoms.ToAtoms())
 	}
 
+	numRoutes := int(in.NumRoutes)
+	switch {
+	case numRoutes == 0:
+		numRoutes = 1
+
+	case numRoutes > MaxQueryRoutes:
+		return nil, fmt.Errorf("num_routes of %v exceeds the maximum "+
+			"of %v", numRoutes, MaxQueryRoutes)
+	}
+
 	// Unmarshall restrictions from request.
 	feeLimit := lnrpc.CalculateFeeLimit(in.FeeLimit, amt)
 
@@ -286,37 +344,90 @@ func (r *RouterBackend) QueryRoutes(ctx context.Context,
 		return nil, err
 	}
 
-	// Query the channel router for a possible path to the destination that
-	// can carry `in.Amt` satoshis _including_ the total fee required on
-	// the route.
-	route, err := r.FindRoute(
-		sourcePubKey, targetPubKey, amt, restrictions,
-		customRecords, routeHintEdges, finalCLTVDelta,
-	)
-	if err != nil {
-		return nil, err
-	}
+	// Query the channel router for possible paths to the destination that
+	// can carry `in.Amt` atoms _including_ the total fee required on the
+	// route. After each path found, we'll ignore all of its node pairs so
+	// that the next one is disjoint from the previous ones.
+	var routes []*route.Route
+	for len(routes) < numRoutes {
+		rt, err := r.FindRoute(
+			ctx, sourcePubKey, targetPubKey, amt, restrictions,
+			customRecords, routeHintEdges, finalCLTVDelta,
+		)
+		if err != nil {
+			// Only fail if not even a single route could be found.
+			if len(routes) == 0 {
+				return nil, err
+			}
 
-	// For each valid route, we'll convert the result into the format
-	// required by the RPC system.
-	rpcRoute, err := r.MarshallRoute(route)
-	if err != nil {
-		return nil, err
+			log.Debugf("Found %v of %v requested routes: %v",
+				len(routes), numRoutes, err)
+			break
+		}
+
+		routes = append(routes, rt)
+
+		fromNode := rt.SourcePubKey
+		for _, hop := range rt.Hops {
+			pair := routing.NewDirectedNodePair(
+				fromNode, hop.PubKeyBytes,
+			)
+			ignoredPairs[pair] = struct{}{}
+			fromNode = hop.PubKeyBytes
+		}
 	}
 
-	// Calculate route success probability. Do not rely on a probability
+	// Calculate route success probabilities. Do not rely on a probability
 	// that could have been returned from path finding, because mission
 	// control may have been disabled in the provided ProbabilitySource.
-	successProb := r.getSuccessProbability(route)
+	successProbs := make([]float64, len(routes))
+	for i, rt := range routes {
+		successProbs[i] = r.getSuccessProbability(rt)
+	}
+
+	// Rank the routes by the fee that is expected to be paid for a
+	// successful payment, which is their fee divided by their success
+	// probability.
+	order := make([]int, len(routes))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return expectedFee(routes[order[i]], successProbs[order[i]]) <
+			expectedFee(routes[order[j]], successProbs[order[j]])
+	})
 
 	routeResp := &lnrpc.QueryRoutesResponse{
-		Routes:      []*lnrpc.Route{rpcRoute},
-		SuccessProb: successProb,
+		SuccessProbs: make([]float64, 0, len(routes)),
+	}
+	for _, i := range order {
+		// For each valid route, we'll convert the result into the
+		// format required by the RPC system.
+		rpcRoute, err := r.MarshallRoute(routes[i])
+		if err != nil {
+			return nil, err
+		}
+
+		routeResp.Routes = append(routeResp.Routes, rpcRoute)
+		routeResp.SuccessProbs = append(
+			routeResp.SuccessProbs, successProbs[i],
+		)
 	}
+	routeResp.SuccessProb = routeResp.SuccessProbs[0]
 
 	return routeResp, nil
 }
 
+// expectedFee returns the fee of the given route divided by its success
+// probability. Routes that are certain to fail are ranked last.
+func expectedFee(rt *route.Route, successProb float64) float64 {
+	if successProb <= 0 {
+		return math.Inf(1)
+	}
+
+	return float64(rt.TotalFees()) / successProb
+}
+
 // getSuccessProbability returns the success probability for the given route
 // based on the current state of mission control.
 func (r *RouterBackend) getSuccessProbability(rt *route.Route) float64 {
@@ -524,6 +635,10 @@ func (r *RouterBackend) UnmarshallRoute(rpcroute *lnrpc.Route) (
 func (r *RouterBackend) extractIntentFromSendRequest(
 	rpcPayReq *SendPaymentRequest) (*routing.LightningPayment, error) {
 
+	if r.PaymentsBlocked() {
+		return nil, ErrPaymentsBlocked
+	}
+
 	payIntent := &routing.LightningPayment{}
 
 	// Pass along restrictions on the outgoing channels that may be used.
@@ -540,6 +655,7 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 			payIntent.OutgoingChannelIDs, rpcPayReq.OutgoingChanId,
 		)
 	}
+	payIntent.ExcludedOutgoingChannelIDs = rpcPayReq.ExcludeOutgoingChanIds
 
 	// Pass along a last hop restriction if specified.
 	if len(rpcPayReq.LastHopPubkey) > 0 {
@@ -564,11 +680,25 @@ func (r *RouterBackend) extractIntentFromSendRequest(
 	// Take max htlcs from the request. Map zero to one for backwards
 	// compatibility.
 	maxParts := rpcPayReq.MaxParts
+	if rpcPayReq.NoSplit {
+		if maxParts > 1 {
+			return nil, errors.New("no_split and max_parts above " +
+				"one are mutually exclusive")
+		}
+		maxParts = 1
+	}
 	if maxParts == 0 {
 		maxParts = 1
 	}
 	payIntent.MaxParts = maxParts
 
+	// Take the limits on the shard sizes from the request.
+	if rpcPayReq.MaxShardSizeMAtoms != 0 {
+		maxShardAmt := lnwire.MilliAtom(rpcPayReq.MaxShardSizeMAtoms)
+		payIntent.MaxShardAmt = &maxShardAmt
+	}
+	payIntent.MinShardAmt = lnwire.MilliAtom(rpcPayReq.MinShardSizeMAtoms)
+
 	// Take fee limit from request.
 	payIntent.FeeLimit, err = lnrpc.UnmarshallAmt(
 		rpcPayReq.FeeLimitAtoms, rpcPayReq.FeeLimitMAtoms,
@@ -866,8 +996,10 @@ func (r *RouterBackend) MarshalHTLCAttempt(
 	}
 
 	rpcAttempt := &lnrpc.HTLCAttempt{
-		AttemptTimeNs: MarshalTimeNano(htlc.AttemptTime),
-		Route:         route,
+		AttemptTimeNs:     MarshalTimeNano(htlc.AttemptTime),
+		PathfindingTimeNs: int64(htlc.PathFindingTime),
+		Route:             route,
+		FirstHopRetry:     htlc.FirstHopRetry,
 	}
 
 	switch {
@@ -1106,24 +1238,105 @@ func marshallChannelUpdate(update *lnwire.ChannelUpdate) *lnrpc.ChannelUpdate {
 	}
 }
 
+// TrackPayment sends the state updates of the payment with the given hash,
+// taken from the control tower, to the send function. The first update is the
+// current state of the payment, and nil is returned once the payment completes.
+// If the quit channel is closed, errQuit is returned.
+func (r *RouterBackend) TrackPayment(ctx context.Context,
+	paymentHash lntypes.Hash, noInflightUpdates bool,
+	send func(*lnrpc.Payment) error, quit <-chan struct{},
+	errQuit error) error {
+
+	// Subscribe to the outcome of this payment.
+	subscription, err := r.Tower.SubscribePayment(paymentHash)
+	switch {
+	case err == channeldb.ErrPaymentNotInitiated:
+		return status.Error(codes.NotFound, err.Error())
+	case err != nil:
+		return err
+	}
+	defer subscription.Close()
+
+	// Stream updates back to the client. The first update is always the
+	// current state of the payment.
+	for {
+		select {
+		case item, ok := <-subscription.Updates:
+			if !ok {
+				// No more payment updates.
+				return nil
+			}
+			result := item.(*channeldb.MPPayment)
+
+			// Skip in-flight updates unless requested.
+			if noInflightUpdates &&
+				result.Status == channeldb.StatusInFlight {
+
+				continue
+			}
+
+			rpcPayment, err := r.MarshallPayment(result)
+			if err != nil {
+				return err
+			}
+
+			// Send event to the client.
+			if err := send(rpcPayment); err != nil {
+				return err
+			}
+
+		case <-quit:
+			return errQuit
+
+		case <-ctx.Done():
+			log.Debugf("Payment status stream %v canceled",
+				paymentHash)
+			return ctx.Err()
+		}
+	}
+}
+
 // MarshallPayment marshall a payment to its rpc representation.
 func (r *RouterBackend) MarshallPayment(payment *channeldb.MPPayment) (
 	*lnrpc.Payment, error) {
 
-	// Fetch the payment's preimage and the total paid in fees.
+	// Fetch the payment's preimage, the total paid in fees and its timings.
 	var (
-		fee      lnwire.MilliAtom
-		preimage lntypes.Preimage
+		fee             lnwire.MilliAtom
+		preimage        lntypes.Preimage
+		pathFindingTime time.Duration
+		resolveTime     time.Time
 	)
 	for _, htlc := range payment.HTLCs {
+		pathFindingTime += htlc.PathFindingTime
+
 		// If any of the htlcs have settled, extract a valid
 		// preimage.
 		if htlc.Settle != nil {
 			preimage = htlc.Settle.Preimage
 			fee += htlc.Route.TotalFees()
+
+			if htlc.Settle.SettleTime.After(resolveTime) {
+				resolveTime = htlc.Settle.SettleTime
+			}
+		}
+		if htlc.Failure != nil &&
+			htlc.Failure.FailTime.After(resolveTime) {
+
+			resolveTime = htlc.Failure.FailTime
 		}
 	}
 
+	// The duration is only known once the payment reached a final state
+	// with at least one resolved htlc.
+	var duration time.Duration
+	if (payment.Status == channeldb.StatusSucceeded ||
+		payment.Status == channeldb.StatusFailed) &&
+		!resolveTime.IsZero() {
+
+		duration = resolveTime.Sub(payment.Info.CreationTime)
+	}
+
 	matomsValue := int64(payment.Info.Value)
 	atomsValue := int64(payment.Info.Value.ToAtoms())
 
@@ -1153,21 +1366,23 @@ func (r *RouterBackend) MarshallPayment(payment *channeldb.MPPayment) (
 	}
 
 	return &lnrpc.Payment{
-		PaymentHash:     hex.EncodeToString(paymentHash[:]),
-		Value:           atomsValue,
-		ValueMAtoms:     matomsValue,
-		ValueAtoms:      atomsValue,
-		CreationDate:    payment.Info.CreationTime.Unix(),
-		CreationTimeNs:  creationTimeNS,
-		Fee:             int64(fee.ToAtoms()),
-		FeeAtoms:        int64(fee.ToAtoms()),
-		FeeMAtoms:       int64(fee),
-		PaymentPreimage: hex.EncodeToString(preimage[:]),
-		PaymentRequest:  string(payment.Info.PaymentRequest),
-		Status:          status,
-		Htlcs:           htlcs,
-		PaymentIndex:    payment.SequenceNum,
-		FailureReason:   failureReason,
+		PaymentHash:       hex.EncodeToString(paymentHash[:]),
+		Value:             atomsValue,
+		ValueMAtoms:       matomsValue,
+		ValueAtoms:        atomsValue,
+		CreationDate:      payment.Info.CreationTime.Unix(),
+		CreationTimeNs:    creationTimeNS,
+		Fee:               int64(fee.ToAtoms()),
+		FeeAtoms:          int64(fee.ToAtoms()),
+		FeeMAtoms:         int64(fee),
+		PaymentPreimage:   hex.EncodeToString(preimage[:]),
+		PaymentRequest:    string(payment.Info.PaymentRequest),
+		Status:            status,
+		Htlcs:             htlcs,
+		PaymentIndex:      payment.SequenceNum,
+		FailureReason:     failureReason,
+		PathfindingTimeNs: int64(pathFindingTime),
+		DurationNs:        int64(duration),
 	}, nil
 }
 
@@ -1219,6 +1434,9 @@ func marshallPaymentFailureReason(reason *channeldb.FailureReason) (
 
 	case channeldb.FailureReasonInsufficientBalance:
 		return lnrpc.PaymentFailureReason_FAILURE_REASON_INSUFFICIENT_BALANCE, nil
+
+	case channeldb.FailureReasonCanceled:
+		return lnrpc.PaymentFailureReason_FAILURE_REASON_CANCELED, nil
 	}
 
 	return 0, errors.New("unknown failure reason")
@@ -4,7 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/hex"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/channeldb"
@@ -117,7 +119,7 @@ func testQueryRoutes(t *testing.T, useMissionControl bool, useMAtoms bool) {
 		}
 	}
 
-	findRoute := func(source, target route.Vertex,
+	findRoute := func(_ context.Context, source, target route.Vertex,
 		amt lnwire.MilliAtom, restrictions *routing.RestrictParams,
 		_ record.CustomSet,
 		routeHints map[route.Vertex][]*channeldb.ChannelEdgePolicy,
@@ -216,6 +218,102 @@ func testQueryRoutes(t *testing.T, useMissionControl bool, useMAtoms bool) {
 	}
 }
 
+// TestQueryRoutesMultiple asserts that multiple disjoint routes are returned
+// ranked by their fee when requested.
+func TestQueryRoutesMultiple(t *testing.T) {
+	destNode, err := route.NewVertexFromStr(destKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The candidate paths to the destination in the order path finding
+	// would return them, along with the fee of the route.
+	candidates := []struct {
+		via route.Vertex
+		fee lnwire.MilliAtom
+	}{
+		{via: node1, fee: 5000},
+		{via: node2, fee: 1000},
+		{via: node1, fee: 2000},
+	}
+
+	findRoute := func(_ context.Context, source, target route.Vertex,
+		amt lnwire.MilliAtom, restrictions *routing.RestrictParams,
+		_ record.CustomSet,
+		_ map[route.Vertex][]*channeldb.ChannelEdgePolicy,
+		_ uint16) (*route.Route, error) {
+
+		for _, c := range candidates {
+			prob := restrictions.ProbabilitySource
+			if prob(source, c.via, amt) == 0 ||
+				prob(c.via, target, amt) == 0 {
+
+				continue
+			}
+
+			hops := []*route.Hop{
+				{PubKeyBytes: c.via, AmtToForward: amt},
+				{PubKeyBytes: target, AmtToForward: amt},
+			}
+			return route.NewRouteFromHops(
+				amt+c.fee, 144, source, hops,
+			)
+		}
+
+		return nil, errors.New("no route")
+	}
+
+	backend := &RouterBackend{
+		MaxPaymentMAtoms: lnwire.NewMAtomsFromAtoms(1000000),
+		FindRoute:        findRoute,
+		SelfNode:         sourceKey,
+		FetchChannelCapacity: func(chanID uint64) (
+			dcrutil.Amount, error) {
+
+			return 1, nil
+		},
+		MissionControl: &mockMissionControl{},
+	}
+
+	request := &lnrpc.QueryRoutesRequest{
+		PubKey:    destKey,
+		Amt:       100000,
+		NumRoutes: 3,
+	}
+
+	// Only two routes are disjoint, and the cheapest one should come
+	// first.
+	resp, err := backend.QueryRoutes(context.Background(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Routes) != 2 || len(resp.SuccessProbs) != 2 {
+		t.Fatalf("expected 2 routes, got %v", len(resp.Routes))
+	}
+	for i, via := range []route.Vertex{node2, node1} {
+		hops := resp.Routes[i].Hops
+		if hops[0].PubKey != hex.EncodeToString(via[:]) ||
+			hops[1].PubKey != hex.EncodeToString(destNode[:]) {
+
+			t.Fatalf("unexpected hops of route %v: %v", i, hops)
+		}
+	}
+	if resp.Routes[0].TotalFeesMAtoms != 1000 ||
+		resp.Routes[1].TotalFeesMAtoms != 5000 {
+
+		t.Fatalf("routes not ranked by fee")
+	}
+	if resp.SuccessProb != resp.SuccessProbs[0] {
+		t.Fatalf("unexpected success probability")
+	}
+
+	request.NumRoutes = MaxQueryRoutes + 1
+	_, err = backend.QueryRoutes(context.Background(), request)
+	if err == nil {
+		t.Fatal("expected too many routes to be rejected")
+	}
+}
+
 type mockMissionControl struct {
 }
 
@@ -239,6 +337,16 @@ func (m *mockMissionControl) GetPairHistorySnapshot(fromNode,
 	return routing.TimedPairResult{}
 }
 
+func (m *mockMissionControl) GetEstimatorConfig() routing.EstimatorConfig {
+	return routing.EstimatorConfig{}
+}
+
+func (m *mockMissionControl) SetEstimatorConfig(
+	cfg routing.EstimatorConfig) error {
+
+	return nil
+}
+
 type mppOutcome byte
 
 const (
@@ -358,3 +466,115 @@ func testUnmarshalMPP(t *testing.T, test unmarshalMPPTest) {
 		t.Fatalf("test case has non-standard outcome")
 	}
 }
+
+// TestPaymentsBlocked asserts that no payments can be sent while payments are
+// blocked.
+func TestPaymentsBlocked(t *testing.T) {
+	backend := &RouterBackend{}
+
+	backend.SetPaymentsBlocked(true)
+	if !backend.PaymentsBlocked() {
+		t.Fatalf("payments not blocked")
+	}
+
+	_, err := backend.extractIntentFromSendRequest(&SendPaymentRequest{})
+	if err != ErrPaymentsBlocked {
+		t.Fatalf("expected payments blocked error, got: %v", err)
+	}
+
+	server := &Server{cfg: &Config{RouterBackend: backend}}
+	_, err = server.SendToRouteV2(
+		context.Background(), &SendToRouteRequest{},
+	)
+	if err != ErrPaymentsBlocked {
+		t.Fatalf("expected payments blocked error, got: %v", err)
+	}
+
+	// Once allowed again, the request is processed and fails on its
+	// missing parameters instead.
+	backend.SetPaymentsBlocked(false)
+	_, err = backend.extractIntentFromSendRequest(&SendPaymentRequest{})
+	if err == nil || err == ErrPaymentsBlocked {
+		t.Fatalf("expected invalid request error, got: %v", err)
+	}
+}
+
+// TestMarshallPaymentTimings asserts that the path finding time and the
+// duration of a payment are derived from its htlcs.
+func TestMarshallPaymentTimings(t *testing.T) {
+	backend := &RouterBackend{
+		FetchChannelCapacity: func(chanID uint64) (dcrutil.Amount,
+			error) {
+
+			return 1, nil
+		},
+	}
+
+	created := time.Unix(1000, 0)
+	rt := route.Route{
+		TotalAmount: 1000,
+		Hops: []*route.Hop{
+			{ChannelID: 1, PubKeyBytes: node1, AmtToForward: 1000},
+		},
+	}
+	payment := &channeldb.MPPayment{
+		Info: &channeldb.PaymentCreationInfo{
+			CreationTime: created,
+		},
+		HTLCs: []channeldb.HTLCAttempt{
+			{
+				HTLCAttemptInfo: channeldb.HTLCAttemptInfo{
+					Route:           rt,
+					AttemptTime:     created,
+					PathFindingTime: 30 * time.Millisecond,
+				},
+				Failure: &channeldb.HTLCFailInfo{
+					FailTime: created.Add(time.Second),
+				},
+			},
+			{
+				HTLCAttemptInfo: channeldb.HTLCAttemptInfo{
+					Route:           rt,
+					AttemptTime:     created.Add(time.Second),
+					PathFindingTime: 20 * time.Millisecond,
+				},
+			},
+		},
+		Status: channeldb.StatusInFlight,
+	}
+
+	rpcPayment, err := backend.MarshallPayment(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpcPayment.PathfindingTimeNs != int64(50*time.Millisecond) {
+		t.Fatalf("unexpected path finding time: %v",
+			time.Duration(rpcPayment.PathfindingTimeNs))
+	}
+	if rpcPayment.Htlcs[1].PathfindingTimeNs != int64(20*time.Millisecond) {
+		t.Fatalf("unexpected htlc path finding time: %v",
+			time.Duration(rpcPayment.Htlcs[1].PathfindingTimeNs))
+	}
+
+	// The duration isn't known while the payment is in flight.
+	if rpcPayment.DurationNs != 0 {
+		t.Fatalf("unexpected duration of in flight payment: %v",
+			time.Duration(rpcPayment.DurationNs))
+	}
+
+	// Once the last htlc settles, the duration spans from the creation of
+	// the payment to the settlement.
+	payment.HTLCs[1].Settle = &channeldb.HTLCSettleInfo{
+		SettleTime: created.Add(3 * time.Second),
+	}
+	payment.Status = channeldb.StatusSucceeded
+
+	rpcPayment, err = backend.MarshallPayment(payment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rpcPayment.DurationNs != int64(3*time.Second) {
+		t.Fatalf("unexpected duration: %v",
+			time.Duration(rpcPayment.DurationNs))
+	}
+}
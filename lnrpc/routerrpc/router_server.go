@@ -5,9 +5,11 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"math"
 	"os"
 	"path/filepath"
 	"sync/atomic"
+	"time"
 
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/channeldb"
@@ -83,6 +85,14 @@ var (
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/routerrpc.Router/GetMissionControlConfig": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/routerrpc.Router/SetMissionControlConfig": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/routerrpc.Router/ResetMissionControl": {{
 			Entity: "offchain",
 			Action: "write",
@@ -95,6 +105,10 @@ var (
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/routerrpc.Router/QueryHtlcEvents": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
 		"/routerrpc.Router/SendPayment": {{
 			Entity: "offchain",
 			Action: "write",
@@ -273,7 +287,24 @@ func (s *Server) SendPaymentV2(req *SendPaymentRequest,
 		return err
 	}
 
+	// If the macaroon of the request carries a spending budget, the
+	// maximum amount of the payment including fees is reserved against
+	// it until the payment is resolved.
+	var dispatched func(error)
+	if s.cfg.RouterBackend.ReservePaymentBudget != nil {
+		dispatched, err = s.cfg.RouterBackend.ReservePaymentBudget(
+			stream.Context(), payment.PaymentHash,
+			payment.Amount+payment.FeeLimit,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	err = s.cfg.Router.SendPaymentAsync(payment)
+	if dispatched != nil {
+		dispatched(err)
+	}
 	if err != nil {
 		// Transform user errors to grpc code.
 		if err == channeldb.ErrPaymentInFlight ||
@@ -297,7 +328,8 @@ func (s *Server) SendPaymentV2(req *SendPaymentRequest,
 }
 
 // EstimateRouteFee allows callers to obtain a lower bound w.r.t how much it
-// may cost to send an HTLC to the target end destination.
+// may cost to send an HTLC to the target end destination, along with the time
+// lock and the success probability of the route, without sending a payment.
 func (s *Server) EstimateRouteFee(ctx context.Context,
 	req *RouteFeeRequest) (*RouteFeeResponse, error) {
 
@@ -316,18 +348,27 @@ func (s *Server) EstimateRouteFee(ctx context.Context,
 	// TODO: Change this into behaviour that makes more sense.
 	feeLimit := lnwire.NewMAtomsFromAtoms(dcrutil.AtomsPerCoin)
 
+	if req.FinalCltvDelta > math.MaxUint16 {
+		return nil, fmt.Errorf("final cltv delta %v exceeds maximum "+
+			"of %v", req.FinalCltvDelta, math.MaxUint16)
+	}
+	finalCltvDelta := s.cfg.RouterBackend.DefaultFinalCltvDelta
+	if req.FinalCltvDelta != 0 {
+		finalCltvDelta = uint16(req.FinalCltvDelta)
+	}
+
 	// Finally, we'll query for a route to the destination that can carry
 	// that target amount, we'll only request a single route. Set a
 	// restriction for the default CLTV limit, otherwise we can find a route
 	// that exceeds it and is useless to us.
 	mc := s.cfg.RouterBackend.MissionControl
 	route, err := s.cfg.Router.FindRoute(
-		s.cfg.RouterBackend.SelfNode, destNode, amtMat,
+		ctx, s.cfg.RouterBackend.SelfNode, destNode, amtMat,
 		&routing.RestrictParams{
 			FeeLimit:          feeLimit,
 			CltvLimit:         s.cfg.RouterBackend.MaxTotalTimelock,
 			ProbabilitySource: mc.GetProbability,
-		}, nil, nil, s.cfg.RouterBackend.DefaultFinalCltvDelta,
+		}, nil, nil, finalCltvDelta,
 	)
 	if err != nil {
 		return nil, err
@@ -336,6 +377,7 @@ func (s *Server) EstimateRouteFee(ctx context.Context,
 	return &RouteFeeResponse{
 		RoutingFeeMatoms: int64(route.TotalFees()),
 		TimeLockDelay:    int64(route.TotalTimeLock),
+		SuccessProb:      s.cfg.RouterBackend.getSuccessProbability(route),
 	}, nil
 }
 
@@ -344,6 +386,10 @@ func (s *Server) EstimateRouteFee(ctx context.Context,
 func (s *Server) SendToRouteV2(ctx context.Context,
 	req *SendToRouteRequest) (*lnrpc.HTLCAttempt, error) {
 
+	if s.cfg.RouterBackend.PaymentsBlocked() {
+		return nil, ErrPaymentsBlocked
+	}
+
 	if req.Route == nil {
 		return nil, fmt.Errorf("unable to send, no routes provided")
 	}
@@ -466,6 +512,47 @@ func (s *Server) QueryProbability(ctx context.Context,
 	}, nil
 }
 
+// GetMissionControlConfig returns the parameters currently used by mission
+// control to estimate the success probability of a hop.
+func (s *Server) GetMissionControlConfig(ctx context.Context,
+	req *GetMissionControlConfigRequest) (*GetMissionControlConfigResponse,
+	error) {
+
+	cfg := s.cfg.RouterBackend.MissionControl.GetEstimatorConfig()
+
+	return &GetMissionControlConfigResponse{
+		Config: &MissionControlConfig{
+			HalfLifeSeconds: uint64(cfg.PenaltyHalfLife.Seconds()),
+			HopProbability:  cfg.AprioriHopProbability,
+			Weight:          cfg.AprioriWeight,
+		},
+	}, nil
+}
+
+// SetMissionControlConfig replaces the parameters used by mission control to
+// estimate the success probability of a hop until the node is restarted.
+func (s *Server) SetMissionControlConfig(ctx context.Context,
+	req *SetMissionControlConfigRequest) (*SetMissionControlConfigResponse,
+	error) {
+
+	if req.Config == nil {
+		return nil, errors.New("mission control config required")
+	}
+
+	cfg := routing.EstimatorConfig{
+		PenaltyHalfLife: time.Duration(req.Config.HalfLifeSeconds) *
+			time.Second,
+		AprioriHopProbability: req.Config.HopProbability,
+		AprioriWeight:         req.Config.Weight,
+	}
+	err := s.cfg.RouterBackend.MissionControl.SetEstimatorConfig(cfg)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return &SetMissionControlConfigResponse{}, nil
+}
+
 // TrackPaymentV2 returns a stream of payment state updates. The stream is
 // closed when the payment completes.
 func (s *Server) TrackPaymentV2(request *TrackPaymentRequest,
@@ -485,63 +572,20 @@ func (s *Server) TrackPaymentV2(request *TrackPaymentRequest,
 func (s *Server) trackPayment(paymentHash lntypes.Hash,
 	stream Router_TrackPaymentV2Server, noInflightUpdates bool) error {
 
-	router := s.cfg.RouterBackend
-
-	// Subscribe to the outcome of this payment.
-	subscription, err := router.Tower.SubscribePayment(
-		paymentHash,
+	return s.cfg.RouterBackend.TrackPayment(
+		stream.Context(), paymentHash, noInflightUpdates, stream.Send,
+		s.quit, errServerShuttingDown,
 	)
-	switch {
-	case err == channeldb.ErrPaymentNotInitiated:
-		return status.Error(codes.NotFound, err.Error())
-	case err != nil:
-		return err
-	}
-	defer subscription.Close()
-
-	// Stream updates back to the client. The first update is always the
-	// current state of the payment.
-	for {
-		select {
-		case item, ok := <-subscription.Updates:
-			if !ok {
-				// No more payment updates.
-				return nil
-			}
-			result := item.(*channeldb.MPPayment)
-
-			// Skip in-flight updates unless requested.
-			if noInflightUpdates &&
-				result.Status == channeldb.StatusInFlight {
-
-				continue
-			}
-
-			rpcPayment, err := router.MarshallPayment(result)
-			if err != nil {
-				return err
-			}
-
-			// Send event to the client.
-			err = stream.Send(rpcPayment)
-			if err != nil {
-				return err
-			}
-
-		case <-s.quit:
-			return errServerShuttingDown
-
-		case <-stream.Context().Done():
-			log.Debugf("Payment status stream %v canceled", paymentHash)
-			return stream.Context().Err()
-		}
-	}
 }
 
 // BuildRoute builds a route from a list of hop addresses.
 func (s *Server) BuildRoute(ctx context.Context,
 	req *BuildRouteRequest) (*BuildRouteResponse, error) {
 
+	if len(req.HopPubkeys) == 0 {
+		return nil, errors.New("at least one hop pubkey is required")
+	}
+
 	// Unmarshall hop list.
 	hops := make([]route.Vertex, len(req.HopPubkeys))
 	for i, pubkeyBytes := range req.HopPubkeys {
@@ -564,9 +608,13 @@ func (s *Server) BuildRoute(ctx context.Context,
 		outgoingChan = &req.OutgoingChanId
 	}
 
+	finalCltvDelta := buildRouteFinalCltvDelta(
+		req.FinalCltvDelta, s.cfg.RouterBackend.DefaultFinalCltvDelta,
+	)
+
 	// Build the route and return it to the caller.
 	route, err := s.cfg.Router.BuildRoute(
-		amt, hops, outgoingChan, req.FinalCltvDelta,
+		amt, hops, outgoingChan, finalCltvDelta,
 	)
 	if err != nil {
 		return nil, err
@@ -584,6 +632,18 @@ func (s *Server) BuildRoute(ctx context.Context,
 	return routeResp, nil
 }
 
+// buildRouteFinalCltvDelta returns the final cltv delta of a route built for a
+// caller that requested the given one. The default delta is used when the
+// caller didn't specify one, as a zero delta would produce a route the
+// recipient would reject.
+func buildRouteFinalCltvDelta(reqDelta int32, defaultDelta uint16) int32 {
+	if reqDelta == 0 {
+		return int32(defaultDelta)
+	}
+
+	return reqDelta
+}
+
 // SubscribeHtlcEvents creates a uni-directional stream from the server to
 // the client which delivers a stream of htlc events.
 func (s *Server) SubscribeHtlcEvents(req *SubscribeHtlcEventsRequest,
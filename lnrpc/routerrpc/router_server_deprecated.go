@@ -44,6 +44,9 @@ func (i *legacyTrackPaymentServer) Send(p *lnrpc.Payment) error {
 		case lnrpc.PaymentFailureReason_FAILURE_REASON_INSUFFICIENT_BALANCE:
 			state = PaymentState_FAILED_INSUFFICIENT_BALANCE
 
+		case lnrpc.PaymentFailureReason_FAILURE_REASON_CANCELED:
+			state = PaymentState_FAILED_CANCELED
+
 		default:
 			return fmt.Errorf("unknown failure reason %v",
 				p.FailureReason)
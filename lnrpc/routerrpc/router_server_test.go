@@ -0,0 +1,30 @@
+package routerrpc
+
+import "testing"
+
+// TestBuildRouteFinalCltvDelta asserts that routes are built with the default
+// final cltv delta unless the caller specifies one.
+func TestBuildRouteFinalCltvDelta(t *testing.T) {
+	const defaultDelta = 80
+
+	tests := []struct {
+		name          string
+		reqDelta      int32
+		expectedDelta int32
+	}{{
+		name:          "default delta",
+		expectedDelta: defaultDelta,
+	}, {
+		name:          "custom delta",
+		reqDelta:      40,
+		expectedDelta: 40,
+	}}
+
+	for _, test := range tests {
+		delta := buildRouteFinalCltvDelta(test.reqDelta, defaultDelta)
+		if delta != test.expectedDelta {
+			t.Fatalf("%v: expected final cltv delta %v, got %v",
+				test.name, test.expectedDelta, delta)
+		}
+	}
+}
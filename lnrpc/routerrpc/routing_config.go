@@ -37,4 +37,13 @@ type RoutingConfig struct {
 	// MaxMcHistory defines the maximum number of payment results that
 	// are held on disk by mission control.
 	MaxMcHistory int `long:"maxmchistory" description:"the maximum number of payment results that are held on disk by mission control"`
+
+	// FirstHopRetries is the maximum number of times the route of a
+	// payment attempt is retried after a transient failure of its first
+	// hop, before the failure is reported to mission control.
+	FirstHopRetries uint32 `long:"firsthopretries" description:"The maximum number of times the route of a payment attempt is retried when our own outgoing channel fails it with a transient error, such as a temporary channel failure or a peer that is briefly offline. Set to 0 to disable retries."`
+
+	// FirstHopRetryBackoff is the time waited before the first retry of a
+	// route, doubling with every subsequent retry.
+	FirstHopRetryBackoff time.Duration `long:"firsthopretrybackoff" description:"The time waited before retrying a route after a transient failure of its first hop. The time waited doubles with every subsequent retry of the same route."`
 }
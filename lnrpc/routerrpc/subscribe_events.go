@@ -32,7 +32,9 @@ func rpcHtlcEvent(htlcEvent interface{}) (*HtlcEvent, error) {
 
 	case *htlcswitch.ForwardingFailEvent:
 		event = &HtlcEvent_ForwardFailEvent{
-			ForwardFailEvent: &ForwardFailEvent{},
+			ForwardFailEvent: &ForwardFailEvent{
+				Info: rpcInfo(e.HtlcInfo),
+			},
 		}
 
 		key = e.HtlcKey
@@ -62,7 +64,9 @@ func rpcHtlcEvent(htlcEvent interface{}) (*HtlcEvent, error) {
 
 	case *htlcswitch.SettleEvent:
 		event = &HtlcEvent_SettleEvent{
-			SettleEvent: &SettleEvent{},
+			SettleEvent: &SettleEvent{
+				Info: rpcInfo(e.HtlcInfo),
+			},
 		}
 
 		key = e.HtlcKey
@@ -194,6 +198,12 @@ func rpcFailureResolution(invoiceFailure invoices.FailResolutionResult) (
 	case invoices.ResultMppInProgress:
 		return FailureDetail_MPP_IN_PROGRESS, nil
 
+	case invoices.ResultInvoicesBlocked:
+		return FailureDetail_INVOICES_BLOCKED, nil
+
+	case invoices.ResultHintChannelMismatch:
+		return FailureDetail_HINT_CHANNEL_MISMATCH, nil
+
 	default:
 		return 0, fmt.Errorf("unknown fail resolution: %v",
 			invoiceFailure.FailureString())
@@ -238,6 +248,9 @@ func rpcOutgoingFailure(failureDetail htlcswitch.OutgoingFailure) (
 	case htlcswitch.OutgoingFailureForwardsDisabled:
 		return FailureDetail_FORWARDS_DISABLED, nil
 
+	case htlcswitch.OutgoingFailureReputationBucketFull:
+		return FailureDetail_REPUTATION_BUCKET_FULL, nil
+
 	default:
 		return 0, fmt.Errorf("unknown outgoing failure "+
 			"detail: %v", failureDetail.FailureString())
@@ -35,4 +35,9 @@ type Config struct {
 	// KeyRing is an interface that the signer will use to derive any keys
 	// for signing messages.
 	KeyRing keychain.SecretKeyRing
+
+	// NodeKeyECDH is the ECDH capable wrapper of the identity key the
+	// node is running with, which might be held by an external signer
+	// instead of being derived by the KeyRing.
+	NodeKeyECDH keychain.SingleKeyECDH
 }
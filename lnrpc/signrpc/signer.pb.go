@@ -780,6 +780,8 @@ type SharedKeyRequest struct {
 	//
 	//The optional key locator of the local key that should be used. If this
 	//parameter is not set then the node's identity private key will be used.
+	//The node key locator (family 6, index 0) always refers to the identity key
+	//the node is running with, even when it is held by an external signer.
 	KeyLoc *KeyLocator `protobuf:"bytes,2,opt,name=key_loc,json=keyLoc,proto3" json:"key_loc,omitempty"`
 }
 
@@ -1286,8 +1288,9 @@ type SignerClient interface {
 	//
 	//DeriveSharedKey returns a shared secret key by performing Diffie-Hellman key
 	//derivation between the ephemeral public key in the request and the node's
-	//key specified in the key_loc parameter (or the node's identity private key
-	//if no key locator is specified):
+	//key specified in the key_loc parameter (or the identity private key the
+	//node is running with if no key locator or the one of the node key is
+	//specified):
 	//P_shared = privKeyNode * ephemeralPubkey
 	//The resulting shared public key is serialized in the compressed format and
 	//hashed with sha256, resulting in the final key length of 256bit.
@@ -1388,8 +1391,9 @@ type SignerServer interface {
 	//
 	//DeriveSharedKey returns a shared secret key by performing Diffie-Hellman key
 	//derivation between the ephemeral public key in the request and the node's
-	//key specified in the key_loc parameter (or the node's identity private key
-	//if no key locator is specified):
+	//key specified in the key_loc parameter (or the identity private key the
+	//node is running with if no key locator or the one of the node key is
+	//specified):
 	//P_shared = privKeyNode * ephemeralPubkey
 	//The resulting shared public key is serialized in the compressed format and
 	//hashed with sha256, resulting in the final key length of 256bit.
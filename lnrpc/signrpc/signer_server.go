@@ -74,6 +74,12 @@ var (
 	// that we expect to find via a file handle within the main
 	// configuration file in this package.
 	DefaultSignerMacFilename = "signer.macaroon"
+
+	// nodeKeyLocator is the locator of the node identity key.
+	nodeKeyLocator = keychain.KeyLocator{
+		Family: keychain.KeyFamilyNodeKey,
+		Index:  0,
+	}
 )
 
 // Server is a sub-server of the main RPC server: the signer RPC. This sub RPC
@@ -501,8 +507,8 @@ func (s *Server) VerifyMessage(ctx context.Context,
 
 // DeriveSharedKey returns a shared secret key by performing Diffie-Hellman key
 // derivation between the ephemeral public key in the request and the node's
-// key specified in the key_loc parameter (or the node's identity private key
-// if no key locator is specified):
+// key specified in the key_loc parameter (or the identity private key the node
+// is running with if no key locator or the one of the node key is specified):
 //     P_shared = privKeyNode * ephemeralPubkey
 // The resulting shared public key is serialized in the compressed format and
 // hashed with sha256, resulting in the final key length of 256bit.
@@ -521,19 +527,25 @@ func (s *Server) DeriveSharedKey(_ context.Context, in *SharedKeyRequest) (
 	}
 
 	// By default, use the node identity private key.
-	locator := keychain.KeyLocator{
-		Family: keychain.KeyFamilyNodeKey,
-		Index:  0,
-	}
+	locator := nodeKeyLocator
 	if in.KeyLoc != nil {
 		locator.Family = keychain.KeyFamily(in.KeyLoc.KeyFamily)
 		locator.Index = uint32(in.KeyLoc.KeyIndex)
 	}
 
 	// Derive the shared key using ECDH and hashing the serialized
-	// compressed shared point.
-	keyDescriptor := keychain.KeyDescriptor{KeyLocator: locator}
-	sharedKeyHash, err := s.cfg.KeyRing.ECDH(keyDescriptor, ephemeralPubkey)
+	// compressed shared point. The identity key the node runs with might
+	// be held by an external signer, so it's used instead of the one of
+	// the key ring.
+	var sharedKeyHash [32]byte
+	if locator == nodeKeyLocator {
+		sharedKeyHash, err = s.cfg.NodeKeyECDH.ECDH(ephemeralPubkey)
+	} else {
+		keyDescriptor := keychain.KeyDescriptor{KeyLocator: locator}
+		sharedKeyHash, err = s.cfg.KeyRing.ECDH(
+			keyDescriptor, ephemeralPubkey,
+		)
+	}
 	if err != nil {
 		err := fmt.Errorf("unable to derive shared key: %v", err)
 		log.Error(err)
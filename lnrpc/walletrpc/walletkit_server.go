@@ -5,6 +5,7 @@ package walletrpc
 import (
 	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io/ioutil"
@@ -13,6 +14,8 @@ import (
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrd/txscript/v3"
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrlnd/input"
@@ -69,6 +72,10 @@ var (
 			Entity: "address",
 			Action: "read",
 		}},
+		"/walletrpc.WalletKit/ExportAccountXPubs": {{
+			Entity: "signer",
+			Action: "generate",
+		}},
 		"/walletrpc.WalletKit/NextAddr": {{
 			Entity: "address",
 			Action: "read",
@@ -101,6 +108,14 @@ var (
 			Entity: "onchain",
 			Action: "write",
 		}},
+		"/walletrpc.WalletKit/SignMessageWithAddr": {{
+			Entity: "onchain",
+			Action: "write",
+		}},
+		"/walletrpc.WalletKit/VerifyMessageWithAddr": {{
+			Entity: "onchain",
+			Action: "read",
+		}},
 		"/walletrpc.WalletKit/LeaseOutput": {{
 			Entity: "onchain",
 			Action: "write",
@@ -243,9 +258,10 @@ func (w *WalletKit) RegisterWithRestServer(ctx context.Context,
 }
 
 // ListUnspent returns useful information about each unspent output owned by the
-// wallet, as reported by the underlying `ListUnspentWitness`; the information
-// returned is: outpoint, amount in satoshis, address, address type,
-// scriptPubKey in hex and number of confirmations.  The result is filtered to
+// given account of the wallet, as reported by the underlying
+// `ListAccountUnspent`; the information returned is: outpoint, amount in
+// satoshis, address, address type, scriptPubKey in hex, number of
+// confirmations and whether the output is locked.  The result is filtered to
 // contain outputs whose number of confirmations is between a
 // minimum and maximum number of confirmations specified by the user, with 0
 // meaning unconfirmed.
@@ -266,7 +282,9 @@ func (w *WalletKit) ListUnspent(ctx context.Context,
 	// be shown available to us.
 	var utxos []*lnwallet.Utxo
 	err = w.cfg.CoinSelectionLocker.WithCoinSelectLock(func() error {
-		utxos, err = w.cfg.Wallet.ListUnspentWitness(minConfs, maxConfs)
+		utxos, err = w.cfg.Wallet.ListAccountUnspent(
+			req.Account, minConfs, maxConfs,
+		)
 		return err
 	})
 	if err != nil {
@@ -289,9 +307,9 @@ func (w *WalletKit) ListUnspent(ctx context.Context,
 // successive invocations of this call. Outputs can be unlocked before their
 // expiration through `ReleaseOutput`.
 //
-// If the output is not known, wtxmgr.ErrUnknownOutput is returned. If the
-// output has already been locked to a different ID, then
-// wtxmgr.ErrOutputAlreadyLocked is returned.
+// If the output is not known, lnwallet.ErrNotMine is returned. If the output
+// has already been locked to a different ID, then
+// lnwallet.ErrOutputAlreadyLocked is returned.
 func (w *WalletKit) LeaseOutput(ctx context.Context,
 	req *LeaseOutputRequest) (*LeaseOutputResponse, error) {
 
@@ -311,11 +329,23 @@ func (w *WalletKit) LeaseOutput(ctx context.Context,
 		return nil, err
 	}
 
+	// The duration is bounded before being converted, as large values
+	// would overflow it.
+	maxSeconds := uint64(lnwallet.MaxLeaseDuration / time.Second)
+	if req.ExpirationSeconds > maxSeconds {
+		return nil, fmt.Errorf("expiration of %d seconds is above the "+
+			"maximum of %d seconds", req.ExpirationSeconds,
+			maxSeconds)
+	}
+	duration := time.Duration(req.ExpirationSeconds) * time.Second
+
 	// Acquire the global coin selection lock to ensure there aren't any
 	// other concurrent processes attempting to lease the same UTXO.
 	var expiration time.Time
 	err = w.cfg.CoinSelectionLocker.WithCoinSelectLock(func() error {
-		expiration, err = w.cfg.Wallet.LeaseOutput(lockID, *op)
+		expiration, err = w.cfg.Wallet.LeaseOutput(
+			lockID, *op, duration,
+		)
 		return err
 	})
 	if err != nil {
@@ -400,6 +430,52 @@ func (w *WalletKit) DeriveKey(ctx context.Context,
 	}, nil
 }
 
+// exportExcludedFamilies are the key families whose account xpubs are never
+// exported. The static backup key is derived from the node key, and the tower
+// keys identify the node to its towers, so handing out their xpubs would let
+// anyone derive the backup encryption key or link the node to its sessions.
+var exportExcludedFamilies = map[keychain.KeyFamily]struct{}{
+	keychain.KeyFamilyNodeKey:      {},
+	keychain.KeyFamilyStaticBackup: {},
+	keychain.KeyFamilyTowerSession: {},
+	keychain.KeyFamilyTowerID:      {},
+}
+
+// ExportAccountXPubs returns the extended public key used as the root of
+// public derivation for each of the key families used by the daemon, except
+// for the node, static backup and tower key families.
+func (w *WalletKit) ExportAccountXPubs(ctx context.Context,
+	req *ExportAccountXPubsRequest) (*ExportAccountXPubsResponse, error) {
+
+	keyRing, ok := w.cfg.KeyRing.(keychain.AccountKeyRing)
+	if !ok {
+		return nil, fmt.Errorf("key ring does not support exporting " +
+			"account xpubs")
+	}
+
+	var xpubs []*AccountXPub
+	lastFam := keychain.KeyFamilyLastKF
+	for fam := keychain.KeyFamily(0); fam <= lastFam; fam++ {
+		if _, ok := exportExcludedFamilies[fam]; ok {
+			continue
+		}
+
+		xpub, err := keyRing.AccountXPub(fam)
+		if err != nil {
+			return nil, err
+		}
+
+		xpubs = append(xpubs, &AccountXPub{
+			KeyFamily: int32(fam),
+			Xpub:      xpub.String(),
+		})
+	}
+
+	return &ExportAccountXPubsResponse{
+		AccountXpubs: xpubs,
+	}, nil
+}
+
 // NextAddr returns the next unused address within the wallet.
 func (w *WalletKit) NextAddr(ctx context.Context,
 	req *AddrRequest) (*AddrResponse, error) {
@@ -589,6 +665,17 @@ func (w *WalletKit) PendingSweeps(ctx context.Context,
 		requestedFee := pendingInput.Params.Fee
 		requestedFeeRate := uint32(requestedFee.FeeRate / 1000)
 
+		var sweepTxid string
+		if pendingInput.SweepTx != nil {
+			sweepTxid = pendingInput.SweepTx.String()
+		}
+		replacedTxids := make(
+			[]string, 0, len(pendingInput.ReplacedTxs),
+		)
+		for _, txid := range pendingInput.ReplacedTxs {
+			replacedTxids = append(replacedTxids, txid.String())
+		}
+
 		rpcPendingSweeps = append(rpcPendingSweeps, &PendingSweep{
 			Outpoint:              op,
 			WitnessType:           witnessType,
@@ -599,6 +686,9 @@ func (w *WalletKit) PendingSweeps(ctx context.Context,
 			RequestedAtomsPerByte: requestedFeeRate,
 			RequestedConfTarget:   requestedFee.ConfTarget,
 			Force:                 pendingInput.Params.Force,
+			SweepTxid:             sweepTxid,
+			ReplacedTxids:         replacedTxids,
+			DeadlineHeight:        pendingInput.Params.DeadlineHeight,
 		})
 	}
 
@@ -669,8 +759,9 @@ func (w *WalletKit) BumpFee(ctx context.Context,
 	// being broadcast. If it is not aware of the input however,
 	// lnwallet.ErrNotMine is returned.
 	params := sweep.ParamsUpdate{
-		Fee:   feePreference,
-		Force: in.Force,
+		Fee:            feePreference,
+		Force:          in.Force,
+		DeadlineHeight: in.DeadlineHeight,
 	}
 
 	_, err = w.cfg.Sweeper.UpdateParams(*op, params)
@@ -729,7 +820,14 @@ func (w *WalletKit) BumpFee(ctx context.Context,
 	}
 
 	input := input.NewBaseInput(op, witnessType, signDesc, uint32(currentHeight))
-	if _, err = w.cfg.Sweeper.SweepInput(input, sweep.Params{Fee: feePreference}); err != nil {
+	// As the wallet output doesn't resolve any contract, its sweep can be
+	// held back while the node is in maintenance.
+	sweepParams := sweep.Params{
+		Fee:            feePreference,
+		DeadlineHeight: in.DeadlineHeight,
+		Deferrable:     true,
+	}
+	if _, err = w.cfg.Sweeper.SweepInput(input, sweepParams); err != nil {
 		return nil, err
 	}
 
@@ -824,3 +922,137 @@ func (w *WalletKit) LabelTransaction(ctx context.Context,
 	err = w.cfg.Wallet.LabelTransaction(*hash, req.Label, req.Overwrite)
 	return &LabelTransactionResponse{}, err
 }
+
+// signedMessageHash returns the hash signed by a signature over the given
+// message in the standard Decred signed message format.
+func signedMessageHash(msg string) []byte {
+	var b bytes.Buffer
+	_ = wire.WriteVarString(&b, 0, "Decred Signed Message:\n")
+	_ = wire.WriteVarString(&b, 0, msg)
+	return chainhash.HashB(b.Bytes())
+}
+
+// SignMessageWithAddr signs a message with the private key of a wallet
+// address or of the wallet key at a key locator, proving the ownership of the
+// address.
+func (w *WalletKit) SignMessageWithAddr(ctx context.Context,
+	req *SignMessageWithAddrRequest) (*SignMessageWithAddrResponse, error) {
+
+	if req.Msg == nil {
+		return nil, fmt.Errorf("a message to sign MUST be passed in")
+	}
+
+	switch {
+	case req.Addr != "" && req.KeyLoc != nil:
+		return nil, fmt.Errorf("only one of addr and key_loc may be set")
+
+	case req.KeyLoc != nil:
+		return w.signMessageWithKeyLoc(req.KeyLoc, string(req.Msg))
+	}
+
+	signer, ok := w.cfg.Wallet.(lnwallet.AddrMessageSigner)
+	if !ok {
+		return nil, fmt.Errorf("wallet does not support signing " +
+			"messages with addresses")
+	}
+
+	addr, err := dcrutil.DecodeAddress(req.Addr, w.cfg.ChainParams)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode address: %v", err)
+	}
+
+	sig, err := signer.SignMessageWithAddr(addr, string(req.Msg))
+	if err != nil {
+		return nil, fmt.Errorf("unable to sign message: %v", err)
+	}
+
+	return &SignMessageWithAddrResponse{
+		Signature: base64.StdEncoding.EncodeToString(sig),
+		Addr:      req.Addr,
+	}, nil
+}
+
+// signMessageWithKeyLoc signs a message in the standard Decred signed message
+// format with the wallet key at the given key locator.
+func (w *WalletKit) signMessageWithKeyLoc(keyLoc *signrpc.KeyLocator,
+	msg string) (*SignMessageWithAddrResponse, error) {
+
+	keyRing, ok := w.cfg.KeyRing.(keychain.SecretKeyRing)
+	if !ok {
+		return nil, fmt.Errorf("key ring does not support signing " +
+			"messages with key locators")
+	}
+
+	privKey, err := keyRing.DerivePrivKey(keychain.KeyDescriptor{
+		KeyLocator: keychain.KeyLocator{
+			Family: keychain.KeyFamily(keyLoc.KeyFamily),
+			Index:  uint32(keyLoc.KeyIndex),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to derive private key: %v", err)
+	}
+
+	addr, err := dcrutil.NewAddressSecpPubKey(
+		privKey.PubKey().SerializeCompressed(), w.cfg.ChainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sig := ecdsa.SignCompact(privKey, signedMessageHash(msg), true)
+
+	return &SignMessageWithAddrResponse{
+		Signature: base64.StdEncoding.EncodeToString(sig),
+		Addr:      addr.Address(),
+	}, nil
+}
+
+// VerifyMessageWithAddr verifies a signature over a message created with the
+// private key of a Decred address, which doesn't need to belong to the
+// wallet.
+func (w *WalletKit) VerifyMessageWithAddr(ctx context.Context,
+	req *VerifyMessageWithAddrRequest) (*VerifyMessageWithAddrResponse,
+	error) {
+
+	if req.Msg == nil {
+		return nil, fmt.Errorf("a message to verify MUST be passed in")
+	}
+
+	addr, err := dcrutil.DecodeAddress(req.Addr, w.cfg.ChainParams)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode address: %v", err)
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	// RecoverCompact both recovers the pubkey and validates the signature.
+	pubKey, wasCompressed, err := ecdsa.RecoverCompact(
+		sig, signedMessageHash(string(req.Msg)),
+	)
+	if err != nil {
+		return &VerifyMessageWithAddrResponse{Valid: false}, nil
+	}
+
+	serializedPubKey := pubKey.SerializeUncompressed()
+	if wasCompressed {
+		serializedPubKey = pubKey.SerializeCompressed()
+	}
+
+	// The signature is only valid if the recovered pubkey hashes to the
+	// address.
+	pubKeyAddr, err := dcrutil.NewAddressSecpPubKey(
+		serializedPubKey, w.cfg.ChainParams,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerifyMessageWithAddrResponse{
+		Valid:  pubKeyAddr.Address() == addr.Address(),
+		Pubkey: serializedPubKey,
+	}, nil
+}
@@ -0,0 +1,282 @@
+// +build !no_walletrpc
+
+package walletrpc
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/hdkeychain/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/signrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+)
+
+// mockKeyRing is a keychain.SecretKeyRing that derives the same private key
+// for every key locator, recording the last locator requested.
+type mockKeyRing struct {
+	keychain.SecretKeyRing
+
+	privKey *secp256k1.PrivateKey
+	keyLoc  keychain.KeyLocator
+}
+
+func (m *mockKeyRing) DerivePrivKey(
+	keyDesc keychain.KeyDescriptor) (*secp256k1.PrivateKey, error) {
+
+	m.keyLoc = keyDesc.KeyLocator
+	return m.privKey, nil
+}
+
+// mockAddrSigner is a wallet that signs messages with the private key of its
+// only address.
+type mockAddrSigner struct {
+	lnwallet.WalletController
+
+	privKey *secp256k1.PrivateKey
+}
+
+func (m *mockAddrSigner) SignMessageWithAddr(_ dcrutil.Address,
+	msg string) ([]byte, error) {
+
+	return ecdsa.SignCompact(m.privKey, signedMessageHash(msg), true), nil
+}
+
+// TestSignMessageWithAddr asserts that messages signed with the key of a
+// wallet address, or with the wallet key at a key locator, can be verified
+// with the address.
+func TestSignMessageWithAddr(t *testing.T) {
+	params := chaincfg.RegNetParams()
+	newKey := func() (*secp256k1.PrivateKey, string) {
+		privKey, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		addr, err := dcrutil.NewAddressSecpPubKey(
+			privKey.PubKey().SerializeCompressed(), params,
+		)
+		if err != nil {
+			t.Fatalf("unable to create address: %v", err)
+		}
+		return privKey, addr.Address()
+	}
+	walletKey, walletAddr := newKey()
+	ringKey, ringAddr := newKey()
+
+	keyRing := &mockKeyRing{privKey: ringKey}
+	w := &WalletKit{cfg: &Config{
+		Wallet:      &mockAddrSigner{privKey: walletKey},
+		KeyRing:     keyRing,
+		ChainParams: params,
+	}}
+
+	ctx := context.Background()
+	msg := []byte("proof of funds")
+
+	verify := func(addr, sig string) *VerifyMessageWithAddrResponse {
+		t.Helper()
+
+		resp, err := w.VerifyMessageWithAddr(
+			ctx, &VerifyMessageWithAddrRequest{
+				Msg:       msg,
+				Signature: sig,
+				Addr:      addr,
+			},
+		)
+		if err != nil {
+			t.Fatalf("unable to verify signature: %v", err)
+		}
+		return resp
+	}
+
+	// A message signed with a wallet address is valid for that address
+	// only.
+	resp, err := w.SignMessageWithAddr(ctx, &SignMessageWithAddrRequest{
+		Msg:  msg,
+		Addr: walletAddr,
+	})
+	if err != nil {
+		t.Fatalf("unable to sign with address: %v", err)
+	}
+	if resp.Addr != walletAddr {
+		t.Fatalf("expected address %v, got %v", walletAddr, resp.Addr)
+	}
+	verifyResp := verify(walletAddr, resp.Signature)
+	if !verifyResp.Valid {
+		t.Fatalf("expected signature to be valid")
+	}
+	if !bytes.Equal(verifyResp.Pubkey,
+		walletKey.PubKey().SerializeCompressed()) {
+
+		t.Fatalf("unexpected recovered pubkey %x", verifyResp.Pubkey)
+	}
+	if verify(ringAddr, resp.Signature).Valid {
+		t.Fatalf("expected signature to be invalid for another address")
+	}
+
+	// A message signed with a key locator is valid for the address of the
+	// derived key, which is returned.
+	resp, err = w.SignMessageWithAddr(ctx, &SignMessageWithAddrRequest{
+		Msg: msg,
+		KeyLoc: &signrpc.KeyLocator{
+			KeyFamily: int32(keychain.KeyFamilyNodeKey),
+			KeyIndex:  3,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to sign with key locator: %v", err)
+	}
+	expectedLoc := keychain.KeyLocator{
+		Family: keychain.KeyFamilyNodeKey,
+		Index:  3,
+	}
+	if keyRing.keyLoc != expectedLoc {
+		t.Fatalf("expected key locator %v, got %v", expectedLoc,
+			keyRing.keyLoc)
+	}
+	if resp.Addr != ringAddr {
+		t.Fatalf("expected address %v, got %v", ringAddr, resp.Addr)
+	}
+	if !verify(ringAddr, resp.Signature).Valid {
+		t.Fatalf("expected signature to be valid")
+	}
+
+	// Only one of the address and the key locator can be set.
+	_, err = w.SignMessageWithAddr(ctx, &SignMessageWithAddrRequest{
+		Msg:    msg,
+		Addr:   walletAddr,
+		KeyLoc: &signrpc.KeyLocator{},
+	})
+	if err == nil {
+		t.Fatalf("expected signing with both address and key " +
+			"locator to fail")
+	}
+}
+
+// TestExportAccountXPubs asserts that the account xpubs of every key family
+// are exported except for the node, static backup and tower key families.
+func TestExportAccountXPubs(t *testing.T) {
+	params := chaincfg.RegNetParams()
+	seed := bytes.Repeat([]byte{0x01}, hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, params)
+	if err != nil {
+		t.Fatalf("unable to create master key: %v", err)
+	}
+
+	masterPubs := make(map[keychain.KeyFamily]*hdkeychain.ExtendedKey)
+	lastFam := keychain.KeyFamilyLastKF
+	for fam := keychain.KeyFamily(0); fam <= lastFam; fam++ {
+		masterPubs[fam], err = master.Child(uint32(fam))
+		if err != nil {
+			t.Fatalf("unable to derive key: %v", err)
+		}
+	}
+	keyRing := keychain.NewHDKeyRing(masterPubs, nil, nil)
+
+	w := &WalletKit{cfg: &Config{
+		KeyRing:     keyRing,
+		ChainParams: params,
+	}}
+	resp, err := w.ExportAccountXPubs(
+		context.Background(), &ExportAccountXPubsRequest{},
+	)
+	if err != nil {
+		t.Fatalf("unable to export account xpubs: %v", err)
+	}
+
+	exported := make(map[keychain.KeyFamily]string)
+	for _, xpub := range resp.AccountXpubs {
+		exported[keychain.KeyFamily(xpub.KeyFamily)] = xpub.Xpub
+	}
+	for fam := keychain.KeyFamily(0); fam <= lastFam; fam++ {
+		xpub, ok := exported[fam]
+		switch fam {
+		case keychain.KeyFamilyNodeKey, keychain.KeyFamilyStaticBackup,
+			keychain.KeyFamilyTowerSession,
+			keychain.KeyFamilyTowerID:
+
+			if ok {
+				t.Fatalf("key family %d must not be "+
+					"exported", fam)
+			}
+			continue
+		}
+
+		if !ok {
+			t.Fatalf("key family %d not exported", fam)
+		}
+		if xpub != masterPubs[fam].Neuter().String() {
+			t.Fatalf("unexpected xpub for key family %d: %v", fam,
+				xpub)
+		}
+	}
+}
+
+// mockLeaser is a wallet recording the duration of the last output lease.
+type mockLeaser struct {
+	lnwallet.WalletController
+
+	duration time.Duration
+}
+
+func (m *mockLeaser) LeaseOutput(_ lnwallet.LockID, _ wire.OutPoint,
+	duration time.Duration) (time.Time, error) {
+
+	m.duration = duration
+	return time.Unix(0, 0).Add(duration), nil
+}
+
+// mockCoinSelectionLocker runs coin selection closures right away.
+type mockCoinSelectionLocker struct{}
+
+func (mockCoinSelectionLocker) WithCoinSelectLock(f func() error) error {
+	return f()
+}
+
+// TestLeaseOutputExpiration asserts that leases up to the maximum duration
+// are passed to the wallet, while longer ones are rejected instead of
+// overflowing their duration.
+func TestLeaseOutputExpiration(t *testing.T) {
+	wallet := &mockLeaser{}
+	w := &WalletKit{cfg: &Config{
+		Wallet:              wallet,
+		CoinSelectionLocker: mockCoinSelectionLocker{},
+	}}
+
+	maxSeconds := uint64(lnwallet.MaxLeaseDuration / time.Second)
+	req := &LeaseOutputRequest{
+		Id:                bytes.Repeat([]byte{0x01}, 32),
+		Outpoint:          &lnrpc.OutPoint{TxidBytes: make([]byte, 32)},
+		ExpirationSeconds: maxSeconds,
+	}
+	_, err := w.LeaseOutput(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+	if wallet.duration != lnwallet.MaxLeaseDuration {
+		t.Fatalf("expected lease duration %v, got %v",
+			lnwallet.MaxLeaseDuration, wallet.duration)
+	}
+
+	for _, secs := range []uint64{maxSeconds + 1, 1 << 63, 1<<64 - 1} {
+		wallet.duration = 0
+		req.ExpirationSeconds = secs
+		_, err := w.LeaseOutput(context.Background(), req)
+		if err == nil {
+			t.Fatalf("expected lease of %d seconds to be rejected",
+				secs)
+		}
+		if wallet.duration != 0 {
+			t.Fatalf("expected lease of %d seconds not to reach "+
+				"the wallet", secs)
+		}
+	}
+}
@@ -166,6 +166,23 @@ func local_request_WalletUnlocker_ChangePassword_0(ctx context.Context, marshale
 
 }
 
+func request_WalletUnlocker_SubscribeMigrationStatus_0(ctx context.Context, marshaler runtime.Marshaler, client WalletUnlockerClient, req *http.Request, pathParams map[string]string) (WalletUnlocker_SubscribeMigrationStatusClient, runtime.ServerMetadata, error) {
+	var protoReq MigrationStatusRequest
+	var metadata runtime.ServerMetadata
+
+	stream, err := client.SubscribeMigrationStatus(ctx, &protoReq)
+	if err != nil {
+		return nil, metadata, err
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return nil, metadata, err
+	}
+	metadata.HeaderMD = header
+	return stream, metadata, nil
+
+}
+
 // RegisterWalletUnlockerHandlerServer registers the http handlers for service WalletUnlocker to "mux".
 // UnaryRPC     :call WalletUnlockerServer directly.
 // StreamingRPC :currently unsupported pending https://github.com/grpc/grpc-go/issues/906.
@@ -251,6 +268,13 @@ func RegisterWalletUnlockerHandlerServer(ctx context.Context, mux *runtime.Serve
 
 	})
 
+	mux.Handle("GET", pattern_WalletUnlocker_SubscribeMigrationStatus_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		err := status.Error(codes.Unimplemented, "streaming calls are not yet supported in the in-process transport")
+		_, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+		return
+	})
+
 	return nil
 }
 
@@ -372,6 +396,26 @@ func RegisterWalletUnlockerHandlerClient(ctx context.Context, mux *runtime.Serve
 
 	})
 
+	mux.Handle("GET", pattern_WalletUnlocker_SubscribeMigrationStatus_0, func(w http.ResponseWriter, req *http.Request, pathParams map[string]string) {
+		ctx, cancel := context.WithCancel(req.Context())
+		defer cancel()
+		inboundMarshaler, outboundMarshaler := runtime.MarshalerForRequest(mux, req)
+		rctx, err := runtime.AnnotateContext(ctx, mux, req)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+		resp, md, err := request_WalletUnlocker_SubscribeMigrationStatus_0(rctx, inboundMarshaler, client, req, pathParams)
+		ctx = runtime.NewServerMetadataContext(ctx, md)
+		if err != nil {
+			runtime.HTTPError(ctx, mux, outboundMarshaler, w, req, err)
+			return
+		}
+
+		forward_WalletUnlocker_SubscribeMigrationStatus_0(ctx, mux, outboundMarshaler, w, req, func() (proto.Message, error) { return resp.Recv() }, mux.GetForwardResponseOptions()...)
+
+	})
+
 	return nil
 }
 
@@ -383,6 +427,8 @@ var (
 	pattern_WalletUnlocker_UnlockWallet_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"v1", "unlockwallet"}, "", runtime.AssumeColonVerbOpt(true)))
 
 	pattern_WalletUnlocker_ChangePassword_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1}, []string{"v1", "changepassword"}, "", runtime.AssumeColonVerbOpt(true)))
+
+	pattern_WalletUnlocker_SubscribeMigrationStatus_0 = runtime.MustPattern(runtime.NewPattern(1, []int{2, 0, 2, 1, 2, 2}, []string{"v1", "migrations", "subscribe"}, "", runtime.AssumeColonVerbOpt(true)))
 )
 
 var (
@@ -393,4 +439,6 @@ var (
 	forward_WalletUnlocker_UnlockWallet_0 = runtime.ForwardResponseMessage
 
 	forward_WalletUnlocker_ChangePassword_0 = runtime.ForwardResponseMessage
+
+	forward_WalletUnlocker_SubscribeMigrationStatus_0 = runtime.ForwardResponseStream
 )
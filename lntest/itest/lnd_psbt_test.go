@@ -17,11 +17,9 @@ import (
 )
 
 // testPsbtChanFunding makes sure a channel can be opened between carol and dave
-// by using a Partially Signed Bitcoin Transaction that funds the channel
+// by using a Partially Signed Decred Transaction that funds the channel
 // multisig funding output.
 func testPsbtChanFunding(net *lntest.NetworkHarness, t *harnessTest) {
-	t.Skipf("psbt is not currently implemented in dcrlnd")
-
 	ctxb := context.Background()
 	const chanSize = defaultChanAmt
 
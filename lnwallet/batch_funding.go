@@ -0,0 +1,158 @@
+package lnwallet
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/dcrutil/v3/txsort"
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/internal/psbt"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+	"github.com/decred/dcrlnd/lnwallet/chanfunding"
+)
+
+// FundBatch creates a transaction that funds the given channel funding
+// outputs from the coins of the wallet, adding a change output if needed. The
+// unsigned transaction is returned as a PSBT packet carrying the outputs spent
+// by its inputs, so it can be verified by the PSBT funding intents of the
+// channels, along with the fully signed transaction that finalizes them.
+//
+// The selected coins are locked until the transaction is published. If it
+// won't be published, ReleaseBatch MUST be called to unlock them.
+func (l *LightningWallet) FundBatch(outputs []*wire.TxOut,
+	feeRate chainfee.AtomPerKByte, minConfs int32) (*psbt.Packet,
+	*wire.MsgTx, error) {
+
+	if len(outputs) == 0 {
+		return nil, nil, ErrNoOutputs
+	}
+
+	var fundingAmt dcrutil.Amount
+	for _, out := range outputs {
+		fundingAmt += dcrutil.Amount(out.Value)
+	}
+
+	// We hold the coin select mutex while performing coin selection and
+	// locking the selected coins, so concurrent funding flows can't pick
+	// the same coins.
+	var (
+		coinSource    = NewCoinSource(l)
+		selectedCoins []chanfunding.Coin
+		changeAmt     dcrutil.Amount
+	)
+	err := l.WithCoinSelectLock(func() error {
+		coins, err := coinSource.ListCoins(minConfs, math.MaxInt32)
+		if err != nil {
+			return err
+		}
+
+		selectedCoins, changeAmt, err = chanfunding.CoinSelectBatch(
+			feeRate, fundingAmt, len(outputs), coins,
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, coin := range selectedCoins {
+			l.LockOutpoint(coin.OutPoint)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tx := wire.NewMsgTx()
+	coins := make(map[wire.OutPoint]wire.TxOut, len(selectedCoins))
+	for _, coin := range selectedCoins {
+		coin := coin
+		tx.AddTxIn(wire.NewTxIn(&coin.OutPoint, coin.Value, nil))
+		coins[coin.OutPoint] = coin.TxOut
+	}
+	for _, out := range outputs {
+		tx.AddTxOut(out)
+	}
+
+	packet, fundingTx, err := l.signBatch(tx, changeAmt, coins)
+	if err != nil {
+		l.ReleaseBatch(tx)
+		return nil, nil, err
+	}
+
+	return packet, fundingTx, nil
+}
+
+// signBatch adds the change output to the batch funding transaction and signs
+// its inputs, which must all spend the given coins of the wallet.
+func (l *LightningWallet) signBatch(tx *wire.MsgTx, changeAmt dcrutil.Amount,
+	coins map[wire.OutPoint]wire.TxOut) (*psbt.Packet, *wire.MsgTx, error) {
+
+	// Only add a change output if it won't be dust.
+	if changeAmt > DefaultDustLimit() {
+		changeAddr, err := l.NewAddress(PubKeyHash, true)
+		if err != nil {
+			return nil, nil, err
+		}
+		changeScript, err := txscript.PayToAddrScript(changeAddr)
+		if err != nil {
+			return nil, nil, err
+		}
+		tx.AddTxOut(&wire.TxOut{
+			Value:    int64(changeAmt),
+			PkScript: changeScript,
+		})
+	}
+	txsort.InPlaceSort(tx)
+
+	packet, err := psbt.NewFromUnsignedTx(tx.Copy())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signDesc := input.SignDescriptor{
+		HashType: txscript.SigHashAll,
+	}
+	for i, txIn := range tx.TxIn {
+		coin, ok := coins[txIn.PreviousOutPoint]
+		if !ok {
+			return nil, nil, fmt.Errorf("unknown input %v",
+				txIn.PreviousOutPoint)
+		}
+		packet.Inputs[i].WitnessUtxo = &wire.TxOut{
+			Value:    coin.Value,
+			Version:  coin.Version,
+			PkScript: coin.PkScript,
+		}
+
+		signDesc.Output = packet.Inputs[i].WitnessUtxo
+		signDesc.InputIndex = i
+		inputScript, err := l.Cfg.Signer.ComputeInputScript(
+			tx, &signDesc,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		sigScript, err := input.WitnessStackToSigScript(
+			inputScript.Witness,
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		txIn.SignatureScript = sigScript
+	}
+
+	return packet, tx, nil
+}
+
+// ReleaseBatch unlocks the coins spent by a batch funding transaction created
+// by FundBatch that won't be published.
+func (l *LightningWallet) ReleaseBatch(tx *wire.MsgTx) {
+	for _, txIn := range tx.TxIn {
+		l.UnlockOutpoint(txIn.PreviousOutPoint)
+	}
+}
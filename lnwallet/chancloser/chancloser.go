@@ -7,6 +7,7 @@ import (
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/lnwallet"
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
@@ -32,6 +33,11 @@ var (
 	// shutdown script previously set for that party.
 	ErrUpfrontShutdownScriptMismatch = fmt.Errorf("shutdown script does not " +
 		"match upfront shutdown script")
+
+	// ErrProposalExceedsMaxFee is returned when the remote party insists on
+	// a closing fee above the maximum fee we were told to pay.
+	ErrProposalExceedsMaxFee = fmt.Errorf("remote fee proposal exceeds " +
+		"max fee")
 )
 
 // closeState represents all the possible states the channel closer state
@@ -91,6 +97,11 @@ type ChanCloseCfg struct {
 	// Disconnect will disconnect from the remote peer in this close.
 	Disconnect func() error
 
+	// MaxFee, if non-zero, is the highest fee rate we're willing to pay
+	// for the closing transaction. It only has an effect if we opened the
+	// channel, as the initiator of the channel pays the closing fee.
+	MaxFee chainfee.AtomPerKByte
+
 	// Quit is a channel that should be sent upon in the occasion the state
 	// machine should cease all progress and shutdown.
 	Quit chan struct{}
@@ -124,6 +135,14 @@ type ChanCloser struct {
 	// offer when starting negotiation. This will be used as a baseline.
 	idealFeeSat dcrutil.Amount
 
+	// idealFeePerKB is the fee rate the ideal fee was computed from. It's
+	// stored so that the negotiation can be resumed with it.
+	idealFeePerKB chainfee.AtomPerKByte
+
+	// maxFee is the highest total fee we agree to pay for the closing
+	// transaction. A value of zero means there is no limit.
+	maxFee dcrutil.Amount
+
 	// lastFeeProposal is the last fee that we proposed to the remote
 	// party.  We'll use this as a pivot point to ratchet our next offer
 	// up, down, or simply accept the remote party's prior offer.
@@ -183,6 +202,20 @@ func NewChanCloser(cfg ChanCloseCfg, deliveryScript []byte,
 		idealFeeSat = channelCommitFee
 	}
 
+	// If we pay the closing fee and a max fee rate was given, our ideal
+	// fee can't be above the resulting max fee either.
+	var maxFee dcrutil.Amount
+	if cfg.MaxFee != 0 && cfg.Channel.IsInitiator() {
+		maxFee = cfg.Channel.CalcFee(cfg.MaxFee)
+		if idealFeeSat > maxFee {
+			chancloserLog.Infof("Ideal starting fee of %v is greater "+
+				"than max fee of %v, clamping", int64(idealFeeSat),
+				int64(maxFee))
+
+			idealFeeSat = maxFee
+		}
+	}
+
 	chancloserLog.Infof("Ideal fee for closure of ChannelPoint(%v) is: %v sat",
 		cfg.Channel.ChannelPoint(), int64(idealFeeSat))
 
@@ -195,6 +228,8 @@ func NewChanCloser(cfg ChanCloseCfg, deliveryScript []byte,
 		cfg:                 cfg,
 		negotiationHeight:   negotiationHeight,
 		idealFeeSat:         idealFeeSat,
+		idealFeePerKB:       idealFeePerKB,
+		maxFee:              maxFee,
 		localDeliveryScript: deliveryScript,
 		priorFeeOffers:      make(map[dcrutil.Amount]*lnwire.ClosingSigned),
 		locallyInitiated:    locallyInitiated,
@@ -233,6 +268,19 @@ func (c *ChanCloser) initChanShutdown() (*lnwire.Shutdown, error) {
 		return nil, err
 	}
 
+	// We'll also store the parameters of the negotiation, so that it can
+	// be resumed with the same delivery script if it's interrupted before
+	// a closing transaction is agreed on.
+	err = c.cfg.Channel.MarkShutdownSent(&channeldb.ShutdownInfo{
+		DeliveryScript: c.localDeliveryScript,
+		LocalInitiator: c.locallyInitiated,
+		FeePerKB:       c.idealFeePerKB,
+		MaxFeePerKB:    c.cfg.MaxFee,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	chancloserLog.Infof("ChannelPoint(%v): sending shutdown message",
 		c.chanPoint)
 
@@ -285,11 +333,23 @@ func (c *ChanCloser) ClosingTx() (*wire.MsgTx, error) {
 // of the state machine.
 //
 // NOTE: This will only return a non-nil pointer if we were the initiator of
-// the cooperative closure workflow.
+// the cooperative closure workflow, or if a request was set afterwards.
 func (c *ChanCloser) CloseRequest() *htlcswitch.ChanClose {
 	return c.closeReq
 }
 
+// SetCloseRequest sets the request to notify of the progress of the closure.
+// This allows a request to follow a negotiation that was resumed on its own.
+// ErrChanAlreadyClosing is returned if another request was already set.
+func (c *ChanCloser) SetCloseRequest(closeReq *htlcswitch.ChanClose) error {
+	if c.closeReq != nil {
+		return ErrChanAlreadyClosing
+	}
+
+	c.closeReq = closeReq
+	return nil
+}
+
 // Channel returns the channel stored in the config.
 func (c *ChanCloser) Channel() *lnwallet.LightningChannel {
 	return c.cfg.Channel
@@ -493,6 +553,17 @@ func (c *ChanCloser) ProcessCloseMsg(msg lnwire.Message) ([]lnwire.Message,
 				c.lastFeeProposal, remoteProposedFee,
 			)
 
+			// Never offer more than the max fee we were given. If
+			// we already offered it and the remote party still
+			// wants more, the negotiation fails.
+			feeProposal, err := capFeeProposal(
+				feeProposal, c.lastFeeProposal, remoteProposedFee,
+				c.maxFee,
+			)
+			if err != nil {
+				return nil, false, err
+			}
+
 			// With our new fee proposal calculated, we'll craft a new close
 			// signed signature to send to the other party so we can continue
 			// the fee negotiation process.
@@ -648,6 +719,25 @@ func ratchetFee(fee dcrutil.Amount, up bool) dcrutil.Amount {
 	return fee - ((fee * 1) / 10)
 }
 
+// capFeeProposal limits our next fee proposal to the max fee, if there is one.
+// An error is returned if we already proposed the max fee and the remote party
+// still proposes a higher fee.
+func capFeeProposal(feeProposal, lastSentFee, remoteFee,
+	maxFee dcrutil.Amount) (dcrutil.Amount, error) {
+
+	if maxFee == 0 || feeProposal <= maxFee {
+		return feeProposal, nil
+	}
+
+	if lastSentFee >= maxFee {
+		return 0, fmt.Errorf("%w: remote fee of %v is above max fee "+
+			"of %v", ErrProposalExceedsMaxFee, int64(remoteFee),
+			int64(maxFee))
+	}
+
+	return maxFee, nil
+}
+
 // calcCompromiseFee performs the current fee negotiation algorithm, taking
 // into consideration our ideal fee based on current fee environment, the fee
 // we last proposed (if any), and the fee proposed by the peer.
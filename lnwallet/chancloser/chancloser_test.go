@@ -2,8 +2,10 @@ package chancloser
 
 import (
 	"crypto/rand"
+	"errors"
 	"testing"
 
+	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrlnd/lnwire"
 )
 
@@ -74,3 +76,64 @@ func TestMaybeMatchScript(t *testing.T) {
 		})
 	}
 }
+
+// TestCapFeeProposal tests that fee proposals are capped to the max fee, and
+// that the negotiation fails once the max fee was offered and the remote party
+// still wants more.
+func TestCapFeeProposal(t *testing.T) {
+	tests := []struct {
+		name        string
+		proposal    dcrutil.Amount
+		lastSent    dcrutil.Amount
+		maxFee      dcrutil.Amount
+		expected    dcrutil.Amount
+		expectedErr error
+	}{
+		{
+			name:     "no max fee",
+			proposal: 5000,
+			lastSent: 4000,
+			maxFee:   0,
+			expected: 5000,
+		},
+		{
+			name:     "proposal below max fee",
+			proposal: 5000,
+			lastSent: 4000,
+			maxFee:   6000,
+			expected: 5000,
+		},
+		{
+			name:     "proposal capped to max fee",
+			proposal: 7000,
+			lastSent: 5000,
+			maxFee:   6000,
+			expected: 6000,
+		},
+		{
+			name:        "max fee already offered",
+			proposal:    7000,
+			lastSent:    6000,
+			maxFee:      6000,
+			expectedErr: ErrProposalExceedsMaxFee,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			fee, err := capFeeProposal(
+				test.proposal, test.lastSent, 8000, test.maxFee,
+			)
+			if !errors.Is(err, test.expectedErr) {
+				t.Fatalf("expected error %v, got %v",
+					test.expectedErr, err)
+			}
+			if fee != test.expected {
+				t.Fatalf("expected fee %v, got %v",
+					test.expected, fee)
+			}
+		})
+	}
+}
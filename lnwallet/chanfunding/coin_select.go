@@ -59,6 +59,15 @@ func selectInputs(amt dcrutil.Amount, coins []Coin) (dcrutil.Amount, []Coin, err
 func CoinSelect(feeRate chainfee.AtomPerKByte, amt dcrutil.Amount,
 	coins []Coin) ([]Coin, dcrutil.Amount, error) {
 
+	return CoinSelectBatch(feeRate, amt, 1, coins)
+}
+
+// CoinSelectBatch attempts to select a sufficient amount of coins, including a
+// change output, to fund numOutputs channel funding outputs that sum up to amt
+// atoms, adhering to the specified fee rate.
+func CoinSelectBatch(feeRate chainfee.AtomPerKByte, amt dcrutil.Amount,
+	numOutputs int, coins []Coin) ([]Coin, dcrutil.Amount, error) {
+
 	amtNeeded := amt
 	for {
 		// First perform an initial round of coin selection to estimate
@@ -83,8 +92,10 @@ func CoinSelect(feeRate chainfee.AtomPerKByte, amt dcrutil.Amount,
 			}
 		}
 
-		// Channel funding multisig output is P2SH.
-		sizeEstimate.AddP2SHOutput()
+		// Channel funding multisig outputs are P2SH.
+		for i := 0; i < numOutputs; i++ {
+			sizeEstimate.AddP2SHOutput()
+		}
 
 		// Assume that change output is a P2PKH output.
 		//
@@ -381,3 +381,37 @@ func TestCoinSelectSubtractFees(t *testing.T) {
 		})
 	}
 }
+
+// TestCoinSelectBatch tests that the fee paid when funding several channel
+// outputs at once accounts for every funding output.
+func TestCoinSelectBatch(t *testing.T) {
+	t.Parallel()
+
+	const feeRate = chainfee.AtomPerKByte(100)
+
+	coins := []Coin{{
+		TxOut: wire.TxOut{
+			PkScript: p2pkhScript,
+			Value:    1 * dcrutil.AtomsPerCoin,
+		},
+	}}
+	amt := dcrutil.Amount(0.5 * dcrutil.AtomsPerCoin)
+
+	_, changeAmt, err := CoinSelectBatch(feeRate, amt, 3, coins)
+	if err != nil {
+		t.Fatalf("unable to select coins: %v", err)
+	}
+
+	var sizeEstimate input.TxSizeEstimator
+	sizeEstimate.AddP2PKHInput()
+	for i := 0; i < 3; i++ {
+		sizeEstimate.AddP2SHOutput()
+	}
+	sizeEstimate.AddP2PKHOutput()
+	fee := feeRate.FeeForSize(sizeEstimate.Size())
+
+	if changeAmt != 1*dcrutil.AtomsPerCoin-amt-fee {
+		t.Fatalf("expected %v change amt, got %v",
+			1*dcrutil.AtomsPerCoin-amt-fee, changeAmt)
+	}
+}
@@ -2,7 +2,6 @@ package chanfunding
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"sync"
@@ -159,7 +158,7 @@ func (i *PsbtIntent) BindKeys(localKey *keychain.KeyDescriptor,
 }
 
 // FundingParams returns the parameters that are necessary to start funding the
-// channel output this intent was created for. It returns the P2WSH funding
+// channel output this intent was created for. It returns the P2SH funding
 // address, the exact funding amount and a PSBT packet that contains exactly one
 // output that encodes the previous two parameters.
 func (i *PsbtIntent) FundingParams() (dcrutil.Address, int64, *psbt.Packet,
@@ -178,12 +177,9 @@ func (i *PsbtIntent) FundingParams() (dcrutil.Address, int64, *psbt.Packet,
 		return nil, 0, nil, fmt.Errorf("unable to create funding "+
 			"output: %v", err)
 	}
-	witnessScriptHash := sha256.Sum256(witnessScript)
 
-	// Encode the address in the human readable bech32 format.
-	addr, err := dcrutil.NewAddressScriptHash(
-		witnessScriptHash[:], i.netParams,
-	)
+	// Encode the P2SH address of the funding script.
+	addr, err := dcrutil.NewAddressScriptHash(witnessScript, i.netParams)
 	if err != nil {
 		return nil, 0, nil, fmt.Errorf("unable to encode address: %v",
 			err)
@@ -194,7 +190,7 @@ func (i *PsbtIntent) FundingParams() (dcrutil.Address, int64, *psbt.Packet,
 	// that one, otherwise we'll create a new one.
 	packet := i.BasePsbt
 	if packet == nil {
-		packet, err = psbt.New(nil, nil, 2, 0, nil)
+		packet, err = psbt.New(nil, nil, int32(wire.TxVersion), 0, nil)
 		if err != nil {
 			return nil, 0, nil, fmt.Errorf("unable to create "+
 				"PSBT: %v", err)
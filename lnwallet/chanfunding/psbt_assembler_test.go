@@ -2,7 +2,6 @@ package chanfunding
 
 import (
 	"bytes"
-	"crypto/sha256"
 	"fmt"
 	"reflect"
 	"sync"
@@ -36,7 +35,6 @@ func privKeyFromBytes(b []byte) (*secp256k1.PrivateKey, *secp256k1.PublicKey) {
 
 // TestPsbtIntent tests the basic happy path of the PSBT assembler and intent.
 func TestPsbtIntent(t *testing.T) {
-	t.Skipf("psbt not implemented")
 
 	t.Parallel()
 
@@ -78,10 +76,7 @@ func TestPsbtIntent(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error calculating script: %v", err)
 	}
-	witnessScriptHash := sha256.Sum256(script)
-	addr, err := dcrutil.NewAddressScriptHash(
-		witnessScriptHash[:], &params,
-	)
+	addr, err := dcrutil.NewAddressScriptHash(script, &params)
 	if err != nil {
 		t.Fatalf("unable to encode address: %v", err)
 	}
@@ -107,9 +102,10 @@ func TestPsbtIntent(t *testing.T) {
 			len(pendingPsbt.UnsignedTx.TxOut), 1)
 	}
 	txOut := pendingPsbt.UnsignedTx.TxOut[0]
-	if !bytes.Equal(txOut.PkScript[2:], witnessScriptHash[:]) {
+	scriptHash := dcrutil.Hash160(script)
+	if !bytes.Equal(txOut.PkScript[2:22], scriptHash) {
 		t.Fatalf("unexpected PK script in output. got %x wanted %x",
-			txOut.PkScript[2:], witnessScriptHash)
+			txOut.PkScript[2:22], scriptHash)
 	}
 	if txOut.Value != int64(chanCapacity) {
 		t.Fatalf("unexpected value in output. got %d wanted %d",
@@ -141,7 +137,6 @@ func TestPsbtIntent(t *testing.T) {
 		PkScript: []byte{99, 99, 99},
 	}
 	pendingPsbt.Inputs[0].FinalScriptSig = []byte{88, 88, 88}
-	pendingPsbt.Inputs[0].FinalScriptWitness = []byte{2, 0, 0}
 
 	// If we call Finalize, the intent will signal to the funding manager
 	// that it can continue with the funding flow. We want to make sure
@@ -189,7 +184,6 @@ func TestPsbtIntent(t *testing.T) {
 // TestPsbtIntentBasePsbt tests that a channel funding output can be appended to
 // a given base PSBT in the funding flow.
 func TestPsbtIntentBasePsbt(t *testing.T) {
-	t.Skipf("psbt not implemented")
 	t.Parallel()
 
 	// First create a dummy PSBT with a single output.
@@ -214,10 +208,7 @@ func TestPsbtIntentBasePsbt(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error calculating script: %v", err)
 	}
-	witnessScriptHash := sha256.Sum256(script)
-	addr, err := dcrutil.NewAddressScriptHash(
-		witnessScriptHash[:], &params,
-	)
+	addr, err := dcrutil.NewAddressScriptHash(script, &params)
 	if err != nil {
 		t.Fatalf("unable to encode address: %v", err)
 	}
@@ -275,7 +266,6 @@ func TestPsbtIntentBasePsbt(t *testing.T) {
 // TestPsbtVerify tests the PSBT verification process more deeply than just
 // the happy path.
 func TestPsbtVerify(t *testing.T) {
-	t.Skipf("psbt not implemented")
 	t.Parallel()
 
 	testCases := []struct {
@@ -423,7 +413,6 @@ func TestPsbtVerify(t *testing.T) {
 // TestPsbtFinalize tests the PSBT finalization process more deeply than just
 // the happy path.
 func TestPsbtFinalize(t *testing.T) {
-	t.Skipf("psbt not implemented")
 	t.Parallel()
 
 	testCases := []struct {
@@ -588,7 +577,7 @@ func TestPsbtFinalize(t *testing.T) {
 					Value:    int64(chanCapacity) + 1,
 					PkScript: []byte{1, 2, 3},
 				},
-				FinalScriptWitness: []byte{0x01, 0x00},
+				FinalScriptSig: []byte{0x01, 0x00},
 			}}
 			err = psbtIntent.Verify(pendingPsbt)
 			if err != nil {
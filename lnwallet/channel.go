@@ -6070,6 +6070,38 @@ func (lc *LightningChannel) ForceClose() (*LocalForceCloseSummary, error) {
 	return summary, nil
 }
 
+// ExportLocalCommitment returns a LocalForceCloseSummary for the latest local
+// commitment transaction, fully signed, without modifying the state of the
+// channel. This allows an operator to keep a copy of the transactions needed
+// to unilaterally close the channel should the daemon be unable to do so.
+//
+// NOTE: The returned commitment transaction is revoked as soon as the channel
+// advances to a new state. Broadcasting a revoked commitment allows the remote
+// party to claim all funds in the channel.
+func (lc *LightningChannel) ExportLocalCommitment() (*LocalForceCloseSummary,
+	error) {
+
+	lc.RLock()
+	defer lc.RUnlock()
+
+	// Just as when force closing, we refuse to export a commitment if
+	// we've detected the local state may be outdated.
+	if lc.channelState.HasChanStatus(channeldb.ChanStatusLocalDataLoss) {
+		return nil, fmt.Errorf("cannot export commitment of channel "+
+			"with state: %v", lc.channelState.ChanStatus())
+	}
+
+	commitTx, err := lc.getSignedCommitTx()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewLocalForceCloseSummary(
+		lc.channelState, lc.Signer, commitTx,
+		lc.channelState.LocalCommitment,
+	)
+}
+
 // NewLocalForceCloseSummary generates a LocalForceCloseSummary from the given
 // channel state.  The passed commitTx must be a fully signed commitment
 // transaction corresponding to localCommit.
@@ -6884,6 +6916,18 @@ func (lc *LightningChannel) MarkCoopBroadcasted(tx *wire.MsgTx,
 	return lc.channelState.MarkCoopBroadcasted(tx, localInitiated)
 }
 
+// MarkShutdownSent stores the parameters of the cooperative close negotiation
+// of the channel once our shutdown message was sent, so that the negotiation
+// can be resumed if it's interrupted.
+func (lc *LightningChannel) MarkShutdownSent(
+	info *channeldb.ShutdownInfo) error {
+
+	lc.Lock()
+	defer lc.Unlock()
+
+	return lc.channelState.MarkShutdownSent(info)
+}
+
 // MarkDataLoss marks sets the channel status to LocalDataLoss and stores the
 // passed commitPoint for use to retrieve funds in case the remote force closes
 // the channel.
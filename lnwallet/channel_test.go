@@ -819,6 +819,69 @@ func testCoopClose(t *testing.T, testCase *coopCloseTestCase) {
 	}
 }
 
+// TestExportLocalCommitment asserts that exporting the latest local commitment
+// yields the signed commitment transaction along with the signed HTLC timeout
+// transactions, while leaving the channel usable.
+func TestExportLocalCommitment(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, bobChannel, cleanUp, err := CreateTestChannels(
+		channeldb.SingleFunderTweaklessBit,
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	// Add an HTLC from Alice to Bob and lock it in, so that it's present
+	// on Alice's latest commitment.
+	htlcAmount := lnwire.NewMAtomsFromAtoms(200000)
+	htlc, _ := createHTLC(0, htlcAmount)
+	if _, err := aliceChannel.AddHTLC(htlc, nil); err != nil {
+		t.Fatalf("alice unable to add htlc: %v", err)
+	}
+	if _, err := bobChannel.ReceiveHTLC(htlc); err != nil {
+		t.Fatalf("bob unable to recv add htlc: %v", err)
+	}
+	if err := ForceStateTransition(aliceChannel, bobChannel); err != nil {
+		t.Fatalf("Can't update the channel state: %v", err)
+	}
+
+	summary, err := aliceChannel.ExportLocalCommitment()
+	if err != nil {
+		t.Fatalf("unable to export commitment: %v", err)
+	}
+
+	localCommit := aliceChannel.channelState.LocalCommitment
+	if summary.CloseTx.TxHash() != localCommit.CommitTx.TxHash() {
+		t.Fatalf("exported commitment doesn't match local commitment")
+	}
+	if len(summary.CloseTx.TxIn[0].SignatureScript) == 0 {
+		t.Fatalf("exported commitment is not signed")
+	}
+
+	outgoing := summary.HtlcResolutions.OutgoingHTLCs
+	if len(outgoing) != 1 {
+		t.Fatalf("expected 1 outgoing htlc resolution, got %v",
+			len(outgoing))
+	}
+	if outgoing[0].SignedTimeoutTx == nil {
+		t.Fatalf("expected signed htlc timeout tx")
+	}
+
+	// Exporting the commitment must not prevent further state updates.
+	htlc, _ = createHTLC(1, htlcAmount)
+	if _, err := aliceChannel.AddHTLC(htlc, nil); err != nil {
+		t.Fatalf("alice unable to add htlc: %v", err)
+	}
+	if _, err := bobChannel.ReceiveHTLC(htlc); err != nil {
+		t.Fatalf("bob unable to recv add htlc: %v", err)
+	}
+	if err := ForceStateTransition(aliceChannel, bobChannel); err != nil {
+		t.Fatalf("Can't update the channel state: %v", err)
+	}
+}
+
 // TestForceClose checks that the resulting ForceCloseSummary is correct when a
 // peer is ForceClosing the channel. Will check outputs both above and below
 // the dust limit. Additionally, we'll ensure that the node which executed the
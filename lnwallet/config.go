@@ -1,6 +1,7 @@
 package lnwallet
 
 import (
+	"github.com/decred/dcrd/chaincfg/chainhash"
 	"github.com/decred/dcrd/chaincfg/v3"
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
@@ -56,4 +57,20 @@ type Config struct {
 	// NetParams is the set of parameters that tells the wallet which chain
 	// it will be operating on.
 	NetParams chaincfg.Params
+
+	// CloseTxMinConfs is the number of confirmations the outputs of our
+	// channel closing transactions need before they're selected to fund
+	// new transactions. Zero or one means they're available as soon as
+	// they confirm.
+	CloseTxMinConfs int32
+
+	// SweepTxMinConfs is the number of confirmations the outputs of our
+	// sweep transactions need before they're selected to fund new
+	// transactions. Zero or one means they're available as soon as they
+	// confirm.
+	SweepTxMinConfs int32
+
+	// IsSweepTx determines whether the given transaction was published by
+	// the sweeper. If nil, no transaction is considered a sweep.
+	IsSweepTx func(chainhash.Hash) (bool, error)
 }
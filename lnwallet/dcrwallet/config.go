@@ -72,6 +72,11 @@ type Config struct {
 	// default BIP44 derivation paths.
 	RecoveryWindow uint32
 
+	// RescanFromHeight is the block height from which the wallet is
+	// rescanned for used addresses. If non-zero, blocks below it are
+	// considered processed and are not rescanned.
+	RescanFromHeight int32
+
 	// Syncer stores a specific implementation of a WalletSyncer (either an
 	// RPC syncer or a SPV syncer) capabale of maintaining the wallet
 	// backend synced to the chain.
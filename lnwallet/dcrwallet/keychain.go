@@ -2,6 +2,7 @@ package dcrwallet
 
 import (
 	"context"
+	"sync"
 
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/keychain"
@@ -11,6 +12,25 @@ import (
 	"github.com/decred/dcrd/hdkeychain/v3"
 )
 
+// retainedKeyFamilies are the key families whose branch private keys are kept
+// in memory while the wallet is locked, as the node can't operate its channels
+// without them: commitment updates, justice transactions and contract sweeps
+// are signed with the channel keys, the node key authenticates our peer
+// connections and channel updates, the static backup key encrypts the backup
+// file updated when channels close and the tower session keys authenticate our
+// watchtower sessions. The branch keys of other families are dropped when the
+// wallet is locked.
+var retainedKeyFamilies = map[keychain.KeyFamily]struct{}{
+	keychain.KeyFamilyMultiSig:       {},
+	keychain.KeyFamilyRevocationBase: {},
+	keychain.KeyFamilyHtlcBase:       {},
+	keychain.KeyFamilyPaymentBase:    {},
+	keychain.KeyFamilyDelayBase:      {},
+	keychain.KeyFamilyNodeKey:        {},
+	keychain.KeyFamilyStaticBackup:   {},
+	keychain.KeyFamilyTowerSession:   {},
+}
+
 // walletKeyRing is an implementation of both the KeyRing and SecretKeyRing
 // interfaces backed by dcrwallet's internal root keys.
 //
@@ -38,6 +58,16 @@ type walletKeyRing struct {
 	// of used keys of the keyring. This is used to track the next
 	// available key to prevent key reuse when establishing channels.
 	db *channeldb.DB
+
+	// masterPrivs are the branch private keys of the key families, derived
+	// while the wallet was unlocked. The keys of the retainedKeyFamilies
+	// are kept when the wallet is locked, so that the keys of the channels
+	// and of their contracts can still be derived, as justice
+	// transactions, htlc sweeps and commitment updates initiated by peers
+	// must still be signed. This means those keys remain usable by the
+	// node while the wallet is locked.
+	masterPrivs    map[keychain.KeyFamily]*hdkeychain.ExtendedKey
+	masterPrivsMtx sync.RWMutex
 }
 
 // Compile time type assertions to ensure walletKeyRing fulfills the desired
@@ -57,6 +87,8 @@ func newWalletKeyRing(w *wallet.Wallet, db *channeldb.DB) (*walletKeyRing, error
 	lastKeyFam := uint32(keychain.KeyFamilyLastKF)
 	masterPubs := make(map[keychain.KeyFamily]*hdkeychain.ExtendedKey,
 		lastKeyFam+1)
+	masterPrivs := make(map[keychain.KeyFamily]*hdkeychain.ExtendedKey,
+		lastKeyFam+1)
 
 	ctKey, err := w.CoinTypePrivKey(context.TODO())
 	if err != nil {
@@ -80,11 +112,13 @@ func newWalletKeyRing(w *wallet.Wallet, db *channeldb.DB) (*walletKeyRing, error
 
 		branchPub := branchKey.Neuter()
 		masterPubs[keychain.KeyFamily(i)] = branchPub
+		masterPrivs[keychain.KeyFamily(i)] = branchKey
 	}
 
 	wkr := &walletKeyRing{
-		wallet: w,
-		db:     db,
+		wallet:      w,
+		db:          db,
+		masterPrivs: masterPrivs,
 	}
 	wkr.HDKeyRing = keychain.NewHDKeyRing(masterPubs, wkr.fetchMasterPriv,
 		wkr.nextIndex)
@@ -98,6 +132,15 @@ func (wkr *walletKeyRing) nextIndex(keyFam keychain.KeyFamily) (uint32, error) {
 func (wkr *walletKeyRing) fetchMasterPriv(keyFam keychain.KeyFamily) (*hdkeychain.ExtendedKey,
 	error) {
 
+	wkr.masterPrivsMtx.RLock()
+	branchKey, ok := wkr.masterPrivs[keyFam]
+	wkr.masterPrivsMtx.RUnlock()
+	if ok {
+		return branchKey, nil
+	}
+
+	// Families past the known ones and the families dropped when the
+	// wallet was locked require the wallet to be unlocked.
 	ctKey, err := wkr.wallet.CoinTypePrivKey(context.TODO())
 	if err != nil {
 		return nil, err
@@ -108,10 +151,30 @@ func (wkr *walletKeyRing) fetchMasterPriv(keyFam keychain.KeyFamily) (*hdkeychai
 		return nil, err
 	}
 
-	branchKey, err := acctKey.Child(udb.ExternalBranch)
+	branchKey, err = acctKey.Child(udb.ExternalBranch)
 	if err != nil {
 		return nil, err
 	}
 
+	if keyFam <= keychain.KeyFamilyLastKF {
+		wkr.masterPrivsMtx.Lock()
+		wkr.masterPrivs[keyFam] = branchKey
+		wkr.masterPrivsMtx.Unlock()
+	}
+
 	return branchKey, nil
 }
+
+// dropUnretainedKeys drops the branch private keys of the key families that
+// aren't needed to operate the channels of the node while the wallet is
+// locked. They are derived again once the wallet is unlocked.
+func (wkr *walletKeyRing) dropUnretainedKeys() {
+	wkr.masterPrivsMtx.Lock()
+	defer wkr.masterPrivsMtx.Unlock()
+
+	for keyFam := range wkr.masterPrivs {
+		if _, ok := retainedKeyFamilies[keyFam]; !ok {
+			delete(wkr.masterPrivs, keyFam)
+		}
+	}
+}
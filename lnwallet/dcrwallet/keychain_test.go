@@ -107,6 +107,66 @@ func TestDcrwalletSecretKeyRingImpl(t *testing.T) {
 
 }
 
+// TestDcrwalletKeyRingLocked asserts that the keys of the key families needed
+// to operate the channels can still be derived after the wallet is locked,
+// while the keys of the other families are only available once it's unlocked
+// again.
+func TestDcrwalletKeyRingLocked(t *testing.T) {
+	t.Parallel()
+
+	cleanUp, wallet, cdb, err := createTestWallet()
+	if err != nil {
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+	defer cleanUp()
+
+	keyRing, err := newWalletKeyRing(wallet, cdb)
+	if err != nil {
+		t.Fatalf("unable to create key ring: %v", err)
+	}
+
+	keyLoc := keychain.KeyLocator{
+		Family: keychain.KeyFamilyRevocationBase,
+		Index:  1,
+	}
+	unlockedKey, err := keyRing.DerivePrivKey(keychain.KeyDescriptor{
+		KeyLocator: keyLoc,
+	})
+	if err != nil {
+		t.Fatalf("unable to derive key: %v", err)
+	}
+
+	wallet.Lock()
+
+	lockedKey, err := keyRing.DerivePrivKey(keychain.KeyDescriptor{
+		KeyLocator: keyLoc,
+	})
+	if err != nil {
+		t.Fatalf("unable to derive key with locked wallet: %v", err)
+	}
+	if lockedKey.Key != unlockedKey.Key {
+		t.Fatalf("derived a different key with locked wallet")
+	}
+
+	rootLoc := keychain.KeyDescriptor{
+		KeyLocator: keychain.KeyLocator{
+			Family: keychain.KeyFamilyRevocationRoot,
+		},
+	}
+	keyRing.dropUnretainedKeys()
+	if _, err := keyRing.DerivePrivKey(rootLoc); err == nil {
+		t.Fatalf("derived revocation root key with locked wallet")
+	}
+
+	err = wallet.Unlock(context.Background(), []byte("test"), nil)
+	if err != nil {
+		t.Fatalf("unable to unlock wallet: %v", err)
+	}
+	if _, err := keyRing.DerivePrivKey(rootLoc); err != nil {
+		t.Fatalf("unable to derive revocation root key: %v", err)
+	}
+}
+
 func init() {
 	// We'll clamp the max range scan to constrain the run time of the
 	// private key scan test.
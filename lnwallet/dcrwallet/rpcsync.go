@@ -59,7 +59,10 @@ func (s *RPCSyncer) start(w *DcrWallet) error {
 
 			syncer := chain.NewSyncer(w.wallet, &chainRpcOpts)
 			syncer.SetCallbacks(&chain.Callbacks{
-				Synced: w.onRPCSyncerSynced,
+				Synced:               w.onRPCSyncerSynced,
+				FetchHeadersFinished: w.skipRescanBelowHeight,
+				RescanProgress:       w.onRescanProgress,
+				RescanFinished:       w.onRescanFinished,
 			})
 
 			dcrwLog.Debugf("Starting rpc syncer")
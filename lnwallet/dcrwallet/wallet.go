@@ -19,6 +19,7 @@ import (
 	"github.com/decred/dcrlnd/lnwallet"
 	"github.com/decred/dcrlnd/lnwallet/chainfee"
 
+	"decred.org/dcrwallet/errors"
 	base "decred.org/dcrwallet/wallet"
 	"decred.org/dcrwallet/wallet/txauthor"
 	"decred.org/dcrwallet/wallet/udb"
@@ -71,6 +72,11 @@ type DcrWallet struct {
 	// synced to the network. It is protected by atomicWalletSynced.
 	syncedChan chan struct{}
 
+	// atomicRescanHeight is the height the ongoing rescan of the syncer
+	// progressed through, or zero if no rescan is in progress. It MUST be
+	// used atomically.
+	atomicRescanHeight int32
+
 	cfg *Config
 
 	netParams *chaincfg.Params
@@ -84,8 +90,9 @@ type DcrWallet struct {
 }
 
 // A compile time check to ensure that DcrWallet implements the
-// WalletController interface.
+// WalletController and ChainSyncReporter interfaces.
 var _ lnwallet.WalletController = (*DcrWallet)(nil)
+var _ lnwallet.ChainSyncReporter = (*DcrWallet)(nil)
 
 // New returns a new fully initialized instance of DcrWallet given a valid
 // configuration struct.
@@ -181,6 +188,28 @@ func (b *DcrWallet) Start() error {
 	return nil
 }
 
+// Lock locks the wallet, making its private keys unavailable until it's
+// unlocked again. Signing with the wallet keys fails while the wallet is
+// locked, but the key ring keeps the keys needed to operate the channels.
+func (b *DcrWallet) Lock() {
+	b.wallet.Lock()
+
+	if b.walletKeyRing != nil {
+		b.walletKeyRing.dropUnretainedKeys()
+	}
+}
+
+// Unlock unlocks the wallet with the given private passphrase after it was
+// locked with Lock.
+func (b *DcrWallet) Unlock(privatePass []byte) error {
+	return b.wallet.Unlock(b.ctx, privatePass, nil)
+}
+
+// Locked returns true if the private keys of the wallet are locked.
+func (b *DcrWallet) Locked() bool {
+	return b.wallet.Locked()
+}
+
 // Stop signals the wallet for shutdown. Shutdown may entail closing
 // any active sockets, database handles, stopping goroutines, etc.
 //
@@ -356,9 +385,18 @@ func (b *DcrWallet) UnlockOutpoint(o wire.OutPoint) {
 // This is a part of the WalletController interface.
 func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 	[]*lnwallet.Utxo, error) {
+
+	return b.listUnspent(minConfs, maxConfs, "")
+}
+
+// listUnspent returns the unlocked unspent outputs of the given account (or
+// of all accounts if empty) which pay to a supported script.
+func (b *DcrWallet) listUnspent(minConfs, maxConfs int32, account string) (
+	[]*lnwallet.Utxo, error) {
+
 	// First, grab all the unfiltered currently unspent outputs.
 	unspentOutputs, err := b.wallet.ListUnspent(context.TODO(), minConfs,
-		maxConfs, nil, "")
+		maxConfs, nil, account)
 	if err != nil {
 		return nil, err
 	}
@@ -406,6 +444,75 @@ func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 	return witnessOutputs, nil
 }
 
+// ListAccountUnspent returns all unspent outputs of the given wallet account
+// that have between minConfs and maxConfs confirmations, including the ones
+// that are currently locked. An empty account lists the same outputs as
+// ListUnspentWitness, those of all accounts.
+//
+// This is a part of the WalletController interface.
+func (b *DcrWallet) ListAccountUnspent(account string, minConfs,
+	maxConfs int32) ([]*lnwallet.Utxo, error) {
+
+	utxos, err := b.listUnspent(minConfs, maxConfs, account)
+	if err != nil {
+		return nil, err
+	}
+
+	// The wallet omits locked outputs from the list above, so add them
+	// separately.
+	ctx := context.TODO()
+	locked, err := b.wallet.LockedOutpoints(ctx, account)
+	if err != nil {
+		return nil, err
+	}
+	for _, input := range locked {
+		txid, err := chainhash.NewHashFromStr(input.Txid)
+		if err != nil {
+			dcrwLog.Warnf("Invalid locked output %v:%v: %v",
+				input.Txid, input.Vout, err)
+			continue
+		}
+		op := wire.OutPoint{
+			Hash:  *txid,
+			Index: input.Vout,
+			Tree:  input.Tree,
+		}
+
+		// Outputs stay locked until they're explicitly unlocked, so
+		// the ones already spent by an unconfirmed transaction, such
+		// as a published funding transaction, must be skipped.
+		_, _, err = b.wallet.Spender(ctx, &op)
+		switch {
+		case err == nil:
+			continue
+
+		case !errors.Is(err, errors.NotExist):
+			dcrwLog.Warnf("Unable to determine whether locked "+
+				"output %v is spent: %v", op, err)
+			continue
+		}
+
+		utxo, err := b.FetchInputInfo(&op)
+		if err != nil {
+			dcrwLog.Warnf("Unable to fetch locked output %v: %v",
+				op, err)
+			continue
+		}
+
+		if utxo.AddressType != lnwallet.PubKeyHash ||
+			utxo.Confirmations < int64(minConfs) ||
+			utxo.Confirmations > int64(maxConfs) {
+
+			continue
+		}
+
+		utxo.Locked = true
+		utxos = append(utxos, utxo)
+	}
+
+	return utxos, nil
+}
+
 // PublishTransaction performs cursory validation (dust checks, etc), then
 // finally broadcasts the passed transaction to the Decred network. If
 // publishing the transaction fails, an error describing the reason is
@@ -555,6 +662,28 @@ func extractBalanceDelta(
 	return balanceDelta, nil
 }
 
+// extractAccounts returns the distinct wallet accounts involved in the inputs
+// and outputs of a transaction.
+func extractAccounts(txSummary base.TransactionSummary) []uint32 {
+	var accounts []uint32
+	addAccount := func(account uint32) {
+		for _, a := range accounts {
+			if a == account {
+				return
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	for _, input := range txSummary.MyInputs {
+		addAccount(input.PreviousAccount)
+	}
+	for _, output := range txSummary.MyOutputs {
+		addAccount(output.Account)
+	}
+
+	return accounts
+}
+
 // minedTransactionsToDetails is a helper function which converts a summary
 // information about mined transactions to a TransactionDetail.
 func minedTransactionsToDetails(
@@ -605,6 +734,7 @@ func minedTransactionsToDetails(
 			return nil, err
 		}
 		txDetail.Value = balanceDelta
+		txDetail.Accounts = extractAccounts(tx)
 
 		details = append(details, txDetail)
 	}
@@ -652,6 +782,7 @@ func unminedTransactionsToDetail(
 		return nil, err
 	}
 	txDetail.Value = balanceDelta
+	txDetail.Accounts = extractAccounts(summary)
 
 	return txDetail, nil
 }
@@ -915,10 +1046,105 @@ func (b *DcrWallet) onRPCSyncerSynced(synced bool) {
 	}
 }
 
+// skipRescanBelowHeight marks the blocks below the configured rescan height as
+// processed, such that the rescan for used addresses performed by the syncer
+// once headers are fetched starts at that height instead of genesis.
+func (b *DcrWallet) skipRescanBelowHeight() {
+	height := b.cfg.RescanFromHeight
+	if height <= 1 {
+		return
+	}
+
+	rescanPoint, err := b.wallet.RescanPoint(b.ctx)
+	if err != nil {
+		dcrwLog.Errorf("Unable to fetch rescan point: %v", err)
+		return
+	}
+
+	// Nothing to do if the wallet doesn't need a rescan or it already
+	// started past the requested height.
+	if rescanPoint == nil {
+		return
+	}
+	header, err := b.wallet.BlockHeader(b.ctx, rescanPoint)
+	if err != nil {
+		dcrwLog.Errorf("Unable to fetch rescan point header: %v", err)
+		return
+	}
+	if int32(header.Height) >= height {
+		return
+	}
+
+	// The last processed block is the parent of the first one to rescan,
+	// which can't be past the current tip.
+	lastProcessed := height - 1
+	if _, tipHeight := b.wallet.MainChainTip(b.ctx); lastProcessed > tipHeight {
+		lastProcessed = tipHeight
+	}
+	blockID := base.NewBlockIdentifierFromHeight(lastProcessed)
+	info, err := b.wallet.BlockInfo(b.ctx, blockID)
+	if err != nil {
+		dcrwLog.Errorf("Unable to fetch block at height %d: %v",
+			lastProcessed, err)
+		return
+	}
+
+	dcrwLog.Infof("Skipping rescan of blocks %d-%d", header.Height,
+		lastProcessed)
+
+	if err := b.wallet.SaveRescanned(b.ctx, &info.Hash, nil); err != nil {
+		dcrwLog.Errorf("Unable to skip rescan below height %d: %v",
+			height, err)
+	}
+}
+
+// onRescanProgress records the height the ongoing rescan of the syncer
+// progressed through.
+func (b *DcrWallet) onRescanProgress(rescannedThrough int32) {
+	atomic.StoreInt32(&b.atomicRescanHeight, rescannedThrough)
+}
+
+// onRescanFinished records that the rescan of the syncer finished.
+func (b *DcrWallet) onRescanFinished() {
+	atomic.StoreInt32(&b.atomicRescanHeight, 0)
+}
+
+// ChainSyncInfo returns the current progress of the synchronization of the
+// wallet.
+//
+// This is a part of the ChainSyncReporter interface.
+func (b *DcrWallet) ChainSyncInfo() (*lnwallet.ChainSyncInfo, error) {
+	// The wallet extends its main chain as headers are fetched, so its
+	// tip is the last fetched header.
+	_, headersHeight := b.wallet.MainChainTip(b.ctx)
+
+	// The blocks from the rescan point onwards still need to be processed.
+	blocksHeight := headersHeight
+	rescanPoint, err := b.wallet.RescanPoint(b.ctx)
+	if err != nil {
+		return nil, err
+	}
+	if rescanPoint != nil {
+		header, err := b.wallet.BlockHeader(b.ctx, rescanPoint)
+		if err != nil {
+			return nil, err
+		}
+		blocksHeight = int32(header.Height) - 1
+	}
+
+	return &lnwallet.ChainSyncInfo{
+		HeadersHeight: int64(headersHeight),
+		BlocksHeight:  int64(blocksHeight),
+		RescanHeight:  int64(atomic.LoadInt32(&b.atomicRescanHeight)),
+	}, nil
+}
+
 func (b *DcrWallet) rpcSyncerFinished() {
 	// The RPC syncer stopped, so if we were previously synced we need to
-	// signal that we aren't anymore.
+	// signal that we aren't anymore. Any rescan it performed was
+	// interrupted.
 	atomic.CompareAndSwapUint32(&b.atomicWalletSynced, syncStatusSynced, syncStatusLostSync)
+	atomic.StoreInt32(&b.atomicRescanHeight, 0)
 }
 
 // LabelTransaction adds the given external label to the specified transaction.
@@ -930,13 +1156,21 @@ func (b *DcrWallet) LabelTransaction(hash chainhash.Hash, label string, overwrit
 
 // LeaseOutput markes the output as used for some time.
 //
+// NOTE: Output leases are tracked by the LightningWallet on top of
+// LockOutpoint and UnlockOutpoint, so this is never called.
+//
 // This is a part of the WalletController interface.
-func (b *DcrWallet) LeaseOutput(lnwallet.LockID, wire.OutPoint) (time.Time, error) {
+func (b *DcrWallet) LeaseOutput(lnwallet.LockID, wire.OutPoint,
+	time.Duration) (time.Time, error) {
+
 	return time.Time{}, fmt.Errorf("unimplemented")
 }
 
 // ReleaseOutput marks the output as unused.
 //
+// NOTE: Output leases are tracked by the LightningWallet on top of
+// LockOutpoint and UnlockOutpoint, so this is never called.
+//
 // This is a part of the WalletController interface.
 func (b *DcrWallet) ReleaseOutput(lnwallet.LockID, wire.OutPoint) error {
 	return fmt.Errorf("unimplemented")
@@ -75,6 +75,16 @@ type Utxo struct {
 	PkScript      []byte
 	wire.OutPoint
 
+	// Locked is true if the output is currently locked by the wallet, for
+	// example as an input of a pending funding or sweep transaction, and
+	// is therefore unavailable for coin selection.
+	Locked bool
+
+	// LeaseExpiration is the time the lease of the output expires at if
+	// it's leased through LeaseOutput, in which case it's also locked. It
+	// is the zero time otherwise.
+	LeaseExpiration time.Time
+
 	// TODO(decred) this needs to include ScriptVersion. Then this version needs
 	// to be filled and used everywhere instead of DefaultScriptVersion.
 }
@@ -121,6 +131,10 @@ type TransactionDetail struct {
 
 	// Label is an optional transaction label.
 	Label string
+
+	// Accounts are the wallet accounts that funded inputs of or received
+	// outputs of the transaction.
+	Accounts []uint32
 }
 
 // TransactionSubscription is an interface which describes an object capable of
@@ -221,6 +235,16 @@ type WalletController interface {
 	// NOTE: This method requires the global coin selection lock to be held.
 	ListUnspentWitness(minconfirms, maxconfirms int32) ([]*Utxo, error)
 
+	// ListAccountUnspent returns all unspent outputs of the given wallet
+	// account that have between minConfs and maxConfs confirmations.
+	// Unlike ListUnspentWitness, outputs that are currently locked are
+	// included and have their Locked flag set. An empty account lists the
+	// same outputs as ListUnspentWitness.
+	//
+	// NOTE: This method requires the global coin selection lock to be held.
+	ListAccountUnspent(account string, minConfs, maxConfs int32) ([]*Utxo,
+		error)
+
 	// ListTransactionDetails returns a list of all transactions which are
 	// relevant to the wallet over [startHeight;endHeight]. If start height
 	// is greater than end height, the transactions will be retrieved in
@@ -245,18 +269,20 @@ type WalletController interface {
 	// NOTE: This method requires the global coin selection lock to be held.
 	UnlockOutpoint(o wire.OutPoint)
 
-	// LeaseOutput locks an output to the given ID, preventing it from being
-	// available for any future coin selection attempts. The absolute time
-	// of the lock's expiration is returned. The expiration of the lock can
-	// be extended by successive invocations of this call. Outputs can be
-	// unlocked before their expiration through `ReleaseOutput`.
+	// LeaseOutput locks an output to the given ID for the given duration,
+	// preventing it from being available for any future coin selection
+	// attempts. The absolute time of the lock's expiration is returned.
+	// The expiration of the lock can be extended by successive
+	// invocations of this call. Outputs can be unlocked before their
+	// expiration through `ReleaseOutput`.
 	//
-	// If the output is not known, wtxmgr.ErrUnknownOutput is returned. If
-	// the output has already been locked to a different ID, then
-	// wtxmgr.ErrOutputAlreadyLocked is returned.
+	// If the output is not known, ErrNotMine is returned. If the output
+	// has already been locked to a different ID, then
+	// ErrOutputAlreadyLocked is returned.
 	//
 	// NOTE: This method requires the global coin selection lock to be held.
-	LeaseOutput(id LockID, op wire.OutPoint) (time.Time, error)
+	LeaseOutput(id LockID, op wire.OutPoint,
+		duration time.Duration) (time.Time, error)
 
 	// ReleaseOutput unlocks an output, allowing it to be available for coin
 	// selection if it remains unspent. The ID should match the one used to
@@ -331,6 +357,32 @@ type WalletController interface {
 	BackEnd() string
 }
 
+// ChainSyncInfo describes the progress of the synchronization of a wallet
+// with its chain backend.
+type ChainSyncInfo struct {
+	// HeadersHeight is the height of the last block header fetched by the
+	// wallet.
+	HeadersHeight int64
+
+	// BlocksHeight is the height of the last block processed by the
+	// wallet. It lags behind HeadersHeight while the blocks are rescanned
+	// for relevant transactions.
+	BlocksHeight int64
+
+	// RescanHeight is the height an ongoing rescan progressed through, or
+	// zero if no rescan is in progress.
+	RescanHeight int64
+}
+
+// ChainSyncReporter is implemented by the WalletController implementations
+// able to report the progress of their synchronization with the chain
+// backend in more detail than IsSynced.
+type ChainSyncReporter interface {
+	// ChainSyncInfo returns the current progress of the synchronization
+	// of the wallet.
+	ChainSyncInfo() (*ChainSyncInfo, error)
+}
+
 // BlockChainIO is a dedicated source which will be used to obtain queries
 // related to the current state of the blockchain. The data returned by each of
 // the defined methods within this interface should always return the most up
@@ -375,6 +427,16 @@ type MessageSigner interface {
 	SignMessage(pubKey *secp256k1.PublicKey, msg []byte) (input.Signature, error)
 }
 
+// AddrMessageSigner is implemented by the WalletController implementations
+// able to sign arbitrary messages with the keys of their addresses, allowing
+// to prove the ownership of the addresses.
+type AddrMessageSigner interface {
+	// SignMessageWithAddr signs the message with the private key of the
+	// given wallet address. The message is signed in the standard Decred
+	// signed message format and the compact signature is returned.
+	SignMessageWithAddr(addr dcrutil.Address, msg string) ([]byte, error)
+}
+
 // WalletDriver represents a "driver" for a particular concrete
 // WalletController implementation. A driver is identified by a globally unique
 // string identifier along with a 'New()' method which is responsible for
@@ -0,0 +1,275 @@
+package lnwallet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+)
+
+var errMockPublish = errors.New("mock publish failure")
+
+// mockLabelWallet is a WalletController that publishes transactions without
+// labelling them, and lists the transactions it published.
+type mockLabelWallet struct {
+	mockUtxoWallet
+
+	published   []*wire.MsgTx
+	failPublish bool
+
+	txClient *mockTxSubscription
+}
+
+// mockTxSubscription is a TransactionSubscription whose notifications are sent
+// by the test.
+type mockTxSubscription struct {
+	confirmed   chan *TransactionDetail
+	unconfirmed chan *TransactionDetail
+	canceled    bool
+}
+
+func (m *mockTxSubscription) ConfirmedTransactions() chan *TransactionDetail {
+	return m.confirmed
+}
+
+func (m *mockTxSubscription) UnconfirmedTransactions() chan *TransactionDetail {
+	return m.unconfirmed
+}
+
+func (m *mockTxSubscription) Cancel() {
+	m.canceled = true
+}
+
+func (m *mockLabelWallet) SendOutputs(outputs []*wire.TxOut,
+	_ chainfee.AtomPerKByte, label string) (*wire.MsgTx, error) {
+
+	tx := wire.NewMsgTx()
+	for _, output := range outputs {
+		tx.AddTxOut(output)
+	}
+	if err := m.PublishTransaction(tx, label); err != nil {
+		return nil, err
+	}
+
+	return tx, nil
+}
+
+func (m *mockLabelWallet) PublishTransaction(tx *wire.MsgTx, _ string) error {
+	if m.failPublish {
+		return errMockPublish
+	}
+
+	m.published = append(m.published, tx)
+	return nil
+}
+
+func (m *mockLabelWallet) SubscribeTransactions() (TransactionSubscription,
+	error) {
+
+	return m.txClient, nil
+}
+
+func (m *mockLabelWallet) ListTransactionDetails(_, _ int32) (
+	[]*TransactionDetail, error) {
+
+	txs := make([]*TransactionDetail, 0, len(m.published))
+	for _, tx := range m.published {
+		txs = append(txs, &TransactionDetail{Hash: tx.TxHash()})
+	}
+
+	return txs, nil
+}
+
+// TestTxLabels asserts that the labels of the transactions published through
+// the wallet are stored in our database and set on the listed transactions.
+func TestTxLabels(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanUp()
+
+	mock := &mockLabelWallet{}
+	wallet, err := NewLightningWallet(Config{
+		Database:         db,
+		WalletController: mock,
+	})
+	if err != nil {
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+
+	// Transactions are published with and without a label.
+	sent, err := wallet.SendOutputs(
+		[]*wire.TxOut{{Value: 1000}}, 0, "sent",
+	)
+	if err != nil {
+		t.Fatalf("unable to send outputs: %v", err)
+	}
+	published := wire.NewMsgTx()
+	published.AddTxOut(&wire.TxOut{Value: 2000})
+	if err := wallet.PublishTransaction(published, "published"); err != nil {
+		t.Fatalf("unable to publish tx: %v", err)
+	}
+	unlabelled := wire.NewMsgTx()
+	unlabelled.AddTxOut(&wire.TxOut{Value: 3000})
+	if err := wallet.PublishTransaction(unlabelled, ""); err != nil {
+		t.Fatalf("unable to publish tx: %v", err)
+	}
+
+	// The label of a transaction that fails to publish isn't stored.
+	failed := wire.NewMsgTx()
+	failed.AddTxOut(&wire.TxOut{Value: 4000})
+	mock.failPublish = true
+	err = wallet.PublishTransaction(failed, "failed")
+	if err != errMockPublish {
+		t.Fatalf("expected publish failure, got %v", err)
+	}
+	mock.failPublish = false
+
+	assertLabels := func(expected map[chainhash.Hash]string) {
+		t.Helper()
+
+		txs, err := wallet.ListTransactionDetails(0, 0)
+		if err != nil {
+			t.Fatalf("unable to list txs: %v", err)
+		}
+		if len(txs) != 3 {
+			t.Fatalf("expected 3 txs, got %d", len(txs))
+		}
+		for _, tx := range txs {
+			if tx.Label != expected[tx.Hash] {
+				t.Fatalf("expected label %q for tx %v, got %q",
+					expected[tx.Hash], tx.Hash, tx.Label)
+			}
+		}
+
+		labels, err := db.FetchTxLabels()
+		if err != nil {
+			t.Fatalf("unable to fetch labels: %v", err)
+		}
+		if _, ok := labels[failed.TxHash()]; ok {
+			t.Fatalf("unexpected label for failed tx")
+		}
+	}
+	assertLabels(map[chainhash.Hash]string{
+		sent.TxHash():      "sent",
+		published.TxHash(): "published",
+	})
+
+	// Labelling a transaction that already has a label fails unless the
+	// label is overwritten.
+	err = wallet.LabelTransaction(sent.TxHash(), "relabelled", false)
+	if err != channeldb.ErrTxLabelExists {
+		t.Fatalf("expected existing label error, got %v", err)
+	}
+	err = wallet.LabelTransaction(sent.TxHash(), "relabelled", true)
+	if err != nil {
+		t.Fatalf("unable to overwrite label: %v", err)
+	}
+	err = wallet.LabelTransaction(unlabelled.TxHash(), "later", false)
+	if err != nil {
+		t.Fatalf("unable to label tx: %v", err)
+	}
+	assertLabels(map[chainhash.Hash]string{
+		sent.TxHash():       "relabelled",
+		published.TxHash():  "published",
+		unlabelled.TxHash(): "later",
+	})
+}
+
+// TestSubscribeTransactionsLabels asserts that the stored labels are set on
+// the transactions notified by a transaction subscription.
+func TestSubscribeTransactionsLabels(t *testing.T) {
+	t.Parallel()
+
+	db, cleanUp, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanUp()
+
+	mock := &mockLabelWallet{
+		txClient: &mockTxSubscription{
+			confirmed:   make(chan *TransactionDetail),
+			unconfirmed: make(chan *TransactionDetail),
+		},
+	}
+	wallet, err := NewLightningWallet(Config{
+		Database:         db,
+		WalletController: mock,
+	})
+	if err != nil {
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+
+	labelled := chainhash.Hash{1}
+	if err := wallet.LabelTransaction(labelled, "label", false); err != nil {
+		t.Fatalf("unable to label tx: %v", err)
+	}
+
+	txClient, err := wallet.SubscribeTransactions()
+	if err != nil {
+		t.Fatalf("unable to subscribe to txs: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		in     chan *TransactionDetail
+		out    chan *TransactionDetail
+		txHash chainhash.Hash
+		label  string
+	}{
+		{
+			name:   "confirmed labelled",
+			in:     mock.txClient.confirmed,
+			out:    txClient.ConfirmedTransactions(),
+			txHash: labelled,
+			label:  "label",
+		},
+		{
+			name:   "unconfirmed labelled",
+			in:     mock.txClient.unconfirmed,
+			out:    txClient.UnconfirmedTransactions(),
+			txHash: labelled,
+			label:  "label",
+		},
+		{
+			name:   "unlabelled",
+			in:     mock.txClient.confirmed,
+			out:    txClient.ConfirmedTransactions(),
+			txHash: chainhash.Hash{2},
+		},
+	}
+	for _, test := range tests {
+		select {
+		case test.in <- &TransactionDetail{Hash: test.txHash}:
+		case <-time.After(time.Second * 5):
+			t.Fatalf("%s: tx not received by subscription",
+				test.name)
+		}
+
+		select {
+		case tx := <-test.out:
+			if tx.Hash != test.txHash || tx.Label != test.label {
+				t.Fatalf("%s: expected tx %v with label %q, "+
+					"got %v with %q", test.name,
+					test.txHash, test.label, tx.Hash,
+					tx.Label)
+			}
+
+		case <-time.After(time.Second * 5):
+			t.Fatalf("%s: tx not notified", test.name)
+		}
+	}
+
+	txClient.Cancel()
+	if !mock.txClient.canceled {
+		t.Fatalf("underlying subscription not canceled")
+	}
+}
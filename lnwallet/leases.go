@@ -0,0 +1,237 @@
+package lnwallet
+
+import (
+	"errors"
+	"time"
+
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+)
+
+const (
+	// DefaultLeaseDuration is the duration of an output lease when the
+	// caller doesn't specify one.
+	DefaultLeaseDuration = 10 * time.Minute
+
+	// MaxLeaseDuration is the longest duration an output can be leased
+	// for at once.
+	MaxLeaseDuration = 365 * 24 * time.Hour
+
+	// leaseExpiryInterval is how often the wallet looks for expired
+	// output leases.
+	leaseExpiryInterval = time.Minute
+)
+
+var (
+	// ErrOutputAlreadyLocked is returned when attempting to lease an
+	// output that is either leased to a different ID or locked by one of
+	// the wallet's own reservations.
+	ErrOutputAlreadyLocked = errors.New("output already locked")
+
+	// ErrOutputUnlockNotAllowed is returned when attempting to release an
+	// output using an ID other than the one it was leased to.
+	ErrOutputUnlockNotAllowed = errors.New("output unlock not allowed")
+)
+
+// outputLease tracks an output locked on behalf of an external coin selector.
+type outputLease struct {
+	id         LockID
+	expiration time.Time
+}
+
+// LeaseOutput locks an output to the given ID for the given duration,
+// preventing it from being available for any future coin selection attempts
+// until either the lease expires or it's released. A zero duration leases the
+// output for DefaultLeaseDuration. Leasing an output already leased to the
+// same ID extends the lease. Leases are stored in our database, so they're
+// restored after a restart. The absolute time of the lease's expiration is
+// returned. This method wraps the internal WalletController method, which
+// only needs to be able to lock and unlock outputs.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) LeaseOutput(id LockID, op wire.OutPoint,
+	duration time.Duration) (time.Time, error) {
+
+	if duration == 0 {
+		duration = DefaultLeaseDuration
+	}
+	expiration := time.Now().Add(duration)
+
+	if lease, ok := l.leases[op]; ok {
+		if lease.id != id {
+			return time.Time{}, ErrOutputAlreadyLocked
+		}
+
+		if err := l.storeLease(op, id, expiration); err != nil {
+			return time.Time{}, err
+		}
+		l.leases[op] = &outputLease{id: id, expiration: expiration}
+		return expiration, nil
+	}
+
+	if _, ok := l.lockedOutPoints[op]; ok {
+		return time.Time{}, ErrOutputAlreadyLocked
+	}
+
+	// Make sure the output is one we can actually spend before locking
+	// it.
+	if _, err := l.FetchInputInfo(&op); err != nil {
+		return time.Time{}, err
+	}
+
+	if err := l.storeLease(op, id, expiration); err != nil {
+		return time.Time{}, err
+	}
+	l.WalletController.LockOutpoint(op)
+	l.leases[op] = &outputLease{id: id, expiration: expiration}
+
+	return expiration, nil
+}
+
+// storeLease stores the lease of an output in our database, so that it
+// survives restarts.
+func (l *LightningWallet) storeLease(op wire.OutPoint, id LockID,
+	expiration time.Time) error {
+
+	return l.Cfg.Database.PutOutputLease(&op, &channeldb.OutputLease{
+		ID:         id,
+		Expiration: expiration,
+	})
+}
+
+// ReleaseOutput unlocks a leased output, allowing it to be available for coin
+// selection if it remains unspent. The ID should match the one used to
+// originally lease the output. Releasing an output that isn't leased is a
+// no-op.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) ReleaseOutput(id LockID, op wire.OutPoint) error {
+	lease, ok := l.leases[op]
+	if !ok {
+		return nil
+	}
+	if lease.id != id {
+		return ErrOutputUnlockNotAllowed
+	}
+
+	if err := l.Cfg.Database.DeleteOutputLease(&op); err != nil {
+		return err
+	}
+	delete(l.leases, op)
+	l.WalletController.UnlockOutpoint(op)
+
+	return nil
+}
+
+// isLeased returns whether the given output is currently leased.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) isLeased(op wire.OutPoint) bool {
+	_, ok := l.leases[op]
+	return ok
+}
+
+// ListAccountUnspent returns all unspent outputs of the given wallet account
+// that have between minConfs and maxConfs confirmations, including the locked
+// ones, with the expiration of their lease set if they're leased.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) ListAccountUnspent(account string, minConfs,
+	maxConfs int32) ([]*Utxo, error) {
+
+	utxos, err := l.WalletController.ListAccountUnspent(
+		account, minConfs, maxConfs,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, utxo := range utxos {
+		if lease, ok := l.leases[utxo.OutPoint]; ok {
+			utxo.Locked = true
+			utxo.LeaseExpiration = lease.expiration
+		}
+	}
+
+	return utxos, nil
+}
+
+// expireLeases releases all the output leases that expired by the given time.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) expireLeases(now time.Time) {
+	for op, lease := range l.leases {
+		if now.Before(lease.expiration) {
+			continue
+		}
+
+		walletLog.Debugf("Lease of output %v expired, releasing", op)
+
+		// A lease that fails to be deleted is dropped on restart, as
+		// it has expired by then.
+		if err := l.Cfg.Database.DeleteOutputLease(&op); err != nil {
+			walletLog.Errorf("Unable to delete lease of output "+
+				"%v: %v", op, err)
+		}
+		delete(l.leases, op)
+		l.WalletController.UnlockOutpoint(op)
+	}
+}
+
+// restoreLeases locks the outputs whose lease is stored in our database, as
+// the locks of the WalletController don't survive restarts. The leases that
+// expired while we were offline are deleted instead.
+func (l *LightningWallet) restoreLeases() error {
+	l.coinSelectMtx.Lock()
+	defer l.coinSelectMtx.Unlock()
+
+	leases, err := l.Cfg.Database.FetchOutputLeases()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for op, lease := range leases {
+		if !now.Before(lease.Expiration) {
+			err := l.Cfg.Database.DeleteOutputLease(&op)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		walletLog.Debugf("Restoring lease of output %v until %v", op,
+			lease.Expiration)
+
+		l.WalletController.LockOutpoint(op)
+		l.leases[op] = &outputLease{
+			id:         lease.ID,
+			expiration: lease.Expiration,
+		}
+	}
+
+	return nil
+}
+
+// leaseExpirer periodically releases the output leases that have expired so
+// that stale leases don't keep funds out of coin selection forever.
+//
+// NOTE: This MUST be run as a goroutine.
+func (l *LightningWallet) leaseExpirer() {
+	defer l.wg.Done()
+
+	ticker := time.NewTicker(leaseExpiryInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			l.coinSelectMtx.Lock()
+			l.expireLeases(now)
+			l.coinSelectMtx.Unlock()
+
+		case <-l.quit:
+			return
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package lnwallet
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+)
+
+// mockLockWallet is a WalletController that only knows about a fixed set of
+// outputs and tracks which of them are locked.
+type mockLockWallet struct {
+	WalletController
+
+	known  map[wire.OutPoint]struct{}
+	locked map[wire.OutPoint]struct{}
+}
+
+func (m *mockLockWallet) FetchInputInfo(op *wire.OutPoint) (*Utxo, error) {
+	if _, ok := m.known[*op]; !ok {
+		return nil, ErrNotMine
+	}
+
+	return &Utxo{OutPoint: *op}, nil
+}
+
+func (m *mockLockWallet) ListAccountUnspent(_ string, _, _ int32) ([]*Utxo,
+	error) {
+
+	utxos := make([]*Utxo, 0, len(m.known))
+	for op := range m.known {
+		_, locked := m.locked[op]
+		utxos = append(utxos, &Utxo{OutPoint: op, Locked: locked})
+	}
+
+	return utxos, nil
+}
+
+func (m *mockLockWallet) LockOutpoint(op wire.OutPoint) {
+	m.locked[op] = struct{}{}
+}
+
+func (m *mockLockWallet) UnlockOutpoint(op wire.OutPoint) {
+	delete(m.locked, op)
+}
+
+// newLeaseTestWallet returns a wallet backed by the given WalletController
+// that stores its leases in a fresh database.
+func newLeaseTestWallet(t *testing.T,
+	mock *mockLockWallet) (*LightningWallet, func()) {
+
+	t.Helper()
+
+	db, cleanUp, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+
+	wallet, err := NewLightningWallet(Config{
+		Database:         db,
+		WalletController: mock,
+	})
+	if err != nil {
+		cleanUp()
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+
+	return wallet, cleanUp
+}
+
+// TestLeaseOutput asserts that leased outputs are locked until they're either
+// released by the ID that leased them or the lease expires.
+func TestLeaseOutput(t *testing.T) {
+	t.Parallel()
+
+	op1 := wire.OutPoint{Hash: chainhash.Hash{1}}
+	op2 := wire.OutPoint{Hash: chainhash.Hash{2}}
+	unknown := wire.OutPoint{Hash: chainhash.Hash{3}}
+
+	mock := &mockLockWallet{
+		known: map[wire.OutPoint]struct{}{
+			op1: {},
+			op2: {},
+		},
+		locked: make(map[wire.OutPoint]struct{}),
+	}
+	wallet, cleanUp := newLeaseTestWallet(t, mock)
+	defer cleanUp()
+
+	id1 := LockID{1}
+	id2 := LockID{2}
+
+	if _, err := wallet.LeaseOutput(id1, unknown, 0); err != ErrNotMine {
+		t.Fatalf("expected ErrNotMine, got %v", err)
+	}
+
+	// A zero duration should lease the output for the default duration.
+	start := time.Now()
+	expiration, err := wallet.LeaseOutput(id1, op1, 0)
+	if err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+	if expiration.Before(start.Add(DefaultLeaseDuration)) {
+		t.Fatalf("unexpected expiration %v", expiration)
+	}
+	if _, ok := mock.locked[op1]; !ok {
+		t.Fatalf("leased output not locked")
+	}
+
+	// The output can't be leased or released by a different ID.
+	_, err = wallet.LeaseOutput(id2, op1, time.Hour)
+	if err != ErrOutputAlreadyLocked {
+		t.Fatalf("expected ErrOutputAlreadyLocked, got %v", err)
+	}
+	err = wallet.ReleaseOutput(id2, op1)
+	if err != ErrOutputUnlockNotAllowed {
+		t.Fatalf("expected ErrOutputUnlockNotAllowed, got %v", err)
+	}
+
+	// Leasing it again with the same ID extends the lease.
+	extended, err := wallet.LeaseOutput(id1, op1, time.Hour)
+	if err != nil {
+		t.Fatalf("unable to extend lease: %v", err)
+	}
+	if !extended.After(expiration) {
+		t.Fatalf("lease not extended: %v", extended)
+	}
+
+	if err := wallet.ReleaseOutput(id1, op1); err != nil {
+		t.Fatalf("unable to release output: %v", err)
+	}
+	if _, ok := mock.locked[op1]; ok {
+		t.Fatalf("released output still locked")
+	}
+
+	// Outputs locked by one of our reservations can't be leased.
+	wallet.lockedOutPoints[op2] = struct{}{}
+	_, err = wallet.LeaseOutput(id1, op2, time.Minute)
+	if err != ErrOutputAlreadyLocked {
+		t.Fatalf("expected ErrOutputAlreadyLocked, got %v", err)
+	}
+	delete(wallet.lockedOutPoints, op2)
+
+	// Finally, stale leases should be released once they expire.
+	short, err := wallet.LeaseOutput(id1, op1, time.Minute)
+	if err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+	if _, err := wallet.LeaseOutput(id2, op2, time.Hour); err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+
+	wallet.expireLeases(short)
+	if _, ok := mock.locked[op1]; ok {
+		t.Fatalf("expired lease not released")
+	}
+	if _, ok := mock.locked[op2]; !ok {
+		t.Fatalf("unexpired lease released")
+	}
+	if wallet.isLeased(op1) || !wallet.isLeased(op2) {
+		t.Fatalf("unexpected leases: %v", wallet.leases)
+	}
+}
+
+// TestListAccountUnspentLeases asserts that leased outputs are listed as
+// locked, along with the expiration of their lease.
+func TestListAccountUnspentLeases(t *testing.T) {
+	t.Parallel()
+
+	leased := wire.OutPoint{Hash: chainhash.Hash{1}}
+	locked := wire.OutPoint{Hash: chainhash.Hash{2}}
+	free := wire.OutPoint{Hash: chainhash.Hash{3}}
+
+	mock := &mockLockWallet{
+		known: map[wire.OutPoint]struct{}{
+			leased: {},
+			locked: {},
+			free:   {},
+		},
+		locked: map[wire.OutPoint]struct{}{
+			locked: {},
+		},
+	}
+	wallet, cleanUp := newLeaseTestWallet(t, mock)
+	defer cleanUp()
+
+	expiration, err := wallet.LeaseOutput(LockID{1}, leased, time.Hour)
+	if err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+
+	utxos, err := wallet.ListAccountUnspent("", 0, 0)
+	if err != nil {
+		t.Fatalf("unable to list utxos: %v", err)
+	}
+	if len(utxos) != 3 {
+		t.Fatalf("expected 3 utxos, got %v", len(utxos))
+	}
+	for _, utxo := range utxos {
+		switch utxo.OutPoint {
+		case leased:
+			if !utxo.Locked || !utxo.LeaseExpiration.Equal(expiration) {
+				t.Fatalf("expected leased output to be locked "+
+					"until %v, got %v %v", expiration,
+					utxo.Locked, utxo.LeaseExpiration)
+			}
+
+		case locked:
+			if !utxo.Locked || !utxo.LeaseExpiration.IsZero() {
+				t.Fatalf("expected locked output without lease")
+			}
+
+		case free:
+			if utxo.Locked || !utxo.LeaseExpiration.IsZero() {
+				t.Fatalf("expected unlocked output")
+			}
+		}
+	}
+}
+
+// TestRestoreLeases asserts that the leases that haven't expired are restored
+// after a restart, locking their outputs again, while the expired ones are
+// dropped.
+func TestRestoreLeases(t *testing.T) {
+	t.Parallel()
+
+	active := wire.OutPoint{Hash: chainhash.Hash{1}}
+	expired := wire.OutPoint{Hash: chainhash.Hash{2}}
+	released := wire.OutPoint{Hash: chainhash.Hash{3}}
+
+	mock := &mockLockWallet{
+		known: map[wire.OutPoint]struct{}{
+			active:   {},
+			expired:  {},
+			released: {},
+		},
+		locked: make(map[wire.OutPoint]struct{}),
+	}
+	wallet, cleanUp := newLeaseTestWallet(t, mock)
+	defer cleanUp()
+
+	expiration, err := wallet.LeaseOutput(LockID{1}, active, time.Hour)
+	if err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+	if _, err := wallet.LeaseOutput(LockID{1}, released, 0); err != nil {
+		t.Fatalf("unable to lease output: %v", err)
+	}
+	if err := wallet.ReleaseOutput(LockID{1}, released); err != nil {
+		t.Fatalf("unable to release output: %v", err)
+	}
+
+	// The lease of the expired output is stored as is, as if it expired
+	// while we were offline.
+	expiredLease := &channeldb.OutputLease{
+		ID:         LockID{2},
+		Expiration: time.Now().Add(-time.Minute),
+	}
+	err = wallet.Cfg.Database.PutOutputLease(&expired, expiredLease)
+	if err != nil {
+		t.Fatalf("unable to store lease: %v", err)
+	}
+
+	// A new wallet, whose WalletController lost its locks, restores the
+	// leases from the database.
+	restartedMock := &mockLockWallet{
+		known:  mock.known,
+		locked: make(map[wire.OutPoint]struct{}),
+	}
+	restarted, err := NewLightningWallet(Config{
+		Database:         wallet.Cfg.Database,
+		WalletController: restartedMock,
+	})
+	if err != nil {
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+	if err := restarted.restoreLeases(); err != nil {
+		t.Fatalf("unable to restore leases: %v", err)
+	}
+
+	if len(restartedMock.locked) != 1 {
+		t.Fatalf("expected 1 locked output, got %v",
+			len(restartedMock.locked))
+	}
+	if _, ok := restartedMock.locked[active]; !ok {
+		t.Fatalf("leased output not locked")
+	}
+	lease, ok := restarted.leases[active]
+	if !ok || lease.id != (LockID{1}) ||
+		!lease.expiration.Equal(expiration) {
+
+		t.Fatalf("unexpected lease %v", lease)
+	}
+
+	// The expired lease was deleted.
+	leases, err := wallet.Cfg.Database.FetchOutputLeases()
+	if err != nil {
+		t.Fatalf("unable to fetch leases: %v", err)
+	}
+	if len(leases) != 1 {
+		t.Fatalf("expected 1 stored lease, got %v", len(leases))
+	}
+	if _, ok := leases[active]; !ok {
+		t.Fatalf("expected lease of %v to be stored", active)
+	}
+}
@@ -0,0 +1,170 @@
+package lnwallet
+
+import (
+	"math"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+)
+
+// fetchCloseTxids returns the set of the closing transactions of all our
+// closed channels.
+func (l *LightningWallet) fetchCloseTxids() (map[chainhash.Hash]struct{}, error) {
+	summaries, err := l.Cfg.Database.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+
+	closeTxids := make(map[chainhash.Hash]struct{}, len(summaries))
+	for _, summary := range summaries {
+		closeTxids[summary.ClosingTXID] = struct{}{}
+	}
+
+	return closeTxids, nil
+}
+
+// splitMaturing partitions the passed utxos into those that may be used for
+// coin selection and those that are still maturing. An output is maturing if
+// it was created by one of our channel closing or sweep transactions and has
+// not yet reached the number of confirmations configured for that class of
+// transaction.
+func (l *LightningWallet) splitMaturing(utxos []*Utxo) ([]*Utxo, []*Utxo,
+	error) {
+
+	closeConfs := int64(l.Cfg.CloseTxMinConfs)
+	sweepConfs := int64(l.Cfg.SweepTxMinConfs)
+	if l.Cfg.IsSweepTx == nil {
+		sweepConfs = 0
+	}
+
+	// If neither class requires more than a single confirmation, there's
+	// nothing to filter.
+	if closeConfs <= 1 && sweepConfs <= 1 {
+		return utxos, nil, nil
+	}
+
+	var (
+		closeTxids       map[chainhash.Hash]struct{}
+		mature, maturing []*Utxo
+		err              error
+	)
+	for _, utxo := range utxos {
+		var isMaturing bool
+		if utxo.Confirmations < closeConfs {
+			// Only hit the database for the closed channels once
+			// we find an output that may need them.
+			if closeTxids == nil {
+				closeTxids, err = l.fetchCloseTxids()
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+
+			_, isMaturing = closeTxids[utxo.Hash]
+		}
+
+		if !isMaturing && utxo.Confirmations < sweepConfs {
+			isMaturing, err = l.Cfg.IsSweepTx(utxo.Hash)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if isMaturing {
+			maturing = append(maturing, utxo)
+			continue
+		}
+		mature = append(mature, utxo)
+	}
+
+	return mature, maturing, nil
+}
+
+// ListUnspentWitness returns all unspent outputs which are available for coin
+// selection and have between minConfs and maxConfs confirmations. Outputs of
+// our channel closing and sweep transactions that haven't yet reached their
+// configured number of confirmations are omitted. This method wraps the
+// internal WalletController method.
+func (l *LightningWallet) ListUnspentWitness(minConfs, maxConfs int32) (
+	[]*Utxo, error) {
+
+	utxos, err := l.WalletController.ListUnspentWitness(minConfs, maxConfs)
+	if err != nil {
+		return nil, err
+	}
+
+	mature, _, err := l.splitMaturing(utxos)
+	if err != nil {
+		return nil, err
+	}
+
+	return mature, nil
+}
+
+// MaturingBalance returns the total value of the confirmed outputs of our
+// channel closing and sweep transactions that are not yet available for coin
+// selection.
+func (l *LightningWallet) MaturingBalance() (dcrutil.Amount, error) {
+	l.coinSelectMtx.Lock()
+	defer l.coinSelectMtx.Unlock()
+
+	return l.maturingBalance()
+}
+
+// maturingBalance returns the total value of the confirmed outputs that are
+// not yet available for coin selection.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) maturingBalance() (dcrutil.Amount, error) {
+	utxos, err := l.WalletController.ListUnspentWitness(1, math.MaxInt32)
+	if err != nil {
+		return 0, err
+	}
+
+	_, maturing, err := l.splitMaturing(utxos)
+	if err != nil {
+		return 0, err
+	}
+
+	var balance dcrutil.Amount
+	for _, utxo := range maturing {
+		balance += utxo.Value
+	}
+
+	return balance, nil
+}
+
+// lockMaturing locks all the currently maturing outputs so that the internal
+// wallet doesn't select them when funding a transaction. The returned closure
+// unlocks them again. Leased outputs are skipped as they're already locked
+// and must remain so.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) lockMaturing() (func(), error) {
+	utxos, err := l.WalletController.ListUnspentWitness(0, math.MaxInt32)
+	if err != nil {
+		return nil, err
+	}
+
+	_, maturing, err := l.splitMaturing(utxos)
+	if err != nil {
+		return nil, err
+	}
+
+	var locked []wire.OutPoint
+	for _, utxo := range maturing {
+		if l.isLeased(utxo.OutPoint) {
+			continue
+		}
+
+		l.WalletController.LockOutpoint(utxo.OutPoint)
+		locked = append(locked, utxo.OutPoint)
+	}
+
+	return func() {
+		for _, op := range locked {
+			l.WalletController.UnlockOutpoint(op)
+		}
+	}, nil
+}
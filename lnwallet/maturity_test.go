@@ -0,0 +1,120 @@
+package lnwallet
+
+import (
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/channeldb"
+)
+
+// mockUtxoWallet is a WalletController that only knows how to list a fixed
+// set of unspent outputs.
+type mockUtxoWallet struct {
+	WalletController
+
+	utxos []*Utxo
+}
+
+func (m *mockUtxoWallet) ListUnspentWitness(minConfs, maxConfs int32) (
+	[]*Utxo, error) {
+
+	var utxos []*Utxo
+	for _, utxo := range m.utxos {
+		if utxo.Confirmations < int64(minConfs) ||
+			utxo.Confirmations > int64(maxConfs) {
+
+			continue
+		}
+		utxos = append(utxos, utxo)
+	}
+
+	return utxos, nil
+}
+
+// TestMaturingUtxos asserts that the outputs of our closing and sweep
+// transactions are withheld from coin selection until they reach their
+// configured number of confirmations.
+func TestMaturingUtxos(t *testing.T) {
+	t.Parallel()
+
+	aliceChannel, _, cleanUp, err := CreateTestChannels(
+		channeldb.SingleFunderTweaklessBit,
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	// Close the channel so its closing transaction is known to the
+	// wallet's database.
+	closeTxid := chainhash.Hash{1}
+	sweepTxid := chainhash.Hash{2}
+	otherTxid := chainhash.Hash{3}
+
+	state := aliceChannel.channelState
+	err = state.CloseChannel(&channeldb.ChannelCloseSummary{
+		ChanPoint:   state.FundingOutpoint,
+		RemotePub:   state.IdentityPub,
+		ClosingTXID: closeTxid,
+	})
+	if err != nil {
+		t.Fatalf("unable to close channel: %v", err)
+	}
+
+	newUtxo := func(txid chainhash.Hash, confs int64) *Utxo {
+		return &Utxo{
+			Value:         1000,
+			Confirmations: confs,
+			OutPoint:      wire.OutPoint{Hash: txid},
+		}
+	}
+	freshClose := newUtxo(closeTxid, 2)
+	freshSweep := newUtxo(sweepTxid, 5)
+	oldSweep := newUtxo(sweepTxid, 6)
+	other := newUtxo(otherTxid, 1)
+
+	wallet, err := NewLightningWallet(Config{
+		Database: state.Db,
+		WalletController: &mockUtxoWallet{
+			utxos: []*Utxo{freshClose, freshSweep, oldSweep, other},
+		},
+		CloseTxMinConfs: 3,
+		SweepTxMinConfs: 6,
+		IsSweepTx: func(txid chainhash.Hash) (bool, error) {
+			return txid == sweepTxid, nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to create wallet: %v", err)
+	}
+
+	utxos, err := wallet.ListUnspentWitness(0, 100)
+	if err != nil {
+		t.Fatalf("unable to list utxos: %v", err)
+	}
+	if len(utxos) != 2 || utxos[0] != oldSweep || utxos[1] != other {
+		t.Fatalf("unexpected spendable utxos: %v", utxos)
+	}
+
+	balance, err := wallet.MaturingBalance()
+	if err != nil {
+		t.Fatalf("unable to fetch maturing balance: %v", err)
+	}
+	if balance != freshClose.Value+freshSweep.Value {
+		t.Fatalf("expected maturing balance %v, got %v",
+			freshClose.Value+freshSweep.Value, balance)
+	}
+
+	// Once the closing transaction reaches the required depth, its output
+	// is no longer maturing.
+	freshClose.Confirmations = 3
+	balance, err = wallet.MaturingBalance()
+	if err != nil {
+		t.Fatalf("unable to fetch maturing balance: %v", err)
+	}
+	if balance != freshSweep.Value {
+		t.Fatalf("expected maturing balance %v, got %v",
+			freshSweep.Value, balance)
+	}
+}
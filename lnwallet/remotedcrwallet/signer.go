@@ -282,3 +282,28 @@ func (b *DcrWallet) SignMessage(pubKey *secp256k1.PublicKey,
 // A compile time check to ensure that DcrWallet implements the Messageinput.Signer
 // interface.
 var _ lnwallet.MessageSigner = (*DcrWallet)(nil)
+
+// SignMessageWithAddr signs the message with the private key of the given
+// wallet address. The message is signed in the standard Decred signed message
+// format and the compact signature is returned.
+//
+// NOTE: This is a part of the AddrMessageSigner interface.
+func (b *DcrWallet) SignMessageWithAddr(addr dcrutil.Address,
+	msg string) ([]byte, error) {
+
+	req := &pb.SignMessageRequest{
+		Address:    addr.Address(),
+		Message:    msg,
+		Passphrase: b.cfg.PrivatePass,
+	}
+	resp, err := b.wallet.SignMessage(b.ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Signature, nil
+}
+
+// A compile time check to ensure that DcrWallet implements the
+// AddrMessageSigner interface.
+var _ lnwallet.AddrMessageSigner = (*DcrWallet)(nil)
@@ -446,12 +446,45 @@ func (b *DcrWallet) UnlockOutpoint(o wire.OutPoint) {
 func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 	[]*lnwallet.Utxo, error) {
 
+	return b.listUnspent(b.account, minConfs, maxConfs, false)
+}
+
+// ListAccountUnspent returns all unspent outputs of the given wallet account
+// that have between minConfs and maxConfs confirmations, including the ones
+// that are currently locked. An empty account lists the same outputs as
+// ListUnspentWitness, those of the account used for the funds of the wallet.
+//
+// This is a part of the WalletController interface.
+func (b *DcrWallet) ListAccountUnspent(account string, minConfs,
+	maxConfs int32) ([]*lnwallet.Utxo, error) {
+
+	accountNb := b.account
+	if account != "" {
+		req := &pb.AccountNumberRequest{
+			AccountName: account,
+		}
+		resp, err := b.wallet.AccountNumber(context.Background(), req)
+		if err != nil {
+			return nil, err
+		}
+		accountNb = resp.AccountNumber
+	}
+
+	return b.listUnspent(accountNb, minConfs, maxConfs, true)
+}
+
+// listUnspent returns the unspent outputs of the given account. Outputs locked
+// by this wallet are either omitted or marked as locked, depending on
+// includeLocked.
+func (b *DcrWallet) listUnspent(account uint32, minConfs, maxConfs int32,
+	includeLocked bool) ([]*lnwallet.Utxo, error) {
+
 	if maxConfs != 0 && maxConfs != math.MaxInt32 {
 		return nil, fmt.Errorf("maxconfs is not supported")
 	}
 
 	req := &pb.UnspentOutputsRequest{
-		Account:               b.account,
+		Account:               account,
 		RequiredConfirmations: minConfs,
 	}
 	stream, err := b.wallet.UnspentOutputs(context.Background(), req)
@@ -478,7 +511,8 @@ func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 			return nil, err
 		}
 
-		// Ensure this utxo hasn't been locked.
+		// Ensure this utxo hasn't been locked, unless the caller is
+		// interested in locked utxos as well.
 		outp := wire.OutPoint{
 			Hash:  *txid,
 			Index: msg.OutputIndex,
@@ -487,7 +521,7 @@ func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 		b.lockedOutpointsMu.Lock()
 		_, lockedUtxo := b.lockedOutpoints[outp]
 		b.lockedOutpointsMu.Unlock()
-		if lockedUtxo {
+		if lockedUtxo && !includeLocked {
 			continue
 		}
 
@@ -512,6 +546,7 @@ func (b *DcrWallet) ListUnspentWitness(minConfs, maxConfs int32) (
 			PkScript:      msg.PkScript,
 			OutPoint:      outp,
 			Confirmations: int64(confs),
+			Locked:        lockedUtxo,
 		}
 		utxos = append(utxos, utxo)
 	}
@@ -683,6 +718,28 @@ func extractBalanceDelta(
 	return balanceDelta, nil
 }
 
+// extractAccounts returns the distinct wallet accounts involved in the inputs
+// and outputs of a transaction.
+func extractAccounts(txSummary *pb.TransactionDetails) []uint32 {
+	var accounts []uint32
+	addAccount := func(account uint32) {
+		for _, a := range accounts {
+			if a == account {
+				return
+			}
+		}
+		accounts = append(accounts, account)
+	}
+	for _, input := range txSummary.Debits {
+		addAccount(input.PreviousAccount)
+	}
+	for _, output := range txSummary.Credits {
+		addAccount(output.Account)
+	}
+
+	return accounts
+}
+
 // minedTransactionsToDetails is a helper function which converts a summary
 // information about mined transactions to a TransactionDetail.
 func minedTransactionsToDetails(
@@ -734,6 +791,7 @@ func minedTransactionsToDetails(
 			return nil, err
 		}
 		txDetail.Value = balanceDelta
+		txDetail.Accounts = extractAccounts(tx)
 
 		details = append(details, txDetail)
 	}
@@ -780,6 +838,7 @@ func unminedTransactionsToDetail(
 		return nil, err
 	}
 	txDetail.Value = balanceDelta
+	txDetail.Accounts = extractAccounts(summary)
 
 	return txDetail, nil
 }
@@ -1098,13 +1157,21 @@ func (b *DcrWallet) LabelTransaction(hash chainhash.Hash, label string, overwrit
 
 // LeaseOutput markes the output as used for some time.
 //
+// NOTE: Output leases are tracked by the LightningWallet on top of
+// LockOutpoint and UnlockOutpoint, so this is never called.
+//
 // This is a part of the WalletController interface.
-func (b *DcrWallet) LeaseOutput(lnwallet.LockID, wire.OutPoint) (time.Time, error) {
+func (b *DcrWallet) LeaseOutput(lnwallet.LockID, wire.OutPoint,
+	time.Duration) (time.Time, error) {
+
 	return time.Time{}, fmt.Errorf("unimplemented")
 }
 
 // ReleaseOutput marks the output as unused.
 //
+// NOTE: Output leases are tracked by the LightningWallet on top of
+// LockOutpoint and UnlockOutpoint, so this is never called.
+//
 // This is a part of the WalletController interface.
 func (b *DcrWallet) ReleaseOutput(lnwallet.LockID, wire.OutPoint) error {
 	return fmt.Errorf("unimplemented")
@@ -683,6 +683,12 @@ func (r *ChannelReservation) Capacity() dcrutil.Amount {
 	return r.partialState.Capacity
 }
 
+// LocalFundingAmt returns the amount we contribute to the funding transaction
+// of this reservation.
+func (r *ChannelReservation) LocalFundingAmt() dcrutil.Amount {
+	return r.fundingIntent.LocalFundingAmt()
+}
+
 // Cancel abandons this channel reservation. This method should be called in
 // the scenario that communications with the counterparty break down. Upon
 // cancellation, all resources previously reserved for this pending payment
@@ -309,6 +309,10 @@ type LightningWallet struct {
 	// the currently locked outpoints.
 	lockedOutPoints map[wire.OutPoint]struct{}
 
+	// leases tracks the outputs currently leased through LeaseOutput,
+	// keyed by their outpoint. It's guarded by the coin selection lock.
+	leases map[wire.OutPoint]*outputLease
+
 	// fundingIntents houses all the "interception" registered by a caller
 	// using the RegisterFundingIntent method.
 	intentMtx      sync.RWMutex
@@ -333,6 +337,7 @@ func NewLightningWallet(cfg Config) (*LightningWallet, error) {
 		nextFundingID:    0,
 		fundingLimbo:     make(map[uint64]*ChannelReservation),
 		lockedOutPoints:  make(map[wire.OutPoint]struct{}),
+		leases:           make(map[wire.OutPoint]*outputLease),
 		fundingIntents:   make(map[[32]byte]chanfunding.Intent),
 		quit:             make(chan struct{}),
 	}, nil
@@ -351,9 +356,15 @@ func (l *LightningWallet) Startup() error {
 		return err
 	}
 
-	l.wg.Add(1)
+	// Lock the outputs that were leased before we restarted.
+	if err := l.restoreLeases(); err != nil {
+		return err
+	}
+
+	l.wg.Add(2)
 	// TODO(roasbeef): multiple request handlers?
 	go l.requestHandler()
+	go l.leaseExpirer()
 
 	return nil
 }
@@ -385,6 +396,49 @@ func (l *LightningWallet) ConfirmedBalance(confs int32) (dcrutil.Amount, error)
 	return l.WalletController.ConfirmedBalance(confs)
 }
 
+// WalletBalances is a snapshot of the on-chain balances of the wallet.
+type WalletBalances struct {
+	// Confirmed is the balance of the outputs with at least one
+	// confirmation.
+	Confirmed dcrutil.Amount
+
+	// Unconfirmed is the balance of the outputs with no confirmations.
+	Unconfirmed dcrutil.Amount
+
+	// Maturing is the part of the confirmed balance that was returned to
+	// us by closing or sweep transactions and isn't yet available for
+	// coin selection.
+	Maturing dcrutil.Amount
+}
+
+// WalletBalances returns the confirmed, unconfirmed and maturing balances of
+// the wallet. As the coin selection lock is held for the duration of the call,
+// the balances are consistent with each other.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) WalletBalances() (*WalletBalances, error) {
+	total, err := l.WalletController.ConfirmedBalance(0)
+	if err != nil {
+		return nil, err
+	}
+
+	confirmed, err := l.WalletController.ConfirmedBalance(1)
+	if err != nil {
+		return nil, err
+	}
+
+	maturing, err := l.maturingBalance()
+	if err != nil {
+		return nil, err
+	}
+
+	return &WalletBalances{
+		Confirmed:   confirmed,
+		Unconfirmed: total - confirmed,
+		Maturing:    maturing,
+	}, nil
+}
+
 // LockedOutpoints returns a list of all currently locked outpoint.
 func (l *LightningWallet) LockedOutpoints() []*wire.OutPoint {
 	outPoints := make([]*wire.OutPoint, 0, len(l.lockedOutPoints))
@@ -397,6 +451,204 @@ func (l *LightningWallet) LockedOutpoints() []*wire.OutPoint {
 	return outPoints
 }
 
+// SendOutputs funds, signs, and broadcasts a transaction paying out to the
+// specified outputs. This method wraps the internal WalletController method so
+// that maturing outputs aren't spent and the optional label is stored with the
+// transaction.
+//
+// NOTE: This method requires the global coin selection lock to be held.
+func (l *LightningWallet) SendOutputs(outputs []*wire.TxOut,
+	feeRate chainfee.AtomPerKByte, label string) (*wire.MsgTx, error) {
+
+	unlockMaturing, err := l.lockMaturing()
+	if err != nil {
+		return nil, err
+	}
+	defer unlockMaturing()
+
+	tx, err := l.WalletController.SendOutputs(outputs, feeRate, label)
+	if err != nil {
+		return nil, err
+	}
+
+	l.storeTxLabel(tx.TxHash(), label)
+
+	return tx, nil
+}
+
+// PublishTransaction broadcasts the passed transaction to the network. This
+// method wraps the internal WalletController method so that the optional label
+// is stored with the transaction.
+func (l *LightningWallet) PublishTransaction(tx *wire.MsgTx,
+	label string) error {
+
+	if err := l.WalletController.PublishTransaction(tx, label); err != nil {
+		return err
+	}
+
+	l.storeTxLabel(tx.TxHash(), label)
+
+	return nil
+}
+
+// storeTxLabel stores the label of a transaction that was just published. As
+// the transaction is already broadcast at this point, failures are only
+// logged.
+func (l *LightningWallet) storeTxLabel(txid chainhash.Hash, label string) {
+	if label == "" {
+		return
+	}
+
+	if err := l.Cfg.Database.PutTxLabel(txid, label, true); err != nil {
+		walletLog.Errorf("Unable to store label of tx %v: %v", txid,
+			err)
+	}
+}
+
+// LabelTransaction adds a label to a transaction. If the tx already has a
+// label, this call will fail unless the overwrite parameter is set. The labels
+// are stored in our database, so they are available regardless of the
+// WalletController in use.
+func (l *LightningWallet) LabelTransaction(hash chainhash.Hash, label string,
+	overwrite bool) error {
+
+	return l.Cfg.Database.PutTxLabel(hash, label, overwrite)
+}
+
+// ListTransactionDetails returns a list of all transactions which are relevant
+// to the wallet over [startHeight;endHeight]. This method wraps the internal
+// WalletController method so that the stored labels are set on the returned
+// transactions.
+func (l *LightningWallet) ListTransactionDetails(startHeight,
+	endHeight int32) ([]*TransactionDetail, error) {
+
+	txs, err := l.WalletController.ListTransactionDetails(
+		startHeight, endHeight,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	txLabels, err := l.Cfg.Database.FetchTxLabels()
+	if err != nil {
+		return nil, err
+	}
+	for _, tx := range txs {
+		if label, ok := txLabels[tx.Hash]; ok {
+			tx.Label = label
+		}
+	}
+
+	return txs, nil
+}
+
+// SubscribeTransactions returns a TransactionSubscription client which is
+// capable of receiving async notifications as new transactions related to the
+// wallet are seen within the network, or found in blocks. This method wraps
+// the internal WalletController method so that the stored labels are set on
+// the notified transactions.
+func (l *LightningWallet) SubscribeTransactions() (TransactionSubscription,
+	error) {
+
+	txClient, err := l.WalletController.SubscribeTransactions()
+	if err != nil {
+		return nil, err
+	}
+
+	labelClient := &txLabelSubscription{
+		txClient:    txClient,
+		db:          l.Cfg.Database,
+		confirmed:   make(chan *TransactionDetail),
+		unconfirmed: make(chan *TransactionDetail),
+		quit:        make(chan struct{}),
+	}
+	labelClient.wg.Add(1)
+	go labelClient.labelProxier()
+
+	return labelClient, nil
+}
+
+// txLabelSubscription proxies the notifications of a TransactionSubscription,
+// setting the labels stored in our database on the notified transactions.
+type txLabelSubscription struct {
+	txClient TransactionSubscription
+
+	db *channeldb.DB
+
+	confirmed   chan *TransactionDetail
+	unconfirmed chan *TransactionDetail
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// ConfirmedTransactions returns a channel which will be sent on as new
+// relevant transactions are confirmed.
+//
+// This is part of the TransactionSubscription interface.
+func (t *txLabelSubscription) ConfirmedTransactions() chan *TransactionDetail {
+	return t.confirmed
+}
+
+// UnconfirmedTransactions returns a channel which will be sent on as new
+// relevant transactions are seen within the network.
+//
+// This is part of the TransactionSubscription interface.
+func (t *txLabelSubscription) UnconfirmedTransactions() chan *TransactionDetail {
+	return t.unconfirmed
+}
+
+// Cancel finalizes the subscription, cleaning up any resources allocated.
+//
+// This is part of the TransactionSubscription interface.
+func (t *txLabelSubscription) Cancel() {
+	close(t.quit)
+	t.wg.Wait()
+
+	t.txClient.Cancel()
+}
+
+// labelProxier sets the stored labels on the transactions notified by the
+// underlying subscription and sends them over the channels of the
+// subscription. Transactions whose label can't be fetched are sent without
+// one.
+//
+// NOTE: This MUST be run as a goroutine.
+func (t *txLabelSubscription) labelProxier() {
+	defer t.wg.Done()
+
+	for {
+		var (
+			tx  *TransactionDetail
+			out chan *TransactionDetail
+		)
+		select {
+		case tx = <-t.txClient.ConfirmedTransactions():
+			out = t.confirmed
+
+		case tx = <-t.txClient.UnconfirmedTransactions():
+			out = t.unconfirmed
+
+		case <-t.quit:
+			return
+		}
+
+		label, err := t.db.FetchTxLabel(tx.Hash)
+		if err != nil {
+			walletLog.Errorf("Unable to fetch label of tx %v: %v",
+				tx.Hash, err)
+		} else {
+			tx.Label = label
+		}
+
+		select {
+		case out <- tx:
+		case <-t.quit:
+			return
+		}
+	}
+}
+
 // ResetReservations reset the volatile wallet state which tracks all currently
 // active reservations.
 func (l *LightningWallet) ResetReservations() {
@@ -23,6 +23,11 @@ const (
 	// FundingOpen request for a channel that is above their current
 	// soft-limit.
 	ErrChanTooLarge FundingError = 3
+
+	// ErrMaxGlobalPendingChannels is returned by a remote peer when the
+	// number of active pending channels across all of its peers exceeds
+	// its maximum policy limit.
+	ErrMaxGlobalPendingChannels FundingError = 4
 )
 
 // String returns a human readable version of the target FundingError.
@@ -34,6 +39,9 @@ func (e FundingError) String() string {
 		return "Synchronizing blockchain"
 	case ErrChanTooLarge:
 		return "channel too large"
+	case ErrMaxGlobalPendingChannels:
+		return "Number of pending channels across all peers exceed " +
+			"maximum"
 	default:
 		return "unknown error"
 	}
@@ -46,6 +54,22 @@ func (e FundingError) Error() string {
 	return e.String()
 }
 
+// ParseFundingError returns the FundingError whose human readable version
+// matches the given error data, as sent by a remote peer. The second return
+// value is false if the data doesn't match any known FundingError.
+func ParseFundingError(data ErrorData) (FundingError, bool) {
+	for _, e := range []FundingError{
+		ErrMaxPendingChannels, ErrSynchronizingChain, ErrChanTooLarge,
+		ErrMaxGlobalPendingChannels,
+	} {
+		if string(data) == e.String() {
+			return e, true
+		}
+	}
+
+	return 0, false
+}
+
 // ErrorData is a set of bytes associated with a particular sent error. A
 // receiving node SHOULD only print out data verbatim if the string is composed
 // solely of printable ASCII characters. For reference, the printable character
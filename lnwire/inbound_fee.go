@@ -0,0 +1,169 @@
+package lnwire
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/decred/dcrlnd/tlv"
+)
+
+const (
+	// InboundFeeRecordType is the type of the TLV record carrying the
+	// inbound fee of a channel in the extra opaque data of its channel
+	// updates. The type is odd, so nodes unaware of inbound fees ignore
+	// the record.
+	InboundFeeRecordType tlv.Type = 55555
+
+	// MaxInboundFeeRate is the largest magnitude of the proportional
+	// inbound fee, which is a fee or a discount of the whole forwarded
+	// amount.
+	MaxInboundFeeRate = 1000000
+)
+
+// InboundFee is a fee charged by a node on HTLCs it receives through a
+// channel, in addition to the fee of the outgoing channel the HTLCs are
+// forwarded to. Negative values are discounts on the outgoing fee. This lets
+// routing nodes price liquidity depending on the direction in which it flows
+// through their channels.
+type InboundFee struct {
+	// BaseFee is the base fee in milli-atoms charged for each HTLC.
+	BaseFee int32
+
+	// FeeRate is the proportional fee in millionths of the forwarded
+	// amount.
+	FeeRate int32
+}
+
+// Validate returns an error if the proportional fee is larger than the
+// forwarded amount.
+func (f InboundFee) Validate() error {
+	if f.FeeRate > MaxInboundFeeRate || f.FeeRate < -MaxInboundFeeRate {
+		return fmt.Errorf("inbound fee rate %v out of range [%v, %v]",
+			f.FeeRate, -MaxInboundFeeRate, MaxInboundFeeRate)
+	}
+
+	return nil
+}
+
+// Fee returns the inbound fee for forwarding the given amount, which is the
+// amount of the outgoing HTLC plus the fee of the outgoing channel. Positive
+// fees are rounded down while negative fees are rounded up. The proportional
+// fee is computed separately for the millions and the remainder of the amount,
+// so that it can't overflow for any valid fee rate and any amount up to the
+// total supply of coins.
+func (f InboundFee) Fee(amt MilliAtom) int64 {
+	rate := int64(f.FeeRate)
+	millions := int64(amt / 1000000)
+	remainder := int64(amt % 1000000)
+
+	return int64(f.BaseFee) + rate*millions + rate*remainder/1000000
+}
+
+// record returns the TLV record of the inbound fee.
+func (f *InboundFee) record() tlv.Record {
+	return tlv.MakeStaticRecord(
+		InboundFeeRecordType, f, 8, encodeInboundFee, decodeInboundFee,
+	)
+}
+
+// encodeInboundFee is the TLV encoder of an inbound fee.
+func encodeInboundFee(w io.Writer, val interface{}, buf *[8]byte) error {
+	if f, ok := val.(*InboundFee); ok {
+		binary.BigEndian.PutUint32(buf[:4], uint32(f.BaseFee))
+		binary.BigEndian.PutUint32(buf[4:], uint32(f.FeeRate))
+		_, err := w.Write(buf[:])
+		return err
+	}
+
+	return tlv.NewTypeForEncodingErr(val, "lnwire.InboundFee")
+}
+
+// decodeInboundFee is the TLV decoder of an inbound fee.
+func decodeInboundFee(r io.Reader, val interface{}, buf *[8]byte,
+	l uint64) error {
+
+	if f, ok := val.(*InboundFee); ok && l == 8 {
+		if _, err := io.ReadFull(r, buf[:]); err != nil {
+			return err
+		}
+		f.BaseFee = int32(binary.BigEndian.Uint32(buf[:4]))
+		f.FeeRate = int32(binary.BigEndian.Uint32(buf[4:]))
+		return f.Validate()
+	}
+
+	return tlv.NewTypeForDecodingErr(val, "lnwire.InboundFee", l, 8)
+}
+
+// ExtractInboundFee returns the inbound fee carried by the extra opaque data
+// of a channel update, or nil if it doesn't carry one.
+func ExtractInboundFee(extraData []byte) (*InboundFee, error) {
+	var fee InboundFee
+	stream, err := tlv.NewStream(fee.record())
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(
+		bytes.NewReader(extraData),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, ok := parsedTypes[InboundFeeRecordType]; !ok {
+		return nil, nil
+	}
+
+	return &fee, nil
+}
+
+// SetInboundFee returns the extra opaque data of a channel update with its
+// inbound fee replaced by the given one, or removed if nil. Any other record
+// of the extra opaque data is preserved.
+func SetInboundFee(extraData []byte, fee *InboundFee) ([]byte, error) {
+	var oldFee InboundFee
+	stream, err := tlv.NewStream(oldFee.record())
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(
+		bytes.NewReader(extraData),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Known types are reported without a value, so this only keeps the
+	// records other than the inbound fee.
+	otherRecords := make(map[uint64][]byte)
+	for typ, value := range parsedTypes {
+		if value != nil {
+			otherRecords[uint64(typ)] = value
+		}
+	}
+
+	records := tlv.MapToRecords(otherRecords)
+	if fee != nil {
+		records = append(records, fee.record())
+		tlv.SortRecords(records)
+	}
+
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	stream, err = tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := stream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
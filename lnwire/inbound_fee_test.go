@@ -0,0 +1,154 @@
+package lnwire
+
+import (
+	"bytes"
+	"math"
+	"testing"
+)
+
+// TestInboundFeeExtraData asserts that the inbound fee record is set,
+// replaced and removed from the extra opaque data of channel updates without
+// affecting other records.
+func TestInboundFeeExtraData(t *testing.T) {
+	t.Parallel()
+
+	// An unknown odd record of type 1 with a two byte value.
+	otherRecord := []byte{0x01, 0x02, 0xaa, 0xbb}
+
+	fee, err := ExtractInboundFee(otherRecord)
+	if err != nil {
+		t.Fatalf("unable to extract inbound fee: %v", err)
+	}
+	if fee != nil {
+		t.Fatalf("expected no inbound fee, got %v", fee)
+	}
+
+	discount := &InboundFee{BaseFee: -1000, FeeRate: -200}
+	extraData, err := SetInboundFee(otherRecord, discount)
+	if err != nil {
+		t.Fatalf("unable to set inbound fee: %v", err)
+	}
+	if !bytes.HasPrefix(extraData, otherRecord) {
+		t.Fatalf("other record not preserved: %x", extraData)
+	}
+
+	fee, err = ExtractInboundFee(extraData)
+	if err != nil {
+		t.Fatalf("unable to extract inbound fee: %v", err)
+	}
+	if fee == nil || *fee != *discount {
+		t.Fatalf("expected inbound fee %v, got %v", discount, fee)
+	}
+
+	surcharge := &InboundFee{BaseFee: 10, FeeRate: 300}
+	extraData, err = SetInboundFee(extraData, surcharge)
+	if err != nil {
+		t.Fatalf("unable to replace inbound fee: %v", err)
+	}
+	fee, err = ExtractInboundFee(extraData)
+	if err != nil {
+		t.Fatalf("unable to extract inbound fee: %v", err)
+	}
+	if fee == nil || *fee != *surcharge {
+		t.Fatalf("expected inbound fee %v, got %v", surcharge, fee)
+	}
+
+	extraData, err = SetInboundFee(extraData, nil)
+	if err != nil {
+		t.Fatalf("unable to remove inbound fee: %v", err)
+	}
+	if !bytes.Equal(extraData, otherRecord) {
+		t.Fatalf("expected extra data %x, got %x", otherRecord,
+			extraData)
+	}
+
+	extraData, err = SetInboundFee(nil, nil)
+	if err != nil {
+		t.Fatalf("unable to remove inbound fee: %v", err)
+	}
+	if len(extraData) != 0 {
+		t.Fatalf("expected no extra data, got %x", extraData)
+	}
+}
+
+// TestInboundFeeFee asserts the rounding of inbound fees.
+func TestInboundFeeFee(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		fee      InboundFee
+		amt      MilliAtom
+		expected int64
+	}{{
+		fee:      InboundFee{BaseFee: 1000, FeeRate: 100},
+		amt:      1999999,
+		expected: 1199,
+	}, {
+		fee:      InboundFee{BaseFee: -1000, FeeRate: -100},
+		amt:      1999999,
+		expected: -1199,
+	}, {
+		fee:      InboundFee{},
+		amt:      1000000,
+		expected: 0,
+	}, {
+		// The largest fee rate applied to an amount whose product
+		// with the rate overflows an int64.
+		fee:      InboundFee{FeeRate: MaxInboundFeeRate},
+		amt:      MilliAtom(math.MaxInt64 / 10000),
+		expected: math.MaxInt64 / 10000,
+	}, {
+		fee:      InboundFee{FeeRate: -MaxInboundFeeRate},
+		amt:      MilliAtom(math.MaxInt64 / 10000),
+		expected: -(math.MaxInt64 / 10000),
+	}, {
+		fee:      InboundFee{FeeRate: 1},
+		amt:      math.MaxUint64,
+		expected: math.MaxUint64 / 1000000,
+	}}
+
+	for _, test := range testCases {
+		fee := test.fee.Fee(test.amt)
+		if fee != test.expected {
+			t.Fatalf("expected fee %v for %v with %v, got %v",
+				test.expected, test.amt, test.fee, fee)
+		}
+	}
+}
+
+// TestInboundFeeValidate asserts that inbound fee rates larger than the
+// forwarded amount are rejected, both when validated and when decoded.
+func TestInboundFeeValidate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		feeRate int32
+		valid   bool
+	}{
+		{feeRate: MaxInboundFeeRate, valid: true},
+		{feeRate: -MaxInboundFeeRate, valid: true},
+		{feeRate: MaxInboundFeeRate + 1, valid: false},
+		{feeRate: -MaxInboundFeeRate - 1, valid: false},
+		{feeRate: math.MinInt32, valid: false},
+	}
+
+	for _, test := range testCases {
+		fee := InboundFee{BaseFee: 1, FeeRate: test.feeRate}
+		err := fee.Validate()
+		if (err == nil) != test.valid {
+			t.Fatalf("unexpected validation of fee rate %v: %v",
+				test.feeRate, err)
+		}
+
+		extraData, err := SetInboundFee(nil, &fee)
+		if err != nil {
+			t.Fatalf("unable to set inbound fee: %v", err)
+		}
+
+		_, err = ExtractInboundFee(extraData)
+		if (err == nil) != test.valid {
+			t.Fatalf("unexpected decoding of fee rate %v: %v",
+				test.feeRate, err)
+		}
+	}
+}
@@ -2,6 +2,7 @@ package dcrlnd
 
 import (
 	"context"
+	"runtime/debug"
 
 	"github.com/decred/dcrd/connmgr"
 	"github.com/decred/dcrlnd/autopilot"
@@ -9,14 +10,20 @@ import (
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/chainscan"
 	"github.com/decred/dcrlnd/chainscan/csdrivers"
+	"github.com/decred/dcrlnd/chanacceptor"
 	"github.com/decred/dcrlnd/chanbackup"
 	"github.com/decred/dcrlnd/chanfitness"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channelnotifier"
 	"github.com/decred/dcrlnd/contractcourt"
+	"github.com/decred/dcrlnd/crashreport"
 	"github.com/decred/dcrlnd/discovery"
+	"github.com/decred/dcrlnd/extsigner"
+	"github.com/decred/dcrlnd/feecontrol"
+	"github.com/decred/dcrlnd/feeschedule"
 	"github.com/decred/dcrlnd/healthcheck"
 	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/htlcswitch/reputation"
 	"github.com/decred/dcrlnd/invoices"
 	"github.com/decred/dcrlnd/keychain"
 	"github.com/decred/dcrlnd/lnrpc/autopilotrpc"
@@ -35,6 +42,7 @@ import (
 	"github.com/decred/dcrlnd/netann"
 	"github.com/decred/dcrlnd/peer"
 	"github.com/decred/dcrlnd/peernotifier"
+	"github.com/decred/dcrlnd/reorgnotifier"
 	"github.com/decred/dcrlnd/routing"
 	"github.com/decred/dcrlnd/routing/localchans"
 	"github.com/decred/dcrlnd/signal"
@@ -44,6 +52,8 @@ import (
 	sphinx "github.com/decred/lightning-onion/v3"
 	"github.com/decred/slog"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // replaceableLogger is a thin wrapper around a logger that is used so the
@@ -107,7 +117,7 @@ func SetupLoggers(root *build.RotatingLogWriter) {
 	AddSubLogger(root, "DISC", discovery.UseLogger)
 	AddSubLogger(root, "NTFN", chainntnfs.UseLogger)
 	AddSubLogger(root, "CHDB", channeldb.UseLogger)
-	AddSubLogger(root, "HSWC", htlcswitch.UseLogger)
+	AddSubLogger(root, htlcswitch.Subsystem, htlcswitch.UseLogger)
 	AddSubLogger(root, "CMGR", connmgr.UseLogger)
 	AddSubLogger(root, "CNCT", contractcourt.UseLogger)
 	AddSubLogger(root, "SPHX", sphinx.UseLogger)
@@ -126,14 +136,21 @@ func SetupLoggers(root *build.RotatingLogWriter) {
 	AddSubLogger(root, "WTCL", wtclient.UseLogger)
 	AddSubLogger(root, "PRNF", peernotifier.UseLogger)
 	AddSubLogger(root, "CHFD", chanfunding.UseLogger)
-	AddSubLogger(root, "PEER", peer.UseLogger)
+	AddSubLogger(root, peer.Subsystem, peer.UseLogger)
 	AddSubLogger(root, "CHCL", chancloser.UseLogger)
 
 	AddSubLogger(root, routing.Subsystem, routing.UseLogger, localchans.UseLogger)
 	AddSubLogger(root, routerrpc.Subsystem, routerrpc.UseLogger)
 	AddSubLogger(root, chanfitness.Subsystem, chanfitness.UseLogger)
+	AddSubLogger(root, feecontrol.Subsystem, feecontrol.UseLogger)
+	AddSubLogger(root, feeschedule.Subsystem, feeschedule.UseLogger)
+	AddSubLogger(root, reputation.Subsystem, reputation.UseLogger)
 	AddSubLogger(root, verrpc.Subsystem, verrpc.UseLogger)
 	AddSubLogger(root, healthcheck.Subsystem, healthcheck.UseLogger)
+	AddSubLogger(root, extsigner.Subsystem, extsigner.UseLogger)
+	AddSubLogger(root, crashreport.Subsystem, crashreport.UseLogger)
+	AddSubLogger(root, reorgnotifier.Subsystem, reorgnotifier.UseLogger)
+	AddSubLogger(root, chanacceptor.Subsystem, chanacceptor.UseLogger)
 
 	// Decred-specific logs.
 	AddSubLogger(root, "DCRW", dcrwallet.UseLogger)
@@ -141,6 +158,12 @@ func SetupLoggers(root *build.RotatingLogWriter) {
 	AddSubLogger(root, "KCHN", keychain.UseLogger)
 	AddSubLogger(root, "CSCN", chainscan.UseLogger)
 	AddSubLogger(root, "CSDR", csdrivers.UseLogger)
+
+	// The lines allowed by the log level override of a single channel or
+	// peer are written through separate loggers of the same subsystems.
+	build.SetTagLogGenerator(func(subsystem string) slog.Logger {
+		return build.NewSubLogger(subsystem, root.GenSubLogger)
+	})
 }
 
 // AddSubLogger is a helper method to conveniently create and register the
@@ -198,6 +221,47 @@ func errorLogUnaryServerInterceptor(logger slog.Logger) grpc.UnaryServerIntercep
 	}
 }
 
+// panicRecoveryUnaryServerInterceptor is a UnaryServerInterceptor that
+// recovers from panics when serving a client's unary request, recording a
+// crash report and returning an internal error instead.
+func panicRecoveryUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (resp interface{}, err error) {
+
+		defer func() {
+			if p := recover(); p != nil {
+				crashreport.RecordPanic(
+					info.FullMethod, p, debug.Stack(),
+				)
+				err = status.Errorf(codes.Internal, "panic: %v", p)
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+// panicRecoveryStreamServerInterceptor is a StreamServerInterceptor that
+// recovers from panics while processing a client or server streaming RPC,
+// recording a crash report and ending the stream with an internal error
+// instead.
+func panicRecoveryStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream,
+		info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+
+		defer func() {
+			if p := recover(); p != nil {
+				crashreport.RecordPanic(
+					info.FullMethod, p, debug.Stack(),
+				)
+				err = status.Errorf(codes.Internal, "panic: %v", p)
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
 // errorLogStreamServerInterceptor is a simple StreamServerInterceptor that
 // will log any errors that occur while processing a client or server streaming
 // RPC.
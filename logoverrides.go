@@ -0,0 +1,182 @@
+package dcrlnd
+
+import (
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/build"
+	"github.com/decred/slog"
+)
+
+// pendingRevert is a scheduled revert of a temporary log level change.
+type pendingRevert struct {
+	timer *time.Timer
+	id    uint64
+}
+
+// logLevelOverrides applies the log level changes requested through the
+// DebugLevel call, reverting the temporary ones once they expire.
+type logLevelOverrides struct {
+	mu sync.Mutex
+
+	logWriter *build.RotatingLogWriter
+
+	// nextID is the ID of the next scheduled revert. It allows a firing
+	// timer to detect that its revert was superseded in the meantime.
+	nextID uint64
+
+	// levelsRevert is the pending revert of a temporary change of the
+	// subsystem levels.
+	levelsRevert *pendingRevert
+
+	// savedLevels are the subsystem levels in effect before the first of
+	// the pending temporary changes, which are restored by levelsRevert.
+	savedLevels map[string]slog.Level
+
+	// tagReverts are the pending reverts of the temporary tag overrides.
+	tagReverts map[string]*pendingRevert
+}
+
+// newLogLevelOverrides creates a logLevelOverrides instance that changes the
+// levels of the sub loggers of the given log writer.
+func newLogLevelOverrides(
+	logWriter *build.RotatingLogWriter) *logLevelOverrides {
+
+	return &logLevelOverrides{
+		logWriter:  logWriter,
+		tagReverts: make(map[string]*pendingRevert),
+	}
+}
+
+// scheduleRevert schedules the given function to run after the given
+// duration. The caller must hold the mutex.
+func (o *logLevelOverrides) scheduleRevert(after time.Duration,
+	revert func(id uint64)) *pendingRevert {
+
+	id := o.nextID
+	o.nextID++
+
+	return &pendingRevert{
+		timer: time.AfterFunc(after, func() { revert(id) }),
+		id:    id,
+	}
+}
+
+// setLevels applies the given level spec to the subsystem loggers. If
+// revertAfter is non-zero, the levels in effect before the change are
+// restored once it elapses. A permanent change cancels a pending revert.
+func (o *logLevelOverrides) setLevels(levelSpec string,
+	revertAfter time.Duration) error {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// Only the levels in effect before the first of a series of temporary
+	// changes are saved, so that extending a temporary change doesn't make
+	// it permanent.
+	savedLevels := o.savedLevels
+	if savedLevels == nil {
+		savedLevels = make(map[string]slog.Level)
+		for subsystem, logger := range o.logWriter.SubLoggers() {
+			savedLevels[subsystem] = logger.Level()
+		}
+	}
+
+	err := build.ParseAndSetDebugLevels(levelSpec, o.logWriter)
+	if err != nil {
+		return err
+	}
+
+	if o.levelsRevert != nil {
+		o.levelsRevert.timer.Stop()
+		o.levelsRevert = nil
+	}
+	o.savedLevels = nil
+
+	if revertAfter == 0 {
+		return nil
+	}
+
+	o.savedLevels = savedLevels
+	o.levelsRevert = o.scheduleRevert(revertAfter, o.revertLevels)
+
+	return nil
+}
+
+// revertLevels restores the saved subsystem levels, unless the revert with
+// the given ID was superseded.
+func (o *logLevelOverrides) revertLevels(id uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.levelsRevert == nil || o.levelsRevert.id != id {
+		return
+	}
+
+	subLoggers := o.logWriter.SubLoggers()
+	for subsystem, level := range o.savedLevels {
+		if logger, ok := subLoggers[subsystem]; ok {
+			logger.SetLevel(level)
+		}
+	}
+
+	rpcsLog.Infof("[debuglevel] temporary debug levels expired")
+
+	o.levelsRevert = nil
+	o.savedLevels = nil
+}
+
+// setTagLevel overrides the level of the loggers with the given tag. If
+// revertAfter is non-zero, the override is removed once it elapses.
+func (o *logLevelOverrides) setTagLevel(tag string, level slog.Level,
+	revertAfter time.Duration) {
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.cancelTagRevert(tag)
+	build.SetTagLogLevel(tag, level)
+
+	if revertAfter == 0 {
+		return
+	}
+
+	o.tagReverts[tag] = o.scheduleRevert(revertAfter, func(id uint64) {
+		o.revertTagLevel(tag, id)
+	})
+}
+
+// clearTagLevel removes the level override of the given tag.
+func (o *logLevelOverrides) clearTagLevel(tag string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.cancelTagRevert(tag)
+	build.ClearTagLogLevel(tag)
+}
+
+// cancelTagRevert cancels the pending revert of the given tag, if any. The
+// caller must hold the mutex.
+func (o *logLevelOverrides) cancelTagRevert(tag string) {
+	if revert, ok := o.tagReverts[tag]; ok {
+		revert.timer.Stop()
+		delete(o.tagReverts, tag)
+	}
+}
+
+// revertTagLevel removes the level override of the given tag, unless the
+// revert with the given ID was superseded.
+func (o *logLevelOverrides) revertTagLevel(tag string, id uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	revert, ok := o.tagReverts[tag]
+	if !ok || revert.id != id {
+		return
+	}
+
+	build.ClearTagLogLevel(tag)
+	delete(o.tagReverts, tag)
+
+	rpcsLog.Infof("[debuglevel] temporary debug level of %v expired", tag)
+}
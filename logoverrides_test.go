@@ -0,0 +1,91 @@
+package dcrlnd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/build"
+	"github.com/decred/slog"
+)
+
+// waitForLevel waits until the given function returns the expected level.
+func waitForLevel(t *testing.T, expected slog.Level,
+	level func() slog.Level) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for level() != expected {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected level %v, got %v", expected, level())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// TestLogLevelOverrides asserts that temporary log level changes are reverted
+// to the levels in effect before the first of them, and that permanent changes
+// cancel a pending revert.
+func TestLogLevelOverrides(t *testing.T) {
+	logWriter := build.NewRotatingLogWriter()
+	logger := logWriter.GenSubLogger("TEST")
+	logger.SetLevel(slog.LevelInfo)
+	logWriter.RegisterSubLogger("TEST", logger)
+
+	overrides := newLogLevelOverrides(logWriter)
+
+	// Two consecutive temporary changes revert to the original level.
+	if err := overrides.setLevels("debug", time.Hour); err != nil {
+		t.Fatalf("unable to set levels: %v", err)
+	}
+	if err := overrides.setLevels("trace", 50*time.Millisecond); err != nil {
+		t.Fatalf("unable to set levels: %v", err)
+	}
+	if logger.Level() != slog.LevelTrace {
+		t.Fatalf("expected trace level, got %v", logger.Level())
+	}
+	waitForLevel(t, slog.LevelInfo, logger.Level)
+
+	// A permanent change cancels the pending revert.
+	if err := overrides.setLevels("debug", 50*time.Millisecond); err != nil {
+		t.Fatalf("unable to set levels: %v", err)
+	}
+	if err := overrides.setLevels("warn", 0); err != nil {
+		t.Fatalf("unable to set levels: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if logger.Level() != slog.LevelWarn {
+		t.Fatalf("expected warn level, got %v", logger.Level())
+	}
+
+	// An invalid level spec is rejected.
+	if err := overrides.setLevels("TEST=nope", 0); err == nil {
+		t.Fatalf("expected invalid level spec to be rejected")
+	}
+
+	// Temporary tag overrides are removed once they expire.
+	tag := build.PeerLogTag([]byte{0x02})
+	overrides.setTagLevel(tag, slog.LevelTrace, 50*time.Millisecond)
+	if _, ok := build.TagLogLevels()[tag]; !ok {
+		t.Fatalf("expected override of %v", tag)
+	}
+	waitForLevel(t, slog.LevelOff, func() slog.Level {
+		level, ok := build.TagLogLevels()[tag]
+		if !ok {
+			return slog.LevelOff
+		}
+		return level
+	})
+
+	// A permanent tag override remains until it's cleared.
+	overrides.setTagLevel(tag, slog.LevelDebug, 50*time.Millisecond)
+	overrides.setTagLevel(tag, slog.LevelDebug, 0)
+	time.Sleep(100 * time.Millisecond)
+	if _, ok := build.TagLogLevels()[tag]; !ok {
+		t.Fatalf("expected override of %v", tag)
+	}
+	overrides.clearTagLevel(tag)
+	if _, ok := build.TagLogLevels()[tag]; ok {
+		t.Fatalf("expected override of %v to be cleared", tag)
+	}
+}
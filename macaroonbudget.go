@@ -0,0 +1,281 @@
+package dcrlnd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwallet/chainfee"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/macaroons"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+var (
+	// budgetEnforcedMethods are the spending methods that enforce the
+	// spending budgets of macaroons. Macaroons carrying a budget can't
+	// call any other method requiring one of budgetSpendingOps.
+	budgetEnforcedMethods = []string{
+		"/lnrpc.Lightning/SendCoins",
+		"/lnrpc.Lightning/SendMany",
+		"/lnrpc.Lightning/SendPayment",
+		"/lnrpc.Lightning/SendPaymentSync",
+		"/lnrpc.Lightning/SendToRoute",
+		"/lnrpc.Lightning/SendToRouteSync",
+		"/routerrpc.Router/SendPaymentV2",
+	}
+
+	// budgetSpendingOps are the permissions of the methods able to spend
+	// funds.
+	budgetSpendingOps = []bakery.Op{{
+		Entity: "onchain",
+		Action: "write",
+	}, {
+		Entity: "offchain",
+		Action: "write",
+	}}
+)
+
+// budgetSpend is a spend reserved against the budgets of a macaroon.
+type budgetSpend struct {
+	db      *channeldb.DB
+	macID   []byte
+	spendID uint64
+}
+
+// resolve sets the final amount of the spend, zero if nothing was spent.
+// Failures are only logged, as the spend already happened or failed.
+func (s *budgetSpend) resolve(amt lnwire.MilliAtom) {
+	if s == nil {
+		return
+	}
+
+	err := s.db.ResolveMacaroonSpend(s.macID, s.spendID, amt)
+	if err != nil {
+		rpcsLog.Errorf("Unable to resolve macaroon spend %v: %v",
+			s.spendID, err)
+	}
+}
+
+// budgetsFromContext returns the identifier and the spending budgets of the
+// macaroon used to authenticate the request of the passed context. No budgets
+// are returned for requests made without a macaroon.
+func budgetsFromContext(ctx context.Context) ([]byte, []*macaroons.Budget,
+	error) {
+
+	mac, err := macaroonFromContext(ctx)
+	if err != nil || mac == nil {
+		return nil, nil, err
+	}
+
+	budgets, err := macaroons.Budgets(mac)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return mac.Id(), budgets, nil
+}
+
+// reserveBudget reserves the given maximum amount against the spending
+// budgets of the macaroon used to authenticate the request of the passed
+// context. A nil spend is returned if the macaroon carries no budget.
+func (r *rpcServer) reserveBudget(ctx context.Context,
+	amt lnwire.MilliAtom) (*budgetSpend, error) {
+
+	macID, budgets, err := budgetsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	return r.reserveMacaroonSpend(macID, budgets, amt)
+}
+
+// reservePaymentBudget reserves the maximum amount of a payment dispatched by
+// the router sub-server against the spending budgets of the macaroon used to
+// authenticate the request of the passed context. The returned function must
+// be called with the result of dispatching the payment: the reservation is
+// released if nothing was sent, and otherwise resolved with the amount paid
+// once the payment completes. A nil function is returned if the macaroon
+// carries no budget.
+func (r *rpcServer) reservePaymentBudget(ctx context.Context,
+	hash lntypes.Hash, amt lnwire.MilliAtom) (func(error), error) {
+
+	spend, err := r.reserveBudget(ctx, amt)
+	if err != nil || spend == nil {
+		return nil, err
+	}
+
+	return func(dispatchErr error) {
+		if dispatchErr == nil {
+			go r.resolvePaymentBudget(spend, hash)
+			return
+		}
+
+		if !paymentMaySpend(
+			r.routerBackend.Tower.FetchPayment, hash, dispatchErr,
+		) {
+
+			spend.resolve(0)
+		}
+	}, nil
+}
+
+// resolvePaymentBudget waits for the payment with the given hash to complete
+// and resolves the spend reserved for it with the amount paid. Nothing is
+// released if the payment failed with htlcs still in flight, or if the server
+// shuts down before the payment completes.
+func (r *rpcServer) resolvePaymentBudget(spend *budgetSpend,
+	hash lntypes.Hash) {
+
+	subscription, err := r.routerBackend.Tower.SubscribePayment(hash)
+	if err != nil {
+		rpcsLog.Errorf("Unable to subscribe to payment %v: %v", hash,
+			err)
+		return
+	}
+	defer subscription.Close()
+
+	// The subscription is closed once the payment completes, after
+	// sending its final state.
+	var payment *channeldb.MPPayment
+updates:
+	for {
+		select {
+		case item, ok := <-subscription.Updates:
+			if !ok {
+				break updates
+			}
+			payment = item.(*channeldb.MPPayment)
+
+		case <-r.quit:
+			return
+		}
+	}
+
+	if payment == nil {
+		return
+	}
+
+	var spent lnwire.MilliAtom
+	for _, htlc := range payment.HTLCs {
+		if htlc.Settle != nil {
+			spent += htlc.Route.TotalAmount
+		}
+	}
+
+	switch {
+	case payment.Status == channeldb.StatusSucceeded:
+		spend.resolve(spent)
+
+	case len(payment.InFlightHTLCs()) == 0:
+		spend.resolve(0)
+	}
+}
+
+// reserveOnChainBudget reserves the amounts paid to the given address to amount
+// pairs, along with the fee of the transaction paying them at the given fee
+// rate, against the spending budgets of the macaroon used to authenticate the
+// request of the passed context, so that the caller can't spend more than the
+// budget as miner fees. The fee is found by authoring the transaction without
+// publishing it, so the coin selection lock MUST be held for it to match the
+// transaction sent afterwards. A nil spend is returned if the macaroon carries
+// no budget.
+func (r *rpcServer) reserveOnChainBudget(ctx context.Context,
+	paymentMap map[string]int64, feeRate chainfee.AtomPerKByte) (
+	*budgetSpend, error) {
+
+	macID, budgets, err := budgetsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+
+	outputs, err := addrPairsToOutputs(paymentMap, activeNetParams.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := r.server.cc.wallet.CreateSimpleTx(outputs, feeRate, true)
+	if err != nil {
+		return nil, err
+	}
+
+	// The total input covers both the outputs and the fee, minus the
+	// change returned to the wallet.
+	spent := tx.TotalInput
+	if tx.ChangeIndex >= 0 {
+		spent -= dcrutil.Amount(tx.Tx.TxOut[tx.ChangeIndex].Value)
+	}
+
+	return r.reserveMacaroonSpend(
+		macID, budgets, lnwire.NewMAtomsFromAtoms(spent),
+	)
+}
+
+// reserveMacaroonSpend reserves the given amount against the passed spending
+// budgets of the macaroon with the given identifier.
+func (r *rpcServer) reserveMacaroonSpend(macID []byte,
+	budgets []*macaroons.Budget, amt lnwire.MilliAtom) (*budgetSpend,
+	error) {
+
+	limits := make([]channeldb.SpendLimit, 0, len(budgets))
+	for _, budget := range budgets {
+		limits = append(limits, channeldb.SpendLimit{
+			Window: budget.Period,
+			Max:    lnwire.NewMAtomsFromAtoms(budget.MaxAmount),
+		})
+	}
+
+	spendID, err := r.server.remoteChanDB.ReserveMacaroonSpend(
+		macID, amt, limits,
+	)
+	if err == channeldb.ErrSpendLimitExceeded {
+		return nil, fmt.Errorf("spending %v would exceed the budget of "+
+			"the macaroon", amt.ToAtoms())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to reserve macaroon spend: %v",
+			err)
+	}
+
+	return &budgetSpend{
+		db:      r.server.remoteChanDB,
+		macID:   macID,
+		spendID: spendID,
+	}, nil
+}
+
+// paymentMaySpend returns whether htlcs of the payment with the given hash may
+// have been or may still be sent after the router returned the passed error,
+// in which case the budget reserved for the payment can't be released.
+func paymentMaySpend(fetchPayment func(lntypes.Hash) (*channeldb.MPPayment,
+	error), hash lntypes.Hash, routerErr error) bool {
+
+	// The payment was already made or is being made by another call, so
+	// nothing was sent by this one.
+	if routerErr == channeldb.ErrAlreadyPaid ||
+		routerErr == channeldb.ErrPaymentInFlight {
+
+		return false
+	}
+
+	payment, err := fetchPayment(hash)
+	switch {
+	case err == channeldb.ErrPaymentNotInitiated:
+		return false
+
+	case err != nil:
+		rpcsLog.Errorf("Unable to fetch payment %v: %v", hash, err)
+		return true
+	}
+
+	return payment.Status != channeldb.StatusFailed ||
+		len(payment.InFlightHTLCs()) > 0
+}
@@ -0,0 +1,192 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/lntest/wait"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/macaroons"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/route"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/macaroon.v2"
+)
+
+// TestPaymentMaySpend asserts that the budget reserved for a failed payment is
+// only released when none of its htlcs may have been sent.
+func TestPaymentMaySpend(t *testing.T) {
+	t.Parallel()
+
+	routerErr := errors.New("router error")
+	inFlight := []channeldb.HTLCAttempt{{}}
+
+	tests := []struct {
+		name      string
+		payment   *channeldb.MPPayment
+		fetchErr  error
+		routerErr error
+		maySpend  bool
+	}{
+		{
+			name:      "already paid",
+			routerErr: channeldb.ErrAlreadyPaid,
+		},
+		{
+			name:      "in flight by another call",
+			routerErr: channeldb.ErrPaymentInFlight,
+		},
+		{
+			name:      "not initiated",
+			fetchErr:  channeldb.ErrPaymentNotInitiated,
+			routerErr: routerErr,
+		},
+		{
+			name:      "unknown payment state",
+			fetchErr:  errors.New("db error"),
+			routerErr: routerErr,
+			maySpend:  true,
+		},
+		{
+			name: "failed",
+			payment: &channeldb.MPPayment{
+				Status: channeldb.StatusFailed,
+			},
+			routerErr: routerErr,
+		},
+		{
+			name: "failed with htlcs in flight",
+			payment: &channeldb.MPPayment{
+				Status: channeldb.StatusFailed,
+				HTLCs:  inFlight,
+			},
+			routerErr: routerErr,
+			maySpend:  true,
+		},
+		{
+			name: "still in flight",
+			payment: &channeldb.MPPayment{
+				Status: channeldb.StatusInFlight,
+			},
+			routerErr: routerErr,
+			maySpend:  true,
+		},
+		{
+			name: "succeeded",
+			payment: &channeldb.MPPayment{
+				Status: channeldb.StatusSucceeded,
+			},
+			routerErr: routerErr,
+			maySpend:  true,
+		},
+	}
+
+	for _, test := range tests {
+		fetchPayment := func(lntypes.Hash) (*channeldb.MPPayment,
+			error) {
+
+			return test.payment, test.fetchErr
+		}
+
+		maySpend := paymentMaySpend(
+			fetchPayment, lntypes.Hash{}, test.routerErr,
+		)
+		if maySpend != test.maySpend {
+			t.Fatalf("%v: expected may spend %v, got %v", test.name,
+				test.maySpend, maySpend)
+		}
+	}
+}
+
+// TestReservePaymentBudget asserts that the budget reserved for a payment
+// dispatched by the router sub-server is released if nothing was sent, and
+// resolved with the amount paid once the payment completes.
+func TestReservePaymentBudget(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanup()
+
+	control := routing.NewControlTower(channeldb.NewPaymentControl(db))
+	r := &rpcServer{
+		server: &server{remoteChanDB: db},
+		routerBackend: &routerrpc.RouterBackend{
+			Tower: control,
+		},
+		quit: make(chan struct{}),
+	}
+	defer close(r.quit)
+
+	mac, err := macaroon.New(
+		[]byte("root key"), []byte("mac"), "lnd", macaroon.LatestVersion,
+	)
+	if err != nil {
+		t.Fatalf("unable to create macaroon: %v", err)
+	}
+	budget := macaroons.BudgetConstraint(macaroons.BudgetPeriodDay, 2000)
+	if err := budget(mac); err != nil {
+		t.Fatalf("unable to add budget: %v", err)
+	}
+	macBytes, err := mac.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unable to serialize macaroon: %v", err)
+	}
+	ctx := metadata.NewIncomingContext(
+		context.Background(),
+		metadata.Pairs("macaroon", hex.EncodeToString(macBytes)),
+	)
+
+	// A payment that couldn't be dispatched releases its budget.
+	dispatched, err := r.reservePaymentBudget(
+		ctx, lntypes.Hash{1}, 1500000,
+	)
+	if err != nil {
+		t.Fatalf("unable to reserve budget: %v", err)
+	}
+	dispatched(errors.New("dispatch failed"))
+
+	// The budget of a dispatched payment remains reserved until it
+	// completes.
+	var self route.Vertex
+	preimage := lntypes.Preimage{1, 2, 3}
+	dispatched, err = r.reservePaymentBudget(
+		ctx, preimage.Hash(), 1500000,
+	)
+	if err != nil {
+		t.Fatalf("unable to reserve budget: %v", err)
+	}
+	if _, err := r.reserveBudget(ctx, 1000000); err == nil {
+		t.Fatalf("expected reserved budget to be exceeded")
+	}
+
+	err = recordFixturePayment(control, self, paymentFixture{
+		Preimage:     hex.EncodeToString(preimage[:]),
+		Value:        1000,
+		Dest:         testFixtureDest,
+		CreationDate: 1600000000,
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("unable to record payment: %v", err)
+	}
+	dispatched(nil)
+
+	// Once the payment completed, only the amount paid is counted.
+	err = wait.NoError(func() error {
+		_, err := r.reserveBudget(ctx, 1000000)
+		return err
+	}, time.Second)
+	if err != nil {
+		t.Fatalf("budget not resolved with the amount paid: %v", err)
+	}
+	if _, err := r.reserveBudget(ctx, 1); err == nil {
+		t.Fatalf("expected budget to be exceeded")
+	}
+}
@@ -0,0 +1,157 @@
+package macaroons
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+	"gopkg.in/macaroon-bakery.v2/bakery/checkers"
+	macaroon "gopkg.in/macaroon.v2"
+)
+
+const (
+	// CondBudget is the name of the caveat limiting the amount that can
+	// be spent with a macaroon over a period of time.
+	CondBudget = "budget"
+
+	// BudgetPeriodDay and BudgetPeriodWeek are the periods over which a
+	// spending budget can apply.
+	BudgetPeriodDay  = "day"
+	BudgetPeriodWeek = "week"
+)
+
+// Budget is the maximum amount that can be spent with a macaroon over a
+// rolling period of time.
+type Budget struct {
+	// Period is the duration over which spends are summed.
+	Period time.Duration
+
+	// MaxAmount is the maximum amount that can be spent within the
+	// period.
+	MaxAmount dcrutil.Amount
+}
+
+// budgetPeriods maps the names of the budget periods to their duration.
+var budgetPeriods = map[string]time.Duration{
+	BudgetPeriodDay:  time.Hour * 24,
+	BudgetPeriodWeek: time.Hour * 24 * 7,
+}
+
+// ParseBudget parses the argument of a budget caveat, of the form
+// "<period> <max atoms>".
+func ParseBudget(arg string) (*Budget, error) {
+	parts := strings.Fields(arg)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid budget %q, expected "+
+			"<period> <max atoms>", arg)
+	}
+
+	period, ok := budgetPeriods[parts[0]]
+	if !ok {
+		return nil, fmt.Errorf("invalid budget period %q, must be "+
+			"%v or %v", parts[0], BudgetPeriodDay, BudgetPeriodWeek)
+	}
+
+	maxAmount, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil || maxAmount < 0 {
+		return nil, fmt.Errorf("invalid budget amount %q", parts[1])
+	}
+
+	return &Budget{
+		Period:    period,
+		MaxAmount: dcrutil.Amount(maxAmount),
+	}, nil
+}
+
+// BudgetConstraint limits the amount that can be spent with the macaroon over
+// the given period, either day or week.
+func BudgetConstraint(period string, maxAtoms int64) func(*macaroon.Macaroon) error {
+	return func(mac *macaroon.Macaroon) error {
+		arg := fmt.Sprintf("%v %v", period, maxAtoms)
+		if _, err := ParseBudget(arg); err != nil {
+			return err
+		}
+
+		caveat := checkers.Condition(CondBudget, arg)
+		return mac.AddFirstPartyCaveat([]byte(caveat))
+	}
+}
+
+// NewBudgetChecker returns a checker for budget caveats. The budget of a
+// macaroon can only be enforced by the given methods, so macaroons carrying a
+// budget are refused for any other method requiring one of the given spending
+// permissions.
+func NewBudgetChecker(enforcedMethods []string,
+	spendingOps []bakery.Op) Checker {
+
+	enforced := make(map[string]struct{}, len(enforcedMethods))
+	for _, method := range enforcedMethods {
+		enforced[method] = struct{}{}
+	}
+
+	return func() (string, checkers.Func) {
+		return CondBudget, func(ctx context.Context, cond,
+			arg string) error {
+
+			if _, err := ParseBudget(arg); err != nil {
+				return err
+			}
+
+			req, ok := ctx.Value(requestContextKey).(*requestInfo)
+			if !ok {
+				return fmt.Errorf("unable to get request info " +
+					"from context")
+			}
+
+			if _, ok := enforced[req.fullMethod]; ok {
+				return nil
+			}
+
+			if requiresAny(req.permissions, spendingOps) {
+				return fmt.Errorf("macaroon with spending "+
+					"budget not allowed to call %v",
+					req.fullMethod)
+			}
+
+			return nil
+		}
+	}
+}
+
+// requiresAny returns whether any of the given operations is part of the
+// required permissions.
+func requiresAny(permissions, ops []bakery.Op) bool {
+	for _, permission := range permissions {
+		for _, op := range ops {
+			if permission == op {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// Budgets returns the spending budgets carried by the caveats of the passed
+// macaroon.
+func Budgets(mac *macaroon.Macaroon) ([]*Budget, error) {
+	var budgets []*Budget
+	for _, caveat := range mac.Caveats() {
+		cond, arg, err := checkers.ParseCaveat(string(caveat.Id))
+		if err != nil || cond != CondBudget {
+			continue
+		}
+
+		budget, err := ParseBudget(arg)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+
+	return budgets, nil
+}
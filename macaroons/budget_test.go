@@ -0,0 +1,86 @@
+package macaroons_test
+
+import (
+	"context"
+	"encoding/hex"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/macaroons"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+// TestBudgetCaveat tests that macaroons carrying a spending budget are only
+// allowed to spend through the methods enforcing it, and that the budgets are
+// extracted from the request context.
+func TestBudgetCaveat(t *testing.T) {
+	tempDir := setupTestRootKeyStorage(t)
+	defer os.RemoveAll(tempDir)
+
+	spendOp := bakery.Op{Entity: "testEntity", Action: "write"}
+	service, err := macaroons.NewService(
+		tempDir, "dcrlnd", macaroons.NewBudgetChecker(
+			[]string{"PayMethod"}, []bakery.Op{spendOp},
+		),
+	)
+	require.NoError(t, err)
+	defer service.Close()
+	require.NoError(t, service.CreateUnlock(&defaultPw))
+
+	mac, err := service.NewMacaroon(
+		context.TODO(), macaroons.DefaultRootKeyID, testOperation,
+		spendOp,
+	)
+	require.NoError(t, err)
+
+	// Invalid budgets can't be added.
+	_, err = macaroons.AddConstraints(
+		mac.M(), macaroons.BudgetConstraint("month", 1000),
+	)
+	require.Error(t, err)
+
+	budgetMac, err := macaroons.AddConstraints(
+		mac.M(), macaroons.BudgetConstraint("day", 1000),
+		macaroons.BudgetConstraint("week", 5000),
+	)
+	require.NoError(t, err)
+	macBytes, err := budgetMac.MarshalBinary()
+	require.NoError(t, err)
+
+	md := metadata.New(map[string]string{
+		"macaroon": hex.EncodeToString(macBytes),
+	})
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+
+	// The macaroon can call methods that don't spend and the methods
+	// enforcing its budget, but not other spending methods.
+	err = service.ValidateMacaroon(
+		ctx, []bakery.Op{testOperation}, "ReadMethod",
+	)
+	require.NoError(t, err)
+	err = service.ValidateMacaroon(ctx, []bakery.Op{spendOp}, "PayMethod")
+	require.NoError(t, err)
+	err = service.ValidateMacaroon(
+		ctx, []bakery.Op{spendOp}, "OtherSpendMethod",
+	)
+	require.Error(t, err)
+
+	budgets, err := macaroons.Budgets(budgetMac)
+	require.NoError(t, err)
+	require.Equal(t, []*macaroons.Budget{{
+		Period:    time.Hour * 24,
+		MaxAmount: dcrutil.Amount(1000),
+	}, {
+		Period:    time.Hour * 24 * 7,
+		MaxAmount: dcrutil.Amount(5000),
+	}}, budgets)
+
+	// Macaroons without a budget caveat carry no budget.
+	budgets, err = macaroons.Budgets(mac.M())
+	require.NoError(t, err)
+	require.Empty(t, budgets)
+}
@@ -3,12 +3,18 @@ package macaroons
 import (
 	"context"
 	"fmt"
+
+	"gopkg.in/macaroon-bakery.v2/bakery"
 )
 
 var (
 	// RootKeyIDContextKey is the key to get rootKeyID from context.
 	RootKeyIDContextKey = contextKey{"rootkeyid"}
 
+	// requestContextKey is the key to get the details of the request
+	// being authorized from the context passed to caveat checkers.
+	requestContextKey = contextKey{"request"}
+
 	// ErrContextRootKeyID is used when the supplied context doesn't have
 	// a root key ID.
 	ErrContextRootKeyID = fmt.Errorf("failed to read root key ID " +
@@ -20,6 +26,26 @@ type contextKey struct {
 	Name string
 }
 
+// requestInfo holds the details of the request being authorized.
+type requestInfo struct {
+	// fullMethod is the URI of the called method.
+	fullMethod string
+
+	// permissions are the permissions required by the called method.
+	permissions []bakery.Op
+}
+
+// contextWithRequest passes the details of the request being authorized to
+// the context, so that caveat checkers can take them into account.
+func contextWithRequest(ctx context.Context, fullMethod string,
+	permissions []bakery.Op) context.Context {
+
+	return context.WithValue(ctx, requestContextKey, &requestInfo{
+		fullMethod:  fullMethod,
+		permissions: permissions,
+	})
+}
+
 // ContextWithRootKeyID passes the root key ID value to context.
 func ContextWithRootKeyID(ctx context.Context,
 	value interface{}) context.Context {
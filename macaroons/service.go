@@ -258,7 +258,9 @@ func (svc *Service) ValidateMacaroon(ctx context.Context,
 	}
 
 	// Check the method being called against the permitted operation and
-	// the expiration time and IP address and return the result.
+	// the expiration time and IP address and return the result. The
+	// details of the request are passed to the caveat checkers.
+	ctx = contextWithRequest(ctx, fullMethod, requiredPermissions)
 	authChecker := svc.Checker.Auth(macaroon.Slice{mac})
 	_, err = authChecker.Allow(ctx, requiredPermissions...)
 
@@ -0,0 +1,127 @@
+package dcrlnd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// maintenanceState tracks whether the node is in maintenance mode, during
+// which our channels are announced as disabled, autopilot is paused and the
+// sweeps of non-urgent inputs are held back.
+type maintenanceState struct {
+	mtx sync.Mutex
+
+	// active is set while the node is in maintenance mode.
+	active bool
+
+	// since is the time at which the node entered maintenance mode.
+	since time.Time
+
+	// autopilotPaused is set if autopilot was active when the node
+	// entered maintenance mode, so that it's resumed afterwards.
+	autopilotPaused bool
+}
+
+// isActive returns whether the node is in maintenance mode.
+func (m *maintenanceState) isActive() bool {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.active
+}
+
+// EnterMaintenance puts the node in maintenance mode ahead of a planned
+// restart. All our public channels are announced as disabled and kept
+// disabled, autopilot is paused and the sweeps of non-urgent inputs are held
+// back.
+func (r *rpcServer) EnterMaintenance(ctx context.Context,
+	_ *lnrpc.EnterMaintenanceRequest) (*lnrpc.EnterMaintenanceResponse,
+	error) {
+
+	m := &r.maintenance
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if m.active {
+		return &lnrpc.EnterMaintenanceResponse{
+			MaintenanceSince: m.since.Unix(),
+		}, nil
+	}
+
+	rpcsLog.Infof("[entermaintenance] Entering maintenance mode")
+
+	if err := r.server.sweeper.PauseNonUrgent(); err != nil {
+		return nil, fmt.Errorf("unable to pause sweeper: %v", err)
+	}
+
+	autopilotPaused := r.autopilot != nil && r.autopilot.IsActive()
+	if autopilotPaused {
+		if err := r.autopilot.StopAgent(); err != nil {
+			r.resumeAfterMaintenance(false)
+			return nil, fmt.Errorf("unable to pause autopilot: %v",
+				err)
+		}
+	}
+
+	if err := r.server.chanStatusMgr.EnterMaintenance(); err != nil {
+		if err := r.server.chanStatusMgr.ExitMaintenance(); err != nil {
+			rpcsLog.Errorf("Unable to re-enable channels: %v", err)
+		}
+		r.resumeAfterMaintenance(autopilotPaused)
+		return nil, fmt.Errorf("unable to disable channels: %v", err)
+	}
+
+	m.active = true
+	m.since = time.Now()
+	m.autopilotPaused = autopilotPaused
+
+	return &lnrpc.EnterMaintenanceResponse{
+		MaintenanceSince: m.since.Unix(),
+	}, nil
+}
+
+// ExitMaintenance ends the maintenance mode started by EnterMaintenance.
+func (r *rpcServer) ExitMaintenance(ctx context.Context,
+	_ *lnrpc.ExitMaintenanceRequest) (*lnrpc.ExitMaintenanceResponse,
+	error) {
+
+	m := &r.maintenance
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if !m.active {
+		return nil, fmt.Errorf("node isn't in maintenance mode")
+	}
+
+	rpcsLog.Infof("[exitmaintenance] Exiting maintenance mode entered "+
+		"at %v", m.since)
+
+	if err := r.server.chanStatusMgr.ExitMaintenance(); err != nil {
+		return nil, fmt.Errorf("unable to enable channels: %v", err)
+	}
+	r.resumeAfterMaintenance(m.autopilotPaused)
+
+	m.active = false
+	m.autopilotPaused = false
+
+	return &lnrpc.ExitMaintenanceResponse{}, nil
+}
+
+// resumeAfterMaintenance resumes the sweeps of non-urgent inputs and, if it
+// was paused, autopilot. Failures are only logged, as the maintenance is over
+// regardless.
+func (r *rpcServer) resumeAfterMaintenance(autopilotPaused bool) {
+	if err := r.server.sweeper.ResumeNonUrgent(); err != nil {
+		rpcsLog.Errorf("Unable to resume sweeper: %v", err)
+	}
+
+	if autopilotPaused {
+		if err := r.autopilot.StartAgent(); err != nil {
+			rpcsLog.Errorf("Unable to resume autopilot: %v", err)
+		}
+	}
+}
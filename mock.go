@@ -329,13 +329,23 @@ func (m *mockWalletController) ListUnspentWitness(minconfirms,
 	ret = append(ret, utxo)
 	return ret, nil
 }
+
+// ListAccountUnspent returns the same utxos as ListUnspentWitness.
+func (m *mockWalletController) ListAccountUnspent(_ string, minConfs,
+	maxConfs int32) ([]*lnwallet.Utxo, error) {
+
+	return m.ListUnspentWitness(minConfs, maxConfs)
+}
+
 func (*mockWalletController) ListTransactionDetails(_, _ int32) ([]*lnwallet.TransactionDetail, error) {
 	return nil, nil
 }
 func (*mockWalletController) LockOutpoint(o wire.OutPoint)   {}
 func (*mockWalletController) UnlockOutpoint(o wire.OutPoint) {}
 
-func (*mockWalletController) LeaseOutput(lnwallet.LockID, wire.OutPoint) (time.Time, error) {
+func (*mockWalletController) LeaseOutput(lnwallet.LockID, wire.OutPoint,
+	time.Duration) (time.Time, error) {
+
 	return time.Now(), nil
 }
 func (*mockWalletController) ReleaseOutput(lnwallet.LockID, wire.OutPoint) error {
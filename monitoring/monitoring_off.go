@@ -4,6 +4,7 @@ package monitoring
 
 import (
 	"fmt"
+	"time"
 
 	"google.golang.org/grpc"
 
@@ -23,3 +24,25 @@ func ExportPrometheusMetrics(_ *grpc.Server, _ lncfg.Prometheus) error {
 	return fmt.Errorf("lnd must be built with the monitoring tag to " +
 		"enable exporting Prometheus metrics")
 }
+
+// RecordFundingOutcome records a funding workflow that ended with the given
+// outcome. Monitoring is currently disabled, so this is a no-op.
+func RecordFundingOutcome(_ string) {}
+
+// ObservePathFinding records the time spent finding the route of a payment
+// attempt. Monitoring is currently disabled, so this is a no-op.
+func ObservePathFinding(_ time.Duration) {}
+
+// ObservePaymentAttempt records the time an HTLC attempt of a payment took to
+// resolve with the given outcome. Monitoring is currently disabled, so this is
+// a no-op.
+func ObservePaymentAttempt(_ string, _ time.Duration) {}
+
+// ObservePayment records the time a payment took to complete with the given
+// outcome. Monitoring is currently disabled, so this is a no-op.
+func ObservePayment(_ string, _ time.Duration) {}
+
+// ObserveHTLCResolution records the time an HTLC handled by the switch took to
+// resolve with the given outcome. Monitoring is currently disabled, so this is
+// a no-op.
+func ObserveHTLCResolution(_, _, _ string, _ time.Duration) {}
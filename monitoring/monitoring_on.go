@@ -5,16 +5,143 @@ package monitoring
 import (
 	"net/http"
 	"sync"
+	"time"
 
 	"google.golang.org/grpc"
 
 	"github.com/decred/dcrlnd/lncfg"
 	"github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var started sync.Once
 
+// fundingOutcomes counts the funding workflows that ended, partitioned by
+// their outcome.
+var fundingOutcomes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "funding",
+		Name:      "outcomes_total",
+		Help:      "Number of funding workflows that ended, by outcome.",
+	},
+	[]string{"outcome"},
+)
+
+// pathFindingTime tracks the time spent finding the route of each payment
+// attempt.
+var pathFindingTime = prometheus.NewHistogram(
+	prometheus.HistogramOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "routing",
+		Name:      "pathfinding_seconds",
+		Help:      "Time spent finding the route of a payment attempt.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16),
+	},
+)
+
+// paymentAttemptTime tracks the time between sending an HTLC attempt of a
+// payment and its resolution, partitioned by outcome.
+var paymentAttemptTime = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "routing",
+		Name:      "htlc_attempt_seconds",
+		Help:      "Time between sending a payment HTLC and its resolution.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	},
+	[]string{"outcome"},
+)
+
+// paymentTime tracks the time between the creation of a payment and its
+// completion, partitioned by outcome.
+var paymentTime = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "routing",
+		Name:      "payment_seconds",
+		Help:      "Time between the creation of a payment and its completion.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	},
+	[]string{"outcome"},
+)
+
+// htlcResolutionTime tracks the time between the switch adding an HTLC to its
+// outgoing link and the HTLC being settled or failed, partitioned by whether
+// the HTLC was ours or forwarded and by outcome.
+var htlcResolutionTime = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "htlcswitch",
+		Name:      "htlc_resolution_seconds",
+		Help:      "Time between adding an HTLC and its resolution.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	},
+	[]string{"type", "outcome"},
+)
+
+// htlcHopResolutionTime tracks the same durations as htlcResolutionTime,
+// partitioned by the outgoing channel of the HTLC, so that slow next hops can
+// be told apart.
+var htlcHopResolutionTime = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Namespace: "dcrlnd",
+		Subsystem: "htlcswitch",
+		Name:      "htlc_hop_resolution_seconds",
+		Help: "Time between adding an HTLC and its resolution, " +
+			"per outgoing channel.",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 16),
+	},
+	[]string{"type", "outcome", "outgoing_channel"},
+)
+
+func init() {
+	prometheus.MustRegister(fundingOutcomes)
+	prometheus.MustRegister(pathFindingTime)
+	prometheus.MustRegister(paymentAttemptTime)
+	prometheus.MustRegister(paymentTime)
+	prometheus.MustRegister(htlcResolutionTime)
+	prometheus.MustRegister(htlcHopResolutionTime)
+}
+
+// RecordFundingOutcome records a funding workflow that ended with the given
+// outcome.
+func RecordFundingOutcome(outcome string) {
+	fundingOutcomes.WithLabelValues(outcome).Inc()
+}
+
+// ObservePathFinding records the time spent finding the route of a payment
+// attempt.
+func ObservePathFinding(d time.Duration) {
+	pathFindingTime.Observe(d.Seconds())
+}
+
+// ObservePaymentAttempt records the time an HTLC attempt of a payment took to
+// resolve with the given outcome.
+func ObservePaymentAttempt(outcome string, d time.Duration) {
+	paymentAttemptTime.WithLabelValues(outcome).Observe(d.Seconds())
+}
+
+// ObservePayment records the time a payment took to complete with the given
+// outcome.
+func ObservePayment(outcome string, d time.Duration) {
+	paymentTime.WithLabelValues(outcome).Observe(d.Seconds())
+}
+
+// ObserveHTLCResolution records the time an HTLC handled by the switch took to
+// resolve with the given outcome, both in aggregate and for the outgoing
+// channel the HTLC was added to. The type tells whether the HTLC was sent by us
+// or forwarded.
+func ObserveHTLCResolution(htlcType, outcome, outgoingChan string,
+	d time.Duration) {
+
+	htlcResolutionTime.WithLabelValues(htlcType, outcome).Observe(d.Seconds())
+	htlcHopResolutionTime.WithLabelValues(
+		htlcType, outcome, outgoingChan,
+	).Observe(d.Seconds())
+}
+
 // GetPromInterceptors returns the set of interceptors for Prometheus
 // monitoring.
 func GetPromInterceptors() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
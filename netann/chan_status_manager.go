@@ -3,6 +3,7 @@ package netann
 import (
 	"errors"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/decred/dcrd/dcrec/secp256k1/v3"
@@ -87,6 +88,10 @@ type ChanStatusManager struct {
 	started sync.Once
 	stopped sync.Once
 
+	// maintenance is set while our channels are kept disabled for a
+	// planned maintenance. To be used atomically.
+	maintenance int32
+
 	cfg *ChanStatusConfig
 
 	// ourPubKeyBytes is the serialized compressed pubkey of our node.
@@ -225,6 +230,66 @@ func (m *ChanStatusManager) RequestDisable(outpoint wire.OutPoint) error {
 	return m.submitRequest(m.disableRequests, outpoint)
 }
 
+// EnterMaintenance announces all our public channels as disabled and keeps
+// them disabled until ExitMaintenance is called, so that payments are routed
+// around our node during a planned maintenance such as a restart.
+func (m *ChanStatusManager) EnterMaintenance() error {
+	atomic.StoreInt32(&m.maintenance, 1)
+
+	channels, err := m.fetchChannels()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		err := m.RequestDisable(c.FundingOutpoint)
+		switch {
+		// Channels that aren't announced yet can't be disabled.
+		case err == channeldb.ErrEdgeNotFound:
+			continue
+
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ExitMaintenance ends the maintenance started by EnterMaintenance, announcing
+// our public channels with an active link as enabled. The other channels are
+// enabled once their peer reconnects.
+func (m *ChanStatusManager) ExitMaintenance() error {
+	atomic.StoreInt32(&m.maintenance, 0)
+
+	channels, err := m.fetchChannels()
+	if err != nil {
+		return err
+	}
+
+	for _, c := range channels {
+		err := m.RequestEnable(c.FundingOutpoint)
+		switch {
+		case err == ErrEnableInactiveChan:
+			continue
+
+		case err == channeldb.ErrEdgeNotFound:
+			continue
+
+		case err != nil:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// InMaintenance returns whether our channels are kept disabled for a planned
+// maintenance.
+func (m *ChanStatusManager) InMaintenance() bool {
+	return atomic.LoadInt32(&m.maintenance) == 1
+}
+
 // statusRequest is passed to the statusManager to request a change in status
 // for a particular channel point.  The exact action is governed by passing the
 // request through one of the enableRequests or disableRequests channels.
@@ -320,6 +385,14 @@ func (m *ChanStatusManager) processEnableRequest(outpoint wire.OutPoint) error {
 		return ErrEnableInactiveChan
 	}
 
+	// Channels stay disabled during maintenance, even if their peer
+	// reconnects. They're enabled again once the maintenance is over.
+	if m.InMaintenance() {
+		log.Debugf("Not enabling channel(%v) during maintenance",
+			outpoint)
+		return nil
+	}
+
 	switch curState.Status {
 
 	// Channel is already enabled, nothing to do.
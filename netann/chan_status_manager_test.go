@@ -791,6 +791,38 @@ var stateMachineTests = []stateMachineTest{
 			h.assertNoUpdates(h.safeDisableTimeout)
 		},
 	},
+	{
+		name:         "maintenance keeps channels disabled",
+		startActive:  true,
+		startEnabled: true,
+		fn: func(h testHarness) {
+			// Entering maintenance should disable all channels on
+			// the network.
+			if err := h.mgr.EnterMaintenance(); err != nil {
+				h.t.Fatalf("unable to enter maintenance: %v",
+					err)
+			}
+			h.assertUpdates(
+				h.graph.chans(), false, h.safeDisableTimeout,
+			)
+
+			// Requests to enable the channels, such as the ones
+			// made when their peers reconnect, should be ignored.
+			h.assertEnables(h.graph.chans(), nil)
+			h.assertNoUpdates(h.safeDisableTimeout)
+
+			// Exiting maintenance should only enable the channels
+			// that are still active.
+			channels := h.graph.chans()
+			activeChans := channels[len(channels)/2:]
+			h.markInactive(channels[:len(channels)/2])
+			if err := h.mgr.ExitMaintenance(); err != nil {
+				h.t.Fatalf("unable to exit maintenance: %v",
+					err)
+			}
+			h.assertUpdates(activeChans, true, h.safeDisableTimeout)
+		},
+	},
 }
 
 // TestChanStatusManagerStateMachine tests the possible state transitions that
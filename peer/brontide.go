@@ -19,10 +19,12 @@ import (
 
 	"github.com/decred/dcrlnd/brontide"
 	"github.com/decred/dcrlnd/buffer"
+	"github.com/decred/dcrlnd/build"
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channelnotifier"
 	"github.com/decred/dcrlnd/contractcourt"
+	"github.com/decred/dcrlnd/crashreport"
 	"github.com/decred/dcrlnd/feature"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/lnpeer"
@@ -54,6 +56,9 @@ const (
 
 	// ErrorBufferSize is the number of historic peer errors that we store.
 	ErrorBufferSize = 10
+
+	// PingHistorySize is the number of historic ping times that we store.
+	PingHistorySize = 10
 )
 
 var (
@@ -132,6 +137,12 @@ type Brontide struct {
 	// our last ping message. To be used atomically.
 	pingLastSend int64
 
+	// pingHistory holds the most recent ping times to the peer, up to
+	// PingHistorySize, ordered from oldest to newest. It is protected by
+	// pingHistoryMtx.
+	pingHistory    []int64
+	pingHistoryMtx sync.Mutex
+
 	cfg Config
 
 	// activeSignal when closed signals that the peer is now active and
@@ -209,6 +220,10 @@ type Brontide struct {
 	// peer's chansync message with its own over and over again.
 	resentChanSyncMsg map[lnwire.ChannelID]struct{}
 
+	// log is the logger of the peer, tagged with its identity key so its
+	// level can be overridden for this peer only.
+	log *build.PrefixLog
+
 	queueQuit chan struct{}
 	quit      chan struct{}
 	wg        sync.WaitGroup
@@ -236,6 +251,10 @@ func NewBrontide(cfg Config) *Brontide {
 		resentChanSyncMsg:  make(map[lnwire.ChannelID]struct{}),
 		queueQuit:          make(chan struct{}),
 		quit:               make(chan struct{}),
+		log: build.NewTaggedPrefixLog(
+			"", peerLog, Subsystem,
+			build.PeerLogTag(cfg.PubKeyBytes[:]),
+		),
 	}
 
 	return p
@@ -248,7 +267,7 @@ func (p *Brontide) Start() error {
 		return nil
 	}
 
-	peerLog.Tracef("Peer %v starting", p)
+	p.log.Tracef("Peer %v starting", p)
 
 	// Exchange local and global features, the init message should be very
 	// first between two nodes.
@@ -304,7 +323,7 @@ func (p *Brontide) Start() error {
 	// peer from the database.
 	activeChans, err := p.cfg.ChannelDB.FetchOpenChannels(p.cfg.Addr.IdentityKey)
 	if err != nil {
-		peerLog.Errorf("unable to fetch active chans "+
+		p.log.Errorf("unable to fetch active chans "+
 			"for peer %v: %v", p, err)
 		return err
 	}
@@ -316,7 +335,7 @@ func (p *Brontide) Start() error {
 	// Next, load all the active channels we have with this peer,
 	// registering them with the switch and launching the necessary
 	// goroutines required to operate them.
-	peerLog.Debugf("Loaded %v active channels from database with "+
+	p.log.Debugf("Loaded %v active channels from database with "+
 		"NodeKey(%x)", len(activeChans), p.PubKey())
 
 	msgs, err := p.loadActiveChannels(activeChans)
@@ -339,10 +358,10 @@ func (p *Brontide) Start() error {
 	// Now that the peer has started up, we send any channel sync messages
 	// that must be resent for borked channels.
 	if len(msgs) > 0 {
-		peerLog.Infof("Sending %d channel sync messages to peer after "+
+		p.log.Infof("Sending %d channel sync messages to peer after "+
 			"loading active channels", len(msgs))
 		if err := p.SendMessage(true, msgs...); err != nil {
-			peerLog.Warnf("Failed sending channel sync "+
+			p.log.Warnf("Failed sending channel sync "+
 				"messages to peer %v: %v", p, err)
 		}
 	}
@@ -370,7 +389,7 @@ func (p *Brontide) initGossipSync() {
 	// If the remote peer knows of the new gossip queries feature, then
 	// we'll create a new gossipSyncer in the AuthenticatedGossiper for it.
 	if p.remoteFeatures.HasFeature(lnwire.GossipQueriesOptional) {
-		peerLog.Infof("Negotiated chan series queries with %x",
+		p.log.Infof("Negotiated chan series queries with %x",
 			p.cfg.PubKeyBytes[:])
 
 		// Register the peer's gossip syncer with the gossiper.
@@ -420,7 +439,7 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 
 		chanID := lnwire.NewChanIDFromOutPoint(chanPoint)
 
-		peerLog.Infof("NodeKey(%x) loading ChannelPoint(%v)",
+		p.log.Infof("NodeKey(%x) loading ChannelPoint(%v)",
 			p.PubKey(), chanPoint)
 
 		// Skip adding any permanently irreconcilable channels to the
@@ -428,7 +447,7 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 		if !dbChan.HasChanStatus(channeldb.ChanStatusDefault) &&
 			!dbChan.HasChanStatus(channeldb.ChanStatusRestored) {
 
-			peerLog.Warnf("ChannelPoint(%v) has status %v, won't "+
+			p.log.Warnf("ChannelPoint(%v) has status %v, won't "+
 				"start.", chanPoint, dbChan.ChanStatus())
 
 			// To help our peer recover from a potential data loss,
@@ -439,13 +458,28 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 			// marking the channel borked.
 			chanSync, err := dbChan.ChanSyncMsg()
 			if err != nil {
-				peerLog.Errorf("Unable to create channel "+
+				p.log.Errorf("Unable to create channel "+
 					"reestablish message for channel %v: "+
 					"%v", chanPoint, err)
 				continue
 			}
 
 			msgs = append(msgs, chanSync)
+
+			// If the channel was in the middle of a cooperative
+			// close negotiation, we'll resume it by resending our
+			// shutdown message.
+			shutdownMsg, err := p.resumeCoopClose(lnChan)
+			if err != nil {
+				p.log.Errorf("Unable to resume cooperative "+
+					"close of channel %v: %v", chanPoint,
+					err)
+				continue
+			}
+			if shutdownMsg != nil {
+				msgs = append(msgs, shutdownMsg)
+			}
+
 			continue
 		}
 
@@ -486,14 +520,25 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 				FeeRate:       selfPolicy.FeeProportionalMillionths,
 				TimeLockDelta: uint32(selfPolicy.TimeLockDelta),
 			}
+
+			inboundFee, err := lnwire.ExtractInboundFee(
+				selfPolicy.ExtraOpaqueData,
+			)
+			if err != nil {
+				p.log.Warnf("Unable to parse inbound fee of "+
+					"channel %v: %v", chanPoint, err)
+			}
+			if inboundFee != nil {
+				forwardingPolicy.InboundFee = *inboundFee
+			}
 		} else {
-			peerLog.Warnf("Unable to find our forwarding policy "+
+			p.log.Warnf("Unable to find our forwarding policy "+
 				"for channel %v, using default values",
 				chanPoint)
 			forwardingPolicy = &p.cfg.RoutingPolicy
 		}
 
-		peerLog.Tracef("Using link policy of: %v",
+		p.log.Tracef("Using link policy of: %v",
 			spew.Sdump(forwardingPolicy))
 
 		// If the channel is pending, set the value to nil in the
@@ -530,11 +575,104 @@ func (p *Brontide) loadActiveChannels(chans []*channeldb.OpenChannel) (
 		p.activeChanMtx.Lock()
 		p.activeChannels[chanID] = lnChan
 		p.activeChanMtx.Unlock()
+
+		// The channel is operating normally again, so any reason
+		// recorded for its closure, such as an error sent by the peer
+		// during an earlier connection, no longer applies.
+		p.cfg.ChannelNotifier.ClearCloseReason(*chanPoint)
 	}
 
 	return msgs, nil
 }
 
+// resumeCoopClose restarts the cooperative close negotiation of the given
+// channel if it was interrupted after our shutdown message was sent but before
+// a closing transaction was agreed on. The shutdown message to resend is
+// returned, or nil if there's no negotiation to resume.
+//
+// NOTE: This MUST be called before the channelManager goroutine is started.
+func (p *Brontide) resumeCoopClose(
+	lnChan *lnwallet.LightningChannel) (*lnwire.Shutdown, error) {
+
+	dbChan := lnChan.State()
+
+	// Only channels that are solely marked as being cooperatively closed
+	// can be resumed.
+	status := dbChan.ChanStatus() &^ (channeldb.ChanStatusCoopBroadcasted |
+		channeldb.ChanStatusLocalCloseInitiator |
+		channeldb.ChanStatusRemoteCloseInitiator)
+	if !dbChan.HasChanStatus(channeldb.ChanStatusCoopBroadcasted) ||
+		status != channeldb.ChanStatusDefault {
+
+		return nil, nil
+	}
+
+	// If a closing transaction was already broadcast, the negotiation is
+	// over and the chain arbitrator will take it from here.
+	_, err := dbChan.BroadcastedCooperative()
+	switch {
+	case err == channeldb.ErrNoCloseTx:
+
+	case err != nil:
+		return nil, err
+
+	default:
+		return nil, nil
+	}
+
+	info, err := dbChan.ShutdownInfo()
+	switch {
+	// Channels marked before the shutdown info was stored can't be
+	// resumed, as we don't know which delivery script we sent.
+	case err == channeldb.ErrNoShutdownInfo:
+		return nil, nil
+
+	case err != nil:
+		return nil, err
+	}
+
+	_, startingHeight, err := p.cfg.ChainIO.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	chanCloser := chancloser.NewChanCloser(
+		chancloser.ChanCloseCfg{
+			Channel:           lnChan,
+			UnregisterChannel: p.cfg.Switch.RemoveLink,
+			BroadcastTx:       p.cfg.Wallet.PublishTransaction,
+			DisableChannel:    p.cfg.ChanStatusMgr.RequestDisable,
+			Disconnect: func() error {
+				return p.cfg.DisconnectPeer(p.IdentityKey())
+			},
+			MaxFee: info.MaxFeePerKB,
+			Quit:   p.quit,
+		},
+		info.DeliveryScript,
+		info.FeePerKB,
+		uint32(startingHeight),
+		nil,
+		info.LocalInitiator,
+	)
+
+	shutdownMsg, err := chanCloser.ShutdownChan()
+	if err != nil {
+		return nil, err
+	}
+
+	p.log.Infof("Resuming cooperative close of ChannelPoint(%v)",
+		dbChan.FundingOutpoint)
+
+	chanID := lnwire.NewChanIDFromOutPoint(&dbChan.FundingOutpoint)
+	p.activeChanMtx.Lock()
+	p.activeChannels[chanID] = lnChan
+	p.activeChanMtx.Unlock()
+
+	p.activeChanCloses[chanID] = chanCloser
+
+	return shutdownMsg, nil
+}
+
 // addLink creates and adds a new ChannelLink from the specified channel.
 func (p *Brontide) addLink(chanPoint *wire.OutPoint,
 	lnChan *lnwallet.LightningChannel,
@@ -574,6 +712,7 @@ func (p *Brontide) addLink(chanPoint *wire.OutPoint,
 		HodlMask:                p.cfg.Hodl.Mask(),
 		Registry:                p.cfg.Invoices,
 		Switch:                  p.cfg.Switch,
+		SettleLocalPayment:      p.cfg.Switch.SettleLocalPayment,
 		Circuits:                p.cfg.Switch.CircuitModifier(),
 		ForwardPackets:          p.cfg.InterceptSwitch.ForwardPackets,
 		FwrdingPolicy:           *forwardingPolicy,
@@ -590,7 +729,7 @@ func (p *Brontide) addLink(chanPoint *wire.OutPoint,
 		UnsafeReplay:            p.cfg.UnsafeReplay,
 		MinFeeUpdateTimeout:     htlcswitch.DefaultMinLinkFeeUpdateTimeout,
 		MaxFeeUpdateTimeout:     htlcswitch.DefaultMaxLinkFeeUpdateTimeout,
-		OutgoingCltvRejectDelta: p.cfg.OutgoingCltvRejectDelta,
+		OutgoingCltvRejectDelta: p.cfg.OutgoingCltvRejectDelta(),
 		TowerClient:             p.cfg.TowerClient,
 		MaxOutgoingCltvExpiry:   p.cfg.MaxOutgoingCltvExpiry,
 		MaxFeeAllocation:        p.cfg.MaxChannelFeeAllocation,
@@ -635,12 +774,12 @@ func (p *Brontide) maybeSendNodeAnn(channels []*channeldb.OpenChannel) {
 
 	ourNodeAnn, err := p.cfg.GenNodeAnnouncement(false)
 	if err != nil {
-		peerLog.Debugf("Unable to retrieve node announcement: %v", err)
+		p.log.Debugf("Unable to retrieve node announcement: %v", err)
 		return
 	}
 
 	if err := p.SendMessageLazy(false, &ourNodeAnn); err != nil {
-		peerLog.Debugf("Unable to resend node announcement to %x: %v",
+		p.log.Debugf("Unable to resend node announcement to %x: %v",
 			p.cfg.PubKeyBytes, err)
 	}
 }
@@ -673,7 +812,7 @@ func (p *Brontide) Disconnect(reason error) {
 	err := fmt.Errorf("disconnecting %s, reason: %v", p, reason)
 	p.storeError(err)
 
-	peerLog.Infof(err.Error())
+	p.log.Infof(err.Error())
 
 	// Ensure that the TCP connection is properly closed before continuing.
 	p.cfg.Conn.Close()
@@ -1033,6 +1172,7 @@ func newDiscMsgStream(p *Brontide) *msgStream {
 // NOTE: This method MUST be run as a goroutine.
 func (p *Brontide) readHandler() {
 	defer p.wg.Done()
+	defer crashreport.Recover(Subsystem)
 
 	// We'll stop the timer after a new messages is received, and also
 	// reset it after we process the next message.
@@ -1063,7 +1203,7 @@ out:
 			}
 		}
 		if err != nil {
-			peerLog.Infof("unable to read message from %v: %v",
+			p.log.Infof("unable to read message from %v: %v",
 				p, err)
 
 			// If we could not read our peer's message due to an
@@ -1121,6 +1261,13 @@ out:
 			delay := (time.Now().UnixNano() - pingSendTime) / 1000
 			atomic.StoreInt64(&p.pingTime, delay)
 
+			p.pingHistoryMtx.Lock()
+			p.pingHistory = append(p.pingHistory, delay)
+			if len(p.pingHistory) > PingHistorySize {
+				p.pingHistory = p.pingHistory[1:]
+			}
+			p.pingHistoryMtx.Unlock()
+
 		case *lnwire.Ping:
 			pongBytes := make([]byte, msg.NumPongBytes)
 			p.queueMsg(lnwire.NewPong(pongBytes), nil)
@@ -1167,7 +1314,7 @@ out:
 				err := p.resendChanSyncMsg(targetChan)
 				if err != nil {
 					// TODO(halseth): send error to peer?
-					peerLog.Errorf("resend failed: %v",
+					p.log.Errorf("resend failed: %v",
 						err)
 				}
 			}
@@ -1195,7 +1342,7 @@ out:
 				uint16(msg.MsgType()))
 			p.storeError(err)
 
-			peerLog.Errorf("peer: %v, %v", p, err)
+			p.log.Errorf("peer: %v, %v", p, err)
 		}
 
 		if isLinkUpdate {
@@ -1222,7 +1369,7 @@ out:
 
 	p.Disconnect(errors.New("read handler closed"))
 
-	peerLog.Tracef("readHandler for peer %v done", p)
+	p.log.Tracef("readHandler for peer %v done", p)
 }
 
 // isActiveChannel returns true if the provided channel id is active, otherwise
@@ -1256,7 +1403,7 @@ func (p *Brontide) storeError(err error) {
 	// If we do not have any active channels with the peer, we do not store
 	// errors as a dos mitigation.
 	if !haveChannels {
-		peerLog.Tracef("no channels with peer: %v, not storing err", p)
+		p.log.Tracef("no channels with peer: %v, not storing err", p)
 		return
 	}
 
@@ -1293,8 +1440,22 @@ func (p *Brontide) handleError(msg *lnwire.Error) bool {
 		p.cfg.ProcessFundingError(msg, key)
 		return false
 
-	// If not we hand the error to the channel link for this channel.
+	// If not we hand the error to the channel link for this channel. As
+	// the link will fail the channel, we'll record the error as the reason
+	// for its upcoming closure.
 	case p.isActiveChannel(msg.ChanID):
+		p.activeChanMtx.RLock()
+		channel := p.activeChannels[msg.ChanID]
+		p.activeChanMtx.RUnlock()
+
+		if channel != nil {
+			p.notifyCloseReason(
+				*channel.ChannelPoint(),
+				channeldb.CloseReasonPeerError, true,
+				string(msg.Data),
+			)
+		}
+
 		return true
 
 	default:
@@ -1445,7 +1606,7 @@ func (p *Brontide) logWireMessage(msg lnwire.Message, read bool) {
 		summaryPrefix = "Sending"
 	}
 
-	peerLog.Debugf("%v", newLogClosure(func() string {
+	p.log.Debugf("%v", newLogClosure(func() string {
 		// Debug summary of message.
 		summary := messageSummary(msg)
 		if len(summary) > 0 {
@@ -1466,7 +1627,7 @@ func (p *Brontide) logWireMessage(msg lnwire.Message, read bool) {
 		prefix = "writeMessage to"
 	}
 
-	peerLog.Tracef(prefix+" %v: %v", p, newLogClosure(func() string {
+	p.log.Tracef(prefix+" %v: %v", p, newLogClosure(func() string {
 		return spew.Sdump(msg)
 	}))
 }
@@ -1587,7 +1748,7 @@ out:
 			// slow to process messages from the wire.
 			err := p.writeMessage(outMsg.msg)
 			if nerr, ok := err.(net.Error); ok && nerr.Timeout() {
-				peerLog.Debugf("Write timeout detected for "+
+				p.log.Debugf("Write timeout detected for "+
 					"peer %s, first write for message "+
 					"attempted %v ago", p,
 					time.Since(startTime))
@@ -1636,7 +1797,7 @@ out:
 
 	p.Disconnect(exitErr)
 
-	peerLog.Tracef("writeHandler for peer %v done", p)
+	p.log.Tracef("writeHandler for peer %v done", p)
 }
 
 // queueHandler is responsible for accepting messages from outside subsystems
@@ -1736,6 +1897,18 @@ func (p *Brontide) PingTime() int64 {
 	return atomic.LoadInt64(&p.pingTime)
 }
 
+// PingTimeHistory returns the most recent ping times to the peer in
+// microseconds, ordered from oldest to newest.
+func (p *Brontide) PingTimeHistory() []int64 {
+	p.pingHistoryMtx.Lock()
+	defer p.pingHistoryMtx.Unlock()
+
+	history := make([]int64, len(p.pingHistory))
+	copy(history, p.pingHistory)
+
+	return history
+}
+
 // queueMsg adds the lnwire.Message to the back of the high priority send queue.
 // If the errChan is non-nil, an error is sent back if the msg failed to queue
 // or failed to write, and nil otherwise.
@@ -1759,7 +1932,7 @@ func (p *Brontide) queue(priority bool, msg lnwire.Message,
 	select {
 	case p.outgoingQueue <- outgoingMsg{priority, msg, errChan}:
 	case <-p.quit:
-		peerLog.Tracef("Peer shutting down, could not enqueue msg: %v.",
+		p.log.Tracef("Peer shutting down, could not enqueue msg: %v.",
 			spew.Sdump(msg))
 		if errChan != nil {
 			errChan <- lnpeer.ErrPeerExiting
@@ -1802,7 +1975,7 @@ func (p *Brontide) genDeliveryScript() ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	peerLog.Infof("Delivery addr for channel close: %v",
+	p.log.Infof("Delivery addr for channel close: %v",
 		deliveryAddr)
 
 	return txscript.PayToAddrScript(deliveryAddr)
@@ -1815,6 +1988,7 @@ func (p *Brontide) genDeliveryScript() ([]byte, error) {
 // NOTE: This method MUST be run as a goroutine.
 func (p *Brontide) channelManager() {
 	defer p.wg.Done()
+	defer crashreport.Recover(Subsystem)
 
 	// reenableTimeout will fire once after the configured channel status
 	// interval  has elapsed. This will trigger us to sign new channel
@@ -1838,7 +2012,7 @@ out:
 			p.activeChanMtx.Lock()
 			currentChan, ok := p.activeChannels[chanID]
 			if ok && currentChan != nil {
-				peerLog.Infof("Already have ChannelPoint(%v), "+
+				p.log.Infof("Already have ChannelPoint(%v), "+
 					"ignoring.", chanPoint)
 
 				p.activeChanMtx.Unlock()
@@ -1852,14 +2026,14 @@ out:
 					continue
 				}
 
-				peerLog.Infof("Processing retransmitted "+
+				p.log.Infof("Processing retransmitted "+
 					"FundingLocked for ChannelPoint(%v)",
 					chanPoint)
 
 				nextRevoke := newChan.RemoteNextRevocation
 				err := currentChan.InitNextRevocation(nextRevoke)
 				if err != nil {
-					peerLog.Errorf("unable to init chan "+
+					p.log.Errorf("unable to init chan "+
 						"revocation: %v", err)
 					continue
 				}
@@ -1878,7 +2052,7 @@ out:
 				p.activeChanMtx.Unlock()
 				err := fmt.Errorf("unable to create "+
 					"LightningChannel: %v", err)
-				peerLog.Errorf(err.Error())
+				p.log.Errorf(err.Error())
 
 				newChanReq.err <- err
 				continue
@@ -1890,7 +2064,7 @@ out:
 			p.addedChannels[chanID] = struct{}{}
 			p.activeChanMtx.Unlock()
 
-			peerLog.Infof("New channel active ChannelPoint(%v) "+
+			p.log.Infof("New channel active ChannelPoint(%v) "+
 				"with NodeKey(%x)", chanPoint, p.PubKey())
 
 			// Next, we'll assemble a ChannelLink along with the
@@ -1903,7 +2077,7 @@ out:
 			if err != nil {
 				err := fmt.Errorf("unable to subscribe to "+
 					"chain events: %v", err)
-				peerLog.Errorf(err.Error())
+				p.log.Errorf(err.Error())
 
 				newChanReq.err <- err
 				continue
@@ -1941,7 +2115,7 @@ out:
 				err := fmt.Errorf("can't register new channel "+
 					"link(%v) with NodeKey(%x)", chanPoint,
 					p.PubKey())
-				peerLog.Errorf(err.Error())
+				p.log.Errorf(err.Error())
 
 				newChanReq.err <- err
 				continue
@@ -2026,6 +2200,12 @@ func (p *Brontide) reenableActiveChannels() {
 			continue
 		}
 
+		// Channels that are being cooperatively closed must remain
+		// disabled.
+		if dbChan.HasChanStatus(channeldb.ChanStatusCoopBroadcasted) {
+			continue
+		}
+
 		// We'll also skip any channels added during this peer's
 		// lifecycle since they haven't waited out the timeout. Their
 		// first announcement will be enabled, and the chan status
@@ -2047,7 +2227,7 @@ func (p *Brontide) reenableActiveChannels() {
 	for _, chanPoint := range activePublicChans {
 		err := p.cfg.ChanStatusMgr.RequestEnable(chanPoint)
 		if err != nil {
-			peerLog.Errorf("Unable to enable channel %v: %v",
+			p.log.Errorf("Unable to enable channel %v: %v",
 				chanPoint, err)
 		}
 	}
@@ -2094,7 +2274,7 @@ func (p *Brontide) fetchActiveChanCloser(chanID lnwire.ChannelID) (
 			var err error
 			deliveryScript, err = p.genDeliveryScript()
 			if err != nil {
-				peerLog.Errorf("unable to gen delivery script: %v", err)
+				p.log.Errorf("unable to gen delivery script: %v", err)
 				return nil, fmt.Errorf("close addr unavailable")
 			}
 		}
@@ -2104,14 +2284,14 @@ func (p *Brontide) fetchActiveChanCloser(chanID lnwire.ChannelID) (
 		// we weren't the ones that initiated the channel closure.
 		feePerKB, err := p.cfg.FeeEstimator.EstimateFeePerKB(6)
 		if err != nil {
-			peerLog.Errorf("unable to query fee estimator: %v", err)
+			p.log.Errorf("unable to query fee estimator: %v", err)
 
 			return nil, fmt.Errorf("unable to estimate fee")
 		}
 
 		_, startingHeight, err := p.cfg.ChainIO.GetBestBlock()
 		if err != nil {
-			peerLog.Errorf("unable to obtain best block: %v", err)
+			p.log.Errorf("unable to obtain best block: %v", err)
 			return nil, fmt.Errorf("cannot obtain best block")
 		}
 
@@ -2183,7 +2363,7 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 	if !ok || channel == nil {
 		err := fmt.Errorf("unable to close channel, ChannelID(%v) is "+
 			"unknown", chanID)
-		peerLog.Errorf(err.Error())
+		p.log.Errorf(err.Error())
 		req.Err <- err
 		return
 	}
@@ -2194,6 +2374,18 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 	// out this channel on-chain, so we execute the cooperative channel
 	// closure workflow.
 	case htlcswitch.CloseRegular:
+		// If a negotiation was resumed on its own after a restart or
+		// reconnection, the request will follow its progress instead of
+		// starting a new one.
+		if chanCloser, ok := p.activeChanCloses[chanID]; ok {
+			if err := chanCloser.SetCloseRequest(req); err != nil {
+				p.log.Errorf("cannot close channel %v: %v",
+					req.ChanPoint, err)
+				req.Err <- err
+			}
+			return
+		}
+
 		// First, we'll choose a delivery address that we'll use to send the
 		// funds to in the case of a successful negotiation.
 
@@ -2206,7 +2398,7 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 			channel.LocalUpfrontShutdownScript(), req.DeliveryScript,
 		)
 		if err != nil {
-			peerLog.Errorf("cannot close channel %v: %v", req.ChanPoint, err)
+			p.log.Errorf("cannot close channel %v: %v", req.ChanPoint, err)
 			req.Err <- err
 			return
 		}
@@ -2216,7 +2408,7 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 		if len(deliveryScript) == 0 {
 			deliveryScript, err = p.genDeliveryScript()
 			if err != nil {
-				peerLog.Errorf(err.Error())
+				p.log.Errorf(err.Error())
 				req.Err <- err
 				return
 			}
@@ -2226,7 +2418,7 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 		// handle the close negotiation.
 		_, startingHeight, err := p.cfg.ChainIO.GetBestBlock()
 		if err != nil {
-			peerLog.Errorf(err.Error())
+			p.log.Errorf(err.Error())
 			req.Err <- err
 			return
 		}
@@ -2240,7 +2432,8 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 				Disconnect: func() error {
 					return p.cfg.DisconnectPeer(p.IdentityKey())
 				},
-				Quit: p.quit,
+				MaxFee: req.MaxFeePerKB,
+				Quit:   p.quit,
 			},
 			deliveryScript,
 			req.TargetFeePerKB,
@@ -2255,7 +2448,7 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 		// party to kick things off.
 		shutdownMsg, err := chanCloser.ShutdownChan()
 		if err != nil {
-			peerLog.Errorf(err.Error())
+			p.log.Errorf(err.Error())
 			req.Err <- err
 			delete(p.activeChanCloses, chanID)
 
@@ -2267,11 +2460,15 @@ func (p *Brontide) handleLocalCloseReq(req *htlcswitch.ChanClose) {
 
 		p.queueMsg(shutdownMsg, nil)
 
+		p.notifyCloseReason(
+			*req.ChanPoint, channeldb.CloseReasonShutdown, false, "",
+		)
+
 	// A type of CloseBreach indicates that the counterparty has breached
 	// the channel therefore we need to clean up our local state.
 	case htlcswitch.CloseBreach:
 		// TODO(roasbeef): no longer need with newer beach logic?
-		peerLog.Infof("ChannelPoint(%v) has been breached, wiping "+
+		p.log.Infof("ChannelPoint(%v) has been breached, wiping "+
 			"channel", req.ChanPoint)
 		p.WipeChannel(req.ChanPoint)
 	}
@@ -2305,17 +2502,22 @@ func (p *Brontide) handleLinkFailure(failure linkFailureReport) {
 	// If the error encountered was severe enough, we'll now force close the
 	// channel to prevent readding it to the switch in the future.
 	if failure.linkErr.ForceClose {
-		peerLog.Warnf("Force closing link(%v)",
+		p.log.Warnf("Force closing link(%v)",
 			failure.shortChanID)
 
+		p.notifyCloseReason(
+			failure.chanPoint, channeldb.CloseReasonLinkFailure,
+			false, failure.linkErr.Error(),
+		)
+
 		closeTx, err := p.cfg.ChainArb.ForceCloseContract(
 			failure.chanPoint,
 		)
 		if err != nil {
-			peerLog.Errorf("unable to force close "+
+			p.log.Errorf("unable to force close "+
 				"link(%v): %v", failure.shortChanID, err)
 		} else {
-			peerLog.Infof("channel(%v) force "+
+			p.log.Infof("channel(%v) force "+
 				"closed with txid %v",
 				failure.shortChanID, closeTx.TxHash())
 		}
@@ -2336,12 +2538,30 @@ func (p *Brontide) handleLinkFailure(failure linkFailureReport) {
 			Data:   data,
 		})
 		if err != nil {
-			peerLog.Errorf("unable to send msg to "+
+			p.log.Errorf("unable to send msg to "+
 				"remote peer: %v", err)
 		}
 	}
 }
 
+// notifyCloseReason records the reason for which the given channel is being
+// closed and notifies the subscribers of channel events of it.
+func (p *Brontide) notifyCloseReason(chanPoint wire.OutPoint,
+	code channeldb.CloseReasonCode, remoteInitiated bool, message string) {
+
+	p.log.Debugf("Channel %v closing with reason %v, remote=%v",
+		chanPoint, code, remoteInitiated)
+
+	p.cfg.ChannelNotifier.NotifyCloseReasonEvent(
+		chanPoint, &channeldb.ChannelCloseReason{
+			Code:            code,
+			RemoteInitiated: remoteInitiated,
+			Message:         message,
+			Timestamp:       time.Now(),
+		},
+	)
+}
+
 // finalizeChanClosure performs the final clean up steps once the cooperative
 // closure transaction has been fully broadcast. The finalized closing state
 // machine should be passed in. Once the transaction has been sufficiently
@@ -2370,7 +2590,7 @@ func (p *Brontide) finalizeChanClosure(chanCloser *chancloser.ChanCloser) {
 	closingTx, err := chanCloser.ClosingTx()
 	if err != nil {
 		if closeReq != nil {
-			peerLog.Error(err)
+			p.log.Error(err)
 			closeReq.Err <- err
 		}
 	}
@@ -2550,7 +2770,7 @@ func (p *Brontide) resendChanSyncMsg(cid lnwire.ChannelID) error {
 			"peer=%x", p.IdentityKey().SerializeCompressed())
 	}
 
-	peerLog.Debugf("Re-sending channel sync message for channel %v to "+
+	p.log.Debugf("Re-sending channel sync message for channel %v to "+
 		"peer %v", cid, p)
 
 	if err := p.SendMessage(true, c.LastChanSyncMsg); err != nil {
@@ -2558,7 +2778,7 @@ func (p *Brontide) resendChanSyncMsg(cid lnwire.ChannelID) error {
 			"message to peer %v: %v", p, err)
 	}
 
-	peerLog.Debugf("Re-sent channel sync message for channel %v to peer "+
+	p.log.Debugf("Re-sent channel sync message for channel %v to peer "+
 		"%v", cid, p)
 
 	// Note down that we sent the message, so we won't resend it again for
@@ -2694,6 +2914,10 @@ func (p *Brontide) StartTime() time.Time {
 // message is received from the remote peer. We'll use this message to advance
 // the chan closer state machine.
 func (p *Brontide) handleCloseMsg(msg *closeMsg) {
+	// If we don't have a closing state machine for this channel yet, then
+	// the remote party is initiating the closure.
+	_, isClosing := p.activeChanCloses[msg.cid]
+
 	// We'll now fetch the matching closing state machine in order to continue,
 	// or finalize the channel closure process.
 	chanCloser, err := p.fetchActiveChanCloser(msg.cid)
@@ -2703,7 +2927,7 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 			return
 		}
 
-		peerLog.Errorf("Unable to respond to remote close msg: %v", err)
+		p.log.Errorf("Unable to respond to remote close msg: %v", err)
 
 		errMsg := &lnwire.Error{
 			ChanID: msg.cid,
@@ -2720,7 +2944,7 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 	)
 	if err != nil {
 		err := fmt.Errorf("unable to process close msg: %v", err)
-		peerLog.Error(err)
+		p.log.Error(err)
 
 		// As the negotiations failed, we'll reset the channel state machine to
 		// ensure we act to on-chain events as normal.
@@ -2733,6 +2957,16 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 		return
 	}
 
+	// The reason is recorded once the shutdown was processed, so that the
+	// delivery script of the negotiation is already stored along with the
+	// channel when subscribers learn about it.
+	if _, ok := msg.msg.(*lnwire.Shutdown); ok && !isClosing {
+		p.notifyCloseReason(
+			*chanCloser.Channel().ChannelPoint(),
+			channeldb.CloseReasonShutdown, true, "",
+		)
+	}
+
 	// Queue any messages to the remote peer that need to be sent as a part of
 	// this latest round of negotiations.
 	for _, msg := range msgs {
@@ -2757,10 +2991,10 @@ func (p *Brontide) handleCloseMsg(msg *closeMsg) {
 func (p *Brontide) HandleLocalCloseChanReqs(req *htlcswitch.ChanClose) {
 	select {
 	case p.localCloseChanReqs <- req:
-		peerLog.Infof("Local close channel request delivered to "+
+		p.log.Infof("Local close channel request delivered to "+
 			"peer: %x", p.PubKey())
 	case <-p.quit:
-		peerLog.Infof("Unable to deliver local close channel request "+
+		p.log.Infof("Unable to deliver local close channel request "+
 			"to peer %x", p.PubKey())
 	}
 }
@@ -11,6 +11,7 @@ import (
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/lnwallet/chancloser"
 	"github.com/decred/dcrlnd/lnwire"
@@ -46,6 +47,12 @@ func TestPeerChannelClosureAcceptFeeResponder(t *testing.T) {
 	}
 	defer cleanUp()
 
+	chanEvents, err := alicePeer.cfg.ChannelNotifier.SubscribeChannelEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe to channel events: %v", err)
+	}
+	defer chanEvents.Cancel()
+
 	chanID := lnwire.NewChanIDFromOutPoint(bobChan.ChannelPoint())
 
 	// We send a shutdown request to Alice. She will now be the responding
@@ -64,6 +71,25 @@ func TestPeerChannelClosureAcceptFeeResponder(t *testing.T) {
 		t.Fatalf("did not receive shutdown message")
 	}
 
+	// As Bob initiated the closure, Alice should have recorded his
+	// shutdown as its reason.
+	select {
+	case e := <-chanEvents.Updates():
+		event, ok := e.(channelnotifier.CloseReasonEvent)
+		if !ok {
+			t.Fatalf("expected CloseReasonEvent, got %T", e)
+		}
+		if *event.ChannelPoint != *bobChan.ChannelPoint() ||
+			event.Reason.Code != channeldb.CloseReasonShutdown ||
+			!event.Reason.RemoteInitiated {
+
+			t.Fatalf("unexpected close reason for %v: %v",
+				event.ChannelPoint, event.Reason)
+		}
+	case <-time.After(timeout):
+		t.Fatalf("did not receive close reason event")
+	}
+
 	shutdownMsg, ok := msg.(*lnwire.Shutdown)
 	if !ok {
 		t.Fatalf("expected Shutdown message, got %T", msg)
@@ -147,6 +173,12 @@ func TestPeerChannelClosureAcceptFeeInitiator(t *testing.T) {
 	}
 	defer cleanUp()
 
+	chanEvents, err := alicePeer.cfg.ChannelNotifier.SubscribeChannelEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe to channel events: %v", err)
+	}
+	defer chanEvents.Cancel()
+
 	// We make Alice send a shutdown request.
 	updateChan := make(chan interface{}, 1)
 	errChan := make(chan error, 1)
@@ -173,6 +205,25 @@ func TestPeerChannelClosureAcceptFeeInitiator(t *testing.T) {
 		t.Fatalf("expected Shutdown message, got %T", msg)
 	}
 
+	// As Alice initiated the closure, she should have recorded her own
+	// shutdown as its reason.
+	select {
+	case e := <-chanEvents.Updates():
+		event, ok := e.(channelnotifier.CloseReasonEvent)
+		if !ok {
+			t.Fatalf("expected CloseReasonEvent, got %T", e)
+		}
+		if *event.ChannelPoint != *bobChan.ChannelPoint() ||
+			event.Reason.Code != channeldb.CloseReasonShutdown ||
+			event.Reason.RemoteInitiated {
+
+			t.Fatalf("unexpected close reason for %v: %v",
+				event.ChannelPoint, event.Reason)
+		}
+	case <-time.After(timeout):
+		t.Fatalf("did not receive close reason event")
+	}
+
 	aliceDeliveryScript := shutdownMsg.Address
 
 	// Bob will respond with his own Shutdown message.
@@ -870,3 +921,123 @@ func genScript(t *testing.T, address string) lnwire.DeliveryAddress {
 
 	return script
 }
+
+// TestResumeCoopClose asserts that an interrupted cooperative close
+// negotiation is resumed with the delivery script we sent, and that a close
+// request made afterwards follows the resumed negotiation.
+func TestResumeCoopClose(t *testing.T) {
+	t.Parallel()
+
+	notifier := &mockNotifier{
+		confChannel: make(chan *chainntnfs.TxConfirmation),
+	}
+	broadcastTxChan := make(chan *wire.MsgTx)
+
+	alicePeer, bobChan, cleanUp, err := createTestPeer(
+		notifier, broadcastTxChan, noUpdate,
+	)
+	if err != nil {
+		t.Fatalf("unable to create test channels: %v", err)
+	}
+	defer cleanUp()
+
+	chanPoint := bobChan.ChannelPoint()
+	chanID := lnwire.NewChanIDFromOutPoint(chanPoint)
+	aliceChan := alicePeer.activeChannels[chanID]
+	dbChan := aliceChan.State()
+
+	// The negotiations are resumed by a new peer, as it happens when
+	// reconnecting, before its channel manager is started.
+	resume := func() (*Brontide, *lnwire.Shutdown) {
+		t.Helper()
+
+		p := NewBrontide(alicePeer.cfg)
+		shutdown, err := p.resumeCoopClose(aliceChan)
+		if err != nil {
+			t.Fatalf("unable to resume coop close: %v", err)
+		}
+		return p, shutdown
+	}
+
+	// There's nothing to resume for a channel that isn't being closed, nor
+	// for a channel marked as closing without the parameters of the
+	// negotiation.
+	if _, shutdown := resume(); shutdown != nil {
+		t.Fatalf("unexpected resumed close of open channel")
+	}
+	if err := dbChan.MarkCoopBroadcasted(nil, true); err != nil {
+		t.Fatalf("unable to mark coop broadcasted: %v", err)
+	}
+	if _, shutdown := resume(); shutdown != nil {
+		t.Fatalf("unexpected resumed close without shutdown info")
+	}
+
+	err = dbChan.MarkShutdownSent(&channeldb.ShutdownInfo{
+		DeliveryScript: dummyDeliveryScript,
+		LocalInitiator: true,
+		FeePerKB:       1e4,
+	})
+	if err != nil {
+		t.Fatalf("unable to mark shutdown sent: %v", err)
+	}
+	p, shutdown := resume()
+	if shutdown == nil {
+		t.Fatalf("expected close to be resumed")
+	}
+	if shutdown.ChannelID != chanID ||
+		!bytes.Equal(shutdown.Address, dummyDeliveryScript) {
+
+		t.Fatalf("unexpected shutdown message for %v to %x",
+			shutdown.ChannelID, shutdown.Address)
+	}
+	chanCloser, ok := p.activeChanCloses[chanID]
+	if !ok {
+		t.Fatalf("expected resumed chan closer to be active")
+	}
+	if _, ok := p.activeChannels[chanID]; !ok {
+		t.Fatalf("expected resumed channel to be active")
+	}
+
+	// A close request follows the resumed negotiation instead of starting
+	// a new one, but only a single request can do so.
+	newReq := func() *htlcswitch.ChanClose {
+		return &htlcswitch.ChanClose{
+			CloseType: htlcswitch.CloseRegular,
+			ChanPoint: chanPoint,
+			Updates:   make(chan interface{}, 1),
+			Err:       make(chan error, 1),
+		}
+	}
+	req := newReq()
+	p.handleLocalCloseReq(req)
+	select {
+	case err := <-req.Err:
+		t.Fatalf("unexpected close request error: %v", err)
+	default:
+	}
+	if chanCloser.CloseRequest() != req {
+		t.Fatalf("expected close request to be set")
+	}
+
+	otherReq := newReq()
+	p.handleLocalCloseReq(otherReq)
+	select {
+	case err := <-otherReq.Err:
+		if err != chancloser.ErrChanAlreadyClosing {
+			t.Fatalf("expected ErrChanAlreadyClosing, got %v", err)
+		}
+	default:
+		t.Fatalf("expected second close request to fail")
+	}
+	if chanCloser.CloseRequest() != req {
+		t.Fatalf("expected first close request to be kept")
+	}
+
+	// Once a closing transaction is broadcast, the negotiation is over.
+	if err := dbChan.MarkCoopBroadcasted(wire.NewMsgTx(), true); err != nil {
+		t.Fatalf("unable to mark coop broadcasted: %v", err)
+	}
+	if _, shutdown := resume(); shutdown != nil {
+		t.Fatalf("unexpected resumed close after broadcast")
+	}
+}
@@ -57,9 +57,10 @@ type Config struct {
 	// default features field.
 	LegacyFeatures *lnwire.FeatureVector
 
-	// OutgoingCltvRejectDelta defines the number of blocks before expiry of
-	// an htlc where we don't offer it anymore.
-	OutgoingCltvRejectDelta uint32
+	// OutgoingCltvRejectDelta returns the number of blocks before expiry
+	// of an htlc where we don't offer it anymore. It is queried each time
+	// a link is created, as the delta can be updated at runtime.
+	OutgoingCltvRejectDelta func() uint32
 
 	// ChanActiveTimeout specifies the duration the peer will wait to request
 	// a channel reenable, beginning from the time the peer was started.
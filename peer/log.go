@@ -5,12 +5,15 @@ import (
 	"github.com/decred/slog"
 )
 
+// Subsystem defines the logging code for this subsystem.
+const Subsystem = "PEER"
+
 // peerLog is a logger that is initialized with the slog.Disabled logger.
 var peerLog slog.Logger
 
 // The default amount of logging is none.
 func init() {
-	UseLogger(build.NewSubLogger("PEER", nil))
+	UseLogger(build.NewSubLogger(Subsystem, nil))
 }
 
 // DisableLog disables all logging output.
@@ -23,6 +23,7 @@ import (
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrlnd/chainntnfs"
 	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/channelnotifier"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/keychain"
@@ -252,6 +253,12 @@ func (*mockWalletController) ListUnspentWitness(minconfirms,
 	return nil, nil
 }
 
+func (*mockWalletController) ListAccountUnspent(string, int32, int32) (
+	[]*lnwallet.Utxo, error) {
+
+	return nil, nil
+}
+
 func (*mockWalletController) ListTransactionDetails(startHeight,
 	endHeight int32) ([]*lnwallet.TransactionDetail, error) {
 
@@ -309,7 +316,7 @@ func (*mockWalletController) InitialSyncChannel() <-chan struct{} {
 }
 
 func (*mockWalletController) LeaseOutput(lnwallet.LockID,
-	wire.OutPoint) (time.Time, error) {
+	wire.OutPoint, time.Duration) (time.Time, error) {
 
 	return time.Now(), nil
 }
@@ -684,6 +691,16 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 		ChainNet:    wire.SimNet,
 	}
 
+	chanNotifier := channelnotifier.New(dbAlice)
+	if err = chanNotifier.Start(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	cleanUp := func() {
+		chanNotifier.Stop()
+		cleanUpFunc()
+	}
+
 	cfg := &Config{
 		Addr:        cfgAddr,
 		PubKeyBytes: pubKey,
@@ -701,6 +718,8 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 		ChanStatusMgr:  chanStatusMgr,
 		DisconnectPeer: func(b *secp256k1.PublicKey) error { return nil },
 		ChainParams:    chainParams,
+
+		ChannelNotifier: chanNotifier,
 	}
 
 	alicePeer := NewBrontide(*cfg)
@@ -711,5 +730,5 @@ func createTestPeer(notifier chainntnfs.ChainNotifier,
 	alicePeer.wg.Add(1)
 	go alicePeer.channelManager()
 
-	return alicePeer, channelBob, cleanUpFunc, nil
+	return alicePeer, channelBob, cleanUp, nil
 }
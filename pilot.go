@@ -9,8 +9,10 @@ import (
 	"github.com/decred/dcrd/dcrutil/v3"
 	"github.com/decred/dcrd/wire"
 	"github.com/decred/dcrlnd/autopilot"
+	"github.com/decred/dcrlnd/chanfitness"
 	"github.com/decred/dcrlnd/lncfg"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
 	"github.com/decred/dcrlnd/tor"
 )
 
@@ -136,6 +138,59 @@ func (c *chanController) SpliceOut(chanPoint *wire.OutPoint,
 // autopilot.ChannelController interface.
 var _ autopilot.ChannelController = (*chanController)(nil)
 
+// nodeReliability returns the reliability metrics of the given nodes. The
+// uptime and flap count of a node are taken from the channels we have had
+// with it, while its forwarding success rate is derived from the latest
+// outcome of each of its outgoing pairs known to mission control.
+func (s *server) nodeReliability(nodes map[autopilot.NodeID]struct{}) (
+	map[autopilot.NodeID]*autopilot.NodeReliability, error) {
+
+	metrics := make(map[autopilot.NodeID]*autopilot.NodeReliability)
+	nodeMetrics := func(nID autopilot.NodeID) *autopilot.NodeReliability {
+		m, ok := metrics[nID]
+		if !ok {
+			m = &autopilot.NodeReliability{}
+			metrics[nID] = m
+		}
+		return m
+	}
+
+	for nID := range nodes {
+		fitness, err := s.chanEventStore.GetPeerFitness(
+			route.Vertex(nID),
+		)
+		switch {
+		case err == chanfitness.ErrPeerNotFound:
+			continue
+
+		case err != nil:
+			return nil, err
+		}
+
+		m := nodeMetrics(nID)
+		m.Lifespan = fitness.Lifespan
+		m.Uptime = fitness.Uptime
+		m.FlapCount = fitness.FlapCount
+	}
+
+	snapshot := s.missionControl.GetHistorySnapshot()
+	for _, pair := range snapshot.Pairs {
+		nID := autopilot.NodeID(pair.Pair.From)
+		if _, ok := nodes[nID]; !ok {
+			continue
+		}
+
+		m := nodeMetrics(nID)
+		if pair.SuccessTime.After(pair.FailTime) {
+			m.ForwardSuccesses++
+		} else {
+			m.ForwardFailures++
+		}
+	}
+
+	return metrics, nil
+}
+
 // initAutoPilot initializes a new autopilot.ManagerCfg to manage an autopilot.
 // Agent instance based on the passed configuration structs. The agent and all
 // interfaces needed to drive it won't be launched before the Manager's
@@ -162,6 +217,10 @@ func initAutoPilot(svr *server, cfg *lncfg.AutoPilot,
 		return nil, err
 	}
 
+	// Feed the reliability heuristic with the behaviour of the nodes we
+	// observed so far.
+	autopilot.SetReliabilitySource(svr.nodeReliability)
+
 	weightedAttachment, err := autopilot.NewWeightedCombAttachment(
 		heuristics...,
 	)
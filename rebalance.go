@@ -0,0 +1,160 @@
+package dcrlnd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/invoicesrpc"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// rebalanceHops returns the hops of a circular route that leaves through the
+// channel with the outgoing peer, traverses the given intermediate hops and
+// returns through the channel with the incoming peer. The hops end with the
+// incoming peer, as the final hop back to ourselves is added by the router.
+func rebalanceHops(outPeer, inPeer route.Vertex,
+	intermediate []route.Vertex) []route.Vertex {
+
+	hops := make([]route.Vertex, 0, len(intermediate)+2)
+	hops = append(hops, outPeer)
+	hops = append(hops, intermediate...)
+
+	// The incoming peer is only added if it isn't already the last hop,
+	// which is the case when both channels are with the same peer.
+	if hops[len(hops)-1] != inPeer {
+		hops = append(hops, inPeer)
+	}
+
+	return hops
+}
+
+// SendRebalance moves funds from one of our channels to another by paying an
+// invoice of our own along a circular route that leaves through the outgoing
+// channel and returns through the incoming channel.
+func (r *rpcServer) SendRebalance(ctx context.Context,
+	in *lnrpc.SendRebalanceRequest) (*lnrpc.SendResponse, error) {
+
+	// We don't allow payments to be sent while the daemon itself is still
+	// syncing as we may be trying to sent a payment over a "stale"
+	// channel.
+	if !r.server.Started() {
+		return nil, ErrServerNotActive
+	}
+
+	if in.AmtMAtoms <= 0 {
+		return nil, errors.New("amount must be positive")
+	}
+	if in.FeeLimitMAtoms < 0 {
+		return nil, errors.New("fee limit must not be negative")
+	}
+	if in.OutgoingChanId == 0 || in.IncomingChanId == 0 {
+		return nil, errors.New("both the outgoing and incoming " +
+			"channels must be set")
+	}
+	if in.OutgoingChanId == in.IncomingChanId {
+		return nil, errors.New("outgoing and incoming channels must " +
+			"differ")
+	}
+
+	// Both channels must be among our open channels, from which we learn
+	// the peers the route leaves and returns through.
+	openChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
+	if err != nil {
+		return nil, err
+	}
+
+	var outPeer, inPeer *route.Vertex
+	for _, channel := range openChannels {
+		peer := route.NewVertex(channel.IdentityPub)
+
+		switch channel.ShortChannelID.ToUint64() {
+		case in.OutgoingChanId:
+			outPeer = &peer
+
+		case in.IncomingChanId:
+			inPeer = &peer
+		}
+	}
+	if outPeer == nil {
+		return nil, fmt.Errorf("outgoing channel %v not found",
+			in.OutgoingChanId)
+	}
+	if inPeer == nil {
+		return nil, fmt.Errorf("incoming channel %v not found",
+			in.IncomingChanId)
+	}
+
+	intermediate := make([]route.Vertex, 0, len(in.HopPubkeys))
+	for _, pubKeyBytes := range in.HopPubkeys {
+		hop, err := route.NewVertexFromBytes(pubKeyBytes)
+		if err != nil {
+			return nil, err
+		}
+		intermediate = append(intermediate, hop)
+	}
+
+	// With the hops known, we'll build the route, using the same final
+	// cltv delta the invoice will require.
+	amt := lnwire.MilliAtom(in.AmtMAtoms)
+	finalCltvDelta := r.cfg.TimeLockDelta
+	rt, err := r.server.chanRouter.BuildCircularRoute(
+		amt, rebalanceHops(*outPeer, *inPeer, intermediate),
+		in.OutgoingChanId, in.IncomingChanId, int32(finalCltvDelta),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	feeLimit := lnwire.MilliAtom(in.FeeLimitMAtoms)
+	if rt.TotalFees() > feeLimit {
+		return nil, fmt.Errorf("route fee of %v exceeds fee limit "+
+			"of %v", rt.TotalFees(), feeLimit)
+	}
+
+	// Now we'll add the invoice we pay ourselves.
+	hash, invoice, err := invoicesrpc.AddInvoice(
+		ctx, r.addInvoiceConfig(), &invoicesrpc.AddInvoiceData{
+			Memo: fmt.Sprintf("rebalance from channel %v to %v",
+				in.OutgoingChanId, in.IncomingChanId),
+			Value:      amt,
+			CltvExpiry: uint64(finalCltvDelta),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Pass the payment address of the invoice along if the final hop
+	// understands tlv payloads, as it would to any other recipient.
+	finalHop := rt.Hops[len(rt.Hops)-1]
+	if !finalHop.LegacyPayload {
+		finalHop.MPP = record.NewMPP(amt, invoice.Terms.PaymentAddr)
+	}
+
+	rpcsLog.Infof("Rebalancing %v from channel %v to %v with "+
+		"hash=%v, fee=%v", amt, in.OutgoingChanId, in.IncomingChanId,
+		hash, rt.TotalFees())
+
+	resp, err := r.sendPaymentSync(ctx, &rpcPaymentRequest{
+		SendRequest: &lnrpc.SendRequest{
+			PaymentHash: hash[:],
+		},
+		route: rt,
+	})
+
+	// The invoice is canceled if the payment failed, so that it doesn't
+	// linger as an open invoice.
+	if err != nil || resp.PaymentError != "" {
+		cancelErr := r.server.invoices.CancelInvoice(*hash)
+		if cancelErr != nil {
+			rpcsLog.Warnf("Unable to cancel rebalance invoice "+
+				"%v: %v", hash, cancelErr)
+		}
+	}
+
+	return resp, err
+}
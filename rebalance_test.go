@@ -0,0 +1,63 @@
+package dcrlnd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/decred/dcrlnd/routing/route"
+)
+
+// TestRebalanceHops tests that the hops of a circular route start with the
+// peer of the outgoing channel and end with the peer of the incoming channel.
+func TestRebalanceHops(t *testing.T) {
+	var a, b, c route.Vertex
+	a[0], b[0], c[0] = 1, 2, 3
+
+	tests := []struct {
+		name         string
+		outPeer      route.Vertex
+		inPeer       route.Vertex
+		intermediate []route.Vertex
+		expected     []route.Vertex
+	}{
+		{
+			name:     "same peer",
+			outPeer:  a,
+			inPeer:   a,
+			expected: []route.Vertex{a},
+		},
+		{
+			name:     "connected peers",
+			outPeer:  a,
+			inPeer:   b,
+			expected: []route.Vertex{a, b},
+		},
+		{
+			name:         "intermediate hops",
+			outPeer:      a,
+			inPeer:       b,
+			intermediate: []route.Vertex{c},
+			expected:     []route.Vertex{a, c, b},
+		},
+		{
+			name:         "intermediate hops ending at incoming peer",
+			outPeer:      a,
+			inPeer:       b,
+			intermediate: []route.Vertex{c, b},
+			expected:     []route.Vertex{a, c, b},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			hops := rebalanceHops(
+				test.outPeer, test.inPeer, test.intermediate,
+			)
+			if !reflect.DeepEqual(hops, test.expected) {
+				t.Fatalf("expected hops %v, got %v",
+					test.expected, hops)
+			}
+		})
+	}
+}
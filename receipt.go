@@ -0,0 +1,261 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/tlv"
+	"github.com/decred/dcrlnd/zpay32"
+)
+
+const (
+	receiptRoleType        tlv.Type = 0
+	receiptPaymentHashType tlv.Type = 2
+	receiptPreimageType    tlv.Type = 4
+	receiptPayeeType       tlv.Type = 6
+	receiptAmtType         tlv.Type = 8
+	receiptCreationType    tlv.Type = 10
+	receiptSettleType      tlv.Type = 12
+	receiptPayReqType      tlv.Type = 14
+)
+
+var (
+	// receiptSignedMsgPrefix is prepended to a serialized payment receipt
+	// before it is signed, so that receipt signatures can't be confused
+	// with the signatures of arbitrary messages.
+	receiptSignedMsgPrefix = []byte("Lightning Payment Receipt:")
+
+	// errReceiptNotSettled is returned when a receipt is requested for a
+	// payment or invoice that isn't settled.
+	errReceiptNotSettled = errors.New("payment not settled")
+)
+
+// receiptSignedMsg returns the message that is signed to attest a serialized
+// receipt.
+func receiptSignedMsg(receiptBytes []byte) []byte {
+	msg := make([]byte, 0, len(receiptSignedMsgPrefix)+len(receiptBytes))
+	msg = append(msg, receiptSignedMsgPrefix...)
+	return append(msg, receiptBytes...)
+}
+
+// receiptRole identifies the party of a payment that issued a receipt.
+type receiptRole uint8
+
+const (
+	// receiptRolePayer is the role of a receipt issued by the sender of a
+	// payment.
+	receiptRolePayer receiptRole = 1
+
+	// receiptRolePayee is the role of a receipt issued by the receiver of
+	// a payment.
+	receiptRolePayee receiptRole = 2
+)
+
+// paymentReceipt is a proof of a settled payment, which is signed by the node
+// that issued it.
+type paymentReceipt struct {
+	// role is the party of the payment that issued the receipt.
+	role receiptRole
+
+	// paymentHash is the hash of the payment.
+	paymentHash lntypes.Hash
+
+	// preimage is the preimage that settled the payment.
+	preimage lntypes.Preimage
+
+	// payee is the public key of the node that received the payment.
+	payee route.Vertex
+
+	// amt is the amount of the payment, excluding routing fees.
+	amt lnwire.MilliAtom
+
+	// creationTime is the time at which the payment or invoice was
+	// created.
+	creationTime time.Time
+
+	// settleTime is the time at which the payment was settled.
+	settleTime time.Time
+
+	// paymentRequest is the payment request that was paid. It is empty for
+	// spontaneous payments.
+	paymentRequest []byte
+}
+
+// newPayerReceipt creates the receipt of an outgoing payment.
+func newPayerReceipt(payment *channeldb.MPPayment) (*paymentReceipt, error) {
+	if payment.Status != channeldb.StatusSucceeded {
+		return nil, errReceiptNotSettled
+	}
+
+	receipt := &paymentReceipt{
+		role:           receiptRolePayer,
+		paymentHash:    payment.Info.PaymentHash,
+		amt:            payment.Info.Value,
+		creationTime:   payment.Info.CreationTime,
+		paymentRequest: payment.Info.PaymentRequest,
+	}
+
+	// The payment is settled once the last of its htlcs is settled. All
+	// settled htlcs carry the same preimage and destination.
+	for _, htlc := range payment.HTLCs {
+		if htlc.Settle == nil {
+			continue
+		}
+
+		receipt.preimage = htlc.Settle.Preimage
+		if htlc.Settle.SettleTime.After(receipt.settleTime) {
+			receipt.settleTime = htlc.Settle.SettleTime
+		}
+
+		hops := htlc.Route.Hops
+		if len(hops) > 0 {
+			receipt.payee = hops[len(hops)-1].PubKeyBytes
+		}
+	}
+
+	if receipt.preimage.Hash() != receipt.paymentHash {
+		return nil, errReceiptNotSettled
+	}
+
+	return receipt, nil
+}
+
+// newPayeeReceipt creates the receipt of an invoice that was paid to the node
+// with the given public key.
+func newPayeeReceipt(invoice *channeldb.Invoice,
+	self route.Vertex) (*paymentReceipt, error) {
+
+	if invoice.State != channeldb.ContractSettled ||
+		invoice.Terms.PaymentPreimage == nil {
+
+		return nil, errReceiptNotSettled
+	}
+
+	preimage := *invoice.Terms.PaymentPreimage
+	return &paymentReceipt{
+		role:           receiptRolePayee,
+		paymentHash:    preimage.Hash(),
+		preimage:       preimage,
+		payee:          self,
+		amt:            invoice.AmtPaid,
+		creationTime:   invoice.CreationDate,
+		settleTime:     invoice.SettleDate,
+		paymentRequest: invoice.PaymentRequest,
+	}, nil
+}
+
+// records returns the tlv records of the receipt's fields.
+func (r *paymentReceipt) records(creationTime, settleTime,
+	amt *uint64) []tlv.Record {
+
+	return []tlv.Record{
+		tlv.MakePrimitiveRecord(receiptRoleType, (*uint8)(&r.role)),
+		tlv.MakePrimitiveRecord(
+			receiptPaymentHashType, (*[32]byte)(&r.paymentHash),
+		),
+		tlv.MakePrimitiveRecord(
+			receiptPreimageType, (*[32]byte)(&r.preimage),
+		),
+		tlv.MakePrimitiveRecord(
+			receiptPayeeType, (*[33]byte)(&r.payee),
+		),
+		tlv.MakePrimitiveRecord(receiptAmtType, amt),
+		tlv.MakePrimitiveRecord(receiptCreationType, creationTime),
+		tlv.MakePrimitiveRecord(receiptSettleType, settleTime),
+		tlv.MakePrimitiveRecord(receiptPayReqType, &r.paymentRequest),
+	}
+}
+
+// serialize encodes the receipt as a tlv stream. The serialized receipt is
+// the message that is signed by the issuer of the receipt.
+func (r *paymentReceipt) serialize() ([]byte, error) {
+	creationTime := uint64(r.creationTime.UnixNano())
+	settleTime := uint64(r.settleTime.UnixNano())
+	amt := uint64(r.amt)
+
+	stream, err := tlv.NewStream(
+		r.records(&creationTime, &settleTime, &amt)...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	if err := stream.Encode(&b); err != nil {
+		return nil, err
+	}
+
+	return b.Bytes(), nil
+}
+
+// deserializePaymentReceipt decodes a receipt that was serialized as a tlv
+// stream.
+func deserializePaymentReceipt(b []byte) (*paymentReceipt, error) {
+	var (
+		r                             paymentReceipt
+		creationTime, settleTime, amt uint64
+	)
+
+	records := r.records(&creationTime, &settleTime, &amt)
+	stream, err := tlv.NewStream(records...)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedTypes, err := stream.DecodeWithParsedTypes(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	for _, record := range records {
+		if _, ok := parsedTypes[record.Type()]; !ok {
+			return nil, fmt.Errorf("receipt field %d missing",
+				record.Type())
+		}
+	}
+
+	r.creationTime = time.Unix(0, int64(creationTime))
+	r.settleTime = time.Unix(0, int64(settleTime))
+	r.amt = lnwire.MilliAtom(amt)
+
+	return &r, nil
+}
+
+// validate checks the consistency of the receipt's fields. The preimage must
+// match the payment hash and the payment request, if any, must pay to the
+// payment hash and payee of the receipt on the given network.
+func (r *paymentReceipt) validate(net *chaincfg.Params) error {
+	if r.role != receiptRolePayer && r.role != receiptRolePayee {
+		return fmt.Errorf("unknown receipt role %d", r.role)
+	}
+
+	if r.preimage.Hash() != r.paymentHash {
+		return errors.New("preimage doesn't match payment hash")
+	}
+
+	if len(r.paymentRequest) == 0 {
+		return nil
+	}
+
+	payReq, err := zpay32.Decode(string(r.paymentRequest), net)
+	if err != nil {
+		return fmt.Errorf("invalid payment request: %v", err)
+	}
+
+	if payReq.PaymentHash == nil || *payReq.PaymentHash != r.paymentHash {
+		return errors.New("payment request doesn't match payment hash")
+	}
+
+	payee := route.NewVertex(payReq.Destination)
+	if payee != r.payee {
+		return errors.New("payment request doesn't match payee")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package dcrlnd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3/ecdsa"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/zpay32"
+)
+
+// TestPaymentReceipt tests the serialization and validation of payment
+// receipts.
+func TestPaymentReceipt(t *testing.T) {
+	t.Parallel()
+
+	net := chaincfg.RegNetParams()
+
+	payeeKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	payee := route.NewVertex(payeeKey.PubKey())
+
+	preimage := lntypes.Preimage{1, 2, 3}
+	paymentHash := preimage.Hash()
+
+	payReq, err := zpay32.NewInvoice(
+		net, paymentHash, time.Unix(1000, 0), zpay32.Amount(10000),
+		zpay32.Description("receipt"),
+	)
+	if err != nil {
+		t.Fatalf("unable to create payment request: %v", err)
+	}
+	payReqStr, err := payReq.Encode(zpay32.MessageSigner{
+		SignCompact: func(hash []byte) ([]byte, error) {
+			return ecdsa.SignCompact(payeeKey, hash, true), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("unable to encode payment request: %v", err)
+	}
+
+	invoice := &channeldb.Invoice{
+		PaymentRequest: []byte(payReqStr),
+		CreationDate:   time.Unix(1000, 0),
+		SettleDate:     time.Unix(2000, 5),
+		State:          channeldb.ContractSettled,
+		AmtPaid:        10000,
+		Terms: channeldb.ContractTerm{
+			PaymentPreimage: &preimage,
+		},
+	}
+	receipt, err := newPayeeReceipt(invoice, payee)
+	if err != nil {
+		t.Fatalf("unable to create receipt: %v", err)
+	}
+
+	// The receipt should survive a serialization round trip and be valid.
+	receiptBytes, err := receipt.serialize()
+	if err != nil {
+		t.Fatalf("unable to serialize receipt: %v", err)
+	}
+	decoded, err := deserializePaymentReceipt(receiptBytes)
+	if err != nil {
+		t.Fatalf("unable to deserialize receipt: %v", err)
+	}
+
+	if decoded.role != receiptRolePayee ||
+		decoded.paymentHash != paymentHash ||
+		decoded.preimage != preimage || decoded.payee != payee ||
+		decoded.amt != invoice.AmtPaid ||
+		!decoded.creationTime.Equal(invoice.CreationDate) ||
+		!decoded.settleTime.Equal(invoice.SettleDate) ||
+		string(decoded.paymentRequest) != payReqStr {
+
+		t.Fatalf("decoded receipt %v doesn't match %v", decoded,
+			receipt)
+	}
+
+	if err := decoded.validate(net); err != nil {
+		t.Fatalf("expected valid receipt: %v", err)
+	}
+
+	// A receipt without some of its fields can't be decoded.
+	if _, err := deserializePaymentReceipt(receiptBytes[:40]); err == nil {
+		t.Fatalf("expected truncated receipt to be rejected")
+	}
+
+	// Receipts that are inconsistent are rejected.
+	invalid := []func(r *paymentReceipt){
+		func(r *paymentReceipt) {
+			r.role = 3
+		},
+		func(r *paymentReceipt) {
+			r.preimage = lntypes.Preimage{4}
+		},
+		func(r *paymentReceipt) {
+			r.payee = route.Vertex{5}
+		},
+		func(r *paymentReceipt) {
+			r.preimage = lntypes.Preimage{6}
+			r.paymentHash = r.preimage.Hash()
+		},
+		func(r *paymentReceipt) {
+			r.paymentRequest = []byte("lnrdcr1invalid")
+		},
+	}
+	for i, modify := range invalid {
+		r := *receipt
+		modify(&r)
+		if err := r.validate(net); err == nil {
+			t.Fatalf("expected invalid receipt %d to be rejected",
+				i)
+		}
+	}
+
+	// Receipts are only issued for settled payments and invoices.
+	invoice.State = channeldb.ContractAccepted
+	if _, err := newPayeeReceipt(invoice, payee); err != errReceiptNotSettled {
+		t.Fatalf("expected not settled error, got: %v", err)
+	}
+
+	payment := &channeldb.MPPayment{
+		Info: &channeldb.PaymentCreationInfo{
+			PaymentHash:    paymentHash,
+			Value:          10000,
+			CreationTime:   time.Unix(1000, 0),
+			PaymentRequest: []byte(payReqStr),
+		},
+		HTLCs: []channeldb.HTLCAttempt{{
+			HTLCAttemptInfo: channeldb.HTLCAttemptInfo{
+				Route: route.Route{
+					Hops: []*route.Hop{{PubKeyBytes: payee}},
+				},
+			},
+			Settle: &channeldb.HTLCSettleInfo{
+				Preimage:   preimage,
+				SettleTime: time.Unix(2000, 0),
+			},
+		}},
+		Status: channeldb.StatusInFlight,
+	}
+	if _, err := newPayerReceipt(payment); err != errReceiptNotSettled {
+		t.Fatalf("expected not settled error, got: %v", err)
+	}
+
+	// The receipt of a succeeded payment is valid.
+	payment.Status = channeldb.StatusSucceeded
+	receipt, err = newPayerReceipt(payment)
+	if err != nil {
+		t.Fatalf("unable to create receipt: %v", err)
+	}
+	if receipt.role != receiptRolePayer || receipt.payee != payee ||
+		!receipt.settleTime.Equal(time.Unix(2000, 0)) {
+
+		t.Fatalf("unexpected payer receipt: %v", receipt)
+	}
+	if err := receipt.validate(net); err != nil {
+		t.Fatalf("expected valid receipt: %v", err)
+	}
+}
@@ -3,4 +3,9 @@ package record
 const (
 	// KeySendType is the custom record identifier for keysend preimages.
 	KeySendType uint64 = 5482373484
+
+	// HoldFeeType is the custom record identifier for the hold fee offered
+	// to an intermediate hop for forwarding an htlc. It is only used by the
+	// experimental hold fee support.
+	HoldFeeType uint64 = 5482373485
 )
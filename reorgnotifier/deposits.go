@@ -0,0 +1,194 @@
+package reorgnotifier
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+)
+
+// DepositKind describes which of our channel scripts a deposit paid to.
+type DepositKind uint8
+
+const (
+	// DepositFunding is a payment to the funding script of one of our
+	// channels.
+	DepositFunding DepositKind = iota
+
+	// DepositClosing is a payment to a script one of our channels was, or
+	// will be, cooperatively closed to.
+	DepositClosing
+)
+
+// String returns a human readable representation of the DepositKind.
+func (k DepositKind) String() string {
+	switch k {
+	case DepositFunding:
+		return "funding"
+
+	case DepositClosing:
+		return "closing"
+
+	default:
+		return fmt.Sprintf("unknown<%d>", uint8(k))
+	}
+}
+
+// ChannelScript identifies the channel an output script belongs to.
+type ChannelScript struct {
+	// ChanPoint is the channel the script belongs to.
+	ChanPoint wire.OutPoint
+
+	// Kind describes how the script is used by the channel.
+	Kind DepositKind
+}
+
+// ChannelScripts holds the output scripts of our channels that third parties
+// could pay to, such as the funding scripts of our channels and the scripts
+// our funds were delivered to by cooperative closes.
+type ChannelScripts struct {
+	// scripts maps output scripts to the channel they belong to.
+	scripts map[string]ChannelScript
+
+	// fundingOutpoints are the funding outpoints of our channels. The
+	// transactions that create or spend them are our own funding and
+	// closing transactions, which pay to our channel scripts by design.
+	fundingOutpoints map[wire.OutPoint]struct{}
+}
+
+// NewChannelScripts returns an empty set of channel scripts.
+func NewChannelScripts() *ChannelScripts {
+	return &ChannelScripts{
+		scripts:          make(map[string]ChannelScript),
+		fundingOutpoints: make(map[wire.OutPoint]struct{}),
+	}
+}
+
+// AddChannel registers the funding outpoint of one of our channels, so that
+// its funding and closing transactions aren't reported as deposits.
+func (c *ChannelScripts) AddChannel(chanPoint wire.OutPoint) {
+	c.fundingOutpoints[regularOutPoint(chanPoint)] = struct{}{}
+}
+
+// AddScript registers an output script of the given channel.
+func (c *ChannelScripts) AddScript(pkScript []byte, chanPoint wire.OutPoint,
+	kind DepositKind) {
+
+	c.scripts[string(pkScript)] = ChannelScript{
+		ChanPoint: chanPoint,
+		Kind:      kind,
+	}
+}
+
+// Deposit is an output paying to one of our channel scripts, created by a
+// transaction that isn't one of our channel transactions.
+type Deposit struct {
+	ChannelScript
+
+	// Txid is the hash of the transaction that created the output.
+	Txid chainhash.Hash
+
+	// Index is the index of the output in the transaction.
+	Index uint32
+
+	// Amount is the value of the output.
+	Amount dcrutil.Amount
+
+	// PkScript is the script the output pays to.
+	PkScript []byte
+}
+
+// Deposits returns the outputs of the transaction that pay to one of the
+// channel scripts. Our own funding and closing transactions are expected to
+// pay to these scripts, so no deposits are returned for them.
+func (c *ChannelScripts) Deposits(tx *wire.MsgTx) []Deposit {
+	txid := tx.TxHash()
+	for i := range tx.TxOut {
+		op := wire.OutPoint{Hash: txid, Index: uint32(i)}
+		if _, ok := c.fundingOutpoints[op]; ok {
+			return nil
+		}
+	}
+	for _, txIn := range tx.TxIn {
+		op := regularOutPoint(txIn.PreviousOutPoint)
+		if _, ok := c.fundingOutpoints[op]; ok {
+			return nil
+		}
+	}
+
+	var deposits []Deposit
+	for i, txOut := range tx.TxOut {
+		script, ok := c.scripts[string(txOut.PkScript)]
+		if !ok {
+			continue
+		}
+
+		deposits = append(deposits, Deposit{
+			ChannelScript: script,
+			Txid:          txid,
+			Index:         uint32(i),
+			Amount:        dcrutil.Amount(txOut.Value),
+			PkScript:      txOut.PkScript,
+		})
+	}
+
+	return deposits
+}
+
+// regularOutPoint returns the outpoint without its tree, as funding outpoints
+// are always part of the regular transaction tree.
+func regularOutPoint(op wire.OutPoint) wire.OutPoint {
+	return wire.OutPoint{Hash: op.Hash, Index: op.Index}
+}
+
+// DepositEvent is sent to subscribers when a transaction of the main chain
+// pays to one of our channel scripts.
+type DepositEvent struct {
+	Deposit
+
+	// Height is the height of the block that included the transaction.
+	Height uint32
+
+	// Block is the hash of the block that included the transaction.
+	Block chainhash.Hash
+}
+
+// scanDeposits notifies subscribers of the deposits to our channel scripts
+// made by the transactions of the given block.
+func (n *ReorgNotifier) scanDeposits(ref blockRef) error {
+	if n.cfg.FetchChannelScripts == nil {
+		return nil
+	}
+
+	scripts, err := n.cfg.FetchChannelScripts()
+	if err != nil {
+		return err
+	}
+
+	block, err := n.cfg.ChainIO.GetBlock(&ref.hash)
+	if err != nil {
+		return err
+	}
+
+	txs := append(block.Transactions, block.STransactions...)
+	for _, tx := range txs {
+		for _, deposit := range scripts.Deposits(tx) {
+			log.Warnf("Transaction %v pays %v to the %v script of "+
+				"ChannelPoint(%v)", deposit.Txid, deposit.Amount,
+				deposit.Kind, deposit.ChanPoint)
+
+			event := DepositEvent{
+				Deposit: deposit,
+				Height:  ref.height,
+				Block:   ref.hash,
+			}
+			if err := n.ntfnServer.SendUpdate(event); err != nil {
+				log.Warnf("Unable to send deposit update: %v",
+					err)
+			}
+		}
+	}
+
+	return nil
+}
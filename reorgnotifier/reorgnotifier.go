@@ -0,0 +1,698 @@
+package reorgnotifier
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chainntnfs"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/subscribe"
+)
+
+// DefaultHistoryDepth is the default number of recent main chain blocks that
+// are tracked in order to detect reorgs and determine their depth.
+const DefaultHistoryDepth = 288
+
+// TxKind describes how a transaction affected by a reorg relates to the node.
+type TxKind uint8
+
+const (
+	// TxKindFunding is the funding transaction of one of our channels.
+	TxKindFunding TxKind = iota
+
+	// TxKindCooperativeClose is the cooperative close transaction of one
+	// of our channels.
+	TxKindCooperativeClose
+
+	// TxKindForceClose is a commitment transaction that force closed one
+	// of our channels.
+	TxKindForceClose
+
+	// TxKindSweep is a transaction published by the sweeper.
+	TxKindSweep
+)
+
+// String returns a human readable representation of the TxKind.
+func (k TxKind) String() string {
+	switch k {
+	case TxKindFunding:
+		return "funding"
+
+	case TxKindCooperativeClose:
+		return "cooperative close"
+
+	case TxKindForceClose:
+		return "force close"
+
+	case TxKindSweep:
+		return "sweep"
+
+	default:
+		return fmt.Sprintf("unknown<%d>", uint8(k))
+	}
+}
+
+// AffectedTx is one of our transactions that was included in a block which
+// got disconnected from the main chain by a reorg.
+type AffectedTx struct {
+	// Txid is the hash of the transaction.
+	Txid chainhash.Hash
+
+	// Kind describes how the transaction relates to the node.
+	Kind TxKind
+
+	// ChanPoint is the channel funded or closed by the transaction. It is
+	// nil for sweeps.
+	ChanPoint *wire.OutPoint
+
+	// Height is the height of the disconnected block that included the
+	// transaction.
+	Height uint32
+}
+
+// ReorgEvent is sent to subscribers when blocks of the main chain get replaced
+// by a competing chain.
+type ReorgEvent struct {
+	// Depth is the number of blocks that were disconnected.
+	Depth uint32
+
+	// ForkHeight is the height of the last block shared by the old and
+	// the new chain.
+	ForkHeight uint32
+
+	// OldTip is the hash of the tip of the old chain.
+	OldTip chainhash.Hash
+
+	// NewTip is the hash of the tip of the new chain.
+	NewTip chainhash.Hash
+
+	// NewHeight is the height of the tip of the new chain.
+	NewHeight uint32
+
+	// AffectedTxs are our transactions that were included in the
+	// disconnected blocks.
+	AffectedTxs []AffectedTx
+}
+
+// ReconciledEvent is sent to subscribers once a transaction affected by a
+// reorg confirmed on the new main chain and the state that depends on it was
+// reconciled.
+type ReconciledEvent struct {
+	AffectedTx
+
+	// NewHeight is the height at which the transaction confirmed on the
+	// new chain.
+	NewHeight uint32
+
+	// NewBlock is the hash of the block that confirmed the transaction on
+	// the new chain.
+	NewBlock chainhash.Hash
+}
+
+// ShortChanIDChangedEvent is sent to subscribers when the funding transaction
+// of an open channel confirmed again at a different position in the new main
+// chain. The short channel ID the channel was announced with no longer points
+// to its funding output. The new short channel ID is stored, but the channel
+// has to be closed and reopened, or re-announced under its new short channel
+// ID, to remain usable by the rest of the network.
+type ShortChanIDChangedEvent struct {
+	// ChanPoint is the funding outpoint of the channel.
+	ChanPoint wire.OutPoint
+
+	// OldShortChanID is the short channel ID of the channel, derived from
+	// the position of the funding transaction in the old chain.
+	OldShortChanID lnwire.ShortChannelID
+
+	// NewShortChanID is the short channel ID derived from the position of
+	// the funding transaction in the new chain.
+	NewShortChanID lnwire.ShortChannelID
+}
+
+// Config houses the dependencies of the ReorgNotifier.
+type Config struct {
+	// Notifier is used to receive the blocks connected to the main chain
+	// and to wait for affected transactions to confirm again.
+	Notifier chainntnfs.ChainNotifier
+
+	// ChainIO is used to query the current main chain and to fetch the
+	// disconnected blocks.
+	ChainIO lnwallet.BlockChainIO
+
+	// FetchAllChannels returns all the channels of the node that aren't
+	// closed yet.
+	FetchAllChannels func() ([]*channeldb.OpenChannel, error)
+
+	// FetchClosedChannels returns the summaries of our closed channels.
+	FetchClosedChannels func(pendingOnly bool) (
+		[]*channeldb.ChannelCloseSummary, error)
+
+	// IsSweepTx determines whether a transaction was published by the
+	// sweeper. If nil, no transaction is considered a sweep.
+	IsSweepTx func(chainhash.Hash) (bool, error)
+
+	// UpdateCloseHeight updates the close height stored for a closed
+	// channel.
+	UpdateCloseHeight func(chanPoint *wire.OutPoint, height uint32) error
+
+	// UpdateShortChanID updates the short channel ID stored for an open
+	// channel whose funding transaction confirmed again at a different
+	// position. If nil, the change is only reported.
+	UpdateShortChanID func(chanPoint *wire.OutPoint,
+		shortChanID lnwire.ShortChannelID) error
+
+	// FetchChannelScripts returns the output scripts of our channels that
+	// third parties could pay to. Subscribers are notified of the
+	// transactions of new blocks paying to them. If nil, no deposits are
+	// detected.
+	FetchChannelScripts func() (*ChannelScripts, error)
+
+	// HistoryDepth is the number of recent main chain blocks tracked.
+	// Reorgs deeper than this are reported with a depth of HistoryDepth.
+	HistoryDepth uint32
+}
+
+// reconcileKey identifies the reconciliation of an affected transaction. A
+// batch funding transaction is reconciled once for each channel it funds.
+type reconcileKey struct {
+	txid      chainhash.Hash
+	chanPoint wire.OutPoint
+}
+
+// blockRef identifies a block of the main chain.
+type blockRef struct {
+	hash   chainhash.Hash
+	height uint32
+}
+
+// affectedTx is an AffectedTx along with the script used to wait for it to
+// confirm again.
+type affectedTx struct {
+	AffectedTx
+
+	pkScript []byte
+
+	// shortChanID is the short channel ID of the channel funded by the
+	// transaction. It is only set for the funding transactions of open
+	// channels.
+	shortChanID *lnwire.ShortChannelID
+}
+
+// ReorgNotifier is a subsystem which watches the main chain for reorgs. On
+// each reorg, it notifies its subscribers of the depth of the reorg and of
+// our funding, closing and sweep transactions that were disconnected, then
+// waits for these transactions to confirm again and reconciles the state that
+// depends on them.
+type ReorgNotifier struct {
+	started sync.Once
+	stopped sync.Once
+
+	cfg Config
+
+	ntfnServer *subscribe.Server
+
+	// chain holds the most recent blocks of the main chain, ordered by
+	// height.
+	chain []blockRef
+
+	// reconciling holds a channel for each affected transaction that is
+	// waiting to confirm again. It is closed to stop the reconciliation if
+	// a later reorg disconnects the transaction again before then.
+	reconciling  map[reconcileKey]chan struct{}
+	reconcileMtx sync.Mutex
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+// New creates a new reorg notifier with the given config.
+func New(cfg Config) *ReorgNotifier {
+	if cfg.HistoryDepth == 0 {
+		cfg.HistoryDepth = DefaultHistoryDepth
+	}
+
+	return &ReorgNotifier{
+		cfg:         cfg,
+		ntfnServer:  subscribe.NewServer(),
+		reconciling: make(map[reconcileKey]chan struct{}),
+		quit:        make(chan struct{}),
+	}
+}
+
+// Start starts the ReorgNotifier's subscription server and begins watching
+// the main chain.
+func (n *ReorgNotifier) Start() error {
+	var err error
+
+	n.started.Do(func() {
+		log.Info("ReorgNotifier starting")
+
+		if err = n.ntfnServer.Start(); err != nil {
+			return
+		}
+
+		var epochs *chainntnfs.BlockEpochEvent
+		epochs, err = n.cfg.Notifier.RegisterBlockEpochNtfn(nil)
+		if err != nil {
+			return
+		}
+
+		n.wg.Add(1)
+		go n.watchChain(epochs)
+	})
+
+	return err
+}
+
+// Stop signals the notifier for a graceful shutdown.
+func (n *ReorgNotifier) Stop() {
+	n.stopped.Do(func() {
+		log.Info("Stopping ReorgNotifier")
+
+		close(n.quit)
+		n.wg.Wait()
+		n.ntfnServer.Stop()
+	})
+}
+
+// SubscribeChainEvents returns a subscribe.Client that will receive a
+// ReorgEvent for each reorg and a ReconciledEvent for each affected
+// transaction that confirmed again, preceded by a ShortChanIDChangedEvent if
+// the transaction funded a channel whose short channel ID changed.
+func (n *ReorgNotifier) SubscribeChainEvents() (*subscribe.Client, error) {
+	return n.ntfnServer.Subscribe()
+}
+
+// watchChain processes the blocks connected to the main chain.
+//
+// NOTE: This MUST be run as a goroutine.
+func (n *ReorgNotifier) watchChain(epochs *chainntnfs.BlockEpochEvent) {
+	defer n.wg.Done()
+	defer epochs.Cancel()
+
+	for {
+		select {
+		case epoch, ok := <-epochs.Epochs:
+			if !ok {
+				return
+			}
+
+			if err := n.connectBlock(epoch); err != nil {
+				log.Errorf("Unable to process block %v: %v",
+					epoch.Hash, err)
+			}
+
+		case <-n.quit:
+			return
+		}
+	}
+}
+
+// appendBlock adds a block to the tip of the tracked chain, dropping the
+// oldest block if the history is full.
+func (n *ReorgNotifier) appendBlock(block blockRef) {
+	n.chain = append(n.chain, block)
+	if uint32(len(n.chain)) > n.cfg.HistoryDepth {
+		n.chain = n.chain[1:]
+	}
+}
+
+// findFork returns the index of the most recent tracked block that is still
+// part of the main chain, or -1 if none of them are.
+func (n *ReorgNotifier) findFork() (int, error) {
+	for i := len(n.chain) - 1; i >= 0; i-- {
+		hash, err := n.cfg.ChainIO.GetBlockHash(int64(n.chain[i].height))
+		if err != nil {
+			return 0, err
+		}
+
+		if *hash == n.chain[i].hash {
+			return i, nil
+		}
+	}
+
+	return -1, nil
+}
+
+// connectBlock processes a new block of the main chain, detecting whether it
+// replaced any of the tracked blocks.
+func (n *ReorgNotifier) connectBlock(epoch *chainntnfs.BlockEpoch) error {
+	newBlock := blockRef{
+		hash:   *epoch.Hash,
+		height: uint32(epoch.Height),
+	}
+
+	if len(n.chain) == 0 {
+		n.appendBlock(newBlock)
+		return nil
+	}
+
+	tip := n.chain[len(n.chain)-1]
+	if tip.hash == newBlock.hash {
+		return nil
+	}
+
+	fork, err := n.findFork()
+	if err != nil {
+		return err
+	}
+
+	// If our tip is still part of the main chain, the block either
+	// extends it or was already accounted for when handling a reorg.
+	if fork == len(n.chain)-1 {
+		if newBlock.height <= tip.height {
+			return nil
+		}
+
+		// Several blocks might have been connected with a single
+		// notification, so the ones in between are fetched and scanned
+		// as well.
+		for h := tip.height + 1; h < newBlock.height; h++ {
+			hash, err := n.cfg.ChainIO.GetBlockHash(int64(h))
+			if err != nil {
+				return err
+			}
+
+			block := blockRef{hash: *hash, height: h}
+			n.appendBlock(block)
+			if err := n.scanDeposits(block); err != nil {
+				return err
+			}
+		}
+
+		n.appendBlock(newBlock)
+		return n.scanDeposits(newBlock)
+	}
+
+	return n.handleReorg(fork, newBlock)
+}
+
+// handleReorg replaces the tracked blocks after the given fork index with the
+// new main chain, notifies subscribers of the reorg and starts reconciling the
+// affected transactions.
+func (n *ReorgNotifier) handleReorg(fork int, newBlock blockRef) error {
+	oldTip := n.chain[len(n.chain)-1]
+	disconnected := n.chain[fork+1:]
+	forkHeight := disconnected[0].height - 1
+
+	affected, err := n.affectedTxs(disconnected)
+	if err != nil {
+		return err
+	}
+
+	// Rebuild the tracked chain from the fork point. We use a full slice
+	// expression so the disconnected blocks aren't overwritten.
+	n.chain = n.chain[: fork+1 : fork+1]
+	for height := forkHeight + 1; height < newBlock.height; height++ {
+		hash, err := n.cfg.ChainIO.GetBlockHash(int64(height))
+		if err != nil {
+			return err
+		}
+
+		n.appendBlock(blockRef{hash: *hash, height: height})
+	}
+	n.appendBlock(newBlock)
+
+	event := ReorgEvent{
+		Depth:      uint32(len(disconnected)),
+		ForkHeight: forkHeight,
+		OldTip:     oldTip.hash,
+		NewTip:     newBlock.hash,
+		NewHeight:  newBlock.height,
+	}
+	for _, tx := range affected {
+		event.AffectedTxs = append(event.AffectedTxs, tx.AffectedTx)
+	}
+
+	log.Warnf("Chain reorg of depth %d detected (fork height %d, old "+
+		"tip %v, new tip %v), %d of our transactions were disconnected",
+		event.Depth, forkHeight, oldTip.hash, newBlock.hash,
+		len(affected))
+
+	if err := n.ntfnServer.SendUpdate(event); err != nil {
+		log.Warnf("Unable to send reorg update: %v", err)
+	}
+
+	for _, tx := range affected {
+		n.startReconcile(tx, forkHeight+1)
+	}
+
+	// Finally, the blocks of the new chain are scanned for deposits, as
+	// none of them were seen before.
+	for _, block := range n.chain[fork+1:] {
+		if err := n.scanDeposits(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// affectedTxs returns our funding, closing and sweep transactions included in
+// the given disconnected blocks.
+func (n *ReorgNotifier) affectedTxs(disconnected []blockRef) ([]affectedTx,
+	error) {
+
+	channels, err := n.cfg.FetchAllChannels()
+	if err != nil {
+		return nil, err
+	}
+	fundings := make(map[chainhash.Hash][]*channeldb.OpenChannel)
+	for _, channel := range channels {
+		op := channel.FundingOutpoint
+		fundings[op.Hash] = append(fundings[op.Hash], channel)
+	}
+
+	summaries, err := n.cfg.FetchClosedChannels(false)
+	if err != nil {
+		return nil, err
+	}
+	closes := make(map[chainhash.Hash]*channeldb.ChannelCloseSummary)
+	for _, summary := range summaries {
+		closes[summary.ClosingTXID] = summary
+	}
+
+	var affected []affectedTx
+	for _, ref := range disconnected {
+		// The backend keeps the blocks of side chains around, but we
+		// can't do much more than logging if it doesn't.
+		block, err := n.cfg.ChainIO.GetBlock(&ref.hash)
+		if err != nil {
+			log.Warnf("Unable to fetch disconnected block %v: %v",
+				ref.hash, err)
+			continue
+		}
+
+		txs := append(block.Transactions, block.STransactions...)
+		for _, tx := range txs {
+			txid := tx.TxHash()
+			if len(tx.TxOut) == 0 {
+				continue
+			}
+
+			for _, channel := range fundings[txid] {
+				chanPoint := channel.FundingOutpoint
+				if chanPoint.Index >= uint32(len(tx.TxOut)) {
+					continue
+				}
+
+				// Pending channels get their short channel ID
+				// once the funding transaction confirms.
+				var shortChanID *lnwire.ShortChannelID
+				if !channel.IsPending {
+					scid := channel.ShortChanID()
+					shortChanID = &scid
+				}
+
+				affected = append(affected, affectedTx{
+					AffectedTx: AffectedTx{
+						Txid:      txid,
+						Kind:      TxKindFunding,
+						ChanPoint: &chanPoint,
+						Height:    ref.height,
+					},
+					pkScript:    tx.TxOut[chanPoint.Index].PkScript,
+					shortChanID: shortChanID,
+				})
+			}
+
+			if summary, ok := closes[txid]; ok {
+				kind := TxKindForceClose
+				if summary.CloseType == channeldb.CooperativeClose {
+					kind = TxKindCooperativeClose
+				}
+
+				chanPoint := summary.ChanPoint
+				affected = append(affected, affectedTx{
+					AffectedTx: AffectedTx{
+						Txid:      txid,
+						Kind:      kind,
+						ChanPoint: &chanPoint,
+						Height:    ref.height,
+					},
+					pkScript: tx.TxOut[0].PkScript,
+				})
+				continue
+			}
+
+			if n.cfg.IsSweepTx == nil {
+				continue
+			}
+			isSweep, err := n.cfg.IsSweepTx(txid)
+			if err != nil {
+				return nil, err
+			}
+			if isSweep {
+				affected = append(affected, affectedTx{
+					AffectedTx: AffectedTx{
+						Txid:   txid,
+						Kind:   TxKindSweep,
+						Height: ref.height,
+					},
+					pkScript: tx.TxOut[0].PkScript,
+				})
+			}
+		}
+	}
+
+	return affected, nil
+}
+
+// startReconcile launches the reconciliation of an affected transaction,
+// stopping any earlier reconciliation of it so that a transaction disconnected
+// by several reorgs is only waited upon once.
+func (n *ReorgNotifier) startReconcile(tx affectedTx, heightHint uint32) {
+	key := reconcileKey{txid: tx.Txid}
+	if tx.ChanPoint != nil {
+		key.chanPoint = *tx.ChanPoint
+	}
+
+	n.reconcileMtx.Lock()
+	defer n.reconcileMtx.Unlock()
+
+	if cancel, ok := n.reconciling[key]; ok {
+		close(cancel)
+	}
+	cancel := make(chan struct{})
+	n.reconciling[key] = cancel
+
+	n.wg.Add(1)
+	go func() {
+		defer n.wg.Done()
+
+		n.reconcile(tx, heightHint, cancel)
+
+		n.reconcileMtx.Lock()
+		if n.reconciling[key] == cancel {
+			delete(n.reconciling, key)
+		}
+		n.reconcileMtx.Unlock()
+	}()
+}
+
+// reconcile waits for an affected transaction to confirm on the new main
+// chain, updates the state that depends on the position it confirmed at and
+// notifies subscribers. Transactions that never confirm again, e.g. because
+// they were double spent, are waited upon until shutdown or until the given
+// cancel channel is closed.
+func (n *ReorgNotifier) reconcile(tx affectedTx, heightHint uint32,
+	cancel <-chan struct{}) {
+
+	confEvent, err := n.cfg.Notifier.RegisterConfirmationsNtfn(
+		&tx.Txid, tx.pkScript, 1, heightHint,
+	)
+	if err != nil {
+		log.Errorf("Unable to register for confirmation of %v "+
+			"transaction %v: %v", tx.Kind, tx.Txid, err)
+		return
+	}
+	defer confEvent.Cancel()
+
+	var conf *chainntnfs.TxConfirmation
+	select {
+	case c, ok := <-confEvent.Confirmed:
+		if !ok {
+			return
+		}
+		conf = c
+
+	case <-cancel:
+		return
+
+	case <-n.quit:
+		return
+	}
+
+	switch tx.Kind {
+	case TxKindCooperativeClose, TxKindForceClose:
+		if conf.BlockHeight == tx.Height {
+			break
+		}
+
+		err := n.cfg.UpdateCloseHeight(tx.ChanPoint, conf.BlockHeight)
+		if err != nil {
+			log.Errorf("Unable to update close height of "+
+				"ChannelPoint(%v): %v", tx.ChanPoint, err)
+			return
+		}
+
+	case TxKindFunding:
+		if tx.shortChanID == nil {
+			break
+		}
+
+		// The transaction may have confirmed at the same height but
+		// at a different index, which changes the short channel ID
+		// just as well.
+		newShortChanID := lnwire.ShortChannelID{
+			BlockHeight: conf.BlockHeight,
+			TxIndex:     conf.TxIndex,
+			TxPosition:  uint16(tx.ChanPoint.Index),
+		}
+		if newShortChanID == *tx.shortChanID {
+			break
+		}
+
+		log.Warnf("Funding transaction of ChannelPoint(%v) confirmed "+
+			"again as %v instead of %v, its short channel ID is "+
+			"stale", tx.ChanPoint, newShortChanID, *tx.shortChanID)
+
+		// Subscribers are notified of the change even if it can't be
+		// stored, as the channel needs their attention either way.
+		if n.cfg.UpdateShortChanID != nil {
+			err := n.cfg.UpdateShortChanID(
+				tx.ChanPoint, newShortChanID,
+			)
+			if err != nil {
+				log.Errorf("Unable to update short channel "+
+					"ID of ChannelPoint(%v): %v",
+					tx.ChanPoint, err)
+			}
+		}
+
+		event := ShortChanIDChangedEvent{
+			ChanPoint:      *tx.ChanPoint,
+			OldShortChanID: *tx.shortChanID,
+			NewShortChanID: newShortChanID,
+		}
+		if err := n.ntfnServer.SendUpdate(event); err != nil {
+			log.Warnf("Unable to send short channel ID update: %v",
+				err)
+		}
+	}
+
+	log.Infof("Reconciled %v transaction %v, confirmed again at height %d",
+		tx.Kind, tx.Txid, conf.BlockHeight)
+
+	event := ReconciledEvent{
+		AffectedTx: tx.AffectedTx,
+		NewHeight:  conf.BlockHeight,
+		NewBlock:   *conf.BlockHash,
+	}
+	if err := n.ntfnServer.SendUpdate(event); err != nil {
+		log.Warnf("Unable to send reconciled update: %v", err)
+	}
+}
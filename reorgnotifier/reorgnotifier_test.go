@@ -0,0 +1,610 @@
+package reorgnotifier
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chainntnfs"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lntest/wait"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/subscribe"
+)
+
+const testTimeout = 5 * time.Second
+
+type mockNotifier struct {
+	chainntnfs.ChainNotifier
+
+	epochs chan *chainntnfs.BlockEpoch
+
+	mtx     sync.Mutex
+	confs   map[chainhash.Hash]chan *chainntnfs.TxConfirmation
+	cancels map[chainhash.Hash]int
+}
+
+func (m *mockNotifier) RegisterBlockEpochNtfn(*chainntnfs.BlockEpoch) (
+	*chainntnfs.BlockEpochEvent, error) {
+
+	return &chainntnfs.BlockEpochEvent{
+		Epochs: m.epochs,
+		Cancel: func() {},
+	}, nil
+}
+
+func (m *mockNotifier) RegisterConfirmationsNtfn(txid *chainhash.Hash,
+	_ []byte, numConfs, _ uint32) (*chainntnfs.ConfirmationEvent, error) {
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	confChan := make(chan *chainntnfs.TxConfirmation, 1)
+	m.confs[*txid] = confChan
+
+	txHash := *txid
+	return &chainntnfs.ConfirmationEvent{
+		Confirmed: confChan,
+		Cancel: func() {
+			m.mtx.Lock()
+			m.cancels[txHash]++
+			m.mtx.Unlock()
+		},
+	}, nil
+}
+
+// numCancels returns the number of confirmation registrations of the given
+// transaction that were canceled.
+func (m *mockNotifier) numCancels(txid chainhash.Hash) int {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	return m.cancels[txid]
+}
+
+func (m *mockNotifier) confirm(t *testing.T, txid chainhash.Hash,
+	conf *chainntnfs.TxConfirmation) {
+
+	t.Helper()
+
+	deadline := time.Now().Add(testTimeout)
+	for time.Now().Before(deadline) {
+		m.mtx.Lock()
+		confChan, ok := m.confs[txid]
+		m.mtx.Unlock()
+		if ok {
+			confChan <- conf
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	t.Fatalf("no confirmation registered for %v", txid)
+}
+
+type mockChainIO struct {
+	lnwallet.BlockChainIO
+
+	mtx       sync.Mutex
+	mainChain map[int64]chainhash.Hash
+	blocks    map[chainhash.Hash]*wire.MsgBlock
+}
+
+func (m *mockChainIO) GetBlockHash(height int64) (*chainhash.Hash, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	hash, ok := m.mainChain[height]
+	if !ok {
+		return nil, fmt.Errorf("no block at height %d", height)
+	}
+
+	return &hash, nil
+}
+
+func (m *mockChainIO) GetBlock(hash *chainhash.Hash) (*wire.MsgBlock, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	block, ok := m.blocks[*hash]
+	if !ok {
+		return nil, fmt.Errorf("unknown block %v", hash)
+	}
+
+	return block, nil
+}
+
+// addBlock makes the given block the main chain block at the given height.
+func (m *mockChainIO) addBlock(height uint32, nonce uint32,
+	txs ...*wire.MsgTx) *chainntnfs.BlockEpoch {
+
+	block := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Height: height,
+			Nonce:  nonce,
+		},
+		Transactions: txs,
+	}
+	hash := block.BlockHash()
+
+	m.mtx.Lock()
+	m.mainChain[int64(height)] = hash
+	m.blocks[hash] = block
+	m.mtx.Unlock()
+
+	return &chainntnfs.BlockEpoch{
+		Hash:   &hash,
+		Height: int32(height),
+	}
+}
+
+func newTestTx(value int64) *wire.MsgTx {
+	tx := wire.NewMsgTx()
+	tx.AddTxOut(wire.NewTxOut(value, []byte{0x51}))
+	return tx
+}
+
+func waitForEvent(t *testing.T, client *subscribe.Client) interface{} {
+	t.Helper()
+
+	select {
+	case event := <-client.Updates():
+		return event
+
+	case <-time.After(testTimeout):
+		t.Fatalf("no event received")
+		return nil
+	}
+}
+
+// TestReorgNotifier asserts that reorgs are detected along with our
+// transactions they disconnected, and that these transactions are reconciled
+// once they confirm again.
+func TestReorgNotifier(t *testing.T) {
+	t.Parallel()
+
+	fundingTx := newTestTx(1)
+	closeTx := newTestTx(2)
+	sweepTx := newTestTx(3)
+	otherTx := newTestTx(4)
+
+	fundingPoint := wire.OutPoint{Hash: fundingTx.TxHash()}
+	fundingChanID := lnwire.ShortChannelID{BlockHeight: 102}
+	closedPoint := wire.OutPoint{Hash: chainhash.Hash{1}}
+
+	notifier := &mockNotifier{
+		epochs:  make(chan *chainntnfs.BlockEpoch),
+		confs:   make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+		cancels: make(map[chainhash.Hash]int),
+	}
+	chainIO := &mockChainIO{
+		mainChain: make(map[int64]chainhash.Hash),
+		blocks:    make(map[chainhash.Hash]*wire.MsgBlock),
+	}
+
+	closeHeights := make(chan uint32, 1)
+	shortChanIDs := make(chan lnwire.ShortChannelID, 1)
+	n := New(Config{
+		Notifier: notifier,
+		ChainIO:  chainIO,
+		FetchAllChannels: func() ([]*channeldb.OpenChannel, error) {
+			return []*channeldb.OpenChannel{{
+				FundingOutpoint: fundingPoint,
+				ShortChannelID:  fundingChanID,
+			}}, nil
+		},
+		FetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return []*channeldb.ChannelCloseSummary{{
+				ChanPoint:   closedPoint,
+				ClosingTXID: closeTx.TxHash(),
+				CloseType:   channeldb.CooperativeClose,
+			}}, nil
+		},
+		IsSweepTx: func(txid chainhash.Hash) (bool, error) {
+			return txid == sweepTx.TxHash(), nil
+		},
+		UpdateCloseHeight: func(chanPoint *wire.OutPoint,
+			height uint32) error {
+
+			if *chanPoint != closedPoint {
+				t.Errorf("unexpected channel point %v", chanPoint)
+			}
+			closeHeights <- height
+			return nil
+		},
+		UpdateShortChanID: func(chanPoint *wire.OutPoint,
+			shortChanID lnwire.ShortChannelID) error {
+
+			if *chanPoint != fundingPoint {
+				t.Errorf("unexpected channel point %v", chanPoint)
+			}
+			shortChanIDs <- shortChanID
+			return nil
+		},
+	})
+	if err := n.Start(); err != nil {
+		t.Fatalf("unable to start notifier: %v", err)
+	}
+	defer n.Stop()
+
+	client, err := n.SubscribeChainEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	// Build the initial chain, with our transactions confirmed at height
+	// 102.
+	notifier.epochs <- chainIO.addBlock(100, 0)
+	notifier.epochs <- chainIO.addBlock(101, 0)
+	notifier.epochs <- chainIO.addBlock(
+		102, 0, fundingTx, closeTx, sweepTx, otherTx,
+	)
+	oldTip := chainIO.addBlock(103, 0)
+	notifier.epochs <- oldTip
+
+	// Notifying the tip again only returns once the notifier is done
+	// processing it, so the chain isn't replaced while it still is.
+	notifier.epochs <- oldTip
+
+	// Now replace the last two blocks with a competing chain.
+	newTip := chainIO.addBlock(102, 1)
+	chainIO.addBlock(103, 1)
+	notifier.epochs <- newTip
+
+	event := waitForEvent(t, client)
+	reorg, ok := event.(ReorgEvent)
+	if !ok {
+		t.Fatalf("expected ReorgEvent, got %T", event)
+	}
+	if reorg.Depth != 2 || reorg.ForkHeight != 101 ||
+		reorg.OldTip != *oldTip.Hash || reorg.NewTip != *newTip.Hash ||
+		reorg.NewHeight != 102 {
+
+		t.Fatalf("unexpected reorg event: %v", reorg)
+	}
+
+	expected := map[chainhash.Hash]TxKind{
+		fundingTx.TxHash(): TxKindFunding,
+		closeTx.TxHash():   TxKindCooperativeClose,
+		sweepTx.TxHash():   TxKindSweep,
+	}
+	if len(reorg.AffectedTxs) != len(expected) {
+		t.Fatalf("expected %d affected txs, got %d", len(expected),
+			len(reorg.AffectedTxs))
+	}
+	for _, tx := range reorg.AffectedTxs {
+		if kind, ok := expected[tx.Txid]; !ok || kind != tx.Kind {
+			t.Fatalf("unexpected affected tx: %v", tx)
+		}
+		if tx.Height != 102 {
+			t.Fatalf("unexpected height for %v: %d", tx.Txid,
+				tx.Height)
+		}
+	}
+
+	// Once the closing transaction confirms again, its close height
+	// should be updated and subscribers notified.
+	notifier.confirm(t, closeTx.TxHash(), &chainntnfs.TxConfirmation{
+		BlockHash:   &chainhash.Hash{2},
+		BlockHeight: 103,
+	})
+
+	select {
+	case height := <-closeHeights:
+		if height != 103 {
+			t.Fatalf("expected close height 103, got %d", height)
+		}
+
+	case <-time.After(testTimeout):
+		t.Fatalf("close height not updated")
+	}
+
+	event = waitForEvent(t, client)
+	reconciled, ok := event.(ReconciledEvent)
+	if !ok {
+		t.Fatalf("expected ReconciledEvent, got %T", event)
+	}
+	if reconciled.Txid != closeTx.TxHash() ||
+		reconciled.NewHeight != 103 ||
+		reconciled.NewBlock != (chainhash.Hash{2}) {
+
+		t.Fatalf("unexpected reconciled event: %v", reconciled)
+	}
+
+	// The funding transaction confirming again at a different position
+	// must update the short channel ID of the channel and be reported.
+	notifier.confirm(t, fundingTx.TxHash(), &chainntnfs.TxConfirmation{
+		BlockHash:   &chainhash.Hash{3},
+		BlockHeight: 102,
+		TxIndex:     1,
+	})
+
+	expectedChanID := lnwire.ShortChannelID{BlockHeight: 102, TxIndex: 1}
+	select {
+	case shortChanID := <-shortChanIDs:
+		if shortChanID != expectedChanID {
+			t.Fatalf("expected short channel ID %v, got %v",
+				expectedChanID, shortChanID)
+		}
+
+	case <-time.After(testTimeout):
+		t.Fatalf("short channel ID not updated")
+	}
+
+	event = waitForEvent(t, client)
+	changed, ok := event.(ShortChanIDChangedEvent)
+	if !ok {
+		t.Fatalf("expected ShortChanIDChangedEvent, got %T", event)
+	}
+	if changed.ChanPoint != fundingPoint ||
+		changed.OldShortChanID != fundingChanID ||
+		changed.NewShortChanID != expectedChanID {
+
+		t.Fatalf("unexpected short channel ID event: %v", changed)
+	}
+
+	event = waitForEvent(t, client)
+	reconciled, ok = event.(ReconciledEvent)
+	if !ok {
+		t.Fatalf("expected ReconciledEvent, got %T", event)
+	}
+	if reconciled.Txid != fundingTx.TxHash() {
+		t.Fatalf("unexpected reconciled event: %v", reconciled)
+	}
+
+	// The sweep confirming again doesn't change any short channel ID.
+	notifier.confirm(t, sweepTx.TxHash(), &chainntnfs.TxConfirmation{
+		BlockHash:   &chainhash.Hash{3},
+		BlockHeight: 102,
+		TxIndex:     2,
+	})
+
+	event = waitForEvent(t, client)
+	if _, ok := event.(ReconciledEvent); !ok {
+		t.Fatalf("expected ReconciledEvent, got %T", event)
+	}
+}
+
+// TestReorgNotifierDeposits asserts that transactions paying to our channel
+// scripts are reported as deposits, unless they're our own channel
+// transactions.
+func TestReorgNotifierDeposits(t *testing.T) {
+	t.Parallel()
+
+	fundingScript := []byte{0xa9, 0x01}
+	closingScript := []byte{0xa9, 0x02}
+
+	fundingTx := wire.NewMsgTx()
+	fundingTx.AddTxOut(wire.NewTxOut(1000, fundingScript))
+	chanPoint := wire.OutPoint{Hash: fundingTx.TxHash()}
+
+	closeTx := wire.NewMsgTx()
+	closeTx.AddTxIn(wire.NewTxIn(&chanPoint, 1000, nil))
+	closeTx.AddTxOut(wire.NewTxOut(900, closingScript))
+
+	depositTx := wire.NewMsgTx()
+	depositTx.AddTxOut(wire.NewTxOut(5, []byte{0x51}))
+	depositTx.AddTxOut(wire.NewTxOut(10, closingScript))
+
+	notifier := &mockNotifier{
+		epochs:  make(chan *chainntnfs.BlockEpoch),
+		confs:   make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+		cancels: make(map[chainhash.Hash]int),
+	}
+	chainIO := &mockChainIO{
+		mainChain: make(map[int64]chainhash.Hash),
+		blocks:    make(map[chainhash.Hash]*wire.MsgBlock),
+	}
+
+	n := New(Config{
+		Notifier: notifier,
+		ChainIO:  chainIO,
+		FetchChannelScripts: func() (*ChannelScripts, error) {
+			scripts := NewChannelScripts()
+			scripts.AddChannel(chanPoint)
+			scripts.AddScript(fundingScript, chanPoint, DepositFunding)
+			scripts.AddScript(closingScript, chanPoint, DepositClosing)
+			return scripts, nil
+		},
+	})
+	if err := n.Start(); err != nil {
+		t.Fatalf("unable to start notifier: %v", err)
+	}
+	defer n.Stop()
+
+	client, err := n.SubscribeChainEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	// Our own funding and closing transactions pay to the channel scripts
+	// by design, so only the deposit should be reported.
+	notifier.epochs <- chainIO.addBlock(100, 0)
+	notifier.epochs <- chainIO.addBlock(101, 0, fundingTx, closeTx)
+	block := chainIO.addBlock(102, 0, depositTx)
+	notifier.epochs <- block
+
+	event := waitForEvent(t, client)
+	deposit, ok := event.(DepositEvent)
+	if !ok {
+		t.Fatalf("expected DepositEvent, got %T", event)
+	}
+	if deposit.Txid != depositTx.TxHash() || deposit.Index != 1 ||
+		deposit.Amount != 10 || deposit.ChanPoint != chanPoint ||
+		deposit.Kind != DepositClosing || deposit.Height != 102 ||
+		deposit.Block != *block.Hash {
+
+		t.Fatalf("unexpected deposit event: %v", deposit)
+	}
+
+	select {
+	case event := <-client.Updates():
+		t.Fatalf("unexpected event: %v", event)
+
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestReorgNotifierDepositsSkippedBlocks asserts that deposits made by blocks
+// that weren't notified one by one are reported as well.
+func TestReorgNotifierDepositsSkippedBlocks(t *testing.T) {
+	t.Parallel()
+
+	fundingScript := []byte{0xa9, 0x01}
+	chanPoint := wire.OutPoint{Index: 1}
+
+	depositTx := wire.NewMsgTx()
+	depositTx.AddTxOut(wire.NewTxOut(10, fundingScript))
+
+	notifier := &mockNotifier{
+		epochs:  make(chan *chainntnfs.BlockEpoch),
+		confs:   make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+		cancels: make(map[chainhash.Hash]int),
+	}
+	chainIO := &mockChainIO{
+		mainChain: make(map[int64]chainhash.Hash),
+		blocks:    make(map[chainhash.Hash]*wire.MsgBlock),
+	}
+
+	n := New(Config{
+		Notifier: notifier,
+		ChainIO:  chainIO,
+		FetchChannelScripts: func() (*ChannelScripts, error) {
+			scripts := NewChannelScripts()
+			scripts.AddChannel(chanPoint)
+			scripts.AddScript(fundingScript, chanPoint, DepositFunding)
+			return scripts, nil
+		},
+	})
+	if err := n.Start(); err != nil {
+		t.Fatalf("unable to start notifier: %v", err)
+	}
+	defer n.Stop()
+
+	client, err := n.SubscribeChainEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	// The block with the deposit is connected along with the next one, but
+	// only the latter is notified.
+	notifier.epochs <- chainIO.addBlock(100, 0)
+	skipped := chainIO.addBlock(101, 0, depositTx)
+	notifier.epochs <- chainIO.addBlock(102, 0)
+
+	event := waitForEvent(t, client)
+	deposit, ok := event.(DepositEvent)
+	if !ok {
+		t.Fatalf("expected DepositEvent, got %T", event)
+	}
+	if deposit.Txid != depositTx.TxHash() || deposit.Height != 101 ||
+		deposit.Block != *skipped.Hash || deposit.Kind != DepositFunding {
+
+		t.Fatalf("unexpected deposit event: %v", deposit)
+	}
+
+	select {
+	case event := <-client.Updates():
+		t.Fatalf("unexpected event: %v", event)
+
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// TestReorgNotifierRepeatedReorg asserts that a transaction disconnected by a
+// reorg while still waiting to confirm again after an earlier one is only
+// waited upon once.
+func TestReorgNotifierRepeatedReorg(t *testing.T) {
+	t.Parallel()
+
+	sweepTx := newTestTx(1)
+
+	notifier := &mockNotifier{
+		epochs:  make(chan *chainntnfs.BlockEpoch),
+		confs:   make(map[chainhash.Hash]chan *chainntnfs.TxConfirmation),
+		cancels: make(map[chainhash.Hash]int),
+	}
+	chainIO := &mockChainIO{
+		mainChain: make(map[int64]chainhash.Hash),
+		blocks:    make(map[chainhash.Hash]*wire.MsgBlock),
+	}
+
+	n := New(Config{
+		Notifier: notifier,
+		ChainIO:  chainIO,
+		FetchAllChannels: func() ([]*channeldb.OpenChannel, error) {
+			return nil, nil
+		},
+		FetchClosedChannels: func(bool) ([]*channeldb.ChannelCloseSummary,
+			error) {
+
+			return nil, nil
+		},
+		IsSweepTx: func(txid chainhash.Hash) (bool, error) {
+			return txid == sweepTx.TxHash(), nil
+		},
+	})
+	if err := n.Start(); err != nil {
+		t.Fatalf("unable to start notifier: %v", err)
+	}
+	defer n.Stop()
+
+	client, err := n.SubscribeChainEvents()
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+	defer client.Cancel()
+
+	// The sweep is disconnected by a first reorg, confirms again in the
+	// new chain and is disconnected again by a second reorg.
+	notifier.epochs <- chainIO.addBlock(100, 0)
+	notifier.epochs <- chainIO.addBlock(101, 0, sweepTx)
+	notifier.epochs <- chainIO.addBlock(101, 1, sweepTx)
+	if _, ok := waitForEvent(t, client).(ReorgEvent); !ok {
+		t.Fatalf("expected ReorgEvent")
+	}
+
+	notifier.epochs <- chainIO.addBlock(101, 2)
+	if _, ok := waitForEvent(t, client).(ReorgEvent); !ok {
+		t.Fatalf("expected ReorgEvent")
+	}
+
+	// The reconciliation started by the first reorg must stop, canceling
+	// its confirmation registration.
+	err = wait.Predicate(func() bool {
+		return notifier.numCancels(sweepTx.TxHash()) == 1
+	}, testTimeout)
+	if err != nil {
+		t.Fatalf("first reconciliation not stopped")
+	}
+
+	notifier.confirm(t, sweepTx.TxHash(), &chainntnfs.TxConfirmation{
+		BlockHash:   &chainhash.Hash{1},
+		BlockHeight: 102,
+	})
+
+	event := waitForEvent(t, client)
+	reconciled, ok := event.(ReconciledEvent)
+	if !ok {
+		t.Fatalf("expected ReconciledEvent, got %T", event)
+	}
+	if reconciled.Txid != sweepTx.TxHash() || reconciled.NewHeight != 102 {
+		t.Fatalf("unexpected reconciled event: %v", reconciled)
+	}
+
+	select {
+	case event := <-client.Updates():
+		t.Fatalf("unexpected event: %v", event)
+
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,210 @@
+package routerstate
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chanbackup"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// BundleVersion denotes the version of the router state bundle format.
+type BundleVersion byte
+
+const (
+	// DefaultBundleVersion is the initial version of the router state
+	// bundle. It holds the mission control history, the forwarding
+	// policies of the channels and the addresses of the channel peers.
+	DefaultBundleVersion BundleVersion = 0
+
+	// maxMissionControlSize is the maximum size of the serialized mission
+	// control history we'll accept within a bundle.
+	maxMissionControlSize = 1 << 30
+)
+
+// ChannelPolicy is the forwarding policy a node applies to one of its
+// channels.
+type ChannelPolicy struct {
+	// ChanPoint is the funding outpoint of the channel.
+	ChanPoint wire.OutPoint
+
+	// BaseFee is the base fee charged for forwarding HTLCs over the
+	// channel.
+	BaseFee lnwire.MilliAtom
+
+	// FeeRate is the proportional fee charged for forwarding HTLCs over
+	// the channel, in millionths.
+	FeeRate uint32
+
+	// TimeLockDelta is the timelock delta required for forwarding HTLCs
+	// over the channel.
+	TimeLockDelta uint16
+
+	// MinHTLC is the smallest HTLC forwarded over the channel.
+	MinHTLC lnwire.MilliAtom
+
+	// MaxHTLC is the largest HTLC forwarded over the channel.
+	MaxHTLC lnwire.MilliAtom
+}
+
+// PeerAddresses is the set of addresses a peer was known to be reachable at.
+type PeerAddresses struct {
+	// PubKey is the identity public key of the peer.
+	PubKey *secp256k1.PublicKey
+
+	// Addresses is the set of addresses of the peer.
+	Addresses []net.Addr
+}
+
+// Bundle is the state a node gathered while routing payments, which can be
+// handed over to a node replacing it so the routing intelligence isn't lost.
+// It is distinct from the static channel backups, which allow recovering the
+// funds of the channels.
+type Bundle struct {
+	// Version is the version of the bundle.
+	Version BundleVersion
+
+	// MissionControl is the mission control history of the node, as
+	// exported by routing.MissionControl.ExportHistory.
+	MissionControl []byte
+
+	// Policies is the set of forwarding policies of the channels of the
+	// node.
+	Policies []ChannelPolicy
+
+	// Peers is the set of addresses of the channel peers of the node.
+	Peers []PeerAddresses
+}
+
+// Serialize writes the plaintext bundle to the passed io.Writer.
+func (b *Bundle) Serialize(w io.Writer) error {
+	switch b.Version {
+	case DefaultBundleVersion:
+	default:
+		return fmt.Errorf("unable to serialize w/ unknown "+
+			"version: %v", b.Version)
+	}
+
+	if err := lnwire.WriteElements(w, byte(b.Version)); err != nil {
+		return err
+	}
+
+	if err := wire.WriteVarBytes(w, 0, b.MissionControl); err != nil {
+		return err
+	}
+
+	err := lnwire.WriteElements(w, uint32(len(b.Policies)))
+	if err != nil {
+		return err
+	}
+	for _, policy := range b.Policies {
+		err := lnwire.WriteElements(
+			w, policy.ChanPoint, policy.BaseFee, policy.FeeRate,
+			policy.TimeLockDelta, policy.MinHTLC, policy.MaxHTLC,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = lnwire.WriteElements(w, uint32(len(b.Peers)))
+	if err != nil {
+		return err
+	}
+	for _, peer := range b.Peers {
+		err := lnwire.WriteElements(w, peer.PubKey, peer.Addresses)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Deserialize attempts to read the plaintext bundle from the passed
+// io.Reader.
+func (b *Bundle) Deserialize(r io.Reader) error {
+	var version byte
+	if err := lnwire.ReadElements(r, &version); err != nil {
+		return err
+	}
+
+	b.Version = BundleVersion(version)
+	switch b.Version {
+	case DefaultBundleVersion:
+	default:
+		return fmt.Errorf("unable to de-serialize w/ unknown "+
+			"version: %v", b.Version)
+	}
+
+	var err error
+	b.MissionControl, err = wire.ReadVarBytes(
+		r, 0, maxMissionControlSize, "mission control",
+	)
+	if err != nil {
+		return err
+	}
+
+	var numPolicies uint32
+	if err := lnwire.ReadElements(r, &numPolicies); err != nil {
+		return err
+	}
+	b.Policies = nil
+	for i := uint32(0); i < numPolicies; i++ {
+		var policy ChannelPolicy
+		err := lnwire.ReadElements(
+			r, &policy.ChanPoint, &policy.BaseFee, &policy.FeeRate,
+			&policy.TimeLockDelta, &policy.MinHTLC, &policy.MaxHTLC,
+		)
+		if err != nil {
+			return err
+		}
+
+		b.Policies = append(b.Policies, policy)
+	}
+
+	var numPeers uint32
+	if err := lnwire.ReadElements(r, &numPeers); err != nil {
+		return err
+	}
+	b.Peers = nil
+	for i := uint32(0); i < numPeers; i++ {
+		var peer PeerAddresses
+		err := lnwire.ReadElements(r, &peer.PubKey, &peer.Addresses)
+		if err != nil {
+			return err
+		}
+
+		b.Peers = append(b.Peers, peer)
+	}
+
+	return nil
+}
+
+// PackToWriter serializes the bundle and writes it, encrypted with the same
+// key used to encrypt the static channel backups, to the passed io.Writer.
+// Only a node restored from the same seed is able to unpack the bundle.
+func (b *Bundle) PackToWriter(w io.Writer, keyRing keychain.KeyRing) error {
+	var bundleBytes bytes.Buffer
+	if err := b.Serialize(&bundleBytes); err != nil {
+		return err
+	}
+
+	return chanbackup.EncryptPayloadToWriter(bundleBytes, w, keyRing)
+}
+
+// UnpackFromReader decrypts a bundle written by PackToWriter from the passed
+// io.Reader and deserializes it.
+func (b *Bundle) UnpackFromReader(r io.Reader, keyRing keychain.KeyRing) error {
+	plaintext, err := chanbackup.DecryptPayloadFromReader(r, keyRing)
+	if err != nil {
+		return err
+	}
+
+	return b.Deserialize(bytes.NewReader(plaintext))
+}
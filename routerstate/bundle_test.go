@@ -0,0 +1,90 @@
+package routerstate
+
+import (
+	"bytes"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/keychain"
+)
+
+var (
+	testPrivKey = secp256k1.PrivKeyFromBytes([]byte{0x01, 0x02, 0x03})
+
+	testAddr = &net.TCPAddr{
+		IP:   net.ParseIP("10.0.0.2").To4(),
+		Port: 9735,
+	}
+)
+
+// mockKeyRing is a key ring that always derives the same test key.
+type mockKeyRing struct{}
+
+func (m *mockKeyRing) DeriveNextKey(
+	keyFam keychain.KeyFamily) (keychain.KeyDescriptor, error) {
+
+	return keychain.KeyDescriptor{
+		PubKey: testPrivKey.PubKey(),
+	}, nil
+}
+
+func (m *mockKeyRing) DeriveKey(
+	keyLoc keychain.KeyLocator) (keychain.KeyDescriptor, error) {
+
+	return keychain.KeyDescriptor{
+		PubKey: testPrivKey.PubKey(),
+	}, nil
+}
+
+// TestBundlePackUnpack tests that a bundle can be packed and unpacked again
+// without losing any information.
+func TestBundlePackUnpack(t *testing.T) {
+	t.Parallel()
+
+	bundle := &Bundle{
+		Version:        DefaultBundleVersion,
+		MissionControl: []byte{0x01, 0x02, 0x03},
+		Policies: []ChannelPolicy{{
+			ChanPoint: wire.OutPoint{
+				Hash:  [32]byte{0x01},
+				Index: 1,
+			},
+			BaseFee:       1000,
+			FeeRate:       1,
+			TimeLockDelta: 80,
+			MinHTLC:       1,
+			MaxHTLC:       1000000,
+		}},
+		Peers: []PeerAddresses{{
+			PubKey:    testPrivKey.PubKey(),
+			Addresses: []net.Addr{testAddr},
+		}},
+	}
+
+	keyRing := &mockKeyRing{}
+
+	var b bytes.Buffer
+	if err := bundle.PackToWriter(&b, keyRing); err != nil {
+		t.Fatalf("unable to pack bundle: %v", err)
+	}
+
+	var unpacked Bundle
+	if err := unpacked.UnpackFromReader(&b, keyRing); err != nil {
+		t.Fatalf("unable to unpack bundle: %v", err)
+	}
+
+	if !reflect.DeepEqual(bundle, &unpacked) {
+		t.Fatalf("unpacked bundle doesn't match: expected %v, got %v",
+			spew.Sdump(bundle), spew.Sdump(&unpacked))
+	}
+
+	// A bundle with an unknown version can't be packed.
+	bundle.Version = 99
+	if err := bundle.PackToWriter(&b, keyRing); err == nil {
+		t.Fatalf("expected error packing bundle with unknown version")
+	}
+}
@@ -0,0 +1,89 @@
+package routing
+
+import (
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+const (
+	// DefaultFirstHopRetries is the default number of times a route is
+	// retried after a transient failure of its first hop. Retries are
+	// disabled by default.
+	DefaultFirstHopRetries = 0
+
+	// DefaultFirstHopRetryBackoff is the default time waited before the
+	// first retry of a route whose first hop failed transiently.
+	DefaultFirstHopRetryBackoff = 500 * time.Millisecond
+
+	// maxFirstHopRetryBackoff caps the time waited between two attempts
+	// along the same route.
+	maxFirstHopRetryBackoff = time.Minute
+)
+
+// FirstHopRetryPolicy describes how payment attempts that fail on our own
+// outgoing channel with a transient error, such as a temporary channel
+// failure or a peer that is briefly offline, are retried along the same route
+// before the failure is reported to mission control.
+type FirstHopRetryPolicy struct {
+	// MaxRetries is the maximum number of times a route is retried after
+	// a transient failure of its first hop. Zero disables the retries.
+	MaxRetries uint32
+
+	// Backoff is the time waited before the first retry of a route. The
+	// time waited doubles with every subsequent retry.
+	Backoff time.Duration
+}
+
+// backoff returns the time to wait before making the given retry, counting
+// from one.
+func (p *FirstHopRetryPolicy) backoff(retry uint32) time.Duration {
+	backoff := p.Backoff
+	for i := uint32(1); i < retry; i++ {
+		backoff *= 2
+		if backoff >= maxFirstHopRetryBackoff {
+			return maxFirstHopRetryBackoff
+		}
+	}
+
+	return backoff
+}
+
+// shouldRetry returns true if the given attempt failed with a transient
+// failure of its first hop and its route may be retried once more.
+func (p *FirstHopRetryPolicy) shouldRetry(attempt *channeldb.HTLCAttemptInfo,
+	sendErr error) bool {
+
+	if attempt.FirstHopRetry >= p.MaxRetries {
+		return false
+	}
+
+	return isTransientFirstHopFailure(sendErr)
+}
+
+// isTransientFirstHopFailure returns true if the given error is a failure of
+// our own outgoing link that is likely to go away on its own: the link may be
+// unavailable because the peer is briefly offline or the channel is
+// temporarily unable to carry the htlc.
+func isTransientFirstHopFailure(sendErr error) bool {
+	rtErr, ok := sendErr.(htlcswitch.ClearTextError)
+	if !ok {
+		return false
+	}
+
+	// Failures reported by other nodes along the route are wrapped in a
+	// forwarding error, they aren't about our first hop.
+	if _, ok := rtErr.(*htlcswitch.ForwardingError); ok {
+		return false
+	}
+
+	switch rtErr.WireMessage().(type) {
+	case *lnwire.FailTemporaryChannelFailure, *lnwire.FailUnknownNextPeer:
+		return true
+
+	default:
+		return false
+	}
+}
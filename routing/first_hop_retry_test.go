@@ -0,0 +1,147 @@
+package routing
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/htlcswitch"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestFirstHopRetryPolicy tests which failures are retried by the first hop
+// retry policy and how long is waited before each retry.
+func TestFirstHopRetryPolicy(t *testing.T) {
+	t.Parallel()
+
+	policy := &FirstHopRetryPolicy{
+		MaxRetries: 2,
+		Backoff:    time.Second,
+	}
+
+	tests := []struct {
+		name          string
+		firstHopRetry uint32
+		err           error
+		retry         bool
+	}{
+		{
+			name: "temporary channel failure",
+			err: htlcswitch.NewLinkError(
+				&lnwire.FailTemporaryChannelFailure{},
+			),
+			retry: true,
+		},
+		{
+			name: "unknown next peer",
+			err: htlcswitch.NewLinkError(
+				&lnwire.FailUnknownNextPeer{},
+			),
+			retry: true,
+		},
+		{
+			name:          "retries exhausted",
+			firstHopRetry: 2,
+			err: htlcswitch.NewLinkError(
+				&lnwire.FailUnknownNextPeer{},
+			),
+		},
+		{
+			name: "permanent local failure",
+			err: htlcswitch.NewLinkError(
+				&lnwire.FailPermanentChannelFailure{},
+			),
+		},
+		{
+			name: "failure of another hop",
+			err: htlcswitch.NewForwardingError(
+				&lnwire.FailTemporaryChannelFailure{}, 1,
+			),
+		},
+		{
+			name: "internal error",
+			err:  errors.New("internal error"),
+		},
+	}
+
+	for _, test := range tests {
+		attempt := &channeldb.HTLCAttemptInfo{
+			FirstHopRetry: test.firstHopRetry,
+		}
+		retry := policy.shouldRetry(attempt, test.err)
+		if retry != test.retry {
+			t.Fatalf("%v: expected retry %v, got %v", test.name,
+				test.retry, retry)
+		}
+	}
+
+	// The backoff doubles with every retry, up to its maximum.
+	expectedBackoffs := map[uint32]time.Duration{
+		1:  time.Second,
+		2:  2 * time.Second,
+		3:  4 * time.Second,
+		20: maxFirstHopRetryBackoff,
+	}
+	for retry, expected := range expectedBackoffs {
+		backoff := policy.backoff(retry)
+		if backoff != expected {
+			t.Fatalf("expected backoff %v for retry %v, got %v",
+				expected, retry, backoff)
+		}
+	}
+}
+
+// TestRetryFirstHopCanceled asserts that a payment canceled by the caller while
+// waiting to retry its first hop is failed as canceled rather than timed out.
+func TestRetryFirstHopCanceled(t *testing.T) {
+	t.Parallel()
+
+	paymentHash := lntypes.Hash{1}
+	control := makeMockControlTower()
+	err := control.InitPayment(paymentHash, &channeldb.PaymentCreationInfo{
+		PaymentHash: paymentHash,
+	})
+	if err != nil {
+		t.Fatalf("unable to init payment: %v", err)
+	}
+
+	router := &ChannelRouter{
+		cfg: &Config{
+			Control: control,
+			Clock:   clock.NewTestClock(time.Unix(1, 0)),
+			FirstHopRetry: FirstHopRetryPolicy{
+				MaxRetries: 1,
+				Backoff:    time.Hour,
+			},
+		},
+		quit: make(chan struct{}),
+	}
+
+	cancelChan := make(chan struct{})
+	close(cancelChan)
+	p := &paymentLifecycle{
+		router:      router,
+		paymentHash: paymentHash,
+		cancelChan:  cancelChan,
+	}
+
+	failed := &firstHopRetry{
+		attempt: &channeldb.HTLCAttemptInfo{},
+		err: htlcswitch.NewLinkError(
+			&lnwire.FailTemporaryChannelFailure{},
+		),
+	}
+	err = p.retryFirstHop(nil, failed, &paymentState{})
+	if err != nil {
+		t.Fatalf("unable to fail payment: %v", err)
+	}
+
+	reason, ok := control.failed[paymentHash]
+	if !ok || reason != channeldb.FailureReasonCanceled {
+		t.Fatalf("expected payment to be failed as canceled, got %v",
+			reason)
+	}
+}
@@ -41,6 +41,14 @@ type nodeWithDist struct {
 	// nextHop is the edge this route comes from.
 	nextHop *channeldb.ChannelEdgePolicy
 
+	// nextHopInboundFee is the inbound fee the next node charges for
+	// HTLCs received over nextHop.
+	nextHopInboundFee lnwire.InboundFee
+
+	// outboundFee is the fee this node charges for the outgoing channel,
+	// not including any inbound fee.
+	outboundFee lnwire.MilliAtom
+
 	// routingInfoSize is the total size requirement for the payloads field
 	// in the onion packet from this hop towards the final destination.
 	routingInfoSize uint64
@@ -9,6 +9,11 @@ import (
 // requests it.
 var log slog.Logger
 
+// The default amount of logging is none.
+func init() {
+	UseLogger(slog.Disabled)
+}
+
 // UseLogger uses a specified Logger to output package logging info. This
 // function is called from the parent package htlcswitch logger initialization.
 func UseLogger(logger slog.Logger) {
@@ -42,11 +42,93 @@ type Manager struct {
 	policyUpdateLock sync.Mutex
 }
 
+// UpdateFailure is the reason the policy of a local channel couldn't be
+// updated.
+type UpdateFailure uint8
+
+const (
+	// UpdateFailureUnknown is the zero value of UpdateFailure.
+	UpdateFailureUnknown UpdateFailure = iota
+
+	// UpdateFailurePending indicates that the channel is still pending
+	// confirmation, so it has no policy yet.
+	UpdateFailurePending
+
+	// UpdateFailureNotFound indicates that the channel isn't one of the
+	// node's channels.
+	UpdateFailureNotFound
+
+	// UpdateFailureInternalErr indicates that the channel parameters
+	// couldn't be retrieved or the new policy couldn't be encoded.
+	UpdateFailureInternalErr
+
+	// UpdateFailureInvalidParameter indicates that the new policy isn't
+	// valid for the channel, e.g. because of its htlc amount constraints.
+	UpdateFailureInvalidParameter
+)
+
+// String returns a human readable form of the update failure.
+func (f UpdateFailure) String() string {
+	switch f {
+	case UpdateFailurePending:
+		return "pending"
+
+	case UpdateFailureNotFound:
+		return "not found"
+
+	case UpdateFailureInternalErr:
+		return "internal error"
+
+	case UpdateFailureInvalidParameter:
+		return "invalid parameter"
+
+	default:
+		return "unknown"
+	}
+}
+
+// FailedUpdate describes a channel whose policy couldn't be updated.
+type FailedUpdate struct {
+	// ChanPoint is the outpoint of the channel.
+	ChanPoint wire.OutPoint
+
+	// Reason is the reason the update failed.
+	Reason UpdateFailure
+
+	// Err is the error the update failed with.
+	Err error
+}
+
+// PolicyUpdateResult is the outcome of updating the policy of local channels.
+type PolicyUpdateResult struct {
+	// Updated holds the edges of the channels whose policy was updated,
+	// along with their new policy. In dry run mode, these are the policies
+	// the channels would have.
+	Updated []discovery.EdgeWithInfo
+
+	// Failed holds the channels whose policy couldn't be updated, in the
+	// order they were encountered.
+	Failed []FailedUpdate
+}
+
 // UpdatePolicy updates the policy for the specified channels on disk and in the
-// active links.
+// active links. Channels whose policy can't be updated are skipped.
 func (r *Manager) UpdatePolicy(newSchema routing.ChannelPolicy,
 	chanPoints ...wire.OutPoint) error {
 
+	_, err := r.UpdatePolicies(newSchema, false, chanPoints...)
+	return err
+}
+
+// UpdatePolicies updates the policy for the specified channels, or all local
+// channels if none is specified, on disk and in the active links. Unlike
+// UpdatePolicy, it reports the resulting policies and the channels that
+// couldn't be updated, including specified channels that aren't open. If
+// dryRun is true, the resulting policies are computed but neither persisted,
+// broadcast nor applied to the links.
+func (r *Manager) UpdatePolicies(newSchema routing.ChannelPolicy, dryRun bool,
+	chanPoints ...wire.OutPoint) (*PolicyUpdateResult, error) {
+
 	r.policyUpdateLock.Lock()
 	defer r.policyUpdateLock.Unlock()
 
@@ -59,8 +141,9 @@ func (r *Manager) UpdatePolicy(newSchema routing.ChannelPolicy,
 
 	haveChanFilter := len(chansToUpdate) != 0
 
-	var edgesToUpdate []discovery.EdgeWithInfo
+	var result PolicyUpdateResult
 	policiesToUpdate := make(map[wire.OutPoint]htlcswitch.ForwardingPolicy)
+	seenChans := make(map[wire.OutPoint]struct{})
 
 	// Next, we'll loop over all the outgoing channels the router knows of.
 	// If we have a filter then we'll only collected those channels,
@@ -75,35 +158,98 @@ func (r *Manager) UpdatePolicy(newSchema routing.ChannelPolicy,
 		if !ok && haveChanFilter {
 			return nil
 		}
+		seenChans[info.ChannelPoint] = struct{}{}
+
+		// In dry run mode, the new policy is applied to a copy of the
+		// edge so the current one is left untouched.
+		if dryRun {
+			edgeCopy := *edge
+			edge = &edgeCopy
+		}
 
 		// Apply the new policy to the edge.
-		err := r.updateEdge(info.ChannelPoint, edge, newSchema)
+		reason, err := r.updateEdge(info.ChannelPoint, edge, newSchema)
 		if err != nil {
 			log.Warnf("Cannot update policy for %v: %v\n",
 				info.ChannelPoint, err,
 			)
+			result.Failed = append(result.Failed, FailedUpdate{
+				ChanPoint: info.ChannelPoint,
+				Reason:    reason,
+				Err:       err,
+			})
+			return nil
+		}
+
+		// The inbound fee may have been kept from the prior policy,
+		// so it's read back from the updated edge.
+		inboundFee, err := lnwire.ExtractInboundFee(
+			edge.ExtraOpaqueData,
+		)
+		if err != nil {
+			log.Warnf("Cannot parse inbound fee for %v: %v",
+				info.ChannelPoint, err)
+			result.Failed = append(result.Failed, FailedUpdate{
+				ChanPoint: info.ChannelPoint,
+				Reason:    UpdateFailureInternalErr,
+				Err:       err,
+			})
 			return nil
 		}
 
 		// Add updated edge to list of edges to send to gossiper.
-		edgesToUpdate = append(edgesToUpdate, discovery.EdgeWithInfo{
+		result.Updated = append(result.Updated, discovery.EdgeWithInfo{
 			Info: info,
 			Edge: edge,
 		})
 
 		// Add updated policy to list of policies to send to switch.
-		policiesToUpdate[info.ChannelPoint] = htlcswitch.ForwardingPolicy{
+		policy := htlcswitch.ForwardingPolicy{
 			BaseFee:       edge.FeeBaseMAtoms,
 			FeeRate:       edge.FeeProportionalMillionths,
 			TimeLockDelta: uint32(edge.TimeLockDelta),
 			MinHTLCOut:    edge.MinHTLC,
 			MaxHTLC:       edge.MaxHTLC,
 		}
+		if inboundFee != nil {
+			policy.InboundFee = *inboundFee
+		}
+		policiesToUpdate[info.ChannelPoint] = policy
 
 		return nil
 	})
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	// Report the specified channels that aren't among the outgoing
+	// channels, in the order they were specified.
+	for _, chanPoint := range chanPoints {
+		if _, ok := seenChans[chanPoint]; ok {
+			continue
+		}
+		seenChans[chanPoint] = struct{}{}
+
+		failure := FailedUpdate{
+			ChanPoint: chanPoint,
+			Reason:    UpdateFailureNotFound,
+			Err:       fmt.Errorf("channel %v not found", chanPoint),
+		}
+
+		// Channels pending confirmation are known to the database
+		// but have no edge yet.
+		channel, err := r.FetchChannel(chanPoint)
+		if err == nil && channel.IsPending {
+			failure.Reason = UpdateFailurePending
+			failure.Err = fmt.Errorf("channel %v is pending",
+				chanPoint)
+		}
+
+		result.Failed = append(result.Failed, failure)
+	}
+
+	if dryRun {
+		return &result, nil
 	}
 
 	// Commit the policy updates to disk and broadcast to the network. We
@@ -111,21 +257,22 @@ func (r *Manager) UpdatePolicy(newSchema routing.ChannelPolicy,
 	// this would happen because of a bug, the link policy will be
 	// desynchronized. It is currently not possible to atomically commit
 	// multiple edge updates.
-	err = r.PropagateChanPolicyUpdate(edgesToUpdate)
+	err = r.PropagateChanPolicyUpdate(result.Updated)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Update active links.
 	r.UpdateForwardingPolicies(policiesToUpdate)
 
-	return nil
+	return &result, nil
 }
 
-// updateEdge updates the given edge with the new schema.
+// updateEdge updates the given edge with the new schema. If the update fails,
+// the reason is returned along with the error.
 func (r *Manager) updateEdge(chanPoint wire.OutPoint,
 	edge *channeldb.ChannelEdgePolicy,
-	newSchema routing.ChannelPolicy) error {
+	newSchema routing.ChannelPolicy) (UpdateFailure, error) {
 
 	// Update forwarding fee scheme and required time lock delta.
 	edge.FeeBaseMAtoms = newSchema.BaseFee
@@ -134,10 +281,28 @@ func (r *Manager) updateEdge(chanPoint wire.OutPoint,
 	)
 	edge.TimeLockDelta = uint16(newSchema.TimeLockDelta)
 
+	// Update the inbound fee carried in the extra data of the channel
+	// update, if a new one was specified.
+	if newSchema.InboundFee != nil {
+		inboundFee := newSchema.InboundFee
+		if *inboundFee == (lnwire.InboundFee{}) {
+			inboundFee = nil
+		}
+
+		extraData, err := lnwire.SetInboundFee(
+			edge.ExtraOpaqueData, inboundFee,
+		)
+		if err != nil {
+			return UpdateFailureInternalErr, fmt.Errorf("unable "+
+				"to set inbound fee: %v", err)
+		}
+		edge.ExtraOpaqueData = extraData
+	}
+
 	// Retrieve negotiated channel htlc amt limits.
 	amtMin, amtMax, err := r.getHtlcAmtLimits(chanPoint)
 	if err != nil {
-		return nil
+		return UpdateFailureInternalErr, err
 	}
 
 	// We now update the edge max htlc value.
@@ -171,25 +336,25 @@ func (r *Manager) updateEdge(chanPoint wire.OutPoint,
 	// Validate htlc amount constraints.
 	switch {
 	case edge.MinHTLC < amtMin:
-		return fmt.Errorf("min htlc amount of %v msat is below "+
-			"min htlc parameter of %v msat for channel %v",
-			edge.MinHTLC, amtMin,
-			chanPoint)
+		return UpdateFailureInvalidParameter, fmt.Errorf("min htlc "+
+			"amount of %v msat is below min htlc parameter of %v "+
+			"msat for channel %v", edge.MinHTLC, amtMin, chanPoint)
 
 	case edge.MaxHTLC > amtMax:
-		return fmt.Errorf("max htlc size of %v msat is above "+
-			"max pending amount of %v msat for channel %v",
-			edge.MaxHTLC, amtMax, chanPoint)
+		return UpdateFailureInvalidParameter, fmt.Errorf("max htlc "+
+			"size of %v msat is above max pending amount of %v "+
+			"msat for channel %v", edge.MaxHTLC, amtMax, chanPoint)
 
 	case edge.MinHTLC > edge.MaxHTLC:
-		return fmt.Errorf("min_htlc %v greater than max_htlc %v",
-			edge.MinHTLC, edge.MaxHTLC)
+		return UpdateFailureInvalidParameter, fmt.Errorf("min_htlc "+
+			"%v greater than max_htlc %v", edge.MinHTLC,
+			edge.MaxHTLC)
 	}
 
 	// Clear signature to help prevent usage of the previous signature.
 	edge.SetSigBytes(nil)
 
-	return nil
+	return UpdateFailureUnknown, nil
 }
 
 // getHtlcAmtLimits retrieves the negotiated channel min and max htlc amount
@@ -39,6 +39,10 @@ func TestManager(t *testing.T) {
 		MessageFlags: lnwire.ChanUpdateOptionMaxHtlc,
 	}
 
+	// expectedInboundFee is the inbound fee the channel is expected to
+	// have after each update.
+	var expectedInboundFee lnwire.InboundFee
+
 	updateForwardingPolicies := func(
 		chanPolicies map[wire.OutPoint]htlcswitch.ForwardingPolicy) {
 
@@ -59,6 +63,9 @@ func TestManager(t *testing.T) {
 		if policy.MaxHTLC != newPolicy.MaxHTLC {
 			t.Fatal("unexpected max htlc")
 		}
+		if policy.InboundFee != expectedInboundFee {
+			t.Fatal("unexpected inbound fee")
+		}
 	}
 
 	propagateChanPolicyUpdate := func(
@@ -85,6 +92,21 @@ func TestManager(t *testing.T) {
 			t.Fatal("unexpected max htlc")
 		}
 
+		inboundFee, err := lnwire.ExtractInboundFee(
+			policy.ExtraOpaqueData,
+		)
+		if err != nil {
+			t.Fatalf("unable to extract inbound fee: %v", err)
+		}
+		switch {
+		case inboundFee == nil &&
+			expectedInboundFee != (lnwire.InboundFee{}):
+			t.Fatal("expected inbound fee")
+
+		case inboundFee != nil && *inboundFee != expectedInboundFee:
+			t.Fatal("unexpected inbound fee")
+		}
+
 		return nil
 	}
 
@@ -145,4 +167,207 @@ func TestManager(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// Setting an inbound fee adds it to the channel update and the link
+	// policy.
+	expectedInboundFee = lnwire.InboundFee{BaseFee: -100, FeeRate: -50}
+	inboundFeePolicy := newPolicy
+	inboundFeePolicy.InboundFee = &expectedInboundFee
+
+	err = manager.UpdatePolicy(inboundFeePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Updating the policy without an inbound fee keeps the current one.
+	err = manager.UpdatePolicy(newPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A zero inbound fee removes it.
+	inboundFeePolicy.InboundFee = &lnwire.InboundFee{}
+	expectedInboundFee = lnwire.InboundFee{}
+
+	err = manager.UpdatePolicy(inboundFeePolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(currentPolicy.ExtraOpaqueData) != 0 {
+		t.Fatal("expected inbound fee to be removed")
+	}
+}
+
+// TestManagerUpdatePolicies tests that the local channel manager reports the
+// channels whose policy couldn't be updated, and leaves policies untouched in
+// dry run mode.
+func TestManagerUpdatePolicies(t *testing.T) {
+	var (
+		openChanPoint    = wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}
+		invalidChanPoint = wire.OutPoint{Hash: chainhash.Hash{2}, Index: 0}
+		pendingChanPoint = wire.OutPoint{Hash: chainhash.Hash{3}, Index: 0}
+		unknownChanPoint = wire.OutPoint{Hash: chainhash.Hash{4}, Index: 0}
+		minHTLC          = lnwire.MilliAtom(2000)
+	)
+
+	newPolicy := routing.ChannelPolicy{
+		FeeSchema: routing.FeeSchema{
+			BaseFee: 100,
+			FeeRate: 200,
+		},
+		TimeLockDelta: 80,
+		MaxHTLC:       5000,
+	}
+
+	// The max htlc of the new policy exceeds the max pending amount of
+	// the invalid channel.
+	maxPendingAmounts := map[wire.OutPoint]lnwire.MilliAtom{
+		openChanPoint:    999000,
+		invalidChanPoint: 4000,
+	}
+	currentPolicies := map[wire.OutPoint]*channeldb.ChannelEdgePolicy{
+		openChanPoint: {
+			MinHTLC:      minHTLC,
+			MessageFlags: lnwire.ChanUpdateOptionMaxHtlc,
+		},
+		invalidChanPoint: {
+			MinHTLC:      minHTLC,
+			MessageFlags: lnwire.ChanUpdateOptionMaxHtlc,
+		},
+	}
+
+	var propagated, forwarded bool
+	manager := Manager{
+		UpdateForwardingPolicies: func(
+			map[wire.OutPoint]htlcswitch.ForwardingPolicy) {
+
+			forwarded = true
+		},
+		PropagateChanPolicyUpdate: func([]discovery.EdgeWithInfo) error {
+			propagated = true
+			return nil
+		},
+		ForAllOutgoingChannels: func(cb func(*channeldb.ChannelEdgeInfo,
+			*channeldb.ChannelEdgePolicy) error) error {
+
+			for _, chanPoint := range []wire.OutPoint{
+				openChanPoint, invalidChanPoint,
+			} {
+				err := cb(
+					&channeldb.ChannelEdgeInfo{
+						ChannelPoint: chanPoint,
+					},
+					currentPolicies[chanPoint],
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			return nil
+		},
+		FetchChannel: func(chanPoint wire.OutPoint) (
+			*channeldb.OpenChannel, error) {
+
+			if chanPoint == pendingChanPoint {
+				return &channeldb.OpenChannel{IsPending: true}, nil
+			}
+
+			maxPendingAmount, ok := maxPendingAmounts[chanPoint]
+			if !ok {
+				return nil, channeldb.ErrChannelNotFound
+			}
+
+			constraints := channeldb.ChannelConstraints{
+				MaxPendingAmount: maxPendingAmount,
+				MinHTLC:          minHTLC,
+			}
+
+			return &channeldb.OpenChannel{
+				LocalChanCfg: channeldb.ChannelConfig{
+					ChannelConstraints: constraints,
+				},
+			}, nil
+		},
+	}
+
+	chanPoints := []wire.OutPoint{
+		unknownChanPoint, openChanPoint, pendingChanPoint,
+		invalidChanPoint,
+	}
+
+	assertResult := func(result *PolicyUpdateResult) {
+		t.Helper()
+
+		if len(result.Updated) != 1 {
+			t.Fatalf("expected 1 updated edge, got %v",
+				len(result.Updated))
+		}
+		updated := result.Updated[0]
+		if updated.Info.ChannelPoint != openChanPoint {
+			t.Fatalf("unexpected updated channel %v",
+				updated.Info.ChannelPoint)
+		}
+		if updated.Edge.FeeBaseMAtoms != newPolicy.BaseFee {
+			t.Fatal("unexpected base fee")
+		}
+
+		expectedFailures := []struct {
+			chanPoint wire.OutPoint
+			reason    UpdateFailure
+		}{
+			{invalidChanPoint, UpdateFailureInvalidParameter},
+			{unknownChanPoint, UpdateFailureNotFound},
+			{pendingChanPoint, UpdateFailurePending},
+		}
+		if len(result.Failed) != len(expectedFailures) {
+			t.Fatalf("expected %v failures, got %v",
+				len(expectedFailures), len(result.Failed))
+		}
+		for i, expected := range expectedFailures {
+			failure := result.Failed[i]
+			if failure.ChanPoint != expected.chanPoint ||
+				failure.Reason != expected.reason {
+
+				t.Fatalf("expected failure %v for %v, got %v "+
+					"for %v", expected.reason,
+					expected.chanPoint, failure.Reason,
+					failure.ChanPoint)
+			}
+			if failure.Err == nil {
+				t.Fatalf("expected error for %v",
+					failure.ChanPoint)
+			}
+		}
+	}
+
+	// A dry run reports the resulting policies and failures without
+	// touching the current policies.
+	result, err := manager.UpdatePolicies(newPolicy, true, chanPoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(result)
+
+	if propagated || forwarded {
+		t.Fatal("dry run must not propagate policies")
+	}
+	if currentPolicies[openChanPoint].FeeBaseMAtoms != 0 {
+		t.Fatal("dry run must not modify the current policy")
+	}
+
+	// Applying the update reports the same outcome, and updates the valid
+	// channel only.
+	result, err = manager.UpdatePolicies(newPolicy, false, chanPoints...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(result)
+
+	if !propagated || !forwarded {
+		t.Fatal("expected policies to be propagated")
+	}
+	if currentPolicies[openChanPoint].FeeBaseMAtoms != newPolicy.BaseFee {
+		t.Fatal("expected the current policy to be updated")
+	}
 }
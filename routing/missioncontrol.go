@@ -1,6 +1,9 @@
 package routing
 
 import (
+	"fmt"
+	"io"
+	"math"
 	"sync"
 	"time"
 
@@ -127,6 +130,49 @@ type MissionControlConfig struct {
 	SelfNode route.Vertex
 }
 
+// EstimatorConfig contains the parameters of the probability estimator that
+// can be changed while mission control is running.
+type EstimatorConfig struct {
+	// PenaltyHalfLife defines after how much time a penalized node or
+	// channel is back at 50% probability.
+	PenaltyHalfLife time.Duration
+
+	// AprioriHopProbability is the assumed success probability of a hop in
+	// a route when no other information is available.
+	AprioriHopProbability float64
+
+	// AprioriWeight is a value in the range [0, 1] that defines to what
+	// extent historical results should be extrapolated to untried
+	// connections. See MissionControlConfig for further explanation.
+	AprioriWeight float64
+}
+
+// Validate checks that the estimator parameters are within their valid
+// ranges.
+func (c *EstimatorConfig) Validate() error {
+	if c.PenaltyHalfLife <= 0 {
+		return fmt.Errorf("penalty half-life %v must be positive",
+			c.PenaltyHalfLife)
+	}
+
+	// NaN compares false against any bound, so it is checked explicitly.
+	if math.IsNaN(c.AprioriHopProbability) ||
+		c.AprioriHopProbability < 0 || c.AprioriHopProbability > 1 {
+
+		return fmt.Errorf("apriori hop probability %v must be in "+
+			"[0, 1]", c.AprioriHopProbability)
+	}
+
+	if math.IsNaN(c.AprioriWeight) ||
+		c.AprioriWeight < 0 || c.AprioriWeight > 1 {
+
+		return fmt.Errorf("apriori weight %v must be in [0, 1]",
+			c.AprioriWeight)
+	}
+
+	return nil
+}
+
 // TimedPairResult describes a timestamped pair result.
 type TimedPairResult struct {
 	// FailTime is the time of the last failure.
@@ -250,6 +296,35 @@ func (m *MissionControl) ResetHistory() error {
 	return nil
 }
 
+// ExportHistory writes the raw payment results from which the mission control
+// state is derived to the given writer. The output can be loaded into the
+// mission control of another node using ImportHistory.
+func (m *MissionControl) ExportHistory(w io.Writer) error {
+	return m.store.exportAll(w)
+}
+
+// ImportHistory reads a set of payment results written by ExportHistory and
+// adds them to the history of mission control. The number of imported results
+// is returned.
+func (m *MissionControl) ImportHistory(r io.Reader) (int, error) {
+	results, err := readExportedResults(r)
+	if err != nil {
+		return 0, fmt.Errorf("unable to read mission control "+
+			"history: %v", err)
+	}
+
+	for _, result := range results {
+		if _, err := m.processPaymentResult(result); err != nil {
+			return 0, err
+		}
+	}
+
+	log.Debugf("Imported %v results into mission control history",
+		len(results))
+
+	return len(results), nil
+}
+
 // GetProbability is expected to return the success probability of a payment
 // from fromNode along edge.
 func (m *MissionControl) GetProbability(fromNode, toNode route.Vertex,
@@ -269,6 +344,43 @@ func (m *MissionControl) GetProbability(fromNode, toNode route.Vertex,
 	return m.estimator.getPairProbability(now, results, toNode, amt)
 }
 
+// GetEstimatorConfig returns the parameters currently used by the
+// probability estimator.
+func (m *MissionControl) GetEstimatorConfig() EstimatorConfig {
+	m.Lock()
+	defer m.Unlock()
+
+	return EstimatorConfig{
+		PenaltyHalfLife:       m.estimator.penaltyHalfLife,
+		AprioriHopProbability: m.estimator.aprioriHopProbability,
+		AprioriWeight:         m.estimator.aprioriWeight,
+	}
+}
+
+// SetEstimatorConfig replaces the parameters of the probability estimator.
+// As probabilities are derived from the payment history when they're
+// requested, the new parameters also apply to the existing history. The
+// change isn't persisted and lasts until mission control is restarted.
+func (m *MissionControl) SetEstimatorConfig(cfg EstimatorConfig) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+
+	log.Infof("Updating mission control estimator config: "+
+		"PenaltyHalfLife=%v, AprioriHopProbability=%v, "+
+		"AprioriWeight=%v", cfg.PenaltyHalfLife,
+		cfg.AprioriHopProbability, cfg.AprioriWeight)
+
+	m.estimator.penaltyHalfLife = cfg.PenaltyHalfLife
+	m.estimator.aprioriHopProbability = cfg.AprioriHopProbability
+	m.estimator.aprioriWeight = cfg.AprioriWeight
+
+	return nil
+}
+
 // GetHistorySnapshot takes a snapshot from the current mission control state
 // and actual probability estimates.
 func (m *MissionControl) GetHistorySnapshot() *MissionControlSnapshot {
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/decred/dcrd/wire"
@@ -26,6 +27,14 @@ const (
 	// unknownFailureSourceIdx is the database encoding of an unknown error
 	// source.
 	unknownFailureSourceIdx = -1
+
+	// maxResultKeySize is the size of the key of a stored result, see
+	// getResultKey.
+	maxResultKeySize = 8 + 8 + 33
+
+	// maxResultValueSize is the maximum size of a serialized result we'll
+	// accept when reading exported results.
+	maxResultValueSize = 65535
 )
 
 // missionControlStore is a bbolt db based implementation of a mission control
@@ -111,6 +120,71 @@ func (b *missionControlStore) fetchAll() ([]*paymentResult, error) {
 	return results, nil
 }
 
+// exportAll writes all results currently stored in the database to the given
+// writer, in the format expected by readExportedResults.
+func (b *missionControlStore) exportAll(w io.Writer) error {
+	return kvdb.View(b.db, func(tx kvdb.RTx) error {
+		resultBucket := tx.ReadBucket(resultsKey)
+
+		var numResults uint32
+		err := resultBucket.ForEach(func(_, _ []byte) error {
+			numResults++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		err = channeldb.WriteElements(w, numResults)
+		if err != nil {
+			return err
+		}
+
+		return resultBucket.ForEach(func(k, v []byte) error {
+			if err := wire.WriteVarBytes(w, 0, k); err != nil {
+				return err
+			}
+
+			return wire.WriteVarBytes(w, 0, v)
+		})
+	})
+}
+
+// readExportedResults reads a set of results written by exportAll from the
+// given reader.
+func readExportedResults(r io.Reader) ([]*paymentResult, error) {
+	var numResults uint32
+	if err := channeldb.ReadElements(r, &numResults); err != nil {
+		return nil, err
+	}
+
+	var results []*paymentResult
+	for i := uint32(0); i < numResults; i++ {
+		k, err := wire.ReadVarBytes(r, 0, maxResultKeySize, "key")
+		if err != nil {
+			return nil, err
+		}
+		if len(k) != maxResultKeySize {
+			return nil, fmt.Errorf("invalid result key size %v",
+				len(k))
+		}
+
+		v, err := wire.ReadVarBytes(r, 0, maxResultValueSize, "value")
+		if err != nil {
+			return nil, err
+		}
+
+		result, err := deserializeResult(k, v)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
 // serializeResult serializes a payment result and returns a key and value byte
 // slice to insert into the bucket.
 func serializeResult(rp *paymentResult) ([]byte, []byte, error) {
@@ -255,7 +329,7 @@ func (b *missionControlStore) AddResult(rp *paymentResult) error {
 // getResultKey returns a byte slice representing a unique key for this payment
 // result.
 func getResultKey(rp *paymentResult) []byte {
-	var keyBytes [8 + 8 + 33]byte
+	var keyBytes [maxResultKeySize]byte
 
 	// Identify records by a combination of time, payment id and sender pub
 	// key. This allows importing mission control data from an external
@@ -1,7 +1,9 @@
 package routing
 
 import (
+	"bytes"
 	"io/ioutil"
+	"math"
 	"os"
 	"testing"
 	"time"
@@ -213,3 +215,106 @@ func TestMissionControlChannelUpdate(t *testing.T) {
 	)
 	ctx.expectP(100, 0)
 }
+
+// TestMissionControlExportImport tests that the history exported from one
+// mission control instance can be imported into another one.
+func TestMissionControlExportImport(t *testing.T) {
+	ctx := createMcTestContext(t)
+	defer ctx.cleanup()
+
+	ctx.reportFailure(1000, lnwire.NewTemporaryChannelFailure(nil))
+	ctx.pid++
+	ctx.reportSuccess()
+
+	var b bytes.Buffer
+	if err := ctx.mc.ExportHistory(&b); err != nil {
+		t.Fatalf("unable to export history: %v", err)
+	}
+
+	importCtx := createMcTestContext(t)
+	defer importCtx.cleanup()
+
+	numResults, err := importCtx.mc.ImportHistory(&b)
+	if err != nil {
+		t.Fatalf("unable to import history: %v", err)
+	}
+	if numResults != 2 {
+		t.Fatalf("expected 2 imported results, got %v", numResults)
+	}
+
+	// The imported history should lead to the same probability estimates
+	// as the original one, also after the imported results are restored
+	// from the database.
+	assertHistory := func() {
+		t.Helper()
+
+		numPairs := len(ctx.mc.GetHistorySnapshot().Pairs)
+		importedPairs := len(importCtx.mc.GetHistorySnapshot().Pairs)
+		if numPairs != importedPairs {
+			t.Fatalf("expected %v pairs, got %v", numPairs,
+				importedPairs)
+		}
+
+		for _, amt := range []lnwire.MilliAtom{500, 1000, 2000} {
+			importCtx.expectP(
+				amt, ctx.mc.GetProbability(
+					mcTestNode1, mcTestNode2, amt,
+				),
+			)
+		}
+	}
+
+	assertHistory()
+	importCtx.restartMc()
+	assertHistory()
+}
+
+// TestMissionControlEstimatorConfig tests that changes to the estimator config
+// are applied to subsequent probability estimates.
+func TestMissionControlEstimatorConfig(t *testing.T) {
+	ctx := createMcTestContext(t)
+	defer ctx.cleanup()
+
+	ctx.expectP(1000, testAprioriHopProbability)
+
+	cfg := EstimatorConfig{
+		PenaltyHalfLife:       time.Hour,
+		AprioriHopProbability: 0.5,
+		AprioriWeight:         1,
+	}
+	if err := ctx.mc.SetEstimatorConfig(cfg); err != nil {
+		t.Fatalf("unable to set estimator config: %v", err)
+	}
+	if ctx.mc.GetEstimatorConfig() != cfg {
+		t.Fatalf("expected config %v, got %v", cfg,
+			ctx.mc.GetEstimatorConfig())
+	}
+	ctx.expectP(1000, 0.5)
+
+	// A failed pair recovers towards the new a priori probability with the
+	// new half-life.
+	ctx.reportFailure(1000, lnwire.NewTemporaryChannelFailure(nil))
+	ctx.expectP(1000, 0)
+
+	ctx.now = ctx.now.Add(time.Hour)
+	ctx.expectP(1000, 0.25)
+
+	// Invalid configs are rejected and leave the current config in place.
+	invalid := []EstimatorConfig{
+		{PenaltyHalfLife: 0, AprioriHopProbability: 0.5},
+		{PenaltyHalfLife: time.Hour, AprioriHopProbability: 1.5},
+		{PenaltyHalfLife: time.Hour, AprioriWeight: -0.1},
+		{PenaltyHalfLife: time.Hour, AprioriHopProbability: math.NaN()},
+		{PenaltyHalfLife: time.Hour, AprioriWeight: math.NaN()},
+	}
+	for _, invalidCfg := range invalid {
+		if err := ctx.mc.SetEstimatorConfig(invalidCfg); err == nil {
+			t.Fatalf("expected config %v to be rejected",
+				invalidCfg)
+		}
+	}
+	if ctx.mc.GetEstimatorConfig() != cfg {
+		t.Fatalf("expected config %v, got %v", cfg,
+			ctx.mc.GetEstimatorConfig())
+	}
+}
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"container/heap"
+	"context"
 	"errors"
 	"fmt"
 	"math"
@@ -38,11 +39,13 @@ const (
 	estimatedNodeCount = 10000
 )
 
-// pathFinder defines the interface of a path finding algorithm.
+// pathFinder defines the interface of a path finding algorithm. Along with the
+// edges of the path, it returns the inbound fee charged by the node each edge
+// leads to for HTLCs received over it.
 type pathFinder = func(g *graphParams, r *RestrictParams,
 	cfg *PathFindingConfig, source, target route.Vertex,
 	amt lnwire.MilliAtom, finalHtlcExpiry int32) (
-	[]*channeldb.ChannelEdgePolicy, error)
+	[]*channeldb.ChannelEdgePolicy, []lnwire.InboundFee, error)
 
 var (
 	// DefaultPaymentAttemptPenalty is the virtual cost in path finding weight
@@ -86,12 +89,16 @@ type finalHopParams struct {
 // method will fail.  If the route is too long, or the selected path cannot
 // support the fully payment including fees, then a non-nil error is returned.
 //
+// The inbound fees, if not nil, are the fees charged by the node each edge
+// leads to for HTLCs received over it.
+//
 // NOTE: The passed slice of ChannelHops MUST be sorted in forward order: from
 // the source to the target node of the path finding attempt. It is assumed
 // that any feature vectors on all hops have been validated for transitive
 // dependencies.
 func newRoute(sourceVertex route.Vertex,
-	pathEdges []*channeldb.ChannelEdgePolicy, currentHeight uint32,
+	pathEdges []*channeldb.ChannelEdgePolicy,
+	inboundFees []lnwire.InboundFee, currentHeight uint32,
 	finalHop finalHopParams) (*route.Route, error) {
 
 	var (
@@ -191,8 +198,15 @@ func newRoute(sourceVertex route.Vertex,
 			// based on the amount that this hop needs to forward
 			// and its policy for the outgoing channel. This policy
 			// is stored as part of the incoming channel of
-			// the next hop.
-			fee = pathEdges[i+1].ComputeFee(amtToForward)
+			// the next hop. The inbound fee the current hop
+			// charges for the incoming channel adds to it.
+			var inboundFee lnwire.InboundFee
+			if inboundFees != nil {
+				inboundFee = inboundFees[i]
+			}
+			fee = forwardingFee(
+				pathEdges[i+1], inboundFee, amtToForward,
+			)
 
 			// We'll take the total timelock of the preceding hop as
 			// the outgoing timelock or this hop. Then we'll
@@ -233,6 +247,49 @@ func newRoute(sourceVertex route.Vertex,
 	return newRoute, nil
 }
 
+// forwardingFee returns the fee a node charges to forward the given amount over
+// the outgoing channel with the given policy, when receiving the HTLC over a
+// channel with the given inbound fee. The inbound fee is computed over the
+// forwarded amount plus the outgoing fee, and the total fee is never negative,
+// as nodes don't forward more than they receive.
+func forwardingFee(policy *channeldb.ChannelEdgePolicy,
+	inboundFee lnwire.InboundFee, amt lnwire.MilliAtom) lnwire.MilliAtom {
+
+	outFee := policy.ComputeFee(amt)
+	fee := int64(outFee) + inboundFee.Fee(amt+outFee)
+	if fee < 0 {
+		return 0
+	}
+
+	return lnwire.MilliAtom(fee)
+}
+
+// forwardingFeeFromIncoming returns the fee a node charges to forward the
+// largest amount it can out of the given incoming amount, following the same
+// rules as forwardingFee.
+func forwardingFeeFromIncoming(policy *channeldb.ChannelEdgePolicy,
+	inboundFee lnwire.InboundFee,
+	incomingAmt lnwire.MilliAtom) lnwire.MilliAtom {
+
+	if inboundFee == (lnwire.InboundFee{}) {
+		return policy.ComputeFeeFromIncoming(incomingAmt)
+	}
+
+	// The forwarded amount plus its fee grows with the forwarded amount,
+	// so we search for the largest amount that fits the incoming one.
+	var lo, hi lnwire.MilliAtom = 0, incomingAmt
+	for lo < hi {
+		mid := lo + (hi-lo+1)/2
+		if mid+forwardingFee(policy, inboundFee, mid) <= incomingAmt {
+			lo = mid
+		} else {
+			hi = mid - 1
+		}
+	}
+
+	return incomingAmt - lo
+}
+
 // edgeWeight computes the weight of an edge. This value is used when searching
 // for the shortest path within the channel graph between two nodes. Weight is
 // is the fee itself plus a time lock penalty added to it. This benefits
@@ -269,6 +326,20 @@ type graphParams struct {
 	// set to the current available sending bandwidth for active local
 	// channels, and 0 for inactive channels.
 	bandwidthHints map[uint64]lnwire.MilliAtom
+
+	// ctx is an optional context of the caller. If set, path finding is
+	// aborted with the error of the context once it's done, so that
+	// cancelled requests don't keep traversing the graph.
+	ctx context.Context
+}
+
+// ctxErr returns the error of the caller's context, if it is done.
+func (g *graphParams) ctxErr() error {
+	if g.ctx == nil {
+		return nil
+	}
+
+	return g.ctx.Err()
 }
 
 // RestrictParams wraps the set of restrictions passed to findPath that the
@@ -287,6 +358,10 @@ type RestrictParams struct {
 	// first hop. If nil, any channel may be used.
 	OutgoingChannelIDs []uint64
 
+	// ExcludedOutgoingChannelIDs is the list of channels that must not be
+	// used for the first hop, even if listed in OutgoingChannelIDs.
+	ExcludedOutgoingChannelIDs []uint64
+
 	// LastHop is the pubkey of the last node before the final destination
 	// is reached. If nil, any node may be used.
 	LastHop *route.Vertex
@@ -325,10 +400,25 @@ type PathFindingConfig struct {
 	MinProbability float64
 }
 
+// chanIDSet returns the given channel IDs as a set, or nil if there are none.
+func chanIDSet(chanIDs []uint64) map[uint64]struct{} {
+	if len(chanIDs) == 0 {
+		return nil
+	}
+
+	set := make(map[uint64]struct{}, len(chanIDs))
+	for _, chanID := range chanIDs {
+		set[chanID] = struct{}{}
+	}
+
+	return set
+}
+
 // getOutgoingBalance returns the maximum available balance in any of the
 // channels of the given node. The second return parameters is the total
 // available balance.
-func getOutgoingBalance(node route.Vertex, outgoingChans map[uint64]struct{},
+func getOutgoingBalance(node route.Vertex, outgoingChans,
+	excludedChans map[uint64]struct{},
 	bandwidthHints map[uint64]lnwire.MilliAtom,
 	g routingGraph) (lnwire.MilliAtom, lnwire.MilliAtom, error) {
 
@@ -348,6 +438,9 @@ func getOutgoingBalance(node route.Vertex, outgoingChans map[uint64]struct{},
 				return nil
 			}
 		}
+		if _, ok := excludedChans[chanID]; ok {
+			return nil
+		}
 
 		bandwidth, ok := bandwidthHints[chanID]
 
@@ -390,7 +483,8 @@ func getOutgoingBalance(node route.Vertex, outgoingChans map[uint64]struct{},
 // available bandwidth.
 func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	source, target route.Vertex, amt lnwire.MilliAtom,
-	finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy, error) {
+	finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy,
+	[]lnwire.InboundFee, error) {
 
 	// Pathfinding can be a significant portion of the total payment
 	// latency, especially on low-powered devices. Log several metrics to
@@ -411,7 +505,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 		var err error
 		features, err = g.graph.fetchNodeFeatures(target)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
 
@@ -420,14 +514,14 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	err := feature.ValidateRequired(features)
 	if err != nil {
 		log.Warnf("Pathfinding destination node features: %v", err)
-		return nil, errUnknownRequiredFeature
+		return nil, nil, errUnknownRequiredFeature
 	}
 
 	// Ensure that all transitive dependencies are set.
 	err = feature.ValidateDeps(features)
 	if err != nil {
 		log.Warnf("Pathfinding destination node features: %v", err)
-		return nil, errMissingDependentFeature
+		return nil, nil, errMissingDependentFeature
 	}
 
 	// Now that we know the feature vector is well formed, we'll proceed in
@@ -439,7 +533,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	if len(r.DestCustomRecords) > 0 &&
 		!features.HasFeature(lnwire.TLVOnionPayloadOptional) {
 
-		return nil, errNoTlvPayload
+		return nil, nil, errNoTlvPayload
 	}
 
 	// If the caller has a payment address to attach, check that our
@@ -447,17 +541,12 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	if r.PaymentAddr != nil &&
 		!features.HasFeature(lnwire.PaymentAddrOptional) {
 
-		return nil, errNoPaymentAddr
+		return nil, nil, errNoPaymentAddr
 	}
 
-	// Set up outgoing channel map for quicker access.
-	var outgoingChanMap map[uint64]struct{}
-	if len(r.OutgoingChannelIDs) > 0 {
-		outgoingChanMap = make(map[uint64]struct{})
-		for _, outChan := range r.OutgoingChannelIDs {
-			outgoingChanMap[outChan] = struct{}{}
-		}
-	}
+	// Set up outgoing channel maps for quicker access.
+	outgoingChanMap := chanIDSet(r.OutgoingChannelIDs)
+	excludedChanMap := chanIDSet(r.ExcludedOutgoingChannelIDs)
 
 	// If we are routing from ourselves, check that we have enough local
 	// balance available.
@@ -465,22 +554,23 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 
 	if source == self {
 		max, total, err := getOutgoingBalance(
-			self, outgoingChanMap, g.bandwidthHints, g.graph,
+			self, outgoingChanMap, excludedChanMap,
+			g.bandwidthHints, g.graph,
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		// If the total outgoing balance isn't sufficient, it will be
 		// impossible to complete the payment.
 		if total < amt {
-			return nil, errInsufficientBalance
+			return nil, nil, errInsufficientBalance
 		}
 
 		// If there is only not enough capacity on a single route, it
 		// may still be possible to complete the payment by splitting.
 		if max < amt {
-			return nil, errNoPathFound
+			return nil, nil, errNoPathFound
 		}
 	}
 
@@ -553,13 +643,28 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	// satisfy our specific requirements.
 	processEdge := func(fromVertex route.Vertex,
 		fromFeatures *lnwire.FeatureVector,
-		edge *channeldb.ChannelEdgePolicy, toNodeDist *nodeWithDist) {
+		edge *channeldb.ChannelEdgePolicy, inboundFee lnwire.InboundFee,
+		toNodeDist *nodeWithDist) {
 
 		edgesExpanded++
 
 		// Calculate amount that the candidate node would have to send
-		// out.
+		// out. Unless the next node is the target, it charges its
+		// inbound fee for the edge on top of the amount it needs to
+		// receive. The inbound fee can be negative, but never more
+		// than the outbound fee of the next node.
 		amountToSend := toNodeDist.amountToReceive
+		var inboundFeeAmt int64
+		if toNodeDist.node != target {
+			inboundFeeAmt = inboundFee.Fee(amountToSend)
+			minFee := -int64(toNodeDist.outboundFee)
+			if inboundFeeAmt < minFee {
+				inboundFeeAmt = minFee
+			}
+			amountToSend = lnwire.MilliAtom(
+				int64(amountToSend) + inboundFeeAmt,
+			)
+		}
 
 		// Request the success probability for this edge.
 		edgeProbability := r.ProbabilitySource(
@@ -629,10 +734,18 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 		}
 
 		// By adding fromVertex in the route, there will be an extra
-		// weight composed of the fee that this node will charge and
-		// the amount that will be locked for timeLockDelta blocks in
-		// the HTLC that is handed out to fromVertex.
-		weight := edgeWeight(amountToReceive, fee, timeLockDelta)
+		// weight composed of the fee that this node will charge, the
+		// inbound fee of the next node and the amount that will be
+		// locked for timeLockDelta blocks in the HTLC that is handed
+		// out to fromVertex.
+		weightFee := int64(fee) + inboundFeeAmt
+		if weightFee < 0 {
+			weightFee = 0
+		}
+		weight := edgeWeight(
+			amountToReceive, lnwire.MilliAtom(weightFee),
+			timeLockDelta,
+		)
 
 		// Compute the tentative weight to this new channel/edge
 		// which is the weight from our toNode to the target node
@@ -707,14 +820,16 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 		// The new better distance is recorded, and also our "next hop"
 		// map is populated with this edge.
 		withDist := &nodeWithDist{
-			dist:            tempDist,
-			weight:          tempWeight,
-			node:            fromVertex,
-			amountToReceive: amountToReceive,
-			incomingCltv:    incomingCltv,
-			probability:     probability,
-			nextHop:         edge,
-			routingInfoSize: routingInfoSize,
+			dist:              tempDist,
+			weight:            tempWeight,
+			node:              fromVertex,
+			amountToReceive:   amountToReceive,
+			incomingCltv:      incomingCltv,
+			probability:       probability,
+			nextHop:           edge,
+			nextHopInboundFee: inboundFee,
+			outboundFee:       fee,
+			routingInfoSize:   routingInfoSize,
 		}
 		distance[fromVertex] = withDist
 
@@ -772,18 +887,28 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 	for {
 		nodesVisited++
 
+		// Stop traversing the graph if the caller is no longer
+		// interested in the result.
+		if err := g.ctxErr(); err != nil {
+			return nil, nil, err
+		}
+
 		pivot := partialPath.node
 
 		// Create unified policies for all incoming connections.
 		u := newUnifiedPolicies(self, pivot, outgoingChanMap)
+		u.outChanExcl = excludedChanMap
 
 		err := u.addGraphPolicies(g.graph)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		for _, reverseEdge := range additionalEdgesWithSrc[pivot] {
-			u.addPolicy(reverseEdge.sourceNode, reverseEdge.edge, 0)
+			u.addPolicy(
+				reverseEdge.sourceNode, reverseEdge.edge,
+				lnwire.InboundFee{}, 0,
+			)
 		}
 
 		amtToSend := partialPath.amountToReceive
@@ -807,7 +932,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 				continue
 			}
 
-			policy := unifiedPolicy.getPolicy(
+			policy, inboundFee := unifiedPolicy.getPolicy(
 				amtToSend, g.bandwidthHints,
 			)
 
@@ -818,7 +943,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 			// Get feature vector for fromNode.
 			fromFeatures, err := getGraphFeatures(fromNode)
 			if err != nil {
-				return nil, err
+				return nil, nil, err
 			}
 
 			// If there are no valid features, skip this node.
@@ -828,7 +953,10 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 
 			// Check if this candidate node is better than what we
 			// already have.
-			processEdge(fromNode, fromFeatures, policy, partialPath)
+			processEdge(
+				fromNode, fromFeatures, policy, inboundFee,
+				partialPath,
+			)
 		}
 
 		if nodeHeap.Len() == 0 {
@@ -849,18 +977,24 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 
 	// Use the distance map to unravel the forward path from source to
 	// target.
-	var pathEdges []*channeldb.ChannelEdgePolicy
+	var (
+		pathEdges   []*channeldb.ChannelEdgePolicy
+		inboundFees []lnwire.InboundFee
+	)
 	currentNode := source
 	for {
 		// Determine the next hop forward using the next map.
 		currentNodeWithDist, ok := distance[currentNode]
 		if !ok {
 			// If the node doesnt have a next hop it means we didn't find a path.
-			return nil, errNoPathFound
+			return nil, nil, errNoPathFound
 		}
 
 		// Add the next hop to the list of path edges.
 		pathEdges = append(pathEdges, currentNodeWithDist.nextHop)
+		inboundFees = append(
+			inboundFees, currentNodeWithDist.nextHopInboundFee,
+		)
 
 		// Advance current node.
 		currentNode = currentNodeWithDist.nextHop.Node.PubKeyBytes
@@ -890,7 +1024,7 @@ func findPath(g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 		distance[source].probability, len(pathEdges),
 		distance[source].amountToReceive-amt)
 
-	return pathEdges, nil
+	return pathEdges, inboundFees, nil
 }
 
 // getProbabilityBasedDist converts a weight into a distance that takes into
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"bytes"
+	"context"
 	"crypto/sha256"
 	"encoding/binary"
 	"encoding/hex"
@@ -345,6 +346,7 @@ type testChannelPolicy struct {
 	LastUpdate    time.Time
 	Disabled      bool
 	Features      *lnwire.FeatureVector
+	InboundFee    *lnwire.InboundFee
 }
 
 type testChannelEnd struct {
@@ -578,6 +580,13 @@ func createTestGraphFromChannels(testChannels []*testChannel, source string) (
 				FeeBaseMAtoms:             node1.FeeBaseMAtoms,
 				FeeProportionalMillionths: node1.FeeRate,
 			}
+			if node1.InboundFee != nil {
+				edgePolicy.ExtraOpaqueData, err =
+					lnwire.SetInboundFee(nil, node1.InboundFee)
+				if err != nil {
+					return nil, err
+				}
+			}
 			if err := graph.UpdateEdgePolicy(edgePolicy); err != nil {
 				return nil, err
 			}
@@ -606,6 +615,13 @@ func createTestGraphFromChannels(testChannels []*testChannel, source string) (
 				FeeBaseMAtoms:             node2.FeeBaseMAtoms,
 				FeeProportionalMillionths: node2.FeeRate,
 			}
+			if node2.InboundFee != nil {
+				edgePolicy.ExtraOpaqueData, err =
+					lnwire.SetInboundFee(nil, node2.InboundFee)
+				if err != nil {
+					return nil, err
+				}
+			}
 			if err := graph.UpdateEdgePolicy(edgePolicy); err != nil {
 				return nil, err
 			}
@@ -678,7 +694,7 @@ func TestFindLowestFeePath(t *testing.T) {
 		t.Fatalf("unable to find path: %v", err)
 	}
 	route, err := newRoute(
-		ctx.source, path, startingHeight,
+		ctx.source, path, nil, startingHeight,
 		finalHopParams{
 			amt:       paymentAmt,
 			cltvDelta: finalHopCLTV,
@@ -697,6 +713,146 @@ func TestFindLowestFeePath(t *testing.T) {
 	}
 }
 
+// TestInboundFeePathFinding tests that path finding accounts for the inbound
+// fees charged by the nodes along the route and that the route pays them.
+func TestInboundFeePathFinding(t *testing.T) {
+	t.Parallel()
+
+	// Set up a test graph with two paths from roasbeef to target that
+	// only differ in the inbound fee that a charges for its channel with
+	// first.
+	newTestChannels := func(inboundFee lnwire.InboundFee) []*testChannel {
+		policy := func() *testChannelPolicy {
+			return &testChannelPolicy{
+				Expiry:        144,
+				FeeBaseMAtoms: 100,
+				MinHTLC:       1,
+				MaxHTLC:       100000000,
+			}
+		}
+
+		aPolicy := policy()
+		aPolicy.InboundFee = &inboundFee
+
+		return []*testChannel{
+			symmetricTestChannel(
+				"roasbeef", "first", 100000, policy(), 1,
+			),
+			asymmetricTestChannel(
+				"first", "a", 100000, policy(), aPolicy, 2,
+			),
+			symmetricTestChannel("a", "target", 100000, policy(), 3),
+			symmetricTestChannel("first", "b", 100000, policy(), 4),
+			symmetricTestChannel("b", "target", 100000, policy(), 5),
+		}
+	}
+
+	const (
+		startingHeight = 100
+		finalHopCLTV   = 1
+	)
+	paymentAmt := lnwire.NewMAtomsFromAtoms(100)
+
+	findRoute := func(inboundFee lnwire.InboundFee) (*route.Route,
+		*pathFindingTestContext) {
+
+		ctx := newPathFindingTestContext(
+			t, newTestChannels(inboundFee), "roasbeef",
+		)
+
+		routingTx, err := newDbRoutingTx(ctx.graph)
+		if err != nil {
+			t.Fatalf("unable to create routing tx: %v", err)
+		}
+		defer routingTx.close()
+
+		path, inboundFees, err := findPath(
+			&graphParams{graph: routingTx}, &ctx.restrictParams,
+			&ctx.pathFindingConfig, ctx.source,
+			ctx.keyFromAlias("target"), paymentAmt, 0,
+		)
+		if err != nil {
+			t.Fatalf("unable to find path: %v", err)
+		}
+
+		route, err := newRoute(
+			ctx.source, path, inboundFees, startingHeight,
+			finalHopParams{
+				amt:       paymentAmt,
+				cltvDelta: finalHopCLTV,
+			},
+		)
+		if err != nil {
+			t.Fatalf("unable to create route: %v", err)
+		}
+
+		return route, ctx
+	}
+
+	// An inbound fee makes the path through a more expensive, so the path
+	// through b is expected.
+	route, ctx := findRoute(lnwire.InboundFee{BaseFee: 1000})
+	if route.Hops[1].PubKeyBytes != ctx.keyFromAlias("b") {
+		t.Fatalf("expected route to pass through b, but got a "+
+			"route through %v",
+			ctx.aliasFromKey(route.Hops[1].PubKeyBytes))
+	}
+	if route.TotalFees() != 200 {
+		t.Fatalf("expected total fees of 200, got %v",
+			route.TotalFees())
+	}
+	ctx.cleanup()
+
+	// An inbound discount makes the path through a cheaper. The discount
+	// is deducted from the fee paid to a.
+	route, ctx = findRoute(lnwire.InboundFee{BaseFee: -50})
+	defer ctx.cleanup()
+	if route.Hops[1].PubKeyBytes != ctx.keyFromAlias("a") {
+		t.Fatalf("expected route to pass through a, but got a "+
+			"route through %v",
+			ctx.aliasFromKey(route.Hops[1].PubKeyBytes))
+	}
+	if route.TotalFees() != 150 {
+		t.Fatalf("expected total fees of 150, got %v",
+			route.TotalFees())
+	}
+	if route.Hops[0].AmtToForward != paymentAmt+50 {
+		t.Fatalf("expected first to forward %v, got %v",
+			paymentAmt+50, route.Hops[0].AmtToForward)
+	}
+}
+
+// TestForwardingFeeFromIncoming tests that forwardingFeeFromIncoming is the
+// inverse of forwardingFee.
+func TestForwardingFeeFromIncoming(t *testing.T) {
+	t.Parallel()
+
+	policy := &channeldb.ChannelEdgePolicy{
+		FeeBaseMAtoms:             1000,
+		FeeProportionalMillionths: 1000,
+	}
+
+	inboundFees := []lnwire.InboundFee{
+		{},
+		{BaseFee: 500, FeeRate: 500},
+		{BaseFee: -200, FeeRate: -300},
+		{BaseFee: -5000, FeeRate: -5000},
+	}
+	for _, inboundFee := range inboundFees {
+		for _, amt := range []lnwire.MilliAtom{0, 1, 12345, 9876543} {
+			fee := forwardingFee(policy, inboundFee, amt)
+			feeFromIncoming := forwardingFeeFromIncoming(
+				policy, inboundFee, amt+fee,
+			)
+			if feeFromIncoming != fee {
+				t.Fatalf("inbound fee %v, amount %v: expected "+
+					"fee %v, got %v", inboundFee, amt,
+					fee, feeFromIncoming)
+			}
+		}
+	}
+}
+
 func getAliasFromPubKey(pubKey route.Vertex,
 	aliases map[string]route.Vertex) string {
 
@@ -831,7 +987,7 @@ func testBasicGraphPathFindingCase(t *testing.T, graphInstance *testGraphInstanc
 	}
 
 	route, err := newRoute(
-		sourceVertex, path, startingHeight,
+		sourceVertex, path, nil, startingHeight,
 		finalHopParams{
 			amt:       paymentAmt,
 			cltvDelta: finalHopCLTV,
@@ -1322,7 +1478,7 @@ func TestNewRoute(t *testing.T) {
 
 		t.Run(testCase.name, func(t *testing.T) {
 			route, err := newRoute(
-				sourceVertex, testCase.hops, startingHeight,
+				sourceVertex, testCase.hops, nil, startingHeight,
 				finalHopParams{
 					amt:         testCase.paymentAmount,
 					totalAmt:    testCase.paymentAmount,
@@ -2075,7 +2231,8 @@ func TestPathFindSpecExample(t *testing.T) {
 	carol := ctx.aliases["C"]
 	const amt lnwire.MilliAtom = 4999999
 	route, err := ctx.router.FindRoute(
-		bobNode.PubKeyBytes, carol, amt, noRestrictions, nil, nil,
+		context.Background(), bobNode.PubKeyBytes, carol, amt,
+		noRestrictions, nil, nil,
 		MinCLTVDelta,
 	)
 	if err != nil {
@@ -2131,7 +2288,8 @@ func TestPathFindSpecExample(t *testing.T) {
 
 	// We'll now request a route from A -> B -> C.
 	route, err = ctx.router.FindRoute(
-		source.PubKeyBytes, carol, amt, noRestrictions, nil, nil,
+		context.Background(), source.PubKeyBytes, carol, amt,
+		noRestrictions, nil, nil,
 		MinCLTVDelta,
 	)
 	if err != nil {
@@ -2315,6 +2473,34 @@ func TestRestrictOutgoingChannel(t *testing.T) {
 		t.Fatalf("expected route to pass through channel %v",
 			chanSourceTarget)
 	}
+
+	// Excluding the direct channel, the next cheapest channel is expected
+	// to be selected.
+	ctx.restrictParams.OutgoingChannelIDs = nil
+	ctx.restrictParams.ExcludedOutgoingChannelIDs = []uint64{
+		chanSourceTarget,
+	}
+	path, err = ctx.findPath(target, paymentAmt)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if path[0].ChannelID != chanSourceA {
+		t.Fatalf("expected route to pass through channel %v",
+			chanSourceA)
+	}
+
+	// Exclusions take precedence over the allowed channels.
+	ctx.restrictParams.OutgoingChannelIDs = []uint64{
+		chanSourceB1, chanSourceTarget,
+	}
+	path, err = ctx.findPath(target, paymentAmt)
+	if err != nil {
+		t.Fatalf("unable to find path: %v", err)
+	}
+	if path[0].ChannelID != chanSourceB1 {
+		t.Fatalf("expected route to pass through channel %v",
+			chanSourceB1)
+	}
 }
 
 // TestRestrictLastHop asserts that a last hop restriction is obeyed by the path
@@ -2428,7 +2614,7 @@ func testCltvLimit(t *testing.T, limit uint32, expectedChannel uint64) {
 		finalHopCLTV   = 1
 	)
 	route, err := newRoute(
-		ctx.source, path, startingHeight,
+		ctx.source, path, nil, startingHeight,
 		finalHopParams{
 			amt:       paymentAmt,
 			cltvDelta: finalHopCLTV,
@@ -2719,7 +2905,7 @@ func TestNoCycle(t *testing.T) {
 		t.Fatalf("unable to find path: %v", err)
 	}
 	route, err := newRoute(
-		ctx.source, path, startingHeight,
+		ctx.source, path, nil, startingHeight,
 		finalHopParams{
 			amt:       paymentAmt,
 			cltvDelta: finalHopCLTV,
@@ -2886,7 +3072,7 @@ func dbFindPath(graph *channeldb.ChannelGraph,
 		}
 	}()
 
-	return findPath(
+	path, _, err := findPath(
 		&graphParams{
 			additionalEdges: additionalEdges,
 			bandwidthHints:  bandwidthHints,
@@ -2894,4 +3080,6 @@ func dbFindPath(graph *channeldb.ChannelGraph,
 		},
 		r, cfg, source, target, amt, finalHtlcExpiry,
 	)
+
+	return path, err
 }
@@ -10,10 +10,21 @@ import (
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/lntypes"
 	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/monitoring"
 	"github.com/decred/dcrlnd/routing/route"
 	sphinx "github.com/decred/lightning-onion/v3"
 )
 
+const (
+	// paymentOutcomeSucceeded is the outcome under which the timings of
+	// settled payments and HTLC attempts are recorded.
+	paymentOutcomeSucceeded = "succeeded"
+
+	// paymentOutcomeFailed is the outcome under which the timings of failed
+	// payments and HTLC attempts are recorded.
+	paymentOutcomeFailed = "failed"
+)
+
 // paymentLifecycle holds all information about the current state of a payment
 // needed to resume if from any point.
 type paymentLifecycle struct {
@@ -23,6 +34,7 @@ type paymentLifecycle struct {
 	paymentHash   lntypes.Hash
 	paySession    PaymentSession
 	timeoutChan   <-chan time.Time
+	cancelChan    <-chan struct{}
 	currentHeight int32
 }
 
@@ -80,10 +92,11 @@ func (p *paymentLifecycle) paymentState(payment *channeldb.MPPayment) (
 // resumePayment resumes the paymentLifecycle from the current state.
 func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 	shardHandler := &shardHandler{
-		router:      p.router,
-		paymentHash: p.paymentHash,
-		shardErrors: make(chan error),
-		quit:        make(chan struct{}),
+		router:            p.router,
+		paymentHash:       p.paymentHash,
+		shardErrors:       make(chan error),
+		firstHopRetryable: true,
+		quit:              make(chan struct{}),
 	}
 
 	// When the payment lifecycle loop exits, we make sure to signal any
@@ -147,15 +160,26 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		// We have a terminal condition and no active shards, we are
 		// ready to exit.
 		case state.terminate && state.numShardsInFlight == 0:
+			paymentTime := p.router.cfg.Clock.Now().Sub(
+				payment.Info.CreationTime,
+			)
+
 			// Find the first successful shard and return
 			// the preimage and route.
 			for _, a := range payment.HTLCs {
 				if a.Settle != nil {
+					monitoring.ObservePayment(
+						paymentOutcomeSucceeded,
+						paymentTime,
+					)
 					return a.Settle.Preimage, &a.Route, nil
 				}
 			}
 
 			// Payment failed.
+			monitoring.ObservePayment(
+				paymentOutcomeFailed, paymentTime,
+			)
 			return [32]byte{}, nil, *payment.FailureReason
 
 		// If we either reached a terminal error condition (but had
@@ -172,10 +196,11 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		}
 
 		// Before we attempt any new shard, we'll check to see if
-		// either we've gone past the payment attempt timeout, or the
-		// router is exiting. In either case, we'll stop this payment
-		// attempt short. If a timeout is not applicable, timeoutChan
-		// will be nil.
+		// either we've gone past the payment attempt timeout, the
+		// caller canceled the payment, or the router is exiting. In
+		// any case, we'll stop this payment attempt short. If a
+		// timeout or cancellation is not applicable, timeoutChan and
+		// cancelChan will be nil.
 		select {
 		case <-p.timeoutChan:
 			log.Warnf("payment attempt not completed before " +
@@ -194,6 +219,22 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 
 			continue
 
+		// The caller waiting for the outcome of the payment has gone
+		// away, so we'll stop the payment the same way we do on a
+		// timeout.
+		case <-p.cancelChan:
+			log.Warnf("payment %v canceled by the caller",
+				p.paymentHash)
+
+			saveErr := p.router.cfg.Control.Fail(
+				p.paymentHash, channeldb.FailureReasonCanceled,
+			)
+			if saveErr != nil {
+				return [32]byte{}, nil, saveErr
+			}
+
+			continue
+
 		case <-p.router.quit:
 			return [32]byte{}, nil, ErrRouterShuttingDown
 
@@ -201,11 +242,25 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		default:
 		}
 
-		// Create a new payment attempt from the given payment session.
+		// Routes whose first hop failed with a transient error are
+		// retried before any new route is requested.
+		if retry := shardHandler.nextFirstHopRetry(); retry != nil {
+			err := p.retryFirstHop(shardHandler, retry, state)
+			if err != nil {
+				return [32]byte{}, nil, err
+			}
+			continue
+		}
+
+		// Create a new payment attempt from the given payment session,
+		// tracking the time spent finding its route.
+		pathFindingStart := p.router.cfg.Clock.Now()
 		rt, err := p.paySession.RequestRoute(
 			state.remainingAmt, state.remainingFees,
 			uint32(state.numShardsInFlight), uint32(p.currentHeight),
 		)
+		pathFindingTime := p.router.cfg.Clock.Now().Sub(pathFindingStart)
+		monitoring.ObservePathFinding(pathFindingTime)
 		if err != nil {
 			log.Warnf("Failed to find route for payment %v: %v",
 				p.paymentHash, err)
@@ -243,7 +298,9 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 		}
 
 		// We found a route to try, launch a new shard.
-		attempt, outcome, err := shardHandler.launchShard(rt)
+		attempt, outcome, err := shardHandler.launchShard(
+			rt, pathFindingTime, 0,
+		)
 		if err != nil {
 			return [32]byte{}, nil, err
 		}
@@ -258,7 +315,7 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 			// We must inspect the error to know whether it was
 			// critical or not, to decide whether we should
 			// continue trying.
-			err := shardHandler.handleSendError(
+			err := shardHandler.handleShardError(
 				attempt, outcome.err,
 			)
 			if err != nil {
@@ -276,6 +333,78 @@ func (p *paymentLifecycle) resumePayment() ([32]byte, *route.Route, error) {
 	}
 }
 
+// retryFirstHop waits for the backoff of the given failed attempt to expire,
+// then sends a new attempt along its route. If the amount of the failed
+// attempt can no longer be sent along its route, its failure is handled as
+// usual instead.
+func (p *paymentLifecycle) retryFirstHop(shardHandler *shardHandler,
+	failed *firstHopRetry, state *paymentState) error {
+
+	rt := &failed.attempt.Route
+	if rt.ReceiverAmt() > state.remainingAmt ||
+		rt.TotalFees() > state.remainingFees {
+
+		return shardHandler.handleSendError(failed.attempt, failed.err)
+	}
+
+	retry := failed.attempt.FirstHopRetry + 1
+	backoff := p.router.cfg.FirstHopRetry.backoff(retry)
+
+	log.Debugf("Retrying route of attempt %v for payment %v in %v "+
+		"(retry %v): %v", failed.attempt.AttemptID, p.paymentHash,
+		backoff, retry, failed.err)
+
+	// The payment may time out or be canceled while we wait, in which
+	// case it is failed the same way the payment loop does.
+	select {
+	case <-p.router.cfg.Clock.TickAfter(backoff):
+
+	case <-p.timeoutChan:
+		log.Warnf("payment attempt not completed before timeout")
+
+		return p.router.cfg.Control.Fail(
+			p.paymentHash, channeldb.FailureReasonTimeout,
+		)
+
+	case <-p.cancelChan:
+		log.Warnf("payment %v canceled by the caller", p.paymentHash)
+
+		return p.router.cfg.Control.Fail(
+			p.paymentHash, channeldb.FailureReasonCanceled,
+		)
+
+	case <-p.router.quit:
+		return ErrRouterShuttingDown
+	}
+
+	attempt, outcome, err := shardHandler.launchShard(rt, 0, retry)
+	if err != nil {
+		return err
+	}
+
+	if outcome.err != nil {
+		log.Warnf("Failed to launch retry %v of attempt %v for "+
+			"payment %v: %v", retry, failed.attempt.AttemptID,
+			p.paymentHash, outcome.err)
+
+		return shardHandler.handleShardError(attempt, outcome.err)
+	}
+
+	shardHandler.collectResultAsync(attempt)
+
+	return nil
+}
+
+// firstHopRetry is a failed attempt whose route will be retried because its
+// first hop failed with a transient error.
+type firstHopRetry struct {
+	// attempt is the failed attempt.
+	attempt *channeldb.HTLCAttemptInfo
+
+	// err is the error the attempt failed with.
+	err error
+}
+
 // shardHandler holds what is necessary to send and collect the result of
 // shards.
 type shardHandler struct {
@@ -289,6 +418,17 @@ type shardHandler struct {
 	// collectResult directly.
 	shardErrors chan error
 
+	// firstHopRetryable indicates whether the routes of attempts failing
+	// on their first hop with a transient error may be retried according
+	// to the router's first hop retry policy. The caller is expected to
+	// launch the retries queued by the handler.
+	firstHopRetryable bool
+
+	// firstHopRetries holds the attempts whose routes are waiting to be
+	// retried.
+	firstHopRetries    []*firstHopRetry
+	firstHopRetriesMtx sync.Mutex
+
 	// quit is closed to signal the sub goroutines of the payment lifecycle
 	// to stop.
 	quit chan struct{}
@@ -351,19 +491,22 @@ type launchOutcome struct {
 }
 
 // launchShard creates and sends an HTLC attempt along the given route,
-// registering it with the control tower before sending it. It returns the
-// HTLCAttemptInfo that was created for the shard, along with a launchOutcome.
-// The launchOutcome is used to indicate whether the attempt was successfully
-// sent. If the launchOutcome wraps a non-nil error, it means that the attempt
-// was not sent onto the network, so no result will be available in the future
-// for it.
-func (p *shardHandler) launchShard(rt *route.Route) (*channeldb.HTLCAttemptInfo,
-	*launchOutcome, error) {
+// registering it with the control tower before sending it. The path finding
+// time is the time spent finding the route and the first hop retry is the
+// number of times the route was already retried, both are recorded with the
+// attempt. It returns the HTLCAttemptInfo that was created for the shard,
+// along with a launchOutcome. The launchOutcome is used to indicate whether
+// the attempt was successfully sent. If the launchOutcome wraps a non-nil
+// error, it means that the attempt was not sent onto the network, so no result
+// will be available in the future for it.
+func (p *shardHandler) launchShard(rt *route.Route,
+	pathFindingTime time.Duration, firstHopRetry uint32) (
+	*channeldb.HTLCAttemptInfo, *launchOutcome, error) {
 
 	// Using the route received from the payment session, create a new
 	// shard to send.
 	firstHop, htlcAdd, attempt, err := p.createNewPaymentAttempt(
-		rt,
+		rt, pathFindingTime, firstHopRetry,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -439,7 +582,7 @@ func (p *shardHandler) collectResultAsync(attempt *channeldb.HTLCAttemptInfo) {
 		// If a non-critical error was encountered handle it and mark
 		// the payment failed if the failure was terminal.
 		if result.err != nil {
-			err := p.handleSendError(attempt, result.err)
+			err := p.handleShardError(attempt, result.err)
 			if err != nil {
 				select {
 				case p.shardErrors <- err:
@@ -563,11 +706,15 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 
 	// In case of success we atomically store settle result to the DB move
 	// the shard to the settled state.
+	settleTime := p.router.cfg.Clock.Now()
+	monitoring.ObservePaymentAttempt(
+		paymentOutcomeSucceeded, settleTime.Sub(attempt.AttemptTime),
+	)
 	htlcAttempt, err := p.router.cfg.Control.SettleAttempt(
 		p.paymentHash, attempt.AttemptID,
 		&channeldb.HTLCSettleInfo{
 			Preimage:   result.Preimage,
-			SettleTime: p.router.cfg.Clock.Now(),
+			SettleTime: settleTime,
 		},
 	)
 	if err != nil {
@@ -581,9 +728,10 @@ func (p *shardHandler) collectResult(attempt *channeldb.HTLCAttemptInfo) (
 }
 
 // createNewPaymentAttempt creates a new payment attempt from the given route.
-func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
-	lnwire.ShortChannelID, *lnwire.UpdateAddHTLC,
-	*channeldb.HTLCAttemptInfo, error) {
+func (p *shardHandler) createNewPaymentAttempt(rt *route.Route,
+	pathFindingTime time.Duration, firstHopRetry uint32) (
+	lnwire.ShortChannelID,
+	*lnwire.UpdateAddHTLC, *channeldb.HTLCAttemptInfo, error) {
 
 	// Generate a new key to be used for this attempt.
 	sessionKey, err := generateNewSessionKey()
@@ -628,10 +776,12 @@ func (p *shardHandler) createNewPaymentAttempt(rt *route.Route) (
 	// We now have all the information needed to populate
 	// the current attempt information.
 	attempt := &channeldb.HTLCAttemptInfo{
-		AttemptID:   attemptID,
-		AttemptTime: p.router.cfg.Clock.Now(),
-		SessionKey:  sessionKey,
-		Route:       *rt,
+		AttemptID:       attemptID,
+		AttemptTime:     p.router.cfg.Clock.Now(),
+		PathFindingTime: pathFindingTime,
+		SessionKey:      sessionKey,
+		Route:           *rt,
+		FirstHopRetry:   firstHopRetry,
 	}
 
 	return firstHop, htlcAdd, attempt, nil
@@ -669,6 +819,48 @@ func (p *shardHandler) sendPaymentAttempt(
 	return nil
 }
 
+// handleShardError handles the failure of the given attempt. If its first hop
+// failed with a transient error and the router's retry policy allows it, its
+// route is queued to be retried without reporting the failure to mission
+// control. Otherwise the error is handled by handleSendError.
+func (p *shardHandler) handleShardError(attempt *channeldb.HTLCAttemptInfo,
+	sendErr error) error {
+
+	if !p.firstHopRetryable ||
+		!p.router.cfg.FirstHopRetry.shouldRetry(attempt, sendErr) {
+
+		return p.handleSendError(attempt, sendErr)
+	}
+
+	log.Debugf("Attempt %v for payment %v failed on its first hop, "+
+		"queueing retry: %v", attempt.AttemptID, p.paymentHash,
+		sendErr)
+
+	p.firstHopRetriesMtx.Lock()
+	p.firstHopRetries = append(p.firstHopRetries, &firstHopRetry{
+		attempt: attempt,
+		err:     sendErr,
+	})
+	p.firstHopRetriesMtx.Unlock()
+
+	return nil
+}
+
+// nextFirstHopRetry pops the oldest attempt queued to be retried, if any.
+func (p *shardHandler) nextFirstHopRetry() *firstHopRetry {
+	p.firstHopRetriesMtx.Lock()
+	defer p.firstHopRetriesMtx.Unlock()
+
+	if len(p.firstHopRetries) == 0 {
+		return nil
+	}
+
+	retry := p.firstHopRetries[0]
+	p.firstHopRetries = p.firstHopRetries[1:]
+
+	return retry
+}
+
 // handleSendError inspects the given error from the Switch and determines
 // whether we should make another payment attempt, or if it should be
 // considered a terminal error. Terminal errors will be recorded with the
@@ -705,6 +897,9 @@ func (p *shardHandler) failAttempt(attempt *channeldb.HTLCAttemptInfo,
 		sendError,
 		p.router.cfg.Clock.Now(),
 	)
+	monitoring.ObservePaymentAttempt(
+		paymentOutcomeFailed, failInfo.FailTime.Sub(attempt.AttemptTime),
+	)
 
 	return p.router.cfg.Control.FailAttempt(
 		p.paymentHash, attempt.AttemptID,
@@ -1,6 +1,7 @@
 package routing
 
 import (
+	"context"
 	"crypto/rand"
 	"fmt"
 	"sync/atomic"
@@ -673,7 +674,9 @@ func TestRouterPaymentStateMachine(t *testing.T) {
 		// information should be registered with the ControlTower.
 		paymentResult := make(chan error)
 		go func() {
-			_, _, err := router.SendPayment(&payment)
+			_, _, err := router.SendPayment(
+				context.Background(), &payment,
+			)
 			paymentResult <- err
 		}()
 
@@ -814,7 +817,9 @@ func TestRouterPaymentStateMachine(t *testing.T) {
 			case resendPayment:
 				resendResult = make(chan error)
 				go func() {
-					_, _, err := router.SendPayment(&payment)
+					_, _, err := router.SendPayment(
+						context.Background(), &payment,
+					)
 					resendResult <- err
 				}()
 
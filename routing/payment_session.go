@@ -166,6 +166,10 @@ func newPaymentSession(p *LightningPayment,
 	missionControl MissionController, pathFindingConfig PathFindingConfig) (
 	*paymentSession, error) {
 
+	if err := p.validateShardLimits(); err != nil {
+		return nil, err
+	}
+
 	edges, err := RouteHintsToEdges(p.RouteHints, p.Target)
 	if err != nil {
 		return nil, err
@@ -173,6 +177,11 @@ func newPaymentSession(p *LightningPayment,
 
 	logPrefix := fmt.Sprintf("PaymentSession(%x):", p.PaymentHash)
 
+	minShardAmt := DefaultShardMinAmt
+	if p.MinShardAmt != 0 {
+		minShardAmt = p.MinShardAmt
+	}
+
 	return &paymentSession{
 		additionalEdges:   edges,
 		getBandwidthHints: getBandwidthHints,
@@ -181,7 +190,7 @@ func newPaymentSession(p *LightningPayment,
 		getRoutingGraph:   getRoutingGraph,
 		pathFindingConfig: pathFindingConfig,
 		missionControl:    missionControl,
-		minShardAmt:       DefaultShardMinAmt,
+		minShardAmt:       minShardAmt,
 		log:               build.NewPrefixLog(logPrefix, log),
 	}, nil
 }
@@ -218,18 +227,35 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 	// to our destination, respecting the recommendations from
 	// MissionControl.
 	restrictions := &RestrictParams{
-		ProbabilitySource:  p.missionControl.GetProbability,
-		FeeLimit:           feeLimit,
-		OutgoingChannelIDs: p.payment.OutgoingChannelIDs,
-		LastHop:            p.payment.LastHop,
-		CltvLimit:          cltvLimit,
-		DestCustomRecords:  p.payment.DestCustomRecords,
-		DestFeatures:       p.payment.DestFeatures,
-		PaymentAddr:        p.payment.PaymentAddr,
+		ProbabilitySource:          p.missionControl.GetProbability,
+		FeeLimit:                   feeLimit,
+		OutgoingChannelIDs:         p.payment.OutgoingChannelIDs,
+		ExcludedOutgoingChannelIDs: p.payment.ExcludedOutgoingChannelIDs,
+		LastHop:                    p.payment.LastHop,
+		CltvLimit:                  cltvLimit,
+		DestCustomRecords:          p.payment.DestCustomRecords,
+		DestFeatures:               p.payment.DestFeatures,
+		PaymentAddr:                p.payment.PaymentAddr,
 	}
 
 	finalHtlcExpiry := int32(height) + int32(finalCltvDelta)
 
+	// Cap the shard to the largest amount allowed for the partial payments.
+	// If this leaves part of the amount to be sent by other shards, the
+	// payment is split, which must fit within the shard limit.
+	maxShard := p.payment.MaxShardAmt
+	if maxShard != nil && maxAmt > *maxShard {
+		if activeShards+1 >= p.payment.MaxParts {
+			p.log.Debugf("not capping shard to %v because shard "+
+				"limit %v has been reached", *maxShard,
+				p.payment.MaxParts)
+
+			return nil, errNoPathFound
+		}
+
+		maxAmt = *maxShard
+	}
+
 	for {
 		// We'll also obtain a set of bandwidthHints from the lower
 		// layer for each of our outbound channels. This will allow the
@@ -253,7 +279,7 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		sourceVertex := routingGraph.sourceNode()
 
 		// Find a route for the current amount.
-		path, err := p.pathFinder(
+		path, inboundFees, err := p.pathFinder(
 			&graphParams{
 				additionalEdges: p.additionalEdges,
 				bandwidthHints:  bandwidthHints,
@@ -264,6 +290,19 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 			maxAmt, finalHtlcExpiry,
 		)
 
+		// If no route is found, look up the peers of our channels
+		// while the routing graph is still open, to find the largest
+		// amount a single peer can carry.
+		var chanPeers map[uint64]route.Vertex
+		if err == errNoPathFound {
+			var peersErr error
+			chanPeers, peersErr = channelPeers(routingGraph)
+			if peersErr != nil {
+				cleanup()
+				return nil, peersErr
+			}
+		}
+
 		// Close routing graph.
 		cleanup()
 
@@ -289,8 +328,21 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 			}
 
 			// This is where the magic happens. If we can't find a
-			// route, try it for half the amount.
-			maxAmt /= 2
+			// route, try it for half the amount. If one of our
+			// peers can carry more than that, we try the largest
+			// amount it can carry instead, so that shards fill up
+			// the channels to that peer rather than being halved
+			// repeatedly.
+			nextAmt := maxAmt / 2
+			fitAmt := maxShardAmt(
+				bandwidthHints, chanPeers,
+				p.payment.OutgoingChannelIDs,
+				p.payment.ExcludedOutgoingChannelIDs, feeLimit,
+			)
+			if fitAmt > nextAmt && fitAmt < maxAmt {
+				nextAmt = fitAmt
+			}
+			maxAmt = nextAmt
 
 			// Put a lower bound on the minimum shard size.
 			if maxAmt < p.minShardAmt {
@@ -322,7 +374,7 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		// this into a route by applying the time-lock and fee
 		// requirements.
 		route, err := newRoute(
-			sourceVertex, path, height,
+			sourceVertex, path, inboundFees, height,
 			finalHopParams{
 				amt:         maxAmt,
 				totalAmt:    p.payment.Amount,
@@ -338,3 +390,70 @@ func (p *paymentSession) RequestRoute(maxAmt, feeLimit lnwire.MilliAtom,
 		return route, err
 	}
 }
+
+// channelPeers returns the peer of each of the channels of the source node of
+// the routing graph.
+func channelPeers(g routingGraph) (map[uint64]route.Vertex, error) {
+	source := g.sourceNode()
+	chanPeers := make(map[uint64]route.Vertex)
+	err := g.forEachNodeChannel(source, func(
+		info *channeldb.ChannelEdgeInfo, _,
+		_ *channeldb.ChannelEdgePolicy) error {
+
+		peer := info.NodeKey1Bytes
+		if peer == source {
+			peer = info.NodeKey2Bytes
+		}
+		chanPeers[info.ChannelID] = peer
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return chanPeers, nil
+}
+
+// maxShardAmt returns the largest amount that can be sent to a single one of
+// our peers, summing the bandwidth of all our channels to that peer. The
+// channels are restricted to the given outgoing channels if any, skipping the
+// excluded ones. Channels whose peer isn't known are counted on their own. The
+// fee limit is reserved from the bandwidth, as the fees of the rest of the
+// route are added to the amount sent to the peer. Zero is returned if no peer
+// can carry more than the fee limit.
+func maxShardAmt(bandwidthHints map[uint64]lnwire.MilliAtom,
+	chanPeers map[uint64]route.Vertex, outgoingChans,
+	excludedChans []uint64, feeLimit lnwire.MilliAtom) lnwire.MilliAtom {
+
+	outChanRestr := chanIDSet(outgoingChans)
+	outChanExcl := chanIDSet(excludedChans)
+
+	var maxBandwidth lnwire.MilliAtom
+	peerBandwidth := make(map[route.Vertex]lnwire.MilliAtom)
+	for chanID, bandwidth := range bandwidthHints {
+		if outChanRestr != nil {
+			if _, ok := outChanRestr[chanID]; !ok {
+				continue
+			}
+		}
+		if _, ok := outChanExcl[chanID]; ok {
+			continue
+		}
+
+		if peer, ok := chanPeers[chanID]; ok {
+			peerBandwidth[peer] += bandwidth
+			bandwidth = peerBandwidth[peer]
+		}
+
+		if bandwidth > maxBandwidth {
+			maxBandwidth = bandwidth
+		}
+	}
+
+	if maxBandwidth <= feeLimit {
+		return 0
+	}
+
+	return maxBandwidth - feeLimit
+}
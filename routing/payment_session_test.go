@@ -44,7 +44,8 @@ func TestRequestRoute(t *testing.T) {
 	session.pathFinder = func(
 		g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
 		source, target route.Vertex, amt lnwire.MilliAtom,
-		finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy, error) {
+		finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy,
+		[]lnwire.InboundFee, error) {
 
 		// We expect find path to receive a cltv limit excluding the
 		// final cltv delta (including the block padding).
@@ -62,7 +63,7 @@ func TestRequestRoute(t *testing.T) {
 			},
 		}
 
-		return path, nil
+		return path, nil, nil
 	}
 
 	route, err := session.RequestRoute(
@@ -80,8 +81,328 @@ func TestRequestRoute(t *testing.T) {
 	}
 }
 
+// TestRequestRouteSplitToPeer asserts that a payment that can't be routed in
+// full is split into a shard that fills the channels to the peer with the
+// largest bandwidth, rather than being halved, if that peer can carry more than
+// half of the amount.
+func TestRequestRouteSplitToPeer(t *testing.T) {
+	const (
+		height   = 10
+		feeLimit = 10
+	)
+
+	paymentAddr := [32]byte{1}
+	payment := &LightningPayment{
+		CltvLimit:      30,
+		FinalCLTVDelta: 8,
+		Amount:         1000,
+		FeeLimit:       feeLimit,
+		PaymentAddr:    &paymentAddr,
+		MaxParts:       10,
+	}
+
+	// A channel to a first peer and two parallel channels to a second
+	// peer, none of which can carry the full payment.
+	peerA, peerB := route.Vertex{1}, route.Vertex{2}
+	chanPeers := map[uint64]route.Vertex{
+		1: peerA,
+		2: peerB,
+		3: peerB,
+	}
+	bandwidthHints := map[uint64]lnwire.MilliAtom{
+		1: 600,
+		2: 300,
+		3: 400,
+	}
+
+	session, err := newPaymentSession(
+		payment,
+		func() (map[uint64]lnwire.MilliAtom, error) {
+			return bandwidthHints, nil
+		},
+		func() (routingGraph, func(), error) {
+			return &sessionGraph{chanPeers: chanPeers}, func() {},
+				nil
+		},
+		&MissionControl{cfg: &MissionControlConfig{}},
+		PathFindingConfig{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	session.minShardAmt = 100
+
+	// The mock pathfinder only finds a path for amounts that fit in the
+	// channels to the second peer, including the fees.
+	var amts []lnwire.MilliAtom
+	session.pathFinder = func(
+		g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
+		source, target route.Vertex, amt lnwire.MilliAtom,
+		finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy,
+		[]lnwire.InboundFee, error) {
+
+		amts = append(amts, amt)
+		if amt+feeLimit > 700 {
+			return nil, nil, errNoPathFound
+		}
+
+		// The destination must understand payment addresses to be
+		// sent a shard.
+		features := lnwire.NewFeatureVector(
+			lnwire.NewRawFeatureVector(
+				lnwire.TLVOnionPayloadOptional,
+				lnwire.PaymentAddrOptional,
+			), lnwire.Features,
+		)
+
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{
+					Features: features,
+				},
+			},
+		}, nil, nil
+	}
+
+	route, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(amts) != 2 || amts[1] != 690 {
+		t.Fatalf("expected a single split to 690, got %v", amts)
+	}
+	if route.ReceiverAmt() != 690 {
+		t.Fatalf("expected shard of 690, got %v", route.ReceiverAmt())
+	}
+
+	// With the outgoing channel restricted to the channel to the first
+	// peer, the payment is split to fit that channel instead.
+	if amt := maxShardAmt(
+		bandwidthHints, chanPeers, []uint64{1}, nil, feeLimit,
+	); amt != 590 {
+		t.Fatalf("expected max shard amount of 590, got %v", amt)
+	}
+
+	// Excluding one of the channels to the second peer has the same
+	// effect.
+	if amt := maxShardAmt(
+		bandwidthHints, chanPeers, nil, []uint64{3}, feeLimit,
+	); amt != 590 {
+		t.Fatalf("expected max shard amount of 590, got %v", amt)
+	}
+
+	// Channels whose peer isn't known are counted on their own.
+	if amt := maxShardAmt(
+		bandwidthHints, nil, nil, nil, feeLimit,
+	); amt != 590 {
+		t.Fatalf("expected max shard amount of 590, got %v", amt)
+	}
+}
+
+// TestRequestRouteMaxShardAmt asserts that shards are capped to the max shard
+// amount of the payment, as long as the shard limit allows the payment to be
+// split.
+func TestRequestRouteMaxShardAmt(t *testing.T) {
+	const height = 10
+
+	paymentAddr := [32]byte{1}
+	maxShard := lnwire.MilliAtom(400)
+	payment := &LightningPayment{
+		CltvLimit:      30,
+		FinalCLTVDelta: 8,
+		Amount:         1000,
+		FeeLimit:       10,
+		PaymentAddr:    &paymentAddr,
+		MaxParts:       3,
+		MaxShardAmt:    &maxShard,
+		MinShardAmt:    100,
+	}
+
+	session, err := newPaymentSession(
+		payment,
+		func() (map[uint64]lnwire.MilliAtom, error) {
+			return nil, nil
+		},
+		func() (routingGraph, func(), error) {
+			return &sessionGraph{}, func() {}, nil
+		},
+		&MissionControl{cfg: &MissionControlConfig{}},
+		PathFindingConfig{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.minShardAmt != payment.MinShardAmt {
+		t.Fatalf("expected min shard amount %v, got %v",
+			payment.MinShardAmt, session.minShardAmt)
+	}
+
+	session.pathFinder = func(
+		g *graphParams, r *RestrictParams, cfg *PathFindingConfig,
+		source, target route.Vertex, amt lnwire.MilliAtom,
+		finalHtlcExpiry int32) ([]*channeldb.ChannelEdgePolicy,
+		[]lnwire.InboundFee, error) {
+
+		features := lnwire.NewFeatureVector(
+			lnwire.NewRawFeatureVector(
+				lnwire.TLVOnionPayloadOptional,
+				lnwire.PaymentAddrOptional,
+			), lnwire.Features,
+		)
+
+		return []*channeldb.ChannelEdgePolicy{
+			{
+				Node: &channeldb.LightningNode{
+					Features: features,
+				},
+			},
+		}, nil, nil
+	}
+
+	// The first shards are capped to the max shard amount.
+	route, err := session.RequestRoute(
+		payment.Amount, payment.FeeLimit, 0, height,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.ReceiverAmt() != maxShard {
+		t.Fatalf("expected shard of %v, got %v", maxShard,
+			route.ReceiverAmt())
+	}
+
+	// A remainder that fits in a shard is sent in full.
+	route, err = session.RequestRoute(200, payment.FeeLimit, 2, height)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if route.ReceiverAmt() != 200 {
+		t.Fatalf("expected shard of 200, got %v", route.ReceiverAmt())
+	}
+
+	// Capping the last allowed shard would leave part of the payment
+	// unsent, so no route is returned.
+	_, err = session.RequestRoute(600, payment.FeeLimit, 2, height)
+	if err != errNoPathFound {
+		t.Fatalf("expected no path found, got %v", err)
+	}
+}
+
+// TestValidateShardLimits asserts that payments that can't be completed within
+// their shard limits are rejected.
+func TestValidateShardLimits(t *testing.T) {
+	paymentAddr := [32]byte{1}
+	amt := func(a lnwire.MilliAtom) *lnwire.MilliAtom {
+		return &a
+	}
+
+	tests := []struct {
+		name    string
+		payment LightningPayment
+		valid   bool
+	}{
+		{
+			name: "no max shard amount",
+			payment: LightningPayment{
+				Amount:   1000,
+				MaxParts: 1,
+			},
+			valid: true,
+		},
+		{
+			name: "zero max shard amount",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    1,
+				MaxShardAmt: amt(0),
+			},
+		},
+		{
+			name: "max below min shard amount",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    10,
+				PaymentAddr: &paymentAddr,
+				MaxShardAmt: amt(100),
+				MinShardAmt: 200,
+			},
+		},
+		{
+			name: "single shard",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    1,
+				MaxShardAmt: amt(1000),
+			},
+			valid: true,
+		},
+		{
+			name: "split without payment address",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    10,
+				MaxShardAmt: amt(500),
+			},
+		},
+		{
+			name: "split within shard limit",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    4,
+				PaymentAddr: &paymentAddr,
+				MaxShardAmt: amt(250),
+			},
+			valid: true,
+		},
+		{
+			name: "split exceeding shard limit",
+			payment: LightningPayment{
+				Amount:      1000,
+				MaxParts:    3,
+				PaymentAddr: &paymentAddr,
+				MaxShardAmt: amt(300),
+			},
+		},
+	}
+
+	for _, test := range tests {
+		err := test.payment.validateShardLimits()
+		if test.valid && err != nil {
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Fatalf("%v: expected error", test.name)
+		}
+	}
+}
+
 type sessionGraph struct {
 	routingGraph
+
+	// chanPeers holds the peer of each channel of the source node.
+	chanPeers map[uint64]route.Vertex
+}
+
+func (g *sessionGraph) forEachNodeChannel(nodePub route.Vertex,
+	cb func(*channeldb.ChannelEdgeInfo, *channeldb.ChannelEdgePolicy,
+		*channeldb.ChannelEdgePolicy) error) error {
+
+	for chanID, peer := range g.chanPeers {
+		info := &channeldb.ChannelEdgeInfo{
+			ChannelID:     chanID,
+			NodeKey1Bytes: nodePub,
+			NodeKey2Bytes: peer,
+		}
+		if err := cb(info, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (g *sessionGraph) sourceNode() route.Vertex {
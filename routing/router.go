@@ -2,6 +2,7 @@ package routing
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"runtime"
 	"sync"
@@ -244,6 +245,11 @@ type ChannelPolicy struct {
 	// MinHTLC is the minimum HTLC size including fees we are allowed to
 	// forward over this channel.
 	MinHTLC *lnwire.MilliAtom
+
+	// InboundFee is the fee charged, or discounted if negative, on HTLCs
+	// received through this channel. If nil, the current inbound fee is
+	// kept, while a zero inbound fee removes it.
+	InboundFee *lnwire.InboundFee
 }
 
 // Config defines the configuration for the ChannelRouter. ALL elements within
@@ -319,6 +325,11 @@ type Config struct {
 	// PathFindingConfig defines global path finding parameters.
 	PathFindingConfig PathFindingConfig
 
+	// FirstHopRetry defines how payment attempts failing transiently on
+	// their first hop are retried before the failure is reported to
+	// mission control.
+	FirstHopRetry FirstHopRetryPolicy
+
 	// Clock is mockable time provider.
 	Clock clock.Clock
 }
@@ -555,7 +566,7 @@ func (r *ChannelRouter) Start() error {
 			// also set a zero fee limit, as no more routes should
 			// be tried.
 			_, _, err := r.sendPayment(
-				payment.Info.Value, 0,
+				context.Background(), payment.Info.Value, 0,
 				payment.Info.PaymentHash, 0, paySession,
 			)
 			if err != nil {
@@ -1419,7 +1430,11 @@ type routingMsg struct {
 // FindRoute attempts to query the ChannelRouter for the optimum path to a
 // particular target destination to which it is able to send `amt` after
 // factoring in channel capacities and cumulative fees along the route.
-func (r *ChannelRouter) FindRoute(source, target route.Vertex,
+//
+// The passed context can be used to abort path finding, in which case the
+// error of the context is returned.
+func (r *ChannelRouter) FindRoute(ctx context.Context,
+	source, target route.Vertex,
 	amt lnwire.MilliAtom, restrictions *RestrictParams,
 	destCustomRecords record.CustomSet,
 	routeHints map[route.Vertex][]*channeldb.ChannelEdgePolicy,
@@ -1458,11 +1473,12 @@ func (r *ChannelRouter) FindRoute(source, target route.Vertex,
 		}
 	}()
 
-	path, err := findPath(
+	path, inboundFees, err := findPath(
 		&graphParams{
 			additionalEdges: routeHints,
 			bandwidthHints:  bandwidthHints,
 			graph:           routingTx,
+			ctx:             ctx,
 		},
 		restrictions,
 		&r.cfg.PathFindingConfig,
@@ -1474,7 +1490,7 @@ func (r *ChannelRouter) FindRoute(source, target route.Vertex,
 
 	// Create the route with absolute time lock values.
 	route, err := newRoute(
-		source, path, uint32(currentHeight),
+		source, path, inboundFees, uint32(currentHeight),
 		finalHopParams{
 			amt:       amt,
 			totalAmt:  amt,
@@ -1617,6 +1633,10 @@ type LightningPayment struct {
 	// first hop. If nil, any channel may be used.
 	OutgoingChannelIDs []uint64
 
+	// ExcludedOutgoingChannelIDs is the list of channels that must not be
+	// used for the first hop, even if listed in OutgoingChannelIDs.
+	ExcludedOutgoingChannelIDs []uint64
+
 	// LastHop is the pubkey of the last node before the final destination
 	// is reached. If nil, any node may be used.
 	LastHop *route.Vertex
@@ -1645,8 +1665,52 @@ type LightningPayment struct {
 	DestCustomRecords record.CustomSet
 
 	// MaxParts is the maximum number of partial payments that may be used
-	// to complete the full amount.
+	// to complete the full amount. A value of one disables splitting.
 	MaxParts uint32
+
+	// MaxShardAmt is the largest amount that may be sent by a single
+	// partial payment. If nil, a partial payment may carry any amount.
+	MaxShardAmt *lnwire.MilliAtom
+
+	// MinShardAmt is the amount beyond which the payment won't be split
+	// further if no route is found. If zero, DefaultShardMinAmt is used.
+	MinShardAmt lnwire.MilliAtom
+}
+
+// validateShardLimits ensures the payment can be completed within the limits
+// set on its partial payments.
+func (p *LightningPayment) validateShardLimits() error {
+	if p.MaxShardAmt == nil {
+		return nil
+	}
+	maxShardAmt := *p.MaxShardAmt
+
+	switch {
+	case maxShardAmt == 0:
+		return errors.New("max shard amount must be positive")
+
+	case maxShardAmt < p.MinShardAmt:
+		return fmt.Errorf("max shard amount %v is below the min shard "+
+			"amount %v", maxShardAmt, p.MinShardAmt)
+
+	// A payment that fits in a single shard doesn't need to be split.
+	case maxShardAmt >= p.Amount:
+		return nil
+
+	case p.PaymentAddr == nil:
+		return fmt.Errorf("payment of %v must be split into shards of "+
+			"at most %v, which requires a payment address",
+			p.Amount, maxShardAmt)
+	}
+
+	numShards := (p.Amount + maxShardAmt - 1) / maxShardAmt
+	if uint64(numShards) > uint64(p.MaxParts) {
+		return fmt.Errorf("payment of %v requires %d shards of at "+
+			"most %v, exceeding the limit of %d parts", p.Amount,
+			numShards, maxShardAmt, p.MaxParts)
+	}
+
+	return nil
 }
 
 // SendPayment attempts to send a payment as described within the passed
@@ -1656,8 +1720,11 @@ type LightningPayment struct {
 // will be returned which describes the path the successful payment traversed
 // within the network to reach the destination. Additionally, the payment
 // preimage will also be returned.
-func (r *ChannelRouter) SendPayment(payment *LightningPayment) ([32]byte,
-	*route.Route, error) {
+//
+// Once the passed context is done, no further attempts are made and the
+// payment fails as soon as the attempts in flight are resolved.
+func (r *ChannelRouter) SendPayment(ctx context.Context,
+	payment *LightningPayment) ([32]byte, *route.Route, error) {
 
 	paySession, err := r.preparePayment(payment)
 	if err != nil {
@@ -1670,7 +1737,7 @@ func (r *ChannelRouter) SendPayment(payment *LightningPayment) ([32]byte,
 	// Since this is the first time this payment is being made, we pass nil
 	// for the existing attempt.
 	return r.sendPayment(
-		payment.Amount, payment.FeeLimit, payment.PaymentHash,
+		ctx, payment.Amount, payment.FeeLimit, payment.PaymentHash,
 		payment.PayAttemptTimeout, paySession,
 	)
 }
@@ -1693,8 +1760,9 @@ func (r *ChannelRouter) SendPaymentAsync(payment *LightningPayment) error {
 			spewPayment(payment))
 
 		_, _, err := r.sendPayment(
-			payment.Amount, payment.FeeLimit, payment.PaymentHash,
-			payment.PayAttemptTimeout, paySession,
+			context.Background(), payment.Amount, payment.FeeLimit,
+			payment.PaymentHash, payment.PayAttemptTimeout,
+			paySession,
 		)
 		if err != nil {
 			log.Errorf("Payment with hash %x failed: %v",
@@ -1810,7 +1878,7 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, rt *route.Route) (
 	}
 
 	var shardError error
-	attempt, outcome, err := sh.launchShard(rt)
+	attempt, outcome, err := sh.launchShard(rt, 0, 0)
 
 	// With SendToRoute, it can happen that the route exceeds protocol
 	// constraints. Mark the payment as failed with an internal error.
@@ -1883,7 +1951,7 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, rt *route.Route) (
 // payment. If the payment succeeds, then a non-nil Route will be returned
 // which describes the path the successful payment traversed within the network
 // to reach the destination. Additionally, the payment preimage will also be
-// returned.
+// returned. No new attempts are made once the passed context is done.
 //
 // The existing attempt argument should be set to nil if this is a payment that
 // haven't had any payment attempt sent to the switch yet. If it has had an
@@ -1893,7 +1961,7 @@ func (r *ChannelRouter) SendToRoute(hash lntypes.Hash, rt *route.Route) (
 // carry out its execution. After restarts it is safe, and assumed, that the
 // router will call this method for every payment still in-flight according to
 // the ControlTower.
-func (r *ChannelRouter) sendPayment(
+func (r *ChannelRouter) sendPayment(ctx context.Context,
 	totalAmt, feeLimit lnwire.MilliAtom, paymentHash lntypes.Hash,
 	timeout time.Duration,
 	paySession PaymentSession) ([32]byte, *route.Route, error) {
@@ -1913,6 +1981,7 @@ func (r *ChannelRouter) sendPayment(
 		feeLimit:      feeLimit,
 		paymentHash:   paymentHash,
 		paySession:    paySession,
+		cancelChan:    ctx.Done(),
 		currentHeight: currentHeight,
 	}
 
@@ -2403,6 +2472,44 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 	log.Tracef("BuildRoute called: hopsCount=%v, amt=%v",
 		len(hops), amt)
 
+	return r.buildRoute(amt, hops, outgoingChan, nil, finalCltvDelta)
+}
+
+// BuildCircularRoute returns a fully specified route that delivers amt back to
+// ourselves. The route leaves through outgoingChan, traverses the given hops
+// and returns through incomingChan, so the hops must start with the peer of the
+// outgoing channel and end with the peer of the incoming channel. This is used
+// to rebalance our channels.
+func (r *ChannelRouter) BuildCircularRoute(amt lnwire.MilliAtom,
+	hops []route.Vertex, outgoingChan, incomingChan uint64,
+	finalCltvDelta int32) (*route.Route, error) {
+
+	log.Tracef("BuildCircularRoute called: hopsCount=%v, amt=%v, "+
+		"outgoingChan=%v, incomingChan=%v", len(hops), amt,
+		outgoingChan, incomingChan)
+
+	if len(hops) == 0 {
+		return nil, fmt.Errorf("circular route requires at least " +
+			"one hop")
+	}
+
+	circularHops := make([]route.Vertex, 0, len(hops)+1)
+	circularHops = append(circularHops, hops...)
+	circularHops = append(circularHops, r.selfNode.PubKeyBytes)
+
+	return r.buildRoute(
+		&amt, circularHops, &outgoingChan, &incomingChan,
+		finalCltvDelta,
+	)
+}
+
+// buildRoute returns a fully specified route based on a list of pubkeys,
+// optionally restricting the local channels the route leaves through and, for
+// circular routes, returns through.
+func (r *ChannelRouter) buildRoute(amt *lnwire.MilliAtom,
+	hops []route.Vertex, outgoingChan, incomingChan *uint64,
+	finalCltvDelta int32) (*route.Route, error) {
+
 	var outgoingChans map[uint64]struct{}
 	if outgoingChan != nil {
 		outgoingChans = map[uint64]struct{}{
@@ -2410,6 +2517,13 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 		}
 	}
 
+	var incomingChans map[uint64]struct{}
+	if incomingChan != nil {
+		incomingChans = map[uint64]struct{}{
+			*incomingChan: {},
+		}
+	}
+
 	// If no amount is specified, we need to build a route for the minimum
 	// amount that this route can carry.
 	useMinAmt := amt == nil
@@ -2460,6 +2574,9 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 	}()
 
 	// Traverse hops backwards to accumulate fees in the running amounts.
+	// The outbound fee of the previously visited hop is kept to bound a
+	// negative inbound fee charged by that same hop.
+	var prevOutFee lnwire.MilliAtom
 	source := r.selfNode.PubKeyBytes
 	for i := len(hops) - 1; i >= 0; i-- {
 		toNode := hops[i]
@@ -2476,6 +2593,7 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 		// Build unified policies for this hop based on the channels
 		// known in the graph.
 		u := newUnifiedPolicies(source, toNode, outgoingChans)
+		u.inChanRestr = incomingChans
 
 		err := u.addGraphPolicies(routingTx)
 		if err != nil {
@@ -2501,7 +2619,9 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 
 		// Get a forwarding policy for the specific amount that we want
 		// to forward.
-		policy := unifiedPolicy.getPolicy(runningAmt, bandwidthHints)
+		policy, inboundFee := unifiedPolicy.getPolicy(
+			runningAmt, bandwidthHints,
+		)
 		if policy == nil {
 			return nil, ErrNoChannel{
 				fromNode: fromNode,
@@ -2509,9 +2629,23 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 			}
 		}
 
+		// Add the inbound fee that the receiving hop charges for this
+		// channel, unless it is the final destination.
+		if i < len(hops)-1 {
+			inboundFeeAmt := inboundFee.Fee(runningAmt)
+			if inboundFeeAmt < -int64(prevOutFee) {
+				inboundFeeAmt = -int64(prevOutFee)
+			}
+			runningAmt = lnwire.MilliAtom(
+				int64(runningAmt) + inboundFeeAmt,
+			)
+		}
+
 		// Add fee for this hop.
+		prevOutFee = 0
 		if !localChan {
-			runningAmt += policy.ComputeFee(runningAmt)
+			prevOutFee = policy.ComputeFee(runningAmt)
+			runningAmt += prevOutFee
 		}
 
 		log.Tracef("Select channel %v at position %v", policy.ChannelID, i)
@@ -2523,10 +2657,15 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 	// total amount, we make a forward pass. Because the amount may have
 	// been increased in the backward pass, fees need to be recalculated and
 	// amount ranges re-checked.
-	var pathEdges []*channeldb.ChannelEdgePolicy
+	var (
+		pathEdges   []*channeldb.ChannelEdgePolicy
+		inboundFees []lnwire.InboundFee
+	)
 	receiverAmt := runningAmt
 	for i, edge := range edges {
-		policy := edge.getPolicy(receiverAmt, bandwidthHints)
+		policy, inboundFee := edge.getPolicy(
+			receiverAmt, bandwidthHints,
+		)
 		if policy == nil {
 			return nil, ErrNoChannel{
 				fromNode: hops[i-1],
@@ -2535,18 +2674,21 @@ func (r *ChannelRouter) BuildRoute(amt *lnwire.MilliAtom,
 		}
 
 		if i > 0 {
-			// Decrease the amount to send while going forward.
-			receiverAmt -= policy.ComputeFeeFromIncoming(
-				receiverAmt,
+			// Decrease the amount to send while going forward,
+			// taking into account the inbound fee the sending hop
+			// charges for the channel it received the HTLC over.
+			receiverAmt -= forwardingFeeFromIncoming(
+				policy, inboundFees[i-1], receiverAmt,
 			)
 		}
 
 		pathEdges = append(pathEdges, policy)
+		inboundFees = append(inboundFees, inboundFee)
 	}
 
 	// Build and return the final route.
 	return newRoute(
-		source, pathEdges, uint32(height),
+		source, pathEdges, inboundFees, uint32(height),
 		finalHopParams{
 			amt:       receiverAmt,
 			totalAmt:  receiverAmt,
@@ -2,10 +2,12 @@ package routing
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"image/color"
 	"math"
 	"math/rand"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -221,7 +223,7 @@ func TestFindRoutesWithFeeLimit(t *testing.T) {
 	}
 
 	route, err := ctx.router.FindRoute(
-		ctx.router.selfNode.PubKeyBytes,
+		context.Background(), ctx.router.selfNode.PubKeyBytes,
 		target, paymentAmt, restrictions, nil, nil,
 		MinCLTVDelta,
 	)
@@ -246,6 +248,57 @@ func TestFindRoutesWithFeeLimit(t *testing.T) {
 	}
 }
 
+// TestCanceledRouteRequests asserts that path finding and payments stop once
+// the context of the caller is done.
+func TestCanceledRouteRequests(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(
+		startingBlockHeight, basicGraphFilePath,
+	)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	canceledCtx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	target := ctx.aliases["sophon"]
+	paymentAmt := lnwire.NewMAtomsFromAtoms(100)
+	_, err = ctx.router.FindRoute(
+		canceledCtx, ctx.router.selfNode.PubKeyBytes,
+		target, paymentAmt, noRestrictions, nil, nil,
+		MinCLTVDelta,
+	)
+	if err != context.Canceled {
+		t.Fatalf("expected path finding to be canceled, got: %v", err)
+	}
+
+	// A payment of a caller that's gone shouldn't launch any attempts.
+	var numAttempts uint32
+	ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+		func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+			atomic.AddUint32(&numAttempts, 1)
+			return [32]byte{}, nil
+		})
+
+	payment := LightningPayment{
+		Target:      target,
+		Amount:      paymentAmt,
+		FeeLimit:    noFeeLimit,
+		PaymentHash: [32]byte{1},
+	}
+	_, _, err = ctx.router.SendPayment(canceledCtx, &payment)
+	if err != channeldb.FailureReasonCanceled {
+		t.Fatalf("expected payment to be canceled, got: %v", err)
+	}
+	if n := atomic.LoadUint32(&numAttempts); n != 0 {
+		t.Fatalf("expected no payment attempts, got %v", n)
+	}
+}
+
 // TestSendPaymentRouteFailureFallback tests that when sending a payment, if
 // one of the target routes is seen as unavailable, then the next route in the
 // queue is used instead. This process should continue until either a payment
@@ -296,7 +349,9 @@ func TestSendPaymentRouteFailureFallback(t *testing.T) {
 
 	// Send off the payment request to the router, route through pham nuwen
 	// should've been selected as a fall back and succeeded correctly.
-	paymentPreImage, route, err := ctx.router.SendPayment(&payment)
+	paymentPreImage, route, err := ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable to send payment: %v", err)
 	}
@@ -551,7 +606,9 @@ func TestSendPaymentErrorRepeatedFeeInsufficient(t *testing.T) {
 
 	// Send off the payment request to the router, route through satoshi
 	// should've been selected as a fall back and succeeded correctly.
-	paymentPreImage, route, err := ctx.router.SendPayment(&payment)
+	paymentPreImage, route, err := ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable to send payment: %v", err)
 	}
@@ -677,7 +734,9 @@ func TestSendPaymentErrorNonFinalTimeLockErrors(t *testing.T) {
 	// Send off the payment request to the router, this payment should
 	// succeed as we should actually go through Pham Nuwen in order to get
 	// to Sophon, even though he has higher fees.
-	paymentPreImage, rt, err := ctx.router.SendPayment(&payment)
+	paymentPreImage, rt, err := ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable to send payment: %v", err)
 	}
@@ -705,7 +764,9 @@ func TestSendPaymentErrorNonFinalTimeLockErrors(t *testing.T) {
 	// w.r.t to the block height, and instead go through Pham Nuwen. We
 	// flip a bit in the payment hash to allow resending this payment.
 	payment.PaymentHash[1] ^= 1
-	paymentPreImage, rt, err = ctx.router.SendPayment(&payment)
+	paymentPreImage, rt, err = ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable to send payment: %v", err)
 	}
@@ -775,7 +836,9 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 
 	// When we try to dispatch that payment, we should receive an error as
 	// both attempts should fail and cause both routes to be pruned.
-	_, _, err = ctx.router.SendPayment(&payment)
+	_, _, err = ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err == nil {
 		t.Fatalf("payment didn't return error")
 	}
@@ -828,7 +891,9 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 	// This shouldn't return an error, as we'll make a payment attempt via
 	// the pham nuwen channel based on the assumption that there might be an
 	// intermittent issue with the songoku <-> sophon channel.
-	paymentPreImage, rt, err := ctx.router.SendPayment(&payment)
+	paymentPreImage, rt, err := ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable send payment: %v", err)
 	}
@@ -872,7 +937,9 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 
 	// We flip a bit in the payment hash to allow resending this payment.
 	payment.PaymentHash[1] ^= 1
-	paymentPreImage, rt, err = ctx.router.SendPayment(&payment)
+	paymentPreImage, rt, err = ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("unable to send payment: %v", err)
 	}
@@ -900,6 +967,128 @@ func TestSendPaymentErrorPathPruning(t *testing.T) {
 	}
 }
 
+// TestSendPaymentFirstHopRetry tests that routes failing transiently on their
+// first hop are retried according to the first hop retry policy, without the
+// failures being reported to mission control.
+func TestSendPaymentFirstHopRetry(t *testing.T) {
+	t.Parallel()
+
+	const startingBlockHeight = 101
+	ctx, cleanUp, err := createTestCtxFromFile(startingBlockHeight, basicGraphFilePath)
+	if err != nil {
+		t.Fatalf("unable to create router: %v", err)
+	}
+	defer cleanUp()
+
+	ctx.router.cfg.FirstHopRetry = FirstHopRetryPolicy{
+		MaxRetries: 2,
+	}
+
+	var preImage [32]byte
+	copy(preImage[:], bytes.Repeat([]byte{9}, 32))
+
+	// sendPayment sends a payment to sophon, failing the given number of
+	// attempts on the first hop of the first route tried with an unknown
+	// next peer error. It returns the first hops of the attempts sent.
+	sendPayment := func(payHash [32]byte, firstHopFailures int) (
+		[]lnwire.ShortChannelID, error) {
+
+		var (
+			firstHops []lnwire.ShortChannelID
+			mtx       sync.Mutex
+		)
+		ctx.router.cfg.Payer.(*mockPaymentAttemptDispatcher).setPaymentResult(
+			func(firstHop lnwire.ShortChannelID) ([32]byte, error) {
+				mtx.Lock()
+				defer mtx.Unlock()
+
+				firstHops = append(firstHops, firstHop)
+				if len(firstHops) <= firstHopFailures &&
+					firstHop == firstHops[0] {
+
+					return [32]byte{}, htlcswitch.NewLinkError(
+						&lnwire.FailUnknownNextPeer{},
+					)
+				}
+
+				return preImage, nil
+			})
+
+		payment := LightningPayment{
+			Target:      ctx.aliases["sophon"],
+			Amount:      lnwire.NewMAtomsFromAtoms(1000),
+			FeeLimit:    noFeeLimit,
+			PaymentHash: payHash,
+		}
+		_, _, err := ctx.router.SendPayment(
+			context.Background(), &payment,
+		)
+
+		mtx.Lock()
+		defer mtx.Unlock()
+		return firstHops, err
+	}
+
+	// The first two failures of the first hop are retried along the same
+	// route, and the third attempt succeeds.
+	payHash := [32]byte{1}
+	firstHops, err := sendPayment(payHash, 2)
+	if err != nil {
+		t.Fatalf("unable to send payment: %v", err)
+	}
+	if len(firstHops) != 3 {
+		t.Fatalf("expected 3 attempts, got %v", len(firstHops))
+	}
+	for _, firstHop := range firstHops {
+		if firstHop != firstHops[0] {
+			t.Fatalf("expected all attempts along the same route")
+		}
+	}
+
+	p, err := ctx.router.cfg.Control.FetchPayment(payHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, htlc := range p.HTLCs {
+		if htlc.FirstHopRetry != uint32(i) {
+			t.Fatalf("expected attempt %v to be retry %v, got %v",
+				i, i, htlc.FirstHopRetry)
+		}
+	}
+
+	// The retried failures were not reported to mission control.
+	mc := ctx.router.cfg.MissionControl.(*MissionControl)
+	for _, pair := range mc.GetHistorySnapshot().Pairs {
+		if !pair.FailTime.IsZero() {
+			t.Fatalf("unexpected failure reported for pair %v",
+				pair.Pair)
+		}
+	}
+
+	// Once the retries are exhausted, the failure is handled as usual and
+	// the next attempt is made along a newly found route.
+	payHash = [32]byte{2}
+	firstHops, err = sendPayment(payHash, 3)
+	if err != nil {
+		t.Fatalf("unable to send payment: %v", err)
+	}
+	if len(firstHops) != 4 {
+		t.Fatalf("expected 4 attempts, got %v", len(firstHops))
+	}
+
+	p, err = ctx.router.cfg.Control.FetchPayment(payHash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedRetries := []uint32{0, 1, 2, 0}
+	for i, htlc := range p.HTLCs {
+		if htlc.FirstHopRetry != expectedRetries[i] {
+			t.Fatalf("expected attempt %v to be retry %v, got %v",
+				i, expectedRetries[i], htlc.FirstHopRetry)
+		}
+	}
+}
+
 // TestAddProof checks that we can update the channel proof after channel
 // info was added to the database.
 func TestAddProof(t *testing.T) {
@@ -1279,7 +1468,7 @@ func TestAddEdgeUnknownVertexes(t *testing.T) {
 	var targetPubKeyBytes route.Vertex
 	copy(targetPubKeyBytes[:], targetNode.SerializeCompressed())
 	_, err = ctx.router.FindRoute(
-		ctx.router.selfNode.PubKeyBytes,
+		context.Background(), ctx.router.selfNode.PubKeyBytes,
 		targetPubKeyBytes, paymentAmt, noRestrictions, nil, nil,
 		MinCLTVDelta,
 	)
@@ -1322,7 +1511,7 @@ func TestAddEdgeUnknownVertexes(t *testing.T) {
 	// Should still be able to find the route, and the info should be
 	// updated.
 	_, err = ctx.router.FindRoute(
-		ctx.router.selfNode.PubKeyBytes,
+		context.Background(), ctx.router.selfNode.PubKeyBytes,
 		targetPubKeyBytes, paymentAmt, noRestrictions, nil, nil,
 		MinCLTVDelta,
 	)
@@ -2536,7 +2725,9 @@ func TestUnknownErrorSource(t *testing.T) {
 	// the route a->b->c is tried first. An unreadable faiure is returned
 	// which should pruning the channel a->b. We expect the payment to
 	// succeed via a->d.
-	_, _, err = ctx.router.SendPayment(&payment)
+	_, _, err = ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err != nil {
 		t.Fatalf("expected payment to succeed, but got: %v", err)
 	}
@@ -2559,7 +2750,9 @@ func TestUnknownErrorSource(t *testing.T) {
 	// Send off the payment request to the router. We expect the payment to
 	// fail because both routes have been pruned.
 	payment.PaymentHash = lntypes.Hash{1}
-	_, _, err = ctx.router.SendPayment(&payment)
+	_, _, err = ctx.router.SendPayment(
+		context.Background(), &payment,
+	)
 	if err == nil {
 		t.Fatalf("expected payment to fail")
 	}
@@ -3059,4 +3252,27 @@ func TestBuildRoute(t *testing.T) {
 	if errNoChannel.fromNode != ctx.aliases["a"] {
 		t.Fatalf("unexpected no channel error node")
 	}
+
+	// Build a circular route that leaves through channel 6 and returns
+	// through channel 1. Only hop b charges a fee for forwarding back to
+	// us, which is 2 sats.
+	hops = []route.Vertex{ctx.aliases["b"]}
+	rt, err = ctx.router.BuildCircularRoute(amt, hops, 6, 1, 40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkHops(rt, []uint64{6, 1})
+	if rt.TotalAmount != 102000 {
+		t.Fatalf("unexpected total amount %v", rt.TotalAmount)
+	}
+	if rt.Hops[1].PubKeyBytes != ctx.aliases["a"] {
+		t.Fatalf("circular route doesn't end at the source node")
+	}
+
+	// A circular route can't return through a channel that doesn't
+	// connect the last hop to us.
+	_, err = ctx.router.BuildCircularRoute(amt, hops, 6, 2, 40)
+	if _, ok := err.(ErrNoChannel); !ok {
+		t.Fatalf("expected no channel error, but got %v", err)
+	}
 }
@@ -22,6 +22,15 @@ type unifiedPolicies struct {
 	// outChanRestr is an optional outgoing channel restriction for the
 	// local channel to use.
 	outChanRestr map[uint64]struct{}
+
+	// outChanExcl is an optional set of local channels that must not be
+	// used.
+	outChanExcl map[uint64]struct{}
+
+	// inChanRestr is an optional incoming channel restriction for the
+	// local channel to use when the to node is the source node, as is the
+	// case for the last hop of a circular route.
+	inChanRestr map[uint64]struct{}
 }
 
 // newUnifiedPolicies instantiates a new unifiedPolicies object. Channel
@@ -38,9 +47,11 @@ func newUnifiedPolicies(sourceNode, toNode route.Vertex,
 }
 
 // addPolicy adds a single channel policy. Capacity may be zero if unknown
-// (light clients).
+// (light clients). The inbound fee is the one charged by the to node for HTLCs
+// received over the channel.
 func (u *unifiedPolicies) addPolicy(fromNode route.Vertex,
-	edge *channeldb.ChannelEdgePolicy, capacity dcrutil.Amount) {
+	edge *channeldb.ChannelEdgePolicy, inboundFee lnwire.InboundFee,
+	capacity dcrutil.Amount) {
 
 	localChan := fromNode == u.sourceNode
 
@@ -51,6 +62,20 @@ func (u *unifiedPolicies) addPolicy(fromNode route.Vertex,
 		}
 	}
 
+	// Skip excluded outgoing channels.
+	if localChan {
+		if _, ok := u.outChanExcl[edge.ChannelID]; ok {
+			return
+		}
+	}
+
+	// Skip channels if there is an incoming channel restriction.
+	if u.toNode == u.sourceNode && u.inChanRestr != nil {
+		if _, ok := u.inChanRestr[edge.ChannelID]; !ok {
+			return
+		}
+	}
+
 	// Update the policies map.
 	policy, ok := u.policies[fromNode]
 	if !ok {
@@ -61,15 +86,16 @@ func (u *unifiedPolicies) addPolicy(fromNode route.Vertex,
 	}
 
 	policy.edges = append(policy.edges, &unifiedPolicyEdge{
-		policy:   edge,
-		capacity: capacity,
+		policy:     edge,
+		inboundFee: inboundFee,
+		capacity:   capacity,
 	})
 }
 
 // addGraphPolicies adds all policies that are known for the toNode in the
 // graph.
 func (u *unifiedPolicies) addGraphPolicies(g routingGraph) error {
-	cb := func(edgeInfo *channeldb.ChannelEdgeInfo, _,
+	cb := func(edgeInfo *channeldb.ChannelEdgeInfo, outEdge,
 		inEdge *channeldb.ChannelEdgePolicy) error {
 
 		// If there is no edge policy for this candidate node, skip.
@@ -85,8 +111,25 @@ func (u *unifiedPolicies) addGraphPolicies(g routingGraph) error {
 			return err
 		}
 
+		// The inbound fee charged by the to node for HTLCs received
+		// over this channel is part of its own policy of the channel.
+		var inboundFee lnwire.InboundFee
+		if outEdge != nil {
+			fee, err := lnwire.ExtractInboundFee(
+				outEdge.ExtraOpaqueData,
+			)
+			if err != nil {
+				log.Debugf("Unable to parse inbound fee of "+
+					"channel %v: %v", outEdge.ChannelID,
+					err)
+			}
+			if fee != nil {
+				inboundFee = *fee
+			}
+		}
+
 		// Add this policy to the unified policies map.
-		u.addPolicy(fromNode, inEdge, edgeInfo.Capacity)
+		u.addPolicy(fromNode, inEdge, inboundFee, edgeInfo.Capacity)
 
 		return nil
 	}
@@ -98,8 +141,9 @@ func (u *unifiedPolicies) addGraphPolicies(g routingGraph) error {
 // unifiedPolicyEdge is the individual channel data that is kept inside an
 // unifiedPolicy object.
 type unifiedPolicyEdge struct {
-	policy   *channeldb.ChannelEdgePolicy
-	capacity dcrutil.Amount
+	policy     *channeldb.ChannelEdgePolicy
+	inboundFee lnwire.InboundFee
+	capacity   dcrutil.Amount
 }
 
 // amtInRange checks whether an amount falls within the valid range for a
@@ -136,10 +180,12 @@ type unifiedPolicy struct {
 }
 
 // getPolicy returns the optimal policy to use for this connection given a
-// specific amount to send. It differentiates between local and network
-// channels.
+// specific amount to send, along with the inbound fee the to node charges for
+// HTLCs received over the channel of the policy. It differentiates between
+// local and network channels.
 func (u *unifiedPolicy) getPolicy(amt lnwire.MilliAtom,
-	bandwidthHints map[uint64]lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
+	bandwidthHints map[uint64]lnwire.MilliAtom) (*channeldb.ChannelEdgePolicy,
+	lnwire.InboundFee) {
 
 	if u.localChan {
 		return u.getPolicyLocal(amt, bandwidthHints)
@@ -151,11 +197,13 @@ func (u *unifiedPolicy) getPolicy(amt lnwire.MilliAtom,
 // getPolicyLocal returns the optimal policy to use for this local connection
 // given a specific amount to send.
 func (u *unifiedPolicy) getPolicyLocal(amt lnwire.MilliAtom,
-	bandwidthHints map[uint64]lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
+	bandwidthHints map[uint64]lnwire.MilliAtom) (*channeldb.ChannelEdgePolicy,
+	lnwire.InboundFee) {
 
 	var (
-		bestPolicy   *channeldb.ChannelEdgePolicy
-		maxBandwidth lnwire.MilliAtom
+		bestPolicy     *channeldb.ChannelEdgePolicy
+		bestInboundFee lnwire.InboundFee
+		maxBandwidth   lnwire.MilliAtom
 	)
 
 	for _, edge := range u.edges {
@@ -197,21 +245,23 @@ func (u *unifiedPolicy) getPolicyLocal(amt lnwire.MilliAtom,
 
 		// Update best policy.
 		bestPolicy = edge.policy
+		bestInboundFee = edge.inboundFee
 	}
 
-	return bestPolicy
+	return bestPolicy, bestInboundFee
 }
 
 // getPolicyNetwork returns the optimal policy to use for this connection given
 // a specific amount to send. The goal is to return a policy that maximizes the
 // probability of a successful forward in a non-strict forwarding context.
 func (u *unifiedPolicy) getPolicyNetwork(
-	amt lnwire.MilliAtom) *channeldb.ChannelEdgePolicy {
+	amt lnwire.MilliAtom) (*channeldb.ChannelEdgePolicy, lnwire.InboundFee) {
 
 	var (
-		bestPolicy  *channeldb.ChannelEdgePolicy
-		maxFee      lnwire.MilliAtom
-		maxTimelock uint16
+		bestPolicy     *channeldb.ChannelEdgePolicy
+		bestInboundFee lnwire.InboundFee
+		maxFee         int64
+		maxTimelock    uint16
 	)
 
 	for _, edge := range u.edges {
@@ -234,19 +284,21 @@ func (u *unifiedPolicy) getPolicyNetwork(
 		}
 
 		// Use the policy that results in the highest fee for this
-		// specific amount.
-		fee := edge.policy.ComputeFee(amt)
-		if fee < maxFee {
+		// specific amount, including the inbound fee of the to node.
+		fee := int64(edge.policy.ComputeFee(amt)) +
+			edge.inboundFee.Fee(amt)
+		if bestPolicy != nil && fee < maxFee {
 			continue
 		}
 		maxFee = fee
 
 		bestPolicy = edge.policy
+		bestInboundFee = edge.inboundFee
 	}
 
 	// Return early if no channel matches.
 	if bestPolicy == nil {
-		return nil
+		return nil, lnwire.InboundFee{}
 	}
 
 	// We have already picked the highest fee that could be required for
@@ -262,7 +314,7 @@ func (u *unifiedPolicy) getPolicyNetwork(
 	modifiedPolicy := *bestPolicy
 	modifiedPolicy.TimeLockDelta = maxTimelock
 
-	return &modifiedPolicy
+	return &modifiedPolicy, bestInboundFee
 }
 
 // minAmt returns the minimum amount that can be forwarded on this connection.
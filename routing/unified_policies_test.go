@@ -36,8 +36,8 @@ func TestUnifiedPolicies(t *testing.T) {
 		MaxHTLC:                   400,
 		MinHTLC:                   100,
 	}
-	u.addPolicy(fromNode, &p1, 7)
-	u.addPolicy(fromNode, &p2, 7)
+	u.addPolicy(fromNode, &p1, lnwire.InboundFee{}, 7)
+	u.addPolicy(fromNode, &p2, lnwire.InboundFee{}, 7)
 
 	checkPolicy := func(policy *channeldb.ChannelEdgePolicy,
 		feeBase lnwire.MilliAtom, feeRate lnwire.MilliAtom,
@@ -61,12 +61,12 @@ func TestUnifiedPolicies(t *testing.T) {
 		}
 	}
 
-	policy := u.policies[fromNode].getPolicy(50, bandwidthHints)
+	policy, _ := u.policies[fromNode].getPolicy(50, bandwidthHints)
 	if policy != nil {
 		t.Fatal("expected no policy for amt below min htlc")
 	}
 
-	policy = u.policies[fromNode].getPolicy(550, bandwidthHints)
+	policy, _ = u.policies[fromNode].getPolicy(550, bandwidthHints)
 	if policy != nil {
 		t.Fatal("expected no policy for amt above max htlc")
 	}
@@ -74,7 +74,7 @@ func TestUnifiedPolicies(t *testing.T) {
 	// For 200 sat, p1 yields the highest fee. Use that policy to forward,
 	// because it will also match p2 in case p1 does not have enough
 	// balance.
-	policy = u.policies[fromNode].getPolicy(200, bandwidthHints)
+	policy, _ = u.policies[fromNode].getPolicy(200, bandwidthHints)
 	checkPolicy(
 		policy, p1.FeeBaseMAtoms, p1.FeeProportionalMillionths,
 		p1.TimeLockDelta,
@@ -83,9 +83,72 @@ func TestUnifiedPolicies(t *testing.T) {
 	// For 400 sat, p2 yields the highest fee. Use that policy to forward,
 	// because it will also match p1 in case p2 does not have enough
 	// balance. In order to match p1, it needs to have p1's time lock delta.
-	policy = u.policies[fromNode].getPolicy(400, bandwidthHints)
+	policy, _ = u.policies[fromNode].getPolicy(400, bandwidthHints)
 	checkPolicy(
 		policy, p2.FeeBaseMAtoms, p2.FeeProportionalMillionths,
 		p1.TimeLockDelta,
 	)
 }
+
+// TestUnifiedPoliciesInboundFee tests that the inbound fee charged by the to
+// node is taken into account when selecting the policy of a connection.
+func TestUnifiedPoliciesInboundFee(t *testing.T) {
+	source := route.Vertex{1}
+	toNode := route.Vertex{2}
+	fromNode := route.Vertex{3}
+
+	u := newUnifiedPolicies(source, toNode, nil)
+
+	// Add two channels between the pair of nodes. Without inbound fees,
+	// p1 yields the highest fee. The to node however charges an inbound
+	// fee for the channel of p2 that makes it the most expensive one.
+	p1 := channeldb.ChannelEdgePolicy{
+		ChannelID:     1,
+		FeeBaseMAtoms: 30,
+		TimeLockDelta: 40,
+	}
+	p2 := channeldb.ChannelEdgePolicy{
+		ChannelID:     2,
+		FeeBaseMAtoms: 10,
+		TimeLockDelta: 40,
+	}
+	p2Inbound := lnwire.InboundFee{BaseFee: 25}
+
+	u.addPolicy(fromNode, &p1, lnwire.InboundFee{}, 7)
+	u.addPolicy(fromNode, &p2, p2Inbound, 7)
+
+	policy, inboundFee := u.policies[fromNode].getPolicy(
+		1000, map[uint64]lnwire.MilliAtom{},
+	)
+	if policy == nil {
+		t.Fatal("expected a policy")
+	}
+	if policy.ChannelID != p2.ChannelID {
+		t.Fatalf("expected channel %v, got %v", p2.ChannelID,
+			policy.ChannelID)
+	}
+	if inboundFee != p2Inbound {
+		t.Fatalf("expected inbound fee %v, got %v", p2Inbound,
+			inboundFee)
+	}
+
+	// A discount on the channel of p2 makes p1 the most expensive one
+	// again.
+	u = newUnifiedPolicies(source, toNode, nil)
+	u.addPolicy(fromNode, &p1, lnwire.InboundFee{}, 7)
+	u.addPolicy(fromNode, &p2, lnwire.InboundFee{BaseFee: -5}, 7)
+
+	policy, inboundFee = u.policies[fromNode].getPolicy(
+		1000, map[uint64]lnwire.MilliAtom{},
+	)
+	if policy == nil {
+		t.Fatal("expected a policy")
+	}
+	if policy.ChannelID != p1.ChannelID {
+		t.Fatalf("expected channel %v, got %v", p1.ChannelID,
+			policy.ChannelID)
+	}
+	if inboundFee != (lnwire.InboundFee{}) {
+		t.Fatalf("expected no inbound fee, got %v", inboundFee)
+	}
+}
@@ -0,0 +1,150 @@
+package dcrlnd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+// rpcLimiter bounds the number of concurrent executions of individual RPC
+// methods. Heavy read RPCs such as DescribeGraph hold long running database
+// transactions, so bursts of them can starve every other user of the
+// database.
+type rpcLimiter struct {
+	// slots holds a semaphore for each limited method, keyed by either
+	// the full or the bare method name.
+	slots map[string]chan struct{}
+
+	// queueTimeout is the maximum time a call waits for a free slot.
+	queueTimeout time.Duration
+}
+
+// newRPCLimiter creates a new limiter from the given method limits. Every
+// limited method must be one of the known RPC methods, which are the keys of
+// the passed permission map.
+func newRPCLimiter(limits map[string]int, queueTimeout time.Duration,
+	permissions map[string][]bakery.Op) (*rpcLimiter, error) {
+
+	// Collect the bare method names exposed by the services so we can
+	// catch typos in the configured limits.
+	bareMethods := make(map[string]struct{}, len(permissions))
+	for fullMethod := range permissions {
+		bareMethods[bareMethodName(fullMethod)] = struct{}{}
+	}
+
+	slots := make(map[string]chan struct{}, len(limits))
+	for method, limit := range limits {
+		_, isFull := permissions[method]
+		_, isBare := bareMethods[method]
+		if !isFull && !isBare {
+			return nil, fmt.Errorf("unknown rpc method %v in rpc "+
+				"limits", method)
+		}
+
+		slots[method] = make(chan struct{}, limit)
+	}
+
+	return &rpcLimiter{
+		slots:        slots,
+		queueTimeout: queueTimeout,
+	}, nil
+}
+
+// bareMethodName returns the method name of a full gRPC method name of the
+// form /<service>/<method>.
+func bareMethodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+// acquire waits for a free execution slot of the given method, returning a
+// closure that releases it. A ResourceExhausted error is returned if no slot
+// becomes available before the queue timeout expires.
+func (l *rpcLimiter) acquire(ctx context.Context,
+	fullMethod string) (func(), error) {
+
+	// A limit on the full method name takes precedence over one on its
+	// bare name.
+	slot, ok := l.slots[fullMethod]
+	if !ok {
+		slot, ok = l.slots[bareMethodName(fullMethod)]
+	}
+	if !ok {
+		return func() {}, nil
+	}
+
+	release := func() {
+		<-slot
+	}
+
+	// Try to get a slot right away, so calls aren't delayed while the
+	// method is below its limit.
+	select {
+	case slot <- struct{}{}:
+		return release, nil
+	default:
+	}
+
+	if l.queueTimeout == 0 {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many "+
+			"concurrent %v calls", fullMethod)
+	}
+
+	rpcsLog.Debugf("Queueing %v call, limit of %v concurrent calls "+
+		"reached", fullMethod, cap(slot))
+
+	timeout := time.NewTimer(l.queueTimeout)
+	defer timeout.Stop()
+
+	select {
+	case slot <- struct{}{}:
+		return release, nil
+
+	case <-timeout.C:
+		return nil, status.Errorf(codes.ResourceExhausted, "too many "+
+			"concurrent %v calls, timed out after %v in queue",
+			fullMethod, l.queueTimeout)
+
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+// UnaryServerInterceptor returns a UnaryServerInterceptor that only executes
+// a unary RPC once the limit of its method allows it.
+func (l *rpcLimiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		release, err := l.acquire(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a StreamServerInterceptor that only opens a
+// stream once the limit of its method allows it. The execution slot is held
+// for as long as the stream is open.
+func (l *rpcLimiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream,
+		info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		release, err := l.acquire(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		defer release()
+
+		return handler(srv, ss)
+	}
+}
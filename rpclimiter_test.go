@@ -0,0 +1,138 @@
+package dcrlnd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+// TestRPCLimiter asserts that calls beyond the limit of a method are queued
+// until a slot is released, and rejected once the queue timeout expires.
+func TestRPCLimiter(t *testing.T) {
+	t.Parallel()
+
+	const (
+		describeGraph = "/lnrpc.Lightning/DescribeGraph"
+		sendToRoute   = "/lnrpc.Lightning/SendToRoute"
+		routerSend    = "/routerrpc.Router/SendToRoute"
+		getInfo       = "/lnrpc.Lightning/GetInfo"
+	)
+	permissions := map[string][]bakery.Op{
+		describeGraph: nil,
+		sendToRoute:   nil,
+		routerSend:    nil,
+		getInfo:       nil,
+	}
+
+	// Unknown methods are rejected.
+	_, err := newRPCLimiter(
+		map[string]int{"DescribeGrpah": 1}, 0, permissions,
+	)
+	if err == nil {
+		t.Fatalf("expected unknown method to be rejected")
+	}
+
+	limiter, err := newRPCLimiter(
+		map[string]int{describeGraph: 1, "SendToRoute": 1},
+		50*time.Millisecond, permissions,
+	)
+	if err != nil {
+		t.Fatalf("unable to create limiter: %v", err)
+	}
+	interceptor := limiter.UnaryServerInterceptor()
+
+	// call executes a unary call of the given method, blocking within the
+	// handler until the returned channel is closed.
+	call := func(method string) (chan struct{}, chan error) {
+		started := make(chan struct{})
+		done := make(chan struct{})
+		errChan := make(chan error, 1)
+		go func() {
+			_, err := interceptor(
+				context.Background(), nil,
+				&grpc.UnaryServerInfo{FullMethod: method},
+				func(context.Context, interface{}) (interface{},
+					error) {
+
+					close(started)
+					<-done
+					return nil, nil
+				},
+			)
+			errChan <- err
+		}()
+
+		select {
+		case <-started:
+		case err := <-errChan:
+			errChan <- err
+		case <-time.After(time.Second):
+			t.Fatalf("%v call not started", method)
+		}
+
+		return done, errChan
+	}
+
+	assertExhausted := func(errChan chan error) {
+		t.Helper()
+
+		select {
+		case err := <-errChan:
+			if status.Code(err) != codes.ResourceExhausted {
+				t.Fatalf("expected ResourceExhausted, got %v",
+					err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("call not rejected")
+		}
+	}
+
+	// The first DescribeGraph call takes the only slot, so a second one
+	// times out in the queue, while unlimited methods aren't affected.
+	done, _ := call(describeGraph)
+	_, errChan := call(describeGraph)
+	assertExhausted(errChan)
+
+	getInfoDone, getInfoErr := call(getInfo)
+	close(getInfoDone)
+	if err := <-getInfoErr; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Once the slot is released, a queued call gets to run.
+	queuedStarted := make(chan struct{})
+	queuedErr := make(chan error, 1)
+	go func() {
+		_, err := interceptor(
+			context.Background(), nil,
+			&grpc.UnaryServerInfo{FullMethod: describeGraph},
+			func(context.Context, interface{}) (interface{}, error) {
+				close(queuedStarted)
+				return nil, nil
+			},
+		)
+		queuedErr <- err
+	}()
+	close(done)
+
+	select {
+	case <-queuedStarted:
+	case <-time.After(time.Second):
+		t.Fatalf("queued call not started")
+	}
+	if err := <-queuedErr; err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A limit on a bare method name is shared by all services exposing
+	// that method.
+	done, _ = call(sendToRoute)
+	_, errChan = call(routerSend)
+	assertExhausted(errChan)
+	close(done)
+}
@@ -9,7 +9,10 @@ import (
 	"fmt"
 	"io"
 	"math"
+	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -35,9 +38,12 @@ import (
 	"github.com/decred/dcrlnd/channeldb"
 	"github.com/decred/dcrlnd/channeldb/kvdb"
 	"github.com/decred/dcrlnd/channelnotifier"
+	"github.com/decred/dcrlnd/clock"
 	"github.com/decred/dcrlnd/contractcourt"
+	"github.com/decred/dcrlnd/crashreport"
 	"github.com/decred/dcrlnd/discovery"
 	"github.com/decred/dcrlnd/feature"
+	"github.com/decred/dcrlnd/feecontrol"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/internal/psbt"
@@ -59,17 +65,23 @@ import (
 	"github.com/decred/dcrlnd/peer"
 	"github.com/decred/dcrlnd/peernotifier"
 	"github.com/decred/dcrlnd/record"
+	"github.com/decred/dcrlnd/routerstate"
 	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/localchans"
 	"github.com/decred/dcrlnd/routing/route"
 	"github.com/decred/dcrlnd/signal"
 	"github.com/decred/dcrlnd/sweep"
+	"github.com/decred/dcrlnd/ticker"
 	"github.com/decred/dcrlnd/watchtower"
 	"github.com/decred/dcrlnd/zpay32"
+	"github.com/decred/slog"
 
 	"github.com/grpc-ecosystem/go-grpc-middleware"
 	proxy "github.com/grpc-ecosystem/grpc-gateway/runtime"
 	"github.com/tv42/zbase32"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gopkg.in/macaroon-bakery.v2/bakery"
 )
 
@@ -125,6 +137,10 @@ var (
 			Entity: "macaroon",
 			Action: "read",
 		},
+		{
+			Entity: "dashboard",
+			Action: "read",
+		},
 	}
 
 	// writePermissions is a slice of all entities that allow write
@@ -206,7 +222,7 @@ var (
 	validEntities = []string{
 		"onchain", "offchain", "address", "message",
 		"peers", "info", "invoices", "signer", "macaroon",
-		macaroons.PermissionEntityCustomURI,
+		"emergency", "dashboard", macaroons.PermissionEntityCustomURI,
 	}
 
 	// If the --no-macaroons flag is used to start lnd, the macaroon service
@@ -242,6 +258,14 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "onchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SubscribeSendConfirmation": {{
+			Entity: "onchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/SubscribeChainEvents": {{
+			Entity: "onchain",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/NewAddress": {{
 			Entity: "address",
 			Action: "write",
@@ -254,6 +278,31 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "message",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/ExportPaymentReceipt": {{
+			Entity: "offchain",
+			Action: "read",
+		}, {
+			Entity: "invoices",
+			Action: "read",
+		}, {
+			Entity: "message",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/VerifyPaymentReceipt": {{
+			Entity: "message",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/AttestNode": {{
+			Entity: "info",
+			Action: "read",
+		}, {
+			Entity: "message",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/VerifyNodeAttestation": {{
+			Entity: "message",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/ConnectPeer": {{
 			Entity: "peers",
 			Action: "write",
@@ -262,6 +311,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "peers",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/RotatePeerCircuit": {{
+			Entity: "peers",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/OpenChannel": {{
 			Entity: "onchain",
 			Action: "write",
@@ -269,6 +322,13 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/BatchOpenChannel": {{
+			Entity: "onchain",
+			Action: "write",
+		}, {
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/OpenChannelSync": {{
 			Entity: "onchain",
 			Action: "write",
@@ -287,14 +347,34 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/ExportChannelCommitment": {{
+			Entity: "emergency",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/GetInfo": {{
 			Entity: "info",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/DashboardNodeSummary": {{
+			Entity: "dashboard",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/DashboardChannelSummary": {{
+			Entity: "dashboard",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/DashboardForwards": {{
+			Entity: "dashboard",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/GetRecoveryInfo": {{
 			Entity: "info",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/DbInfo": {{
+			Entity: "info",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/ListPeers": {{
 			Entity: "peers",
 			Action: "read",
@@ -307,14 +387,39 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "onchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/EstimateChannelOpen": {{
+			Entity: "onchain",
+			Action: "read",
+		}, {
+			Entity: "offchain",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/ChannelBalance": {{
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/Balances": {{
+			Entity: "onchain",
+			Action: "read",
+		}, {
+			Entity: "offchain",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/PendingChannels": {{
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/ListFundingReservations": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/CancelFundingReservation": {{
+			Entity: "offchain",
+			Action: "write",
+		}, {
+			Entity: "onchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/ListChannels": {{
 			Entity: "offchain",
 			Action: "read",
@@ -343,10 +448,25 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SendRebalance": {{
+			Entity: "offchain",
+			Action: "write",
+		}, {
+			Entity: "invoices",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/AddInvoice": {{
 			Entity: "invoices",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SettleHoldInvoice": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/CancelHoldInvoice": {{
+			Entity: "invoices",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/LookupInvoice": {{
 			Entity: "invoices",
 			Action: "read",
@@ -379,6 +499,14 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "info",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/GetChanPolicyHistory": {{
+			Entity: "info",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/ResolveChannelId": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/GetNodeInfo": {{
 			Entity: "info",
 			Action: "read",
@@ -395,6 +523,18 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "info",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SubscribeShutdownProgress": {{
+			Entity: "info",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/EnterMaintenance": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/ExitMaintenance": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/SubscribeChannelGraph": {{
 			Entity: "info",
 			Action: "read",
@@ -403,6 +543,14 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/TrackPayment": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/DeletePayment": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/DeleteAllPayments": {{
 			Entity: "offchain",
 			Action: "write",
@@ -411,6 +559,35 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "info",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/GetCrashReport": {{
+			Entity: "info",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/GetKillSwitches": {{
+			Entity: "info",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/UpdateKillSwitches": {{
+			Entity: "offchain",
+			Action: "write",
+		}, {
+			Entity: "invoices",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/LockWallet": {{
+			Entity: "onchain",
+			Action: "write",
+		}, {
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/UnlockRunningWallet": {{
+			Entity: "onchain",
+			Action: "write",
+		}, {
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/DecodePayReq": {{
 			Entity: "offchain",
 			Action: "read",
@@ -423,6 +600,26 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/SubscribeFeeAdjustments": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/SetFeeSchedule": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/ListFeeSchedules": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/UpdateHtlcExpiryGrace": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/UpdatePendingChannelLimits": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/ForwardingHistory": {{
 			Entity: "offchain",
 			Action: "read",
@@ -431,6 +628,18 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "write",
 		}},
+		"/lnrpc.Lightning/DeriveChannelRecoveryKeys": {{
+			Entity: "emergency",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/SweepRecoveredChannel": {{
+			Entity: "emergency",
+			Action: "write",
+		}},
+		"/lnrpc.Lightning/SignChannelRescue": {{
+			Entity: "emergency",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/ExportChannelBackup": {{
 			Entity: "offchain",
 			Action: "read",
@@ -447,6 +656,14 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "offchain",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/ExportRouterState": {{
+			Entity: "offchain",
+			Action: "read",
+		}},
+		"/lnrpc.Lightning/ImportRouterState": {{
+			Entity: "offchain",
+			Action: "write",
+		}},
 		"/lnrpc.Lightning/ChannelAcceptor": {{
 			Entity: "onchain",
 			Action: "write",
@@ -474,6 +691,10 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 			Entity: "peers",
 			Action: "read",
 		}},
+		"/lnrpc.Lightning/PeerFeatureReport": {{
+			Entity: "peers",
+			Action: "read",
+		}},
 		"/lnrpc.Lightning/FundingStateStep": {{
 			Entity: "onchain",
 			Action: "write",
@@ -489,6 +710,7 @@ func MainRPCServerPermissions() map[string][]bakery.Op {
 type rpcServer struct {
 	started  int32 // To be used atomically.
 	shutdown int32 // To be used atomically.
+	draining int32 // To be used atomically.
 
 	server *server
 
@@ -547,6 +769,36 @@ type rpcServer struct {
 	// allPermissions is a map of all registered gRPC URIs (including
 	// internal and external subservers) to the permissions they require.
 	allPermissions map[string][]bakery.Op
+
+	// sendConfs tracks the confirmation notifications registered for
+	// transactions sent through SendCoins and SendMany.
+	sendConfs *sendConfRegistry
+
+	// logOverrides applies the log level changes requested through
+	// DebugLevel and reverts the temporary ones.
+	logOverrides *logLevelOverrides
+
+	// dashboardCache caches the responses of the dashboard RPCs.
+	dashboardCache *dashboardCache
+
+	// centralityCache caches the centrality of the nodes of the graph
+	// used to estimate the flows of new channels.
+	centralityCache *dashboardCache
+
+	// autopilot is the manager of the autopilot agent, paused during
+	// maintenance.
+	autopilot *autopilot.Manager
+
+	// walletLock locks the wallet when requested or after being idle. It
+	// is nil if the wallet can't be locked while running.
+	walletLock *walletLocker
+
+	// maintenance tracks whether the node is in maintenance mode.
+	maintenance maintenanceState
+
+	// backupExportMtx serializes the writes of exported backup files, as
+	// they share the staging file of their directory.
+	backupExportMtx sync.Mutex
 }
 
 // A compile time check to ensure that rpcServer fully implements the
@@ -627,9 +879,9 @@ func newRPCServer(cfg *Config, s *server, macService *macaroons.Service,
 	err = subServerCgs.PopulateDependencies(
 		cfg, s.cc, cfg.networkDir, macService, atpl, invoiceRegistry,
 		s.htlcSwitch, activeNetParams.Params, s.chanRouter,
-		routerBackend, s.nodeSigner, s.remoteChanDB, s.sweeper, tower,
-		s.towerClient, cfg.net.ResolveTCPAddr, genInvoiceFeatures,
-		rpcsLog,
+		routerBackend, s.htlcEventStore, s.nodeSigner, s.identityECDH,
+		s.remoteChanDB, s.sweeper, tower, s.towerClient,
+		cfg.net.ResolveTCPAddr, genInvoiceFeatures, rpcsLog,
 	)
 	if err != nil {
 		return nil, err
@@ -745,6 +997,120 @@ func newRPCServer(cfg *Config, s *server, macService *macaroons.Service,
 		strmInterceptors, errorLogStreamServerInterceptor(rpcsLog),
 	)
 
+	// The calls requiring the private keys of the wallet are refused while
+	// it's locked. Wallets that can't be locked while running, such as a
+	// remote wallet, are left alone.
+	var walletLock *walletLocker
+	if wallet, ok := s.cc.wc.(lockableWallet); ok {
+		walletLock = newWalletLocker(&walletLockerConfig{
+			Wallet:       wallet,
+			IdleTimeout:  cfg.WalletLock.IdleTimeout,
+			DrainTimeout: cfg.WalletLock.DrainTimeout,
+			Pause: func() func() {
+				// The autopilot agent funds channels from the
+				// wallet, so it's stopped until the wallet is
+				// unlocked.
+				if !atpl.IsActive() {
+					return func() {}
+				}
+
+				if err := atpl.StopAgent(); err != nil {
+					rpcsLog.Errorf("Unable to stop autopilot "+
+						"agent: %v", err)
+					return func() {}
+				}
+
+				return func() {
+					err := atpl.StartAgent()
+					if err != nil {
+						rpcsLog.Errorf("Unable to restart "+
+							"autopilot agent: %v", err)
+					}
+				}
+			},
+			PendingFundings: func() int {
+				var numFundings int
+				reservations := s.fundingMgr.Reservations()
+				for _, res := range reservations {
+					if res.initiator() && !res.isPsbt &&
+						!res.isCannedShim &&
+						res.localFundingAmt > 0 {
+
+						numFundings++
+					}
+				}
+
+				return numFundings
+			},
+			PendingForceCloses: func() int {
+				waiting, err := s.remoteChanDB.
+					FetchWaitingCloseChannels()
+				if err != nil {
+					rpcsLog.Errorf("Unable to fetch "+
+						"waiting close channels: %v",
+						err)
+				}
+
+				pending, err := s.remoteChanDB.
+					FetchClosedChannels(true)
+				if err != nil {
+					rpcsLog.Errorf("Unable to fetch "+
+						"pending closed channels: %v",
+						err)
+				}
+
+				// Cooperative closes have no outputs of ours
+				// to sweep once confirmed.
+				numForceCloses := len(waiting)
+				for _, summary := range pending {
+					if summary.CloseType !=
+						channeldb.CooperativeClose {
+
+						numForceCloses++
+					}
+				}
+
+				return numForceCloses
+			},
+			Permissions: permissions,
+			Ticker:      ticker.New(walletLockPollInterval),
+			Clock:       clock.NewDefaultClock(),
+		})
+
+		unaryInterceptors = append(
+			unaryInterceptors, walletLock.UnaryServerInterceptor(),
+		)
+		strmInterceptors = append(
+			strmInterceptors, walletLock.StreamServerInterceptor(),
+		)
+	}
+
+	// The per method concurrency limits are enforced after the errors are
+	// logged, so rejected calls show up in the logs as well.
+	limiter, err := newRPCLimiter(
+		cfg.RPCLimits.MethodLimits(), cfg.RPCLimits.QueueTimeout,
+		permissions,
+	)
+	if err != nil {
+		return nil, err
+	}
+	unaryInterceptors = append(
+		unaryInterceptors, limiter.UnaryServerInterceptor(),
+	)
+	strmInterceptors = append(
+		strmInterceptors, limiter.StreamServerInterceptor(),
+	)
+
+	// The panic recovery interceptors are added last so they directly wrap
+	// the handlers, turning a panic into an error that is logged by the
+	// interceptors above instead of crashing the process.
+	unaryInterceptors = append(
+		unaryInterceptors, panicRecoveryUnaryServerInterceptor(),
+	)
+	strmInterceptors = append(
+		strmInterceptors, panicRecoveryStreamServerInterceptor(),
+	)
+
 	// If any interceptors have been set up, add them to the server options.
 	if len(unaryInterceptors) != 0 && len(strmInterceptors) != 0 {
 		chainedUnary := grpc_middleware.WithUnaryServerChain(
@@ -775,7 +1141,18 @@ func newRPCServer(cfg *Config, s *server, macService *macaroons.Service,
 		macService:      macService,
 		selfNode:        selfNode.PubKeyBytes,
 		allPermissions:  permissions,
+		sendConfs:       newSendConfRegistry(),
+		logOverrides:    newLogLevelOverrides(cfg.LogWriter),
+		dashboardCache: newDashboardCache(
+			cfg.Dashboard.CacheTTL, time.Now,
+		),
+		centralityCache: newDashboardCache(
+			centralityCacheTTL, time.Now,
+		),
+		autopilot:  atpl,
+		walletLock: walletLock,
 	}
+	routerBackend.ReservePaymentBudget = rootRPCServer.reservePaymentBudget
 	lnrpc.RegisterLightningServer(grpcServer, rootRPCServer)
 
 	// Now the main RPC server has been registered, we'll iterate through
@@ -819,6 +1196,12 @@ func (r *rpcServer) Start() error {
 		}
 	}
 
+	if r.walletLock != nil {
+		if err := r.walletLock.Start(); err != nil {
+			return err
+		}
+	}
+
 	// With all the sub-servers started, we'll spin up the listeners for
 	// the main RPC server itself.
 	for _, lis := range r.listeners {
@@ -884,6 +1267,13 @@ func (r *rpcServer) Start() error {
 	// Wrap the default grpc-gateway handler with the WebSocket handler.
 	restHandler := lnrpc.NewWebSocketProxy(restMux, rpcsLog)
 
+	// If enabled, compress the responses for clients that accept it.
+	if r.cfg.Compression.REST {
+		restHandler = lnrpc.NewGzipHandler(
+			restHandler, r.cfg.Compression.Level,
+		)
+	}
+
 	// With our custom REST proxy mux created, register our main RPC and
 	// give all subservers a chance to register as well.
 	err := lnrpc.RegisterLightningHandlerFromEndpoint(
@@ -938,10 +1328,14 @@ func (r *rpcServer) Start() error {
 
 			// Create our proxy chain now. A request will pass
 			// through the following chain:
-			// req ---> CORS handler --> WS proxy --->
-			//   REST proxy --> gRPC endpoint
+			// req ---> proxy header handler --> CORS handler -->
+			//   gzip handler --> WS proxy --> REST proxy -->
+			//   gRPC endpoint
 			corsHandler := allowCORS(restHandler, r.cfg.RestCORS)
-			err := http.Serve(lis, corsHandler)
+			proxyHandler := trustProxyHeaders(
+				corsHandler, r.cfg.restTrustedProxies,
+			)
+			err := http.Serve(lis, proxyHandler)
 			if err != nil && !lnrpc.IsClosedConnError(err) {
 				rpcsLog.Error(err)
 			}
@@ -961,19 +1355,26 @@ func (r *rpcServer) Stop() error {
 
 	close(r.quit)
 
+	if r.walletLock != nil {
+		r.walletLock.Stop()
+	}
+
+	// Confirmation registrations of sent transactions that were never
+	// subscribed to are no longer needed.
+	r.sendConfs.cancelAll()
+
 	// After we've signalled all of our active goroutines to exit, we'll
 	// then do the same to signal a graceful shutdown of all the sub
-	// servers.
+	// servers. A sub-server that doesn't stop in time doesn't hold up the
+	// shutdown of the others.
+	steps := make([]shutdownStep, 0, len(r.subServers))
 	for _, subServer := range r.subServers {
-		rpcsLog.Infof("Stopping %v Sub-RPC Server",
-			subServer.Name())
-
-		if err := subServer.Stop(); err != nil {
-			rpcsLog.Errorf("unable to stop sub-server %v: %v",
-				subServer.Name(), err)
-			continue
-		}
+		steps = append(steps, shutdownStep{
+			name: fmt.Sprintf("%v Sub-RPC Server", subServer.Name()),
+			stop: subServer.Stop,
+		})
 	}
+	runShutdown(rpcsLog, steps, r.cfg.ShutdownTimeout, nil)
 
 	// Finally, we can clean up all the listening sockets to ensure that we
 	// give the file descriptors back to the OS.
@@ -1059,39 +1460,108 @@ func allowCORS(handler http.Handler, origins []string) http.Handler {
 	})
 }
 
-// sendCoinsOnChain makes an on-chain transaction in or to send coins to one or
-// more addresses specified in the passed payment map. The payment map maps an
-// address to a specified output value to be sent to that address.
-func (r *rpcServer) sendCoinsOnChain(paymentMap map[string]int64,
-	feeRate chainfee.AtomPerKByte, label string) (*chainhash.Hash, error) {
+// trustProxyHeaders wraps the given http.Handler with a function that resolves
+// the address of the client that sent a request. The X-Forwarded-For header is
+// only honored for requests received from one of the trusted proxies, and is
+// removed from any other request so that clients can't spoof their address.
+func trustProxyHeaders(handler http.Handler, proxies []*net.IPNet) http.Handler {
+	forwardedFor := "X-Forwarded-For"
 
-	outputs, err := addrPairsToOutputs(paymentMap, activeNetParams.Params)
-	if err != nil {
-		return nil, err
-	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remoteHost := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(remoteHost); err == nil {
+			remoteHost = host
+		}
 
-	tx, err := r.server.cc.wallet.SendOutputs(outputs, feeRate, label)
-	if err != nil {
-		return nil, err
-	}
+		// The REST proxy appends the address of the sender to the
+		// header when forwarding the request, so it only needs to
+		// carry the client's address if it's different.
+		clientAddr := restClientAddr(remoteHost, r.Header, proxies)
+		if clientAddr == remoteHost {
+			r.Header.Del(forwardedFor)
+		} else {
+			r.Header.Set(forwardedFor, clientAddr)
+		}
 
-	txHash := tx.TxHash()
-	return &txHash, nil
+		rpcsLog.Debugf("REST request %v %v from %v", r.Method,
+			r.URL.Path, clientAddr)
+
+		handler.ServeHTTP(w, r)
+	})
 }
 
-// ListUnspent returns useful information about each unspent output owned by
-// the wallet, as reported by the underlying `ListUnspentWitness`; the
-// information returned is: outpoint, amount in satoshis, address, address
-// type, scriptPubKey in hex and number of confirmations.  The result is
-// filtered to contain outputs whose number of confirmations is between a
-// minimum and maximum number of confirmations specified by the user, with 0
-// meaning unconfirmed.
-func (r *rpcServer) ListUnspent(ctx context.Context,
-	in *lnrpc.ListUnspentRequest) (*lnrpc.ListUnspentResponse, error) {
+// restClientAddr returns the address of the client that sent a REST request
+// received from the given remote host. If the remote host is a trusted proxy,
+// the X-Forwarded-For header is walked from the right, skipping the addresses
+// of trusted proxies, until the address of the client is found.
+func restClientAddr(remoteHost string, header http.Header,
+	proxies []*net.IPNet) string {
 
-	// Validate the confirmation arguments.
-	minConfs, maxConfs, err := lnrpc.ParseConfs(in.MinConfs, in.MaxConfs)
-	if err != nil {
+	isTrusted := func(addr string) bool {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return false
+		}
+		for _, proxy := range proxies {
+			if proxy.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	clientAddr := remoteHost
+	if !isTrusted(clientAddr) {
+		return clientAddr
+	}
+
+	var hops []string
+	for _, value := range header["X-Forwarded-For"] {
+		hops = append(hops, strings.Split(value, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+
+		clientAddr = hop
+		if !isTrusted(clientAddr) {
+			break
+		}
+	}
+
+	return clientAddr
+}
+
+// sendCoinsOnChain makes an on-chain transaction in or to send coins to one or
+// more addresses specified in the passed payment map. The payment map maps an
+// address to a specified output value to be sent to that address.
+func (r *rpcServer) sendCoinsOnChain(paymentMap map[string]int64,
+	feeRate chainfee.AtomPerKByte, label string) (*wire.MsgTx, error) {
+
+	outputs, err := addrPairsToOutputs(paymentMap, activeNetParams.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.server.cc.wallet.SendOutputs(outputs, feeRate, label)
+}
+
+// ListUnspent returns useful information about each unspent output owned by
+// the given account of the wallet, as reported by the underlying
+// `ListAccountUnspent`; the information returned is: outpoint, amount in
+// satoshis, address, address type, scriptPubKey in hex, number of
+// confirmations and whether the output is locked.  The result is
+// filtered to contain outputs whose number of confirmations is between a
+// minimum and maximum number of confirmations specified by the user, with 0
+// meaning unconfirmed.
+func (r *rpcServer) ListUnspent(ctx context.Context,
+	in *lnrpc.ListUnspentRequest) (*lnrpc.ListUnspentResponse, error) {
+
+	// Validate the confirmation arguments.
+	minConfs, maxConfs, err := lnrpc.ParseConfs(in.MinConfs, in.MaxConfs)
+	if err != nil {
 		return nil, err
 	}
 
@@ -1100,11 +1570,12 @@ func (r *rpcServer) ListUnspent(ctx context.Context,
 	//
 	// We'll acquire the global coin selection lock to ensure there aren't
 	// any other concurrent processes attempting to lock any UTXOs which may
-	// be shown available to us.
+	// be shown available to us. Outputs which are locked or still maturing
+	// are listed as well.
 	var utxos []*lnwallet.Utxo
 	err = r.server.cc.wallet.WithCoinSelectLock(func() error {
-		utxos, err = r.server.cc.wallet.ListUnspentWitness(
-			minConfs, maxConfs,
+		utxos, err = r.server.cc.wallet.ListAccountUnspent(
+			in.Account, minConfs, maxConfs,
 		)
 		return err
 	})
@@ -1226,7 +1697,18 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 		return nil, err
 	}
 
-	var txid *chainhash.Hash
+	// Sweeping all coins isn't allowed with a macaroon carrying a spending
+	// budget, as the amount isn't known in advance.
+	_, budgets, err := budgetsFromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if in.SendAll && len(budgets) > 0 {
+		return nil, fmt.Errorf("sending all coins isn't allowed with " +
+			"a spending budget")
+	}
+
+	var tx *wire.MsgTx
 
 	wallet := r.server.cc.wallet
 
@@ -1250,10 +1732,13 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 		// With the sweeper instance created, we can now generate a
 		// transaction that will sweep ALL outputs from the wallet in a
 		// single transaction. This will be generated in a concurrent
-		// safe manner, so no need to worry about locking.
+		// safe manner, so no need to worry about locking. The outputs
+		// are listed through the LightningWallet so that those which
+		// are still maturing are left out, just like for any other
+		// coin selection.
 		sweepTxPkg, err := sweep.CraftSweepAllTx(
 			feePerKB, uint32(bestHeight), targetAddr, wallet,
-			wallet.WalletController, wallet.WalletController,
+			wallet, wallet.WalletController,
 			r.server.cc.feeEstimator, r.server.cc.signer,
 			activeNetParams.Params,
 		)
@@ -1275,8 +1760,7 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 				"transaction: %v", err)
 		}
 
-		sweepTXID := sweepTxPkg.SweepTx.TxHash()
-		txid = &sweepTXID
+		tx = sweepTxPkg.SweepTx
 	} else {
 
 		// We'll now construct out payment map, and use the wallet's
@@ -1285,14 +1769,25 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 		// while we instruct the wallet to send this transaction.
 		paymentMap := map[string]int64{targetAddr.String(): in.Amount}
 		err := wallet.WithCoinSelectLock(func() error {
-			newTXID, err := r.sendCoinsOnChain(
+			// If the macaroon of the request carries a spending
+			// budget, the amount sent and the fee are reserved
+			// against it.
+			spend, err := r.reserveOnChainBudget(
+				ctx, paymentMap, feePerKB,
+			)
+			if err != nil {
+				return err
+			}
+
+			newTx, err := r.sendCoinsOnChain(
 				paymentMap, feePerKB, label,
 			)
 			if err != nil {
+				spend.resolve(0)
 				return err
 			}
 
-			txid = newTXID
+			tx = newTx
 
 			return nil
 		})
@@ -1301,9 +1796,22 @@ func (r *rpcServer) SendCoins(ctx context.Context,
 		}
 	}
 
+	txid := tx.TxHash()
 	rpcsLog.Infof("[sendcoins] spend generated txid: %v", txid.String())
 
-	return &lnrpc.SendCoinsResponse{Txid: txid.String()}, nil
+	resp := &lnrpc.SendCoinsResponse{Txid: txid.String()}
+	if in.NotifyConfs > 0 {
+		resp.ConfSubscriptionId, err = r.registerSendConf(
+			tx, in.NotifyConfs,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %v sent, but unable "+
+				"to register confirmation notification: %v",
+				txid, err)
+		}
+	}
+
+	return resp, nil
 }
 
 // SendMany handles a request for a transaction create multiple specified
@@ -1332,22 +1840,31 @@ func (r *rpcServer) SendMany(ctx context.Context,
 	rpcsLog.Infof("[sendmany] outputs=%v, atom/kB=%v",
 		spew.Sdump(in.AddrToAmount), int64(feePerKB))
 
-	var txid *chainhash.Hash
+	var tx *wire.MsgTx
 
 	// We'll attempt to send to the target set of outputs, ensuring that we
 	// synchronize with any other ongoing coin selection attempts which
 	// happen to also be concurrently executing.
 	wallet := r.server.cc.wallet
 	err = wallet.WithCoinSelectLock(func() error {
+		// If the macaroon of the request carries a spending budget,
+		// the total amount sent and the fee are reserved against it.
+		spend, err := r.reserveOnChainBudget(
+			ctx, in.AddrToAmount, feePerKB,
+		)
+		if err != nil {
+			return err
+		}
 
-		sendManyTXID, err := r.sendCoinsOnChain(
+		sendManyTx, err := r.sendCoinsOnChain(
 			in.AddrToAmount, feePerKB, label,
 		)
 		if err != nil {
+			spend.resolve(0)
 			return err
 		}
 
-		txid = sendManyTXID
+		tx = sendManyTx
 
 		return nil
 	})
@@ -1355,9 +1872,22 @@ func (r *rpcServer) SendMany(ctx context.Context,
 		return nil, err
 	}
 
+	txid := tx.TxHash()
 	rpcsLog.Infof("[sendmany] spend generated txid: %v", txid.String())
 
-	return &lnrpc.SendManyResponse{Txid: txid.String()}, nil
+	resp := &lnrpc.SendManyResponse{Txid: txid.String()}
+	if in.NotifyConfs > 0 {
+		resp.ConfSubscriptionId, err = r.registerSendConf(
+			tx, in.NotifyConfs,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("transaction %v sent, but unable "+
+				"to register confirmation notification: %v",
+				txid, err)
+		}
+	}
+
+	return resp, nil
 }
 
 // NewAddress creates a new address under control of the local wallet.
@@ -1470,6 +2000,226 @@ func (r *rpcServer) VerifyMessage(ctx context.Context,
 	}, nil
 }
 
+// ExportPaymentReceipt produces a receipt for a settled payment or invoice,
+// signed by the node's identity key.
+func (r *rpcServer) ExportPaymentReceipt(ctx context.Context,
+	in *lnrpc.ExportPaymentReceiptRequest) (*lnrpc.PaymentReceipt, error) {
+
+	paymentHash, err := lntypes.MakeHash(in.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	var receipt *paymentReceipt
+	switch in.Role {
+	case lnrpc.ReceiptRole_UNKNOWN_ROLE, lnrpc.ReceiptRole_PAYER:
+		payment, err := r.routerBackend.Tower.FetchPayment(paymentHash)
+		switch {
+		case err == nil:
+			receipt, err = newPayerReceipt(payment)
+			if err != nil {
+				return nil, err
+			}
+
+		// Without an explicit role, we fall back to the receipt of an
+		// invoice if we didn't make an outgoing payment.
+		case err == channeldb.ErrPaymentNotInitiated &&
+			in.Role == lnrpc.ReceiptRole_UNKNOWN_ROLE:
+
+		default:
+			return nil, err
+		}
+
+	case lnrpc.ReceiptRole_PAYEE:
+
+	default:
+		return nil, fmt.Errorf("unknown receipt role: %v", in.Role)
+	}
+
+	if receipt == nil {
+		invoice, err := r.server.invoices.LookupInvoice(paymentHash)
+		if err != nil {
+			return nil, err
+		}
+
+		receipt, err = newPayeeReceipt(&invoice, r.selfNode)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	receiptBytes, err := receipt.serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := receiptSignedMsg(receiptBytes)
+	sigBytes, err := r.server.nodeSigner.SignCompact(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Debugf("[exportpaymentreceipt] exported receipt for %v",
+		paymentHash)
+
+	rpcReceipt := marshallPaymentReceipt(receipt)
+	rpcReceipt.Receipt = receiptBytes
+	rpcReceipt.Signature = zbase32.EncodeToString(sigBytes)
+
+	return rpcReceipt, nil
+}
+
+// marshallPaymentReceipt converts the fields of a receipt into their rpc
+// representation.
+func marshallPaymentReceipt(receipt *paymentReceipt) *lnrpc.PaymentReceipt {
+	role := lnrpc.ReceiptRole_PAYER
+	if receipt.role == receiptRolePayee {
+		role = lnrpc.ReceiptRole_PAYEE
+	}
+
+	return &lnrpc.PaymentReceipt{
+		Role:           role,
+		PaymentHash:    receipt.paymentHash[:],
+		Preimage:       receipt.preimage[:],
+		Payee:          hex.EncodeToString(receipt.payee[:]),
+		ValueMAtoms:    int64(receipt.amt),
+		CreationTimeNs: receipt.creationTime.UnixNano(),
+		SettleTimeNs:   receipt.settleTime.UnixNano(),
+		PaymentRequest: string(receipt.paymentRequest),
+	}
+}
+
+// VerifyPaymentReceipt verifies the signature and the consistency of a
+// payment receipt, and returns the public key of the node that signed it.
+func (r *rpcServer) VerifyPaymentReceipt(ctx context.Context,
+	in *lnrpc.VerifyPaymentReceiptRequest) (
+	*lnrpc.VerifyPaymentReceiptResponse, error) {
+
+	receipt, err := deserializePaymentReceipt(in.Receipt)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode receipt: %v", err)
+	}
+
+	sig, err := zbase32.DecodeString(in.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	resp := &lnrpc.VerifyPaymentReceiptResponse{
+		Receipt: marshallPaymentReceipt(receipt),
+	}
+	resp.Receipt.Receipt = in.Receipt
+	resp.Receipt.Signature = in.Signature
+
+	// RecoverCompact both recovers the pubkey and validates the signature
+	// over the BLAKE-256 hash of the prefixed receipt.
+	msg := receiptSignedMsg(in.Receipt)
+	pubKey, _, err := ecdsa.RecoverCompact(sig, chainhash.HashB(msg))
+	if err != nil {
+		resp.Error = "invalid signature"
+		return resp, nil
+	}
+	signer := route.NewVertex(pubKey)
+	resp.Signer = hex.EncodeToString(signer[:])
+
+	if err := receipt.validate(activeNetParams.Params); err != nil {
+		resp.Error = err.Error()
+		return resp, nil
+	}
+
+	// Anyone can claim to have paid, but only the payee can attest that
+	// it received a payment.
+	if receipt.role == receiptRolePayee && signer != receipt.payee {
+		resp.Error = "receipt of payee not signed by payee"
+		return resp, nil
+	}
+
+	resp.Valid = true
+	return resp, nil
+}
+
+// AttestNode returns a summary of the state of the node, signed by its
+// identity key along with the nonce supplied by the caller.
+func (r *rpcServer) AttestNode(ctx context.Context,
+	in *lnrpc.AttestNodeRequest) (*lnrpc.NodeAttestation, error) {
+
+	if err := validateAttestationNonce(in.Nonce); err != nil {
+		return nil, err
+	}
+
+	info, err := r.GetInfo(ctx, &lnrpc.GetInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	attestation, err := newNodeAttestation(in.Nonce, info, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	attestationBytes, err := attestation.serialize()
+	if err != nil {
+		return nil, err
+	}
+
+	msg := attestationSignedMsg(attestationBytes)
+	sigBytes, err := r.server.nodeSigner.SignCompact(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Debugf("[attestnode] attested node for nonce %x", in.Nonce)
+
+	rpcAttestation := marshallNodeAttestation(attestation)
+	rpcAttestation.Attestation = attestationBytes
+	rpcAttestation.Signature = zbase32.EncodeToString(sigBytes)
+
+	return rpcAttestation, nil
+}
+
+// VerifyNodeAttestation verifies the signature of a node attestation and that
+// it was made for the expected nonce, and returns the public key of the node
+// that signed it.
+func (r *rpcServer) VerifyNodeAttestation(ctx context.Context,
+	in *lnrpc.VerifyNodeAttestationRequest) (
+	*lnrpc.VerifyNodeAttestationResponse, error) {
+
+	attestation, err := deserializeNodeAttestation(in.Attestation)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode attestation: %v", err)
+	}
+
+	sig, err := zbase32.DecodeString(in.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signature: %v", err)
+	}
+
+	resp := &lnrpc.VerifyNodeAttestationResponse{
+		Attestation: marshallNodeAttestation(attestation),
+	}
+	resp.Attestation.Attestation = in.Attestation
+	resp.Attestation.Signature = in.Signature
+
+	// RecoverCompact both recovers the pubkey and validates the signature
+	// over the BLAKE-256 hash of the prefixed attestation.
+	msg := attestationSignedMsg(in.Attestation)
+	pubKey, _, err := ecdsa.RecoverCompact(sig, chainhash.HashB(msg))
+	if err != nil {
+		resp.Error = "invalid signature"
+		return resp, nil
+	}
+	signer := route.NewVertex(pubKey)
+	resp.Signer = hex.EncodeToString(signer[:])
+
+	if err := attestation.validate(signer, in.Nonce); err != nil {
+		resp.Error = err.Error()
+		return resp, nil
+	}
+
+	resp.Valid = true
+	return resp, nil
+}
+
 // ConnectPeer attempts to establish a connection to a remote peer.
 func (r *rpcServer) ConnectPeer(ctx context.Context,
 	in *lnrpc.ConnectPeerRequest) (*lnrpc.ConnectPeerResponse, error) {
@@ -1570,6 +2320,34 @@ func (r *rpcServer) DisconnectPeer(ctx context.Context,
 	return &lnrpc.DisconnectPeerResponse{}, nil
 }
 
+// RotatePeerCircuit moves the connection with a peer onto a fresh Tor circuit,
+// reconnecting to the peer over the new circuit.
+func (r *rpcServer) RotatePeerCircuit(ctx context.Context,
+	in *lnrpc.RotatePeerCircuitRequest) (*lnrpc.RotatePeerCircuitResponse,
+	error) {
+
+	rpcsLog.Debugf("[rotatepeercircuit] peer(%s)", in.PubKey)
+
+	if !r.server.Started() {
+		return nil, ErrServerNotActive
+	}
+
+	pubKeyBytes, err := hex.DecodeString(in.PubKey)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode pubkey bytes: %v", err)
+	}
+	peerPubKey, err := secp256k1.ParsePubKey(pubKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse pubkey: %v", err)
+	}
+
+	if err := r.server.RotatePeerCircuit(peerPubKey); err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.RotatePeerCircuitResponse{}, nil
+}
+
 // extractOpenChannelMinConfs extracts the minimum number of confirmations from
 // the OpenChannelRequest that each output used to fund the channel's funding
 // transaction should satisfy.
@@ -1614,6 +2392,9 @@ func newFundingShimAssembler(chanPointShim *lnrpc.ChanPointShim, initiator bool,
 	// Perform some basic sanity checks to ensure that all the expected
 	// fields are populated.
 	switch {
+	case chanPointShim.Amt <= 0:
+		return nil, fmt.Errorf("amount not set")
+
 	case chanPointShim.RemoteKey == nil:
 		return nil, fmt.Errorf("remote key not set")
 
@@ -1757,6 +2538,59 @@ func (r *rpcServer) canOpenChannel() error {
 	return nil
 }
 
+// openChannelFundingAmt returns the amount we're committing to the channel
+// being opened. When the channel is funded by an externally crafted
+// transaction through a chan point shim, the size of the channel is dictated
+// by the pre-crafted output, so the local funding amount may be omitted, but
+// must otherwise match it.
+func openChannelFundingAmt(in *lnrpc.OpenChannelRequest) (dcrutil.Amount,
+	error) {
+
+	localFundingAmt := dcrutil.Amount(in.LocalFundingAmount)
+
+	chanPointShim := in.FundingShim.GetChanPointShim()
+	if chanPointShim == nil {
+		return localFundingAmt, nil
+	}
+
+	shimAmt := dcrutil.Amount(chanPointShim.Amt)
+	switch {
+	case localFundingAmt == 0:
+		return shimAmt, nil
+
+	case localFundingAmt != shimAmt:
+		return 0, fmt.Errorf("local funding amount %v doesn't match "+
+			"the chan point shim amount %v", localFundingAmt,
+			shimAmt)
+	}
+
+	return localFundingAmt, nil
+}
+
+// rpcFundingError converts the well known funding errors sent by remote peers
+// into gRPC errors with a matching status code, so clients can tell them apart
+// without parsing the error message. Other errors are returned as is.
+func rpcFundingError(err error) error {
+	var fundingErr lnwire.FundingError
+	if !errors.As(err, &fundingErr) {
+		return err
+	}
+
+	switch fundingErr {
+	case lnwire.ErrMaxPendingChannels, lnwire.ErrMaxGlobalPendingChannels:
+		return status.Error(codes.ResourceExhausted, err.Error())
+
+	case lnwire.ErrSynchronizingChain:
+		return status.Error(codes.Unavailable, err.Error())
+
+	case lnwire.ErrChanTooLarge:
+		return status.Error(codes.FailedPrecondition, err.Error())
+
+	default:
+		return err
+	}
+}
+
 // praseOpenChannelReq parses an OpenChannelRequest message into the server's
 // native openChanReq struct. The logic is abstracted so that it can be shared
 // between OpenChannel and OpenChannelSync.
@@ -1767,7 +2601,10 @@ func (r *rpcServer) parseOpenChannelReq(in *lnrpc.OpenChannelRequest,
 		"allocation(us=%v, them=%v)", in.NodePubkey,
 		in.LocalFundingAmount, in.PushAtoms)
 
-	localFundingAmt := dcrutil.Amount(in.LocalFundingAmount)
+	localFundingAmt, err := openChannelFundingAmt(in)
+	if err != nil {
+		return nil, err
+	}
 	remoteInitialBalance := dcrutil.Amount(in.PushAtoms)
 	minHtlcIn := lnwire.MilliAtom(in.MinHtlcMAtoms)
 	remoteCsvDelay := uint16(in.RemoteCsvDelay)
@@ -1883,6 +2720,22 @@ func (r *rpcServer) parseOpenChannelReq(in *lnrpc.OpenChannelRequest,
 			err)
 	}
 
+	if len(in.Label) > labels.TxLabelLimit {
+		return nil, fmt.Errorf("label length: %v exceeds limit of %v",
+			len(in.Label), labels.TxLabelLimit)
+	}
+
+	// If the caller requires a particular commitment type, we'll map it
+	// to the wallet type so the funding manager can enforce it.
+	var commitType *lnwallet.CommitmentType
+	if in.CommitmentTypeSpecified {
+		t, err := parseRPCCommitmentType(in.CommitmentType)
+		if err != nil {
+			return nil, err
+		}
+		commitType = &t
+	}
+
 	// Instruct the server to trigger the necessary events to attempt to
 	// open a new channel. A stream is returned in place, this stream will
 	// be used to consume updates of the state of the pending channel.
@@ -1899,6 +2752,8 @@ func (r *rpcServer) parseOpenChannelReq(in *lnrpc.OpenChannelRequest,
 		shutdownScript:   script,
 		maxValueInFlight: maxValue,
 		maxHtlcs:         maxHtlcs,
+		commitType:       commitType,
+		label:            in.Label,
 	}, nil
 }
 
@@ -1967,7 +2822,7 @@ out:
 		case err := <-errChan:
 			rpcsLog.Errorf("unable to open channel to NodeKey(%x): %v",
 				req.targetPubkey.SerializeCompressed(), err)
-			return err
+			return rpcFundingError(err)
 		case fundingUpdate := <-updateChan:
 			rpcsLog.Tracef("[openchannel] sending update: %v",
 				fundingUpdate)
@@ -2024,7 +2879,7 @@ func (r *rpcServer) OpenChannelSync(ctx context.Context,
 	case err := <-errChan:
 		rpcsLog.Errorf("unable to open channel to NodeKey(%x): %v",
 			req.targetPubkey.SerializeCompressed(), err)
-		return nil, err
+		return nil, rpcFundingError(err)
 
 	// Otherwise, wait for the first channel update. The first update sent
 	// is when the funding transaction is broadcast to the network.
@@ -2108,10 +2963,20 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 
 	// If force closing a channel, the fee set in the commitment transaction
 	// is used.
-	if in.Force && (in.AtomsPerByte != 0 || in.TargetConf != 0) {
+	if in.Force && (in.AtomsPerByte != 0 || in.TargetConf != 0 ||
+		in.MaxFeeRate != 0) {
+
 		return fmt.Errorf("force closing a channel uses a pre-defined fee")
 	}
 
+	// The starting fee rate of the negotiation can't be above the max fee
+	// rate we're willing to agree to.
+	if in.MaxFeeRate != 0 && in.AtomsPerByte > in.MaxFeeRate {
+		return fmt.Errorf("fee rate of %v atoms/byte is above the max "+
+			"fee rate of %v atoms/byte", in.AtomsPerByte,
+			in.MaxFeeRate)
+	}
+
 	force := in.Force
 	index := in.ChannelPoint.OutputIndex
 	txid, err := GetChanPointFundingTxid(in.GetChannelPoint())
@@ -2180,7 +3045,13 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 		}
 
 		// With the necessary indexes cleaned up, we'll now force close
-		// the channel.
+		// the channel, replacing any earlier reason for its closure.
+		r.server.channelNotifier.NotifyCloseReasonEvent(
+			*chanPoint, &channeldb.ChannelCloseReason{
+				Code:      channeldb.CloseReasonForceClose,
+				Timestamp: time.Now(),
+			},
+		)
 		chainArbitrator := r.server.chainArb
 		closingTx, err := chainArbitrator.ForceCloseContract(
 			*chanPoint,
@@ -2210,6 +3081,29 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 					Success:     true,
 				}
 			})
+	} else if channel.HasChanStatus(channeldb.ChanStatusCoopBroadcasted) {
+		// The channel is already being cooperatively closed, so we'll
+		// follow the progress of the ongoing negotiation, which is
+		// resumed whenever the peer reconnects.
+		if _, err := channel.BroadcastedCooperative(); err == nil {
+			return fmt.Errorf("closing transaction of channel %v "+
+				"already broadcast", chanPoint)
+		}
+
+		chanPeer, err := r.server.FindPeer(channel.IdentityPub)
+		if err != nil {
+			return fmt.Errorf("cooperative close of channel %v will "+
+				"resume once the peer reconnects", chanPoint)
+		}
+
+		updateChan = make(chan interface{}, 2)
+		errChan = make(chan error, 1)
+		chanPeer.HandleLocalCloseChanReqs(&htlcswitch.ChanClose{
+			CloseType: htlcswitch.CloseRegular,
+			ChanPoint: chanPoint,
+			Updates:   updateChan,
+			Err:       errChan,
+		})
 	} else {
 		// If this is a frozen channel, then we only allow the co-op
 		// close to proceed if we were the responder to this channel if
@@ -2251,8 +3145,15 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 			return err
 		}
 
-		rpcsLog.Debugf("Target atom/kB for closing transaction: %v",
-			int64(feeRate))
+		// If the fee rate was estimated, it's clamped to the max fee
+		// rate.
+		maxFeeRate := chainfee.AtomPerKByte(in.MaxFeeRate * 1000)
+		if maxFeeRate != 0 && feeRate > maxFeeRate {
+			feeRate = maxFeeRate
+		}
+
+		rpcsLog.Debugf("Target atom/kB for closing transaction: %v "+
+			"(max=%v)", int64(feeRate), int64(maxFeeRate))
 
 		// Before we attempt the cooperative channel closure, we'll
 		// examine the channel to ensure that it doesn't have a
@@ -2287,7 +3188,8 @@ func (r *rpcServer) CloseChannel(in *lnrpc.CloseChannelRequest,
 		}
 
 		updateChan, errChan = r.server.htlcSwitch.CloseLink(
-			chanPoint, htlcswitch.CloseRegular, feeRate, deliveryScript,
+			chanPoint, htlcswitch.CloseRegular, feeRate, maxFeeRate,
+			deliveryScript,
 		)
 	}
 out:
@@ -2387,10 +3289,19 @@ func (r *rpcServer) AbandonChannel(_ context.Context,
 	// If this isn't the dev build, then we won't allow the RPC to be
 	// executed, as it's an advanced feature and won't be activated in
 	// regular production/release builds except for the explicit case of
-	// externally funded channels that are still pending.
+	// externally funded channels that are still pending, or when the
+	// operator explicitly opted in both in the config and the request.
 	if !in.PendingFundingShimOnly && !build.IsDevBuild() {
-		return nil, fmt.Errorf("AbandonChannel RPC call only " +
-			"available in dev builds")
+		switch {
+		case !r.cfg.AllowAbandonChannel:
+			return nil, fmt.Errorf("AbandonChannel RPC call only " +
+				"available in dev builds or with " +
+				"--allow-abandon-channel")
+
+		case !in.IKnowWhatIAmDoing:
+			return nil, fmt.Errorf("AbandonChannel RPC call " +
+				"requires i_know_what_i_am_doing to be set")
+		}
 	}
 
 	// We'll parse out the arguments to we can obtain the chanPoint of the
@@ -2409,36 +3320,68 @@ func (r *rpcServer) AbandonChannel(_ context.Context,
 		return nil, err
 	}
 
-	dbChan, err := r.server.remoteChanDB.FetchChannel(*chanPoint)
-	switch {
-	// If the channel isn't found in the set of open channels, then we can
-	// continue on as it can't be loaded into the link/peer.
-	case err == channeldb.ErrChannelNotFound:
+	// If the user requested the more safe version that only allows the
+	// removal of externally (shim) funded channels that are still pending,
+	// we enforce this option once we know the state of the channel.
+	var checkChannel func(*channeldb.OpenChannel) error
+	if in.PendingFundingShimOnly {
+		checkChannel = func(dbChan *channeldb.OpenChannel) error {
+			// TODO(guggero): Properly store the funding type
+			// (wallet, shim, PSBT) on the channel so we don't need
+			// to use the thaw height.
+			isShimFunded := dbChan.ThawHeight > 0
+			if !isShimFunded || !dbChan.IsPending {
+				return fmt.Errorf("channel %v is not externally "+
+					"funded or not pending", chanPoint)
+			}
+
+			return nil
+		}
+	}
+
+	err = r.abandonChannel(
+		chanPoint, uint32(bestHeight), in.PendingFundingShimOnly,
+		checkChannel,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.AbandonChannelResponse{}, nil
+}
+
+// abandonChannel removes all state of the channel with the given channel point
+// from the database, the graph, the contract court and the nursery, using the
+// passed best height as its close height. If the channel is still open, it's
+// first checked by the optional checkChannel function and removed from the
+// switch and its peer.
+func (r *rpcServer) abandonChannel(chanPoint *wire.OutPoint, bestHeight uint32,
+	pendingFundingShimOnly bool,
+	checkChannel func(*channeldb.OpenChannel) error) error {
+
+	dbChan, err := r.server.remoteChanDB.FetchChannel(*chanPoint)
+	wasOpen := err == nil
+	switch {
+	// If the channel isn't found in the set of open channels, then we can
+	// continue on as it can't be loaded into the link/peer.
+	case err == channeldb.ErrChannelNotFound:
 		break
 
 	// If the channel is still known to be open, then before we modify any
 	// on-disk state, we'll remove the channel from the switch and peer
 	// state if it's been loaded in.
 	case err == nil:
-		// If the user requested the more safe version that only allows
-		// the removal of externally (shim) funded channels that are
-		// still pending, we enforce this option now that we know the
-		// state of the channel.
-		//
-		// TODO(guggero): Properly store the funding type (wallet, shim,
-		// PSBT) on the channel so we don't need to use the thaw height.
-		isShimFunded := dbChan.ThawHeight > 0
-		isPendingShimFunded := isShimFunded && dbChan.IsPending
-		if in.PendingFundingShimOnly && !isPendingShimFunded {
-			return nil, fmt.Errorf("channel %v is not externally "+
-				"funded or not pending", chanPoint)
+		if checkChannel != nil {
+			if err := checkChannel(dbChan); err != nil {
+				return err
+			}
 		}
 
 		// We'll mark the channel as borked before we remove the state
 		// from the switch/peer so it won't be loaded back in if the
 		// peer reconnects.
 		if err := dbChan.MarkBorked(); err != nil {
-			return nil, err
+			return err
 		}
 		remotePub := dbChan.IdentityPub
 		if peer, err := r.server.FindPeer(remotePub); err == nil {
@@ -2446,7 +3389,25 @@ func (r *rpcServer) AbandonChannel(_ context.Context,
 		}
 
 	default:
-		return nil, err
+		return err
+	}
+
+	// Record the abandonment in the audit log before touching any of the
+	// channel state, so there's a trace of it even if one of the steps
+	// below fails.
+	rpcsLog.Warnf("[abandonchannel] abandoning ChannelPoint(%v), "+
+		"was_open=%v", chanPoint, wasOpen)
+	err = r.server.remoteChanDB.AddAbandonAuditEntry(
+		&channeldb.AbandonAuditEntry{
+			ChanPoint:              *chanPoint,
+			Timestamp:              time.Now(),
+			BestHeight:             bestHeight,
+			WasOpen:                wasOpen,
+			PendingFundingShimOnly: pendingFundingShimOnly,
+		},
+	)
+	if err != nil {
+		return err
 	}
 
 	// Abandoning a channel is a three step process: remove from the open
@@ -2454,19 +3415,19 @@ func (r *rpcServer) AbandonChannel(_ context.Context,
 	// court. Between any step it's possible that the users restarts the
 	// process all over again. As a result, each of the steps below are
 	// intended to be idempotent.
-	err = r.server.remoteChanDB.AbandonChannel(chanPoint, uint32(bestHeight))
+	err = r.server.remoteChanDB.AbandonChannel(chanPoint, bestHeight)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	err = abandonChanFromGraph(
 		r.server.localChanDB.ChannelGraph(), chanPoint,
 	)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	err = r.server.chainArb.ResolveContract(*chanPoint)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	// If this channel was in the process of being closed, but didn't fully
@@ -2475,14 +3436,89 @@ func (r *rpcServer) AbandonChannel(_ context.Context,
 	// state for this channel from the nursery.
 	err = r.server.utxoNursery.cfg.Store.RemoveChannel(chanPoint)
 	if err != nil && err != ErrContractNotFound {
-		return nil, err
+		return err
 	}
 
 	// Finally, notify the backup listeners that the channel can be removed
 	// from any channel backups.
 	r.server.channelNotifier.NotifyClosedChannelEvent(*chanPoint)
 
-	return &lnrpc.AbandonChannelResponse{}, nil
+	return nil
+}
+
+// ExportChannelCommitment returns the latest fully signed local commitment
+// transaction of a channel, along with the signed HTLC timeout transactions
+// spending from it.
+func (r *rpcServer) ExportChannelCommitment(_ context.Context,
+	in *lnrpc.ExportChannelCommitmentRequest) (
+	*lnrpc.ExportChannelCommitmentResponse, error) {
+
+	if in.ChannelPoint == nil {
+		return nil, fmt.Errorf("channel point must be specified")
+	}
+	txid, err := GetChanPointFundingTxid(in.GetChannelPoint())
+	if err != nil {
+		return nil, err
+	}
+	index := in.ChannelPoint.OutputIndex
+	chanPoint := wire.NewOutPoint(txid, index, wire.TxTreeRegular)
+
+	dbChan, err := r.server.remoteChanDB.FetchChannel(*chanPoint)
+	if err != nil {
+		return nil, err
+	}
+
+	chanMachine, err := lnwallet.NewLightningChannel(
+		r.server.cc.signer, dbChan, nil, activeNetParams.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	summary, err := chanMachine.ExportLocalCommitment()
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Warnf("[exportchannelcommitment] exported signed commitment "+
+		"of ChannelPoint(%v) at height %v", chanPoint,
+		dbChan.LocalCommitment.CommitHeight)
+
+	rawCommit, err := summary.CloseTx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	resp := &lnrpc.ExportChannelCommitmentResponse{
+		CommitTxHex:  hex.EncodeToString(rawCommit),
+		CommitTxid:   summary.CloseTx.TxHash().String(),
+		CommitHeight: dbChan.LocalCommitment.CommitHeight,
+		CsvDelay:     uint32(dbChan.LocalChanCfg.CsvDelay),
+	}
+
+	htlcs := summary.HtlcResolutions
+	if htlcs == nil {
+		return resp, nil
+	}
+	resp.NumIncomingHtlcs = uint32(len(htlcs.IncomingHTLCs))
+	for _, htlc := range htlcs.OutgoingHTLCs {
+		if htlc.SignedTimeoutTx == nil {
+			continue
+		}
+
+		rawTx, err := htlc.SignedTimeoutTx.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		resp.HtlcTimeoutTxs = append(
+			resp.HtlcTimeoutTxs, &lnrpc.ExportedHTLCTimeoutTx{
+				RawTxHex:    hex.EncodeToString(rawTx),
+				Expiry:      htlc.Expiry,
+				CsvDelay:    htlc.CsvDelay,
+				AmountAtoms: htlc.SweepSignDesc.Output.Value,
+			},
+		)
+	}
+
+	return resp, nil
 }
 
 // GetInfo returns general information concerning the lightning node including
@@ -2529,6 +3565,10 @@ func (r *rpcServer) GetInfo(ctx context.Context,
 			"with current best block in the main chain: %v", err)
 	}
 
+	syncInfo, blocksRemaining := r.chainSyncInfo(
+		bestHeight, isSynced, bestHeaderTimestamp,
+	)
+
 	network := normalizeNetwork(activeNetParams.Name)
 	activeChains := make([]*lnrpc.Chain, r.cfg.registeredChains.NumActiveChains())
 	for i, chain := range r.cfg.registeredChains.ActiveChains() {
@@ -2570,7 +3610,6 @@ func (r *rpcServer) GetInfo(ctx context.Context,
 		}
 	}
 
-	// TODO(roasbeef): add synced height n stuff
 	return &lnrpc.GetInfoResponse{
 		IdentityPubkey:      encodedIDPub,
 		NumPendingChannels:  nPendingChannels,
@@ -2590,10 +3629,99 @@ func (r *rpcServer) GetInfo(ctx context.Context,
 		CommitHash:          build.Commit,
 		SyncedToGraph:       isGraphSynced,
 		Features:            features,
+		CltvConstants:       r.cltvConstants(),
 		ServerActive:        r.server.Started() && !r.server.Stopped(),
+		ChainBackend:        r.server.cc.wc.BackEnd(),
+		WalletHeadersHeight: uint32(syncInfo.HeadersHeight),
+		WalletBlocksHeight:  uint32(syncInfo.BlocksHeight),
+		RescanHeight:        uint32(syncInfo.RescanHeight),
+		BlocksRemaining:     blocksRemaining,
+		MaintenanceMode:     r.maintenance.isActive(),
+		WalletLocked:        r.walletLock != nil && r.walletLock.Locked(),
+	}, nil
+}
+
+// walletSyncInfo returns the progress of the synchronization of the wallet
+// with its chain backend, falling back to the best block of the wallet if it
+// can't report more details.
+func (r *rpcServer) walletSyncInfo() (*lnwallet.ChainSyncInfo, error) {
+	if reporter, ok := r.server.cc.wc.(lnwallet.ChainSyncReporter); ok {
+		syncInfo, err := reporter.ChainSyncInfo()
+		if err == nil {
+			return syncInfo, nil
+		}
+
+		rpcsLog.Warnf("Unable to get chain sync info, falling back to "+
+			"the best block of the wallet: %v", err)
+	}
+
+	// Without more details, all the blocks up to the tip of the wallet are
+	// considered processed.
+	height, _, _, err := r.server.cc.wc.BestBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnwallet.ChainSyncInfo{
+		HeadersHeight: height,
+		BlocksHeight:  height,
 	}, nil
 }
 
+// chainSyncInfo returns the progress of the synchronization of the wallet
+// with its chain backend, along with the estimated number of blocks it still
+// needs to process given the best known height of the chain. The progress is
+// only informational, so empty sync details are returned if none are
+// available.
+func (r *rpcServer) chainSyncInfo(bestHeight int32, isSynced bool,
+	bestHeaderTimestamp int64) (*lnwallet.ChainSyncInfo, uint32) {
+
+	syncInfo, err := r.walletSyncInfo()
+	if err != nil {
+		rpcsLog.Errorf("Unable to get chain sync info: %v", err)
+		return &lnwallet.ChainSyncInfo{}, 0
+	}
+
+	if isSynced {
+		return syncInfo, 0
+	}
+
+	// The blocks up to the best known height of either the chain backend
+	// or the fetched headers still need to be processed.
+	targetHeight := int64(bestHeight)
+	if syncInfo.HeadersHeight > targetHeight {
+		targetHeight = syncInfo.HeadersHeight
+	}
+	if targetHeight > syncInfo.BlocksHeight {
+		return syncInfo, uint32(targetHeight - syncInfo.BlocksHeight)
+	}
+
+	// Otherwise the chain backend itself is still syncing, so we'll
+	// estimate the number of blocks mined since the best header.
+	sinceBestHeader := time.Since(time.Unix(bestHeaderTimestamp, 0))
+	if sinceBestHeader <= 0 {
+		return syncInfo, 0
+	}
+	blocksRemaining := sinceBestHeader / activeNetParams.TargetTimePerBlock
+
+	return syncInfo, uint32(blocksRemaining)
+}
+
+// cltvConstants returns the currently effective CLTV related constants of the
+// node.
+func (r *rpcServer) cltvConstants() *lnrpc.CltvConstants {
+	finalDelta, outgoingDelta := r.server.cltvRejectDeltas()
+
+	return &lnrpc.CltvConstants{
+		FinalCltvRejectDelta:    finalDelta,
+		OutgoingCltvRejectDelta: outgoingDelta,
+		IncomingBroadcastDelta:  lncfg.DefaultIncomingBroadcastDelta,
+		OutgoingBroadcastDelta:  lncfg.DefaultOutgoingBroadcastDelta,
+		MaxOutgoingCltvExpiry:   r.cfg.MaxOutgoingCltvExpiry,
+		TimeLockDelta:           r.cfg.TimeLockDelta,
+	}
+}
+
 // GetRecoveryInfo returns a boolean indicating whether the wallet is started
 // in recovery mode, whether the recovery is finished, and the progress made
 // so far.
@@ -2615,6 +3743,46 @@ func (r *rpcServer) GetRecoveryInfo(ctx context.Context,
 	}, nil
 }
 
+// marshallDBCompactionStats converts the stats of a db compaction into their
+// RPC representation.
+func marshallDBCompactionStats(
+	stats dbCompactionStats) *lnrpc.DbCompactionStats {
+
+	return &lnrpc.DbCompactionStats{
+		FwdPkgsRemoved:  stats.fwdPkgsRemoved,
+		CircuitsRemoved: stats.circuitsRemoved,
+		BytesReclaimed:  stats.bytesReclaimed,
+	}
+}
+
+// DbInfo returns information about the channel database, including the amount
+// of forwarding data reclaimed by the background compaction.
+func (r *rpcServer) DbInfo(ctx context.Context,
+	in *lnrpc.DbInfoRequest) (*lnrpc.DbInfoResponse, error) {
+
+	compactor := r.server.dbCompactor
+	if compactor == nil {
+		return &lnrpc.DbInfoResponse{
+			LastCompaction:  &lnrpc.DbCompactionStats{},
+			TotalCompaction: &lnrpc.DbCompactionStats{},
+		}, nil
+	}
+
+	lastCompaction, last, total := compactor.Stats()
+
+	var lastCompactionTime int64
+	if !lastCompaction.IsZero() {
+		lastCompactionTime = lastCompaction.Unix()
+	}
+
+	return &lnrpc.DbInfoResponse{
+		CompactionEnabled:  true,
+		LastCompactionTime: lastCompactionTime,
+		LastCompaction:     marshallDBCompactionStats(last),
+		TotalCompaction:    marshallDBCompactionStats(total),
+	}, nil
+}
+
 // ListPeers returns a verbose listing of all currently active peers.
 func (r *rpcServer) ListPeers(ctx context.Context,
 	in *lnrpc.ListPeersRequest) (*lnrpc.ListPeersResponse, error) {
@@ -2626,6 +3794,8 @@ func (r *rpcServer) ListPeers(ctx context.Context,
 		Peers: make([]*lnrpc.Peer, 0, len(serverPeers)),
 	}
 
+	pendingCounts, _ := r.server.fundingMgr.PendingChannelCounts()
+
 	for _, serverPeer := range serverPeers {
 		var (
 			atomsSent int64
@@ -2686,6 +3856,27 @@ func (r *rpcServer) ListPeers(ctx context.Context,
 			PingTime:  serverPeer.PingTime(),
 			SyncType:  lnrpcSyncType,
 			Features:  features,
+
+			PingTimeHistory:    serverPeer.PingTimeHistory(),
+			NumPendingChannels: pendingCounts[nodePub],
+		}
+
+		if startTime := serverPeer.StartTime(); !startTime.IsZero() {
+			rpcPeer.ConnUptime = int64(time.Since(startTime).Seconds())
+		}
+
+		// The flaps of the peer are only tracked for peers we have
+		// channels with, so a peer unknown to the event store hasn't
+		// flapped as far as we know.
+		fitness, err := r.server.chanEventStore.GetPeerFitness(nodePub)
+		switch err {
+		case nil:
+			rpcPeer.RecentFlapCount = uint32(fitness.RecentFlapCount)
+
+		case chanfitness.ErrPeerNotFound:
+
+		default:
+			return nil, err
 		}
 
 		var peerErrors []interface{}
@@ -2768,6 +3959,80 @@ func (r *rpcServer) SubscribePeerEvents(req *lnrpc.PeerEventSubscription,
 	}
 }
 
+// PeerFeatureReport diffs the features advertised by our node against the ones
+// advertised by the given peer and reports which features would be negotiated
+// on a new connection or channel with it.
+func (r *rpcServer) PeerFeatureReport(ctx context.Context,
+	in *lnrpc.PeerFeatureReportRequest) (*lnrpc.PeerFeatureReportResponse,
+	error) {
+
+	pubKey, err := route.NewVertexFromStr(in.PubKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the peer is currently connected, we'll use the features it sent
+	// us in its init message. Otherwise, we fall back to the features of
+	// its latest node announcement.
+	var (
+		remoteFeatures *lnwire.FeatureVector
+		connected      bool
+	)
+	serverPeer, err := r.server.FindPeerByPubStr(string(pubKey[:]))
+	switch {
+	case err == nil:
+		remoteFeatures = serverPeer.RemoteFeatures()
+		connected = true
+
+	case err == ErrPeerNotConnected:
+		graph := r.server.localChanDB.ChannelGraph()
+		node, err := graph.FetchLightningNode(nil, pubKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to find features of "+
+				"peer %v: %v", pubKey, err)
+		}
+		remoteFeatures = node.Features
+
+	default:
+		return nil, err
+	}
+
+	localFeatures := r.server.featureMgr.Get(feature.SetInit)
+
+	report := feature.Compare(localFeatures, remoteFeatures)
+	rpcFeatures := make([]*lnrpc.FeatureCompatibility, 0, len(report))
+	for _, c := range report {
+		rpcFeatures = append(rpcFeatures, &lnrpc.FeatureCompatibility{
+			Bit:            uint32(c.Bit),
+			Name:           c.Name,
+			LocalSupport:   c.LocalSupport,
+			RemoteSupport:  c.RemoteSupport,
+			LocalRequired:  c.LocalRequired,
+			RemoteRequired: c.RemoteRequired,
+			Negotiated:     c.Negotiated(),
+			Incompatible:   c.Incompatible(),
+		})
+	}
+
+	var rpcCommitType lnrpc.CommitmentType
+	switch commitmentType(localFeatures, remoteFeatures) {
+	case lnwallet.CommitmentTypeAnchors:
+		rpcCommitType = lnrpc.CommitmentType_ANCHORS
+
+	case lnwallet.CommitmentTypeTweakless:
+		rpcCommitType = lnrpc.CommitmentType_STATIC_REMOTE_KEY
+
+	default:
+		rpcCommitType = lnrpc.CommitmentType_LEGACY
+	}
+
+	return &lnrpc.PeerFeatureReportResponse{
+		Connected:      connected,
+		Features:       rpcFeatures,
+		CommitmentType: rpcCommitType,
+	}, nil
+}
+
 // WalletBalance returns total unspent outputs(confirmed and unconfirmed), all
 // confirmed unspent outputs and all unconfirmed unspent outputs under control
 // by the wallet. This method can be modified by having the request specify
@@ -2776,30 +4041,29 @@ func (r *rpcServer) SubscribePeerEvents(req *lnrpc.PeerEventSubscription,
 func (r *rpcServer) WalletBalance(ctx context.Context,
 	in *lnrpc.WalletBalanceRequest) (*lnrpc.WalletBalanceResponse, error) {
 
-	// Get total balance, from txs that have >= 0 confirmations.
-	totalBal, err := r.server.cc.wallet.ConfirmedBalance(0)
-	if err != nil {
-		return nil, err
-	}
-
-	// Get confirmed balance, from txs that have >= 1 confirmations.
-	// TODO(halseth): get both unconfirmed and confirmed balance in one
-	// call, as this is racy.
-	confirmedBal, err := r.server.cc.wallet.ConfirmedBalance(1)
+	// Fetch all the balances under the coin selection lock, so they're
+	// consistent with each other.
+	var walletBals *lnwallet.WalletBalances
+	err := r.server.cc.wallet.WithCoinSelectLock(func() error {
+		var err error
+		walletBals, err = r.server.cc.wallet.WalletBalances()
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Get unconfirmed balance, from txs with 0 confirmations.
-	unconfirmedBal := totalBal - confirmedBal
+	totalBal := walletBals.Confirmed + walletBals.Unconfirmed
 
 	rpcsLog.Debugf("[walletbalance] Total balance=%v (confirmed=%v, "+
-		"unconfirmed=%v)", totalBal, confirmedBal, unconfirmedBal)
+		"unconfirmed=%v, maturing=%v)", totalBal, walletBals.Confirmed,
+		walletBals.Unconfirmed, walletBals.Maturing)
 
 	return &lnrpc.WalletBalanceResponse{
 		TotalBalance:       int64(totalBal),
-		ConfirmedBalance:   int64(confirmedBal),
-		UnconfirmedBalance: int64(unconfirmedBal),
+		ConfirmedBalance:   int64(walletBals.Confirmed),
+		UnconfirmedBalance: int64(walletBals.Unconfirmed),
+		MaturingBalance:    int64(walletBals.Maturing),
 	}, nil
 }
 
@@ -2813,16 +4077,34 @@ func (r *rpcServer) ChannelBalance(ctx context.Context,
 		return nil, err
 	}
 
-	var balance dcrutil.Amount
-	var maxInbound dcrutil.Amount
-	var maxOutbound dcrutil.Amount
+	var (
+		localBalance           lnwire.MilliAtom
+		remoteBalance          lnwire.MilliAtom
+		unsettledLocalBalance  lnwire.MilliAtom
+		unsettledRemoteBalance lnwire.MilliAtom
+		pendingOpenLocal       lnwire.MilliAtom
+		pendingOpenRemote      lnwire.MilliAtom
+		maxInbound             dcrutil.Amount
+		maxOutbound            dcrutil.Amount
+	)
 	for _, channel := range openChannels {
 		local := channel.LocalCommitment.LocalBalance.ToAtoms()
 		localReserve := channel.LocalChanCfg.ChannelConstraints.ChanReserve
 		remote := channel.RemoteCommitment.RemoteBalance.ToAtoms()
 		remoteReserve := channel.RemoteChanCfg.ChannelConstraints.ChanReserve
 
-		balance += local
+		localBalance += channel.LocalCommitment.LocalBalance
+		remoteBalance += channel.LocalCommitment.RemoteBalance
+
+		// The htlcs on our commitment are neither part of the local
+		// nor the remote balance until they're resolved.
+		for _, htlc := range channel.LocalCommitment.Htlcs {
+			if htlc.Incoming {
+				unsettledRemoteBalance += htlc.Amt
+			} else {
+				unsettledLocalBalance += htlc.Amt
+			}
+		}
 
 		// The maximum amount we can receive from this channel is however much
 		// the remote node has, minus its required channel reserve.
@@ -2844,22 +4126,129 @@ func (r *rpcServer) ChannelBalance(ctx context.Context,
 		return nil, err
 	}
 
-	var pendingOpenBalance dcrutil.Amount
 	for _, channel := range pendingChannels {
-		pendingOpenBalance += channel.LocalCommitment.LocalBalance.ToAtoms()
+		pendingOpenLocal += channel.LocalCommitment.LocalBalance
+		pendingOpenRemote += channel.LocalCommitment.RemoteBalance
 	}
 
-	rpcsLog.Debugf("[channelbalance] balance=%v pending-open=%v",
-		balance, pendingOpenBalance)
+	rpcsLog.Debugf("[channelbalance] local_balance=%v remote_balance=%v "+
+		"unsettled_local_balance=%v unsettled_remote_balance=%v "+
+		"pending_open_local=%v pending_open_remote=%v",
+		localBalance, remoteBalance, unsettledLocalBalance,
+		unsettledRemoteBalance, pendingOpenLocal, pendingOpenRemote)
 
 	return &lnrpc.ChannelBalanceResponse{
-		Balance:            int64(balance),
-		PendingOpenBalance: int64(pendingOpenBalance),
-		MaxInboundAmount:   int64(maxInbound),
-		MaxOutboundAmount:  int64(maxOutbound),
+		LocalBalance:           marshalAmount(localBalance),
+		RemoteBalance:          marshalAmount(remoteBalance),
+		UnsettledLocalBalance:  marshalAmount(unsettledLocalBalance),
+		UnsettledRemoteBalance: marshalAmount(unsettledRemoteBalance),
+		PendingOpenLocalBalance: marshalAmount(
+			pendingOpenLocal,
+		),
+		PendingOpenRemoteBalance: marshalAmount(
+			pendingOpenRemote,
+		),
+		MaxInboundAmount:  int64(maxInbound),
+		MaxOutboundAmount: int64(maxOutbound),
+
+		// Deprecated fields.
+		Balance:            int64(localBalance.ToAtoms()),
+		PendingOpenBalance: int64(pendingOpenLocal.ToAtoms()),
 	}, nil
 }
 
+// isOurScript returns whether the given output script pays to the wallet.
+func (r *rpcServer) isOurScript(version uint16, pkScript []byte) bool {
+	_, addrs, _, err := txscript.ExtractPkScriptAddrs(
+		version, pkScript, activeNetParams.Params, false,
+	)
+	if err != nil {
+		return false
+	}
+	for _, addr := range addrs {
+		if r.server.cc.wallet.IsOurAddress(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Balances returns a single snapshot of all the funds of the node, both on
+// chain and within channels. The snapshot is taken while holding the coin
+// selection lock, so funds can't move between the wallet and newly funded
+// channels while it's being computed.
+func (r *rpcServer) Balances(ctx context.Context,
+	in *lnrpc.BalancesRequest) (*lnrpc.BalancesResponse, error) {
+
+	var (
+		walletBals  *lnwallet.WalletBalances
+		chanBals    *lnrpc.ChannelBalanceResponse
+		pending     *lnrpc.PendingChannelsResponse
+		walletLimbo dcrutil.Amount
+	)
+	err := r.server.cc.wallet.WithCoinSelectLock(func() error {
+		var err error
+		walletBals, err = r.server.cc.wallet.WalletBalances()
+		if err != nil {
+			return err
+		}
+
+		chanBals, err = r.ChannelBalance(
+			ctx, &lnrpc.ChannelBalanceRequest{},
+		)
+		if err != nil {
+			return err
+		}
+
+		pending, err = r.PendingChannels(
+			ctx, &lnrpc.PendingChannelsRequest{},
+		)
+		if err != nil {
+			return err
+		}
+
+		// The cooperative closing transactions of channels waiting to
+		// be closed are already part of the wallet balance.
+		waitingClose, err := r.server.remoteChanDB.
+			FetchWaitingCloseChannels()
+		if err != nil {
+			return err
+		}
+		walletLimbo, err = walletLimboBalance(
+			waitingClose,
+			(*channeldb.OpenChannel).BroadcastedCooperative,
+			r.isOurScript,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	resp := aggregateBalances(
+		walletBals, chanBals, pending.TotalLimboBalance, walletLimbo,
+	)
+
+	rpcsLog.Debugf("[balances] total_balance=%v confirmed=%v "+
+		"unconfirmed=%v maturing=%v channel_local=%v "+
+		"unsettled_local=%v pending_open_local=%v limbo=%v",
+		resp.TotalBalance, resp.ConfirmedBalance,
+		resp.UnconfirmedBalance, resp.MaturingBalance,
+		resp.ChannelLocalBalance.Atoms, resp.UnsettledLocalBalance.Atoms,
+		resp.PendingOpenLocalBalance.Atoms, resp.LimboBalance)
+
+	return resp, nil
+}
+
+// marshalAmount converts an amount in milli-atoms to its rpc representation.
+func marshalAmount(amt lnwire.MilliAtom) *lnrpc.Amount {
+	return &lnrpc.Amount{
+		Atoms:  uint64(amt.ToAtoms()),
+		MAtoms: uint64(amt),
+	}
+}
+
 // PendingChannels returns a list of all the channels that are currently
 // considered "pending". A channel is pending if it has finished the funding
 // workflow and is waiting for confirmations for the funding txn, or is in the
@@ -3326,66 +4715,169 @@ func (r *rpcServer) ClosedChannels(ctx context.Context,
 	return resp, nil
 }
 
-// ListChannels returns a description of all the open channels that this node
-// is a participant in.
-func (r *rpcServer) ListChannels(ctx context.Context,
-	in *lnrpc.ListChannelsRequest) (*lnrpc.ListChannelsResponse, error) {
+// isChannelActive returns whether the channel with the given peer and funding
+// outpoint is active, i.e. the peer is online and the link of the channel is
+// able to forward payments.
+func (r *rpcServer) isChannelActive(nodePub *secp256k1.PublicKey,
+	chanPoint wire.OutPoint) bool {
 
-	if in.ActiveOnly && in.InactiveOnly {
-		return nil, fmt.Errorf("either `active_only` or " +
-			"`inactive_only` can be set, but not both")
+	if _, err := r.server.FindPeer(nodePub); err != nil {
+		return false
 	}
 
-	if in.PublicOnly && in.PrivateOnly {
-		return nil, fmt.Errorf("either `public_only` or " +
-			"`private_only` can be set, but not both")
+	// A channel is only considered active if it is known by the switch
+	// *and* able to forward incoming/outgoing payments.
+	channelID := lnwire.NewChanIDFromOutPoint(&chanPoint)
+	link, err := r.server.htlcSwitch.GetLink(channelID)
+	if err != nil {
+		return false
 	}
 
-	if len(in.Peer) > 0 && len(in.Peer) != 33 {
-		_, err := route.NewVertexFromBytes(in.Peer)
-		return nil, fmt.Errorf("invalid `peer` key: %v", err)
-	}
+	return link.EligibleToForward()
+}
 
-	resp := &lnrpc.ListChannelsResponse{}
+// ListFundingReservations returns a description of all funding reservations
+// that are currently active.
+func (r *rpcServer) ListFundingReservations(ctx context.Context,
+	in *lnrpc.ListFundingReservationsRequest) (
+	*lnrpc.ListFundingReservationsResponse, error) {
 
-	graph := r.server.localChanDB.ChannelGraph()
+	reservations := r.server.fundingMgr.Reservations()
 
-	dbChannels, err := r.server.remoteChanDB.FetchAllOpenChannels()
-	if err != nil {
-		return nil, err
+	resp := &lnrpc.ListFundingReservationsResponse{
+		Reservations: make(
+			[]*lnrpc.FundingReservation, 0, len(reservations),
+		),
 	}
+	for _, res := range reservations {
+		var state lnrpc.FundingReservation_State
+		switch res.state {
+		case fundingStateAwaitingAccept:
+			state = lnrpc.FundingReservation_AWAITING_ACCEPT
+		case fundingStateAwaitingPsbt:
+			state = lnrpc.FundingReservation_AWAITING_PSBT
+		case fundingStateAwaitingFundingSigned:
+			state = lnrpc.FundingReservation_AWAITING_FUNDING_SIGNED
+		case fundingStateAwaitingFundingCreated:
+			state = lnrpc.FundingReservation_AWAITING_FUNDING_CREATED
+		}
 
-	rpcsLog.Debugf("[listchannels] fetched %v channels from DB",
-		len(dbChannels))
+		initiator := lnrpc.Initiator_INITIATOR_REMOTE
+		if res.initiator() {
+			initiator = lnrpc.Initiator_INITIATOR_LOCAL
+		}
 
-	for _, dbChannel := range dbChannels {
-		nodePub := dbChannel.IdentityPub
-		nodePubBytes := nodePub.SerializeCompressed()
-		chanPoint := dbChannel.FundingOutpoint
-
-		// If the caller requested channels for a target node, skip any
-		// that don't match the provided pubkey.
-		if len(in.Peer) > 0 && !bytes.Equal(nodePubBytes, in.Peer) {
-			continue
+		rpcRes := &lnrpc.FundingReservation{
+			PendingChanId: res.pendingChanID[:],
+			RemoteNodePub: hex.EncodeToString(
+				res.identityPub.SerializeCompressed(),
+			),
+			Initiator:          initiator,
+			State:              state,
+			Capacity:           int64(res.capacity),
+			LocalFundingAmount: int64(res.localFundingAmt),
+			CreatedTime:        res.created.Unix(),
+			Psbt:               res.isPsbt,
+			CannedShim:         res.isCannedShim,
 		}
+		if !res.lastUpdated.IsZero() {
+			rpcRes.LastUpdateTime = res.lastUpdated.Unix()
+		}
+
+		resp.Reservations = append(resp.Reservations, rpcRes)
+	}
+
+	return resp, nil
+}
+
+// CancelFundingReservation cancels the funding workflow of an active
+// reservation, releasing the coins it holds.
+func (r *rpcServer) CancelFundingReservation(ctx context.Context,
+	in *lnrpc.CancelFundingReservationRequest) (
+	*lnrpc.CancelFundingReservationResponse, error) {
+
+	if len(in.PendingChanId) != 32 {
+		return nil, fmt.Errorf("pending chan ID not exactly 32 bytes")
+	}
+
+	var pendingChanID [32]byte
+	copy(pendingChanID[:], in.PendingChanId)
+
+	rpcsLog.Infof("[cancelfundingreservation] pending_chan_id=%x",
+		pendingChanID[:])
+
+	err := r.server.fundingMgr.CancelReservation(pendingChanID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.CancelFundingReservationResponse{}, nil
+}
+
+// ListChannels returns a description of all the open channels that this node
+// is a participant in.
+func (r *rpcServer) ListChannels(ctx context.Context,
+	in *lnrpc.ListChannelsRequest) (*lnrpc.ListChannelsResponse, error) {
+
+	if in.ActiveOnly && in.InactiveOnly {
+		return nil, fmt.Errorf("either `active_only` or " +
+			"`inactive_only` can be set, but not both")
+	}
 
-		var peerOnline bool
-		if _, err := r.server.FindPeer(nodePub); err == nil {
-			peerOnline = true
+	if in.PublicOnly && in.PrivateOnly {
+		return nil, fmt.Errorf("either `public_only` or " +
+			"`private_only` can be set, but not both")
+	}
+
+	// If the caller requested channels for a target node, only fetch the
+	// channels with that node using the index of open channels by node
+	// key.
+	var peerKey *secp256k1.PublicKey
+	if len(in.Peer) > 0 {
+		var err error
+		peerKey, err = secp256k1.ParsePubKey(in.Peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid `peer` key: %v", err)
 		}
+	}
+
+	resp := &lnrpc.ListChannelsResponse{}
+
+	graph := r.server.localChanDB.ChannelGraph()
+
+	var (
+		dbChannels []*channeldb.OpenChannel
+		err        error
+	)
+	if peerKey != nil {
+		dbChannels, err = r.server.remoteChanDB.FetchOpenChannels(
+			peerKey,
+		)
+	} else {
+		dbChannels, err = r.server.remoteChanDB.FetchAllOpenChannels()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Debugf("[listchannels] fetched %v channels from DB",
+		len(dbChannels))
 
-		channelID := lnwire.NewChanIDFromOutPoint(&chanPoint)
-		var linkActive bool
-		if link, err := r.server.htlcSwitch.GetLink(channelID); err == nil {
-			// A channel is only considered active if it is known
-			// by the switch *and* able to forward
-			// incoming/outgoing payments.
-			linkActive = link.EligibleToForward()
+	for _, dbChannel := range dbChannels {
+		// Unlike FetchAllOpenChannels, the channels of a single node
+		// also include the pending and waiting close ones, which
+		// aren't listed.
+		if dbChannel.IsPending ||
+			dbChannel.ChanStatus() != channeldb.ChanStatusDefault {
+
+			continue
 		}
 
 		// Next, we'll determine whether we should add this channel to
 		// our list depending on the type of channels requested to us.
-		isActive := peerOnline && linkActive
+		isActive := r.isChannelActive(
+			dbChannel.IdentityPub, dbChannel.FundingOutpoint,
+		)
 		channel, err := createRPCOpenChannel(r, graph, dbChannel, isActive)
 		if err != nil {
 			return nil, err
@@ -3427,6 +4919,26 @@ func rpcCommitmentType(chanType channeldb.ChannelType) lnrpc.CommitmentType {
 	return lnrpc.CommitmentType_LEGACY
 }
 
+// parseRPCCommitmentType maps the given lnrpc.CommitmentType to the
+// corresponding lnwallet.CommitmentType.
+func parseRPCCommitmentType(
+	commitType lnrpc.CommitmentType) (lnwallet.CommitmentType, error) {
+
+	switch commitType {
+	case lnrpc.CommitmentType_LEGACY:
+		return lnwallet.CommitmentTypeLegacy, nil
+
+	case lnrpc.CommitmentType_STATIC_REMOTE_KEY:
+		return lnwallet.CommitmentTypeTweakless, nil
+
+	case lnrpc.CommitmentType_ANCHORS:
+		return lnwallet.CommitmentTypeAnchors, nil
+
+	default:
+		return 0, fmt.Errorf("unknown commitment type %v", commitType)
+	}
+}
+
 // createChannelConstraint creates a *lnrpc.ChannelConstraints using the
 // *Channeldb.ChannelConfig.
 func createChannelConstraint(
@@ -3613,6 +5125,44 @@ func createRPCOpenChannel(r *rpcServer, graph *channeldb.ChannelGraph,
 	return channel, nil
 }
 
+// marshalCloseReason converts the reason of a channel closure into its RPC
+// representation.
+func marshalCloseReason(
+	reason *channeldb.ChannelCloseReason) *lnrpc.ChannelCloseReason {
+
+	var code lnrpc.CloseReasonCode
+	switch reason.Code {
+	case channeldb.CloseReasonShutdown:
+		code = lnrpc.CloseReasonCode_CLOSE_REASON_SHUTDOWN
+
+	case channeldb.CloseReasonPeerError:
+		code = lnrpc.CloseReasonCode_CLOSE_REASON_PEER_ERROR
+
+	case channeldb.CloseReasonLinkFailure:
+		code = lnrpc.CloseReasonCode_CLOSE_REASON_LINK_FAILURE
+
+	case channeldb.CloseReasonForceClose:
+		code = lnrpc.CloseReasonCode_CLOSE_REASON_FORCE_CLOSE
+
+	case channeldb.CloseReasonBreach:
+		code = lnrpc.CloseReasonCode_CLOSE_REASON_BREACH
+	}
+
+	initiator := lnrpc.Initiator_INITIATOR_LOCAL
+	if reason.RemoteInitiated {
+		initiator = lnrpc.Initiator_INITIATOR_REMOTE
+	}
+
+	// The message may have been sent by the remote peer, so we make sure
+	// it's valid utf-8 before returning it.
+	return &lnrpc.ChannelCloseReason{
+		Code:      code,
+		Initiator: initiator,
+		Message:   strings.ToValidUTF8(reason.Message, "\uFFFD"),
+		Timestamp: reason.Timestamp.Unix(),
+	}
+}
+
 // createRPCClosedChannel creates an *lnrpc.ClosedChannelSummary from a
 // *channeldb.ChannelCloseSummary.
 func (r *rpcServer) createRPCClosedChannel(
@@ -3668,6 +5218,10 @@ func (r *rpcServer) createRPCClosedChannel(
 		CloseInitiator:    closeInitiator,
 	}
 
+	if dbChannel.CloseReason != nil {
+		channel.CloseReason = marshalCloseReason(dbChannel.CloseReason)
+	}
+
 	reports, err := r.server.remoteChanDB.FetchChannelReports(
 		activeNetParams.GenesisHash, &dbChannel.ChanPoint,
 	)
@@ -3912,6 +5466,22 @@ func (r *rpcServer) SubscribeChannelEvents(req *lnrpc.ChannelEventSubscription,
 					},
 				}
 
+			case channelnotifier.CloseReasonEvent:
+				update = &lnrpc.ChannelEventUpdate{
+					Type: lnrpc.ChannelEventUpdate_CLOSE_REASON,
+					Channel: &lnrpc.ChannelEventUpdate_CloseReason{
+						CloseReason: &lnrpc.ChannelCloseReasonUpdate{
+							ChannelPoint: &lnrpc.ChannelPoint{
+								FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+									FundingTxidBytes: event.ChannelPoint.Hash[:],
+								},
+								OutputIndex: event.ChannelPoint.Index,
+							},
+							Reason: marshalCloseReason(event.Reason),
+						},
+					},
+				}
+
 			// Completely ignore ActiveLinkEvent as this is explicitly not
 			// exposed to the RPC.
 			case channelnotifier.ActiveLinkEvent:
@@ -3937,6 +5507,10 @@ func (r *rpcServer) SubscribeChannelEvents(req *lnrpc.ChannelEventSubscription,
 type paymentStream struct {
 	recv func() (*rpcPaymentRequest, error)
 	send func(*lnrpc.SendResponse) error
+
+	// ctx is the context of the underlying stream, which is done once the
+	// client goes away.
+	ctx context.Context
 }
 
 // rpcPaymentRequest wraps lnrpc.SendRequest so that routes from
@@ -3970,6 +5544,7 @@ func (r *rpcServer) SendPayment(stream lnrpc.Lightning_SendPaymentServer) error
 			defer lock.Unlock()
 			return stream.Send(r)
 		},
+		ctx: stream.Context(),
 	})
 }
 
@@ -3996,6 +5571,7 @@ func (r *rpcServer) SendToRoute(stream lnrpc.Lightning_SendToRouteServer) error
 			defer lock.Unlock()
 			return stream.Send(r)
 		},
+		ctx: stream.Context(),
 	})
 }
 
@@ -4035,11 +5611,15 @@ type rpcPaymentIntent struct {
 	cltvDelta            uint16
 	routeHints           [][]zpay32.HopHint
 	outgoingChannelIDs   []uint64
+	excludedChannelIDs   []uint64
 	lastHop              *route.Vertex
 	ignoreMaxOutboundAmt bool
 	destFeatures         *lnwire.FeatureVector
 	paymentAddr          *[32]byte
 	payReq               []byte
+	maxParts             uint32
+	maxShardAmt          *lnwire.MilliAtom
+	minShardAmt          lnwire.MilliAtom
 
 	destCustomRecords record.CustomSet
 
@@ -4055,6 +5635,11 @@ func (r *rpcServer) extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPayme
 		ignoreMaxOutboundAmt: rpcPayReq.IgnoreMaxOutboundAmt,
 	}
 
+	// Refuse to originate new payments if they are blocked.
+	if r.routerBackend.PaymentsBlocked() {
+		return payIntent, routerrpc.ErrPaymentsBlocked
+	}
+
 	// If a route was specified, then we can use that directly.
 	if rpcPayReq.route != nil {
 		// If the user is using the REST interface, then they'll be
@@ -4076,14 +5661,41 @@ func (r *rpcServer) extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPayme
 		return payIntent, nil
 	}
 
-	// If there are no routes specified, pass along a outgoing channel
-	// restriction if specified. The main server rpc does not support
-	// multiple channel restrictions.
+	// If there are no routes specified, pass along the outgoing channel
+	// restrictions if specified.
+	payIntent.outgoingChannelIDs = rpcPayReq.OutgoingChanIds
 	if rpcPayReq.OutgoingChanId != 0 {
+		if payIntent.outgoingChannelIDs != nil {
+			return payIntent, errors.New("outgoing_chan_id and " +
+				"outgoing_chan_ids are mutually exclusive")
+		}
+
 		payIntent.outgoingChannelIDs = []uint64{
 			rpcPayReq.OutgoingChanId,
 		}
 	}
+	payIntent.excludedChannelIDs = rpcPayReq.ExcludeOutgoingChanIds
+
+	// Take the maximum number of parts from the request. Map zero to one
+	// for backwards compatibility.
+	payIntent.maxParts = rpcPayReq.MaxParts
+	if rpcPayReq.NoSplit {
+		if payIntent.maxParts > 1 {
+			return payIntent, errors.New("no_split and max_parts " +
+				"above one are mutually exclusive")
+		}
+		payIntent.maxParts = 1
+	}
+	if payIntent.maxParts == 0 {
+		payIntent.maxParts = 1
+	}
+
+	// Take the limits on the shard sizes from the request.
+	if rpcPayReq.MaxShardSizeMAtoms != 0 {
+		maxShardAmt := lnwire.MilliAtom(rpcPayReq.MaxShardSizeMAtoms)
+		payIntent.maxShardAmt = &maxShardAmt
+	}
+	payIntent.minShardAmt = lnwire.MilliAtom(rpcPayReq.MinShardSizeMAtoms)
 
 	// Pass along a last hop restriction if specified.
 	if len(rpcPayReq.LastHopPubkey) > 0 {
@@ -4275,9 +5887,14 @@ func (r *rpcServer) extractPaymentIntent(rpcPayReq *rpcPaymentRequest) (rpcPayme
 }
 
 type paymentIntentResponse struct {
-	Route    *route.Route
-	Preimage [32]byte
-	Err      error
+	Route      *route.Route
+	HtlcRoutes []*route.Route
+	Preimage   [32]byte
+	Err        error
+
+	// Failure is the failure of the htlc of a payment sent to a route, if
+	// the htlc failed.
+	Failure *lnrpc.Failure
 }
 
 // checkCanSendPayment verifies whether the minimum conditions for sending the
@@ -4372,8 +5989,9 @@ func (r *rpcServer) checkCanSendPayment(payIntent *rpcPaymentIntent) error {
 // We'll either pass the payment as a whole to the channel router, or give it a
 // pre-built route. The first error this method returns denotes if we were
 // unable to save the payment. The second error returned denotes if the payment
-// didn't succeed.
-func (r *rpcServer) dispatchPaymentIntent(
+// didn't succeed. Once the passed context is done, no further attempts are
+// made to send the payment.
+func (r *rpcServer) dispatchPaymentIntent(ctx context.Context,
 	payIntent *rpcPaymentIntent) (*paymentIntentResponse, error) {
 
 	// Perform a pre-flight check for sending this payment.
@@ -4383,13 +6001,29 @@ func (r *rpcServer) dispatchPaymentIntent(
 		}, nil
 	}
 
+	// If the macaroon of the request carries a spending budget, the
+	// maximum amount of the payment including fees is reserved against
+	// it until the payment is resolved.
+	maxSpend := payIntent.mat + payIntent.feeLimit
+	if payIntent.route != nil {
+		maxSpend = payIntent.route.TotalAmount
+	}
+	spend, err := r.reserveBudget(ctx, maxSpend)
+	if err != nil {
+		return &paymentIntentResponse{
+			Err: err,
+		}, nil
+	}
+
 	// Construct a payment request to send to the channel router. If the
 	// payment is successful, the route chosen will be returned. Otherwise,
 	// we'll get a non-nil error.
 	var (
-		preImage  [32]byte
-		route     *route.Route
-		routerErr error
+		preImage   [32]byte
+		htlcRoutes []*route.Route
+		route      *route.Route
+		attempt    *channeldb.HTLCAttempt
+		routerErr  error
 	)
 
 	// If a route was specified, then we'll pass the route directly to the
@@ -4410,17 +6044,16 @@ func (r *rpcServer) dispatchPaymentIntent(
 			DestCustomRecords:  payIntent.destCustomRecords,
 			DestFeatures:       payIntent.destFeatures,
 			PaymentAddr:        payIntent.paymentAddr,
-
-			// Don't enable multi-part payments on the main rpc.
-			// Users need to use routerrpc for that.
-			MaxParts: 1,
+			MaxParts:           payIntent.maxParts,
 		}
+		payment.ExcludedOutgoingChannelIDs = payIntent.excludedChannelIDs
+		payment.MaxShardAmt = payIntent.maxShardAmt
+		payment.MinShardAmt = payIntent.minShardAmt
 
 		preImage, route, routerErr = r.server.chanRouter.SendPayment(
-			payment,
+			ctx, payment,
 		)
 	} else {
-		var attempt *channeldb.HTLCAttempt
 		attempt, routerErr = r.server.chanRouter.SendToRoute(
 			payIntent.rHash, payIntent.route,
 		)
@@ -4433,18 +6066,100 @@ func (r *rpcServer) dispatchPaymentIntent(
 	}
 
 	// If the route failed, then we'll return a nil save err, but a non-nil
-	// routing err.
+	// routing err. The reserved budget is only released if nothing was
+	// sent, otherwise the maximum amount of the payment remains reserved,
+	// as the router may have returned before all of its htlcs resolved,
+	// such as on shutdown.
 	if routerErr != nil {
 		rpcsLog.Warnf("Unable to send payment: %v", routerErr)
 
-		return &paymentIntentResponse{
+		if spend != nil && !paymentMaySpend(
+			r.routerBackend.Tower.FetchPayment, payIntent.rHash,
+			routerErr,
+		) {
+
+			spend.resolve(0)
+		}
+
+		resp := &paymentIntentResponse{
 			Err: routerErr,
-		}, nil
+		}
+
+		// If the htlc sent to the route failed, we'll also return the
+		// details of its failure, such as the failing hop and the
+		// channel update it carried, so the caller can react to it.
+		if attempt != nil && attempt.Failure != nil {
+			rpcAttempt, err := r.routerBackend.MarshalHTLCAttempt(
+				*attempt,
+			)
+			if err != nil {
+				return nil, err
+			}
+			resp.Failure = rpcAttempt.Failure
+		}
+
+		return resp, nil
+	}
+
+	// A payment that may have been split into multiple parts was settled
+	// by one or more htlcs, so we'll look up the routes of all of them.
+	if payIntent.route == nil && payIntent.maxParts > 1 {
+		payment, err := r.routerBackend.Tower.FetchPayment(
+			payIntent.rHash,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, htlc := range payment.HTLCs {
+			if htlc.Settle == nil {
+				continue
+			}
+
+			htlcRoute := htlc.Route
+			htlcRoutes = append(htlcRoutes, &htlcRoute)
+		}
+	} else {
+		htlcRoutes = append(htlcRoutes, route)
 	}
 
+	var spent lnwire.MilliAtom
+	for _, htlcRoute := range htlcRoutes {
+		spent += htlcRoute.TotalAmount
+	}
+	spend.resolve(spent)
+
 	return &paymentIntentResponse{
-		Route:    route,
-		Preimage: preImage,
+		Route:      route,
+		HtlcRoutes: htlcRoutes,
+		Preimage:   preImage,
+	}, nil
+}
+
+// marshallSendResponse converts the response of a successfully dispatched
+// payment intent into its rpc representation.
+func (r *rpcServer) marshallSendResponse(payIntent *rpcPaymentIntent,
+	resp *paymentIntentResponse) (*lnrpc.SendResponse, error) {
+
+	rpcRoute, err := r.routerBackend.MarshallRoute(resp.Route)
+	if err != nil {
+		return nil, err
+	}
+
+	htlcRoutes := make([]*lnrpc.Route, 0, len(resp.HtlcRoutes))
+	for _, htlcRoute := range resp.HtlcRoutes {
+		rpcHtlcRoute, err := r.routerBackend.MarshallRoute(htlcRoute)
+		if err != nil {
+			return nil, err
+		}
+		htlcRoutes = append(htlcRoutes, rpcHtlcRoute)
+	}
+
+	return &lnrpc.SendResponse{
+		PaymentHash:     payIntent.rHash[:],
+		PaymentPreimage: resp.Preimage[:],
+		PaymentRoute:    rpcRoute,
+		HtlcRoutes:      htlcRoutes,
 	}, nil
 }
 
@@ -4595,7 +6310,7 @@ sendLoop:
 				}()
 
 				resp, saveErr := r.dispatchPaymentIntent(
-					payIntent,
+					stream.ctx, payIntent,
 				)
 
 				switch {
@@ -4617,8 +6332,9 @@ sendLoop:
 				// to the user.
 				case resp.Err != nil:
 					err := stream.send(&lnrpc.SendResponse{
-						PaymentError: resp.Err.Error(),
-						PaymentHash:  payIntent.rHash[:],
+						PaymentError:   resp.Err.Error(),
+						PaymentHash:    payIntent.rHash[:],
+						PaymentFailure: resp.Failure,
 					})
 					if err != nil {
 						rpcsLog.Errorf("Failed "+
@@ -4633,20 +6349,15 @@ sendLoop:
 					return
 				}
 
-				backend := r.routerBackend
-				marshalledRouted, err := backend.MarshallRoute(
-					resp.Route,
+				sendResp, err := r.marshallSendResponse(
+					payIntent, resp,
 				)
 				if err != nil {
 					errChan <- err
 					return
 				}
 
-				err = stream.send(&lnrpc.SendResponse{
-					PaymentHash:     payIntent.rHash[:],
-					PaymentPreimage: resp.Preimage[:],
-					PaymentRoute:    marshalledRouted,
-				})
+				err = stream.send(sendResp)
 				if err != nil {
 					rpcsLog.Errorf("Failed sending "+
 						"response: %v", err)
@@ -4718,28 +6429,20 @@ func (r *rpcServer) sendPaymentSync(ctx context.Context,
 
 	// With the payment validated, we'll now attempt to dispatch the
 	// payment.
-	resp, saveErr := r.dispatchPaymentIntent(&payIntent)
+	resp, saveErr := r.dispatchPaymentIntent(ctx, &payIntent)
 	switch {
 	case saveErr != nil:
 		return nil, saveErr
 
 	case resp.Err != nil:
 		return &lnrpc.SendResponse{
-			PaymentError: resp.Err.Error(),
-			PaymentHash:  payIntent.rHash[:],
+			PaymentError:   resp.Err.Error(),
+			PaymentHash:    payIntent.rHash[:],
+			PaymentFailure: resp.Failure,
 		}, nil
 	}
 
-	rpcRoute, err := r.routerBackend.MarshallRoute(resp.Route)
-	if err != nil {
-		return nil, err
-	}
-
-	return &lnrpc.SendResponse{
-		PaymentHash:     payIntent.rHash[:],
-		PaymentPreimage: resp.Preimage[:],
-		PaymentRoute:    rpcRoute,
-	}, nil
+	return r.marshallSendResponse(&payIntent, resp)
 }
 
 // checkCanReceiveInvoice performs a check on available inbound capacity from
@@ -4748,7 +6451,7 @@ func (r *rpcServer) sendPaymentSync(ctx context.Context,
 // It returns nil if there is enough capacity to potentially settle the invoice
 // or an error otherwise.
 func (r *rpcServer) checkCanReceiveInvoice(ctx context.Context,
-	invoice *lnrpc.Invoice) error {
+	invoice *lnrpc.Invoice, value lnwire.MilliAtom) error {
 
 	// Return early if we've been instructed to ignore the available inbound
 	// bandwidth.
@@ -4770,7 +6473,7 @@ func (r *rpcServer) checkCanReceiveInvoice(ctx context.Context,
 		return errors.New("no open channels")
 	}
 
-	amt := dcrutil.Amount(invoice.Value)
+	amt := value.ToAtoms()
 
 	// Loop through all available channels, check for liveliness and capacity.
 	var maxInbound dcrutil.Amount
@@ -4824,35 +6527,54 @@ func (r *rpcServer) checkCanReceiveInvoice(ctx context.Context,
 		missingCap)
 }
 
-// AddInvoice attempts to add a new invoice to the invoice database. Any
-// duplicated invoices are rejected, therefore all invoices *must* have a
-// unique payment preimage.
-func (r *rpcServer) AddInvoice(ctx context.Context,
-	invoice *lnrpc.Invoice) (*lnrpc.AddInvoiceResponse, error) {
-
-	if err := r.checkCanReceiveInvoice(ctx, invoice); err != nil {
-		return nil, err
-	}
-
-	defaultDelta := r.cfg.TimeLockDelta
-
-	addInvoiceCfg := &invoicesrpc.AddInvoiceConfig{
+// addInvoiceConfig returns the configuration used to add invoices on behalf
+// of RPC callers.
+func (r *rpcServer) addInvoiceConfig() *invoicesrpc.AddInvoiceConfig {
+	return &invoicesrpc.AddInvoiceConfig{
 		AddInvoice:        r.server.invoices.AddInvoice,
 		IsChannelActive:   r.server.htlcSwitch.HasActiveLink,
 		ChainParams:       activeNetParams.Params,
 		NodeSigner:        r.server.nodeSigner,
-		DefaultCLTVExpiry: defaultDelta,
+		DefaultCLTVExpiry: r.cfg.TimeLockDelta,
+		InvoiceDefaults:   newInvoiceDefaults(r.cfg.Invoices),
 		ChanDB:            r.server.remoteChanDB,
 		GenInvoiceFeatures: func() *lnwire.FeatureVector {
 			return r.server.featureMgr.Get(feature.SetInvoice)
 		},
 	}
+}
+
+// AddInvoice attempts to add a new invoice to the invoice database. Any
+// duplicated invoices are rejected, therefore all invoices *must* have a
+// unique payment preimage.
+func (r *rpcServer) AddInvoice(ctx context.Context,
+	invoice *lnrpc.Invoice) (*lnrpc.AddInvoiceResponse, error) {
 
 	value, err := lnrpc.UnmarshallAmt(invoice.Value, invoice.ValueMAtoms)
 	if err != nil {
 		return nil, err
 	}
 
+	// The value may also be given as a string, which JSON clients can use
+	// to express exact amounts.
+	if invoice.ValueStr != "" {
+		if value != 0 {
+			return nil, fmt.Errorf("value_str is mutually " +
+				"exclusive with value and value_m_atoms")
+		}
+
+		value, err = lnrpc.UnmarshallAmtStr(invoice.ValueStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.checkCanReceiveInvoice(ctx, invoice, value); err != nil {
+		return nil, err
+	}
+
+	addInvoiceCfg := r.addInvoiceConfig()
+
 	addInvoiceData := &invoicesrpc.AddInvoiceData{
 		Memo:            invoice.Memo,
 		Value:           value,
@@ -4861,14 +6583,13 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		FallbackAddr:    invoice.FallbackAddr,
 		CltvExpiry:      invoice.CltvExpiry,
 		Private:         invoice.Private,
+		NoRouteHints:    invoice.NoRouteHints,
+
+		PrivateRouteHintsOnly: invoice.PrivateRouteHintsOnly,
 	}
 
-	if invoice.RPreimage != nil {
-		preimage, err := lntypes.MakePreimage(invoice.RPreimage)
-		if err != nil {
-			return nil, err
-		}
-		addInvoiceData.Preimage = &preimage
+	if err := setInvoicePreimage(invoice, addInvoiceData); err != nil {
+		return nil, err
 	}
 
 	hash, dbInvoice, err := invoicesrpc.AddInvoice(
@@ -4878,21 +6599,120 @@ func (r *rpcServer) AddInvoice(ctx context.Context,
 		return nil, err
 	}
 
-	return &lnrpc.AddInvoiceResponse{
+	expiryTime := dbInvoice.CreationDate.Add(dbInvoice.Terms.Expiry)
+
+	resp := &lnrpc.AddInvoiceResponse{
 		AddIndex:       dbInvoice.AddIndex,
 		PaymentRequest: string(dbInvoice.PaymentRequest),
 		RHash:          hash[:],
-	}, nil
+		ValueMAtoms:    int64(dbInvoice.Terms.Value),
+		ExpiryTime:     expiryTime.Unix(),
+	}
+
+	if invoice.IncludeUris {
+		resp.LightningUri = zpay32.LightningURI(resp.PaymentRequest)
+
+		// The unified URI can only be built if the invoice has an
+		// on-chain fallback address. The invoice is already stored at
+		// this point, so failing to build the URI is only logged
+		// rather than failing the call.
+		if invoice.FallbackAddr != "" {
+			uri, err := unifiedInvoiceURI(resp.PaymentRequest)
+			if err != nil {
+				rpcsLog.Errorf("Unable to build unified URI of "+
+					"invoice %x: %v", hash[:], err)
+			}
+			resp.UnifiedUri = uri
+		}
+	}
+
+	return resp, nil
+}
+
+// unifiedInvoiceURI returns the unified URI of the given encoded payment
+// request, which must have an on-chain fallback address.
+func unifiedInvoiceURI(payReq string) (string, error) {
+	invoice, err := zpay32.Decode(payReq, activeNetParams.Params)
+	if err != nil {
+		return "", err
+	}
+
+	return zpay32.UnifiedURI(invoice, payReq)
+}
+
+// setInvoicePreimage sets the preimage of a new invoice, or its payment hash if
+// a hold invoice is requested, in which case the preimage is held by the
+// caller. The payment hash is ignored otherwise, as the invoice is identified
+// by the hash of its preimage.
+func setInvoicePreimage(invoice *lnrpc.Invoice,
+	addInvoiceData *invoicesrpc.AddInvoiceData) error {
+
+	if invoice.Hold {
+		if invoice.RPreimage != nil {
+			return fmt.Errorf("the preimage of a hold invoice " +
+				"must not be set")
+		}
+
+		hash, err := lntypes.MakeHash(invoice.RHash)
+		if err != nil {
+			return fmt.Errorf("invalid payment hash of hold "+
+				"invoice: %v", err)
+		}
+		addInvoiceData.Hash = &hash
+		addInvoiceData.HodlInvoice = true
+
+		return nil
+	}
+
+	if invoice.RPreimage != nil {
+		preimage, err := lntypes.MakePreimage(invoice.RPreimage)
+		if err != nil {
+			return err
+		}
+		addInvoiceData.Preimage = &preimage
+	}
+
+	return nil
+}
+
+// SettleHoldInvoice settles an accepted hold invoice with the given preimage.
+// If the invoice is already settled, this call will succeed.
+func (r *rpcServer) SettleHoldInvoice(ctx context.Context,
+	in *lnrpc.SettleHoldInvoiceRequest) (*lnrpc.SettleHoldInvoiceResponse,
+	error) {
+
+	err := invoicesrpc.SettleHoldInvoice(r.server.invoices, in.Preimage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.SettleHoldInvoiceResponse{}, nil
+}
+
+// CancelHoldInvoice cancels a currently open invoice, failing back any of its
+// accepted htlcs. If the invoice is already canceled, this call will succeed.
+// If the invoice is already settled, it will fail.
+func (r *rpcServer) CancelHoldInvoice(ctx context.Context,
+	in *lnrpc.CancelHoldInvoiceRequest) (*lnrpc.CancelHoldInvoiceResponse,
+	error) {
+
+	err := invoicesrpc.CancelHoldInvoice(r.server.invoices, in.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.CancelHoldInvoiceResponse{}, nil
 }
 
-// LookupInvoice attempts to look up an invoice according to its payment hash.
-// The passed payment hash *must* be exactly 32 bytes, if not an error is
-// returned.
+// LookupInvoice attempts to look up an invoice according to its payment hash,
+// payment address or add index. The passed payment hash and payment address
+// *must* be exactly 32 bytes, if not an error is returned.
 func (r *rpcServer) LookupInvoice(ctx context.Context,
 	req *lnrpc.PaymentHash) (*lnrpc.Invoice, error) {
 
 	var (
 		payHash [32]byte
+		payAddr [32]byte
 		rHash   []byte
 		err     error
 	)
@@ -4915,15 +6735,44 @@ func (r *rpcServer) LookupInvoice(ctx context.Context,
 	}
 	copy(payHash[:], rHash)
 
-	rpcsLog.Tracef("[lookupinvoice] searching for invoice %x", payHash[:])
-
-	invoice, err := r.server.invoices.LookupInvoice(payHash)
-	if err != nil {
-		return nil, err
+	// Likewise, the payment address must be *exactly* 32-bytes if set.
+	if len(req.PaymentAddr) != 0 && len(req.PaymentAddr) != 32 {
+		return nil, fmt.Errorf("payment addr must be exactly "+
+			"32 bytes, is instead %v", len(req.PaymentAddr))
 	}
+	copy(payAddr[:], req.PaymentAddr)
 
-	rpcsLog.Tracef("[lookupinvoice] located invoice %v",
-		newLogClosure(func() string {
+	// Map the provided identifiers into an invoice reference. The add
+	// index uniquely identifies an invoice, so it can't be combined with
+	// the other identifiers.
+	var ref channeldb.InvoiceRef
+	switch {
+	case req.AddIndex != 0 && (len(rHash) != 0 || len(req.PaymentAddr) != 0):
+		return nil, fmt.Errorf("add index cannot be combined with a " +
+			"payment hash or payment addr")
+
+	case req.AddIndex != 0:
+		ref = channeldb.InvoiceRefByAddIndex(req.AddIndex)
+
+	case len(rHash) != 0 && len(req.PaymentAddr) != 0:
+		ref = channeldb.InvoiceRefByHashAndAddr(payHash, payAddr)
+
+	case len(req.PaymentAddr) != 0:
+		ref = channeldb.InvoiceRefByAddr(payAddr)
+
+	default:
+		ref = channeldb.InvoiceRefByHash(payHash)
+	}
+
+	rpcsLog.Tracef("[lookupinvoice] searching for invoice %v", ref)
+
+	invoice, err := r.server.invoices.LookupInvoiceByRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Tracef("[lookupinvoice] located invoice %v",
+		newLogClosure(func() string {
 			return spew.Sdump(invoice)
 		}))
 
@@ -5040,6 +6889,11 @@ func (r *rpcServer) SubscribeInvoices(req *lnrpc.InvoiceSubscription,
 func (r *rpcServer) SubscribeTransactions(req *lnrpc.GetTransactionsRequest,
 	updateStream lnrpc.Lightning_SubscribeTransactionsServer) error {
 
+	filter, err := newTxFilter(req, activeNetParams.Params)
+	if err != nil {
+		return err
+	}
+
 	txClient, err := r.server.cc.wallet.SubscribeTransactions()
 	if err != nil {
 		return err
@@ -5049,6 +6903,10 @@ func (r *rpcServer) SubscribeTransactions(req *lnrpc.GetTransactionsRequest,
 	for {
 		select {
 		case tx := <-txClient.ConfirmedTransactions():
+			if !filter.match(tx) {
+				continue
+			}
+
 			destAddresses := make([]string, 0, len(tx.DestAddresses))
 			for _, destAddress := range tx.DestAddresses {
 				destAddresses = append(destAddresses, destAddress.Address())
@@ -5063,12 +6921,19 @@ func (r *rpcServer) SubscribeTransactions(req *lnrpc.GetTransactionsRequest,
 				TotalFees:        tx.TotalFees,
 				DestAddresses:    destAddresses,
 				RawTxHex:         hex.EncodeToString(tx.RawTx),
+				Label:            tx.Label,
 			}
+			tagNewChanTx(r.chanTxSource(), detail, tx.RawTx)
+			tagStakeTx(detail)
 			if err := updateStream.Send(detail); err != nil {
 				return err
 			}
 
 		case tx := <-txClient.UnconfirmedTransactions():
+			if !filter.match(tx) {
+				continue
+			}
+
 			var destAddresses []string
 			for _, destAddress := range tx.DestAddresses {
 				destAddresses = append(destAddresses, destAddress.Address())
@@ -5080,7 +6945,10 @@ func (r *rpcServer) SubscribeTransactions(req *lnrpc.GetTransactionsRequest,
 				TotalFees:     tx.TotalFees,
 				DestAddresses: destAddresses,
 				RawTxHex:      hex.EncodeToString(tx.RawTx),
+				Label:         tx.Label,
 			}
+			tagNewChanTx(r.chanTxSource(), detail, tx.RawTx)
+			tagStakeTx(detail)
 			if err := updateStream.Send(detail); err != nil {
 				return err
 			}
@@ -5096,6 +6964,11 @@ func (r *rpcServer) SubscribeTransactions(req *lnrpc.GetTransactionsRequest,
 func (r *rpcServer) GetTransactions(ctx context.Context,
 	req *lnrpc.GetTransactionsRequest) (*lnrpc.TransactionDetails, error) {
 
+	filter, err := newTxFilter(req, activeNetParams.Params)
+	if err != nil {
+		return nil, err
+	}
+
 	// To remain backwards compatible with the old api, default to the
 	// special case end height which will return transactions from the start
 	// height until the chain tip, including unconfirmed transactions.
@@ -5113,7 +6986,26 @@ func (r *rpcServer) GetTransactions(ctx context.Context,
 		return nil, err
 	}
 
-	return lnrpc.RPCTransactionDetails(transactions), nil
+	chanTxs, err := fetchChanTxs(r.chanTxSource())
+	if err != nil {
+		return nil, err
+	}
+	// The channel script index only annotates deposits, so failing to
+	// fetch it shouldn't prevent the transactions from being returned.
+	chanScripts, err := r.server.chanScripts.fetch()
+	if err != nil {
+		rpcsLog.Errorf("Unable to fetch channel scripts: %v", err)
+		chanScripts = nil
+	}
+
+	txDetails := lnrpc.RPCTransactionDetails(filter.filterTxs(transactions))
+	for _, tx := range txDetails.Transactions {
+		tagChanTx(tx, chanTxs)
+		tagStakeTx(tx)
+		tagDepositTx(tx, chanScripts)
+	}
+
+	return txDetails, nil
 }
 
 // DescribeGraph returns a description of the latest graph state from the PoV
@@ -5137,6 +7029,12 @@ func (r *rpcServer) DescribeGraph(ctx context.Context,
 	// within the graph), collating their current state into the RPC
 	// response.
 	err := graph.ForEachNode(func(_ kvdb.RTx, node *channeldb.LightningNode) error {
+		// Stop traversing the graph if the client went away, so we
+		// don't hold the database transaction open for nothing.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		nodeAddrs := make([]*lnrpc.NodeAddress, 0)
 		for _, addr := range node.Addresses {
 			nodeAddr := &lnrpc.NodeAddress{
@@ -5169,6 +7067,10 @@ func (r *rpcServer) DescribeGraph(ctx context.Context,
 	err = graph.ForEachChannel(func(edgeInfo *channeldb.ChannelEdgeInfo,
 		c1, c2 *channeldb.ChannelEdgePolicy) error {
 
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Do not include unannounced channels unless specifically
 		// requested. Unannounced channels include both private channels as
 		// well as public channels whose authentication proof were not
@@ -5218,32 +7120,46 @@ func marshalDbEdge(edgeInfo *channeldb.ChannelEdgeInfo,
 	}
 
 	if c1 != nil {
-		edge.Node1Policy = &lnrpc.RoutingPolicy{
-			TimeLockDelta:      uint32(c1.TimeLockDelta),
-			MinHtlc:            int64(c1.MinHTLC),
-			MaxHtlcMAtoms:      uint64(c1.MaxHTLC),
-			FeeBaseMAtoms:      int64(c1.FeeBaseMAtoms),
-			FeeRateMilliMAtoms: int64(c1.FeeProportionalMillionths),
-			Disabled:           c1.ChannelFlags&lnwire.ChanUpdateDisabled != 0,
-			LastUpdate:         uint32(c1.LastUpdate.Unix()),
-		}
+		edge.Node1Policy = marshalDbRoutingPolicy(c1)
 	}
 
 	if c2 != nil {
-		edge.Node2Policy = &lnrpc.RoutingPolicy{
-			TimeLockDelta:      uint32(c2.TimeLockDelta),
-			MinHtlc:            int64(c2.MinHTLC),
-			MaxHtlcMAtoms:      uint64(c2.MaxHTLC),
-			FeeBaseMAtoms:      int64(c2.FeeBaseMAtoms),
-			FeeRateMilliMAtoms: int64(c2.FeeProportionalMillionths),
-			Disabled:           c2.ChannelFlags&lnwire.ChanUpdateDisabled != 0,
-			LastUpdate:         uint32(c2.LastUpdate.Unix()),
-		}
+		edge.Node2Policy = marshalDbRoutingPolicy(c2)
 	}
 
 	return edge
 }
 
+// marshalDbRoutingPolicy converts a channel edge policy to its RPC form.
+func marshalDbRoutingPolicy(
+	policy *channeldb.ChannelEdgePolicy) *lnrpc.RoutingPolicy {
+
+	inboundFee := edgeInboundFee(policy)
+
+	return &lnrpc.RoutingPolicy{
+		TimeLockDelta:        uint32(policy.TimeLockDelta),
+		MinHtlc:              int64(policy.MinHTLC),
+		MaxHtlcMAtoms:        uint64(policy.MaxHTLC),
+		FeeBaseMAtoms:        int64(policy.FeeBaseMAtoms),
+		FeeRateMilliMAtoms:   int64(policy.FeeProportionalMillionths),
+		Disabled:             policy.ChannelFlags&lnwire.ChanUpdateDisabled != 0,
+		LastUpdate:           uint32(policy.LastUpdate.Unix()),
+		InboundBaseFeeMAtoms: inboundFee.BaseFee,
+		InboundFeeRatePpm:    inboundFee.FeeRate,
+	}
+}
+
+// edgeInboundFee returns the inbound fee of a channel edge policy. Policies
+// whose extra data can't be parsed are reported without an inbound fee.
+func edgeInboundFee(policy *channeldb.ChannelEdgePolicy) lnwire.InboundFee {
+	inboundFee, err := lnwire.ExtractInboundFee(policy.ExtraOpaqueData)
+	if err != nil || inboundFee == nil {
+		return lnwire.InboundFee{}
+	}
+
+	return *inboundFee
+}
+
 // GetNodeMetrics returns all available node metrics calculated from the
 // current channel graph.
 func (r *rpcServer) GetNodeMetrics(ctx context.Context,
@@ -5323,6 +7239,126 @@ func (r *rpcServer) GetChanInfo(ctx context.Context,
 	return channelEdge, nil
 }
 
+// GetChanPolicyHistory returns the recorded routing policy changes of both
+// directions of the given channel.
+func (r *rpcServer) GetChanPolicyHistory(ctx context.Context,
+	in *lnrpc.ChanPolicyHistoryRequest) (*lnrpc.ChanPolicyHistoryResponse,
+	error) {
+
+	graph := r.server.localChanDB.ChannelGraph()
+
+	edgeInfo, _, _, err := graph.FetchChannelEdgesByID(in.ChanId)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := graph.FetchChanPolicyHistory(in.ChanId)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &lnrpc.ChanPolicyHistoryResponse{
+		Node1Pub: hex.EncodeToString(edgeInfo.NodeKey1Bytes[:]),
+		Node2Pub: hex.EncodeToString(edgeInfo.NodeKey2Bytes[:]),
+	}
+	for _, entry := range entries {
+		policy := &lnrpc.RoutingPolicy{
+			TimeLockDelta:      uint32(entry.TimeLockDelta),
+			MinHtlc:            int64(entry.MinHTLC),
+			MaxHtlcMAtoms:      uint64(entry.MaxHTLC),
+			FeeBaseMAtoms:      int64(entry.FeeBaseMAtoms),
+			FeeRateMilliMAtoms: int64(entry.FeeProportionalMillionths),
+			Disabled:           entry.Disabled,
+			LastUpdate:         uint32(entry.LastUpdate.Unix()),
+		}
+		if entry.Direction {
+			resp.Node2Policies = append(resp.Node2Policies, policy)
+		} else {
+			resp.Node1Policies = append(resp.Node1Policies, policy)
+		}
+	}
+
+	return resp, nil
+}
+
+// nodeChannelsPage gathers the statistics of the channels of a node and the
+// page of its channels requested by a GetNodeInfo call as they are iterated.
+type nodeChannelsPage struct {
+	req      *lnrpc.NodeInfoRequest
+	selfNode route.Vertex
+
+	numChannels         uint32
+	totalCapacity       dcrutil.Amount
+	announcedCapacity   dcrutil.Amount
+	unannouncedCapacity dcrutil.Amount
+
+	// numMatched is the number of channels matching the request so far,
+	// including those before the requested offset.
+	numMatched uint32
+
+	// nextOffset is the offset of the next page of channels, or zero if
+	// all matching channels fit in this page.
+	nextOffset uint32
+
+	channels []*lnrpc.ChannelEdge
+}
+
+// addChannel accounts for a channel of the node, adding it to the page if it
+// matches the request.
+func (p *nodeChannelsPage) addChannel(edge *channeldb.ChannelEdgeInfo,
+	c1, c2 *channeldb.ChannelEdgePolicy) {
+
+	p.numChannels++
+	p.totalCapacity += edge.Capacity
+	if edge.AuthProof != nil {
+		p.announcedCapacity += edge.Capacity
+	} else {
+		p.unannouncedCapacity += edge.Capacity
+	}
+
+	// Only populate the node's channels if the user requested them.
+	switch {
+	// When only our shared channels are requested, we include unannounced
+	// ones as well, as we know their policies.
+	case p.req.SharedChannelsOnly:
+		if edge.NodeKey1Bytes != p.selfNode &&
+			edge.NodeKey2Bytes != p.selfNode {
+
+			return
+		}
+
+	// Do not include unannounced channels - private channels or public
+	// channels whose authentication proof were not confirmed yet.
+	case p.req.IncludeChannels:
+		if edge.AuthProof == nil {
+			return
+		}
+
+	default:
+		return
+	}
+
+	// Skip the channels before the requested offset, and stop adding
+	// channels once the page is full, remembering where the next page
+	// starts.
+	p.numMatched++
+	switch {
+	case p.numMatched <= p.req.ChannelsOffset:
+		return
+
+	case p.req.MaxChannels > 0 &&
+		uint32(len(p.channels)) >= p.req.MaxChannels:
+
+		if p.nextOffset == 0 {
+			p.nextOffset = p.numMatched - 1
+		}
+		return
+	}
+
+	// Convert the database's edge format into the network/RPC edge format.
+	p.channels = append(p.channels, marshalDbEdge(edge, c1, c2))
+}
+
 // GetNodeInfo returns the latest advertised and aggregate authenticated
 // channel information for the specified node identified by its public key.
 func (r *rpcServer) GetNodeInfo(ctx context.Context,
@@ -5347,34 +7383,15 @@ func (r *rpcServer) GetNodeInfo(ctx context.Context,
 
 	// With the node obtained, we'll now iterate through all its out going
 	// edges to gather some basic statistics about its out going channels.
-	var (
-		numChannels   uint32
-		totalCapacity dcrutil.Amount
-		channels      []*lnrpc.ChannelEdge
-	)
-
+	page := &nodeChannelsPage{
+		req:      in,
+		selfNode: route.NewVertex(r.server.identityECDH.PubKey()),
+	}
 	if err := node.ForEachChannel(nil, func(_ kvdb.RTx,
 		edge *channeldb.ChannelEdgeInfo,
 		c1, c2 *channeldb.ChannelEdgePolicy) error {
 
-		numChannels++
-		totalCapacity += edge.Capacity
-
-		// Only populate the node's channels if the user requested them.
-		if in.IncludeChannels {
-			// Do not include unannounced channels - private
-			// channels or public channels whose authentication
-			// proof were not confirmed yet.
-			if edge.AuthProof == nil {
-				return nil
-			}
-
-			// Convert the database's edge format into the
-			// network/RPC edge format.
-			channelEdge := marshalDbEdge(edge, c1, c2)
-			channels = append(channels, channelEdge)
-		}
-
+		page.addChannel(edge, c1, c2)
 		return nil
 	}); err != nil {
 		return nil, err
@@ -5400,9 +7417,12 @@ func (r *rpcServer) GetNodeInfo(ctx context.Context,
 			Color:      routing.EncodeHexColor(node.Color),
 			Features:   features,
 		},
-		NumChannels:   numChannels,
-		TotalCapacity: int64(totalCapacity),
-		Channels:      channels,
+		NumChannels:         page.numChannels,
+		TotalCapacity:       int64(page.totalCapacity),
+		Channels:            page.channels,
+		AnnouncedCapacity:   int64(page.announcedCapacity),
+		UnannouncedCapacity: int64(page.unannouncedCapacity),
+		NextChannelsOffset:  page.nextOffset,
 	}, nil
 }
 
@@ -5452,6 +7472,11 @@ func (r *rpcServer) GetNetworkInfo(ctx context.Context,
 	// each node so we can measure the graph diameter and degree stats
 	// below.
 	if err := graph.ForEachNode(func(tx kvdb.RTx, node *channeldb.LightningNode) error {
+		// Stop traversing the graph if the client went away.
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
 		// Increment the total number of nodes with each iteration.
 		numNodes++
 
@@ -5553,14 +7578,91 @@ func (r *rpcServer) GetNetworkInfo(ctx context.Context,
 }
 
 // StopDaemon will send a shutdown request to the interrupt handler, triggering
-// a graceful shutdown of the daemon.
+// a graceful shutdown of the daemon. If a drain is requested, the shutdown is
+// delayed until the node's in-flight htlcs and pending sweeps have settled.
 func (r *rpcServer) StopDaemon(ctx context.Context,
-	_ *lnrpc.StopRequest) (*lnrpc.StopResponse, error) {
+	req *lnrpc.StopRequest) (*lnrpc.StopResponse, error) {
+
+	if req.Drain {
+		timeout := defaultDrainTimeout
+		if req.DrainTimeout != 0 {
+			timeout = time.Duration(req.DrainTimeout) * time.Second
+		}
+
+		if err := r.drainAndShutdown(timeout); err != nil {
+			return nil, err
+		}
+
+		return &lnrpc.StopResponse{}, nil
+	}
 
 	signal.RequestShutdown()
 	return &lnrpc.StopResponse{}, nil
 }
 
+// marshalShutdownProgress converts the status of the shutdown steps into its
+// RPC representation.
+func marshalShutdownProgress(steps []shutdownStepStatus,
+	complete bool) *lnrpc.ShutdownProgress {
+
+	progress := &lnrpc.ShutdownProgress{
+		ShuttingDown: steps != nil,
+		Complete:     complete,
+		Steps:        make([]*lnrpc.ShutdownStep, 0, len(steps)),
+	}
+	for _, step := range steps {
+		var state lnrpc.ShutdownStepState
+		switch step.state {
+		case shutdownStepPending:
+			state = lnrpc.ShutdownStepState_SHUTDOWN_PENDING
+		case shutdownStepStopping:
+			state = lnrpc.ShutdownStepState_SHUTDOWN_STOPPING
+		case shutdownStepStopped:
+			state = lnrpc.ShutdownStepState_SHUTDOWN_STOPPED
+		case shutdownStepFailed:
+			state = lnrpc.ShutdownStepState_SHUTDOWN_FAILED
+		case shutdownStepTimedOut:
+			state = lnrpc.ShutdownStepState_SHUTDOWN_TIMED_OUT
+		}
+
+		progress.Steps = append(progress.Steps, &lnrpc.ShutdownStep{
+			Subsystem: step.name,
+			State:     state,
+			ElapsedMs: step.elapsed.Milliseconds(),
+		})
+	}
+
+	return progress
+}
+
+// SubscribeShutdownProgress returns a stream reporting the progress of the
+// daemon's shutdown. The current progress is sent right away, then again on
+// each update, until the shutdown of the server's subsystems completes.
+func (r *rpcServer) SubscribeShutdownProgress(
+	req *lnrpc.ShutdownProgressRequest,
+	stream lnrpc.Lightning_SubscribeShutdownProgressServer) error {
+
+	for {
+		steps, complete, updated := r.server.shutdownProgress.snapshot()
+		err := stream.Send(marshalShutdownProgress(steps, complete))
+		if err != nil {
+			return err
+		}
+
+		if complete {
+			return nil
+		}
+
+		select {
+		case <-updated:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-r.quit:
+			return nil
+		}
+	}
+}
+
 // SubscribeChannelGraph launches a streaming RPC that allows the caller to
 // receive notifications upon any changes the channel graph topology from the
 // review of the responding node. Events notified include: new nodes coming
@@ -5700,6 +7802,26 @@ func (r *rpcServer) ListPayments(ctx context.Context,
 		IncludeIncomplete: req.IncludeIncomplete,
 	}
 
+	if req.CreationDateStart != 0 {
+		query.CreationDateStart = time.Unix(
+			int64(req.CreationDateStart), 0,
+		)
+	}
+	if req.CreationDateEnd != 0 {
+		// The end date is inclusive, so we'll include all payments
+		// created within its second.
+		query.CreationDateEnd = time.Unix(
+			int64(req.CreationDateEnd), int64(time.Second)-1,
+		)
+	}
+	if req.CreationDateStart != 0 && req.CreationDateEnd != 0 &&
+		req.CreationDateStart > req.CreationDateEnd {
+
+		return nil, fmt.Errorf("creation date start %v is after "+
+			"creation date end %v", req.CreationDateStart,
+			req.CreationDateEnd)
+	}
+
 	// If the maximum number of payments wasn't specified, then we'll
 	// default to return the maximal number of payments representable.
 	if req.MaxPayments == 0 {
@@ -5732,13 +7854,60 @@ func (r *rpcServer) ListPayments(ctx context.Context,
 	return paymentsResp, nil
 }
 
+// TrackPayment returns a stream of state updates of a payment, taken from the
+// control tower. The first update is the current state of the payment and the
+// stream is closed once the payment completes.
+func (r *rpcServer) TrackPayment(req *lnrpc.TrackPaymentRequest,
+	updateStream lnrpc.Lightning_TrackPaymentServer) error {
+
+	paymentHash, err := lntypes.MakeHash(req.PaymentHash)
+	if err != nil {
+		return err
+	}
+
+	rpcsLog.Debugf("[TrackPayment] payment_hash=%v", paymentHash)
+
+	return r.routerBackend.TrackPayment(
+		updateStream.Context(), paymentHash, req.NoInflightUpdates,
+		updateStream.Send, r.quit, ErrServerShuttingDown,
+	)
+}
+
+// DeletePayment deletes an outgoing payment from DB.
+func (r *rpcServer) DeletePayment(ctx context.Context,
+	req *lnrpc.DeletePaymentRequest) (*lnrpc.DeletePaymentResponse, error) {
+
+	hash, err := lntypes.MakeHash(req.PaymentHash)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Debugf("[DeletePayment] payment_hash=%v", hash)
+
+	err = r.server.remoteChanDB.DeletePayment(hash)
+	switch {
+	case err == channeldb.ErrPaymentNotInitiated:
+		return nil, status.Error(codes.NotFound, err.Error())
+
+	case err == channeldb.ErrPaymentInFlight:
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+
+	case err != nil:
+		return nil, err
+	}
+
+	return &lnrpc.DeletePaymentResponse{}, nil
+}
+
 // DeleteAllPayments deletes all outgoing payments from DB.
 func (r *rpcServer) DeleteAllPayments(ctx context.Context,
-	_ *lnrpc.DeleteAllPaymentsRequest) (*lnrpc.DeleteAllPaymentsResponse, error) {
+	req *lnrpc.DeleteAllPaymentsRequest) (*lnrpc.DeleteAllPaymentsResponse, error) {
 
-	rpcsLog.Debugf("[DeleteAllPayments]")
+	rpcsLog.Debugf("[DeleteAllPayments] failed_payments_only=%v",
+		req.FailedPaymentsOnly)
 
-	if err := r.server.remoteChanDB.DeletePayments(); err != nil {
+	err := r.server.remoteChanDB.DeletePayments(req.FailedPaymentsOnly)
+	if err != nil {
 		return nil, err
 	}
 
@@ -5753,20 +7922,77 @@ func (r *rpcServer) DebugLevel(ctx context.Context,
 	req *lnrpc.DebugLevelRequest) (*lnrpc.DebugLevelResponse, error) {
 
 	// If show is set, then we simply print out the list of available
-	// sub-systems.
+	// sub-systems and the active overrides of channels and peers.
 	if req.Show {
+		tagLevels := make(map[string]string)
+		for tag, level := range build.TagLogLevels() {
+			tagLevels[tag] = level.String()
+		}
+
 		return &lnrpc.DebugLevelResponse{
 			SubSystems: strings.Join(
 				r.cfg.LogWriter.SupportedSubsystems(), " ",
 			),
+			TagLevels: tagLevels,
 		}, nil
 	}
 
-	rpcsLog.Infof("[debuglevel] changing debug level to: %v", req.LevelSpec)
+	revertAfter := time.Duration(req.RevertAfterMinutes) * time.Minute
+
+	// If a channel or peer is targeted, we'll only override the log level
+	// of the loggers tagged with it.
+	var tags []string
+	if req.ChanPoint != "" {
+		chanPoint, err := parseOutPoint(req.ChanPoint)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, build.ChannelLogTag(chanPoint))
+	}
+	if len(req.Peer) != 0 {
+		peerKey, err := secp256k1.ParsePubKey(req.Peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid peer pubkey: %v", err)
+		}
+		tags = append(tags, build.PeerLogTag(
+			peerKey.SerializeCompressed(),
+		))
+	}
+
+	if len(tags) != 0 {
+		if req.LevelSpec == "" {
+			rpcsLog.Infof("[debuglevel] removing debug level "+
+				"override of %v", tags)
+
+			for _, tag := range tags {
+				r.logOverrides.clearTagLevel(tag)
+			}
+
+			return &lnrpc.DebugLevelResponse{}, nil
+		}
+
+		level, ok := slog.LevelFromString(req.LevelSpec)
+		if !ok {
+			return nil, fmt.Errorf("the specified debug level [%v] "+
+				"is invalid", req.LevelSpec)
+		}
+
+		rpcsLog.Infof("[debuglevel] changing debug level of %v to: "+
+			"%v (revert after %v)", tags, req.LevelSpec, revertAfter)
+
+		for _, tag := range tags {
+			r.logOverrides.setTagLevel(tag, level, revertAfter)
+		}
+
+		return &lnrpc.DebugLevelResponse{}, nil
+	}
+
+	rpcsLog.Infof("[debuglevel] changing debug level to: %v (revert "+
+		"after %v)", req.LevelSpec, revertAfter)
 
 	// Otherwise, we'll attempt to set the logging level using the
 	// specified level spec.
-	err := build.ParseAndSetDebugLevels(req.LevelSpec, r.cfg.LogWriter)
+	err := r.logOverrides.setLevels(req.LevelSpec, revertAfter)
 	if err != nil {
 		return nil, err
 	}
@@ -5774,59 +8000,194 @@ func (r *rpcServer) DebugLevel(ctx context.Context,
 	return &lnrpc.DebugLevelResponse{}, nil
 }
 
-// DecodePayReq takes an encoded payment request string and attempts to decode
-// it, returning a full description of the conditions encoded within the
-// payment request.
-func (r *rpcServer) DecodePayReq(ctx context.Context,
-	req *lnrpc.PayReqString) (*lnrpc.PayReq, error) {
-
-	rpcsLog.Tracef("[decodepayreq] decoding: %v", req.PayReq)
+// GetCrashReport returns the report recorded on the last panic.
+func (r *rpcServer) GetCrashReport(ctx context.Context,
+	_ *lnrpc.CrashReportRequest) (*lnrpc.CrashReport, error) {
 
-	// Fist we'll attempt to decode the payment request string, if the
-	// request is invalid or the checksum doesn't match, then we'll exit
-	// here with an error.
-	payReq, err := zpay32.Decode(req.PayReq, activeNetParams.Params)
-	if err != nil {
+	report, err := crashreport.LastReport()
+	switch {
+	case err == crashreport.ErrNoReport:
+		return nil, status.Error(codes.NotFound, err.Error())
+	case err != nil:
 		return nil, err
 	}
 
-	// Let the fields default to empty strings.
-	desc := ""
-	if payReq.Description != nil {
-		desc = *payReq.Description
+	return &lnrpc.CrashReport{
+		Timestamp:    report.Timestamp.Unix(),
+		Subsystem:    report.Subsystem,
+		PanicMessage: report.Panic,
+		Stack:        report.Stack,
+		RecentEvents: report.RecentEvents,
+	}, nil
+}
+
+// parseOutPoint parses an outpoint of the form txid:output_index.
+func parseOutPoint(s string) (*wire.OutPoint, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid outpoint %q, expected "+
+			"txid:output_index", s)
 	}
 
-	descHash := []byte("")
-	if payReq.DescriptionHash != nil {
-		descHash = payReq.DescriptionHash[:]
+	hash, err := chainhash.NewHashFromStr(parts[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid txid in outpoint %q: %v", s,
+			err)
 	}
 
-	fallbackAddr := ""
-	if payReq.FallbackAddr != nil {
-		fallbackAddr = payReq.FallbackAddr.String()
+	index, err := strconv.ParseUint(parts[1], 10, 32)
+	if err != nil {
+		return nil, fmt.Errorf("invalid output index in outpoint %q: "+
+			"%v", s, err)
 	}
 
-	// Expiry time will default to 3600 seconds if not specified
-	// explicitly.
-	expiry := int64(payReq.Expiry().Seconds())
+	return wire.NewOutPoint(hash, uint32(index), wire.TxTreeRegular), nil
+}
 
-	// Convert between the `lnrpc` and `routing` types.
-	routeHints := invoicesrpc.CreateRPCRouteHints(payReq.RouteHints)
+// killSwitches returns the current state of the node-wide kill switches.
+func (r *rpcServer) killSwitches() *lnrpc.KillSwitches {
+	return &lnrpc.KillSwitches{
+		PaymentsBlocked: r.routerBackend.PaymentsBlocked(),
+		InvoicesBlocked: r.server.invoices.InvoicesBlocked(),
+		ForwardsBlocked: r.server.htlcSwitch.ForwardsBlocked(),
+	}
+}
 
-	var amtAtoms, amtMAtoms int64
-	if payReq.MilliAt != nil {
-		amtAtoms = int64(payReq.MilliAt.ToAtoms())
-		amtMAtoms = int64(*payReq.MilliAt)
+// GetKillSwitches returns the state of the node-wide switches that refuse new
+// payments, invoices and forwards.
+func (r *rpcServer) GetKillSwitches(ctx context.Context,
+	_ *lnrpc.GetKillSwitchesRequest) (*lnrpc.KillSwitches, error) {
+
+	return r.killSwitches(), nil
+}
+
+// UpdateKillSwitches blocks or allows new payments, invoices and forwards
+// node-wide. Switches that aren't specified in the request keep their state.
+func (r *rpcServer) UpdateKillSwitches(ctx context.Context,
+	req *lnrpc.UpdateKillSwitchesRequest) (*lnrpc.KillSwitches, error) {
+
+	// Validate all actions before applying any of them, so that an
+	// invalid request leaves the switches untouched.
+	actions := []lnrpc.KillSwitchAction{
+		req.Payments, req.Invoices, req.Forwards,
 	}
+	for _, action := range actions {
+		switch action {
+		case lnrpc.KillSwitchAction_KILL_SWITCH_UNCHANGED,
+			lnrpc.KillSwitchAction_KILL_SWITCH_BLOCK,
+			lnrpc.KillSwitchAction_KILL_SWITCH_ALLOW:
 
-	// Extract the payment address from the payment request, if present.
-	var paymentAddr []byte
-	if payReq.PaymentAddr != nil {
-		paymentAddr = payReq.PaymentAddr[:]
+		default:
+			return nil, fmt.Errorf("unknown kill switch action: "+
+				"%v", action)
+		}
 	}
 
-	dest := payReq.Destination.SerializeCompressed()
-	return &lnrpc.PayReq{
+	update := func(name string, action lnrpc.KillSwitchAction,
+		set func(bool)) {
+
+		if action == lnrpc.KillSwitchAction_KILL_SWITCH_UNCHANGED {
+			return
+		}
+
+		blocked := action == lnrpc.KillSwitchAction_KILL_SWITCH_BLOCK
+		rpcsLog.Infof("[updatekillswitches] %v blocked=%v", name,
+			blocked)
+		set(blocked)
+	}
+	update("payments", req.Payments, r.routerBackend.SetPaymentsBlocked)
+	update("invoices", req.Invoices, r.server.invoices.SetInvoicesBlocked)
+	update("forwards", req.Forwards, r.server.htlcSwitch.SetForwardsBlocked)
+
+	return r.killSwitches(), nil
+}
+
+// LockWallet pauses the operations spending on-chain funds, then locks the
+// wallet once the channel funding flows in progress have completed.
+func (r *rpcServer) LockWallet(ctx context.Context,
+	_ *lnrpc.LockWalletRequest) (*lnrpc.LockWalletResponse, error) {
+
+	if r.walletLock == nil {
+		return nil, errors.New("the wallet can't be locked while the " +
+			"node is running")
+	}
+
+	r.walletLock.Lock()
+
+	return &lnrpc.LockWalletResponse{}, nil
+}
+
+// UnlockRunningWallet unlocks the wallet after it was locked while the node is
+// running, and resumes the operations spending on-chain funds.
+func (r *rpcServer) UnlockRunningWallet(ctx context.Context,
+	req *lnrpc.UnlockRunningWalletRequest) (
+	*lnrpc.UnlockRunningWalletResponse, error) {
+
+	if r.walletLock == nil {
+		return nil, errors.New("the wallet can't be locked while the " +
+			"node is running")
+	}
+
+	if err := r.walletLock.Unlock(req.WalletPassword); err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.UnlockRunningWalletResponse{}, nil
+}
+
+// DecodePayReq takes an encoded payment request string and attempts to decode
+// it, returning a full description of the conditions encoded within the
+// payment request.
+func (r *rpcServer) DecodePayReq(ctx context.Context,
+	req *lnrpc.PayReqString) (*lnrpc.PayReq, error) {
+
+	rpcsLog.Tracef("[decodepayreq] decoding: %v", req.PayReq)
+
+	// Fist we'll attempt to decode the payment request string, if the
+	// request is invalid or the checksum doesn't match, then we'll exit
+	// here with an error.
+	payReq, err := zpay32.Decode(req.PayReq, activeNetParams.Params)
+	if err != nil {
+		return nil, err
+	}
+
+	// Let the fields default to empty strings.
+	desc := ""
+	if payReq.Description != nil {
+		desc = *payReq.Description
+	}
+
+	descHash := []byte("")
+	if payReq.DescriptionHash != nil {
+		descHash = payReq.DescriptionHash[:]
+	}
+
+	fallbackAddr := ""
+	if payReq.FallbackAddr != nil {
+		fallbackAddr = payReq.FallbackAddr.String()
+	}
+
+	// Expiry time will default to 3600 seconds if not specified
+	// explicitly.
+	expiry := int64(payReq.Expiry().Seconds())
+
+	// Convert between the `lnrpc` and `routing` types.
+	routeHints := invoicesrpc.CreateRPCRouteHints(payReq.RouteHints)
+
+	var amtAtoms, amtMAtoms int64
+	if payReq.MilliAt != nil {
+		amtAtoms = int64(payReq.MilliAt.ToAtoms())
+		amtMAtoms = int64(*payReq.MilliAt)
+	}
+
+	// Extract the payment address from the payment request, if present.
+	var paymentAddr []byte
+	if payReq.PaymentAddr != nil {
+		paymentAddr = payReq.PaymentAddr[:]
+	}
+
+	dest := payReq.Destination.SerializeCompressed()
+	return &lnrpc.PayReq{
 		Destination:     hex.EncodeToString(dest),
 		PaymentHash:     hex.EncodeToString(payReq.PaymentHash[:]),
 		NumAtoms:        amtAtoms,
@@ -5882,13 +8243,17 @@ func (r *rpcServer) FeeReport(ctx context.Context,
 		feeRateFixedPoint := edgePolicy.FeeProportionalMillionths
 		feeRate := float64(feeRateFixedPoint) / float64(feeBase)
 
+		inboundFee := edgeInboundFee(edgePolicy)
+
 		// TODO(roasbeef): also add stats for revenue for each channel
 		feeReports = append(feeReports, &lnrpc.ChannelFeeReport{
-			ChanId:        chanInfo.ChannelID,
-			ChannelPoint:  chanInfo.ChannelPoint.String(),
-			BaseFeeMAtoms: int64(edgePolicy.FeeBaseMAtoms),
-			FeePerMil:     int64(feeRateFixedPoint),
-			FeeRate:       feeRate,
+			ChanId:               chanInfo.ChannelID,
+			ChannelPoint:         chanInfo.ChannelPoint.String(),
+			BaseFeeMAtoms:        int64(edgePolicy.FeeBaseMAtoms),
+			FeePerMil:            int64(feeRateFixedPoint),
+			FeeRate:              feeRate,
+			InboundBaseFeeMAtoms: inboundFee.BaseFee,
+			InboundFeeRatePpm:    inboundFee.FeeRate,
 		})
 
 		return nil
@@ -5999,6 +8364,11 @@ const minFeeRate = 1e-6
 func (r *rpcServer) UpdateChannelPolicy(ctx context.Context,
 	req *lnrpc.PolicyUpdateRequest) (*lnrpc.PolicyUpdateResponse, error) {
 
+	if len(req.ChanPoints) != 0 && req.Scope != nil {
+		return nil, fmt.Errorf("chan_points can't be used along with " +
+			"another scope")
+	}
+
 	var targetChans []wire.OutPoint
 	switch scope := req.Scope.(type) {
 	// If the request is targeting all active channels, then we don't need
@@ -6016,6 +8386,24 @@ func (r *rpcServer) UpdateChannelPolicy(ctx context.Context,
 			Hash:  *txid,
 			Index: scope.ChanPoint.OutputIndex,
 		})
+
+	// Or a list of channels by their channel points.
+	case nil:
+		if len(req.ChanPoints) == 0 {
+			return nil, fmt.Errorf("unknown scope: %v", scope)
+		}
+
+		for _, chanPoint := range req.ChanPoints {
+			txid, err := GetChanPointFundingTxid(chanPoint)
+			if err != nil {
+				return nil, err
+			}
+			targetChans = append(targetChans, wire.OutPoint{
+				Hash:  *txid,
+				Index: chanPoint.OutputIndex,
+			})
+		}
+
 	default:
 		return nil, fmt.Errorf("unknown scope: %v", scope)
 	}
@@ -6055,28 +8443,326 @@ func (r *rpcServer) UpdateChannelPolicy(ctx context.Context,
 		minHtlc = &min
 	}
 
+	var inboundFee *lnwire.InboundFee
+	if req.InboundFee != nil {
+		inboundFee = &lnwire.InboundFee{
+			BaseFee: req.InboundFee.BaseFeeMAtoms,
+			FeeRate: req.InboundFee.FeeRatePpm,
+		}
+		if err := inboundFee.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
 	chanPolicy := routing.ChannelPolicy{
 		FeeSchema:     feeSchema,
 		TimeLockDelta: req.TimeLockDelta,
 		MaxHTLC:       maxHtlc,
 		MinHTLC:       minHtlc,
+		InboundFee:    inboundFee,
 	}
 
 	rpcsLog.Debugf("[updatechanpolicy] updating channel policy base_fee=%v, "+
 		"rate_float=%v, rate_fixed=%v, time_lock_delta: %v, "+
-		"min_htlc=%v, max_htlc=%v, targets=%v",
-		req.BaseFeeMAtoms, req.FeeRate, feeRateFixed, req.TimeLockDelta,
-		minHtlc, maxHtlc,
+		"min_htlc=%v, max_htlc=%v, inbound_fee=%v, dry_run=%v, "+
+		"targets=%v", req.BaseFeeMAtoms, req.FeeRate, feeRateFixed,
+		req.TimeLockDelta, minHtlc, maxHtlc, inboundFee, req.DryRun,
 		spew.Sdump(targetChans))
 
 	// With the scope resolved, we'll now send this to the local channel
 	// manager so it can propagate the new policy for our target channel(s).
-	err := r.server.localChanMgr.UpdatePolicy(chanPolicy, targetChans...)
+	result, err := r.server.localChanMgr.UpdatePolicies(
+		chanPolicy, req.DryRun, targetChans...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &lnrpc.PolicyUpdateResponse{}
+	for _, failure := range result.Failed {
+		resp.FailedUpdates = append(resp.FailedUpdates, &lnrpc.FailedUpdate{
+			Outpoint: &lnrpc.OutPoint{
+				OutputIndex: failure.ChanPoint.Index,
+				TxidStr:     failure.ChanPoint.Hash.String(),
+				TxidBytes:   failure.ChanPoint.Hash[:],
+			},
+			Reason:      marshalUpdateFailure(failure.Reason),
+			UpdateError: failure.Err.Error(),
+		})
+	}
+	for _, updated := range result.Updated {
+		resp.UpdatedPolicies = append(
+			resp.UpdatedPolicies, &lnrpc.UpdatedChannelPolicy{
+				ChannelPoint: updated.Info.ChannelPoint.String(),
+				ChanId:       updated.Info.ChannelID,
+				Policy:       marshalDbRoutingPolicy(updated.Edge),
+			},
+		)
+	}
+
+	return resp, nil
+}
+
+// marshalUpdateFailure converts the reason a channel policy update failed to
+// its RPC form.
+func marshalUpdateFailure(
+	reason localchans.UpdateFailure) lnrpc.UpdateFailure {
+
+	switch reason {
+	case localchans.UpdateFailurePending:
+		return lnrpc.UpdateFailure_UPDATE_FAILURE_PENDING
+
+	case localchans.UpdateFailureNotFound:
+		return lnrpc.UpdateFailure_UPDATE_FAILURE_NOT_FOUND
+
+	case localchans.UpdateFailureInternalErr:
+		return lnrpc.UpdateFailure_UPDATE_FAILURE_INTERNAL_ERR
+
+	case localchans.UpdateFailureInvalidParameter:
+		return lnrpc.UpdateFailure_UPDATE_FAILURE_INVALID_PARAMETER
+
+	default:
+		return lnrpc.UpdateFailure_UPDATE_FAILURE_UNKNOWN
+	}
+}
+
+// SubscribeFeeAdjustments returns a uni-directional stream (server -> client)
+// for notifying the client of fee rate changes made by the automatic fee
+// controller.
+func (r *rpcServer) SubscribeFeeAdjustments(
+	req *lnrpc.FeeAdjustmentSubscription,
+	updateStream lnrpc.Lightning_SubscribeFeeAdjustmentsServer) error {
+
+	if r.server.feeController == nil {
+		return fmt.Errorf("fee controller not active, enable it with " +
+			"--feecontrol.active")
+	}
+
+	feeSub, err := r.server.feeController.SubscribeFeeAdjustments()
+	if err != nil {
+		return err
+	}
+	defer feeSub.Cancel()
+
+	for {
+		select {
+		// A new adjustment has been made by the fee controller, we'll
+		// marshal it into the form expected by the gRPC client, then
+		// send it off to the client.
+		case e := <-feeSub.Updates():
+			adjustment, ok := e.(feecontrol.FeeAdjustment)
+			if !ok {
+				return fmt.Errorf("unexpected fee adjustment "+
+					"event: %v", e)
+			}
+
+			event := &lnrpc.FeeAdjustmentEvent{
+				ChanId:             adjustment.ShortChanID.ToUint64(),
+				ChannelPoint:       adjustment.ChanPoint.String(),
+				OldFeePerMil:       int64(adjustment.OldFeeRate),
+				NewFeePerMil:       int64(adjustment.NewFeeRate),
+				LocalBalanceRatio:  adjustment.LocalRatio,
+				OutgoingFlowMAtoms: uint64(adjustment.OutgoingFlow),
+				IncomingFlowMAtoms: uint64(adjustment.IncomingFlow),
+				Timestamp:          adjustment.Timestamp.Unix(),
+			}
+			if err := updateStream.Send(event); err != nil {
+				return err
+			}
+
+		case <-updateStream.Context().Done():
+			return updateStream.Context().Err()
+
+		case <-r.quit:
+			return nil
+		}
+	}
+}
+
+// SetFeeSchedule sets the fee schedule of a channel, or the default fee
+// schedule of all channels without their own.
+func (r *rpcServer) SetFeeSchedule(ctx context.Context,
+	req *lnrpc.SetFeeScheduleRequest) (*lnrpc.SetFeeScheduleResponse,
+	error) {
+
+	if r.server.feeSchedules == nil {
+		return nil, fmt.Errorf("fee schedules unavailable while the fee " +
+			"controller is active")
+	}
+
+	var chanPoint *wire.OutPoint
+	switch scope := req.Scope.(type) {
+	case *lnrpc.SetFeeScheduleRequest_Global:
+
+	case *lnrpc.SetFeeScheduleRequest_ChanPoint:
+		txid, err := GetChanPointFundingTxid(scope.ChanPoint)
+		if err != nil {
+			return nil, err
+		}
+		chanPoint = &wire.OutPoint{
+			Hash:  *txid,
+			Index: scope.ChanPoint.OutputIndex,
+		}
+
+	default:
+		return nil, fmt.Errorf("unknown scope: %v", scope)
+	}
+
+	var schedule *channeldb.FeeSchedule
+	if len(req.Tiers) > 0 {
+		schedule = &channeldb.FeeSchedule{}
+		for _, tier := range req.Tiers {
+			if tier.BaseFeeMAtoms < 0 {
+				return nil, fmt.Errorf("negative base fee: %v",
+					tier.BaseFeeMAtoms)
+			}
+
+			schedule.Tiers = append(
+				schedule.Tiers, channeldb.FeeScheduleTier{
+					MinLocalPercent: tier.MinLocalPercent,
+					BaseFee: lnwire.MilliAtom(
+						tier.BaseFeeMAtoms,
+					),
+					FeeRate: tier.FeeRatePpm,
+				},
+			)
+		}
+	}
+
+	rpcsLog.Infof("[setfeeschedule] chan_point=%v, tiers=%v", chanPoint,
+		len(req.Tiers))
+
+	err := r.server.feeSchedules.SetSchedule(chanPoint, schedule)
+
+	// Removing a schedule that doesn't exist is a no-op.
+	if err != nil && err != channeldb.ErrFeeScheduleNotFound {
+		return nil, err
+	}
+
+	return &lnrpc.SetFeeScheduleResponse{}, nil
+}
+
+// ListFeeSchedules returns the default fee schedule and the fee schedules of
+// specific channels.
+func (r *rpcServer) ListFeeSchedules(ctx context.Context,
+	req *lnrpc.ListFeeSchedulesRequest) (*lnrpc.ListFeeSchedulesResponse,
+	error) {
+
+	if r.server.feeSchedules == nil {
+		return nil, fmt.Errorf("fee schedules unavailable while the fee " +
+			"controller is active")
+	}
+
+	defaultSchedule, chanSchedules := r.server.feeSchedules.Schedules()
+
+	resp := &lnrpc.ListFeeSchedulesResponse{}
+	if defaultSchedule != nil {
+		resp.DefaultTiers = marshalFeeScheduleTiers(defaultSchedule)
+	}
+	for chanPoint, schedule := range chanSchedules {
+		resp.ChannelSchedules = append(
+			resp.ChannelSchedules, &lnrpc.ChannelFeeSchedule{
+				ChannelPoint: chanPoint.String(),
+				Tiers:        marshalFeeScheduleTiers(schedule),
+			},
+		)
+	}
+	sort.Slice(resp.ChannelSchedules, func(i, j int) bool {
+		return resp.ChannelSchedules[i].ChannelPoint <
+			resp.ChannelSchedules[j].ChannelPoint
+	})
+
+	return resp, nil
+}
+
+// marshalFeeScheduleTiers converts the tiers of a fee schedule to their RPC
+// form.
+func marshalFeeScheduleTiers(
+	schedule *channeldb.FeeSchedule) []*lnrpc.FeeScheduleTier {
+
+	tiers := make([]*lnrpc.FeeScheduleTier, 0, len(schedule.Tiers))
+	for _, tier := range schedule.Tiers {
+		tiers = append(tiers, &lnrpc.FeeScheduleTier{
+			MinLocalPercent: tier.MinLocalPercent,
+			BaseFeeMAtoms:   int64(tier.BaseFee),
+			FeeRatePpm:      tier.FeeRate,
+		})
+	}
+
+	return tiers
+}
+
+// UpdateHtlcExpiryGrace updates the number of blocks before their expiry at
+// which incoming htlcs paying to us and outgoing htlcs are canceled back.
+func (r *rpcServer) UpdateHtlcExpiryGrace(ctx context.Context,
+	req *lnrpc.UpdateHtlcExpiryGraceRequest) (
+	*lnrpc.UpdateHtlcExpiryGraceResponse, error) {
+
+	// Any value left unset keeps its current setting.
+	finalDelta, outgoingDelta := r.server.cltvRejectDeltas()
+	if req.FinalCltvRejectDelta != 0 {
+		finalDelta = req.FinalCltvRejectDelta
+	}
+	if req.OutgoingCltvRejectDelta != 0 {
+		outgoingDelta = req.OutgoingCltvRejectDelta
+	}
+
+	err := validateCltvRejectDeltas(
+		finalDelta, outgoingDelta, r.cfg.TimeLockDelta,
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	return &lnrpc.PolicyUpdateResponse{}, nil
+	rpcsLog.Infof("[updatehtlcexpirygrace] final_cltv_reject_delta=%v, "+
+		"outgoing_cltv_reject_delta=%v", finalDelta, outgoingDelta)
+
+	r.server.updateCltvRejectDeltas(finalDelta, outgoingDelta)
+
+	return &lnrpc.UpdateHtlcExpiryGraceResponse{
+		CltvConstants: r.cltvConstants(),
+	}, nil
+}
+
+// UpdatePendingChannelLimits updates the maximum number of incoming pending
+// channels permitted per peer and across all peers.
+func (r *rpcServer) UpdatePendingChannelLimits(ctx context.Context,
+	req *lnrpc.UpdatePendingChannelLimitsRequest) (
+	*lnrpc.PendingChannelLimits, error) {
+
+	if req.RemoveGlobalLimit && req.MaxGlobalPendingChannels != 0 {
+		return nil, fmt.Errorf("remove_global_limit can't be combined " +
+			"with max_global_pending_channels")
+	}
+
+	fundingMgr := r.server.fundingMgr
+
+	// Any value left unset keeps its current setting.
+	maxPending, maxGlobalPending := fundingMgr.PendingChannelLimits()
+	if req.MaxPendingChannels != 0 {
+		maxPending = req.MaxPendingChannels
+	}
+	switch {
+	case req.RemoveGlobalLimit:
+		maxGlobalPending = 0
+
+	case req.MaxGlobalPendingChannels != 0:
+		maxGlobalPending = req.MaxGlobalPendingChannels
+	}
+
+	rpcsLog.Infof("[updatependingchannellimits] "+
+		"max_pending_channels=%v, max_global_pending_channels=%v",
+		maxPending, maxGlobalPending)
+
+	fundingMgr.UpdatePendingChannelLimits(maxPending, maxGlobalPending)
+
+	_, numPending := fundingMgr.PendingChannelCounts()
+
+	return &lnrpc.PendingChannelLimits{
+		MaxPendingChannels:       maxPending,
+		MaxGlobalPendingChannels: maxGlobalPending,
+		NumPendingChannels:       numPending,
+	}, nil
 }
 
 // ForwardingHistory allows the caller to query the htlcswitch for a record of
@@ -6127,6 +8813,28 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		numEvents = 100
 	}
 
+	filter, err := newFwdHistoryFilter(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// The peers of the channels are only needed to filter by peer or to
+	// resolve their aliases.
+	var (
+		chanPeers   map[lnwire.ShortChannelID]route.Vertex
+		lookupAlias func(lnwire.ShortChannelID) string
+	)
+	if filter.peer != nil || req.PeerAliasLookup {
+		chanPeers, err = r.fetchChanPeers()
+		if err != nil {
+			return nil, fmt.Errorf("unable to fetch channel "+
+				"peers: %v", err)
+		}
+	}
+	if req.PeerAliasLookup {
+		lookupAlias = r.peerAliasLookup(chanPeers)
+	}
+
 	// Next, we'll map the proto request into a format that is understood by
 	// the forwarding log.
 	eventQuery := channeldb.ForwardingEventQuery{
@@ -6134,39 +8842,81 @@ func (r *rpcServer) ForwardingHistory(ctx context.Context,
 		EndTime:      endTime,
 		IndexOffset:  req.IndexOffset,
 		NumMaxEvents: numEvents,
-	}
-	timeSlice, err := r.server.remoteChanDB.ForwardingLog().Query(eventQuery)
-	if err != nil {
-		return nil, fmt.Errorf("unable to query forwarding log: %v", err)
+		Failed:       req.FailedHoldFees,
 	}
 
 	// TODO(roasbeef): add settlement latency?
 	//  * use FPE on all records?
 
 	// With the events retrieved, we'll now map them into the proper proto
-	// response.
+	// response. When filtering, we keep querying the log until enough
+	// events matched or the time slice is exhausted.
 	//
 	// TODO(roasbeef): show in ns for the outside?
 	resp := &lnrpc.ForwardingHistoryResponse{
-		ForwardingEvents: make([]*lnrpc.ForwardingEvent, len(timeSlice.ForwardingEvents)),
-		LastOffsetIndex:  timeSlice.LastIndexOffset,
+		LastOffsetIndex: req.IndexOffset,
 	}
-	for i, event := range timeSlice.ForwardingEvents {
-		amtInMAtoms := event.AmtIn
-		amtOutMAtoms := event.AmtOut
-		feeMAtoms := event.AmtIn - event.AmtOut
+	for {
+		timeSlice, err := r.server.remoteChanDB.ForwardingLog().Query(
+			eventQuery,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to query forwarding "+
+				"log: %v", err)
+		}
+
+		for i := range timeSlice.ForwardingEvents {
+			if uint32(len(resp.ForwardingEvents)) >= numEvents {
+				break
+			}
+
+			event := &timeSlice.ForwardingEvents[i]
+			resp.LastOffsetIndex = eventQuery.IndexOffset +
+				uint32(i) + 1
+
+			if !filter.matches(event, chanPeers) {
+				continue
+			}
+
+			amtInMAtoms := event.AmtIn
+			amtOutMAtoms := event.AmtOut
+			feeMAtoms := fwdEventFee(event)
+
+			rpcEvent := &lnrpc.ForwardingEvent{
+				Timestamp:     uint64(event.Timestamp.Unix()),
+				ChanIdIn:      event.IncomingChanID.ToUint64(),
+				ChanIdOut:     event.OutgoingChanID.ToUint64(),
+				AmtIn:         uint64(amtInMAtoms.ToAtoms()),
+				AmtOut:        uint64(amtOutMAtoms.ToAtoms()),
+				Fee:           uint64(feeMAtoms.ToAtoms()),
+				FeeMAtoms:     uint64(feeMAtoms),
+				AmtInMAtoms:   uint64(amtInMAtoms),
+				AmtOutMAtoms:  uint64(amtOutMAtoms),
+				HoldFee:       uint64(event.HoldFee.ToAtoms()),
+				HoldFeeMAtoms: uint64(event.HoldFee),
+				Failed:        event.Failed,
+			}
+			if lookupAlias != nil {
+				rpcEvent.PeerAliasIn = lookupAlias(
+					event.IncomingChanID,
+				)
+				rpcEvent.PeerAliasOut = lookupAlias(
+					event.OutgoingChanID,
+				)
+			}
+			resp.ForwardingEvents = append(
+				resp.ForwardingEvents, rpcEvent,
+			)
+		}
 
-		resp.ForwardingEvents[i] = &lnrpc.ForwardingEvent{
-			Timestamp:    uint64(event.Timestamp.Unix()),
-			ChanIdIn:     event.IncomingChanID.ToUint64(),
-			ChanIdOut:    event.OutgoingChanID.ToUint64(),
-			AmtIn:        uint64(amtInMAtoms.ToAtoms()),
-			AmtOut:       uint64(amtOutMAtoms.ToAtoms()),
-			Fee:          uint64(feeMAtoms.ToAtoms()),
-			FeeMAtoms:    uint64(feeMAtoms),
-			AmtInMAtoms:  uint64(amtInMAtoms),
-			AmtOutMAtoms: uint64(amtOutMAtoms),
+		if !filter.active() ||
+			uint32(len(resp.ForwardingEvents)) >= numEvents ||
+			uint32(len(timeSlice.ForwardingEvents)) < numEvents {
+
+			break
 		}
+
+		eventQuery.IndexOffset = timeSlice.LastIndexOffset
 	}
 
 	return resp, nil
@@ -6264,12 +9014,14 @@ func (r *rpcServer) VerifyChanBackup(ctx context.Context,
 		// With our PackedSingles created, we'll attempt to unpack the
 		// backup. If this fails, then we know the backup is invalid for
 		// some reason.
-		_, err := chanBackup.Unpack(r.server.cc.keyRing)
+		singles, err := chanBackup.Unpack(r.server.cc.keyRing)
 		if err != nil {
 			return nil, fmt.Errorf("invalid single channel "+
 				"backup: %v", err)
 		}
 
+		return marshalVerifiedBackups(singles), nil
+
 	case in.GetMultiChanBackup() != nil:
 		// We'll convert the raw byte slice into a PackedMulti that we
 		// can easily work with.
@@ -6278,20 +9030,60 @@ func (r *rpcServer) VerifyChanBackup(ctx context.Context,
 
 		// We'll now attempt to unpack the Multi. If this fails, then we
 		// know it's invalid.
-		_, err := packedMulti.Unpack(r.server.cc.keyRing)
+		multi, err := packedMulti.Unpack(r.server.cc.keyRing)
 		if err != nil {
 			return nil, fmt.Errorf("invalid multi channel backup: "+
 				"%v", err)
 		}
+
+		return marshalVerifiedBackups(multi.StaticBackups), nil
 	}
 
 	return &lnrpc.VerifyChanBackupResponse{}, nil
 }
 
-// createBackupSnapshot converts the passed Single backup into a snapshot which
-// contains individual packed single backups, as well as a single packed multi
-// backup.
-func (r *rpcServer) createBackupSnapshot(backups []chanbackup.Single) (
+// marshalVerifiedBackups converts the channel metadata contained in the passed
+// unpacked Single backups into the response of VerifyChanBackup.
+func marshalVerifiedBackups(
+	singles []chanbackup.Single) *lnrpc.VerifyChanBackupResponse {
+
+	resp := &lnrpc.VerifyChanBackupResponse{
+		Channels: make([]*lnrpc.ChanBackupMetadata, 0, len(singles)),
+	}
+	for _, single := range singles {
+		txid := single.FundingOutpoint.Hash
+		chanPoint := &lnrpc.ChannelPoint{
+			FundingTxid: &lnrpc.ChannelPoint_FundingTxidBytes{
+				FundingTxidBytes: txid[:],
+			},
+			OutputIndex: single.FundingOutpoint.Index,
+		}
+
+		addrs := make([]string, 0, len(single.Addresses))
+		for _, addr := range single.Addresses {
+			addrs = append(addrs, addr.String())
+		}
+
+		resp.Channels = append(resp.Channels, &lnrpc.ChanBackupMetadata{
+			ChanPoint: chanPoint,
+			ChanId:    single.ShortChannelID.ToUint64(),
+			RemoteNodePub: hex.EncodeToString(
+				single.RemoteNodePub.SerializeCompressed(),
+			),
+			Addresses: addrs,
+			Capacity:  int64(single.Capacity),
+			Initiator: single.IsInitiator,
+			Version:   uint32(single.Version),
+		})
+	}
+
+	return resp
+}
+
+// createBackupSnapshot converts the passed Single backup into a snapshot which
+// contains individual packed single backups, as well as a single packed multi
+// backup.
+func (r *rpcServer) createBackupSnapshot(backups []chanbackup.Single) (
 	*lnrpc.ChanBackupSnapshot, error) {
 
 	// Once we have the set of back ups, we'll attempt to pack them all
@@ -6373,8 +9165,219 @@ func (r *rpcServer) ExportAllChannelBackups(ctx context.Context,
 			"backups: %v", err)
 	}
 
+	// Parse the filters of the request, if any, that restrict the set of
+	// channels to export backups for.
+	var peerKey *secp256k1.PublicKey
+	if len(in.Peer) > 0 {
+		peerKey, err = secp256k1.ParsePubKey(in.Peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid `peer` key: %v", err)
+		}
+	}
+
+	chanPoints := make(map[wire.OutPoint]struct{}, len(in.ChanPoints))
+	for _, rpcChanPoint := range in.ChanPoints {
+		txid, err := GetChanPointFundingTxid(rpcChanPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		chanPoints[wire.OutPoint{
+			Hash:  *txid,
+			Index: rpcChanPoint.OutputIndex,
+		}] = struct{}{}
+	}
+
+	backups := make([]chanbackup.Single, 0, len(allUnpackedBackups))
+	for _, backup := range allUnpackedBackups {
+		_, isRequested := chanPoints[backup.FundingOutpoint]
+
+		switch {
+		case peerKey != nil && !backup.RemoteNodePub.IsEqual(peerKey):
+			continue
+
+		case len(chanPoints) > 0 && !isRequested:
+			continue
+
+		case in.ActiveOnly && !r.isChannelActive(
+			backup.RemoteNodePub, backup.FundingOutpoint,
+		):
+			continue
+		}
+
+		backups = append(backups, backup)
+	}
+
 	// With the backups assembled, we'll create a full snapshot.
-	return r.createBackupSnapshot(allUnpackedBackups)
+	snapshot, err := r.createBackupSnapshot(backups)
+	if err != nil {
+		return nil, err
+	}
+
+	// If requested, we'll also write the multi backup to the given file,
+	// swapping it atomically just like the live backup file. As this
+	// writes to the file system, it requires a write permission on top of
+	// the read permission of the call itself.
+	if in.ExportFilePath != "" {
+		err := r.checkExtraPermission(
+			ctx, backupExportPermission,
+			"/lnrpc.Lightning/ExportAllChannelBackups",
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		exportPath, err := resolveBackupExportPath(
+			r.cfg.BackupExportDir, r.cfg.BackupFilePath,
+			in.ExportFilePath,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		rpcsLog.Infof("[exportallchannelbackups] writing multi backup "+
+			"of %v channels to %v", len(backups), exportPath)
+
+		r.backupExportMtx.Lock()
+		defer r.backupExportMtx.Unlock()
+
+		err = os.MkdirAll(filepath.Dir(exportPath), 0700)
+		if err != nil {
+			return nil, fmt.Errorf("unable to create backup "+
+				"directory: %v", err)
+		}
+
+		packedMulti := chanbackup.PackedMulti(
+			snapshot.MultiChanBackup.MultiChanBackup,
+		)
+		err = chanbackup.NewMultiFile(exportPath).UpdateAndSwap(
+			packedMulti,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to write backup file: "+
+				"%v", err)
+		}
+	}
+
+	return snapshot, nil
+}
+
+// backupExportPermission is the permission required to export channel backups
+// to a file, on top of the permission of ExportAllChannelBackups.
+var backupExportPermission = bakery.Op{
+	Entity: "offchain",
+	Action: "write",
+}
+
+// checkExtraPermission ensures the macaroon of the request grants the given
+// permission, on top of the permissions required by the called method, for
+// calls that only need it for some of their options. It's a no-op if macaroons
+// are disabled.
+func (r *rpcServer) checkExtraPermission(ctx context.Context, op bakery.Op,
+	fullMethod string) error {
+
+	if r.macService == nil {
+		return nil
+	}
+
+	err := r.macService.ValidateMacaroon(ctx, []bakery.Op{op}, fullMethod)
+	if err != nil {
+		return status.Errorf(codes.PermissionDenied, "permission "+
+			"%v:%v required: %v", op.Entity, op.Action, err)
+	}
+
+	return nil
+}
+
+// evalExistingSymlinks resolves the symlinks of the longest existing prefix of
+// the given clean absolute path, as the rest of it has yet to be created.
+func evalExistingSymlinks(path string) (string, error) {
+	existing := path
+	var missing []string
+	for {
+		resolved, err := filepath.EvalSymlinks(existing)
+		switch {
+		case err == nil:
+			return filepath.Join(append(
+				[]string{resolved}, missing...,
+			)...), nil
+
+		case !os.IsNotExist(err):
+			return "", err
+		}
+
+		// A dangling symlink can't be resolved, but it would still be
+		// followed once the file is created.
+		if _, err := os.Lstat(existing); err == nil {
+			return "", fmt.Errorf("%v is a dangling symlink",
+				existing)
+		}
+
+		parent := filepath.Dir(existing)
+		if parent == existing {
+			return path, nil
+		}
+		missing = append([]string{filepath.Base(existing)}, missing...)
+		existing = parent
+	}
+}
+
+// resolveBackupExportPath returns the absolute location of a backup file to
+// export, which must be located within the given export directory, but not in
+// the directory of the live backup file. Symlinks are resolved before checking
+// the location, so they can't be used to escape the export directory.
+func resolveBackupExportPath(exportDir, liveBackupPath,
+	exportPath string) (string, error) {
+
+	if exportDir == "" {
+		return "", errors.New("exporting backups to a file requires " +
+			"the backupexportdir option to be set")
+	}
+
+	exportDir, err := evalExistingSymlinks(filepath.Clean(exportDir))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v: %v", exportDir,
+			err)
+	}
+	liveBackupPath, err = evalExistingSymlinks(
+		filepath.Clean(liveBackupPath),
+	)
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v: %v",
+			liveBackupPath, err)
+	}
+
+	if !filepath.IsAbs(exportPath) {
+		exportPath = filepath.Join(exportDir, exportPath)
+	}
+	exportPath, err = evalExistingSymlinks(filepath.Clean(exportPath))
+	if err != nil {
+		return "", fmt.Errorf("unable to resolve %v: %v", exportPath,
+			err)
+	}
+
+	rel, err := filepath.Rel(exportDir, exportPath)
+	if err != nil || rel == "." || rel == ".." ||
+		strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+
+		return "", fmt.Errorf("backup file %v is not located within "+
+			"%v", exportPath, exportDir)
+	}
+
+	// The live backup file is swapped through the same staging file, so
+	// we can't write to its directory.
+	if filepath.Dir(exportPath) == filepath.Dir(liveBackupPath) {
+		return "", fmt.Errorf("backup file %v must not be located in "+
+			"the directory of the channel backup file", exportPath)
+	}
+
+	// The staging file of the directory can't be used as the backup file.
+	if filepath.Base(exportPath) == chanbackup.DefaultTempBackupFileName {
+		return "", fmt.Errorf("invalid backup file name %v",
+			chanbackup.DefaultTempBackupFileName)
+	}
+
+	return exportPath, nil
 }
 
 // RestoreChannelBackups accepts a set of singular channel backups, or a single
@@ -6443,6 +9446,263 @@ func (r *rpcServer) RestoreChannelBackups(ctx context.Context,
 	return &lnrpc.RestoreBackupResponse{}, nil
 }
 
+// DeriveChannelRecoveryKeys re-derives the keys of the channels contained in a
+// set of static channel backups, along with the keys used by our commitment
+// transaction at each of the requested commitment heights.
+func (r *rpcServer) DeriveChannelRecoveryKeys(ctx context.Context,
+	in *lnrpc.ChannelRecoveryKeysRequest) (
+	*lnrpc.ChannelRecoveryKeysResponse, error) {
+
+	if len(in.CommitHeights) > maxRecoveryCommitHeights {
+		return nil, fmt.Errorf("at most %v commitment heights may be "+
+			"requested", maxRecoveryCommitHeights)
+	}
+
+	// We'll accept either a list of Single backups, or a single Multi
+	// backup which contains several single backups.
+	var backups []chanbackup.Single
+	switch {
+	case in.GetChanBackups() != nil:
+		chanBackupsProtos := in.GetChanBackups()
+
+		packedBackups := make([][]byte, 0, len(chanBackupsProtos.ChanBackups))
+		for _, chanBackup := range chanBackupsProtos.ChanBackups {
+			packedBackups = append(
+				packedBackups, chanBackup.ChanBackup,
+			)
+		}
+
+		var err error
+		backups, err = chanbackup.PackedSingles(packedBackups).Unpack(
+			r.server.cc.keyRing,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unpack single "+
+				"backups: %v", err)
+		}
+
+	case in.GetMultiChanBackup() != nil:
+		packedMulti := chanbackup.PackedMulti(in.GetMultiChanBackup())
+		multi, err := packedMulti.Unpack(r.server.cc.keyRing)
+		if err != nil {
+			return nil, fmt.Errorf("unable to unpack chan "+
+				"backup: %v", err)
+		}
+		backups = multi.StaticBackups
+
+	default:
+		return nil, errors.New("either a Single or Multi channel " +
+			"backup must be specified")
+	}
+
+	// The restorer is only used to map the backups into channel shells,
+	// so nothing is written to the database.
+	chanRestorer := &chanDBRestorer{
+		secretKeys: r.server.cc.keyRing,
+	}
+
+	resp := &lnrpc.ChannelRecoveryKeysResponse{}
+	for _, backup := range backups {
+		chanKeys, err := channelRecoveryKeys(
+			chanRestorer, backup, in.CommitHeights,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("unable to derive keys for "+
+				"ChannelPoint(%v): %v", backup.FundingOutpoint,
+				err)
+		}
+		resp.Channels = append(resp.Channels, chanKeys)
+	}
+
+	return resp, nil
+}
+
+// recoveryChannelShell unpacks the passed single channel backup and maps it
+// into a channel shell, without writing anything to the database.
+func (r *rpcServer) recoveryChannelShell(
+	packedBackup []byte) (*channeldb.OpenChannel, error) {
+
+	backups, err := chanbackup.PackedSingles{packedBackup}.Unpack(
+		r.server.cc.keyRing,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack single backup: %v",
+			err)
+	}
+
+	chanRestorer := &chanDBRestorer{
+		secretKeys: r.server.cc.keyRing,
+	}
+	shell, err := chanRestorer.openChannelShell(backups[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return shell.Chan, nil
+}
+
+// SweepRecoveredChannel sweeps our output of the commitment transaction that
+// force closed a channel, using only the channel's static channel backup.
+func (r *rpcServer) SweepRecoveredChannel(ctx context.Context,
+	in *lnrpc.SweepRecoveredChannelRequest) (
+	*lnrpc.SweepRecoveredChannelResponse, error) {
+
+	channel, err := r.recoveryChannelShell(in.ChanBackup)
+	if err != nil {
+		return nil, err
+	}
+
+	commitTx := &wire.MsgTx{}
+	err = commitTx.Deserialize(bytes.NewReader(in.CommitTx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode commitment "+
+			"transaction: %v", err)
+	}
+
+	sweepAddr, err := dcrutil.DecodeAddress(
+		in.SweepAddr, activeNetParams.Params,
+	)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(sweepAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	feePerKB, err := sweep.DetermineFeePerKB(
+		r.server.cc.feeEstimator, sweep.FeePreference{
+			ConfTarget: uint32(in.TargetConf),
+			FeeRate:    chainfee.AtomPerKByte(in.AtomsPerByte * 1000),
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	sweepInput, local, err := recoverySweepInput(channel, commitTx)
+	if err != nil {
+		return nil, err
+	}
+	sweepTx, err := recoverySweepTx(
+		sweepInput, pkScript, feePerKB, r.server.cc.signer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Warnf("[sweeprecoveredchannel] created sweep tx %v of "+
+		"ChannelPoint(%v) from commitment %v", sweepTx.TxHash(),
+		channel.FundingOutpoint, commitTx.TxHash())
+
+	if in.Publish {
+		err := r.server.cc.wallet.PublishTransaction(sweepTx, "")
+		if err != nil {
+			return nil, fmt.Errorf("unable to publish sweep "+
+				"transaction: %v", err)
+		}
+	}
+
+	rawTx, err := sweepTx.Bytes()
+	if err != nil {
+		return nil, err
+	}
+
+	return &lnrpc.SweepRecoveredChannelResponse{
+		SweepTx:         rawTx,
+		SweepTxid:       sweepTx.TxHash().String(),
+		AmountAtoms:     sweepTx.TxOut[0].Value,
+		LocalCommitment: local,
+		CsvDelay:        sweepInput.BlocksToMaturity(),
+		Published:       in.Publish,
+	}, nil
+}
+
+// SignChannelRescue signs a transaction spending the funding output of a
+// channel with our multisig key, using only the channel's static channel
+// backup.
+func (r *rpcServer) SignChannelRescue(ctx context.Context,
+	in *lnrpc.SignChannelRescueRequest) (*lnrpc.SignChannelRescueResponse,
+	error) {
+
+	channel, err := r.recoveryChannelShell(in.ChanBackup)
+	if err != nil {
+		return nil, err
+	}
+
+	// A channel that is still open or pending, rather than restored from
+	// a backup, must be closed through its commitments instead.
+	dbChan, err := r.server.remoteChanDB.FetchChannel(
+		channel.FundingOutpoint,
+	)
+	switch {
+	case err == nil &&
+		!dbChan.HasChanStatus(channeldb.ChanStatusRestored):
+
+		return nil, fmt.Errorf("channel %v is still open or pending",
+			channel.FundingOutpoint)
+
+	case err != nil && err != channeldb.ErrChannelNotFound &&
+		err != channeldb.ErrNoActiveChannels:
+
+		return nil, err
+	}
+
+	rescueTx := &wire.MsgTx{}
+	err = rescueTx.Deserialize(bytes.NewReader(in.RescueTx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode rescue "+
+			"transaction: %v", err)
+	}
+	commitTx := &wire.MsgTx{}
+	err = commitTx.Deserialize(bytes.NewReader(in.CommitTx))
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode commitment "+
+			"transaction: %v", err)
+	}
+
+	var remoteSig input.Signature
+	if len(in.RemoteSig) > 0 {
+		remoteSig, err = ecdsa.ParseDERSignature(in.RemoteSig)
+		if err != nil {
+			return nil, fmt.Errorf("invalid remote signature: %v",
+				err)
+		}
+	}
+
+	fetchFundingOutput := func(pkScript []byte) (*wire.TxOut, error) {
+		return r.server.cc.chainIO.GetUtxo(
+			&channel.FundingOutpoint, pkScript,
+			channel.ShortChannelID.BlockHeight, r.quit,
+		)
+	}
+	localSig, fundingScript, err := signChannelRescue(
+		channel, rescueTx, commitTx, remoteSig, fetchFundingOutput,
+		r.isOurScript, r.server.cc.signer,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rpcsLog.Warnf("[signchannelrescue] signed rescue tx %v of "+
+		"ChannelPoint(%v)", rescueTx.TxHash(), channel.FundingOutpoint)
+
+	resp := &lnrpc.SignChannelRescueResponse{
+		LocalSig: localSig.Serialize(),
+		LocalMultiSigKey: channel.LocalChanCfg.MultiSigKey.PubKey.
+			SerializeCompressed(),
+		FundingScript: fundingScript,
+	}
+	if remoteSig != nil {
+		resp.SignedTx, err = rescueTx.Bytes()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
 // SubscribeChannelBackups allows a client to sub-subscribe to the most up to
 // date information concerning the state of all channel back ups. Each time a
 // new channel is added, we return the new set of channels, along with a
@@ -6481,6 +9741,8 @@ func (r *rpcServer) SubscribeChannelBackups(req *lnrpc.ChannelBackupSubscription
 				continue
 			case channelnotifier.ActiveLinkEvent:
 				continue
+			case channelnotifier.CloseReasonEvent:
+				continue
 			}
 
 			// Now that we know the channel state has changed,
@@ -6513,35 +9775,283 @@ func (r *rpcServer) SubscribeChannelBackups(req *lnrpc.ChannelBackupSubscription
 	}
 }
 
+// ExportRouterState returns an encrypted bundle of the state gathered while
+// routing payments: the mission control history, the forwarding policies of
+// the channels and the addresses of the channel peers.
+func (r *rpcServer) ExportRouterState(ctx context.Context,
+	in *lnrpc.RouterStateExportRequest) (*lnrpc.RouterStateBundle, error) {
+
+	bundle := &routerstate.Bundle{
+		Version: routerstate.DefaultBundleVersion,
+	}
+
+	var mcHistory bytes.Buffer
+	if err := r.server.missionControl.ExportHistory(&mcHistory); err != nil {
+		return nil, fmt.Errorf("unable to export mission control "+
+			"history: %v", err)
+	}
+	bundle.MissionControl = mcHistory.Bytes()
+
+	selfNode, err := r.server.localChanDB.ChannelGraph().SourceNode()
+	if err != nil {
+		return nil, err
+	}
+	err = selfNode.ForEachChannel(nil, func(_ kvdb.RTx,
+		chanInfo *channeldb.ChannelEdgeInfo,
+		edgePolicy, _ *channeldb.ChannelEdgePolicy) error {
+
+		// Channels for which we haven't created a policy yet have
+		// nothing worth exporting.
+		if edgePolicy == nil {
+			return nil
+		}
+
+		bundle.Policies = append(bundle.Policies, routerstate.ChannelPolicy{
+			ChanPoint: chanInfo.ChannelPoint,
+			BaseFee:   edgePolicy.FeeBaseMAtoms,
+			FeeRate: uint32(
+				edgePolicy.FeeProportionalMillionths,
+			),
+			TimeLockDelta: edgePolicy.TimeLockDelta,
+			MinHTLC:       edgePolicy.MinHTLC,
+			MaxHTLC:       edgePolicy.MaxHTLC,
+		})
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	linkNodes, err := r.server.remoteChanDB.FetchAllLinkNodes()
+	if err != nil {
+		return nil, err
+	}
+	for _, linkNode := range linkNodes {
+		bundle.Peers = append(bundle.Peers, routerstate.PeerAddresses{
+			PubKey:    linkNode.IdentityPub,
+			Addresses: linkNode.Addresses,
+		})
+	}
+
+	rpcsLog.Debugf("[exportrouterstate] policies=%v, peers=%v",
+		len(bundle.Policies), len(bundle.Peers))
+
+	var b bytes.Buffer
+	if err := bundle.PackToWriter(&b, r.server.cc.keyRing); err != nil {
+		return nil, fmt.Errorf("unable to pack router state: %v", err)
+	}
+
+	return &lnrpc.RouterStateBundle{
+		Bundle: b.Bytes(),
+	}, nil
+}
+
+// ImportRouterState imports a bundle created by ExportRouterState. The mission
+// control history is merged into the existing one, the forwarding policies are
+// applied to the matching channels and the peer addresses are added to the
+// known addresses of the matching channel peers.
+func (r *rpcServer) ImportRouterState(ctx context.Context,
+	in *lnrpc.RouterStateImportRequest) (*lnrpc.RouterStateImportResponse,
+	error) {
+
+	var bundle routerstate.Bundle
+	err := bundle.UnpackFromReader(
+		bytes.NewReader(in.Bundle), r.server.cc.keyRing,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to unpack router state: %v", err)
+	}
+
+	numResults, err := r.server.missionControl.ImportHistory(
+		bytes.NewReader(bundle.MissionControl),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Policies are only applied to the channels we currently have, as
+	// updating the policy without a channel filter would apply it to all
+	// of them.
+	selfNode, err := r.server.localChanDB.ChannelGraph().SourceNode()
+	if err != nil {
+		return nil, err
+	}
+	ourChans := make(map[wire.OutPoint]struct{})
+	err = selfNode.ForEachChannel(nil, func(_ kvdb.RTx,
+		chanInfo *channeldb.ChannelEdgeInfo,
+		_, _ *channeldb.ChannelEdgePolicy) error {
+
+		ourChans[chanInfo.ChannelPoint] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var numPolicies uint32
+	for _, policy := range bundle.Policies {
+		if _, ok := ourChans[policy.ChanPoint]; !ok {
+			continue
+		}
+
+		minHTLC := policy.MinHTLC
+		err := r.server.localChanMgr.UpdatePolicy(routing.ChannelPolicy{
+			FeeSchema: routing.FeeSchema{
+				BaseFee: policy.BaseFee,
+				FeeRate: policy.FeeRate,
+			},
+			TimeLockDelta: uint32(policy.TimeLockDelta),
+			MaxHTLC:       policy.MaxHTLC,
+			MinHTLC:       &minHTLC,
+		}, policy.ChanPoint)
+		if err != nil {
+			return nil, err
+		}
+
+		numPolicies++
+	}
+
+	// Peer addresses are only added for the peers we have channels with,
+	// as link nodes without channels are pruned on startup.
+	var numPeers uint32
+	for _, peer := range bundle.Peers {
+		linkNode, err := r.server.remoteChanDB.FetchLinkNode(peer.PubKey)
+		switch {
+		case err == channeldb.ErrNodeNotFound:
+			continue
+
+		case err != nil:
+			return nil, err
+		}
+
+		for _, addr := range peer.Addresses {
+			if err := linkNode.AddAddress(addr); err != nil {
+				return nil, err
+			}
+		}
+
+		numPeers++
+	}
+
+	rpcsLog.Infof("Imported router state: mission_control_results=%v, "+
+		"policies=%v, peers=%v", numResults, numPolicies, numPeers)
+
+	return &lnrpc.RouterStateImportResponse{
+		NumMissionControlResults: uint32(numResults),
+		NumPolicies:              numPolicies,
+		NumPeers:                 numPeers,
+	}, nil
+}
+
+// maxChanAcceptErrLen is the maximum length of the custom error a channel
+// acceptor client may return to the peer initiating a channel.
+const maxChanAcceptErrLen = 500
+
 // chanAcceptInfo is used in the ChannelAcceptor bidirectional stream and
 // encapsulates the request information sent from the RPCAcceptor to the
 // RPCServer.
 type chanAcceptInfo struct {
 	chanReq      *chanacceptor.ChannelAcceptRequest
-	responseChan chan bool
+	responseChan chan *chanacceptor.ChannelAcceptResponse
+}
+
+// chanAcceptResult is a validated response of a ChannelAcceptor client along
+// with the pending channel id it applies to.
+type chanAcceptResult struct {
+	pendingChanID [32]byte
+	resp          *chanacceptor.ChannelAcceptResponse
+}
+
+// chanAcceptorTimeout validates the timeout in seconds requested by a
+// ChannelAcceptor client for its stream.
+func chanAcceptorTimeout(seconds uint32, first bool) (time.Duration, error) {
+	if !first {
+		return 0, fmt.Errorf("channel acceptor timeout can only be " +
+			"set in the first message of the stream")
+	}
+
+	timeout := time.Duration(seconds) * time.Second
+	if timeout > maxAcceptorTimeout {
+		return 0, fmt.Errorf("channel acceptor timeout of %v exceeds "+
+			"the maximum of %v", timeout, maxAcceptorTimeout)
+	}
+
+	return timeout, nil
+}
+
+// unmarshalChanAcceptResponse validates the response of a ChannelAcceptor
+// client and converts it into the form expected by the funding manager.
+func unmarshalChanAcceptResponse(
+	resp *lnrpc.ChannelAcceptResponse) (*chanacceptor.ChannelAcceptResponse,
+	error) {
+
+	// An accepted channel can't come with an error for the peer, and a
+	// rejected one can't come with constraints to apply.
+	hasConstraints := resp.CsvDelay != 0 || resp.ReserveAtoms != 0 ||
+		resp.MaxHtlcCount != 0 || resp.MinHtlcIn != 0
+	switch {
+	case resp.Accept && resp.Error != "":
+		return nil, fmt.Errorf("channel acceptor response accepts " +
+			"the channel but sets an error")
+
+	case !resp.Accept && hasConstraints:
+		return nil, fmt.Errorf("channel acceptor response rejects " +
+			"the channel but sets channel constraints")
+
+	case len(resp.Error) > maxChanAcceptErrLen:
+		return nil, fmt.Errorf("channel acceptor error exceeds max "+
+			"length of %v characters", maxChanAcceptErrLen)
+
+	case resp.CsvDelay > math.MaxUint16:
+		return nil, fmt.Errorf("invalid csv delay: %v", resp.CsvDelay)
+
+	case resp.MaxHtlcCount > input.MaxHTLCNumber/2:
+		return nil, fmt.Errorf("max htlc count %v is greater than %v",
+			resp.MaxHtlcCount, input.MaxHTLCNumber/2)
+	}
+
+	var acceptErr error
+	if resp.Error != "" {
+		acceptErr = errors.New(resp.Error)
+	}
+
+	return chanacceptor.NewChannelAcceptResponse(
+		resp.Accept, acceptErr, uint16(resp.CsvDelay),
+		dcrutil.Amount(resp.ReserveAtoms),
+		lnwire.MilliAtom(resp.MinHtlcIn), uint16(resp.MaxHtlcCount),
+	), nil
 }
 
 // ChannelAcceptor dispatches a bi-directional streaming RPC in which
 // OpenChannel requests are sent to the client and the client responds with
-// a boolean that tells LND whether or not to accept the channel. This allows
-// node operators to specify their own criteria for accepting inbound channels
-// through a single persistent connection.
+// a boolean that tells LND whether or not to accept the channel, along with
+// either an error to deliver to the peer or the constraints to apply to the
+// channel. This allows node operators to specify their own criteria for
+// accepting inbound channels through a single persistent connection.
 func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer) error {
 	chainedAcceptor := r.chanPredicate
 
 	// Create two channels to handle requests and responses respectively.
 	newRequests := make(chan *chanAcceptInfo)
-	responses := make(chan *lnrpc.ChannelAcceptResponse)
+	responses := make(chan *chanAcceptResult)
 
 	// Define a quit channel that will be used to signal to the RPCAcceptor's
 	// closure whether the stream still exists.
 	quit := make(chan struct{})
 	defer close(quit)
 
+	// acceptorTimeout is the time after which the requests sent over this
+	// stream expire. It can be overridden by the first message of the
+	// client, so it's accessed atomically.
+	acceptorTimeout := int64(r.cfg.AcceptorTimeout)
+
 	// demultiplexReq is a closure that will be passed to the RPCAcceptor and
 	// acts as an intermediary between the RPCAcceptor and the RPCServer.
-	demultiplexReq := func(req *chanacceptor.ChannelAcceptRequest) bool {
-		respChan := make(chan bool, 1)
+	demultiplexReq := func(
+		req *chanacceptor.ChannelAcceptRequest) *chanacceptor.ChannelAcceptResponse {
+
+		respChan := make(chan *chanacceptor.ChannelAcceptResponse, 1)
 
 		newRequest := &chanAcceptInfo{
 			chanReq:      req,
@@ -6549,19 +10059,28 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 		}
 
 		// timeout is the time after which ChannelAcceptRequests expire.
-		timeout := time.After(r.cfg.AcceptorTimeout)
+		timeoutDuration := time.Duration(
+			atomic.LoadInt64(&acceptorTimeout),
+		)
+		timeout := time.After(timeoutDuration)
+
+		// reject is the generic rejection returned when we don't get a
+		// response from the client.
+		reject := chanacceptor.NewChannelAcceptResponse(
+			false, nil, 0, 0, 0, 0,
+		)
 
 		// Send the request to the newRequests channel.
 		select {
 		case newRequests <- newRequest:
 		case <-timeout:
-			rpcsLog.Errorf("RPCAcceptor returned false - reached timeout of %d",
-				r.cfg.AcceptorTimeout)
-			return false
+			rpcsLog.Errorf("RPCAcceptor returned false - reached timeout of %v",
+				timeoutDuration)
+			return reject
 		case <-quit:
-			return false
+			return reject
 		case <-r.quit:
-			return false
+			return reject
 		}
 
 		// Receive the response and return it. If no response has been received
@@ -6570,13 +10089,13 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 		case resp := <-respChan:
 			return resp
 		case <-timeout:
-			rpcsLog.Errorf("RPCAcceptor returned false - reached timeout of %d",
-				r.cfg.AcceptorTimeout)
-			return false
+			rpcsLog.Errorf("RPCAcceptor returned false - reached timeout of %v",
+				timeoutDuration)
+			return reject
 		case <-quit:
-			return false
+			return reject
 		case <-r.quit:
-			return false
+			return reject
 		}
 	}
 
@@ -6596,20 +10115,41 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 	// blocking and would prevent us from sending more ChannelAcceptRequests to
 	// the RPC client.
 	go func() {
-		for {
+		for first := true; ; first = false {
 			resp, err := stream.Recv()
 			if err != nil {
 				errChan <- err
 				return
 			}
 
-			var pendingID [32]byte
-			copy(pendingID[:], resp.PendingChanId)
+			// The client may only override the timeout of the
+			// stream in its first message.
+			if resp.Timeout != 0 {
+				timeout, err := chanAcceptorTimeout(
+					resp.Timeout, first,
+				)
+				if err != nil {
+					errChan <- err
+					return
+				}
+
+				rpcsLog.Debugf("ChannelAcceptor stream set "+
+					"timeout of %v", timeout)
+				atomic.StoreInt64(&acceptorTimeout, int64(timeout))
+			}
+
+			// A malformed response is a client error, so we'll tear
+			// down the stream rather than guess its intent.
+			acceptResp, err := unmarshalChanAcceptResponse(resp)
+			if err != nil {
+				errChan <- err
+				return
+			}
 
-			openChanResp := &lnrpc.ChannelAcceptResponse{
-				Accept:        resp.Accept,
-				PendingChanId: pendingID[:],
+			openChanResp := &chanAcceptResult{
+				resp: acceptResp,
 			}
+			copy(openChanResp.pendingChanID[:], resp.PendingChanId)
 
 			// Now that we have the response from the RPC client, send it to
 			// the responses chan.
@@ -6623,7 +10163,9 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 		}
 	}()
 
-	acceptRequests := make(map[[32]byte]chan bool)
+	acceptRequests := make(
+		map[[32]byte]chan *chanacceptor.ChannelAcceptResponse,
+	)
 
 	for {
 		select {
@@ -6649,6 +10191,7 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 				CsvDelay:         uint32(req.OpenChanMsg.CsvDelay),
 				MaxAcceptedHtlcs: uint32(req.OpenChanMsg.MaxAcceptedHTLCs),
 				ChannelFlags:     uint32(req.OpenChanMsg.ChannelFlags),
+				MinAcceptDepth:   uint32(req.MinAcceptDepth),
 			}
 
 			if err := stream.Send(chanAcceptReq); err != nil {
@@ -6657,15 +10200,14 @@ func (r *rpcServer) ChannelAcceptor(stream lnrpc.Lightning_ChannelAcceptorServer
 		case resp := <-responses:
 			// Look up the appropriate channel to send on given the pending ID.
 			// If a channel is found, send the response over it.
-			var pendingID [32]byte
-			copy(pendingID[:], resp.PendingChanId)
+			pendingID := resp.pendingChanID
 			respChan, ok := acceptRequests[pendingID]
 			if !ok {
 				continue
 			}
 
-			// Send the response boolean over the buffered response channel.
-			respChan <- resp.Accept
+			// Send the response over the buffered response channel.
+			respChan <- resp.resp
 
 			// Delete the channel from the acceptRequests map.
 			delete(acceptRequests, pendingID)
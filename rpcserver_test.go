@@ -0,0 +1,991 @@
+package dcrlnd
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chanbackup"
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/input"
+	"github.com/decred/dcrlnd/lncfg"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnrpc/invoicesrpc"
+	"github.com/decred/dcrlnd/lnrpc/routerrpc"
+	"github.com/decred/dcrlnd/lntypes"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/routing"
+	"github.com/decred/dcrlnd/routing/route"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// TestRestClientAddr asserts that the X-Forwarded-For header is only used to
+// resolve the address of a REST client when it's set by a trusted proxy.
+func TestRestClientAddr(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := lncfg.ParseIPNets([]string{"127.0.0.1", "10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("unable to parse proxies: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		remoteHost   string
+		forwardedFor []string
+		clientAddr   string
+	}{{
+		name:         "untrusted remote",
+		remoteHost:   "1.2.3.4",
+		forwardedFor: []string{"5.6.7.8"},
+		clientAddr:   "1.2.3.4",
+	}, {
+		name:       "trusted remote without header",
+		remoteHost: "127.0.0.1",
+		clientAddr: "127.0.0.1",
+	}, {
+		name:         "trusted remote",
+		remoteHost:   "127.0.0.1",
+		forwardedFor: []string{"5.6.7.8"},
+		clientAddr:   "5.6.7.8",
+	}, {
+		name:         "chain of trusted proxies",
+		remoteHost:   "127.0.0.1",
+		forwardedFor: []string{"9.9.9.9, 5.6.7.8", "10.1.1.1"},
+		clientAddr:   "5.6.7.8",
+	}, {
+		name:         "all hops trusted",
+		remoteHost:   "127.0.0.1",
+		forwardedFor: []string{"10.2.2.2, 10.1.1.1"},
+		clientAddr:   "10.2.2.2",
+	}, {
+		name:         "invalid hop",
+		remoteHost:   "127.0.0.1",
+		forwardedFor: []string{"5.6.7.8, garbage, 10.1.1.1"},
+		clientAddr:   "10.1.1.1",
+	}}
+
+	for _, test := range tests {
+		header := http.Header{}
+		for _, value := range test.forwardedFor {
+			header.Add("X-Forwarded-For", value)
+		}
+
+		clientAddr := restClientAddr(test.remoteHost, header, proxies)
+		if clientAddr != test.clientAddr {
+			t.Fatalf("%v: expected client %v, got %v", test.name,
+				test.clientAddr, clientAddr)
+		}
+	}
+}
+
+// TestTrustProxyHeaders asserts that the X-Forwarded-For header only reaches
+// the REST handler when it carries the address of a client behind a trusted
+// proxy.
+func TestTrustProxyHeaders(t *testing.T) {
+	t.Parallel()
+
+	proxies, err := lncfg.ParseIPNets([]string{"127.0.0.1"})
+	if err != nil {
+		t.Fatalf("unable to parse proxies: %v", err)
+	}
+
+	var forwardedFor []string
+	handler := trustProxyHeaders(http.HandlerFunc(
+		func(_ http.ResponseWriter, r *http.Request) {
+			forwardedFor = r.Header["X-Forwarded-For"]
+		},
+	), proxies)
+
+	tests := []struct {
+		name         string
+		remoteAddr   string
+		forwardedFor []string
+		expected     []string
+	}{{
+		name:         "spoofed by untrusted remote",
+		remoteAddr:   "1.2.3.4:1234",
+		forwardedFor: []string{"5.6.7.8"},
+	}, {
+		name:       "trusted remote without header",
+		remoteAddr: "127.0.0.1:1234",
+	}, {
+		name:         "trusted remote",
+		remoteAddr:   "127.0.0.1:1234",
+		forwardedFor: []string{"9.9.9.9, 5.6.7.8"},
+		expected:     []string{"5.6.7.8"},
+	}, {
+		name:         "only trusted hops",
+		remoteAddr:   "127.0.0.1:1234",
+		forwardedFor: []string{"127.0.0.1"},
+	}}
+
+	for _, test := range tests {
+		req := httptest.NewRequest("GET", "/v1/getinfo", nil)
+		req.RemoteAddr = test.remoteAddr
+		for _, value := range test.forwardedFor {
+			req.Header.Add("X-Forwarded-For", value)
+		}
+
+		forwardedFor = nil
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		if !reflect.DeepEqual(forwardedFor, test.expected) {
+			t.Fatalf("%v: expected X-Forwarded-For %v, got %v",
+				test.name, test.expected, forwardedFor)
+		}
+	}
+}
+
+// TestOpenChannelFundingAmt asserts that the amount of a chan point shim is
+// used as the local funding amount when the latter is omitted, and that the
+// two must otherwise match.
+func TestOpenChannelFundingAmt(t *testing.T) {
+	t.Parallel()
+
+	shim := &lnrpc.FundingShim{
+		Shim: &lnrpc.FundingShim_ChanPointShim{
+			ChanPointShim: &lnrpc.ChanPointShim{
+				Amt: 100000,
+			},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		req        *lnrpc.OpenChannelRequest
+		fundingAmt dcrutil.Amount
+		fail       bool
+	}{{
+		name: "no shim",
+		req: &lnrpc.OpenChannelRequest{
+			LocalFundingAmount: 50000,
+		},
+		fundingAmt: 50000,
+	}, {
+		name: "amount from shim",
+		req: &lnrpc.OpenChannelRequest{
+			FundingShim: shim,
+		},
+		fundingAmt: 100000,
+	}, {
+		name: "matching amount",
+		req: &lnrpc.OpenChannelRequest{
+			LocalFundingAmount: 100000,
+			FundingShim:        shim,
+		},
+		fundingAmt: 100000,
+	}, {
+		name: "mismatched amount",
+		req: &lnrpc.OpenChannelRequest{
+			LocalFundingAmount: 50000,
+			FundingShim:        shim,
+		},
+		fail: true,
+	}}
+
+	for _, test := range tests {
+		fundingAmt, err := openChannelFundingAmt(test.req)
+		switch {
+		case test.fail && err == nil:
+			t.Fatalf("%v: expected failure", test.name)
+
+		case !test.fail && err != nil:
+			t.Fatalf("%v: unexpected error: %v", test.name, err)
+
+		case fundingAmt != test.fundingAmt:
+			t.Fatalf("%v: expected funding amount %v, got %v",
+				test.name, test.fundingAmt, fundingAmt)
+		}
+	}
+}
+
+// TestMarshalVerifiedBackups asserts that the channel metadata contained in a
+// verified backup is returned to the caller.
+func TestMarshalVerifiedBackups(t *testing.T) {
+	t.Parallel()
+
+	privKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remotePub := privKey.PubKey()
+
+	single := chanbackup.Single{
+		Version:     chanbackup.TweaklessCommitVersion,
+		IsInitiator: true,
+		FundingOutpoint: wire.OutPoint{
+			Hash:  chainhash.Hash{1, 2, 3},
+			Index: 1,
+		},
+		ShortChannelID: lnwire.NewShortChanIDFromInt(1234),
+		RemoteNodePub:  remotePub,
+		Addresses: []net.Addr{&net.TCPAddr{
+			IP:   net.ParseIP("1.2.3.4"),
+			Port: 9735,
+		}},
+		Capacity: 500000,
+	}
+
+	resp := marshalVerifiedBackups([]chanbackup.Single{single})
+	if len(resp.Channels) != 1 {
+		t.Fatalf("expected 1 channel, got %d", len(resp.Channels))
+	}
+
+	channel := resp.Channels[0]
+	txid, err := chainhash.NewHash(channel.ChanPoint.GetFundingTxidBytes())
+	if err != nil {
+		t.Fatalf("unable to parse txid: %v", err)
+	}
+	if *txid != single.FundingOutpoint.Hash ||
+		channel.ChanPoint.OutputIndex != 1 {
+
+		t.Fatalf("unexpected channel point %v", channel.ChanPoint)
+	}
+	if channel.ChanId != 1234 {
+		t.Fatalf("expected chan id 1234, got %d", channel.ChanId)
+	}
+	expectedPub := hex.EncodeToString(remotePub.SerializeCompressed())
+	if channel.RemoteNodePub != expectedPub {
+		t.Fatalf("expected remote node %v, got %v", expectedPub,
+			channel.RemoteNodePub)
+	}
+	if len(channel.Addresses) != 1 ||
+		channel.Addresses[0] != "1.2.3.4:9735" {
+
+		t.Fatalf("unexpected addresses %v", channel.Addresses)
+	}
+	if channel.Capacity != 500000 || !channel.Initiator ||
+		channel.Version != uint32(chanbackup.TweaklessCommitVersion) {
+
+		t.Fatalf("unexpected channel metadata %v", channel)
+	}
+}
+
+// TestResolveBackupExportPath asserts that exported backup files are only
+// written within the configured export directory.
+func TestResolveBackupExportPath(t *testing.T) {
+	t.Parallel()
+
+	exportDir := filepath.Join("/", "data", "exports")
+	liveBackup := filepath.Join(
+		"/", "data", "exports", "live", "channel.backup",
+	)
+
+	tests := []struct {
+		name       string
+		exportDir  string
+		path       string
+		resolved   string
+		shouldFail bool
+	}{{
+		name:       "no export dir",
+		path:       "scb.backup",
+		shouldFail: true,
+	}, {
+		name:      "relative path",
+		exportDir: exportDir,
+		path:      "scb.backup",
+		resolved:  filepath.Join(exportDir, "scb.backup"),
+	}, {
+		name:      "absolute path in sub directory",
+		exportDir: exportDir,
+		path:      filepath.Join(exportDir, "daily", "scb.backup"),
+		resolved:  filepath.Join(exportDir, "daily", "scb.backup"),
+	}, {
+		name:       "escaping relative path",
+		exportDir:  exportDir,
+		path:       filepath.Join("..", "scb.backup"),
+		shouldFail: true,
+	}, {
+		name:       "absolute path outside export dir",
+		exportDir:  exportDir,
+		path:       filepath.Join("/", "etc", "scb.backup"),
+		shouldFail: true,
+	}, {
+		name:       "export dir itself",
+		exportDir:  exportDir,
+		path:       exportDir,
+		shouldFail: true,
+	}, {
+		name:       "live backup directory",
+		exportDir:  exportDir,
+		path:       filepath.Join("live", "scb.backup"),
+		shouldFail: true,
+	}, {
+		name:       "staging file",
+		exportDir:  exportDir,
+		path:       "temp-dont-use.backup",
+		shouldFail: true,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			resolved, err := resolveBackupExportPath(
+				test.exportDir, liveBackup, test.path,
+			)
+			switch {
+			case test.shouldFail && err == nil:
+				t.Fatalf("expected error, resolved %v", resolved)
+
+			case !test.shouldFail && err != nil:
+				t.Fatalf("unexpected error: %v", err)
+
+			case resolved != test.resolved:
+				t.Fatalf("expected %v, got %v", test.resolved,
+					resolved)
+			}
+		})
+	}
+}
+
+// TestResolveBackupExportPathSymlinks asserts that symlinks are resolved
+// before checking whether a backup export path is within the export directory.
+func TestResolveBackupExportPathSymlinks(t *testing.T) {
+	t.Parallel()
+
+	tempDir, err := ioutil.TempDir("", "backupexport")
+	if err != nil {
+		t.Fatalf("unable to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Resolve the temp dir itself, since it might be a symlink on some
+	// platforms.
+	tempDir, err = filepath.EvalSymlinks(tempDir)
+	if err != nil {
+		t.Fatalf("unable to resolve temp dir: %v", err)
+	}
+
+	exportDir := filepath.Join(tempDir, "exports")
+	outsideDir := filepath.Join(tempDir, "outside")
+	for _, dir := range []string{exportDir, outsideDir} {
+		if err := os.Mkdir(dir, 0700); err != nil {
+			t.Fatalf("unable to create dir: %v", err)
+		}
+	}
+	liveBackup := filepath.Join(tempDir, "live", "channel.backup")
+
+	// A symlink within the export dir pointing outside of it must not
+	// allow writing outside of the export dir.
+	escapeLink := filepath.Join(exportDir, "escape")
+	if err := os.Symlink(outsideDir, escapeLink); err != nil {
+		t.Fatalf("unable to create symlink: %v", err)
+	}
+	_, err = resolveBackupExportPath(
+		exportDir, liveBackup, filepath.Join("escape", "scb.backup"),
+	)
+	if err == nil {
+		t.Fatalf("expected path through escaping symlink to fail")
+	}
+
+	// The same applies to a symlinked file.
+	fileLink := filepath.Join(exportDir, "scb.backup")
+	err = os.Symlink(filepath.Join(outsideDir, "scb.backup"), fileLink)
+	if err != nil {
+		t.Fatalf("unable to create symlink: %v", err)
+	}
+	_, err = resolveBackupExportPath(exportDir, liveBackup, "scb.backup")
+	if err == nil {
+		t.Fatalf("expected symlinked file outside export dir to fail")
+	}
+
+	// A symlinked export dir is resolved to its target, so paths within
+	// it are accepted.
+	linkedExportDir := filepath.Join(tempDir, "linked")
+	if err := os.Symlink(exportDir, linkedExportDir); err != nil {
+		t.Fatalf("unable to create symlink: %v", err)
+	}
+	resolved, err := resolveBackupExportPath(
+		linkedExportDir, liveBackup, "daily.backup",
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := filepath.Join(exportDir, "daily.backup")
+	if resolved != expected {
+		t.Fatalf("expected %v, got %v", expected, resolved)
+	}
+}
+
+// TestUnmarshalChanAcceptResponse asserts that inconsistent responses of a
+// channel acceptor client are rejected.
+func TestUnmarshalChanAcceptResponse(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		resp       *lnrpc.ChannelAcceptResponse
+		reject     bool
+		csvDelay   uint16
+		shouldFail bool
+	}{{
+		name:   "rejected without error",
+		resp:   &lnrpc.ChannelAcceptResponse{},
+		reject: true,
+	}, {
+		name: "rejected with error",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Error: "no thanks",
+		},
+		reject: true,
+	}, {
+		name: "accepted with constraints",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Accept:   true,
+			CsvDelay: 144,
+		},
+		csvDelay: 144,
+	}, {
+		name: "accepted with error",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Accept: true,
+			Error:  "no thanks",
+		},
+		shouldFail: true,
+	}, {
+		name: "rejected with constraints",
+		resp: &lnrpc.ChannelAcceptResponse{
+			ReserveAtoms: 1000,
+		},
+		shouldFail: true,
+	}, {
+		name: "error too long",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Error: strings.Repeat("a", maxChanAcceptErrLen+1),
+		},
+		shouldFail: true,
+	}, {
+		name: "csv delay too large",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Accept:   true,
+			CsvDelay: 1 << 16,
+		},
+		shouldFail: true,
+	}, {
+		name: "htlc limit too large",
+		resp: &lnrpc.ChannelAcceptResponse{
+			Accept:       true,
+			MaxHtlcCount: input.MaxHTLCNumber/2 + 1,
+		},
+		shouldFail: true,
+	}}
+
+	for _, test := range tests {
+		test := test
+
+		t.Run(test.name, func(t *testing.T) {
+			resp, err := unmarshalChanAcceptResponse(test.resp)
+			switch {
+			case test.shouldFail && err == nil:
+				t.Fatalf("expected error, got %v", resp)
+
+			case test.shouldFail:
+				return
+
+			case err != nil:
+				t.Fatalf("unexpected error: %v", err)
+
+			case resp.RejectChannel() != test.reject:
+				t.Fatalf("expected reject=%v", test.reject)
+
+			case resp.CSVDelay != test.csvDelay:
+				t.Fatalf("expected csv delay %v, got %v",
+					test.csvDelay, resp.CSVDelay)
+			}
+		})
+	}
+}
+
+// TestChanAcceptorTimeout asserts that a ChannelAcceptor client can only set
+// the timeout of its stream in its first message, up to the maximum timeout.
+func TestChanAcceptorTimeout(t *testing.T) {
+	t.Parallel()
+
+	timeout, err := chanAcceptorTimeout(60, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if timeout != time.Minute {
+		t.Fatalf("expected timeout of %v, got %v", time.Minute,
+			timeout)
+	}
+
+	if _, err := chanAcceptorTimeout(60, false); err == nil {
+		t.Fatalf("expected timeout to be rejected after the first " +
+			"message")
+	}
+
+	maxSeconds := uint32(maxAcceptorTimeout / time.Second)
+	if _, err := chanAcceptorTimeout(maxSeconds, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := chanAcceptorTimeout(maxSeconds+1, true); err == nil {
+		t.Fatalf("expected timeout above the maximum to be rejected")
+	}
+}
+
+// TestSetInvoicePreimage asserts that hold invoices are only created when
+// explicitly requested, and that the payment hash is ignored otherwise.
+func TestSetInvoicePreimage(t *testing.T) {
+	t.Parallel()
+
+	preimage := lntypes.Preimage{1}
+	hash := preimage.Hash()
+
+	testCases := []struct {
+		name         string
+		invoice      *lnrpc.Invoice
+		expectErr    bool
+		expectHash   *lntypes.Hash
+		expectPreimg *lntypes.Preimage
+		expectHold   bool
+	}{{
+		name:    "random preimage",
+		invoice: &lnrpc.Invoice{},
+	}, {
+		name:         "preimage",
+		invoice:      &lnrpc.Invoice{RPreimage: preimage[:]},
+		expectPreimg: &preimage,
+	}, {
+		name:    "hash without hold",
+		invoice: &lnrpc.Invoice{RHash: hash[:]},
+	}, {
+		name:       "hold invoice",
+		invoice:    &lnrpc.Invoice{RHash: hash[:], Hold: true},
+		expectHash: &hash,
+		expectHold: true,
+	}, {
+		name:      "hold invoice without hash",
+		invoice:   &lnrpc.Invoice{Hold: true},
+		expectErr: true,
+	}, {
+		name: "hold invoice with preimage",
+		invoice: &lnrpc.Invoice{
+			RPreimage: preimage[:],
+			RHash:     hash[:],
+			Hold:      true,
+		},
+		expectErr: true,
+	}}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := &invoicesrpc.AddInvoiceData{}
+			err := setInvoicePreimage(test.invoice, data)
+			if test.expectErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(data.Hash, test.expectHash) {
+				t.Fatalf("expected hash %v, got %v",
+					test.expectHash, data.Hash)
+			}
+			if !reflect.DeepEqual(data.Preimage, test.expectPreimg) {
+				t.Fatalf("expected preimage %v, got %v",
+					test.expectPreimg, data.Preimage)
+			}
+			if data.HodlInvoice != test.expectHold {
+				t.Fatalf("expected hold %v, got %v",
+					test.expectHold, data.HodlInvoice)
+			}
+		})
+	}
+}
+
+// TestNodeChannelsPage asserts that the channels of a node returned by
+// GetNodeInfo are filtered and paginated as requested, while the statistics
+// account for all of them.
+func TestNodeChannelsPage(t *testing.T) {
+	t.Parallel()
+
+	selfNode := route.Vertex{1}
+	newEdge := func(id uint64, node1, node2 route.Vertex,
+		announced bool) *channeldb.ChannelEdgeInfo {
+
+		edge := &channeldb.ChannelEdgeInfo{
+			ChannelID:     id,
+			NodeKey1Bytes: node1,
+			NodeKey2Bytes: node2,
+			Capacity:      dcrutil.Amount(id * 1000),
+		}
+		if announced {
+			edge.AuthProof = &channeldb.ChannelAuthProof{}
+		}
+		return edge
+	}
+	edges := []*channeldb.ChannelEdgeInfo{
+		newEdge(1, selfNode, route.Vertex{2}, true),
+		newEdge(2, selfNode, route.Vertex{2}, false),
+		newEdge(3, route.Vertex{2}, route.Vertex{3}, true),
+		newEdge(4, route.Vertex{2}, route.Vertex{4}, true),
+	}
+
+	tests := []struct {
+		name       string
+		req        *lnrpc.NodeInfoRequest
+		channelIDs []uint64
+		nextOffset uint32
+	}{{
+		name: "no channels",
+		req:  &lnrpc.NodeInfoRequest{},
+	}, {
+		name:       "announced channels",
+		req:        &lnrpc.NodeInfoRequest{IncludeChannels: true},
+		channelIDs: []uint64{1, 3, 4},
+	}, {
+		name: "first page",
+		req: &lnrpc.NodeInfoRequest{
+			IncludeChannels: true,
+			MaxChannels:     2,
+		},
+		channelIDs: []uint64{1, 3},
+		nextOffset: 2,
+	}, {
+		name: "last page",
+		req: &lnrpc.NodeInfoRequest{
+			IncludeChannels: true,
+			MaxChannels:     2,
+			ChannelsOffset:  2,
+		},
+		channelIDs: []uint64{4},
+	}, {
+		name: "shared channels",
+		req: &lnrpc.NodeInfoRequest{
+			SharedChannelsOnly: true,
+		},
+		channelIDs: []uint64{1, 2},
+	}}
+
+	for _, test := range tests {
+		page := &nodeChannelsPage{req: test.req, selfNode: selfNode}
+		for _, edge := range edges {
+			page.addChannel(edge, nil, nil)
+		}
+
+		var channelIDs []uint64
+		for _, channel := range page.channels {
+			channelIDs = append(channelIDs, channel.ChannelId)
+		}
+		if !reflect.DeepEqual(channelIDs, test.channelIDs) {
+			t.Fatalf("%v: expected channels %v, got %v", test.name,
+				test.channelIDs, channelIDs)
+		}
+		if page.nextOffset != test.nextOffset {
+			t.Fatalf("%v: expected next offset %v, got %v",
+				test.name, test.nextOffset, page.nextOffset)
+		}
+
+		if page.numChannels != 4 || page.totalCapacity != 10000 ||
+			page.announcedCapacity != 8000 ||
+			page.unannouncedCapacity != 2000 {
+
+			t.Fatalf("%v: unexpected statistics: %d channels, "+
+				"capacity %v (announced %v, unannounced %v)",
+				test.name, page.numChannels, page.totalCapacity,
+				page.announcedCapacity,
+				page.unannouncedCapacity)
+		}
+	}
+}
+
+// mockBestBlockWallet is a wallet reporting only its best block.
+type mockBestBlockWallet struct {
+	lnwallet.WalletController
+
+	height int64
+	err    error
+}
+
+func (m *mockBestBlockWallet) BestBlock() (int64, chainhash.Hash, int64,
+	error) {
+
+	return m.height, chainhash.Hash{}, 0, m.err
+}
+
+// mockSyncReporterWallet is a wallet reporting the details of its
+// synchronization.
+type mockSyncReporterWallet struct {
+	lnwallet.WalletController
+
+	syncInfo lnwallet.ChainSyncInfo
+	err      error
+}
+
+func (m *mockSyncReporterWallet) ChainSyncInfo() (*lnwallet.ChainSyncInfo,
+	error) {
+
+	if m.err != nil {
+		return nil, m.err
+	}
+
+	syncInfo := m.syncInfo
+	return &syncInfo, nil
+}
+
+// TestChainSyncInfo asserts that the number of blocks the wallet still needs
+// to process is estimated from the most accurate source available, and that
+// failing to get the sync details doesn't fail the caller.
+func TestChainSyncInfo(t *testing.T) {
+	t.Parallel()
+
+	blockTime := activeNetParams.TargetTimePerBlock
+	now := time.Now()
+
+	tests := []struct {
+		name                string
+		wc                  lnwallet.WalletController
+		bestHeight          int32
+		isSynced            bool
+		bestHeaderTimestamp int64
+		syncInfo            lnwallet.ChainSyncInfo
+		blocksRemaining     uint32
+	}{{
+		name:       "synced",
+		wc:         &mockBestBlockWallet{height: 100},
+		bestHeight: 90,
+		isSynced:   true,
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 100,
+			BlocksHeight:  100,
+		},
+	}, {
+		name:       "behind chain backend",
+		wc:         &mockBestBlockWallet{height: 90},
+		bestHeight: 100,
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 90,
+			BlocksHeight:  90,
+		},
+		blocksRemaining: 10,
+	}, {
+		name: "behind fetched headers",
+		wc: &mockSyncReporterWallet{
+			syncInfo: lnwallet.ChainSyncInfo{
+				HeadersHeight: 120,
+				BlocksHeight:  95,
+				RescanHeight:  95,
+			},
+		},
+		bestHeight: 100,
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 120,
+			BlocksHeight:  95,
+			RescanHeight:  95,
+		},
+		blocksRemaining: 25,
+	}, {
+		name: "chain backend syncing",
+		wc: &mockSyncReporterWallet{
+			syncInfo: lnwallet.ChainSyncInfo{
+				HeadersHeight: 100,
+				BlocksHeight:  100,
+			},
+		},
+		bestHeight: 100,
+		bestHeaderTimestamp: now.Add(
+			-10*blockTime - blockTime/2,
+		).Unix(),
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 100,
+			BlocksHeight:  100,
+		},
+		blocksRemaining: 10,
+	}, {
+		name:                "best header in the future",
+		wc:                  &mockBestBlockWallet{height: 100},
+		bestHeight:          100,
+		bestHeaderTimestamp: now.Add(time.Hour).Unix(),
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 100,
+			BlocksHeight:  100,
+		},
+	}, {
+		name: "sync details unavailable",
+		wc: &mockSyncReporterWallet{
+			WalletController: &mockBestBlockWallet{height: 90},
+			err:              errors.New("sync info failure"),
+		},
+		bestHeight: 100,
+		syncInfo: lnwallet.ChainSyncInfo{
+			HeadersHeight: 90,
+			BlocksHeight:  90,
+		},
+		blocksRemaining: 10,
+	}, {
+		name: "best block unavailable",
+		wc: &mockBestBlockWallet{
+			err: errors.New("best block failure"),
+		},
+		bestHeight: 100,
+	}}
+
+	for _, test := range tests {
+		r := &rpcServer{
+			server: &server{cc: &chainControl{wc: test.wc}},
+		}
+		syncInfo, blocksRemaining := r.chainSyncInfo(
+			test.bestHeight, test.isSynced,
+			test.bestHeaderTimestamp,
+		)
+		if *syncInfo != test.syncInfo {
+			t.Fatalf("%v: expected sync info %v, got %v", test.name,
+				test.syncInfo, *syncInfo)
+		}
+		if blocksRemaining != test.blocksRemaining {
+			t.Fatalf("%v: expected %d blocks remaining, got %d",
+				test.name, test.blocksRemaining, blocksRemaining)
+		}
+	}
+}
+
+// mockTrackPaymentStream is a TrackPayment stream collecting the payment
+// updates sent to the client.
+type mockTrackPaymentStream struct {
+	lnrpc.Lightning_TrackPaymentServer
+
+	ctx     context.Context
+	updates []*lnrpc.Payment
+	onSend  func()
+}
+
+func (s *mockTrackPaymentStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockTrackPaymentStream) Send(payment *lnrpc.Payment) error {
+	s.updates = append(s.updates, payment)
+	if s.onSend != nil {
+		s.onSend()
+	}
+	return nil
+}
+
+// TestTrackPayment asserts that TrackPayment streams the state of a payment
+// from the control tower and stops when the server shuts down.
+func TestTrackPayment(t *testing.T) {
+	t.Parallel()
+
+	db, cleanup, err := channeldb.MakeTestDB()
+	if err != nil {
+		t.Fatalf("unable to create db: %v", err)
+	}
+	defer cleanup()
+
+	control := routing.NewControlTower(channeldb.NewPaymentControl(db))
+	r := &rpcServer{
+		routerBackend: &routerrpc.RouterBackend{
+			Tower: control,
+			FetchChannelCapacity: func(uint64) (dcrutil.Amount,
+				error) {
+
+				return 0, nil
+			},
+		},
+		quit: make(chan struct{}),
+	}
+
+	track := func(hash lntypes.Hash, noInflightUpdates bool,
+		onSend func()) (*mockTrackPaymentStream, error) {
+
+		stream := &mockTrackPaymentStream{
+			ctx:    context.Background(),
+			onSend: onSend,
+		}
+		err := r.TrackPayment(&lnrpc.TrackPaymentRequest{
+			PaymentHash:       hash[:],
+			NoInflightUpdates: noInflightUpdates,
+		}, stream)
+
+		return stream, err
+	}
+
+	// Tracking an unknown payment fails.
+	_, err = track(lntypes.Hash{9}, false, nil)
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	// A completed payment is streamed once and the stream is closed.
+	var self route.Vertex
+	preimage := lntypes.Preimage{1, 2, 3}
+	err = recordFixturePayment(control, self, paymentFixture{
+		Preimage:     hex.EncodeToString(preimage[:]),
+		Value:        1000,
+		Dest:         testFixtureDest,
+		CreationDate: 1600000000,
+	}, time.Now())
+	if err != nil {
+		t.Fatalf("unable to record payment: %v", err)
+	}
+
+	stream, err := track(preimage.Hash(), false, nil)
+	if err != nil {
+		t.Fatalf("unable to track payment: %v", err)
+	}
+	if len(stream.updates) != 1 ||
+		stream.updates[0].Status != lnrpc.Payment_SUCCEEDED {
+
+		t.Fatalf("expected a single succeeded update, got %v",
+			stream.updates)
+	}
+
+	// The state of a payment in flight is streamed, and tracking stops
+	// when the server shuts down.
+	inflightHash := lntypes.Hash{4, 5, 6}
+	err = control.InitPayment(inflightHash, &channeldb.PaymentCreationInfo{
+		PaymentHash:  inflightHash,
+		Value:        1000,
+		CreationTime: time.Now(),
+	})
+	if err != nil {
+		t.Fatalf("unable to init payment: %v", err)
+	}
+
+	stream, err = track(inflightHash, false, func() { close(r.quit) })
+	if err != ErrServerShuttingDown {
+		t.Fatalf("expected ErrServerShuttingDown, got %v", err)
+	}
+	if len(stream.updates) != 1 ||
+		stream.updates[0].Status != lnrpc.Payment_IN_FLIGHT {
+
+		t.Fatalf("expected a single in flight update, got %v",
+			stream.updates)
+	}
+
+	// The in-flight updates can be suppressed.
+	stream, err = track(inflightHash, true, nil)
+	if err != ErrServerShuttingDown {
+		t.Fatalf("expected ErrServerShuttingDown, got %v", err)
+	}
+	if len(stream.updates) != 0 {
+		t.Fatalf("expected no in flight updates, got %v",
+			stream.updates)
+	}
+}
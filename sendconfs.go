@@ -0,0 +1,180 @@
+package dcrlnd
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/decred/dcrd/chaincfg/chainhash"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/chainntnfs"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// maxSendConfRegistrations is the maximum number of confirmation
+// registrations of sent transactions that are kept without a subscriber. Once
+// exceeded, the oldest registration is canceled.
+const maxSendConfRegistrations = 1000
+
+// sendConfRegistration is a confirmation notification registered for a
+// transaction sent through SendCoins or SendMany.
+type sendConfRegistration struct {
+	txid  chainhash.Hash
+	event *chainntnfs.ConfirmationEvent
+}
+
+// sendConfRegistry keeps track of the confirmation registrations of sent
+// transactions until they're consumed by a subscriber.
+type sendConfRegistry struct {
+	mu     sync.Mutex
+	nextID uint64
+	regs   map[uint64]*sendConfRegistration
+}
+
+// newSendConfRegistry creates an empty registry.
+func newSendConfRegistry() *sendConfRegistry {
+	return &sendConfRegistry{
+		nextID: 1,
+		regs:   make(map[uint64]*sendConfRegistration),
+	}
+}
+
+// add stores a registration, returning its ID.
+func (s *sendConfRegistry) add(reg *sendConfRegistration) uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := s.nextID
+	s.nextID++
+	s.regs[id] = reg
+
+	// IDs are increasing, so the lowest one belongs to the oldest
+	// registration.
+	if len(s.regs) > maxSendConfRegistrations {
+		oldest := id
+		for regID := range s.regs {
+			if regID < oldest {
+				oldest = regID
+			}
+		}
+		s.regs[oldest].event.Cancel()
+		delete(s.regs, oldest)
+	}
+
+	return id
+}
+
+// take removes and returns the registration with the given ID.
+func (s *sendConfRegistry) take(id uint64) (*sendConfRegistration, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reg, ok := s.regs[id]
+	delete(s.regs, id)
+	return reg, ok
+}
+
+// restore puts back a registration taken by a subscriber that went away
+// before the transaction confirmed.
+func (s *sendConfRegistry) restore(id uint64, reg *sendConfRegistration) {
+	s.mu.Lock()
+	s.regs[id] = reg
+	s.mu.Unlock()
+}
+
+// cancelAll cancels all registrations without a subscriber.
+func (s *sendConfRegistry) cancelAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for id, reg := range s.regs {
+		reg.event.Cancel()
+		delete(s.regs, id)
+	}
+}
+
+// registerSendConf registers a notification for the given sent transaction
+// reaching numConfs confirmations, returning the ID of the registration.
+func (r *rpcServer) registerSendConf(tx *wire.MsgTx,
+	numConfs uint32) (uint64, error) {
+
+	if len(tx.TxOut) == 0 {
+		return 0, fmt.Errorf("transaction has no outputs")
+	}
+
+	_, bestHeight, err := r.server.cc.chainIO.GetBestBlock()
+	if err != nil {
+		return 0, err
+	}
+
+	txid := tx.TxHash()
+	event, err := r.server.cc.chainNotifier.RegisterConfirmationsNtfn(
+		&txid, tx.TxOut[0].PkScript, numConfs, uint32(bestHeight),
+	)
+	if err != nil {
+		return 0, err
+	}
+
+	return r.sendConfs.add(&sendConfRegistration{
+		txid:  txid,
+		event: event,
+	}), nil
+}
+
+// SubscribeSendConfirmation streams the confirmation progress of a transaction
+// sent through SendCoins or SendMany with a confirmation notification.
+func (r *rpcServer) SubscribeSendConfirmation(
+	req *lnrpc.SendConfirmationSubscription,
+	updateStream lnrpc.Lightning_SubscribeSendConfirmationServer) error {
+
+	reg, ok := r.sendConfs.take(req.ConfSubscriptionId)
+	if !ok {
+		return fmt.Errorf("unknown confirmation subscription %d",
+			req.ConfSubscriptionId)
+	}
+
+	// Unless the transaction confirmed, the registration is kept so the
+	// caller can subscribe again.
+	confirmed := false
+	defer func() {
+		if !confirmed {
+			r.sendConfs.restore(req.ConfSubscriptionId, reg)
+		}
+	}()
+
+	for {
+		select {
+		case confsLeft := <-reg.event.Updates:
+			// The final update is followed by the confirmation.
+			if confsLeft == 0 {
+				continue
+			}
+
+			err := updateStream.Send(&lnrpc.SendConfirmationEvent{
+				Txid:      reg.txid.String(),
+				ConfsLeft: confsLeft,
+			})
+			if err != nil {
+				return err
+			}
+
+		case conf, ok := <-reg.event.Confirmed:
+			if !ok {
+				return ErrServerShuttingDown
+			}
+			confirmed = true
+
+			return updateStream.Send(&lnrpc.SendConfirmationEvent{
+				Txid:        reg.txid.String(),
+				Confirmed:   true,
+				BlockHash:   conf.BlockHash.String(),
+				BlockHeight: conf.BlockHeight,
+			})
+
+		case <-updateStream.Context().Done():
+			return updateStream.Context().Err()
+
+		case <-r.quit:
+			return ErrServerShuttingDown
+		}
+	}
+}
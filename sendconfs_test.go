@@ -0,0 +1,57 @@
+package dcrlnd
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/chainntnfs"
+)
+
+// TestSendConfRegistry asserts that registrations can be taken and restored
+// and that the oldest registration is canceled once the registry is full.
+func TestSendConfRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := newSendConfRegistry()
+
+	var canceled []int
+	newReg := func(i int) *sendConfRegistration {
+		return &sendConfRegistration{
+			event: chainntnfs.NewConfirmationEvent(1, func() {
+				canceled = append(canceled, i)
+			}),
+		}
+	}
+
+	firstID := registry.add(newReg(0))
+	reg, ok := registry.take(firstID)
+	if !ok {
+		t.Fatalf("registration %d not found", firstID)
+	}
+	if _, ok := registry.take(firstID); ok {
+		t.Fatalf("registration %d taken twice", firstID)
+	}
+	registry.restore(firstID, reg)
+
+	for i := 1; i < maxSendConfRegistrations; i++ {
+		registry.add(newReg(i))
+	}
+	if len(canceled) != 0 {
+		t.Fatalf("unexpected canceled registrations %v", canceled)
+	}
+
+	// Exceeding the limit cancels the oldest registration.
+	registry.add(newReg(maxSendConfRegistrations))
+	if len(canceled) != 1 || canceled[0] != 0 {
+		t.Fatalf("expected first registration to be canceled, got %v",
+			canceled)
+	}
+	if _, ok := registry.take(firstID); ok {
+		t.Fatalf("canceled registration still present")
+	}
+
+	registry.cancelAll()
+	if len(canceled) != maxSendConfRegistrations+1 {
+		t.Fatalf("expected all registrations to be canceled, got %d",
+			len(canceled))
+	}
+}
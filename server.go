@@ -13,6 +13,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -35,9 +36,12 @@ import (
 	"github.com/decred/dcrlnd/contractcourt"
 	"github.com/decred/dcrlnd/discovery"
 	"github.com/decred/dcrlnd/feature"
+	"github.com/decred/dcrlnd/feecontrol"
+	"github.com/decred/dcrlnd/feeschedule"
 	"github.com/decred/dcrlnd/healthcheck"
 	"github.com/decred/dcrlnd/htlcswitch"
 	"github.com/decred/dcrlnd/htlcswitch/hop"
+	"github.com/decred/dcrlnd/htlcswitch/reputation"
 	"github.com/decred/dcrlnd/input"
 	"github.com/decred/dcrlnd/invoices"
 	"github.com/decred/dcrlnd/keychain"
@@ -55,6 +59,7 @@ import (
 	"github.com/decred/dcrlnd/peernotifier"
 	"github.com/decred/dcrlnd/pool"
 	"github.com/decred/dcrlnd/queue"
+	"github.com/decred/dcrlnd/reorgnotifier"
 	"github.com/decred/dcrlnd/routing"
 	"github.com/decred/dcrlnd/routing/localchans"
 	"github.com/decred/dcrlnd/routing/route"
@@ -133,6 +138,11 @@ type server struct {
 	active   int32 // atomic
 	stopping int32 // atomic
 
+	// outgoingCltvRejectDelta is the currently effective number of blocks
+	// before the expiry of an htlc at which we no longer offer it to the
+	// next peer.
+	outgoingCltvRejectDelta uint32 // atomic
+
 	start sync.Once
 	stop  sync.Once
 
@@ -158,6 +168,10 @@ type server struct {
 	// creating and setting up onion services, etc.
 	torController *tor.Controller
 
+	// onionAddr is the address of the onion service created for the node
+	// through the torController, if any.
+	onionAddr *tor.OnionAddr
+
 	// natTraversal is the specific NAT traversal technique used to
 	// automatically set up port forwarding rules in order to advertise to
 	// the network that the node is accepting inbound connections.
@@ -219,8 +233,18 @@ type server struct {
 
 	peerNotifier *peernotifier.PeerNotifier
 
+	reorgNotifier *reorgnotifier.ReorgNotifier
+
+	// chanScripts indexes the output scripts of our channels, to detect
+	// third party payments to them.
+	chanScripts *chanScriptIndex
+
 	htlcNotifier *htlcswitch.HtlcNotifier
 
+	// htlcEventStore persists the events of the htlcNotifier. It is nil
+	// unless enabled.
+	htlcEventStore *routerrpc.HtlcEventStore
+
 	witnessBeacon contractcourt.WitnessBeacon
 
 	breachArbiter *breachArbiter
@@ -275,11 +299,27 @@ type server struct {
 	// provide insights into their health and performance.
 	chanEventStore *chanfitness.ChannelEventStore
 
+	// feeController adjusts the fee rate of our channels based on their
+	// liquidity skew and recent payment flow. It is nil unless enabled.
+	feeController *feecontrol.Controller
+
+	// feeSchedules applies the fee schedules set by the operator to our
+	// channels as their balance changes. It is nil if the fee controller
+	// is enabled, as both would fight over the fees of our channels.
+	feeSchedules *feeschedule.Manager
+
+	// dbCompactor periodically removes stale forwarding data from the
+	// channel database. It is nil if compaction is disabled.
+	dbCompactor *dbCompactor
+
 	hostAnn *netann.HostAnnouncer
 
 	// livelinessMonitor monitors that lnd has access to critical resources.
 	livelinessMonitor *healthcheck.Monitor
 
+	// shutdownProgress tracks the progress of the server's shutdown.
+	shutdownProgress *shutdownProgress
+
 	quit chan struct{}
 
 	wg sync.WaitGroup
@@ -337,18 +377,13 @@ func noiseDial(idKey keychain.SingleKeyECDH,
 func newServer(cfg *Config, listenAddrs []net.Addr,
 	localChanDB, remoteChanDB *channeldb.DB,
 	towerClientDB *wtdb.ClientDB, cc *chainControl,
-	nodeKeyDesc *keychain.KeyDescriptor,
+	nodeKeyECDH keychain.SingleKeyECDH,
+	nodeKeySigner keychain.SingleKeyDigestSigner,
 	chansToRestore walletunlocker.ChannelsToRecover,
 	chanPredicate chanacceptor.ChannelAcceptor,
 	torController *tor.Controller) (*server, error) {
 
-	var (
-		err           error
-		nodeKeyECDH   = keychain.NewPubKeyECDH(*nodeKeyDesc, cc.keyRing)
-		nodeKeySigner = keychain.NewPubKeyDigestSigner(
-			*nodeKeyDesc, cc.keyRing,
-		)
-	)
+	var err error
 
 	listeners := make([]net.Listener, len(listenAddrs))
 	for i, listenAddr := range listenAddrs {
@@ -406,14 +441,21 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 	}
 
 	registryConfig := invoices.RegistryConfig{
-		FinalCltvRejectDelta: lncfg.DefaultFinalCltvRejectDelta,
+		FinalCltvRejectDelta: int32(cfg.FinalCltvRejectDelta),
 		HtlcHoldDuration:     invoices.DefaultHtlcHoldDuration,
 		Clock:                clock.NewDefaultClock(),
 		AcceptKeySend:        cfg.AcceptKeySend,
 		KeysendHoldTime:      cfg.KeysendHoldTime,
+		KeysendCltvDelta:     int32(cfg.Invoices.Keysend.CltvDelta),
+		SettleApprovalThreshold: lnwire.NewMAtomsFromAtoms(
+			dcrutil.Amount(cfg.Invoices.SettleApprovalThreshold),
+		),
+		Notifier: cc.chainNotifier,
 	}
 
 	s := &server{
+		outgoingCltvRejectDelta: cfg.OutgoingCltvRejectDelta,
+
 		cfg:            cfg,
 		localChanDB:    localChanDB,
 		remoteChanDB:   remoteChanDB,
@@ -455,8 +497,9 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		peerConnectedListeners:    make(map[string][]chan<- lnpeer.Peer),
 		peerDisconnectedListeners: make(map[string][]chan<- struct{}),
 
-		featureMgr: featureMgr,
-		quit:       make(chan struct{}),
+		featureMgr:       featureMgr,
+		shutdownProgress: newShutdownProgress(),
+		quit:             make(chan struct{}),
 	}
 
 	s.witnessBeacon = &preimageBeacon{
@@ -471,6 +514,40 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 
 	s.htlcNotifier = htlcswitch.NewHtlcNotifier(time.Now)
 
+	if cfg.HtlcEvents.Store {
+		s.htlcEventStore = routerrpc.NewHtlcEventStore(
+			&routerrpc.HtlcEventStoreConfig{
+				Log:                 remoteChanDB.HtlcEventLog(),
+				MaxAge:              cfg.HtlcEvents.MaxAge,
+				MaxCount:            cfg.HtlcEvents.MaxCount,
+				SubscribeHtlcEvents: s.htlcNotifier.SubscribeHtlcEvents,
+			},
+		)
+	}
+
+	// If enabled, build the reputation of our peers from our forwarding
+	// history, so that the htlcs of unreputable peers can only occupy a
+	// share of our channels.
+	var htlcReputation *reputation.Tracker
+	if cfg.HtlcReputation.Active {
+		htlcReputation = reputation.NewTracker(&reputation.Config{
+			Policy: reputation.Policy{
+				BucketPercent: cfg.HtlcReputation.BucketPercent,
+				MinRevenue: lnwire.MilliAtom(
+					cfg.HtlcReputation.MinRevenue,
+				),
+				Window: cfg.HtlcReputation.Window,
+			},
+			Clock:                clock.NewDefaultClock(),
+			FetchAllOpenChannels: remoteChanDB.FetchAllOpenChannels,
+			FetchClosedChannels:  remoteChanDB.FetchClosedChannels,
+			QueryForwardingLog:   remoteChanDB.ForwardingLog().Query,
+		})
+		if err := htlcReputation.LoadHistory(); err != nil {
+			return nil, err
+		}
+	}
+
 	s.htlcSwitch, err = htlcswitch.New(htlcswitch.Config{
 		DB: remoteChanDB,
 		LocalChannelClose: func(pubKey []byte,
@@ -500,6 +577,8 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		RejectHTLC:             cfg.RejectHTLC,
 		Clock:                  clock.NewDefaultClock(),
 		HTLCExpiry:             htlcswitch.DefaultHTLCExpiry,
+		HoldFeePolicy:          cfg.HoldFee.Policy(),
+		HtlcReputation:         htlcReputation,
 	}, uint32(currentHeight))
 	if err != nil {
 		return nil, err
@@ -757,7 +836,11 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		AssumeChannelValid: cfg.Routing.UseAssumeChannelValid(),
 		NextPaymentID:      sequencer.NextID,
 		PathFindingConfig:  pathFindingConfig,
-		Clock:              clock.NewDefaultClock(),
+		FirstHopRetry: routing.FirstHopRetryPolicy{
+			MaxRetries: routingConfig.FirstHopRetries,
+			Backoff:    routingConfig.FirstHopRetryBackoff,
+		},
+		Clock: clock.NewDefaultClock(),
 	})
 	if err != nil {
 		return nil, fmt.Errorf("can't create router: %v", err)
@@ -827,11 +910,21 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		return nil, err
 	}
 
+	// A wallet that can be locked while the node is running must not have
+	// its outputs added to sweeps while it's locked.
+	var sweeperWallet sweep.Wallet = cc.wallet
+	if wallet, ok := cc.wc.(lockableWallet); ok {
+		sweeperWallet = &lockAwareSweeperWallet{
+			Wallet: cc.wallet,
+			wallet: wallet,
+		}
+	}
+
 	s.sweeper = sweep.New(&sweep.UtxoSweeperConfig{
 		FeeEstimator:   cc.feeEstimator,
 		GenSweepScript: newSweepPkScriptGen(cc.wallet),
 		Signer:         cc.wallet.Cfg.Signer,
-		Wallet:         cc.wallet,
+		Wallet:         sweeperWallet,
 		NewBatchTimer: func() <-chan time.Time {
 			return time.NewTimer(sweep.DefaultBatchWindowDuration).C
 		},
@@ -845,6 +938,35 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		FeeRateBucketSize:    sweep.DefaultFeeRateBucketSize,
 	})
 
+	s.chanScripts = newChanScriptIndex(
+		remoteChanDB, cc.chainIO, cc.wallet.IsOurAddress,
+		activeNetParams.Params,
+		s.channelNotifier.SubscribeChannelEvents,
+	)
+
+	s.reorgNotifier = reorgnotifier.New(reorgnotifier.Config{
+		Notifier:            cc.chainNotifier,
+		ChainIO:             cc.chainIO,
+		FetchAllChannels:    remoteChanDB.FetchAllChannels,
+		FetchClosedChannels: remoteChanDB.FetchClosedChannels,
+		IsSweepTx:           sweeperStore.IsOurTx,
+		UpdateCloseHeight:   remoteChanDB.UpdateCloseHeight,
+		UpdateShortChanID: func(chanPoint *wire.OutPoint,
+			shortChanID lnwire.ShortChannelID) error {
+
+			// The channel's link keeps forwarding under the old
+			// short channel ID until the channel is reloaded.
+			channel, err := remoteChanDB.FetchChannel(*chanPoint)
+			if err != nil {
+				return err
+			}
+
+			return channel.MarkAsOpen(shortChanID)
+		},
+		FetchChannelScripts: s.chanScripts.fetch,
+		HistoryDepth:        reorgnotifier.DefaultHistoryDepth,
+	})
+
 	s.utxoNursery = newUtxoNursery(&NurseryConfig{
 		ChainIO:             cc.chainIO,
 		ConfDepth:           1,
@@ -862,10 +984,22 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		// TODO(conner): Properly respect the update and error channels
 		// returned by CloseLink.
 
+		// A breach is recorded as the close reason of the channel
+		// whether or not the peer is online.
+		if closureType == htlcswitch.CloseBreach {
+			s.channelNotifier.NotifyCloseReasonEvent(
+				*chanPoint, &channeldb.ChannelCloseReason{
+					Code:            channeldb.CloseReasonBreach,
+					RemoteInitiated: true,
+					Timestamp:       time.Now(),
+				},
+			)
+		}
+
 		// Instruct the switch to close the channel.  Provide no close out
 		// delivery script or target fee per kw because user input is not
 		// available when the remote peer closes the channel.
-		s.htlcSwitch.CloseLink(chanPoint, closureType, 0, nil)
+		s.htlcSwitch.CloseLink(chanPoint, closureType, 0, 0, nil)
 	}
 
 	// We will use the following channel to reliably hand off contract
@@ -1039,6 +1173,12 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 				return defaultConf
 			}
 
+			// Otherwise, if the user has configured a tier for
+			// channels of this size, we use its value.
+			if numConfs, ok := cfg.ChanConfs.NumConfs(chanAmt); ok {
+				return numConfs
+			}
+
 			minConf := uint64(3)
 			maxConf := uint64(6)
 
@@ -1063,6 +1203,14 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 			}
 			return uint16(conf)
 		},
+		InitiatorNumRequiredConfs: func(chanAmt dcrutil.Amount) uint16 {
+			// When we're the initiator, the remote party chooses
+			// the number of confirmations. We'll only wait for
+			// more of them if the user has configured a tier for
+			// channels of this size.
+			numConfs, _ := cfg.ChanConfs.NumConfs(chanAmt)
+			return numConfs
+		},
 		RequiredRemoteDelay: func(chanAmt dcrutil.Amount) uint16 {
 			// We scale the remote CSV delay (the time the
 			// remote have to claim funds in case of a unilateral
@@ -1155,6 +1303,8 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		MinChanSize:                   dcrutil.Amount(cfg.MinChanSize),
 		MaxChanSize:                   dcrutil.Amount(cfg.MaxChanSize),
 		MaxPendingChannels:            cfg.MaxPendingChannels,
+		MaxGlobalPendingChannels:      cfg.MaxGlobalPendingChannels,
+		SubscribeChannelEvents:        s.channelNotifier.SubscribeChannelEvents,
 		RejectPush:                    cfg.RejectPush,
 		NotifyOpenChannelEvent:        s.channelNotifier.NotifyOpenChannelEvent,
 		OpenChannelPredicate:          chanPredicate,
@@ -1195,6 +1345,59 @@ func newServer(cfg *Config, listenAddrs []net.Addr,
 		GetOpenChannels:        s.remoteChanDB.FetchAllOpenChannels,
 	})
 
+	// If enabled, create the fee controller which will periodically adjust
+	// the fee rate of our channels.
+	if cfg.FeeControl.Active {
+		s.feeController = feecontrol.New(&feecontrol.Config{
+			Curve: feecontrol.Curve{
+				MinFeeRate: cfg.FeeControl.MinFeeRate,
+				MaxFeeRate: cfg.FeeControl.MaxFeeRate,
+				Exponent:   cfg.FeeControl.Exponent,
+				FlowWeight: cfg.FeeControl.FlowWeight,
+			},
+			Interval:               cfg.FeeControl.Interval,
+			MinChange:              cfg.FeeControl.MinChange,
+			Ticker:                 ticker.New(cfg.FeeControl.Interval),
+			Clock:                  clock.NewDefaultClock(),
+			FetchAllOpenChannels:   s.remoteChanDB.FetchAllOpenChannels,
+			ForAllOutgoingChannels: s.chanRouter.ForAllOutgoingChannels,
+			QueryForwardingLog:     s.remoteChanDB.ForwardingLog().Query,
+			UpdatePolicy:           s.localChanMgr.UpdatePolicy,
+		})
+	} else {
+		s.feeSchedules = feeschedule.New(&feeschedule.Config{
+			Ticker: ticker.New(
+				feeschedule.DefaultEvaluationInterval,
+			),
+			Hysteresis:             feeschedule.DefaultHysteresis,
+			FetchFeeSchedules:      s.remoteChanDB.FetchFeeSchedules,
+			PutFeeSchedule:         s.remoteChanDB.PutFeeSchedule,
+			DeleteFeeSchedule:      s.remoteChanDB.DeleteFeeSchedule,
+			FetchClosedChannel:     s.remoteChanDB.FetchClosedChannel,
+			FetchAllOpenChannels:   s.remoteChanDB.FetchAllOpenChannels,
+			ForAllOutgoingChannels: s.chanRouter.ForAllOutgoingChannels,
+			UpdatePolicy:           s.localChanMgr.UpdatePolicy,
+			SubscribeHtlcEvents:    s.htlcNotifier.SubscribeHtlcEvents,
+			SubscribeChannelEvents: s.channelNotifier.SubscribeChannelEvents,
+		})
+	}
+
+	// If enabled, create the compactor which will periodically remove
+	// fully acked forwarding packages and resolved circuits.
+	if cfg.DB.Compaction.Interval != 0 {
+		s.dbCompactor = newDBCompactor(&dbCompactorConfig{
+			Retention: cfg.DB.Compaction.Retention,
+			Ticker:    ticker.New(cfg.DB.Compaction.Interval),
+			BestHeight: func() (uint32, error) {
+				_, height, err := s.cc.chainIO.GetBestBlock()
+				return uint32(height), err
+			},
+			FetchClosedChannels: s.remoteChanDB.FetchClosedChannels,
+			CompactFwdPkgs:      s.remoteChanDB.CompactFwdPkgs,
+			CompactCircuits:     s.htlcSwitch.CompactCircuits,
+		})
+	}
+
 	if cfg.WtClient.Active {
 		policy := wtpolicy.DefaultPolicy()
 
@@ -1343,6 +1546,16 @@ func (s *server) Start() error {
 			go s.watchExternalIP()
 		}
 
+		if s.cfg.Tor.Active && s.cfg.Tor.CircuitRotation > 0 {
+			s.wg.Add(1)
+			go s.rotateTorCircuits()
+		}
+
+		if s.onionAddr != nil && s.cfg.Tor.OnionKeyRotation > 0 {
+			s.wg.Add(1)
+			go s.rotateOnionKeys()
+		}
+
 		if s.hostAnn != nil {
 			if err := s.hostAnn.Start(); err != nil {
 				startErr = err
@@ -1386,10 +1599,24 @@ func (s *server) Start() error {
 			startErr = err
 			return
 		}
+		if err := s.chanScripts.Start(); err != nil {
+			startErr = err
+			return
+		}
+		if err := s.reorgNotifier.Start(); err != nil {
+			startErr = err
+			return
+		}
 		if err := s.htlcNotifier.Start(); err != nil {
 			startErr = err
 			return
 		}
+		if s.htlcEventStore != nil {
+			if err := s.htlcEventStore.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
 		if err := s.sphinx.Start(); err != nil {
 			startErr = err
 			return
@@ -1446,6 +1673,27 @@ func (s *server) Start() error {
 			return
 		}
 
+		if s.feeController != nil {
+			if err := s.feeController.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
+		if s.feeSchedules != nil {
+			if err := s.feeSchedules.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
+		if s.dbCompactor != nil {
+			if err := s.dbCompactor.Start(); err != nil {
+				startErr = err
+				return
+			}
+		}
+
 		// Before we start the connMgr, we'll check to see if we have
 		// any backups to recover. We do this now as we want to ensure
 		// that have all the information we need to handle channel
@@ -1541,69 +1789,109 @@ func (s *server) Stop() error {
 
 		close(s.quit)
 
-		// Shutdown the wallet, funding manager, and the rpc server.
-		s.chanStatusMgr.Stop()
-		s.cc.chainNotifier.Stop()
-		s.chanRouter.Stop()
-		s.htlcSwitch.Stop()
-		s.sphinx.Stop()
-		s.utxoNursery.Stop()
-		s.breachArbiter.Stop()
-		s.authGossiper.Stop()
-		s.chainArb.Stop()
-		s.sweeper.Stop()
-		s.channelNotifier.Stop()
-		s.peerNotifier.Stop()
-		s.htlcNotifier.Stop()
-		s.cc.wallet.Shutdown()
-		s.cc.chainView.Stop()
-		s.connMgr.Stop()
-		s.cc.feeEstimator.Stop()
-		s.invoices.Stop()
-		s.fundingMgr.Stop()
-		s.chanSubSwapper.Stop()
-		s.chanEventStore.Stop()
+		// Stop the subsystems in dependency order. Subsystems that
+		// create new work, such as new connections, channels and
+		// payments, are stopped first, followed by the subsystems
+		// that resolve the in-flight state of channels. The chain
+		// backend and wallet everything else depends on are stopped
+		// last.
+		var steps []shutdownStep
+		addStep := func(name string, stop func() error) {
+			steps = append(steps, shutdownStep{
+				name: name,
+				stop: stop,
+			})
+		}
+		noErr := func(stop func()) func() error {
+			return func() error {
+				stop()
+				return nil
+			}
+		}
+
+		addStep("connection manager", noErr(s.connMgr.Stop))
+		if s.hostAnn != nil {
+			addStep("host announcer", s.hostAnn.Stop)
+		}
+		if s.livelinessMonitor != nil {
+			addStep("liveliness monitor", s.livelinessMonitor.Stop)
+		}
+		addStep("channel status manager", s.chanStatusMgr.Stop)
+		addStep("gossiper", noErr(s.authGossiper.Stop))
+		addStep("funding manager", s.fundingMgr.Stop)
+		addStep("channel router", s.chanRouter.Stop)
+		addStep("htlc switch", s.htlcSwitch.Stop)
 
 		// Disconnect from each active peers to ensure that
 		// peerTerminationWatchers signal completion to each peer.
-		for _, peer := range s.Peers() {
-			err := s.DisconnectPeer(peer.IdentityKey())
-			if err != nil {
-				srvrLog.Warnf("could not disconnect peer: %v"+
-					"received error: %v", peer.IdentityKey(),
-					err,
-				)
+		addStep("peers", noErr(func() {
+			for _, peer := range s.Peers() {
+				err := s.DisconnectPeer(peer.IdentityKey())
+				if err != nil {
+					srvrLog.Warnf("could not disconnect "+
+						"peer: %v received error: %v",
+						peer.IdentityKey(), err)
+				}
 			}
-		}
+		}))
 
 		// Now that all connections have been torn down, stop the tower
 		// client which will reliably flush all queued states to the
 		// tower. If this is halted for any reason, the force quit timer
 		// will kick in and abort to allow this method to return.
 		if s.towerClient != nil {
-			s.towerClient.Stop()
-		}
-
-		if s.hostAnn != nil {
-			if err := s.hostAnn.Stop(); err != nil {
-				srvrLog.Warnf("unable to shut down host "+
-					"annoucner: %v", err)
-			}
-		}
-
-		if s.livelinessMonitor != nil {
-			if err := s.livelinessMonitor.Stop(); err != nil {
-				srvrLog.Warnf("unable to shutdown liveliness "+
-					"monitor: %v", err)
-			}
-		}
+			addStep("tower client", s.towerClient.Stop)
+		}
+
+		addStep("invoice registry", noErr(s.invoices.Stop))
+		addStep("chain arbitrator", s.chainArb.Stop)
+		addStep("breach arbiter", s.breachArbiter.Stop)
+		addStep("utxo nursery", s.utxoNursery.Stop)
+		addStep("sweeper", s.sweeper.Stop)
+		addStep("sphinx", s.sphinx.Stop)
+		addStep("channel backup swapper", s.chanSubSwapper.Stop)
+		addStep("channel event store", noErr(s.chanEventStore.Stop))
+		if s.feeSchedules != nil {
+			addStep("fee schedules", noErr(s.feeSchedules.Stop))
+		}
+		if s.htlcEventStore != nil {
+			addStep("htlc event store", noErr(s.htlcEventStore.Stop))
+		}
+		addStep("htlc notifier", noErr(s.htlcNotifier.Stop))
+		addStep("channel notifier", noErr(s.channelNotifier.Stop))
+		addStep("peer notifier", noErr(s.peerNotifier.Stop))
+		addStep("reorg notifier", noErr(s.reorgNotifier.Stop))
+		addStep("channel script index", noErr(s.chanScripts.Stop))
+		if s.feeController != nil {
+			addStep("fee controller", noErr(s.feeController.Stop))
+		}
+		if s.dbCompactor != nil {
+			addStep("db compactor", noErr(s.dbCompactor.Stop))
+		}
+		addStep("wallet", s.cc.wallet.Shutdown)
+		addStep("chain view", s.cc.chainView.Stop)
+		addStep("chain notifier", s.cc.chainNotifier.Stop)
+		addStep("fee estimator", s.cc.feeEstimator.Stop)
 
 		// Wait for all lingering goroutines to quit.
-		s.wg.Wait()
+		addStep("server goroutines", noErr(s.wg.Wait))
+
+		addStep("worker pools", func() error {
+			s.sigPool.Stop()
+			s.writePool.Stop()
+			return s.readPool.Stop()
+		})
 
-		s.sigPool.Stop()
-		s.writePool.Stop()
-		s.readPool.Stop()
+		// Subsystems that didn't stop in time are left behind, so that
+		// a hanging subsystem doesn't prevent the daemon from exiting.
+		timedOut := runShutdown(
+			srvrLog, steps, s.cfg.ShutdownTimeout,
+			s.shutdownProgress,
+		)
+		if len(timedOut) > 0 {
+			srvrLog.Errorf("Forced shutdown, subsystems not stopped "+
+				"in time: %v", strings.Join(timedOut, ", "))
+		}
 	})
 
 	return nil
@@ -1615,6 +1903,33 @@ func (s *server) Stopped() bool {
 	return atomic.LoadInt32(&s.stopping) != 0
 }
 
+// updateCltvRejectDeltas updates the number of blocks before the expiry of
+// incoming exit hop htlcs and of outgoing htlcs at which they are canceled
+// back. The new deltas apply to htlcs processed after the update and aren't
+// persisted across restarts.
+func (s *server) updateCltvRejectDeltas(finalDelta, outgoingDelta uint32) {
+	s.invoices.SetFinalCltvRejectDelta(int32(finalDelta))
+
+	// Store the new outgoing delta before updating the active links, so
+	// that links created from now on use it as well.
+	atomic.StoreUint32(&s.outgoingCltvRejectDelta, outgoingDelta)
+	s.htlcSwitch.UpdateOutgoingCltvRejectDelta(outgoingDelta)
+}
+
+// cltvRejectDeltas returns the currently effective number of blocks before the
+// expiry of incoming exit hop htlcs and of outgoing htlcs at which they are
+// canceled back.
+func (s *server) cltvRejectDeltas() (uint32, uint32) {
+	return uint32(s.invoices.FinalCltvRejectDelta()),
+		s.outgoingRejectDelta()
+}
+
+// outgoingRejectDelta returns the currently effective number of blocks before
+// the expiry of outgoing htlcs at which they are canceled back.
+func (s *server) outgoingRejectDelta() uint32 {
+	return atomic.LoadUint32(&s.outgoingCltvRejectDelta)
+}
+
 // configurePortForwarding attempts to set up port forwarding for the different
 // ports that the server will be listening on.
 //
@@ -2113,6 +2428,37 @@ func (s *server) initialPeerBootstrap(ctx context.Context,
 // createNewHiddenService automatically sets up a v2 or v3 onion service in
 // order to listen for inbound connections over Tor.
 func (s *server) createNewHiddenService() error {
+	// The service's private key will be saved to disk in order to regain
+	// access to this service when restarting `lnd`.
+	onionCfg := s.onionServiceConfig(
+		tor.NewOnionFile(s.cfg.Tor.PrivateKeyPath, 0600),
+	)
+	addr, err := s.torController.AddOnion(onionCfg)
+	if err != nil {
+		return err
+	}
+	s.onionAddr = addr
+
+	// Now that the onion service has been created, we'll add the onion
+	// address it can be reached at to our list of advertised addresses.
+	newNodeAnn, err := s.genNodeAnnouncement(
+		true, func(currentAnn *lnwire.NodeAnnouncement) {
+			currentAnn.Addresses = append(currentAnn.Addresses, addr)
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("unable to generate new node "+
+			"announcement: %v", err)
+	}
+
+	// Finally, we'll update the on-disk version of our announcement so it
+	// will eventually propagate to nodes in the network.
+	return s.setSelfNode(&newNodeAnn)
+}
+
+// onionServiceConfig returns the configuration of the node's onion service,
+// whose private key is kept in the given store.
+func (s *server) onionServiceConfig(store tor.OnionStore) tor.AddOnionConfig {
 	// Determine the different ports the server is listening on. The onion
 	// service's virtual port will map to these ports and one will be picked
 	// at random when the onion service is being accessed.
@@ -2122,13 +2468,10 @@ func (s *server) createNewHiddenService() error {
 		listenPorts = append(listenPorts, port)
 	}
 
-	// Once the port mapping has been set, we can go ahead and automatically
-	// create our onion service. The service's private key will be saved to
-	// disk in order to regain access to this service when restarting `lnd`.
 	onionCfg := tor.AddOnionConfig{
 		VirtualPort: defaultPeerPort,
 		TargetPorts: listenPorts,
-		Store:       tor.NewOnionFile(s.cfg.Tor.PrivateKeyPath, 0600),
+		Store:       store,
 	}
 
 	switch {
@@ -2138,35 +2481,22 @@ func (s *server) createNewHiddenService() error {
 		onionCfg.Type = tor.V3
 	}
 
-	addr, err := s.torController.AddOnion(onionCfg)
-	if err != nil {
-		return err
-	}
-
-	// Now that the onion service has been created, we'll add the onion
-	// address it can be reached at to our list of advertised addresses.
-	newNodeAnn, err := s.genNodeAnnouncement(
-		true, func(currentAnn *lnwire.NodeAnnouncement) {
-			currentAnn.Addresses = append(currentAnn.Addresses, addr)
-		},
-	)
-	if err != nil {
-		return fmt.Errorf("unable to generate new node "+
-			"announcement: %v", err)
-	}
+	return onionCfg
+}
 
-	// Finally, we'll update the on-disk version of our announcement so it
-	// will eventually propagate to nodes in the network.
+// setSelfNode stores the given announcement as the on-disk version of our own
+// node announcement.
+func (s *server) setSelfNode(nodeAnn *lnwire.NodeAnnouncement) error {
 	selfNode := &channeldb.LightningNode{
 		HaveNodeAnnouncement: true,
-		LastUpdate:           time.Unix(int64(newNodeAnn.Timestamp), 0),
-		Addresses:            newNodeAnn.Addresses,
-		Alias:                newNodeAnn.Alias.String(),
+		LastUpdate:           time.Unix(int64(nodeAnn.Timestamp), 0),
+		Addresses:            nodeAnn.Addresses,
+		Alias:                nodeAnn.Alias.String(),
 		Features: lnwire.NewFeatureVector(
-			newNodeAnn.Features, lnwire.Features,
+			nodeAnn.Features, lnwire.Features,
 		),
-		Color:        newNodeAnn.RGBColor,
-		AuthSigBytes: newNodeAnn.Signature.ToSignatureBytes(),
+		Color:        nodeAnn.RGBColor,
+		AuthSigBytes: nodeAnn.Signature.ToSignatureBytes(),
 	}
 	copy(selfNode.PubKeyBytes[:], s.identityECDH.PubKey().SerializeCompressed())
 	if err := s.localChanDB.ChannelGraph().SetSourceNode(selfNode); err != nil {
@@ -2947,7 +3277,7 @@ func (s *server) peerConnected(conn net.Conn, connReq *connmgr.ConnReq,
 		Inbound:                 inbound,
 		Features:                initFeatures,
 		LegacyFeatures:          legacyFeatures,
-		OutgoingCltvRejectDelta: lncfg.DefaultOutgoingCltvRejectDelta,
+		OutgoingCltvRejectDelta: s.outgoingRejectDelta,
 		ChanActiveTimeout:       s.cfg.ChanEnableTimeout,
 		ErrorBuffer:             errBuffer,
 		WritePool:               s.writePool,
@@ -3374,6 +3704,11 @@ type openChanReq struct {
 
 	maxHtlcs uint16
 
+	// commitType is an optional commitment type the caller requires for
+	// the channel. If nil, the type implicitly negotiated with the peer is
+	// used.
+	commitType *lnwallet.CommitmentType
+
 	// TODO(roasbeef): add ability to specify channel constraints as well
 
 	// chanFunder is an optional channel funder that allows the caller to
@@ -3387,6 +3722,10 @@ type openChanReq struct {
 	// protocol.
 	pendingChanID [32]byte
 
+	// label is an optional label stored with the funding transaction when
+	// we publish it.
+	label string
+
 	updates chan *lnrpc.OpenStatusUpdate
 	err     chan error
 }
@@ -0,0 +1,285 @@
+package dcrlnd
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+const (
+	// defaultShutdownTimeout is the default time each subsystem is given
+	// to stop during shutdown.
+	defaultShutdownTimeout = 30 * time.Second
+
+	// defaultShutdownGracePeriod is the default time the whole shutdown is
+	// given to complete before the process is forcefully terminated.
+	defaultShutdownGracePeriod = 5 * time.Minute
+)
+
+// shutdownStep is a single step of the ordered shutdown of a set of
+// subsystems.
+type shutdownStep struct {
+	// name is the name of the subsystem stopped by the step, used for
+	// logging.
+	name string
+
+	// stop stops the subsystem.
+	stop func() error
+}
+
+// shutdownStepState is the state of a single shutdown step.
+type shutdownStepState uint8
+
+const (
+	// shutdownStepPending indicates that the step hasn't started yet.
+	shutdownStepPending shutdownStepState = iota
+
+	// shutdownStepStopping indicates that the subsystem is being stopped.
+	shutdownStepStopping
+
+	// shutdownStepStopped indicates that the subsystem was stopped.
+	shutdownStepStopped
+
+	// shutdownStepFailed indicates that the subsystem failed to stop.
+	shutdownStepFailed
+
+	// shutdownStepTimedOut indicates that the subsystem didn't stop in
+	// time and was left behind.
+	shutdownStepTimedOut
+)
+
+// shutdownStepStatus is the status of a single shutdown step.
+type shutdownStepStatus struct {
+	// name is the name of the subsystem stopped by the step.
+	name string
+
+	// state is the state of the step.
+	state shutdownStepState
+
+	// elapsed is the time the step took, or has taken so far if it is
+	// still stopping.
+	elapsed time.Duration
+
+	// started is the time the step started.
+	started time.Time
+}
+
+// shutdownProgress tracks the progress of an ordered shutdown, so that it can
+// be reported while the shutdown is taking place.
+type shutdownProgress struct {
+	mu sync.Mutex
+
+	// steps is the status of each step of the shutdown. It is nil until
+	// the shutdown starts.
+	steps []shutdownStepStatus
+
+	// complete is true once all steps either completed or timed out.
+	complete bool
+
+	// updated is closed and replaced each time the progress changes.
+	updated chan struct{}
+}
+
+// newShutdownProgress returns a progress tracker for a shutdown that hasn't
+// started yet.
+func newShutdownProgress() *shutdownProgress {
+	return &shutdownProgress{
+		updated: make(chan struct{}),
+	}
+}
+
+// notify wakes up the callers waiting for an update of the progress.
+//
+// NOTE: Must be called with the mutex held.
+func (p *shutdownProgress) notify() {
+	close(p.updated)
+	p.updated = make(chan struct{})
+}
+
+// begin marks the start of a shutdown made of the given steps.
+func (p *shutdownProgress) begin(steps []shutdownStep) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.steps = make([]shutdownStepStatus, len(steps))
+	for i, step := range steps {
+		p.steps[i].name = step.name
+	}
+	p.notify()
+}
+
+// setState updates the state of the step at the given index.
+func (p *shutdownProgress) setState(i int, state shutdownStepState) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	step := &p.steps[i]
+	if state == shutdownStepStopping {
+		step.started = time.Now()
+	} else {
+		step.elapsed = time.Since(step.started)
+	}
+	step.state = state
+	p.notify()
+}
+
+// finish marks the completion of the shutdown.
+func (p *shutdownProgress) finish() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.complete = true
+	p.notify()
+}
+
+// snapshot returns the current status of the shutdown steps, whether the
+// shutdown completed, and a channel that is closed on the next update.
+func (p *shutdownProgress) snapshot() ([]shutdownStepStatus, bool,
+	<-chan struct{}) {
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.steps == nil {
+		return nil, p.complete, p.updated
+	}
+
+	steps := make([]shutdownStepStatus, len(p.steps))
+	copy(steps, p.steps)
+	for i := range steps {
+		if steps[i].state == shutdownStepStopping {
+			steps[i].elapsed = time.Since(steps[i].started)
+		}
+	}
+
+	return steps, p.complete, p.updated
+}
+
+// runShutdown executes the given shutdown steps in order. Each step is given
+// the passed timeout to complete, after which the shutdown moves on to the
+// next step, leaving the timed out step to finish in the background. A zero
+// timeout waits for each step indefinitely. The progress of the steps is
+// reported to the passed tracker, if any. The names of the steps that timed
+// out are returned.
+func runShutdown(log slog.Logger, steps []shutdownStep,
+	timeout time.Duration, progress *shutdownProgress) []string {
+
+	setState := func(int, shutdownStepState) {}
+	if progress != nil {
+		progress.begin(steps)
+		defer progress.finish()
+
+		setState = progress.setState
+	}
+
+	var timedOut []string
+	for i, step := range steps {
+		log.Debugf("Stopping %v (%d/%d)", step.name, i+1, len(steps))
+		setState(i, shutdownStepStopping)
+
+		start := time.Now()
+		done := make(chan error, 1)
+		go func(step shutdownStep) {
+			done <- step.stop()
+		}(step)
+
+		var (
+			timer       *time.Timer
+			timeoutChan <-chan time.Time
+		)
+		if timeout > 0 {
+			timer = time.NewTimer(timeout)
+			timeoutChan = timer.C
+		}
+
+		select {
+		case err := <-done:
+			if err != nil {
+				log.Warnf("Unable to stop %v: %v", step.name,
+					err)
+				setState(i, shutdownStepFailed)
+				break
+			}
+
+			log.Debugf("Stopped %v in %v", step.name,
+				time.Since(start))
+			setState(i, shutdownStepStopped)
+
+		case <-timeoutChan:
+			log.Errorf("Timeout stopping %v after %v, continuing "+
+				"shutdown", step.name, timeout)
+			timedOut = append(timedOut, step.name)
+			setState(i, shutdownStepTimedOut)
+		}
+
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+
+	return timedOut
+}
+
+// forcedTeardown terminates the process if a shutdown doesn't complete within
+// a grace period, instead of leaving it hanging until it is killed.
+type forcedTeardown struct {
+	log   slog.Logger
+	grace time.Duration
+	exit  func(code int)
+
+	startOnce sync.Once
+	stopOnce  sync.Once
+	quit      chan struct{}
+}
+
+// newForcedTeardown returns a forced teardown that calls exit once the given
+// grace period elapses after it is started. A zero grace period disables it.
+func newForcedTeardown(log slog.Logger, grace time.Duration,
+	exit func(code int)) *forcedTeardown {
+
+	return &forcedTeardown{
+		log:   log,
+		grace: grace,
+		exit:  exit,
+		quit:  make(chan struct{}),
+	}
+}
+
+// start starts the grace period of the shutdown.
+func (f *forcedTeardown) start() {
+	if f.grace == 0 {
+		return
+	}
+
+	f.startOnce.Do(func() {
+		go func() {
+			timer := time.NewTimer(f.grace)
+			defer timer.Stop()
+
+			select {
+			case <-timer.C:
+			case <-f.quit:
+				return
+			}
+
+			// Log the stacks of all goroutines, so the subsystems
+			// that hold up the shutdown can be identified.
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			f.log.Criticalf("Shutdown didn't complete within %v, "+
+				"forcing teardown. Goroutines:\n%s", f.grace,
+				buf)
+
+			f.exit(1)
+		}()
+	})
+}
+
+// stop stops the grace period, once the shutdown completed.
+func (f *forcedTeardown) stop() {
+	f.stopOnce.Do(func() {
+		close(f.quit)
+	})
+}
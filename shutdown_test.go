@@ -0,0 +1,130 @@
+package dcrlnd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/decred/slog"
+)
+
+// TestRunShutdown asserts that shutdown steps are executed in order and that a
+// step that doesn't complete in time doesn't hold up the following steps.
+func TestRunShutdown(t *testing.T) {
+	t.Parallel()
+
+	var stopped []string
+	stop := func(name string) func() error {
+		return func() error {
+			stopped = append(stopped, name)
+			return nil
+		}
+	}
+
+	hang := make(chan struct{})
+	defer close(hang)
+
+	steps := []shutdownStep{
+		{name: "a", stop: stop("a")},
+		{name: "hang", stop: func() error {
+			<-hang
+			return nil
+		}},
+		{name: "fail", stop: func() error {
+			return errors.New("fail")
+		}},
+		{name: "b", stop: stop("b")},
+	}
+
+	progress := newShutdownProgress()
+	status, complete, updated := progress.snapshot()
+	if status != nil || complete {
+		t.Fatalf("shutdown reported before it started")
+	}
+
+	timedOut := runShutdown(
+		slog.Disabled, steps, 10*time.Millisecond, progress,
+	)
+	if !reflect.DeepEqual(timedOut, []string{"hang"}) {
+		t.Fatalf("unexpected timed out steps: %v", timedOut)
+	}
+	if !reflect.DeepEqual(stopped, []string{"a", "b"}) {
+		t.Fatalf("unexpected stopped steps: %v", stopped)
+	}
+
+	// The progress must report the final state of each step, and the
+	// callers waiting for an update must have been notified.
+	select {
+	case <-updated:
+	default:
+		t.Fatalf("progress update not notified")
+	}
+
+	status, complete, _ = progress.snapshot()
+	if !complete {
+		t.Fatalf("shutdown not reported complete")
+	}
+	wantStates := []shutdownStepState{
+		shutdownStepStopped, shutdownStepTimedOut, shutdownStepFailed,
+		shutdownStepStopped,
+	}
+	if len(status) != len(wantStates) {
+		t.Fatalf("expected %d steps, got %d", len(wantStates),
+			len(status))
+	}
+	for i, want := range wantStates {
+		if status[i].name != steps[i].name {
+			t.Fatalf("step %d: expected name %v, got %v", i,
+				steps[i].name, status[i].name)
+		}
+		if status[i].state != want {
+			t.Fatalf("step %v: expected state %v, got %v",
+				status[i].name, want, status[i].state)
+		}
+	}
+}
+
+// TestForcedTeardown asserts that the process is terminated once the grace
+// period of a shutdown elapses, unless the shutdown completed before.
+func TestForcedTeardown(t *testing.T) {
+	t.Parallel()
+
+	exited := make(chan int, 1)
+	exit := func(code int) {
+		exited <- code
+	}
+
+	// A shutdown that doesn't complete in time is torn down.
+	teardown := newForcedTeardown(slog.Disabled, 10*time.Millisecond, exit)
+	teardown.start()
+	select {
+	case code := <-exited:
+		if code != 1 {
+			t.Fatalf("expected exit code 1, got %d", code)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("process not torn down after grace period")
+	}
+	teardown.stop()
+
+	// A shutdown that completes in time isn't torn down.
+	teardown = newForcedTeardown(slog.Disabled, 50*time.Millisecond, exit)
+	teardown.start()
+	teardown.stop()
+	select {
+	case <-exited:
+		t.Fatalf("process torn down after shutdown completed")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// A zero grace period disables the teardown.
+	teardown = newForcedTeardown(slog.Disabled, 0, exit)
+	teardown.start()
+	select {
+	case <-exited:
+		t.Fatalf("process torn down with teardown disabled")
+	case <-time.After(50 * time.Millisecond):
+	}
+	teardown.stop()
+}
@@ -27,6 +27,12 @@ var (
 	// maps: txHash -> empty slice
 	txHashesBucketKey = []byte("sweeper-tx-hashes")
 
+	// replacementsBucketKey is the key that points to a bucket containing
+	// the sweep txes that were replaced by a later sweep tx.
+	//
+	// maps: replacementTxHash -> replacedTxHash1 || replacedTxHash2 || ...
+	replacementsBucketKey = []byte("sweeper-replacements")
+
 	// utxnChainPrefix is the bucket prefix for nursery buckets.
 	utxnChainPrefix = []byte("utxn")
 
@@ -41,6 +47,9 @@ var (
 	byteOrder = binary.BigEndian
 
 	errNoTxHashesBucket = errors.New("tx hashes bucket does not exist")
+
+	errNoReplacementsBucket = errors.New("replacements bucket does not " +
+		"exist")
 )
 
 // SweeperStore stores published txes.
@@ -58,6 +67,16 @@ type SweeperStore interface {
 
 	// ListSweeps lists all the sweeps we have successfully published.
 	ListSweeps() ([]chainhash.Hash, error)
+
+	// NotifyReplacement records that the sweep tx with the given hash
+	// replaces the passed earlier sweep txes.
+	NotifyReplacement(replacement chainhash.Hash,
+		replaced []chainhash.Hash) error
+
+	// ListReplacements returns the hashes of all the sweep txes that were
+	// directly or indirectly replaced by the given sweep tx, most recent
+	// first.
+	ListReplacements(hash chainhash.Hash) ([]chainhash.Hash, error)
 }
 
 type sweeperStore struct {
@@ -76,6 +95,13 @@ func NewSweeperStore(db kvdb.Backend, chainHash *chainhash.Hash) (
 			return err
 		}
 
+		_, err = tx.CreateTopLevelBucket(
+			replacementsBucketKey,
+		)
+		if err != nil {
+			return err
+		}
+
 		if tx.ReadWriteBucket(txHashesBucketKey) != nil {
 			return nil
 		}
@@ -283,5 +309,77 @@ func (s *sweeperStore) ListSweeps() ([]chainhash.Hash, error) {
 	return sweepTxns, nil
 }
 
+// NotifyReplacement records that the sweep tx with the given hash replaces the
+// passed earlier sweep txes.
+func (s *sweeperStore) NotifyReplacement(replacement chainhash.Hash,
+	replaced []chainhash.Hash) error {
+
+	return kvdb.Update(s.db, func(tx kvdb.RwTx) error {
+		replacementsBucket := tx.ReadWriteBucket(replacementsBucketKey)
+		if replacementsBucket == nil {
+			return errNoReplacementsBucket
+		}
+
+		var b bytes.Buffer
+		for _, hash := range replaced {
+			if _, err := b.Write(hash[:]); err != nil {
+				return err
+			}
+		}
+
+		return replacementsBucket.Put(replacement[:], b.Bytes())
+	})
+}
+
+// ListReplacements returns the hashes of all the sweep txes that were directly
+// or indirectly replaced by the given sweep tx, most recent first.
+func (s *sweeperStore) ListReplacements(hash chainhash.Hash) ([]chainhash.Hash,
+	error) {
+
+	var replaced []chainhash.Hash
+
+	err := kvdb.View(s.db, func(tx kvdb.RTx) error {
+		replacementsBucket := tx.ReadBucket(replacementsBucketKey)
+		if replacementsBucket == nil {
+			return errNoReplacementsBucket
+		}
+
+		// Walk the replacement chain breadth first, so that the txes
+		// replaced most recently are returned first.
+		seen := map[chainhash.Hash]struct{}{hash: {}}
+		queue := []chainhash.Hash{hash}
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+
+			v := replacementsBucket.Get(next[:])
+			if len(v)%chainhash.HashSize != 0 {
+				return fmt.Errorf("invalid replacements of "+
+					"tx %v", next)
+			}
+
+			for i := 0; i < len(v); i += chainhash.HashSize {
+				var prev chainhash.Hash
+				copy(prev[:], v[i:i+chainhash.HashSize])
+
+				if _, ok := seen[prev]; ok {
+					continue
+				}
+				seen[prev] = struct{}{}
+
+				replaced = append(replaced, prev)
+				queue = append(queue, prev)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return replaced, nil
+}
+
 // Compile-time constraint to ensure sweeperStore implements SweeperStore.
 var _ SweeperStore = (*sweeperStore)(nil)
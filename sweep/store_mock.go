@@ -8,14 +8,19 @@ import (
 // MockSweeperStore is a mock implementation of sweeper store. This type is
 // exported, because it is currently used in nursery tests too.
 type MockSweeperStore struct {
-	lastTx  *wire.MsgTx
-	ourTxes map[chainhash.Hash]struct{}
+	lastTx       *wire.MsgTx
+	ourTxes      map[chainhash.Hash]struct{}
+	replacements map[chainhash.Hash][]chainhash.Hash
+
+	// replacementErr is returned by NotifyReplacement when set.
+	replacementErr error
 }
 
 // NewMockSweeperStore returns a new instance.
 func NewMockSweeperStore() *MockSweeperStore {
 	return &MockSweeperStore{
-		ourTxes: make(map[chainhash.Hash]struct{}),
+		ourTxes:      make(map[chainhash.Hash]struct{}),
+		replacements: make(map[chainhash.Hash][]chainhash.Hash),
 	}
 }
 
@@ -55,5 +60,45 @@ func (s *MockSweeperStore) ListSweeps() ([]chainhash.Hash, error) {
 	return txns, nil
 }
 
+// NotifyReplacement records that the sweep tx with the given hash replaces the
+// passed earlier sweep txes.
+func (s *MockSweeperStore) NotifyReplacement(replacement chainhash.Hash,
+	replaced []chainhash.Hash) error {
+
+	if s.replacementErr != nil {
+		return s.replacementErr
+	}
+
+	s.replacements[replacement] = replaced
+
+	return nil
+}
+
+// ListReplacements returns the hashes of all the sweep txes that were directly
+// or indirectly replaced by the given sweep tx, most recent first.
+func (s *MockSweeperStore) ListReplacements(
+	hash chainhash.Hash) ([]chainhash.Hash, error) {
+
+	var replaced []chainhash.Hash
+	seen := map[chainhash.Hash]struct{}{hash: {}}
+	queue := []chainhash.Hash{hash}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		for _, prev := range s.replacements[next] {
+			if _, ok := seen[prev]; ok {
+				continue
+			}
+			seen[prev] = struct{}{}
+
+			replaced = append(replaced, prev)
+			queue = append(queue, prev)
+		}
+	}
+
+	return replaced, nil
+}
+
 // Compile-time constraint to ensure MockSweeperStore implements SweeperStore.
 var _ SweeperStore = (*MockSweeperStore)(nil)
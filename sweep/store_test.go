@@ -1,6 +1,7 @@
 package sweep
 
 import (
+	"reflect"
 	"testing"
 
 	"github.com/decred/dcrd/chaincfg/chainhash"
@@ -147,4 +148,39 @@ func testStore(t *testing.T, createStore func() (SweeperStore, error)) {
 			t.Fatalf("unexpected tx: %v", tx)
 		}
 	}
+
+	// Record tx2 as replacing tx1 and a third tx as replacing tx2. The
+	// third tx should be reported as replacing both.
+	var tx3Hash chainhash.Hash
+	tx3Hash[0] = 3
+
+	err = store.NotifyReplacement(tx2.TxHash(), []chainhash.Hash{
+		tx1.TxHash(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.NotifyReplacement(tx3Hash, []chainhash.Hash{tx2.TxHash()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	replaced, err := store.ListReplacements(tx3Hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectedReplaced := []chainhash.Hash{tx2.TxHash(), tx1.TxHash()}
+	if !reflect.DeepEqual(replaced, expectedReplaced) {
+		t.Fatalf("expected replaced txs %v, got %v", expectedReplaced,
+			replaced)
+	}
+
+	// A tx that didn't replace any other shouldn't report replacements.
+	replaced, err = store.ListReplacements(tx1.TxHash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(replaced) != 0 {
+		t.Fatalf("expected no replaced txs, got %v", replaced)
+	}
 }
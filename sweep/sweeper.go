@@ -37,6 +37,16 @@ const (
 	//   #1: min = 1 atom/KB, max = 10 atom/KB
 	//   #2: min = 11 atom/KB, max = 20 atom/KB...
 	DefaultFeeRateBucketSize = 10
+
+	// DefaultDeadlineBumpWindow is the number of blocks before the deadline
+	// of an input from which its unconfirmed sweeps are fee bumped on every
+	// new block.
+	DefaultDeadlineBumpWindow = 6
+
+	// deadlineFeeBumpPercent is the minimum percentage by which the fee
+	// rate of an unconfirmed sweep is increased when it is replaced due to
+	// an approaching deadline.
+	deadlineFeeBumpPercent = 25
 )
 
 var (
@@ -82,6 +92,18 @@ type Params struct {
 	// ExclusiveGroup is an identifier that, if set, prevents other inputs
 	// with the same identifier from being batched together.
 	ExclusiveGroup *uint64
+
+	// DeadlineHeight is the height by which the input should be swept. If
+	// set, unconfirmed sweeps of the input are replaced by sweeps with an
+	// increasing fee rate once the deadline approaches.
+	DeadlineHeight int32
+
+	// Deferrable indicates whether the sweep of the input can be held
+	// back while the sweeps of non-urgent inputs are paused. Inputs
+	// resolving a contract, such as the outputs swept by the nursery, the
+	// contract resolvers and the anchors, must never be deferred, so only
+	// inputs that don't resolve any contract should set it.
+	Deferrable bool
 }
 
 // ParamsUpdate contains a new set of parameters to update a pending sweep with.
@@ -94,12 +116,17 @@ type ParamsUpdate struct {
 	// Force indicates whether the input should be swept regardless of
 	// whether it is economical to do so.
 	Force bool
+
+	// DeadlineHeight is the new deadline of the input. A zero value leaves
+	// the current deadline unchanged.
+	DeadlineHeight int32
 }
 
 // String returns a human readable interpretation of the sweep parameters.
 func (p Params) String() string {
-	return fmt.Sprintf("fee=%v, force=%v, exclusive_group=%v",
-		p.Fee, p.Force, p.ExclusiveGroup)
+	return fmt.Sprintf("fee=%v, force=%v, exclusive_group=%v, "+
+		"deadline_height=%v, deferrable=%v", p.Fee, p.Force,
+		p.ExclusiveGroup, p.DeadlineHeight, p.Deferrable)
 }
 
 // pendingInput is created when an input reaches the main loop for the first
@@ -130,6 +157,14 @@ type pendingInput struct {
 	// lastFeeRate is the most recent fee rate used for this input within a
 	// transaction broadcast to the network.
 	lastFeeRate chainfee.AtomPerKByte
+
+	// publishedFeeRate is the fee rate of the last sweep transaction of
+	// this input that was broadcast to the network.
+	publishedFeeRate chainfee.AtomPerKByte
+
+	// lastSweepTx is the hash of the last sweep transaction of this input
+	// that was broadcast to the network, if any.
+	lastSweepTx *chainhash.Hash
 }
 
 // parameters returns the sweep parameters for this input.
@@ -156,6 +191,12 @@ type pendingSweepsReq struct {
 	respChan chan map[wire.OutPoint]*PendingInput
 }
 
+// pauseReq is a request to pause or resume the sweeps of non-urgent inputs.
+type pauseReq struct {
+	pause bool
+	done  chan struct{}
+}
+
 // PendingInput contains information about an input that is currently being
 // swept by the UtxoSweeper.
 type PendingInput struct {
@@ -182,6 +223,14 @@ type PendingInput struct {
 
 	// Params contains the sweep parameters for this pending request.
 	Params Params
+
+	// SweepTx is the hash of the last sweep transaction of the input that
+	// was broadcast to the network, if any.
+	SweepTx *chainhash.Hash
+
+	// ReplacedTxs are the hashes of the earlier sweep transactions that
+	// were replaced by SweepTx, most recent first.
+	ReplacedTxs []chainhash.Hash
 }
 
 // updateReq is an internal message we'll use to represent an external caller's
@@ -218,6 +267,14 @@ type UtxoSweeper struct {
 	// callers who wish to bump the fee rate of a given input.
 	updateReqs chan *updateReq
 
+	// pauseReqs is a channel that will be sent requests by external
+	// callers who wish to pause or resume the sweeps of non-urgent
+	// inputs.
+	pauseReqs chan *pauseReq
+
+	// paused is set while the sweeps of non-urgent inputs are paused.
+	paused bool
+
 	// pendingInputs is the total set of inputs the UtxoSweeper has been
 	// requested to sweep.
 	pendingInputs pendingInputs
@@ -332,6 +389,7 @@ func New(cfg *UtxoSweeperConfig) *UtxoSweeper {
 		newInputs:         make(chan *sweepInputMessage),
 		spendChan:         make(chan *chainntnfs.SpendDetail),
 		updateReqs:        make(chan *updateReq),
+		pauseReqs:         make(chan *pauseReq),
 		pendingSweepsReqs: make(chan *pendingSweepsReq),
 		quit:              make(chan struct{}),
 		pendingInputs:     make(pendingInputs),
@@ -670,6 +728,23 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch) {
 				err:        err,
 			}
 
+		// A new external request has been received to pause or resume
+		// the sweeps of non-urgent inputs.
+		case req := <-s.pauseReqs:
+			s.paused = req.pause
+			close(req.done)
+
+			log.Infof("Sweeps of non-urgent inputs paused=%v",
+				s.paused)
+
+			// The inputs held back may be swept again.
+			if !s.paused {
+				err := s.scheduleSweep(bestHeight)
+				if err != nil {
+					log.Errorf("schedule sweep: %v", err)
+				}
+			}
+
 		// The timer expires and we are going to (re)sweep.
 		case <-s.timer:
 			log.Debugf("Sweep timer expired")
@@ -684,7 +759,7 @@ func (s *UtxoSweeper) collector(blockEpochs <-chan *chainntnfs.BlockEpoch) {
 			// this to ensure any inputs which have had their fee
 			// rate bumped are broadcast first in order enforce the
 			// RBF policy.
-			inputClusters := s.clusterBySweepFeeRate()
+			inputClusters := s.clusterBySweepFeeRate(bestHeight)
 			sort.Slice(inputClusters, func(i, j int) bool {
 				return inputClusters[i].sweepFeeRate >
 					inputClusters[j].sweepFeeRate
@@ -791,18 +866,26 @@ func (s *UtxoSweeper) bucketForFeeRate(
 // and clusters those together with similar fee rates. Each cluster contains a
 // sweep fee rate, which is determined by calculating the average fee rate of
 // all inputs within that cluster.
-func (s *UtxoSweeper) clusterBySweepFeeRate() []inputCluster {
+func (s *UtxoSweeper) clusterBySweepFeeRate(
+	currentHeight int32) []inputCluster {
+
 	bucketInputs := make(map[int]*bucketList)
 	inputFeeRates := make(map[wire.OutPoint]chainfee.AtomPerKByte)
 
 	// First, we'll group together all inputs with similar fee rates. This
 	// is done by determining the fee rate bucket they should belong in.
 	for op, input := range s.pendingInputs {
+		// While paused, only the urgent inputs are swept.
+		if s.paused && !isUrgent(input) {
+			continue
+		}
+
 		feeRate, err := s.feeRateForPreference(input.params.Fee)
 		if err != nil {
 			log.Warnf("Skipping input %v: %v", op, err)
 			continue
 		}
+		feeRate = s.deadlineFeeRate(input, feeRate, currentHeight)
 		feeGroup := s.bucketForFeeRate(feeRate)
 
 		// Create a bucket list for this fee rate if there isn't one
@@ -841,6 +924,55 @@ func (s *UtxoSweeper) clusterBySweepFeeRate() []inputCluster {
 	return inputClusters
 }
 
+// isUrgent returns whether the input must be swept even while the sweeps of
+// non-urgent inputs are paused, as it resolves a contract, has a deadline or
+// must be swept regardless of whether it's economical to do so.
+func isUrgent(input *pendingInput) bool {
+	return !input.params.Deferrable || input.params.Force ||
+		input.params.DeadlineHeight != 0
+}
+
+// inDeadlineWindow returns whether the deadline of the given input is close
+// enough for its unconfirmed sweeps to be fee bumped.
+func inDeadlineWindow(input *pendingInput, currentHeight int32) bool {
+	deadline := input.params.DeadlineHeight
+	if deadline == 0 {
+		return false
+	}
+
+	return currentHeight+DefaultDeadlineBumpWindow >= deadline
+}
+
+// deadlineFeeRate returns the fee rate to sweep the given input with, taking
+// into account its deadline. Once the deadline approaches, an input that was
+// already broadcast is swept with a fee rate of at least
+// deadlineFeeBumpPercent above its previous sweep, as the estimator evidently
+// underquoted the fee rate required to confirm it in time. The returned fee
+// rate never exceeds the maximum fee rate of the UtxoSweeper.
+func (s *UtxoSweeper) deadlineFeeRate(input *pendingInput,
+	feeRate chainfee.AtomPerKByte,
+	currentHeight int32) chainfee.AtomPerKByte {
+
+	if input.publishAttempts == 0 || !inDeadlineWindow(input, currentHeight) {
+		return feeRate
+	}
+
+	bumpedFeeRate := input.publishedFeeRate *
+		(100 + deadlineFeeBumpPercent) / 100
+	if bumpedFeeRate <= feeRate {
+		return feeRate
+	}
+	if bumpedFeeRate > s.cfg.MaxFeeRate {
+		bumpedFeeRate = s.cfg.MaxFeeRate
+	}
+
+	log.Debugf("Bumping fee rate of input %v from %v to %v due to "+
+		"deadline at height %v", input.OutPoint(), feeRate,
+		bumpedFeeRate, input.params.DeadlineHeight)
+
+	return bumpedFeeRate
+}
+
 // scheduleSweep starts the sweep timer to create an opportunity for more inputs
 // to be added.
 func (s *UtxoSweeper) scheduleSweep(currentHeight int32) error {
@@ -853,7 +985,7 @@ func (s *UtxoSweeper) scheduleSweep(currentHeight int32) error {
 
 	// We'll only start our timer once we have inputs we're able to sweep.
 	startTimer := false
-	for _, cluster := range s.clusterBySweepFeeRate() {
+	for _, cluster := range s.clusterBySweepFeeRate(currentHeight) {
 		// Examine pending inputs and try to construct lists of inputs.
 		// We don't need to obtain the coin selection lock, because we
 		// just need an indication as to whether we can sweep. More
@@ -1045,8 +1177,16 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate chainfee.AtomPerKByte,
 
 	// Keep the output script in case of an error, so that it can be reused
 	// for the next transaction and causes no address inflation.
-	if err == nil {
+	published := err == nil
+	if published {
 		s.currentOutputScript = nil
+
+		// The transaction is published at this point, so the inputs
+		// must still be rescheduled below if tracking fails.
+		if err := s.trackReplacements(tx); err != nil {
+			log.Errorf("Unable to track replacements of sweep tx "+
+				"%v: %v", tx.TxHash(), err)
+		}
 	}
 
 	// Reschedule sweep.
@@ -1063,6 +1203,11 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate chainfee.AtomPerKByte,
 
 		// Record another publish attempt.
 		pi.publishAttempts++
+		if published {
+			sweepHash := tx.TxHash()
+			pi.lastSweepTx = &sweepHash
+			pi.publishedFeeRate = feeRate
+		}
 
 		// We don't care what the result of the publish call was. Even
 		// if it is published successfully, it can still be that it
@@ -1072,6 +1217,13 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate chainfee.AtomPerKByte,
 			pi.publishAttempts,
 		)
 
+		// If the deadline of the input is approaching, we'll attempt to
+		// replace the sweep with a higher fee rate one on every block
+		// until it confirms.
+		if inDeadlineWindow(pi, currentHeight) {
+			nextAttemptDelta = 1
+		}
+
 		pi.minPublishHeight = currentHeight + nextAttemptDelta
 
 		log.Debugf("Rescheduling input %v after %v attempts at "+
@@ -1090,6 +1242,45 @@ func (s *UtxoSweeper) sweep(inputs inputSet, feeRate chainfee.AtomPerKByte,
 	return nil
 }
 
+// trackReplacements records the earlier sweep transactions of the inputs of
+// the given sweep transaction as being replaced by it.
+//
+// NOTE: Decred's relay policy has no notion of opt-in replaceability, so
+// replacements only propagate to nodes that haven't yet seen the transaction
+// they replace. The earlier transactions are tracked nonetheless, as any one
+// of them may still be the one that ends up confirmed.
+func (s *UtxoSweeper) trackReplacements(tx *wire.MsgTx) error {
+	sweepHash := tx.TxHash()
+
+	var replaced []chainhash.Hash
+	seen := make(map[chainhash.Hash]struct{})
+	for _, txIn := range tx.TxIn {
+		pi, ok := s.pendingInputs[txIn.PreviousOutPoint]
+		if !ok || pi.lastSweepTx == nil {
+			continue
+		}
+
+		prevHash := *pi.lastSweepTx
+		if prevHash == sweepHash {
+			continue
+		}
+		if _, ok := seen[prevHash]; ok {
+			continue
+		}
+		seen[prevHash] = struct{}{}
+
+		replaced = append(replaced, prevHash)
+	}
+
+	if len(replaced) == 0 {
+		return nil
+	}
+
+	log.Debugf("Sweep tx %v replaces %v", sweepHash, replaced)
+
+	return s.cfg.Store.NotifyReplacement(sweepHash, replaced)
+}
+
 // waitForSpend registers a spend notification with the chain notifier. It
 // returns a cancel function that can be used to cancel the registration.
 func (s *UtxoSweeper) waitForSpend(outpoint wire.OutPoint,
@@ -1151,6 +1342,41 @@ func (s *UtxoSweeper) PendingInputs() (map[wire.OutPoint]*PendingInput, error) {
 	}
 }
 
+// PauseNonUrgent pauses the sweeps of the deferrable inputs without a deadline
+// that aren't forced, such as the wallet outputs swept to bump the fee of
+// their parent, until ResumeNonUrgent is called. Inputs resolving a contract
+// and inputs already being swept are unaffected.
+func (s *UtxoSweeper) PauseNonUrgent() error {
+	return s.setPaused(true)
+}
+
+// ResumeNonUrgent resumes the sweeps of the non-urgent inputs paused by
+// PauseNonUrgent.
+func (s *UtxoSweeper) ResumeNonUrgent() error {
+	return s.setPaused(false)
+}
+
+// setPaused submits a request to pause or resume the sweeps of non-urgent
+// inputs and waits for it to be applied.
+func (s *UtxoSweeper) setPaused(pause bool) error {
+	req := &pauseReq{
+		pause: pause,
+		done:  make(chan struct{}),
+	}
+	select {
+	case s.pauseReqs <- req:
+	case <-s.quit:
+		return ErrSweeperShuttingDown
+	}
+
+	select {
+	case <-req.done:
+		return nil
+	case <-s.quit:
+		return ErrSweeperShuttingDown
+	}
+}
+
 // handlePendingSweepsReq handles a request to retrieve all pending inputs the
 // UtxoSweeper is attempting to sweep.
 func (s *UtxoSweeper) handlePendingSweepsReq(
@@ -1172,6 +1398,19 @@ func (s *UtxoSweeper) handlePendingSweepsReq(
 			NextBroadcastHeight: uint32(pendingInput.minPublishHeight),
 			Params:              pendingInput.params,
 		}
+
+		if pendingInput.lastSweepTx == nil {
+			continue
+		}
+
+		sweepTx := *pendingInput.lastSweepTx
+		replaced, err := s.cfg.Store.ListReplacements(sweepTx)
+		if err != nil {
+			log.Errorf("Unable to fetch replacements of sweep tx "+
+				"%v: %v", sweepTx, err)
+		}
+		pendingInputs[op].SweepTx = &sweepTx
+		pendingInputs[op].ReplacedTxs = replaced
 	}
 
 	return pendingInputs
@@ -1241,6 +1480,9 @@ func (s *UtxoSweeper) handleUpdateReq(req *updateReq, bestHeight int32) (
 	newParams := pendingInput.params
 	newParams.Fee = req.params.Fee
 	newParams.Force = req.params.Force
+	if req.params.DeadlineHeight != 0 {
+		newParams.DeadlineHeight = req.params.DeadlineHeight
+	}
 
 	log.Debugf("Updating sweep parameters for %v from %v to %v", req.input,
 		pendingInput.params, newParams)
@@ -1316,6 +1558,12 @@ func (s *UtxoSweeper) ListSweeps() ([]chainhash.Hash, error) {
 	return s.cfg.Store.ListSweeps()
 }
 
+// IsSweepTx returns whether the given transaction is a sweep published by the
+// sweeper.
+func (s *UtxoSweeper) IsSweepTx(hash chainhash.Hash) (bool, error) {
+	return s.cfg.Store.IsOurTx(hash)
+}
+
 // init initializes the random generator for random input rescheduling.
 func init() {
 	rand.Seed(time.Now().Unix())
@@ -1,6 +1,7 @@
 package sweep
 
 import (
+	"errors"
 	"os"
 	"runtime/debug"
 	"runtime/pprof"
@@ -1261,6 +1262,136 @@ func TestBumpFeeRBF(t *testing.T) {
 	ctx.finish(1)
 }
 
+// TestDeadlineFeeBump ensures that the UtxoSweeper replaces an unconfirmed
+// sweep with a higher fee rate one once the deadline of its input approaches
+// and tracks the replaced sweep.
+func TestDeadlineFeeBump(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	const feeRate = 20000
+	feePref := FeePreference{ConfTarget: 6}
+	ctx.estimator.blocksToFee[feePref.ConfTarget] = feeRate
+
+	input := createTestInput(
+		dcrutil.AtomsPerCoin, input.CommitmentTimeLock,
+	)
+	resultChan, err := ctx.sweeper.SweepInput(
+		&input, Params{
+			Fee:            feePref,
+			DeadlineHeight: mockChainHeight + 3,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The first sweep is broadcast with the estimated fee rate.
+	ctx.tick()
+	sweepTx := ctx.receiveTx()
+	assertTxFeeRate(t, &sweepTx, feeRate, &input)
+
+	// Simulate the sweep being dropped from the mempool without
+	// confirming, so that it can be replaced.
+	ctx.backend.deleteUnconfirmed(sweepTx.TxHash())
+
+	// On the next block, the input is within the deadline window, so a new
+	// sweep with a bumped fee rate is expected even though the estimate is
+	// unchanged.
+	ctx.notifier.NotifyEpoch(mockChainHeight + 1)
+	ctx.tick()
+	bumpedTx := ctx.receiveTx()
+	bumpedFeeRate := chainfee.AtomPerKByte(
+		feeRate * (100 + deadlineFeeBumpPercent) / 100,
+	)
+	assertTxFeeRate(t, &bumpedTx, bumpedFeeRate, &input)
+
+	// The pending input should report the replacement chain.
+	pendingInputs, err := ctx.sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput, ok := pendingInputs[*input.OutPoint()]
+	if !ok {
+		t.Fatalf("expected input %v to be pending", input.OutPoint())
+	}
+	bumpedHash := bumpedTx.TxHash()
+	if pendingInput.SweepTx == nil || *pendingInput.SweepTx != bumpedHash {
+		t.Fatalf("expected sweep tx %v, got %v", bumpedHash,
+			pendingInput.SweepTx)
+	}
+	if len(pendingInput.ReplacedTxs) != 1 ||
+		pendingInput.ReplacedTxs[0] != sweepTx.TxHash() {
+
+		t.Fatalf("expected replaced txs [%v], got %v",
+			sweepTx.TxHash(), pendingInput.ReplacedTxs)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+
+	ctx.finish(1)
+}
+
+// TestTrackReplacementsFailure ensures that a replacement sweep is still
+// tracked by its inputs when its replacements can't be recorded.
+func TestTrackReplacementsFailure(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	const feeRate = 20000
+	feePref := FeePreference{ConfTarget: 6}
+	ctx.estimator.blocksToFee[feePref.ConfTarget] = feeRate
+
+	input := createTestInput(
+		dcrutil.AtomsPerCoin, input.CommitmentTimeLock,
+	)
+	resultChan, err := ctx.sweeper.SweepInput(
+		&input, Params{
+			Fee:            feePref,
+			DeadlineHeight: mockChainHeight + 3,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	sweepTx := ctx.receiveTx()
+	ctx.backend.deleteUnconfirmed(sweepTx.TxHash())
+
+	// Fail recording the replacement of the first sweep by the bumped
+	// one.
+	ctx.store.replacementErr = errors.New("store failure")
+
+	ctx.notifier.NotifyEpoch(mockChainHeight + 1)
+	ctx.tick()
+	bumpedTx := ctx.receiveTx()
+
+	// The input must still refer to the published sweep, without any
+	// recorded replacement.
+	pendingInputs, err := ctx.sweeper.PendingInputs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pendingInput, ok := pendingInputs[*input.OutPoint()]
+	if !ok {
+		t.Fatalf("expected input %v to be pending", input.OutPoint())
+	}
+	bumpedHash := bumpedTx.TxHash()
+	if pendingInput.SweepTx == nil || *pendingInput.SweepTx != bumpedHash {
+		t.Fatalf("expected sweep tx %v, got %v", bumpedHash,
+			pendingInput.SweepTx)
+	}
+	if len(pendingInput.ReplacedTxs) != 0 {
+		t.Fatalf("expected no replaced txs, got %v",
+			pendingInput.ReplacedTxs)
+	}
+
+	ctx.backend.mine()
+	ctx.expectResult(resultChan, nil)
+
+	ctx.finish(1)
+}
+
 // TestExclusiveGroup tests the sweeper exclusive group functionality.
 func TestExclusiveGroup(t *testing.T) {
 	ctx := createSweeperTestContext(t)
@@ -1320,3 +1451,76 @@ func TestExclusiveGroup(t *testing.T) {
 		t.Fatal("expected third input to be canceled")
 	}
 }
+
+// TestPauseNonUrgent asserts that only the urgent inputs are swept while the
+// sweeps of non-urgent inputs are paused, and that the non-urgent inputs are
+// swept once resumed.
+func TestPauseNonUrgent(t *testing.T) {
+	ctx := createSweeperTestContext(t)
+
+	if err := ctx.sweeper.PauseNonUrgent(); err != nil {
+		t.Fatal(err)
+	}
+
+	nonUrgentChan, err := ctx.sweeper.SweepInput(
+		spendableInputs[0], Params{
+			Fee:        FeePreference{ConfTarget: 6},
+			Deferrable: true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The non-urgent input alone doesn't start the sweep timer.
+	ctx.assertNoNewTimer()
+
+	deadlineChan, err := ctx.sweeper.SweepInput(
+		spendableInputs[1], Params{
+			Fee:            FeePreference{ConfTarget: 6},
+			DeadlineHeight: mockChainHeight + 100,
+			Deferrable:     true,
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the input with a deadline is swept.
+	ctx.tick()
+	deadlineTx := ctx.receiveTx()
+	assertTxSweepsInputs(t, &deadlineTx, spendableInputs[1])
+
+	ctx.backend.mine()
+	ctx.expectResult(deadlineChan, nil)
+
+	// Inputs resolving a contract are never deferred, even without a
+	// deadline.
+	contractChan, err := ctx.sweeper.SweepInput(
+		spendableInputs[2], defaultFeePref,
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	contractTx := ctx.receiveTx()
+	assertTxSweepsInputs(t, &contractTx, spendableInputs[2])
+
+	ctx.backend.mine()
+	ctx.expectResult(contractChan, nil)
+
+	// Once resumed, the non-urgent input is swept too.
+	if err := ctx.sweeper.ResumeNonUrgent(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx.tick()
+	nonUrgentTx := ctx.receiveTx()
+	assertTxSweepsInputs(t, &nonUrgentTx, spendableInputs[0])
+
+	ctx.backend.mine()
+	ctx.expectResult(nonUrgentChan, nil)
+
+	ctx.finish(1)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"strings"
 )
 
 var (
@@ -215,3 +216,12 @@ func (c *Controller) AddOnion(cfg AddOnionConfig) (*OnionAddr, error) {
 		Port:         cfg.VirtualPort,
 	}, nil
 }
+
+// DelOnion tells the Tor server to stop serving the onion service with the
+// given onion address. Only onion services created through the current
+// connection with the Tor server can be deleted.
+func (c *Controller) DelOnion(addr *OnionAddr) error {
+	serviceID := strings.TrimSuffix(addr.OnionService, ".onion")
+	_, _, err := c.sendCommand(fmt.Sprintf("DEL_ONION %s", serviceID))
+	return err
+}
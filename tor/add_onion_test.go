@@ -3,6 +3,8 @@ package tor
 import (
 	"bytes"
 	"io/ioutil"
+	"net"
+	"net/textproto"
 	"path/filepath"
 	"testing"
 )
@@ -49,3 +51,36 @@ func TestOnionFile(t *testing.T) {
 		t.Fatal("found deleted private key")
 	}
 }
+
+// TestDelOnion tests that DelOnion requests the Tor server to remove the onion
+// service with the given address.
+func TestDelOnion(t *testing.T) {
+	t.Parallel()
+
+	client, server := net.Pipe()
+	defer client.Close()
+	defer server.Close()
+
+	c := &Controller{conn: textproto.NewConn(client)}
+
+	cmds := make(chan string, 1)
+	go func() {
+		conn := textproto.NewConn(server)
+		cmd, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		cmds <- cmd
+		conn.PrintfLine("250 OK")
+	}()
+
+	addr := &OnionAddr{OnionService: "testonion1234567.onion", Port: 9735}
+	if err := c.DelOnion(addr); err != nil {
+		t.Fatalf("unable to delete onion service: %v", err)
+	}
+
+	cmd := <-cmds
+	if cmd != "DEL_ONION testonion1234567" {
+		t.Fatalf("unexpected command sent: %v", cmd)
+	}
+}
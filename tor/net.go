@@ -3,6 +3,15 @@ package tor
 import (
 	"errors"
 	"net"
+	"sync"
+
+	"golang.org/x/net/proxy"
+)
+
+const (
+	// maxRotatedCircuits is the maximum number of addresses whose rotated
+	// circuit is remembered by a ProxyNet.
+	maxRotatedCircuits = 1000
 )
 
 // TODO: this interface and its implementations should ideally be moved
@@ -67,6 +76,26 @@ type ProxyNet struct {
 	// means that our traffic may be harder to correlate as each connection
 	// will now use a distinct circuit.
 	StreamIsolation bool
+
+	// circuits holds the SOCKS credentials of the addresses whose circuit
+	// was rotated with RotateCircuit. Connections to other addresses share
+	// the default circuits of Tor, unless StreamIsolation is set. It holds
+	// at most maxRotatedCircuits entries, the least recently rotated ones
+	// being evicted first.
+	circuits   map[string]*rotatedCircuit
+	rotations  uint64
+	circuitsMu sync.Mutex
+}
+
+// rotatedCircuit is the circuit used to connect to an address since it was
+// last rotated.
+type rotatedCircuit struct {
+	// auth is the SOCKS credentials that isolate the circuit.
+	auth *proxy.Auth
+
+	// rotation is the sequence number of the rotation that created the
+	// circuit, used to evict the least recently rotated circuits.
+	rotation uint64
 }
 
 // Dial uses the Tor Dial function in order to establish connections through
@@ -77,9 +106,73 @@ func (p *ProxyNet) Dial(network, address string) (net.Conn, error) {
 	default:
 		return nil, errors.New("cannot dial non-tcp network via Tor")
 	}
+
+	if !p.StreamIsolation {
+		p.circuitsMu.Lock()
+		circuit, ok := p.circuits[address]
+		p.circuitsMu.Unlock()
+
+		if ok {
+			return dialAuth(address, p.SOCKS, circuit.auth)
+		}
+	}
+
 	return Dial(address, p.SOCKS, p.StreamIsolation)
 }
 
+// RotateCircuit makes the next connections to the given address use a fresh
+// circuit, distinct from the circuits used by any prior connection. Existing
+// connections are unaffected and keep using their circuit until closed.
+func (p *ProxyNet) RotateCircuit(address string) error {
+	// With stream isolation, every connection already uses a fresh
+	// circuit.
+	if p.StreamIsolation {
+		return nil
+	}
+
+	auth, err := randomAuth()
+	if err != nil {
+		return err
+	}
+
+	p.circuitsMu.Lock()
+	defer p.circuitsMu.Unlock()
+
+	if p.circuits == nil {
+		p.circuits = make(map[string]*rotatedCircuit)
+	}
+
+	// Once the limit is reached, the address whose circuit was rotated
+	// the longest time ago goes back to the default circuits.
+	if _, ok := p.circuits[address]; !ok &&
+		len(p.circuits) >= maxRotatedCircuits {
+
+		var (
+			oldestAddr     string
+			oldestRotation uint64
+		)
+		for addr, circuit := range p.circuits {
+			if oldestAddr != "" &&
+				circuit.rotation >= oldestRotation {
+
+				continue
+			}
+
+			oldestAddr = addr
+			oldestRotation = circuit.rotation
+		}
+		delete(p.circuits, oldestAddr)
+	}
+
+	p.rotations++
+	p.circuits[address] = &rotatedCircuit{
+		auth:     auth,
+		rotation: p.rotations,
+	}
+
+	return nil
+}
+
 // LookupHost uses the Tor LookupHost function in order to resolve hosts over
 // Tor.
 func (p *ProxyNet) LookupHost(host string) ([]string, error) {
@@ -0,0 +1,212 @@
+package tor
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// socksAuth is the username and password a connection authenticated with to
+// the mock SOCKS5 proxy. Both are empty if no authentication was used.
+type socksAuth struct {
+	user     string
+	password string
+}
+
+// serveMockSOCKS accepts connections on the listener, negotiating a SOCKS5
+// CONNECT request on each and reporting the credentials they used.
+func serveMockSOCKS(t *testing.T, l net.Listener, auths chan<- socksAuth) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			auth, err := mockSOCKSHandshake(conn)
+			if err != nil {
+				t.Errorf("socks handshake failed: %v", err)
+				conn.Close()
+				return
+			}
+			auths <- auth
+		}()
+	}
+}
+
+// mockSOCKSHandshake reads a SOCKS5 greeting and CONNECT request, accepting
+// username and password authentication if offered.
+func mockSOCKSHandshake(conn net.Conn) (socksAuth, error) {
+	var auth socksAuth
+
+	var header [2]byte
+	if _, err := io.ReadFull(conn, header[:]); err != nil {
+		return auth, err
+	}
+	methods := make([]byte, header[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return auth, err
+	}
+
+	method := byte(0x00)
+	for _, m := range methods {
+		if m == 0x02 {
+			method = m
+		}
+	}
+	if _, err := conn.Write([]byte{0x05, method}); err != nil {
+		return auth, err
+	}
+
+	readString := func() (string, error) {
+		var l [1]byte
+		if _, err := io.ReadFull(conn, l[:]); err != nil {
+			return "", err
+		}
+		b := make([]byte, l[0])
+		_, err := io.ReadFull(conn, b)
+		return string(b), err
+	}
+
+	if method == 0x02 {
+		var version [1]byte
+		if _, err := io.ReadFull(conn, version[:]); err != nil {
+			return auth, err
+		}
+
+		var err error
+		if auth.user, err = readString(); err != nil {
+			return auth, err
+		}
+		if auth.password, err = readString(); err != nil {
+			return auth, err
+		}
+		if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+			return auth, err
+		}
+	}
+
+	// Read the CONNECT request, which targets a domain name.
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return auth, err
+	}
+	if _, err := readString(); err != nil {
+		return auth, err
+	}
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return auth, err
+	}
+
+	reply := []byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}
+	binary.BigEndian.PutUint16(reply[8:], 9735)
+	_, err := conn.Write(reply)
+
+	return auth, err
+}
+
+// TestProxyNetRotateCircuit asserts that connections to an address share the
+// default circuit until it is rotated, after which they use the credentials of
+// the new circuit.
+func TestProxyNetRotateCircuit(t *testing.T) {
+	t.Parallel()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer l.Close()
+
+	auths := make(chan socksAuth, 1)
+	go serveMockSOCKS(t, l, auths)
+
+	proxyNet := &ProxyNet{SOCKS: l.Addr().String()}
+
+	addrA := strings.Repeat("a", V3Len-OnionSuffixLen) + ".onion:9735"
+	addrB := strings.Repeat("b", V3Len-OnionSuffixLen) + ".onion:9735"
+
+	dial := func(address string) socksAuth {
+		t.Helper()
+
+		conn, err := proxyNet.Dial("tcp", address)
+		if err != nil {
+			t.Fatalf("unable to dial %v: %v", address, err)
+		}
+		conn.Close()
+
+		return <-auths
+	}
+
+	// Before rotating, no credentials are used so the default circuits of
+	// Tor are shared.
+	if auth := dial(addrA); auth != (socksAuth{}) {
+		t.Fatalf("expected no credentials, got %v", auth)
+	}
+
+	if err := proxyNet.RotateCircuit(addrA); err != nil {
+		t.Fatalf("unable to rotate circuit: %v", err)
+	}
+
+	// The rotated address uses the same new credentials for all its
+	// connections, while other addresses are unaffected.
+	first := dial(addrA)
+	if first == (socksAuth{}) {
+		t.Fatalf("expected credentials after rotation")
+	}
+	if auth := dial(addrA); auth != first {
+		t.Fatalf("expected credentials %v, got %v", first, auth)
+	}
+	if auth := dial(addrB); auth != (socksAuth{}) {
+		t.Fatalf("expected no credentials, got %v", auth)
+	}
+
+	// Rotating again switches to another circuit.
+	if err := proxyNet.RotateCircuit(addrA); err != nil {
+		t.Fatalf("unable to rotate circuit: %v", err)
+	}
+	if auth := dial(addrA); auth == first || auth == (socksAuth{}) {
+		t.Fatalf("expected new credentials, got %v", auth)
+	}
+}
+
+// TestProxyNetRotatedCircuitsLimit asserts that only the most recently rotated
+// circuits are remembered.
+func TestProxyNetRotatedCircuitsLimit(t *testing.T) {
+	t.Parallel()
+
+	proxyNet := &ProxyNet{}
+	rotate := func(address string) {
+		t.Helper()
+
+		if err := proxyNet.RotateCircuit(address); err != nil {
+			t.Fatalf("unable to rotate circuit: %v", err)
+		}
+	}
+
+	for i := 0; i < maxRotatedCircuits; i++ {
+		rotate(fmt.Sprintf("127.0.0.1:%d", i))
+	}
+
+	// Rotating the first address again makes the second one the least
+	// recently rotated, so it is evicted by the next address.
+	rotate("127.0.0.1:0")
+	rotate("127.0.0.1:9735")
+
+	if len(proxyNet.circuits) != maxRotatedCircuits {
+		t.Fatalf("expected %d circuits, got %d", maxRotatedCircuits,
+			len(proxyNet.circuits))
+	}
+	if _, ok := proxyNet.circuits["127.0.0.1:1"]; ok {
+		t.Fatalf("expected least recently rotated circuit to be " +
+			"evicted")
+	}
+	for _, addr := range []string{"127.0.0.1:0", "127.0.0.1:9735"} {
+		if _, ok := proxyNet.circuits[addr]; !ok {
+			t.Fatalf("expected circuit of %v to be kept", addr)
+		}
+	}
+}
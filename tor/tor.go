@@ -55,7 +55,19 @@ func (c *proxyConn) RemoteAddr() net.Addr {
 // around net.Conn in order to expose the actual remote address we're dialing,
 // rather than the proxy's address.
 func Dial(address, socksAddr string, streamIsolation bool) (net.Conn, error) {
-	conn, err := dial(address, socksAddr, streamIsolation)
+	auth, err := isolationAuth(streamIsolation)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialAuth(address, socksAddr, auth)
+}
+
+// dialAuth is like Dial, but uses the given SOCKS credentials. Tor uses a
+// distinct circuit for each set of credentials, while streams using the same
+// credentials may share a circuit.
+func dialAuth(address, socksAddr string, auth *proxy.Auth) (net.Conn, error) {
+	conn, err := dialSOCKS(address, socksAddr, auth)
 	if err != nil {
 		return nil, err
 	}
@@ -80,23 +92,17 @@ func Dial(address, socksAddr string, streamIsolation bool) (net.Conn, error) {
 // connection will use a fresh circuit, rather than possibly re-using an
 // existing circuit.
 func dial(address, socksAddr string, streamIsolation bool) (net.Conn, error) {
-	// If we were requested to force stream isolation for this connection,
-	// we'll populate the authentication credentials with random data as
-	// Tor will create a new circuit for each set of credentials.
-	var auth *proxy.Auth
-	if streamIsolation {
-		var b [16]byte
-		if _, err := rand.Read(b[:]); err != nil {
-			return nil, err
-		}
-
-		auth = &proxy.Auth{
-			User:     hex.EncodeToString(b[:8]),
-			Password: hex.EncodeToString(b[8:]),
-		}
-	}
-
-	// Establish the connection through Tor's SOCKS proxy.
+	auth, err := isolationAuth(streamIsolation)
+	if err != nil {
+		return nil, err
+	}
+
+	return dialSOCKS(address, socksAddr, auth)
+}
+
+// dialSOCKS establishes a connection to the address through Tor's SOCKS proxy
+// with the given credentials.
+func dialSOCKS(address, socksAddr string, auth *proxy.Auth) (net.Conn, error) {
 	dialer, err := proxy.SOCKS5("tcp", socksAddr, auth, proxy.Direct)
 	if err != nil {
 		return nil, err
@@ -105,6 +111,32 @@ func dial(address, socksAddr string, streamIsolation bool) (net.Conn, error) {
 	return dialer.Dial("tcp", address)
 }
 
+// isolationAuth returns the SOCKS credentials to use for a new connection. If
+// we were requested to force stream isolation for this connection, we'll
+// populate the authentication credentials with random data as Tor will create
+// a new circuit for each set of credentials.
+func isolationAuth(streamIsolation bool) (*proxy.Auth, error) {
+	if !streamIsolation {
+		return nil, nil
+	}
+
+	return randomAuth()
+}
+
+// randomAuth returns random SOCKS credentials, which Tor won't share a circuit
+// for with any other credentials.
+func randomAuth() (*proxy.Auth, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return nil, err
+	}
+
+	return &proxy.Auth{
+		User:     hex.EncodeToString(b[:8]),
+		Password: hex.EncodeToString(b[8:]),
+	}, nil
+}
+
 // LookupHost performs DNS resolution on a given host via Tor's native resolver.
 // Only IPv4 addresses are returned.
 func LookupHost(host, socksAddr string) ([]string, error) {
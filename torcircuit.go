@@ -0,0 +1,195 @@
+package dcrlnd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/brontide"
+	"github.com/decred/dcrlnd/peer"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/tor"
+)
+
+const (
+	// torCircuitCheckInterval is how often the connections to our peers
+	// are checked for a due circuit rotation.
+	torCircuitCheckInterval = time.Minute
+)
+
+var (
+	// ErrTorInactive is returned when rotating the circuit of a peer
+	// while connections aren't routed through Tor.
+	ErrTorInactive = errors.New("outbound connections are not routed " +
+		"through Tor")
+)
+
+// RotatePeerCircuit moves the connection with the given peer onto a fresh Tor
+// circuit. A new connection is established over the new circuit before the
+// current one is closed, so the peer is replaced just as if it had reconnected
+// and its channels are re-established on the new connection. If the new
+// connection can't be established, the current one is kept.
+//
+// NOTE: This function is safe for concurrent access.
+func (s *server) RotatePeerCircuit(pubKey *secp256k1.PublicKey) error {
+	proxyNet, ok := s.cfg.net.(*tor.ProxyNet)
+	if !ok {
+		return ErrTorInactive
+	}
+
+	pubBytes := pubKey.SerializeCompressed()
+	pubStr := string(pubBytes)
+
+	s.mu.RLock()
+	oldPeer, err := s.findPeerByPubStr(pubStr)
+	s.mu.RUnlock()
+	if err == ErrPeerNotConnected {
+		return fmt.Errorf("peer %x is not connected", pubBytes)
+	}
+
+	// The circuits of inbound connections to our onion service are built
+	// by the remote peer, so we can only rotate the ones we dialed.
+	if oldPeer.Inbound() {
+		return fmt.Errorf("connection with peer %x is inbound, its "+
+			"circuit is chosen by the peer", pubBytes)
+	}
+
+	addr := oldPeer.NetAddress()
+	if err := proxyNet.RotateCircuit(addr.Address.String()); err != nil {
+		return err
+	}
+
+	srvrLog.Infof("Rotating Tor circuit of peer %v", oldPeer)
+
+	// Dial the peer over the new circuit without holding the server mutex,
+	// as connecting through Tor may take a while.
+	conn, err := brontide.Dial(s.identityECDH, addr, s.cfg.net.Dial)
+	if err != nil {
+		return fmt.Errorf("unable to connect to %v over a new "+
+			"circuit: %v", addr, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Make sure the peer we're replacing is still the active one, and
+	// that no other connection is already due to replace it.
+	connectedPeer, err := s.findPeerByPubStr(pubStr)
+	if err != nil || connectedPeer != oldPeer {
+		conn.Close()
+		return fmt.Errorf("connection with peer %x changed while "+
+			"rotating its circuit", pubBytes)
+	}
+	if _, ok := s.scheduledPeerConnection[pubStr]; ok {
+		conn.Close()
+		return fmt.Errorf("connection with peer %x already being "+
+			"replaced", pubBytes)
+	}
+
+	// The new connection replaces any pending connection request, which
+	// will be relaunched by the peer termination watcher if this is a
+	// persistent peer and the new connection is lost.
+	s.cancelConnReqs(pubStr, nil)
+
+	// Remove the current peer and schedule the new connection once it has
+	// fully disconnected, signaling that the peer termination watcher
+	// must not attempt to reconnect.
+	s.removePeer(oldPeer)
+	s.ignorePeerTermination[oldPeer] = struct{}{}
+	s.scheduledPeerConnection[pubStr] = func() {
+		s.peerConnected(conn, nil, false)
+	}
+
+	return nil
+}
+
+// rotateTorCircuits periodically rotates the Tor circuits of the outbound
+// connections with our peers, once they have been in use for longer than the
+// configured rotation interval. As the connections are established at
+// different times, their rotations are naturally spread out.
+//
+// NOTE: This MUST be run as a goroutine.
+func (s *server) rotateTorCircuits() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(torCircuitCheckInterval)
+	defer ticker.Stop()
+
+	// failed tracks the peers whose rotation failed, so they're only
+	// retried after another rotation interval.
+	failed := make(map[route.Vertex]time.Time)
+
+	for {
+		select {
+		case <-ticker.C:
+			due := s.peersDueCircuitRotation()
+			failed = pruneFailedRotations(
+				failed, due, s.cfg.Tor.CircuitRotation,
+			)
+
+			for _, p := range due {
+				if _, ok := failed[p.PubKey()]; ok {
+					continue
+				}
+
+				err := s.RotatePeerCircuit(p.IdentityKey())
+				if err != nil {
+					srvrLog.Warnf("Unable to rotate Tor "+
+						"circuit of peer %v: %v", p, err)
+					failed[p.PubKey()] = time.Now()
+				}
+
+				select {
+				case <-s.quit:
+					return
+				default:
+				}
+			}
+
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+// pruneFailedRotations returns the failed rotations that must not be retried
+// yet: those that failed within the last rotation interval, of peers that are
+// still due a rotation. The peers that disconnected or reconnected since are
+// forgotten.
+func pruneFailedRotations(failed map[route.Vertex]time.Time,
+	due []*peer.Brontide,
+	interval time.Duration) map[route.Vertex]time.Time {
+
+	pruned := make(map[route.Vertex]time.Time)
+	for _, p := range due {
+		t, ok := failed[p.PubKey()]
+		if !ok || time.Since(t) >= interval {
+			continue
+		}
+
+		pruned[p.PubKey()] = t
+	}
+
+	return pruned
+}
+
+// peersDueCircuitRotation returns the peers connected through an outbound
+// connection that has been in use for longer than the circuit rotation
+// interval.
+func (s *server) peersDueCircuitRotation() []*peer.Brontide {
+	var due []*peer.Brontide
+	for _, p := range s.Peers() {
+		if p.Inbound() {
+			continue
+		}
+
+		if time.Since(p.StartTime()) < s.cfg.Tor.CircuitRotation {
+			continue
+		}
+
+		due = append(due, p)
+	}
+
+	return due
+}
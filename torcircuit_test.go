@@ -0,0 +1,329 @@
+package dcrlnd
+
+import (
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrd/connmgr"
+	"github.com/decred/dcrd/dcrec/secp256k1/v3"
+	"github.com/decred/dcrlnd/brontide"
+	"github.com/decred/dcrlnd/keychain"
+	"github.com/decred/dcrlnd/lnwire"
+	"github.com/decred/dcrlnd/peer"
+	"github.com/decred/dcrlnd/peernotifier"
+	"github.com/decred/dcrlnd/queue"
+	"github.com/decred/dcrlnd/routing/route"
+	"github.com/decred/dcrlnd/tor"
+)
+
+// serveForwardingSOCKS accepts SOCKS5 connections on the listener and forwards
+// them to the target address, whatever the requested destination, reporting
+// whether each connection was authenticated.
+func serveForwardingSOCKS(l net.Listener, target string,
+	authenticated chan<- bool) {
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			auth, err := forwardingSOCKSHandshake(conn)
+			if err != nil {
+				return
+			}
+			authenticated <- auth
+
+			targetConn, err := net.Dial("tcp", target)
+			if err != nil {
+				return
+			}
+			defer targetConn.Close()
+
+			go io.Copy(targetConn, conn)
+			io.Copy(conn, targetConn)
+		}()
+	}
+}
+
+// forwardingSOCKSHandshake negotiates a SOCKS5 CONNECT request to a domain
+// name, accepting username and password authentication if offered.
+func forwardingSOCKSHandshake(conn net.Conn) (bool, error) {
+	readBytes := func(n int) ([]byte, error) {
+		b := make([]byte, n)
+		_, err := io.ReadFull(conn, b)
+		return b, err
+	}
+	readString := func() error {
+		l, err := readBytes(1)
+		if err != nil {
+			return err
+		}
+		_, err = readBytes(int(l[0]))
+		return err
+	}
+
+	header, err := readBytes(2)
+	if err != nil {
+		return false, err
+	}
+	methods, err := readBytes(int(header[1]))
+	if err != nil {
+		return false, err
+	}
+
+	var auth bool
+	for _, m := range methods {
+		if m == 0x02 {
+			auth = true
+		}
+	}
+	method := byte(0x00)
+	if auth {
+		method = 0x02
+	}
+	if _, err := conn.Write([]byte{0x05, method}); err != nil {
+		return false, err
+	}
+
+	if auth {
+		if _, err := readBytes(1); err != nil {
+			return false, err
+		}
+		if err := readString(); err != nil {
+			return false, err
+		}
+		if err := readString(); err != nil {
+			return false, err
+		}
+		if _, err := conn.Write([]byte{0x01, 0x00}); err != nil {
+			return false, err
+		}
+	}
+
+	if _, err := readBytes(4); err != nil {
+		return false, err
+	}
+	if err := readString(); err != nil {
+		return false, err
+	}
+	if _, err := readBytes(2); err != nil {
+		return false, err
+	}
+
+	reply := []byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0x26, 0x07}
+	_, err = conn.Write(reply)
+
+	return auth, err
+}
+
+// newRotationTestPeer returns an unstarted peer connected over the given
+// connection to the node with the given key at an onion address.
+func newRotationTestPeer(t *testing.T, conn net.Conn,
+	pubKey *secp256k1.PublicKey, inbound bool) *peer.Brontide {
+
+	errBuffer, err := queue.NewCircularBuffer(peer.ErrorBufferSize)
+	if err != nil {
+		t.Fatalf("unable to create error buffer: %v", err)
+	}
+
+	var pubKeyBytes [33]byte
+	copy(pubKeyBytes[:], pubKey.SerializeCompressed())
+
+	return peer.NewBrontide(peer.Config{
+		Conn: conn,
+		Addr: &lnwire.NetAddress{
+			IdentityKey: pubKey,
+			Address: &tor.OnionAddr{
+				OnionService: strings.Repeat(
+					"a", tor.V3Len-tor.OnionSuffixLen,
+				) + ".onion",
+				Port: 9735,
+			},
+		},
+		PubKeyBytes: pubKeyBytes,
+		Inbound:     inbound,
+		ErrorBuffer: errBuffer,
+	})
+}
+
+// TestRotatePeerCircuit asserts that rotating the circuit of a peer dials it
+// over a new circuit and schedules the new connection to replace the current
+// one, which is only replaced if the new connection is established.
+func TestRotatePeerCircuit(t *testing.T) {
+	t.Parallel()
+
+	localKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remoteKey, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		t.Fatalf("unable to generate key: %v", err)
+	}
+	remotePub := remoteKey.PubKey()
+	pubStr := string(remotePub.SerializeCompressed())
+
+	// The remote peer listens for brontide connections, which our mock
+	// Tor proxy forwards to it.
+	remoteListener, err := brontide.NewListener(
+		&keychain.PrivKeyECDH{PrivKey: remoteKey}, "127.0.0.1:0",
+	)
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer remoteListener.Close()
+
+	socksListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer socksListener.Close()
+
+	authenticated := make(chan bool, 1)
+	go serveForwardingSOCKS(
+		socksListener, remoteListener.Addr().String(), authenticated,
+	)
+
+	peerNotifier := peernotifier.New()
+	if err := peerNotifier.Start(); err != nil {
+		t.Fatalf("unable to start peer notifier: %v", err)
+	}
+	defer peerNotifier.Stop()
+
+	proxyNet := &tor.ProxyNet{SOCKS: socksListener.Addr().String()}
+	localECDH := &keychain.PrivKeyECDH{PrivKey: localKey}
+	s := &server{
+		cfg:                     &Config{net: proxyNet},
+		identityECDH:            localECDH,
+		peersByPub:              make(map[string]*peer.Brontide),
+		inboundPeers:            make(map[string]*peer.Brontide),
+		outboundPeers:           make(map[string]*peer.Brontide),
+		persistentConnReqs:      make(map[string][]*connmgr.ConnReq),
+		persistentRetryCancels:  make(map[string]chan struct{}),
+		peerErrors:              make(map[string]*queue.CircularBuffer),
+		ignorePeerTermination:   make(map[*peer.Brontide]struct{}),
+		scheduledPeerConnection: make(map[string]func()),
+		peerNotifier:            peerNotifier,
+	}
+
+	// Rotating the circuit of a peer that isn't connected fails.
+	if err := s.RotatePeerCircuit(remotePub); err == nil {
+		t.Fatalf("expected rotation of unknown peer to fail")
+	}
+
+	// The circuits of inbound connections can't be rotated.
+	conn, _ := net.Pipe()
+	inboundPeer := newRotationTestPeer(t, conn, remotePub, true)
+	s.peersByPub[pubStr] = inboundPeer
+	s.inboundPeers[pubStr] = inboundPeer
+	if err := s.RotatePeerCircuit(remotePub); err == nil {
+		t.Fatalf("expected rotation of inbound peer to fail")
+	}
+	delete(s.inboundPeers, pubStr)
+
+	conn, _ = net.Pipe()
+	oldPeer := newRotationTestPeer(t, conn, remotePub, false)
+	s.peersByPub[pubStr] = oldPeer
+	s.outboundPeers[pubStr] = oldPeer
+
+	// Connections can't be rotated unless they're routed through Tor.
+	s.cfg.net = &tor.ClearNet{}
+	if err := s.RotatePeerCircuit(remotePub); err != ErrTorInactive {
+		t.Fatalf("expected ErrTorInactive, got %v", err)
+	}
+	s.cfg.net = proxyNet
+
+	// The peer is dialed over a new circuit, and the new connection is
+	// scheduled to replace the current peer once it is disconnected.
+	if err := s.RotatePeerCircuit(remotePub); err != nil {
+		t.Fatalf("unable to rotate circuit: %v", err)
+	}
+	select {
+	case auth := <-authenticated:
+		if !auth {
+			t.Fatalf("expected new circuit to be isolated")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("peer not dialed over new circuit")
+	}
+
+	remoteConn, err := remoteListener.Accept()
+	if err != nil {
+		t.Fatalf("unable to accept connection: %v", err)
+	}
+	defer remoteConn.Close()
+
+	if _, ok := s.peersByPub[pubStr]; ok {
+		t.Fatalf("expected current peer to be removed")
+	}
+	if _, ok := s.ignorePeerTermination[oldPeer]; !ok {
+		t.Fatalf("expected termination of current peer to be ignored")
+	}
+	if _, ok := s.scheduledPeerConnection[pubStr]; !ok {
+		t.Fatalf("expected new connection to be scheduled")
+	}
+	select {
+	case <-oldPeer.QuitSignal():
+	default:
+		t.Fatalf("expected current peer to be disconnected")
+	}
+
+	// If the peer can't be dialed over the new circuit, the current
+	// connection is kept.
+	delete(s.scheduledPeerConnection, pubStr)
+	conn, _ = net.Pipe()
+	currentPeer := newRotationTestPeer(t, conn, remotePub, false)
+	s.peersByPub[pubStr] = currentPeer
+	s.outboundPeers[pubStr] = currentPeer
+
+	socksListener.Close()
+	if err := s.RotatePeerCircuit(remotePub); err == nil {
+		t.Fatalf("expected rotation to fail")
+	}
+	if s.peersByPub[pubStr] != currentPeer {
+		t.Fatalf("expected current peer to be kept")
+	}
+	if _, ok := s.scheduledPeerConnection[pubStr]; ok {
+		t.Fatalf("unexpected scheduled connection")
+	}
+}
+
+// TestPruneFailedRotations asserts that failed rotations are only remembered
+// for peers still due a rotation, within the rotation interval.
+func TestPruneFailedRotations(t *testing.T) {
+	t.Parallel()
+
+	const interval = time.Hour
+
+	newPeer := func() *peer.Brontide {
+		key, err := secp256k1.GeneratePrivateKey()
+		if err != nil {
+			t.Fatalf("unable to generate key: %v", err)
+		}
+		conn, _ := net.Pipe()
+		return newRotationTestPeer(t, conn, key.PubKey(), false)
+	}
+	recent, expired, gone := newPeer(), newPeer(), newPeer()
+
+	failed := map[route.Vertex]time.Time{
+		recent.PubKey():  time.Now(),
+		expired.PubKey(): time.Now().Add(-interval),
+		gone.PubKey():    time.Now(),
+	}
+	due := []*peer.Brontide{recent, expired}
+
+	pruned := pruneFailedRotations(failed, due, interval)
+	if len(pruned) != 1 {
+		t.Fatalf("expected 1 failed rotation, got %d", len(pruned))
+	}
+	if _, ok := pruned[recent.PubKey()]; !ok {
+		t.Fatalf("expected recent failure to be kept")
+	}
+}
@@ -0,0 +1,193 @@
+package dcrlnd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/decred/dcrlnd/netann"
+	"github.com/decred/dcrlnd/tor"
+)
+
+const (
+	// onionKeyRetryInterval is how long to wait before retrying a failed
+	// rotation of the onion service key.
+	onionKeyRetryInterval = 10 * time.Minute
+)
+
+// memOnionStore is an in-memory tor.OnionStore, used to hold the private key
+// of a new onion service until its address has been announced.
+type memOnionStore struct {
+	privateKey []byte
+}
+
+// A compile-time constraint to ensure memOnionStore satisfies the
+// tor.OnionStore interface.
+var _ tor.OnionStore = (*memOnionStore)(nil)
+
+// StorePrivateKey stores the private key in memory.
+func (m *memOnionStore) StorePrivateKey(_ tor.OnionType, key []byte) error {
+	m.privateKey = key
+	return nil
+}
+
+// PrivateKey returns the private key held in memory, if any.
+func (m *memOnionStore) PrivateKey(_ tor.OnionType) ([]byte, error) {
+	if m.privateKey == nil {
+		return nil, tor.ErrNoPrivateKey
+	}
+	return m.privateKey, nil
+}
+
+// DeletePrivateKey forgets the private key held in memory.
+func (m *memOnionStore) DeletePrivateKey(_ tor.OnionType) error {
+	m.privateKey = nil
+	return nil
+}
+
+// onionKeyRotationDelay returns how long to wait before rotating an onion
+// service key created at the given time.
+func onionKeyRotationDelay(created time.Time, rotation time.Duration,
+	now time.Time) time.Duration {
+
+	delay := created.Add(rotation).Sub(now)
+	if delay < 0 {
+		return 0
+	}
+
+	return delay
+}
+
+// rotateOnionKey replaces the onion service of the node with a new one, using
+// a fresh private key. The new onion address replaces the old one in our node
+// announcement, which is broadcast to our peers before the old onion service is
+// removed. The new private key only replaces the stored one once the
+// announcement has been updated, so that the stored key matches the announced
+// address.
+func (s *server) rotateOnionKey() error {
+	keyStore := &memOnionStore{}
+	onionCfg := s.onionServiceConfig(keyStore)
+	newAddr, err := s.torController.AddOnion(onionCfg)
+	if err != nil {
+		return fmt.Errorf("unable to create onion service: %v", err)
+	}
+
+	// The new key is written next to the current one, to be moved in
+	// place once the new address is announced.
+	keyPath := s.cfg.Tor.PrivateKeyPath
+	newKeyPath := keyPath + ".new"
+	newKeyStore := tor.NewOnionFile(newKeyPath, 0600)
+
+	// removeNew removes the new onion service if the rotation can't be
+	// completed, leaving the current one in place.
+	removeNew := func() {
+		if err := s.torController.DelOnion(newAddr); err != nil {
+			srvrLog.Warnf("Unable to remove onion service %v: %v",
+				newAddr, err)
+		}
+		os.Remove(newKeyPath)
+	}
+
+	err = newKeyStore.StorePrivateKey(onionCfg.Type, keyStore.privateKey)
+	if err != nil {
+		removeNew()
+		return fmt.Errorf("unable to store private key of onion "+
+			"service %v: %v", newAddr, err)
+	}
+
+	currentNodeAnn, err := s.genNodeAnnouncement(false)
+	if err != nil {
+		removeNew()
+		return fmt.Errorf("unable to retrieve current node "+
+			"announcement: %v", err)
+	}
+
+	oldAddr := s.onionAddr
+	newAddrs := []net.Addr{newAddr}
+	for _, addr := range currentNodeAnn.Addresses {
+		if addr.String() == oldAddr.String() {
+			continue
+		}
+		newAddrs = append(newAddrs, addr)
+	}
+
+	newNodeAnn, err := s.genNodeAnnouncement(
+		true, netann.NodeAnnSetAddrs(newAddrs),
+	)
+	if err != nil {
+		removeNew()
+		return fmt.Errorf("unable to generate new node "+
+			"announcement: %v", err)
+	}
+	if err := s.setSelfNode(&newNodeAnn); err != nil {
+		removeNew()
+		return err
+	}
+
+	s.onionAddr = newAddr
+	renameErr := os.Rename(newKeyPath, keyPath)
+
+	srvrLog.Infof("Rotated onion service key, advertising %v instead of "+
+		"%v", newAddr, oldAddr)
+
+	if err := s.BroadcastMessage(nil, &newNodeAnn); err != nil {
+		srvrLog.Warnf("Unable to broadcast new node announcement to "+
+			"peers: %v", err)
+	}
+
+	if err := s.torController.DelOnion(oldAddr); err != nil {
+		srvrLog.Warnf("Unable to remove onion service %v: %v",
+			oldAddr, err)
+	}
+
+	// The new address is announced either way, but the rotation is
+	// reported as failed so that it is retried after a while rather than
+	// based on the age of the key that is still stored.
+	if renameErr != nil {
+		return fmt.Errorf("unable to replace private key of onion "+
+			"service, %v will be restored on restart: %v", oldAddr,
+			renameErr)
+	}
+
+	return nil
+}
+
+// rotateOnionKeys replaces the onion service key of the node each time it gets
+// older than the configured rotation interval. The age of the key is taken from
+// the modification time of the file it is stored in, so that the schedule is
+// kept across restarts.
+//
+// NOTE: This MUST be run as a goroutine.
+func (s *server) rotateOnionKeys() {
+	defer s.wg.Done()
+
+	for {
+		created := time.Now()
+		info, err := os.Stat(s.cfg.Tor.PrivateKeyPath)
+		if err == nil {
+			created = info.ModTime()
+		}
+
+		delay := onionKeyRotationDelay(
+			created, s.cfg.Tor.OnionKeyRotation, time.Now(),
+		)
+		select {
+		case <-time.After(delay):
+		case <-s.quit:
+			return
+		}
+
+		if err := s.rotateOnionKey(); err != nil {
+			srvrLog.Errorf("Unable to rotate onion service key, "+
+				"retrying in %v: %v", onionKeyRetryInterval,
+				err)
+
+			select {
+			case <-time.After(onionKeyRetryInterval):
+			case <-s.quit:
+				return
+			}
+		}
+	}
+}
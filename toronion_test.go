@@ -0,0 +1,50 @@
+package dcrlnd
+
+import (
+	"testing"
+	"time"
+)
+
+// TestOnionKeyRotationDelay asserts that the rotation of an onion service key
+// is scheduled once the key reaches the rotation age.
+func TestOnionKeyRotationDelay(t *testing.T) {
+	t.Parallel()
+
+	const rotation = 24 * time.Hour
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		created time.Time
+		delay   time.Duration
+	}{
+		{
+			name:    "new key",
+			created: now,
+			delay:   rotation,
+		},
+		{
+			name:    "key partially aged",
+			created: now.Add(-time.Hour),
+			delay:   rotation - time.Hour,
+		},
+		{
+			name:    "key due",
+			created: now.Add(-rotation),
+			delay:   0,
+		},
+		{
+			name:    "key overdue",
+			created: now.Add(-2 * rotation),
+			delay:   0,
+		},
+	}
+
+	for _, test := range tests {
+		delay := onionKeyRotationDelay(test.created, rotation, now)
+		if delay != test.delay {
+			t.Fatalf("%v: expected delay %v, got %v", test.name,
+				test.delay, delay)
+		}
+	}
+}
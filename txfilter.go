@@ -0,0 +1,139 @@
+package dcrlnd
+
+import (
+	"fmt"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+)
+
+// txFilter restricts the wallet transactions returned by GetTransactions and
+// SubscribeTransactions. The zero value matches every transaction.
+type txFilter struct {
+	// addresses, if non-empty, is the set of addresses one of which must
+	// be paid by a transaction.
+	addresses map[string]struct{}
+
+	// accounts, if non-empty, is the set of wallet accounts one of which
+	// must be involved in a transaction.
+	accounts map[uint32]struct{}
+
+	// minAmount is the minimum absolute net amount of a transaction.
+	minAmount dcrutil.Amount
+
+	// confirmedOnly excludes unconfirmed transactions.
+	confirmedOnly bool
+
+	// label is the label a transaction must have.
+	label string
+
+	// excludeStake excludes the transactions of the staking and treasury
+	// subsystems.
+	excludeStake bool
+}
+
+// newTxFilter parses the filters of a transaction request.
+func newTxFilter(req *lnrpc.GetTransactionsRequest,
+	params *chaincfg.Params) (*txFilter, error) {
+
+	if req.MinAmount < 0 {
+		return nil, fmt.Errorf("min_amount must not be negative")
+	}
+
+	filter := &txFilter{
+		minAmount:     dcrutil.Amount(req.MinAmount),
+		confirmedOnly: req.ConfirmedOnly,
+		label:         req.Label,
+		excludeStake:  req.ExcludeStake,
+	}
+
+	if len(req.Addresses) > 0 {
+		filter.addresses = make(map[string]struct{}, len(req.Addresses))
+		for _, addrStr := range req.Addresses {
+			addr, err := dcrutil.DecodeAddress(addrStr, params)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %v: %v",
+					addrStr, err)
+			}
+			filter.addresses[addr.Address()] = struct{}{}
+		}
+	}
+
+	if len(req.Accounts) > 0 {
+		filter.accounts = make(map[uint32]struct{}, len(req.Accounts))
+		for _, account := range req.Accounts {
+			filter.accounts[account] = struct{}{}
+		}
+	}
+
+	return filter, nil
+}
+
+// match returns true if the transaction passes all filters.
+func (f *txFilter) match(tx *lnwallet.TransactionDetail) bool {
+	if f.confirmedOnly && tx.NumConfirmations <= 0 {
+		return false
+	}
+
+	if f.label != "" && tx.Label != f.label {
+		return false
+	}
+
+	if f.excludeStake &&
+		stakeTxType(tx.RawTx) != lnrpc.TransactionType_TX_TYPE_UNKNOWN {
+
+		return false
+	}
+
+	amt := tx.Value
+	if amt < 0 {
+		amt = -amt
+	}
+	if amt < f.minAmount {
+		return false
+	}
+
+	if len(f.addresses) > 0 {
+		found := false
+		for _, addr := range tx.DestAddresses {
+			if _, ok := f.addresses[addr.Address()]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(f.accounts) > 0 {
+		found := false
+		for _, account := range tx.Accounts {
+			if _, ok := f.accounts[account]; ok {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterTxs returns the transactions that pass all filters.
+func (f *txFilter) filterTxs(
+	txs []*lnwallet.TransactionDetail) []*lnwallet.TransactionDetail {
+
+	filtered := make([]*lnwallet.TransactionDetail, 0, len(txs))
+	for _, tx := range txs {
+		if f.match(tx) {
+			filtered = append(filtered, tx)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,175 @@
+package dcrlnd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/decred/dcrd/chaincfg/v3"
+	"github.com/decred/dcrd/dcrec"
+	"github.com/decred/dcrd/dcrutil/v3"
+	"github.com/decred/dcrd/txscript/v3"
+	"github.com/decred/dcrd/wire"
+	"github.com/decred/dcrlnd/lnrpc"
+	"github.com/decred/dcrlnd/lnwallet"
+)
+
+// newTestTicket returns a serialized ticket purchase with a single commitment.
+func newTestTicket(t *testing.T) []byte {
+	t.Helper()
+
+	pkh := bytes.Repeat([]byte{0x01}, 20)
+	p2pkh := func(tag byte) []byte {
+		script, err := txscript.NewScriptBuilder().AddOp(tag).
+			AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160).
+			AddData(pkh).AddOp(txscript.OP_EQUALVERIFY).
+			AddOp(txscript.OP_CHECKSIG).Script()
+		if err != nil {
+			t.Fatalf("unable to build script: %v", err)
+		}
+		return script
+	}
+
+	commitment := append(append([]byte{}, pkh...), make([]byte, 10)...)
+	commitScript, err := txscript.NewScriptBuilder().
+		AddOp(txscript.OP_RETURN).AddData(commitment).Script()
+	if err != nil {
+		t.Fatalf("unable to build script: %v", err)
+	}
+
+	ticket := wire.NewMsgTx()
+	ticket.AddTxIn(wire.NewTxIn(&wire.OutPoint{}, 100000, nil))
+	ticket.AddTxOut(wire.NewTxOut(100000, p2pkh(txscript.OP_SSTX)))
+	ticket.AddTxOut(wire.NewTxOut(0, commitScript))
+	ticket.AddTxOut(wire.NewTxOut(0, p2pkh(txscript.OP_SSTXCHANGE)))
+
+	rawTx, err := ticket.Bytes()
+	if err != nil {
+		t.Fatalf("unable to serialize ticket: %v", err)
+	}
+	return rawTx
+}
+
+// TestTxFilter asserts that the transaction filters of a request are applied.
+func TestTxFilter(t *testing.T) {
+	t.Parallel()
+
+	params := chaincfg.RegNetParams()
+	newAddr := func(b byte) dcrutil.Address {
+		var hash [20]byte
+		hash[0] = b
+		addr, err := dcrutil.NewAddressPubKeyHash(
+			hash[:], params, dcrec.STEcdsaSecp256k1,
+		)
+		if err != nil {
+			t.Fatalf("unable to create address: %v", err)
+		}
+		return addr
+	}
+	addr1, addr2 := newAddr(1), newAddr(2)
+
+	withdrawal := &lnwallet.TransactionDetail{
+		Value:            -5000,
+		NumConfirmations: 3,
+		DestAddresses:    []dcrutil.Address{addr1},
+		Accounts:         []uint32{0},
+		Label:            "rent",
+	}
+	dust := &lnwallet.TransactionDetail{
+		Value:         100,
+		DestAddresses: []dcrutil.Address{addr2},
+		Accounts:      []uint32{1},
+	}
+	ticket := &lnwallet.TransactionDetail{
+		Value:            -100000,
+		NumConfirmations: 3,
+		RawTx:            newTestTicket(t),
+	}
+	txs := []*lnwallet.TransactionDetail{withdrawal, dust, ticket}
+
+	tests := []struct {
+		name string
+		req  *lnrpc.GetTransactionsRequest
+		exp  []*lnwallet.TransactionDetail
+	}{{
+		name: "no filter",
+		req:  &lnrpc.GetTransactionsRequest{},
+		exp:  txs,
+	}, {
+		name: "address",
+		req: &lnrpc.GetTransactionsRequest{
+			Addresses: []string{addr2.Address()},
+		},
+		exp: []*lnwallet.TransactionDetail{dust},
+	}, {
+		name: "account",
+		req: &lnrpc.GetTransactionsRequest{
+			Accounts: []uint32{0, 5},
+		},
+		exp: []*lnwallet.TransactionDetail{withdrawal},
+	}, {
+		name: "min amount applies to outgoing amounts",
+		req: &lnrpc.GetTransactionsRequest{
+			MinAmount: 1000,
+		},
+		exp: []*lnwallet.TransactionDetail{withdrawal, ticket},
+	}, {
+		name: "confirmed only",
+		req: &lnrpc.GetTransactionsRequest{
+			ConfirmedOnly: true,
+		},
+		exp: []*lnwallet.TransactionDetail{withdrawal, ticket},
+	}, {
+		name: "exclude stake",
+		req: &lnrpc.GetTransactionsRequest{
+			ExcludeStake: true,
+		},
+		exp: []*lnwallet.TransactionDetail{withdrawal, dust},
+	}, {
+		name: "label",
+		req: &lnrpc.GetTransactionsRequest{
+			Label: "rent",
+		},
+		exp: []*lnwallet.TransactionDetail{withdrawal},
+	}, {
+		name: "combined filters",
+		req: &lnrpc.GetTransactionsRequest{
+			Addresses:     []string{addr1.Address()},
+			ConfirmedOnly: true,
+			MinAmount:     10000,
+		},
+	}}
+
+	for _, test := range tests {
+		filter, err := newTxFilter(test.req, params)
+		if err != nil {
+			t.Fatalf("%s: unable to create filter: %v", test.name,
+				err)
+		}
+
+		got := filter.filterTxs(txs)
+		if len(got) != len(test.exp) {
+			t.Fatalf("%s: expected %d transactions, got %d",
+				test.name, len(test.exp), len(got))
+		}
+		for i := range got {
+			if got[i] != test.exp[i] {
+				t.Fatalf("%s: unexpected transaction %d",
+					test.name, i)
+			}
+		}
+	}
+
+	// Invalid addresses and amounts are rejected.
+	_, err := newTxFilter(&lnrpc.GetTransactionsRequest{
+		Addresses: []string{"invalid"},
+	}, params)
+	if err == nil {
+		t.Fatalf("expected invalid address to be rejected")
+	}
+	_, err = newTxFilter(&lnrpc.GetTransactionsRequest{
+		MinAmount: -1,
+	}, params)
+	if err == nil {
+		t.Fatalf("expected negative amount to be rejected")
+	}
+}
@@ -0,0 +1,443 @@
+package dcrlnd
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/sweep"
+	"github.com/decred/dcrlnd/ticker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+const (
+	// walletLockPollInterval is the interval at which the wallet locker
+	// checks whether the wallet has been idle for long enough and whether
+	// the funding flows in progress have completed while locking.
+	walletLockPollInterval = time.Second
+)
+
+var (
+	// errWalletLocked is returned by the RPC calls requiring the private
+	// keys of the wallet while it's locked.
+	errWalletLocked = status.Error(
+		codes.FailedPrecondition, "wallet is locked, unlock it to "+
+			"resume signing operations",
+	)
+
+	// errWalletNotLocked is returned when unlocking a wallet that wasn't
+	// locked.
+	errWalletNotLocked = errors.New("wallet is not locked")
+
+	// errSweepWalletLocked is returned to the sweeper when it looks for
+	// wallet outputs while the wallet is locked, so that the sweep is
+	// retried once it's unlocked.
+	errSweepWalletLocked = errors.New("wallet is locked, unable to add " +
+		"wallet inputs to sweep")
+
+	// walletLockedEntities are the macaroon entities whose write access
+	// requires the private keys of the wallet.
+	walletLockedEntities = map[string]struct{}{
+		"onchain":  {},
+		"offchain": {},
+		"signer":   {},
+	}
+
+	// walletLockExemptMethods are the methods requiring the private keys
+	// of the wallet that must remain available while it's locked.
+	walletLockExemptMethods = map[string]struct{}{
+		"/lnrpc.Lightning/LockWallet":          {},
+		"/lnrpc.Lightning/UnlockRunningWallet": {},
+	}
+)
+
+// lockableWallet is a wallet whose private keys can be locked and unlocked
+// while the daemon is running.
+type lockableWallet interface {
+	// Lock locks the private keys of the wallet.
+	Lock()
+
+	// Unlock unlocks the private keys of the wallet with the given private
+	// passphrase.
+	Unlock(privatePass []byte) error
+
+	// Locked returns true if the private keys of the wallet are locked.
+	Locked() bool
+}
+
+// walletLockState is the state of the wallet as seen by the wallet locker.
+type walletLockState uint8
+
+const (
+	// walletUnlocked indicates the wallet is unlocked and the node
+	// operates normally.
+	walletUnlocked walletLockState = iota
+
+	// walletLocking indicates the operations spending the on-chain funds
+	// of the wallet are paused and the locker waits for the channel
+	// funding flows in progress to complete before locking the wallet.
+	walletLocking
+
+	// walletLocked indicates the wallet is locked.
+	walletLocked
+)
+
+// walletLockerConfig holds the dependencies of the wallet locker.
+type walletLockerConfig struct {
+	// Wallet is the wallet that is locked.
+	Wallet lockableWallet
+
+	// IdleTimeout is the time after the last activity requiring the
+	// wallet at which it's locked. Zero disables the automatic locking.
+	IdleTimeout time.Duration
+
+	// DrainTimeout is the maximum time to wait for the channel funding
+	// flows in progress to complete before locking the wallet.
+	DrainTimeout time.Duration
+
+	// Pause pauses the operations spending the on-chain funds of the
+	// wallet, returning a closure that restores their previous state.
+	Pause func() func()
+
+	// PendingFundings returns the number of channel funding flows in
+	// progress whose funding transaction is signed by the wallet.
+	PendingFundings func() int
+
+	// PendingForceCloses returns the number of force closed channels whose
+	// outputs haven't been fully swept yet. The automatic locking is
+	// postponed while there are any, as their sweeps may need to be
+	// topped up with wallet inputs.
+	PendingForceCloses func() int
+
+	// Permissions are the macaroon permissions of all the RPC methods,
+	// used to find the methods requiring the wallet.
+	Permissions map[string][]bakery.Op
+
+	// Ticker is used to periodically check the state of the wallet.
+	Ticker ticker.Ticker
+
+	// Clock is used to determine how long the wallet has been idle.
+	Clock clock.Clock
+}
+
+// walletLocker locks the wallet after a period without RPC calls requiring
+// its private keys, or when requested to. Locking the wallet only protects its
+// on-chain funds: the keys of the channels are kept by the key ring, so that
+// commitment updates, justice transactions and contract sweeps are still
+// signed while the wallet is locked. Before locking, the operations spending
+// on-chain funds are paused and the funding flows in progress are given some
+// time to complete. RPC calls spending funds or requiring the private keys are
+// refused until the wallet is unlocked again.
+type walletLocker struct {
+	cfg *walletLockerConfig
+
+	// methods holds the RPC methods requiring the private keys of the
+	// wallet.
+	methods map[string]struct{}
+
+	// state is the current state of the wallet.
+	state walletLockState
+
+	// requested is true if locking was requested rather than triggered
+	// by the idle timeout. Activity only cancels the latter.
+	requested bool
+
+	// lastActivity is the time of the last RPC call requiring the wallet.
+	lastActivity time.Time
+
+	// drainDeadline is the time at which the wallet is locked even if
+	// htlcs are still in flight.
+	drainDeadline time.Time
+
+	// resume restores the operations paused when the wallet started
+	// locking.
+	resume func()
+
+	mtx sync.Mutex
+
+	started sync.Once
+	stopped sync.Once
+	quit    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// newWalletLocker creates a new wallet locker.
+func newWalletLocker(cfg *walletLockerConfig) *walletLocker {
+	methods := make(map[string]struct{})
+	for method, ops := range cfg.Permissions {
+		if _, ok := walletLockExemptMethods[method]; ok {
+			continue
+		}
+
+		for _, op := range ops {
+			_, ok := walletLockedEntities[op.Entity]
+			if ok && op.Action != "read" {
+				methods[method] = struct{}{}
+				break
+			}
+		}
+	}
+
+	return &walletLocker{
+		cfg:          cfg,
+		methods:      methods,
+		lastActivity: cfg.Clock.Now(),
+		quit:         make(chan struct{}),
+	}
+}
+
+// Start starts the goroutine locking the wallet once it has been idle for too
+// long.
+func (l *walletLocker) Start() error {
+	l.started.Do(func() {
+		l.wg.Add(1)
+		go l.lockLoop()
+	})
+
+	return nil
+}
+
+// Stop stops the wallet locker. The state of the wallet is left untouched.
+func (l *walletLocker) Stop() error {
+	l.stopped.Do(func() {
+		close(l.quit)
+		l.wg.Wait()
+	})
+
+	return nil
+}
+
+// lockLoop periodically checks whether the wallet should start locking, and
+// locks it once the funding flows in progress have completed.
+func (l *walletLocker) lockLoop() {
+	defer l.wg.Done()
+
+	l.cfg.Ticker.Resume()
+	defer l.cfg.Ticker.Stop()
+
+	for {
+		select {
+		case <-l.cfg.Ticker.Ticks():
+			l.poll()
+
+		case <-l.quit:
+			return
+		}
+	}
+}
+
+// poll advances the state of the wallet locker.
+func (l *walletLocker) poll() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	now := l.cfg.Clock.Now()
+
+	switch l.state {
+	case walletUnlocked:
+		if l.cfg.IdleTimeout == 0 ||
+			now.Sub(l.lastActivity) < l.cfg.IdleTimeout {
+
+			return
+		}
+
+		numForceCloses := l.cfg.PendingForceCloses()
+		if numForceCloses > 0 {
+			ltndLog.Debugf("Postponing wallet lock until %d force "+
+				"closed channels are resolved", numForceCloses)
+			return
+		}
+
+		ltndLog.Infof("Wallet idle for %v, locking it",
+			l.cfg.IdleTimeout)
+		l.startLocking(false)
+
+	case walletLocking:
+		numFundings := l.cfg.PendingFundings()
+
+		switch {
+		case numFundings == 0:
+
+		case now.Before(l.drainDeadline):
+			ltndLog.Debugf("Waiting for %d channel fundings to "+
+				"complete before locking the wallet",
+				numFundings)
+			return
+
+		default:
+			ltndLog.Warnf("Drain timeout expired, locking the "+
+				"wallet with %d channel fundings in progress, "+
+				"which will fail", numFundings)
+		}
+
+		l.cfg.Wallet.Lock()
+		l.state = walletLocked
+
+		ltndLog.Infof("Wallet locked")
+	}
+}
+
+// startLocking pauses the operations spending on-chain funds. The wallet is
+// locked once the funding flows in progress have completed or the drain
+// timeout expires.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (l *walletLocker) startLocking(requested bool) {
+	l.resume = l.cfg.Pause()
+	l.state = walletLocking
+	l.requested = requested
+	l.drainDeadline = l.cfg.Clock.Now().Add(l.cfg.DrainTimeout)
+}
+
+// Lock starts locking the wallet. The wallet is locked once the funding flows
+// in progress have completed, and remains locked regardless of new activity
+// until it is unlocked.
+func (l *walletLocker) Lock() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	switch l.state {
+	case walletUnlocked:
+		ltndLog.Infof("Locking wallet as requested")
+		l.startLocking(true)
+
+		numForceCloses := l.cfg.PendingForceCloses()
+		if numForceCloses > 0 {
+			ltndLog.Warnf("Locking wallet with %d force closed "+
+				"channels unresolved, their sweeps can't be "+
+				"topped up with wallet inputs until it's "+
+				"unlocked", numForceCloses)
+		}
+
+	case walletLocking:
+		l.requested = true
+	}
+}
+
+// Unlock unlocks the wallet with the given private passphrase and resumes the
+// operations spending on-chain funds. This also cancels the locking of a
+// wallet that is still waiting for the funding flows in progress to complete.
+func (l *walletLocker) Unlock(privatePass []byte) error {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.state == walletUnlocked {
+		return errWalletNotLocked
+	}
+
+	// The passphrase is checked even if the wallet wasn't locked yet.
+	if err := l.cfg.Wallet.Unlock(privatePass); err != nil {
+		return err
+	}
+
+	l.unlocked()
+
+	ltndLog.Infof("Wallet unlocked")
+
+	return nil
+}
+
+// unlocked returns the locker to the unlocked state.
+//
+// NOTE: The mutex MUST be held when calling this method.
+func (l *walletLocker) unlocked() {
+	l.resume()
+	l.resume = nil
+	l.state = walletUnlocked
+	l.requested = false
+	l.lastActivity = l.cfg.Clock.Now()
+}
+
+// Locked returns true if the wallet is locked or being locked.
+func (l *walletLocker) Locked() bool {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	return l.state != walletUnlocked
+}
+
+// checkMethod records an RPC call to the given method. Calls requiring the
+// private keys of the wallet count as activity, cancelling an automatic lock
+// still waiting for htlcs to resolve, and are refused while the wallet is
+// locked.
+func (l *walletLocker) checkMethod(fullMethod string) error {
+	if _, ok := l.methods[fullMethod]; !ok {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	if l.state == walletLocking && !l.requested {
+		ltndLog.Infof("Activity while locking the wallet, keeping " +
+			"it unlocked")
+		l.unlocked()
+	}
+
+	if l.state != walletUnlocked {
+		return errWalletLocked
+	}
+
+	l.lastActivity = l.cfg.Clock.Now()
+
+	return nil
+}
+
+// UnaryServerInterceptor returns a UnaryServerInterceptor that refuses unary
+// RPC calls requiring the private keys of the wallet while it's locked.
+func (l *walletLocker) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+
+		if err := l.checkMethod(info.FullMethod); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a StreamServerInterceptor that refuses to
+// open streams requiring the private keys of the wallet while it's locked.
+func (l *walletLocker) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream,
+		info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+
+		if err := l.checkMethod(info.FullMethod); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// lockAwareSweeperWallet refuses to hand the outputs of the wallet to the
+// sweeper while the wallet is locked, so that sweeps of small outputs that need
+// to be topped up with wallet inputs are retried until it is unlocked, rather
+// than failing to sign.
+type lockAwareSweeperWallet struct {
+	sweep.Wallet
+
+	wallet lockableWallet
+}
+
+// ListUnspentWitness returns the unspent outputs of the wallet, or an error
+// while it's locked.
+func (w *lockAwareSweeperWallet) ListUnspentWitness(minConfs,
+	maxConfs int32) ([]*lnwallet.Utxo, error) {
+
+	if w.wallet.Locked() {
+		ltndLog.Warnf("Sweep needs wallet inputs while the wallet " +
+			"is locked, unlock it to complete the sweep")
+		return nil, errSweepWalletLocked
+	}
+
+	return w.Wallet.ListUnspentWitness(minConfs, maxConfs)
+}
@@ -0,0 +1,240 @@
+package dcrlnd
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/decred/dcrlnd/clock"
+	"github.com/decred/dcrlnd/lnwallet"
+	"github.com/decred/dcrlnd/sweep"
+	"github.com/decred/dcrlnd/ticker"
+	"gopkg.in/macaroon-bakery.v2/bakery"
+)
+
+// mockLockableWallet is a lockableWallet accepting a single passphrase.
+type mockLockableWallet struct {
+	locked bool
+	pass   string
+}
+
+func (w *mockLockableWallet) Lock() {
+	w.locked = true
+}
+
+func (w *mockLockableWallet) Unlock(privatePass []byte) error {
+	if string(privatePass) != w.pass {
+		return errors.New("invalid passphrase")
+	}
+
+	w.locked = false
+	return nil
+}
+
+func (w *mockLockableWallet) Locked() bool {
+	return w.locked
+}
+
+// TestWalletLocker asserts that the wallet is locked once it has been idle for
+// long enough and the funding flows in progress are completed, that activity
+// cancels an automatic lock but not a requested one, and that the methods
+// requiring the wallet are refused until it's unlocked.
+func TestWalletLocker(t *testing.T) {
+	t.Parallel()
+
+	const (
+		sendCoins   = "/lnrpc.Lightning/SendCoins"
+		getInfo     = "/lnrpc.Lightning/GetInfo"
+		unlock      = "/lnrpc.Lightning/UnlockRunningWallet"
+		idleTimeout = time.Minute
+	)
+
+	var (
+		testTime       = time.Unix(1600000000, 0)
+		testClock      = clock.NewTestClock(testTime)
+		wallet         = &mockLockableWallet{pass: "pass"}
+		paused         bool
+		numFundings    int
+		numForceCloses int
+	)
+
+	locker := newWalletLocker(&walletLockerConfig{
+		Wallet:       wallet,
+		IdleTimeout:  idleTimeout,
+		DrainTimeout: time.Minute,
+		Pause: func() func() {
+			paused = true
+			return func() { paused = false }
+		},
+		PendingFundings: func() int {
+			return numFundings
+		},
+		PendingForceCloses: func() int {
+			return numForceCloses
+		},
+		Permissions: map[string][]bakery.Op{
+			sendCoins: {{Entity: "onchain", Action: "write"}},
+			getInfo:   {{Entity: "info", Action: "read"}},
+			unlock:    {{Entity: "onchain", Action: "write"}},
+		},
+		Ticker: ticker.NewForce(walletLockPollInterval),
+		Clock:  testClock,
+	})
+
+	advance := func(d time.Duration) {
+		testTime = testTime.Add(d)
+		testClock.SetTime(testTime)
+		locker.poll()
+	}
+
+	// The wallet isn't locked before the idle timeout.
+	advance(idleTimeout / 2)
+	if locker.Locked() || paused {
+		t.Fatalf("wallet locked before idle timeout")
+	}
+
+	// Activity postpones the lock.
+	if err := locker.checkMethod(sendCoins); err != nil {
+		t.Fatalf("unable to call method: %v", err)
+	}
+	advance(idleTimeout / 2)
+	if locker.Locked() {
+		t.Fatalf("wallet locked despite activity")
+	}
+
+	// Unresolved force closes postpone the automatic lock.
+	numForceCloses = 1
+	advance(idleTimeout / 2)
+	if locker.Locked() || paused {
+		t.Fatalf("wallet locking with force closes unresolved")
+	}
+	numForceCloses = 0
+
+	// Once idle for long enough, the wallet starts locking but waits for
+	// the fundings in progress.
+	numFundings = 1
+	advance(idleTimeout / 2)
+	if !locker.Locked() || !paused {
+		t.Fatalf("wallet not locking after idle timeout")
+	}
+	if wallet.Locked() {
+		t.Fatalf("wallet locked with fundings in progress")
+	}
+
+	// Methods not requiring the wallet don't count as activity.
+	if err := locker.checkMethod(getInfo); err != nil {
+		t.Fatalf("unable to call method: %v", err)
+	}
+	if !locker.Locked() {
+		t.Fatalf("wallet lock cancelled by read method")
+	}
+
+	// Activity cancels the automatic lock.
+	if err := locker.checkMethod(sendCoins); err != nil {
+		t.Fatalf("unable to call method: %v", err)
+	}
+	if locker.Locked() || paused {
+		t.Fatalf("wallet lock not cancelled by activity")
+	}
+
+	// Unlocking a wallet that isn't locked fails.
+	if err := locker.Unlock([]byte("pass")); err != errWalletNotLocked {
+		t.Fatalf("expected errWalletNotLocked, got %v", err)
+	}
+
+	// A requested lock isn't cancelled by activity.
+	locker.Lock()
+	if err := locker.checkMethod(sendCoins); err != errWalletLocked {
+		t.Fatalf("expected errWalletLocked, got %v", err)
+	}
+	if !paused {
+		t.Fatalf("operations not paused while locking")
+	}
+
+	// The wallet is locked once the fundings are completed.
+	advance(time.Second)
+	if wallet.Locked() {
+		t.Fatalf("wallet locked with fundings in progress")
+	}
+	numFundings = 0
+	advance(time.Second)
+	if !wallet.Locked() {
+		t.Fatalf("wallet not locked once fundings completed")
+	}
+
+	// The unlock method remains available while locked.
+	if err := locker.checkMethod(unlock); err != nil {
+		t.Fatalf("unable to call unlock method: %v", err)
+	}
+
+	// An invalid passphrase leaves the wallet locked.
+	if err := locker.Unlock([]byte("wrong")); err == nil {
+		t.Fatalf("expected invalid passphrase to be rejected")
+	}
+	if !locker.Locked() || !wallet.Locked() {
+		t.Fatalf("wallet unlocked with invalid passphrase")
+	}
+
+	if err := locker.Unlock([]byte("pass")); err != nil {
+		t.Fatalf("unable to unlock wallet: %v", err)
+	}
+	if locker.Locked() || wallet.Locked() || paused {
+		t.Fatalf("wallet not unlocked")
+	}
+	if err := locker.checkMethod(sendCoins); err != nil {
+		t.Fatalf("unable to call method: %v", err)
+	}
+
+	// The drain timeout locks the wallet even with fundings in progress.
+	numFundings = 1
+	locker.Lock()
+	advance(30 * time.Second)
+	if wallet.Locked() {
+		t.Fatalf("wallet locked before drain timeout")
+	}
+	advance(30 * time.Second)
+	if !wallet.Locked() {
+		t.Fatalf("wallet not locked after drain timeout")
+	}
+}
+
+// mockSweeperWallet is a sweep.Wallet returning a fixed set of utxos.
+type mockSweeperWallet struct {
+	sweep.Wallet
+
+	utxos []*lnwallet.Utxo
+}
+
+func (w *mockSweeperWallet) ListUnspentWitness(_, _ int32) ([]*lnwallet.Utxo,
+	error) {
+
+	return w.utxos, nil
+}
+
+// TestLockAwareSweeperWallet asserts that the utxos of the wallet are refused
+// to the sweeper while the wallet is locked.
+func TestLockAwareSweeperWallet(t *testing.T) {
+	t.Parallel()
+
+	wallet := &mockLockableWallet{}
+	sweeperWallet := &lockAwareSweeperWallet{
+		Wallet: &mockSweeperWallet{
+			utxos: []*lnwallet.Utxo{{Value: 1000}},
+		},
+		wallet: wallet,
+	}
+
+	utxos, err := sweeperWallet.ListUnspentWitness(1, 100)
+	if err != nil {
+		t.Fatalf("unable to list utxos: %v", err)
+	}
+	if len(utxos) != 1 {
+		t.Fatalf("expected 1 utxo, got %d", len(utxos))
+	}
+
+	wallet.Lock()
+	_, err = sweeperWallet.ListUnspentWitness(1, 100)
+	if err != errSweepWalletLocked {
+		t.Fatalf("expected errSweepWalletLocked, got %v", err)
+	}
+}
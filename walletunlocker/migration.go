@@ -0,0 +1,141 @@
+package walletunlocker
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/decred/dcrlnd/channeldb"
+	"github.com/decred/dcrlnd/lnrpc"
+)
+
+// errShuttingDown is returned when the unlocker service is stopped while
+// waiting for the database migrations to complete.
+var errShuttingDown = errors.New("unlocker service shutting down")
+
+// MigrationProgress records the progress of the database migrations applied at
+// startup and notifies any active subscribers. It can be used as the
+// migration progress callback of the channel database.
+func (u *UnlockerService) MigrationProgress(
+	progress channeldb.MigrationProgress) {
+
+	u.migrationMtx.Lock()
+	defer u.migrationMtx.Unlock()
+
+	// Start timing once the first migration begins, so that the estimate
+	// only accounts for the time spent applying migrations.
+	if progress.Applied == 0 {
+		u.migrationStart = time.Now()
+	}
+	u.migration = progress
+	u.notifyMigrationUpdate()
+}
+
+// SetChanDB provides the database to the service once it has been opened,
+// marking the end of the database migrations.
+func (u *UnlockerService) SetChanDB(db *channeldb.DB) {
+	u.migrationMtx.Lock()
+	defer u.migrationMtx.Unlock()
+
+	u.db = db
+	u.migrationsDone = true
+	u.notifyMigrationUpdate()
+}
+
+// Stop signals any active migration status subscriptions to exit.
+func (u *UnlockerService) Stop() {
+	close(u.quit)
+}
+
+// notifyMigrationUpdate wakes up all subscribers waiting for a migration
+// status update.
+//
+// NOTE: The migrationMtx must be held when calling this method.
+func (u *UnlockerService) notifyMigrationUpdate() {
+	close(u.migrationUpdates)
+	u.migrationUpdates = make(chan struct{})
+}
+
+// waitForMigrations blocks until the database migrations have completed, the
+// passed context is canceled or the service is stopped.
+func (u *UnlockerService) waitForMigrations(ctx context.Context) error {
+	for {
+		status, updates := u.migrationStatus()
+		if status.Done {
+			return nil
+		}
+
+		select {
+		case <-updates:
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-u.quit:
+			return errShuttingDown
+		}
+	}
+}
+
+// migrationStatus returns the current migration status, along with a channel
+// that is closed on the next update.
+func (u *UnlockerService) migrationStatus() (*lnrpc.MigrationStatus,
+	chan struct{}) {
+
+	u.migrationMtx.Lock()
+	defer u.migrationMtx.Unlock()
+
+	progress := u.migration
+	status := &lnrpc.MigrationStatus{
+		CurrentVersion:    progress.Version,
+		AppliedMigrations: uint32(progress.Applied),
+		TotalMigrations:   uint32(progress.Total),
+		Done:              u.migrationsDone,
+	}
+
+	switch {
+	case u.migrationsDone:
+		status.Percent = 100
+
+	case progress.Total > 0:
+		status.Percent = float64(progress.Applied) * 100 /
+			float64(progress.Total)
+	}
+
+	// Estimate the remaining time from the average duration of the
+	// migrations applied so far.
+	if !u.migrationsDone && progress.Applied > 0 {
+		elapsed := time.Since(u.migrationStart)
+		remaining := progress.Total - progress.Applied
+		eta := elapsed / time.Duration(progress.Applied) *
+			time.Duration(remaining)
+		status.EtaSeconds = int64(eta.Seconds())
+	}
+
+	return status, u.migrationUpdates
+}
+
+// SubscribeMigrationStatus returns a stream of updates on the progress of the
+// database migrations applied at startup. The stream ends once the database is
+// ready.
+func (u *UnlockerService) SubscribeMigrationStatus(
+	_ *lnrpc.MigrationStatusRequest,
+	stream lnrpc.WalletUnlocker_SubscribeMigrationStatusServer) error {
+
+	for {
+		status, updates := u.migrationStatus()
+		if err := stream.Send(status); err != nil {
+			return err
+		}
+
+		if status.Done {
+			return nil
+		}
+
+		select {
+		case <-updates:
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-u.quit:
+			return errShuttingDown
+		}
+	}
+}
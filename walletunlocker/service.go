@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/v3"
@@ -61,6 +62,11 @@ type WalletInitMsg struct {
 	// creation.
 	RecoveryWindow uint32
 
+	// RescanFromHeight is the block height from which the wallet is
+	// rescanned for used addresses. Zero indicates the wallet should be
+	// rescanned from genesis.
+	RescanFromHeight int32
+
 	// ChanBackups a set of static channel backups that should be received
 	// after the wallet has been initialized.
 	ChanBackups ChannelsToRecover
@@ -81,6 +87,11 @@ type WalletUnlockMsg struct {
 	// creation, but before any addresses have been created.
 	RecoveryWindow uint32
 
+	// RescanFromHeight is the block height from which an interrupted
+	// rescan for used addresses is resumed. Zero indicates the rescan
+	// should be resumed where it was interrupted.
+	RescanFromHeight int32
+
 	// Wallet is the loaded and unlocked Wallet. This is returned through
 	// the channel to avoid it being unlocked twice (once to check if the
 	// password is correct, here in the WalletUnlocker and again later when
@@ -123,6 +134,16 @@ type UnlockerService struct {
 	dcrwClientKey  string
 	dcrwClientCert string
 	dcrwAccount    int32
+
+	// migrationMtx guards the fields below, which track the progress of
+	// the database migrations applied at startup.
+	migrationMtx     sync.Mutex
+	migration        channeldb.MigrationProgress
+	migrationStart   time.Time
+	migrationsDone   bool
+	migrationUpdates chan struct{}
+
+	quit chan struct{}
 }
 
 // New creates and returns a new UnlockerService.
@@ -143,6 +164,12 @@ func New(chainDir string, params *chaincfg.Params, noFreelistSync bool,
 		dcrwClientKey:  dcrwClientKey,
 		dcrwClientCert: dcrwClientCert,
 		dcrwAccount:    dcrwAccount,
+
+		// If the database was already provided, there are no
+		// migrations left to wait for.
+		migrationsDone:   db != nil,
+		migrationUpdates: make(chan struct{}),
+		quit:             make(chan struct{}),
 	}
 }
 
@@ -273,6 +300,12 @@ func extractChanBackups(chanBackups *lnrpc.ChanBackupSnapshot) *ChannelsToRecove
 func (u *UnlockerService) InitWallet(ctx context.Context,
 	in *lnrpc.InitWalletRequest) (*lnrpc.InitWalletResponse, error) {
 
+	// The wallet can't be used before the database is ready, so we wait
+	// for any migrations to complete.
+	if err := u.waitForMigrations(ctx); err != nil {
+		return nil, err
+	}
+
 	// Make sure the password meets our constraints.
 	password := in.WalletPassword
 	if err := ValidatePassword(password); err != nil {
@@ -291,6 +324,12 @@ func (u *UnlockerService) InitWallet(ctx context.Context,
 		gapLimit = uint32(recoveryWindow)
 	}
 
+	// Likewise, the rescan height must be non-negative.
+	if in.RescanFromHeight < 0 {
+		return nil, fmt.Errorf("rescan height %d must be "+
+			"non-negative", in.RescanFromHeight)
+	}
+
 	// We'll then open up the directory that will be used to store the
 	// wallet's files so we can check if the wallet already exists. This
 	// loader is only used for this check and should not leak to the
@@ -326,9 +365,10 @@ func (u *UnlockerService) InitWallet(ctx context.Context,
 	// now send over the wallet password and the seed. This will allow the
 	// daemon to initialize itself and startup.
 	initMsg := &WalletInitMsg{
-		Passphrase:     password,
-		WalletSeed:     cipherSeed,
-		RecoveryWindow: gapLimit,
+		Passphrase:       password,
+		WalletSeed:       cipherSeed,
+		RecoveryWindow:   gapLimit,
+		RescanFromHeight: in.RescanFromHeight,
 	}
 
 	// Before we return the unlock payload, we'll check if we can extract
@@ -467,8 +507,26 @@ func (u *UnlockerService) unlockRemoteWallet(ctx context.Context,
 func (u *UnlockerService) UnlockWallet(ctx context.Context,
 	in *lnrpc.UnlockWalletRequest) (*lnrpc.UnlockWalletResponse, error) {
 
+	// The wallet can't be used before the database is ready, so we wait
+	// for any migrations to complete.
+	if err := u.waitForMigrations(ctx); err != nil {
+		return nil, err
+	}
+
+	if in.RescanFromHeight < 0 {
+		return nil, fmt.Errorf("rescan height %d must be "+
+			"non-negative", in.RescanFromHeight)
+	}
+
 	password := in.WalletPassword
 	if u.dcrwHost != "" && u.dcrwCert != "" {
+		// The rescans of remote wallets are managed by the wallet
+		// itself.
+		if in.RescanFromHeight != 0 {
+			return nil, fmt.Errorf("rescan height not supported " +
+				"with remote wallets")
+		}
+
 		// Using a remote wallet.
 		return u.unlockRemoteWallet(ctx, in)
 	}
@@ -503,10 +561,11 @@ func (u *UnlockerService) UnlockWallet(ctx context.Context,
 	// We successfully opened the wallet and pass the instance back to
 	// avoid it needing to be unlocked again.
 	walletUnlockMsg := &WalletUnlockMsg{
-		Passphrase:     password,
-		RecoveryWindow: gapLimit,
-		Wallet:         unlockedWallet,
-		Loader:         loader,
+		Passphrase:       password,
+		RecoveryWindow:   gapLimit,
+		RescanFromHeight: in.RescanFromHeight,
+		Wallet:           unlockedWallet,
+		Loader:           loader,
 	}
 
 	// Before we return the unlock payload, we'll check if we can extract
@@ -530,6 +589,12 @@ func (u *UnlockerService) UnlockWallet(ctx context.Context,
 func (u *UnlockerService) ChangePassword(ctx context.Context,
 	in *lnrpc.ChangePasswordRequest) (*lnrpc.ChangePasswordResponse, error) {
 
+	// The wallet can't be used before the database is ready, so we wait
+	// for any migrations to complete.
+	if err := u.waitForMigrations(ctx); err != nil {
+		return nil, err
+	}
+
 	netDir := dcrwallet.NetworkDir(u.chainDir, u.netParams)
 	loader := walletloader.NewLoader(u.netParams, netDir, wallet.DefaultGapLimit)
 
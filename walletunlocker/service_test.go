@@ -18,6 +18,7 @@ import (
 	"github.com/decred/dcrlnd/lnwallet/dcrwallet"
 	walletloader "github.com/decred/dcrlnd/lnwallet/dcrwallet/loader"
 	"github.com/decred/dcrlnd/walletunlocker"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -34,6 +35,8 @@ var (
 	testNetParams = chaincfg.SimNetParams()
 
 	testRecoveryWindow uint32 = 150
+
+	testRescanFromHeight int32 = 420000
 )
 
 func createTestWallet(t *testing.T, dir string, netParams *chaincfg.Params) {
@@ -217,6 +220,7 @@ func TestInitWallet(t *testing.T) {
 		CipherSeedMnemonic: mnemonic[:],
 		AezeedPassphrase:   pass,
 		RecoveryWindow:     int32(testRecoveryWindow),
+		RescanFromHeight:   testRescanFromHeight,
 	}
 	_, err = service.InitWallet(ctx, req)
 	if err != nil {
@@ -251,11 +255,28 @@ func TestInitWallet(t *testing.T) {
 				"got %v", testRecoveryWindow,
 				msg.RecoveryWindow)
 		}
+		if msg.RescanFromHeight != testRescanFromHeight {
+			t.Fatalf("mismatched rescan height: expected %v, "+
+				"got %v", testRescanFromHeight,
+				msg.RescanFromHeight)
+		}
 
 	case <-time.After(3 * time.Second):
 		t.Fatalf("password not received")
 	}
 
+	// A negative rescan height should be rejected.
+	invalidReq := &lnrpc.InitWalletRequest{
+		WalletPassword:     testPassword,
+		CipherSeedMnemonic: mnemonic[:],
+		AezeedPassphrase:   pass,
+		RescanFromHeight:   -1,
+	}
+	_, err = service.InitWallet(ctx, invalidReq)
+	if err == nil {
+		t.Fatalf("InitWallet did not fail with negative rescan height")
+	}
+
 	// Create a wallet in testDir.
 	createTestWallet(t, testDir, testNetParams)
 
@@ -471,3 +492,101 @@ func TestChangeWalletPassword(t *testing.T) {
 		t.Fatalf("password not received")
 	}
 }
+
+// mockMigrationStatusStream is a mock implementation of the migration status
+// subscription stream.
+type mockMigrationStatusStream struct {
+	grpc.ServerStream
+
+	updates chan *lnrpc.MigrationStatus
+}
+
+func (m *mockMigrationStatusStream) Send(s *lnrpc.MigrationStatus) error {
+	m.updates <- s
+	return nil
+}
+
+func (m *mockMigrationStatusStream) Context() context.Context {
+	return context.Background()
+}
+
+// TestMigrationStatus tests that unlocking the wallet waits for the database
+// migrations to complete, and that their progress is streamed to subscribers.
+func TestMigrationStatus(t *testing.T) {
+	t.Parallel()
+
+	testDir, err := ioutil.TempDir("", "testmigration")
+	if err != nil {
+		t.Fatalf("unable to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(testDir)
+
+	service := walletunlocker.New(
+		testDir, testNetParams, true, nil, nil, "", "", "", "", 0,
+	)
+	defer service.Stop()
+
+	// Unlocking the wallet blocks until the migrations are done.
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 50*time.Millisecond,
+	)
+	defer cancel()
+	req := &lnrpc.UnlockWalletRequest{WalletPassword: testPassword}
+	_, err = service.UnlockWallet(ctx, req)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	stream := &mockMigrationStatusStream{
+		updates: make(chan *lnrpc.MigrationStatus, 10),
+	}
+	errChan := make(chan error, 1)
+	go func() {
+		errChan <- service.SubscribeMigrationStatus(
+			&lnrpc.MigrationStatusRequest{}, stream,
+		)
+	}()
+
+	nextUpdate := func() *lnrpc.MigrationStatus {
+		t.Helper()
+
+		select {
+		case s := <-stream.updates:
+			return s
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no migration status received")
+			return nil
+		}
+	}
+
+	// The initial status is sent right away.
+	if status := nextUpdate(); status.Done {
+		t.Fatalf("migrations reported done before starting")
+	}
+
+	service.MigrationProgress(channeldb.MigrationProgress{
+		Version: 5,
+		Applied: 1,
+		Total:   2,
+	})
+	status := nextUpdate()
+	if status.CurrentVersion != 5 || status.Percent != 50 ||
+		status.Done {
+
+		t.Fatalf("unexpected migration status: %v", status)
+	}
+
+	// Once the database is provided, the subscription is done.
+	service.SetChanDB(&channeldb.DB{})
+	if status := nextUpdate(); !status.Done || status.Percent != 100 {
+		t.Fatalf("unexpected migration status: %v", status)
+	}
+	select {
+	case err := <-errChan:
+		if err != nil {
+			t.Fatalf("unexpected subscription error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("subscription did not exit")
+	}
+}
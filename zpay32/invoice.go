@@ -3,6 +3,7 @@ package zpay32
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/decred/dcrd/chaincfg/v3"
@@ -106,6 +107,42 @@ var decredHRPPrefixes = map[string]string{
 	chaincfg.TestNet3Params().Name: "tdcr",
 }
 
+// RegisterNetwork registers the prefix of the HRP of the invoices of a custom
+// network. It must be called before any invoice of the network is encoded or
+// decoded, as the registered prefixes aren't protected for concurrent access.
+func RegisterNetwork(net *chaincfg.Params, hrpPrefix string) error {
+	if _, ok := decredHRPPrefixes[net.Name]; ok {
+		return fmt.Errorf("network %q is already registered", net.Name)
+	}
+
+	// The prefix is followed by the amount of the invoice, so it's limited
+	// to lowercase letters.
+	if hrpPrefix == "" {
+		return errors.New("invoice HRP prefix must not be empty")
+	}
+	for _, c := range hrpPrefix {
+		if c < 'a' || c > 'z' {
+			return fmt.Errorf("invalid invoice HRP prefix %q: only "+
+				"lowercase letters are allowed", hrpPrefix)
+		}
+	}
+
+	// A prefix extending another would make the network of an invoice
+	// ambiguous.
+	for name, prefix := range decredHRPPrefixes {
+		if strings.HasPrefix(hrpPrefix, prefix) ||
+			strings.HasPrefix(prefix, hrpPrefix) {
+
+			return fmt.Errorf("invoice HRP prefix %q conflicts with "+
+				"prefix %q of network %q", hrpPrefix, prefix,
+				name)
+		}
+	}
+
+	decredHRPPrefixes[net.Name] = hrpPrefix
+	return nil
+}
+
 // MessageSigner is passed to the Encode method to provide a signature
 // corresponding to the node's pubkey.
 type MessageSigner struct {
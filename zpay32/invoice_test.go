@@ -777,6 +777,50 @@ func TestMaxInvoiceLength(t *testing.T) {
 	}
 }
 
+// TestRegisterNetwork asserts that invoices of registered custom networks are
+// encoded with their HRP prefix, and that conflicting prefixes are rejected.
+func TestRegisterNetwork(t *testing.T) {
+	net := chaincfg.RegNetParams()
+	net.Name = "privnet"
+
+	for _, prefix := range []string{"", "dcrp", "dc", "p1", "Pdcr"} {
+		if err := RegisterNetwork(net, prefix); err == nil {
+			t.Fatalf("expected error registering prefix %q", prefix)
+		}
+	}
+
+	if err := RegisterNetwork(net, "pdcr"); err != nil {
+		t.Fatalf("unable to register network: %v", err)
+	}
+	defer delete(decredHRPPrefixes, net.Name)
+
+	if err := RegisterNetwork(net, "qdcr"); err == nil {
+		t.Fatalf("expected error registering network twice")
+	}
+
+	invoice, err := NewInvoice(
+		net, testPaymentHash, time.Unix(0, 0), Description("test"),
+		Amount(testMilliAt25mDCR),
+	)
+	if err != nil {
+		t.Fatalf("unable to create invoice: %v", err)
+	}
+	encoded, err := invoice.Encode(testMessageSigner)
+	if err != nil {
+		t.Fatalf("unable to encode invoice: %v", err)
+	}
+	if !strings.HasPrefix(encoded, "lnpdcr25m1") {
+		t.Fatalf("unexpected invoice prefix: %v", encoded)
+	}
+
+	if _, err := Decode(encoded, net); err != nil {
+		t.Fatalf("unable to decode invoice: %v", err)
+	}
+	if _, err := Decode(encoded, chaincfg.RegNetParams()); err == nil {
+		t.Fatalf("expected error decoding invoice of another network")
+	}
+}
+
 // TestInvoiceChecksumMalleability ensures that the malleability of the
 // checksum in bech32 strings cannot cause a signature to become valid and
 // therefore cause a wrong destination to be decoded for invoices where the
@@ -0,0 +1,82 @@
+package zpay32
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/decred/dcrd/dcrutil/v3"
+)
+
+const (
+	// LightningURIScheme is the URI scheme of payment requests.
+	LightningURIScheme = "lightning"
+
+	// DecredURIScheme is the URI scheme of on-chain payments.
+	DecredURIScheme = "decred"
+)
+
+// ErrNoFallbackAddr is returned when building the unified URI of an invoice
+// without an on-chain fallback address.
+var ErrNoFallbackAddr = errors.New("invoice has no fallback address")
+
+// LightningURI returns the URI of an encoded payment request, of the form
+// "lightning:<payment request>". Payment requests are bech32 encoded, so the
+// whole URI may be uppercased by clients, e.g. to render it as a more compact
+// QR code.
+func LightningURI(payReq string) string {
+	return LightningURIScheme + ":" + strings.ToLower(payReq)
+}
+
+// UnifiedURI returns a BIP21-like URI paying to the on-chain fallback address
+// of the invoice, which carries the encoded payment request as its lightning
+// parameter so wallets supporting Lightning can pay it off-chain instead. The
+// URI has the form:
+//
+//	decred:<address>?amount=<DCR>&message=<description>&lightning=<payreq>
+//
+// The amount and message are omitted if the invoice has none. Unlike the
+// Lightning URI, the unified URI must not be uppercased, as on-chain addresses
+// are case sensitive.
+func UnifiedURI(invoice *Invoice, payReq string) (string, error) {
+	if invoice.FallbackAddr == nil {
+		return "", ErrNoFallbackAddr
+	}
+
+	var params []string
+	if invoice.MilliAt != nil && *invoice.MilliAt > 0 {
+		// On-chain payments can't carry fractions of atoms, so the
+		// amount is rounded up.
+		atoms := dcrutil.Amount((*invoice.MilliAt + 999) / 1000)
+		params = append(params, "amount="+formatURIAmount(atoms))
+	}
+	if invoice.Description != nil && *invoice.Description != "" {
+		params = append(
+			params, "message="+uriEscape(*invoice.Description),
+		)
+	}
+	params = append(params, "lightning="+strings.ToLower(payReq))
+
+	return fmt.Sprintf("%s:%s?%s", DecredURIScheme,
+		invoice.FallbackAddr.Address(), strings.Join(params, "&")), nil
+}
+
+// formatURIAmount formats an amount in DCR without trailing zeros, as
+// expected by the amount parameter of payment URIs.
+func formatURIAmount(amt dcrutil.Amount) string {
+	whole := int64(amt) / dcrutil.AtomsPerCoin
+	frac := int64(amt) % dcrutil.AtomsPerCoin
+	if frac == 0 {
+		return fmt.Sprintf("%d", whole)
+	}
+
+	fracStr := strings.TrimRight(fmt.Sprintf("%08d", frac), "0")
+	return fmt.Sprintf("%d.%s", whole, fracStr)
+}
+
+// uriEscape percent-encodes a URI parameter value. Spaces are encoded as %20
+// rather than +, which BIP21 parsers don't decode.
+func uriEscape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
@@ -0,0 +1,73 @@
+package zpay32
+
+import (
+	"testing"
+
+	"github.com/decred/dcrlnd/lnwire"
+)
+
+// TestPaymentURIs asserts that the Lightning and unified URIs of invoices are
+// correctly cased and encoded.
+func TestPaymentURIs(t *testing.T) {
+	t.Parallel()
+
+	const payReq = "LNDCR1TESTPAYREQ"
+
+	uri := LightningURI(payReq)
+	if uri != "lightning:lndcr1testpayreq" {
+		t.Fatalf("unexpected lightning uri: %v", uri)
+	}
+
+	oddAmt := lnwire.MilliAtom(150000001)
+	message := "1 cup coffee & 50% off+"
+
+	testCases := []struct {
+		name     string
+		invoice  *Invoice
+		expected string
+	}{{
+		name: "no amount",
+		invoice: &Invoice{
+			FallbackAddr: testRustyAddr,
+		},
+		expected: "decred:DsQxuVRvS4eaJ42dhQEsCXauMWjvopWgrVg?" +
+			"lightning=lndcr1testpayreq",
+	}, {
+		name: "whole amount",
+		invoice: &Invoice{
+			MilliAt:      &testMilliAt24DCR,
+			FallbackAddr: testRustyAddr,
+		},
+		expected: "decred:DsQxuVRvS4eaJ42dhQEsCXauMWjvopWgrVg?" +
+			"amount=24&lightning=lndcr1testpayreq",
+	}, {
+		name: "fractional amount rounded up and message",
+		invoice: &Invoice{
+			MilliAt:      &oddAmt,
+			Description:  &message,
+			FallbackAddr: testRustyAddr,
+		},
+		expected: "decred:DsQxuVRvS4eaJ42dhQEsCXauMWjvopWgrVg?" +
+			"amount=0.00150001&message=1%20cup%20coffee%20%26%20" +
+			"50%25%20off%2B&lightning=lndcr1testpayreq",
+	}}
+
+	for _, test := range testCases {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			uri, err := UnifiedURI(test.invoice, payReq)
+			if err != nil {
+				t.Fatalf("unable to build unified uri: %v", err)
+			}
+			if uri != test.expected {
+				t.Fatalf("expected uri %v, got %v",
+					test.expected, uri)
+			}
+		})
+	}
+
+	_, err := UnifiedURI(&Invoice{}, payReq)
+	if err != ErrNoFallbackAddr {
+		t.Fatalf("expected ErrNoFallbackAddr, got %v", err)
+	}
+}